@@ -0,0 +1,107 @@
+// Package apiversion negotiates the version of the API response shapes.
+// Clients ask for a version with the api_version query parameter or an
+// Accept: application/vnd.moon.v{N}+json header; without either they get V1,
+// so existing integrations keep the shapes they were written against.
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Version is an API response version
+type Version int
+
+// Supported versions
+const (
+	// V1 is the original response shape
+	V1 Version = 1
+	// V2 nests errors in a single error object and list pagination under "page"
+	V2 Version = 2
+)
+
+// Default is the version of requests that do not ask for one
+const Default = V1
+
+// Supported lists the versions a client may request, oldest first
+var Supported = []Version{V1, V2}
+
+// QueryParam is the query parameter selecting a version
+const QueryParam = "api_version"
+
+// Media type of a versioned Accept header: application/vnd.moon.v2+json
+const (
+	mediaTypePrefix = "application/vnd.moon.v"
+	mediaTypeSuffix = "+json"
+)
+
+// String returns the version number
+func (v Version) String() string {
+	return strconv.Itoa(int(v))
+}
+
+// Parse returns the supported version with the given number
+func Parse(s string) (Version, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	for _, v := range Supported {
+		if int(v) == n {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Negotiate returns the version requested by r. The query parameter wins
+// over the Accept header, where the first vendor media type counts and other
+// media types are ignored. An unsupported version is an error naming the
+// supported ones.
+func Negotiate(r *http.Request) (Version, error) {
+	if requested := r.URL.Query().Get(QueryParam); requested != "" {
+		return parseRequested(requested)
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(part)
+			if err != nil || !strings.HasPrefix(mediaType, mediaTypePrefix) || !strings.HasSuffix(mediaType, mediaTypeSuffix) {
+				continue
+			}
+			return parseRequested(strings.TrimSuffix(strings.TrimPrefix(mediaType, mediaTypePrefix), mediaTypeSuffix))
+		}
+	}
+	return Default, nil
+}
+
+// parseRequested parses a requested version number
+func parseRequested(requested string) (Version, error) {
+	if v, ok := Parse(requested); ok {
+		return v, nil
+	}
+	names := make([]string, len(Supported))
+	for i, v := range Supported {
+		names[i] = v.String()
+	}
+	return 0, fmt.Errorf("unsupported API version '%s'; supported versions: %s", requested, strings.Join(names, ", "))
+}
+
+// contextKey is the context key of the negotiated version
+type contextKey struct{}
+
+// WithContext returns a context carrying the version
+func WithContext(ctx context.Context, v Version) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the version stored in ctx, or Default
+func FromContext(ctx context.Context) Version {
+	if v, ok := ctx.Value(contextKey{}).(Version); ok {
+		return v
+	}
+	return Default
+}
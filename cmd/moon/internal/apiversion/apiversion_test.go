@@ -0,0 +1,56 @@
+package apiversion
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		accept  string
+		want    Version
+		wantErr bool
+	}{
+		{"default", "/notes:list", "", V1, false},
+		{"plain json", "/notes:list", "application/json", V1, false},
+		{"query v1", "/notes:list?api_version=1", "", V1, false},
+		{"query v2", "/notes:list?api_version=2", "", V2, false},
+		{"accept v2", "/notes:list", "application/vnd.moon.v2+json", V2, false},
+		{"accept v2 among others", "/notes:list", "text/html, application/vnd.moon.v2+json; q=0.9", V2, false},
+		{"query wins over accept", "/notes:list?api_version=1", "application/vnd.moon.v2+json", V1, false},
+		{"unknown query", "/notes:list?api_version=9", "", 0, true},
+		{"malformed query", "/notes:list?api_version=two", "", 0, true},
+		{"unknown accept", "/notes:list", "application/vnd.moon.v3+json", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, err := Negotiate(r)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "supported versions: 1, 2") {
+					t.Fatalf("expected an error listing the supported versions, got %v %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("expected v%s, got v%s %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if v := FromContext(context.Background()); v != Default {
+		t.Errorf("expected the default version without one in context, got %s", v)
+	}
+	if v := FromContext(WithContext(context.Background(), V2)); v != V2 {
+		t.Errorf("expected v2 from context, got %s", v)
+	}
+}
@@ -0,0 +1,135 @@
+// Package audit records successful mutating requests in the moon_audit
+// system table: who changed which collection or record, with what action,
+// and in which request. Entries are queued in memory and written by one
+// background writer, so requests do not wait for the audit insert and entries
+// are stored in the order they were recorded.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// logModule is the module of the audit logs, configured by logging.levels.audit
+const logModule = "audit"
+
+// MaxRecordIDs is the number of record ids kept per entry; RecordCount still
+// counts every record of larger batches
+const MaxRecordIDs = 100
+
+// Actor types of an entry
+const (
+	ActorUser      = "user"
+	ActorAPIKey    = "apikey"
+	ActorAnonymous = "anonymous"
+)
+
+// Entry is one recorded mutating request
+type Entry struct {
+	ID          string    `json:"id"` // ULID; entries sort by it in the order they were recorded
+	Timestamp   time.Time `json:"timestamp"`
+	Actor       string    `json:"actor"`      // user or API key id, or "anonymous"
+	ActorType   string    `json:"actor_type"` // user, apikey or anonymous
+	Collection  string    `json:"collection"` // physical table name; empty for auth events
+	Action      string    `json:"action"`
+	RecordIDs   []string  `json:"record_ids"` // at most MaxRecordIDs ids
+	RecordCount int       `json:"record_count"`
+	RequestID   string    `json:"request_id"`
+}
+
+// Log queues entries and writes them to the moon_audit table
+type Log struct {
+	db database.Driver
+
+	mu     sync.Mutex // guards closed, and orders ids with sends on queue
+	closed bool
+	queue  chan Entry
+	done   chan struct{} // closed when the writer has drained the queue
+}
+
+// New creates an audit log writing to db and starts its writer. queueSize
+// entries may wait to be written before further ones are dropped.
+func New(db database.Driver, queueSize int) *Log {
+	l := &Log{
+		db:    db,
+		queue: make(chan Entry, queueSize),
+		done:  make(chan struct{}),
+	}
+	go l.write()
+	return l
+}
+
+// Record queues an entry without blocking. The id and timestamp are assigned
+// here, and record ids beyond MaxRecordIDs are dropped after counting them.
+// When the queue is full or the log is closed the entry is dropped and logged.
+// A nil log ignores entries.
+func (l *Log) Record(entry Entry) {
+	if l == nil {
+		return
+	}
+
+	if entry.RecordCount < len(entry.RecordIDs) {
+		entry.RecordCount = len(entry.RecordIDs)
+	}
+	if len(entry.RecordIDs) > MaxRecordIDs {
+		entry.RecordIDs = entry.RecordIDs[:MaxRecordIDs:MaxRecordIDs]
+	}
+	if entry.RecordIDs == nil {
+		entry.RecordIDs = []string{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.ID = ulid.Generate()
+	entry.Timestamp = time.Now().UTC()
+	if l.closed {
+		logging.Module(logModule).Warnf("Audit entry %s (%s by %s) dropped: audit log is shut down", entry.ID, entry.Action, entry.Actor)
+		return
+	}
+	select {
+	case l.queue <- entry:
+	default:
+		logging.Module(logModule).Warnf("Audit entry %s (%s by %s) dropped: queue is full", entry.ID, entry.Action, entry.Actor)
+	}
+}
+
+// Close stops accepting entries and waits until the queued ones are written.
+// When ctx expires first the remaining entries are lost.
+func (l *Log) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.queue)
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d audit entries not written: %w", len(l.queue), ctx.Err())
+	}
+}
+
+// write inserts queued entries until the queue is closed and drained
+func (l *Log) write() {
+	defer close(l.done)
+	for entry := range l.queue {
+		if err := l.insert(context.Background(), entry); err != nil {
+			logging.Module(logModule).Errorf("Audit entry %s (%s by %s) lost: %v", entry.ID, entry.Action, entry.Actor, err)
+		}
+	}
+}
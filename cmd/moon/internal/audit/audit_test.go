@@ -0,0 +1,186 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// setupLog returns an audit log on a fresh in-memory database
+func setupLog(t *testing.T, queueSize int) *Log {
+	t.Helper()
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	l := New(driver, queueSize)
+	if err := l.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return l
+}
+
+func TestLog_WritesInOrder(t *testing.T) {
+	l := setupLog(t, 500)
+	for i := range 300 {
+		l.Record(Entry{Actor: "key1", ActorType: ActorAPIKey, Collection: "products", Action: "create", RecordIDs: []string{fmt.Sprint(i)}})
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	entries, err := l.Query(context.Background(), Filter{Limit: 1000})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 300 {
+		t.Fatalf("expected 300 entries after the flush, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.RecordIDs[0] != fmt.Sprint(i) {
+			t.Fatalf("entry %d: expected record %d, got %v", i, i, entry.RecordIDs)
+		}
+		if i > 0 && entry.Timestamp.Before(entries[i-1].Timestamp) {
+			t.Errorf("entry %d: timestamp %s before the previous %s", i, entry.Timestamp, entries[i-1].Timestamp)
+		}
+	}
+
+	// Entries recorded after Close are dropped, not written
+	l.Record(Entry{Actor: "key1", ActorType: ActorAPIKey, Action: "create"})
+	if entries, _ := l.Query(context.Background(), Filter{Limit: 1000}); len(entries) != 300 {
+		t.Errorf("expected no entry after Close, got %d entries", len(entries))
+	}
+}
+
+func TestLog_CapsRecordIDs(t *testing.T) {
+	l := setupLog(t, 10)
+	ids := make([]string, MaxRecordIDs+50)
+	for i := range ids {
+		ids[i] = ulid.Generate()
+	}
+	l.Record(Entry{Actor: "u1", ActorType: ActorUser, Collection: "products", Action: "destroy", RecordIDs: ids})
+	l.Close(context.Background())
+
+	entries, err := l.Query(context.Background(), Filter{Limit: 10})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one entry, got %v %v", entries, err)
+	}
+	entry := entries[0]
+	if len(entry.RecordIDs) != MaxRecordIDs || entry.RecordCount != len(ids) {
+		t.Errorf("expected %d ids of %d records, got %d of %d", MaxRecordIDs, len(ids), len(entry.RecordIDs), entry.RecordCount)
+	}
+	if entry.RecordIDs[0] != ids[0] || entry.RecordIDs[MaxRecordIDs-1] != ids[MaxRecordIDs-1] {
+		t.Errorf("expected the first ids to be kept")
+	}
+}
+
+func TestLog_QueryPages(t *testing.T) {
+	l := setupLog(t, 100)
+	for i := range 7 {
+		collection := "products"
+		if i%2 == 1 {
+			collection = "orders"
+		}
+		l.Record(Entry{Actor: "u1", ActorType: ActorUser, Collection: collection, Action: "update"})
+	}
+	l.Close(context.Background())
+
+	all, _ := l.Query(context.Background(), Filter{Limit: 100})
+	var pages [][]Entry
+	after := ""
+	for {
+		page, err := l.Query(context.Background(), Filter{Collection: "products", After: after, Limit: 2})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, page)
+		after = page[len(page)-1].ID
+	}
+
+	if len(pages) != 2 || len(pages[0]) != 2 || len(pages[1]) != 2 {
+		t.Fatalf("expected two pages of two products entries, got %v", pages)
+	}
+	for i, entry := range append(pages[0], pages[1]...) {
+		if entry.Collection != "products" || entry.ID != all[2*i].ID {
+			t.Errorf("entry %d: expected %s of products, got %s of %s", i, all[2*i].ID, entry.ID, entry.Collection)
+		}
+	}
+}
+
+func TestLog_Prune(t *testing.T) {
+	l := setupLog(t, 10)
+	ctx := context.Background()
+	now := time.Now()
+	for _, age := range []time.Duration{100 * 24 * time.Hour, 31 * 24 * time.Hour, 29 * 24 * time.Hour} {
+		recorded := now.Add(-age)
+		entry := Entry{ID: ulid.GenerateWithTime(recorded), Timestamp: recorded, Actor: "u1", ActorType: ActorUser, Action: "create", RecordIDs: []string{}}
+		if err := l.insert(ctx, entry); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+	l.Record(Entry{Actor: "u1", ActorType: ActorUser, Action: "create"})
+	l.Close(ctx)
+
+	pruned, err := l.Prune(ctx, 30)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("expected 2 entries pruned, got %d", pruned)
+	}
+	entries, _ := l.Query(ctx, Filter{Limit: 10})
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries kept, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if now.Sub(entry.Timestamp) > 30*24*time.Hour {
+			t.Errorf("expected entries of the last 30 days, got one of %s", entry.Timestamp)
+		}
+	}
+}
+
+func TestNote(t *testing.T) {
+	ctx, note := Track(context.Background())
+	SetCollection(ctx, "products")
+	for i := range MaxRecordIDs + 5 {
+		AddRecords(ctx, fmt.Sprint(i))
+	}
+	entry, ok := note.Entry(Entry{Action: "create", Collection: "ignored", Actor: "k1", ActorType: ActorAPIKey})
+	if !ok || entry.Collection != "products" || entry.Actor != "k1" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if len(entry.RecordIDs) != MaxRecordIDs || entry.RecordCount != MaxRecordIDs+5 {
+		t.Errorf("expected %d ids of %d records, got %d of %d", MaxRecordIDs, MaxRecordIDs+5, len(entry.RecordIDs), entry.RecordCount)
+	}
+
+	SetActor(ctx, "u1", ActorUser)
+	if entry, _ := note.Entry(Entry{}); entry.Actor != "u1" || entry.ActorType != ActorUser {
+		t.Errorf("expected the noted actor, got %+v", entry)
+	}
+
+	Discard(ctx)
+	if _, ok := note.Entry(Entry{}); ok {
+		t.Error("expected a discarded note to give no entry")
+	}
+
+	// Requests that are not audited carry no note
+	AddRecords(context.Background(), "x")
+	Discard(context.Background())
+}
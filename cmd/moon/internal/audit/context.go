@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// Note collects what a handler knows about its request for the audit entry
+// written once the request succeeds: the records and collection it changed,
+// an actor for requests that authenticate themselves, or that nothing changed.
+type Note struct {
+	mu          sync.Mutex
+	collection  string
+	recordIDs   []string
+	recordCount int
+	actor       string
+	actorType   string
+	discarded   bool
+}
+
+// noteKey is the context key of the request's note
+type noteKey struct{}
+
+// Track returns a context carrying a new note for the handlers of a request
+func Track(ctx context.Context) (context.Context, *Note) {
+	note := &Note{}
+	return context.WithValue(ctx, noteKey{}, note), note
+}
+
+// noteFrom returns the note of ctx, or nil when the request is not audited
+func noteFrom(ctx context.Context) *Note {
+	note, _ := ctx.Value(noteKey{}).(*Note)
+	return note
+}
+
+// Tracked reports whether the request of ctx is audited, for handlers that
+// would read record ids only for its entry
+func Tracked(ctx context.Context) bool {
+	return noteFrom(ctx) != nil
+}
+
+// AddRecords notes ids of records the request changed. Only the first
+// MaxRecordIDs are kept; the rest are counted.
+func AddRecords(ctx context.Context, ids ...string) {
+	note := noteFrom(ctx)
+	if note == nil {
+		return
+	}
+	note.mu.Lock()
+	defer note.mu.Unlock()
+	note.recordCount += len(ids)
+	if room := MaxRecordIDs - len(note.recordIDs); room > 0 {
+		note.recordIDs = append(note.recordIDs, ids[:min(room, len(ids))]...)
+	}
+}
+
+// SetCollection notes the collection the request changed, for requests
+// naming it in the body rather than the path
+func SetCollection(ctx context.Context, collection string) {
+	if note := noteFrom(ctx); note != nil {
+		note.mu.Lock()
+		note.collection = collection
+		note.mu.Unlock()
+	}
+}
+
+// SetActor notes who made a request that authenticated itself, such as a
+// login, in place of the request's credentials
+func SetActor(ctx context.Context, actor, actorType string) {
+	if note := noteFrom(ctx); note != nil {
+		note.mu.Lock()
+		note.actor, note.actorType = actor, actorType
+		note.mu.Unlock()
+	}
+}
+
+// Discard notes that a successful request changed nothing, as for dry runs,
+// so that no entry is written
+func Discard(ctx context.Context) {
+	if note := noteFrom(ctx); note != nil {
+		note.mu.Lock()
+		note.discarded = true
+		note.mu.Unlock()
+	}
+}
+
+// Entry completes an entry with what the handlers noted. ok is false when a
+// handler discarded the request.
+func (n *Note) Entry(entry Entry) (Entry, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.discarded {
+		return Entry{}, false
+	}
+	if n.collection != "" {
+		entry.Collection = n.collection
+	}
+	if n.actor != "" {
+		entry.Actor, entry.ActorType = n.actor, n.actorType
+	}
+	entry.RecordIDs = append([]string(nil), n.recordIDs...)
+	entry.RecordCount = n.recordCount
+	return entry, true
+}
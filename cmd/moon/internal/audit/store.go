@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// Filter selects the entries returned by Query
+type Filter struct {
+	Collection string // entries of one collection; empty for all
+	After      string // id of the last entry of the previous page
+	Limit      int
+}
+
+// Init creates the moon_audit table and its index if they do not exist
+func (l *Log) Init(ctx context.Context) error {
+	for _, statement := range createTableSQL(l.db.Dialect()) {
+		if _, err := l.db.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", constants.TableAudit, err)
+		}
+	}
+	return nil
+}
+
+// Query returns up to filter.Limit entries ordered by id, oldest first
+func (l *Log) Query(ctx context.Context, filter Filter) ([]Entry, error) {
+	dialect := l.db.Dialect()
+	sql := "SELECT id, created_at, actor, actor_type, collection, action, record_ids, record_count, request_id FROM " + constants.TableAudit
+	var where []string
+	var args []any
+	if filter.Collection != "" {
+		args = append(args, filter.Collection)
+		where = append(where, "collection = "+query.Placeholder(dialect, len(args)))
+	}
+	if filter.After != "" {
+		args = append(args, filter.After)
+		where = append(where, "id > "+query.Placeholder(dialect, len(args)))
+	}
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += fmt.Sprintf(" ORDER BY id LIMIT %d", filter.Limit)
+
+	rows, err := l.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableAudit, err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var recordIDs string
+		if err := rows.Scan(&entry.ID, &entry.Timestamp, &entry.Actor, &entry.ActorType, &entry.Collection,
+			&entry.Action, &recordIDs, &entry.RecordCount, &entry.RequestID); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", constants.TableAudit, err)
+		}
+		if err := json.Unmarshal([]byte(recordIDs), &entry.RecordIDs); err != nil {
+			return nil, fmt.Errorf("invalid record ids in audit entry %s: %w", entry.ID, err)
+		}
+		entry.Timestamp = entry.Timestamp.UTC()
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableAudit, err)
+	}
+	return entries, nil
+}
+
+// Prune deletes the entries recorded more than retentionDays days ago and
+// returns how many were deleted. Ids sort by creation time, so the cutoff is
+// compared as the id of an entry recorded at that moment.
+func (l *Log) Prune(ctx context.Context, retentionDays int) (int64, error) {
+	cutoff := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour)
+	result, err := l.db.Exec(ctx, "DELETE FROM "+constants.TableAudit+" WHERE id < "+query.Placeholder(l.db.Dialect(), 1), ulid.GenerateWithTime(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune %s: %w", constants.TableAudit, err)
+	}
+	return result.RowsAffected()
+}
+
+// insert writes one entry
+func (l *Log) insert(ctx context.Context, entry Entry) error {
+	recordIDs, err := json.Marshal(entry.RecordIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode record ids: %w", err)
+	}
+
+	dialect := l.db.Dialect()
+	values := make([]string, 9)
+	for i := range values {
+		values[i] = query.Placeholder(dialect, i+1)
+	}
+	_, err = l.db.Exec(ctx, "INSERT INTO "+constants.TableAudit+
+		" (id, created_at, actor, actor_type, collection, action, record_ids, record_count, request_id) VALUES ("+strings.Join(values, ", ")+")",
+		entry.ID, entry.Timestamp, entry.Actor, entry.ActorType, entry.Collection, entry.Action, string(recordIDs), entry.RecordCount, entry.RequestID)
+	return err
+}
+
+// createTableSQL returns the moon_audit DDL for the given dialect. The index
+// serves the collection filter of Query; the primary key serves its order.
+func createTableSQL(dialect database.DialectType) []string {
+	switch dialect {
+	case database.DialectPostgres:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableAudit + ` (
+			id VARCHAR(26) PRIMARY KEY,
+			created_at TIMESTAMP NOT NULL,
+			actor VARCHAR(64) NOT NULL,
+			actor_type VARCHAR(20) NOT NULL,
+			collection VARCHAR(255) NOT NULL DEFAULT '',
+			action VARCHAR(100) NOT NULL,
+			record_ids TEXT NOT NULL,
+			record_count INTEGER NOT NULL,
+			request_id VARCHAR(128) NOT NULL DEFAULT ''
+		)`,
+			`CREATE INDEX IF NOT EXISTS idx_moon_audit_collection ON ` + constants.TableAudit + `(collection, id)`,
+		}
+	case database.DialectMySQL:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableAudit + ` (
+			id VARCHAR(26) PRIMARY KEY,
+			created_at DATETIME NOT NULL,
+			actor VARCHAR(64) NOT NULL,
+			actor_type VARCHAR(20) NOT NULL,
+			collection VARCHAR(255) NOT NULL DEFAULT '',
+			action VARCHAR(100) NOT NULL,
+			record_ids TEXT NOT NULL,
+			record_count INT NOT NULL,
+			request_id VARCHAR(128) NOT NULL DEFAULT '',
+			INDEX idx_moon_audit_collection (collection, id)
+		)`,
+		}
+	default:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableAudit + ` (
+			id TEXT PRIMARY KEY,
+			created_at DATETIME NOT NULL,
+			actor TEXT NOT NULL,
+			actor_type TEXT NOT NULL,
+			collection TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			record_ids TEXT NOT NULL,
+			record_count INTEGER NOT NULL,
+			request_id TEXT NOT NULL DEFAULT ''
+		)`,
+			`CREATE INDEX IF NOT EXISTS idx_moon_audit_collection ON ` + constants.TableAudit + `(collection, id)`,
+		}
+	}
+}
@@ -8,12 +8,16 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"sync"
 	"time"
 
 	"github.com/thalib/moon/cmd/moon/internal/database"
 	moonulid "github.com/thalib/moon/cmd/moon/internal/ulid"
 )
 
+// apiKeyColumns are the columns read by scanAPIKey.
+const apiKeyColumns = "pkid, id, name, description, key_hash, role, can_write, scopes, tenant, created_at, last_used_at, expires_at, revoked_at"
+
 // base62Charset is used for generating API keys.
 const base62Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 
@@ -56,28 +60,33 @@ func HashAPIKey(key string) string {
 
 // Create creates a new API key in the database.
 func (r *APIKeyRepository) Create(ctx context.Context, apiKey *APIKey) error {
+	scopes, err := encodeScopes(apiKey.Scopes)
+	if err != nil {
+		return err
+	}
+
 	apiKey.ID = moonulid.Generate()
 	apiKey.CreatedAt = time.Now()
 
 	var query string
 	switch r.db.Dialect() {
 	case database.DialectPostgres:
-		query = fmt.Sprintf(`INSERT INTO %s (id, name, description, key_hash, role, can_write, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING pkid`, constants.TableAPIKeys)
+		query = fmt.Sprintf(`INSERT INTO %s (id, name, description, key_hash, role, can_write, scopes, tenant, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING pkid`, constants.TableAPIKeys)
 		err := r.db.QueryRow(ctx, query,
 			apiKey.ID, apiKey.Name, apiKey.Description, apiKey.KeyHash,
-			apiKey.Role, apiKey.CanWrite, apiKey.CreatedAt,
+			apiKey.Role, apiKey.CanWrite, scopes, apiKey.Tenant, apiKey.CreatedAt, apiKey.ExpiresAt,
 		).Scan(&apiKey.PKID)
 		if err != nil {
 			return fmt.Errorf("failed to create API key: %w", err)
 		}
 		return nil
 	default:
-		query = fmt.Sprintf(`INSERT INTO %s (id, name, description, key_hash, role, can_write, created_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?)`, constants.TableAPIKeys)
+		query = fmt.Sprintf(`INSERT INTO %s (id, name, description, key_hash, role, can_write, scopes, tenant, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, constants.TableAPIKeys)
 		result, err := r.db.Exec(ctx, query,
 			apiKey.ID, apiKey.Name, apiKey.Description, apiKey.KeyHash,
-			apiKey.Role, apiKey.CanWrite, apiKey.CreatedAt,
+			apiKey.Role, apiKey.CanWrite, scopes, apiKey.Tenant, apiKey.CreatedAt, apiKey.ExpiresAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to create API key: %w", err)
@@ -93,16 +102,12 @@ func (r *APIKeyRepository) Create(ctx context.Context, apiKey *APIKey) error {
 
 // GetByPKID retrieves an API key by internal primary key ID.
 func (r *APIKeyRepository) GetByPKID(ctx context.Context, pkid int64) (*APIKey, error) {
-	query := fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s WHERE pkid = ?", constants.TableAPIKeys)
+	query := fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s WHERE pkid = ?", constants.TableAPIKeys)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s WHERE pkid = $1", constants.TableAPIKeys)
+		query = fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s WHERE pkid = $1", constants.TableAPIKeys)
 	}
 
-	apiKey := &APIKey{}
-	err := r.db.QueryRow(ctx, query, pkid).Scan(
-		&apiKey.PKID, &apiKey.ID, &apiKey.Name, &apiKey.Description, &apiKey.KeyHash,
-		&apiKey.Role, &apiKey.CanWrite, &apiKey.CreatedAt, &apiKey.LastUsedAt,
-	)
+	apiKey, err := scanAPIKey(r.db.QueryRow(ctx, query, pkid))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -114,16 +119,12 @@ func (r *APIKeyRepository) GetByPKID(ctx context.Context, pkid int64) (*APIKey,
 
 // GetByID retrieves an API key by ID (ULID).
 func (r *APIKeyRepository) GetByID(ctx context.Context, id string) (*APIKey, error) {
-	query := fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s WHERE id = ?", constants.TableAPIKeys)
+	query := fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s WHERE id = ?", constants.TableAPIKeys)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s WHERE id = $1", constants.TableAPIKeys)
+		query = fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s WHERE id = $1", constants.TableAPIKeys)
 	}
 
-	apiKey := &APIKey{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&apiKey.PKID, &apiKey.ID, &apiKey.Name, &apiKey.Description, &apiKey.KeyHash,
-		&apiKey.Role, &apiKey.CanWrite, &apiKey.CreatedAt, &apiKey.LastUsedAt,
-	)
+	apiKey, err := scanAPIKey(r.db.QueryRow(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -135,16 +136,12 @@ func (r *APIKeyRepository) GetByID(ctx context.Context, id string) (*APIKey, err
 
 // GetByHash retrieves an API key by its hash.
 func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*APIKey, error) {
-	query := fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s WHERE key_hash = ?", constants.TableAPIKeys)
+	query := fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s WHERE key_hash = ?", constants.TableAPIKeys)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s WHERE key_hash = $1", constants.TableAPIKeys)
+		query = fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s WHERE key_hash = $1", constants.TableAPIKeys)
 	}
 
-	apiKey := &APIKey{}
-	err := r.db.QueryRow(ctx, query, keyHash).Scan(
-		&apiKey.PKID, &apiKey.ID, &apiKey.Name, &apiKey.Description, &apiKey.KeyHash,
-		&apiKey.Role, &apiKey.CanWrite, &apiKey.CreatedAt, &apiKey.LastUsedAt,
-	)
+	apiKey, err := scanAPIKey(r.db.QueryRow(ctx, query, keyHash))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -156,16 +153,21 @@ func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*APIK
 
 // Update updates an API key in the database.
 func (r *APIKeyRepository) Update(ctx context.Context, apiKey *APIKey) error {
+	scopes, err := encodeScopes(apiKey.Scopes)
+	if err != nil {
+		return err
+	}
+
 	var query string
 	switch r.db.Dialect() {
 	case database.DialectPostgres:
-		query = fmt.Sprintf(`UPDATE %s SET name = $1, description = $2, role = $3, can_write = $4, last_used_at = $5 WHERE pkid = $6`, constants.TableAPIKeys)
+		query = fmt.Sprintf(`UPDATE %s SET name = $1, description = $2, role = $3, can_write = $4, scopes = $5, last_used_at = $6 WHERE pkid = $7`, constants.TableAPIKeys)
 	default:
-		query = fmt.Sprintf(`UPDATE %s SET name = ?, description = ?, role = ?, can_write = ?, last_used_at = ? WHERE pkid = ?`, constants.TableAPIKeys)
+		query = fmt.Sprintf(`UPDATE %s SET name = ?, description = ?, role = ?, can_write = ?, scopes = ?, last_used_at = ? WHERE pkid = ?`, constants.TableAPIKeys)
 	}
 
-	_, err := r.db.Exec(ctx, query,
-		apiKey.Name, apiKey.Description, apiKey.Role, apiKey.CanWrite, apiKey.LastUsedAt, apiKey.PKID,
+	_, err = r.db.Exec(ctx, query,
+		apiKey.Name, apiKey.Description, apiKey.Role, apiKey.CanWrite, scopes, apiKey.LastUsedAt, apiKey.PKID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update API key: %w", err)
@@ -191,6 +193,64 @@ func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, pkid int64) error
 	return nil
 }
 
+// LastUsedThrottle limits the last_used_at updates of each API key to one
+// per APIKeyLastUsedInterval, so that authenticating a request does not
+// write to the database every time.
+type LastUsedThrottle struct {
+	mu      sync.Mutex
+	touched map[int64]time.Time
+}
+
+// NewLastUsedThrottle creates a new last-used throttle.
+func NewLastUsedThrottle() *LastUsedThrottle {
+	return &LastUsedThrottle{touched: make(map[int64]time.Time)}
+}
+
+// Due reports whether a use of the API key at now should be recorded. A due
+// update is reserved, so concurrent requests with the same key skip it.
+func (t *LastUsedThrottle) Due(apiKey *APIKey, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := t.touched[apiKey.PKID]
+	if apiKey.LastUsedAt != nil && apiKey.LastUsedAt.After(last) {
+		last = *apiKey.LastUsedAt
+	}
+	if now.Sub(last) < APIKeyLastUsedInterval {
+		return false
+	}
+	t.touched[apiKey.PKID] = now
+	return true
+}
+
+// Revoke marks an API key as revoked; it is kept for auditing but no longer authenticates.
+func (r *APIKeyRepository) Revoke(ctx context.Context, apiKey *APIKey) error {
+	now := time.Now()
+	var query string
+	switch r.db.Dialect() {
+	case database.DialectPostgres:
+		query = fmt.Sprintf("UPDATE %s SET revoked_at = $1 WHERE pkid = $2", constants.TableAPIKeys)
+	default:
+		query = fmt.Sprintf("UPDATE %s SET revoked_at = ? WHERE pkid = ?", constants.TableAPIKeys)
+	}
+
+	if _, err := r.db.Exec(ctx, query, now, apiKey.PKID); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	apiKey.RevokedAt = &now
+	return nil
+}
+
+// Count returns the number of API keys, including revoked and expired ones.
+func (r *APIKeyRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", constants.TableAPIKeys)
+	if err := r.db.QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	return count, nil
+}
+
 // Delete deletes an API key from the database.
 func (r *APIKeyRepository) Delete(ctx context.Context, pkid int64) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE pkid = ?", constants.TableAPIKeys)
@@ -207,7 +267,7 @@ func (r *APIKeyRepository) Delete(ctx context.Context, pkid int64) error {
 
 // List retrieves all API keys.
 func (r *APIKeyRepository) List(ctx context.Context) ([]*APIKey, error) {
-	query := fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s ORDER BY created_at DESC", constants.TableAPIKeys)
+	query := fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s ORDER BY created_at DESC", constants.TableAPIKeys)
 
 	rows, err := r.db.Query(ctx, query)
 	if err != nil {
@@ -217,11 +277,7 @@ func (r *APIKeyRepository) List(ctx context.Context) ([]*APIKey, error) {
 
 	var keys []*APIKey
 	for rows.Next() {
-		apiKey := &APIKey{}
-		err := rows.Scan(
-			&apiKey.PKID, &apiKey.ID, &apiKey.Name, &apiKey.Description, &apiKey.KeyHash,
-			&apiKey.Role, &apiKey.CanWrite, &apiKey.CreatedAt, &apiKey.LastUsedAt,
-		)
+		apiKey, err := scanAPIKey(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan API key: %w", err)
 		}
@@ -247,7 +303,7 @@ func (r *APIKeyRepository) ListPaginated(ctx context.Context, opts APIKeyListOpt
 	var args []any
 	argIdx := 1
 
-	baseSelect := fmt.Sprintf("SELECT pkid, id, name, description, key_hash, role, can_write, created_at, last_used_at FROM %s", constants.TableAPIKeys)
+	baseSelect := fmt.Sprintf("SELECT "+apiKeyColumns+" FROM %s", constants.TableAPIKeys)
 
 	if opts.AfterID != "" {
 		if r.db.Dialect() == database.DialectPostgres {
@@ -278,11 +334,7 @@ func (r *APIKeyRepository) ListPaginated(ctx context.Context, opts APIKeyListOpt
 
 	var keys []*APIKey
 	for rows.Next() {
-		apiKey := &APIKey{}
-		err := rows.Scan(
-			&apiKey.PKID, &apiKey.ID, &apiKey.Name, &apiKey.Description, &apiKey.KeyHash,
-			&apiKey.Role, &apiKey.CanWrite, &apiKey.CreatedAt, &apiKey.LastUsedAt,
-		)
+		apiKey, err := scanAPIKey(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan API key: %w", err)
 		}
@@ -340,19 +392,47 @@ func (r *APIKeyRepository) UpdateKeyHash(ctx context.Context, pkid int64, newHas
 	return nil
 }
 
-// UpdateMetadata updates only name, description, and can_write fields.
+// UpdateMetadata updates only name, description, can_write and scopes fields.
 func (r *APIKeyRepository) UpdateMetadata(ctx context.Context, apiKey *APIKey) error {
+	scopes, err := encodeScopes(apiKey.Scopes)
+	if err != nil {
+		return err
+	}
+
 	var query string
 	switch r.db.Dialect() {
 	case database.DialectPostgres:
-		query = fmt.Sprintf(`UPDATE %s SET name = $1, description = $2, can_write = $3 WHERE pkid = $4`, constants.TableAPIKeys)
+		query = fmt.Sprintf(`UPDATE %s SET name = $1, description = $2, can_write = $3, scopes = $4 WHERE pkid = $5`, constants.TableAPIKeys)
 	default:
-		query = fmt.Sprintf(`UPDATE %s SET name = ?, description = ?, can_write = ? WHERE pkid = ?`, constants.TableAPIKeys)
+		query = fmt.Sprintf(`UPDATE %s SET name = ?, description = ?, can_write = ?, scopes = ? WHERE pkid = ?`, constants.TableAPIKeys)
 	}
 
-	_, err := r.db.Exec(ctx, query, apiKey.Name, apiKey.Description, apiKey.CanWrite, apiKey.PKID)
+	_, err = r.db.Exec(ctx, query, apiKey.Name, apiKey.Description, apiKey.CanWrite, scopes, apiKey.PKID)
 	if err != nil {
 		return fmt.Errorf("failed to update API key metadata: %w", err)
 	}
 	return nil
 }
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAPIKey scans one API key row selected with apiKeyColumns.
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	apiKey := &APIKey{}
+	var scopes *string
+	err := row.Scan(
+		&apiKey.PKID, &apiKey.ID, &apiKey.Name, &apiKey.Description, &apiKey.KeyHash,
+		&apiKey.Role, &apiKey.CanWrite, &scopes, &apiKey.Tenant, &apiKey.CreatedAt, &apiKey.LastUsedAt,
+		&apiKey.ExpiresAt, &apiKey.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.Scopes, err = decodeScopes(scopes); err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
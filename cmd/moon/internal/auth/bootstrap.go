@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
 )
 
@@ -13,8 +15,12 @@ type BootstrapConfig struct {
 	Username string
 	Email    string
 	Password string
+	APIKey   string // optional raw moon_live_ key registered as an all-access admin key
 }
 
+// BootstrapAPIKeyName is the name of the API key created from BootstrapConfig.APIKey.
+const BootstrapAPIKeyName = "bootstrap-admin"
+
 // Bootstrap initializes the auth tables and creates the bootstrap admin user if needed.
 func Bootstrap(ctx context.Context, db database.Driver, cfg *BootstrapConfig) error {
 	// Initialize auth tables
@@ -29,6 +35,13 @@ func Bootstrap(ctx context.Context, db database.Driver, cfg *BootstrapConfig) er
 		}
 	}
 
+	// Register the bootstrap API key if configured
+	if cfg != nil && cfg.APIKey != "" {
+		if err := createBootstrapAPIKey(ctx, db, cfg.APIKey); err != nil {
+			return fmt.Errorf("failed to create bootstrap API key: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -42,6 +55,58 @@ func initializeSchema(ctx context.Context, db database.Driver) error {
 		}
 	}
 
+	// API key tables created before scoping existed get the scopes column;
+	// existing keys keep NULL scopes and stay unrestricted
+	if err := addMissingColumn(ctx, db, constants.TableAPIKeys, "scopes", "TEXT"); err != nil {
+		return err
+	}
+
+	// Users and API keys created before tenancy existed belong to no tenant
+	for _, table := range []string{constants.TableUsers, constants.TableAPIKeys} {
+		if err := addMissingColumn(ctx, db, table, "tenant", "VARCHAR(20) NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+
+	// API keys created before expiry and revocation existed never expire
+	timestampType := "DATETIME"
+	if db.Dialect() == database.DialectPostgres {
+		timestampType = "TIMESTAMP"
+	}
+	for _, column := range []string{"expires_at", "revoked_at"} {
+		if err := addMissingColumn(ctx, db, constants.TableAPIKeys, column, timestampType); err != nil {
+			return err
+		}
+	}
+
+	// Refresh token tables created before rotation tracking get the family
+	// and revoked columns; existing tokens start a family on their next refresh
+	revokedType := "BOOLEAN NOT NULL DEFAULT false"
+	if db.Dialect() == database.DialectSQLite {
+		revokedType = "INTEGER NOT NULL DEFAULT 0"
+	}
+	if err := addMissingColumn(ctx, db, constants.TableRefreshTokens, "family_id", "VARCHAR(26)"); err != nil {
+		return err
+	}
+	return addMissingColumn(ctx, db, constants.TableRefreshTokens, "revoked", revokedType)
+}
+
+// addMissingColumn adds a column to an auth table created by an older release.
+func addMissingColumn(ctx context.Context, db database.Driver, table, column, definition string) error {
+	info, err := db.GetTableInfo(ctx, table)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	for _, col := range info.Columns {
+		if col.Name == column {
+			return nil
+		}
+	}
+	if _, err := db.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s column: %w", column, err)
+	}
+	log.Printf("Added missing %s column to table: %s", column, table)
+
 	return nil
 }
 
@@ -84,12 +149,92 @@ func createBootstrapAdmin(ctx context.Context, db database.Driver, cfg *Bootstra
 	return nil
 }
 
+// createBootstrapAPIKey registers the configured key as an admin key with
+// access to every collection, unless a key with the same hash already exists.
+func createBootstrapAPIKey(ctx context.Context, db database.Driver, rawKey string) error {
+	if err := validateBootstrapAPIKey(rawKey); err != nil {
+		return err
+	}
+
+	repo := NewAPIKeyRepository(db)
+	keyHash := HashAPIKey(rawKey)
+
+	existing, err := repo.GetByHash(ctx, keyHash)
+	if err != nil {
+		return fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	apiKey := &APIKey{
+		Name:        BootstrapAPIKeyName,
+		Description: "All-access admin key created at bootstrap",
+		KeyHash:     keyHash,
+		Role:        string(RoleAdmin),
+		CanWrite:    true,
+		Scopes:      AllAccessScopes(),
+	}
+	if err := repo.Create(ctx, apiKey); err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	log.Printf("Bootstrap API key '%s' created successfully", BootstrapAPIKeyName)
+	return nil
+}
+
+// MintBootstrapAPIKey creates an all-access admin key when no API key
+// exists yet and returns the raw key, which is not stored and must be shown
+// to the operator once. It returns an empty string when keys already exist.
+func MintBootstrapAPIKey(ctx context.Context, db database.Driver) (string, error) {
+	repo := NewAPIKeyRepository(db)
+
+	count, err := repo.Count(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count > 0 {
+		return "", nil
+	}
+
+	rawKey, keyHash, err := GenerateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	apiKey := &APIKey{
+		Name:        BootstrapAPIKeyName,
+		Description: "All-access admin key minted at first startup",
+		KeyHash:     keyHash,
+		Role:        string(RoleAdmin),
+		CanWrite:    true,
+		Scopes:      AllAccessScopes(),
+	}
+	if err := repo.Create(ctx, apiKey); err != nil {
+		return "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	log.Printf("Bootstrap API key '%s' minted", BootstrapAPIKeyName)
+	return rawKey, nil
+}
+
+// validateBootstrapAPIKey checks the format of a configured bootstrap API key.
+func validateBootstrapAPIKey(rawKey string) error {
+	if rawKey != "" && (!strings.HasPrefix(rawKey, APIKeyPrefix) || len(rawKey) < constants.MinAPIKeyLengthWithPrefix) {
+		return fmt.Errorf("bootstrap API key must start with %s and be at least %d characters", APIKeyPrefix, constants.MinAPIKeyLengthWithPrefix)
+	}
+	return nil
+}
+
 // ValidateBootstrapConfig validates the bootstrap configuration.
 func ValidateBootstrapConfig(cfg *BootstrapConfig) error {
 	if cfg == nil {
 		return nil // Empty config is valid (no bootstrap)
 	}
 
+	if err := validateBootstrapAPIKey(cfg.APIKey); err != nil {
+		return err
+	}
+
 	if cfg.Username == "" && cfg.Email == "" && cfg.Password == "" {
 		return nil // All empty is valid (no bootstrap)
 	}
@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"context"
 	"strings"
 	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 )
 
 func TestValidateBootstrapConfig(t *testing.T) {
@@ -103,3 +106,38 @@ func TestValidateBootstrapConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestMintBootstrapAPIKey(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	rawKey, err := MintBootstrapAPIKey(ctx, db)
+	if err != nil {
+		t.Fatalf("MintBootstrapAPIKey() error = %v", err)
+	}
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		t.Fatalf("MintBootstrapAPIKey() key = %q, want prefix %q", rawKey, APIKeyPrefix)
+	}
+
+	apiKey, err := NewAPIKeyRepository(db).GetByHash(ctx, HashAPIKey(rawKey))
+	if err != nil || apiKey == nil {
+		t.Fatalf("minted key not found by hash: %v", err)
+	}
+	if apiKey.Role != string(RoleAdmin) || !apiKey.CanWrite || apiKey.Name != BootstrapAPIKeyName {
+		t.Errorf("minted key = %+v, want an all-access admin key", apiKey)
+	}
+
+	// The raw key is not stored in any column
+	var count int
+	query := "SELECT COUNT(*) FROM " + constants.TableAPIKeys + " WHERE key_hash = ? OR name = ? OR description = ?"
+	if err := db.QueryRow(ctx, query, rawKey, rawKey, rawKey).Scan(&count); err != nil || count != 0 {
+		t.Errorf("raw key found in the database: count=%d err=%v", count, err)
+	}
+
+	// Keys exist now, so nothing is minted on the next startup
+	again, err := MintBootstrapAPIKey(ctx, db)
+	if err != nil || again != "" {
+		t.Errorf("second MintBootstrapAPIKey() = %q, %v, want no key", again, err)
+	}
+}
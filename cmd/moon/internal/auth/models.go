@@ -13,6 +13,7 @@ type User struct {
 	PasswordHash string     `json:"-"`
 	Role         string     `json:"role"`
 	CanWrite     bool       `json:"can_write"`
+	Tenant       string     `json:"tenant,omitempty"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
@@ -46,10 +47,14 @@ func IsValidRole(role string) bool {
 }
 
 // RefreshToken represents a refresh token for JWT authentication.
+// Tokens issued by rotating one another share a FamilyID; a rotated-out
+// token is kept as Revoked until it expires so that reuse can be detected.
 type RefreshToken struct {
 	PKID       int64     `json:"-"`
 	UserPKID   int64     `json:"-"`
 	TokenHash  string    `json:"-"`
+	FamilyID   string    `json:"-"`
+	Revoked    bool      `json:"-"`
 	ExpiresAt  time.Time `json:"expires_at"`
 	CreatedAt  time.Time `json:"created_at"`
 	LastUsedAt time.Time `json:"last_used_at"`
@@ -69,12 +74,52 @@ type APIKey struct {
 	KeyHash     string     `json:"-"`
 	Role        string     `json:"role"`
 	CanWrite    bool       `json:"can_write"`
+	Scopes      Scopes     `json:"scopes,omitempty"`
+	Tenant      string     `json:"tenant,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
 }
 
+// IsExpired checks if the API key has an expiry that has passed.
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && !time.Now().Before(*k.ExpiresAt)
+}
+
+// IsRevoked checks if the API key has been revoked.
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Scope grants actions on one collection, or on every collection with "*".
+type Scope struct {
+	Collection string   `json:"collection"`
+	Actions    []string `json:"actions"`
+}
+
+// Scopes restricts an API key to the listed collections and actions.
+// An empty list leaves the key unrestricted, as keys were before scoping.
+type Scopes []Scope
+
+// Scope actions.
+const (
+	// ScopeRead allows reading records and the collection schema.
+	ScopeRead = "read"
+	// ScopeWrite allows creating, updating and deleting records.
+	ScopeWrite = "write"
+	// ScopeSchema allows creating, updating, renaming and dropping the collection.
+	ScopeSchema = "schema"
+)
+
+// ScopeAllCollections matches every collection.
+const ScopeAllCollections = "*"
+
 // APIKeyPrefix is the prefix for generated API keys.
 const APIKeyPrefix = "moon_live_"
 
 // APIKeyLength is the length of the random portion of API keys (64 chars base62).
 const APIKeyLength = 64
+
+// APIKeyLastUsedInterval is the minimum time between two last_used_at updates of an API key.
+const APIKeyLastUsedInterval = time.Minute
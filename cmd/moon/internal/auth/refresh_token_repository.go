@@ -6,10 +6,11 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
-	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	moonulid "github.com/thalib/moon/cmd/moon/internal/ulid"
 )
 
 // RefreshTokenRepository provides database operations for refresh tokens.
@@ -28,28 +29,32 @@ func HashToken(token string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// Create creates a new refresh token in the database.
+// Create creates a new refresh token in the database. A token without a
+// FamilyID starts a new family.
 func (r *RefreshTokenRepository) Create(ctx context.Context, token *RefreshToken) error {
 	token.CreatedAt = time.Now()
 	token.LastUsedAt = time.Now()
+	if token.FamilyID == "" {
+		token.FamilyID = moonulid.Generate()
+	}
 
 	var query string
 	switch r.db.Dialect() {
 	case database.DialectPostgres:
-		query = fmt.Sprintf(`INSERT INTO %s (user_pkid, token_hash, expires_at, created_at, last_used_at)
-			VALUES ($1, $2, $3, $4, $5) RETURNING pkid`, constants.TableRefreshTokens)
+		query = fmt.Sprintf(`INSERT INTO %s (user_pkid, token_hash, family_id, revoked, expires_at, created_at, last_used_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING pkid`, constants.TableRefreshTokens)
 		err := r.db.QueryRow(ctx, query,
-			token.UserPKID, token.TokenHash, token.ExpiresAt, token.CreatedAt, token.LastUsedAt,
+			token.UserPKID, token.TokenHash, token.FamilyID, token.Revoked, token.ExpiresAt, token.CreatedAt, token.LastUsedAt,
 		).Scan(&token.PKID)
 		if err != nil {
 			return fmt.Errorf("failed to create refresh token: %w", err)
 		}
 		return nil
 	default:
-		query = fmt.Sprintf(`INSERT INTO %s (user_pkid, token_hash, expires_at, created_at, last_used_at)
-			VALUES (?, ?, ?, ?, ?)`, constants.TableRefreshTokens)
+		query = fmt.Sprintf(`INSERT INTO %s (user_pkid, token_hash, family_id, revoked, expires_at, created_at, last_used_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, constants.TableRefreshTokens)
 		result, err := r.db.Exec(ctx, query,
-			token.UserPKID, token.TokenHash, token.ExpiresAt, token.CreatedAt, token.LastUsedAt,
+			token.UserPKID, token.TokenHash, token.FamilyID, token.Revoked, token.ExpiresAt, token.CreatedAt, token.LastUsedAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to create refresh token: %w", err)
@@ -65,14 +70,15 @@ func (r *RefreshTokenRepository) Create(ctx context.Context, token *RefreshToken
 
 // GetByHash retrieves a refresh token by its hash.
 func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
-	query := fmt.Sprintf("SELECT pkid, user_pkid, token_hash, expires_at, created_at, last_used_at FROM %s WHERE token_hash = ?", constants.TableRefreshTokens)
+	query := fmt.Sprintf("SELECT pkid, user_pkid, token_hash, family_id, revoked, expires_at, created_at, last_used_at FROM %s WHERE token_hash = ?", constants.TableRefreshTokens)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, user_pkid, token_hash, expires_at, created_at, last_used_at FROM %s WHERE token_hash = $1", constants.TableRefreshTokens)
+		query = fmt.Sprintf("SELECT pkid, user_pkid, token_hash, family_id, revoked, expires_at, created_at, last_used_at FROM %s WHERE token_hash = $1", constants.TableRefreshTokens)
 	}
 
 	token := &RefreshToken{}
+	var familyID sql.NullString
 	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
-		&token.PKID, &token.UserPKID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt, &token.LastUsedAt,
+		&token.PKID, &token.UserPKID, &token.TokenHash, &familyID, &token.Revoked, &token.ExpiresAt, &token.CreatedAt, &token.LastUsedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -80,6 +86,7 @@ func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string
 	if err != nil {
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
+	token.FamilyID = familyID.String
 	return token, nil
 }
 
@@ -100,6 +107,35 @@ func (r *RefreshTokenRepository) UpdateLastUsed(ctx context.Context, pkid int64)
 	return nil
 }
 
+// Revoke marks a refresh token as revoked. The row is kept until it expires
+// so that presenting the token again can be detected as reuse.
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, pkid int64) error {
+	query := fmt.Sprintf("UPDATE %s SET revoked = ? WHERE pkid = ?", constants.TableRefreshTokens)
+	if r.db.Dialect() == database.DialectPostgres {
+		query = fmt.Sprintf("UPDATE %s SET revoked = $1 WHERE pkid = $2", constants.TableRefreshTokens)
+	}
+
+	_, err := r.db.Exec(ctx, query, true, pkid)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// RevokeFamily revokes every refresh token descended from the same login.
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := fmt.Sprintf("UPDATE %s SET revoked = ? WHERE family_id = ?", constants.TableRefreshTokens)
+	if r.db.Dialect() == database.DialectPostgres {
+		query = fmt.Sprintf("UPDATE %s SET revoked = $1 WHERE family_id = $2", constants.TableRefreshTokens)
+	}
+
+	_, err := r.db.Exec(ctx, query, true, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
 // Delete deletes a refresh token from the database.
 func (r *RefreshTokenRepository) Delete(ctx context.Context, pkid int64) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE pkid = ?", constants.TableRefreshTokens)
@@ -147,7 +183,7 @@ func (r *RefreshTokenRepository) DeleteAllByUserID(ctx context.Context, userPKID
 	return r.DeleteByUserID(ctx, userPKID)
 }
 
-// DeleteExpired deletes all expired refresh tokens.
+// DeleteExpired deletes all expired refresh tokens, revoked or not.
 func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
 	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at < ?", constants.TableRefreshTokens)
 	if r.db.Dialect() == database.DialectPostgres {
@@ -1,8 +1,10 @@
 package auth
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHashToken(t *testing.T) {
@@ -40,3 +42,50 @@ func TestHashToken_DifferentInputs(t *testing.T) {
 		t.Error("HashToken() produced same hash for different inputs")
 	}
 }
+
+func TestRefreshTokenRepository_RevokeFamily(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	user := &User{Username: "tokenuser", Email: "token@example.com", PasswordHash: "hash", Role: "user"}
+	if err := NewUserRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	repo := NewRefreshTokenRepository(db)
+	first := &RefreshToken{UserPKID: user.PKID, TokenHash: "first", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repo.Create(ctx, first); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if first.FamilyID == "" {
+		t.Fatal("expected Create() to start a token family")
+	}
+	second := &RefreshToken{UserPKID: user.PKID, TokenHash: "second", FamilyID: first.FamilyID, ExpiresAt: time.Now().Add(time.Hour)}
+	other := &RefreshToken{UserPKID: user.PKID, TokenHash: "other", ExpiresAt: time.Now().Add(time.Hour)}
+	for _, token := range []*RefreshToken{second, other} {
+		if err := repo.Create(ctx, token); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if err := repo.Revoke(ctx, first.PKID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if token, _ := repo.GetByHash(ctx, "second"); token.Revoked {
+		t.Error("Revoke() should only revoke the given token")
+	}
+
+	if err := repo.RevokeFamily(ctx, first.FamilyID); err != nil {
+		t.Fatalf("RevokeFamily() error = %v", err)
+	}
+	for hash, want := range map[string]bool{"first": true, "second": true, "other": false} {
+		token, err := repo.GetByHash(ctx, hash)
+		if err != nil || token == nil {
+			t.Fatalf("GetByHash(%q) = %v, %v", hash, token, err)
+		}
+		if token.Revoked != want {
+			t.Errorf("token %q revoked = %v, want %v", hash, token.Revoked, want)
+		}
+	}
+}
@@ -483,6 +483,71 @@ func TestAPIKeyRepository_GetByHash(t *testing.T) {
 	}
 }
 
+func TestAPIKeyRepository_ExpiryAndRevoke(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Hour)
+	_, keyHash, _ := GenerateAPIKey()
+	apiKey := &APIKey{Name: "Expiring Key", KeyHash: keyHash, Role: "user", ExpiresAt: &expires}
+	if err := repo.Create(ctx, apiKey); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	found, err := repo.GetByHash(ctx, keyHash)
+	if err != nil || found == nil {
+		t.Fatalf("GetByHash() = %v, %v", found, err)
+	}
+	if found.ExpiresAt == nil || !found.ExpiresAt.Equal(expires) {
+		t.Errorf("GetByHash() expires_at = %v, want %v", found.ExpiresAt, expires)
+	}
+	if found.IsExpired() || found.IsRevoked() {
+		t.Error("new key should be neither expired nor revoked")
+	}
+
+	if err := repo.Revoke(ctx, found); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	found, _ = repo.GetByHash(ctx, keyHash)
+	if found == nil || !found.IsRevoked() {
+		t.Errorf("Revoke() should keep the key and mark it revoked, got %+v", found)
+	}
+
+	past := time.Now().Add(-time.Second)
+	found.ExpiresAt = &past
+	if !found.IsExpired() {
+		t.Error("IsExpired() should be true for a passed expiry")
+	}
+}
+
+func TestLastUsedThrottle(t *testing.T) {
+	throttle := NewLastUsedThrottle()
+	now := time.Now()
+	apiKey := &APIKey{PKID: 1}
+
+	if !throttle.Due(apiKey, now) {
+		t.Error("first use should be recorded")
+	}
+	if throttle.Due(apiKey, now.Add(30*time.Second)) {
+		t.Error("a second use within the interval should not be recorded")
+	}
+	if !throttle.Due(&APIKey{PKID: 2}, now) {
+		t.Error("keys should be throttled independently")
+	}
+	if !throttle.Due(apiKey, now.Add(APIKeyLastUsedInterval)) {
+		t.Error("a use after the interval should be recorded")
+	}
+
+	// A recent stored last use counts, e.g. after a restart
+	recent := now.Add(-10 * time.Second)
+	if NewLastUsedThrottle().Due(&APIKey{PKID: 3, LastUsedAt: &recent}, now) {
+		t.Error("a key used within the interval should not be recorded again")
+	}
+}
+
 func TestAPIKeyRepository_List(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
@@ -30,6 +30,7 @@ func getSQLiteSchema() []string {
 			password_hash TEXT NOT NULL,
 			role TEXT NOT NULL DEFAULT 'user',
 			can_write INTEGER NOT NULL DEFAULT 1,
+			tenant VARCHAR(20) NOT NULL DEFAULT '',
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL,
 			last_login_at DATETIME
@@ -42,6 +43,8 @@ func getSQLiteSchema() []string {
 			pkid INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_pkid INTEGER NOT NULL,
 			token_hash TEXT NOT NULL UNIQUE,
+			family_id TEXT,
+			revoked INTEGER NOT NULL DEFAULT 0,
 			expires_at DATETIME NOT NULL,
 			created_at DATETIME NOT NULL,
 			last_used_at DATETIME NOT NULL,
@@ -59,8 +62,12 @@ func getSQLiteSchema() []string {
 			key_hash TEXT NOT NULL UNIQUE,
 			role TEXT NOT NULL DEFAULT 'user',
 			can_write INTEGER NOT NULL DEFAULT 1,
+			tenant VARCHAR(20) NOT NULL DEFAULT '',
+			scopes TEXT,
 			created_at DATETIME NOT NULL,
-			last_used_at DATETIME
+			last_used_at DATETIME,
+			expires_at DATETIME,
+			revoked_at DATETIME
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_moon_apikeys_id ON ` + constants.TableAPIKeys + `(id)`,
 		`CREATE INDEX IF NOT EXISTS idx_moon_apikeys_key_hash ON ` + constants.TableAPIKeys + `(key_hash)`,
@@ -88,6 +95,7 @@ func getPostgresSchema() []string {
 			password_hash VARCHAR(255) NOT NULL,
 			role VARCHAR(50) NOT NULL DEFAULT 'user',
 			can_write BOOLEAN NOT NULL DEFAULT true,
+			tenant VARCHAR(20) NOT NULL DEFAULT '',
 			created_at TIMESTAMP NOT NULL,
 			updated_at TIMESTAMP NOT NULL,
 			last_login_at TIMESTAMP
@@ -100,6 +108,8 @@ func getPostgresSchema() []string {
 			pkid BIGSERIAL PRIMARY KEY,
 			user_pkid BIGINT NOT NULL REFERENCES ` + constants.TableUsers + `(pkid) ON DELETE CASCADE,
 			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			family_id VARCHAR(26),
+			revoked BOOLEAN NOT NULL DEFAULT false,
 			expires_at TIMESTAMP NOT NULL,
 			created_at TIMESTAMP NOT NULL,
 			last_used_at TIMESTAMP NOT NULL
@@ -116,8 +126,12 @@ func getPostgresSchema() []string {
 			key_hash VARCHAR(64) NOT NULL UNIQUE,
 			role VARCHAR(50) NOT NULL DEFAULT 'user',
 			can_write BOOLEAN NOT NULL DEFAULT true,
+			tenant VARCHAR(20) NOT NULL DEFAULT '',
+			scopes TEXT,
 			created_at TIMESTAMP NOT NULL,
-			last_used_at TIMESTAMP
+			last_used_at TIMESTAMP,
+			expires_at TIMESTAMP,
+			revoked_at TIMESTAMP
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_moon_apikeys_id ON ` + constants.TableAPIKeys + `(id)`,
 		`CREATE INDEX IF NOT EXISTS idx_moon_apikeys_key_hash ON ` + constants.TableAPIKeys + `(key_hash)`,
@@ -144,6 +158,7 @@ func getMySQLSchema() []string {
 			password_hash VARCHAR(255) NOT NULL,
 			role VARCHAR(50) NOT NULL DEFAULT 'user',
 			can_write BOOLEAN NOT NULL DEFAULT true,
+			tenant VARCHAR(20) NOT NULL DEFAULT '',
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL,
 			last_login_at DATETIME,
@@ -156,6 +171,8 @@ func getMySQLSchema() []string {
 			pkid BIGINT AUTO_INCREMENT PRIMARY KEY,
 			user_pkid BIGINT NOT NULL,
 			token_hash VARCHAR(64) NOT NULL UNIQUE,
+			family_id VARCHAR(26),
+			revoked BOOLEAN NOT NULL DEFAULT false,
 			expires_at DATETIME NOT NULL,
 			created_at DATETIME NOT NULL,
 			last_used_at DATETIME NOT NULL,
@@ -173,8 +190,12 @@ func getMySQLSchema() []string {
 			key_hash VARCHAR(64) NOT NULL UNIQUE,
 			role VARCHAR(50) NOT NULL DEFAULT 'user',
 			can_write BOOLEAN NOT NULL DEFAULT true,
+			tenant VARCHAR(20) NOT NULL DEFAULT '',
+			scopes TEXT,
 			created_at DATETIME NOT NULL,
 			last_used_at DATETIME,
+			expires_at DATETIME,
+			revoked_at DATETIME,
 			INDEX idx_moon_apikeys_id (id),
 			INDEX idx_moon_apikeys_key_hash (key_hash)
 		)`,
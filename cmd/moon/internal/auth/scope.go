@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// ValidScopeActions returns the actions a scope can grant.
+func ValidScopeActions() []string {
+	return []string{ScopeRead, ScopeWrite, ScopeSchema}
+}
+
+// AllAccessScopes returns scopes granting every action on every collection.
+func AllAccessScopes() Scopes {
+	return Scopes{{Collection: ScopeAllCollections, Actions: ValidScopeActions()}}
+}
+
+// Allows reports whether the scopes grant the action on the collection.
+func (s Scopes) Allows(collection, action string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, scope := range s {
+		if scope.Collection != ScopeAllCollections && scope.Collection != collection {
+			continue
+		}
+		if slices.Contains(scope.Actions, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every scope names a collection and only known actions.
+func (s Scopes) Validate() error {
+	for i, scope := range s {
+		if scope.Collection == "" {
+			return fmt.Errorf("scopes[%d]: collection is required", i)
+		}
+		if len(scope.Actions) == 0 {
+			return fmt.Errorf("scopes[%d]: at least one action is required", i)
+		}
+		for _, action := range scope.Actions {
+			if !slices.Contains(ValidScopeActions(), action) {
+				return fmt.Errorf("scopes[%d]: invalid action '%s' (must be read, write or schema)", i, action)
+			}
+		}
+	}
+	return nil
+}
+
+// encodeScopes serializes scopes for the scopes column; unrestricted keys store NULL.
+func encodeScopes(s Scopes) (*string, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode scopes: %w", err)
+	}
+	encoded := string(data)
+	return &encoded, nil
+}
+
+// decodeScopes parses the scopes column.
+func decodeScopes(value *string) (Scopes, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	var s Scopes
+	if err := json.Unmarshal([]byte(*value), &s); err != nil {
+		return nil, fmt.Errorf("failed to decode scopes: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestScopes_Allows(t *testing.T) {
+	scopes := Scopes{
+		{Collection: "products", Actions: []string{ScopeRead, ScopeWrite}},
+		{Collection: "*", Actions: []string{ScopeRead}},
+	}
+
+	tests := []struct {
+		name       string
+		scopes     Scopes
+		collection string
+		action     string
+		want       bool
+	}{
+		{"unrestricted", nil, "orders", ScopeSchema, true},
+		{"named collection", scopes, "products", ScopeWrite, true},
+		{"wildcard read", scopes, "orders", ScopeRead, true},
+		{"write outside named collection", scopes, "orders", ScopeWrite, false},
+		{"schema not granted", scopes, "products", ScopeSchema, false},
+		{"all access", AllAccessScopes(), "orders", ScopeSchema, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scopes.Allows(tt.collection, tt.action); got != tt.want {
+				t.Errorf("Allows(%q, %q) = %v, want %v", tt.collection, tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopes_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		scopes      Scopes
+		errContains string
+	}{
+		{"valid", Scopes{{Collection: "products", Actions: []string{ScopeRead}}}, ""},
+		{"missing collection", Scopes{{Actions: []string{ScopeRead}}}, "collection is required"},
+		{"no actions", Scopes{{Collection: "products"}}, "at least one action"},
+		{"unknown action", Scopes{{Collection: "products", Actions: []string{"delete"}}}, "invalid action 'delete'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scopes.Validate()
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRepository_Scopes(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	ctx := context.Background()
+
+	apiKey := &APIKey{
+		Name:    "scoped",
+		KeyHash: "scoped-hash",
+		Role:    "user",
+		Scopes:  Scopes{{Collection: "products", Actions: []string{ScopeRead}}},
+	}
+	if err := repo.Create(ctx, apiKey); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	found, err := repo.GetByHash(ctx, "scoped-hash")
+	if err != nil || found == nil {
+		t.Fatalf("GetByHash() = %v, %v", found, err)
+	}
+	if len(found.Scopes) != 1 || found.Scopes[0].Collection != "products" || found.Scopes[0].Actions[0] != ScopeRead {
+		t.Errorf("expected scopes to round-trip, got %+v", found.Scopes)
+	}
+
+	// Clearing the scopes stores NULL and leaves the key unrestricted
+	found.Scopes = nil
+	if err := repo.UpdateMetadata(ctx, found); err != nil {
+		t.Fatalf("UpdateMetadata() error = %v", err)
+	}
+	found, _ = repo.GetByID(ctx, apiKey.ID)
+	if found.Scopes != nil {
+		t.Errorf("expected no scopes after clearing, got %+v", found.Scopes)
+	}
+}
+
+func TestBootstrap_APIKey(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	rawKey := APIKeyPrefix + strings.Repeat("k", APIKeyLength)
+	cfg := &BootstrapConfig{APIKey: rawKey}
+
+	// Bootstrapping twice keeps a single key
+	for i := 0; i < 2; i++ {
+		if err := Bootstrap(ctx, db, cfg); err != nil {
+			t.Fatalf("Bootstrap() error = %v", err)
+		}
+	}
+
+	keys, err := NewAPIKeyRepository(db).List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 bootstrap key, got %d", len(keys))
+	}
+	key := keys[0]
+	if key.Name != BootstrapAPIKeyName || key.Role != string(RoleAdmin) || key.KeyHash != HashAPIKey(rawKey) {
+		t.Errorf("unexpected bootstrap key: %+v", key)
+	}
+	for _, action := range ValidScopeActions() {
+		if !key.Scopes.Allows("anything", action) || len(key.Scopes) == 0 {
+			t.Errorf("expected bootstrap key to carry all-access scopes, got %+v", key.Scopes)
+		}
+	}
+
+	if err := Bootstrap(ctx, db, &BootstrapConfig{APIKey: "short"}); err == nil {
+		t.Error("expected malformed bootstrap API key to be rejected")
+	}
+}
+
+func TestBootstrap_AddsScopesColumn(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Recreate the API key table as it was before scopes existed
+	if _, err := db.Exec(ctx, "DROP TABLE moon_apikeys"); err != nil {
+		t.Fatalf("failed to drop table: %v", err)
+	}
+	if _, err := db.Exec(ctx, `CREATE TABLE moon_apikeys (
+		pkid INTEGER PRIMARY KEY AUTOINCREMENT, id TEXT NOT NULL UNIQUE, name TEXT NOT NULL, description TEXT,
+		key_hash TEXT NOT NULL UNIQUE, role TEXT NOT NULL DEFAULT 'user', can_write INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL, last_used_at DATETIME)`); err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+	if _, err := db.Exec(ctx, `INSERT INTO moon_apikeys (id, name, description, key_hash, role, can_write, created_at)
+		VALUES ('01ARZ3NDEKTSV4RRFFQ69G5FAV', 'legacy', '', 'legacy-hash', 'user', 1, CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("failed to insert legacy key: %v", err)
+	}
+
+	if err := Bootstrap(ctx, db, nil); err != nil {
+		t.Fatalf("Bootstrap() error = %v", err)
+	}
+
+	key, err := NewAPIKeyRepository(db).GetByHash(ctx, "legacy-hash")
+	if err != nil || key == nil {
+		t.Fatalf("GetByHash() = %v, %v", key, err)
+	}
+	if key.Scopes != nil {
+		t.Errorf("expected legacy key to stay unrestricted, got %+v", key.Scopes)
+	}
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+var tenantRegex = regexp.MustCompile(constants.TenantPattern)
+
+// ValidateTenant checks a tenant identifier. An empty tenant is valid and
+// leaves the principal outside every tenant.
+func ValidateTenant(tenant string) error {
+	if tenant == "" {
+		return nil
+	}
+	if len(tenant) > constants.MaxTenantLength {
+		return fmt.Errorf("tenant must not exceed %d characters", constants.MaxTenantLength)
+	}
+	if !tenantRegex.MatchString(tenant) {
+		return fmt.Errorf("tenant must start with a lowercase letter and contain only lowercase letters and numbers")
+	}
+	return nil
+}
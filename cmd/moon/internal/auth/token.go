@@ -24,6 +24,7 @@ type Claims struct {
 	Username string `json:"username"`
 	Role     string `json:"role"`
 	CanWrite bool   `json:"can_write"`
+	Tenant   string `json:"tenant,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -73,6 +74,7 @@ func (s *TokenService) generateAccessToken(user *User) (string, time.Time, error
 		Username: user.Username,
 		Role:     user.Role,
 		CanWrite: user.CanWrite,
+		Tenant:   user.Tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
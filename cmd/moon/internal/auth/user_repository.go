@@ -30,22 +30,22 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 	var query string
 	switch r.db.Dialect() {
 	case database.DialectPostgres:
-		query = fmt.Sprintf(`INSERT INTO %s (id, username, email, password_hash, role, can_write, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING pkid`, constants.TableUsers)
+		query = fmt.Sprintf(`INSERT INTO %s (id, username, email, password_hash, role, can_write, tenant, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING pkid`, constants.TableUsers)
 		err := r.db.QueryRow(ctx, query,
 			user.ID, user.Username, user.Email, user.PasswordHash,
-			user.Role, user.CanWrite, user.CreatedAt, user.UpdatedAt,
+			user.Role, user.CanWrite, user.Tenant, user.CreatedAt, user.UpdatedAt,
 		).Scan(&user.PKID)
 		if err != nil {
 			return fmt.Errorf("failed to create user: %w", err)
 		}
 		return nil
 	default:
-		query = fmt.Sprintf(`INSERT INTO %s (id, username, email, password_hash, role, can_write, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, constants.TableUsers)
+		query = fmt.Sprintf(`INSERT INTO %s (id, username, email, password_hash, role, can_write, tenant, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, constants.TableUsers)
 		result, err := r.db.Exec(ctx, query,
 			user.ID, user.Username, user.Email, user.PasswordHash,
-			user.Role, user.CanWrite, user.CreatedAt, user.UpdatedAt,
+			user.Role, user.CanWrite, user.Tenant, user.CreatedAt, user.UpdatedAt,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to create user: %w", err)
@@ -61,15 +61,15 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 
 // GetByPKID retrieves a user by internal PKID.
 func (r *UserRepository) GetByPKID(ctx context.Context, pkid int64) (*User, error) {
-	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE pkid = ?", constants.TableUsers)
+	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE pkid = ?", constants.TableUsers)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE pkid = $1", constants.TableUsers)
+		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE pkid = $1", constants.TableUsers)
 	}
 
 	user := &User{}
 	err := r.db.QueryRow(ctx, query, pkid).Scan(
 		&user.PKID, &user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Role, &user.CanWrite, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.Role, &user.CanWrite, &user.Tenant, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -82,15 +82,15 @@ func (r *UserRepository) GetByPKID(ctx context.Context, pkid int64) (*User, erro
 
 // GetByID retrieves a user by ID (ULID).
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error) {
-	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE id = ?", constants.TableUsers)
+	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE id = ?", constants.TableUsers)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE id = $1", constants.TableUsers)
+		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE id = $1", constants.TableUsers)
 	}
 
 	user := &User{}
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&user.PKID, &user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Role, &user.CanWrite, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.Role, &user.CanWrite, &user.Tenant, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -103,15 +103,15 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*User, error)
 
 // GetByUsername retrieves a user by username.
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
-	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE username = ?", constants.TableUsers)
+	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE username = ?", constants.TableUsers)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE username = $1", constants.TableUsers)
+		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE username = $1", constants.TableUsers)
 	}
 
 	user := &User{}
 	err := r.db.QueryRow(ctx, query, username).Scan(
 		&user.PKID, &user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Role, &user.CanWrite, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.Role, &user.CanWrite, &user.Tenant, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -124,15 +124,15 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*U
 
 // GetByEmail retrieves a user by email.
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
-	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE email = ?", constants.TableUsers)
+	query := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE email = ?", constants.TableUsers)
 	if r.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s WHERE email = $1", constants.TableUsers)
+		query = fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s WHERE email = $1", constants.TableUsers)
 	}
 
 	user := &User{}
 	err := r.db.QueryRow(ctx, query, email).Scan(
 		&user.PKID, &user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Role, &user.CanWrite, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+		&user.Role, &user.CanWrite, &user.Tenant, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -306,7 +306,7 @@ func (r *UserRepository) List(ctx context.Context, opts ListOptions) ([]*User, e
 	var args []any
 	argIdx := 1
 
-	baseSelect := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, created_at, updated_at, last_login_at FROM %s", constants.TableUsers)
+	baseSelect := fmt.Sprintf("SELECT pkid, id, username, email, password_hash, role, can_write, tenant, created_at, updated_at, last_login_at FROM %s", constants.TableUsers)
 
 	var conditions []string
 	if opts.AfterID != "" {
@@ -359,7 +359,7 @@ func (r *UserRepository) List(ctx context.Context, opts ListOptions) ([]*User, e
 		user := &User{}
 		if err := rows.Scan(
 			&user.PKID, &user.ID, &user.Username, &user.Email, &user.PasswordHash,
-			&user.Role, &user.CanWrite, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
+			&user.Role, &user.CanWrite, &user.Tenant, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -0,0 +1,140 @@
+// Package cache provides a bounded in-memory cache of serialized GET responses
+// for collection read endpoints. Entries are keyed by collection and request,
+// expire after a TTL and are evicted least recently used first. Each collection
+// has a generation counter that is part of every key, so invalidating a
+// collection is O(1): its old entries are simply never looked up again and
+// age out of the LRU list.
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is a cached response
+type Entry struct {
+	Status int
+	Header http.Header // headers set by the handler, e.g. Content-Type and ETag
+	Body   []byte
+}
+
+// Stats reports cache effectiveness
+type Stats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+// item is an entry in the LRU list
+type item struct {
+	key     string
+	entry   Entry
+	expires time.Time
+}
+
+// Cache is an LRU response cache safe for concurrent use
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu          sync.Mutex
+	items       map[string]*list.Element
+	lru         *list.List // front is most recently used
+	generations map[string]uint64
+	epoch       uint64 // bumped by InvalidateAll
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// New creates a cache holding at most maxEntries responses for ttl each
+func New(maxEntries int, ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:         ttl,
+		maxEntries:  maxEntries,
+		now:         time.Now,
+		items:       make(map[string]*list.Element),
+		lru:         list.New(),
+		generations: make(map[string]uint64),
+	}
+}
+
+// Key returns the cache key for a request on a collection at its current
+// generation. Callers take the key before running the query so a response
+// computed before an invalidation is stored under a key that is already stale.
+func (c *Cache) Key(collection, request string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("%d:%d:%s\x00%s", c.epoch, c.generations[collection], collection, request)
+}
+
+// Get returns the entry for key and counts a hit or a miss
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok && c.now().Before(el.Value.(*item).expires) {
+		c.lru.MoveToFront(el)
+		c.hits.Add(1)
+		return el.Value.(*item).entry, true
+	}
+	if ok {
+		c.remove(el)
+	}
+	c.misses.Add(1)
+	return Entry{}, false
+}
+
+// Set stores an entry under key, evicting the least recently used entries
+// beyond maxEntries
+func (c *Cache) Set(key string, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		el.Value.(*item).entry = entry
+		el.Value.(*item).expires = expires
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&item{key: key, entry: entry, expires: expires})
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Invalidate makes every cached response of a collection unreachable
+func (c *Cache) Invalidate(collection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[collection]++
+}
+
+// InvalidateAll makes every cached response unreachable, e.g. after a schema change
+func (c *Cache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch++
+}
+
+// Stats returns the hit and miss counters and the number of stored entries
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries}
+}
+
+// remove deletes an element from the list and the index; c.mu must be held
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*item).key)
+}
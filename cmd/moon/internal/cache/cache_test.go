@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func entry(body string) Entry {
+	return Entry{Status: 200, Body: []byte(body)}
+}
+
+func TestCache_GetSet(t *testing.T) {
+	c := New(10, time.Minute)
+
+	key := c.Key("products", "/products:list?limit=5")
+	if _, ok := c.Get(key); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	c.Set(key, entry("a"))
+	got, ok := c.Get(key)
+	if !ok || string(got.Body) != "a" {
+		t.Fatalf("expected hit with body a, got %v %q", ok, got.Body)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCache_TTLExpiry(t *testing.T) {
+	c := New(10, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	key := c.Key("products", "/products:count")
+	c.Set(key, entry("1"))
+
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get(key); !ok {
+		t.Fatal("expected hit before TTL")
+	}
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get(key); ok {
+		t.Fatal("expected miss after TTL")
+	}
+	if c.Stats().Entries != 0 {
+		t.Error("expected expired entry to be removed")
+	}
+}
+
+func TestCache_LRUEviction(t *testing.T) {
+	c := New(2, time.Minute)
+
+	a := c.Key("products", "a")
+	b := c.Key("products", "b")
+	d := c.Key("products", "d")
+	c.Set(a, entry("a"))
+	c.Set(b, entry("b"))
+	c.Get(a) // a is now most recently used
+	c.Set(d, entry("d"))
+
+	if _, ok := c.Get(b); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if _, ok := c.Get(a); !ok {
+		t.Error("expected recently used entry to remain")
+	}
+	if _, ok := c.Get(d); !ok {
+		t.Error("expected newest entry to remain")
+	}
+	if c.Stats().Entries != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Stats().Entries)
+	}
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	c := New(10, time.Minute)
+
+	products := c.Key("products", "q")
+	orders := c.Key("orders", "q")
+	c.Set(products, entry("p"))
+	c.Set(orders, entry("o"))
+
+	c.Invalidate("products")
+	if _, ok := c.Get(c.Key("products", "q")); ok {
+		t.Error("expected invalidated collection to miss")
+	}
+	if _, ok := c.Get(c.Key("orders", "q")); !ok {
+		t.Error("expected other collections to keep their entries")
+	}
+
+	c.InvalidateAll()
+	if _, ok := c.Get(c.Key("orders", "q")); ok {
+		t.Error("expected InvalidateAll to drop every collection")
+	}
+}
+
+// A response computed before an invalidation must never be served after it
+func TestCache_StaleWriteAfterInvalidate(t *testing.T) {
+	c := New(10, time.Minute)
+
+	key := c.Key("products", "q") // reader takes its key, then runs a slow query
+	c.Invalidate("products")      // a write lands meanwhile
+	c.Set(key, entry("stale"))    // the reader stores its now-stale result
+
+	if _, ok := c.Get(c.Key("products", "q")); ok {
+		t.Error("expected stale response to be unreachable")
+	}
+}
+
+func TestCache_ConcurrentReadersDuringInvalidation(t *testing.T) {
+	c := New(100, time.Minute)
+
+	// Each reader stores the version it read; no reader may see a version older
+	// than the last invalidation that happened before it took its key
+	var mu sync.Mutex
+	version := 0
+
+	stop := make(chan struct{})
+	invalidated := make(chan struct{})
+	go func() {
+		defer close(invalidated)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			mu.Lock()
+			version++
+			c.Invalidate("products")
+			mu.Unlock()
+			runtime.Gosched()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(reader int) {
+			defer wg.Done()
+			for n := 0; n < 2000; n++ {
+				mu.Lock()
+				minVersion := version
+				mu.Unlock()
+
+				key := c.Key("products", fmt.Sprintf("q%d", reader%3))
+				if got, ok := c.Get(key); ok {
+					var v int
+					fmt.Sscanf(string(got.Body), "%d", &v)
+					if v < minVersion {
+						t.Errorf("reader %d got version %d after invalidation to %d", reader, v, minVersion)
+						return
+					}
+					continue
+				}
+				mu.Lock()
+				current := version
+				mu.Unlock()
+				c.Set(key, entry(fmt.Sprint(current)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	<-invalidated
+
+	if stats := c.Stats(); stats.Entries > 100 {
+		t.Errorf("expected at most 100 entries, got %d", stats.Entries)
+	}
+}
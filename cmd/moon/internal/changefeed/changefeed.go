@@ -0,0 +1,274 @@
+// Package changefeed tracks the record changes of each collection for
+// long-polling clients. Every successful create, update and destroy bumps a
+// per-collection sequence by one per record; the most recent changes are kept
+// in memory, and the sequences are checkpointed to the moon_changes system
+// table so that they keep increasing across restarts.
+package changefeed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+)
+
+// logModule is the module of the change feed logs, configured by logging.levels.changefeed
+const logModule = "changefeed"
+
+// Change is one changed record of a collection
+type Change struct {
+	Seq    uint64 `json:"seq"`
+	ID     string `json:"id"`
+	Action string `json:"action"` // create, update or destroy
+}
+
+// Feed holds the change sequence and recent changes of every collection
+type Feed struct {
+	db   database.Driver
+	size int // changes kept per collection
+
+	mu      sync.Mutex
+	streams map[string]*stream
+
+	released chan struct{} // closed by Release to end every wait
+	release  sync.Once
+
+	stop chan struct{} // closed by Close to end the checkpoint loop
+	done chan struct{} // closed when the checkpoint loop has returned
+}
+
+// stream is the change state of one collection
+type stream struct {
+	seq     uint64   // sequence of the latest change
+	floor   uint64   // changes after floor are all in changes
+	changes []Change // oldest first, at most Feed.size
+	dirty   bool     // seq changed since the last checkpoint
+
+	watchers int           // requests waiting in Wait
+	notify   chan struct{} // closed on the next change; nil without watchers
+}
+
+// New creates a feed keeping constants.ChangeFeedSize changes per collection.
+// Sequences start at zero until Start loads the checkpoints from db.
+func New(db database.Driver) *Feed {
+	return &Feed{
+		db:       db,
+		size:     constants.ChangeFeedSize,
+		streams:  make(map[string]*stream),
+		released: make(chan struct{}),
+	}
+}
+
+// stream returns the state of a collection, creating it on first use. The
+// caller holds f.mu.
+func (f *Feed) stream(collection string) *stream {
+	s, ok := f.streams[collection]
+	if !ok {
+		s = &stream{}
+		f.streams[collection] = s
+	}
+	return s
+}
+
+// Record appends one change per id to the collection, in order, and wakes
+// the requests waiting for it. A nil feed ignores changes.
+func (f *Feed) Record(collection, action string, ids []string) {
+	if f == nil || len(ids) == 0 {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.stream(collection)
+	for _, id := range ids {
+		s.seq++
+		s.changes = append(s.changes, Change{Seq: s.seq, ID: id, Action: action})
+	}
+	if drop := len(s.changes) - f.size; drop > 0 {
+		s.floor = s.changes[drop-1].Seq
+		s.changes = append(s.changes[:0:0], s.changes[drop:]...)
+	}
+	s.dirty = true
+
+	if s.notify != nil {
+		close(s.notify)
+		s.notify = nil
+	}
+}
+
+// Seq returns the sequence of the latest change of a collection
+func (f *Feed) Seq(collection string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.streams[collection]; ok {
+		return s.seq
+	}
+	return 0
+}
+
+// since returns the changes after seq. reset reports that they are not all
+// known: seq is older than the kept changes, or newer than the latest change
+// because the feed restarted from an older checkpoint.
+func (s *stream) since(seq uint64) (changes []Change, reset bool) {
+	if seq < s.floor || seq > s.seq {
+		return nil, true
+	}
+	first := len(s.changes) - int(s.seq-seq)
+	if first == len(s.changes) {
+		return nil, false
+	}
+	return append([]Change(nil), s.changes[first:]...), false
+}
+
+// Wait returns the changes of a collection after seq, waiting for one when
+// there is none until ctx is done; the request context carries the client
+// disconnect and the long-poll timeout. It also returns the sequence of the
+// latest change, and reset when the changes after seq are not all known, in
+// which case the client should reload the collection and continue from the
+// returned sequence.
+func (f *Feed) Wait(ctx context.Context, collection string, seq uint64) (changes []Change, latest uint64, reset bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.stream(collection)
+	for {
+		changes, reset = s.since(seq)
+		if len(changes) > 0 || reset {
+			return changes, s.seq, reset
+		}
+
+		if s.notify == nil {
+			s.notify = make(chan struct{})
+		}
+		notify := s.notify
+		s.watchers++
+		f.mu.Unlock()
+
+		done := false
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			done = true
+		case <-f.released:
+			done = true
+		}
+
+		f.mu.Lock()
+		s.watchers--
+		if s.watchers == 0 {
+			// Nobody is left to wake; the next watcher makes a new channel
+			s.notify = nil
+		}
+		if done {
+			return nil, s.seq, false
+		}
+	}
+}
+
+// Release ends every current and future wait with no changes, so that
+// long-polls do not hold up a server shutdown
+func (f *Feed) Release() {
+	f.release.Do(func() { close(f.released) })
+}
+
+// watchers returns the number of requests waiting on a collection
+func (f *Feed) watchers(collection string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.streams[collection]; ok {
+		return s.watchers
+	}
+	return 0
+}
+
+// Start creates the moon_changes table, resumes every collection from its
+// checkpointed sequence and checkpoints changed sequences every interval
+// until Close. Changes from before the restart are not known, so cursors
+// older than the checkpoint are reset.
+func (f *Feed) Start(ctx context.Context, interval time.Duration) error {
+	if err := f.init(ctx); err != nil {
+		return err
+	}
+	checkpoints, err := f.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	for collection, seq := range checkpoints {
+		s := f.stream(collection)
+		if seq > s.seq {
+			s.seq, s.floor = seq, seq
+		}
+	}
+	f.mu.Unlock()
+
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+	go f.run(interval)
+	return nil
+}
+
+// run checkpoints the changed sequences every interval until stop is closed
+func (f *Feed) run(interval time.Duration) {
+	defer close(f.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			if err := f.Checkpoint(context.Background()); err != nil {
+				logging.Module(logModule).Errorf("Failed to checkpoint change sequences: %v", err)
+			}
+		}
+	}
+}
+
+// Checkpoint writes the sequences changed since the last checkpoint
+func (f *Feed) Checkpoint(ctx context.Context) error {
+	f.mu.Lock()
+	pending := make(map[string]uint64)
+	for collection, s := range f.streams {
+		if s.dirty {
+			pending[collection] = s.seq
+			s.dirty = false
+		}
+	}
+	f.mu.Unlock()
+
+	var firstErr error
+	for collection, seq := range pending {
+		if err := f.save(ctx, collection, seq); err != nil {
+			// Retry with the next checkpoint
+			f.mu.Lock()
+			f.streams[collection].dirty = true
+			f.mu.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Close stops the checkpoint loop and writes a final checkpoint, so that a
+// clean restart resumes every sequence where it stopped. It does nothing when
+// the feed was not started.
+func (f *Feed) Close(ctx context.Context) error {
+	if f.stop == nil {
+		return nil
+	}
+	close(f.stop)
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return f.Checkpoint(ctx)
+}
@@ -0,0 +1,195 @@
+package changefeed
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// setupDriver returns a fresh in-memory database
+func setupDriver(t *testing.T) database.Driver {
+	t.Helper()
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	return driver
+}
+
+func TestFeed_RecordAndWait(t *testing.T) {
+	f := New(nil)
+	f.Record("orders", "create", []string{"a", "b"})
+	f.Record("orders", "update", []string{"a"})
+	f.Record("products", "create", []string{"p"})
+
+	changes, seq, reset := f.Wait(context.Background(), "orders", 1)
+	if reset || seq != 3 || len(changes) != 2 {
+		t.Fatalf("expected two changes up to seq 3, got %v seq=%d reset=%v", changes, seq, reset)
+	}
+	if changes[0] != (Change{Seq: 2, ID: "b", Action: "create"}) || changes[1] != (Change{Seq: 3, ID: "a", Action: "update"}) {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	if f.Seq("products") != 1 || f.Seq("missing") != 0 {
+		t.Errorf("expected sequences to be per collection, got %d and %d", f.Seq("products"), f.Seq("missing"))
+	}
+
+	// Up to date: the wait ends with the context and no changes
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	changes, seq, reset = f.Wait(ctx, "orders", 3)
+	if len(changes) != 0 || seq != 3 || reset {
+		t.Errorf("expected an empty result at seq 3, got %v seq=%d reset=%v", changes, seq, reset)
+	}
+}
+
+func TestFeed_Reset(t *testing.T) {
+	f := New(nil)
+	f.size = 3
+	f.Record("orders", "create", []string{"a", "b", "c", "d", "e"})
+
+	if _, seq, reset := f.Wait(context.Background(), "orders", 1); !reset || seq != 5 {
+		t.Errorf("expected a cursor older than the kept changes to reset, got seq=%d reset=%v", seq, reset)
+	}
+	if changes, _, reset := f.Wait(context.Background(), "orders", 2); reset || len(changes) != 3 {
+		t.Errorf("expected the three kept changes, got %v reset=%v", changes, reset)
+	}
+	if _, seq, reset := f.Wait(context.Background(), "orders", 9); !reset || seq != 5 {
+		t.Errorf("expected a cursor beyond the sequence to reset, got seq=%d reset=%v", seq, reset)
+	}
+}
+
+func TestFeed_WaitWakesOnRecord(t *testing.T) {
+	f := New(nil)
+	result := make(chan []Change, 1)
+	go func() {
+		changes, _, _ := f.Wait(context.Background(), "orders", 0)
+		result <- changes
+	}()
+
+	waitFor(t, func() bool { return f.watchers("orders") == 1 })
+	f.Record("orders", "create", []string{"a"})
+
+	select {
+	case changes := <-result:
+		if len(changes) != 1 || changes[0].ID != "a" {
+			t.Errorf("expected the new record, got %v", changes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watcher was not woken by the change")
+	}
+	if n := f.watchers("orders"); n != 0 {
+		t.Errorf("expected no watchers left, got %d", n)
+	}
+}
+
+func TestFeed_CancelledWatchersDoNotLeak(t *testing.T) {
+	f := New(nil)
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Wait(ctx, "orders", 0)
+		}()
+	}
+	waitFor(t, func() bool { return f.watchers("orders") == 100 })
+
+	cancel()
+	wg.Wait()
+	if n := f.watchers("orders"); n != 0 {
+		t.Errorf("expected no watchers left, got %d", n)
+	}
+	waitFor(t, func() bool { return runtime.NumGoroutine() <= before })
+
+	f.mu.Lock()
+	notify := f.streams["orders"].notify
+	f.mu.Unlock()
+	if notify != nil {
+		t.Error("expected the broadcast channel to be dropped with the last watcher")
+	}
+}
+
+func TestFeed_Release(t *testing.T) {
+	f := New(nil)
+	done := make(chan struct{})
+	go func() {
+		f.Wait(context.Background(), "orders", 0)
+		close(done)
+	}()
+	waitFor(t, func() bool { return f.watchers("orders") == 1 })
+
+	f.Release()
+	f.Release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Release did not end the wait")
+	}
+	if changes, _, _ := f.Wait(context.Background(), "orders", 0); len(changes) != 0 {
+		t.Errorf("expected waits after Release to return at once, got %v", changes)
+	}
+}
+
+func TestFeed_CheckpointResumes(t *testing.T) {
+	driver := setupDriver(t)
+	ctx := context.Background()
+
+	f := New(driver)
+	if err := f.Start(ctx, time.Hour); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	f.Record("orders", "create", []string{"a", "b"})
+	if err := f.Checkpoint(ctx); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	f.Record("orders", "destroy", []string{"a"})
+	f.Record("products", "create", []string{"p"})
+	if err := f.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	restarted := New(driver)
+	if err := restarted.Start(ctx, time.Hour); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer restarted.Close(ctx)
+	if restarted.Seq("orders") != 3 || restarted.Seq("products") != 1 {
+		t.Errorf("expected sequences 3 and 1 after restart, got %d and %d", restarted.Seq("orders"), restarted.Seq("products"))
+	}
+	if _, _, reset := restarted.Wait(ctx, "orders", 2); !reset {
+		t.Error("expected changes from before the restart to reset")
+	}
+
+	restarted.Record("orders", "create", []string{"c"})
+	if changes, seq, reset := restarted.Wait(ctx, "orders", 3); reset || seq != 4 || len(changes) != 1 {
+		t.Errorf("expected the sequence to continue at 4, got %v seq=%d reset=%v", changes, seq, reset)
+	}
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
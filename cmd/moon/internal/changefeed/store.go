@@ -0,0 +1,76 @@
+package changefeed
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// init creates the moon_changes table if it does not exist
+func (f *Feed) init(ctx context.Context) error {
+	if _, err := f.db.Exec(ctx, createTableSQL(f.db.Dialect())); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", constants.TableChanges, err)
+	}
+	return nil
+}
+
+// load returns the checkpointed sequence of every collection
+func (f *Feed) load(ctx context.Context) (map[string]uint64, error) {
+	rows, err := f.db.Query(ctx, "SELECT collection, seq FROM "+constants.TableChanges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableChanges, err)
+	}
+	defer rows.Close()
+
+	checkpoints := make(map[string]uint64)
+	for rows.Next() {
+		var collection string
+		var seq int64
+		if err := rows.Scan(&collection, &seq); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", constants.TableChanges, err)
+		}
+		checkpoints[collection] = uint64(seq)
+	}
+	return checkpoints, rows.Err()
+}
+
+// save writes the sequence of a collection, inserting its row on the first
+// checkpoint
+func (f *Feed) save(ctx context.Context, collection string, seq uint64) error {
+	update := "UPDATE " + constants.TableChanges + " SET seq = ? WHERE collection = ?"
+	insert := "INSERT INTO " + constants.TableChanges + " (collection, seq) VALUES (?, ?)"
+	if f.db.Dialect() == database.DialectPostgres {
+		update = "UPDATE " + constants.TableChanges + " SET seq = $1 WHERE collection = $2"
+		insert = "INSERT INTO " + constants.TableChanges + " (collection, seq) VALUES ($1, $2)"
+	}
+
+	result, err := f.db.Exec(ctx, update, int64(seq), collection)
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint %s: %w", collection, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	if _, err := f.db.Exec(ctx, insert, collection, int64(seq)); err != nil {
+		return fmt.Errorf("failed to checkpoint %s: %w", collection, err)
+	}
+	return nil
+}
+
+// createTableSQL returns the moon_changes DDL for the given dialect
+func createTableSQL(dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectPostgres, database.DialectMySQL:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableChanges + ` (
+			collection VARCHAR(255) PRIMARY KEY,
+			seq BIGINT NOT NULL
+		)`
+	default:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableChanges + ` (
+			collection TEXT PRIMARY KEY,
+			seq INTEGER NOT NULL
+		)`
+	}
+}
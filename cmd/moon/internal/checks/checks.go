@@ -0,0 +1,362 @@
+// Package checks holds the startup self-test run by moon -check. Each check
+// verifies one part of the configuration or environment without serving
+// traffic; the health endpoint reuses the database check.
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/consistency"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/preflight"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/schemastore"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	// StatusPass means the check found nothing wrong
+	StatusPass Status = "pass"
+	// StatusWarn means the check found a problem that does not stop startup
+	StatusWarn Status = "warn"
+	// StatusFail means startup would fail, or the server would misbehave
+	StatusFail Status = "fail"
+	// StatusSkip means the check could not run because an earlier check failed
+	StatusSkip Status = "skip"
+)
+
+// Check is one step of the self-test. Run returns a short description of
+// what it verified, or an error: Warn and Skip errors report a warning or a
+// skipped check, any other error a failure.
+type Check interface {
+	Name() string
+	Run(ctx context.Context) (string, error)
+}
+
+// outcome is an error reporting a status other than fail
+type outcome struct {
+	status  Status
+	message string
+}
+
+func (o *outcome) Error() string { return o.message }
+
+// Warn returns the error of a check that found a problem startup tolerates
+func Warn(format string, args ...any) error {
+	return &outcome{status: StatusWarn, message: fmt.Sprintf(format, args...)}
+}
+
+// Skip returns the error of a check whose prerequisite failed
+func Skip(format string, args ...any) error {
+	return &outcome{status: StatusSkip, message: fmt.Sprintf(format, args...)}
+}
+
+// Result is the outcome of one check in a report
+type Result struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of a self-test. OK is false when any check failed;
+// warnings and skipped checks leave it true.
+type Report struct {
+	OK     bool     `json:"ok"`
+	Checks []Result `json:"checks"`
+}
+
+// Run runs the checks in order, each with constants.SelfTestTimeout
+func Run(ctx context.Context, checks []Check) *Report {
+	report := &Report{OK: true, Checks: make([]Result, 0, len(checks))}
+	for _, check := range checks {
+		start := time.Now()
+		checkCtx, cancel := context.WithTimeout(ctx, constants.SelfTestTimeout)
+		message, err := check.Run(checkCtx)
+		cancel()
+
+		result := Result{Name: check.Name(), Status: StatusPass, Message: message, DurationMs: time.Since(start).Milliseconds()}
+		var o *outcome
+		if errors.As(err, &o) {
+			result.Status, result.Message = o.status, o.message
+		} else if err != nil {
+			result.Status, result.Message = StatusFail, err.Error()
+			report.OK = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+// ExitCode returns the process exit code of the report: 0 when OK, else 1
+func (r *Report) ExitCode() int {
+	if r.OK {
+		return 0
+	}
+	return 1
+}
+
+// WriteText writes the report for a terminal, one line per check
+func (r *Report) WriteText(w io.Writer) {
+	symbols := map[Status]string{StatusPass: "✓", StatusWarn: "⚠", StatusFail: "✗", StatusSkip: "-"}
+	for _, result := range r.Checks {
+		fmt.Fprintf(w, "%s %-12s %s\n", symbols[result.Status], result.Name, result.Message)
+	}
+	if r.OK {
+		fmt.Fprintln(w, "Self-test passed")
+	} else {
+		fmt.Fprintln(w, "Self-test failed")
+	}
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// SelfTest runs the checks of moon -check against cfg and returns the report.
+// It connects to the database with dbConfig and closes the connection before
+// returning. Nothing is served and no authentication data is written.
+func SelfTest(ctx context.Context, cfg *config.AppConfig, dbConfig database.Config) *Report {
+	db := &Database{Config: dbConfig}
+	defer db.Close()
+	return Run(ctx, []Check{
+		&Preflight{Files: PreflightFiles(cfg)},
+		&Auth{JWT: cfg.JWT, APIKey: cfg.APIKey},
+		&Port{Addrs: ListenAddrs(cfg)},
+		db,
+		&Consistency{Database: db, Recovery: cfg.Recovery, Discovery: cfg.Discovery.Enabled},
+	})
+}
+
+// PreflightFiles returns the directories startup requires: the log directory
+// and, for SQLite, the directory of the database file
+func PreflightFiles(cfg *config.AppConfig) []preflight.FileCheck {
+	files := []preflight.FileCheck{{Path: cfg.Logging.Path, IsDir: true, Required: true, FailFatal: true}}
+
+	// Database path is already normalized to absolute in config.validate()
+	if cfg.Database.Connection == config.Defaults.Database.Connection {
+		files = append(files, preflight.FileCheck{Path: filepath.Dir(cfg.Database.Database), IsDir: true, Required: true, FailFatal: true})
+	}
+	return files
+}
+
+// ListenAddrs returns the addresses the server listens on: the public port
+// and, when configured, the admin port
+func ListenAddrs(cfg *config.AppConfig) []string {
+	addrs := []string{net.JoinHostPort(cfg.Server.Host, fmt.Sprint(cfg.Server.Port))}
+	if cfg.Server.AdminPort != 0 {
+		addrs = append(addrs, net.JoinHostPort(cfg.Server.AdminHost, fmt.Sprint(cfg.Server.AdminPort)))
+	}
+	return addrs
+}
+
+// Preflight verifies, and creates when missing, the directories startup requires
+type Preflight struct {
+	Files []preflight.FileCheck
+}
+
+// Name implements Check
+func (c *Preflight) Name() string { return "preflight" }
+
+// Run implements Check
+func (c *Preflight) Run(ctx context.Context) (string, error) {
+	results, err := preflight.ValidateAndCreate(c.Files)
+	if err != nil {
+		return "", err
+	}
+	var verified, problems []string
+	for _, result := range results {
+		switch {
+		case result.Error != nil:
+			problems = append(problems, result.Error.Error())
+		case result.Created:
+			verified = append(verified, "created "+result.Path)
+		default:
+			verified = append(verified, "verified "+result.Path)
+		}
+	}
+	if len(problems) > 0 {
+		return "", Warn("%s", strings.Join(problems, "; "))
+	}
+	return strings.Join(verified, ", "), nil
+}
+
+// headerTokenRegex matches a valid HTTP header name (RFC 9110 token)
+var headerTokenRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// reservedHeaders cannot carry API keys without breaking other parts of a request
+var reservedHeaders = map[string]bool{
+	"authorization": true,
+	"content-type":  true,
+	"cookie":        true,
+	"host":          true,
+	"origin":        true,
+}
+
+// Auth verifies the JWT secret and the API key header
+type Auth struct {
+	JWT    config.JWTConfig
+	APIKey config.APIKeyConfig
+}
+
+// Name implements Check
+func (c *Auth) Name() string { return "auth" }
+
+// Run implements Check
+func (c *Auth) Run(ctx context.Context) (string, error) {
+	if len(c.JWT.Secret) < constants.MinJWTSecretLength {
+		return "", fmt.Errorf("jwt.secret is %d characters long, at least %d required", len(c.JWT.Secret), constants.MinJWTSecretLength)
+	}
+	header := c.APIKey.Header
+	if header == "" {
+		header = constants.HeaderAPIKey
+	}
+	if !headerTokenRegex.MatchString(header) {
+		return "", fmt.Errorf("apikey.header %q is not a valid HTTP header name", header)
+	}
+	if reservedHeaders[strings.ToLower(header)] {
+		return "", fmt.Errorf("apikey.header %q is reserved and cannot carry API keys", header)
+	}
+	return fmt.Sprintf("jwt.secret is %d characters long, API keys are read from %s", len(c.JWT.Secret), header), nil
+}
+
+// Port verifies that the server can listen on its addresses by binding each
+// briefly. It fails while another process, such as a running Moon, holds one.
+type Port struct {
+	Addrs []string
+}
+
+// Name implements Check
+func (c *Port) Name() string { return "port" }
+
+// Run implements Check
+func (c *Port) Run(ctx context.Context) (string, error) {
+	for _, addr := range c.Addrs {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return "", fmt.Errorf("cannot listen on %s: %w", addr, err)
+		}
+		listener.Close()
+	}
+	return "can listen on " + strings.Join(c.Addrs, ", "), nil
+}
+
+// Database verifies that the database answers. Without a Driver it connects
+// with Config, keeps the connection for later checks and closes it on Close.
+type Database struct {
+	Config database.Config
+	Driver database.Driver
+
+	opened bool
+}
+
+// Name implements Check
+func (c *Database) Name() string { return "database" }
+
+// Run implements Check
+func (c *Database) Run(ctx context.Context) (string, error) {
+	if c.Driver == nil {
+		driver, err := database.NewDriver(c.Config)
+		if err != nil {
+			return "", fmt.Errorf("failed to create database driver: %w", err)
+		}
+		if err := driver.Connect(ctx); err != nil {
+			driver.Close()
+			return "", fmt.Errorf("failed to connect to database: %w", err)
+		}
+		c.Driver, c.opened = driver, true
+	}
+	if err := c.Driver.Ping(ctx); err != nil {
+		return "", fmt.Errorf("database ping failed: %w", err)
+	}
+	return fmt.Sprintf("connected to %s", c.Driver.Dialect()), nil
+}
+
+// Close closes the connection Run opened
+func (c *Database) Close() error {
+	if !c.opened {
+		return nil
+	}
+	c.opened = false
+	return c.Driver.Close()
+}
+
+// Consistency runs the consistency check against the persisted schemas in
+// report-only mode: nothing is repaired. Inconsistencies fail when startup
+// would refuse them, with recovery.auto_repair off, and warn otherwise.
+type Consistency struct {
+	Database  *Database
+	Recovery  config.RecoveryConfig
+	Discovery bool // orphaned tables are registered by discovery rather than reported
+}
+
+// Name implements Check
+func (c *Consistency) Name() string { return "consistency" }
+
+// Run implements Check
+func (c *Consistency) Run(ctx context.Context) (string, error) {
+	driver := c.Database.Driver
+	if driver == nil {
+		return "", Skip("database unavailable")
+	}
+	exists, err := driver.TableExists(ctx, constants.TableSchemas)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", constants.TableSchemas, err)
+	}
+	if !exists {
+		return fmt.Sprintf("no %s table yet, the first startup creates it", constants.TableSchemas), nil
+	}
+
+	collections, err := schemastore.New(driver).LoadAll(ctx)
+	if err != nil {
+		return "", err
+	}
+	reg := registry.NewSchemaRegistry()
+	for _, collection := range collections {
+		if err := reg.Set(collection); err != nil {
+			return "", fmt.Errorf("failed to register collection '%s': %w", collection.Name, err)
+		}
+	}
+
+	recovery := c.Recovery
+	recovery.AutoRepair, recovery.DropOrphans = false, false
+	if recovery.CheckTimeout <= 0 {
+		recovery.CheckTimeout = config.Defaults.Recovery.CheckTimeout
+	}
+	result, err := consistency.NewChecker(driver, reg, &recovery).Check(ctx)
+	if err != nil {
+		return "", fmt.Errorf("consistency check error: %w", err)
+	}
+
+	var issues []string
+	for _, issue := range result.Issues {
+		if c.Discovery && issue.Type == consistency.IssueOrphanedTable {
+			continue
+		}
+		issues = append(issues, fmt.Sprintf("%s %s", issue.Type, issue.Name))
+	}
+	if len(issues) == 0 {
+		return fmt.Sprintf("%d collection(s) consistent", len(collections)), nil
+	}
+	summary := fmt.Sprintf("%d issue(s): %s", len(issues), strings.Join(issues, ", "))
+	if !c.Recovery.AutoRepair {
+		return "", fmt.Errorf("%s (auto_repair disabled, startup would fail)", summary)
+	}
+	return "", Warn("%s (repaired at startup)", summary)
+}
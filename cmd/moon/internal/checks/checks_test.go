@@ -0,0 +1,209 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// loadTestConfig writes a config file for a SQLite database at dbPath and loads it
+func loadTestConfig(t *testing.T, dbPath string) (*config.AppConfig, database.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "moon.conf")
+	content := fmt.Sprintf(`server:
+  host: 127.0.0.1
+  port: %d
+database:
+  connection: sqlite
+  database: %s
+logging:
+  path: %s
+jwt:
+  secret: 0123456789abcdef0123456789abcdef
+`, freePort(t), dbPath, filepath.Join(dir, "log"))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	return cfg, database.Config{ConnectionString: "sqlite://" + cfg.Database.Database, MaxOpenConns: 1, MaxIdleConns: 1}
+}
+
+// decodeReport runs the self-test and returns its JSON report decoded
+func decodeReport(t *testing.T, cfg *config.AppConfig, dbConfig database.Config) (Report, map[string]Result) {
+	t.Helper()
+	var buf bytes.Buffer
+	report := SelfTest(context.Background(), cfg, dbConfig)
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON report: %v\n%s", err, buf.String())
+	}
+	if decoded.OK != report.OK || (report.ExitCode() == 0) != report.OK {
+		t.Errorf("Expected ok and the exit code to agree, got ok=%v exit=%d", decoded.OK, report.ExitCode())
+	}
+	byName := map[string]Result{}
+	for _, result := range decoded.Checks {
+		byName[result.Name] = result
+	}
+	return decoded, byName
+}
+
+func TestSelfTest_BadDatabasePath(t *testing.T) {
+	// The database directory cannot be created below a regular file
+	blocker := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cfg, dbConfig := loadTestConfig(t, filepath.Join(blocker, "data", "moon.db"))
+
+	report, results := decodeReport(t, cfg, dbConfig)
+	if report.OK || SelfTest(context.Background(), cfg, dbConfig).ExitCode() != 1 {
+		t.Fatalf("Expected the self-test to fail with exit code 1, got %+v", report)
+	}
+	var names []string
+	for _, result := range report.Checks {
+		names = append(names, result.Name)
+	}
+	if got := strings.Join(names, ","); got != "preflight,auth,port,database,consistency" {
+		t.Errorf("Expected every check in order, got %s", got)
+	}
+
+	want := map[string]Status{
+		"preflight":   StatusFail,
+		"auth":        StatusPass,
+		"port":        StatusPass,
+		"database":    StatusFail,
+		"consistency": StatusSkip,
+	}
+	for name, status := range want {
+		if results[name].Status != status {
+			t.Errorf("%s: expected %s, got %s (%s)", name, status, results[name].Status, results[name].Message)
+		}
+	}
+	if !strings.Contains(results["preflight"].Message, blocker) {
+		t.Errorf("Expected the preflight failure to name the path, got %q", results["preflight"].Message)
+	}
+	if results["database"].Message == "" {
+		t.Error("Expected the database failure to carry the error")
+	}
+}
+
+func TestSelfTest_Pass(t *testing.T) {
+	cfg, dbConfig := loadTestConfig(t, filepath.Join(t.TempDir(), "data", "moon.db"))
+
+	report, results := decodeReport(t, cfg, dbConfig)
+	if !report.OK {
+		t.Fatalf("Expected the self-test to pass, got %+v", report)
+	}
+	for name, result := range results {
+		if result.Status != StatusPass {
+			t.Errorf("%s: expected pass, got %s (%s)", name, result.Status, result.Message)
+		}
+	}
+	if !strings.Contains(results["consistency"].Message, "first startup") {
+		t.Errorf("Expected a fresh database to be reported as such, got %q", results["consistency"].Message)
+	}
+
+	var text bytes.Buffer
+	SelfTest(context.Background(), cfg, dbConfig).WriteText(&text)
+	if !strings.Contains(text.String(), "✓ database") || !strings.HasSuffix(text.String(), "Self-test passed\n") {
+		t.Errorf("Unexpected text report:\n%s", text.String())
+	}
+}
+
+func TestSelfTest_PortInUse(t *testing.T) {
+	cfg, dbConfig := loadTestConfig(t, filepath.Join(t.TempDir(), "moon.db"))
+	listener, err := net.Listen("tcp", ListenAddrs(cfg)[0])
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	report, results := decodeReport(t, cfg, dbConfig)
+	if report.OK || results["port"].Status != StatusFail {
+		t.Errorf("Expected the port check to fail while the port is taken, got %+v", results["port"])
+	}
+}
+
+func TestAuth(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		ok     bool
+	}{
+		{"defaults", secret, "", true},
+		{"custom header", secret, "X-Moon-Key", true},
+		{"short secret", "change-me", "", false},
+		{"header with space", secret, "X API Key", false},
+		{"header with colon", secret, "X-API-Key:", false},
+		{"authorization header", secret, "authorization", false},
+	}
+	for _, tt := range tests {
+		check := &Auth{JWT: config.JWTConfig{Secret: tt.secret}, APIKey: config.APIKeyConfig{Enabled: true, Header: tt.header}}
+		_, err := check.Run(context.Background())
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: expected ok=%v, got %v", tt.name, tt.ok, err)
+		}
+	}
+}
+
+// staticCheck reports a fixed outcome
+type staticCheck struct {
+	name string
+	err  error
+}
+
+func (c staticCheck) Name() string                            { return c.name }
+func (c staticCheck) Run(ctx context.Context) (string, error) { return "done", c.err }
+
+func TestRun_Statuses(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		staticCheck{"pass", nil},
+		staticCheck{"warn", Warn("disk %d%% full", 91)},
+		staticCheck{"skip", Skip("no database")},
+	})
+	if !report.OK || report.ExitCode() != 0 {
+		t.Errorf("Expected warnings and skipped checks to pass, got %+v", report)
+	}
+	for i, want := range []Status{StatusPass, StatusWarn, StatusSkip} {
+		if report.Checks[i].Status != want {
+			t.Errorf("%s: expected %s, got %s", report.Checks[i].Name, want, report.Checks[i].Status)
+		}
+	}
+	if report.Checks[1].Message != "disk 91% full" {
+		t.Errorf("Expected the warning message, got %q", report.Checks[1].Message)
+	}
+
+	report = Run(context.Background(), []Check{staticCheck{"fail", fmt.Errorf("broken")}})
+	if report.OK || report.ExitCode() != 1 || report.Checks[0].Message != "broken" {
+		t.Errorf("Expected a failed report, got %+v", report)
+	}
+}
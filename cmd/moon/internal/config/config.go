@@ -1,15 +1,19 @@
 // Package config provides configuration management for the Moon application.
-// It uses YAML-only configuration with centralized defaults and no environment
-// variable overrides, following the principles defined in SPEC.md.
+// It uses YAML configuration with centralized defaults, overridden by MOON_*
+// environment variables and -set flags, following the principles defined in SPEC.md.
 package config
 
 import (
 	"fmt"
 	"log"
+	"net/netip"
+	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/viper"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 )
 
 const (
@@ -30,9 +34,16 @@ func Version() string {
 // centralized in one place to avoid hardcoded literals
 var Defaults = struct {
 	Server struct {
-		Port   int
-		Host   string
-		Prefix string
+		Port                int
+		Host                string
+		Prefix              string
+		PublicURL           string
+		ShutdownTimeout     int
+		LegacyErrors        bool
+		MaxBodyBytes        int
+		CompressionMinBytes int
+		AdminPort           int
+		AdminHost           string
 	}
 	Database struct {
 		Connection         string
@@ -42,10 +53,17 @@ var Defaults = struct {
 		Host               string
 		QueryTimeout       int
 		SlowQueryThreshold int
+		Pool               struct {
+			MaxOpen         int
+			MaxIdle         int
+			ConnMaxLifetime int
+			ConnMaxIdleTime int
+		}
 	}
 	Logging struct {
 		Path            string
 		RedactSensitive bool
+		Level           string
 	}
 	JWT struct {
 		Expiry        int
@@ -69,6 +87,10 @@ var Defaults = struct {
 		DropOrphans  bool
 		CheckTimeout int
 	}
+	Discovery struct {
+		Enabled bool
+		Adopt   bool
+	}
 	CORS struct {
 		Enabled          bool
 		AllowedOrigins   []string
@@ -91,17 +113,83 @@ var Defaults = struct {
 	Batch struct {
 		MaxSize         int
 		MaxPayloadBytes int
+		ImportChunkSize int
+		MaxImportBytes  int
+		MaxTransactOps  int
+	}
+	Webhooks struct {
+		QueueSize   int
+		MaxAttempts int
+		Timeout     int
+	}
+	Cache struct {
+		Enabled    bool
+		TTL        int
+		MaxEntries int
+	}
+	Audit struct {
+		Enabled       bool
+		RetentionDays int
+		QueueSize     int
+	}
+	Backup struct {
+		Directory string
+	}
+	Tenancy struct {
+		Enabled bool
+	}
+	API struct {
+		IncludeTotalDefault    bool
+		MaxBulkDelete          int
+		MaxPageOffset          int
+		FieldCase              string
+		StrictQueryParams      bool
+		LenientBooleans        bool
+		MaxRecordResponseBytes int
+	}
+	Stats struct {
+		CacheTTL   int
+		SampleSize int
+	}
+	Doc struct {
+		SampleCollection string
+	}
+	Idempotency struct {
+		TTL int
+	}
+	Jobs struct {
+		RetentionDays int
+	}
+	TTL struct {
+		SweepInterval int
+	}
+	Bootstrap struct {
+		SampleCollection bool
 	}
 	ConfigPath string
 }{
 	Server: struct {
-		Port   int
-		Host   string
-		Prefix string
+		Port                int
+		Host                string
+		Prefix              string
+		PublicURL           string
+		ShutdownTimeout     int
+		LegacyErrors        bool
+		MaxBodyBytes        int
+		CompressionMinBytes int
+		AdminPort           int
+		AdminHost           string
 	}{
-		Port:   6006,
-		Host:   "0.0.0.0",
-		Prefix: "",
+		Port:                6006,
+		Host:                "0.0.0.0",
+		Prefix:              "",
+		PublicURL:           "",
+		ShutdownTimeout:     30, // 30 seconds
+		LegacyErrors:        false,
+		MaxBodyBytes:        4194304, // 4 MB
+		CompressionMinBytes: 1024,    // 1 KB
+		AdminPort:           0,       // administrative routes on the public port
+		AdminHost:           "127.0.0.1",
 	},
 	Database: struct {
 		Connection         string
@@ -111,6 +199,12 @@ var Defaults = struct {
 		Host               string
 		QueryTimeout       int
 		SlowQueryThreshold int
+		Pool               struct {
+			MaxOpen         int
+			MaxIdle         int
+			ConnMaxLifetime int
+			ConnMaxIdleTime int
+		}
 	}{
 		Connection:         "sqlite",
 		Database:           "/opt/moon/sqlite.db",
@@ -119,13 +213,26 @@ var Defaults = struct {
 		Host:               "0.0.0.0",
 		QueryTimeout:       30,  // 30 seconds
 		SlowQueryThreshold: 500, // 500 milliseconds
+		Pool: struct {
+			MaxOpen         int
+			MaxIdle         int
+			ConnMaxLifetime int
+			ConnMaxIdleTime int
+		}{
+			MaxOpen:         25,
+			MaxIdle:         10,
+			ConnMaxLifetime: 1800, // 30 minutes
+			ConnMaxIdleTime: 300,  // 5 minutes
+		},
 	},
 	Logging: struct {
 		Path            string
 		RedactSensitive bool
+		Level           string
 	}{
 		Path:            "/var/log/moon",
 		RedactSensitive: true,
+		Level:           "info",
 	},
 	JWT: struct {
 		Expiry        int
@@ -172,6 +279,13 @@ var Defaults = struct {
 		DropOrphans:  false,
 		CheckTimeout: 5,
 	},
+	Discovery: struct {
+		Enabled bool
+		Adopt   bool
+	}{
+		Enabled: false, // Tables Moon did not create are left to the consistency check
+		Adopt:   false, // Tables without an id column are not altered
+	},
 	CORS: struct {
 		Enabled          bool
 		AllowedOrigins   []string
@@ -184,7 +298,7 @@ var Defaults = struct {
 		Enabled:          false, // Disabled by default for security
 		AllowedOrigins:   []string{},
 		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key", "If-Match", "Idempotency-Key"},
 		AllowCredentials: true,
 		MaxAge:           3600, // 1 hour
 		Endpoints: []CORSEndpointConfig{
@@ -197,6 +311,15 @@ var Defaults = struct {
 				AllowCredentials: false,
 				BypassAuth:       true,
 			},
+			{
+				Path:             "/health/live",
+				PatternType:      "exact",
+				AllowedOrigins:   []string{"*"},
+				AllowedMethods:   []string{"GET", "OPTIONS"},
+				AllowedHeaders:   []string{"Content-Type"},
+				AllowCredentials: false,
+				BypassAuth:       true,
+			},
 			{
 				Path:             "/doc/", // Prefix pattern matches /doc, /doc/, /doc/llms.md, /doc/llms.txt, /doc/llms.json, etc.
 				PatternType:      "prefix",
@@ -235,6 +358,15 @@ var Defaults = struct {
 				AllowCredentials: true,
 				BypassAuth:       false,
 			},
+			{
+				Path:             "*:export",
+				PatternType:      "suffix",
+				AllowedOrigins:   []string{},
+				AllowedMethods:   []string{"GET", "OPTIONS"},
+				AllowedHeaders:   []string{"Content-Type", "Authorization"},
+				AllowCredentials: true,
+				BypassAuth:       false,
+			},
 			{
 				Path:             "*:create",
 				PatternType:      "suffix",
@@ -262,6 +394,42 @@ var Defaults = struct {
 				AllowCredentials: true,
 				BypassAuth:       false,
 			},
+			{
+				Path:             "*:upsert",
+				PatternType:      "suffix",
+				AllowedOrigins:   []string{},
+				AllowedMethods:   []string{"POST", "OPTIONS"},
+				AllowedHeaders:   []string{"Content-Type", "Authorization"},
+				AllowCredentials: true,
+				BypassAuth:       false,
+			},
+			{
+				Path:             "*:import",
+				PatternType:      "suffix",
+				AllowedOrigins:   []string{},
+				AllowedMethods:   []string{"POST", "OPTIONS"},
+				AllowedHeaders:   []string{"Content-Type", "Authorization"},
+				AllowCredentials: true,
+				BypassAuth:       false,
+			},
+			{
+				Path:             "*:restore",
+				PatternType:      "suffix",
+				AllowedOrigins:   []string{},
+				AllowedMethods:   []string{"POST", "OPTIONS"},
+				AllowedHeaders:   []string{"Content-Type", "Authorization"},
+				AllowCredentials: true,
+				BypassAuth:       false,
+			},
+			{
+				Path:             "*:query",
+				PatternType:      "suffix",
+				AllowedOrigins:   []string{},
+				AllowedMethods:   []string{"POST", "OPTIONS"},
+				AllowedHeaders:   []string{"Content-Type", "Authorization"},
+				AllowCredentials: true,
+				BypassAuth:       false,
+			},
 		},
 	},
 	Pagination: struct {
@@ -285,9 +453,101 @@ var Defaults = struct {
 	Batch: struct {
 		MaxSize         int
 		MaxPayloadBytes int
+		ImportChunkSize int
+		MaxImportBytes  int
+		MaxTransactOps  int
 	}{
 		MaxSize:         50,
-		MaxPayloadBytes: 2097152, // 2 MB
+		MaxPayloadBytes: 2097152,   // 2 MB
+		ImportChunkSize: 500,       // Records per import transaction
+		MaxImportBytes:  104857600, // 100 MB
+		MaxTransactOps:  50,        // Operations per batch:transact request
+	},
+	Webhooks: struct {
+		QueueSize   int
+		MaxAttempts int
+		Timeout     int
+	}{
+		QueueSize:   1000, // Pending deliveries before new ones are dropped
+		MaxAttempts: 5,    // Attempts per delivery including the first
+		Timeout:     10,   // 10 seconds per attempt
+	},
+	Cache: struct {
+		Enabled    bool
+		TTL        int
+		MaxEntries int
+	}{
+		Enabled:    false, // Read responses are not cached unless enabled
+		TTL:        60,    // 60 seconds
+		MaxEntries: 1000,  // Cached responses before LRU eviction
+	},
+	Audit: struct {
+		Enabled       bool
+		RetentionDays int
+		QueueSize     int
+	}{
+		Enabled:       false, // Mutating requests are not recorded unless enabled
+		RetentionDays: 90,    // Entries older than 90 days are deleted at startup
+		QueueSize:     1000,  // Pending entries before new ones are dropped
+	},
+	Backup: struct {
+		Directory string
+	}{
+		Directory: "/opt/moon/backups", // admin:backup snapshots of a SQLite database
+	},
+	Tenancy: struct {
+		Enabled bool
+	}{
+		Enabled: false, // Collections are shared by every principal unless enabled
+	},
+	API: struct {
+		IncludeTotalDefault    bool
+		MaxBulkDelete          int
+		MaxPageOffset          int
+		FieldCase              string
+		StrictQueryParams      bool
+		LenientBooleans        bool
+		MaxRecordResponseBytes int
+	}{
+		IncludeTotalDefault:    true,           // :list and :query count the matching records
+		MaxBulkDelete:          1000,           // Records one :destroy by filter may delete without force
+		MaxPageOffset:          10000,          // Records a ?page= may skip before cursor pagination is required
+		FieldCase:              FieldCaseSnake, // Record fields are named as their columns
+		StrictQueryParams:      false,          // Unknown query parameters are ignored
+		LenientBooleans:        false,          // Boolean fields of written records must be JSON booleans
+		MaxRecordResponseBytes: 1048576,        // 1 MB - larger :get records have their json values stubbed
+	},
+	Stats: struct {
+		CacheTTL   int
+		SampleSize int
+	}{
+		CacheTTL:   60,    // 60 seconds
+		SampleSize: 10000, // Rows examined for null counts
+	},
+	Doc: struct {
+		SampleCollection string
+	}{
+		SampleCollection: "", // Examples use the first collection by name
+	},
+	Idempotency: struct {
+		TTL int
+	}{
+		TTL: 86400, // 24 hours
+	},
+	Jobs: struct {
+		RetentionDays int
+	}{
+		RetentionDays: 7, // Journals older than 7 days are deleted at startup
+	},
+	TTL: struct {
+		SweepInterval int
+	}{
+		SweepInterval: 300, // 5 minutes between two sweeps of expired records
+	},
+	Bootstrap: struct {
+		SampleCollection bool
+	}{
+		SampleCollection: false, // No demo_tasks collection on first startup
 	},
 	ConfigPath: "/etc/moon.conf",
 }
@@ -295,42 +555,96 @@ var Defaults = struct {
 // AppConfig holds the application configuration.
 // It is designed to be immutable after initialization.
 type AppConfig struct {
-	Server     ServerConfig     `mapstructure:"server"`
-	Database   DatabaseConfig   `mapstructure:"database"`
-	Logging    LoggingConfig    `mapstructure:"logging"`
-	JWT        JWTConfig        `mapstructure:"jwt"`
-	APIKey     APIKeyConfig     `mapstructure:"apikey"`
-	Auth       AuthConfig       `mapstructure:"auth"`
-	Recovery   RecoveryConfig   `mapstructure:"recovery"`
-	CORS       CORSConfig       `mapstructure:"cors"`
-	Pagination PaginationConfig `mapstructure:"pagination"`
-	Limits     LimitsConfig     `mapstructure:"limits"`
-	Batch      BatchConfig      `mapstructure:"batch"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Logging     LoggingConfig     `mapstructure:"logging"`
+	JWT         JWTConfig         `mapstructure:"jwt"`
+	APIKey      APIKeyConfig      `mapstructure:"apikey"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Recovery    RecoveryConfig    `mapstructure:"recovery"`
+	Discovery   DiscoveryConfig   `mapstructure:"discovery"`
+	CORS        CORSConfig        `mapstructure:"cors"`
+	Pagination  PaginationConfig  `mapstructure:"pagination"`
+	Limits      LimitsConfig      `mapstructure:"limits"`
+	Batch       BatchConfig       `mapstructure:"batch"`
+	Webhooks    WebhooksConfig    `mapstructure:"webhooks"`
+	Cache       CacheConfig       `mapstructure:"cache"`
+	Audit       AuditConfig       `mapstructure:"audit"`
+	Backup      BackupConfig      `mapstructure:"backup"`
+	Tenancy     TenancyConfig     `mapstructure:"tenancy"`
+	API         APIConfig         `mapstructure:"api"`
+	Stats       StatsConfig       `mapstructure:"stats"`
+	Doc         DocConfig         `mapstructure:"doc"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	Jobs        JobsConfig        `mapstructure:"jobs"`
+	TTL         TTLConfig         `mapstructure:"ttl"`
+	Bootstrap   BootstrapConfig   `mapstructure:"bootstrap"`
+
+	Overrides []Override `mapstructure:"-"` // settings taken from the environment and -set, in the order applied
 }
 
 // ServerConfig holds server-related configuration.
 type ServerConfig struct {
-	Port   int    `mapstructure:"port"`
-	Host   string `mapstructure:"host"`
-	Prefix string `mapstructure:"prefix"`
+	Port                int      `mapstructure:"port"`
+	Host                string   `mapstructure:"host"`
+	Prefix              string   `mapstructure:"prefix"`
+	PublicURL           string   `mapstructure:"public_url"`            // external base URL used in generated documentation and record links
+	ShutdownTimeout     int      `mapstructure:"shutdown_timeout"`      // seconds to drain in-flight requests on shutdown
+	LegacyErrors        bool     `mapstructure:"legacy_errors"`         // emit the pre-error-code response shape
+	MaxBodyBytes        int      `mapstructure:"max_body_bytes"`        // request body limit outside the data endpoints, which use the batch limits
+	CompressionMinBytes int      `mapstructure:"compression_min_bytes"` // smallest response body gzipped for clients that accept it; negative disables compression
+	TrustedProxies      []string `mapstructure:"trusted_proxies"`       // CIDRs or addresses of proxies whose X-Forwarded-For is believed
+	AdminPort           int      `mapstructure:"admin_port"`            // port of a separate listener for the administrative routes; 0 serves them on port
+	AdminHost           string   `mapstructure:"admin_host"`            // address the admin listener binds to
+}
+
+// TrustedProxyPrefixes parses server.trusted_proxies. A bare address is
+// trusted alone, as a /32 or /128 prefix.
+func (c ServerConfig) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for i, entry := range c.TrustedProxies {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("server.trusted_proxies[%d]: %q is not a CIDR or IP address", i, entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
 }
 
 // DatabaseConfig holds database connection configuration.
 type DatabaseConfig struct {
-	Connection         string `mapstructure:"connection"`           // database type: sqlite, postgres, mysql
-	Database           string `mapstructure:"database"`             // database file/name
-	User               string `mapstructure:"user"`                 // database user
-	Password           string `mapstructure:"password"`             // database password
-	Host               string `mapstructure:"host"`                 // database host
-	QueryTimeout       int    `mapstructure:"query_timeout"`        // query timeout in seconds
-	SlowQueryThreshold int    `mapstructure:"slow_query_threshold"` // slow query threshold in milliseconds
+	Connection         string     `mapstructure:"connection"`           // database type: sqlite, postgres, mysql
+	Database           string     `mapstructure:"database"`             // database file/name
+	User               string     `mapstructure:"user"`                 // database user
+	Password           string     `mapstructure:"password"`             // database password
+	Host               string     `mapstructure:"host"`                 // database host
+	QueryTimeout       int        `mapstructure:"query_timeout"`        // query timeout in seconds
+	SlowQueryThreshold int        `mapstructure:"slow_query_threshold"` // slow query threshold in milliseconds
+	Pool               PoolConfig `mapstructure:"pool"`
+}
+
+// PoolConfig holds the connection pool configuration of the database.
+type PoolConfig struct {
+	MaxOpen         int `mapstructure:"max_open"`           // max open connections; 0 is unlimited
+	MaxIdle         int `mapstructure:"max_idle"`           // max idle connections kept open; 0 keeps none
+	ConnMaxLifetime int `mapstructure:"conn_max_lifetime"`  // seconds a connection is reused for; 0 is forever
+	ConnMaxIdleTime int `mapstructure:"conn_max_idle_time"` // seconds a connection may sit idle; 0 is forever
 }
 
 // LoggingConfig holds logging configuration.
 type LoggingConfig struct {
-	Path                      string   `mapstructure:"path"`                        // log directory path
-	RedactSensitive           bool     `mapstructure:"redact_sensitive"`            // redact sensitive data in logs
-	AdditionalSensitiveFields []string `mapstructure:"additional_sensitive_fields"` // additional fields to redact
+	Path                      string            `mapstructure:"path"`                        // log directory path
+	RedactSensitive           bool              `mapstructure:"redact_sensitive"`            // redact sensitive data in logs
+	AdditionalSensitiveFields []string          `mapstructure:"additional_sensitive_fields"` // additional fields to redact
+	Level                     string            `mapstructure:"level"`                       // base log level: debug, info, warn or error
+	Levels                    map[string]string `mapstructure:"levels"`                      // log level per module, e.g. handlers: debug
 }
 
 // JWTConfig holds JWT authentication configuration.
@@ -358,6 +672,7 @@ type BootstrapAdminConfig struct {
 	Username string `mapstructure:"username"`
 	Email    string `mapstructure:"email"`
 	Password string `mapstructure:"password"`
+	APIKey   string `mapstructure:"api_key"` // optional all-access admin API key (moon_live_...)
 }
 
 // RateLimitConfig holds rate limiting configuration.
@@ -375,6 +690,12 @@ type RecoveryConfig struct {
 	CheckTimeout int  `mapstructure:"check_timeout"` // consistency check timeout in seconds
 }
 
+// DiscoveryConfig holds the startup discovery of tables Moon did not create.
+type DiscoveryConfig struct {
+	Enabled bool `mapstructure:"enabled"` // register pre-existing tables at startup
+	Adopt   bool `mapstructure:"adopt"`   // add and fill an id column on tables without one
+}
+
 // CORSConfig holds CORS (Cross-Origin Resource Sharing) configuration.
 type CORSConfig struct {
 	Enabled          bool                 `mapstructure:"enabled"`           // enable CORS support (default: false for security)
@@ -415,20 +736,131 @@ type LimitsConfig struct {
 type BatchConfig struct {
 	MaxSize         int `mapstructure:"max_size"`          // maximum number of items per batch request
 	MaxPayloadBytes int `mapstructure:"max_payload_bytes"` // maximum payload size in bytes
+	ImportChunkSize int `mapstructure:"import_chunk_size"` // records inserted per transaction by :import
+	MaxImportBytes  int `mapstructure:"max_import_bytes"`  // maximum :import upload size in bytes
+	MaxTransactOps  int `mapstructure:"max_transact_ops"`  // maximum number of operations per batch:transact request
+}
+
+// WebhooksConfig holds webhook delivery configuration for data mutations.
+type WebhooksConfig struct {
+	QueueSize   int                     `mapstructure:"queue_size"`   // pending deliveries kept in memory
+	MaxAttempts int                     `mapstructure:"max_attempts"` // delivery attempts including the first
+	Timeout     int                     `mapstructure:"timeout"`      // per-attempt timeout in seconds
+	Endpoints   []WebhookEndpointConfig `mapstructure:"endpoints"`    // receivers of mutation events
+}
+
+// WebhookEndpointConfig represents a single webhook receiver
+type WebhookEndpointConfig struct {
+	URL    string   `mapstructure:"url"`    // http or https URL receiving POSTed events
+	Secret string   `mapstructure:"secret"` // HMAC-SHA256 key for the X-Moon-Signature header
+	Events []string `mapstructure:"events"` // filters such as products:create, *:destroy, orders:* (empty: all events)
+}
+
+// CacheConfig holds the query result cache configuration for GET data endpoints.
+type CacheConfig struct {
+	Enabled    bool `mapstructure:"enabled"`     // cache :list, :get, :schema and aggregation responses
+	TTL        int  `mapstructure:"ttl"`         // seconds a cached response is served
+	MaxEntries int  `mapstructure:"max_entries"` // cached responses kept before LRU eviction
+}
+
+// AuditConfig holds the configuration of the audit log of mutating requests.
+type AuditConfig struct {
+	Enabled       bool `mapstructure:"enabled"`        // record mutating requests in the moon_audit table
+	RetentionDays int  `mapstructure:"retention_days"` // entries older than this are deleted at startup; 0 keeps them
+	QueueSize     int  `mapstructure:"queue_size"`     // entries waiting to be written before new ones are dropped
+}
+
+// BackupConfig holds the configuration of admin:backup and admin:restore.
+type BackupConfig struct {
+	Directory string `mapstructure:"directory"` // directory holding the snapshots of a SQLite database
+}
+
+// TenancyConfig holds the multi-tenant collection isolation configuration.
+type TenancyConfig struct {
+	Enabled bool `mapstructure:"enabled"` // prefix collections with the principal's tenant
+}
+
+// APIConfig holds defaults of the data API.
+type APIConfig struct {
+	IncludeTotalDefault    *bool  `mapstructure:"include_total_default"`     // count matching records on :list and :query unless ?total= says otherwise
+	MaxBulkDelete          int    `mapstructure:"max_bulk_delete"`           // records a :destroy by filter may delete unless ?force=true
+	MaxPageOffset          int    `mapstructure:"max_page_offset"`           // records a :list ?page= may skip; deeper pages must use cursors
+	FieldCase              string `mapstructure:"field_case"`                // naming of record fields in responses unless ?case= says otherwise
+	StrictQueryParams      bool   `mapstructure:"strict_query_params"`       // reject unknown query parameters on :list and aggregations unless ?strict= says otherwise
+	LenientBooleans        bool   `mapstructure:"lenient_booleans"`          // accept "true"/"false"/"1"/"0" strings and 0/1 numbers for boolean fields on writes
+	MaxRecordResponseBytes int    `mapstructure:"max_record_response_bytes"` // size over which :get replaces the largest json values of a record by stubs unless ?full=true; 0 disables
+}
+
+// Naming conventions of record fields, for api.field_case and ?case=
+const (
+	FieldCaseSnake = "snake" // fields are named as their columns, such as unit_price
+	FieldCaseCamel = "camel" // fields are named in camelCase, such as unitPrice
+)
+
+// StatsConfig holds the configuration of the :stats endpoint.
+type StatsConfig struct {
+	CacheTTL   int `mapstructure:"cache_ttl"`   // seconds :stats responses are cached per collection; 0 disables
+	SampleSize int `mapstructure:"sample_size"` // rows examined for null counts on larger collections
+}
+
+// IdempotencyConfig holds the configuration of Idempotency-Key on :create.
+type IdempotencyConfig struct {
+	TTL int `mapstructure:"ttl"` // seconds a response is replayed for its key
+}
+
+// JobsConfig holds the configuration of the journals of :import and batch
+// :create requests sent with journal=true.
+type JobsConfig struct {
+	RetentionDays int `mapstructure:"retention_days"` // journals older than this are deleted at startup; 0 keeps them
+}
+
+// TTLConfig holds the configuration of the sweeper deleting the expired
+// records of collections with a ttl policy.
+type TTLConfig struct {
+	SweepInterval int `mapstructure:"sweep_interval"` // seconds between two sweeps; 0 disables sweeping, expired records stay hidden
+}
+
+// DocConfig holds the configuration of the generated documentation.
+type DocConfig struct {
+	SampleCollection string `mapstructure:"sample_collection"` // collection the quickstart examples use; empty picks the first by name
+}
+
+// BootstrapConfig holds the one-time setup of a new instance.
+type BootstrapConfig struct {
+	SampleCollection bool `mapstructure:"sample_collection"` // create and seed demo_tasks on first startup
+}
+
+// IncludeTotal reports whether :list and :query return a total when the
+// request does not say; unset means true.
+func (c APIConfig) IncludeTotal() bool {
+	return c.IncludeTotalDefault == nil || *c.IncludeTotalDefault
 }
 
 var globalConfig *AppConfig
 
-// Load initializes and loads the application configuration.
-// It reads from YAML config files only.
-// No environment variable overrides are supported (YAML-only approach).
+// Load initializes and loads the application configuration from the YAML
+// config file, then applies the MOON_* environment variables over it.
 func Load(configPath string) (*AppConfig, error) {
+	return LoadWith(configPath, nil)
+}
+
+// LoadWith loads the configuration like Load, then applies the command-line
+// overrides keyed by setting, such as server.port. The file is overridden by
+// the environment and both by the command line.
+func LoadWith(configPath string, flags map[string]string) (*AppConfig, error) {
 	v := viper.New()
 
 	// Set default values from centralized Defaults struct
 	v.SetDefault("server.port", Defaults.Server.Port)
 	v.SetDefault("server.host", Defaults.Server.Host)
 	v.SetDefault("server.prefix", Defaults.Server.Prefix)
+	v.SetDefault("server.public_url", Defaults.Server.PublicURL)
+	v.SetDefault("server.shutdown_timeout", Defaults.Server.ShutdownTimeout)
+	v.SetDefault("server.legacy_errors", Defaults.Server.LegacyErrors)
+	v.SetDefault("server.max_body_bytes", Defaults.Server.MaxBodyBytes)
+	v.SetDefault("server.compression_min_bytes", Defaults.Server.CompressionMinBytes)
+	v.SetDefault("server.admin_port", Defaults.Server.AdminPort)
+	v.SetDefault("server.admin_host", Defaults.Server.AdminHost)
 	v.SetDefault("database.connection", Defaults.Database.Connection)
 	v.SetDefault("database.database", Defaults.Database.Database)
 	v.SetDefault("database.user", Defaults.Database.User)
@@ -436,8 +868,13 @@ func Load(configPath string) (*AppConfig, error) {
 	v.SetDefault("database.host", Defaults.Database.Host)
 	v.SetDefault("database.query_timeout", Defaults.Database.QueryTimeout)
 	v.SetDefault("database.slow_query_threshold", Defaults.Database.SlowQueryThreshold)
+	v.SetDefault("database.pool.max_open", Defaults.Database.Pool.MaxOpen)
+	v.SetDefault("database.pool.max_idle", Defaults.Database.Pool.MaxIdle)
+	v.SetDefault("database.pool.conn_max_lifetime", Defaults.Database.Pool.ConnMaxLifetime)
+	v.SetDefault("database.pool.conn_max_idle_time", Defaults.Database.Pool.ConnMaxIdleTime)
 	v.SetDefault("logging.path", Defaults.Logging.Path)
 	v.SetDefault("logging.redact_sensitive", Defaults.Logging.RedactSensitive)
+	v.SetDefault("logging.level", Defaults.Logging.Level)
 	v.SetDefault("jwt.expiry", Defaults.JWT.Expiry)
 	v.SetDefault("jwt.access_expiry", Defaults.JWT.AccessExpiry)
 	v.SetDefault("jwt.refresh_expiry", Defaults.JWT.RefreshExpiry)
@@ -450,6 +887,8 @@ func Load(configPath string) (*AppConfig, error) {
 	v.SetDefault("recovery.auto_repair", Defaults.Recovery.AutoRepair)
 	v.SetDefault("recovery.drop_orphans", Defaults.Recovery.DropOrphans)
 	v.SetDefault("recovery.check_timeout", Defaults.Recovery.CheckTimeout)
+	v.SetDefault("discovery.enabled", Defaults.Discovery.Enabled)
+	v.SetDefault("discovery.adopt", Defaults.Discovery.Adopt)
 	v.SetDefault("cors.enabled", Defaults.CORS.Enabled)
 	v.SetDefault("cors.allowed_origins", Defaults.CORS.AllowedOrigins)
 	v.SetDefault("cors.allowed_methods", Defaults.CORS.AllowedMethods)
@@ -465,6 +904,34 @@ func Load(configPath string) (*AppConfig, error) {
 	v.SetDefault("limits.max_sort_fields_per_request", Defaults.Limits.MaxSortFieldsPerRequest)
 	v.SetDefault("batch.max_size", Defaults.Batch.MaxSize)
 	v.SetDefault("batch.max_payload_bytes", Defaults.Batch.MaxPayloadBytes)
+	v.SetDefault("batch.import_chunk_size", Defaults.Batch.ImportChunkSize)
+	v.SetDefault("batch.max_import_bytes", Defaults.Batch.MaxImportBytes)
+	v.SetDefault("batch.max_transact_ops", Defaults.Batch.MaxTransactOps)
+	v.SetDefault("webhooks.queue_size", Defaults.Webhooks.QueueSize)
+	v.SetDefault("webhooks.max_attempts", Defaults.Webhooks.MaxAttempts)
+	v.SetDefault("webhooks.timeout", Defaults.Webhooks.Timeout)
+	v.SetDefault("cache.enabled", Defaults.Cache.Enabled)
+	v.SetDefault("cache.ttl", Defaults.Cache.TTL)
+	v.SetDefault("cache.max_entries", Defaults.Cache.MaxEntries)
+	v.SetDefault("audit.enabled", Defaults.Audit.Enabled)
+	v.SetDefault("audit.retention_days", Defaults.Audit.RetentionDays)
+	v.SetDefault("audit.queue_size", Defaults.Audit.QueueSize)
+	v.SetDefault("backup.directory", Defaults.Backup.Directory)
+	v.SetDefault("tenancy.enabled", Defaults.Tenancy.Enabled)
+	v.SetDefault("api.include_total_default", Defaults.API.IncludeTotalDefault)
+	v.SetDefault("api.max_bulk_delete", Defaults.API.MaxBulkDelete)
+	v.SetDefault("api.max_page_offset", Defaults.API.MaxPageOffset)
+	v.SetDefault("api.field_case", Defaults.API.FieldCase)
+	v.SetDefault("api.strict_query_params", Defaults.API.StrictQueryParams)
+	v.SetDefault("api.lenient_booleans", Defaults.API.LenientBooleans)
+	v.SetDefault("api.max_record_response_bytes", Defaults.API.MaxRecordResponseBytes)
+	v.SetDefault("stats.cache_ttl", Defaults.Stats.CacheTTL)
+	v.SetDefault("stats.sample_size", Defaults.Stats.SampleSize)
+	v.SetDefault("idempotency.ttl", Defaults.Idempotency.TTL)
+	v.SetDefault("jobs.retention_days", Defaults.Jobs.RetentionDays)
+	v.SetDefault("ttl.sweep_interval", Defaults.TTL.SweepInterval)
+	v.SetDefault("doc.sample_collection", Defaults.Doc.SampleCollection)
+	v.SetDefault("bootstrap.sample_collection", Defaults.Bootstrap.SampleCollection)
 
 	// Configure Viper to read from YAML config file only
 	// Explicitly disable TOML support
@@ -492,11 +959,17 @@ func Load(configPath string) (*AppConfig, error) {
 		}
 	}
 
+	overrides, err := applyOverrides(v, os.LookupEnv, flags)
+	if err != nil {
+		return nil, err
+	}
+
 	// Unmarshal configuration into struct
 	var cfg AppConfig
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	cfg.Overrides = overrides
 
 	// Validate required fields
 	if err := validate(&cfg); err != nil {
@@ -514,12 +987,44 @@ func validate(cfg *AppConfig) error {
 	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d", cfg.Server.Port)
 	}
+	if cfg.Server.AdminPort < 0 || cfg.Server.AdminPort > 65535 {
+		return fmt.Errorf("invalid server admin port: %d", cfg.Server.AdminPort)
+	}
+	if cfg.Server.AdminPort == cfg.Server.Port {
+		return fmt.Errorf("server.admin_port must differ from server.port %d", cfg.Server.Port)
+	}
+	if cfg.Server.AdminHost == "" {
+		cfg.Server.AdminHost = Defaults.Server.AdminHost
+	}
+
+	if cfg.Server.ShutdownTimeout <= 0 {
+		cfg.Server.ShutdownTimeout = Defaults.Server.ShutdownTimeout
+	}
+	if cfg.Server.MaxBodyBytes <= 0 {
+		cfg.Server.MaxBodyBytes = Defaults.Server.MaxBodyBytes
+	}
 
-	// Normalize prefix: add leading slash if missing, preserve trailing slash
+	// Normalize prefix: add leading slash if missing, strip trailing slashes,
+	// so "api/v1/" mounts the routes under /api/v1 like "/api/v1"
+	cfg.Server.Prefix = strings.TrimRight(cfg.Server.Prefix, "/")
 	if cfg.Server.Prefix != "" && !strings.HasPrefix(cfg.Server.Prefix, "/") {
 		cfg.Server.Prefix = "/" + cfg.Server.Prefix
 	}
 
+	if _, err := cfg.Server.TrustedProxyPrefixes(); err != nil {
+		return err
+	}
+
+	// The public URL replaces scheme and host in documentation examples;
+	// the prefix is still appended to it
+	if cfg.Server.PublicURL != "" {
+		u, err := url.Parse(cfg.Server.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("server.public_url must be an absolute http or https URL without query or fragment")
+		}
+		cfg.Server.PublicURL = strings.TrimRight(cfg.Server.PublicURL, "/")
+	}
+
 	// Apply default database values if not provided
 	if cfg.Database.Connection == "" {
 		cfg.Database.Connection = Defaults.Database.Connection
@@ -535,6 +1040,10 @@ func validate(cfg *AppConfig) error {
 		cfg.Database.SlowQueryThreshold = Defaults.Database.SlowQueryThreshold
 	}
 
+	if err := validatePool(cfg.Database.Pool); err != nil {
+		return err
+	}
+
 	// For SQLite, normalize database path to absolute
 	if cfg.Database.Connection == Defaults.Database.Connection && !filepath.IsAbs(cfg.Database.Database) {
 		absPath, err := filepath.Abs(cfg.Database.Database)
@@ -548,6 +1057,21 @@ func validate(cfg *AppConfig) error {
 	if cfg.Logging.Path == "" {
 		cfg.Logging.Path = Defaults.Logging.Path
 	}
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = Defaults.Logging.Level
+	}
+	level, err := logging.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		return fmt.Errorf("logging.level: %w", err)
+	}
+	cfg.Logging.Level = string(level)
+	for module, name := range cfg.Logging.Levels {
+		level, err := logging.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("logging.levels.%s: %w", module, err)
+		}
+		cfg.Logging.Levels[module] = string(level)
+	}
 
 	// JWT secret is required for authentication
 	if cfg.JWT.Secret == "" {
@@ -588,12 +1112,141 @@ func validate(cfg *AppConfig) error {
 	if cfg.Batch.MaxPayloadBytes <= 0 {
 		cfg.Batch.MaxPayloadBytes = Defaults.Batch.MaxPayloadBytes
 	}
+	if cfg.Batch.ImportChunkSize <= 0 {
+		cfg.Batch.ImportChunkSize = Defaults.Batch.ImportChunkSize
+	}
+	if cfg.Batch.MaxImportBytes <= 0 {
+		cfg.Batch.MaxImportBytes = Defaults.Batch.MaxImportBytes
+	}
+	if cfg.Batch.MaxTransactOps <= 0 {
+		cfg.Batch.MaxTransactOps = Defaults.Batch.MaxTransactOps
+	}
+	if cfg.API.MaxBulkDelete <= 0 {
+		cfg.API.MaxBulkDelete = Defaults.API.MaxBulkDelete
+	}
+	if cfg.API.MaxPageOffset <= 0 {
+		cfg.API.MaxPageOffset = Defaults.API.MaxPageOffset
+	}
+	if cfg.API.MaxRecordResponseBytes < 0 {
+		return fmt.Errorf("api.max_record_response_bytes must be 0 or more, got %d", cfg.API.MaxRecordResponseBytes)
+	}
+	switch cfg.API.FieldCase {
+	case "":
+		cfg.API.FieldCase = Defaults.API.FieldCase
+	case FieldCaseSnake, FieldCaseCamel:
+	default:
+		return fmt.Errorf("api.field_case must be %s or %s, got '%s'", FieldCaseSnake, FieldCaseCamel, cfg.API.FieldCase)
+	}
+	if cfg.Stats.CacheTTL < 0 {
+		cfg.Stats.CacheTTL = Defaults.Stats.CacheTTL
+	}
+	if cfg.Stats.SampleSize <= 0 {
+		cfg.Stats.SampleSize = Defaults.Stats.SampleSize
+	}
+	if cfg.Idempotency.TTL <= 0 {
+		cfg.Idempotency.TTL = Defaults.Idempotency.TTL
+	}
 
 	// Validate CORS endpoint configuration (PRD-058)
 	if err := validateCORSEndpoints(&cfg.CORS); err != nil {
 		return fmt.Errorf("CORS configuration validation failed: %w", err)
 	}
 
+	// Validate webhook configuration (apply defaults if missing or zero)
+	if cfg.Webhooks.QueueSize <= 0 {
+		cfg.Webhooks.QueueSize = Defaults.Webhooks.QueueSize
+	}
+	if cfg.Webhooks.MaxAttempts <= 0 {
+		cfg.Webhooks.MaxAttempts = Defaults.Webhooks.MaxAttempts
+	}
+	if cfg.Webhooks.Timeout <= 0 {
+		cfg.Webhooks.Timeout = Defaults.Webhooks.Timeout
+	}
+	if err := validateWebhookEndpoints(cfg.Webhooks.Endpoints); err != nil {
+		return fmt.Errorf("webhook configuration validation failed: %w", err)
+	}
+
+	// Validate cache configuration (apply defaults if missing or zero)
+	if cfg.Cache.TTL <= 0 {
+		cfg.Cache.TTL = Defaults.Cache.TTL
+	}
+	if cfg.Cache.MaxEntries <= 0 {
+		cfg.Cache.MaxEntries = Defaults.Cache.MaxEntries
+	}
+
+	// Validate audit configuration (apply defaults if missing or zero)
+	if cfg.Audit.RetentionDays < 0 {
+		return fmt.Errorf("audit.retention_days must be 0 or more, got %d", cfg.Audit.RetentionDays)
+	}
+	if cfg.Audit.QueueSize <= 0 {
+		cfg.Audit.QueueSize = Defaults.Audit.QueueSize
+	}
+
+	// Validate job journal configuration
+	if cfg.Jobs.RetentionDays < 0 {
+		return fmt.Errorf("jobs.retention_days must be 0 or more, got %d", cfg.Jobs.RetentionDays)
+	}
+
+	// Validate TTL sweeper configuration
+	if cfg.TTL.SweepInterval < 0 {
+		return fmt.Errorf("ttl.sweep_interval must be 0 or more, got %d", cfg.TTL.SweepInterval)
+	}
+
+	// Validate backup configuration (apply defaults if missing)
+	if cfg.Backup.Directory == "" {
+		cfg.Backup.Directory = Defaults.Backup.Directory
+	}
+
+	return nil
+}
+
+// validatePool rejects negative pool settings and idle connections that the
+// open limit would never let the pool keep
+func validatePool(pool PoolConfig) error {
+	for name, value := range map[string]int{
+		"max_open":           pool.MaxOpen,
+		"max_idle":           pool.MaxIdle,
+		"conn_max_lifetime":  pool.ConnMaxLifetime,
+		"conn_max_idle_time": pool.ConnMaxIdleTime,
+	} {
+		if value < 0 {
+			return fmt.Errorf("database.pool.%s must be 0 or more, got %d", name, value)
+		}
+	}
+	if pool.MaxOpen > 0 && pool.MaxIdle > pool.MaxOpen {
+		return fmt.Errorf("database.pool.max_idle (%d) cannot exceed database.pool.max_open (%d)", pool.MaxIdle, pool.MaxOpen)
+	}
+	return nil
+}
+
+// validateWebhookEndpoints validates webhook receiver URLs, secrets and event filters
+func validateWebhookEndpoints(endpoints []WebhookEndpointConfig) error {
+	validActions := map[string]bool{
+		"create":  true,
+		"update":  true,
+		"destroy": true,
+		"*":       true,
+	}
+
+	for i, endpoint := range endpoints {
+		u, err := url.Parse(endpoint.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks.endpoints[%d]: url must be an absolute http or https URL", i)
+		}
+		if endpoint.Secret == "" {
+			return fmt.Errorf("webhooks.endpoints[%d]: secret cannot be empty", i)
+		}
+		for _, filter := range endpoint.Events {
+			if filter == "*" {
+				continue
+			}
+			name, action, ok := strings.Cut(filter, ":")
+			if !ok || name == "" || !validActions[action] {
+				return fmt.Errorf("webhooks.endpoints[%d]: invalid event filter '%s', expected {collection}:{action} with action create, update, destroy or *", i, filter)
+			}
+		}
+	}
+
 	return nil
 }
 
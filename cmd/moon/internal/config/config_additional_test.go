@@ -333,3 +333,84 @@ func TestJWTDefaultExpiry(t *testing.T) {
 		t.Errorf("Expected default JWT.Expiry %d, got %d", Defaults.JWT.Expiry, cfg.JWT.Expiry)
 	}
 }
+
+// TestValidateWebhookEndpoints tests webhook endpoint validation
+func TestValidateWebhookEndpoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoint  WebhookEndpointConfig
+		wantError bool
+	}{
+		{"valid filters", WebhookEndpointConfig{URL: "https://example.com/hook", Secret: "s", Events: []string{"products:create", "*:destroy", "orders:*", "*"}}, false},
+		{"no filters", WebhookEndpointConfig{URL: "http://localhost:9000", Secret: "s"}, false},
+		{"relative url", WebhookEndpointConfig{URL: "/hook", Secret: "s"}, true},
+		{"unsupported scheme", WebhookEndpointConfig{URL: "ftp://example.com", Secret: "s"}, true},
+		{"missing secret", WebhookEndpointConfig{URL: "https://example.com"}, true},
+		{"unknown action", WebhookEndpointConfig{URL: "https://example.com", Secret: "s", Events: []string{"products:read"}}, true},
+		{"missing action", WebhookEndpointConfig{URL: "https://example.com", Secret: "s", Events: []string{"products"}}, true},
+		{"missing collection", WebhookEndpointConfig{URL: "https://example.com", Secret: "s", Events: []string{":create"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebhookEndpoints([]WebhookEndpointConfig{tt.endpoint})
+			if (err != nil) != tt.wantError {
+				t.Errorf("validateWebhookEndpoints() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+// TestLoad_WebhookDefaults tests that webhook settings fall back to defaults
+func TestLoad_WebhookDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	content := `jwt:
+  secret: test-secret
+webhooks:
+  endpoints:
+    - url: https://example.com/hook
+      secret: hook-secret
+      events: ["products:*"]
+`
+
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Webhooks.QueueSize != Defaults.Webhooks.QueueSize || cfg.Webhooks.MaxAttempts != Defaults.Webhooks.MaxAttempts || cfg.Webhooks.Timeout != Defaults.Webhooks.Timeout {
+		t.Errorf("expected webhook defaults, got %+v", cfg.Webhooks)
+	}
+	if len(cfg.Webhooks.Endpoints) != 1 || cfg.Webhooks.Endpoints[0].Events[0] != "products:*" {
+		t.Errorf("unexpected endpoints: %+v", cfg.Webhooks.Endpoints)
+	}
+}
+
+func TestLoad_CacheDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	content := `jwt:
+  secret: test-secret
+cache:
+  enabled: true
+  ttl: 0
+`
+
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Cache.Enabled || cfg.Cache.TTL != Defaults.Cache.TTL || cfg.Cache.MaxEntries != Defaults.Cache.MaxEntries {
+		t.Errorf("expected cache defaults, got %+v", cfg.Cache)
+	}
+}
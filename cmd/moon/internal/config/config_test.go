@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -39,6 +40,10 @@ func TestLoad_DefaultValues(t *testing.T) {
 		t.Errorf("Expected default host %s, got %s", Defaults.Server.Host, cfg.Server.Host)
 	}
 
+	if cfg.Server.ShutdownTimeout != Defaults.Server.ShutdownTimeout {
+		t.Errorf("Expected default shutdown timeout %d, got %d", Defaults.Server.ShutdownTimeout, cfg.Server.ShutdownTimeout)
+	}
+
 	if cfg.Database.Connection != Defaults.Database.Connection {
 		t.Errorf("Expected default connection %s, got %s", Defaults.Database.Connection, cfg.Database.Connection)
 	}
@@ -397,12 +402,17 @@ func TestLoad_PrefixNormalization(t *testing.T) {
 		{
 			name:     "prefix with trailing slash",
 			input:    "/moon/api/",
-			expected: "/moon/api/",
+			expected: "/moon/api",
 		},
 		{
 			name:     "prefix without leading slash with trailing slash",
 			input:    "moon/api/",
-			expected: "/moon/api/",
+			expected: "/moon/api",
+		},
+		{
+			name:     "root prefix",
+			input:    "/",
+			expected: "",
 		},
 	}
 
@@ -433,6 +443,301 @@ jwt:
 	}
 }
 
+func TestLoad_PublicURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "unset", input: "", expected: ""},
+		{name: "https host", input: "https://api.example.com", expected: "https://api.example.com"},
+		{name: "trailing slash trimmed", input: "https://example.com/moon/", expected: "https://example.com/moon"},
+		{name: "missing scheme", input: "api.example.com", wantErr: true},
+		{name: "unsupported scheme", input: "ftp://api.example.com", wantErr: true},
+		{name: "query string", input: "https://api.example.com?x=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			content := `server:
+  public_url: "` + tt.input + `"
+jwt:
+  secret: test-secret
+`
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			cfg, err := Load(configPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for public_url %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if cfg.Server.PublicURL != tt.expected {
+				t.Errorf("Expected public_url %q, got %q", tt.expected, cfg.Server.PublicURL)
+			}
+		})
+	}
+}
+
+func TestLoad_LoggingLevels(t *testing.T) {
+	load := func(logging string) (*AppConfig, error) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := "logging:\n" + logging + "jwt:\n  secret: test-secret\n"
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	cfg, err := load("  path: /tmp/logs\n")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Logging.Level != "info" || len(cfg.Logging.Levels) != 0 {
+		t.Errorf("Expected level info without module levels, got %q %v", cfg.Logging.Level, cfg.Logging.Levels)
+	}
+
+	cfg, err = load("  level: warn\n  levels:\n    handlers: debug\n    database: error\n")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Logging.Level != "warn" || cfg.Logging.Levels["handlers"] != "debug" || cfg.Logging.Levels["database"] != "error" {
+		t.Errorf("Unexpected logging levels: %q %v", cfg.Logging.Level, cfg.Logging.Levels)
+	}
+
+	if _, err := load("  level: verbose\n"); err == nil {
+		t.Error("Expected error for an invalid logging.level")
+	}
+	if _, err := load("  levels:\n    handlers: trace\n"); err == nil {
+		t.Error("Expected error for an invalid module level")
+	}
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	load := func(proxies string) (*AppConfig, error) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := "server:\n  trusted_proxies: " + proxies + "\njwt:\n  secret: test-secret\n"
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	cfg, err := load(`["10.0.0.0/8", "192.168.1.7", "2001:db8::/32"]`)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	prefixes, err := cfg.Server.TrustedProxyPrefixes()
+	if err != nil {
+		t.Fatalf("TrustedProxyPrefixes() failed: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.7/32", "2001:db8::/32"}
+	if len(prefixes) != len(want) {
+		t.Fatalf("Expected %d prefixes, got %v", len(want), prefixes)
+	}
+	for i, prefix := range prefixes {
+		if prefix.String() != want[i] {
+			t.Errorf("Expected prefix %s, got %s", want[i], prefix)
+		}
+	}
+
+	if _, err := load(`["10.0.0.0/8", "proxy.internal"]`); err == nil {
+		t.Error("Expected error for a trusted proxy that is not an address")
+	}
+}
+
+func TestLoad_AdminPort(t *testing.T) {
+	load := func(server string) (*AppConfig, error) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := "server:\n" + server + "jwt:\n  secret: test-secret\n"
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	cfg, err := load("  port: 6006\n")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Server.AdminPort != 0 || cfg.Server.AdminHost != "127.0.0.1" {
+		t.Errorf("Expected no admin port on 127.0.0.1 by default, got %d on %q", cfg.Server.AdminPort, cfg.Server.AdminHost)
+	}
+
+	cfg, err = load("  port: 6006\n  admin_port: 6007\n  admin_host: \"10.0.0.5\"\n")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Server.AdminPort != 6007 || cfg.Server.AdminHost != "10.0.0.5" {
+		t.Errorf("Expected admin port 6007 on 10.0.0.5, got %d on %q", cfg.Server.AdminPort, cfg.Server.AdminHost)
+	}
+
+	for _, server := range []string{"  port: 6006\n  admin_port: 6006\n", "  admin_port: 70000\n", "  admin_port: -1\n"} {
+		if _, err := load(server); err == nil {
+			t.Errorf("Expected error for %q", server)
+		}
+	}
+}
+
+func TestLoad_FieldCase(t *testing.T) {
+	load := func(api string) (*AppConfig, error) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := "api:\n" + api + "jwt:\n  secret: test-secret\n"
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	cfg, err := load("  max_page_offset: 500\n")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.API.FieldCase != FieldCaseSnake {
+		t.Errorf("Expected field_case %q by default, got %q", FieldCaseSnake, cfg.API.FieldCase)
+	}
+
+	cfg, err = load("  field_case: camel\n")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.API.FieldCase != FieldCaseCamel {
+		t.Errorf("Expected field_case %q, got %q", FieldCaseCamel, cfg.API.FieldCase)
+	}
+
+	if _, err := load("  field_case: kebab\n"); err == nil {
+		t.Error("Expected error for an invalid api.field_case")
+	}
+}
+
+func TestLoad_StrictQueryParams(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("jwt:\n  secret: test-secret\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.API.StrictQueryParams {
+		t.Error("Expected strict_query_params to be off by default")
+	}
+	if cfg.API.LenientBooleans {
+		t.Error("Expected lenient_booleans to be off by default")
+	}
+
+	if err := os.WriteFile(configPath, []byte("api:\n  strict_query_params: true\n  lenient_booleans: true\njwt:\n  secret: test-secret\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if cfg, err = Load(configPath); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !cfg.API.StrictQueryParams {
+		t.Error("Expected strict_query_params to be on")
+	}
+	if !cfg.API.LenientBooleans {
+		t.Error("Expected lenient_booleans to be on")
+	}
+}
+
+func TestLoad_TTLSweepInterval(t *testing.T) {
+	load := func(content string) (*AppConfig, error) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(content+"jwt:\n  secret: test-secret\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	cfg, err := load("")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.TTL.SweepInterval != 300 {
+		t.Errorf("Expected a sweep every 300 seconds by default, got %d", cfg.TTL.SweepInterval)
+	}
+	if cfg, err = load("ttl:\n  sweep_interval: 0\n"); err != nil || cfg.TTL.SweepInterval != 0 {
+		t.Errorf("Expected sweep_interval 0 to disable sweeping, got %v, %v", cfg, err)
+	}
+	if _, err := load("ttl:\n  sweep_interval: -1\n"); err == nil {
+		t.Error("Expected error for a negative ttl.sweep_interval")
+	}
+}
+
+func TestLoad_MaxRecordResponseBytes(t *testing.T) {
+	load := func(content string) (*AppConfig, error) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(content+"jwt:\n  secret: test-secret\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	cfg, err := load("")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.API.MaxRecordResponseBytes != 1048576 {
+		t.Errorf("Expected 1 MB by default, got %d", cfg.API.MaxRecordResponseBytes)
+	}
+	if cfg, err = load("api:\n  max_record_response_bytes: 0\n"); err != nil || cfg.API.MaxRecordResponseBytes != 0 {
+		t.Errorf("Expected 0 to disable the guard, got %v, %v", cfg, err)
+	}
+	if _, err := load("api:\n  max_record_response_bytes: -1\n"); err == nil {
+		t.Error("Expected error for a negative api.max_record_response_bytes")
+	}
+}
+
+func TestLoad_DatabasePool(t *testing.T) {
+	load := func(pool string) (*AppConfig, error) {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		content := "database:\n  pool:\n" + pool + "jwt:\n  secret: test-secret\n"
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	cfg, err := load("")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Database.Pool != (PoolConfig{MaxOpen: 25, MaxIdle: 10, ConnMaxLifetime: 1800, ConnMaxIdleTime: 300}) {
+		t.Errorf("Expected the default pool, got %+v", cfg.Database.Pool)
+	}
+
+	cfg, err = load("    max_open: 50\n    max_idle: 50\n    conn_max_lifetime: 0\n    conn_max_idle_time: 30\n")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Database.Pool != (PoolConfig{MaxOpen: 50, MaxIdle: 50, ConnMaxLifetime: 0, ConnMaxIdleTime: 30}) {
+		t.Errorf("Expected the configured pool, got %+v", cfg.Database.Pool)
+	}
+
+	// Without an open limit any number of idle connections fits
+	if _, err := load("    max_open: 0\n    max_idle: 100\n"); err != nil {
+		t.Errorf("Expected unlimited open connections to accept max_idle, got %v", err)
+	}
+
+	_, err = load("    max_open: 5\n    max_idle: 10\n")
+	if err == nil || !strings.Contains(err.Error(), "database.pool.max_idle (10) cannot exceed database.pool.max_open (5)") {
+		t.Errorf("Expected the idle > open error, got %v", err)
+	}
+	for _, pool := range []string{"    max_open: -1\n", "    max_idle: -1\n", "    conn_max_lifetime: -1\n", "    conn_max_idle_time: -1\n"} {
+		if _, err := load(pool); err == nil {
+			t.Errorf("Expected error for %q", pool)
+		}
+	}
+}
+
 func TestDefaults_Prefix(t *testing.T) {
 	// Verify that Defaults struct has correct prefix value
 	if Defaults.Server.Prefix != "" {
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/thalib/moon/cmd/moon/internal/redact"
+)
+
+// EnvPrefix starts the environment variables overriding settings, such as
+// MOON_SERVER_PORT for server.port
+const EnvPrefix = "MOON_"
+
+// Setting is a single configuration value that the environment and the
+// command line can override
+type Setting struct {
+	Key  string       // dotted key, such as auth.bootstrap_admin.password
+	Env  string       // environment variable, such as MOON_AUTH_BOOTSTRAP_ADMIN_PASSWORD
+	Type reflect.Type // type of the AppConfig field
+}
+
+// Override is a setting taken from the environment or the command line
+type Override struct {
+	Key    string // dotted key of the setting
+	Source string // environment variable or command-line flag
+	Value  string // value as given
+}
+
+// String describes the override with the value of a secret masked
+func (o Override) String() string {
+	return fmt.Sprintf("%s=%s (%s)", o.Key, maskValue(o.Key, o.Value), o.Source)
+}
+
+// Settings lists every setting of AppConfig, derived from its mapstructure
+// tags. Lists of structs, such as cors.endpoints, and maps, such as
+// logging.levels, are left out: they can only be set in the file.
+func Settings() []Setting {
+	return appendSettings(nil, "", reflect.TypeOf(AppConfig{}))
+}
+
+func appendSettings(settings []Setting, prefix string, t reflect.Type) []Setting {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		if field.Type.Kind() == reflect.Struct {
+			settings = appendSettings(settings, key+".", field.Type)
+			continue
+		}
+		if !supportedSetting(field.Type) {
+			continue
+		}
+		settings = append(settings, Setting{
+			Key:  key,
+			Env:  EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_")),
+			Type: field.Type,
+		})
+	}
+	return settings
+}
+
+// supportedSetting reports whether parseSetting can give a field of type t
+func supportedSetting(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Pointer:
+		return supportedSetting(t.Elem())
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// parseSetting converts the text of an override to the type of its field:
+// integers, booleans, durations such as 90s, and lists split on commas
+func parseSetting(t reflect.Type, value string) (any, error) {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q", value)
+		}
+		return d, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", value)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", value)
+		}
+		return n, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", value)
+		}
+		return f, nil
+	case reflect.Pointer:
+		return parseSetting(t.Elem(), value)
+	case reflect.Slice:
+		items := []string{}
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported setting type %s", t)
+}
+
+// applyOverrides sets the environment variables found by lookup, then the
+// command-line overrides keyed by setting, so that the file is overridden by
+// the environment and both by the command line
+func applyOverrides(v *viper.Viper, lookup func(string) (string, bool), flags map[string]string) ([]Override, error) {
+	settings := Settings()
+	var overrides []Override
+	set := func(s Setting, source, value string) error {
+		parsed, err := parseSetting(s.Type, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		v.Set(s.Key, parsed)
+		overrides = append(overrides, Override{Key: s.Key, Source: source, Value: value})
+		return nil
+	}
+
+	for _, s := range settings {
+		if value, ok := lookup(s.Env); ok {
+			if err := set(s, s.Env, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(flags)) {
+		i := slices.IndexFunc(settings, func(s Setting) bool { return s.Key == key })
+		if i < 0 {
+			return nil, fmt.Errorf("-set %s: unknown setting", key)
+		}
+		if err := set(settings[i], "-set "+key, flags[key]); err != nil {
+			return nil, err
+		}
+	}
+	return overrides, nil
+}
+
+// Masked returns the configuration keyed as in the file, with the values of
+// secrets such as jwt.secret and passwords masked, for -print-config
+func (c *AppConfig) Masked() map[string]any {
+	return maskedValue("", reflect.ValueOf(*c)).(map[string]any)
+}
+
+func maskedValue(name string, v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]any)
+		for i := range v.NumField() {
+			tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("mapstructure"), ",")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			out[tag] = maskedValue(tag, v.Field(i))
+		}
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return maskedValue(name, v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return []any{}
+		}
+		out := make([]any, v.Len())
+		for i := range v.Len() {
+			out[i] = maskedValue(name, v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any)
+		for _, k := range v.MapKeys() {
+			out[fmt.Sprint(k.Interface())] = maskedValue(fmt.Sprint(k.Interface()), v.MapIndex(k))
+		}
+		return out
+	case reflect.String:
+		return maskValue(name, v.String())
+	}
+	return v.Interface()
+}
+
+// maskValue masks the non-empty value of a setting whose name marks a secret
+func maskValue(key, value string) string {
+	name := key[strings.LastIndex(key, ".")+1:]
+	if value != "" && redact.IsSensitive(name) {
+		return redact.RedactedPlaceholder
+	}
+	return value
+}
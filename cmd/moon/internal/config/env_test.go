@@ -0,0 +1,188 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/redact"
+)
+
+// writeConfig writes a config file and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return configPath
+}
+
+func TestSettings(t *testing.T) {
+	envs := make(map[string]string)
+	for _, s := range Settings() {
+		envs[s.Key] = s.Env
+	}
+	for key, env := range map[string]string{
+		"server.port":                         "MOON_SERVER_PORT",
+		"database.connection":                 "MOON_DATABASE_CONNECTION",
+		"database.pool.max_open":              "MOON_DATABASE_POOL_MAX_OPEN",
+		"jwt.secret":                          "MOON_JWT_SECRET",
+		"auth.bootstrap_admin.password":       "MOON_AUTH_BOOTSTRAP_ADMIN_PASSWORD",
+		"auth.rate_limit.user_rpm":            "MOON_AUTH_RATE_LIMIT_USER_RPM",
+		"cors.allowed_origins":                "MOON_CORS_ALLOWED_ORIGINS",
+		"api.include_total_default":           "MOON_API_INCLUDE_TOTAL_DEFAULT",
+		"logging.additional_sensitive_fields": "MOON_LOGGING_ADDITIONAL_SENSITIVE_FIELDS",
+	} {
+		if envs[key] != env {
+			t.Errorf("expected %s for %s, got %q", env, key, envs[key])
+		}
+	}
+
+	// Lists of structs and maps only come from the file
+	for _, key := range []string{"cors.endpoints", "webhooks.endpoints", "logging.levels", "overrides"} {
+		if _, ok := envs[key]; ok {
+			t.Errorf("expected no setting for %s", key)
+		}
+	}
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	configPath := writeConfig(t, `server:
+  port: 6006
+  host: "127.0.0.1"
+jwt:
+  secret: "file-secret"
+cors:
+  allowed_origins: ["https://file.example.com"]
+`)
+	t.Setenv("MOON_SERVER_PORT", "8080")
+	t.Setenv("MOON_DATABASE_CONNECTION", "postgres")
+	t.Setenv("MOON_JWT_SECRET", "env-secret")
+	t.Setenv("MOON_AUTH_RATE_LIMIT_USER_RPM", "42")
+	t.Setenv("MOON_CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com")
+	t.Setenv("MOON_API_INCLUDE_TOTAL_DEFAULT", "false")
+	t.Setenv("MOON_API_STRICT_QUERY_PARAMS", "true")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.Host != "127.0.0.1" {
+		t.Errorf("expected port 8080 on 127.0.0.1, got %d on %s", cfg.Server.Port, cfg.Server.Host)
+	}
+	if cfg.Database.Connection != "postgres" || cfg.JWT.Secret != "env-secret" {
+		t.Errorf("expected postgres and the env secret, got %s and %s", cfg.Database.Connection, cfg.JWT.Secret)
+	}
+	if cfg.Auth.RateLimit.UserRPM != 42 {
+		t.Errorf("expected nested user_rpm 42, got %d", cfg.Auth.RateLimit.UserRPM)
+	}
+	if !slices.Equal(cfg.CORS.AllowedOrigins, []string{"https://a.example.com", "https://b.example.com"}) {
+		t.Errorf("expected the env origins, got %v", cfg.CORS.AllowedOrigins)
+	}
+	if cfg.API.IncludeTotal() || !cfg.API.StrictQueryParams {
+		t.Errorf("expected include_total_default false and strict_query_params true, got %v %v", cfg.API.IncludeTotal(), cfg.API.StrictQueryParams)
+	}
+
+	// The overrides are recorded with secrets masked
+	var described []string
+	for _, o := range cfg.Overrides {
+		described = append(described, o.String())
+	}
+	summary := strings.Join(described, "\n")
+	if !strings.Contains(summary, "server.port=8080 (MOON_SERVER_PORT)") {
+		t.Errorf("expected the port override to be recorded, got:\n%s", summary)
+	}
+	if strings.Contains(summary, "env-secret") || !strings.Contains(summary, "jwt.secret="+redact.RedactedPlaceholder) {
+		t.Errorf("expected the secret to be masked, got:\n%s", summary)
+	}
+}
+
+func TestLoad_EnvOverrideErrors(t *testing.T) {
+	configPath := writeConfig(t, `jwt:
+  secret: "file-secret"
+`)
+	tests := []struct {
+		env, value, want string
+	}{
+		{"MOON_SERVER_PORT", "eighty", `MOON_SERVER_PORT: invalid integer "eighty"`},
+		{"MOON_CACHE_ENABLED", "maybe", `MOON_CACHE_ENABLED: invalid boolean "maybe"`},
+		{"MOON_SERVER_PORT", "70000", "invalid server port: 70000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			_, err := Load(configPath)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := LoadWith(configPath, map[string]string{"server.nope": "1"}); err == nil || !strings.Contains(err.Error(), "-set server.nope: unknown setting") {
+		t.Errorf("expected an unknown setting error, got %v", err)
+	}
+}
+
+func TestLoad_OverridePrecedence(t *testing.T) {
+	configPath := writeConfig(t, `server:
+  port: 7000
+  prefix: "/file"
+  shutdown_timeout: 5
+jwt:
+  secret: "file-secret"
+`)
+	t.Setenv("MOON_SERVER_PORT", "8000")
+	t.Setenv("MOON_SERVER_PREFIX", "/env")
+
+	// file < env < flags
+	cfg, err := LoadWith(configPath, map[string]string{"server.port": "9000"})
+	if err != nil {
+		t.Fatalf("LoadWith failed: %v", err)
+	}
+	if cfg.Server.Port != 9000 {
+		t.Errorf("expected the flag port 9000, got %d", cfg.Server.Port)
+	}
+	if cfg.Server.Prefix != "/env" {
+		t.Errorf("expected the env prefix, got %q", cfg.Server.Prefix)
+	}
+	if cfg.Server.ShutdownTimeout != 5 {
+		t.Errorf("expected the file shutdown_timeout 5, got %d", cfg.Server.ShutdownTimeout)
+	}
+	if n := len(cfg.Overrides); n != 3 || cfg.Overrides[n-1].Source != "-set server.port" {
+		t.Errorf("expected the flag to be applied last, got %v", cfg.Overrides)
+	}
+}
+
+func TestAppConfig_Masked(t *testing.T) {
+	cfg := &AppConfig{
+		Server:   ServerConfig{Port: 6006},
+		Database: DatabaseConfig{User: "moon", Password: "db-pass"},
+		JWT:      JWTConfig{Secret: "jwt-secret"},
+		Webhooks: WebhooksConfig{Endpoints: []WebhookEndpointConfig{{URL: "https://hooks.example.com", Secret: "hook-secret"}}},
+	}
+	masked := cfg.Masked()
+
+	if port := masked["server"].(map[string]any)["port"]; port != 6006 {
+		t.Errorf("expected port 6006, got %v", port)
+	}
+	database := masked["database"].(map[string]any)
+	if database["user"] != "moon" || database["password"] != redact.RedactedPlaceholder {
+		t.Errorf("expected the user kept and the password masked, got %v", database)
+	}
+	if secret := masked["jwt"].(map[string]any)["secret"]; secret != redact.RedactedPlaceholder {
+		t.Errorf("expected the JWT secret masked, got %v", secret)
+	}
+	endpoint := masked["webhooks"].(map[string]any)["endpoints"].([]any)[0].(map[string]any)
+	if endpoint["url"] != "https://hooks.example.com" || endpoint["secret"] != redact.RedactedPlaceholder {
+		t.Errorf("expected the webhook secret masked, got %v", endpoint)
+	}
+	if bootstrap := masked["auth"].(map[string]any)["bootstrap_admin"].(map[string]any); bootstrap["password"] != "" {
+		t.Errorf("expected an unset password to stay empty, got %v", bootstrap["password"])
+	}
+	if _, ok := masked["overrides"]; ok {
+		t.Error("expected the overrides to be left out")
+	}
+}
@@ -6,6 +6,7 @@ package consistency
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,9 +14,13 @@ import (
 	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
 	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/query"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 )
 
+// logModule is the module of the consistency logs, configured by logging.levels.consistency
+const logModule = "consistency"
+
 // IssueType represents the type of consistency issue
 type IssueType string
 
@@ -25,8 +30,27 @@ const (
 
 	// IssueOrphanedRegistry indicates a collection is in the registry but the table doesn't exist
 	IssueOrphanedRegistry IssueType = "orphaned_registry"
+
+	// IssueIndexMismatch indicates the registered indexes of a collection differ from its table
+	IssueIndexMismatch IssueType = "index_mismatch"
+
+	// IssueColumnTypeDrift indicates a registered column has a different type in the table
+	IssueColumnTypeDrift IssueType = "column_type_drift"
+
+	// IssueExtraColumn indicates a table has a column that is not in the registry
+	IssueExtraColumn IssueType = "extra_column"
 )
 
+// systemColumns are maintained by the server and never registered as collection columns
+var systemColumns = map[string]bool{
+	"pkid":                     true,
+	"id":                       true,
+	constants.CreatedAtColumn:  true,
+	constants.UpdatedAtColumn:  true,
+	constants.RevisionColumn:   true,
+	constants.SoftDeleteColumn: true,
+}
+
 // Issue represents a detected consistency issue
 type Issue struct {
 	Type        IssueType `json:"type"`
@@ -48,6 +72,7 @@ type Checker struct {
 	db       database.Driver
 	registry *registry.SchemaRegistry
 	config   *config.RecoveryConfig
+	ignored  map[string]bool // tables left out of the orphaned table check
 }
 
 // NewChecker creates a new consistency checker
@@ -59,6 +84,18 @@ func NewChecker(db database.Driver, reg *registry.SchemaRegistry, cfg *config.Re
 	}
 }
 
+// IgnoreTables leaves tables out of the orphaned table check, so they are
+// neither reported nor repaired. Startup uses it for the tables schema
+// discovery could not register.
+func (c *Checker) IgnoreTables(names ...string) {
+	if c.ignored == nil {
+		c.ignored = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		c.ignored[name] = true
+	}
+}
+
 // Check performs a consistency check and optionally repairs issues
 func (c *Checker) Check(ctx context.Context) (*CheckResult, error) {
 	start := time.Now()
@@ -109,6 +146,10 @@ func (c *Checker) Check(ctx context.Context) (*CheckResult, error) {
 
 	// Check for orphaned registry entries (in registry but table doesn't exist)
 	for _, col := range collections {
+		if err := checkCtx.Err(); err != nil {
+			return interrupted(result, start, err)
+		}
+
 		// Skip system tables in registry check
 		if constants.IsSystemTable(col) {
 			continue
@@ -124,10 +165,10 @@ func (c *Checker) Check(ctx context.Context) (*CheckResult, error) {
 			if c.config.AutoRepair {
 				// Remove from registry
 				if err := c.registry.Delete(col); err != nil {
-					logging.Warnf("Failed to remove orphaned registry entry '%s': %v", col, err)
+					logging.Module(logModule).Warnf("Failed to remove orphaned registry entry '%s': %v", col, err)
 				} else {
 					issue.Repaired = true
-					logging.Infof("Removed orphaned registry entry: %s", col)
+					logging.Module(logModule).Infof("Removed orphaned registry entry: %s", col)
 				}
 			}
 
@@ -138,7 +179,11 @@ func (c *Checker) Check(ctx context.Context) (*CheckResult, error) {
 
 	// Check for orphaned tables (in database but not in registry)
 	for _, table := range tables {
-		if !collectionMap[table] {
+		if err := checkCtx.Err(); err != nil {
+			return interrupted(result, start, err)
+		}
+
+		if !collectionMap[table] && !c.ignored[table] {
 			issue := Issue{
 				Type:        IssueOrphanedTable,
 				Name:        table,
@@ -149,25 +194,25 @@ func (c *Checker) Check(ctx context.Context) (*CheckResult, error) {
 				if c.config.DropOrphans {
 					// Validate table name to prevent SQL injection
 					if !isValidTableName(table) {
-						logging.Warnf("Skipping drop of table '%s': invalid table name", table)
+						logging.Module(logModule).Warnf("Skipping drop of table '%s': invalid table name", table)
 						continue
 					}
 
 					// Drop the orphaned table
-					dropSQL := fmt.Sprintf("DROP TABLE %s", table)
+					dropSQL := fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(c.db.Dialect(), table))
 					if _, err := c.db.Exec(checkCtx, dropSQL); err != nil {
-						logging.Warnf("Failed to drop orphaned table '%s': %v", table, err)
+						logging.Module(logModule).Warnf("Failed to drop orphaned table '%s': %v", table, err)
 					} else {
 						issue.Repaired = true
-						logging.Infof("Dropped orphaned table: %s", table)
+						logging.Module(logModule).Infof("Dropped orphaned table: %s", table)
 					}
 				} else {
 					// Try to register the orphaned table
 					if err := c.registerOrphanedTable(checkCtx, table); err != nil {
-						logging.Warnf("Failed to register orphaned table '%s': %v", table, err)
+						logging.Module(logModule).Warnf("Failed to register orphaned table '%s': %v", table, err)
 					} else {
 						issue.Repaired = true
-						logging.Infof("Registered orphaned table: %s", table)
+						logging.Module(logModule).Infof("Registered orphaned table: %s", table)
 					}
 				}
 			}
@@ -177,25 +222,80 @@ func (c *Checker) Check(ctx context.Context) (*CheckResult, error) {
 		}
 	}
 
+	// Compare columns and indexes of collections whose tables exist
+	for _, col := range collections {
+		if constants.IsSystemTable(col) || !tableMap[col] {
+			continue
+		}
+		if err := checkCtx.Err(); err != nil {
+			return interrupted(result, start, err)
+		}
+		if issues := c.checkColumns(checkCtx, col); len(issues) > 0 {
+			result.Issues = append(result.Issues, issues...)
+			result.Consistent = false
+		}
+		if issues := c.checkIndexes(checkCtx, col); len(issues) > 0 {
+			result.Issues = append(result.Issues, issues...)
+			result.Consistent = false
+		}
+	}
+
 	result.Duration = time.Since(start)
 
 	// Log summary
 	if result.Consistent {
-		logging.Info("Consistency check passed: registry and database are synchronized")
+		logging.Module(logModule).Info("Consistency check passed: registry and database are synchronized")
 	} else {
-		logging.Warnf("Consistency check found %d issue(s)", len(result.Issues))
+		logging.Module(logModule).Warnf("Consistency check found %d issue(s)", len(result.Issues))
 		for _, issue := range result.Issues {
 			status := "not repaired"
 			if issue.Repaired {
 				status = "repaired"
 			}
-			logging.Warnf("  - %s: %s (%s)", issue.Type, issue.Name, status)
+			logging.Module(logModule).Warnf("  - %s: %s (%s)", issue.Type, issue.Name, status)
 		}
 	}
 
 	return result, nil
 }
 
+// interrupted finishes a check stopped by its context. A timeout is reported in
+// the partial result; a cancelled request only returns the context error.
+func interrupted(result *CheckResult, start time.Time, err error) (*CheckResult, error) {
+	result.Duration = time.Since(start)
+	if err == context.DeadlineExceeded {
+		result.TimedOut = true
+		return result, fmt.Errorf("%s", constants.ConsistencyErrorMessages.CheckTimeout)
+	}
+	return nil, err
+}
+
+// AdoptTables registers every user table that has no registry entry by
+// inferring its schema from the database, regardless of the auto-repair
+// settings. It is used once to migrate instances that predate persisted
+// schemas and returns the names of the adopted tables.
+func (c *Checker) AdoptTables(ctx context.Context) ([]string, error) {
+	tables, err := c.db.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var adopted []string
+	for _, table := range tables {
+		if constants.IsSystemTable(table) || c.registry.Exists(table) {
+			continue
+		}
+		if err := c.registerOrphanedTable(ctx, table); err != nil {
+			logging.Module(logModule).Warnf("Failed to migrate table '%s': %v", table, err)
+			continue
+		}
+		logging.Module(logModule).Infof("Migrated table to persisted schema: %s", table)
+		adopted = append(adopted, table)
+	}
+
+	return adopted, nil
+}
+
 // registerOrphanedTable attempts to infer schema and register an orphaned table
 func (c *Checker) registerOrphanedTable(ctx context.Context, tableName string) error {
 	// Get table info from database
@@ -210,20 +310,35 @@ func (c *Checker) registerOrphanedTable(ctx context.Context, tableName string) e
 
 	// Convert database columns to registry columns
 	var columns []registry.Column
+	softDelete := false
+	hasTimestamp := map[string]bool{}
+	hasRevision := false
 	for _, col := range tableInfo.Columns {
-		// Skip primary key column (ulid) as it's automatically added
-		if col.IsPrimaryKey && strings.ToLower(col.Name) == "ulid" {
+		// Skip the id column, the legacy ulid primary key and integer row
+		// keys such as pkid; they are maintained by the server or the database
+		if col.Name == "id" || (col.IsPrimaryKey && (strings.ToLower(col.Name) == "ulid" || database.InferColumnType(col.Type) == registry.TypeInteger)) {
+			continue
+		}
+
+		// The deleted_at system column marks a soft-delete collection
+		if col.Name == constants.SoftDeleteColumn {
+			softDelete = true
+			continue
+		}
+
+		// Record timestamps are system columns maintained by the server
+		if col.Name == constants.CreatedAtColumn || col.Name == constants.UpdatedAtColumn {
+			hasTimestamp[col.Name] = true
 			continue
 		}
 
-		regCol := registry.Column{
-			Name:         col.Name,
-			Type:         database.InferColumnType(col.Type),
-			Nullable:     col.Nullable,
-			Unique:       col.IsUnique,
-			DefaultValue: col.DefaultValue,
+		// The record revision is a system column maintained by the server
+		if col.Name == constants.RevisionColumn {
+			hasRevision = true
+			continue
 		}
-		columns = append(columns, regCol)
+
+		columns = append(columns, registryColumn(col))
 	}
 
 	// Only register if we have columns besides the primary key
@@ -231,10 +346,34 @@ func (c *Checker) registerOrphanedTable(ctx context.Context, tableName string) e
 		return fmt.Errorf("table only has primary key column")
 	}
 
+	// Tables created before record timestamps existed get the columns added;
+	// existing rows keep NULL timestamps until they are next updated
+	for _, name := range []string{constants.CreatedAtColumn, constants.UpdatedAtColumn} {
+		if hasTimestamp[name] {
+			continue
+		}
+		ddl := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", query.QuoteIdent(c.db.Dialect(), tableName), name, timestampColumnType(c.db.Dialect()))
+		if _, err := c.db.Exec(ctx, ddl); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", name, err)
+		}
+		logging.Module(logModule).Infof("Added missing %s column to table: %s", name, tableName)
+	}
+
+	// Tables created before record revisions existed start every row at revision 1
+	if !hasRevision {
+		ddl := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s NOT NULL DEFAULT 1", query.QuoteIdent(c.db.Dialect(), tableName), constants.RevisionColumn, revisionColumnType(c.db.Dialect()))
+		if _, err := c.db.Exec(ctx, ddl); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", constants.RevisionColumn, err)
+		}
+		logging.Module(logModule).Infof("Added missing %s column to table: %s", constants.RevisionColumn, tableName)
+	}
+
 	// Register in the registry
 	collection := &registry.Collection{
-		Name:    tableName,
-		Columns: columns,
+		Name:       tableName,
+		Columns:    columns,
+		Indexes:    collectionIndexes(tableInfo.Indexes, columns),
+		SoftDelete: softDelete,
 	}
 
 	if err := c.registry.Set(collection); err != nil {
@@ -244,6 +383,247 @@ func (c *Checker) registerOrphanedTable(ctx context.Context, tableName string) e
 	return nil
 }
 
+// registryColumn converts a database column to a registry column
+func registryColumn(col database.ColumnInfo) registry.Column {
+	return registry.Column{
+		Name:         col.Name,
+		Type:         database.InferColumnType(col.Type),
+		Nullable:     col.Nullable,
+		Unique:       col.IsUnique,
+		DefaultValue: col.DefaultValue,
+	}
+}
+
+// checkColumns compares the registered columns of a collection with the columns
+// of its table. With auto-repair, the registry adopts the table's type for
+// drifted columns and registers columns that only exist in the table; the
+// table itself is never altered.
+func (c *Checker) checkColumns(ctx context.Context, name string) []Issue {
+	collection, ok := c.registry.Get(name)
+	if !ok {
+		return nil
+	}
+
+	tableInfo, err := c.db.GetTableInfo(ctx, name)
+	if err != nil {
+		logging.Module(logModule).Warnf("Failed to read columns of table '%s': %v", name, err)
+		return nil
+	}
+
+	registered := make(map[string]int, len(collection.Columns))
+	for i, col := range collection.Columns {
+		registered[col.Name] = i
+	}
+
+	var issues []Issue
+	columns := slices.Clone(collection.Columns)
+	for _, dbCol := range tableInfo.Columns {
+		// Primary keys, including the legacy ulid key, are never collection columns
+		if systemColumns[dbCol.Name] || dbCol.IsPrimaryKey {
+			continue
+		}
+
+		i, exists := registered[dbCol.Name]
+		if !exists {
+			issue := Issue{
+				Type:        IssueExtraColumn,
+				Name:        name + "." + dbCol.Name,
+				Description: constants.ConsistencyErrorMessages.ExtraColumn,
+			}
+			if c.config.AutoRepair {
+				columns = append(columns, registryColumn(dbCol))
+				issue.Repaired = true
+			}
+			issues = append(issues, issue)
+			continue
+		}
+
+		if !columnTypeDrifted(columns[i].Type, dbCol.Type, c.db.Dialect()) {
+			continue
+		}
+		issue := Issue{
+			Type:        IssueColumnTypeDrift,
+			Name:        name + "." + dbCol.Name,
+			Description: fmt.Sprintf("%s (registry: %s, database: %s)", constants.ConsistencyErrorMessages.ColumnTypeDrift, columns[i].Type, dbCol.Type),
+		}
+		if c.config.AutoRepair {
+			columns[i].Type = database.InferColumnType(dbCol.Type)
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+
+	if c.config.AutoRepair && len(issues) > 0 {
+		updated := *collection
+		updated.Columns = columns
+		if err := c.registry.Set(&updated); err != nil {
+			logging.Module(logModule).Warnf("Failed to update columns of '%s' in registry: %v", name, err)
+		}
+	}
+
+	return issues
+}
+
+// columnTypeDrifted reports whether a table column no longer stores the
+// registered type. Both sides are compared as InferColumnType reads them back,
+// so dialect storage choices such as SQLite booleans in INTEGER columns or
+// MySQL booleans in TINYINT(1) columns are not drift. Neither is a column
+// that reads back as the registered type itself, such as a TIMESTAMP column
+// of a discovered SQLite table, where Moon would have used TEXT.
+func columnTypeDrifted(registered registry.ColumnType, dbType string, dialect database.DialectType) bool {
+	expected := database.InferColumnType(query.ColumnSQLType(dialect, registered))
+	actual := database.InferColumnType(dbType)
+	if actual == registered {
+		return false
+	}
+	if expected == registry.TypeBoolean && actual == registry.TypeInteger {
+		return false
+	}
+	return expected != actual
+}
+
+// checkIndexes compares the registered indexes of a collection with the indexes
+// reported by the database. With auto-repair, indexes missing from the table are
+// recreated and the registry adopts indexes that only exist in the database.
+func (c *Checker) checkIndexes(ctx context.Context, name string) []Issue {
+	collection, ok := c.registry.Get(name)
+	if !ok {
+		return nil
+	}
+
+	tableInfo, err := c.db.GetTableInfo(ctx, name)
+	if err != nil {
+		logging.Module(logModule).Warnf("Failed to read indexes of table '%s': %v", name, err)
+		return nil
+	}
+
+	actual := collectionIndexes(tableInfo.Indexes, collection.Columns)
+	dbIndexes := make(map[string]registry.Index, len(actual))
+	for _, idx := range actual {
+		dbIndexes[idx.Name] = idx
+	}
+
+	var issues []Issue
+	registered := make(map[string]bool, len(collection.Indexes))
+	indexes := make([]registry.Index, 0, len(collection.Indexes))
+	for _, idx := range collection.Indexes {
+		registered[idx.Name] = true
+		dbIdx, exists := dbIndexes[idx.Name]
+		switch {
+		case !exists:
+			issue := Issue{
+				Type:        IssueIndexMismatch,
+				Name:        name + "." + idx.Name,
+				Description: constants.ConsistencyErrorMessages.MissingIndex,
+			}
+			if c.config.AutoRepair {
+				if _, err := c.db.Exec(ctx, createIndexDDL(name, idx, c.db.Dialect())); err != nil {
+					logging.Module(logModule).Warnf("Failed to recreate index '%s' on table '%s': %v", idx.Name, name, err)
+				} else {
+					issue.Repaired = true
+					logging.Module(logModule).Infof("Recreated missing index %s on table: %s", idx.Name, name)
+				}
+			}
+			issues = append(issues, issue)
+			indexes = append(indexes, idx)
+		case !sameIndex(idx, dbIdx):
+			issue := Issue{
+				Type:        IssueIndexMismatch,
+				Name:        name + "." + idx.Name,
+				Description: constants.ConsistencyErrorMessages.IndexDefinition,
+			}
+			if c.config.AutoRepair {
+				idx = dbIdx
+				issue.Repaired = true
+			}
+			issues = append(issues, issue)
+			indexes = append(indexes, idx)
+		default:
+			indexes = append(indexes, idx)
+		}
+	}
+
+	for _, idx := range actual {
+		if registered[idx.Name] {
+			continue
+		}
+		issue := Issue{
+			Type:        IssueIndexMismatch,
+			Name:        name + "." + idx.Name,
+			Description: constants.ConsistencyErrorMessages.UnregisteredIndex,
+		}
+		if c.config.AutoRepair {
+			indexes = append(indexes, idx)
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+
+	if c.config.AutoRepair && len(issues) > 0 {
+		collection.Indexes = indexes
+		if err := c.registry.Set(collection); err != nil {
+			logging.Module(logModule).Warnf("Failed to update indexes of '%s' in registry: %v", name, err)
+		}
+	}
+
+	return issues
+}
+
+// collectionIndexes converts database indexes to registry indexes. Single-column
+// unique indexes on id or on a unique column back the column constraint and are skipped.
+func collectionIndexes(indexes []database.IndexInfo, columns []registry.Column) []registry.Index {
+	uniqueColumns := map[string]bool{"id": true}
+	for _, col := range columns {
+		if col.Unique {
+			uniqueColumns[col.Name] = true
+		}
+	}
+
+	var result []registry.Index
+	for _, idx := range indexes {
+		if idx.Unique && len(idx.Columns) == 1 && uniqueColumns[idx.Columns[0]] {
+			continue
+		}
+		result = append(result, registry.Index{
+			Name:    idx.Name,
+			Columns: idx.Columns,
+			Unique:  idx.Unique,
+		})
+	}
+	return result
+}
+
+// sameIndex reports whether two index definitions cover the same columns in the same order
+func sameIndex(a, b registry.Index) bool {
+	return a.Unique == b.Unique && strings.Join(a.Columns, ",") == strings.Join(b.Columns, ",")
+}
+
+// createIndexDDL returns the CREATE INDEX statement for a registered index
+func createIndexDDL(tableName string, idx registry.Index, dialect database.DialectType) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique,
+		query.QuoteIdent(dialect, idx.Name), query.QuoteIdent(dialect, tableName), query.QuoteIdents(dialect, idx.Columns))
+}
+
+// timestampColumnType returns the SQL type used for datetime system columns
+func timestampColumnType(dialect database.DialectType) string {
+	if dialect == database.DialectSQLite {
+		return "TEXT"
+	}
+	return "TIMESTAMP"
+}
+
+// revisionColumnType returns the SQL type used for the _rev system column
+func revisionColumnType(dialect database.DialectType) string {
+	if dialect == database.DialectSQLite {
+		return "INTEGER"
+	}
+	return "BIGINT"
+}
+
 // GetStatus returns a simple status string for health checks
 func (c *Checker) GetStatus(ctx context.Context) string {
 	result, err := c.Check(ctx)
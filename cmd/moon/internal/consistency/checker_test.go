@@ -287,6 +287,318 @@ func TestChecker_OrphanedTable_RepairByRegistering(t *testing.T) {
 	}
 }
 
+func TestChecker_AdoptTables(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, ddl := range []string{
+		"CREATE TABLE orders (ulid TEXT PRIMARY KEY, total REAL NOT NULL)",
+		"CREATE TABLE notes (ulid TEXT PRIMARY KEY, body TEXT)",
+		"CREATE TABLE moon_users (pkid INTEGER PRIMARY KEY, username TEXT)",
+	} {
+		if _, err := driver.Exec(ctx, ddl); err != nil {
+			t.Fatalf("failed to create table: %v", err)
+		}
+	}
+	reg.Set(&registry.Collection{Name: "notes", Columns: []registry.Column{{Name: "body", Type: registry.TypeString, Nullable: true}}})
+
+	// Tables are adopted even when auto-repair is disabled
+	cfg := &config.RecoveryConfig{AutoRepair: false, CheckTimeout: 5}
+	adopted, err := NewChecker(driver, reg, cfg).AdoptTables(ctx)
+	if err != nil {
+		t.Fatalf("AdoptTables() error = %v", err)
+	}
+
+	if len(adopted) != 1 || adopted[0] != "orders" {
+		t.Errorf("Expected only orders to be adopted, got %v", adopted)
+	}
+	collection, ok := reg.Get("orders")
+	if !ok || len(collection.Columns) != 1 || collection.Columns[0].Nullable {
+		t.Errorf("Expected orders registered with its non-nullable column, got %+v", collection)
+	}
+	if reg.Exists("moon_users") {
+		t.Error("System tables must not be adopted")
+	}
+}
+
+func TestChecker_OrphanedTable_SoftDeleteColumn(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, err := driver.Exec(ctx, "CREATE TABLE notes (ulid TEXT PRIMARY KEY, body TEXT, deleted_at TEXT)")
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	cfg := &config.RecoveryConfig{
+		AutoRepair:   true,
+		DropOrphans:  false,
+		CheckTimeout: 5,
+	}
+
+	checker := NewChecker(driver, reg, cfg)
+	if _, err := checker.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	collection, exists := reg.Get("notes")
+	if !exists {
+		t.Fatalf("Collection not found in registry")
+	}
+
+	if !collection.SoftDelete {
+		t.Error("Expected deleted_at column to enable soft delete")
+	}
+
+	// deleted_at is a system column and is not registered as a user column
+	if len(collection.Columns) != 1 || collection.Columns[0].Name != "body" {
+		t.Errorf("Expected only the body column, got %+v", collection.Columns)
+	}
+
+	// A second check finds the collection consistent
+	result, err := checker.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !result.Consistent {
+		t.Errorf("Expected consistent state after registration, got issues: %+v", result.Issues)
+	}
+}
+
+func TestChecker_OrphanedTable_AddsTimestampColumns(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, err := driver.Exec(ctx, "CREATE TABLE legacy (ulid TEXT PRIMARY KEY, title TEXT, created_at TEXT)")
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	cfg := &config.RecoveryConfig{
+		AutoRepair:   true,
+		DropOrphans:  false,
+		CheckTimeout: 5,
+	}
+
+	checker := NewChecker(driver, reg, cfg)
+	if _, err := checker.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	collection, exists := reg.Get("legacy")
+	if !exists {
+		t.Fatalf("Collection not found in registry")
+	}
+
+	// Timestamp columns are system columns and are not registered as user columns
+	if len(collection.Columns) != 1 || collection.Columns[0].Name != "title" {
+		t.Errorf("Expected only the title column, got %+v", collection.Columns)
+	}
+
+	tableInfo, err := driver.GetTableInfo(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("GetTableInfo() error = %v", err)
+	}
+	found := map[string]bool{}
+	for _, col := range tableInfo.Columns {
+		found[col.Name] = true
+	}
+	if !found["created_at"] || !found["updated_at"] {
+		t.Errorf("Expected created_at and updated_at columns, got %+v", tableInfo.Columns)
+	}
+	if !found["_rev"] {
+		t.Errorf("Expected _rev column, got %+v", tableInfo.Columns)
+	}
+}
+
+func TestChecker_OrphanedTable_RegistersIndexes(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	statements := []string{
+		"CREATE TABLE stock (pkid INTEGER PRIMARY KEY AUTOINCREMENT, id CHAR(26) NOT NULL UNIQUE, created_at TEXT, updated_at TEXT, tenant_id TEXT, sku TEXT)",
+		"CREATE UNIQUE INDEX idx_tenant_sku ON stock (tenant_id, sku)",
+	}
+	for _, stmt := range statements {
+		if _, err := driver.Exec(ctx, stmt); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+
+	cfg := &config.RecoveryConfig{
+		AutoRepair:   true,
+		DropOrphans:  false,
+		CheckTimeout: 5,
+	}
+
+	checker := NewChecker(driver, reg, cfg)
+	if _, err := checker.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	collection, exists := reg.Get("stock")
+	if !exists {
+		t.Fatalf("Collection not found in registry")
+	}
+	if len(collection.Indexes) != 1 {
+		t.Fatalf("Expected 1 index, got %+v", collection.Indexes)
+	}
+	idx := collection.Indexes[0]
+	if idx.Name != "idx_tenant_sku" || !idx.Unique || len(idx.Columns) != 2 || idx.Columns[0] != "tenant_id" || idx.Columns[1] != "sku" {
+		t.Errorf("Unexpected index: %+v", idx)
+	}
+
+	// A second check finds the registry and table in sync
+	result, err := checker.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !result.Consistent {
+		t.Errorf("Expected consistent state, got issues %+v", result.Issues)
+	}
+}
+
+func TestChecker_IndexMismatch(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	statements := []string{
+		"CREATE TABLE stock (pkid INTEGER PRIMARY KEY AUTOINCREMENT, id CHAR(26) NOT NULL UNIQUE, tenant_id TEXT, sku TEXT, price INTEGER)",
+		"CREATE INDEX idx_price ON stock (price)",
+	}
+	for _, stmt := range statements {
+		if _, err := driver.Exec(ctx, stmt); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+
+	// The registry expects idx_tenant_sku, which the table lacks, and does not know idx_price
+	reg.Set(&registry.Collection{
+		Name: "stock",
+		Columns: []registry.Column{
+			{Name: "tenant_id", Type: registry.TypeString, Nullable: true},
+			{Name: "sku", Type: registry.TypeString, Nullable: true},
+			{Name: "price", Type: registry.TypeInteger, Nullable: true},
+		},
+		Indexes: []registry.Index{
+			{Name: "idx_tenant_sku", Columns: []string{"tenant_id", "sku"}, Unique: true},
+		},
+	})
+
+	cfg := &config.RecoveryConfig{
+		AutoRepair:   true,
+		DropOrphans:  false,
+		CheckTimeout: 5,
+	}
+
+	checker := NewChecker(driver, reg, cfg)
+	result, err := checker.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	if result.Consistent || len(result.Issues) != 2 {
+		t.Fatalf("Expected 2 index issues, got %+v", result.Issues)
+	}
+	for _, issue := range result.Issues {
+		if issue.Type != IssueIndexMismatch || !issue.Repaired {
+			t.Errorf("Expected repaired index mismatch, got %+v", issue)
+		}
+	}
+
+	// The missing index is recreated and the unregistered one adopted
+	tableInfo, err := driver.GetTableInfo(ctx, "stock")
+	if err != nil {
+		t.Fatalf("GetTableInfo() error = %v", err)
+	}
+	if len(tableInfo.Indexes) != 2 {
+		t.Errorf("Expected 2 indexes in database, got %+v", tableInfo.Indexes)
+	}
+	collection, _ := reg.Get("stock")
+	if len(collection.Indexes) != 2 {
+		t.Errorf("Expected 2 indexes in registry, got %+v", collection.Indexes)
+	}
+}
+
+func TestChecker_ColumnDrift(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// The table stores quantity as TEXT and has a note column the registry does not know
+	_, err := driver.Exec(ctx, "CREATE TABLE stock (pkid INTEGER PRIMARY KEY AUTOINCREMENT, id CHAR(26) NOT NULL UNIQUE, sku TEXT, active INTEGER, quantity TEXT, note TEXT)")
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	reg.Set(&registry.Collection{
+		Name: "stock",
+		Columns: []registry.Column{
+			{Name: "sku", Type: registry.TypeString, Nullable: true},
+			{Name: "active", Type: registry.TypeBoolean, Nullable: true},
+			{Name: "quantity", Type: registry.TypeInteger, Nullable: true},
+		},
+	})
+
+	cfg := &config.RecoveryConfig{CheckTimeout: 5}
+	result, err := NewChecker(driver, reg, cfg).Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	want := map[string]IssueType{"stock.quantity": IssueColumnTypeDrift, "stock.note": IssueExtraColumn}
+	if result.Consistent || len(result.Issues) != len(want) {
+		t.Fatalf("Expected %d column issues, got %+v", len(want), result.Issues)
+	}
+	for _, issue := range result.Issues {
+		if want[issue.Name] != issue.Type || issue.Repaired {
+			t.Errorf("Unexpected issue %+v", issue)
+		}
+	}
+
+	// Repair adopts the table definition into the registry
+	cfg.AutoRepair = true
+	if _, err := NewChecker(driver, reg, cfg).Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	collection, _ := reg.Get("stock")
+	types := map[string]registry.ColumnType{}
+	for _, col := range collection.Columns {
+		types[col.Name] = col.Type
+	}
+	if types["quantity"] != registry.TypeString || types["note"] != registry.TypeString || types["active"] != registry.TypeBoolean {
+		t.Errorf("Expected the registry to match the table, got %+v", collection.Columns)
+	}
+
+	result, err = NewChecker(driver, reg, cfg).Check(ctx)
+	if err != nil || !result.Consistent {
+		t.Errorf("Expected consistent state after repair, got %+v, %v", result, err)
+	}
+}
+
+func TestChecker_Cancelled(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := NewChecker(driver, reg, &config.RecoveryConfig{CheckTimeout: 5}).Check(ctx)
+	if err == nil || result != nil {
+		t.Errorf("Expected a cancelled check to return only an error, got %+v, %v", result, err)
+	}
+}
+
 func TestChecker_OrphanedTable_RepairByDropping(t *testing.T) {
 	driver, reg, cleanup := setupTest(t)
 	defer cleanup()
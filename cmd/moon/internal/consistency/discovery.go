@@ -0,0 +1,178 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+	moonulid "github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// ulidLength is the length of a ULID in its string form
+const ulidLength = 26
+
+// DiscoveryResult summarizes the startup discovery of tables Moon did not create
+type DiscoveryResult struct {
+	Adopted []string       `json:"adopted"`
+	Skipped []SkippedTable `json:"skipped"`
+}
+
+// SkippedTable is a table discovery left unregistered and the reason why
+type SkippedTable struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Discover registers the user tables that have no registry entry, the way
+// the consistency check registers orphaned tables, but only when they can be
+// served as collections: the table name must pass validName and the table
+// must have an id column of a text type holding ULIDs. With adopt, a table
+// without an id column is given one, filled with new ULIDs through its
+// single-column primary key. Tables that cannot be registered are reported in
+// the result rather than failing; only errors reading the table list do.
+func (c *Checker) Discover(ctx context.Context, adopt bool, validName func(string) error) (*DiscoveryResult, error) {
+	tables, err := c.db.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	result := &DiscoveryResult{Adopted: []string{}, Skipped: []SkippedTable{}}
+	for _, table := range tables {
+		if constants.IsSystemTable(table) || c.registry.Exists(table) {
+			continue
+		}
+		if err := c.discoverTable(ctx, table, adopt, validName); err != nil {
+			result.Skipped = append(result.Skipped, SkippedTable{Name: table, Reason: err.Error()})
+			continue
+		}
+		result.Adopted = append(result.Adopted, table)
+	}
+	return result, nil
+}
+
+// discoverTable checks that a table can be served as a collection, gives it
+// an id column if allowed, and registers it
+func (c *Checker) discoverTable(ctx context.Context, table string, adopt bool, validName func(string) error) error {
+	if err := validName(table); err != nil {
+		return err
+	}
+
+	tableInfo, err := c.db.GetTableInfo(ctx, table)
+	if err != nil {
+		return fmt.Errorf("failed to get table info: %w", err)
+	}
+
+	var idColumn, primaryKeys []database.ColumnInfo
+	for _, col := range tableInfo.Columns {
+		if col.Name == "id" {
+			idColumn = append(idColumn, col)
+		}
+		if col.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, col)
+		}
+	}
+
+	switch {
+	case len(idColumn) == 1:
+		if err := c.checkIDColumn(ctx, table, idColumn[0]); err != nil {
+			return err
+		}
+	case !adopt:
+		return fmt.Errorf("no id column (discovery.adopt adds one)")
+	case len(primaryKeys) != 1:
+		return fmt.Errorf("no id column and no single-column primary key to assign ids by")
+	default:
+		if err := c.addIDColumn(ctx, table, primaryKeys[0].Name); err != nil {
+			return err
+		}
+	}
+
+	return c.registerOrphanedTable(ctx, table)
+}
+
+// checkIDColumn reports whether an existing id column can hold the ULIDs
+// Moon reads and writes: a text column whose values are all ULID-sized
+func (c *Checker) checkIDColumn(ctx context.Context, table string, col database.ColumnInfo) error {
+	if database.InferColumnType(col.Type) != registry.TypeString {
+		return fmt.Errorf("id column has type %s, not a text type able to hold ULIDs", col.Type)
+	}
+
+	dialect := c.db.Dialect()
+	var invalid int64
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id IS NULL OR LENGTH(id) <> %d", query.QuoteIdent(dialect, table), ulidLength)
+	if err := c.db.QueryRow(ctx, countSQL).Scan(&invalid); err != nil {
+		return fmt.Errorf("failed to read id column: %w", err)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("id column holds %d value(s) that are not ULIDs", invalid)
+	}
+	return nil
+}
+
+// addIDColumn adds the id column to a table and fills it with a new ULID per
+// row, in one transaction where the dialect allows. A unique index backs the
+// column like the UNIQUE constraint of tables Moon creates.
+func (c *Checker) addIDColumn(ctx context.Context, table, primaryKey string) error {
+	dialect := c.db.Dialect()
+	quotedTable := query.QuoteIdent(dialect, table)
+
+	if _, err := c.db.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN id CHAR(%d)", quotedTable, ulidLength)); err != nil {
+		return fmt.Errorf("failed to add id column: %w", err)
+	}
+
+	keys, err := c.primaryKeyValues(ctx, table, primaryKey)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	builder := query.NewBuilder(dialect)
+	for i, id := range moonulid.GenerateBatch(len(keys)) {
+		update, args := builder.Update(table, map[string]any{"id": id},
+			[]query.Condition{{Column: primaryKey, Operator: query.OpEqual, Value: keys[i]}})
+		if _, err := tx.ExecContext(ctx, update, args...); err != nil {
+			return fmt.Errorf("failed to assign ids: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to assign ids: %w", err)
+	}
+
+	index := registry.Index{Name: table + "_id_unique", Columns: []string{"id"}, Unique: true}
+	if _, err := c.db.Exec(ctx, createIndexDDL(table, index, dialect)); err != nil {
+		return fmt.Errorf("failed to create id index: %w", err)
+	}
+	return nil
+}
+
+// primaryKeyValues reads the primary key of every row of a table
+func (c *Checker) primaryKeyValues(ctx context.Context, table, primaryKey string) ([]any, error) {
+	dialect := c.db.Dialect()
+	rows, err := c.db.Query(ctx, fmt.Sprintf("SELECT %s FROM %s",
+		query.QuoteIdent(dialect, primaryKey), query.QuoteIdent(dialect, table)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []any
+	for rows.Next() {
+		var key any
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to read primary keys: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read primary keys: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,219 @@
+package consistency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/handlers"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+	moonulid "github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// createDiscoveryTables creates tables Moon did not create: one it can serve
+// as is, one without an id column and four it cannot serve
+func createDiscoveryTables(t *testing.T, driver database.Driver) string {
+	t.Helper()
+	ctx := context.Background()
+
+	customerID := moonulid.Generate()
+	for _, stmt := range []string{
+		"CREATE TABLE customers (id TEXT PRIMARY KEY, name TEXT NOT NULL, age INTEGER, joined TIMESTAMP)",
+		"INSERT INTO customers (id, name, age) VALUES ('" + customerID + "', 'Ada', 36)",
+		"CREATE TABLE orders (order_no INTEGER PRIMARY KEY, total INTEGER NOT NULL)",
+		"INSERT INTO orders (order_no, total) VALUES (1, 100), (2, 250)",
+		"CREATE TABLE tickets (id INTEGER PRIMARY KEY, subject TEXT)",
+		"CREATE TABLE legacy_ids (id TEXT, note TEXT)",
+		"INSERT INTO legacy_ids (id, note) VALUES ('42', 'old')",
+		"CREATE TABLE x (id TEXT, note TEXT)",
+		"CREATE TABLE moon_extra (id TEXT, note TEXT)",
+	} {
+		if _, err := driver.Exec(ctx, stmt); err != nil {
+			t.Fatalf("failed to run %q: %v", stmt, err)
+		}
+	}
+	return customerID
+}
+
+func skippedNames(result *DiscoveryResult) []string {
+	var names []string
+	for _, table := range result.Skipped {
+		names = append(names, table.Name)
+	}
+	return names
+}
+
+func TestChecker_Discover(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createDiscoveryTables(t, driver)
+
+	cfg := &config.RecoveryConfig{AutoRepair: false, CheckTimeout: 5}
+	checker := NewChecker(driver, reg, cfg)
+	result, err := checker.Discover(ctx, false, handlers.ValidateCollectionName)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	if !slices.Equal(result.Adopted, []string{"customers"}) {
+		t.Errorf("Expected only customers adopted, got %v", result.Adopted)
+	}
+	if want := []string{"legacy_ids", "moon_extra", "orders", "tickets", "x"}; !slices.Equal(skippedNames(result), want) {
+		t.Errorf("Expected %v skipped, got %+v", want, result.Skipped)
+	}
+	for _, table := range result.Skipped {
+		if table.Reason == "" {
+			t.Errorf("Expected a reason for skipping %s", table.Name)
+		}
+	}
+
+	collection, ok := reg.Get("customers")
+	if !ok {
+		t.Fatal("Expected customers registered")
+	}
+	want := []registry.Column{
+		{Name: "name", Type: registry.TypeString, Nullable: false},
+		{Name: "age", Type: registry.TypeInteger, Nullable: true},
+		{Name: "joined", Type: registry.TypeDatetime, Nullable: true},
+	}
+	if len(collection.Columns) != len(want) {
+		t.Fatalf("Expected columns %+v, got %+v", want, collection.Columns)
+	}
+	for i, col := range collection.Columns {
+		if col.Name != want[i].Name || col.Type != want[i].Type || col.Nullable != want[i].Nullable {
+			t.Errorf("Expected column %+v, got %+v", want[i], col)
+		}
+	}
+
+	// The record timestamps and revision are added like on orphaned tables
+	info, err := driver.GetTableInfo(ctx, "customers")
+	if err != nil {
+		t.Fatalf("GetTableInfo() error = %v", err)
+	}
+	for _, name := range []string{constants.CreatedAtColumn, constants.UpdatedAtColumn, constants.RevisionColumn} {
+		if !slices.ContainsFunc(info.Columns, func(col database.ColumnInfo) bool { return col.Name == name }) {
+			t.Errorf("Expected %s column added to customers", name)
+		}
+	}
+
+	// Skipped tables are left alone: not registered and not altered
+	if reg.Exists("orders") || reg.Exists("moon_extra") {
+		t.Error("Expected skipped tables not to be registered")
+	}
+	if info, _ := driver.GetTableInfo(ctx, "orders"); len(info.Columns) != 2 {
+		t.Errorf("Expected orders unchanged without discovery.adopt, got %+v", info.Columns)
+	}
+
+	// The startup check ignores the skipped tables instead of failing on them
+	checker.IgnoreTables(skippedNames(result)...)
+	check, err := checker.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !check.Consistent {
+		t.Errorf("Expected consistent state after discovery, got %+v", check.Issues)
+	}
+}
+
+func TestChecker_Discover_Adopt(t *testing.T) {
+	driver, reg, cleanup := setupTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	customerID := createDiscoveryTables(t, driver)
+
+	cfg := &config.RecoveryConfig{AutoRepair: false, CheckTimeout: 5}
+	result, err := NewChecker(driver, reg, cfg).Discover(ctx, true, handlers.ValidateCollectionName)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if !slices.Equal(result.Adopted, []string{"customers", "orders"}) {
+		t.Errorf("Expected customers and orders adopted, got %v (skipped %+v)", result.Adopted, result.Skipped)
+	}
+
+	collection, ok := reg.Get("orders")
+	if !ok || len(collection.Columns) != 1 || collection.Columns[0].Name != "total" {
+		t.Fatalf("Expected orders registered with its total column, got %+v", collection)
+	}
+	if len(collection.Indexes) != 0 {
+		t.Errorf("Expected the id index not to be registered, got %+v", collection.Indexes)
+	}
+
+	// Existing rows were given ULIDs
+	rows, err := driver.Query(ctx, "SELECT id FROM orders")
+	if err != nil {
+		t.Fatalf("failed to read ids: %v", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) != 2 || !moonulid.IsValid(ids[0]) || !moonulid.IsValid(ids[1]) || ids[0] == ids[1] {
+		t.Errorf("Expected two distinct ULIDs, got %v", ids)
+	}
+
+	// Adopted tables serve the data API
+	appCfg := &config.AppConfig{
+		Batch: config.BatchConfig{MaxSize: 50, MaxPayloadBytes: 2097152},
+		API:   config.APIConfig{MaxBulkDelete: 1000, MaxPageOffset: 10000},
+	}
+	data := handlers.NewDataHandler(driver, reg, appCfg)
+	call := func(method func(http.ResponseWriter, *http.Request, string), httpMethod, target, collection string, body any) *httptest.ResponseRecorder {
+		reader := bytes.NewReader(nil)
+		if body != nil {
+			encoded, _ := json.Marshal(body)
+			reader = bytes.NewReader(encoded)
+		}
+		w := httptest.NewRecorder()
+		method(w, httptest.NewRequest(httpMethod, target, reader), collection)
+		return w
+	}
+
+	if w := call(data.Get, http.MethodGet, "/customers:get?id="+customerID, "customers", nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Ada"`) {
+		t.Fatalf("Expected existing customer, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := call(data.Get, http.MethodGet, "/orders:get?id="+ids[0], "orders", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected existing order, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := call(data.Create, http.MethodPost, "/orders:create", "orders", handlers.CreateDataRequest{Data: map[string]any{"total": 75}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+	}
+	var created handlers.CreateDataResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	newID, _ := created.Data["id"].(string)
+
+	if w := call(data.Update, http.MethodPost, "/orders:update", "orders", handlers.UpdateDataRequest{ID: newID, Data: map[string]any{"total": 80}}); w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+
+	w = call(data.List, http.MethodGet, "/orders:list?sort=total", "orders", nil)
+	var list handlers.DataListResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list.Data) != 3 || list.Data[0]["total"] != float64(80) {
+		t.Fatalf("Expected three orders starting with the updated one, got %d: %+v", w.Code, list.Data)
+	}
+
+	if w := call(data.Destroy, http.MethodPost, "/orders:destroy", "orders", handlers.DestroyDataRequest{ID: newID}); w.Code != http.StatusOK {
+		t.Fatalf("Destroy failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(data.Get, http.MethodGet, "/orders:get?id="+newID, "orders", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after destroy, got %d", w.Code)
+	}
+}
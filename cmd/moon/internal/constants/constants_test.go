@@ -53,6 +53,7 @@ func TestTimeoutConstants(t *testing.T) {
 		{"HTTP write timeout", HTTPWriteTimeout, 15 * time.Second},
 		{"HTTP idle timeout", HTTPIdleTimeout, 60 * time.Second},
 		{"Health check timeout", HealthCheckTimeout, 5 * time.Second},
+		{"Health ping timeout", HealthPingTimeout, 200 * time.Millisecond},
 		{"JWT clock skew", JWTClockSkew, 30 * time.Second},
 		{"Slow query threshold", SlowQueryThreshold, 500 * time.Millisecond},
 	}
@@ -186,6 +187,7 @@ func TestContextKeys(t *testing.T) {
 		{"Request ID context key", ContextKeyRequestID, "request_id"},
 		{"User claims context key", ContextKeyUserClaims, "user_claims"},
 		{"API key info context key", ContextKeyAPIKeyInfo, "api_key_info"},
+		{"Client IP context key", ContextKeyClientIP, "client_ip"},
 	}
 
 	for _, tt := range tests {
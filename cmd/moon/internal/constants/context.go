@@ -21,4 +21,9 @@ const (
 	// Used in: middleware/apikey.go
 	// Purpose: Passing API key metadata through middleware chain
 	ContextKeyAPIKeyInfo = "api_key_info"
+
+	// ContextKeyClientIP is the context key for storing the resolved client IP.
+	// Used in: logging/logger.go, server/clientip.go
+	// Purpose: The peer address, or the forwarded one behind server.trusted_proxies
+	ContextKeyClientIP = "client_ip"
 )
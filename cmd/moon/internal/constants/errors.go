@@ -24,14 +24,24 @@ var (
 	// ConsistencyErrorMessages contains error messages for consistency check failures.
 	// Used in: consistency/checker.go for reporting consistency issues
 	ConsistencyErrorMessages = struct {
-		OrphanedTable    string
-		OrphanedRegistry string
-		RepairFailed     string
-		CheckTimeout     string
+		OrphanedTable     string
+		OrphanedRegistry  string
+		MissingIndex      string
+		UnregisteredIndex string
+		IndexDefinition   string
+		ColumnTypeDrift   string
+		ExtraColumn       string
+		RepairFailed      string
+		CheckTimeout      string
 	}{
-		OrphanedTable:    "table exists in database but not in registry",
-		OrphanedRegistry: "collection registered but table does not exist",
-		RepairFailed:     "failed to repair consistency issues",
-		CheckTimeout:     "consistency check timed out",
+		OrphanedTable:     "table exists in database but not in registry",
+		OrphanedRegistry:  "collection registered but table does not exist",
+		MissingIndex:      "index registered but does not exist in database",
+		UnregisteredIndex: "index exists in database but not in registry",
+		IndexDefinition:   "index definition in registry differs from database",
+		ColumnTypeDrift:   "column type in registry differs from database",
+		ExtraColumn:       "column exists in database but not in registry",
+		RepairFailed:      "failed to repair consistency issues",
+		CheckTimeout:      "consistency check timed out",
 	}
 )
@@ -7,10 +7,15 @@ package constants
 // These constants ensure consistent header naming across all components.
 const (
 	// HeaderRequestID is the HTTP header used for request tracking and correlation.
-	// Used in: errors/errors.go, logging/logger.go
+	// Used in: errors/errors.go, logging/logger.go, server/server.go
 	// Purpose: Enables request tracing across distributed systems and logs
 	HeaderRequestID = "X-Request-ID"
 
+	// MaxRequestIDLength is the longest client-supplied X-Request-ID that is honored.
+	// Used in: server/server.go
+	// Purpose: Longer or non-printable IDs are replaced to keep access logs clean
+	MaxRequestIDLength = 128
+
 	// HeaderAuthorization is the standard HTTP Authorization header.
 	// Used in: middleware/auth.go
 	// Purpose: Contains JWT bearer tokens for authentication
@@ -26,6 +31,72 @@ const (
 	// Used in: multiple handlers and middleware
 	// Purpose: Specifies the media type of the request/response body
 	HeaderContentType = "Content-Type"
+
+	// HeaderETag is the standard HTTP ETag response header.
+	// Used in: handlers/data.go
+	// Purpose: Carries the record revision returned by :get and :update
+	HeaderETag = "ETag"
+
+	// HeaderIfMatch is the standard HTTP If-Match request header.
+	// Used in: handlers/data.go
+	// Purpose: Supplies the expected record revision for :update and :destroy
+	HeaderIfMatch = "If-Match"
+
+	// HeaderWebhookSignature carries the HMAC-SHA256 signature of a webhook body.
+	// Used in: webhook/webhook.go
+	// Purpose: Lets receivers verify deliveries with the endpoint secret ("sha256=<hex>")
+	HeaderWebhookSignature = "X-Moon-Signature"
+
+	// HeaderWebhookEvent names the event of a webhook delivery.
+	// Used in: webhook/webhook.go
+	// Purpose: Lets receivers route deliveries without parsing the body ("{collection}:{action}")
+	HeaderWebhookEvent = "X-Moon-Event"
+
+	// HeaderWebhookDelivery carries the unique event ID of a webhook delivery.
+	// Used in: webhook/webhook.go
+	// Purpose: Lets receivers discard duplicates when a retried delivery arrives twice
+	HeaderWebhookDelivery = "X-Moon-Delivery"
+
+	// HeaderCache reports whether a GET data response came from the query cache.
+	// Used in: server/server.go
+	// Purpose: Makes caching observable ("hit" or "miss"); absent when the cache is disabled
+	HeaderCache = "X-Moon-Cache"
+
+	// HeaderAPIVersion reports the API version a response was written in.
+	// Used in: middleware/apiversion.go
+	// Purpose: Confirms the version negotiated from ?api_version or the Accept header
+	HeaderAPIVersion = "X-Moon-API-Version"
+
+	// HeaderNextCursor carries the next page cursor of a CSV or NDJSON list.
+	// Used in: handlers/data_list_format.go
+	// Purpose: Replaces next_cursor of the JSON envelope; absent on the last page
+	HeaderNextCursor = "X-Next-Cursor"
+
+	// HeaderTotal carries the matching record count of a CSV or NDJSON list.
+	// Used in: handlers/data_list_format.go
+	// Purpose: Replaces total of the JSON envelope; absent when the count is skipped
+	HeaderTotal = "X-Total"
+
+	// HeaderIdempotencyKey names a :create request so that retries of it are
+	// answered with the stored response.
+	// Used in: handlers/data_idempotency.go
+	// Purpose: Lets clients retry creates on flaky networks without duplicating records
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderIdempotentReplay marks a response replayed for an Idempotency-Key.
+	// Used in: handlers/data_idempotency.go
+	// Purpose: Tells clients that the records were created by an earlier request
+	HeaderIdempotentReplay = "X-Idempotent-Replay"
+
+	// HeaderLocation points at the record a single :create made.
+	// Used in: handlers/data.go, handlers/data_idempotency.go
+	// Purpose: Lets REST clients fetch the created record without building its URL
+	HeaderLocation = "Location"
+
+	// HeaderJob carries the id of the job journaling a request sent with journal=true.
+	// Used in: handlers/data_import.go, handlers/data.go
+	// Purpose: Lets clients look up the progress of an import or batch create and resume it
+	HeaderJob = "X-Moon-Job"
 )
 
 // MIME types used in HTTP responses.
@@ -37,6 +108,14 @@ const (
 	// MIMETextPlain is the MIME type for plain text responses.
 	// Used for simple text responses like the root message
 	MIMETextPlain = "text/plain; charset=utf-8"
+
+	// MIMETextCSV is the MIME type for CSV responses.
+	// Used for :list with Accept: text/csv
+	MIMETextCSV = "text/csv; charset=utf-8"
+
+	// MIMEApplicationNDJSON is the MIME type for newline-delimited JSON responses.
+	// Used for :list with Accept: application/x-ndjson
+	MIMEApplicationNDJSON = "application/x-ndjson"
 )
 
 // Authentication schemes and prefixes.
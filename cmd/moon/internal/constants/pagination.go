@@ -25,6 +25,14 @@ const (
 	// Default: 200 records (configurable via pagination.max_page_size)
 	MaxPaginationLimit = 200
 
+	// MaxCollectionPaginationLimit is the hard ceiling of the page sizes a
+	// collection may set with its pagination option.
+	// This is hardcoded and not configurable.
+	// Used in: handlers/collections.go
+	// Purpose: Bounds the pages of collections that raise the default maximum
+	// Default: 1000 records
+	MaxCollectionPaginationLimit = 1000
+
 	// DefaultPaginationOffset is the default starting position for pagination
 	// when no offset is specified.
 	// Used in: handlers/data.go
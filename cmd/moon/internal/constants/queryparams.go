@@ -0,0 +1,51 @@
+package constants
+
+// Query parameters of the data API besides filters (column[op]=value). With
+// strict query parameter checking (api.strict_query_params or ?strict=true)
+// :list and the aggregation endpoints reject any other parameter, so a new
+// parameter is added to the lists of the endpoints that read it.
+const (
+	// QueryParamStrict overrides api.strict_query_params for one request.
+	// Used in: handlers/strict_query.go
+	QueryParamStrict = "strict"
+
+	// QueryParamAPIVersion selects an API version; it is read by the
+	// apiversion middleware before the handlers.
+	QueryParamAPIVersion = "api_version"
+
+	// QueryParamFull makes :get return json values whatever the record size,
+	// streaming the response instead of stubbing them.
+	// Used in: handlers/data_record_size.go
+	QueryParamFull = "full"
+
+	// QueryParamFilter holds a :query filter tree as JSON on :count, :sum,
+	// :avg, :min and :max.
+	// Used in: handlers/data_query.go
+	QueryParamFilter = "filter"
+)
+
+var (
+	// CommonQueryParams are accepted by :list and every aggregation.
+	CommonQueryParams = []string{QueryParamStrict, QueryParamAPIVersion}
+
+	// SearchQueryParams select records beside the filters, as in :list.
+	SearchQueryParams = []string{"q", "q_fields", "q_mode", "include_deleted"}
+
+	// ListQueryParams are the paging and response parameters of :list.
+	ListQueryParams = []string{QueryParamLimit, "after", "page", "per_page", "total", "sort", "fields", "include_hidden", "expand", "case"}
+
+	// CountQueryParams are the parameters of :count.
+	CountQueryParams = []string{QueryParamFilter}
+
+	// AggregateQueryParams are the parameters of :sum, :avg, :min and :max.
+	AggregateQueryParams = []string{"field", QueryParamFilter, "include_hidden"}
+
+	// GroupByQueryParams are the parameters of :groupby.
+	GroupByQueryParams = []string{"by", "agg", "field", QueryParamFilter, "include_hidden"}
+
+	// DistinctQueryParams are the parameters of :distinct.
+	DistinctQueryParams = []string{"field", QueryParamLimit, "count", QueryParamFilter, "include_hidden"}
+
+	// TimeSeriesQueryParams are the parameters of :timeseries.
+	TimeSeriesQueryParams = []string{"field", "interval", "agg", "value_field", "from", "to", "include_hidden"}
+)
@@ -15,6 +15,27 @@ const (
 
 	// TableBlacklistedTokens is the system table for revoked JWT access tokens
 	TableBlacklistedTokens = "moon_blacklisted_tokens"
+
+	// TableSchemas is the system table persisting collection schemas across restarts
+	TableSchemas = "moon_schemas"
+
+	// TableAudit is the system table recording mutating requests when audit.enabled is set
+	TableAudit = "moon_audit"
+
+	// TableChanges is the system table checkpointing the change sequence of each collection
+	TableChanges = "moon_changes"
+
+	// TableIdempotency is the system table storing the responses of :create requests sent with an Idempotency-Key
+	TableIdempotency = "moon_idempotency"
+
+	// TableJobs is the system table journaling the progress of :import and batch :create requests sent with journal=true
+	TableJobs = "moon_jobs"
+
+	// TableMeta is the system table holding instance-wide markers, such as whether the sample collection was seeded
+	TableMeta = "moon_meta"
+
+	// TableLock is the system table holding the instance lock of PostgreSQL and MySQL databases
+	TableLock = "moon_lock"
 )
 
 // SystemTables is a list of all system tables that should be excluded from
@@ -24,6 +45,13 @@ var SystemTables = []string{
 	TableRefreshTokens,
 	TableAPIKeys,
 	TableBlacklistedTokens,
+	TableSchemas,
+	TableAudit,
+	TableChanges,
+	TableIdempotency,
+	TableJobs,
+	TableMeta,
+	TableLock,
 }
 
 // systemTableMap is a map for O(1) lookup of system tables.
@@ -32,6 +60,13 @@ var systemTableMap = map[string]bool{
 	TableRefreshTokens:     true,
 	TableAPIKeys:           true,
 	TableBlacklistedTokens: true,
+	TableSchemas:           true,
+	TableAudit:             true,
+	TableChanges:           true,
+	TableIdempotency:       true,
+	TableJobs:              true,
+	TableMeta:              true,
+	TableLock:              true,
 }
 
 // IsSystemTable checks if a given table name is a system table.
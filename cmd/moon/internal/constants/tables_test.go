@@ -13,6 +13,12 @@ func TestTableConstants(t *testing.T) {
 		{"Refresh tokens table", TableRefreshTokens, "moon_refresh_tokens"},
 		{"API keys table", TableAPIKeys, "moon_apikeys"},
 		{"Blacklisted tokens table", TableBlacklistedTokens, "moon_blacklisted_tokens"},
+		{"Schemas table", TableSchemas, "moon_schemas"},
+		{"Audit table", TableAudit, "moon_audit"},
+		{"Changes table", TableChanges, "moon_changes"},
+		{"Jobs table", TableJobs, "moon_jobs"},
+		{"Meta table", TableMeta, "moon_meta"},
+		{"Lock table", TableLock, "moon_lock"},
 	}
 
 	for _, tt := range tests {
@@ -31,6 +37,13 @@ func TestSystemTables(t *testing.T) {
 		"moon_refresh_tokens",
 		"moon_apikeys",
 		"moon_blacklisted_tokens",
+		"moon_schemas",
+		"moon_audit",
+		"moon_changes",
+		"moon_idempotency",
+		"moon_jobs",
+		"moon_meta",
+		"moon_lock",
 	}
 
 	if len(SystemTables) != len(expectedTables) {
@@ -61,6 +74,9 @@ func TestIsSystemTable(t *testing.T) {
 		{"Refresh tokens table is system", "moon_refresh_tokens", true},
 		{"API keys table is system", "moon_apikeys", true},
 		{"Blacklisted tokens table is system", "moon_blacklisted_tokens", true},
+		{"Schemas table is system", "moon_schemas", true},
+		{"Audit table is system", "moon_audit", true},
+		{"Changes table is system", "moon_changes", true},
 		{"Regular table is not system", "products", false},
 		{"Regular table with moon prefix is not system", "moon_products", false},
 		{"Empty string is not system", "", false},
@@ -7,7 +7,7 @@ import "time"
 // indefinite blocking and ensure responsive behavior.
 const (
 	// ShutdownTimeout is the maximum time allowed for graceful shutdown.
-	// Used in: shutdown/shutdown.go
+	// Used in: shutdown/shutdown.go, server/server.go (when server.shutdown_timeout is unset)
 	// Purpose: Allows in-flight requests to complete before forcing shutdown
 	// Default: 30 seconds
 	ShutdownTimeout = 30 * time.Second
@@ -39,6 +39,18 @@ const (
 	// Default: 5 seconds
 	HealthCheckTimeout = 5 * time.Second
 
+	// HealthPingTimeout is the maximum time the /health endpoint waits for the database ping.
+	// Used in: server/server.go
+	// Purpose: Keeps health probes fast so load balancers can act on an unreachable database
+	// Default: 200 milliseconds
+	HealthPingTimeout = 200 * time.Millisecond
+
+	// SelfTestTimeout is the time each check of moon -check may take.
+	// Used in: checks/checks.go
+	// Purpose: Fails the self-test on an unreachable database instead of hanging CI
+	// Default: 10 seconds
+	SelfTestTimeout = 10 * time.Second
+
 	// JWTClockSkew is the tolerance for JWT token expiration time validation.
 	// Used in: middleware/auth.go
 	// Purpose: Accounts for clock drift between servers
@@ -56,4 +68,51 @@ const (
 	// Purpose: Prevents consistency checks from blocking startup indefinitely
 	// Default: 5 seconds (configurable via recovery.check_timeout)
 	ConsistencyCheckTimeout = 5 * time.Second
+
+	// MaintenanceTimeout is the maximum time allowed for an admin:maintenance operation.
+	// Used in: server/maintenance.go
+	// Purpose: Gives VACUUM on a large database time to finish without running unbounded
+	// Default: 30 minutes
+	MaintenanceTimeout = 30 * time.Minute
+
+	// WebhookRetryBackoff is the wait before the first retry of a failed webhook delivery.
+	// Used in: webhook/webhook.go
+	// Purpose: Spaces out retries; the wait doubles after each failed attempt
+	// Default: 1 second
+	WebhookRetryBackoff = 1 * time.Second
+
+	// WebhookMaxRetryBackoff caps the wait between webhook delivery retries.
+	// Used in: webhook/webhook.go
+	// Purpose: Keeps retries of a slow receiver within a bounded interval
+	// Default: 1 minute
+	WebhookMaxRetryBackoff = 1 * time.Minute
+
+	// ChangeFeedCheckpointInterval is the time between two checkpoints of the change sequences.
+	// Used in: server/server.go
+	// Purpose: Bounds the sequences lost on a crash without a write per change
+	// Default: 10 seconds
+	ChangeFeedCheckpointInterval = 10 * time.Second
+
+	// DefaultChangesTimeout is the time a :changes request waits for a change without ?timeout.
+	// Used in: handlers/data_changes.go
+	// Purpose: Holds a long-poll long enough to avoid frequent reconnects
+	// Default: 30 seconds
+	DefaultChangesTimeout = 30 * time.Second
+
+	// MaxChangesTimeout is the longest ?timeout a :changes request accepts.
+	// Used in: handlers/data_changes.go
+	// Purpose: Bounds the connections held open by long-polling clients
+	// Default: 60 seconds
+	MaxChangesTimeout = 60 * time.Second
+
+	// InstanceLockStaleAfter is the time without a heartbeat after which an instance lock row is stale.
+	// Used in: main.go, daemon/lock.go (the holder refreshes its heartbeat three times as often)
+	// Purpose: Lets a new instance take over the database of a crashed one on PostgreSQL and MySQL
+	// Default: 30 seconds
+	InstanceLockStaleAfter = 30 * time.Second
 )
+
+// WebhookWorkers is the number of goroutines posting webhook deliveries.
+// Used in: webhook/webhook.go
+// Purpose: Lets a receiver that is retrying not hold up deliveries to others
+const WebhookWorkers = 4
@@ -17,6 +17,12 @@ const (
 	// Default: 40 characters
 	MinAPIKeyLength = 40
 
+	// MinJWTSecretLength is the shortest jwt.secret the startup self-test accepts.
+	// Used in: checks/checks.go
+	// Purpose: HS256 keys shorter than the 256-bit hash output weaken the signature
+	// Default: 32 characters
+	MinJWTSecretLength = 32
+
 	// Collection name constraints (PRD-047, PRD-048)
 	// MinCollectionNameLength is the minimum length for collection names.
 	MinCollectionNameLength = 2
@@ -34,11 +40,36 @@ const (
 	// MaxColumnNameLength is the maximum length for column names.
 	MaxColumnNameLength = 63
 	// MaxColumnsPerCollection is the maximum number of columns per collection.
-	// This includes system columns (id, ulid).
+	// This includes system columns (pkid, id, created_at, updated_at, _rev).
 	MaxColumnsPerCollection = 100
 	// SystemColumnsCount is the number of automatically added system columns.
-	// System columns are: id (auto-increment primary key), ulid (external ID).
-	SystemColumnsCount = 2
+	// System columns are: pkid (auto-increment primary key), id (ULID external ID),
+	// created_at and updated_at (record timestamps) and _rev (record revision).
+	SystemColumnsCount = 5
+	// CreatedAtColumn is the system column holding the record creation time (UTC).
+	CreatedAtColumn = "created_at"
+	// UpdatedAtColumn is the system column holding the last modification time (UTC).
+	UpdatedAtColumn = "updated_at"
+	// RevisionColumn is the system column holding the record revision.
+	// It starts at 1 on insert and is incremented by every update.
+	RevisionColumn = "_rev"
+	// PKIDColumn is the system column holding the auto-increment primary key.
+	// It is internal unless the collection sets expose_sequence.
+	PKIDColumn = "pkid"
+	// SequenceField is the read-only field exposing PKIDColumn on collections
+	// created or updated with expose_sequence.
+	SequenceField = "seq"
+	// ExpandedField is the response field holding the records expanded with ?expand.
+	ExpandedField = "_expanded"
+	// SoftDeleteColumn is the system column added to collections created with soft_delete.
+	// It stores the deletion timestamp and is NULL for live records.
+	SoftDeleteColumn = "deleted_at"
+	// MaxIndexColumns is the maximum number of columns in a collection index.
+	// MySQL allows at most 16 key parts per index.
+	MaxIndexColumns = 16
+	// MaxClientIDLength is the maximum length of a client-supplied record id,
+	// the width of the id column of collections with id_type client.
+	MaxClientIDLength = 64
 
 	// Data type constraints (PRD-048)
 	// DecimalDefaultScale is the default number of decimal places.
@@ -51,6 +82,38 @@ const (
 	MaxFiltersPerRequest = 20
 	// MaxSortFieldsPerRequest is the maximum number of sort fields per request.
 	MaxSortFieldsPerRequest = 5
+	// MaxFilterDepth is the maximum nesting of and/or groups in a :query filter.
+	MaxFilterDepth = 4
+	// MaxFilterConditions is the maximum number of conditions in a :query filter.
+	MaxFilterConditions = 20
+	// MaxGroupByGroups is the maximum number of groups returned by a :groupby request.
+	// Requests producing more groups are rejected with 400 Bad Request.
+	MaxGroupByGroups = 1000
+	// MaxTimeSeriesBuckets is the maximum number of buckets returned by a :timeseries request,
+	// zero-filled ones included. Longer ranges are rejected with 400 Bad Request.
+	MaxTimeSeriesBuckets = 1000
+	// DefaultDistinctLimit is the number of values returned by :distinct when no limit is given.
+	DefaultDistinctLimit = 100
+	// MaxDistinctLimit is the maximum limit accepted by a :distinct request.
+	MaxDistinctLimit = 1000
+	// MaxImportErrors is the maximum number of row-level errors reported by :import.
+	// Further failing rows are still counted as skipped.
+	MaxImportErrors = 100
+	// DuplicateBatchSize is the number of records collections:duplicate copies per transaction.
+	DuplicateBatchSize = 500
+	// ReferenceLookupSize is the number of referenced ids checked per lookup query.
+	ReferenceLookupSize = 500
+	// MaxExpandFields is the maximum number of reference fields expanded per request.
+	MaxExpandFields = 3
+	// MaxFieldPaths is the maximum number of dotted json paths in a fields parameter.
+	MaxFieldPaths = 20
+	// MaxFieldPathDepth is the maximum number of keys of a json path in a fields
+	// parameter, below the column (meta.a.b.c.d).
+	MaxFieldPathDepth = 4
+	// ChangeFeedSize is the number of recent changes kept per collection for :changes.
+	ChangeFeedSize = 1000
+	// TTLSweepBatchSize is the number of expired records the TTL sweeper deletes per transaction.
+	TTLSweepBatchSize = 500
 
 	// Performance constraints (PRD-048)
 	// DefaultQueryTimeout is the default query timeout in seconds.
@@ -63,6 +126,13 @@ const (
 	SystemPrefix = "moon_"
 	// SystemNamespace is the reserved namespace name.
 	SystemNamespace = "moon"
+
+	// Tenancy constraints
+	// TenantSeparator joins a tenant and a collection name in the physical table
+	// name ({tenant}__{collection}); logical names may not contain it when tenancy is enabled.
+	TenantSeparator = "__"
+	// MaxTenantLength is the maximum length for tenant identifiers.
+	MaxTenantLength = 20
 )
 
 // Regular expression patterns for validation.
@@ -81,6 +151,35 @@ const (
 	// Pattern: Must start with a lowercase letter, followed by lowercase letters, numbers, or underscores.
 	// Note: Uppercase is rejected, not auto-converted (PRD-048)
 	ColumnNamePattern = `^[a-z][a-z0-9_]*$`
+
+	// JSONPathKeyPattern is the regex pattern for keys in JSON path filters (?meta.color[eq]=red).
+	// Used in: handlers/data.go
+	// Purpose: Keys are written into the SQL path literal, so only plain identifiers are accepted
+	JSONPathKeyPattern = `^[a-zA-Z0-9_]+$`
+
+	// TenantPattern is the regex pattern for valid tenant identifiers.
+	// Pattern: Must start with a lowercase letter, followed by lowercase letters or numbers.
+	// Used in: auth/tenant.go
+	// Purpose: Tenants prefix table names, so they cannot contain the tenant separator
+	TenantPattern = `^[a-z][a-z0-9]*$`
+
+	// ClientIDPattern is the regex pattern for client-supplied record ids.
+	// Pattern: Must start with a letter or number, followed by letters, numbers, '_', '-', '.' or ':'.
+	// Used in: handlers/data_id.go
+	// Purpose: Ids appear in URLs, cursors and logs, so they are kept opaque but plain
+	ClientIDPattern = `^[A-Za-z0-9][A-Za-z0-9_.:-]*$`
+
+	// ULIDPattern is the regex pattern for the ULIDs Moon generates.
+	// Pattern: 26 uppercase Crockford base32 characters.
+	// Used in: schema/jsonschema.go
+	// Purpose: Describes generated ids to clients validating records
+	ULIDPattern = `^[0-9A-HJKMNP-TV-Z]{26}$`
+
+	// UUIDv7Pattern is the regex pattern for the UUIDv7s Moon generates.
+	// Pattern: Lowercase hyphenated UUID with version 7 and the RFC 4122 variant.
+	// Used in: schema/jsonschema.go
+	// Purpose: Describes generated ids to clients validating records
+	UUIDv7Pattern = `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`
 )
 
 // ReservedEndpointNames are collection names that conflict with system endpoints.
@@ -92,6 +191,8 @@ var ReservedEndpointNames = []string{
 	"apikeys",
 	"doc",
 	"health",
+	"admin",
+	"batch",
 }
 
 // IsReservedEndpointName checks if a name conflicts with system endpoints (case-insensitive).
@@ -196,7 +297,9 @@ var ValidFilterOperators = map[string]bool{
 	"startswith": true, // string starts with
 	"endswith":   true, // string ends with
 	"null":       true, // is null
+	"isnull":     true, // is null (true) / is not null (false)
 	"notnull":    true, // is not null
+	"between":    true, // inclusive range: low,high
 }
 
 // IsValidFilterOperator checks if an operator is valid.
@@ -0,0 +1,278 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// lockName is the key of the instance lock row in moon_lock
+const lockName = "instance"
+
+// Owner identifies the process holding an instance lock
+type Owner struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// LockedError is returned when a live process already holds the instance lock
+type LockedError struct {
+	Owner    Owner
+	Resource string // the lock file, or the lock table
+}
+
+func (e *LockedError) Error() string {
+	if e.Owner.PID == 0 {
+		return fmt.Sprintf("database is in use by another moon instance (%s)", e.Resource)
+	}
+	return fmt.Sprintf("database is in use by another moon instance: PID %d on host %s, started %s (%s)",
+		e.Owner.PID, e.Owner.Host, e.Owner.StartedAt.Format(time.RFC3339), e.Resource)
+}
+
+// InstanceLock keeps a second Moon process from serving the same database.
+// SQLite databases are locked with flock(2) on a lock file next to the
+// database file, which the kernel releases when the holder exits. PostgreSQL
+// and MySQL databases, which may be shared between hosts, are locked with a
+// row in moon_lock whose heartbeat the holder refreshes until it releases it.
+type InstanceLock struct {
+	// Broken is the holder of the stale lock taken over, nil when the lock was free
+	Broken *Owner
+
+	file  *os.File
+	db    database.Driver
+	token string
+
+	stop    chan struct{}
+	done    chan struct{}
+	release sync.Once
+}
+
+// currentOwner describes this process
+func currentOwner() Owner {
+	host, _ := os.Hostname()
+	return Owner{PID: os.Getpid(), Host: host, StartedAt: time.Now().UTC().Truncate(time.Second)}
+}
+
+// processAlive reports whether a process with the given PID runs on this host
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Signal 0 checks for existence; EPERM means it runs as another user
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// LockFile acquires the instance lock of a SQLite database with an exclusive
+// flock on path, and writes this process's PID, host and start time to it.
+// A lock file left by a process that exited without releasing it is taken
+// over and its previous content returned in Broken.
+func LockFile(path string) (*InstanceLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, constants.FilePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder := readLockFile(file)
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			lockErr := &LockedError{Resource: "lock file " + path}
+			if holder != nil {
+				lockErr.Owner = *holder
+			}
+			return nil, lockErr
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	l := &InstanceLock{file: file, Broken: readLockFile(file)}
+	content, _ := json.Marshal(currentOwner())
+	if err := file.Truncate(0); err == nil {
+		_, err = file.WriteAt(append(content, '\n'), 0)
+	}
+	if err != nil {
+		l.Release(context.Background())
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return l, nil
+}
+
+// readLockFile returns the holder written to a lock file, or nil when it is
+// empty or unreadable
+func readLockFile(file *os.File) *Owner {
+	content, err := io.ReadAll(io.NewSectionReader(file, 0, 4096))
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	var owner Owner
+	if err := json.Unmarshal(content, &owner); err != nil || owner.PID == 0 {
+		return nil
+	}
+	return &owner
+}
+
+// LockDatabase acquires the instance lock row of a PostgreSQL or MySQL
+// database in moon_lock, creating the table if needed, and refreshes its
+// heartbeat every staleAfter/3 until released. A row whose heartbeat is
+// older than staleAfter, or whose holder is a dead process on this host, is
+// taken over and its holder returned in Broken.
+func LockDatabase(ctx context.Context, db database.Driver, staleAfter time.Duration) (*InstanceLock, error) {
+	dialect := db.Dialect()
+	if _, err := db.Exec(ctx, createLockTableSQL(dialect)); err != nil {
+		return nil, fmt.Errorf("failed to create %s table: %w", constants.TableLock, err)
+	}
+
+	l := &InstanceLock{db: db, token: ulid.Generate()}
+	owner := currentOwner()
+	now := time.Now().UTC()
+	insert := fmt.Sprintf("INSERT INTO %s (name, token, pid, host, started_at, heartbeat_at) VALUES (%s, %s, %s, %s, %s, %s)",
+		constants.TableLock, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3),
+		query.Placeholder(dialect, 4), query.Placeholder(dialect, 5), query.Placeholder(dialect, 6))
+	if _, insertErr := db.Exec(ctx, insert, lockName, l.token, owner.PID, owner.Host, owner.StartedAt, now); insertErr != nil {
+		// The row exists, unless the insert failed for another reason
+		holder, token, heartbeat, err := readLockRow(ctx, db)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to write %s: %w", constants.TableLock, insertErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		lockErr := &LockedError{Owner: holder, Resource: "table " + constants.TableLock}
+		stale := now.Sub(heartbeat) > staleAfter || (holder.Host == owner.Host && !processAlive(holder.PID))
+		if !stale {
+			return nil, lockErr
+		}
+
+		// Only one of several instances breaking the same stale lock wins
+		update := fmt.Sprintf("UPDATE %s SET token = %s, pid = %s, host = %s, started_at = %s, heartbeat_at = %s WHERE name = %s AND token = %s",
+			constants.TableLock, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3),
+			query.Placeholder(dialect, 4), query.Placeholder(dialect, 5), query.Placeholder(dialect, 6), query.Placeholder(dialect, 7))
+		result, err := db.Exec(ctx, update, l.token, owner.PID, owner.Host, owner.StartedAt, now, lockName, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", constants.TableLock, err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n != 1 {
+			return nil, lockErr
+		}
+		l.Broken = &holder
+	}
+
+	l.stop, l.done = make(chan struct{}), make(chan struct{})
+	go l.heartbeat(staleAfter / 3)
+	return l, nil
+}
+
+// readLockRow returns the holder, token and heartbeat of the lock row
+func readLockRow(ctx context.Context, db database.Driver) (Owner, string, time.Time, error) {
+	var owner Owner
+	var token string
+	var heartbeat time.Time
+	statement := "SELECT token, pid, host, started_at, heartbeat_at FROM " + constants.TableLock + " WHERE name = " + query.Placeholder(db.Dialect(), 1)
+	err := db.QueryRow(ctx, statement, lockName).Scan(&token, &owner.PID, &owner.Host, &owner.StartedAt, &heartbeat)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("failed to read %s: %w", constants.TableLock, err)
+	}
+	owner.StartedAt = owner.StartedAt.UTC()
+	return owner, token, heartbeat.UTC(), err
+}
+
+// heartbeat refreshes the heartbeat of the lock row every interval until
+// the lock is released
+func (l *InstanceLock) heartbeat(interval time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	dialect := l.db.Dialect()
+	statement := fmt.Sprintf("UPDATE %s SET heartbeat_at = %s WHERE name = %s AND token = %s",
+		constants.TableLock, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3))
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		result, err := l.db.Exec(ctx, statement, time.Now().UTC(), lockName, l.token)
+		cancel()
+		if err != nil {
+			logging.Warnf("Failed to refresh the instance lock heartbeat: %v", err)
+			continue
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			logging.Error("The instance lock was taken over by another moon instance; stop one of them")
+		}
+	}
+}
+
+// Release releases the lock: the lock file is emptied and unlocked, the lock
+// row deleted. Later calls do nothing.
+func (l *InstanceLock) Release(ctx context.Context) error {
+	var err error
+	l.release.Do(func() {
+		if l.file != nil {
+			l.file.Truncate(0)
+			err = errors.Join(syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN), l.file.Close())
+			return
+		}
+		close(l.stop)
+		<-l.done
+		statement := fmt.Sprintf("DELETE FROM %s WHERE name = %s AND token = %s",
+			constants.TableLock, query.Placeholder(l.db.Dialect(), 1), query.Placeholder(l.db.Dialect(), 2))
+		if _, execErr := l.db.Exec(ctx, statement, lockName, l.token); execErr != nil {
+			err = fmt.Errorf("failed to release instance lock: %w", execErr)
+		}
+	})
+	return err
+}
+
+// createLockTableSQL returns the moon_lock DDL for the given dialect
+func createLockTableSQL(dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectPostgres:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableLock + ` (
+			name VARCHAR(64) PRIMARY KEY,
+			token VARCHAR(26) NOT NULL,
+			pid INTEGER NOT NULL,
+			host VARCHAR(255) NOT NULL,
+			started_at TIMESTAMP NOT NULL,
+			heartbeat_at TIMESTAMP NOT NULL
+		)`
+	case database.DialectMySQL:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableLock + ` (
+			name VARCHAR(64) PRIMARY KEY,
+			token VARCHAR(26) NOT NULL,
+			pid INT NOT NULL,
+			host VARCHAR(255) NOT NULL,
+			started_at DATETIME NOT NULL,
+			heartbeat_at DATETIME NOT NULL
+		)`
+	default:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableLock + ` (
+			name TEXT PRIMARY KEY,
+			token TEXT NOT NULL,
+			pid INTEGER NOT NULL,
+			host TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			heartbeat_at DATETIME NOT NULL
+		)`
+	}
+}
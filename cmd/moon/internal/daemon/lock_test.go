@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// deadPID is a PID no process has
+const deadPID = 999999999
+
+func TestLockFile_SecondInstanceFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moon.db.lock")
+	lock, err := LockFile(path)
+	if err != nil {
+		t.Fatalf("LockFile() error = %v", err)
+	}
+	if lock.Broken != nil {
+		t.Errorf("Expected a free lock, got Broken = %+v", lock.Broken)
+	}
+
+	// flock locks open files, so a second lock within one process conflicts too
+	_, err = LockFile(path)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+	host, _ := os.Hostname()
+	if locked.Owner.PID != os.Getpid() || locked.Owner.Host != host {
+		t.Errorf("Expected the holder to be PID %d on %s, got %+v", os.Getpid(), host, locked.Owner)
+	}
+
+	// Shutdown releases the lock for the next instance
+	if err := lock.Release(context.Background()); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := lock.Release(context.Background()); err != nil {
+		t.Errorf("Expected a second Release() to do nothing, got %v", err)
+	}
+	next, err := LockFile(path)
+	if err != nil {
+		t.Fatalf("Expected the released lock to be free, got %v", err)
+	}
+	if next.Broken != nil {
+		t.Errorf("Expected a released lock not to be reported as broken, got %+v", next.Broken)
+	}
+	next.Release(context.Background())
+}
+
+func TestLockFile_StaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moon.db.lock")
+	content := `{"pid": 999999999, "host": "old-host", "started_at": "2026-01-02T03:04:05Z"}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	lock, err := LockFile(path)
+	if err != nil {
+		t.Fatalf("Expected a lock file without flock to be taken over, got %v", err)
+	}
+	defer lock.Release(context.Background())
+	if lock.Broken == nil || lock.Broken.PID != deadPID || lock.Broken.Host != "old-host" {
+		t.Errorf("Expected the previous holder in Broken, got %+v", lock.Broken)
+	}
+}
+
+func setupLockDB(t *testing.T) database.Driver {
+	t.Helper()
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://:memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	return driver
+}
+
+func TestLockDatabase_SecondInstanceFails(t *testing.T) {
+	db := setupLockDB(t)
+	ctx := context.Background()
+
+	lock, err := LockDatabase(ctx, db, time.Minute)
+	if err != nil {
+		t.Fatalf("LockDatabase() error = %v", err)
+	}
+	start := time.Now()
+	_, err = LockDatabase(ctx, db, time.Minute)
+	var locked *LockedError
+	if !errors.As(err, &locked) || locked.Owner.PID != os.Getpid() {
+		t.Fatalf("Expected LockedError naming this process, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected the second instance to fail fast, took %v", time.Since(start))
+	}
+
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	var rows int
+	db.QueryRow(ctx, "SELECT COUNT(*) FROM moon_lock").Scan(&rows)
+	if rows != 0 {
+		t.Errorf("Expected Release() to delete the lock row, %d left", rows)
+	}
+	next, err := LockDatabase(ctx, db, time.Minute)
+	if err != nil {
+		t.Fatalf("Expected the released lock to be free, got %v", err)
+	}
+	next.Release(ctx)
+}
+
+func TestLockDatabase_StaleLock(t *testing.T) {
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	tests := []struct {
+		name      string
+		pid       int
+		host      string
+		heartbeat time.Time
+		stale     bool
+	}{
+		{"live holder on another host", deadPID, "other-host", now, false},
+		{"no heartbeat within the threshold", os.Getpid(), "other-host", now.Add(-2 * time.Minute), true},
+		{"dead process on this host", deadPID, host, now, true},
+		{"live process on this host", os.Getpid(), host, now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupLockDB(t)
+			ctx := context.Background()
+			if _, err := db.Exec(ctx, createLockTableSQL(db.Dialect())); err != nil {
+				t.Fatalf("Failed to create table: %v", err)
+			}
+			if _, err := db.Exec(ctx, "INSERT INTO moon_lock (name, token, pid, host, started_at, heartbeat_at) VALUES (?, ?, ?, ?, ?, ?)",
+				lockName, "01ARZ3NDEKTSV4RRFFQ69G5FAV", tt.pid, tt.host, now.Add(-time.Hour), tt.heartbeat); err != nil {
+				t.Fatalf("Failed to insert lock row: %v", err)
+			}
+
+			lock, err := LockDatabase(ctx, db, time.Minute)
+			if !tt.stale {
+				var locked *LockedError
+				if !errors.As(err, &locked) || locked.Owner.PID != tt.pid || locked.Owner.Host != tt.host {
+					t.Fatalf("Expected LockedError naming PID %d on %s, got %v", tt.pid, tt.host, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+			}
+			defer lock.Release(ctx)
+			if lock.Broken == nil || lock.Broken.PID != tt.pid || lock.Broken.Host != tt.host {
+				t.Errorf("Expected the previous holder in Broken, got %+v", lock.Broken)
+			}
+			holder, _, _, err := readLockRow(ctx, db)
+			if err != nil || holder.PID != os.Getpid() || holder.Host != host {
+				t.Errorf("Expected the row to name this process, got %+v, %v", holder, err)
+			}
+		})
+	}
+}
+
+func TestLockDatabase_Heartbeat(t *testing.T) {
+	db := setupLockDB(t)
+	ctx := context.Background()
+
+	lock, err := LockDatabase(ctx, db, 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("LockDatabase() error = %v", err)
+	}
+	defer lock.Release(ctx)
+	_, _, first, _ := readLockRow(ctx, db)
+
+	// A holder that keeps its heartbeat is never stale
+	time.Sleep(500 * time.Millisecond)
+	_, _, last, err := readLockRow(ctx, db)
+	if err != nil || !last.After(first) {
+		t.Errorf("Expected the heartbeat to advance from %v, got %v (%v)", first, last, err)
+	}
+	var locked *LockedError
+	if _, err := LockDatabase(ctx, db, 300*time.Millisecond); !errors.As(err, &locked) {
+		t.Errorf("Expected LockedError while the heartbeat runs, got %v", err)
+	}
+}
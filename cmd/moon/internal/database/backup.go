@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteRestorer is implemented by the connections of the SQLite driver
+type sqliteRestorer interface {
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// BackupSQLite writes a consistent snapshot of a SQLite database to path with
+// VACUUM INTO. The snapshot is compacted and holds no WAL; path must not exist.
+func BackupSQLite(ctx context.Context, driver Driver, path string) error {
+	if driver.Dialect() != DialectSQLite {
+		return fmt.Errorf("backups require a SQLite database, not %s", driver.Dialect())
+	}
+	if _, err := driver.Exec(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// InspectSQLite opens the SQLite database file at path read-only, verifies it
+// with PRAGMA quick_check and returns the names of its tables
+func InspectSQLite(ctx context.Context, path string) ([]string, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer db.Close()
+
+	var check string
+	if err := db.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&check); err != nil {
+		return nil, fmt.Errorf("%s is not a readable SQLite database: %w", path, err)
+	}
+	if check != "ok" {
+		return nil, fmt.Errorf("%s failed the integrity check: %s", path, check)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tables of %s: %w", path, err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list the tables of %s: %w", path, err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// RestoreSQLite replaces the content of a SQLite database with the database
+// file at path through the SQLite backup API. The pools stay open: readers see
+// the old content until the restore commits and the new content after it.
+// Writes must be paused by the caller, as the restore waits for the write lock.
+func RestoreSQLite(ctx context.Context, driver Driver, path string) error {
+	if driver.Dialect() != DialectSQLite {
+		return fmt.Errorf("restores require a SQLite database, not %s", driver.Dialect())
+	}
+	conn, err := driver.DB().Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		restorer, ok := driverConn.(sqliteRestorer)
+		if !ok {
+			return fmt.Errorf("the SQLite driver does not support restores")
+		}
+		restore, err := restorer.NewRestore("file:" + path + "?mode=ro")
+		if err != nil {
+			return fmt.Errorf("failed to start the restore: %w", err)
+		}
+		for more := true; more; {
+			if more, err = restore.Step(-1); err != nil {
+				restore.Finish()
+				return fmt.Errorf("failed to restore: %w", err)
+			}
+		}
+		if err := restore.Finish(); err != nil {
+			return fmt.Errorf("failed to finish the restore: %w", err)
+		}
+		return nil
+	})
+}
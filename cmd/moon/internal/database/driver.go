@@ -6,7 +6,11 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -61,6 +65,9 @@ type Driver interface {
 
 	// TableExists checks if a table exists in the database
 	TableExists(ctx context.Context, tableName string) (bool, error)
+
+	// TableStats counts the rows of a table and estimates its size on disk
+	TableStats(ctx context.Context, tableName string) (*TableStats, error)
 }
 
 // Config holds database connection configuration
@@ -69,27 +76,48 @@ type Config struct {
 	MaxOpenConns     int
 	MaxIdleConns     int
 	ConnMaxLifetime  time.Duration
+	ConnMaxIdleTime  time.Duration
 }
 
+// SQLite file databases run in WAL mode so readers never block the writer,
+// and wait up to sqliteBusyTimeout for a lock held by another process
+const (
+	sqliteBusyTimeout = 5 * time.Second
+	sqliteBusyRetry   = 50 * time.Millisecond
+)
+
 // baseDriver implements common functionality for all database drivers
 type baseDriver struct {
 	db      *sql.DB
 	dialect DialectType
 	dsn     string
 	config  Config
+
+	// writeDB serializes the writes of a SQLite file database through a
+	// single connection; nil for other databases, which write through db
+	writeDB *sql.DB
 }
 
 // Connect establishes a connection to the database
 func (d *baseDriver) Connect(ctx context.Context) error {
 	var err error
 	driverName := string(d.dialect)
+	dsn := d.dsn
 
 	// For SQLite, use a different driver name
 	if d.dialect == DialectSQLite {
 		driverName = "sqlite"
 	}
 
-	d.db, err = sql.Open(driverName, d.dsn)
+	sqliteFile := d.dialect == DialectSQLite && !isSQLiteMemory(dsn)
+	if sqliteFile {
+		if err := checkSQLiteWritable(sqlitePath(dsn)); err != nil {
+			return err
+		}
+		dsn = sqliteFileDSN(dsn)
+	}
+
+	d.db, err = sql.Open(driverName, dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
@@ -98,6 +126,7 @@ func (d *baseDriver) Connect(ctx context.Context) error {
 	d.db.SetMaxOpenConns(d.config.MaxOpenConns)
 	d.db.SetMaxIdleConns(d.config.MaxIdleConns)
 	d.db.SetConnMaxLifetime(d.config.ConnMaxLifetime)
+	d.db.SetConnMaxIdleTime(d.config.ConnMaxIdleTime)
 
 	// Verify connection
 	if err := d.db.PingContext(ctx); err != nil {
@@ -105,20 +134,54 @@ func (d *baseDriver) Connect(ctx context.Context) error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	if sqliteFile {
+		if d.writeDB, err = sql.Open(driverName, dsn); err != nil {
+			d.db.Close()
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		d.writeDB.SetMaxOpenConns(1)
+		d.writeDB.SetMaxIdleConns(1)
+		d.writeDB.SetConnMaxLifetime(d.config.ConnMaxLifetime)
+		d.writeDB.SetConnMaxIdleTime(d.config.ConnMaxIdleTime)
+		if err := d.writeDB.PingContext(ctx); err != nil {
+			d.Close()
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // Close closes the database connection
 func (d *baseDriver) Close() error {
+	if d.writeDB != nil {
+		d.writeDB.Close()
+	}
 	if d.db != nil {
 		return d.db.Close()
 	}
 	return nil
 }
 
-// Exec executes a query without returning rows
+// writer returns the pool that writes go through
+func (d *baseDriver) writer() *sql.DB {
+	if d.writeDB != nil {
+		return d.writeDB
+	}
+	return d.db
+}
+
+// Exec executes a query without returning rows. On SQLite a write that still
+// finds the database busy is retried once after a short, jittered pause.
 func (d *baseDriver) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return d.db.ExecContext(ctx, query, args...)
+	result, err := d.writer().ExecContext(ctx, query, args...)
+	if err != nil && d.dialect == DialectSQLite && isSQLiteBusy(err) {
+		if waitErr := sleepJitter(ctx, sqliteBusyRetry); waitErr != nil {
+			return nil, err
+		}
+		return d.writer().ExecContext(ctx, query, args...)
+	}
+	return result, err
 }
 
 // Query executes a query that returns rows
@@ -131,9 +194,10 @@ func (d *baseDriver) QueryRow(ctx context.Context, query string, args ...any) *s
 	return d.db.QueryRowContext(ctx, query, args...)
 }
 
-// BeginTx starts a new transaction
+// BeginTx starts a new transaction; on SQLite file databases it holds the
+// single write connection until it commits or rolls back
 func (d *baseDriver) BeginTx(ctx context.Context) (*sql.Tx, error) {
-	return d.db.BeginTx(ctx, nil)
+	return d.writer().BeginTx(ctx, nil)
 }
 
 // Ping verifies the connection to the database is still alive
@@ -216,3 +280,63 @@ func detectDialect(connectionString string) (DialectType, string, error) {
 
 	return "", "", fmt.Errorf("unable to detect database dialect from connection string: %s", connectionString)
 }
+
+// isSQLiteMemory reports whether a SQLite DSN names an in-memory database
+func isSQLiteMemory(dsn string) bool {
+	return strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+// sqlitePath returns the file path of a SQLite DSN without its "file:"
+// scheme and query parameters
+func sqlitePath(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	return path
+}
+
+// sqliteFileDSN adds the WAL journal mode and busy timeout pragmas to a SQLite
+// file DSN; both apply to every connection the pool opens
+func sqliteFileDSN(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)", dsn, separator, sqliteBusyTimeout.Milliseconds())
+}
+
+// checkSQLiteWritable verifies that the database file, if it exists, and its
+// directory, which holds the WAL and shared-memory files, can be written.
+// SQLite would otherwise open the file read-only and fail on the first write.
+func checkSQLiteWritable(path string) error {
+	if f, err := os.OpenFile(path, os.O_RDWR, 0); err == nil {
+		f.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("sqlite database %s is not writable by uid %d: %w", path, os.Geteuid(), err)
+	}
+
+	dir := filepath.Dir(path)
+	probe, err := os.CreateTemp(dir, ".moon-write-check-*")
+	if err != nil {
+		return fmt.Errorf("sqlite database directory %s is not writable by uid %d: %w", dir, os.Geteuid(), err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// isSQLiteBusy reports whether an error is SQLITE_BUSY or SQLITE_LOCKED
+func isSQLiteBusy(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY") || strings.Contains(msg, "database table is locked")
+}
+
+// sleepJitter waits between base and twice base, or until ctx is done
+func sleepJitter(ctx context.Context, base time.Duration) error {
+	timer := time.NewTimer(base + rand.N(base))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -2,6 +2,11 @@ package database
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -382,3 +387,146 @@ func TestDriver_Close(t *testing.T) {
 		t.Errorf("Second Close() error = %v", err)
 	}
 }
+
+func TestDriver_SQLiteFile_ConcurrentWrites(t *testing.T) {
+	driver, err := NewDriver(Config{
+		ConnectionString: "sqlite://" + filepath.Join(t.TempDir(), "moon.db"),
+		MaxOpenConns:     10,
+		MaxIdleConns:     5,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer driver.Close()
+
+	var journalMode string
+	if err := driver.QueryRow(ctx, "PRAGMA journal_mode").Scan(&journalMode); err != nil || journalMode != "wal" {
+		t.Fatalf("expected WAL journal mode, got %q (%v)", journalMode, err)
+	}
+	if _, err := driver.Exec(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, worker INTEGER)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	const workers, rounds = 20, 10
+	errs := make(chan error, workers*rounds*3)
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				if _, err := driver.Exec(ctx, "INSERT INTO items (worker) VALUES (?)", worker); err != nil {
+					errs <- err
+				}
+				var count int
+				if err := driver.QueryRow(ctx, "SELECT COUNT(*) FROM items WHERE worker = ?", worker).Scan(&count); err != nil {
+					errs <- err
+				}
+				tx, err := driver.BeginTx(ctx)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				if _, err := tx.ExecContext(ctx, "INSERT INTO items (worker) VALUES (?)", worker); err != nil {
+					tx.Rollback()
+					errs <- err
+					continue
+				}
+				if err := tx.Commit(); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent access failed: %v", err)
+	}
+	var total int
+	if err := driver.QueryRow(ctx, "SELECT COUNT(*) FROM items").Scan(&total); err != nil || total != workers*rounds*2 {
+		t.Errorf("expected %d rows, got %d (%v)", workers*rounds*2, total, err)
+	}
+}
+
+func TestDriver_SQLiteFile_PoolSaturation(t *testing.T) {
+	driver, err := NewDriver(Config{
+		ConnectionString: "sqlite://" + filepath.Join(t.TempDir(), "moon.db"),
+		MaxOpenConns:     2,
+		MaxIdleConns:     2,
+		ConnMaxLifetime:  time.Minute,
+		ConnMaxIdleTime:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer driver.Close()
+
+	// Each query counts to 50000 so that it holds its connection while the
+	// other workers queue for the two the pool allows
+	const slowQuery = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 50000) SELECT SUM(i) FROM n"
+	const workers, rounds = 16, 3
+	errs := make(chan error, workers*rounds)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				var sum int64
+				if err := driver.QueryRow(ctx, slowQuery).Scan(&sum); err != nil {
+					errs <- err
+				} else if sum != 50000*50001/2 {
+					errs <- fmt.Errorf("unexpected sum %d", sum)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("saturated pool failed a query: %v", err)
+	}
+	stats := driver.DB().Stats()
+	if stats.MaxOpenConnections != 2 || stats.OpenConnections > 2 {
+		t.Errorf("expected at most 2 open connections, got %d of %d", stats.OpenConnections, stats.MaxOpenConnections)
+	}
+	if stats.WaitCount == 0 || stats.WaitDuration == 0 {
+		t.Errorf("expected queries to wait for a connection, got wait count %d over %v", stats.WaitCount, stats.WaitDuration)
+	}
+}
+
+func TestDriver_SQLiteFile_ReadonlyDirectory(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("file permissions do not apply to root")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o500); err != nil {
+		t.Fatalf("failed to chmod: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o700) })
+
+	driver, err := NewDriver(Config{ConnectionString: "sqlite://" + filepath.Join(dir, "moon.db")})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	err = driver.Connect(context.Background())
+	if err == nil {
+		driver.Close()
+		t.Fatal("expected Connect() to fail for a read-only directory")
+	}
+	if !strings.Contains(err.Error(), dir) || !strings.Contains(err.Error(), fmt.Sprintf("uid %d", os.Geteuid())) {
+		t.Errorf("expected the error to name the directory and uid, got %v", err)
+	}
+}
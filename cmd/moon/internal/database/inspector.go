@@ -13,6 +13,7 @@ import (
 type TableInfo struct {
 	Name    string
 	Columns []ColumnInfo
+	Indexes []IndexInfo
 }
 
 // ColumnInfo contains information about a table column
@@ -25,6 +26,14 @@ type ColumnInfo struct {
 	IsUnique     bool
 }
 
+// IndexInfo contains information about a secondary index.
+// Indexes backing the primary key are not reported.
+type IndexInfo struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
 // ListTables returns a list of all user tables in the database
 // Excludes system tables and internal metadata tables
 func (d *baseDriver) ListTables(ctx context.Context) ([]string, error) {
@@ -178,13 +187,146 @@ func (d *baseDriver) GetTableInfo(ctx context.Context, tableName string) (*Table
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating column rows: %w", err)
 	}
+	rows.Close()
+
+	indexes, err := d.getTableIndexes(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
 
 	return &TableInfo{
 		Name:    tableName,
 		Columns: columns,
+		Indexes: indexes,
 	}, nil
 }
 
+// getTableIndexes lists the secondary indexes of a table with their columns in key order.
+// On SQLite and PostgreSQL indexes created implicitly for UNIQUE constraints are excluded;
+// MySQL does not distinguish them from indexes created with CREATE INDEX.
+func (d *baseDriver) getTableIndexes(ctx context.Context, tableName string) ([]IndexInfo, error) {
+	if d.dialect == DialectSQLite {
+		return d.getSQLiteIndexes(ctx, tableName)
+	}
+
+	var query string
+	switch d.dialect {
+	case DialectMySQL:
+		query = `SELECT index_name, column_name, non_unique = 0
+		         FROM information_schema.statistics
+		         WHERE table_schema = DATABASE() AND table_name = ? AND index_name <> 'PRIMARY'
+		         ORDER BY index_name, seq_in_index`
+
+	case DialectPostgres:
+		query = `SELECT i.relname, a.attname, ix.indisunique
+		         FROM pg_class t
+		         JOIN pg_index ix ON t.oid = ix.indrelid
+		         JOIN pg_class i ON i.oid = ix.indexrelid
+		         JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+		         JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		         WHERE t.relname = $1 AND NOT ix.indisprimary
+		           AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid)
+		         ORDER BY i.relname, k.ord`
+
+	default:
+		return nil, fmt.Errorf("unsupported database dialect: %s", d.dialect)
+	}
+
+	rows, err := d.Query(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []IndexInfo
+	for rows.Next() {
+		var name, column string
+		var unique bool
+		if err := rows.Scan(&name, &column, &unique); err != nil {
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+		// Rows are ordered by index name, so columns of one index are adjacent
+		if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		indexes = append(indexes, IndexInfo{Name: name, Columns: []string{column}, Unique: unique})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating index rows: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// getSQLiteIndexes lists indexes created with CREATE INDEX (origin 'c')
+func (d *baseDriver) getSQLiteIndexes(ctx context.Context, tableName string) ([]IndexInfo, error) {
+	// PRAGMA index_list returns: seq, name, unique, origin, partial
+	rows, err := d.Query(ctx, fmt.Sprintf("PRAGMA index_list(%s)", tableName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+
+	var indexes []IndexInfo
+	for rows.Next() {
+		var seq, unique, partial int
+		var name, origin string
+		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+		if origin == "c" {
+			indexes = append(indexes, IndexInfo{Name: name, Unique: unique == 1})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating index rows: %w", err)
+	}
+	// Close before querying index columns; the connection pool may hold a single connection
+	rows.Close()
+
+	for i := range indexes {
+		if !isValidIdentifier(indexes[i].Name) {
+			return nil, fmt.Errorf("invalid index name: %s", indexes[i].Name)
+		}
+		columns, err := d.getSQLiteIndexColumns(ctx, indexes[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i].Columns = columns
+	}
+
+	return indexes, nil
+}
+
+// getSQLiteIndexColumns returns the columns of a SQLite index in key order
+func (d *baseDriver) getSQLiteIndexColumns(ctx context.Context, indexName string) ([]string, error) {
+	// PRAGMA index_info returns: seqno, cid, name (ordered by seqno)
+	rows, err := d.Query(ctx, fmt.Sprintf("PRAGMA index_info(%s)", indexName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var seqno, cid int
+		var name string
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan index column: %w", err)
+		}
+		columns = append(columns, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating index columns: %w", err)
+	}
+
+	return columns, nil
+}
+
 // TableExists checks if a table exists in the database
 func (d *baseDriver) TableExists(ctx context.Context, tableName string) (bool, error) {
 	tables, err := d.ListTables(ctx)
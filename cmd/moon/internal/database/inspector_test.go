@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -158,6 +159,56 @@ func TestGetTableInfo(t *testing.T) {
 	}
 }
 
+func TestGetTableInfo_Indexes(t *testing.T) {
+	cfg := Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     10,
+		MaxIdleConns:     5,
+		ConnMaxLifetime:  time.Minute * 5,
+	}
+	driver, err := NewDriver(cfg)
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer driver.Close()
+
+	statements := []string{
+		"CREATE TABLE items (pkid INTEGER PRIMARY KEY AUTOINCREMENT, id CHAR(26) NOT NULL UNIQUE, tenant_id TEXT, sku TEXT, price INTEGER)",
+		"CREATE UNIQUE INDEX idx_tenant_sku ON items (tenant_id, sku)",
+		"CREATE INDEX idx_price ON items (price)",
+	}
+	for _, stmt := range statements {
+		if _, err := driver.Exec(ctx, stmt); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+
+	info, err := driver.GetTableInfo(ctx, "items")
+	if err != nil {
+		t.Fatalf("GetTableInfo() error = %v", err)
+	}
+
+	// The automatic index behind the id UNIQUE constraint is not reported
+	if len(info.Indexes) != 2 {
+		t.Fatalf("expected 2 indexes, got %+v", info.Indexes)
+	}
+	byName := map[string]IndexInfo{}
+	for _, idx := range info.Indexes {
+		byName[idx.Name] = idx
+	}
+	if idx := byName["idx_tenant_sku"]; !idx.Unique || strings.Join(idx.Columns, ",") != "tenant_id,sku" {
+		t.Errorf("unexpected idx_tenant_sku: %+v", idx)
+	}
+	if idx := byName["idx_price"]; idx.Unique || strings.Join(idx.Columns, ",") != "price" {
+		t.Errorf("unexpected idx_price: %+v", idx)
+	}
+}
+
 func TestTableExists(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// TableStats contains storage statistics of a table
+type TableStats struct {
+	RowCount  int64
+	SizeBytes *int64 // approximate size on disk; nil when the dialect cannot report it
+}
+
+// TableStats counts the rows of a table and estimates its size on disk.
+// The row count is portable; the size comes from dialect-specific catalogs
+// and is left nil when they are unavailable rather than failing the call.
+func (d *baseDriver) TableStats(ctx context.Context, tableName string) (*TableStats, error) {
+	// Validate table name to prevent SQL injection
+	if !isValidIdentifier(tableName) {
+		return nil, fmt.Errorf("invalid table name: %s", tableName)
+	}
+
+	quoted := `"` + tableName + `"`
+	if d.dialect == DialectMySQL {
+		quoted = "`" + tableName + "`"
+	}
+
+	stats := &TableStats{}
+	if err := d.QueryRow(ctx, "SELECT COUNT(*) FROM "+quoted).Scan(&stats.RowCount); err != nil {
+		return nil, fmt.Errorf("failed to count rows of %s: %w", tableName, err)
+	}
+	if size, ok := d.tableSize(ctx, tableName); ok {
+		stats.SizeBytes = &size
+	}
+	return stats, nil
+}
+
+// tableSize returns the approximate size of a table in bytes
+func (d *baseDriver) tableSize(ctx context.Context, tableName string) (int64, bool) {
+	var size int64
+	switch d.dialect {
+	case DialectSQLite:
+		// SQLite: pages of the table b-tree from dbstat, or of the whole
+		// database when dbstat is not compiled in, times the page size
+		var pageSize, pages int64
+		if err := d.QueryRow(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+			return 0, false
+		}
+		if err := d.QueryRow(ctx, "SELECT COUNT(*) FROM dbstat WHERE name = ?", tableName).Scan(&pages); err != nil {
+			if err := d.QueryRow(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+				return 0, false
+			}
+		}
+		return pages * pageSize, true
+
+	case DialectPostgres:
+		// PostgreSQL: table, indexes and TOAST data
+		if err := d.QueryRow(ctx, "SELECT pg_total_relation_size(quote_ident($1))", tableName).Scan(&size); err != nil {
+			return 0, false
+		}
+		return size, true
+
+	case DialectMySQL:
+		// MySQL: data and index length estimated by the storage engine
+		query := `SELECT COALESCE(data_length + index_length, 0)
+		          FROM information_schema.tables
+		          WHERE table_schema = DATABASE() AND table_name = ?`
+		if err := d.QueryRow(ctx, query, tableName).Scan(&size); err != nil {
+			return 0, false
+		}
+		return size, true
+	}
+
+	return 0, false
+}
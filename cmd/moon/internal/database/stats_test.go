@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTableStats_SQLite(t *testing.T) {
+	ctx := context.Background()
+	driver, err := NewDriver(Config{ConnectionString: "sqlite://:memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer driver.Close()
+
+	if _, err := driver.Exec(ctx, "CREATE TABLE notes (id TEXT, title TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for _, title := range []string{"a", "b", "c"} {
+		if _, err := driver.Exec(ctx, "INSERT INTO notes (id, title) VALUES (?, ?)", title, title); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	stats, err := driver.TableStats(ctx, "notes")
+	if err != nil {
+		t.Fatalf("TableStats failed: %v", err)
+	}
+	if stats.RowCount != 3 {
+		t.Errorf("Expected 3 rows, got %d", stats.RowCount)
+	}
+	if stats.SizeBytes == nil || *stats.SizeBytes <= 0 {
+		t.Errorf("Expected a positive size, got %v", stats.SizeBytes)
+	}
+
+	if _, err := driver.TableStats(ctx, "notes; DROP TABLE notes"); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// UniqueViolation describes a write that failed because it would duplicate a
+// value of a unique column or index. Drivers report different parts of it.
+type UniqueViolation struct {
+	Constraint string   // constraint or index name (Postgres, MySQL)
+	Columns    []string // columns of the constraint (SQLite, Postgres)
+	Value      string   // duplicated value as text (Postgres, MySQL)
+}
+
+const (
+	postgresUniqueViolation = "23505" // SQLSTATE unique_violation
+	mysqlDuplicateEntry     = 1062    // ER_DUP_ENTRY
+)
+
+var (
+	// sqliteUniqueRegex matches "UNIQUE constraint failed: users.email", with
+	// a comma-separated list for a composite index and an optional " (2067)"
+	// extended result code
+	sqliteUniqueRegex = regexp.MustCompile(`UNIQUE constraint failed: ([^()]+?)(?: \(\d+\))?$`)
+
+	// postgresKeyRegex matches the detail "Key (email)=(x@y.z) already exists."
+	postgresKeyRegex = regexp.MustCompile(`^Key \((.+)\)=\((.*)\) already exists\.?$`)
+
+	// mysqlDuplicateRegex matches "Duplicate entry 'x@y.z' for key 'users.email'"
+	mysqlDuplicateRegex = regexp.MustCompile(`^Duplicate entry '(.*)' for key '(.+)'$`)
+)
+
+// AsUniqueViolation reports whether err is a unique constraint violation and
+// describes it from the SQLite message, the Postgres SQLSTATE 23505 error or
+// the MySQL error 1062. Other errors mentioning a unique constraint are
+// violations without details.
+func AsUniqueViolation(err error) (*UniqueViolation, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code != postgresUniqueViolation {
+			return nil, false
+		}
+		v := &UniqueViolation{Constraint: pqErr.Constraint}
+		if m := postgresKeyRegex.FindStringSubmatch(pqErr.Detail); m != nil {
+			v.Columns = splitColumns(m[1])
+			v.Value = m[2]
+		}
+		return v, true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		if mysqlErr.Number != mysqlDuplicateEntry {
+			return nil, false
+		}
+		v := &UniqueViolation{}
+		if m := mysqlDuplicateRegex.FindStringSubmatch(mysqlErr.Message); m != nil {
+			v.Value = m[1]
+			// MySQL 8 qualifies the key with the table name
+			v.Constraint = m[2][strings.LastIndex(m[2], ".")+1:]
+		}
+		return v, true
+	}
+
+	msg := err.Error()
+	if m := sqliteUniqueRegex.FindStringSubmatch(msg); m != nil {
+		var columns []string
+		for _, column := range splitColumns(m[1]) {
+			// Columns are qualified with the table name
+			columns = append(columns, column[strings.LastIndex(column, ".")+1:])
+		}
+		return &UniqueViolation{Columns: columns}, true
+	}
+	if strings.Contains(msg, "UNIQUE") || strings.Contains(msg, "unique") {
+		return &UniqueViolation{}, true
+	}
+	return nil, false
+}
+
+// splitColumns splits a comma-separated column list, unquoting each name
+func splitColumns(list string) []string {
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		columns = append(columns, strings.Trim(strings.TrimSpace(column), "\"`"))
+	}
+	return columns
+}
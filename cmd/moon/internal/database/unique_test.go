@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestAsUniqueViolation_SQLite(t *testing.T) {
+	ctx := context.Background()
+	driver, err := NewDriver(Config{ConnectionString: "sqlite://" + filepath.Join(t.TempDir(), "unique.db")})
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer driver.Close()
+
+	for _, stmt := range []string{
+		"CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT UNIQUE, org TEXT, name TEXT)",
+		"CREATE UNIQUE INDEX idx_users_org_name ON users (org, name)",
+		"INSERT INTO users VALUES ('1', 'ada@example.com', 'acme', 'ada')",
+	} {
+		if _, err := driver.Exec(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	tests := []struct {
+		stmt    string
+		columns []string
+	}{
+		{"INSERT INTO users VALUES ('2', 'ada@example.com', 'acme', 'bob')", []string{"email"}},
+		{"INSERT INTO users VALUES ('1', 'bob@example.com', 'acme', 'bob')", []string{"id"}},
+		{"INSERT INTO users VALUES ('2', 'bob@example.com', 'acme', 'ada')", []string{"org", "name"}},
+	}
+	for _, tt := range tests {
+		_, err := driver.Exec(ctx, tt.stmt)
+		v, ok := AsUniqueViolation(err)
+		if !ok {
+			t.Errorf("%s: expected a unique violation, got %v", tt.stmt, err)
+			continue
+		}
+		if !slices.Equal(v.Columns, tt.columns) {
+			t.Errorf("%s: expected columns %v, got %v (%v)", tt.stmt, tt.columns, v.Columns, err)
+		}
+	}
+}
+
+func TestAsUniqueViolation_Drivers(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *UniqueViolation
+	}{
+		{
+			name: "postgres",
+			err: &pq.Error{Code: "23505", Constraint: "users_email_key",
+				Detail: "Key (email)=(ada@example.com) already exists."},
+			want: &UniqueViolation{Constraint: "users_email_key", Columns: []string{"email"}, Value: "ada@example.com"},
+		},
+		{
+			name: "postgres composite",
+			err: &pq.Error{Code: "23505", Constraint: "idx_users_org_name",
+				Detail: `Key (org, "name")=(acme, ada) already exists.`},
+			want: &UniqueViolation{Constraint: "idx_users_org_name", Columns: []string{"org", "name"}, Value: "acme, ada"},
+		},
+		{
+			name: "mysql 8",
+			err:  fmt.Errorf("exec: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'ada@example.com' for key 'users.email'"}),
+			want: &UniqueViolation{Constraint: "email", Value: "ada@example.com"},
+		},
+		{
+			name: "mysql 5.7",
+			err:  &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'PRIMARY'"},
+			want: &UniqueViolation{Constraint: "PRIMARY", Value: "x"},
+		},
+		{
+			name: "other text",
+			err:  errors.New("pq: duplicate key value violates unique constraint"),
+			want: &UniqueViolation{},
+		},
+		{name: "postgres not null", err: &pq.Error{Code: "23502"}},
+		{name: "mysql other", err: &mysql.MySQLError{Number: 1048, Message: "Column 'email' cannot be null"}},
+		{name: "plain", err: errors.New("database is locked")},
+		{name: "nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, ok := AsUniqueViolation(tt.err)
+			if ok != (tt.want != nil) {
+				t.Fatalf("expected violation %v, got %v", tt.want != nil, ok)
+			}
+			if tt.want == nil {
+				return
+			}
+			if v.Constraint != tt.want.Constraint || v.Value != tt.want.Value || !slices.Equal(v.Columns, tt.want.Columns) {
+				t.Errorf("expected %+v, got %+v", tt.want, v)
+			}
+		})
+	}
+}
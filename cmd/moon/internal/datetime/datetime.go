@@ -0,0 +1,133 @@
+// Package datetime normalizes datetime values to the single format Moon
+// stores: RFC3339 in UTC with second precision (e.g. "2024-06-01T10:00:00Z").
+// SQLite keeps datetime columns as TEXT, so one fixed-width format is what
+// makes lexicographic comparison and sorting agree with time order.
+package datetime
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout is the storage and response format of datetime values
+const Layout = time.RFC3339
+
+// dateLayout is the short date accepted as midnight UTC
+const dateLayout = "2006-01-02"
+
+// legacyLayouts are unambiguous formats without a zone accepted by
+// ParseLegacy; their values are taken as UTC
+var legacyLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// legacyZonedLayouts are unambiguous formats with a zone accepted by ParseLegacy
+var legacyZonedLayouts = []string{
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02T15:04:05-0700",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// slashDateRegex matches day and month separated by slashes with a four-digit
+// year and an optional time, e.g. "06/13/2024 10:00"
+var slashDateRegex = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/(\d{4})(?:[ T](\d{1,2}):(\d{2})(?::(\d{2}))?)?$`)
+
+// Format returns t in the storage format
+func Format(t time.Time) string {
+	return t.UTC().Format(Layout)
+}
+
+// Parse parses a datetime accepted on input: RFC3339 with any offset and
+// optional fractional seconds, or a short date "2024-06-01" meaning midnight UTC
+func Parse(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not an RFC3339 datetime (e.g. '2024-06-01T10:00:00Z') or a date (e.g. '2024-06-01')", value)
+}
+
+// Normalize parses a datetime accepted on input and returns it in the storage
+// format. Fractional seconds are dropped.
+func Normalize(value string) (string, error) {
+	t, err := Parse(value)
+	if err != nil {
+		return "", err
+	}
+	return Format(t), nil
+}
+
+// ParseLegacy parses values written before datetime input was enforced. Besides
+// the input formats it accepts common formats whose meaning is unambiguous;
+// values without a zone are taken as UTC. Slash dates are only accepted when
+// the day is greater than 12 or equal to the month, so "06/01/2024" is
+// rejected while "13/06/2024" and "06/13/2024" are read as June 13.
+func ParseLegacy(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if t, err := Parse(value); err == nil {
+		return t, true
+	}
+	for _, layout := range legacyLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	for _, layout := range legacyZonedLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return parseSlashDate(value)
+}
+
+// parseSlashDate parses a slash date whose day and month order can be told apart
+func parseSlashDate(value string) (time.Time, bool) {
+	m := slashDateRegex.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, false
+	}
+	first, _ := strconv.Atoi(m[1])
+	second, _ := strconv.Atoi(m[2])
+	year, _ := strconv.Atoi(m[3])
+
+	var day, month int
+	switch {
+	case first == second:
+		day, month = first, second
+	case first > 12 && second <= 12:
+		day, month = first, second
+	case second > 12 && first <= 12:
+		day, month = second, first
+	default:
+		return time.Time{}, false
+	}
+
+	var hour, minute, sec int
+	if m[4] != "" {
+		hour, _ = strconv.Atoi(m[4])
+		minute, _ = strconv.Atoi(m[5])
+		if m[6] != "" {
+			sec, _ = strconv.Atoi(m[6])
+		}
+	}
+	if month < 1 || hour > 23 || minute > 59 || sec > 59 {
+		return time.Time{}, false
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, sec, 0, time.UTC)
+	// time.Date normalizes out-of-range days such as 31/02 into the next month
+	if t.Day() != day || int(t.Month()) != month {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,91 @@
+package datetime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"2024-06-01T10:00:00Z", "2024-06-01T10:00:00Z", false},
+		{"2024-06-01T12:30:00+02:30", "2024-06-01T10:00:00Z", false},
+		{"2024-06-01T03:00:00-07:00", "2024-06-01T10:00:00Z", false},
+		{"2024-06-01T10:00:00.123456Z", "2024-06-01T10:00:00Z", false},
+		{"2024-06-01", "2024-06-01T00:00:00Z", false},
+		{"06/01/2024 10:00", "", true},
+		{"2024-06-01 10:00:00", "", true},
+		{"2024-06-01T10:00:00", "", true},
+		{"2024-13-01", "", true},
+		{"yesterday", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Normalize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize_SortsLikeTime(t *testing.T) {
+	// 09:30Z, 10:00Z and 10:30Z written with different offsets
+	later, _ := Normalize("2024-06-01T12:30:00+02:00")
+	earlier, _ := Normalize("2024-06-01T05:30:00-04:00")
+	middle, _ := Normalize("2024-06-01T10:00:00Z")
+	if !(earlier < middle && middle < later) {
+		t.Errorf("expected %s < %s < %s", earlier, middle, later)
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"2024-06-01T10:00:00+02:00", "2024-06-01T08:00:00Z", true},
+		{"2024-06-01 10:00:00", "2024-06-01T10:00:00Z", true},
+		{"2024-06-01T10:00:00", "2024-06-01T10:00:00Z", true},
+		{"2024-06-01 10:00", "2024-06-01T10:00:00Z", true},
+		{" 2024-06-01 ", "2024-06-01T00:00:00Z", true},
+		{"2024-06-01 10:00:00+02:00", "2024-06-01T08:00:00Z", true},
+		{"Sat, 01 Jun 2024 10:00:00 +0000", "2024-06-01T10:00:00Z", true},
+		{"13/06/2024", "2024-06-13T00:00:00Z", true},
+		{"06/13/2024 10:00", "2024-06-13T10:00:00Z", true},
+		{"06/06/2024 10:00:30", "2024-06-06T10:00:30Z", true},
+		{"06/01/2024 10:00", "", false}, // June 1 or January 6
+		{"31/02/2024", "", false},
+		{"13/13/2024", "", false},
+		{"06/13/2024 25:00", "", false},
+		{"next tuesday", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseLegacy(tt.input)
+			if ok != tt.ok {
+				t.Fatalf("ParseLegacy(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+			}
+			if ok && Format(got) != tt.want {
+				t.Errorf("ParseLegacy(%q) = %s, want %s", tt.input, Format(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	if got := Format(time.Date(2024, 6, 1, 15, 30, 0, 500, loc)); got != "2024-06-01T10:00:00Z" {
+		t.Errorf("Format() = %s, want 2024-06-01T10:00:00Z", got)
+	}
+}
@@ -108,13 +108,9 @@ func (d Decimal) StringWithScale(scale int) string {
 }
 
 // formatWithScale formats a big.Rat to a string with fixed scale.
+// The value is rounded exactly, with halves rounded away from zero.
 func formatWithScale(rat *big.Rat, scale int) string {
-	// Get the float representation
-	f, _ := rat.Float64()
-
-	// Format with the required scale
-	format := fmt.Sprintf("%%.%df", scale)
-	return fmt.Sprintf(format, f)
+	return rat.FloatString(scale)
 }
 
 // Float64 returns the float64 approximation of the Decimal.
@@ -275,6 +271,8 @@ func (d *Decimal) UnmarshalJSON(data []byte) error {
 }
 
 // Scan implements sql.Scanner for database reads.
+// Stored values are not limited to a scale, since aggregates such as AVG
+// return more digits than the column holds.
 func (d *Decimal) Scan(value any) error {
 	if value == nil {
 		d.rat = big.NewRat(0, 1)
@@ -283,19 +281,9 @@ func (d *Decimal) Scan(value any) error {
 
 	switch v := value.(type) {
 	case string:
-		parsed, err := ParseDecimalWithScale(v, constants.MaxDecimalScale)
-		if err != nil {
-			return fmt.Errorf("failed to scan decimal: %w", err)
-		}
-		d.rat = parsed.rat
-		return nil
+		return d.scanString(v)
 	case []byte:
-		parsed, err := ParseDecimalWithScale(string(v), constants.MaxDecimalScale)
-		if err != nil {
-			return fmt.Errorf("failed to scan decimal: %w", err)
-		}
-		d.rat = parsed.rat
-		return nil
+		return d.scanString(string(v))
 	case int64:
 		d.rat = big.NewRat(v, 1)
 		return nil
@@ -307,6 +295,20 @@ func (d *Decimal) Scan(value any) error {
 	}
 }
 
+// scanString parses a decimal string read from the database
+func (d *Decimal) scanString(s string) error {
+	s = strings.TrimSpace(s)
+	if !decimalRegex.MatchString(s) {
+		return fmt.Errorf("failed to scan decimal: invalid value '%s'", s)
+	}
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return fmt.Errorf("failed to scan decimal: invalid value '%s'", s)
+	}
+	d.rat = rat
+	return nil
+}
+
 // Value implements driver.Valuer for database writes.
 // Returns the string representation for database storage.
 func (d Decimal) Value() (driver.Value, error) {
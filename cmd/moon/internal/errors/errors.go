@@ -6,84 +6,225 @@ package errors
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"runtime/debug"
 	"strings"
+	"sync/atomic"
 
 	"github.com/google/uuid"
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
 )
 
-// ErrorCode represents a standard error code
+// ErrorCode is a stable, machine-readable error code. Codes are lowercase
+// snake_case and never change once released; messages may.
 type ErrorCode string
 
 const (
 	// Validation errors (PRD-049)
-	CodeValidationFailed      ErrorCode = "VALIDATION_ERROR"
-	CodeValidationError       ErrorCode = "VALIDATION_ERROR" // alias
-	CodeInvalidInput          ErrorCode = "INVALID_INPUT"
-	CodeMissingField          ErrorCode = "MISSING_FIELD"
-	CodeRequiredField         ErrorCode = "REQUIRED_FIELD"
-	CodeInvalidType           ErrorCode = "INVALID_TYPE"
-	CodeInvalidJSON           ErrorCode = "INVALID_JSON"
-	CodeInvalidULID           ErrorCode = "INVALID_ULID"
-	CodeInvalidCursor         ErrorCode = "INVALID_CURSOR"
-	CodePageSizeExceeded      ErrorCode = "PAGE_SIZE_EXCEEDED"
-	CodeFiltersExceeded       ErrorCode = "FILTERS_EXCEEDED"
-	CodeSortFieldsExceeded    ErrorCode = "SORT_FIELDS_EXCEEDED"
-	CodeCollectionNameInvalid ErrorCode = "COLLECTION_NAME_INVALID"
-	CodeColumnNameInvalid     ErrorCode = "COLUMN_NAME_INVALID"
-	CodeReservedName          ErrorCode = "RESERVED_NAME"
-	CodeDeprecatedType        ErrorCode = "DEPRECATED_TYPE"
+	CodeValidationFailed      ErrorCode = "validation_failed"
+	CodeValidationError       ErrorCode = "validation_failed" // alias
+	CodeUnknownField          ErrorCode = "validation_unknown_field"
+	CodeRequiredField         ErrorCode = "validation_required_field"
+	CodeMissingField          ErrorCode = "validation_required_field" // alias
+	CodeNullField             ErrorCode = "validation_null_field"
+	CodeReadOnlyField         ErrorCode = "validation_read_only_field"
+	CodeInvalidType           ErrorCode = "validation_invalid_type"
+	CodeInvalidFieldValue     ErrorCode = "validation_invalid_value"
+	CodeInvalidInput          ErrorCode = "invalid_input"
+	CodeInvalidJSON           ErrorCode = "invalid_json"
+	CodeInvalidULID           ErrorCode = "invalid_ulid"
+	CodeInvalidID             ErrorCode = "invalid_id"
+	CodeInvalidReference      ErrorCode = "invalid_reference"
+	CodeInvalidCursor         ErrorCode = "invalid_cursor"
+	CodeInvalidFilter         ErrorCode = "invalid_filter"
+	CodeInvalidSort           ErrorCode = "invalid_sort"
+	CodeInvalidParameter      ErrorCode = "invalid_parameter"
+	CodeUnknownParameter      ErrorCode = "unknown_parameter"
+	CodeInvalidRevision       ErrorCode = "invalid_revision"
+	CodeInvalidSchema         ErrorCode = "invalid_schema"
+	CodePageSizeExceeded      ErrorCode = "page_size_exceeded"
+	CodeFiltersExceeded       ErrorCode = "filters_exceeded"
+	CodeSortFieldsExceeded    ErrorCode = "sort_fields_exceeded"
+	CodeCollectionNameInvalid ErrorCode = "invalid_collection_name"
+	CodeColumnNameInvalid     ErrorCode = "invalid_column_name"
+	CodeReservedName          ErrorCode = "reserved_name"
+	CodeDeprecatedType        ErrorCode = "deprecated_type"
+	CodeBatchTooLarge         ErrorCode = "batch_too_large"
+	CodeBulkDeleteTooLarge    ErrorCode = "bulk_delete_too_large"
+	CodePayloadTooLarge       ErrorCode = "payload_too_large"
 
 	// Authentication errors
-	CodeUnauthorized  ErrorCode = "UNAUTHORIZED"
-	CodeInvalidToken  ErrorCode = "INVALID_TOKEN"
-	CodeTokenExpired  ErrorCode = "TOKEN_EXPIRED"
-	CodeMissingToken  ErrorCode = "MISSING_TOKEN"
-	CodeInvalidAPIKey ErrorCode = "INVALID_API_KEY"
-	CodeMissingAPIKey ErrorCode = "MISSING_API_KEY"
+	CodeUnauthorized           ErrorCode = "unauthorized"
+	CodeAuthenticationRequired ErrorCode = "authentication_required"
+	CodeInvalidCredentials     ErrorCode = "invalid_credentials"
+	CodeInvalidToken           ErrorCode = "invalid_token"
+	CodeInvalidTokenFormat     ErrorCode = "invalid_token_format"
+	CodeTokenExpired           ErrorCode = "token_expired"
+	CodeTokenRevoked           ErrorCode = "token_revoked"
+	CodeMissingToken           ErrorCode = "missing_token"
+	CodeInvalidAPIKey          ErrorCode = "invalid_api_key"
+	CodeMissingAPIKey          ErrorCode = "missing_api_key"
+	CodeAPIKeyExpired          ErrorCode = "api_key_expired"
+	CodeAPIKeyRevoked          ErrorCode = "api_key_revoked"
 
 	// Authorization errors
-	CodeForbidden               ErrorCode = "FORBIDDEN"
-	CodeInsufficientPermissions ErrorCode = "INSUFFICIENT_PERMISSIONS"
+	CodeForbidden               ErrorCode = "forbidden"
+	CodeInsufficientPermissions ErrorCode = "insufficient_permissions"
+	CodeAdminRequired           ErrorCode = "admin_required"
+	CodeInsufficientScope       ErrorCode = "insufficient_scope"
+	CodeOriginNotAllowed        ErrorCode = "origin_not_allowed"
+
+	// User and API key management errors
+	CodeWeakPassword          ErrorCode = "weak_password"
+	CodeInvalidEmailFormat    ErrorCode = "invalid_email_format"
+	CodeInvalidRole           ErrorCode = "invalid_role"
+	CodeInvalidKeyName        ErrorCode = "invalid_key_name"
+	CodeInvalidAction         ErrorCode = "invalid_action"
+	CodeInvalidScope          ErrorCode = "invalid_scope"
+	CodeInvalidTenant         ErrorCode = "invalid_tenant"
+	CodeCannotModifySelf      ErrorCode = "cannot_modify_self"
+	CodeCannotDeleteLastAdmin ErrorCode = "cannot_delete_last_admin"
+	CodeUserNotFound          ErrorCode = "user_not_found"
+	CodeUsernameExists        ErrorCode = "username_exists"
+	CodeEmailExists           ErrorCode = "email_exists"
+	CodeAPIKeyNotFound        ErrorCode = "api_key_not_found"
+	CodeAPIKeyNameExists      ErrorCode = "api_key_name_exists"
 
 	// Resource errors (PRD-049)
-	CodeNotFound              ErrorCode = "NOT_FOUND"
-	CodeResourceNotFound      ErrorCode = "RESOURCE_NOT_FOUND"
-	CodeCollectionNotFound    ErrorCode = "COLLECTION_NOT_FOUND"
-	CodeRecordNotFound        ErrorCode = "RECORD_NOT_FOUND"
-	CodeAlreadyExists         ErrorCode = "ALREADY_EXISTS"
-	CodeConflict              ErrorCode = "CONFLICT"
-	CodeDuplicateCollection   ErrorCode = "DUPLICATE_COLLECTION"
-	CodeUniqueViolation       ErrorCode = "UNIQUE_CONSTRAINT_VIOLATION"
-	CodeMaxCollectionsReached ErrorCode = "MAX_COLLECTIONS_REACHED"
-	CodeMaxColumnsReached     ErrorCode = "MAX_COLUMNS_REACHED"
+	CodeNotFound              ErrorCode = "not_found"
+	CodeUnknownAction         ErrorCode = "unknown_action"
+	CodeResourceNotFound      ErrorCode = "resource_not_found"
+	CodeCollectionNotFound    ErrorCode = "collection_not_found"
+	CodeCollectionArchived    ErrorCode = "collection_archived"
+	CodeRecordNotFound        ErrorCode = "record_not_found"
+	CodeAlreadyExists         ErrorCode = "already_exists"
+	CodeConflict              ErrorCode = "conflict"
+	CodeDuplicateCollection   ErrorCode = "collection_exists"
+	CodeUniqueViolation       ErrorCode = "unique_violation"
+	CodeRevisionConflict      ErrorCode = "revision_conflict"
+	CodeRevisionRequired      ErrorCode = "revision_required"
+	CodeMaxCollectionsReached ErrorCode = "max_collections_reached"
+	CodeMaxColumnsReached     ErrorCode = "max_columns_reached"
+	CodeIncompatibleData      ErrorCode = "incompatible_data"
+	CodeIdempotencyKeyReuse   ErrorCode = "idempotency_key_reuse"
+	CodeJobNotFound           ErrorCode = "job_not_found"
+	CodeJobNotResumable       ErrorCode = "job_not_resumable"
+	CodeChecksumMismatch      ErrorCode = "checksum_mismatch"
 
 	// Server errors (PRD-049)
-	CodeInternalError      ErrorCode = "INTERNAL_ERROR"
-	CodeDatabaseError      ErrorCode = "DATABASE_ERROR"
-	CodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
-	CodeQueryTimeout       ErrorCode = "QUERY_TIMEOUT"
+	CodeInternalError      ErrorCode = "internal_error"
+	CodeDatabaseError      ErrorCode = "database_error"
+	CodeServiceUnavailable ErrorCode = "service_unavailable"
+	CodeQueryTimeout       ErrorCode = "query_timeout"
+	CodeNotImplemented     ErrorCode = "not_implemented"
 
 	// Request errors
-	CodeBadRequest        ErrorCode = "BAD_REQUEST"
-	CodeMethodNotAllowed  ErrorCode = "METHOD_NOT_ALLOWED"
-	CodeTooManyRequests   ErrorCode = "TOO_MANY_REQUESTS"
-	CodeRateLimitExceeded ErrorCode = "RATE_LIMIT_EXCEEDED"
+	CodeBadRequest            ErrorCode = "bad_request"
+	CodeMethodNotAllowed      ErrorCode = "method_not_allowed"
+	CodeUnsupportedAPIVersion ErrorCode = "unsupported_api_version"
+	CodeUnsupportedEncoding   ErrorCode = "unsupported_encoding"
+	CodeTooManyRequests       ErrorCode = "too_many_requests"
+	CodeRateLimitExceeded     ErrorCode = "rate_limit_exceeded"
+	CodeLoginRateLimited      ErrorCode = "login_rate_limited"
 )
 
+// ErrorDetail is the error object of a response
+type ErrorDetail struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
 // ErrorResponse represents the standard error response format
 type ErrorResponse struct {
-	Error     string         `json:"error"`
-	Code      int            `json:"code"`
-	ErrorCode ErrorCode      `json:"error_code,omitempty"`
+	Error     ErrorDetail    `json:"error"`
+	Code      ErrorCode      `json:"code"`
+	Status    int            `json:"status"`
 	Details   map[string]any `json:"details,omitempty"`
 	RequestID string         `json:"request_id,omitempty"`
 }
 
+// legacyFormat selects the legacy error shape, see SetLegacyFormat
+var legacyFormat atomic.Bool
+
+// SetLegacyFormat switches every error response to the legacy shape
+// {"error": "message", "code": 409, "error_code": "unique_violation"}, where
+// code is the HTTP status. It is driven by server.legacy_errors and will be
+// removed in the next release.
+func SetLegacyFormat(enabled bool) {
+	legacyFormat.Store(enabled)
+}
+
+// Body returns the JSON payload of an error response in API version 1:
+//
+//	{"error": {"code": "unique_violation", "message": "..."}, "code": "unique_violation", "status": 409}
+//
+// or the legacy shape when SetLegacyFormat is enabled. A non-empty request ID
+// is added as request_id. Callers may add further top-level fields.
+func Body(statusCode int, code ErrorCode, message, requestID string) map[string]any {
+	return VersionedBody(apiversion.V1, statusCode, code, message, requestID)
+}
+
+// VersionedBody returns the JSON payload of an error response in the given
+// API version. From version 2 the error object holds every field and the
+// legacy shape no longer applies:
+//
+//	{"error": {"code": "unique_violation", "message": "...", "status": 409, "request_id": "..."}}
+func VersionedBody(version apiversion.Version, statusCode int, code ErrorCode, message, requestID string) map[string]any {
+	if version >= apiversion.V2 {
+		detail := map[string]any{
+			"code":    code,
+			"message": message,
+			"status":  statusCode,
+		}
+		if requestID != "" {
+			detail["request_id"] = requestID
+		}
+		return map[string]any{"error": detail}
+	}
+
+	var body map[string]any
+	if legacyFormat.Load() {
+		body = map[string]any{
+			"error":      message,
+			"code":       statusCode,
+			"error_code": code,
+		}
+	} else {
+		body = map[string]any{
+			"error":  ErrorDetail{Code: code, Message: message},
+			"code":   code,
+			"status": statusCode,
+		}
+	}
+	if requestID != "" {
+		body["request_id"] = requestID
+	}
+	return body
+}
+
+// SetField adds a field to an error payload built by VersionedBody. From
+// version 2 it goes into the error object, before that next to the error.
+func SetField(body map[string]any, key string, value any) {
+	if detail, ok := body["error"].(map[string]any); ok {
+		detail[key] = value
+		return
+	}
+	body[key] = value
+}
+
+// CodeOf returns the code of an APIError in the chain of err, or fallback
+func CodeOf(err error, fallback ErrorCode) ErrorCode {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode
+	}
+	return fallback
+}
+
 // APIError represents an application error
 type APIError struct {
 	Message    string
@@ -129,6 +270,11 @@ func NewAPIError(statusCode int, errorCode ErrorCode, message string) *APIError
 	}
 }
 
+// Newf creates a new API error with a formatted message
+func Newf(statusCode int, errorCode ErrorCode, format string, args ...any) *APIError {
+	return NewAPIError(statusCode, errorCode, fmt.Sprintf(format, args...))
+}
+
 // Predefined errors for common scenarios
 
 // NewBadRequestError creates a 400 Bad Request error
@@ -238,24 +384,20 @@ func (h *ErrorHandler) RecoveryMiddleware(next http.HandlerFunc) http.HandlerFun
 func (h *ErrorHandler) WriteError(w http.ResponseWriter, r *http.Request, err *APIError) {
 	requestID := GetRequestID(r)
 
-	response := ErrorResponse{
-		Error:     err.Message,
-		Code:      err.StatusCode,
-		ErrorCode: err.ErrorCode,
-		RequestID: requestID,
-	}
+	response := VersionedBody(apiversion.FromContext(r.Context()), err.StatusCode, err.ErrorCode, err.Message, requestID)
 
 	// Add details if present
-	if err.Details != nil {
-		response.Details = err.Details
-	}
+	details := err.Details
 
 	// In development, include wrapped error details
 	if h.config.ShowInternalErrors && err.Err != nil {
-		if response.Details == nil {
-			response.Details = make(map[string]any)
+		if details == nil {
+			details = make(map[string]any)
 		}
-		response.Details["internal_error"] = err.Err.Error()
+		details["internal_error"] = err.Err.Error()
+	}
+	if details != nil {
+		response["details"] = details
 	}
 
 	// Log the error
@@ -371,7 +513,7 @@ func MapDatabaseError(err error) *APIError {
 
 	// Duplicate key / unique constraint
 	if containsAny(errStr, constants.DuplicateKeyPatterns) {
-		return NewConflictError("Resource already exists")
+		return NewAPIError(http.StatusConflict, CodeUniqueViolation, "Resource already exists")
 	}
 
 	// Foreign key constraint
@@ -381,7 +523,7 @@ func MapDatabaseError(err error) *APIError {
 
 	// Not null constraint
 	if contains(errStr, "not null", "NOT NULL") {
-		return NewBadRequestError("Required field is missing")
+		return NewAPIError(http.StatusBadRequest, CodeRequiredField, "Required field is missing")
 	}
 
 	// Connection error
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
 )
 
 func TestNewAPIError(t *testing.T) {
@@ -222,12 +225,16 @@ func TestErrorHandler_WriteError(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if response.Error != "Test error" {
-		t.Errorf("Expected error 'Test error', got '%s'", response.Error)
+	if response.Error.Message != "Test error" || response.Error.Code != CodeBadRequest {
+		t.Errorf("Expected error {bad_request, 'Test error'}, got %+v", response.Error)
+	}
+
+	if response.Code != CodeBadRequest {
+		t.Errorf("Expected code %s, got %s", CodeBadRequest, response.Code)
 	}
 
-	if response.Code != http.StatusBadRequest {
-		t.Errorf("Expected code %d, got %d", http.StatusBadRequest, response.Code)
+	if response.Status != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, response.Status)
 	}
 
 	if response.RequestID != "test-request-id" {
@@ -327,8 +334,8 @@ func TestErrorHandler_RecoveryMiddleware(t *testing.T) {
 	var response ErrorResponse
 	json.NewDecoder(w.Body).Decode(&response)
 
-	if response.Error != "Internal server error" {
-		t.Errorf("Expected error 'Internal server error', got '%s'", response.Error)
+	if response.Error.Message != "Internal server error" || response.Code != CodeInternalError {
+		t.Errorf("Expected internal_error 'Internal server error', got %+v", response.Error)
 	}
 }
 
@@ -476,7 +483,7 @@ func TestMapDatabaseError(t *testing.T) {
 			name:           "Duplicate key",
 			err:            errors.New("UNIQUE constraint failed: users.email"),
 			expectedStatus: http.StatusConflict,
-			expectedCode:   CodeConflict,
+			expectedCode:   CodeUniqueViolation,
 		},
 		{
 			name:           "Foreign key",
@@ -488,7 +495,7 @@ func TestMapDatabaseError(t *testing.T) {
 			name:           "Not null",
 			err:            errors.New("NOT NULL constraint failed"),
 			expectedStatus: http.StatusBadRequest,
-			expectedCode:   CodeBadRequest,
+			expectedCode:   CodeRequiredField,
 		},
 		{
 			name:           "Connection refused",
@@ -566,3 +573,74 @@ func TestErrorHandler_OnError(t *testing.T) {
 		t.Error("Captured error should not be nil")
 	}
 }
+
+func TestBody(t *testing.T) {
+	body := Body(http.StatusConflict, CodeUniqueViolation, "duplicate email", "req-1")
+
+	detail, ok := body["error"].(ErrorDetail)
+	if !ok || detail.Code != CodeUniqueViolation || detail.Message != "duplicate email" {
+		t.Errorf("Expected error object {unique_violation, duplicate email}, got %v", body["error"])
+	}
+	if body["code"] != CodeUniqueViolation || body["status"] != http.StatusConflict {
+		t.Errorf("Expected code unique_violation and status 409, got %v and %v", body["code"], body["status"])
+	}
+	if body["request_id"] != "req-1" {
+		t.Errorf("Expected request_id req-1, got %v", body["request_id"])
+	}
+	if _, ok := Body(http.StatusConflict, CodeConflict, "conflict", "")["request_id"]; ok {
+		t.Error("request_id should be omitted when empty")
+	}
+}
+
+func TestBody_LegacyFormat(t *testing.T) {
+	SetLegacyFormat(true)
+	defer SetLegacyFormat(false)
+
+	body := Body(http.StatusConflict, CodeUniqueViolation, "duplicate email", "")
+	if body["error"] != "duplicate email" || body["code"] != http.StatusConflict || body["error_code"] != CodeUniqueViolation {
+		t.Errorf("Expected legacy shape, got %v", body)
+	}
+	if _, ok := body["status"]; ok {
+		t.Error("legacy shape should not carry status")
+	}
+}
+
+func TestVersionedBody_V2(t *testing.T) {
+	// v2 ignores the legacy format
+	SetLegacyFormat(true)
+	defer SetLegacyFormat(false)
+
+	body := VersionedBody(apiversion.V2, http.StatusConflict, CodeUniqueViolation, "duplicate email", "req-1")
+	if len(body) != 1 {
+		t.Errorf("Expected only the error object, got %v", body)
+	}
+	detail, ok := body["error"].(map[string]any)
+	if !ok || detail["code"] != CodeUniqueViolation || detail["message"] != "duplicate email" ||
+		detail["status"] != http.StatusConflict || detail["request_id"] != "req-1" {
+		t.Errorf("Expected a complete error object, got %v", body["error"])
+	}
+
+	SetField(body, "current_rev", 3)
+	if detail["current_rev"] != 3 {
+		t.Errorf("Expected SetField to extend the error object, got %v", body)
+	}
+	v1 := VersionedBody(apiversion.V1, http.StatusConflict, CodeUniqueViolation, "duplicate email", "")
+	SetField(v1, "current_rev", 3)
+	if v1["current_rev"] != 3 {
+		t.Errorf("Expected SetField to extend a v1 body at the top level, got %v", v1)
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	apiErr := NewAPIError(http.StatusBadRequest, CodeUnknownField, "unknown field 'x'")
+
+	if code := CodeOf(apiErr, CodeValidationFailed); code != CodeUnknownField {
+		t.Errorf("Expected %s, got %s", CodeUnknownField, code)
+	}
+	if code := CodeOf(fmt.Errorf("item 2: %w", apiErr), CodeValidationFailed); code != CodeUnknownField {
+		t.Errorf("Expected %s through wrapping, got %s", CodeUnknownField, code)
+	}
+	if code := CodeOf(errors.New("plain"), CodeValidationFailed); code != CodeValidationFailed {
+		t.Errorf("Expected fallback %s, got %s", CodeValidationFailed, code)
+	}
+}
@@ -0,0 +1,256 @@
+// Package expiry implements the ttl policy of collections. Reads hide the
+// records that have expired with the Live condition, so they disappear at
+// their expiry whatever the sweep timing; the Sweeper deletes them from the
+// table periodically, in bounded batches.
+package expiry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// logModule is the module of the sweeper logs, configured by logging.levels.expiry
+const logModule = "expiry"
+
+// ErrBusy is returned by SweepCollection while another sweep of the same
+// collection runs
+var ErrBusy = errors.New("a sweep of the collection is already running")
+
+var (
+	clockMu sync.RWMutex
+	clock   = time.Now
+)
+
+// Now returns the time records expire against
+func Now() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return clock()
+}
+
+// SetClock replaces the clock of Now and returns a function restoring the
+// previous one. Tests use it to expire records without waiting.
+func SetClock(now func() time.Time) (restore func()) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	previous := clock
+	clock = now
+	return func() {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		clock = previous
+	}
+}
+
+// Live returns the condition selecting the records of a collection that have
+// not expired at now, and false when the collection has no ttl policy
+func Live(collection *registry.Collection, now time.Time) (query.Condition, bool) {
+	if collection.TTL == nil {
+		return query.Condition{}, false
+	}
+	column, cutoff := collection.TTL.Cutoff(now)
+	return query.Condition{Operator: query.OpOr, Conditions: []query.Condition{
+		{Column: column, Operator: query.OpIsNull},
+		{Column: column, Operator: query.OpGreaterThan, Value: datetime.Format(cutoff)},
+	}}, true
+}
+
+// expired returns the condition selecting the records of a collection with
+// a ttl policy that have expired at now
+func expired(collection *registry.Collection, now time.Time) query.Condition {
+	column, cutoff := collection.TTL.Cutoff(now)
+	return query.Condition{Column: column, Operator: query.OpLessThanOrEqual, Value: datetime.Format(cutoff)}
+}
+
+// Sweeper deletes the expired records of the collections with a ttl policy
+type Sweeper struct {
+	db        database.Driver
+	registry  *registry.SchemaRegistry
+	batchSize int
+
+	mu      sync.Mutex
+	running map[string]bool // collections being swept
+
+	cancel context.CancelFunc // cancels the running sweep on Close
+	done   chan struct{}      // closed when the sweep loop has returned
+}
+
+// NewSweeper creates a sweeper deleting constants.TTLSweepBatchSize records
+// per transaction
+func NewSweeper(db database.Driver, reg *registry.SchemaRegistry) *Sweeper {
+	return &Sweeper{
+		db:        db,
+		registry:  reg,
+		batchSize: constants.TTLSweepBatchSize,
+		running:   make(map[string]bool),
+	}
+}
+
+// Start sweeps every interval until Close
+func (s *Sweeper) Start(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, interval)
+}
+
+// run sweeps every interval until ctx is canceled
+func (s *Sweeper) run(ctx context.Context, interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+// Close stops the sweep loop, canceling a running sweep, whose current batch
+// is rolled back. It does nothing when the sweeper was not started.
+func (s *Sweeper) Close(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sweep deletes the expired records of every collection with a ttl policy
+// and returns the number deleted per collection. Archived collections and
+// collections another sweep is working on are skipped; failures are logged.
+func (s *Sweeper) Sweep(ctx context.Context) map[string]int64 {
+	deleted := make(map[string]int64)
+	for _, collection := range s.registry.GetAll() {
+		if collection.TTL == nil || collection.Archived {
+			continue
+		}
+		n, err := s.SweepCollection(ctx, collection)
+		if n > 0 {
+			deleted[collection.Name] = n
+			logging.Module(logModule).Infof("TTL_SWEEP collection=%s deleted=%d", collection.Name, n)
+		}
+		switch {
+		case errors.Is(err, ErrBusy):
+			logging.Module(logModule).Debugf("TTL sweep of %s skipped: %v", collection.Name, err)
+		case err != nil && ctx.Err() == nil:
+			logging.Module(logModule).Errorf("TTL sweep of %s failed: %v", collection.Name, err)
+		}
+	}
+	return deleted
+}
+
+// SweepCollection deletes the records of a collection with a ttl policy
+// that have expired, a batch per transaction, and returns the number
+// deleted. It returns ErrBusy while another sweep of the collection runs.
+func (s *Sweeper) SweepCollection(ctx context.Context, collection *registry.Collection) (int64, error) {
+	if !s.claim(collection.Name) {
+		return 0, ErrBusy
+	}
+	defer s.release(collection.Name)
+
+	now := Now()
+	var total int64
+	for {
+		n, err := s.sweepBatch(ctx, collection, now)
+		total += n
+		if err != nil || n < int64(s.batchSize) {
+			return total, err
+		}
+	}
+}
+
+// claim marks a collection as being swept, false if it already is
+func (s *Sweeper) claim(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+// release ends the sweep of a collection
+func (s *Sweeper) release(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, name)
+}
+
+// sweepBatch deletes up to batchSize records expired at now in one
+// transaction. The ids are selected first, since MySQL cannot limit a
+// subquery of IN, and the delete checks the expiry again.
+func (s *Sweeper) sweepBatch(ctx context.Context, collection *registry.Collection, now time.Time) (int64, error) {
+	dialect := s.db.Dialect()
+	table := query.QuoteIdent(dialect, collection.Name)
+	condition := expired(collection, now)
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT id FROM %s WHERE ", table)
+	args := query.WriteCondition(&sb, dialect, condition, nil)
+	fmt.Fprintf(&sb, " LIMIT %d", s.batchSize)
+	rows, err := tx.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select expired records: %w", err)
+	}
+	var ids []any
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read expired records: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read expired records: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	sb.Reset()
+	fmt.Fprintf(&sb, "DELETE FROM %s WHERE ", table)
+	args = query.WriteCondition(&sb, dialect, query.Condition{Column: "id", Operator: query.OpIn, Value: ids}, nil)
+	sb.WriteString(" AND ")
+	args = query.WriteCondition(&sb, dialect, condition, args)
+	result, err := tx.ExecContext(ctx, sb.String(), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired records: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted records: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return n, nil
+}
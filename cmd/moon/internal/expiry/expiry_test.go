@@ -0,0 +1,211 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+var epoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func setupSessions(t *testing.T, ttl *registry.TTL) (database.Driver, *registry.SchemaRegistry) {
+	t.Helper()
+	db, err := database.NewDriver(database.Config{ConnectionString: "sqlite://:memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := db.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(context.Background(), "CREATE TABLE sessions (id TEXT PRIMARY KEY, created_at TEXT NOT NULL, expires_at TEXT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name:    "sessions",
+		Columns: []registry.Column{{Name: "expires_at", Type: registry.TypeDatetime, Nullable: true}},
+		TTL:     ttl,
+	})
+	return db, reg
+}
+
+// insertSession adds a session created at created that expires at expires, never when nil
+func insertSession(t *testing.T, db database.Driver, id string, created time.Time, expires *time.Time) {
+	t.Helper()
+	var expiresAt any
+	if expires != nil {
+		expiresAt = datetime.Format(*expires)
+	}
+	if _, err := db.Exec(context.Background(), "INSERT INTO sessions (id, created_at, expires_at) VALUES (?, ?, ?)", id, datetime.Format(created), expiresAt); err != nil {
+		t.Fatalf("Failed to insert %s: %v", id, err)
+	}
+}
+
+// sessionIDs returns the ids left in the table, and those a read at now sees
+func sessionIDs(t *testing.T, db database.Driver, reg *registry.SchemaRegistry, now time.Time) (stored, live string) {
+	t.Helper()
+	collection, _ := reg.Get("sessions")
+	scan := func(sql string, args ...any) string {
+		rows, err := db.Query(context.Background(), sql, args...)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		defer rows.Close()
+		var ids []string
+		for rows.Next() {
+			var id string
+			rows.Scan(&id)
+			ids = append(ids, id)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT id FROM sessions WHERE ")
+	condition, ok := Live(collection, now)
+	if !ok {
+		t.Fatal("Expected a Live condition for a collection with a ttl policy")
+	}
+	args := query.WriteCondition(&sb, database.DialectSQLite, condition, nil)
+	return scan("SELECT id FROM sessions ORDER BY id"), scan(sb.String()+" ORDER BY id", args...)
+}
+
+func TestLive_NoPolicy(t *testing.T) {
+	if _, ok := Live(&registry.Collection{Name: "notes"}, epoch); ok {
+		t.Error("Expected no condition for a collection without a ttl policy")
+	}
+}
+
+func TestSweep_Field(t *testing.T) {
+	db, reg := setupSessions(t, &registry.TTL{Field: "expires_at"})
+	soon, later := epoch.Add(time.Hour), epoch.Add(48*time.Hour)
+	insertSession(t, db, "a", epoch, &soon)
+	insertSession(t, db, "b", epoch, &later)
+	insertSession(t, db, "c", epoch, nil)
+
+	now := epoch
+	defer SetClock(func() time.Time { return now })()
+	sweeper := NewSweeper(db, reg)
+
+	if stored, live := sessionIDs(t, db, reg, Now()); stored != "a,b,c" || live != "a,b,c" {
+		t.Fatalf("Expected every session live, got stored %s, live %s", stored, live)
+	}
+
+	// Expired records are hidden from reads at once, before any sweep
+	now = epoch.Add(time.Hour)
+	if stored, live := sessionIDs(t, db, reg, Now()); stored != "a,b,c" || live != "b,c" {
+		t.Errorf("Expected a hidden but stored, got stored %s, live %s", stored, live)
+	}
+
+	if deleted := sweeper.Sweep(context.Background()); deleted["sessions"] != 1 {
+		t.Errorf("Expected the sweep to delete one session, got %v", deleted)
+	}
+	if stored, live := sessionIDs(t, db, reg, Now()); stored != "b,c" || live != "b,c" {
+		t.Errorf("Expected a deleted, got stored %s, live %s", stored, live)
+	}
+
+	// Records without an expiry are kept
+	now = epoch.Add(365 * 24 * time.Hour)
+	sweeper.Sweep(context.Background())
+	if stored, _ := sessionIDs(t, db, reg, Now()); stored != "c" {
+		t.Errorf("Expected only the session without expiry left, got %s", stored)
+	}
+}
+
+func TestSweep_Duration(t *testing.T) {
+	db, reg := setupSessions(t, &registry.TTL{Duration: "720h", From: "created_at"})
+	insertSession(t, db, "old", epoch.Add(-31*24*time.Hour), nil)
+	insertSession(t, db, "edge", epoch.Add(-30*24*time.Hour), nil)
+	insertSession(t, db, "new", epoch.Add(-time.Hour), nil)
+
+	defer SetClock(func() time.Time { return epoch })()
+	if _, live := sessionIDs(t, db, reg, Now()); live != "new" {
+		t.Errorf("Expected records created 720h ago or earlier to be hidden, got %s", live)
+	}
+	collection, _ := reg.Get("sessions")
+	n, err := NewSweeper(db, reg).SweepCollection(context.Background(), collection)
+	if err != nil || n != 2 {
+		t.Errorf("Expected two records swept, got %d, %v", n, err)
+	}
+	if stored, _ := sessionIDs(t, db, reg, Now()); stored != "new" {
+		t.Errorf("Expected only the new session left, got %s", stored)
+	}
+}
+
+func TestSweepCollection_Batches(t *testing.T) {
+	db, reg := setupSessions(t, &registry.TTL{Field: "expires_at"})
+	for i := 0; i < 7; i++ {
+		insertSession(t, db, fmt.Sprintf("s%d", i), epoch, &epoch)
+	}
+	defer SetClock(func() time.Time { return epoch })()
+
+	sweeper := NewSweeper(db, reg)
+	sweeper.batchSize = 3
+	collection, _ := reg.Get("sessions")
+	n, err := sweeper.SweepCollection(context.Background(), collection)
+	if err != nil || n != 7 {
+		t.Errorf("Expected all seven records swept in batches of 3, got %d, %v", n, err)
+	}
+	if stored, _ := sessionIDs(t, db, reg, Now()); stored != "" {
+		t.Errorf("Expected an empty table, got %s", stored)
+	}
+}
+
+func TestSweepCollection_NotConcurrent(t *testing.T) {
+	db, reg := setupSessions(t, &registry.TTL{Field: "expires_at"})
+	insertSession(t, db, "a", epoch, &epoch)
+	defer SetClock(func() time.Time { return epoch })()
+
+	sweeper := NewSweeper(db, reg)
+	collection, _ := reg.Get("sessions")
+	sweeper.claim("sessions")
+	if _, err := sweeper.SweepCollection(context.Background(), collection); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Expected ErrBusy while another sweep runs, got %v", err)
+	}
+	if deleted := sweeper.Sweep(context.Background()); len(deleted) != 0 {
+		t.Errorf("Expected the busy collection to be skipped, got %v", deleted)
+	}
+
+	sweeper.release("sessions")
+	if n, err := sweeper.SweepCollection(context.Background(), collection); err != nil || n != 1 {
+		t.Errorf("Expected the sweep to run once released, got %d, %v", n, err)
+	}
+}
+
+func TestSweeper_StartClose(t *testing.T) {
+	db, reg := setupSessions(t, &registry.TTL{Field: "expires_at"})
+	insertSession(t, db, "a", epoch, &epoch)
+	defer SetClock(func() time.Time { return epoch })()
+
+	sweeper := NewSweeper(db, reg)
+	if err := sweeper.Close(context.Background()); err != nil {
+		t.Errorf("Expected Close of an idle sweeper to do nothing, got %v", err)
+	}
+	sweeper.Start(20 * time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if stored, _ := sessionIDs(t, db, reg, Now()); stored == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the sweep loop to delete the expired session")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := sweeper.Close(ctx); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
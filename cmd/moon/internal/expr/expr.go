@@ -0,0 +1,381 @@
+// Package expr parses the expressions of computed columns: column names,
+// numeric literals, the operators + - * / with the usual precedence, unary
+// minus and parentheses, e.g. "price * quantity" or "(net + tax) / 100".
+// Expressions are evaluated in Go for inserts and rendered to SQL for updates
+// and backfills; in both a null operand or a division by zero gives null.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxLength is the longest expression accepted, in bytes
+const MaxLength = 500
+
+// maxDepth bounds the nesting of parentheses and unary minus
+const maxDepth = 32
+
+// Expr is a parsed expression
+type Expr struct {
+	root node
+}
+
+type node interface {
+	precedence() int
+}
+
+type number struct {
+	value float64
+	text  string
+}
+
+type column struct {
+	name string
+}
+
+type negate struct {
+	operand node
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (number) precedence() int { return 4 }
+func (column) precedence() int { return 4 }
+func (negate) precedence() int { return 3 }
+
+func (b binary) precedence() int {
+	if b.op == '+' || b.op == '-' {
+		return 1
+	}
+	return 2
+}
+
+// Parse parses an expression
+func Parse(src string) (*Expr, error) {
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("expression longer than %d characters", MaxLength)
+	}
+	p := &parser{src: src}
+	p.next()
+	if p.tok == tokEOF {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	root, err := p.parseSum(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != tokEOF {
+		return nil, p.unexpected()
+	}
+	return &Expr{root: root}, nil
+}
+
+// Columns returns the columns the expression reads, in order of first use
+func (e *Expr) Columns() []string {
+	var names []string
+	walk(e.root, func(c *column) {
+		for _, name := range names {
+			if name == c.name {
+				return
+			}
+		}
+		names = append(names, c.name)
+	})
+	return names
+}
+
+// Rename replaces references to the column old with new
+func (e *Expr) Rename(old, new string) {
+	walk(e.root, func(c *column) {
+		if c.name == old {
+			c.name = new
+		}
+	})
+}
+
+// Eval evaluates the expression with the operand values given by value,
+// which reports false for a null. The result is false when an operand is
+// null or a divisor zero.
+func (e *Expr) Eval(value func(column string) (float64, bool)) (float64, bool) {
+	return eval(e.root, value)
+}
+
+// SQL renders the expression as SQL with every operation parenthesized and
+// divisors wrapped in NULLIF(x, 0), so that division by zero gives NULL in
+// every dialect. operand returns the SQL of a column reference. Literals
+// are written with a decimal point so that no dialect divides integers.
+func (e *Expr) SQL(operand func(column string) string) string {
+	var sb strings.Builder
+	writeSQL(&sb, e.root, operand)
+	return sb.String()
+}
+
+// String returns the expression in canonical form: single spaces around
+// operators and only the parentheses precedence requires
+func (e *Expr) String() string {
+	var sb strings.Builder
+	writeString(&sb, e.root)
+	return sb.String()
+}
+
+func walk(n node, visit func(*column)) {
+	switch n := n.(type) {
+	case *column:
+		visit(n)
+	case *negate:
+		walk(n.operand, visit)
+	case *binary:
+		walk(n.left, visit)
+		walk(n.right, visit)
+	}
+}
+
+func eval(n node, value func(string) (float64, bool)) (float64, bool) {
+	switch n := n.(type) {
+	case *number:
+		return n.value, true
+	case *column:
+		return value(n.name)
+	case *negate:
+		v, ok := eval(n.operand, value)
+		return -v, ok
+	case *binary:
+		left, ok := eval(n.left, value)
+		if !ok {
+			return 0, false
+		}
+		right, ok := eval(n.right, value)
+		if !ok {
+			return 0, false
+		}
+		switch n.op {
+		case '+':
+			return left + right, true
+		case '-':
+			return left - right, true
+		case '*':
+			return left * right, true
+		default:
+			if right == 0 {
+				return 0, false
+			}
+			return left / right, true
+		}
+	}
+	return 0, false
+}
+
+func writeSQL(sb *strings.Builder, n node, operand func(string) string) {
+	switch n := n.(type) {
+	case *number:
+		sb.WriteString(n.text)
+		if !strings.Contains(n.text, ".") {
+			sb.WriteString(".0")
+		}
+	case *column:
+		sb.WriteString(operand(n.name))
+	case *negate:
+		sb.WriteString("(-")
+		writeSQL(sb, n.operand, operand)
+		sb.WriteString(")")
+	case *binary:
+		sb.WriteString("(")
+		writeSQL(sb, n.left, operand)
+		if n.op == '/' {
+			sb.WriteString(" / NULLIF(")
+			writeSQL(sb, n.right, operand)
+			sb.WriteString(", 0))")
+			return
+		}
+		sb.WriteString(" " + string(n.op) + " ")
+		writeSQL(sb, n.right, operand)
+		sb.WriteString(")")
+	}
+}
+
+func writeString(sb *strings.Builder, n node) {
+	switch n := n.(type) {
+	case *number:
+		sb.WriteString(n.text)
+	case *column:
+		sb.WriteString(n.name)
+	case *negate:
+		sb.WriteString("-")
+		writeOperand(sb, n.operand, n.operand.precedence() < 3)
+	case *binary:
+		// Operators are left-associative: a right operand of the same
+		// precedence keeps its parentheses, a - (b - c)
+		writeOperand(sb, n.left, n.left.precedence() < n.precedence())
+		sb.WriteString(" " + string(n.op) + " ")
+		writeOperand(sb, n.right, n.right.precedence() <= n.precedence())
+	}
+}
+
+func writeOperand(sb *strings.Builder, n node, parenthesize bool) {
+	if parenthesize {
+		sb.WriteString("(")
+	}
+	writeString(sb, n)
+	if parenthesize {
+		sb.WriteString(")")
+	}
+}
+
+type token int
+
+const (
+	tokEOF token = iota
+	tokNumber
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokInvalid
+)
+
+type parser struct {
+	src   string
+	pos   int    // offset after the current token
+	start int    // offset of the current token
+	tok   token  // current token
+	text  string // text of the current token
+}
+
+// next scans the next token
+func (p *parser) next() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+	p.start = p.pos
+	if p.pos == len(p.src) {
+		p.tok, p.text = tokEOF, ""
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case isDigit(c) || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = tokNumber
+	case isLetter(c):
+		for p.pos < len(p.src) && (isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = tokIdent
+	default:
+		p.pos++
+		switch c {
+		case '+', '-', '*', '/':
+			p.tok = tokOp
+		case '(':
+			p.tok = tokLParen
+		case ')':
+			p.tok = tokRParen
+		default:
+			p.tok = tokInvalid
+		}
+	}
+	p.text = p.src[p.start:p.pos]
+}
+
+func (p *parser) unexpected() error {
+	if p.tok == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected '%s' at position %d", p.text, p.start+1)
+}
+
+// parseSum parses terms joined by + and -
+func (p *parser) parseSum(depth int) (node, error) {
+	left, err := p.parseProduct(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == tokOp && (p.text == "+" || p.text == "-") {
+		op := p.text[0]
+		p.next()
+		right, err := p.parseProduct(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseProduct parses factors joined by * and /
+func (p *parser) parseProduct(depth int) (node, error) {
+	left, err := p.parseFactor(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == tokOp && (p.text == "*" || p.text == "/") {
+		op := p.text[0]
+		p.next()
+		right, err := p.parseFactor(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseFactor parses a literal, a column, a negation or a parenthesized sum
+func (p *parser) parseFactor(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d levels", maxDepth)
+	}
+	switch p.tok {
+	case tokNumber:
+		value, err := strconv.ParseFloat(p.text, 64)
+		if err != nil || strings.HasPrefix(p.text, ".") || strings.HasSuffix(p.text, ".") {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", p.text, p.start+1)
+		}
+		n := &number{value: value, text: p.text}
+		p.next()
+		return n, nil
+	case tokIdent:
+		n := &column{name: p.text}
+		p.next()
+		return n, nil
+	case tokOp:
+		if p.text != "-" {
+			return nil, p.unexpected()
+		}
+		p.next()
+		operand, err := p.parseFactor(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &negate{operand: operand}, nil
+	case tokLParen:
+		open := p.start
+		p.next()
+		inner, err := p.parseSum(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != tokRParen {
+			return nil, fmt.Errorf("missing ')' for '(' at position %d", open+1)
+		}
+		p.next()
+		return inner, nil
+	}
+	return nil, p.unexpected()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
@@ -0,0 +1,142 @@
+package expr
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// values returns an operand lookup over a map; missing columns are null
+func values(m map[string]float64) func(string) (float64, bool) {
+	return func(name string) (float64, bool) {
+		v, ok := m[name]
+		return v, ok
+	}
+}
+
+func TestEval(t *testing.T) {
+	operands := map[string]float64{"price": 2.5, "quantity": 4, "tax": 1, "zero": 0}
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"price * quantity", 10},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"10 - (4 - 3)", 9},
+		{"24 / 4 / 2", 3},
+		{"-price + tax", -1.5},
+		{"-(price + tax) * 2", -7},
+		{"7 / 2", 3.5},
+		{"price*quantity+tax", 11},
+		{"0.5 * quantity", 2},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.src, err)
+		}
+		got, ok := e.Eval(values(operands))
+		if !ok || got != tt.want {
+			t.Errorf("Eval(%q) = %v, %v; want %v", tt.src, got, ok, tt.want)
+		}
+	}
+}
+
+func TestEval_Null(t *testing.T) {
+	operands := map[string]float64{"price": 2.5, "zero": 0}
+	for _, src := range []string{
+		"price / zero",
+		"price / (zero * 3)",
+		"1 / 0",
+		"price * missing",
+		"missing / zero + 1",
+	} {
+		e, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", src, err)
+		}
+		if got, ok := e.Eval(values(operands)); ok {
+			t.Errorf("Eval(%q) = %v; want null", src, got)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"", "expression is empty"},
+		{"   ", "expression is empty"},
+		{"price *", "unexpected end of expression"},
+		{"price quantity", "unexpected 'quantity' at position 7"},
+		{"(price + 1", "missing ')' for '(' at position 1"},
+		{"price + 1)", "unexpected ')' at position 10"},
+		{"price % 2", "unexpected '%' at position 7"},
+		{"* price", "unexpected '*' at position 1"},
+		{"1.2.3", "invalid number '1.2.3'"},
+		{".5", "invalid number '.5'"},
+		{"upper(name)", "unexpected '(' at position 6"},
+		{"'text'", "unexpected '''"},
+		{strings.Repeat("(", 40) + "1" + strings.Repeat(")", 40), "nested deeper than 32 levels"},
+		{strings.Repeat("a + ", 200) + "a", "longer than 500 characters"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): expected error containing %q, got %v", tt.src, tt.want, err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"price*quantity", "price * quantity"},
+		{"((price)) * (quantity)", "price * quantity"},
+		{"(a + b) * c", "(a + b) * c"},
+		{"a + (b * c)", "a + b * c"},
+		{"a - (b - c)", "a - (b - c)"},
+		{"(a - b) - c", "a - b - c"},
+		{"a / (b * c)", "a / (b * c)"},
+		{"-(a + b)", "-(a + b)"},
+		{"- a * 2", "-a * 2"},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.src, err)
+		}
+		if got := e.String(); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestColumnsAndRename(t *testing.T) {
+	e, err := Parse("price * quantity + price / discount")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := e.Columns(); !slices.Equal(got, []string{"price", "quantity", "discount"}) {
+		t.Errorf("expected each column once in order, got %v", got)
+	}
+	e.Rename("price", "unit_price")
+	if got := e.String(); got != "unit_price * quantity + unit_price / discount" {
+		t.Errorf("expected the column renamed, got %q", got)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	e, err := Parse("-price * 2 + total / (quantity - 1)")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := e.SQL(func(name string) string { return `"` + name + `"` })
+	want := `(((-"price") * 2.0) + ("total" / NULLIF(("quantity" - 1.0), 0)))`
+	if got != want {
+		t.Errorf("SQL = %s, want %s", got, want)
+	}
+}
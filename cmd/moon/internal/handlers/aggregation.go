@@ -4,17 +4,21 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
-	"github.com/thalib/moon/cmd/moon/internal/logging"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 	"github.com/thalib/moon/cmd/moon/internal/query"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 )
 
 // AggregationHandler handles aggregation operations on collection data
 type AggregationHandler struct {
-	db       database.Driver
-	registry *registry.SchemaRegistry
+	db          database.Driver
+	registry    *registry.SchemaRegistry
+	strictQuery bool // api.strict_query_params
 }
 
 // NewAggregationHandler creates a new aggregation handler
@@ -25,31 +29,54 @@ func NewAggregationHandler(db database.Driver, reg *registry.SchemaRegistry) *Ag
 	}
 }
 
+// SetStrictQueryParams sets whether unknown query parameters are rejected
+// unless a request passes ?strict=false
+func (h *AggregationHandler) SetStrictQueryParams(strict bool) {
+	h.strictQuery = strict
+}
+
+// checkQueryParams writes a 400 and returns false when strict checking
+// rejects a query parameter of the request. It runs before the parameters are
+// read, so that a misspelled required parameter is named with a suggestion;
+// an unknown collection is left to the caller.
+func (h *AggregationHandler) checkQueryParams(w http.ResponseWriter, r *http.Request, collectionName string, known ...[]string) bool {
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		return true
+	}
+	if err := checkQueryParams(r, h.strictQuery, collection, known...); err != nil {
+		writeAPIError(w, r, err)
+		return false
+	}
+	return true
+}
+
 // AggregationResponse represents response for aggregation operations
 type AggregationResponse struct {
-	Value any `json:"value"`
+	Value any   `json:"value"`
+	Count int64 `json:"count"` // records counted, or values the aggregate covers
 }
 
 // Count handles GET /{name}:count
 func (h *AggregationHandler) Count(w http.ResponseWriter, r *http.Request, collectionName string) {
+	if !h.checkQueryParams(w, r, collectionName, constants.SearchQueryParams, constants.CountQueryParams) {
+		return
+	}
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
-	// Parse filters from query parameters
-	filters, err := parseFilters(r)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid filter: %v", err))
-		return
+	// Select records by filters and search as :list does, and by the
+	// :query filter tree of the filter parameter
+	conditions, err := parseRecordConditions(r, collection)
+	if err == nil {
+		conditions, err = withFilterTree(r, collection, conditions)
 	}
-
-	// Build conditions from filters
-	conditions, err := buildConditions(filters, collection)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidFilter), err.Error())
 		return
 	}
 
@@ -59,346 +86,508 @@ func (h *AggregationHandler) Count(w http.ResponseWriter, r *http.Request, colle
 	// Build COUNT query
 	sqlQuery, args := builder.Count(collectionName, conditions)
 
-	// Debug logging when filters are present
-	if len(conditions) > 0 {
-		logging.GetLogger().WithFields(map[string]any{
-			"operation":  "count",
-			"collection": collectionName,
-			"sql":        sqlQuery,
-			"args":       args,
-			"filters":    len(conditions),
-		}).Debug("Aggregation query with filters")
-	}
+	logQuery(r.Context(), "count", sqlQuery, args)
 
 	// Execute query
 	ctx := r.Context()
 	var count int64
 	err = h.db.QueryRow(ctx, sqlQuery, args...).Scan(&count)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to execute count: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to execute count: %v", err))
 		return
 	}
 
 	response := AggregationResponse{
 		Value: count,
+		Count: count,
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // Sum handles GET /{name}:sum?field={field}
 func (h *AggregationHandler) Sum(w http.ResponseWriter, r *http.Request, collectionName string) {
+	h.aggregate(w, r, collectionName, "sum")
+}
+
+// Avg handles GET /{name}:avg?field={field}
+func (h *AggregationHandler) Avg(w http.ResponseWriter, r *http.Request, collectionName string) {
+	h.aggregate(w, r, collectionName, "avg")
+}
+
+// Min handles GET /{name}:min?field={field}
+func (h *AggregationHandler) Min(w http.ResponseWriter, r *http.Request, collectionName string) {
+	h.aggregate(w, r, collectionName, "min")
+}
+
+// Max handles GET /{name}:max?field={field}
+func (h *AggregationHandler) Max(w http.ResponseWriter, r *http.Request, collectionName string) {
+	h.aggregate(w, r, collectionName, "max")
+}
+
+// aggregate answers a sum, avg, min or max request with the aggregate of the
+// field over the matching records and the number of values it covers
+func (h *AggregationHandler) aggregate(w http.ResponseWriter, r *http.Request, collectionName, agg string) {
+	if !h.checkQueryParams(w, r, collectionName, constants.SearchQueryParams, constants.AggregateQueryParams) {
+		return
+	}
 	// Get field parameter
 	field := r.URL.Query().Get("field")
 	if field == "" {
-		writeError(w, http.StatusBadRequest, "field parameter is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "field parameter is required")
 		return
 	}
 
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
-	// Validate field exists and is numeric
-	if err := validateNumericField(collection, field); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	// Parse filters from query parameters
-	filters, err := parseFilters(r)
+	// Validate field exists and has a type the function accepts
+	fieldCol, err := aggregateField(collection, field, agg)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid filter: %v", err))
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
 		return
 	}
+	if revealsValues(agg) {
+		if err := checkRevealedField(r, collectionName, collection, field); err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+	}
 
-	// Build conditions from filters
-	conditions, err := buildConditions(filters, collection)
+	// Select records by filters and search as :list does, and by the
+	// :query filter tree of the filter parameter
+	conditions, err := parseRecordConditions(r, collection)
+	if err == nil {
+		conditions, err = withFilterTree(r, collection, conditions)
+	}
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidFilter), err.Error())
 		return
 	}
 
 	// Create query builder
 	builder := query.NewBuilder(h.db.Dialect())
 
-	// Build SUM query
-	sqlQuery, args := builder.Sum(collectionName, field, conditions)
-
-	// Debug logging when filters are present
-	if len(conditions) > 0 {
-		logging.GetLogger().WithFields(map[string]any{
-			"operation":  "sum",
-			"collection": collectionName,
-			"field":      field,
-			"sql":        sqlQuery,
-			"args":       args,
-			"filters":    len(conditions),
-		}).Debug("Aggregation query with filters")
+	var sqlQuery string
+	var args []any
+	switch agg {
+	case "sum":
+		sqlQuery, args = builder.Sum(collectionName, field, conditions)
+	case "avg":
+		sqlQuery, args = builder.Avg(collectionName, field, conditions)
+	case "min":
+		sqlQuery, args = builder.Min(collectionName, field, conditions)
+	default:
+		sqlQuery, args = builder.Max(collectionName, field, conditions)
 	}
 
+	logQuery(r.Context(), agg, sqlQuery, args)
+
 	// Execute query
 	ctx := r.Context()
-	var sum sql.NullFloat64
-	err = h.db.QueryRow(ctx, sqlQuery, args...).Scan(&sum)
+	var raw any
+	var count int64
+	err = h.db.QueryRow(ctx, sqlQuery, args...).Scan(&raw, &count)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to execute sum: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to execute %s: %v", agg, err))
 		return
 	}
 
-	// Return 0 if no rows or NULL result
-	var result float64
-	if sum.Valid {
-		result = sum.Float64
+	// NULL, from no matching values, stays null
+	result, err := aggregateValue(raw, fieldCol.Type, agg)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read %s: %v", agg, err))
+		return
 	}
 
 	response := AggregationResponse{
 		Value: result,
+		Count: count,
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
-// Avg handles GET /{name}:avg?field={field}
-func (h *AggregationHandler) Avg(w http.ResponseWriter, r *http.Request, collectionName string) {
-	// Get field parameter
-	field := r.URL.Query().Get("field")
-	if field == "" {
-		writeError(w, http.StatusBadRequest, "field parameter is required")
+// GroupByResult represents a single group in a group-by aggregation
+type GroupByResult struct {
+	Key   any `json:"key"`
+	Value any `json:"value"`
+}
+
+// GroupByResponse represents response for group-by aggregation
+type GroupByResponse struct {
+	Groups []GroupByResult `json:"groups"`
+	Count  int             `json:"count"`
+}
+
+// GroupBy handles GET /{name}:groupby?by={column}&agg={function}&field={field}
+func (h *AggregationHandler) GroupBy(w http.ResponseWriter, r *http.Request, collectionName string) {
+	if !h.checkQueryParams(w, r, collectionName, constants.SearchQueryParams, constants.GroupByQueryParams) {
+		return
+	}
+	params := r.URL.Query()
+	by := params.Get("by")
+	if by == "" {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "by parameter is required")
+		return
+	}
+
+	agg := params.Get("agg")
+	if agg == "" {
+		agg = "count"
+	}
+	if err := query.ValidateAggregateFunction(agg); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
 		return
 	}
 
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
-	// Validate field exists and is numeric
-	if err := validateNumericField(collection, field); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	// Validate group column exists
+	var groupCol *registry.Column
+	for i := range collection.Columns {
+		if collection.Columns[i].Name == by {
+			groupCol = &collection.Columns[i]
+			break
+		}
+	}
+	if groupCol == nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeUnknownField, fmt.Sprintf("field '%s' not found in collection", by))
 		return
 	}
-
-	// Parse filters from query parameters
-	filters, err := parseFilters(r)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid filter: %v", err))
+	if err := checkRevealedField(r, collectionName, collection, by); err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	// Build conditions from filters
-	conditions, err := buildConditions(filters, collection)
+	// Validate aggregated field (not needed for count)
+	field := params.Get("field")
+	var fieldCol registry.Column
+	if agg != "count" {
+		if field == "" {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "field parameter is required")
+			return
+		}
+		var err error
+		if fieldCol, err = aggregateField(collection, field, agg); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+			return
+		}
+		if revealsValues(agg) {
+			if err := checkRevealedField(r, collectionName, collection, field); err != nil {
+				writeAPIError(w, r, err)
+				return
+			}
+		}
+	}
+
+	// Select records by filters and search as :list does, and by the
+	// :query filter tree of the filter parameter
+	conditions, err := parseRecordConditions(r, collection)
+	if err == nil {
+		conditions, err = withFilterTree(r, collection, conditions)
+	}
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidFilter), err.Error())
 		return
 	}
 
 	// Create query builder
 	builder := query.NewBuilder(h.db.Dialect())
 
-	// Build AVG query
-	sqlQuery, args := builder.Avg(collectionName, field, conditions)
+	// Fetch one extra group to detect when the cap is exceeded
+	sqlQuery, args := builder.GroupBy(collectionName, by, agg, field, conditions, constants.MaxGroupByGroups+1)
 
-	// Debug logging when filters are present
-	if len(conditions) > 0 {
-		logging.GetLogger().WithFields(map[string]any{
-			"operation":  "avg",
-			"collection": collectionName,
-			"field":      field,
-			"sql":        sqlQuery,
-			"args":       args,
-			"filters":    len(conditions),
-		}).Debug("Aggregation query with filters")
-	}
+	logQuery(r.Context(), "groupby", sqlQuery, args)
 
 	// Execute query
 	ctx := r.Context()
-	var avg sql.NullFloat64
-	err = h.db.QueryRow(ctx, sqlQuery, args...).Scan(&avg)
+	rows, err := h.db.Query(ctx, sqlQuery, args...)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to execute avg: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to execute groupby: %v", err))
 		return
 	}
+	defer rows.Close()
 
-	// Return 0 if no rows or NULL result
-	var result float64
-	if avg.Valid {
-		result = avg.Float64
-	}
-
-	response := AggregationResponse{
-		Value: result,
-	}
-
-	writeJSON(w, http.StatusOK, response)
-}
+	groups := []GroupByResult{}
+	for rows.Next() {
+		var key any
+		var value any
+		if err := rows.Scan(&key, &value); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to scan groupby row: %v", err))
+			return
+		}
 
-// Min handles GET /{name}:min?field={field}
-func (h *AggregationHandler) Min(w http.ResponseWriter, r *http.Request, collectionName string) {
-	// Get field parameter
-	field := r.URL.Query().Get("field")
-	if field == "" {
-		writeError(w, http.StatusBadRequest, "field parameter is required")
-		return
-	}
+		key = aggregateKey(key, groupCol.Type)
 
-	// Validate collection exists in registry
-	collection, exists := h.registry.Get(collectionName)
-	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
-		return
-	}
+		var result any
+		if agg == "count" {
+			var count sql.NullInt64
+			err = count.Scan(value)
+			result = count.Int64
+		} else {
+			result, err = aggregateValue(value, fieldCol.Type, agg)
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read groupby value: %v", err))
+			return
+		}
 
-	// Validate field exists and is numeric
-	if err := validateNumericField(collection, field); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+		groups = append(groups, GroupByResult{Key: key, Value: result})
 	}
-
-	// Parse filters from query parameters
-	filters, err := parseFilters(r)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid filter: %v", err))
+	if err := rows.Err(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read groupby rows: %v", err))
 		return
 	}
 
-	// Build conditions from filters
-	conditions, err := buildConditions(filters, collection)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	if len(groups) > constants.MaxGroupByGroups {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("groupby produced more than %d groups; add filters to narrow the result", constants.MaxGroupByGroups))
 		return
 	}
 
-	// Create query builder
-	builder := query.NewBuilder(h.db.Dialect())
+	writeResponse(w, r, http.StatusOK, GroupByResponse{
+		Groups: groups,
+		Count:  len(groups),
+	})
+}
 
-	// Build MIN query
-	sqlQuery, args := builder.Min(collectionName, field, conditions)
+// DistinctValue represents a distinct value and the number of records holding it
+type DistinctValue struct {
+	Value any   `json:"value"`
+	Count int64 `json:"count"`
+}
 
-	// Debug logging when filters are present
-	if len(conditions) > 0 {
-		logging.GetLogger().WithFields(map[string]any{
-			"operation":  "min",
-			"collection": collectionName,
-			"field":      field,
-			"sql":        sqlQuery,
-			"args":       args,
-			"filters":    len(conditions),
-		}).Debug("Aggregation query with filters")
-	}
+// DistinctResponse represents response for distinct values. Values holds plain
+// values, or DistinctValue pairs when occurrence counts are requested.
+type DistinctResponse struct {
+	Field  string `json:"field"`
+	Values []any  `json:"values"`
+	Count  int    `json:"count"`
+}
 
-	// Execute query
-	ctx := r.Context()
-	var min sql.NullFloat64
-	err = h.db.QueryRow(ctx, sqlQuery, args...).Scan(&min)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to execute min: %v", err))
+// Distinct handles GET /{name}:distinct?field={field}&limit={n}&count={bool}
+func (h *AggregationHandler) Distinct(w http.ResponseWriter, r *http.Request, collectionName string) {
+	if !h.checkQueryParams(w, r, collectionName, constants.SearchQueryParams, constants.DistinctQueryParams) {
 		return
 	}
-
-	// Return 0 if no rows or NULL result
-	var result float64
-	if min.Valid {
-		result = min.Float64
+	params := r.URL.Query()
+	field := params.Get("field")
+	if field == "" {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "field parameter is required")
+		return
 	}
 
-	response := AggregationResponse{
-		Value: result,
+	limit := constants.DefaultDistinctLimit
+	if limitStr := params.Get(constants.QueryParamLimit); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "limit must be a positive integer")
+			return
+		}
+		if l > constants.MaxDistinctLimit {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("limit cannot exceed %d", constants.MaxDistinctLimit))
+			return
+		}
+		limit = l
 	}
 
-	writeJSON(w, http.StatusOK, response)
-}
-
-// Max handles GET /{name}:max?field={field}
-func (h *AggregationHandler) Max(w http.ResponseWriter, r *http.Request, collectionName string) {
-	// Get field parameter
-	field := r.URL.Query().Get("field")
-	if field == "" {
-		writeError(w, http.StatusBadRequest, "field parameter is required")
+	withCount, err := QueryBoolean(r, "count", false)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
-	// Validate field exists and is numeric
-	if err := validateNumericField(collection, field); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	// Validate field exists
+	var fieldCol *registry.Column
+	for i := range collection.Columns {
+		if collection.Columns[i].Name == field {
+			fieldCol = &collection.Columns[i]
+			break
+		}
+	}
+	if fieldCol == nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeUnknownField, fmt.Sprintf("field '%s' not found in collection", field))
 		return
 	}
-
-	// Parse filters from query parameters
-	filters, err := parseFilters(r)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid filter: %v", err))
+	if err := checkRevealedField(r, collectionName, collection, field); err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	// Build conditions from filters
-	conditions, err := buildConditions(filters, collection)
+	// Select records by filters and search as :list does, and by the
+	// :query filter tree of the filter parameter
+	conditions, err := parseRecordConditions(r, collection)
+	if err == nil {
+		conditions, err = withFilterTree(r, collection, conditions)
+	}
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidFilter), err.Error())
 		return
 	}
 
 	// Create query builder
 	builder := query.NewBuilder(h.db.Dialect())
 
-	// Build MAX query
-	sqlQuery, args := builder.Max(collectionName, field, conditions)
+	sqlQuery, args := builder.Distinct(collectionName, field, withCount, conditions, limit)
 
-	// Debug logging when filters are present
-	if len(conditions) > 0 {
-		logging.GetLogger().WithFields(map[string]any{
-			"operation":  "max",
-			"collection": collectionName,
-			"field":      field,
-			"sql":        sqlQuery,
-			"args":       args,
-			"filters":    len(conditions),
-		}).Debug("Aggregation query with filters")
-	}
+	logQuery(r.Context(), "distinct", sqlQuery, args)
 
 	// Execute query
 	ctx := r.Context()
-	var max sql.NullFloat64
-	err = h.db.QueryRow(ctx, sqlQuery, args...).Scan(&max)
+	rows, err := h.db.Query(ctx, sqlQuery, args...)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to execute max: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to execute distinct: %v", err))
 		return
 	}
+	defer rows.Close()
+
+	values := []any{}
+	for rows.Next() {
+		var value any
+		var count sql.NullInt64
+		if withCount {
+			err = rows.Scan(&value, &count)
+		} else {
+			err = rows.Scan(&value)
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to scan distinct row: %v", err))
+			return
+		}
+
+		value = aggregateKey(value, fieldCol.Type)
 
-	// Return 0 if no rows or NULL result
-	var result float64
-	if max.Valid {
-		result = max.Float64
+		if withCount {
+			values = append(values, DistinctValue{Value: value, Count: count.Int64})
+		} else {
+			values = append(values, value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read distinct rows: %v", err))
+		return
 	}
 
-	response := AggregationResponse{
-		Value: result,
+	writeResponse(w, r, http.StatusOK, DistinctResponse{
+		Field:  field,
+		Values: values,
+		Count:  len(values),
+	})
+}
+
+// checkRevealedField returns an error when the field is a hidden column
+// whose values an aggregation would return: the values of :distinct, the
+// group keys of :groupby and :timeseries and the results of min and max.
+// Counts, sums and averages do not reveal single values and are allowed.
+// As for :list, an admin with the schema scope on the collection may pass
+// ?include_hidden=true. Errors are *apperrors.APIError values.
+func checkRevealedField(r *http.Request, collectionName string, collection *registry.Collection, field string) error {
+	masked, err := responseHidden(r, collectionName, collection)
+	if err != nil {
+		return err
+	}
+	if masked[field] {
+		return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidParameter, "field '%s' is hidden", field)
 	}
+	return nil
+}
 
-	writeJSON(w, http.StatusOK, response)
+// revealsValues reports whether an aggregate function returns a stored value
+func revealsValues(agg string) bool {
+	return agg == "min" || agg == "max"
 }
 
-// validateNumericField checks if a field exists and is numeric type
-func validateNumericField(collection *registry.Collection, fieldName string) error {
-	for _, col := range collection.Columns {
-		if col.Name == fieldName {
-			if col.Type == registry.TypeInteger || col.Type == registry.TypeDecimal {
-				return nil
+// aggregateField returns the column a sum, avg, min or max aggregates. Sum
+// and avg need a numeric field; min and max also order strings and
+// datetimes, so the system timestamps and id can be used with them.
+func aggregateField(collection *registry.Collection, fieldName, agg string) (registry.Column, error) {
+	columns := collection.Columns
+	if agg == "min" || agg == "max" {
+		columns = append(slices.Clone(columns), queryableSystemColumns()...)
+	}
+	for _, col := range columns {
+		if col.Name != fieldName {
+			continue
+		}
+		switch col.Type {
+		case registry.TypeInteger, registry.TypeDecimal:
+			return col, nil
+		case registry.TypeString, registry.TypeDatetime:
+			if agg == "min" || agg == "max" {
+				return col, nil
+			}
+		default:
+			if agg == "min" || agg == "max" {
+				return col, fmt.Errorf("field '%s' cannot be used with %s (type: %s); use an integer, decimal, string or datetime field", fieldName, agg, col.Type)
 			}
-			return fmt.Errorf("field '%s' is not numeric (type: %s)", fieldName, col.Type)
 		}
+		return col, fmt.Errorf("field '%s' is not numeric (type: %s)", fieldName, col.Type)
+	}
+	return registry.Column{}, fmt.Errorf("field '%s' not found in collection", fieldName)
+}
+
+// aggregateKey converts a scanned group key or distinct value to the form
+// list responses use: text as a string, booleans as true/false and decimals
+// as decimal strings with the column scale. NULL stays nil.
+func aggregateKey(raw any, colType registry.ColumnType) any {
+	if b, ok := raw.([]byte); ok {
+		raw = string(b)
+	}
+	if raw == nil {
+		return nil
+	}
+	switch colType {
+	case registry.TypeBoolean:
+		return convertToBoolean(raw)
+	case registry.TypeDecimal:
+		return formatDecimal(raw)
+	}
+	return raw
+}
+
+// aggregateValue converts a scanned sum, avg, min or max to its API value.
+// Integer fields report a number; decimal fields report a decimal string with
+// the column scale (up to constants.MaxDecimalScale places for avg) so no
+// precision is lost. Strings are returned as they are and datetimes in the UTC
+// RFC3339 form of list responses. NULL, from no matching values, is nil.
+func aggregateValue(raw any, fieldType registry.ColumnType, agg string) (any, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	if b, ok := raw.([]byte); ok && (fieldType == registry.TypeString || fieldType == registry.TypeDatetime) {
+		raw = string(b)
+	}
+	switch fieldType {
+	case registry.TypeDecimal:
+		if agg == "avg" {
+			return formatDecimalAverage(raw), nil
+		}
+		return formatDecimal(raw), nil
+	case registry.TypeString:
+		return raw, nil
+	case registry.TypeDatetime:
+		return formatDatetime(raw), nil
+	}
+
+	var value sql.NullFloat64
+	if err := value.Scan(raw); err != nil {
+		return nil, err
 	}
-	return fmt.Errorf("field '%s' not found in collection", fieldName)
+	return value.Float64, nil
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func newDistinctTestHandler(t *testing.T) *AggregationHandler {
+	t.Helper()
+	driver := createTestDB(t)
+	t.Cleanup(func() { driver.Close() })
+
+	ctx := context.Background()
+	_, err := driver.Exec(ctx, `CREATE TABLE products (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		category TEXT,
+		active INTEGER NOT NULL,
+		rating INTEGER NOT NULL
+	)`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// 300 rows cycling through a few values, inserted out of order
+	categories := []string{"toys", "books", "garden", "electronics"}
+	for i := 0; i < 300; i++ {
+		var category any = categories[i%len(categories)]
+		if i%50 == 0 {
+			category = nil
+		}
+		_, err := driver.Exec(ctx, "INSERT INTO products (category, active, rating) VALUES (?, ?, ?)", category, i%2, 5-i%5)
+		if err != nil {
+			t.Fatalf("Failed to insert row: %v", err)
+		}
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "category", Type: registry.TypeString, Nullable: true},
+			{Name: "active", Type: registry.TypeBoolean},
+			{Name: "rating", Type: registry.TypeInteger},
+		},
+	})
+	return NewAggregationHandler(driver, reg)
+}
+
+func getDistinct(t *testing.T, handler *AggregationHandler, url string) DistinctResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+	handler.Distinct(w, req, "products")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp DistinctResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestAggregationHandler_Distinct_Integration(t *testing.T) {
+	handler := newDistinctTestHandler(t)
+
+	t.Run("string values are deduplicated and ordered", func(t *testing.T) {
+		resp := getDistinct(t, handler, "/products:distinct?field=category")
+		want := []any{nil, "books", "electronics", "garden", "toys"}
+		if resp.Field != "category" || resp.Count != len(want) || len(resp.Values) != len(want) {
+			t.Fatalf("expected %d values, got %+v", len(want), resp)
+		}
+		for i, v := range want {
+			if resp.Values[i] != v {
+				t.Errorf("value %d: expected %v, got %v", i, v, resp.Values[i])
+			}
+		}
+	})
+
+	t.Run("integers are numbers", func(t *testing.T) {
+		resp := getDistinct(t, handler, "/products:distinct?field=rating")
+		want := []any{float64(1), float64(2), float64(3), float64(4), float64(5)}
+		if len(resp.Values) != len(want) {
+			t.Fatalf("expected %d values, got %v", len(want), resp.Values)
+		}
+		for i, v := range want {
+			if resp.Values[i] != v {
+				t.Errorf("value %d: expected %v, got %v", i, v, resp.Values[i])
+			}
+		}
+	})
+
+	t.Run("booleans are converted", func(t *testing.T) {
+		resp := getDistinct(t, handler, "/products:distinct?field=active")
+		if len(resp.Values) != 2 || resp.Values[0] != false || resp.Values[1] != true {
+			t.Errorf("expected [false true], got %v", resp.Values)
+		}
+	})
+
+	t.Run("limit and filters", func(t *testing.T) {
+		resp := getDistinct(t, handler, "/products:distinct?field=category&limit=2&category[notnull]=true")
+		if resp.Count != 2 || resp.Values[0] != "books" || resp.Values[1] != "electronics" {
+			t.Errorf("expected [books electronics], got %v", resp.Values)
+		}
+	})
+
+	t.Run("counts per value", func(t *testing.T) {
+		resp := getDistinct(t, handler, "/products:distinct?field=rating&count=true&rating[gte]=4")
+		if resp.Count != 2 {
+			t.Fatalf("expected 2 values, got %+v", resp)
+		}
+		for i, want := range []float64{4, 5} {
+			pair, _ := resp.Values[i].(map[string]any)
+			if pair["value"] != want || pair["count"] != float64(60) {
+				t.Errorf("value %d: expected {%v 60}, got %v", i, want, resp.Values[i])
+			}
+		}
+	})
+}
+
+func TestAggregationHandler_Distinct_Validation(t *testing.T) {
+	handler := newDistinctTestHandler(t)
+
+	tests := []struct {
+		name           string
+		url            string
+		collection     string
+		expectedStatus int
+	}{
+		{"missing field", "/products:distinct", "products", http.StatusBadRequest},
+		{"unknown field", "/products:distinct?field=brand", "products", http.StatusBadRequest},
+		{"invalid limit", "/products:distinct?field=category&limit=abc", "products", http.StatusBadRequest},
+		{"zero limit", "/products:distinct?field=category&limit=0", "products", http.StatusBadRequest},
+		{"limit above max", "/products:distinct?field=category&limit=1001", "products", http.StatusBadRequest},
+		{"invalid count", "/products:distinct?field=category&count=maybe", "products", http.StatusBadRequest},
+		{"invalid filter", "/products:distinct?field=category&brand[eq]=x", "products", http.StatusBadRequest},
+		{"unknown collection", "/missing:distinct?field=category", "missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			handler.Distinct(w, req, tt.collection)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
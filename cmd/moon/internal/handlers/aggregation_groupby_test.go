@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func newGroupByTestHandler(t *testing.T) *AggregationHandler {
+	t.Helper()
+	driver := createTestDB(t)
+	t.Cleanup(func() { driver.Close() })
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "orders",
+		Columns: []registry.Column{
+			{Name: "total", Type: registry.TypeInteger},
+			{Name: "quantity", Type: registry.TypeInteger},
+			{Name: "status", Type: registry.TypeString},
+		},
+	})
+	return NewAggregationHandler(driver, reg)
+}
+
+func TestAggregationHandler_GroupBy_Integration(t *testing.T) {
+	handler := newGroupByTestHandler(t)
+
+	tests := []struct {
+		name string
+		url  string
+		want map[string]float64
+	}{
+		{
+			name: "count by status (default agg)",
+			url:  "/orders:groupby?by=status",
+			want: map[string]float64{"cancelled": 1, "completed": 3, "pending": 1},
+		},
+		{
+			name: "sum total by status",
+			url:  "/orders:groupby?by=status&agg=sum&field=total",
+			want: map[string]float64{"cancelled": 50, "completed": 600, "pending": 150},
+		},
+		{
+			name: "max total by status with filter",
+			url:  "/orders:groupby?by=status&agg=max&field=total&status[ne]=cancelled",
+			want: map[string]float64{"completed": 300, "pending": 150},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			handler.GroupBy(w, req, "orders")
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp GroupByResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Count != len(tt.want) || len(resp.Groups) != len(tt.want) {
+				t.Fatalf("expected %d groups, got %+v", len(tt.want), resp)
+			}
+			for _, g := range resp.Groups {
+				key, _ := g.Key.(string)
+				value, _ := g.Value.(float64)
+				if want, ok := tt.want[key]; !ok || value != want {
+					t.Errorf("group %q: expected %v, got %v", key, want, g.Value)
+				}
+			}
+			// Groups are ordered by key
+			for i := 1; i < len(resp.Groups); i++ {
+				prev, _ := resp.Groups[i-1].Key.(string)
+				cur, _ := resp.Groups[i].Key.(string)
+				if prev > cur {
+					t.Errorf("groups not ordered by key: %q before %q", prev, cur)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregationHandler_GroupBy_Validation(t *testing.T) {
+	handler := newGroupByTestHandler(t)
+
+	tests := []struct {
+		name           string
+		url            string
+		collection     string
+		expectedStatus int
+	}{
+		{"missing by", "/orders:groupby", "orders", http.StatusBadRequest},
+		{"unknown group column", "/orders:groupby?by=region", "orders", http.StatusBadRequest},
+		{"invalid agg", "/orders:groupby?by=status&agg=median&field=total", "orders", http.StatusBadRequest},
+		{"missing field for sum", "/orders:groupby?by=status&agg=sum", "orders", http.StatusBadRequest},
+		{"non-numeric field", "/orders:groupby?by=status&agg=avg&field=status", "orders", http.StatusBadRequest},
+		{"invalid filter", "/orders:groupby?by=status&region[eq]=x", "orders", http.StatusBadRequest},
+		{"unknown collection", "/missing:groupby?by=status", "missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			handler.GroupBy(w, req, tt.collection)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAggregationHandler_GroupBy_DecimalKeys(t *testing.T) {
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "price", "type": "decimal", "nullable": false},
+			{"name": "stock", "type": "integer", "nullable": false},
+		},
+	},
+		map[string]any{"price": "10.50", "stock": 1},
+		map[string]any{"price": "10.50", "stock": 2},
+		map[string]any{"price": "3.00", "stock": 4},
+	)
+	handler := NewAggregationHandler(nt.driver, nt.reg)
+
+	req := httptest.NewRequest(http.MethodGet, "/notes:groupby?by=price&agg=sum&field=stock", nil)
+	w := httptest.NewRecorder()
+	handler.GroupBy(w, req, "notes")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp GroupByResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// Keys are decimal strings with the column scale, as in list responses
+	want := map[string]float64{"3.00": 4, "10.50": 3}
+	if len(resp.Groups) != len(want) {
+		t.Fatalf("expected %d groups, got %+v", len(want), resp)
+	}
+	for _, g := range resp.Groups {
+		key, ok := g.Key.(string)
+		if !ok {
+			t.Errorf("expected a decimal string key, got %T %v", g.Key, g.Key)
+			continue
+		}
+		if value, _ := g.Value.(float64); value != want[key] {
+			t.Errorf("group %q: expected %v, got %v", key, want[key], g.Value)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupAggregationTypesTest creates a notes collection with a column of each
+// type and three records
+func setupAggregationTypesTest(t *testing.T) *AggregationHandler {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "stock", "type": "integer", "nullable": false},
+			{"name": "price", "type": "decimal", "nullable": false},
+			{"name": "due", "type": "datetime", "nullable": true},
+			{"name": "done", "type": "boolean", "nullable": false},
+			{"name": "meta", "type": "json", "nullable": true},
+		},
+	},
+		map[string]any{"title": "Banana", "stock": 5, "price": "2.50", "due": "2026-03-01T10:00:00Z", "done": true},
+		map[string]any{"title": "apple", "stock": 3, "price": "10.00", "due": "2026-01-15T08:30:00+02:00", "done": false},
+		map[string]any{"title": "Cherry", "stock": 8, "price": "0.75", "done": false},
+	)
+	return NewAggregationHandler(nt.driver, nt.reg)
+}
+
+func TestAggregation_FieldTypes(t *testing.T) {
+	agg := setupAggregationTypesTest(t)
+
+	tests := []struct {
+		name      string
+		action    func(http.ResponseWriter, *http.Request, string)
+		url       string
+		want      any
+		wantCount float64
+	}{
+		{"sum integer", agg.Sum, "/notes:sum?field=stock", float64(16), 3},
+		{"sum decimal", agg.Sum, "/notes:sum?field=price", "13.25", 3},
+		{"avg integer", agg.Avg, "/notes:avg?field=stock", 16.0 / 3, 3},
+		{"avg decimal", agg.Avg, "/notes:avg?field=price", "4.4166666667", 3},
+		{"min integer", agg.Min, "/notes:min?field=stock", float64(3), 3},
+		{"max integer", agg.Max, "/notes:max?field=stock", float64(8), 3},
+		{"min decimal", agg.Min, "/notes:min?field=price", "0.75", 3},
+		{"max decimal", agg.Max, "/notes:max?field=price", "10.00", 3},
+		// Strings compare by their bytes, so upper case sorts first
+		{"min string", agg.Min, "/notes:min?field=title", "Banana", 3},
+		{"max string", agg.Max, "/notes:max?field=title", "apple", 3},
+		// Datetimes are stored and returned in UTC; the NULL due is not counted
+		{"min datetime", agg.Min, "/notes:min?field=due", "2026-01-15T06:30:00Z", 2},
+		{"max datetime", agg.Max, "/notes:max?field=due", "2026-03-01T10:00:00Z", 2},
+		{"max datetime with filter", agg.Max, "/notes:max?field=due&done[eq]=false", "2026-01-15T06:30:00Z", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := aggregate(tt.action, tt.url)
+			if status != http.StatusOK {
+				t.Fatalf("expected 200, got %d %v", status, resp)
+			}
+			if resp["value"] != tt.want || resp["count"] != tt.wantCount {
+				t.Errorf("expected value %v (%T) and count %v, got %v (%T) and %v",
+					tt.want, tt.want, tt.wantCount, resp["value"], resp["value"], resp["count"])
+			}
+		})
+	}
+
+	// The record timestamps are datetimes too
+	for _, url := range []string{"/notes:min?field=created_at", "/notes:max?field=updated_at"} {
+		action := agg.Min
+		if strings.Contains(url, ":max") {
+			action = agg.Max
+		}
+		status, resp := aggregate(action, url)
+		value, _ := resp["value"].(string)
+		if _, err := time.Parse(time.RFC3339, value); status != http.StatusOK || err != nil || resp["count"] != float64(3) {
+			t.Errorf("%s: expected an RFC3339 timestamp, got %d %v", url, status, resp)
+		}
+	}
+}
+
+func TestAggregation_EmptySet(t *testing.T) {
+	agg := setupAggregationTypesTest(t)
+
+	tests := []struct {
+		name   string
+		action func(http.ResponseWriter, *http.Request, string)
+		url    string
+		want   any
+	}{
+		{"count", agg.Count, "/notes:count?stock[gt]=100", float64(0)},
+		{"sum", agg.Sum, "/notes:sum?field=stock&stock[gt]=100", nil},
+		{"sum decimal", agg.Sum, "/notes:sum?field=price&stock[gt]=100", nil},
+		{"avg", agg.Avg, "/notes:avg?field=price&stock[gt]=100", nil},
+		{"min", agg.Min, "/notes:min?field=title&stock[gt]=100", nil},
+		{"max", agg.Max, "/notes:max?field=due&stock[gt]=100", nil},
+		// Matching records whose values are all NULL cover no values either
+		{"max of nulls", agg.Max, "/notes:max?field=due&title[eq]=Cherry", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := aggregate(tt.action, tt.url)
+			if status != http.StatusOK {
+				t.Fatalf("expected 200, got %d %v", status, resp)
+			}
+			value, hasValue := resp["value"]
+			if !hasValue || value != tt.want || resp["count"] != float64(0) {
+				t.Errorf("expected value %v and count 0, got %v", tt.want, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupAggregationSearchTest creates a notes collection with three electronics
+// records mentioning laptop, one accessory mentioning laptop and one without it
+func setupAggregationSearchTest(t *testing.T) (*DataHandler, *AggregationHandler) {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "category", "type": "string", "nullable": false},
+			{"name": "stock", "type": "integer", "nullable": false},
+		},
+	},
+		map[string]any{"title": "Laptop Pro", "category": "electronics", "stock": 5},
+		map[string]any{"title": "Laptop Air", "category": "electronics", "stock": 3},
+		map[string]any{"title": "Gaming laptop", "category": "electronics", "stock": 0},
+		map[string]any{"title": "Laptop bag", "category": "accessories", "stock": 10},
+		map[string]any{"title": "Monitor", "category": "electronics", "stock": 2},
+	)
+	return nt.data, NewAggregationHandler(nt.driver, nt.reg)
+}
+
+// aggregate runs an aggregation and returns its status and decoded body
+func aggregate(action func(http.ResponseWriter, *http.Request, string), url string) (int, map[string]any) {
+	w := httptest.NewRecorder()
+	action(w, httptest.NewRequest(http.MethodGet, url, nil), "notes")
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestAggregation_Search(t *testing.T) {
+	data, agg := setupAggregationSearchTest(t)
+	params := "q=laptop&category[eq]=electronics"
+
+	titles, _ := listTitles(t, data, "/notes:list?"+params)
+	if len(titles) != 3 {
+		t.Fatalf("expected 3 listed records, got %v", titles)
+	}
+
+	tests := []struct {
+		name   string
+		action func(http.ResponseWriter, *http.Request, string)
+		url    string
+		want   float64
+	}{
+		{"count", agg.Count, "/notes:count?" + params, 3},
+		{"sum", agg.Sum, "/notes:sum?field=stock&" + params, 8},
+		{"avg", agg.Avg, "/notes:avg?field=stock&" + params, 8.0 / 3},
+		{"min", agg.Min, "/notes:min?field=stock&" + params, 0},
+		{"max", agg.Max, "/notes:max?field=stock&" + params, 5},
+		{"search only", agg.Count, "/notes:count?q=laptop", 4},
+		{"search fields", agg.Count, "/notes:count?q=electronics&q_fields=title", 0},
+		{"wildcards match literally", agg.Count, "/notes:count?q=%25", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := aggregate(tt.action, tt.url)
+			if status != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %v", status, resp)
+			}
+			if resp["value"] != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, resp["value"])
+			}
+		})
+	}
+
+	status, resp := aggregate(agg.GroupBy, "/notes:groupby?by=category&"+"q=laptop")
+	if status != http.StatusOK || resp["count"] != float64(2) {
+		t.Errorf("expected 2 groups of searched records, got %d %v", status, resp)
+	}
+	status, resp = aggregate(agg.Distinct, "/notes:distinct?field=title&"+params)
+	if status != http.StatusOK || resp["count"] != float64(3) {
+		t.Errorf("expected 3 distinct searched titles, got %d %v", status, resp)
+	}
+
+	// Aggregations report the same validation errors as :list
+	status, resp = aggregate(agg.Count, "/notes:count?q=laptop&q_fields=stock")
+	if status != http.StatusBadRequest || resp["code"] != string(apperrors.CodeInvalidParameter) {
+		t.Errorf("expected invalid_parameter for a non-string search field, got %d %v", status, resp)
+	}
+	status, resp = aggregate(agg.Count, "/notes:count?missing[eq]=1")
+	if status != http.StatusBadRequest || resp["code"] != string(apperrors.CodeInvalidFilter) {
+		t.Errorf("expected invalid_filter for an unknown column, got %d %v", status, resp)
+	}
+}
+
+func TestAggregation_FilterTree(t *testing.T) {
+	_, agg := setupAggregationSearchTest(t)
+	agg.SetStrictQueryParams(true)
+	filter := "filter=" + url.QueryEscape(`{"or": [{"category": {"eq": "accessories"}}, {"stock": {"gte": 5}}]}`)
+
+	tests := []struct {
+		name   string
+		action func(http.ResponseWriter, *http.Request, string)
+		url    string
+		want   float64
+	}{
+		{"count", agg.Count, "/notes:count?" + filter, 2},
+		{"sum", agg.Sum, "/notes:sum?field=stock&" + filter, 15},
+		{"avg", agg.Avg, "/notes:avg?field=stock&" + filter, 7.5},
+		{"min", agg.Min, "/notes:min?field=stock&" + filter, 5},
+		{"max", agg.Max, "/notes:max?field=stock&" + filter, 10},
+		{"with filters and search", agg.Count, "/notes:count?q=laptop&stock[lt]=10&" + filter, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := aggregate(tt.action, tt.url)
+			if status != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %v", status, resp)
+			}
+			if resp["value"] != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, resp["value"])
+			}
+		})
+	}
+
+	status, resp := aggregate(agg.GroupBy, "/notes:groupby?by=category&agg=sum&field=stock&"+filter)
+	if status != http.StatusOK || resp["count"] != float64(2) {
+		t.Errorf("expected 2 groups of filtered records, got %d %v", status, resp)
+	}
+	status, resp = aggregate(agg.Distinct, "/notes:distinct?field=title&"+filter)
+	if status != http.StatusOK || resp["count"] != float64(2) {
+		t.Errorf("expected 2 distinct filtered titles, got %d %v", status, resp)
+	}
+	status, resp = aggregate(agg.Distinct, "/notes:distinct?field=title&filter="+url.QueryEscape(`{"or": []}`))
+	if status != http.StatusBadRequest || resp["code"] != string(apperrors.CodeInvalidFilter) {
+		t.Errorf("expected invalid_filter on :distinct, got %d %v", status, resp)
+	}
+
+	// Malformed trees are rejected as on :query
+	for _, bad := range []string{`{"or": []}`, `{"missing": {"eq": 1}}`, `{"stock": {"near": 1}}`, `[1]`} {
+		status, resp := aggregate(agg.Sum, "/notes:sum?field=stock&filter="+url.QueryEscape(bad))
+		if status != http.StatusBadRequest || resp["code"] != string(apperrors.CodeInvalidFilter) {
+			t.Errorf("expected invalid_filter for %s, got %d %v", bad, status, resp)
+		}
+	}
+}
+
+func TestAggregation_Search_PostgresPlaceholders(t *testing.T) {
+	collection := &registry.Collection{
+		Name: "notes",
+		Columns: []registry.Column{
+			{Name: "title", Type: registry.TypeString},
+			{Name: "category", Type: registry.TypeString},
+			{Name: "stock", Type: registry.TypeInteger},
+		},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/notes:count?q=100%25_off&q_fields=title&category[eq]=electronics&stock[gt]=1", nil)
+	conditions, err := parseRecordConditions(req, collection)
+	if err != nil {
+		t.Fatalf("parseRecordConditions failed: %v", err)
+	}
+
+	sql, args := query.NewBuilder(database.DialectPostgres).Count("notes", conditions)
+	want := `SELECT COUNT(*) FROM "notes" WHERE "category" = $1 AND "stock" > $2 AND ("title" LIKE $3)`
+	if sql != want {
+		t.Errorf("unexpected count SQL:\n got %s\nwant %s", sql, want)
+	}
+	if len(args) != 3 || args[2] != `%100\%\_off%` {
+		t.Errorf("unexpected count args: %v", args)
+	}
+
+	// The list counts the records with the same WHERE clause and args
+	where, listArgs := buildListWhere(conditions, "", nil, database.DialectPostgres)
+	if buildCountQuery("notes", where, database.DialectPostgres) != want {
+		t.Errorf("expected the list count to match :count, got %s", where)
+	}
+	if len(listArgs) != len(args) {
+		t.Errorf("expected %d list args, got %v", len(args), listArgs)
+	}
+}
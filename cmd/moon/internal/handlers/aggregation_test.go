@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 
@@ -76,50 +77,44 @@ func (m *mockAggDriver) TableExists(ctx context.Context, tableName string) (bool
 	return false, nil
 }
 
-func TestValidateNumericField(t *testing.T) {
+func (m *mockAggDriver) TableStats(ctx context.Context, tableName string) (*database.TableStats, error) {
+	return &database.TableStats{}, nil
+}
+
+func TestAggregateField(t *testing.T) {
 	collection := &registry.Collection{
 		Name: "orders",
 		Columns: []registry.Column{
 			{Name: "total", Type: registry.TypeInteger},
-			{Name: "quantity", Type: registry.TypeInteger},
+			{Name: "price", Type: registry.TypeDecimal},
 			{Name: "status", Type: registry.TypeString},
+			{Name: "shipped_at", Type: registry.TypeDatetime},
+			{Name: "paid", Type: registry.TypeBoolean},
+			{Name: "meta", Type: registry.TypeJSON},
 		},
 	}
 
-	tests := []struct {
-		name      string
-		fieldName string
-		wantError bool
-	}{
-		{
-			name:      "valid integer field total",
-			fieldName: "total",
-			wantError: false,
-		},
-		{
-			name:      "valid integer field quantity",
-			fieldName: "quantity",
-			wantError: false,
-		},
-		{
-			name:      "invalid non-numeric field",
-			fieldName: "status",
-			wantError: true,
-		},
-		{
-			name:      "invalid non-existent field",
-			fieldName: "unknown",
-			wantError: true,
-		},
+	// Fields each function accepts; every other combination is rejected
+	accepted := map[string][]string{
+		"sum": {"total", "price"},
+		"avg": {"total", "price"},
+		"min": {"total", "price", "status", "shipped_at", "created_at", "updated_at", "id"},
+		"max": {"total", "price", "status", "shipped_at", "created_at", "updated_at", "id"},
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := validateNumericField(collection, tt.fieldName)
-			if (err != nil) != tt.wantError {
-				t.Errorf("validateNumericField() error = %v, wantError %v", err, tt.wantError)
-			}
-		})
+	fields := []string{"total", "price", "status", "shipped_at", "paid", "meta", "created_at", "updated_at", "id", "unknown"}
+
+	for agg, want := range accepted {
+		for _, field := range fields {
+			t.Run(agg+"/"+field, func(t *testing.T) {
+				col, err := aggregateField(collection, field, agg)
+				if ok := slices.Contains(want, field); (err == nil) != ok {
+					t.Fatalf("aggregateField(%s, %s) error = %v, want accepted %v", field, agg, err, ok)
+				}
+				if err == nil && col.Name != field {
+					t.Errorf("expected column %s, got %+v", field, col)
+				}
+			})
+		}
 	}
 }
 
@@ -232,6 +227,8 @@ func TestAggregationHandler_NonNumericField(t *testing.T) {
 		Name: "orders",
 		Columns: []registry.Column{
 			{Name: "status", Type: registry.TypeString},
+			{Name: "paid", Type: registry.TypeBoolean},
+			{Name: "meta", Type: registry.TypeJSON},
 		},
 	}
 	reg.Set(collection)
@@ -243,26 +240,31 @@ func TestAggregationHandler_NonNumericField(t *testing.T) {
 		name    string
 		handler func(w http.ResponseWriter, r *http.Request, collection string)
 		url     string
+		wantMsg string
 	}{
 		{
 			name:    "sum non-numeric field",
 			handler: handler.Sum,
 			url:     "/api/v1/orders:sum?field=status",
+			wantMsg: "not numeric",
 		},
 		{
 			name:    "avg non-numeric field",
 			handler: handler.Avg,
 			url:     "/api/v1/orders:avg?field=status",
+			wantMsg: "not numeric",
 		},
 		{
-			name:    "min non-numeric field",
+			name:    "min boolean field",
 			handler: handler.Min,
-			url:     "/api/v1/orders:min?field=status",
+			url:     "/api/v1/orders:min?field=paid",
+			wantMsg: "cannot be used with min (type: boolean)",
 		},
 		{
-			name:    "max non-numeric field",
+			name:    "max json field",
 			handler: handler.Max,
-			url:     "/api/v1/orders:max?field=status",
+			url:     "/api/v1/orders:max?field=meta",
+			wantMsg: "cannot be used with max (type: json)",
 		},
 	}
 
@@ -278,8 +280,8 @@ func TestAggregationHandler_NonNumericField(t *testing.T) {
 			}
 
 			body := w.Body.String()
-			if !strings.Contains(body, "not numeric") {
-				t.Error("expected error message about non-numeric field")
+			if !strings.Contains(body, tt.wantMsg) {
+				t.Errorf("expected %q in the error, got %s", tt.wantMsg, body)
 			}
 		})
 	}
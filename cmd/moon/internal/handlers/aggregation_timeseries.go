@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// TimeSeriesBucket is the aggregate of the records of one interval
+type TimeSeriesBucket struct {
+	Bucket string `json:"bucket"`
+	Value  any    `json:"value"`
+}
+
+// TimeSeriesResponse represents response for time-bucketed aggregation
+type TimeSeriesResponse struct {
+	Interval string             `json:"interval"`
+	Buckets  []TimeSeriesBucket `json:"buckets"`
+	Count    int                `json:"count"`
+}
+
+// timeBucketLayouts are the formats of the buckets of query.TimeSeries
+var timeBucketLayouts = map[string]string{
+	"hour":  time.RFC3339,
+	"day":   time.DateOnly,
+	"week":  time.DateOnly,
+	"month": "2006-01",
+}
+
+// TimeSeries handles GET /{name}:timeseries?field={datetime}&interval={interval}&agg={function}&value_field={field}
+// Records are bucketed by a datetime field in UTC and aggregated per bucket.
+// Buckets without records between the first and last one, or the from and to
+// parameters, are filled in so that charts have no gaps.
+func (h *AggregationHandler) TimeSeries(w http.ResponseWriter, r *http.Request, collectionName string) {
+	if !h.checkQueryParams(w, r, collectionName, constants.SearchQueryParams, constants.TimeSeriesQueryParams) {
+		return
+	}
+	params := r.URL.Query()
+	field := params.Get("field")
+	if field == "" {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "field parameter is required")
+		return
+	}
+
+	interval := params.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	if err := query.ValidateTimeInterval(interval); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	agg := params.Get("agg")
+	if agg == "" {
+		agg = "count"
+	}
+	if err := query.ValidateAggregateFunction(agg); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	// Validate collection exists in registry
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	if err := timeSeriesField(collection, field); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+	if err := checkRevealedField(r, collectionName, collection, field); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	// Validate aggregated field (not needed for count)
+	valueField := params.Get("value_field")
+	var valueCol registry.Column
+	if agg != "count" {
+		if valueField == "" {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("value_field parameter is required for %s", agg))
+			return
+		}
+		var err error
+		if valueCol, err = aggregateField(collection, valueField, agg); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+			return
+		}
+		if revealsValues(agg) {
+			if err := checkRevealedField(r, collectionName, collection, valueField); err != nil {
+				writeAPIError(w, r, err)
+				return
+			}
+		}
+	}
+
+	// Select records by filters and search as :list does
+	conditions, err := parseRecordConditions(r, collection)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidFilter), err.Error())
+		return
+	}
+
+	// An explicit range limits the records to its buckets
+	var from, to time.Time
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := params.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := datetime.Parse(value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("invalid %s '%s': %v", bound.name, value, err))
+			return
+		}
+		*bound.value = truncateTime(t, interval)
+	}
+	if !from.IsZero() {
+		conditions = append(conditions, query.Condition{Column: field, Operator: query.OpGreaterThanOrEqual, Value: datetime.Format(from)})
+	}
+	if !to.IsZero() {
+		conditions = append(conditions, query.Condition{Column: field, Operator: query.OpLessThan, Value: datetime.Format(nextBucket(to, interval))})
+	}
+	if !from.IsZero() && !to.IsZero() {
+		if to.Before(from) {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "to must not be before from")
+			return
+		}
+		if err := checkBucketCount(from, to, interval); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+			return
+		}
+	}
+
+	builder := query.NewBuilder(h.db.Dialect())
+	sqlQuery, args := builder.TimeSeries(collectionName, field, interval, agg, valueField, conditions)
+
+	logQuery(r.Context(), "timeseries", sqlQuery, args)
+
+	ctx := r.Context()
+	rows, err := h.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to execute timeseries: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	values := make(map[time.Time]any)
+	var first, last time.Time
+	for rows.Next() {
+		var bucket sql.NullString
+		var value any
+		if err := rows.Scan(&bucket, &value); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to scan timeseries row: %v", err))
+			return
+		}
+		// Text that is not a datetime has no bucket
+		start, err := time.Parse(timeBucketLayouts[interval], bucket.String)
+		if !bucket.Valid || err != nil {
+			continue
+		}
+
+		var result any
+		if agg == "count" {
+			var count sql.NullInt64
+			err = count.Scan(value)
+			result = count.Int64
+		} else {
+			result, err = aggregateValue(value, valueCol.Type, agg)
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read timeseries value: %v", err))
+			return
+		}
+
+		values[start] = result
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read timeseries rows: %v", err))
+		return
+	}
+
+	if from.IsZero() {
+		from = first
+	}
+	if to.IsZero() {
+		to = last
+	}
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		// No records and no complete range
+		writeResponse(w, r, http.StatusOK, TimeSeriesResponse{Interval: interval, Buckets: []TimeSeriesBucket{}})
+		return
+	}
+	if err := checkBucketCount(from, to, interval); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	empty := emptyBucketValue(agg, valueCol.Type)
+	buckets := []TimeSeriesBucket{}
+	for start := from; !start.After(to); start = nextBucket(start, interval) {
+		value, ok := values[start]
+		if !ok {
+			value = empty
+		}
+		buckets = append(buckets, TimeSeriesBucket{Bucket: start.Format(timeBucketLayouts[interval]), Value: value})
+	}
+
+	writeResponse(w, r, http.StatusOK, TimeSeriesResponse{
+		Interval: interval,
+		Buckets:  buckets,
+		Count:    len(buckets),
+	})
+}
+
+// timeSeriesField checks that a field is a datetime column of the collection,
+// created_at and updated_at included
+func timeSeriesField(collection *registry.Collection, field string) error {
+	for _, col := range append(slices.Clone(collection.Columns), queryableSystemColumns()...) {
+		if col.Name != field {
+			continue
+		}
+		if col.Type != registry.TypeDatetime {
+			return fmt.Errorf("field '%s' is not a datetime (type: %s)", field, col.Type)
+		}
+		return nil
+	}
+	return fmt.Errorf("field '%s' not found in collection", field)
+}
+
+// checkBucketCount rejects a range of more than constants.MaxTimeSeriesBuckets
+// buckets, suggesting the next coarser interval
+func checkBucketCount(from, to time.Time, interval string) error {
+	count := 0
+	for start := from; !start.After(to); start = nextBucket(start, interval) {
+		if count++; count > constants.MaxTimeSeriesBuckets {
+			idx := slices.Index(query.TimeIntervals, interval)
+			if idx+1 < len(query.TimeIntervals) {
+				return fmt.Errorf("timeseries would return more than %d buckets; use a coarser interval such as %s or a shorter from/to range", constants.MaxTimeSeriesBuckets, query.TimeIntervals[idx+1])
+			}
+			return fmt.Errorf("timeseries would return more than %d buckets; use a shorter from/to range", constants.MaxTimeSeriesBuckets)
+		}
+	}
+	return nil
+}
+
+// truncateTime returns the start of the bucket holding t, in UTC. Weeks
+// start on Monday.
+func truncateTime(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+// nextBucket returns the start of the bucket after the one starting at start
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		return start.Add(time.Hour)
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// emptyBucketValue is the value of a bucket without records: zero for count
+// and sum, null for the other functions
+func emptyBucketValue(agg string, fieldType registry.ColumnType) any {
+	switch agg {
+	case "count":
+		return int64(0)
+	case "sum":
+		value, _ := aggregateValue(int64(0), fieldType, agg)
+		return value
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// newTimeSeriesTestHandler seeds events around the turn of May 2024; 1 June
+// 2024 is a Saturday
+func newTimeSeriesTestHandler(t *testing.T) *AggregationHandler {
+	t.Helper()
+	driver := newTestDriver(t)
+	ctx := context.Background()
+
+	if _, err := driver.Exec(ctx, `CREATE TABLE events (id TEXT PRIMARY KEY, placed_at TEXT, amount INTEGER NOT NULL, price NUMERIC, status TEXT NOT NULL)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for _, e := range []struct {
+		id       string
+		placedAt any
+		amount   int
+		price    string
+		status   string
+	}{
+		{"1", "2024-06-01T00:00:00Z", 10, "1.50", "paid"},
+		{"2", "2024-06-01T23:59:59Z", 5, "2.25", "paid"},
+		{"3", "2024-06-02T00:00:00Z", 7, "3.00", "refunded"},
+		{"4", "2024-06-04T12:00:00Z", 3, "4.00", "paid"},
+		{"5", nil, 100, "9.00", "paid"},
+	} {
+		if _, err := driver.Exec(ctx, "INSERT INTO events (id, placed_at, amount, price, status) VALUES (?, ?, ?, ?, ?)", e.id, e.placedAt, e.amount, e.price, e.status); err != nil {
+			t.Fatalf("Failed to insert event: %v", err)
+		}
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "events",
+		Columns: []registry.Column{
+			{Name: "placed_at", Type: registry.TypeDatetime, Nullable: true},
+			{Name: "amount", Type: registry.TypeInteger},
+			{Name: "price", Type: registry.TypeDecimal},
+			{Name: "status", Type: registry.TypeString},
+		},
+	})
+	return NewAggregationHandler(driver, reg)
+}
+
+func TestAggregationHandler_TimeSeries(t *testing.T) {
+	handler := newTimeSeriesTestHandler(t)
+
+	tests := []struct {
+		name string
+		url  string
+		want string // bucket=value pairs
+	}{
+		{
+			name: "count per day fills the gap",
+			url:  "/events:timeseries?field=placed_at&interval=day",
+			want: "2024-06-01=2 2024-06-02=1 2024-06-03=0 2024-06-04=1",
+		},
+		{
+			name: "sum per day with a filter",
+			url:  "/events:timeseries?field=placed_at&agg=sum&value_field=amount&status[eq]=paid",
+			want: "2024-06-01=15 2024-06-02=0 2024-06-03=0 2024-06-04=3",
+		},
+		{
+			name: "weeks start on Monday",
+			url:  "/events:timeseries?field=placed_at&interval=week",
+			want: "2024-05-27=3 2024-06-03=1",
+		},
+		{
+			name: "month",
+			url:  "/events:timeseries?field=placed_at&interval=month",
+			want: "2024-06=4",
+		},
+		{
+			name: "hours of an explicit range",
+			url:  "/events:timeseries?field=placed_at&interval=hour&from=2024-06-01T23:30:00Z&to=2024-06-02T01:00:00Z",
+			want: "2024-06-01T23:00:00Z=1 2024-06-02T00:00:00Z=1 2024-06-02T01:00:00Z=0",
+		},
+		{
+			name: "days of an explicit range",
+			url:  "/events:timeseries?field=placed_at&from=2024-05-30&to=2024-06-02",
+			want: "2024-05-30=0 2024-05-31=0 2024-06-01=2 2024-06-02=1",
+		},
+		{
+			name: "from only runs to the last record",
+			url:  "/events:timeseries?field=placed_at&from=2024-06-03",
+			want: "2024-06-03=0 2024-06-04=1",
+		},
+		{
+			name: "decimal sum",
+			url:  "/events:timeseries?field=placed_at&agg=sum&value_field=price&from=2024-06-02&to=2024-06-03",
+			want: "2024-06-02=3.00 2024-06-03=0.00",
+		},
+		{
+			name: "avg of an empty bucket is null",
+			url:  "/events:timeseries?field=placed_at&agg=avg&value_field=amount&from=2024-06-02&to=2024-06-03",
+			want: "2024-06-02=7 2024-06-03=<nil>",
+		},
+		{
+			name: "no records",
+			url:  "/events:timeseries?field=placed_at&status[eq]=none",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.TimeSeries(w, httptest.NewRequest(http.MethodGet, tt.url, nil), "events")
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp TimeSeriesResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var got []string
+			for _, b := range resp.Buckets {
+				got = append(got, b.Bucket+"="+bucketValueString(b.Value))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, strings.Join(got, " "))
+			}
+			if resp.Count != len(resp.Buckets) {
+				t.Errorf("expected count %d, got %d", len(resp.Buckets), resp.Count)
+			}
+		})
+	}
+}
+
+func TestAggregationHandler_TimeSeries_Errors(t *testing.T) {
+	handler := newTimeSeriesTestHandler(t)
+
+	tests := []struct {
+		url    string
+		status int
+		want   string
+	}{
+		{"/events:timeseries", http.StatusBadRequest, "field parameter is required"},
+		{"/events:timeseries?field=amount", http.StatusBadRequest, "not a datetime"},
+		{"/events:timeseries?field=missing", http.StatusBadRequest, "not found"},
+		{"/events:timeseries?field=placed_at&interval=year", http.StatusBadRequest, "invalid interval"},
+		{"/events:timeseries?field=placed_at&agg=median", http.StatusBadRequest, "invalid aggregate function"},
+		{"/events:timeseries?field=placed_at&agg=sum", http.StatusBadRequest, "value_field parameter is required"},
+		{"/events:timeseries?field=placed_at&agg=sum&value_field=status", http.StatusBadRequest, "not numeric"},
+		{"/events:timeseries?field=placed_at&from=yesterday", http.StatusBadRequest, "invalid from"},
+		{"/events:timeseries?field=placed_at&from=2024-06-02&to=2024-06-01", http.StatusBadRequest, "to must not be before from"},
+		{"/events:timeseries?field=placed_at&interval=hour&from=2024-01-01&to=2024-12-31", http.StatusBadRequest, "coarser interval such as day"},
+		{"/events:timeseries?field=placed_at&interval=month&from=1900-01-01&to=2100-01-01", http.StatusBadRequest, "shorter from/to range"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.TimeSeries(w, httptest.NewRequest(http.MethodGet, tt.url, nil), "events")
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: expected %d with %q, got %d: %s", tt.url, tt.status, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.TimeSeries(w, httptest.NewRequest(http.MethodGet, "/missing:timeseries?field=created_at", nil), "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown collection, got %d", w.Code)
+	}
+}
+
+// bucketValueString writes a bucket value as the tests compare it
+func bucketValueString(value any) string {
+	if value == nil {
+		return "<nil>"
+	}
+	data, _ := json.Marshal(value)
+	return strings.Trim(string(data), `"`)
+}
@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/audit"
 	"github.com/thalib/moon/cmd/moon/internal/auth"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 // APIKeysHandler handles API key management endpoints (admin only).
@@ -27,14 +31,6 @@ func NewAPIKeysHandler(db database.Driver, jwtSecret string, accessExpiry, refre
 	}
 }
 
-// Error codes for API key management.
-const (
-	ErrCodeInvalidKeyName   = "INVALID_KEY_NAME"
-	ErrCodeInvalidAction    = "INVALID_ACTION"
-	ErrCodeAPIKeyNotFound   = "APIKEY_NOT_FOUND"
-	ErrCodeAPIKeyNameExists = "APIKEY_NAME_EXISTS"
-)
-
 // API key name validation constants.
 const (
 	MinKeyNameLength     = 3
@@ -57,21 +53,28 @@ type APIKeyListResponse struct {
 
 // APIKeyPublicInfo represents public API key information (no actual key).
 type APIKeyPublicInfo struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description,omitempty"`
-	Role        string  `json:"role"`
-	CanWrite    bool    `json:"can_write"`
-	CreatedAt   string  `json:"created_at"`
-	LastUsedAt  *string `json:"last_used_at,omitempty"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Role        string      `json:"role"`
+	CanWrite    bool        `json:"can_write"`
+	Scopes      auth.Scopes `json:"scopes,omitempty"`
+	Tenant      string      `json:"tenant,omitempty"`
+	CreatedAt   string      `json:"created_at"`
+	LastUsedAt  *string     `json:"last_used_at,omitempty"`
+	ExpiresAt   *string     `json:"expires_at,omitempty"`
+	RevokedAt   *string     `json:"revoked_at,omitempty"`
 }
 
 // CreateAPIKeyRequest represents a request to create an API key.
 type CreateAPIKeyRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	Role        string `json:"role"`
-	CanWrite    *bool  `json:"can_write,omitempty"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Role        string      `json:"role"`
+	CanWrite    *bool       `json:"can_write,omitempty"`
+	Scopes      auth.Scopes `json:"scopes,omitempty"`
+	Tenant      string      `json:"tenant,omitempty"` // fixed at creation
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
 }
 
 // CreateAPIKeyResponse represents a response after creating an API key.
@@ -84,10 +87,11 @@ type CreateAPIKeyResponse struct {
 
 // UpdateAPIKeyRequest represents a request to update an API key.
 type UpdateAPIKeyRequest struct {
-	Name        *string `json:"name,omitempty"`
-	Description *string `json:"description,omitempty"`
-	CanWrite    *bool   `json:"can_write,omitempty"`
-	Action      string  `json:"action,omitempty"`
+	Name        *string      `json:"name,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	CanWrite    *bool        `json:"can_write,omitempty"`
+	Scopes      *auth.Scopes `json:"scopes,omitempty"`
+	Action      string       `json:"action,omitempty"`
 }
 
 // UpdateAPIKeyResponse represents a response after updating an API key.
@@ -118,16 +122,30 @@ func IsValidAPIKeyRole(role string) bool {
 	return false
 }
 
+// normalizeScopes lowercases scope collection names and validates the scopes.
+func normalizeScopes(scopes auth.Scopes) error {
+	for i := range scopes {
+		scopes[i].Collection = strings.ToLower(scopes[i].Collection)
+		if scopes[i].Collection == "" || scopes[i].Collection == auth.ScopeAllCollections {
+			continue
+		}
+		if err := validateCollectionName(scopes[i].Collection); err != nil {
+			return fmt.Errorf("scopes[%d]: %v", i, err)
+		}
+	}
+	return scopes.Validate()
+}
+
 // List handles GET /apikeys:list
 func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, "admin access required", ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, "admin access required")
 		return
 	}
 
@@ -148,7 +166,7 @@ func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
 		AfterID: after,
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list API keys")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to list API keys")
 		return
 	}
 
@@ -164,9 +182,9 @@ func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
 		publicKeys[i] = apiKeyToPublicInfo(key)
 	}
 
-	h.logAdminAction("apikey_list", claims.UserID, "")
+	h.logAdminAction(r, "apikey_list", claims.UserID, "")
 
-	writeJSON(w, http.StatusOK, APIKeyListResponse{
+	writeResponse(w, r, http.StatusOK, APIKeyListResponse{
 		APIKeys:    publicKeys,
 		NextCursor: nextCursor,
 		Limit:      limit,
@@ -176,13 +194,13 @@ func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
 // Get handles GET /apikeys:get?id={ulid}
 func (h *APIKeysHandler) Get(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	_, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, "admin access required", ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, "admin access required")
 		return
 	}
 
@@ -190,22 +208,22 @@ func (h *APIKeysHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	keyID := r.URL.Query().Get("id")
 	if keyID == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "id is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 
 	apiKey, err := h.apiKeyRepo.GetByID(ctx, keyID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get API key")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get API key")
 		return
 	}
 
 	if apiKey == nil {
-		writeErrorWithCode(w, http.StatusNotFound, "API key not found", ErrCodeAPIKeyNotFound)
+		writeError(w, r, http.StatusNotFound, apperrors.CodeAPIKeyNotFound, "API key not found")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
+	writeResponse(w, r, http.StatusOK, map[string]any{
 		"apikey": apiKeyToPublicInfo(apiKey),
 	})
 }
@@ -213,19 +231,19 @@ func (h *APIKeysHandler) Get(w http.ResponseWriter, r *http.Request) {
 // Create handles POST /apikeys:create
 func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, "admin access required", ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, "admin access required")
 		return
 	}
 
 	var req CreateAPIKeyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
@@ -233,47 +251,65 @@ func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// Validate required fields
 	if req.Name == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "name is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "name is required")
 		return
 	}
 	if req.Role == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "role is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "role is required")
 		return
 	}
 
 	// Validate name length
 	if len(req.Name) < MinKeyNameLength || len(req.Name) > MaxKeyNameLength {
-		writeErrorWithCode(w, http.StatusBadRequest, "name must be between 3 and 100 characters", ErrCodeInvalidKeyName)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidKeyName, "name must be between 3 and 100 characters")
 		return
 	}
 
 	// Validate description length
 	if len(req.Description) > MaxDescriptionLength {
-		writeErrorWithCode(w, http.StatusBadRequest, "description must not exceed 500 characters", ErrCodeInvalidFieldValue)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidFieldValue, "description must not exceed 500 characters")
 		return
 	}
 
 	// Validate role
 	if !IsValidAPIKeyRole(req.Role) {
-		writeErrorWithCode(w, http.StatusBadRequest, "role must be 'admin' or 'user'", ErrCodeInvalidRole)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRole, "role must be 'admin' or 'user'")
+		return
+	}
+
+	// Validate scopes
+	if err := normalizeScopes(req.Scopes); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidScope, err.Error())
+		return
+	}
+
+	// Validate tenant
+	if err := auth.ValidateTenant(req.Tenant); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidTenant, err.Error())
+		return
+	}
+
+	// Validate expiry
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidFieldValue, "expires_at must be in the future")
 		return
 	}
 
 	// Check if name exists
 	exists, err := h.apiKeyRepo.NameExists(ctx, req.Name, 0)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to check name")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check name")
 		return
 	}
 	if exists {
-		writeErrorWithCode(w, http.StatusConflict, "API key name already exists", ErrCodeAPIKeyNameExists)
+		writeError(w, r, http.StatusConflict, apperrors.CodeAPIKeyNameExists, "API key name already exists")
 		return
 	}
 
 	// Generate API key
 	rawKey, keyHash, err := auth.GenerateAPIKey()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to generate API key")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "failed to generate API key")
 		return
 	}
 
@@ -289,16 +325,20 @@ func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
 		KeyHash:     keyHash,
 		Role:        req.Role,
 		CanWrite:    canWrite,
+		Scopes:      req.Scopes,
+		Tenant:      req.Tenant,
+		ExpiresAt:   req.ExpiresAt,
 	}
 
 	if err := h.apiKeyRepo.Create(ctx, apiKey); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create API key")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to create API key")
 		return
 	}
 
-	h.logAdminAction("apikey_created", claims.UserID, apiKey.ID)
+	h.logAdminAction(r, "apikey_created", claims.UserID, apiKey.ID)
+	audit.AddRecords(r.Context(), apiKey.ID)
 
-	writeJSON(w, http.StatusCreated, CreateAPIKeyResponse{
+	writeResponse(w, r, http.StatusCreated, CreateAPIKeyResponse{
 		Message: "API key created successfully",
 		Warning: "Store this key securely. It will not be shown again.",
 		APIKey:  apiKeyToPublicInfo(apiKey),
@@ -309,13 +349,13 @@ func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
 // Update handles POST /apikeys:update?id={ulid}
 func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, "admin access required", ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, "admin access required")
 		return
 	}
 
@@ -323,24 +363,47 @@ func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	keyID := r.URL.Query().Get("id")
 	if keyID == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "id is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 
 	var req UpdateAPIKeyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
 	apiKey, err := h.apiKeyRepo.GetByID(ctx, keyID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get API key")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get API key")
 		return
 	}
 
 	if apiKey == nil {
-		writeErrorWithCode(w, http.StatusNotFound, "API key not found", ErrCodeAPIKeyNotFound)
+		writeError(w, r, http.StatusNotFound, apperrors.CodeAPIKeyNotFound, "API key not found")
+		return
+	}
+
+	// A revoked key cannot be rotated or revoked again
+	if apiKey.IsRevoked() && (req.Action == "rotate" || req.Action == "revoke") {
+		writeError(w, r, http.StatusConflict, apperrors.CodeAPIKeyRevoked, "API key has been revoked")
+		return
+	}
+
+	// Handle revoke action
+	if req.Action == "revoke" {
+		if err := h.apiKeyRepo.Revoke(ctx, apiKey); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to revoke API key")
+			return
+		}
+
+		h.logAdminAction(r, "apikey_revoked", claims.UserID, apiKey.ID)
+		audit.AddRecords(r.Context(), apiKey.ID)
+
+		writeResponse(w, r, http.StatusOK, UpdateAPIKeyResponse{
+			Message: "API key revoked successfully",
+			APIKey:  apiKeyToPublicInfo(apiKey),
+		})
 		return
 	}
 
@@ -348,18 +411,19 @@ func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Action == "rotate" {
 		rawKey, keyHash, err := auth.GenerateAPIKey()
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to generate new API key")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "failed to generate new API key")
 			return
 		}
 
 		if err := h.apiKeyRepo.UpdateKeyHash(ctx, apiKey.PKID, keyHash); err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to rotate API key")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to rotate API key")
 			return
 		}
 
-		h.logAdminAction("apikey_rotated", claims.UserID, apiKey.ID)
+		h.logAdminAction(r, "apikey_rotated", claims.UserID, apiKey.ID)
+		audit.AddRecords(r.Context(), apiKey.ID)
 
-		writeJSON(w, http.StatusOK, UpdateAPIKeyResponse{
+		writeResponse(w, r, http.StatusOK, UpdateAPIKeyResponse{
 			Message: "API key rotated successfully",
 			Warning: "Store this key securely. It will not be shown again.",
 			APIKey:  apiKeyToPublicInfo(apiKey),
@@ -370,7 +434,7 @@ func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	// Handle invalid action
 	if req.Action != "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "invalid action", ErrCodeInvalidAction)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidAction, "invalid action")
 		return
 	}
 
@@ -380,18 +444,18 @@ func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Name != nil {
 		// Validate name length
 		if len(*req.Name) < MinKeyNameLength || len(*req.Name) > MaxKeyNameLength {
-			writeErrorWithCode(w, http.StatusBadRequest, "name must be between 3 and 100 characters", ErrCodeInvalidKeyName)
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidKeyName, "name must be between 3 and 100 characters")
 			return
 		}
 
 		// Check if name exists for another key
 		exists, err := h.apiKeyRepo.NameExists(ctx, *req.Name, apiKey.PKID)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to check name")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check name")
 			return
 		}
 		if exists {
-			writeErrorWithCode(w, http.StatusConflict, "API key name already exists", ErrCodeAPIKeyNameExists)
+			writeError(w, r, http.StatusConflict, apperrors.CodeAPIKeyNameExists, "API key name already exists")
 			return
 		}
 
@@ -402,7 +466,7 @@ func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Description != nil {
 		// Validate description length
 		if len(*req.Description) > MaxDescriptionLength {
-			writeErrorWithCode(w, http.StatusBadRequest, "description must not exceed 500 characters", ErrCodeInvalidFieldValue)
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidFieldValue, "description must not exceed 500 characters")
 			return
 		}
 		apiKey.Description = *req.Description
@@ -414,19 +478,30 @@ func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 		updated = true
 	}
 
+	// An empty scopes list removes the restriction
+	if req.Scopes != nil {
+		if err := normalizeScopes(*req.Scopes); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidScope, err.Error())
+			return
+		}
+		apiKey.Scopes = *req.Scopes
+		updated = true
+	}
+
 	if !updated {
-		writeError(w, http.StatusBadRequest, "no fields to update")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "no fields to update")
 		return
 	}
 
 	if err := h.apiKeyRepo.UpdateMetadata(ctx, apiKey); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update API key")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to update API key")
 		return
 	}
 
-	h.logAdminAction("apikey_updated", claims.UserID, apiKey.ID)
+	h.logAdminAction(r, "apikey_updated", claims.UserID, apiKey.ID)
+	audit.AddRecords(r.Context(), apiKey.ID)
 
-	writeJSON(w, http.StatusOK, UpdateAPIKeyResponse{
+	writeResponse(w, r, http.StatusOK, UpdateAPIKeyResponse{
 		Message: "API key updated successfully",
 		APIKey:  apiKeyToPublicInfo(apiKey),
 	})
@@ -435,13 +510,13 @@ func (h *APIKeysHandler) Update(w http.ResponseWriter, r *http.Request) {
 // Destroy handles POST /apikeys:destroy?id={ulid}
 func (h *APIKeysHandler) Destroy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, "admin access required", ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, "admin access required")
 		return
 	}
 
@@ -449,29 +524,30 @@ func (h *APIKeysHandler) Destroy(w http.ResponseWriter, r *http.Request) {
 
 	keyID := r.URL.Query().Get("id")
 	if keyID == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "id is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 
 	apiKey, err := h.apiKeyRepo.GetByID(ctx, keyID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get API key")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get API key")
 		return
 	}
 
 	if apiKey == nil {
-		writeErrorWithCode(w, http.StatusNotFound, "API key not found", ErrCodeAPIKeyNotFound)
+		writeError(w, r, http.StatusNotFound, apperrors.CodeAPIKeyNotFound, "API key not found")
 		return
 	}
 
 	if err := h.apiKeyRepo.Delete(ctx, apiKey.PKID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to delete API key")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to delete API key")
 		return
 	}
 
-	h.logAdminAction("apikey_deleted", claims.UserID, keyID)
+	h.logAdminAction(r, "apikey_deleted", claims.UserID, keyID)
+	audit.AddRecords(r.Context(), keyID)
 
-	writeJSON(w, http.StatusOK, DeleteAPIKeyResponse{
+	writeResponse(w, r, http.StatusOK, DeleteAPIKeyResponse{
 		Message: "API key deleted successfully",
 	})
 }
@@ -506,11 +582,12 @@ func (h *APIKeysHandler) validateAdminAccess(r *http.Request) (*auth.Claims, err
 }
 
 // logAdminAction logs an admin action for audit purposes.
-func (h *APIKeysHandler) logAdminAction(action, adminULID, targetULID string) {
+func (h *APIKeysHandler) logAdminAction(r *http.Request, action, adminULID, targetULID string) {
+	ip := getClientIP(r)
 	if targetULID != "" {
-		log.Printf("INFO: ADMIN_ACTION %s by=%s key_id=%s", action, adminULID, targetULID)
+		log.Printf("INFO: ADMIN_ACTION %s by=%s key_id=%s ip=%s", action, adminULID, targetULID, ip)
 	} else {
-		log.Printf("INFO: ADMIN_ACTION %s by=%s", action, adminULID)
+		log.Printf("INFO: ADMIN_ACTION %s by=%s ip=%s", action, adminULID, ip)
 	}
 }
 
@@ -522,6 +599,8 @@ func apiKeyToPublicInfo(apiKey *auth.APIKey) APIKeyPublicInfo {
 		Description: apiKey.Description,
 		Role:        apiKey.Role,
 		CanWrite:    apiKey.CanWrite,
+		Scopes:      apiKey.Scopes,
+		Tenant:      apiKey.Tenant,
 		CreatedAt:   apiKey.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 
@@ -530,5 +609,15 @@ func apiKeyToPublicInfo(apiKey *auth.APIKey) APIKeyPublicInfo {
 		info.LastUsedAt = &lastUsed
 	}
 
+	if apiKey.ExpiresAt != nil {
+		expires := apiKey.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z")
+		info.ExpiresAt = &expires
+	}
+
+	if apiKey.RevokedAt != nil {
+		revoked := apiKey.RevokedAt.UTC().Format("2006-01-02T15:04:05Z")
+		info.RevokedAt = &revoked
+	}
+
 	return info
 }
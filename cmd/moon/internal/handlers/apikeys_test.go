@@ -6,10 +6,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 func setupTestAPIKeysHandler(t *testing.T) (*APIKeysHandler, *auth.User, string, database.Driver) {
@@ -256,8 +260,8 @@ func TestAPIKeysHandler_Create_MissingName(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeMissingRequiredField {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeMissingRequiredField)
+	if resp["code"] != string(apperrors.CodeRequiredField) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeRequiredField)
 	}
 }
 
@@ -283,8 +287,97 @@ func TestAPIKeysHandler_Create_MissingRole(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeMissingRequiredField {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeMissingRequiredField)
+	if resp["code"] != string(apperrors.CodeRequiredField) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeRequiredField)
+	}
+}
+
+func TestAPIKeysHandler_Create_WithScopes(t *testing.T) {
+	handler, _, adminToken, db := setupTestAPIKeysHandler(t)
+	defer db.Close()
+
+	body := CreateAPIKeyRequest{
+		Name:   "scoped-key",
+		Role:   "user",
+		Scopes: auth.Scopes{{Collection: "Products", Actions: []string{auth.ScopeRead}}},
+	}
+	bodyBytes, _ := json.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/apikeys:create", bytes.NewReader(bodyBytes))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.Create(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Create() status = %d, want %d, body: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var resp CreateAPIKeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.APIKey.Scopes) != 1 || resp.APIKey.Scopes[0].Collection != "products" {
+		t.Errorf("Create() scopes = %+v, want products scope", resp.APIKey.Scopes)
+	}
+}
+
+func TestAPIKeysHandler_Create_InvalidScope(t *testing.T) {
+	handler, _, adminToken, db := setupTestAPIKeysHandler(t)
+	defer db.Close()
+
+	for _, scopes := range []auth.Scopes{
+		{{Collection: "products", Actions: []string{"delete"}}},
+		{{Collection: "products"}},
+		{{Collection: "1bad", Actions: []string{auth.ScopeRead}}},
+	} {
+		bodyBytes, _ := json.Marshal(CreateAPIKeyRequest{Name: "scoped-key", Role: "user", Scopes: scopes})
+
+		req := httptest.NewRequest(http.MethodPost, "/apikeys:create", bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		handler.Create(w, req)
+
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusBadRequest || resp["code"] != string(apperrors.CodeInvalidScope) {
+			t.Errorf("Create() with scopes %+v = %d %v, want 400 %v", scopes, w.Code, resp["code"], apperrors.CodeInvalidScope)
+		}
+	}
+}
+
+func TestAPIKeysHandler_Create_Tenant(t *testing.T) {
+	handler, _, adminToken, db := setupTestAPIKeysHandler(t)
+	defer db.Close()
+
+	create := func(name, tenant string) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(CreateAPIKeyRequest{Name: name, Role: "admin", Tenant: tenant})
+		req := httptest.NewRequest(http.MethodPost, "/apikeys:create", bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.Create(w, req)
+		return w
+	}
+
+	w := create("acme-key", "acme")
+	var created CreateAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if w.Code != http.StatusCreated || created.APIKey.Tenant != "acme" {
+		t.Errorf("Create() with tenant = %d %+v, want 201 with tenant acme", w.Code, created.APIKey)
+	}
+
+	for _, tenant := range []string{"Acme", "1acme", "ac_me", "averyveryverylongtenantname"} {
+		w := create("bad-"+tenant, tenant)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusBadRequest || resp["code"] != string(apperrors.CodeInvalidTenant) {
+			t.Errorf("Create() with tenant %q = %d %v, want 400 %v", tenant, w.Code, resp["code"], apperrors.CodeInvalidTenant)
+		}
 	}
 }
 
@@ -311,8 +404,8 @@ func TestAPIKeysHandler_Create_InvalidRole(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeInvalidRole {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeInvalidRole)
+	if resp["code"] != string(apperrors.CodeInvalidRole) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeInvalidRole)
 	}
 }
 
@@ -339,8 +432,8 @@ func TestAPIKeysHandler_Create_NameTooShort(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeInvalidKeyName {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeInvalidKeyName)
+	if resp["code"] != string(apperrors.CodeInvalidKeyName) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeInvalidKeyName)
 	}
 }
 
@@ -378,8 +471,8 @@ func TestAPIKeysHandler_Create_DuplicateName(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeAPIKeyNameExists {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeAPIKeyNameExists)
+	if resp["code"] != string(apperrors.CodeAPIKeyNameExists) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeAPIKeyNameExists)
 	}
 }
 
@@ -438,8 +531,8 @@ func TestAPIKeysHandler_Get_NotFound(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeAPIKeyNotFound {
-		t.Errorf("Get() error_code = %v, want %v", resp["error_code"], ErrCodeAPIKeyNotFound)
+	if resp["code"] != string(apperrors.CodeAPIKeyNotFound) {
+		t.Errorf("Get() code = %v, want %v", resp["code"], apperrors.CodeAPIKeyNotFound)
 	}
 }
 
@@ -595,8 +688,8 @@ func TestAPIKeysHandler_Update_InvalidAction(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeInvalidAction {
-		t.Errorf("Update() error_code = %v, want %v", resp["error_code"], ErrCodeInvalidAction)
+	if resp["code"] != string(apperrors.CodeInvalidAction) {
+		t.Errorf("Update() code = %v, want %v", resp["code"], apperrors.CodeInvalidAction)
 	}
 }
 
@@ -885,8 +978,8 @@ func TestAPIKeysHandler_Update_DuplicateName(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeAPIKeyNameExists {
-		t.Errorf("Update() error_code = %v, want %v", resp["error_code"], ErrCodeAPIKeyNameExists)
+	if resp["code"] != string(apperrors.CodeAPIKeyNameExists) {
+		t.Errorf("Update() code = %v, want %v", resp["code"], apperrors.CodeAPIKeyNameExists)
 	}
 }
 
@@ -1068,3 +1161,141 @@ func TestAPIKeysHandler_Update_MissingID(t *testing.T) {
 func hasPrefix(s, prefix string) bool {
 	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
 }
+
+func TestAPIKeysHandler_Create_KeyNotPersisted(t *testing.T) {
+	handler, _, adminToken, db := setupTestAPIKeysHandler(t)
+	defer db.Close()
+
+	bodyBytes, _ := json.Marshal(CreateAPIKeyRequest{Name: "secret-key", Role: "user"})
+	req := httptest.NewRequest(http.MethodPost, "/apikeys:create", bytes.NewReader(bodyBytes))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	handler.Create(w, req)
+
+	var resp CreateAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Key == "" {
+		t.Fatalf("Create() should return the key, body: %s", w.Body.String())
+	}
+
+	// Only the hash is stored: no column of any row holds the key
+	rows, err := db.Query(context.Background(), "SELECT * FROM "+constants.TableAPIKeys)
+	if err != nil {
+		t.Fatalf("failed to read API keys: %v", err)
+	}
+	defer rows.Close()
+	columns, _ := rows.Columns()
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatalf("failed to scan API key: %v", err)
+		}
+		for i, value := range values {
+			if v, ok := value.(string); ok && strings.Contains(v, resp.Key) {
+				t.Errorf("column %s holds the plaintext key", columns[i])
+			}
+			if v, ok := value.([]byte); ok && strings.Contains(string(v), resp.Key) {
+				t.Errorf("column %s holds the plaintext key", columns[i])
+			}
+		}
+	}
+
+	// Neither list nor get shows it
+	req = httptest.NewRequest(http.MethodGet, "/apikeys:list", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w = httptest.NewRecorder()
+	handler.List(w, req)
+	if strings.Contains(w.Body.String(), resp.Key) {
+		t.Error("List() should not return the key")
+	}
+}
+
+func TestAPIKeysHandler_Create_ExpiresAt(t *testing.T) {
+	handler, _, adminToken, db := setupTestAPIKeysHandler(t)
+	defer db.Close()
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      int
+	}{
+		{"expiring-key", time.Now().Add(24 * time.Hour), http.StatusCreated},
+		{"expired-key", time.Now().Add(-time.Minute), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		expiresAt := tt.expiresAt
+		bodyBytes, _ := json.Marshal(CreateAPIKeyRequest{Name: tt.name, Role: "user", ExpiresAt: &expiresAt})
+		req := httptest.NewRequest(http.MethodPost, "/apikeys:create", bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		handler.Create(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: Create() status = %d, want %d, body: %s", tt.name, w.Code, tt.want, w.Body.String())
+			continue
+		}
+		if tt.want != http.StatusCreated {
+			continue
+		}
+		var resp CreateAPIKeyResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if want := expiresAt.UTC().Format("2006-01-02T15:04:05Z"); resp.APIKey.ExpiresAt == nil || *resp.APIKey.ExpiresAt != want {
+			t.Errorf("%s: Create() expires_at = %v, want %s", tt.name, resp.APIKey.ExpiresAt, want)
+		}
+	}
+}
+
+func TestAPIKeysHandler_Update_Revoke(t *testing.T) {
+	handler, _, adminToken, db := setupTestAPIKeysHandler(t)
+	defer db.Close()
+
+	bodyBytes, _ := json.Marshal(CreateAPIKeyRequest{Name: "revoke-test-key", Role: "user"})
+	req := httptest.NewRequest(http.MethodPost, "/apikeys:create", bytes.NewReader(bodyBytes))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	handler.Create(w, req)
+
+	var createResp CreateAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&createResp)
+
+	update := func(action string) *httptest.ResponseRecorder {
+		bodyBytes, _ := json.Marshal(UpdateAPIKeyRequest{Action: action})
+		req := httptest.NewRequest(http.MethodPost, "/apikeys:update?id="+createResp.APIKey.ID, bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		handler.Update(w, req)
+		return w
+	}
+
+	w = update("revoke")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update() with revoke status = %d, want %d, body: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var resp UpdateAPIKeyResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.APIKey.RevokedAt == nil || resp.Key != "" {
+		t.Errorf("Update() with revoke should set revoked_at and return no key, got %+v", resp)
+	}
+
+	// The key is kept and listed as revoked
+	apiKey, _ := auth.NewAPIKeyRepository(db).GetByID(context.Background(), createResp.APIKey.ID)
+	if apiKey == nil || !apiKey.IsRevoked() {
+		t.Errorf("revoked key should be kept and marked revoked, got %+v", apiKey)
+	}
+
+	for _, action := range []string{"revoke", "rotate"} {
+		w = update(action)
+		if w.Code != http.StatusConflict {
+			t.Errorf("Update() with %s on a revoked key status = %d, want %d", action, w.Code, http.StatusConflict)
+		}
+		var errResp map[string]any
+		json.NewDecoder(w.Body).Decode(&errResp)
+		if errResp["code"] != string(apperrors.CodeAPIKeyRevoked) {
+			t.Errorf("Update() with %s on a revoked key code = %v, want %s", action, errResp["code"], apperrors.CodeAPIKeyRevoked)
+		}
+	}
+}
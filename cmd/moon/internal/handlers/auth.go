@@ -3,13 +3,18 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/audit"
 	"github.com/thalib/moon/cmd/moon/internal/auth"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 	"github.com/thalib/moon/cmd/moon/internal/middleware"
 )
 
@@ -92,18 +97,18 @@ type UpdateMeRequest struct {
 // Login handles POST /auth:login
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
 	if req.Username == "" || req.Password == "" {
-		writeError(w, http.StatusBadRequest, "username and password are required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "username and password are required")
 		return
 	}
 
@@ -114,7 +119,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	blocked, resetAt := h.loginRateLimiter.IsBlocked(clientIP, req.Username)
 	if blocked {
 		middleware.LogLoginRateLimitExceeded(clientIP, req.Username, r.URL.Path)
-		middleware.WriteLoginRateLimitError(w, resetAt)
+		middleware.WriteLoginRateLimitError(w, r, resetAt)
 		return
 	}
 
@@ -123,14 +128,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Get user by username
 	user, err := h.userRepo.GetByUsername(ctx, req.Username)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to authenticate")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "failed to authenticate")
 		return
 	}
 
 	if user == nil {
 		// Record failed attempt
 		h.loginRateLimiter.CheckAndRecord(clientIP, req.Username)
-		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials, "invalid credentials")
 		return
 	}
 
@@ -138,7 +143,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if err := auth.ComparePassword(user.PasswordHash, req.Password); err != nil {
 		// Record failed attempt
 		h.loginRateLimiter.CheckAndRecord(clientIP, req.Username)
-		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials, "invalid credentials")
 		return
 	}
 
@@ -148,7 +153,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Generate token pair
 	tokenPair, rawRefreshToken, err := h.tokenService.GenerateTokenPair(user)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to generate tokens")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "failed to generate tokens")
 		return
 	}
 
@@ -160,7 +165,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.tokenRepo.Create(ctx, refreshToken); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create session")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to create session")
 		return
 	}
 
@@ -169,6 +174,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		// Non-fatal error, log but continue
 	}
 
+	audit.SetActor(ctx, user.ID, audit.ActorUser)
 	response := LoginResponse{
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
@@ -183,33 +189,34 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // Logout handles POST /auth:logout
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var req RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
 	if req.RefreshToken == "" {
-		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "refresh_token is required")
 		return
 	}
 
 	ctx := r.Context()
 
-	// Delete the refresh token
+	// Revoke the refresh token; presenting it again is treated as reuse
 	tokenHash := auth.HashToken(req.RefreshToken)
-	if err := h.tokenRepo.DeleteByHash(ctx, tokenHash); err != nil {
-		// Non-fatal, token might already be deleted
+	if refreshToken, err := h.tokenRepo.GetByHash(ctx, tokenHash); err == nil && refreshToken != nil {
+		// Non-fatal, the token expires on its own
+		_ = h.tokenRepo.Revoke(ctx, refreshToken.PKID)
 	}
 
 	// Blacklist the current access token to invalidate it immediately
@@ -227,7 +234,7 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"message": "logged out successfully"})
+	writeResponse(w, r, http.StatusOK, map[string]string{"message": "logged out successfully"})
 }
 
 // extractAccessToken extracts the access token from the Authorization header
@@ -253,18 +260,18 @@ func (h *AuthHandler) extractAccessToken(r *http.Request) (string, error) {
 // Refresh handles POST /auth:refresh
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var req RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
 	if req.RefreshToken == "" {
-		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "refresh_token is required")
 		return
 	}
 
@@ -274,51 +281,74 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	tokenHash := auth.HashToken(req.RefreshToken)
 	refreshToken, err := h.tokenRepo.GetByHash(ctx, tokenHash)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to validate token")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "failed to validate token")
 		return
 	}
 
 	if refreshToken == nil {
-		writeError(w, http.StatusUnauthorized, "invalid refresh token")
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidToken, "invalid refresh token")
+		return
+	}
+
+	if refreshToken.Revoked {
+		// A rotated-out or logged-out token is being replayed, so whoever holds
+		// the rest of its family may be an attacker; end the whole session.
+		// Should that fail the family stays usable, so the replay is not
+		// answered as handled and a retry tries again.
+		if refreshToken.FamilyID != "" {
+			if err := h.tokenRepo.RevokeFamily(ctx, refreshToken.FamilyID); err != nil {
+				log.Printf("ERROR: Failed to revoke the session of a replayed refresh token (user_pkid=%d): %v", refreshToken.UserPKID, err)
+				writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to revoke session")
+				return
+			}
+		}
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeTokenRevoked, "refresh token has been revoked")
 		return
 	}
 
 	if refreshToken.IsExpired() {
-		// Delete expired token
-		h.tokenRepo.Delete(ctx, refreshToken.PKID)
-		writeError(w, http.StatusUnauthorized, "refresh token expired")
+		h.tokenRepo.DeleteExpired(ctx)
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeTokenExpired, "refresh token expired")
 		return
 	}
 
 	// Get user
 	user, err := h.userRepo.GetByPKID(ctx, refreshToken.UserPKID)
 	if err != nil || user == nil {
-		writeError(w, http.StatusUnauthorized, "user not found")
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidToken, "user not found")
 		return
 	}
 
 	// Generate new token pair
 	tokenPair, newRawRefreshToken, err := h.tokenService.GenerateTokenPair(user)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to generate tokens")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "failed to generate tokens")
 		return
 	}
 
-	// Delete old refresh token
-	h.tokenRepo.Delete(ctx, refreshToken.PKID)
+	// Rotate: the old token stays behind as revoked so reuse can be detected
+	if err := h.tokenRepo.Revoke(ctx, refreshToken.PKID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to rotate session")
+		return
+	}
 
-	// Store new refresh token
+	// Store new refresh token in the same family
 	newRefreshToken := &auth.RefreshToken{
 		UserPKID:  user.PKID,
 		TokenHash: auth.HashToken(newRawRefreshToken),
+		FamilyID:  refreshToken.FamilyID,
 		ExpiresAt: time.Now().Add(h.tokenService.RefreshExpiry()),
 	}
 
 	if err := h.tokenRepo.Create(ctx, newRefreshToken); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create session")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to create session")
 		return
 	}
 
+	// Purge expired rows lazily instead of running a background sweep
+	h.tokenRepo.DeleteExpired(ctx)
+
+	audit.SetActor(ctx, user.ID, audit.ActorUser)
 	response := LoginResponse{
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
@@ -333,7 +363,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // Me handles GET /auth:me (get current user) and POST /auth:me (update current user)
@@ -344,7 +374,7 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPost:
 		h.UpdateMe(w, r)
 	default:
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 	}
 }
 
@@ -353,7 +383,7 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from JWT token in Authorization header
 	userID, err := h.extractUserIDFromToken(r)
 	if err != nil {
-		writeError(w, http.StatusUnauthorized, "unauthorized")
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeUnauthorized, "unauthorized")
 		return
 	}
 
@@ -361,12 +391,12 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get user")
 		return
 	}
 
 	if user == nil {
-		writeError(w, http.StatusNotFound, "user not found")
+		writeError(w, r, http.StatusNotFound, apperrors.CodeUserNotFound, "user not found")
 		return
 	}
 
@@ -378,7 +408,7 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 		CanWrite: user.CanWrite,
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"user": response})
+	writeResponse(w, r, http.StatusOK, map[string]any{"user": response})
 }
 
 // UpdateMe handles POST /auth:me
@@ -386,13 +416,13 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from JWT token
 	userID, err := h.extractUserIDFromToken(r)
 	if err != nil {
-		writeError(w, http.StatusUnauthorized, "unauthorized")
+		writeError(w, r, http.StatusUnauthorized, apperrors.CodeUnauthorized, "unauthorized")
 		return
 	}
 
 	var req UpdateMeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
@@ -400,12 +430,12 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get user")
 		return
 	}
 
 	if user == nil {
-		writeError(w, http.StatusNotFound, "user not found")
+		writeError(w, r, http.StatusNotFound, apperrors.CodeUserNotFound, "user not found")
 		return
 	}
 
@@ -418,20 +448,20 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	if req.Password != "" {
 		// Require old password for password change
 		if req.OldPassword == "" {
-			writeError(w, http.StatusBadRequest, "old_password is required to change password")
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "old_password is required to change password")
 			return
 		}
 
 		// Verify old password
 		if err := auth.ComparePassword(user.PasswordHash, req.OldPassword); err != nil {
-			writeError(w, http.StatusUnauthorized, "invalid old password")
+			writeError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials, "invalid old password")
 			return
 		}
 
 		// Hash new password
 		newHash, err := auth.HashPassword(req.Password)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to update password")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to update password")
 			return
 		}
 		user.PasswordHash = newHash
@@ -439,7 +469,7 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 		// Password changed - revoke all refresh tokens to force re-login
 		if err := h.tokenRepo.DeleteAllByUserID(ctx, user.PKID); err != nil {
 			// Log but don't fail - password update is more important
-			writeError(w, http.StatusInternalServerError, "failed to revoke sessions")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to revoke sessions")
 			return
 		}
 
@@ -456,7 +486,7 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 
 	// Save changes
 	if err := h.userRepo.Update(ctx, user); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to update user")
 		return
 	}
 
@@ -473,7 +503,7 @@ func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 		message = "password updated successfully, please login again"
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
+	writeResponse(w, r, http.StatusOK, map[string]any{
 		"message": message,
 		"user":    response,
 	})
@@ -504,34 +534,16 @@ func (h *AuthHandler) extractUserIDFromToken(r *http.Request) (string, error) {
 	return claims.UserID, nil
 }
 
-// getClientIP extracts the client IP from the request.
-// It checks X-Forwarded-For and X-Real-IP headers first (for reverse proxy scenarios),
-// then falls back to RemoteAddr.
+// getClientIP returns the client IP resolved by the server from the peer
+// address and server.trusted_proxies. Forwarding headers are never read here,
+// so a client cannot pick its own rate limit bucket; without a resolved IP in
+// the context the peer address is used.
 func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (common in reverse proxies)
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		// X-Forwarded-For can contain multiple IPs; the first one is the client
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if ip != "" {
-				return ip
-			}
-		}
+	if ip := logging.GetClientIP(r.Context()); ip != "" {
+		return ip
 	}
-
-	// Check X-Real-IP header (used by nginx)
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return strings.TrimSpace(xri)
-	}
-
-	// Fall back to RemoteAddr
-	// RemoteAddr is in the form "IP:port", we need to extract just the IP
-	addr := r.RemoteAddr
-	if colonIndex := strings.LastIndex(addr, ":"); colonIndex != -1 {
-		return addr[:colonIndex]
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
 	}
-	return addr
+	return r.RemoteAddr
 }
@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 )
 
 func setupTestAuthHandler(t *testing.T) (*AuthHandler, database.Driver) {
@@ -292,6 +297,131 @@ func TestAuthHandler_Refresh_InvalidToken(t *testing.T) {
 	}
 }
 
+// loginTestUser creates a user and logs in, returning the issued tokens
+func loginTestUser(t *testing.T, handler *AuthHandler, db database.Driver) LoginResponse {
+	t.Helper()
+	passwordHash, _ := auth.HashPassword("testpassword123")
+	user := &auth.User{
+		Username:     "testuser",
+		Email:        "test@example.com",
+		PasswordHash: passwordHash,
+		Role:         "user",
+		CanWrite:     true,
+	}
+	if err := auth.NewUserRepository(db).Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	loginBytes, _ := json.Marshal(LoginRequest{Username: "testuser", Password: "testpassword123"})
+	w := httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodPost, "/auth:login", bytes.NewReader(loginBytes)))
+
+	var resp LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode login response: %v", err)
+	}
+	return resp
+}
+
+// postRefreshToken sends a refresh token to the refresh or logout action
+func postRefreshToken(action http.HandlerFunc, url, token string) *httptest.ResponseRecorder {
+	bodyBytes, _ := json.Marshal(RefreshRequest{RefreshToken: token})
+	w := httptest.NewRecorder()
+	action(w, httptest.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes)))
+	return w
+}
+
+func TestAuthHandler_Refresh_ReuseRevokesFamily(t *testing.T) {
+	handler, db := setupTestAuthHandler(t)
+	defer db.Close()
+
+	login := loginTestUser(t, handler, db)
+
+	w := postRefreshToken(handler.Refresh, "/auth:refresh", login.RefreshToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Refresh() status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var rotated LoginResponse
+	json.NewDecoder(w.Body).Decode(&rotated)
+
+	// The rotated-out token is rejected, and replaying it ends the session
+	assertErrorCode(t, postRefreshToken(handler.Refresh, "/auth:refresh", login.RefreshToken), http.StatusUnauthorized, apperrors.CodeTokenRevoked)
+	assertErrorCode(t, postRefreshToken(handler.Refresh, "/auth:refresh", rotated.RefreshToken), http.StatusUnauthorized, apperrors.CodeTokenRevoked)
+
+	// A fresh login starts an unaffected family
+	loginBytes, _ := json.Marshal(LoginRequest{Username: "testuser", Password: "testpassword123"})
+	lw := httptest.NewRecorder()
+	handler.Login(lw, httptest.NewRequest(http.MethodPost, "/auth:login", bytes.NewReader(loginBytes)))
+	var relogin LoginResponse
+	json.NewDecoder(lw.Body).Decode(&relogin)
+	if w := postRefreshToken(handler.Refresh, "/auth:refresh", relogin.RefreshToken); w.Code != http.StatusOK {
+		t.Errorf("Refresh() after new login status = %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthHandler_Refresh_ReuseRevokeFailure(t *testing.T) {
+	handler, db := setupTestAuthHandler(t)
+	defer db.Close()
+
+	login := loginTestUser(t, handler, db)
+	if w := postRefreshToken(handler.Refresh, "/auth:refresh", login.RefreshToken); w.Code != http.StatusOK {
+		t.Fatalf("Refresh() status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	// Revoking the family fails, so the replay is not reported as handled
+	ctx := context.Background()
+	trigger := fmt.Sprintf(`CREATE TRIGGER fail_revoke BEFORE UPDATE OF revoked ON %s BEGIN SELECT RAISE(ABORT, 'revoke failed'); END`, constants.TableRefreshTokens)
+	if _, err := db.Exec(ctx, trigger); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	assertErrorCode(t, postRefreshToken(handler.Refresh, "/auth:refresh", login.RefreshToken), http.StatusInternalServerError, apperrors.CodeDatabaseError)
+
+	// Once the database recovers, a retry of the replay ends the session
+	if _, err := db.Exec(ctx, "DROP TRIGGER fail_revoke"); err != nil {
+		t.Fatalf("failed to drop trigger: %v", err)
+	}
+	assertErrorCode(t, postRefreshToken(handler.Refresh, "/auth:refresh", login.RefreshToken), http.StatusUnauthorized, apperrors.CodeTokenRevoked)
+}
+
+func TestAuthHandler_Refresh_Expired(t *testing.T) {
+	handler, db := setupTestAuthHandler(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	login := loginTestUser(t, handler, db)
+
+	repo := auth.NewRefreshTokenRepository(db)
+	current, _ := repo.GetByHash(ctx, auth.HashToken(login.RefreshToken))
+	for _, raw := range []string{"expired-token", "other-expired-token"} {
+		token := &auth.RefreshToken{UserPKID: current.UserPKID, TokenHash: auth.HashToken(raw), ExpiresAt: time.Now().Add(-time.Hour)}
+		if err := repo.Create(ctx, token); err != nil {
+			t.Fatalf("failed to create expired token: %v", err)
+		}
+	}
+
+	assertErrorCode(t, postRefreshToken(handler.Refresh, "/auth:refresh", "expired-token"), http.StatusUnauthorized, apperrors.CodeTokenExpired)
+
+	// Expired rows are purged lazily; live tokens are untouched
+	if token, _ := repo.GetByHash(ctx, auth.HashToken("other-expired-token")); token != nil {
+		t.Error("expected expired refresh tokens to be purged")
+	}
+	if w := postRefreshToken(handler.Refresh, "/auth:refresh", login.RefreshToken); w.Code != http.StatusOK {
+		t.Errorf("Refresh() status = %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthHandler_Logout_RevokesRefreshToken(t *testing.T) {
+	handler, db := setupTestAuthHandler(t)
+	defer db.Close()
+
+	login := loginTestUser(t, handler, db)
+
+	if w := postRefreshToken(handler.Logout, "/auth:logout", login.RefreshToken); w.Code != http.StatusOK {
+		t.Fatalf("Logout() status = %d, body: %s", w.Code, w.Body.String())
+	}
+	assertErrorCode(t, postRefreshToken(handler.Refresh, "/auth:refresh", login.RefreshToken), http.StatusUnauthorized, apperrors.CodeTokenRevoked)
+}
+
 func TestAuthHandler_GetMe_Unauthorized(t *testing.T) {
 	handler, db := setupTestAuthHandler(t)
 	defer db.Close()
@@ -759,6 +889,7 @@ func TestGetClientIP(t *testing.T) {
 		remoteAddr string
 		xForwarded string
 		xRealIP    string
+		resolvedIP string
 		expectedIP string
 	}{
 		{
@@ -767,28 +898,27 @@ func TestGetClientIP(t *testing.T) {
 			expectedIP: "192.168.1.1",
 		},
 		{
-			name:       "X-Forwarded-For single",
-			remoteAddr: "10.0.0.1:12345",
-			xForwarded: "203.0.113.195",
-			expectedIP: "203.0.113.195",
+			name:       "IPv6 remote addr",
+			remoteAddr: "[2001:db8::1]:12345",
+			expectedIP: "2001:db8::1",
 		},
 		{
-			name:       "X-Forwarded-For multiple",
+			name:       "X-Forwarded-For is ignored",
 			remoteAddr: "10.0.0.1:12345",
-			xForwarded: "203.0.113.195, 70.41.3.18, 150.172.238.178",
-			expectedIP: "203.0.113.195",
+			xForwarded: "203.0.113.195",
+			expectedIP: "10.0.0.1",
 		},
 		{
-			name:       "X-Real-IP",
+			name:       "X-Real-IP is ignored",
 			remoteAddr: "10.0.0.1:12345",
 			xRealIP:    "203.0.113.100",
-			expectedIP: "203.0.113.100",
+			expectedIP: "10.0.0.1",
 		},
 		{
-			name:       "X-Forwarded-For takes precedence over X-Real-IP",
+			name:       "Resolved client IP takes precedence",
 			remoteAddr: "10.0.0.1:12345",
 			xForwarded: "203.0.113.195",
-			xRealIP:    "203.0.113.100",
+			resolvedIP: "203.0.113.195",
 			expectedIP: "203.0.113.195",
 		},
 	}
@@ -803,6 +933,9 @@ func TestGetClientIP(t *testing.T) {
 			if tt.xRealIP != "" {
 				req.Header.Set("X-Real-IP", tt.xRealIP)
 			}
+			if tt.resolvedIP != "" {
+				req = req.WithContext(logging.SetClientIP(req.Context(), tt.resolvedIP))
+			}
 
 			ip := getClientIP(req)
 			if ip != tt.expectedIP {
@@ -7,16 +7,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/config"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/query"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 )
 
@@ -31,8 +42,12 @@ var (
 
 	// System columns that cannot be added, removed, or renamed
 	systemColumns = map[string]bool{
-		"pkid": true,
-		"id":   true,
+		"pkid":                     true,
+		"id":                       true,
+		constants.CreatedAtColumn:  true,
+		constants.UpdatedAtColumn:  true,
+		constants.RevisionColumn:   true,
+		constants.SoftDeleteColumn: true,
 	}
 )
 
@@ -40,6 +55,7 @@ var (
 type CollectionsHandler struct {
 	db       database.Driver
 	registry *registry.SchemaRegistry
+	tenancy  bool
 }
 
 // NewCollectionsHandler creates a new collections handler
@@ -50,6 +66,58 @@ func NewCollectionsHandler(db database.Driver, reg *registry.SchemaRegistry) *Co
 	}
 }
 
+// SetTenancy enables tenant-prefixed tables for principals with a tenant
+func (h *CollectionsHandler) SetTenancy(enabled bool) {
+	h.tenancy = enabled
+}
+
+// tableName returns the physical table of a collection for the requesting
+// principal's tenant
+func (h *CollectionsHandler) tableName(r *http.Request, name string) string {
+	if !h.tenancy {
+		return name
+	}
+	return registry.TenantKey(middleware.GetTenant(r.Context()), name)
+}
+
+// logicalView returns the collection named as its tenant sees it, including
+// the collections its columns reference
+func (h *CollectionsHandler) logicalView(collection *registry.Collection) *registry.Collection {
+	if !h.tenancy || collection == nil {
+		return collection
+	}
+	return tenantView(collection)
+}
+
+// tenantView returns a copy of a tenant's collection without the tenant
+// prefix in its name and references. Collections of no tenant are returned
+// as they are.
+func tenantView(collection *registry.Collection) *registry.Collection {
+	tenant, name := registry.SplitTenantKey(collection.Name)
+	if tenant == "" {
+		return collection
+	}
+	view := *collection
+	view.Name = name
+	view.Columns = append([]registry.Column(nil), collection.Columns...)
+	for i := range view.Columns {
+		_, view.Columns[i].References = registry.SplitTenantKey(view.Columns[i].References)
+	}
+	return &view
+}
+
+// validateName applies validateCollectionName and, with tenancy enabled,
+// reserves the tenant separator for physical table names
+func (h *CollectionsHandler) validateName(name string) error {
+	if err := validateCollectionName(name); err != nil {
+		return err
+	}
+	if h.tenancy && strings.Contains(name, constants.TenantSeparator) {
+		return fmt.Errorf("collection name must not contain '%s'", constants.TenantSeparator)
+	}
+	return nil
+}
+
 // ListRequest represents the request for listing collections
 type ListRequest struct {
 	// Optional filters can be added here
@@ -57,8 +125,9 @@ type ListRequest struct {
 
 // CollectionItem represents a collection with its metadata
 type CollectionItem struct {
-	Name    string `json:"name"`
-	Records int    `json:"records"`
+	Name     string `json:"name"`
+	Records  int    `json:"records"`
+	Archived bool   `json:"archived,omitempty"` // listed with ?include_archived=true
 }
 
 // ListResponse represents the response for listing collections
@@ -79,13 +148,25 @@ type GetResponse struct {
 
 // CreateRequest represents the request for creating a collection
 type CreateRequest struct {
-	Name    string            `json:"name"`
-	Columns []registry.Column `json:"columns"`
+	Name            string               `json:"name"`
+	Columns         []registry.Column    `json:"columns"`
+	Indexes         []registry.Index     `json:"indexes,omitempty"`
+	SoftDelete      bool                 `json:"soft_delete,omitempty"`
+	RequireRevision bool                 `json:"require_revision,omitempty"`
+	ExposeSequence  bool                 `json:"expose_sequence,omitempty"`
+	IDType          registry.IDType      `json:"id_type,omitempty"` // ulid (default), uuidv7 or client
+	DefaultSort     []string             `json:"default_sort,omitempty"`
+	DefaultFields   []string             `json:"default_fields,omitempty"`
+	Pagination      *registry.Pagination `json:"pagination,omitempty"`
+	TTL             *registry.TTL        `json:"ttl,omitempty"`  // expiry of records, see validateTTL
+	UI              *registry.UI         `json:"ui,omitempty"`   // presentation metadata, see validateUI
+	Seed            []map[string]any     `json:"seed,omitempty"` // records inserted with the new table
 }
 
 // CreateResponse represents the response for creating a collection
 type CreateResponse struct {
 	Collection *registry.Collection `json:"collection"`
+	Seeded     int                  `json:"seeded,omitempty"` // number of seed records inserted
 	Message    string               `json:"message"`
 }
 
@@ -102,20 +183,42 @@ type ModifyColumn struct {
 	Nullable     *bool               `json:"nullable,omitempty"`
 	Unique       *bool               `json:"unique,omitempty"`
 	DefaultValue *string             `json:"default_value,omitempty"`
+
+	// Constraints replace those of the column; omitted ones are removed
+	MaxLength *int         `json:"max_length,omitempty"`
+	Min       *json.Number `json:"min,omitempty"`
+	Max       *json.Number `json:"max,omitempty"`
+	Enum      []string     `json:"enum,omitempty"`
+
+	// Hidden changes whether the column is left out of read responses; omitted keeps it
+	Hidden *bool `json:"hidden,omitempty"`
+
+	// Computed replaces the expression of a computed column and recomputes its
+	// records; an empty string makes it a plain column and omitted keeps it
+	Computed *string `json:"computed,omitempty"`
 }
 
 // UpdateRequest represents the request for updating a collection
 type UpdateRequest struct {
-	Name          string            `json:"name"`
-	AddColumns    []registry.Column `json:"add_columns,omitempty"`
-	RemoveColumns []string          `json:"remove_columns,omitempty"`
-	RenameColumns []RenameColumn    `json:"rename_columns,omitempty"`
-	ModifyColumns []ModifyColumn    `json:"modify_columns,omitempty"`
+	Name            string               `json:"name"`
+	AddColumns      []registry.Column    `json:"add_columns,omitempty"`
+	RemoveColumns   []string             `json:"remove_columns,omitempty"`
+	RenameColumns   []RenameColumn       `json:"rename_columns,omitempty"`
+	ModifyColumns   []ModifyColumn       `json:"modify_columns,omitempty"`
+	Indexes         []registry.Index     `json:"indexes,omitempty"`
+	RemoveIndexes   []string             `json:"remove_indexes,omitempty"`
+	RequireRevision *bool                `json:"require_revision,omitempty"`
+	ExposeSequence  *bool                `json:"expose_sequence,omitempty"`
+	DefaultSort     []string             `json:"default_sort,omitempty"`   // an empty array clears the default
+	DefaultFields   []string             `json:"default_fields,omitempty"` // an empty array clears the default
+	Pagination      *registry.Pagination `json:"pagination,omitempty"`     // an empty object clears the override
+	UI              *registry.UI         `json:"ui,omitempty"`             // replaces the presentation metadata; an empty object clears it
 }
 
 // UpdateResponse represents the response for updating a collection
 type UpdateResponse struct {
 	Collection *registry.Collection `json:"collection"`
+	Coerced    int                  `json:"coerced,omitempty"` // incompatible values set to null or the default by force=true
 	Message    string               `json:"message"`
 }
 
@@ -129,52 +232,131 @@ type DestroyResponse struct {
 	Message string `json:"message"`
 }
 
+// RenameRequest represents the request for renaming a collection
+type RenameRequest struct {
+	Name    string `json:"name"`
+	NewName string `json:"new_name"`
+}
+
+// RenameResponse represents the response for renaming a collection
+type RenameResponse struct {
+	Collection *registry.Collection `json:"collection"`
+	Message    string               `json:"message"`
+}
+
+// DuplicateRequest represents the request for duplicating a collection
+type DuplicateRequest struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	CopyData bool   `json:"copy_data,omitempty"` // copy the records with fresh ids
+}
+
+// DuplicateResponse represents the response for duplicating a collection
+type DuplicateResponse struct {
+	Collection *registry.Collection `json:"collection"`
+	Copied     int                  `json:"copied"` // number of records copied
+	Message    string               `json:"message"`
+}
+
 // decodeCreateRequest decodes a CreateRequest and validates that no default fields are present
 func decodeCreateRequest(body io.Reader, req *CreateRequest) error {
-	// Read body into buffer so we can parse it twice
-	bodyBytes, err := io.ReadAll(body)
+	bodyBytes, err := readSchemaRequest(body)
 	if err != nil {
-		return fmt.Errorf("invalid request body")
-	}
-
-	// First, validate for forbidden default fields
-	if err := validateNoDefaultFields(bodyBytes); err != nil {
 		return err
 	}
-
-	// Decode into the target struct
-	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(req); err != nil {
-		return fmt.Errorf("invalid request body")
+	if err := decodeSchemaRequest(bodyBytes, req); err != nil {
+		return err
 	}
-
-	return nil
+	return rejectDefaultValues("columns", req.Columns)
 }
 
 // decodeUpdateRequest decodes an UpdateRequest and validates that no default fields are present
 func decodeUpdateRequest(body io.Reader, req *UpdateRequest) error {
-	// Read body into buffer so we can parse it twice
-	bodyBytes, err := io.ReadAll(body)
+	bodyBytes, err := readSchemaRequest(body)
 	if err != nil {
-		return fmt.Errorf("invalid request body")
+		return err
 	}
-
-	// First, validate for forbidden default fields
-	if err := validateNoDefaultFields(bodyBytes); err != nil {
+	if err := decodeSchemaRequest(bodyBytes, req); err != nil {
 		return err
 	}
+	if err := rejectDefaultValues("add_columns", req.AddColumns); err != nil {
+		return err
+	}
+	for i, col := range req.ModifyColumns {
+		if col.DefaultValue != nil {
+			return fmt.Errorf("unknown field 'default_value' in modify_columns[%d]", i)
+		}
+	}
+	return nil
+}
 
-	// Decode into the target struct
+// readSchemaRequest reads the body of a collections:create or :update request
+// once; a body over the size limit is returned as the *http.MaxBytesError
+func readSchemaRequest(body io.Reader) ([]byte, error) {
+	bodyBytes, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, err
+		}
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
+	}
+	return bodyBytes, nil
+}
+
+// decodeSchemaRequest decodes a schema request strictly. The forbidden
+// "default" key is unknown to the request types and fails the decode, so the
+// body is only parsed a second time on failure, to name the offending column.
+func decodeSchemaRequest(bodyBytes []byte, req any) error {
 	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(req); err != nil {
-		return fmt.Errorf("invalid request body")
+		if err := validateNoDefaultFields(bodyBytes); err != nil {
+			return err
+		}
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
 	}
+	return nil
+}
 
+// rejectDefaultValues rejects default_value on decoded columns; defaults are
+// derived from the column type and nullability
+func rejectDefaultValues(field string, columns []registry.Column) error {
+	for i, col := range columns {
+		if col.DefaultValue != nil {
+			return fmt.Errorf("unknown field 'default_value' in %s[%d]", field, i)
+		}
+	}
 	return nil
 }
 
+// writeSchemaRequestError reports a failed decodeCreateRequest or
+// decodeUpdateRequest: 413 over the body limit, otherwise 400
+func writeSchemaRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeBodyError(w, r, err, "")
+		return
+	}
+	writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidSchema), err.Error())
+}
+
+// writeAPIError writes an *apperrors.APIError with its status and code; any
+// other error is an internal error
+func writeAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	var incompatible *incompatibleValuesError
+	if errors.As(err, &incompatible) {
+		writeIncompatibleValues(w, r, incompatible)
+		return
+	}
+	var apiErr *apperrors.APIError
+	if errors.As(err, &apiErr) {
+		writeError(w, r, apiErr.StatusCode, apiErr.ErrorCode, apiErr.Message)
+		return
+	}
+	writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, err.Error())
+}
+
 // validateNoDefaultFields checks if any default or default_value fields are present in the JSON
 func validateNoDefaultFields(data []byte) error {
 	// Parse as generic JSON to check for forbidden fields
@@ -232,16 +414,32 @@ func validateNoDefaultFields(data []byte) error {
 func (h *CollectionsHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	allCollections := h.registry.List()
+	if h.tenancy {
+		allCollections = h.registry.ListTenant(middleware.GetTenant(ctx))
+	}
+
+	includeArchived, err := QueryBoolean(r, "include_archived", false)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
 
 	// Filter out system tables and build collection items with record counts
 	collections := make([]CollectionItem, 0, len(allCollections))
 	for _, col := range allCollections {
-		if !constants.IsSystemTable(col) {
+		if !constants.IsSystemTable(col) && middleware.HasScope(ctx, col, auth.ScopeRead) {
+			table := h.tableName(r, col)
+			collection, ok := h.registry.Get(table)
+			archived := ok && collection.Archived
+			if archived && !includeArchived {
+				continue
+			}
 			// Count records in this collection
-			recordCount := h.getRecordCount(ctx, col)
+			recordCount := h.getRecordCount(ctx, table)
 			collections = append(collections, CollectionItem{
-				Name:    col,
-				Records: recordCount,
+				Name:     col,
+				Records:  recordCount,
+				Archived: archived,
 			})
 		}
 	}
@@ -251,23 +449,28 @@ func (h *CollectionsHandler) List(w http.ResponseWriter, r *http.Request) {
 		Count:       len(collections),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // getRecordCount returns the number of records in a collection
 // Returns -1 if count cannot be retrieved (with warning log)
 func (h *CollectionsHandler) getRecordCount(ctx context.Context, collectionName string) int {
 	// Verify collection exists in registry (extra safety check)
-	if !h.registry.Exists(collectionName) {
+	collection, ok := h.registry.Get(collectionName)
+	if !ok {
 		log.Printf("WARNING: Attempted to count records for non-existent collection '%s'", collectionName)
 		return -1
 	}
 
-	// Quote identifier based on dialect for defense-in-depth
-	quotedName := h.quoteIdentifier(collectionName)
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", quotedName)
+	// Soft-deleted and expired records are not counted
+	var live []query.Condition
+	if collection.SoftDelete {
+		live = append(live, query.Condition{Column: constants.SoftDeleteColumn, Operator: query.OpIsNull})
+	}
+	where, args := buildListWhere(withExpiryFilter(collection, live), "", nil, h.db.Dialect())
+	sqlQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", query.QuoteIdent(h.db.Dialect(), collectionName), where)
 	var count int
-	err := h.db.QueryRow(ctx, query).Scan(&count)
+	err := h.db.QueryRow(ctx, sqlQuery, args...).Scan(&count)
 	if err != nil {
 		// Log warning but continue with -1 as per PRD requirement
 		log.Printf("WARNING: Failed to count records for collection '%s': %v", collectionName, err)
@@ -276,53 +479,49 @@ func (h *CollectionsHandler) getRecordCount(ctx context.Context, collectionName
 	return count
 }
 
-// quoteIdentifier quotes an identifier (table/column name) based on database dialect
-func (h *CollectionsHandler) quoteIdentifier(name string) string {
-	switch h.db.Dialect() {
-	case database.DialectPostgres:
-		// PostgreSQL uses double quotes
-		return fmt.Sprintf(`"%s"`, name)
-	case database.DialectMySQL:
-		// MySQL uses backticks
-		return fmt.Sprintf("`%s`", name)
-	case database.DialectSQLite:
-		// SQLite supports double quotes and backticks, prefer double quotes
-		return fmt.Sprintf(`"%s"`, name)
-	default:
-		// Fallback: double quotes (SQL standard)
-		return fmt.Sprintf(`"%s"`, name)
-	}
-}
-
 // Get handles GET /collections:get
 func (h *CollectionsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
-		writeError(w, http.StatusBadRequest, "collection name is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "collection name is required")
 		return
 	}
 
 	// Normalize collection name to lowercase for lookup (PRD-047)
 	name = strings.ToLower(name)
 
-	collection, exists := h.registry.Get(name)
-	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", name))
+	if !requireScope(w, r, name, auth.ScopeRead) {
+		return
+	}
+
+	collection, exists := h.registry.Get(h.tableName(r, name))
+	if !exists || h.tenancy && strings.Contains(name, constants.TenantSeparator) {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", name))
 		return
 	}
 
 	response := GetResponse{
-		Collection: collection,
+		Collection: h.logicalView(collection),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// requireScope writes a 403 and returns false when the API key's scopes do not
+// allow the action on the collection
+func requireScope(w http.ResponseWriter, r *http.Request, collection, action string) bool {
+	if middleware.HasScope(r.Context(), collection, action) {
+		return true
+	}
+	middleware.WriteScopeError(w, r, collection, action)
+	return false
 }
 
 // Create handles POST /collections:create
 func (h *CollectionsHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateRequest
 	if err := decodeCreateRequest(r.Body, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeSchemaRequestError(w, r, err)
 		return
 	}
 
@@ -330,98 +529,319 @@ func (h *CollectionsHandler) Create(w http.ResponseWriter, r *http.Request) {
 	req.Name = strings.ToLower(req.Name)
 
 	// Validate collection name
-	if err := validateCollectionName(req.Name); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	if err := h.validateName(req.Name); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, err.Error())
+		return
+	}
+	if !requireScope(w, r, req.Name, auth.ScopeSchema) {
+		return
+	}
+	table := h.tableName(r, req.Name)
+	audit.SetCollection(r.Context(), table)
+	if len(table) > constants.MaxCollectionNameLength {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, fmt.Sprintf("collection name must not exceed %d characters including the tenant prefix", constants.MaxCollectionNameLength))
 		return
 	}
 
 	// Check if collection already exists
-	if h.registry.Exists(req.Name) {
-		writeError(w, http.StatusConflict, fmt.Sprintf("collection '%s' already exists", req.Name))
+	if h.registry.Exists(table) {
+		writeError(w, r, http.StatusConflict, apperrors.CodeDuplicateCollection, fmt.Sprintf("collection '%s' already exists", req.Name))
 		return
 	}
 
 	// Check collection count limit (PRD-048)
 	if err := validateCollectionCount(h.registry); err != nil {
-		writeError(w, http.StatusConflict, err.Error())
+		writeError(w, r, http.StatusConflict, apperrors.CodeMaxCollectionsReached, err.Error())
 		return
 	}
 
-	// Validate columns
-	if len(req.Columns) == 0 {
-		writeError(w, http.StatusBadRequest, "at least one column is required")
+	// Validate the columns, indexes and list defaults of the new collection
+	collection := &registry.Collection{
+		Name:            table,
+		Columns:         req.Columns,
+		SoftDelete:      req.SoftDelete,
+		RequireRevision: req.RequireRevision,
+		ExposeSequence:  req.ExposeSequence,
+		IDType:          req.IDType,
+		DefaultSort:     req.DefaultSort,
+		DefaultFields:   req.DefaultFields,
+		Pagination:      nilIfUnset(req.Pagination),
+		TTL:             req.TTL,
+		UI:              nilIfNoUI(req.UI),
+	}
+	if err := h.validateNewCollection(collection, req.Indexes); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	if err := h.resolveReferences(r, table, collection.Columns, nil); err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	// Check column count limit (PRD-048)
-	// Total includes system columns (id, ulid) plus user-defined columns
-	if len(req.Columns)+constants.SystemColumnsCount > constants.MaxColumnsPerCollection {
-		writeError(w, http.StatusConflict, fmt.Sprintf("maximum number of columns (%d) exceeded", constants.MaxColumnsPerCollection))
+	// Apply type-based defaults for nullable fields if not explicitly set
+	for i := range collection.Columns {
+		if err := validateDefaultValue(&collection.Columns[i]); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+			return
+		}
+		applyColumnDefaults(&collection.Columns[i])
+	}
+
+	// Validate seed records before anything is created
+	if err := validateSeed(req.Seed, collection); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeValidationFailed), err.Error())
 		return
 	}
 
-	for i, col := range req.Columns {
-		if col.Name == "" {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("column %d: name is required", i))
+	// Create the table and its indexes and register the collection
+	ctx := r.Context()
+	if err := h.createCollection(ctx, collection, req.Indexes); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	// Insert seed records; the collection is removed entirely if any of them fails
+	if len(req.Seed) > 0 {
+		if idx, err := h.insertSeed(ctx, collection, req.Seed); err != nil {
+			if _, rollbackErr := h.db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(h.db.Dialect(), table))); rollbackErr != nil {
+				log.Printf("WARNING: Failed to drop table '%s' after seeding failed: %v", table, rollbackErr)
+			}
+			if deleteErr := h.registry.Delete(table); deleteErr != nil {
+				log.Printf("WARNING: Failed to remove collection '%s' from registry after seeding failed: %v", table, deleteErr)
+			}
+			status, code := http.StatusInternalServerError, apperrors.CodeDatabaseError
+			var apiErr *apperrors.APIError
+			if errors.As(err, &apiErr) {
+				status, code = apiErr.StatusCode, apiErr.ErrorCode
+			} else if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
+				status, code = http.StatusConflict, apperrors.CodeUniqueViolation
+			}
+			writeError(w, r, status, code, fmt.Sprintf("failed to insert seed record at index %d: %v", idx, err))
 			return
 		}
+	}
+
+	message := fmt.Sprintf("Collection '%s' created successfully", req.Name)
+	if len(req.Seed) > 0 {
+		message = fmt.Sprintf("Collection '%s' created successfully with %d seed records", req.Name, len(req.Seed))
+	}
+
+	response := CreateResponse{
+		Collection: h.logicalView(collection),
+		Seeded:     len(req.Seed),
+		Message:    message,
+	}
+
+	writeResponse(w, r, http.StatusCreated, response)
+}
+
+// validateNewCollection validates the columns, indexes and list defaults of a
+// collection about to be created. Errors are *apperrors.APIError values.
+func (h *CollectionsHandler) validateNewCollection(collection *registry.Collection, indexes []registry.Index) error {
+	if len(collection.Columns) == 0 {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, "at least one column is required")
+	}
+
+	// Check column count limit (PRD-048)
+	// Total includes system columns (id, ulid, and deleted_at for soft delete) plus user-defined columns
+	if len(collection.Columns)+systemColumnCount(collection.SoftDelete) > constants.MaxColumnsPerCollection {
+		return apperrors.Newf(http.StatusConflict, apperrors.CodeMaxColumnsReached, "maximum number of columns (%d) exceeded", constants.MaxColumnsPerCollection)
+	}
+
+	for i, col := range collection.Columns {
+		if col.Name == "" {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "column %d: name is required", i)
+		}
 
 		// Validate column name (PRD-048)
 		if err := validateColumnName(col.Name); err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("column '%s': %v", col.Name, err))
-			return
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "column '%s': %v", col.Name, err)
 		}
 
 		// Validate column type with deprecated type checking (PRD-048)
 		if err := validateColumnType(string(col.Type)); err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("column '%s': %v", col.Name, err))
-			return
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "column '%s': %v", col.Name, err)
 		}
 
-		// Validate default value if provided (PRD-048)
-		if err := validateDefaultValue(&req.Columns[i]); err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
-			return
+		if err := validateColumnConstraints(col); err != nil {
+			return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+		}
+	}
+	if err := validateComputedColumns(collection.Columns); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+
+	if err := h.validateIndexes(indexes, collection); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := validateListDefaults(collection); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := validateExposeSequence(collection); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := validateIDType(collection); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := validateTTL(collection); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := validateUI(collection); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	return nil
+}
+
+// resolveReferences replaces the collections referenced by columns with their
+// tables for the requesting tenant. A referenced collection must exist, be
+// the collection of table itself or one of pending, the tables about to be
+// created alongside it, and reference columns must hold strings. Errors are
+// *apperrors.APIError values.
+func (h *CollectionsHandler) resolveReferences(r *http.Request, table string, columns []registry.Column, pending map[string]bool) error {
+	for i := range columns {
+		col := &columns[i]
+		if col.References == "" {
+			continue
+		}
+		name := strings.ToLower(col.References)
+		if err := h.validateName(name); err != nil {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "column '%s': invalid references: %v", col.Name, err)
 		}
+		if col.Type != registry.TypeString {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "column '%s': references requires a string column, got %s", col.Name, col.Type)
+		}
+		target := h.tableName(r, name)
+		if target != table && !pending[target] && !h.registry.Exists(target) {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "column '%s': referenced collection '%s' not found", col.Name, name)
+		}
+		col.References = target
+	}
+	return nil
+}
 
-		// Apply type-based defaults for nullable fields if not explicitly set
-		applyColumnDefaults(&req.Columns[i])
+// validateExposeSequence rejects expose_sequence on a collection with a user
+// column named seq, which the field would shadow
+func validateExposeSequence(collection *registry.Collection) error {
+	if !collection.ExposeSequence {
+		return nil
+	}
+	for _, col := range collection.Columns {
+		if col.Name == constants.SequenceField {
+			return fmt.Errorf("expose_sequence adds a read-only '%s' field; rename the '%s' column first", constants.SequenceField, col.Name)
+		}
+	}
+	return nil
+}
+
+// requirePKID returns an error unless the table has the pkid column
+// expose_sequence reads
+func (h *CollectionsHandler) requirePKID(ctx context.Context, table string) error {
+	info, err := h.db.GetTableInfo(ctx, table)
+	if err != nil {
+		return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to read table '%s': %v", table, err)
 	}
+	for _, col := range info.Columns {
+		if col.Name == constants.PKIDColumn {
+			return nil
+		}
+	}
+	return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "expose_sequence requires a %s column, which table '%s' does not have", constants.PKIDColumn, table)
+}
 
-	// Generate CREATE TABLE DDL
-	ddl := generateCreateTableDDL(req.Name, req.Columns, h.db.Dialect())
+// createCollection creates the table and indexes of a validated collection
+// and registers it. The table is dropped again if a later step fails. Errors
+// are *apperrors.APIError values.
+func (h *CollectionsHandler) createCollection(ctx context.Context, collection *registry.Collection, indexes []registry.Index) error {
+	table := collection.Name
 
-	// Execute DDL
-	ctx := r.Context()
-	if _, err := h.db.Exec(ctx, ddl); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create table: %v", err))
-		return
+	// Generate CREATE TABLE DDL; soft delete adds a nullable deleted_at system column
+	ddlColumns := collection.Columns
+	if collection.SoftDelete {
+		ddlColumns = append(append([]registry.Column{}, collection.Columns...), softDeleteColumn())
+	}
+	if _, err := h.db.Exec(ctx, generateCreateTableDDL(table, ddlColumns, collection.RecordIDType(), h.db.Dialect())); err != nil {
+		return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to create table: %v", err)
 	}
 
-	// Update registry
-	collection := &registry.Collection{
-		Name:    req.Name,
-		Columns: req.Columns,
+	// Create indexes; the table is dropped again if any of them fails
+	for _, idx := range indexes {
+		if _, err := h.db.Exec(ctx, generateCreateIndexDDL(table, idx, h.db.Dialect())); err != nil {
+			if _, rollbackErr := h.db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(h.db.Dialect(), table))); rollbackErr != nil {
+				log.Printf("WARNING: Failed to drop table '%s' after index creation failed: %v", table, rollbackErr)
+			}
+			return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to create index '%s': %v", idx.Name, err)
+		}
 	}
 
+	// Update registry
+	collection.Indexes = indexes
+
 	if err := h.registry.Set(collection); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update registry: %v", err))
-		return
+		if _, rollbackErr := h.db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(h.db.Dialect(), table))); rollbackErr != nil {
+			log.Printf("WARNING: Failed to drop table '%s' after registry update failed: %v", table, rollbackErr)
+		}
+		return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeInternalError, "failed to update registry: %v", err)
 	}
+	return nil
+}
 
-	response := CreateResponse{
-		Collection: collection,
-		Message:    fmt.Sprintf("Collection '%s' created successfully", req.Name),
+// validateSeed checks the seed records of a create request against the new
+// collection, using the same rules as a data :create batch.
+func validateSeed(seed []map[string]any, collection *registry.Collection) error {
+	if maxSize := config.Defaults.Batch.MaxSize; len(seed) > maxSize {
+		return apperrors.Newf(http.StatusBadRequest, apperrors.CodeBatchTooLarge, "seed size %d exceeds limit of %d", len(seed), maxSize)
+	}
+	for idx, item := range seed {
+		if item == nil {
+			return fmt.Errorf("seed validation error at index %d: record must be an object", idx)
+		}
+		if err := validateFields(item, collection); err != nil {
+			return fmt.Errorf("seed validation error at index %d: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+// insertSeed inserts seed records in one transaction with new record ids.
+// On failure nothing is inserted and the index of the failing record is returned.
+func (h *CollectionsHandler) insertSeed(ctx context.Context, collection *registry.Collection, seed []map[string]any) (int, error) {
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	writeJSON(w, http.StatusCreated, response)
+	now := currentTimestamp()
+	ids := newRecordIDs(collection, seed)
+	for idx, item := range seed {
+		query, values := buildInsertQuery(collection.Name, collection, item, ids[idx], now, h.db.Dialect())
+		if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+			return idx, err
+		}
+	}
+
+	// References are checked once every seed record exists, so seed records
+	// may reference each other
+	failed, err := referenceErrors(ctx, tx.QueryContext, h.registry, collection, seed, h.db.Dialect())
+	if err != nil {
+		return 0, fmt.Errorf("failed to check references: %w", err)
+	}
+	if len(failed) > 0 {
+		idx := slices.Min(slices.Collect(maps.Keys(failed)))
+		return idx, failed[idx]
+	}
+
+	if err := tx.Commit(); err != nil {
+		return len(seed) - 1, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return 0, nil
 }
 
 // Update handles POST /collections:update
 func (h *CollectionsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	var req UpdateRequest
 	if err := decodeUpdateRequest(r.Body, &req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeSchemaRequestError(w, r, err)
 		return
 	}
 
@@ -429,49 +849,137 @@ func (h *CollectionsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	req.Name = strings.ToLower(req.Name)
 
 	// Validate collection name
-	if err := validateCollectionName(req.Name); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	if err := h.validateName(req.Name); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, err.Error())
+		return
+	}
+	if !requireScope(w, r, req.Name, auth.ScopeSchema) {
 		return
 	}
 
 	// Check if collection exists
-	collection, exists := h.registry.Get(req.Name)
+	table := h.tableName(r, req.Name)
+	audit.SetCollection(r.Context(), table)
+	collection, exists := h.registry.Get(table)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", req.Name))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", req.Name))
 		return
 	}
 
 	// Validate that at least one operation is requested
 	if len(req.AddColumns) == 0 && len(req.RemoveColumns) == 0 &&
-		len(req.RenameColumns) == 0 && len(req.ModifyColumns) == 0 {
-		writeError(w, http.StatusBadRequest, "no operations specified")
+		len(req.RenameColumns) == 0 && len(req.ModifyColumns) == 0 &&
+		len(req.Indexes) == 0 && len(req.RemoveIndexes) == 0 &&
+		req.RequireRevision == nil && req.ExposeSequence == nil && req.DefaultSort == nil && req.DefaultFields == nil &&
+		req.Pagination == nil && req.UI == nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "no operations specified")
+		return
+	}
+	if err := h.resolveReferences(r, table, req.AddColumns, nil); err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	// Save original collection state for rollback
-	originalColumns := make([]registry.Column, len(collection.Columns))
-	copy(originalColumns, collection.Columns)
+	// A dry run validates the update and reports the DDL it would run; force
+	// sets values a type change cannot convert to null or the default
+	flags := map[string]bool{"dry_run": false, "force": false}
+	for name := range flags {
+		parsed, err := QueryBoolean(r, name, false)
+		if err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+		flags[name] = parsed
+	}
+	if flags["dry_run"] {
+		h.dryRunUpdate(w, r, table, collection, &req, flags["force"])
+		return
+	}
 
-	ctx := r.Context()
+	coerced, err := h.applyUpdate(r.Context(), table, collection, &req, flags["force"])
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
 
-	// Execute operations in order: rename → modify → add → remove
+	response := UpdateResponse{
+		Collection: h.logicalView(collection),
+		Coerced:    coerced,
+		Message:    fmt.Sprintf("Collection '%s' updated successfully", req.Name),
+	}
+
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// applyUpdate applies the operations of an update request to a collection
+// and its table. The statements run in one transaction where the dialect
+// supports transactional DDL, so a failure leaves the table and the registry
+// unchanged. force coerces values that a type change cannot convert, see
+// planCoercions; the number coerced is returned. Computed columns the update
+// adds or changes are then recomputed in batches. Errors are
+// *apperrors.APIError values.
+func (h *CollectionsHandler) applyUpdate(ctx context.Context, table string, collection *registry.Collection, req *UpdateRequest, force bool) (int, error) {
+	previous := slices.Clone(collection.Columns)
+	statements, err := h.planUpdate(ctx, table, collection, req, force)
+	if err != nil {
+		return 0, err
+	}
+	if err := h.runUpdateStatements(ctx, statements); err != nil {
+		return 0, err
+	}
+
+	// Update registry with final state
+	if err := h.registry.Set(collection); err != nil {
+		return 0, apperrors.Newf(http.StatusInternalServerError, apperrors.CodeInternalError, "failed to update registry: %v", err)
+	}
+
+	// Computed columns added or changed are recomputed once the schema is in place
+	if err := h.recomputeChanged(ctx, table, collection, previous); err != nil {
+		return 0, err
+	}
+	return coercedValues(statements), nil
+}
+
+// planUpdate validates the operations of an update request, applies them to
+// the collection and returns the statements that apply them to its table, in
+// execution order. Nothing is written; only the column checks read the table.
+// Errors are *apperrors.APIError values.
+func (h *CollectionsHandler) planUpdate(ctx context.Context, table string, collection *registry.Collection, req *UpdateRequest, force bool) ([]updateStatement, error) {
+	// List defaults are checked against the final columns before any DDL runs
+	if err := validateUpdateListDefaults(req, collection); err != nil {
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := validateUpdateComputed(req, collection); err != nil {
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+
+	// The seq field reads the pkid column, which tables Moon did not create may lack
+	if req.ExposeSequence != nil && *req.ExposeSequence && !collection.ExposeSequence {
+		if err := h.requirePKID(ctx, table); err != nil {
+			return nil, err
+		}
+	}
+
+	dialect := h.db.Dialect()
+	var statements []updateStatement
+	add := func(sql, failure string, code apperrors.ErrorCode) {
+		statements = append(statements, updateStatement{SQL: sql, failure: failure, code: code})
+	}
+
+	// Operations run in order: rename → modify → add columns → remove indexes → add indexes → remove columns
 
 	// 1. RENAME COLUMNS
+	// renamed maps the new names to the names in the table before the update
+	renamed := make(map[string]string, len(req.RenameColumns))
 	if len(req.RenameColumns) > 0 {
 		if err := h.validateRenameColumns(req.RenameColumns, collection); err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
-			return
+			return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
 		}
 
 		for _, rename := range req.RenameColumns {
-			ddl := generateRenameColumnDDL(req.Name, rename.OldName, rename.NewName, h.db.Dialect())
-			if _, err := h.db.Exec(ctx, ddl); err != nil {
-				// Rollback registry on failure
-				collection.Columns = originalColumns
-				h.registry.Set(collection)
-				writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to rename column '%s': %v", rename.OldName, err))
-				return
-			}
+			add(generateRenameColumnDDL(table, rename.OldName, rename.NewName, dialect),
+				fmt.Sprintf("rename column '%s'", rename.OldName), apperrors.CodeInvalidSchema)
+			renamed[rename.NewName] = rename.OldName
 
 			// Update column name in registry
 			for i := range collection.Columns {
@@ -480,41 +988,48 @@ func (h *CollectionsHandler) Update(w http.ResponseWriter, r *http.Request) {
 					break
 				}
 			}
+			renameIndexColumn(collection.Indexes, rename.OldName, rename.NewName)
+			renameComputedOperand(collection.Columns, rename.OldName, rename.NewName)
+			collection.DefaultSort = renameDefaultColumn(collection.DefaultSort, rename.OldName, rename.NewName)
+			collection.DefaultFields = renameDefaultColumn(collection.DefaultFields, rename.OldName, rename.NewName)
+			renameTTLColumn(collection, rename.OldName, rename.NewName)
+			renameUIColumn(collection, rename.OldName, rename.NewName)
 		}
 	}
 
 	// 2. MODIFY COLUMNS
 	if len(req.ModifyColumns) > 0 {
 		if err := h.validateModifyColumns(req.ModifyColumns, collection); err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
-			return
+			return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
 		}
 
-		for _, modify := range req.ModifyColumns {
-			ddl := generateModifyColumnDDL(req.Name, modify, h.db.Dialect())
-			if _, err := h.db.Exec(ctx, ddl); err != nil {
-				// Rollback registry on failure
-				collection.Columns = originalColumns
-				h.registry.Set(collection)
-				writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to modify column '%s': %v", modify.Name, err))
-				return
+		// Values a type change cannot convert are refused, or coerced first
+		coercions, err := h.planCoercions(ctx, table, collection, req.ModifyColumns, renamed, force)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, coercions...)
+
+		if dialect == database.DialectSQLite {
+			// SQLite cannot alter a column in place, so the table is rebuilt
+			// from the modified columns once the records are known to fit them
+			previous := append([]registry.Column(nil), collection.Columns...)
+			for _, modify := range req.ModifyColumns {
+				applyModifyColumn(collection, modify)
+			}
+			if err := h.checkModifiedColumns(ctx, table, collection.Columns, previous, renamed); err != nil {
+				return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
 			}
+			for _, sql := range sqliteRebuildStatements(table, collection, previous) {
+				add(sql, "modify columns", apperrors.CodeInvalidSchema)
+			}
+		} else {
+			for _, modify := range req.ModifyColumns {
+				add(generateModifyColumnDDL(table, modify, dialect),
+					fmt.Sprintf("modify column '%s'", modify.Name), apperrors.CodeInvalidSchema)
 
-			// Update column definition in registry
-			for i := range collection.Columns {
-				if collection.Columns[i].Name == modify.Name {
-					collection.Columns[i].Type = modify.Type
-					if modify.Nullable != nil {
-						collection.Columns[i].Nullable = *modify.Nullable
-					}
-					if modify.Unique != nil {
-						collection.Columns[i].Unique = *modify.Unique
-					}
-					if modify.DefaultValue != nil {
-						collection.Columns[i].DefaultValue = modify.DefaultValue
-					}
-					break
-				}
+				// Update column definition in registry
+				applyModifyColumn(collection, modify)
 			}
 		}
 	}
@@ -522,131 +1037,507 @@ func (h *CollectionsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// 3. ADD COLUMNS
 	if len(req.AddColumns) > 0 {
 		if err := h.validateAddColumns(req.AddColumns, collection); err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
-			return
+			return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
 		}
 
-		for _, col := range req.AddColumns {
-			// Step 1: Add the column without unique constraint
-			ddl := generateAddColumnDDL(req.Name, col, h.db.Dialect())
-			if _, err := h.db.Exec(ctx, ddl); err != nil {
-				// Rollback registry on failure
-				collection.Columns = originalColumns
-				h.registry.Set(collection)
-				writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to add column '%s': %v", col.Name, err))
-				return
-			}
+		for _, col := range req.AddColumns {
+			// The column is added without its unique constraint, which is added separately
+			add(generateAddColumnDDL(table, col, dialect),
+				fmt.Sprintf("add column '%s'", col.Name), apperrors.CodeInvalidSchema)
+			if col.Unique {
+				statements = append(statements, updateStatement{
+					SQL:     generateAddUniqueConstraintDDL(table, col.Name, dialect),
+					failure: fmt.Sprintf("add unique constraint on column '%s'", col.Name),
+					code:    apperrors.CodeInvalidSchema,
+					undo:    generateDropColumnDDL(table, col.Name, dialect),
+				})
+			}
+
+			collection.Columns = append(collection.Columns, col)
+		}
+	}
+
+	// 4. REMOVE INDEXES
+	if len(req.RemoveIndexes) > 0 {
+		if err := validateRemoveIndexes(req.RemoveIndexes, collection); err != nil {
+			return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+		}
+
+		for _, indexName := range req.RemoveIndexes {
+			add(generateDropIndexDDL(table, indexName, dialect),
+				fmt.Sprintf("remove index '%s'", indexName), apperrors.CodeDatabaseError)
+
+			// Remove index from registry
+			newIndexes := make([]registry.Index, 0, len(collection.Indexes)-1)
+			for _, idx := range collection.Indexes {
+				if idx.Name != indexName {
+					newIndexes = append(newIndexes, idx)
+				}
+			}
+			collection.Indexes = newIndexes
+		}
+	}
+
+	// 5. ADD INDEXES
+	if len(req.Indexes) > 0 {
+		if err := h.validateIndexes(req.Indexes, collection); err != nil {
+			return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+		}
+
+		for _, idx := range req.Indexes {
+			add(generateCreateIndexDDL(table, idx, dialect),
+				fmt.Sprintf("create index '%s'", idx.Name), apperrors.CodeDatabaseError)
+			collection.Indexes = append(collection.Indexes, idx)
+		}
+	}
+
+	// 6. REMOVE COLUMNS
+	if len(req.RemoveColumns) > 0 {
+		if err := h.validateRemoveColumns(req.RemoveColumns, collection); err != nil {
+			return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+		}
+
+		for _, colName := range req.RemoveColumns {
+			add(generateDropColumnDDL(table, colName, dialect),
+				fmt.Sprintf("remove column '%s'", colName), apperrors.CodeInvalidSchema)
+
+			// Remove column from registry
+			newColumns := make([]registry.Column, 0, len(collection.Columns)-1)
+			for _, col := range collection.Columns {
+				if col.Name != colName {
+					newColumns = append(newColumns, col)
+				}
+			}
+			collection.Columns = newColumns
+			removeUIColumn(collection, colName)
+		}
+	}
+
+	// Revision enforcement, the seq field, list defaults and presentation
+	// metadata are registry settings and need no DDL
+	if req.RequireRevision != nil {
+		collection.RequireRevision = *req.RequireRevision
+	}
+	if req.ExposeSequence != nil {
+		collection.ExposeSequence = *req.ExposeSequence
+	}
+	if req.DefaultSort != nil {
+		collection.DefaultSort = nilIfEmpty(req.DefaultSort)
+	}
+	if req.DefaultFields != nil {
+		collection.DefaultFields = nilIfEmpty(req.DefaultFields)
+	}
+	if req.Pagination != nil {
+		collection.Pagination = nilIfUnset(req.Pagination)
+	}
+	if req.UI != nil {
+		collection.UI = nilIfNoUI(req.UI)
+		if err := validateUI(collection); err != nil {
+			return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+		}
+	}
+	return statements, nil
+}
+
+// Destroy handles POST /collections:destroy
+func (h *CollectionsHandler) Destroy(w http.ResponseWriter, r *http.Request) {
+	var req DestroyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	// Normalize collection name to lowercase (PRD-047)
+	req.Name = strings.ToLower(req.Name)
+
+	// Validate collection name
+	if err := h.validateName(req.Name); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, err.Error())
+		return
+	}
+	if !requireScope(w, r, req.Name, auth.ScopeSchema) {
+		return
+	}
+
+	// Check if collection exists
+	table := h.tableName(r, req.Name)
+	audit.SetCollection(r.Context(), table)
+	if !h.registry.Exists(table) {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", req.Name))
+		return
+	}
+
+	// A collection other collections reference is only destroyed on request,
+	// which turns their reference columns into plain string columns
+	cascadeCheck, err := QueryBoolean(r, "cascade_check", true)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	referencing := h.registry.Referencing(table)
+	if cascadeCheck && len(referencing) > 0 {
+		names := make([]string, len(referencing))
+		for i, collection := range referencing {
+			names[i] = h.logicalView(collection).Name
+		}
+		writeError(w, r, http.StatusConflict, apperrors.CodeConflict, fmt.Sprintf("collection '%s' is referenced by %s; remove the references first or pass cascade_check=false", req.Name, strings.Join(names, ", ")))
+		return
+	}
+
+	// Generate DROP TABLE DDL; PostgreSQL drops the foreign keys of the
+	// referencing tables with it
+	ddl := fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(h.db.Dialect(), table))
+	if len(referencing) > 0 && h.db.Dialect() == database.DialectPostgres {
+		ddl += " CASCADE"
+	}
+
+	// Execute DDL
+	ctx := r.Context()
+	if _, err := h.db.Exec(ctx, ddl); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to drop table: %v", err))
+		return
+	}
+
+	// Remove from registry
+	if err := h.registry.Delete(table); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to update registry: %v", err))
+		return
+	}
+	h.replaceReferences(referencing, table, "")
+
+	response := DestroyResponse{
+		Message: fmt.Sprintf("Collection '%s' destroyed successfully", req.Name),
+	}
+
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// replaceReferences points the reference columns of collections from table
+// to replacement, or turns them into plain string columns when replacement
+// is empty, and saves the collections that changed
+func (h *CollectionsHandler) replaceReferences(collections []*registry.Collection, table, replacement string) {
+	for _, collection := range collections {
+		changed := false
+		for i := range collection.Columns {
+			if collection.Columns[i].References == table {
+				collection.Columns[i].References = replacement
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := h.registry.Set(collection); err != nil {
+			log.Printf("WARNING: Failed to update the references of collection '%s' to '%s': %v", collection.Name, table, err)
+		}
+	}
+}
+
+// Rename handles POST /collections:rename
+func (h *CollectionsHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	var req RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	// Normalize collection names to lowercase (PRD-047)
+	req.Name = strings.ToLower(req.Name)
+	req.NewName = strings.ToLower(req.NewName)
+
+	// Validate collection names
+	if err := h.validateName(req.Name); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, err.Error())
+		return
+	}
+	if !requireScope(w, r, req.Name, auth.ScopeSchema) {
+		return
+	}
+	if err := h.validateName(req.NewName); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, fmt.Sprintf("invalid new_name: %v", err))
+		return
+	}
+	if req.NewName == req.Name {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "new_name must differ from name")
+		return
+	}
+	if !requireScope(w, r, req.NewName, auth.ScopeSchema) {
+		return
+	}
+
+	table, newTable := h.tableName(r, req.Name), h.tableName(r, req.NewName)
+	audit.SetCollection(r.Context(), table)
+	if len(newTable) > constants.MaxCollectionNameLength {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, fmt.Sprintf("invalid new_name: collection name must not exceed %d characters including the tenant prefix", constants.MaxCollectionNameLength))
+		return
+	}
+
+	// Check that the collection exists and the new name is free
+	if !h.registry.Exists(table) {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", req.Name))
+		return
+	}
+	if h.registry.Exists(newTable) {
+		writeError(w, r, http.StatusConflict, apperrors.CodeDuplicateCollection, fmt.Sprintf("collection '%s' already exists", req.NewName))
+		return
+	}
+
+	// Rename the table
+	ctx := r.Context()
+	if _, err := h.db.Exec(ctx, generateRenameTableDDL(table, newTable, h.db.Dialect())); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to rename table: %v", err))
+		return
+	}
+
+	// Swap the registry entry; on failure rename the table back so the two never diverge
+	if err := h.registry.Rename(table, newTable); err != nil {
+		if _, rollbackErr := h.db.Exec(ctx, generateRenameTableDDL(newTable, table, h.db.Dialect())); rollbackErr != nil {
+			log.Printf("WARNING: Failed to rollback rename of table '%s' to '%s': %v", newTable, table, rollbackErr)
+		}
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to update registry: %v", err))
+		return
+	}
+
+	// Reference columns follow the rename; the foreign keys of PostgreSQL do so by themselves
+	h.replaceReferences(h.registry.GetAll(), table, newTable)
+
+	collection, _ := h.registry.Get(newTable)
+	response := RenameResponse{
+		Collection: h.logicalView(collection),
+		Message:    fmt.Sprintf("Collection '%s' renamed to '%s' successfully", req.Name, req.NewName),
+	}
+
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// Duplicate handles POST /collections:duplicate
+func (h *CollectionsHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	var req DuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	// Normalize collection names to lowercase (PRD-047)
+	req.Source = strings.ToLower(req.Source)
+	req.Target = strings.ToLower(req.Target)
+
+	// Validate collection names
+	if err := h.validateName(req.Source); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, fmt.Sprintf("invalid source: %v", err))
+		return
+	}
+	if !requireScope(w, r, req.Source, auth.ScopeSchema) {
+		return
+	}
+	if err := h.validateName(req.Target); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, fmt.Sprintf("invalid target: %v", err))
+		return
+	}
+	if req.Target == req.Source {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "target must differ from source")
+		return
+	}
+	if !requireScope(w, r, req.Target, auth.ScopeSchema) {
+		return
+	}
+
+	source, target := h.tableName(r, req.Source), h.tableName(r, req.Target)
+	audit.SetCollection(r.Context(), target)
+	if len(target) > constants.MaxCollectionNameLength {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, fmt.Sprintf("invalid target: collection name must not exceed %d characters including the tenant prefix", constants.MaxCollectionNameLength))
+		return
+	}
+
+	// Check that the source exists, the target is free and the limit allows one more
+	collection, exists := h.registry.Get(source)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", req.Source))
+		return
+	}
+	if h.registry.Exists(target) {
+		writeError(w, r, http.StatusConflict, apperrors.CodeDuplicateCollection, fmt.Sprintf("collection '%s' already exists", req.Target))
+		return
+	}
+	if err := validateCollectionCount(h.registry); err != nil {
+		writeError(w, r, http.StatusConflict, apperrors.CodeMaxCollectionsReached, err.Error())
+		return
+	}
+
+	// Registry.Get returns a copy, so the clone only needs its own name and
+	// index names; the copy of an archived collection is served
+	indexes := duplicateIndexes(collection.Indexes, req.Source, req.Target)
+	collection.Name = target
+	collection.Indexes = nil
+	collection.Archived = false
+	if err := h.validateIndexes(indexes, collection); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidSchema, fmt.Sprintf("cannot copy indexes: %v", err))
+		return
+	}
 
-			// Step 2: If unique constraint is needed, add it separately
-			if col.Unique {
-				uniqueDDL := generateAddUniqueConstraintDDL(req.Name, col.Name, h.db.Dialect())
-				if _, err := h.db.Exec(ctx, uniqueDDL); err != nil {
-					// Rollback: drop the column we just added, then rollback registry
-					dropDDL := generateDropColumnDDL(req.Name, col.Name, h.db.Dialect())
-					if _, rollbackErr := h.db.Exec(ctx, dropDDL); rollbackErr != nil {
-						// Log rollback failure but continue with error handling
-						log.Printf("WARNING: Failed to rollback column addition for '%s': %v", col.Name, rollbackErr)
-					}
-					collection.Columns = originalColumns
-					h.registry.Set(collection)
-					writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to add unique constraint on column '%s': %v", col.Name, err))
-					return
-				}
-			}
+	ddlColumns := collection.Columns
+	if collection.SoftDelete {
+		ddlColumns = append(append([]registry.Column{}, collection.Columns...), softDeleteColumn())
+	}
 
-			collection.Columns = append(collection.Columns, col)
+	ctx := r.Context()
+	if _, err := h.db.Exec(ctx, generateCreateTableDDL(target, ddlColumns, collection.RecordIDType(), h.db.Dialect())); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to create table: %v", err))
+		return
+	}
+	dropTarget := func(reason string) {
+		if _, rollbackErr := h.db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(h.db.Dialect(), target))); rollbackErr != nil {
+			log.Printf("WARNING: Failed to drop table '%s' after %s: %v", target, reason, rollbackErr)
 		}
 	}
 
-	// 4. REMOVE COLUMNS
-	if len(req.RemoveColumns) > 0 {
-		if err := h.validateRemoveColumns(req.RemoveColumns, collection); err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
+	for _, idx := range indexes {
+		if _, err := h.db.Exec(ctx, generateCreateIndexDDL(target, idx, h.db.Dialect())); err != nil {
+			dropTarget("index creation failed")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to create index '%s': %v", idx.Name, err))
 			return
 		}
+	}
 
-		for _, colName := range req.RemoveColumns {
-			ddl := generateDropColumnDDL(req.Name, colName, h.db.Dialect())
-			if _, err := h.db.Exec(ctx, ddl); err != nil {
-				// Rollback registry on failure
-				collection.Columns = originalColumns
-				h.registry.Set(collection)
-				writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to remove column '%s': %v", colName, err))
-				return
-			}
-
-			// Remove column from registry
-			newColumns := make([]registry.Column, 0, len(collection.Columns)-1)
-			for _, col := range collection.Columns {
-				if col.Name != colName {
-					newColumns = append(newColumns, col)
-				}
-			}
-			collection.Columns = newColumns
+	// Records are copied before the target is registered, so it never
+	// becomes visible half filled
+	copied := 0
+	if req.CopyData {
+		var err error
+		if copied, err = h.copyRecords(ctx, source, collection); err != nil {
+			dropTarget("copying records failed")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to copy records after %d: %v", copied, err))
+			return
 		}
 	}
 
-	// Update registry with final state
+	collection.Indexes = indexes
 	if err := h.registry.Set(collection); err != nil {
-		// Attempt to rollback
-		collection.Columns = originalColumns
-		h.registry.Set(collection)
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update registry: %v", err))
+		dropTarget("registry update failed")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to update registry: %v", err))
 		return
 	}
 
-	response := UpdateResponse{
-		Collection: collection,
-		Message:    fmt.Sprintf("Collection '%s' updated successfully", req.Name),
+	message := fmt.Sprintf("Collection '%s' duplicated to '%s' successfully", req.Source, req.Target)
+	if req.CopyData {
+		message = fmt.Sprintf("Collection '%s' duplicated to '%s' successfully with %d records", req.Source, req.Target, copied)
 	}
 
-	writeJSON(w, http.StatusOK, response)
-}
-
-// Destroy handles POST /collections:destroy
-func (h *CollectionsHandler) Destroy(w http.ResponseWriter, r *http.Request) {
-	var req DestroyRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
-		return
+	response := DuplicateResponse{
+		Collection: h.logicalView(collection),
+		Copied:     copied,
+		Message:    message,
 	}
 
-	// Normalize collection name to lowercase (PRD-047)
-	req.Name = strings.ToLower(req.Name)
+	writeResponse(w, r, http.StatusCreated, response)
+}
 
-	// Validate collection name
-	if err := validateCollectionName(req.Name); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+// duplicateIndexes renames the indexes of a collection for its duplicate,
+// since index names share one namespace per database: a leading source name
+// is replaced by the target name, other names get the target name as prefix
+func duplicateIndexes(indexes []registry.Index, source, target string) []registry.Index {
+	if len(indexes) == 0 {
+		return nil
 	}
+	renamed := make([]registry.Index, len(indexes))
+	for i, idx := range indexes {
+		idx.Name = target + "_" + strings.TrimPrefix(idx.Name, source+"_")
+		renamed[i] = idx
+	}
+	return renamed
+}
 
-	// Check if collection exists
-	if !h.registry.Exists(req.Name) {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", req.Name))
-		return
+// copyRecords copies the records of the source table into the table of the
+// collection in batches of constants.DuplicateBatchSize, one transaction per
+// batch. Each copy gets a new id, except in collections of client-supplied
+// ids, which keep theirs; timestamps, _rev and deleted_at are kept.
+// It returns the number of records copied, including on failure.
+func (h *CollectionsHandler) copyRecords(ctx context.Context, source string, collection *registry.Collection) (int, error) {
+	dialect := h.db.Dialect()
+	columns := []string{constants.CreatedAtColumn, constants.UpdatedAtColumn, constants.RevisionColumn}
+	for _, col := range collection.Columns {
+		columns = append(columns, col.Name)
+	}
+	if collection.SoftDelete {
+		columns = append(columns, constants.SoftDeleteColumn)
 	}
 
-	// Generate DROP TABLE DDL
-	ddl := fmt.Sprintf("DROP TABLE %s", req.Name)
+	quoted := make([]string, len(columns))
+	placeholders := []string{query.Placeholder(dialect, 1)}
+	for i, col := range columns {
+		quoted[i] = query.QuoteIdent(dialect, col)
+		placeholders = append(placeholders, query.Placeholder(dialect, i+2))
+	}
+	selectSQL := fmt.Sprintf("SELECT pkid, id, %s FROM %s WHERE pkid > %s ORDER BY pkid LIMIT %d",
+		strings.Join(quoted, ", "), query.QuoteIdent(dialect, source), query.Placeholder(dialect, 1), constants.DuplicateBatchSize)
+	insertSQL := fmt.Sprintf("INSERT INTO %s (id, %s) VALUES (%s)",
+		query.QuoteIdent(dialect, collection.Name), strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+
+	copied := 0
+	var lastPKID int64
+	for {
+		count, last, err := h.copyBatch(ctx, selectSQL, insertSQL, lastPKID, len(columns), collection.RecordIDType())
+		if err != nil {
+			return copied, err
+		}
+		copied += count
+		lastPKID = last
+		if count < constants.DuplicateBatchSize {
+			return copied, nil
+		}
+		log.Printf("INFO: Duplicating '%s' to '%s': %d records copied", source, collection.Name, copied)
+	}
+}
 
-	// Execute DDL
-	ctx := r.Context()
-	if _, err := h.db.Exec(ctx, ddl); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to drop table: %v", err))
-		return
+// copyBatch copies the records after the given pkid in one transaction and
+// returns how many were copied and the last pkid read. The batch is read in
+// full before inserting, as SQLite runs on a single connection.
+func (h *CollectionsHandler) copyBatch(ctx context.Context, selectSQL, insertSQL string, afterPKID int64, columnCount int, idType registry.IDType) (int, int64, error) {
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		return 0, afterPKID, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Remove from registry
-	if err := h.registry.Delete(req.Name); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update registry: %v", err))
-		return
+	rows, err := tx.QueryContext(ctx, selectSQL, afterPKID)
+	if err != nil {
+		return 0, afterPKID, err
+	}
+	var batch [][]any
+	lastPKID := afterPKID
+	for rows.Next() {
+		values := make([]any, columnCount+1)
+		dest := make([]any, columnCount+2)
+		dest[0] = &lastPKID
+		for i := range values {
+			dest[i+1] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, afterPKID, err
+		}
+		batch = append(batch, values)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, afterPKID, err
 	}
 
-	response := DestroyResponse{
-		Message: fmt.Sprintf("Collection '%s' destroyed successfully", req.Name),
+	if idType != registry.IDTypeClient {
+		for i, id := range generateRecordIDs(idType, len(batch)) {
+			batch[i][0] = id
+		}
+	}
+	for _, values := range batch {
+		if _, err := tx.ExecContext(ctx, insertSQL, values...); err != nil {
+			return 0, afterPKID, err
+		}
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	if err := tx.Commit(); err != nil {
+		return 0, afterPKID, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(batch), lastPKID, nil
 }
 
 // validateCollectionName validates a collection name against all PRD-047 and PRD-048 rules.
@@ -694,6 +1585,12 @@ func validateCollectionName(name string) error {
 	return nil
 }
 
+// ValidateCollectionName reports why name cannot be a collection name, if it
+// cannot. Startup schema discovery uses it to decide which tables to register.
+func ValidateCollectionName(name string) error {
+	return validateCollectionName(name)
+}
+
 // validateCollectionCount checks if the collection count limit has been reached.
 func validateCollectionCount(reg *registry.SchemaRegistry) error {
 	count := reg.Count()
@@ -707,7 +1604,7 @@ func validateCollectionCount(reg *registry.SchemaRegistry) error {
 // Rules applied:
 // 1. Name cannot be empty
 // 2. Length must be between 3 and 63 characters
-// 3. Cannot be a system column (id, ulid)
+// 3. Cannot be a system column (pkid, id, created_at, updated_at, _rev, deleted_at)
 // 4. Must match pattern: start with lowercase letter, contain only lowercase letters, numbers, and underscores
 // 5. Cannot be a SQL reserved keyword
 func validateColumnName(name string) error {
@@ -744,8 +1641,8 @@ func validateColumnName(name string) error {
 
 // validateColumnCount checks if adding more columns would exceed the limit.
 func validateColumnCount(collection *registry.Collection, addingCount int) error {
-	// collection.Columns does not include system columns, so add SystemColumnsCount
-	totalColumns := len(collection.Columns) + constants.SystemColumnsCount + addingCount
+	// collection.Columns does not include system columns, so add them separately
+	totalColumns := len(collection.Columns) + systemColumnCount(collection.SoftDelete) + addingCount
 	if totalColumns > constants.MaxColumnsPerCollection {
 		return fmt.Errorf("maximum number of columns (%d) reached for collection '%s'",
 			constants.MaxColumnsPerCollection, collection.Name)
@@ -753,6 +1650,23 @@ func validateColumnCount(collection *registry.Collection, addingCount int) error
 	return nil
 }
 
+// systemColumnCount returns the number of system columns for a collection.
+func systemColumnCount(softDelete bool) int {
+	if softDelete {
+		return constants.SystemColumnsCount + 1
+	}
+	return constants.SystemColumnsCount
+}
+
+// softDeleteColumn returns the definition of the deleted_at system column.
+func softDeleteColumn() registry.Column {
+	return registry.Column{
+		Name:     constants.SoftDeleteColumn,
+		Type:     registry.TypeDatetime,
+		Nullable: true,
+	}
+}
+
 // validateColumnType validates a column type with deprecated type checking.
 func validateColumnType(typeStr string) error {
 	// Check for deprecated types first
@@ -774,8 +1688,8 @@ func validateColumnType(typeStr string) error {
 // applyColumnDefaults applies type-based defaults to nullable columns if not explicitly set.
 // This ensures that nullable columns have database-level defaults during table creation.
 func applyColumnDefaults(column *registry.Column) {
-	// Only apply defaults for nullable fields
-	if !column.Nullable {
+	// Only apply defaults for nullable fields; computed columns are always written
+	if !column.Nullable || column.Computed != "" {
 		return
 	}
 
@@ -784,6 +1698,13 @@ func applyColumnDefaults(column *registry.Column) {
 		return
 	}
 
+	// A reference holds a record id or nothing; '' would name no record
+	if column.References != "" {
+		null := "NULL"
+		column.DefaultValue = &null
+		return
+	}
+
 	// Apply type-based defaults for nullable fields
 	// Note: These are SQL DEFAULT values, so string types need quotes
 	var defaultValue string
@@ -805,6 +1726,13 @@ func applyColumnDefaults(column *registry.Column) {
 	}
 
 	column.DefaultValue = &defaultValue
+
+	// A type default the column's constraints reject becomes NULL instead,
+	// e.g. '' for an enum column or 0 for an integer with min 1
+	if value, ok := insertDefault(*column); ok && value != nil && validateFieldConstraints(*column, value) != nil {
+		null := "NULL"
+		column.DefaultValue = &null
+	}
 }
 
 // validateDefaultValue validates a default value against column type.
@@ -967,6 +1895,10 @@ func (h *CollectionsHandler) validateAddColumns(columns []registry.Column, colle
 		if err := validateDefaultValue(&col); err != nil {
 			return err
 		}
+
+		if err := validateColumnConstraints(col); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -994,10 +1926,334 @@ func (h *CollectionsHandler) validateRemoveColumns(columnNames []string, collect
 		if !found {
 			return fmt.Errorf("column '%s' does not exist", colName)
 		}
+
+		// Indexed columns can only be removed once their indexes are removed
+		for _, idx := range collection.Indexes {
+			for _, indexed := range idx.Columns {
+				if indexed == colName {
+					return fmt.Errorf("column '%s' is used by index '%s'", colName, idx.Name)
+				}
+			}
+		}
+		if colName == ttlColumn(collection) {
+			return fmt.Errorf("column '%s' is used by the ttl policy", colName)
+		}
+	}
+	return nil
+}
+
+// validateIndexes validates indexes to be created on a collection.
+// Index columns may be user columns or the id and timestamp system columns.
+func (h *CollectionsHandler) validateIndexes(indexes []registry.Index, collection *registry.Collection) error {
+	columns := make(map[string]bool, len(collection.Columns)+4)
+	for _, col := range collection.Columns {
+		columns[col.Name] = true
+	}
+	for _, col := range queryableSystemColumns() {
+		columns[col.Name] = true
+	}
+	if collection.SoftDelete {
+		columns[constants.SoftDeleteColumn] = true
+	}
+
+	existing := make(map[string]bool, len(collection.Indexes))
+	for _, idx := range collection.Indexes {
+		existing[idx.Name] = true
+	}
+
+	seen := make(map[string]bool, len(indexes))
+	for i, idx := range indexes {
+		if idx.Name == "" {
+			return fmt.Errorf("index %d: name is required", i)
+		}
+
+		if err := validateIndexName(idx.Name); err != nil {
+			return fmt.Errorf("index '%s': %v", idx.Name, err)
+		}
+
+		if seen[idx.Name] {
+			return fmt.Errorf("duplicate index name '%s'", idx.Name)
+		}
+		seen[idx.Name] = true
+
+		// Index names share one namespace per database on SQLite and PostgreSQL
+		if existing[idx.Name] {
+			return fmt.Errorf("index '%s' already exists", idx.Name)
+		}
+		if owner := h.indexOwner(idx.Name, collection.Name); owner != "" {
+			return fmt.Errorf("index '%s' already exists on collection '%s'", idx.Name, owner)
+		}
+
+		if len(idx.Columns) == 0 {
+			return fmt.Errorf("index '%s': at least one column is required", idx.Name)
+		}
+		if len(idx.Columns) > constants.MaxIndexColumns {
+			return fmt.Errorf("index '%s': maximum number of columns (%d) exceeded", idx.Name, constants.MaxIndexColumns)
+		}
+
+		indexed := make(map[string]bool, len(idx.Columns))
+		for _, col := range idx.Columns {
+			if !columns[col] {
+				return fmt.Errorf("index '%s': column '%s' does not exist", idx.Name, col)
+			}
+			if indexed[col] {
+				return fmt.Errorf("index '%s': duplicate column '%s'", idx.Name, col)
+			}
+			indexed[col] = true
+		}
+	}
+	return nil
+}
+
+// validateIndexName validates an index name using the column naming rules.
+func validateIndexName(name string) error {
+	if len(name) > constants.MaxColumnNameLength {
+		return fmt.Errorf("index name must not exceed %d characters", constants.MaxColumnNameLength)
+	}
+
+	if !columnNameRegex.MatchString(name) {
+		return fmt.Errorf("index name must start with a lowercase letter and contain only lowercase letters, numbers, and underscores")
+	}
+
+	if constants.IsReservedKeyword(name) {
+		return fmt.Errorf("'%s' is a reserved keyword and cannot be used as an index name", name)
+	}
+
+	return nil
+}
+
+// indexOwner returns the name of another collection that already has an index with the given name
+func (h *CollectionsHandler) indexOwner(indexName, collectionName string) string {
+	for _, other := range h.registry.GetAll() {
+		if other.Name == collectionName {
+			continue
+		}
+		for _, idx := range other.Indexes {
+			if idx.Name == indexName {
+				return other.Name
+			}
+		}
+	}
+	return ""
+}
+
+// validateRemoveIndexes validates indexes to be removed
+func validateRemoveIndexes(indexNames []string, collection *registry.Collection) error {
+	seen := make(map[string]bool, len(indexNames))
+	for _, indexName := range indexNames {
+		if indexName == "" {
+			return fmt.Errorf("index name cannot be empty")
+		}
+
+		if seen[indexName] {
+			return fmt.Errorf("duplicate index name '%s'", indexName)
+		}
+		seen[indexName] = true
+
+		found := false
+		for _, idx := range collection.Indexes {
+			if idx.Name == indexName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("index '%s' does not exist", indexName)
+		}
+	}
+	return nil
+}
+
+// renameIndexColumn replaces a renamed column in index definitions.
+// Column slices are rebuilt so saved copies of the indexes are not modified.
+func renameIndexColumn(indexes []registry.Index, oldName, newName string) {
+	for i := range indexes {
+		columns := make([]string, len(indexes[i].Columns))
+		for j, col := range indexes[i].Columns {
+			if col == oldName {
+				col = newName
+			}
+			columns[j] = col
+		}
+		indexes[i].Columns = columns
+	}
+}
+
+// renameDefaultColumn replaces a renamed column in a default_sort or
+// default_fields list, keeping any sort direction prefix. The list is rebuilt
+// so saved copies are not modified.
+func renameDefaultColumn(defaults []string, oldName, newName string) []string {
+	if defaults == nil {
+		return nil
+	}
+	renamed := make([]string, len(defaults))
+	for i, entry := range defaults {
+		prefix, name := splitSortDirection(entry)
+		if name == oldName {
+			entry = prefix + newName
+		}
+		renamed[i] = entry
+	}
+	return renamed
+}
+
+// splitSortDirection splits a default_sort entry into its direction prefix and column
+func splitSortDirection(entry string) (string, string) {
+	if strings.HasPrefix(entry, "-") || strings.HasPrefix(entry, "+") {
+		return entry[:1], entry[1:]
+	}
+	return "", entry
+}
+
+// nilIfEmpty returns nil for an empty list so a cleared default is omitted from JSON
+func nilIfEmpty(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// nilIfUnset returns nil for a pagination override that sets no limit
+func nilIfUnset(pagination *registry.Pagination) *registry.Pagination {
+	if pagination == nil || *pagination == (registry.Pagination{}) {
+		return nil
+	}
+	return pagination
+}
+
+// paginationValue returns the pagination override of a collection, the zero
+// value when it has none
+func paginationValue(pagination *registry.Pagination) registry.Pagination {
+	if pagination == nil {
+		return registry.Pagination{}
+	}
+	return *pagination
+}
+
+// validateListDefaults checks that default_sort and default_fields use the
+// syntax of the sort and fields query parameters and only name columns the
+// collection can list
+func validateListDefaults(collection *registry.Collection) error {
+	if len(collection.DefaultSort) > constants.MaxSortFieldsPerRequest {
+		return fmt.Errorf("default_sort: maximum number of sort fields (%d) exceeded", constants.MaxSortFieldsPerRequest)
+	}
+	sortable := sortableColumns(collection)
+	hidden := hiddenColumns(collection)
+	for _, entry := range collection.DefaultSort {
+		sorts, _ := parseSortParam(entry)
+		if len(sorts) != 1 {
+			return fmt.Errorf("default_sort: invalid entry '%s'", entry)
+		}
+		if !sortable[sorts[0].column] {
+			return fmt.Errorf("default_sort: invalid sort column: %s", sorts[0].column)
+		}
+		if hidden[sorts[0].column] {
+			return fmt.Errorf("default_sort: sort field '%s' is hidden", sorts[0].column)
+		}
+	}
+
+	for _, field := range collection.DefaultFields {
+		if strings.TrimSpace(field) == "" || strings.Contains(field, ",") {
+			return fmt.Errorf("default_fields: invalid entry '%s'", field)
+		}
+	}
+	if _, _, err := parseFieldsParam(strings.Join(collection.DefaultFields, ","), collection, hidden); err != nil {
+		return fmt.Errorf("default_fields: %v", err)
+	}
+	return validatePagination(collection)
+}
+
+// validatePagination checks that the page sizes of a collection are at least
+// 1, at most the server ceiling, and that the default does not exceed the
+// maximum
+func validatePagination(collection *registry.Collection) error {
+	if collection.Pagination == nil {
+		return nil
+	}
+	limits := map[string]int{
+		"default_limit": collection.Pagination.DefaultLimit,
+		"max_limit":     collection.Pagination.MaxLimit,
+	}
+	for _, name := range []string{"default_limit", "max_limit"} {
+		if limits[name] < 0 || limits[name] > constants.MaxCollectionPaginationLimit {
+			return fmt.Errorf("pagination: %s must be between %d and %d", name, constants.MinPageSize, constants.MaxCollectionPaginationLimit)
+		}
+	}
+	if defaultLimit, maxLimit := pageLimits(collection); defaultLimit > maxLimit {
+		return fmt.Errorf("pagination: default_limit %d exceeds max_limit %d", defaultLimit, maxLimit)
 	}
 	return nil
 }
 
+// validateUpdateListDefaults checks the list defaults a collection will have
+// once the update is applied. Columns still named by the defaults cannot be
+// removed or hidden, and new defaults may name columns renamed or added by the
+// request. The seq field is checked the same way against expose_sequence.
+func validateUpdateListDefaults(req *UpdateRequest, collection *registry.Collection) error {
+	planned := &registry.Collection{
+		Name:           collection.Name,
+		SoftDelete:     collection.SoftDelete,
+		ExposeSequence: collection.ExposeSequence,
+		Columns:        append([]registry.Column(nil), collection.Columns...),
+		DefaultSort:    collection.DefaultSort,
+		DefaultFields:  collection.DefaultFields,
+		Pagination:     collection.Pagination,
+	}
+	if req.ExposeSequence != nil {
+		planned.ExposeSequence = *req.ExposeSequence
+	}
+	if req.Pagination != nil {
+		planned.Pagination = nilIfUnset(req.Pagination)
+	}
+	for _, rename := range req.RenameColumns {
+		for i := range planned.Columns {
+			if planned.Columns[i].Name == rename.OldName {
+				planned.Columns[i].Name = rename.NewName
+			}
+		}
+		planned.DefaultSort = renameDefaultColumn(planned.DefaultSort, rename.OldName, rename.NewName)
+		planned.DefaultFields = renameDefaultColumn(planned.DefaultFields, rename.OldName, rename.NewName)
+	}
+	hides := false
+	for _, modify := range req.ModifyColumns {
+		for i := range planned.Columns {
+			if planned.Columns[i].Name == modify.Name && modify.Hidden != nil {
+				planned.Columns[i].Hidden = *modify.Hidden
+				hides = hides || *modify.Hidden
+			}
+		}
+	}
+	planned.Columns = append(planned.Columns, req.AddColumns...)
+	if req.DefaultSort != nil {
+		planned.DefaultSort = req.DefaultSort
+	}
+	if req.DefaultFields != nil {
+		planned.DefaultFields = req.DefaultFields
+	}
+
+	for _, colName := range req.RemoveColumns {
+		for _, entry := range planned.DefaultSort {
+			if _, name := splitSortDirection(entry); name == colName {
+				return fmt.Errorf("column '%s' is used by default_sort; change the default in the same request to remove it", colName)
+			}
+		}
+		if slices.Contains(planned.DefaultFields, colName) {
+			return fmt.Errorf("column '%s' is used by default_fields; change the default in the same request to remove it", colName)
+		}
+	}
+	planned.Columns = slices.DeleteFunc(planned.Columns, func(col registry.Column) bool {
+		return slices.Contains(req.RemoveColumns, col.Name)
+	})
+
+	if err := validateExposeSequence(planned); err != nil {
+		return err
+	}
+	if req.DefaultSort == nil && req.DefaultFields == nil && req.ExposeSequence == nil && req.Pagination == nil && !hides {
+		return nil
+	}
+	return validateListDefaults(planned)
+}
+
 // validateRenameColumns validates columns to be renamed
 func (h *CollectionsHandler) validateRenameColumns(renames []RenameColumn, collection *registry.Collection) error {
 	for _, rename := range renames {
@@ -1063,6 +2319,13 @@ func (h *CollectionsHandler) validateModifyColumns(modifies []ModifyColumn, coll
 			if existing.Name == modify.Name {
 				found = true
 
+				if existing.References != "" && modify.Type != registry.TypeString {
+					return fmt.Errorf("column '%s' references another collection and must remain a string", modify.Name)
+				}
+				if existing.Name == ttlColumn(collection) && modify.Type != registry.TypeDatetime {
+					return fmt.Errorf("column '%s' is used by the ttl policy and must remain a datetime", modify.Name)
+				}
+
 				// Prevent changing default value after collection creation
 				// to avoid data inconsistency and corruption
 				if modify.DefaultValue != nil {
@@ -1075,6 +2338,13 @@ func (h *CollectionsHandler) validateModifyColumns(modifies []ModifyColumn, coll
 						return fmt.Errorf("cannot change default value for column '%s': default values are immutable after collection creation to prevent data inconsistency", modify.Name)
 					}
 				}
+
+				modified := existing
+				modified.Type = modify.Type
+				modified.MaxLength, modified.Min, modified.Max, modified.Enum = modify.MaxLength, modify.Min, modify.Max, modify.Enum
+				if err := validateColumnConstraints(modified); err != nil {
+					return err
+				}
 				break
 			}
 		}
@@ -1085,11 +2355,12 @@ func (h *CollectionsHandler) validateModifyColumns(modifies []ModifyColumn, coll
 	return nil
 }
 
-// generateCreateTableDDL generates CREATE TABLE DDL for the given dialect
-func generateCreateTableDDL(tableName string, columns []registry.Column, dialect database.DialectType) string {
+// generateCreateTableDDL generates CREATE TABLE DDL for the given dialect,
+// with an id column sized for the collection's id strategy
+func generateCreateTableDDL(tableName string, columns []registry.Column, idType registry.IDType, dialect database.DialectType) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (", tableName))
+	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (", query.QuoteIdent(dialect, tableName)))
 
 	// Add pkid column (auto-increment primary key)
 	switch dialect {
@@ -1101,13 +2372,21 @@ func generateCreateTableDDL(tableName string, columns []registry.Column, dialect
 		sb.WriteString("\n  pkid INTEGER PRIMARY KEY AUTOINCREMENT")
 	}
 
-	// Add id column (ULID: unique, not null)
-	sb.WriteString(",\n  id CHAR(26) NOT NULL UNIQUE")
+	// Add id column (ULID, UUIDv7 or client-supplied: unique, not null)
+	sb.WriteString(fmt.Sprintf(",\n  id %s NOT NULL UNIQUE", idColumnSQL(idType)))
+
+	// Add record timestamps (maintained by the server on create and update)
+	timestampType := mapColumnTypeToSQL(registry.TypeDatetime, dialect)
+	sb.WriteString(fmt.Sprintf(",\n  %s %s", constants.CreatedAtColumn, timestampType))
+	sb.WriteString(fmt.Sprintf(",\n  %s %s", constants.UpdatedAtColumn, timestampType))
+
+	// Add record revision (starts at 1 and is incremented by every update)
+	sb.WriteString(fmt.Sprintf(",\n  %s %s NOT NULL DEFAULT 1", constants.RevisionColumn, mapColumnTypeToSQL(registry.TypeInteger, dialect)))
 
 	// Add user-defined columns
 	for _, col := range columns {
 		sb.WriteString(",\n  ")
-		sb.WriteString(col.Name)
+		sb.WriteString(query.QuoteIdent(dialect, col.Name))
 		sb.WriteString(" ")
 		sb.WriteString(mapColumnTypeToSQL(col.Type, dialect))
 
@@ -1123,6 +2402,8 @@ func generateCreateTableDDL(tableName string, columns []registry.Column, dialect
 			sb.WriteString(" DEFAULT ")
 			sb.WriteString(*col.DefaultValue)
 		}
+
+		sb.WriteString(referencesSQL(col, dialect))
 	}
 
 	sb.WriteString("\n)")
@@ -1137,7 +2418,7 @@ func generateAddColumnDDL(tableName string, column registry.Column, dialect data
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s",
-		tableName, column.Name, mapColumnTypeToSQL(column.Type, dialect)))
+		query.QuoteIdent(dialect, tableName), query.QuoteIdent(dialect, column.Name), mapColumnTypeToSQL(column.Type, dialect)))
 
 	if !column.Nullable {
 		sb.WriteString(" NOT NULL")
@@ -1150,26 +2431,64 @@ func generateAddColumnDDL(tableName string, column registry.Column, dialect data
 		sb.WriteString(*column.DefaultValue)
 	}
 
+	sb.WriteString(referencesSQL(column, dialect))
+
 	return sb.String()
 }
 
+// referencesSQL returns the foreign key clause of a reference column, which
+// only PostgreSQL tables get: MySQL cannot use TEXT columns in a foreign key
+// and SQLite only enforces them with a pragma Moon does not set. The data
+// handlers check references on every dialect.
+func referencesSQL(column registry.Column, dialect database.DialectType) string {
+	if column.References == "" || dialect != database.DialectPostgres {
+		return ""
+	}
+	return fmt.Sprintf(" REFERENCES %s(id)", query.QuoteIdent(dialect, column.References))
+}
+
 // generateAddUniqueConstraintDDL generates DDL to add a unique constraint/index to an existing column
 // This is called after the column has been added via generateAddColumnDDL
 func generateAddUniqueConstraintDDL(tableName string, columnName string, dialect database.DialectType) string {
+	table := query.QuoteIdent(dialect, tableName)
+	column := query.QuoteIdent(dialect, columnName)
 	switch dialect {
 	case database.DialectSQLite:
 		// SQLite doesn't support ALTER TABLE ADD CONSTRAINT for UNIQUE
 		// Use CREATE UNIQUE INDEX instead
-		indexName := fmt.Sprintf("idx_%s_%s", tableName, columnName)
-		return fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s(%s)", indexName, tableName, columnName)
+		indexName := query.QuoteIdent(dialect, fmt.Sprintf("idx_%s_%s", tableName, columnName))
+		return fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s(%s)", indexName, table, column)
 	case database.DialectPostgres, database.DialectMySQL:
 		// PostgreSQL and MySQL support ADD CONSTRAINT
-		constraintName := fmt.Sprintf("%s_%s_unique", tableName, columnName)
-		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE(%s)", tableName, constraintName, columnName)
+		constraintName := query.QuoteIdent(dialect, fmt.Sprintf("%s_%s_unique", tableName, columnName))
+		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE(%s)", table, constraintName, column)
 	default:
 		// Fallback to constraint syntax
-		constraintName := fmt.Sprintf("%s_%s_unique", tableName, columnName)
-		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE(%s)", tableName, constraintName, columnName)
+		constraintName := query.QuoteIdent(dialect, fmt.Sprintf("%s_%s_unique", tableName, columnName))
+		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE(%s)", table, constraintName, column)
+	}
+}
+
+// generateCreateIndexDDL generates CREATE [UNIQUE] INDEX DDL for a collection index
+func generateCreateIndexDDL(tableName string, index registry.Index, dialect database.DialectType) string {
+	// The statement is the same for all supported dialects
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique,
+		query.QuoteIdent(dialect, index.Name), query.QuoteIdent(dialect, tableName), query.QuoteIdents(dialect, index.Columns))
+}
+
+// generateDropIndexDDL generates DROP INDEX DDL for the given dialect
+func generateDropIndexDDL(tableName string, indexName string, dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectMySQL:
+		// MySQL index names are scoped to the table
+		return fmt.Sprintf("DROP INDEX %s ON %s", query.QuoteIdent(dialect, indexName), query.QuoteIdent(dialect, tableName))
+	default:
+		// SQLite and PostgreSQL index names are scoped to the schema
+		return fmt.Sprintf("DROP INDEX %s", query.QuoteIdent(dialect, indexName))
 	}
 }
 
@@ -1177,11 +2496,14 @@ func generateAddUniqueConstraintDDL(tableName string, columnName string, dialect
 func generateDropColumnDDL(tableName string, columnName string, dialect database.DialectType) string {
 	// SQLite has limited ALTER TABLE support, but DROP COLUMN is supported in SQLite 3.35.0+
 	// Since we're using modernc.org/sqlite, it should support this
-	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, columnName)
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", query.QuoteIdent(dialect, tableName), query.QuoteIdent(dialect, columnName))
 }
 
 // generateRenameColumnDDL generates column rename DDL for the given dialect
 func generateRenameColumnDDL(tableName string, oldName string, newName string, dialect database.DialectType) string {
+	tableName = query.QuoteIdent(dialect, tableName)
+	oldName = query.QuoteIdent(dialect, oldName)
+	newName = query.QuoteIdent(dialect, newName)
 	switch dialect {
 	case database.DialectPostgres:
 		return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", tableName, oldName, newName)
@@ -1198,15 +2520,24 @@ func generateRenameColumnDDL(tableName string, oldName string, newName string, d
 	}
 }
 
-// generateModifyColumnDDL generates column modification DDL for the given dialect
+// generateRenameTableDDL generates table rename DDL; the syntax is shared by
+// SQLite, PostgreSQL and MySQL
+func generateRenameTableDDL(oldName string, newName string, dialect database.DialectType) string {
+	return fmt.Sprintf("ALTER TABLE %s RENAME TO %s", query.QuoteIdent(dialect, oldName), query.QuoteIdent(dialect, newName))
+}
+
+// generateModifyColumnDDL generates column modification DDL for the given
+// dialect; SQLite columns are modified by sqliteRebuildStatements instead
 func generateModifyColumnDDL(tableName string, modify ModifyColumn, dialect database.DialectType) string {
 	var sb strings.Builder
+	tableName = query.QuoteIdent(dialect, tableName)
+	columnName := query.QuoteIdent(dialect, modify.Name)
 
 	switch dialect {
 	case database.DialectPostgres:
 		// PostgreSQL requires separate ALTER COLUMN statements for each change
 		sb.WriteString(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s",
-			tableName, modify.Name, mapColumnTypeToSQL(modify.Type, dialect)))
+			tableName, columnName, mapColumnTypeToSQL(modify.Type, dialect)))
 
 		// Note: Additional ALTER COLUMN statements for nullable, default, etc. would be separate queries
 		// For simplicity, we're only handling type changes here
@@ -1214,7 +2545,7 @@ func generateModifyColumnDDL(tableName string, modify ModifyColumn, dialect data
 	case database.DialectMySQL:
 		// MySQL uses MODIFY COLUMN with full column definition
 		sb.WriteString(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s",
-			tableName, modify.Name, mapColumnTypeToSQL(modify.Type, dialect)))
+			tableName, columnName, mapColumnTypeToSQL(modify.Type, dialect)))
 
 		if modify.Nullable != nil && !*modify.Nullable {
 			sb.WriteString(" NOT NULL")
@@ -1226,14 +2557,9 @@ func generateModifyColumnDDL(tableName string, modify ModifyColumn, dialect data
 			sb.WriteString(" DEFAULT ")
 			sb.WriteString(*modify.DefaultValue)
 		}
-	case database.DialectSQLite:
-		// SQLite has very limited ALTER TABLE support for modifying columns
-		// In production, you'd need to recreate the table
-		// For now, we'll return an error-prone statement
-		sb.WriteString(fmt.Sprintf("-- SQLite ALTER COLUMN not fully supported: %s", modify.Name))
 	default:
 		sb.WriteString(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s",
-			tableName, modify.Name, mapColumnTypeToSQL(modify.Type, dialect)))
+			tableName, columnName, mapColumnTypeToSQL(modify.Type, dialect)))
 	}
 
 	return sb.String()
@@ -1265,6 +2591,8 @@ func mapColumnTypeToPostgres(colType registry.ColumnType) string {
 		return "TIMESTAMP"
 	case registry.TypeJSON:
 		return "JSONB"
+	case registry.TypeDecimal:
+		return fmt.Sprintf("NUMERIC(%d,%d)", constants.DefaultDecimalPrecision, constants.DefaultDecimalScale)
 	default:
 		return "TEXT"
 	}
@@ -1282,6 +2610,8 @@ func mapColumnTypeToMySQL(colType registry.ColumnType) string {
 		return "DATETIME"
 	case registry.TypeJSON:
 		return "JSON"
+	case registry.TypeDecimal:
+		return fmt.Sprintf("DECIMAL(%d,%d)", constants.DefaultDecimalPrecision, constants.DefaultDecimalScale)
 	default:
 		return "TEXT"
 	}
@@ -1299,6 +2629,8 @@ func mapColumnTypeToSQLite(colType registry.ColumnType) string {
 		return "TEXT"
 	case registry.TypeJSON:
 		return "TEXT"
+	case registry.TypeDecimal:
+		return "NUMERIC"
 	default:
 		return "TEXT"
 	}
@@ -1311,9 +2643,36 @@ func writeJSON(w http.ResponseWriter, statusCode int, data any) {
 	json.NewEncoder(w).Encode(data)
 }
 
-func writeError(w http.ResponseWriter, statusCode int, message string) {
-	writeJSON(w, statusCode, map[string]any{
-		"error": message,
-		"code":  statusCode,
-	})
+// versionedResponse is a response whose shape depends on the API version
+type versionedResponse interface {
+	forVersion(version apiversion.Version) any
+}
+
+// writeResponse writes a JSON response in the API version negotiated for r,
+// with the field case of a data request
+func writeResponse(w http.ResponseWriter, r *http.Request, statusCode int, data any) {
+	if cased, ok := data.(camelCasedResponse); ok && camelFields(r.Context()) {
+		data = cased.camelCased()
+	}
+	if versioned, ok := data.(versionedResponse); ok {
+		data = versioned.forVersion(apiversion.FromContext(r.Context()))
+	}
+	writeJSON(w, statusCode, data)
+}
+
+// writeError writes a JSON error response with a machine-readable code,
+// carrying the request ID for correlation. Database errors of a request whose
+// deadline has passed are reported as 504 query_timeout: the driver error is
+// then only the cancellation of the statement.
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, code apperrors.ErrorCode, message string) {
+	if code == apperrors.CodeDatabaseError && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		statusCode, code, message = http.StatusGatewayTimeout, apperrors.CodeQueryTimeout, "the query did not finish within database.query_timeout; narrow the filters or search"
+	}
+	writeJSON(w, statusCode, errorBody(r, statusCode, code, message))
+}
+
+// errorBody builds an error payload in the negotiated API version, carrying
+// the request ID from the request context; callers may add further fields
+func errorBody(r *http.Request, statusCode int, code apperrors.ErrorCode, message string) map[string]any {
+	return apperrors.VersionedBody(apiversion.FromContext(r.Context()), statusCode, code, message, logging.GetRequestID(r.Context()))
 }
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// ArchiveRequest represents the request for archiving or unarchiving a collection
+type ArchiveRequest struct {
+	Name string `json:"name"`
+}
+
+// ArchiveResponse represents the response for archiving or unarchiving a collection
+type ArchiveResponse struct {
+	Collection *registry.Collection `json:"collection"`
+	Message    string               `json:"message"`
+}
+
+// Archive handles POST /collections:archive
+// It detaches a collection from the data endpoints, which then answer 410
+// Gone, and from collections:list and the documentation. The table and its
+// records are kept; collections:unarchive brings the collection back.
+func (h *CollectionsHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// Unarchive handles POST /collections:unarchive
+func (h *CollectionsHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+// setArchived archives or unarchives the collection named in the request
+func (h *CollectionsHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	var req ArchiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	// Normalize collection name to lowercase (PRD-047)
+	req.Name = strings.ToLower(req.Name)
+	if err := h.validateName(req.Name); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, err.Error())
+		return
+	}
+	if !requireScope(w, r, req.Name, auth.ScopeSchema) {
+		return
+	}
+
+	table := h.tableName(r, req.Name)
+	audit.SetCollection(r.Context(), table)
+	collection, exists := h.registry.Get(table)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", req.Name))
+		return
+	}
+
+	state := "archived"
+	if !archived {
+		state = "unarchived"
+	}
+	if collection.Archived == archived {
+		writeError(w, r, http.StatusConflict, apperrors.CodeConflict, fmt.Sprintf("collection '%s' is already %s", req.Name, state))
+		return
+	}
+
+	collection.Archived = archived
+	if err := h.registry.Set(collection); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to update registry: %v", err))
+		return
+	}
+
+	response := ArchiveResponse{
+		Collection: h.logicalView(collection),
+		Message:    fmt.Sprintf("Collection '%s' %s successfully", req.Name, state),
+	}
+	writeResponse(w, r, http.StatusOK, response)
+}
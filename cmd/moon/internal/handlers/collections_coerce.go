@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// maxIncompatibleSamples is how many ids of records holding incompatible
+// values a rejected type change lists
+const maxIncompatibleSamples = 5
+
+// coerceBatchSize is how many records one coercing UPDATE statement names
+const coerceBatchSize = 500
+
+// incompatibleValuesError rejects a type change while records hold values
+// that the new type cannot represent
+type incompatibleValuesError struct {
+	*apperrors.APIError
+	Count     int      // records holding an incompatible value
+	SampleIDs []string // ids of the first of them
+}
+
+func (e *incompatibleValuesError) Unwrap() error {
+	return e.APIError
+}
+
+// writeIncompatibleValues writes a 409 incompatible_data with the number of
+// incompatible values and sample record ids
+func writeIncompatibleValues(w http.ResponseWriter, r *http.Request, e *incompatibleValuesError) {
+	body := errorBody(r, e.StatusCode, e.ErrorCode, e.Message)
+	apperrors.SetField(body, "incompatible_count", e.Count)
+	apperrors.SetField(body, "sample_ids", e.SampleIDs)
+	writeJSON(w, e.StatusCode, body)
+}
+
+// planCoercions checks the records of every column whose type a modify
+// changes against the new type, before the collection is changed. Without
+// force, any value the new type cannot represent rejects the update. With
+// force, the statements returned set those values to NULL when the modified
+// column is nullable or to its default otherwise; a column with neither is
+// still rejected. renamed maps columns renamed by the same update, which
+// have not run yet, to their names in the table. The statements are to run
+// before the type changes.
+func (h *CollectionsHandler) planCoercions(ctx context.Context, table string, collection *registry.Collection, modifies []ModifyColumn, renamed map[string]string, force bool) ([]updateStatement, error) {
+	dialect := h.db.Dialect()
+	var statements []updateStatement
+
+	for _, modify := range modifies {
+		before, ok := findColumn(collection.Columns, modify.Name)
+		if !ok || before.Type == modify.Type {
+			continue
+		}
+		stored := modify.Name
+		if name, ok := renamed[modify.Name]; ok {
+			stored = name
+		}
+
+		ids, err := h.incompatibleIDs(ctx, table, stored, modify.Type)
+		if err != nil {
+			return nil, apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check column '%s': %v", modify.Name, err)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		nullable := before.Nullable
+		if modify.Nullable != nil {
+			nullable = *modify.Nullable
+		}
+		var replacement any
+		reason := "pass force=true to set them to null"
+		if !nullable {
+			value, ok := coercionDefault(before.DefaultValue, modify.Type, dialect)
+			if !ok {
+				return nil, incompatibleValues(modify, ids, "the column is not nullable and has no default of that type to set them to")
+			}
+			replacement = value
+			reason = fmt.Sprintf("pass force=true to set them to the default '%s'", value)
+		}
+		if !force {
+			return nil, incompatibleValues(modify, ids, reason)
+		}
+
+		for chunk := range slices.Chunk(ids, coerceBatchSize) {
+			statements = append(statements, coerceStatement(table, modify.Name, replacement, chunk, dialect))
+		}
+	}
+	return statements, nil
+}
+
+// incompatibleValues returns the 409 rejecting a type change
+func incompatibleValues(modify ModifyColumn, ids []string, reason string) *incompatibleValuesError {
+	noun := "values"
+	if len(ids) == 1 {
+		noun = "value"
+	}
+	return &incompatibleValuesError{
+		APIError: apperrors.Newf(http.StatusConflict, apperrors.CodeIncompatibleData,
+			"column '%s' holds %d %s that cannot be converted to %s; %s", modify.Name, len(ids), noun, modify.Type, reason),
+		Count:     len(ids),
+		SampleIDs: ids[:min(len(ids), maxIncompatibleSamples)],
+	}
+}
+
+// incompatibleIDs returns the ids of the records whose value of a column, in
+// its text form, does not convert to a column type
+func (h *CollectionsHandler) incompatibleIDs(ctx context.Context, table, column string, colType registry.ColumnType) ([]string, error) {
+	dialect := h.db.Dialect()
+	quoted := query.QuoteIdent(dialect, column)
+	sqlQuery := fmt.Sprintf("SELECT id, CAST(%s AS %s) FROM %s WHERE %s IS NOT NULL",
+		quoted, textCastType(dialect), query.QuoteIdent(dialect, table), quoted)
+
+	rows, err := h.db.Query(ctx, sqlQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		if !castable(value, colType, dialect) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// textCastType returns the type casting a value to its text form
+func textCastType(dialect database.DialectType) string {
+	if dialect == database.DialectMySQL {
+		return "CHAR"
+	}
+	return "TEXT"
+}
+
+// coercionDefault returns the text form of a column default, kept as an SQL
+// literal such as '0.00', and whether it converts to the new type. A NULL
+// default is none. Boolean defaults such as "true" are written as the dialect
+// reads them back.
+func coercionDefault(defaultValue *string, colType registry.ColumnType, dialect database.DialectType) (string, bool) {
+	if defaultValue == nil || strings.EqualFold(*defaultValue, "null") {
+		return "", false
+	}
+	value, _ := unquoteSQLString(*defaultValue)
+	if colType == registry.TypeBoolean {
+		if b, err := strconv.ParseBool(value); err == nil && dialect != database.DialectPostgres {
+			value = "0"
+			if b {
+				value = "1"
+			}
+		}
+	}
+	return value, castable(value, colType, dialect)
+}
+
+// coerceStatement returns the statement setting a column of the given records
+// to a replacement value, or to NULL when replacement is nil
+func coerceStatement(table, column string, replacement any, ids []string, dialect database.DialectType) updateStatement {
+	var args []any
+	value := "NULL"
+	if replacement != nil {
+		args = append(args, replacement)
+		value = query.Placeholder(dialect, 1)
+	}
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		args = append(args, id)
+		placeholders[i] = query.Placeholder(dialect, len(args))
+	}
+	return updateStatement{
+		SQL: fmt.Sprintf("UPDATE %s SET %s = %s WHERE id IN (%s)", query.QuoteIdent(dialect, table),
+			query.QuoteIdent(dialect, column), value, strings.Join(placeholders, ", ")),
+		args:    args,
+		failure: fmt.Sprintf("coerce column '%s'", column),
+		code:    apperrors.CodeDatabaseError,
+		coerces: len(ids),
+	}
+}
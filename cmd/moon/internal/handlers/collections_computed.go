@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/expr"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// recomputeBatchSize is how many records one statement recomputing a
+// computed column updates
+const recomputeBatchSize = 500
+
+// validateComputedColumns checks the computed columns among columns: each is
+// a nullable integer or decimal without constraints, and its expression
+// parses and reads only integer or decimal columns that are not computed
+func validateComputedColumns(columns []registry.Column) error {
+	for _, col := range columns {
+		if col.Computed == "" {
+			continue
+		}
+		if col.Type != registry.TypeInteger && col.Type != registry.TypeDecimal {
+			return fmt.Errorf("computed column '%s' must be an integer or decimal, got %s", col.Name, col.Type)
+		}
+		if !col.Nullable {
+			return fmt.Errorf("computed column '%s' must be nullable: a null operand or a division by zero gives null", col.Name)
+		}
+		if col.MaxLength != nil || col.Min != nil || col.Max != nil || col.Enum != nil {
+			return fmt.Errorf("computed column '%s' cannot have constraints", col.Name)
+		}
+		e, err := expr.Parse(col.Computed)
+		if err != nil {
+			return fmt.Errorf("computed column '%s': invalid expression: %v", col.Name, err)
+		}
+		for _, name := range e.Columns() {
+			operand, ok := findColumn(columns, name)
+			switch {
+			case !ok:
+				return fmt.Errorf("computed column '%s' reads unknown column '%s'", col.Name, name)
+			case operand.Computed != "":
+				return fmt.Errorf("computed column '%s' cannot read computed column '%s'", col.Name, name)
+			case operand.Type != registry.TypeInteger && operand.Type != registry.TypeDecimal:
+				return fmt.Errorf("computed column '%s' reads '%s', which must be an integer or decimal, not %s", col.Name, name, operand.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// validateUpdateComputed checks the computed columns of the collection an
+// update would leave, so that an operand cannot be removed or lose its type
+func validateUpdateComputed(req *UpdateRequest, collection *registry.Collection) error {
+	planned := &registry.Collection{Columns: slices.Clone(collection.Columns)}
+	for _, rename := range req.RenameColumns {
+		for i := range planned.Columns {
+			if planned.Columns[i].Name == rename.OldName {
+				planned.Columns[i].Name = rename.NewName
+			}
+		}
+		renameComputedOperand(planned.Columns, rename.OldName, rename.NewName)
+	}
+	for _, modify := range req.ModifyColumns {
+		applyModifyColumn(planned, modify)
+	}
+	planned.Columns = append(planned.Columns, req.AddColumns...)
+	planned.Columns = slices.DeleteFunc(planned.Columns, func(col registry.Column) bool {
+		return slices.Contains(req.RemoveColumns, col.Name)
+	})
+	return validateComputedColumns(planned.Columns)
+}
+
+// renameComputedOperand rewrites the expressions of computed columns reading
+// a renamed column
+func renameComputedOperand(columns []registry.Column, oldName, newName string) {
+	for i := range columns {
+		if columns[i].Computed == "" {
+			continue
+		}
+		e, err := expr.Parse(columns[i].Computed)
+		if err != nil || !slices.Contains(e.Columns(), oldName) {
+			continue
+		}
+		e.Rename(oldName, newName)
+		columns[i].Computed = e.String()
+	}
+}
+
+// recomputeChanged recomputes the records of every computed column that an
+// update added or whose expression or type it changed. previous holds the
+// columns before the update. Errors are *apperrors.APIError values.
+func (h *CollectionsHandler) recomputeChanged(ctx context.Context, table string, collection *registry.Collection, previous []registry.Column) error {
+	for _, col := range collection.Columns {
+		if col.Computed == "" {
+			continue
+		}
+		if before, ok := findColumn(previous, col.Name); ok && before.Computed == col.Computed && before.Type == col.Type {
+			continue
+		}
+		if err := h.recomputeColumn(ctx, table, collection, col); err != nil {
+			return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to recompute column '%s': %v", col.Name, err)
+		}
+	}
+	return nil
+}
+
+// recomputeColumn sets a computed column of every record from its expression,
+// recomputeBatchSize records at a time in id order, so that a large table is
+// not locked by one long statement. updated_at and _rev are left alone.
+func (h *CollectionsHandler) recomputeColumn(ctx context.Context, table string, collection *registry.Collection, col registry.Column) error {
+	e, err := expr.Parse(col.Computed)
+	if err != nil {
+		return err
+	}
+	dialect := h.db.Dialect()
+	quotedTable := query.QuoteIdent(dialect, table)
+	value, _ := computedSQL(collection, col, e, nil, nil, dialect)
+	batchSQL := fmt.Sprintf("SELECT id FROM %s WHERE id > %s ORDER BY id LIMIT %d",
+		quotedTable, query.Placeholder(dialect, 1), recomputeBatchSize)
+	updateSQL := fmt.Sprintf("UPDATE %s SET %s = %s WHERE id > %s AND id <= %s",
+		quotedTable, query.QuoteIdent(dialect, col.Name), value, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2))
+
+	after := ""
+	for {
+		ids, err := h.batchIDs(ctx, batchSQL, after)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		last := ids[len(ids)-1]
+		if _, err := h.db.Exec(ctx, updateSQL, after, last); err != nil {
+			return err
+		}
+		if len(ids) < recomputeBatchSize {
+			return nil
+		}
+		after = last
+	}
+}
+
+// batchIDs returns the ids a batch query selects after the given id
+func (h *CollectionsHandler) batchIDs(ctx context.Context, batchSQL, after string) ([]string, error) {
+	rows, err := h.db.Query(ctx, batchSQL, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
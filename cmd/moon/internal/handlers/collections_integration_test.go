@@ -3,10 +3,12 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -178,6 +180,247 @@ func TestCollectionsHandler_Destroy_Integration(t *testing.T) {
 	}
 }
 
+// TestCollectionsHandler_Rename_Integration tests Rename with real database
+func TestCollectionsHandler_Rename_Integration(t *testing.T) {
+	driver := createTestDBForCollections(t)
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	handler := NewCollectionsHandler(driver, reg)
+
+	for _, name := range []string{"products", "orders"} {
+		body, _ := json.Marshal(map[string]any{
+			"name":    name,
+			"columns": []map[string]any{{"name": "title", "type": "string"}},
+		})
+		w := httptest.NewRecorder()
+		handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create collection %s: %s", name, w.Body.String())
+		}
+	}
+
+	data := NewDataHandler(driver, reg, testConfig())
+	if w := postData(t, data.Create, "/products:create", CreateDataRequest{Data: map[string]any{"title": "Widget"}}, ""); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create record: %s", w.Body.String())
+	}
+
+	rename := func(body map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		handler.Rename(w, httptest.NewRequest(http.MethodPost, "/collections:rename", bytes.NewReader(b)))
+		return w
+	}
+
+	rejected := []struct {
+		body map[string]any
+		want int
+	}{
+		{map[string]any{"name": "products", "new_name": "orders"}, http.StatusConflict},
+		{map[string]any{"name": "missing", "new_name": "catalog"}, http.StatusNotFound},
+		{map[string]any{"name": "products", "new_name": "products"}, http.StatusBadRequest},
+		{map[string]any{"name": "products", "new_name": "1bad"}, http.StatusBadRequest},
+		{map[string]any{"name": "products", "new_name": "users"}, http.StatusBadRequest},
+		{map[string]any{"name": "products"}, http.StatusBadRequest},
+	}
+	for _, tt := range rejected {
+		if w := rename(tt.body); w.Code != tt.want {
+			t.Errorf("%v: expected status %d, got %d. Body: %s", tt.body, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	w := rename(map[string]any{"name": "Products", "new_name": "Catalog"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp RenameResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Collection == nil || resp.Collection.Name != "catalog" {
+		t.Errorf("Expected renamed collection in response, got %+v", resp.Collection)
+	}
+
+	if reg.Exists("products") || !reg.Exists("catalog") {
+		t.Error("Expected registry entry to move from products to catalog")
+	}
+
+	// Existing records are reachable under the new name right away
+	w = httptest.NewRecorder()
+	data.List(w, httptest.NewRequest(http.MethodGet, "/catalog:list", nil), "catalog")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d listing catalog, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var list DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Data[0]["title"] != "Widget" {
+		t.Errorf("Expected the existing record under the new name, got %v", list.Data)
+	}
+	createBody, _ := json.Marshal(CreateDataRequest{Data: map[string]any{"title": "Gadget"}})
+	w = httptest.NewRecorder()
+	data.Create(w, httptest.NewRequest(http.MethodPost, "/catalog:create", bytes.NewReader(createBody)), "catalog")
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected create on renamed collection to succeed, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// The old name is gone
+	w = httptest.NewRecorder()
+	data.List(w, httptest.NewRequest(http.MethodGet, "/products:list", nil), "products")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for old name, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestCollectionsHandler_Duplicate_Integration tests schema-only and data duplicates
+func TestCollectionsHandler_Duplicate_Integration(t *testing.T) {
+	driver := createTestDBForCollections(t)
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	handler := NewCollectionsHandler(driver, reg)
+
+	body, _ := json.Marshal(map[string]any{
+		"name": "products",
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false, "unique": true},
+			{"name": "price", "type": "integer", "nullable": true, "min": 0},
+			{"name": "status", "type": "string", "nullable": true, "enum": []string{"draft", "live"}},
+		},
+		"indexes": []map[string]any{
+			{"name": "products_price_idx", "columns": []string{"price"}},
+			{"name": "by_status", "columns": []string{"status", "price"}},
+		},
+		"soft_delete":  true,
+		"default_sort": []string{"-price"},
+	})
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %s", w.Body.String())
+	}
+
+	// Spans two copy batches
+	const records = 700
+	ctx := context.Background()
+	source, _ := reg.Get("products")
+	tx, err := driver.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	for i := range records {
+		sqlQuery, values := buildInsertQuery("products", source, map[string]any{"title": fmt.Sprintf("item_%03d", i), "price": i, "status": "live"}, ulidpkg.Generate(), currentTimestamp(), driver.Dialect())
+		if _, err := tx.ExecContext(ctx, sqlQuery, values...); err != nil {
+			t.Fatalf("Failed to insert record %d: %v", i, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit records: %v", err)
+	}
+
+	duplicate := func(body map[string]any) (*httptest.ResponseRecorder, DuplicateResponse) {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		handler.Duplicate(w, httptest.NewRequest(http.MethodPost, "/collections:duplicate", bytes.NewReader(b)))
+		var resp DuplicateResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	rejected := []struct {
+		body map[string]any
+		want int
+	}{
+		{map[string]any{"source": "missing", "target": "staging"}, http.StatusNotFound},
+		{map[string]any{"source": "products", "target": "products"}, http.StatusBadRequest},
+		{map[string]any{"source": "products", "target": "1bad"}, http.StatusBadRequest},
+		{map[string]any{"source": "products", "target": "moon_copy"}, http.StatusBadRequest},
+		{map[string]any{"source": "products"}, http.StatusBadRequest},
+	}
+	for _, tt := range rejected {
+		if w, _ := duplicate(tt.body); w.Code != tt.want {
+			t.Errorf("%v: expected status %d, got %d. Body: %s", tt.body, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	// Schema only
+	w, resp := duplicate(map[string]any{"source": "Products", "target": "Products_Staging"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if resp.Copied != 0 || resp.Collection == nil || resp.Collection.Name != "products_staging" {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+	staging, ok := reg.Get("products_staging")
+	if !ok {
+		t.Fatal("Expected products_staging in the registry")
+	}
+	if len(staging.Columns) != len(source.Columns) || !staging.SoftDelete || len(staging.DefaultSort) != 1 {
+		t.Errorf("Expected the schema of products, got %+v", staging)
+	}
+	if staging.Columns[2].Enum == nil || staging.Columns[1].Min == nil || !staging.Columns[0].Unique {
+		t.Errorf("Expected constraints to be copied, got %+v", staging.Columns)
+	}
+	if len(staging.Indexes) != 2 || staging.Indexes[0].Name != "products_staging_price_idx" || staging.Indexes[1].Name != "products_staging_by_status" {
+		t.Errorf("Expected renamed indexes, got %+v", staging.Indexes)
+	}
+	var count int
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM products_staging").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected an empty table, got %d records", count)
+	}
+
+	if w, _ := duplicate(map[string]any{"source": "products", "target": "products_staging"}); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for an existing target, got %d", http.StatusConflict, w.Code)
+	}
+
+	// With data
+	w, resp = duplicate(map[string]any{"source": "products", "target": "products_copy", "copy_data": true})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if resp.Copied != records {
+		t.Errorf("Expected %d records copied, got %d", records, resp.Copied)
+	}
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM products_copy").Scan(&count)
+	if count != records {
+		t.Errorf("Expected %d records in the copy, got %d", records, count)
+	}
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM products_copy WHERE id IN (SELECT id FROM products)").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected fresh ids, got %d shared with the source", count)
+	}
+	var title string
+	var price int
+	driver.QueryRow(ctx, "SELECT title, price FROM products_copy ORDER BY pkid DESC LIMIT 1").Scan(&title, &price)
+	if title != "item_699" || price != 699 {
+		t.Errorf("Expected the last record to be copied in order, got %s %d", title, price)
+	}
+
+	// A failed copy drops the target: the copy declares price NOT NULL, which
+	// a record in the second batch violates
+	if _, err := driver.Exec(ctx, "INSERT INTO products (id, title, price) VALUES (?, ?, NULL)", ulidpkg.Generate(), "unpriced"); err != nil {
+		t.Fatalf("Failed to insert record: %v", err)
+	}
+	source.Columns[1].Nullable = false
+	if err := reg.Set(source); err != nil {
+		t.Fatalf("Failed to update registry: %v", err)
+	}
+	if w, _ := duplicate(map[string]any{"source": "products", "target": "products_broken", "copy_data": true}); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d for a failed copy, got %d. Body: %s", http.StatusInternalServerError, w.Code, w.Body.String())
+	}
+	if reg.Exists("products_broken") {
+		t.Error("Expected the failed target not to be registered")
+	}
+	tables, _ := driver.ListTables(ctx)
+	for _, table := range tables {
+		if table == "products_broken" {
+			t.Error("Expected the failed target table to be dropped")
+		}
+	}
+}
+
 // TestCollectionsHandler_List_WithData tests List when there are collections
 func TestCollectionsHandler_List_WithData(t *testing.T) {
 	driver := createTestDBForCollections(t)
@@ -483,7 +726,7 @@ func TestCollectionsHandler_List_DetailedResponse(t *testing.T) {
 	}
 }
 
-// TestSystemColumnsProtection_Integration tests that system columns (pkid, id) cannot be modified, deleted, or renamed
+// TestSystemColumnsProtection_Integration tests that system columns (pkid, id, deleted_at) cannot be modified, deleted, or renamed
 func TestSystemColumnsProtection_Integration(t *testing.T) {
 	driver := createTestDBForCollections(t)
 	defer driver.Close()
@@ -527,7 +770,7 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 
 		var errResp map[string]any
 		json.NewDecoder(w.Body).Decode(&errResp)
-		if errStr, ok := errResp["error"].(string); ok {
+		if errStr, ok := errorMessage(errResp); ok {
 			if !strings.Contains(errStr, "system column") {
 				t.Errorf("Expected error to mention 'system column', got: %s", errStr)
 			}
@@ -552,6 +795,24 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 		}
 	})
 
+	// Test 2b: Cannot add 'deleted_at' as a column (reserved for soft delete)
+	t.Run("CannotAddDeletedAt", func(t *testing.T) {
+		updateBody := map[string]any{
+			"name": "products",
+			"add_columns": []map[string]any{
+				{"name": "deleted_at", "type": "datetime", "nullable": true},
+			},
+		}
+		body, _ := json.Marshal(updateBody)
+		req := httptest.NewRequest(http.MethodPost, "/collections:update", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.Update(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for adding deleted_at, got %d. Body: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	})
+
 	// Test 3: Cannot remove 'pkid'
 	t.Run("CannotRemovePkid", func(t *testing.T) {
 		updateBody := map[string]any{
@@ -569,7 +830,7 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 
 		var errResp map[string]any
 		json.NewDecoder(w.Body).Decode(&errResp)
-		if errStr, ok := errResp["error"].(string); ok {
+		if errStr, ok := errorMessage(errResp); ok {
 			if !strings.Contains(errStr, "system column") {
 				t.Errorf("Expected error to mention 'system column', got: %s", errStr)
 			}
@@ -593,7 +854,7 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 
 		var errResp map[string]any
 		json.NewDecoder(w.Body).Decode(&errResp)
-		if errStr, ok := errResp["error"].(string); ok {
+		if errStr, ok := errorMessage(errResp); ok {
 			if !strings.Contains(errStr, "system column") {
 				t.Errorf("Expected error to mention 'system column', got: %s", errStr)
 			}
@@ -619,7 +880,7 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 
 		var errResp map[string]any
 		json.NewDecoder(w.Body).Decode(&errResp)
-		if errStr, ok := errResp["error"].(string); ok {
+		if errStr, ok := errorMessage(errResp); ok {
 			if !strings.Contains(errStr, "system column") {
 				t.Errorf("Expected error to mention 'system column', got: %s", errStr)
 			}
@@ -645,7 +906,7 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 
 		var errResp map[string]any
 		json.NewDecoder(w.Body).Decode(&errResp)
-		if errStr, ok := errResp["error"].(string); ok {
+		if errStr, ok := errorMessage(errResp); ok {
 			if !strings.Contains(errStr, "system column") {
 				t.Errorf("Expected error to mention 'system column', got: %s", errStr)
 			}
@@ -672,7 +933,7 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 
 		var errResp map[string]any
 		json.NewDecoder(w.Body).Decode(&errResp)
-		if errStr, ok := errResp["error"].(string); ok {
+		if errStr, ok := errorMessage(errResp); ok {
 			if !strings.Contains(errStr, "system column") {
 				t.Errorf("Expected error to mention 'system column', got: %s", errStr)
 			}
@@ -699,7 +960,7 @@ func TestSystemColumnsProtection_Integration(t *testing.T) {
 
 		var errResp map[string]any
 		json.NewDecoder(w.Body).Decode(&errResp)
-		if errStr, ok := errResp["error"].(string); ok {
+		if errStr, ok := errorMessage(errResp); ok {
 			if !strings.Contains(errStr, "system column") {
 				t.Errorf("Expected error to mention 'system column', got: %s", errStr)
 			}
@@ -791,3 +1052,465 @@ func TestCollectionsHandler_AddUniqueColumn_Integration(t *testing.T) {
 		t.Errorf("Failed to insert record with different slug: %v", err)
 	}
 }
+
+// TestCollectionsHandler_Indexes_Integration tests index creation, validation and removal
+func TestCollectionsHandler_Indexes_Integration(t *testing.T) {
+	driver := createTestDBForCollections(t)
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	handler := NewCollectionsHandler(driver, reg)
+	ctx := context.Background()
+
+	post := func(action func(http.ResponseWriter, *http.Request), path string, reqBody map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		w := httptest.NewRecorder()
+		action(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+		return w
+	}
+
+	columns := []map[string]any{
+		{"name": "tenant_id", "type": "string", "nullable": false},
+		{"name": "sku", "type": "string", "nullable": false},
+		{"name": "price", "type": "integer", "nullable": true},
+	}
+
+	// Invalid index definitions are rejected before any table is created
+	invalid := []struct {
+		name    string
+		indexes []map[string]any
+		errPart string
+	}{
+		{"unknown column", []map[string]any{{"name": "idx_color", "columns": []string{"color"}}}, "column 'color' does not exist"},
+		{"duplicate name", []map[string]any{
+			{"name": "idx_sku", "columns": []string{"sku"}},
+			{"name": "idx_sku", "columns": []string{"price"}},
+		}, "duplicate index name 'idx_sku'"},
+		{"no columns", []map[string]any{{"name": "idx_empty", "columns": []string{}}}, "at least one column"},
+		{"invalid name", []map[string]any{{"name": "Idx-Bad", "columns": []string{"sku"}}}, "index name must start"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(handler.Create, "/collections:create", map[string]any{"name": "stock", "columns": columns, "indexes": tt.indexes})
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.errPart) {
+				t.Errorf("Expected error containing %q, got %s", tt.errPart, w.Body.String())
+			}
+			if exists, _ := driver.TableExists(ctx, "stock"); exists {
+				t.Error("Expected no table to be created")
+			}
+		})
+	}
+
+	w := post(handler.Create, "/collections:create", map[string]any{
+		"name":    "stock",
+		"columns": columns,
+		"indexes": []map[string]any{{"name": "idx_tenant_sku", "columns": []string{"tenant_id", "sku"}, "unique": true}},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// The composite unique index is enforced by the database
+	insert := "INSERT INTO stock (id, tenant_id, sku) VALUES (?, ?, ?)"
+	if _, err := driver.Exec(ctx, insert, "01ARYZ6S41TSV4RRFFQ69G5FA1", "t1", "A-1"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if _, err := driver.Exec(ctx, insert, "01ARYZ6S41TSV4RRFFQ69G5FA2", "t2", "A-1"); err != nil {
+		t.Fatalf("Expected same sku in another tenant to be allowed: %v", err)
+	}
+	if _, err := driver.Exec(ctx, insert, "01ARYZ6S41TSV4RRFFQ69G5FA3", "t1", "A-1"); err == nil {
+		t.Error("Expected duplicate (tenant_id, sku) to be rejected")
+	}
+
+	// collections:get exposes the index
+	getW := httptest.NewRecorder()
+	handler.Get(getW, httptest.NewRequest(http.MethodGet, "/collections:get?name=stock", nil))
+	var getResp GetResponse
+	json.Unmarshal(getW.Body.Bytes(), &getResp)
+	if len(getResp.Collection.Indexes) != 1 || getResp.Collection.Indexes[0].Name != "idx_tenant_sku" {
+		t.Errorf("Expected idx_tenant_sku in collections:get, got %+v", getResp.Collection.Indexes)
+	}
+
+	// Index names must be unique across collections
+	w = post(handler.Create, "/collections:create", map[string]any{
+		"name":    "archive",
+		"columns": columns,
+		"indexes": []map[string]any{{"name": "idx_tenant_sku", "columns": []string{"sku"}}},
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "already exists on collection 'stock'") {
+		t.Errorf("Expected cross-collection name conflict, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Renaming an indexed column updates the index definition
+	w = post(handler.Update, "/collections:update", map[string]any{
+		"name":           "stock",
+		"rename_columns": []map[string]any{{"old_name": "sku", "new_name": "item_code"}},
+		"indexes":        []map[string]any{{"name": "idx_price", "columns": []string{"price", "created_at"}}},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	collection, _ := reg.Get("stock")
+	if len(collection.Indexes) != 2 || strings.Join(collection.Indexes[0].Columns, ",") != "tenant_id,item_code" {
+		t.Errorf("Unexpected indexes after update: %+v", collection.Indexes)
+	}
+
+	// Indexed columns cannot be removed while the index exists
+	w = post(handler.Update, "/collections:update", map[string]any{"name": "stock", "remove_columns": []string{"price"}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "used by index 'idx_price'") {
+		t.Errorf("Expected indexed column removal to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Removing the index and its column in one request succeeds
+	w = post(handler.Update, "/collections:update", map[string]any{
+		"name":           "stock",
+		"remove_indexes": []string{"idx_price"},
+		"remove_columns": []string{"price"},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = post(handler.Update, "/collections:update", map[string]any{"name": "stock", "remove_indexes": []string{"idx_missing"}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for unknown index, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	tableInfo, err := driver.GetTableInfo(ctx, "stock")
+	if err != nil {
+		t.Fatalf("GetTableInfo() error = %v", err)
+	}
+	if len(tableInfo.Indexes) != 1 || tableInfo.Indexes[0].Name != "idx_tenant_sku" {
+		t.Errorf("Expected only idx_tenant_sku in database, got %+v", tableInfo.Indexes)
+	}
+}
+
+// TestCollectionsHandler_Create_Seed_Integration tests seeding records on create
+func TestCollectionsHandler_Create_Seed_Integration(t *testing.T) {
+	driver := createTestDBForCollections(t)
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	handler := NewCollectionsHandler(driver, reg)
+
+	create := func(reqBody map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		w := httptest.NewRecorder()
+		handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+		return w
+	}
+	columns := []map[string]any{
+		{"name": "sku", "type": "string", "nullable": false, "unique": true},
+		{"name": "price", "type": "integer", "nullable": true},
+	}
+	countRows := func(table string) int {
+		var count int
+		if err := driver.QueryRow(context.Background(), fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+			t.Fatalf("Failed to count rows: %v", err)
+		}
+		return count
+	}
+
+	w := create(map[string]any{
+		"name":    "products",
+		"columns": columns,
+		"seed": []map[string]any{
+			{"sku": "A-1", "price": 10},
+			{"sku": "A-2"},
+		},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var resp CreateResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Seeded != 2 {
+		t.Errorf("Expected 2 seeded records, got %d", resp.Seeded)
+	}
+	if count := countRows("products"); count != 2 {
+		t.Errorf("Expected 2 rows, got %d", count)
+	}
+	var id string
+	driver.QueryRow(context.Background(), "SELECT id FROM products WHERE sku = 'A-1'").Scan(&id)
+	if !ulidpkg.IsValid(id) {
+		t.Errorf("Expected seed record to get a ULID, got %q", id)
+	}
+
+	// Invalid rows are rejected with their index before anything is created
+	w = create(map[string]any{
+		"name":    "orders",
+		"columns": columns,
+		"seed": []map[string]any{
+			{"sku": "B-1"},
+			{"sku": "B-2", "price": "free"},
+		},
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "index 1") {
+		t.Errorf("Expected 400 naming index 1, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if reg.Exists("orders") {
+		t.Error("Expected invalid seed to leave no collection")
+	}
+
+	// Insert failures roll back the table and the registry entry
+	w = create(map[string]any{
+		"name":    "orders",
+		"columns": columns,
+		"seed": []map[string]any{
+			{"sku": "C-1"},
+			{"sku": "C-1"},
+		},
+	})
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "index 1") {
+		t.Errorf("Expected 409 naming index 1, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if reg.Exists("orders") {
+		t.Error("Expected failed seed to remove the collection from the registry")
+	}
+	if exists, _ := driver.TableExists(context.Background(), "orders"); exists {
+		t.Error("Expected failed seed to drop the table")
+	}
+
+	// Seeds are capped at the batch size limit
+	seed := make([]map[string]any, 51)
+	for i := range seed {
+		seed[i] = map[string]any{"sku": fmt.Sprintf("D-%d", i)}
+	}
+	w = create(map[string]any{"name": "orders", "columns": columns, "seed": seed})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for oversized seed, got %d. Body: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestCollectionsHandler_Update_ModifyColumn_SQLite tests that modify_columns
+// rebuilds the SQLite table with the records converted to the new type
+func TestCollectionsHandler_Update_ModifyColumn_SQLite(t *testing.T) {
+	driver := createTestDBForCollections(t)
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	handler := NewCollectionsHandler(driver, reg)
+
+	body, _ := json.Marshal(map[string]any{
+		"name": "products",
+		"columns": []map[string]any{
+			{"name": "code", "type": "string", "nullable": false},
+			{"name": "label", "type": "string", "nullable": true},
+		},
+		"indexes":     []map[string]any{{"name": "products_code_idx", "columns": []string{"code"}}},
+		"soft_delete": true,
+	})
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %s", w.Body.String())
+	}
+
+	ctx := context.Background()
+	collection, _ := reg.Get("products")
+	ids := map[string]string{}
+	for _, record := range []map[string]any{
+		{"code": "7", "label": "seven"},
+		{"code": "42", "label": nil},
+		{"code": "-3", "label": "minus three"},
+	} {
+		id := ulidpkg.Generate()
+		ids[record["code"].(string)] = id
+		sqlQuery, values := buildInsertQuery("products", collection, record, id, currentTimestamp(), driver.Dialect())
+		if _, err := driver.Exec(ctx, sqlQuery, values...); err != nil {
+			t.Fatalf("Failed to insert record: %v", err)
+		}
+	}
+
+	update := func(body map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		handler.Update(w, httptest.NewRequest(http.MethodPost, "/collections:update", bytes.NewReader(b)))
+		return w
+	}
+	tableSQL := func() string {
+		t.Helper()
+		var sqlText string
+		if err := driver.QueryRow(ctx, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'products'").Scan(&sqlText); err != nil {
+			t.Fatalf("Failed to read table definition: %v", err)
+		}
+		return sqlText
+	}
+
+	// Records that do not fit are reported before the table is touched
+	before := tableSQL()
+	rejected := []struct {
+		modify      map[string]any
+		status      int
+		errContains string
+	}{
+		{map[string]any{"name": "label", "type": "integer"}, http.StatusConflict, "column 'label' holds 2 values that cannot be converted to integer"},
+		{map[string]any{"name": "label", "type": "string", "nullable": false}, http.StatusBadRequest, "column 'label' cannot be made not nullable"},
+		{map[string]any{"name": "code", "type": "boolean"}, http.StatusConflict, "cannot be converted to boolean"},
+	}
+	for _, tt := range rejected {
+		w := update(map[string]any{"name": "products", "modify_columns": []map[string]any{tt.modify}})
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.errContains) {
+			t.Errorf("expected %d containing %q, got %d %s", tt.status, tt.errContains, w.Code, w.Body.String())
+		}
+	}
+	if after := tableSQL(); after != before {
+		t.Errorf("expected the table to be unchanged, got %s", after)
+	}
+	if col, _ := reg.Get("products"); col.Columns[1].Type != registry.TypeString || !col.Columns[1].Nullable {
+		t.Errorf("expected the registry to be unchanged, got %+v", col.Columns[1])
+	}
+
+	// A castable column is converted with its records, ids and indexes kept
+	w = update(map[string]any{"name": "products", "modify_columns": []map[string]any{{"name": "code", "type": "integer"}}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if col, _ := reg.Get("products"); col.Columns[0].Type != registry.TypeInteger {
+		t.Errorf("expected code to be an integer in the registry, got %s", col.Columns[0].Type)
+	}
+	if !strings.Contains(tableSQL(), `"code" INTEGER NOT NULL`) {
+		t.Errorf("expected an INTEGER code column, got %s", tableSQL())
+	}
+	for code, id := range ids {
+		var value any
+		var kind string
+		if err := driver.QueryRow(ctx, "SELECT code, typeof(code) FROM products WHERE id = ?", id).Scan(&value, &kind); err != nil {
+			t.Fatalf("Failed to read record %s: %v", id, err)
+		}
+		if kind != "integer" || fmt.Sprint(value) != code {
+			t.Errorf("expected record %s to hold the integer %s, got %v (%s)", id, code, value, kind)
+		}
+	}
+	var indexes, leftovers int
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'products_code_idx' AND tbl_name = 'products'").Scan(&indexes)
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name LIKE 'moon_rebuild_%'").Scan(&leftovers)
+	if indexes != 1 || leftovers != 0 {
+		t.Errorf("expected the index to be recreated and no rebuild table left, got %d and %d", indexes, leftovers)
+	}
+
+	// New records continue the pkid sequence of the old table
+	sqlQuery, values := buildInsertQuery("products", collection, map[string]any{"code": 8}, ulidpkg.Generate(), currentTimestamp(), driver.Dialect())
+	if _, err := driver.Exec(ctx, sqlQuery, values...); err != nil {
+		t.Fatalf("Failed to insert after rebuild: %v", err)
+	}
+	var maxPKID int
+	driver.QueryRow(ctx, "SELECT MAX(pkid) FROM products").Scan(&maxPKID)
+	if maxPKID != 4 {
+		t.Errorf("expected the next pkid to be 4, got %d", maxPKID)
+	}
+}
+
+// TestCollectionsHandler_Update_IncompatibleTypeChange tests that a type change
+// is refused while records hold values the new type cannot represent, and
+// that force=true sets them to null
+func TestCollectionsHandler_Update_IncompatibleTypeChange(t *testing.T) {
+	driver := createTestDBForCollections(t)
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	handler := NewCollectionsHandler(driver, reg)
+
+	body, _ := json.Marshal(map[string]any{
+		"name":    "readings",
+		"columns": []map[string]any{{"name": "value", "type": "string", "nullable": true}},
+	})
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %s", w.Body.String())
+	}
+
+	ctx := context.Background()
+	collection, _ := reg.Get("readings")
+	ids := map[string]string{}
+	for _, value := range []string{"12", "hello", "-4", "n/a", "3.5", "7", "x", "y", "z"} {
+		id := ulidpkg.Generate()
+		ids[value] = id
+		sqlQuery, values := buildInsertQuery("readings", collection, map[string]any{"value": value}, id, currentTimestamp(), driver.Dialect())
+		if _, err := driver.Exec(ctx, sqlQuery, values...); err != nil {
+			t.Fatalf("Failed to insert record: %v", err)
+		}
+	}
+	incompatible := []string{ids["hello"], ids["n/a"], ids["3.5"], ids["x"], ids["y"], ids["z"]}
+
+	update := func(path string, modify map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		b, _ := json.Marshal(map[string]any{"name": "readings", "modify_columns": []map[string]any{modify}})
+		w := httptest.NewRecorder()
+		handler.Update(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b)))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) map[string]any {
+		t.Helper()
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body
+	}
+
+	// Mixed data is refused with the count and at most 5 sample ids
+	w = update("/collections:update", map[string]any{"name": "value", "type": "integer", "nullable": true})
+	if w.Code != http.StatusConflict || errorCode(w) != "incompatible_data" {
+		t.Fatalf("expected 409 incompatible_data, got %d: %s", w.Code, w.Body.String())
+	}
+	rejected := decode(w)
+	if count := rejected["incompatible_count"]; count != float64(6) {
+		t.Errorf("expected 6 incompatible values, got %v", count)
+	}
+	samples, _ := rejected["sample_ids"].([]any)
+	if len(samples) != 5 {
+		t.Fatalf("expected 5 sample ids, got %v", rejected["sample_ids"])
+	}
+	for _, id := range samples {
+		if !slices.Contains(incompatible, id.(string)) {
+			t.Errorf("expected sample %v to hold an incompatible value", id)
+		}
+	}
+	if col, _ := reg.Get("readings"); col.Columns[0].Type != registry.TypeString {
+		t.Errorf("expected the column to stay a string, got %s", col.Columns[0].Type)
+	}
+
+	// A non-nullable target has nothing to set the values to, even with force
+	w = update("/collections:update?force=true", map[string]any{"name": "value", "type": "integer", "nullable": false})
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "not nullable") {
+		t.Errorf("expected 409 for a non-nullable target, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w = update("/collections:update?force=maybe", map[string]any{"name": "value", "type": "integer"}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid force, got %d", w.Code)
+	}
+
+	// A dry run with force reports what it would coerce and changes nothing
+	w = update("/collections:update?force=true&dry_run=true", map[string]any{"name": "value", "type": "integer", "nullable": true})
+	if w.Code != http.StatusOK || decode(w)["coerced"] != float64(6) {
+		t.Errorf("expected a dry run coercing 6 values, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// force sets the incompatible values to null and converts the others
+	w = update("/collections:update?force=true", map[string]any{"name": "value", "type": "integer", "nullable": true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with force, got %d: %s", w.Code, w.Body.String())
+	}
+	if coerced := decode(w)["coerced"]; coerced != float64(6) {
+		t.Errorf("expected 6 coerced values, got %v", coerced)
+	}
+	for value, id := range ids {
+		var got sql.NullInt64
+		if err := driver.QueryRow(ctx, "SELECT value FROM readings WHERE id = ?", id).Scan(&got); err != nil {
+			t.Fatalf("Failed to read record %s: %v", id, err)
+		}
+		if slices.Contains(incompatible, id) {
+			if got.Valid {
+				t.Errorf("expected %q to become null, got %d", value, got.Int64)
+			}
+		} else if !got.Valid || fmt.Sprint(got.Int64) != value {
+			t.Errorf("expected %q to convert, got %v", value, got)
+		}
+	}
+}
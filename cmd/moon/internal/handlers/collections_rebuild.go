@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// applyModifyColumn applies a modify_columns entry to the column definition
+// in the collection
+func applyModifyColumn(collection *registry.Collection, modify ModifyColumn) {
+	for i := range collection.Columns {
+		if collection.Columns[i].Name == modify.Name {
+			collection.Columns[i].Type = modify.Type
+			if modify.Nullable != nil {
+				collection.Columns[i].Nullable = *modify.Nullable
+			}
+			if modify.Unique != nil {
+				collection.Columns[i].Unique = *modify.Unique
+			}
+			if modify.DefaultValue != nil {
+				collection.Columns[i].DefaultValue = modify.DefaultValue
+			}
+			if modify.Hidden != nil {
+				collection.Columns[i].Hidden = *modify.Hidden
+			}
+			collection.Columns[i].MaxLength = modify.MaxLength
+			collection.Columns[i].Min = modify.Min
+			collection.Columns[i].Max = modify.Max
+			collection.Columns[i].Enum = modify.Enum
+			if modify.Computed != nil {
+				// Computed columns are always written, so a default no longer applies
+				collection.Columns[i].Computed = *modify.Computed
+				if *modify.Computed != "" {
+					collection.Columns[i].DefaultValue = nil
+				}
+			}
+			return
+		}
+	}
+}
+
+// checkModifiedColumns verifies that the records of a table fit its modified
+// columns before a SQLite rebuild: a column made NOT NULL may hold no nulls
+// and a column made unique no duplicates. Values of a column whose type
+// changes are checked by planCoercions. previous holds the columns before the
+// change and renamed maps columns renamed by the same update, which have not
+// run yet, to their names in the table.
+func (h *CollectionsHandler) checkModifiedColumns(ctx context.Context, table string, columns, previous []registry.Column, renamed map[string]string) error {
+	dialect := h.db.Dialect()
+	quotedTable := query.QuoteIdent(dialect, table)
+
+	for _, col := range columns {
+		before, ok := findColumn(previous, col.Name)
+		if !ok {
+			continue
+		}
+		stored := col.Name
+		if name, ok := renamed[col.Name]; ok {
+			stored = name
+		}
+		column := query.QuoteIdent(dialect, stored)
+
+		if !col.Nullable && before.Nullable {
+			var id string
+			err := h.db.QueryRow(ctx, fmt.Sprintf("SELECT id FROM %s WHERE %s IS NULL LIMIT 1", quotedTable, column)).Scan(&id)
+			if err == nil {
+				return fmt.Errorf("column '%s' cannot be made not nullable: record '%s' has no value", col.Name, id)
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		if col.Unique && !before.Unique {
+			var value string
+			err := h.db.QueryRow(ctx, fmt.Sprintf("SELECT CAST(%s AS TEXT) FROM %s WHERE %s IS NOT NULL GROUP BY %s HAVING COUNT(*) > 1 LIMIT 1",
+				column, quotedTable, column, column)).Scan(&value)
+			if err == nil {
+				return fmt.Errorf("column '%s' cannot be made unique: value '%s' appears more than once", col.Name, value)
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// castable reports whether a value in its text form converts to a column
+// type without loss. Booleans are stored as 0 and 1, except on PostgreSQL
+// whose booleans read back as true and false.
+func castable(value string, colType registry.ColumnType, dialect database.DialectType) bool {
+	switch colType {
+	case registry.TypeInteger:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case registry.TypeDecimal:
+		_, err := parseDecimalFilter(value)
+		return err == nil
+	case registry.TypeBoolean:
+		if dialect == database.DialectPostgres && (value == "true" || value == "false") {
+			return true
+		}
+		return value == "0" || value == "1"
+	case registry.TypeJSON:
+		return json.Valid([]byte(value))
+	default:
+		return true
+	}
+}
+
+// findColumn returns the column with the given name
+func findColumn(columns []registry.Column, name string) (registry.Column, bool) {
+	for _, col := range columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return registry.Column{}, false
+}
+
+// sqliteRebuildStatements returns the statements applying modified columns
+// to a SQLite table, which cannot alter a column in place: a table is created
+// from the collection, the records are copied with their pkid and a cast for
+// every column whose type changed, the old table is dropped, the new one
+// renamed and the indexes recreated. They must run in one transaction so any
+// failure leaves the table untouched. previous holds the columns before the
+// change.
+func sqliteRebuildStatements(table string, collection *registry.Collection, previous []registry.Column) []string {
+	dialect := database.DialectSQLite
+	rebuilt := constants.SystemPrefix + "rebuild_" + table
+
+	names := []string{constants.PKIDColumn, "id", constants.CreatedAtColumn, constants.UpdatedAtColumn, constants.RevisionColumn}
+	values := append([]string(nil), names...)
+	for _, col := range collection.Columns {
+		column := query.QuoteIdent(dialect, col.Name)
+		names = append(names, column)
+		if before, ok := findColumn(previous, col.Name); ok && before.Type != col.Type {
+			column = fmt.Sprintf("CAST(%s AS %s)", column, mapColumnTypeToSQLite(col.Type))
+		}
+		values = append(values, column)
+	}
+
+	ddlColumns := collection.Columns
+	if collection.SoftDelete {
+		ddlColumns = append(append([]registry.Column{}, collection.Columns...), softDeleteColumn())
+		names = append(names, constants.SoftDeleteColumn)
+		values = append(values, constants.SoftDeleteColumn)
+	}
+
+	statements := []string{
+		generateCreateTableDDL(rebuilt, ddlColumns, collection.RecordIDType(), dialect),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", query.QuoteIdent(dialect, rebuilt),
+			strings.Join(names, ", "), strings.Join(values, ", "), query.QuoteIdent(dialect, table)),
+		fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(dialect, table)),
+		generateRenameTableDDL(rebuilt, table, dialect),
+	}
+	for _, idx := range collection.Indexes {
+		statements = append(statements, generateCreateIndexDDL(table, idx, dialect))
+	}
+	return statements
+}
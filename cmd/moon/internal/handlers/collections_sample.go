@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/meta"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// SampleCollection is the collection created by bootstrap.sample_collection
+// and recreated by admin:reset-demo. It is an ordinary collection otherwise.
+const SampleCollection = "demo_tasks"
+
+// sampleCollection returns the schema of the sample collection
+func sampleCollection() *registry.Collection {
+	return &registry.Collection{
+		Name: SampleCollection,
+		Columns: []registry.Column{
+			{Name: "title", Type: registry.TypeString},
+			{Name: "done", Type: registry.TypeBoolean},
+			{Name: "due", Type: registry.TypeDatetime, Nullable: true},
+		},
+	}
+}
+
+// sampleRecords returns the seed records of the sample collection, due
+// around now
+func sampleRecords(now time.Time) []map[string]any {
+	day := 24 * time.Hour
+	return []map[string]any{
+		{"title": "Read the Moon documentation", "done": true, "due": datetime.Format(now.Add(-day))},
+		{"title": "Create your first collection", "done": false, "due": datetime.Format(now.Add(day))},
+		{"title": "Destroy demo_tasks when you are done", "done": false, "due": datetime.Format(now.Add(7 * day))},
+	}
+}
+
+// SeedSample creates and seeds the sample collection on the first startup
+// with bootstrap.sample_collection. A marker in moon_meta keeps restarts from
+// seeding it again, even after the collection was destroyed. A collection
+// that already uses the name is left alone. It reports whether the
+// collection was created.
+func SeedSample(ctx context.Context, db database.Driver, reg *registry.SchemaRegistry) (bool, error) {
+	store := meta.New(db)
+	if err := store.Init(ctx); err != nil {
+		return false, err
+	}
+	if _, seeded, err := store.Get(ctx, meta.KeySampleSeeded); err != nil || seeded {
+		return false, err
+	}
+
+	created := false
+	if !reg.Exists(SampleCollection) {
+		if err := NewCollectionsHandler(db, reg).createSample(ctx); err != nil {
+			return false, err
+		}
+		created = true
+	}
+	return created, store.Set(ctx, meta.KeySampleSeeded, datetime.Format(time.Now()))
+}
+
+// createSample creates the sample collection and inserts its seed records;
+// the collection is removed again if seeding fails
+func (h *CollectionsHandler) createSample(ctx context.Context) error {
+	collection := sampleCollection()
+	for i := range collection.Columns {
+		applyColumnDefaults(&collection.Columns[i])
+	}
+	records := sampleRecords(time.Now())
+	if err := validateSeed(records, collection); err != nil {
+		return err
+	}
+
+	if err := h.createCollection(ctx, collection, nil); err != nil {
+		return err
+	}
+	if idx, err := h.insertSeed(ctx, collection, records); err != nil {
+		if _, rollbackErr := h.db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(h.db.Dialect(), SampleCollection))); rollbackErr != nil {
+			log.Printf("WARNING: Failed to drop table '%s' after seeding failed: %v", SampleCollection, rollbackErr)
+		}
+		if deleteErr := h.registry.Delete(SampleCollection); deleteErr != nil {
+			log.Printf("WARNING: Failed to remove collection '%s' from registry after seeding failed: %v", SampleCollection, deleteErr)
+		}
+		return fmt.Errorf("failed to insert sample record at index %d: %w", idx, err)
+	}
+	return nil
+}
+
+// ResetSample handles POST /admin:reset-demo
+// It drops the sample collection if it exists, whatever it holds, and
+// creates it again with its seed records.
+func (h *CollectionsHandler) ResetSample(w http.ResponseWriter, r *http.Request) {
+	if !requireScope(w, r, SampleCollection, auth.ScopeSchema) {
+		return
+	}
+	ctx := r.Context()
+	audit.SetCollection(ctx, SampleCollection)
+
+	if h.registry.Exists(SampleCollection) {
+		// Collections referencing the old records keep plain string columns
+		referencing := h.registry.Referencing(SampleCollection)
+		ddl := fmt.Sprintf("DROP TABLE %s", query.QuoteIdent(h.db.Dialect(), SampleCollection))
+		if len(referencing) > 0 && h.db.Dialect() == database.DialectPostgres {
+			ddl += " CASCADE"
+		}
+		if _, err := h.db.Exec(ctx, ddl); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to drop table: %v", err))
+			return
+		}
+		if err := h.registry.Delete(SampleCollection); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to update registry: %v", err))
+			return
+		}
+		h.replaceReferences(referencing, SampleCollection, "")
+	}
+
+	if err := h.createSample(ctx); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	collection, _ := h.registry.Get(SampleCollection)
+	records := len(sampleRecords(time.Now()))
+	writeResponse(w, r, http.StatusOK, CreateResponse{
+		Collection: collection,
+		Seeded:     records,
+		Message:    fmt.Sprintf("Sample collection '%s' reset with %d records", SampleCollection, records),
+	})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/schemastore"
+)
+
+// bootSample opens the database file and loads its registry the way the
+// server does at startup, then seeds the sample collection
+func bootSample(t *testing.T, path string) (database.Driver, *registry.SchemaRegistry, bool) {
+	t.Helper()
+	ctx := context.Background()
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://" + path, MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	store := schemastore.New(driver)
+	if _, err := store.Init(ctx); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	collections, err := store.LoadAll(ctx)
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	reg := registry.NewSchemaRegistry()
+	for _, collection := range collections {
+		reg.Set(collection)
+	}
+	reg.SetStore(store)
+
+	created, err := SeedSample(ctx, driver, reg)
+	if err != nil {
+		t.Fatalf("SeedSample() error = %v", err)
+	}
+	return driver, reg, created
+}
+
+func TestSeedSample_FirstBootOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moon.db")
+
+	driver, reg, created := bootSample(t, path)
+	if !created || !reg.Exists(SampleCollection) {
+		t.Fatalf("expected the first boot to create %s", SampleCollection)
+	}
+	if n := countRows(t, driver, SampleCollection); n != 3 {
+		t.Fatalf("expected 3 seed records, got %d", n)
+	}
+	driver.Close()
+
+	driver, reg, created = bootSample(t, path)
+	if created || !reg.Exists(SampleCollection) {
+		t.Errorf("expected the second boot to keep the collection without creating it, created=%v", created)
+	}
+	if n := countRows(t, driver, SampleCollection); n != 3 {
+		t.Errorf("expected the second boot not to seed again, got %d records", n)
+	}
+
+	// A destroyed sample collection stays destroyed across restarts
+	handler := NewCollectionsHandler(driver, reg)
+	w := httptest.NewRecorder()
+	handler.Destroy(w, httptest.NewRequest(http.MethodPost, "/collections:destroy", bytes.NewBufferString(`{"name": "demo_tasks"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to destroy the sample collection: %d %s", w.Code, w.Body.String())
+	}
+	driver.Close()
+
+	driver, reg, created = bootSample(t, path)
+	if created || reg.Exists(SampleCollection) {
+		t.Fatal("expected a destroyed sample collection not to be recreated on restart")
+	}
+
+	// admin:reset-demo restores it
+	handler = NewCollectionsHandler(driver, reg)
+	w = httptest.NewRecorder()
+	handler.ResetSample(w, httptest.NewRequest(http.MethodPost, "/admin:reset-demo", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from reset-demo, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := countRows(t, driver, SampleCollection); !reg.Exists(SampleCollection) || n != 3 {
+		t.Fatalf("expected reset-demo to restore 3 records, got %d", n)
+	}
+
+	// Resetting again discards records added since
+	if _, err := driver.Exec(context.Background(), "DELETE FROM demo_tasks WHERE done = 0"); err != nil {
+		t.Fatalf("failed to delete records: %v", err)
+	}
+	w = httptest.NewRecorder()
+	handler.ResetSample(w, httptest.NewRequest(http.MethodPost, "/admin:reset-demo", nil))
+	if n := countRows(t, driver, SampleCollection); w.Code != http.StatusOK || n != 3 {
+		t.Errorf("expected a second reset to restore 3 records, got %d (%d)", n, w.Code)
+	}
+}
+
+func TestDocHandler_QuickstartUsesSampleCollection(t *testing.T) {
+	driver, reg, _ := bootSample(t, filepath.Join(t.TempDir(), "moon.db"))
+	reg.Set(&registry.Collection{Name: "articles", Columns: []registry.Column{{Name: "title", Type: registry.TypeString}}})
+
+	var firstID string
+	if err := driver.QueryRow(context.Background(), "SELECT id FROM demo_tasks ORDER BY id LIMIT 1").Scan(&firstID); err != nil {
+		t.Fatalf("failed to read a sample record: %v", err)
+	}
+
+	handler := NewDocHandler(reg, &config.AppConfig{Server: config.ServerConfig{Host: "localhost", Port: 6006}}, "1.99")
+	handler.SetDatabase(driver)
+	rec := httptest.NewRecorder()
+	handler.Markdown(rec, httptest.NewRequest(http.MethodGet, "/doc/llms.md", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"a **sample collection** created by `bootstrap.sample_collection`",
+		"POST /admin:reset-demo",
+		`/demo_tasks:list?done[eq]=true"`,
+		`/demo_tasks:get?id=` + firstID + `"`,
+		`-d '{"id": "` + firstID + `", "data": {"done": true}}'`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the quickstart", want)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// SchemaImportMode selects what collections:import applies
+type SchemaImportMode string
+
+const (
+	// SchemaImportCreateMissing creates the collections that do not exist yet
+	SchemaImportCreateMissing SchemaImportMode = "create_missing"
+	// SchemaImportSync also applies additions and modifications to existing collections
+	SchemaImportSync SchemaImportMode = "sync"
+	// SchemaImportDryRun reports the changes of a sync without applying them
+	SchemaImportDryRun SchemaImportMode = "dry_run"
+)
+
+// SchemaChangeAction names a difference between an imported and a live schema
+type SchemaChangeAction string
+
+const (
+	SchemaChangeCreateCollection SchemaChangeAction = "create_collection"
+	SchemaChangeDropCollection   SchemaChangeAction = "drop_collection"
+	SchemaChangeAddColumn        SchemaChangeAction = "add_column"
+	SchemaChangeModifyColumn     SchemaChangeAction = "modify_column"
+	SchemaChangeDropColumn       SchemaChangeAction = "drop_column"
+	SchemaChangeAddIndex         SchemaChangeAction = "add_index"
+	SchemaChangeChangeIndex      SchemaChangeAction = "change_index"
+	SchemaChangeDropIndex        SchemaChangeAction = "drop_index"
+	SchemaChangeSetOption        SchemaChangeAction = "set_option"
+)
+
+// SchemaDocument holds the schema of every collection, as returned by
+// collections:export and accepted by collections:import
+type SchemaDocument struct {
+	Collections []*registry.Collection `json:"collections"`
+}
+
+// SchemaImportRequest represents the request for importing a schema document
+type SchemaImportRequest struct {
+	Mode SchemaImportMode `json:"mode"`
+	SchemaDocument
+}
+
+// SchemaChange is one difference between the imported and the live schema.
+// Manual changes are destructive or unsupported and never applied by an import.
+type SchemaChange struct {
+	Collection string             `json:"collection"`
+	Action     SchemaChangeAction `json:"action"`
+	Detail     string             `json:"detail"`
+	Manual     bool               `json:"manual,omitempty"`
+	Applied    bool               `json:"applied"`
+}
+
+// SchemaImportResponse represents the response for importing a schema document
+type SchemaImportResponse struct {
+	Mode    SchemaImportMode `json:"mode"`
+	Changes []SchemaChange   `json:"changes"`
+	Applied int              `json:"applied"`
+	Message string           `json:"message"`
+}
+
+// importableTypeChanges lists the type changes an import applies; every
+// value of the old type converts to the new one without loss
+var importableTypeChanges = map[[2]registry.ColumnType]bool{
+	{registry.TypeInteger, registry.TypeDecimal}: true,
+	{registry.TypeInteger, registry.TypeString}:  true,
+	{registry.TypeDecimal, registry.TypeString}:  true,
+	{registry.TypeDatetime, registry.TypeString}: true,
+}
+
+// schemaPlan is the diff of one imported collection and what applies it: a
+// collection to create, or an update of the live collection
+type schemaPlan struct {
+	name    string // logical name
+	table   string
+	create  *registry.Collection
+	indexes []registry.Index
+	update  *UpdateRequest
+	live    *registry.Collection
+	changes []SchemaChange
+}
+
+// Export handles GET /collections:export
+func (h *CollectionsHandler) Export(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, http.StatusOK, SchemaDocument{Collections: h.liveCollections(r)})
+}
+
+// liveCollections returns the non-system collections the caller may read, as
+// its tenant sees them and ordered by name
+func (h *CollectionsHandler) liveCollections(r *http.Request) []*registry.Collection {
+	ctx := r.Context()
+	names := h.registry.List()
+	if h.tenancy {
+		names = h.registry.ListTenant(middleware.GetTenant(ctx))
+	}
+	slices.Sort(names)
+
+	collections := make([]*registry.Collection, 0, len(names))
+	for _, name := range names {
+		if constants.IsSystemTable(name) || !middleware.HasScope(ctx, name, auth.ScopeRead) {
+			continue
+		}
+		if collection, ok := h.registry.Get(h.tableName(r, name)); ok {
+			collections = append(collections, h.logicalView(collection))
+		}
+	}
+	return collections
+}
+
+// Import handles POST /collections:import
+func (h *CollectionsHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var req SchemaImportRequest
+	bodyBytes, err := readSchemaRequest(r.Body)
+	if err == nil {
+		err = decodeSchemaRequest(bodyBytes, &req)
+	}
+	if err != nil {
+		writeSchemaRequestError(w, r, err)
+		return
+	}
+
+	switch req.Mode {
+	case SchemaImportCreateMissing, SchemaImportSync, SchemaImportDryRun:
+	default:
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "mode must be create_missing, sync or dry_run")
+		return
+	}
+
+	// Every collection is validated and diffed before anything is applied
+	plans, err := h.planImport(r, req.Collections)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	var changes []SchemaChange
+	applied := 0
+	for _, plan := range plans {
+		if req.Mode != SchemaImportDryRun {
+			n, err := h.applyPlan(ctx, plan, req.Mode)
+			if err != nil {
+				writeAPIError(w, r, inCollection(plan.name, err))
+				return
+			}
+			applied += n
+		}
+		changes = append(changes, plan.changes...)
+	}
+
+	// Collections missing from the document are never dropped by an import
+	imported := make(map[string]bool, len(plans))
+	for _, plan := range plans {
+		imported[plan.name] = true
+	}
+	for _, live := range h.liveCollections(r) {
+		if !imported[live.Name] {
+			changes = append(changes, SchemaChange{Collection: live.Name, Action: SchemaChangeDropCollection, Detail: "drop collection", Manual: true})
+		}
+	}
+
+	if changes == nil {
+		changes = []SchemaChange{}
+	}
+	manual := 0
+	for _, change := range changes {
+		if change.Manual {
+			manual++
+		}
+	}
+
+	message := fmt.Sprintf("Applied %d of %d changes, %d need manual migration", applied, len(changes), manual)
+	if req.Mode == SchemaImportDryRun {
+		message = fmt.Sprintf("Dry run: %d changes, %d need manual migration", len(changes), manual)
+		audit.Discard(r.Context())
+	}
+
+	writeResponse(w, r, http.StatusOK, SchemaImportResponse{
+		Mode:    req.Mode,
+		Changes: changes,
+		Applied: applied,
+		Message: message,
+	})
+}
+
+// planImport validates the collections of a schema document and diffs each
+// against the live registry. Errors are *apperrors.APIError values.
+func (h *CollectionsHandler) planImport(r *http.Request, collections []*registry.Collection) ([]*schemaPlan, error) {
+	plans := make([]*schemaPlan, 0, len(collections))
+	seen := make(map[string]bool, len(collections))
+
+	// Columns may reference any collection of the document
+	documented := make(map[string]bool, len(collections))
+	for _, doc := range collections {
+		if doc != nil {
+			documented[h.tableName(r, strings.ToLower(doc.Name))] = true
+		}
+	}
+
+	for i, doc := range collections {
+		if doc == nil {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "collections[%d] must be an object", i)
+		}
+
+		// Normalize collection name to lowercase (PRD-047)
+		name := strings.ToLower(doc.Name)
+		if err := h.validateName(name); err != nil {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, "collections[%d]: %v", i, err)
+		}
+		if seen[name] {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "collection '%s' appears more than once", name)
+		}
+		seen[name] = true
+		if !middleware.HasScope(r.Context(), name, auth.ScopeSchema) {
+			return nil, apperrors.Newf(http.StatusForbidden, apperrors.CodeInsufficientScope, "API key scope does not allow %s on collection '%s'", auth.ScopeSchema, name)
+		}
+
+		table := h.tableName(r, name)
+		if len(table) > constants.MaxCollectionNameLength {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeCollectionNameInvalid, "collection '%s': name must not exceed %d characters including the tenant prefix", name, constants.MaxCollectionNameLength)
+		}
+
+		plan := &schemaPlan{name: name, table: table}
+		if live, exists := h.registry.Get(table); exists {
+			plan.live = live
+			plan.changes, plan.update = diffCollection(name, h.logicalView(live), doc)
+			if plan.update != nil {
+				if err := h.resolveReferences(r, table, plan.update.AddColumns, documented); err != nil {
+					return nil, inCollection(name, err)
+				}
+				if err := validateImportUpdate(h, plan.update, live); err != nil {
+					return nil, inCollection(name, err)
+				}
+			}
+		} else {
+			collection, err := h.importedCollection(table, doc)
+			if err != nil {
+				return nil, inCollection(name, err)
+			}
+			if err := h.resolveReferences(r, table, collection.Columns, documented); err != nil {
+				return nil, inCollection(name, err)
+			}
+			plan.create, plan.indexes = collection, doc.Indexes
+			plan.changes = []SchemaChange{{Collection: name, Action: SchemaChangeCreateCollection, Detail: "create collection"}}
+		}
+		plans = append(plans, plan)
+	}
+	return orderByReferences(plans), nil
+}
+
+// orderByReferences orders plans so that each collection is created or
+// updated after the collections created by the import that it references;
+// other plans keep their order
+func orderByReferences(plans []*schemaPlan) []*schemaPlan {
+	created := make(map[string]*schemaPlan, len(plans))
+	for _, plan := range plans {
+		if plan.create != nil {
+			created[plan.table] = plan
+		}
+	}
+
+	ordered := make([]*schemaPlan, 0, len(plans))
+	placed := make(map[*schemaPlan]bool, len(plans))
+	var place func(plan *schemaPlan)
+	place = func(plan *schemaPlan) {
+		if placed[plan] {
+			return
+		}
+		placed[plan] = true
+		var columns []registry.Column
+		if plan.create != nil {
+			columns = plan.create.Columns
+		} else if plan.update != nil {
+			columns = plan.update.AddColumns
+		}
+		for _, col := range columns {
+			if target, ok := created[col.References]; ok {
+				place(target)
+			}
+		}
+		ordered = append(ordered, plan)
+	}
+	for _, plan := range plans {
+		place(plan)
+	}
+	return ordered
+}
+
+// importedCollection validates a collection of a schema document that does
+// not exist yet and returns it named after its table. Default values are kept
+// as exported; they must be absent or the default of the column type.
+func (h *CollectionsHandler) importedCollection(table string, doc *registry.Collection) (*registry.Collection, error) {
+	collection := &registry.Collection{
+		Name:            table,
+		Columns:         append([]registry.Column(nil), doc.Columns...),
+		SoftDelete:      doc.SoftDelete,
+		RequireRevision: doc.RequireRevision,
+		ExposeSequence:  doc.ExposeSequence,
+		IDType:          doc.IDType,
+		DefaultSort:     doc.DefaultSort,
+		DefaultFields:   doc.DefaultFields,
+		Pagination:      nilIfUnset(doc.Pagination),
+		Archived:        doc.Archived,
+		TTL:             doc.TTL,
+		UI:              nilIfNoUI(doc.UI),
+	}
+	if err := h.validateNewCollection(collection, doc.Indexes); err != nil {
+		return nil, err
+	}
+	for _, col := range collection.Columns {
+		if col.DefaultValue == nil {
+			continue
+		}
+		derived := col
+		derived.DefaultValue = nil
+		applyColumnDefaults(&derived)
+		if derived.DefaultValue == nil || *derived.DefaultValue != *col.DefaultValue {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSchema, "column '%s': default_value must be absent or the default of its type", col.Name)
+		}
+	}
+	return collection, nil
+}
+
+// validateImportUpdate checks the update of an existing collection before any
+// collection of the import is changed. Errors are *apperrors.APIError values.
+func validateImportUpdate(h *CollectionsHandler, req *UpdateRequest, live *registry.Collection) error {
+	if err := h.validateModifyColumns(req.ModifyColumns, live); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := h.validateAddColumns(req.AddColumns, live); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	if err := validateUpdateListDefaults(req, live); err != nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSchema, err.Error())
+	}
+	return nil
+}
+
+// applyPlan applies the changes of a plan that the mode allows and marks them
+// applied. It returns the number of applied changes.
+func (h *CollectionsHandler) applyPlan(ctx context.Context, plan *schemaPlan, mode SchemaImportMode) (int, error) {
+	if plan.create != nil {
+		if err := validateCollectionCount(h.registry); err != nil {
+			return 0, apperrors.NewAPIError(http.StatusConflict, apperrors.CodeMaxCollectionsReached, err.Error())
+		}
+		if err := h.createCollection(ctx, plan.create, plan.indexes); err != nil {
+			return 0, err
+		}
+		plan.changes[0].Applied = true
+		return 1, nil
+	}
+
+	if mode != SchemaImportSync || plan.update == nil {
+		return 0, nil
+	}
+	if _, err := h.applyUpdate(ctx, plan.table, plan.live, plan.update, false); err != nil {
+		return 0, err
+	}
+	applied := 0
+	for i := range plan.changes {
+		if !plan.changes[i].Manual {
+			plan.changes[i].Applied = true
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// inCollection prefixes the message of an import error with the collection
+func inCollection(name string, err error) error {
+	var apiErr *apperrors.APIError
+	if errors.As(err, &apiErr) {
+		return apperrors.Newf(apiErr.StatusCode, apiErr.ErrorCode, "collection '%s': %s", name, apiErr.Message)
+	}
+	return fmt.Errorf("collection '%s': %w", name, err)
+}
+
+// diffCollection compares an imported collection with the live one. It
+// returns the differences and the update applying those that are neither
+// destructive nor unsupported, or nil when there is nothing to apply.
+// Default values are derived from the column definition and not compared.
+func diffCollection(name string, live, doc *registry.Collection) ([]SchemaChange, *UpdateRequest) {
+	var changes []SchemaChange
+	update := &UpdateRequest{Name: name}
+	change := func(action SchemaChangeAction, manual bool, format string, args ...any) {
+		changes = append(changes, SchemaChange{Collection: name, Action: action, Detail: fmt.Sprintf(format, args...), Manual: manual})
+	}
+
+	for _, col := range doc.Columns {
+		current, ok := findColumn(live.Columns, col.Name)
+		if !ok {
+			col.DefaultValue = nil
+			update.AddColumns = append(update.AddColumns, col)
+			change(SchemaChangeAddColumn, false, "add column %s", col.Name)
+			continue
+		}
+		if current.References != col.References {
+			change(SchemaChangeModifyColumn, true, "references change %s: %s→%s (unsupported)", col.Name, describeReference(current.References), describeReference(col.References))
+		}
+		if current.Type != col.Type && !importableTypeChanges[[2]registry.ColumnType{current.Type, col.Type}] {
+			change(SchemaChangeModifyColumn, true, "type change %s: %s→%s (unsupported)", col.Name, current.Type, col.Type)
+			continue
+		}
+		if differences := columnDifferences(current, col); len(differences) > 0 {
+			update.ModifyColumns = append(update.ModifyColumns, ModifyColumn{
+				Name:      col.Name,
+				Type:      col.Type,
+				Nullable:  &col.Nullable,
+				Unique:    &col.Unique,
+				MaxLength: col.MaxLength,
+				Min:       col.Min,
+				Max:       col.Max,
+				Enum:      col.Enum,
+				Hidden:    &col.Hidden,
+			})
+			change(SchemaChangeModifyColumn, false, "modify column %s: %s", col.Name, strings.Join(differences, ", "))
+		}
+	}
+	for _, col := range live.Columns {
+		if _, ok := findColumn(doc.Columns, col.Name); !ok {
+			change(SchemaChangeDropColumn, true, "drop column %s", col.Name)
+		}
+	}
+
+	for _, idx := range doc.Indexes {
+		current, ok := findIndex(live.Indexes, idx.Name)
+		switch {
+		case !ok:
+			update.Indexes = append(update.Indexes, idx)
+			change(SchemaChangeAddIndex, false, "add index %s", idx.Name)
+		case !slices.Equal(current.Columns, idx.Columns) || current.Unique != idx.Unique:
+			change(SchemaChangeChangeIndex, true, "change index %s: drop and recreate it", idx.Name)
+		}
+	}
+	for _, idx := range live.Indexes {
+		if _, ok := findIndex(doc.Indexes, idx.Name); !ok {
+			change(SchemaChangeDropIndex, true, "drop index %s", idx.Name)
+		}
+	}
+
+	if live.SoftDelete != doc.SoftDelete {
+		change(SchemaChangeSetOption, true, "soft_delete %t→%t (unsupported)", live.SoftDelete, doc.SoftDelete)
+	}
+	if a, b := describeTTL(live.TTL), describeTTL(doc.TTL); a != b {
+		change(SchemaChangeSetOption, true, "ttl %s→%s (unsupported)", a, b)
+	}
+	if live.RecordIDType() != doc.RecordIDType() {
+		change(SchemaChangeSetOption, true, "id_type %s→%s (unsupported)", live.RecordIDType(), doc.RecordIDType())
+	}
+	if live.RequireRevision != doc.RequireRevision {
+		update.RequireRevision = &doc.RequireRevision
+		change(SchemaChangeSetOption, false, "require_revision %t→%t", live.RequireRevision, doc.RequireRevision)
+	}
+	if live.ExposeSequence != doc.ExposeSequence {
+		update.ExposeSequence = &doc.ExposeSequence
+		change(SchemaChangeSetOption, false, "expose_sequence %t→%t", live.ExposeSequence, doc.ExposeSequence)
+	}
+	if !slices.Equal(live.DefaultSort, doc.DefaultSort) {
+		update.DefaultSort = append([]string{}, doc.DefaultSort...)
+		change(SchemaChangeSetOption, false, "default_sort %v→%v", live.DefaultSort, doc.DefaultSort)
+	}
+	if !slices.Equal(live.DefaultFields, doc.DefaultFields) {
+		update.DefaultFields = append([]string{}, doc.DefaultFields...)
+		change(SchemaChangeSetOption, false, "default_fields %v→%v", live.DefaultFields, doc.DefaultFields)
+	}
+	if livePagination, docPagination := paginationValue(live.Pagination), paginationValue(doc.Pagination); livePagination != docPagination {
+		update.Pagination = &docPagination
+		change(SchemaChangeSetOption, false, "pagination %+v→%+v", livePagination, docPagination)
+	}
+	if liveUI, docUI := describeUI(live.UI), describeUI(nilIfNoUI(doc.UI)); liveUI != docUI {
+		update.UI = &registry.UI{}
+		if doc.UI != nil {
+			update.UI = doc.UI
+		}
+		change(SchemaChangeSetOption, false, "ui %s→%s", liveUI, docUI)
+	}
+
+	if len(update.AddColumns) == 0 && len(update.ModifyColumns) == 0 && len(update.Indexes) == 0 &&
+		update.RequireRevision == nil && update.ExposeSequence == nil && update.DefaultSort == nil && update.DefaultFields == nil &&
+		update.Pagination == nil && update.UI == nil {
+		update = nil
+	}
+	return changes, update
+}
+
+// columnDifferences describes how an imported column differs from the live one
+func columnDifferences(live, doc registry.Column) []string {
+	var differences []string
+	if live.Type != doc.Type {
+		differences = append(differences, fmt.Sprintf("type %s→%s", live.Type, doc.Type))
+	}
+	if live.Nullable != doc.Nullable {
+		differences = append(differences, fmt.Sprintf("nullable %t→%t", live.Nullable, doc.Nullable))
+	}
+	if live.Unique != doc.Unique {
+		differences = append(differences, fmt.Sprintf("unique %t→%t", live.Unique, doc.Unique))
+	}
+	if a, b := describeConstraint(live.MaxLength), describeConstraint(doc.MaxLength); a != b {
+		differences = append(differences, fmt.Sprintf("max_length %s→%s", a, b))
+	}
+	if a, b := describeConstraint(live.Min), describeConstraint(doc.Min); a != b {
+		differences = append(differences, fmt.Sprintf("min %s→%s", a, b))
+	}
+	if a, b := describeConstraint(live.Max), describeConstraint(doc.Max); a != b {
+		differences = append(differences, fmt.Sprintf("max %s→%s", a, b))
+	}
+	if !slices.Equal(live.Enum, doc.Enum) {
+		differences = append(differences, fmt.Sprintf("enum %v→%v", live.Enum, doc.Enum))
+	}
+	if live.Hidden != doc.Hidden {
+		differences = append(differences, fmt.Sprintf("hidden %t→%t", live.Hidden, doc.Hidden))
+	}
+	return differences
+}
+
+// describeReference formats the referenced collection of a column, "none" when unset
+func describeReference(name string) string {
+	if name == "" {
+		return "none"
+	}
+	return name
+}
+
+// describeConstraint formats an optional column constraint, "none" when unset
+func describeConstraint[T any](value *T) string {
+	if value == nil {
+		return "none"
+	}
+	return fmt.Sprint(*value)
+}
+
+// findIndex returns the index with the given name
+func findIndex(indexes []registry.Index, name string) (registry.Index, bool) {
+	for _, idx := range indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return registry.Index{}, false
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// exportSchema runs collections:export and returns the decoded document
+func exportSchema(t *testing.T, handler *CollectionsHandler) SchemaDocument {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.Export(w, httptest.NewRequest(http.MethodGet, "/collections:export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export failed: %d %s", w.Code, w.Body.String())
+	}
+	var doc SchemaDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	return doc
+}
+
+// importSchema runs collections:import and returns the status and response
+func importSchema(t *testing.T, handler *CollectionsHandler, mode SchemaImportMode, doc SchemaDocument) (int, SchemaImportResponse) {
+	t.Helper()
+	w := postCollections(handler.Import, SchemaImportRequest{Mode: mode, SchemaDocument: doc})
+	var resp SchemaImportResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func setupSchemaImportTest(t *testing.T) *CollectionsHandler {
+	t.Helper()
+	handler, driver := setupTestHandler(t)
+	t.Cleanup(func() { driver.Close() })
+
+	for _, body := range []map[string]any{
+		{
+			"name": "orders",
+			"columns": []map[string]any{
+				{"name": "number", "type": "string", "nullable": false, "unique": true, "max_length": 20},
+				{"name": "status", "type": "string", "nullable": false, "enum": []string{"open", "paid"}},
+				{"name": "total", "type": "decimal", "nullable": true, "min": 0},
+				{"name": "qty", "type": "integer", "nullable": false},
+				{"name": "meta", "type": "json", "nullable": true},
+			},
+			"indexes":          []map[string]any{{"name": "orders_status_idx", "columns": []string{"status", "qty"}}},
+			"soft_delete":      true,
+			"require_revision": true,
+			"default_sort":     []string{"-qty"},
+			"default_fields":   []string{"number", "status"},
+		},
+		{
+			"name": "customers",
+			"columns": []map[string]any{
+				{"name": "email", "type": "string", "nullable": false, "unique": true},
+				{"name": "note", "type": "string", "nullable": true},
+			},
+		},
+	} {
+		if w := postCollections(handler.Create, body); w.Code != http.StatusCreated {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+	return handler
+}
+
+func TestSchemaExportImport_RoundTrip(t *testing.T) {
+	handler := setupSchemaImportTest(t)
+
+	exported := exportSchema(t, handler)
+	if len(exported.Collections) != 2 || exported.Collections[0].Name != "customers" {
+		t.Fatalf("expected customers and orders ordered by name, got %+v", exported.Collections)
+	}
+
+	for _, name := range []string{"orders", "customers"} {
+		if w := postCollections(handler.Destroy, map[string]any{"name": name}); w.Code != http.StatusOK {
+			t.Fatalf("Destroy failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	status, resp := importSchema(t, handler, SchemaImportCreateMissing, exported)
+	if status != http.StatusOK {
+		t.Fatalf("Import failed: %d %+v", status, resp)
+	}
+	if resp.Applied != 2 || len(resp.Changes) != 2 || resp.Changes[0].Action != SchemaChangeCreateCollection {
+		t.Errorf("expected two applied create_collection changes, got %+v", resp)
+	}
+
+	if reimported := exportSchema(t, handler); !reflect.DeepEqual(exported, reimported) {
+		t.Errorf("schema changed on round trip:\n got %+v\nwant %+v", reimported.Collections, exported.Collections)
+	}
+
+	// Importing the same document again finds nothing to do
+	status, resp = importSchema(t, handler, SchemaImportSync, exported)
+	if status != http.StatusOK || len(resp.Changes) != 0 || resp.Applied != 0 {
+		t.Errorf("expected no changes, got %d %+v", status, resp)
+	}
+}
+
+func TestSchemaImport_DryRun(t *testing.T) {
+	handler := setupSchemaImportTest(t)
+	doc := exportSchema(t, handler)
+
+	orders := doc.Collections[1]
+	orders.Columns = []registry.Column{
+		orders.Columns[0],
+		{Name: "status", Type: registry.TypeInteger},                  // string→integer
+		{Name: "total", Type: registry.TypeDecimal, Nullable: false},  // nullable change
+		{Name: "qty", Type: registry.TypeDecimal},                     // integer→decimal
+		{Name: "meta", Type: registry.TypeJSON, Nullable: true},       // unchanged
+		{Name: "shipped", Type: registry.TypeBoolean, Nullable: true}, // new
+	}
+	orders.Indexes = nil
+	orders.RequireRevision = false
+	doc.Collections = doc.Collections[1:]
+
+	status, resp := importSchema(t, handler, SchemaImportDryRun, doc)
+	if status != http.StatusOK {
+		t.Fatalf("Import failed: %d %+v", status, resp)
+	}
+
+	want := []SchemaChange{
+		{Collection: "orders", Action: SchemaChangeModifyColumn, Detail: "type change status: string→integer (unsupported)", Manual: true},
+		{Collection: "orders", Action: SchemaChangeModifyColumn, Detail: "modify column total: nullable true→false, min 0→none"},
+		{Collection: "orders", Action: SchemaChangeModifyColumn, Detail: "modify column qty: type integer→decimal"},
+		{Collection: "orders", Action: SchemaChangeAddColumn, Detail: "add column shipped"},
+		{Collection: "orders", Action: SchemaChangeDropIndex, Detail: "drop index orders_status_idx", Manual: true},
+		{Collection: "orders", Action: SchemaChangeSetOption, Detail: "require_revision true→false"},
+		{Collection: "customers", Action: SchemaChangeDropCollection, Detail: "drop collection", Manual: true},
+	}
+	if !reflect.DeepEqual(resp.Changes, want) {
+		t.Errorf("unexpected changes:\n got %+v\nwant %+v", resp.Changes, want)
+	}
+	if resp.Applied != 0 || resp.Message != "Dry run: 7 changes, 3 need manual migration" {
+		t.Errorf("unexpected summary: %d %q", resp.Applied, resp.Message)
+	}
+
+	collection, _ := handler.registry.Get("orders")
+	if len(collection.Columns) != 5 || !collection.RequireRevision {
+		t.Errorf("dry run changed the collection: %+v", collection)
+	}
+}
+
+func TestSchemaImport_Sync(t *testing.T) {
+	handler := setupSchemaImportTest(t)
+	doc := exportSchema(t, handler)
+
+	customers := doc.Collections[0]
+	customers.Columns = append(customers.Columns[:1], registry.Column{Name: "age", Type: registry.TypeInteger, Nullable: true})
+	customers.DefaultSort = []string{"email"}
+	doc.Collections = append(doc.Collections, &registry.Collection{
+		Name:    "invoices",
+		Columns: []registry.Column{{Name: "amount", Type: registry.TypeDecimal}},
+	})
+
+	status, resp := importSchema(t, handler, SchemaImportSync, doc)
+	if status != http.StatusOK {
+		t.Fatalf("Import failed: %d %+v", status, resp)
+	}
+	if resp.Applied != 3 || len(resp.Changes) != 4 {
+		t.Errorf("expected 3 of 4 changes applied, got %+v", resp)
+	}
+	for _, change := range resp.Changes {
+		if change.Applied == change.Manual {
+			t.Errorf("expected exactly the non-manual changes applied, got %+v", change)
+		}
+	}
+
+	collection, _ := handler.registry.Get("customers")
+	if _, ok := findColumn(collection.Columns, "age"); !ok {
+		t.Errorf("expected column age to be added, got %+v", collection.Columns)
+	}
+	if _, ok := findColumn(collection.Columns, "note"); !ok {
+		t.Error("expected column note to be kept")
+	}
+	if !reflect.DeepEqual(collection.DefaultSort, []string{"email"}) {
+		t.Errorf("expected default_sort to be set, got %v", collection.DefaultSort)
+	}
+	if !handler.registry.Exists("invoices") {
+		t.Error("expected invoices to be created")
+	}
+}
+
+func TestSchemaImport_Validation(t *testing.T) {
+	handler := setupSchemaImportTest(t)
+	derived := "'0.00'"
+	custom := "'5'"
+
+	tests := []struct {
+		name   string
+		mode   SchemaImportMode
+		doc    SchemaDocument
+		status int
+		code   apperrors.ErrorCode
+	}{
+		{"invalid mode", "replace", SchemaDocument{}, http.StatusBadRequest, apperrors.CodeInvalidParameter},
+		{"invalid name", SchemaImportSync, SchemaDocument{Collections: []*registry.Collection{
+			{Name: "moon_users", Columns: []registry.Column{{Name: "a", Type: registry.TypeString}}},
+		}}, http.StatusBadRequest, apperrors.CodeCollectionNameInvalid},
+		{"custom default", SchemaImportSync, SchemaDocument{Collections: []*registry.Collection{
+			{Name: "prices", Columns: []registry.Column{{Name: "a", Type: registry.TypeDecimal, Nullable: true, DefaultValue: &custom}}},
+		}}, http.StatusBadRequest, apperrors.CodeInvalidSchema},
+		{"invalid later collection", SchemaImportSync, SchemaDocument{Collections: []*registry.Collection{
+			{Name: "prices", Columns: []registry.Column{{Name: "a", Type: registry.TypeDecimal, Nullable: true, DefaultValue: &derived}}},
+			{Name: "empty"},
+		}}, http.StatusBadRequest, apperrors.CodeInvalidSchema},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postCollections(handler.Import, SchemaImportRequest{Mode: tt.mode, SchemaDocument: tt.doc})
+			if w.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			var resp map[string]any
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp["code"] != string(tt.code) {
+				t.Errorf("expected code %s, got %v", tt.code, resp["code"])
+			}
+		})
+	}
+
+	// Nothing is applied when any collection of the document is invalid
+	if handler.registry.Exists("prices") {
+		t.Error("expected prices not to be created")
+	}
+}
+
+func TestSchemaImport_References(t *testing.T) {
+	handler, driver := setupTestHandler(t)
+	t.Cleanup(func() { driver.Close() })
+
+	// invoices references zones, which comes later in the document
+	doc := SchemaDocument{Collections: []*registry.Collection{
+		{Name: "invoices", Columns: []registry.Column{{Name: "zone_id", Type: registry.TypeString, Nullable: true, References: "zones"}}},
+		{Name: "zones", Columns: []registry.Column{{Name: "label", Type: registry.TypeString}}},
+	}}
+	status, resp := importSchema(t, handler, SchemaImportCreateMissing, doc)
+	if status != http.StatusOK || resp.Applied != 2 {
+		t.Fatalf("Import failed: %d %+v", status, resp)
+	}
+	if resp.Changes[0].Collection != "zones" || resp.Changes[1].Collection != "invoices" {
+		t.Errorf("expected zones to be created before invoices, got %+v", resp.Changes)
+	}
+	if invoices, _ := handler.registry.Get("invoices"); invoices.Columns[0].References != "zones" {
+		t.Errorf("expected the reference to be imported, got %+v", invoices.Columns[0])
+	}
+
+	// Changing a reference is left to a manual migration
+	doc.Collections[0].Columns[0].References = "invoices"
+	status, resp = importSchema(t, handler, SchemaImportSync, doc)
+	if status != http.StatusOK || len(resp.Changes) != 1 || !resp.Changes[0].Manual || resp.Changes[0].Detail != "references change zone_id: zones→invoices (unsupported)" {
+		t.Errorf("expected a manual references change, got %d %+v", status, resp)
+	}
+
+	// A reference to a collection neither live nor imported is rejected
+	doc = SchemaDocument{Collections: []*registry.Collection{
+		{Name: "payments", Columns: []registry.Column{{Name: "order_id", Type: registry.TypeString, References: "orders"}}},
+	}}
+	if status, _ := importSchema(t, handler, SchemaImportCreateMissing, doc); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown reference, got %d", status)
+	}
+}
@@ -344,6 +344,38 @@ func TestUpdate_AddColumns(t *testing.T) {
 	}
 }
 
+func TestUpdate_RequireRevision(t *testing.T) {
+	handler, driver := setupTestHandler(t)
+	defer driver.Close()
+
+	createReq := CreateRequest{
+		Name:            "orders",
+		Columns:         []registry.Column{{Name: "customer", Type: registry.TypeString}},
+		RequireRevision: true,
+	}
+	body, _ := json.Marshal(createReq)
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if collection, _ := handler.registry.Get("orders"); !collection.RequireRevision {
+		t.Error("Expected require_revision to be stored in the registry")
+	}
+
+	// The flag can be switched off without other schema changes
+	disabled := false
+	body, _ = json.Marshal(UpdateRequest{Name: "orders", RequireRevision: &disabled})
+	w = httptest.NewRecorder()
+	handler.Update(w, httptest.NewRequest(http.MethodPost, "/collections:update", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if collection, _ := handler.registry.Get("orders"); collection.RequireRevision {
+		t.Error("Expected require_revision to be cleared")
+	}
+}
+
 func TestUpdate_NotFound(t *testing.T) {
 	handler, driver := setupTestHandler(t)
 	defer driver.Close()
@@ -621,25 +653,49 @@ func TestGenerateDDL(t *testing.T) {
 	}
 
 	// Test SQLite DDL
-	ddl := generateCreateTableDDL("test", columns, database.DialectSQLite)
+	ddl := generateCreateTableDDL("test", columns, registry.IDTypeULID, database.DialectSQLite)
 	if ddl == "" {
 		t.Error("Expected non-empty DDL")
 	}
-	if !bytes.Contains([]byte(ddl), []byte("CREATE TABLE test")) {
+	if !bytes.Contains([]byte(ddl), []byte(`CREATE TABLE "test"`)) {
 		t.Error("DDL should contain CREATE TABLE statement")
 	}
+	if !bytes.Contains([]byte(ddl), []byte("created_at TEXT")) || !bytes.Contains([]byte(ddl), []byte("updated_at TEXT")) {
+		t.Error("SQLite DDL should include created_at and updated_at system columns")
+	}
+	if !bytes.Contains([]byte(ddl), []byte("_rev INTEGER NOT NULL DEFAULT 1")) {
+		t.Error("SQLite DDL should include the _rev system column")
+	}
 
 	// Test PostgreSQL DDL
-	ddl = generateCreateTableDDL("test", columns, database.DialectPostgres)
+	ddl = generateCreateTableDDL("test", columns, registry.IDTypeULID, database.DialectPostgres)
 	if !bytes.Contains([]byte(ddl), []byte("SERIAL PRIMARY KEY")) {
 		t.Error("PostgreSQL DDL should use SERIAL")
 	}
+	if !bytes.Contains([]byte(ddl), []byte("created_at TIMESTAMP")) {
+		t.Error("PostgreSQL DDL should use TIMESTAMP for created_at")
+	}
 
 	// Test MySQL DDL
-	ddl = generateCreateTableDDL("test", columns, database.DialectMySQL)
+	ddl = generateCreateTableDDL("test", columns, registry.IDTypeULID, database.DialectMySQL)
 	if !bytes.Contains([]byte(ddl), []byte("AUTO_INCREMENT PRIMARY KEY")) {
 		t.Error("MySQL DDL should use AUTO_INCREMENT")
 	}
+	if !bytes.Contains([]byte(ddl), []byte("CREATE TABLE `test`")) || !bytes.Contains([]byte(ddl), []byte("`name` TEXT")) {
+		t.Error("MySQL DDL should quote identifiers with backticks")
+	}
+
+	// Reference columns get a foreign key on PostgreSQL only
+	columns = append(columns, registry.Column{Name: "parent_id", Type: registry.TypeString, Nullable: true, References: "test"})
+	ddl = generateCreateTableDDL("test", columns, registry.IDTypeULID, database.DialectPostgres)
+	if !strings.Contains(ddl, `"parent_id" TEXT REFERENCES "test"(id)`) {
+		t.Errorf("PostgreSQL DDL should reference the target id, got %s", ddl)
+	}
+	for _, dialect := range []database.DialectType{database.DialectSQLite, database.DialectMySQL} {
+		if ddl := generateCreateTableDDL("test", columns, registry.IDTypeULID, dialect); strings.Contains(ddl, "REFERENCES") {
+			t.Errorf("%s DDL should not declare foreign keys, got %s", dialect, ddl)
+		}
+	}
 }
 
 // TestGenerateAddColumnDDL tests the generateAddColumnDDL function
@@ -679,6 +735,24 @@ func TestGenerateAddColumnDDL(t *testing.T) {
 			contains:    []string{"ALTER TABLE", "ADD COLUMN", "code", "TEXT", "NOT NULL"},
 			notContains: []string{"UNIQUE"},
 		},
+		{
+			name:     "PostgreSQL - reference column gets a foreign key",
+			dialect:  database.DialectPostgres,
+			column:   registry.Column{Name: "customer_id", Type: registry.TypeString, Nullable: true, References: "customers"},
+			contains: []string{`"customer_id" TEXT REFERENCES "customers"(id)`},
+		},
+		{
+			name:        "SQLite - reference column is checked by the server only",
+			dialect:     database.DialectSQLite,
+			column:      registry.Column{Name: "customer_id", Type: registry.TypeString, Nullable: true, References: "customers"},
+			notContains: []string{"REFERENCES"},
+		},
+		{
+			name:        "MySQL - reference column is checked by the server only",
+			dialect:     database.DialectMySQL,
+			column:      registry.Column{Name: "customer_id", Type: registry.TypeString, Nullable: true, References: "customers"},
+			notContains: []string{"REFERENCES"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -714,21 +788,21 @@ func TestGenerateAddUniqueConstraintDDL(t *testing.T) {
 			dialect:    database.DialectSQLite,
 			tableName:  "products",
 			columnName: "slug",
-			contains:   []string{"CREATE UNIQUE INDEX", "idx_products_slug", "ON products(slug)"},
+			contains:   []string{"CREATE UNIQUE INDEX", "idx_products_slug", `ON "products"("slug")`},
 		},
 		{
 			name:       "PostgreSQL - uses ALTER TABLE ADD CONSTRAINT",
 			dialect:    database.DialectPostgres,
 			tableName:  "users",
 			columnName: "email",
-			contains:   []string{"ALTER TABLE users", "ADD CONSTRAINT", "users_email_unique", "UNIQUE(email)"},
+			contains:   []string{`ALTER TABLE "users"`, "ADD CONSTRAINT", "users_email_unique", `UNIQUE("email")`},
 		},
 		{
 			name:       "MySQL - uses ALTER TABLE ADD CONSTRAINT",
 			dialect:    database.DialectMySQL,
 			tableName:  "orders",
 			columnName: "code",
-			contains:   []string{"ALTER TABLE orders", "ADD CONSTRAINT", "orders_code_unique", "UNIQUE(code)"},
+			contains:   []string{"ALTER TABLE `orders`", "ADD CONSTRAINT", "orders_code_unique", "UNIQUE(`code`)"},
 		},
 	}
 
@@ -745,6 +819,44 @@ func TestGenerateAddUniqueConstraintDDL(t *testing.T) {
 	}
 }
 
+// TestGenerateIndexDDL tests the generateCreateIndexDDL and generateDropIndexDDL functions
+func TestGenerateIndexDDL(t *testing.T) {
+	index := registry.Index{Name: "idx_tenant_sku", Columns: []string{"tenant_id", "sku"}, Unique: true}
+
+	creates := []struct {
+		dialect database.DialectType
+		want    string
+	}{
+		{database.DialectSQLite, `CREATE UNIQUE INDEX "idx_tenant_sku" ON "items" ("tenant_id", "sku")`},
+		{database.DialectPostgres, `CREATE UNIQUE INDEX "idx_tenant_sku" ON "items" ("tenant_id", "sku")`},
+		{database.DialectMySQL, "CREATE UNIQUE INDEX `idx_tenant_sku` ON `items` (`tenant_id`, `sku`)"},
+	}
+	for _, tt := range creates {
+		if ddl := generateCreateIndexDDL("items", index, tt.dialect); ddl != tt.want {
+			t.Errorf("%s: unexpected create DDL: %s", tt.dialect, ddl)
+		}
+	}
+
+	plain := registry.Index{Name: "idx_price", Columns: []string{"price"}}
+	if ddl := generateCreateIndexDDL("items", plain, database.DialectSQLite); ddl != `CREATE INDEX "idx_price" ON "items" ("price")` {
+		t.Errorf("unexpected create DDL: %s", ddl)
+	}
+
+	tests := []struct {
+		dialect database.DialectType
+		want    string
+	}{
+		{database.DialectSQLite, `DROP INDEX "idx_price"`},
+		{database.DialectPostgres, `DROP INDEX "idx_price"`},
+		{database.DialectMySQL, "DROP INDEX `idx_price` ON `items`"},
+	}
+	for _, tt := range tests {
+		if ddl := generateDropIndexDDL("items", "idx_price", tt.dialect); ddl != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.dialect, tt.want, ddl)
+		}
+	}
+}
+
 // Tests for Remove Columns functionality
 func TestUpdate_RemoveColumns_Success(t *testing.T) {
 	handler, driver := setupTestHandler(t)
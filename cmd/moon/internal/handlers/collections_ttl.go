@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// minTTLDuration is the shortest record lifetime a ttl policy may set
+const minTTLDuration = time.Second
+
+// validateTTL checks the ttl policy of a collection: either field, a datetime
+// column of the collection, or duration counted from from, which defaults to
+// created_at and may be a timestamp or datetime column
+func validateTTL(collection *registry.Collection) error {
+	ttl := collection.TTL
+	if ttl == nil {
+		return nil
+	}
+	if (ttl.Field == "") == (ttl.Duration == "") {
+		return fmt.Errorf("ttl: set either field, or duration with an optional from")
+	}
+	if ttl.Field != "" {
+		if ttl.From != "" {
+			return fmt.Errorf("ttl: from only applies to duration")
+		}
+		return validateTTLColumn(collection, "field", ttl.Field, false)
+	}
+
+	duration, err := time.ParseDuration(ttl.Duration)
+	if err != nil || duration < minTTLDuration {
+		return fmt.Errorf("ttl: invalid duration '%s': must be a duration of at least %s, such as 720h", ttl.Duration, minTTLDuration)
+	}
+	if ttl.From == "" {
+		ttl.From = constants.CreatedAtColumn
+	}
+	return validateTTLColumn(collection, "from", ttl.From, true)
+}
+
+// validateTTLColumn checks that name is a datetime column of the collection,
+// or a timestamp system column when timestamps is set
+func validateTTLColumn(collection *registry.Collection, key, name string, timestamps bool) error {
+	if timestamps && (name == constants.CreatedAtColumn || name == constants.UpdatedAtColumn) {
+		return nil
+	}
+	for _, col := range collection.Columns {
+		if col.Name == name {
+			if col.Type != registry.TypeDatetime {
+				return fmt.Errorf("ttl: %s '%s' must be a datetime column, got %s", key, name, col.Type)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("ttl: %s '%s' is not a column of the collection", key, name)
+}
+
+// ttlColumn returns the user column the ttl policy of a collection reads,
+// empty when it has none or reads a timestamp system column
+func ttlColumn(collection *registry.Collection) string {
+	if collection.TTL == nil {
+		return ""
+	}
+	if collection.TTL.Field != "" {
+		return collection.TTL.Field
+	}
+	if collection.TTL.From == constants.CreatedAtColumn || collection.TTL.From == constants.UpdatedAtColumn {
+		return ""
+	}
+	return collection.TTL.From
+}
+
+// renameTTLColumn follows a column rename in the ttl policy of a collection
+func renameTTLColumn(collection *registry.Collection, oldName, newName string) {
+	if collection.TTL == nil {
+		return
+	}
+	if collection.TTL.Field == oldName {
+		collection.TTL.Field = newName
+	}
+	if collection.TTL.From == oldName {
+		collection.TTL.From = newName
+	}
+}
+
+// describeTTL describes a ttl policy for schema import plans
+func describeTTL(ttl *registry.TTL) string {
+	switch {
+	case ttl == nil:
+		return "none"
+	case ttl.Field != "":
+		return "field " + ttl.Field
+	case ttl.From == "":
+		return ttl.Duration + " from " + constants.CreatedAtColumn
+	default:
+		return ttl.Duration + " from " + ttl.From
+	}
+}
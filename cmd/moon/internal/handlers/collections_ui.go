@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// validateUI checks the presentation metadata of a collection: order and
+// groups only name columns of the collection, a column appears at most once
+// in order and in at most one group, and groups have distinct labels and at
+// least one column
+func validateUI(collection *registry.Collection) error {
+	ui := collection.UI
+	if ui == nil {
+		return nil
+	}
+	columns := make(map[string]bool, len(collection.Columns))
+	for _, col := range collection.Columns {
+		columns[col.Name] = true
+	}
+
+	ordered := make(map[string]bool, len(ui.Order))
+	for _, name := range ui.Order {
+		if !columns[name] {
+			return fmt.Errorf("ui: order column '%s' is not a column of the collection", name)
+		}
+		if ordered[name] {
+			return fmt.Errorf("ui: column '%s' appears more than once in order", name)
+		}
+		ordered[name] = true
+	}
+
+	labels := make(map[string]bool, len(ui.Groups))
+	grouped := make(map[string]string)
+	for _, group := range ui.Groups {
+		if strings.TrimSpace(group.Label) == "" {
+			return fmt.Errorf("ui: every group needs a label")
+		}
+		if labels[group.Label] {
+			return fmt.Errorf("ui: duplicate group label '%s'", group.Label)
+		}
+		labels[group.Label] = true
+		if len(group.Columns) == 0 {
+			return fmt.Errorf("ui: group '%s' has no columns", group.Label)
+		}
+		for _, name := range group.Columns {
+			if !columns[name] {
+				return fmt.Errorf("ui: group '%s' column '%s' is not a column of the collection", group.Label, name)
+			}
+			if label, ok := grouped[name]; ok {
+				return fmt.Errorf("ui: column '%s' appears in group '%s' and group '%s'", name, label, group.Label)
+			}
+			grouped[name] = group.Label
+		}
+	}
+	return nil
+}
+
+// nilIfNoUI returns nil for presentation metadata without order or groups,
+// so that an empty object clears it
+func nilIfNoUI(ui *registry.UI) *registry.UI {
+	if ui == nil || len(ui.Order) == 0 && len(ui.Groups) == 0 {
+		return nil
+	}
+	return ui
+}
+
+// renameUIColumn follows a column rename in the presentation metadata of a
+// collection
+func renameUIColumn(collection *registry.Collection, oldName, newName string) {
+	if collection.UI == nil {
+		return
+	}
+	rename := func(columns []string) {
+		if i := slices.Index(columns, oldName); i >= 0 {
+			columns[i] = newName
+		}
+	}
+	rename(collection.UI.Order)
+	for _, group := range collection.UI.Groups {
+		rename(group.Columns)
+	}
+}
+
+// removeUIColumn drops a removed column from the presentation metadata of a
+// collection. Groups left without columns are dropped, and the metadata is
+// cleared once nothing is left.
+func removeUIColumn(collection *registry.Collection, name string) {
+	if collection.UI == nil {
+		return
+	}
+	isName := func(column string) bool { return column == name }
+	collection.UI.Order = slices.DeleteFunc(collection.UI.Order, isName)
+	for i := range collection.UI.Groups {
+		collection.UI.Groups[i].Columns = slices.DeleteFunc(collection.UI.Groups[i].Columns, isName)
+	}
+	collection.UI.Groups = slices.DeleteFunc(collection.UI.Groups, func(group registry.UIGroup) bool {
+		return len(group.Columns) == 0
+	})
+	collection.UI = nilIfNoUI(collection.UI)
+}
+
+// describeUI describes presentation metadata for schema import plans
+func describeUI(ui *registry.UI) string {
+	if ui == nil {
+		return "none"
+	}
+	groups := make([]string, 0, len(ui.Groups))
+	for _, group := range ui.Groups {
+		groups = append(groups, fmt.Sprintf("%s: %v", group.Label, group.Columns))
+	}
+	return fmt.Sprintf("order %v groups [%s]", ui.Order, strings.Join(groups, ", "))
+}
+
+// uiOrderedColumns returns columns in the order of the presentation metadata
+// of a collection: the columns it orders first, the others after them in
+// their given order
+func uiOrderedColumns(ui *registry.UI, columns []registry.Column) []registry.Column {
+	if ui == nil || len(ui.Order) == 0 {
+		return columns
+	}
+	position := func(col registry.Column) int {
+		if i := slices.Index(ui.Order, col.Name); i >= 0 {
+			return i
+		}
+		return len(ui.Order)
+	}
+	ordered := slices.Clone(columns)
+	slices.SortStableFunc(ordered, func(a, b registry.Column) int { return position(a) - position(b) })
+	return ordered
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupUITest creates a products collection for presentation metadata tests
+func setupUITest(t *testing.T) (*registry.SchemaRegistry, *CollectionsHandler, *DataHandler) {
+	t.Helper()
+	driver, reg, handler := setupTTLTest(t)
+	body, _ := json.Marshal(map[string]any{
+		"name": "products",
+		"columns": []map[string]any{
+			{"name": "stock", "type": "integer", "nullable": true},
+			{"name": "price", "type": "decimal", "nullable": true},
+			{"name": "name", "type": "string", "nullable": false},
+			{"name": "discount", "type": "decimal", "nullable": true},
+		},
+	})
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+	}
+	return reg, handler, NewDataHandler(driver, reg, testConfig())
+}
+
+// updateProducts sends collections:update for the products collection
+func updateProducts(handler *CollectionsHandler, body map[string]any) *httptest.ResponseRecorder {
+	body["name"] = "products"
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	handler.Update(w, httptest.NewRequest(http.MethodPost, "/collections:update", bytes.NewReader(payload)))
+	return w
+}
+
+func TestCollectionsUpdate_UI(t *testing.T) {
+	reg, handler, data := setupUITest(t)
+	ui := &registry.UI{
+		Order:  []string{"name", "price", "stock"},
+		Groups: []registry.UIGroup{{Label: "Pricing", Columns: []string{"price", "discount"}}},
+	}
+	if w := updateProducts(handler, map[string]any{"ui": ui}); w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+
+	// collections:get, :schema and the persisted schema return it as set
+	w := httptest.NewRecorder()
+	handler.Get(w, httptest.NewRequest(http.MethodGet, "/collections:get?name=products", nil))
+	var got GetResponse
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if !reflect.DeepEqual(got.Collection.UI, ui) {
+		t.Errorf("Expected collections:get to return %+v, got %+v", ui, got.Collection.UI)
+	}
+	w = httptest.NewRecorder()
+	data.Schema(w, httptest.NewRequest(http.MethodGet, "/products:schema", nil), "products")
+	var schema SchemaResponse
+	json.Unmarshal(w.Body.Bytes(), &schema)
+	if !reflect.DeepEqual(schema.UI, ui) {
+		t.Errorf("Expected :schema to return %+v, got %+v", ui, schema.UI)
+	}
+	collection, _ := reg.Get("products")
+	persisted, _ := json.Marshal(collection)
+	var restored registry.Collection
+	json.Unmarshal(persisted, &restored)
+	if !reflect.DeepEqual(restored.UI, ui) {
+		t.Errorf("Expected the persisted schema to keep %+v, got %+v", ui, restored.UI)
+	}
+
+	// A rename follows, an empty object clears it
+	if w := updateProducts(handler, map[string]any{"rename_columns": []RenameColumn{{OldName: "price", NewName: "list_price"}}}); w.Code != http.StatusOK {
+		t.Fatalf("Rename failed: %d %s", w.Code, w.Body.String())
+	}
+	collection, _ = reg.Get("products")
+	if collection.UI.Order[1] != "list_price" || collection.UI.Groups[0].Columns[0] != "list_price" {
+		t.Errorf("Expected the rename to follow, got %+v", collection.UI)
+	}
+	if w := updateProducts(handler, map[string]any{"ui": map[string]any{}}); w.Code != http.StatusOK {
+		t.Fatalf("Clear failed: %d %s", w.Code, w.Body.String())
+	}
+	if collection, _ = reg.Get("products"); collection.UI != nil {
+		t.Errorf("Expected an empty object to clear the metadata, got %+v", collection.UI)
+	}
+}
+
+func TestCollectionsUpdate_UIValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		ui   any
+		want string
+	}{
+		{"unknown order column", map[string]any{"order": []string{"name", "color"}}, "order column 'color' is not a column"},
+		{"system column", map[string]any{"order": []string{"id"}}, "order column 'id' is not a column"},
+		{"repeated in order", map[string]any{"order": []string{"name", "price", "name"}}, "more than once in order"},
+		{"unknown group column", map[string]any{"groups": []map[string]any{{"label": "Pricing", "columns": []string{"cost"}}}}, "column 'cost' is not a column"},
+		{"in two groups", map[string]any{"groups": []map[string]any{
+			{"label": "Pricing", "columns": []string{"price"}},
+			{"label": "Stock", "columns": []string{"stock", "price"}},
+		}}, "appears in group 'Pricing' and group 'Stock'"},
+		{"missing label", map[string]any{"groups": []map[string]any{{"label": " ", "columns": []string{"price"}}}}, "needs a label"},
+		{"duplicate label", map[string]any{"groups": []map[string]any{
+			{"label": "Pricing", "columns": []string{"price"}},
+			{"label": "Pricing", "columns": []string{"discount"}},
+		}}, "duplicate group label"},
+		{"empty group", map[string]any{"groups": []map[string]any{{"label": "Pricing", "columns": []string{}}}}, "has no columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, handler, _ := setupUITest(t)
+			w := updateProducts(handler, map[string]any{"ui": tt.ui})
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("Expected 400 containing %q, got %d %s", tt.want, w.Code, w.Body.String())
+			}
+			if collection, _ := reg.Get("products"); collection.UI != nil {
+				t.Errorf("Expected the metadata to stay unset, got %+v", collection.UI)
+			}
+		})
+	}
+
+	t.Run("columns removed in the same request", func(t *testing.T) {
+		_, handler, _ := setupUITest(t)
+		w := updateProducts(handler, map[string]any{"remove_columns": []string{"stock"}, "ui": map[string]any{"order": []string{"stock"}}})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "'stock' is not a column") {
+			t.Errorf("Expected 400, got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestCollectionsUpdate_UIRemoveColumns(t *testing.T) {
+	reg, handler, _ := setupUITest(t)
+	ui := map[string]any{
+		"order": []string{"name", "price", "stock"},
+		"groups": []map[string]any{
+			{"label": "Pricing", "columns": []string{"price", "discount"}},
+			{"label": "Inventory", "columns": []string{"stock"}},
+		},
+	}
+	if w := updateProducts(handler, map[string]any{"ui": ui}); w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := updateProducts(handler, map[string]any{"remove_columns": []string{"price", "stock"}}); w.Code != http.StatusOK {
+		t.Fatalf("Remove failed: %d %s", w.Code, w.Body.String())
+	}
+
+	collection, _ := reg.Get("products")
+	want := &registry.UI{
+		Order:  []string{"name"},
+		Groups: []registry.UIGroup{{Label: "Pricing", Columns: []string{"discount"}}},
+	}
+	if !reflect.DeepEqual(collection.UI, want) {
+		t.Errorf("Expected the removed columns to be dropped, got %+v", collection.UI)
+	}
+
+	if w := updateProducts(handler, map[string]any{"remove_columns": []string{"name", "discount"}}); w.Code != http.StatusOK {
+		t.Fatalf("Remove failed: %d %s", w.Code, w.Body.String())
+	}
+	if collection, _ = reg.Get("products"); collection.UI != nil {
+		t.Errorf("Expected the metadata to be cleared, got %+v", collection.UI)
+	}
+}
+
+func TestDocHandler_SampleFollowsUIOrder(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "stock", Type: registry.TypeInteger},
+			{Name: "price", Type: registry.TypeInteger},
+			{Name: "name", Type: registry.TypeString},
+			{Name: "active", Type: registry.TypeBoolean},
+		},
+		UI: &registry.UI{Order: []string{"name", "price"}},
+	})
+	handler := NewDocHandler(reg, &config.AppConfig{Server: config.ServerConfig{Host: "localhost", Port: 6006}}, "1.99")
+
+	sample := handler.buildDocSample(handler.documentedCollections(""), time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	want := `{"name": "example", "price": 42, "active": true, "stock": 42}`
+	if sample.Data != want {
+		t.Errorf("Expected the ui order, then the other columns by name: got %s, want %s", sample.Data, want)
+	}
+
+	var spec map[string]any
+	doc, _ := handler.generateOpenAPI("http://localhost:6006", "")
+	json.Unmarshal(doc, &spec)
+	required := spec["components"].(map[string]any)["schemas"].(map[string]any)[openAPISchemaName("products")].(map[string]any)["required"]
+	if !reflect.DeepEqual(required, []any{"name", "price", "stock", "active"}) {
+		t.Errorf("Expected required columns in the ui order, got %v", required)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// UpdatePlanResponse is the response of a collections:update dry run: what
+// the update would do, without doing it
+type UpdatePlanResponse struct {
+	Collection    *registry.Collection `json:"collection"`        // the collection as the update would leave it
+	Dialect       string               `json:"dialect"`           // database dialect the statements are written for
+	Statements    []string             `json:"statements"`        // DDL in execution order; empty for registry-only changes
+	Transactional bool                 `json:"transactional"`     // the statements would run in one transaction
+	Coerced       int                  `json:"coerced,omitempty"` // values force=true would set to null or the default
+	DryRun        bool                 `json:"dry_run"`
+	Message       string               `json:"message"`
+}
+
+// updateStatement is one statement of a planned collection update
+type updateStatement struct {
+	SQL     string
+	args    []any               // bind parameters of SQL
+	failure string              // the operation, for the error when the statement fails
+	code    apperrors.ErrorCode // error code when the statement fails
+	undo    string              // statement reverting the previous one when this fails without a transaction
+	coerces int                 // values the statement sets to null or the default
+}
+
+// transactionalDDL reports whether a dialect can roll back schema changes.
+// MySQL commits every DDL statement implicitly.
+func transactionalDDL(dialect database.DialectType) bool {
+	return dialect != database.DialectMySQL
+}
+
+// updateStatementSQL returns the SQL of planned statements
+func updateStatementSQL(statements []updateStatement) []string {
+	sqls := make([]string, len(statements))
+	for i, statement := range statements {
+		sqls[i] = statement.SQL
+	}
+	return sqls
+}
+
+// coercedValues returns the number of values planned statements coerce
+func coercedValues(statements []updateStatement) int {
+	coerced := 0
+	for _, statement := range statements {
+		coerced += statement.coerces
+	}
+	return coerced
+}
+
+// runUpdateStatements executes the statements of a planned update. Where the
+// dialect supports transactional DDL they run in one transaction, so a failure
+// leaves the table as it was; on MySQL they run one by one and a failure
+// leaves the statements before it applied. Errors are *apperrors.APIError values.
+func (h *CollectionsHandler) runUpdateStatements(ctx context.Context, statements []updateStatement) error {
+	if len(statements) == 0 {
+		return nil
+	}
+
+	if !transactionalDDL(h.db.Dialect()) {
+		for i, statement := range statements {
+			if _, err := h.db.Exec(ctx, statement.SQL, statement.args...); err != nil {
+				if statement.undo != "" {
+					if _, undoErr := h.db.Exec(ctx, statement.undo); undoErr != nil {
+						log.Printf("WARNING: Failed to revert %s: %v", statements[i-1].failure, undoErr)
+					}
+				}
+				if i > 0 {
+					log.Printf("WARNING: Collection update failed after %d of %d statements were applied", i, len(statements))
+				}
+				return apperrors.Newf(http.StatusInternalServerError, statement.code, "failed to %s: %v", statement.failure, err)
+			}
+		}
+		return nil
+	}
+
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement.SQL, statement.args...); err != nil {
+			return apperrors.Newf(http.StatusInternalServerError, statement.code, "failed to %s: %v", statement.failure, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// dryRunUpdate validates an update and writes the statements it would run and
+// the collection it would leave, without changing the table or the registry
+func (h *CollectionsHandler) dryRunUpdate(w http.ResponseWriter, r *http.Request, table string, collection *registry.Collection, req *UpdateRequest, force bool) {
+	statements, err := h.planUpdate(r.Context(), table, collection, req, force)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	audit.Discard(r.Context())
+
+	dialect := h.db.Dialect()
+	writeResponse(w, r, http.StatusOK, UpdatePlanResponse{
+		Collection:    h.logicalView(collection),
+		Dialect:       string(dialect),
+		Statements:    updateStatementSQL(statements),
+		Transactional: transactionalDDL(dialect),
+		Coerced:       coercedValues(statements),
+		DryRun:        true,
+		Message:       fmt.Sprintf("Collection '%s' would be updated with %d statements", req.Name, len(statements)),
+	})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// statementRecorder collects the statements executed through recording
+// connections and fails the failAt-th one when failAt is set
+type statementRecorder struct {
+	mu         sync.Mutex
+	statements []string
+	failAt     int
+}
+
+func (r *statementRecorder) record(query string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, query)
+	if len(r.statements) == r.failAt {
+		return fmt.Errorf("forced failure of statement %d", r.failAt)
+	}
+	return nil
+}
+
+func (r *statementRecorder) reset(failAt int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements, r.failAt = nil, failAt
+}
+
+// recordingSQLDriver wraps a database/sql driver so every Exec, also inside
+// transactions, is recorded
+type recordingSQLDriver struct {
+	driver.Driver
+	recorder *statementRecorder
+}
+
+func (d recordingSQLDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingConn{Conn: conn, recorder: d.recorder}, nil
+}
+
+type recordingConn struct {
+	driver.Conn
+	recorder *statementRecorder
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.recorder.record(query); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *recordingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+// recordingDriver runs the queries of a SQLite file database through
+// recording connections; table inspection uses the wrapped driver
+type recordingDriver struct {
+	database.Driver
+	db *sql.DB
+}
+
+func (d *recordingDriver) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+func (d *recordingDriver) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, query, args...)
+}
+
+func (d *recordingDriver) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.db.QueryRowContext(ctx, query, args...)
+}
+
+func (d *recordingDriver) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return d.db.BeginTx(ctx, nil)
+}
+
+func (d *recordingDriver) DB() *sql.DB { return d.db }
+
+// setupUpdateRecordingTest creates a notes collection with two records on a
+// recording SQLite file database
+func setupUpdateRecordingTest(t *testing.T) (*CollectionsHandler, *recordingDriver, *statementRecorder) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "moon.db")
+	real, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://" + path,
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := real.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { real.Close() })
+
+	recorder := &statementRecorder{}
+	connector := recordingSQLDriver{Driver: real.DB().Driver(), recorder: recorder}
+	sql.Register("sqlite_recording_"+t.Name(), connector)
+	db, err := sql.Open("sqlite_recording_"+t.Name(), path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("Failed to open recording database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	recording := &recordingDriver{Driver: real, db: db}
+
+	handler := NewCollectionsHandler(recording, registry.NewSchemaRegistry())
+	w := postCollections(handler.Create, map[string]any{
+		"name": "notes",
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "qty", "type": "integer", "nullable": true},
+			{"name": "tag", "type": "string", "nullable": true},
+		},
+		"seed": []map[string]any{{"title": "a", "qty": 1}, {"title": "b", "qty": 2, "tag": "x"}},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+	}
+	recorder.reset(0)
+	return handler, recording, recorder
+}
+
+// tableColumnNames returns the column names of a table in the database
+func tableColumnNames(t *testing.T, db database.Driver, table string) []string {
+	t.Helper()
+	info, err := db.GetTableInfo(context.Background(), table)
+	if err != nil {
+		t.Fatalf("GetTableInfo() error = %v", err)
+	}
+	var names []string
+	for _, col := range info.Columns {
+		names = append(names, col.Name)
+	}
+	return names
+}
+
+func TestCollectionsUpdate_DryRunMatchesExecution(t *testing.T) {
+	handler, db, recorder := setupUpdateRecordingTest(t)
+	before := tableColumnNames(t, db, "notes")
+
+	update := map[string]any{
+		"name":           "notes",
+		"rename_columns": []map[string]any{{"old_name": "title", "new_name": "headline"}},
+		"modify_columns": []map[string]any{{"name": "qty", "type": "decimal"}},
+		"add_columns":    []map[string]any{{"name": "code", "type": "string", "nullable": true, "unique": true}},
+		"indexes":        []map[string]any{{"name": "idx_notes_headline", "columns": []string{"headline"}}},
+		"remove_columns": []string{"tag"},
+	}
+
+	w := postCollections(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.RawQuery = "dry_run=true"
+		handler.Update(w, r)
+	}, update)
+	if w.Code != http.StatusOK {
+		t.Fatalf("dry run failed: %d %s", w.Code, w.Body.String())
+	}
+	var plan UpdatePlanResponse
+	json.Unmarshal(w.Body.Bytes(), &plan)
+	if !plan.DryRun || !plan.Transactional || plan.Dialect != "sqlite" || len(plan.Statements) == 0 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	// Nothing was executed or registered
+	if len(recorder.statements) != 0 {
+		t.Errorf("expected no statements executed by the dry run, got %v", recorder.statements)
+	}
+	if after := tableColumnNames(t, db, "notes"); !slices.Equal(before, after) {
+		t.Errorf("expected table unchanged by the dry run, got %v, want %v", after, before)
+	}
+	if live, _ := handler.registry.Get("notes"); live.Columns[0].Name != "title" {
+		t.Errorf("expected registry unchanged by the dry run, got %+v", live.Columns)
+	}
+
+	w = postCollections(handler.Update, update)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	if !slices.Equal(recorder.statements, plan.Statements) {
+		t.Errorf("executed statements differ from the dry run:\nexecuted: %q\nplanned:  %q", recorder.statements, plan.Statements)
+	}
+
+	live, _ := handler.registry.Get("notes")
+	var planned, applied []string
+	for _, col := range plan.Collection.Columns {
+		planned = append(planned, fmt.Sprintf("%s:%s", col.Name, col.Type))
+	}
+	for _, col := range live.Columns {
+		applied = append(applied, fmt.Sprintf("%s:%s", col.Name, col.Type))
+	}
+	if !slices.Equal(planned, applied) || !slices.Equal(applied, []string{"headline:string", "qty:decimal", "code:string"}) {
+		t.Errorf("expected the planned columns %v to be applied, got %v", planned, applied)
+	}
+}
+
+func TestCollectionsUpdate_FailureRollsBack(t *testing.T) {
+	handler, db, recorder := setupUpdateRecordingTest(t)
+	before := tableColumnNames(t, db, "notes")
+
+	// The third statement fails after a rename and an added column
+	recorder.reset(3)
+	w := postCollections(handler.Update, map[string]any{
+		"name":           "notes",
+		"rename_columns": []map[string]any{{"old_name": "title", "new_name": "headline"}},
+		"add_columns": []map[string]any{
+			{"name": "code", "type": "string", "nullable": true},
+			{"name": "note", "type": "string", "nullable": true},
+		},
+	})
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "failed to add column 'note'") {
+		t.Fatalf("expected the third statement to fail, got %d %s", w.Code, w.Body.String())
+	}
+	if len(recorder.statements) != 3 {
+		t.Errorf("expected execution to stop at the third statement, got %v", recorder.statements)
+	}
+
+	if after := tableColumnNames(t, db, "notes"); !slices.Equal(before, after) {
+		t.Errorf("expected table untouched, got %v, want %v", after, before)
+	}
+	if live, _ := handler.registry.Get("notes"); len(live.Columns) != 3 || live.Columns[0].Name != "title" {
+		t.Errorf("expected registry untouched, got %+v", live.Columns)
+	}
+	var count int
+	if err := db.QueryRow(context.Background(), "SELECT COUNT(*) FROM notes WHERE title IS NOT NULL").Scan(&count); err != nil || count != 2 {
+		t.Errorf("expected both records kept, got %d (%v)", count, err)
+	}
+}
+
+func TestCollectionsUpdate_DryRunInvalid(t *testing.T) {
+	handler, _, recorder := setupUpdateRecordingTest(t)
+
+	dryRun := func(query string, body map[string]any) *httptest.ResponseRecorder {
+		return postCollections(func(w http.ResponseWriter, r *http.Request) {
+			r.URL.RawQuery = query
+			handler.Update(w, r)
+		}, body)
+	}
+
+	if w := dryRun("dry_run=maybe", map[string]any{"name": "notes", "remove_columns": []string{"tag"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid dry_run, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Validation runs as for a real update
+	w := dryRun("dry_run=true", map[string]any{"name": "notes", "remove_columns": []string{"missing"}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "missing") {
+		t.Errorf("expected the remove validation to fail, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Registry-only changes plan no statements
+	w = dryRun("dry_run=true", map[string]any{"name": "notes", "require_revision": true})
+	var plan UpdatePlanResponse
+	json.Unmarshal(w.Body.Bytes(), &plan)
+	if w.Code != http.StatusOK || plan.Statements == nil || len(plan.Statements) != 0 || !plan.Collection.RequireRevision {
+		t.Errorf("expected an empty statement list, got %d %s", w.Code, w.Body.String())
+	}
+	if live, _ := handler.registry.Get("notes"); live.RequireRevision || len(recorder.statements) != 0 {
+		t.Error("expected the dry run not to change anything")
+	}
+}
@@ -5,26 +5,44 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/changefeed"
 	"github.com/thalib/moon/cmd/moon/internal/config"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/expiry"
+	"github.com/thalib/moon/cmd/moon/internal/idempotency"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
 	"github.com/thalib/moon/cmd/moon/internal/query"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 	"github.com/thalib/moon/cmd/moon/internal/schema"
 	moonulid "github.com/thalib/moon/cmd/moon/internal/ulid"
+	"github.com/thalib/moon/cmd/moon/internal/webhook"
 )
 
 // DataHandler handles CRUD operations on collection data
 type DataHandler struct {
-	db       database.Driver
-	registry *registry.SchemaRegistry
-	config   *config.AppConfig
+	db          database.Driver
+	registry    *registry.SchemaRegistry
+	config      *config.AppConfig
+	webhooks    *webhook.Dispatcher
+	changes     *changefeed.Feed
+	idempotency *idempotency.Store
+	jobs        *jobs.Store
 }
 
 // NewDataHandler creates a new data handler
@@ -36,24 +54,51 @@ func NewDataHandler(db database.Driver, reg *registry.SchemaRegistry, cfg *confi
 	}
 }
 
+// SetWebhooks sets the dispatcher notified after successful creates, updates
+// and destroys. A nil dispatcher disables notifications.
+func (h *DataHandler) SetWebhooks(d *webhook.Dispatcher) {
+	h.webhooks = d
+}
+
+// SetChanges sets the feed that records successful creates, updates and
+// destroys for :changes. A nil feed disables :changes.
+func (h *DataHandler) SetChanges(f *changefeed.Feed) {
+	h.changes = f
+}
+
+// SetIdempotency sets the store replaying :create responses by their
+// Idempotency-Key. A nil store ignores the header.
+func (h *DataHandler) SetIdempotency(s *idempotency.Store) {
+	h.idempotency = s
+}
+
+// SetJobs sets the store journaling imports and batch creates sent with
+// journal=true. A nil store rejects journal and resume.
+func (h *DataHandler) SetJobs(s *jobs.Store) {
+	h.jobs = s
+}
+
 // DataListRequest represents query parameters for list operation
 type DataListRequest struct {
 	Limit  int               `json:"limit"`
-	After  string            `json:"after,omitempty"` // ULID cursor for pagination
+	After  string            `json:"after,omitempty"` // Cursor for pagination
 	Filter map[string]string `json:"filter,omitempty"`
 }
 
 // DataListResponse represents response for list operation (PRD-062)
 type DataListResponse struct {
 	Data       []map[string]any `json:"data"`
-	Total      int              `json:"total"`       // PRD-062: Total record count matching the query
-	NextCursor *string          `json:"next_cursor"` // Next ULID cursor, null if no more data
-	Limit      int              `json:"limit"`       // Always include pagination limit
+	Total      *int             `json:"total"`                 // PRD-062: Total record count matching the query; null when skipped with total=false
+	NextCursor *string          `json:"next_cursor"`           // Next page cursor, null if no more data
+	Limit      int              `json:"limit"`                 // Always include pagination limit
+	Page       *int             `json:"page,omitempty"`        // Page number with ?page= pagination
+	TotalPages *int             `json:"total_pages,omitempty"` // Page count with ?page= pagination
 }
 
 // DataGetResponse represents response for get operation
 type DataGetResponse struct {
-	Data map[string]any `json:"data"`
+	Data  map[string]any `json:"data"`
+	Links *RecordLinks   `json:"links,omitempty"`
 }
 
 // CreateDataRequest represents request for create operation
@@ -64,24 +109,29 @@ type CreateDataRequest struct {
 // CreateDataResponse represents response for create operation
 type CreateDataResponse struct {
 	Data    map[string]any `json:"data"`
+	Links   *RecordLinks   `json:"links,omitempty"`
 	Message string         `json:"message"`
 }
 
 // UpdateDataRequest represents request for update operation
 type UpdateDataRequest struct {
-	ID   string         `json:"id"` // ULID
-	Data map[string]any `json:"data"`
+	ID   string          `json:"id"` // ULID
+	Data map[string]any  `json:"data"`
+	Rev  json.RawMessage `json:"rev,omitempty"` // expected record revision
 }
 
 // UpdateDataResponse represents response for update operation
 type UpdateDataResponse struct {
-	Data    map[string]any `json:"data"`
-	Message string         `json:"message"`
+	Data    map[string]any         `json:"data"`
+	Changed map[string]FieldChange `json:"changed,omitzero"` // with ?return=diff
+	Links   *RecordLinks           `json:"links,omitempty"`
+	Message string                 `json:"message"`
 }
 
 // DestroyDataRequest represents request for destroy operation
 type DestroyDataRequest struct {
-	ID string `json:"id"` // ULID
+	ID  string          `json:"id"`            // ULID
+	Rev json.RawMessage `json:"rev,omitempty"` // expected record revision
 }
 
 // DestroyDataResponse represents response for destroy operation
@@ -111,18 +161,26 @@ const (
 	BatchItemCreated  BatchItemStatus = "created"
 	BatchItemUpdated  BatchItemStatus = "updated"
 	BatchItemDeleted  BatchItemStatus = "deleted"
+	BatchItemRestored BatchItemStatus = "restored"
 	BatchItemFailed   BatchItemStatus = "failed"
 	BatchItemNotFound BatchItemStatus = "not_found"
+	BatchItemConflict BatchItemStatus = "conflict"
+	BatchItemValid    BatchItemStatus = "valid"   // passed the checks of :validate
+	BatchItemInvalid  BatchItemStatus = "invalid" // failed a check of :validate
 )
 
 // BatchItemResult represents the result of processing a single item in a batch (PRD-064)
 type BatchItemResult struct {
-	Index        int             `json:"index"`
-	ID           string          `json:"id,omitempty"`
-	Status       BatchItemStatus `json:"status"`
-	Data         map[string]any  `json:"data,omitempty"`
-	ErrorCode    string          `json:"error_code,omitempty"`
-	ErrorMessage string          `json:"error_message,omitempty"`
+	Index        int                    `json:"index"`
+	ID           string                 `json:"id,omitempty"`
+	Status       BatchItemStatus        `json:"status"`
+	Data         map[string]any         `json:"data,omitempty"`
+	ErrorCode    apperrors.ErrorCode    `json:"error_code,omitempty"`
+	ErrorMessage string                 `json:"error_message,omitempty"`
+	CurrentRev   *int64                 `json:"current_rev,omitempty"` // stored revision on conflict
+	Field        string                 `json:"field,omitempty"`       // duplicated field of a unique violation
+	Changed      map[string]FieldChange `json:"changed,omitzero"`      // changes of an update with ?return=diff
+	Link         string                 `json:"link,omitempty"`        // URL of a created record
 }
 
 // BatchSummary represents summary statistics for a batch operation (PRD-064)
@@ -146,8 +204,9 @@ type BatchCreateResponse struct {
 
 // BatchUpdateResponse represents response for successful batch update operation (PRD-064)
 type BatchUpdateResponse struct {
-	Data    []map[string]any `json:"data"`
-	Message string           `json:"message"`
+	Data    []map[string]any         `json:"data"`
+	Changed []map[string]FieldChange `json:"changed,omitempty"` // with ?return=diff, one per record of data
+	Message string                   `json:"message"`
 }
 
 // BatchDestroyResponse represents response for successful batch destroy operation (PRD-064)
@@ -157,157 +216,361 @@ type BatchDestroyResponse struct {
 
 // List handles GET /{name}:list
 func (h *DataHandler) List(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+	if err := checkQueryParams(r, h.config.API.StrictQueryParams, collection, constants.ListQueryParams, constants.SearchQueryParams); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	lq, err := h.parseListQuery(r, collectionName, collection)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
+	h.list(w, r, collectionName, collection, lq)
+}
 
+// parseListQuery reads the paging, filter and response parameters of :list
+// from the URL. Errors are *apperrors.APIError values.
+func (h *DataHandler) parseListQuery(r *http.Request, collectionName string, collection *registry.Collection) (listQuery, error) {
 	// Parse query parameters
 	limitStr := r.URL.Query().Get(constants.QueryParamLimit)
-	after := r.URL.Query().Get("after") // ULID cursor
 
 	// Parse and validate limit (PRD-046)
-	limit := constants.DefaultPaginationLimit
+	limit, _ := pageLimits(collection)
 	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil {
 			limit = l
 		}
 	}
+	if err := validatePageLimit(limit, logicalName(r, collectionName), collection); err != nil {
+		return listQuery{}, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+	}
+
+	// Build conditions from the filter parameters
+	conditions, err := parseFilterConditions(r, collection)
+	if err != nil {
+		return listQuery{}, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidFilter, err.Error())
+	}
+
+	includeTotal := h.config.API.IncludeTotal()
+	if includeTotal, err = QueryBoolean(r, "total", includeTotal); err != nil {
+		return listQuery{}, err
+	}
+
+	// Numbered pages (?page=&per_page=) replace the cursor; the records are
+	// always counted so that total_pages can be reported
+	after := r.URL.Query().Get("after") // ULID or cursor token
+	page := 0
+	if r.URL.Query().Has("page") || r.URL.Query().Has("per_page") {
+		if after != "" {
+			return listQuery{}, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, "page and after cannot be combined: use either page numbers or cursor pagination")
+		}
+		if page, limit, err = parsePageParams(r, limit, h.config.API.MaxPageOffset, collectionName, collection); err != nil {
+			return listQuery{}, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		}
+		includeTotal = true
+	}
+
+	return listQuery{
+		limit:      limit,
+		after:      after,
+		page:       page,
+		conditions: conditions,
+		sort:       queryOrDefault(r, "sort", collection.DefaultSort),
+		fields:     queryOrDefault(r, "fields", collection.DefaultFields),
+		total:      includeTotal,
+		format:     NegotiateListFormat(r),
+		expand:     r.URL.Query().Get("expand"),
+	}, nil
+}
+
+// listQuery holds the parsed inputs of a list: the query string of :list or
+// the JSON body of :query
+type listQuery struct {
+	limit      int
+	after      string
+	page       int // 1-based page number of offset pagination; 0 pages by cursor
+	conditions []query.Condition
+	sort       string // sort in the syntax of the sort parameter
+	fields     string // field list in the syntax of the fields parameter
+	total      bool   // count the matching records
+	format     string // response format; JSON when empty
+	expand     string // reference fields in the syntax of the expand parameter
+}
+
+// pageLimits returns the default and maximum page sizes of list requests on
+// a collection: its pagination override, or the server defaults. A default
+// left unset is capped at the maximum and a maximum left unset is raised to
+// the default.
+func pageLimits(collection *registry.Collection) (defaultLimit, maxLimit int) {
+	defaultLimit, maxLimit = constants.DefaultPaginationLimit, constants.MaxPaginationLimit
+	if collection.Pagination == nil {
+		return defaultLimit, maxLimit
+	}
+	if collection.Pagination.MaxLimit > 0 {
+		maxLimit = collection.Pagination.MaxLimit
+		defaultLimit = min(defaultLimit, maxLimit)
+	}
+	if collection.Pagination.DefaultLimit > 0 {
+		defaultLimit = collection.Pagination.DefaultLimit
+		if collection.Pagination.MaxLimit == 0 {
+			maxLimit = max(maxLimit, defaultLimit)
+		}
+	}
+	return defaultLimit, maxLimit
+}
 
-	// Enforce pagination limits (PRD-046)
+// validatePageLimit enforces the pagination limits of a collection (PRD-046).
+// name is the collection name the caller knows, without a tenant prefix.
+func validatePageLimit(limit int, name string, collection *registry.Collection) error {
 	if limit < constants.MinPageSize {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be at least %d", constants.MinPageSize))
-		return
+		return fmt.Errorf("limit must be at least %d", constants.MinPageSize)
 	}
-	if limit > constants.MaxPaginationLimit {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("limit cannot exceed %d", constants.MaxPaginationLimit))
-		return
+	if _, maxLimit := pageLimits(collection); limit > maxLimit {
+		if collection.Pagination != nil {
+			return fmt.Errorf("limit cannot exceed %d, the max_limit of collection '%s'", maxLimit, name)
+		}
+		return fmt.Errorf("limit cannot exceed %d", maxLimit)
 	}
+	return nil
+}
 
-	// Validate after cursor if provided
-	if after != "" {
-		if err := validateULID(after); err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid cursor: %v", err))
-			return
+// parsePageParams parses ?page= and ?per_page= of offset pagination. The page
+// defaults to 1 and the page size to limit. Pages that would skip more than
+// maxOffset records are refused, as the database still reads every skipped row.
+func parsePageParams(r *http.Request, limit, maxOffset int, collectionName string, collection *registry.Collection) (int, int, error) {
+	page := 1
+	if value := r.URL.Query().Get("page"); value != "" {
+		p, err := strconv.Atoi(value)
+		if err != nil || p < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive integer")
+		}
+		page = p
+	}
+	if value := r.URL.Query().Get("per_page"); value != "" {
+		perPage, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("per_page must be an integer")
 		}
+		limit = perPage
+	}
+	if err := validatePageLimit(limit, logicalName(r, collectionName), collection); err != nil {
+		return 0, 0, fmt.Errorf("per_page: %v", err)
+	}
+	// Compared by division so that huge page numbers cannot overflow the offset
+	if page-1 > maxOffset/limit {
+		return 0, 0, fmt.Errorf("page %d is too deep: offset pagination may skip at most %d records, use cursor pagination (after) instead", page, maxOffset)
 	}
+	return page, limit, nil
+}
 
-	// Parse filters from query parameters
-	filters, err := parseFilters(r)
+// totalPages returns the number of pages of size limit holding total records
+func totalPages(total, limit int) int {
+	return (total + limit - 1) / limit
+}
+
+// listPlan is the SQL a list runs, built from its listQuery without touching
+// the records
+type listPlan struct {
+	masked       map[string]bool   // hidden columns left out of the records
+	countSQL     string            // counts the matching records; empty without a total
+	countArgs    []any             // arguments of countSQL
+	sql          string            // selects the page, plus one record to tell whether there is more
+	args         []any             // arguments of sql
+	sorts        []sortField       // sort columns, ending with the id tie-breaker
+	expand       []registry.Column // reference columns to expand
+	hiddenFields []string          // columns selected for the cursor or expansion only
+	projection   jsonProjection    // json paths selected by dotted fields
+}
+
+// planList builds the count and page queries of a list. Search (q) and
+// include_deleted are read from the URL. Errors are *apperrors.APIError
+// values. Only a cursor whose sort values must be looked up reads the database.
+func (h *DataHandler) planList(r *http.Request, collectionName string, collection *registry.Collection, lq listQuery) (*listPlan, error) {
+	limit, after := lq.limit, lq.after
+
+	// Hidden columns are left out of the records unless include_hidden is allowed
+	masked, err := responseHidden(r, collectionName, collection)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid filter: %v", err))
-		return
+		return nil, err
 	}
 
-	// Build conditions from filters
-	conditions, err := buildConditions(filters, collection)
+	// Hide soft-deleted records unless requested and add the search
+	conditions, err := withSearchFilter(r, collection, withExpiryFilter(collection, withSoftDeleteFilter(r, collection, lq.conditions)))
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
 	}
 
-	// Parse search query
-	searchQuery := r.URL.Query().Get("q")
-	var searchSQL string
-	var searchArgs []any
-	if searchQuery != "" {
-		// Validate search term
-		if len(searchQuery) < 1 {
-			writeError(w, http.StatusBadRequest, "search term must be at least 1 character")
-			return
-		}
+	// The search and filters are compiled once; the count runs on them and the
+	// page query extends them with the cursor
+	dialect := h.db.Dialect()
+	where, args := buildListWhere(conditions, "", nil, dialect)
 
-		// Build search conditions (OR across all text columns)
-		searchSQL, searchArgs = buildSearchConditions(searchQuery, collection, h.db.Dialect())
+	// Count with current filters (PRD-062), unless skipped
+	plan := &listPlan{masked: masked}
+	if lq.total {
+		plan.countSQL = buildCountQuery(collectionName, where, dialect)
+		plan.countArgs = slices.Clone(args)
 	}
 
-	// Create query builder
-	builder := query.NewBuilder(h.db.Dialect())
+	// Parse sort parameters, falling back to the collection's default sort;
+	// the id is appended as a tie-breaker so that page boundaries are deterministic
+	sorts, err := parseSortParam(lq.sort)
+	if err != nil {
+		return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSort, "invalid sort parameter: %v", err)
+	}
+	sorts = paginationSorts(sorts)
 
-	// Calculate total count with current filters (PRD-062)
-	// Must be done BEFORE adding cursor condition
-	ctx := r.Context()
-	var total int
-	if searchSQL != "" {
-		// Count query with search and filters (no cursor)
-		countSQL, countArgs := buildCountQuery(collectionName, conditions, searchSQL, searchArgs, h.db.Dialect())
-		row := h.db.QueryRow(ctx, countSQL, countArgs...)
-		if err := row.Scan(&total); err != nil {
-			// If count fails, default to 0
-			total = 0
-		}
-	} else {
-		// Count query without search (no cursor)
-		countSQL, countArgs := builder.Count(collectionName, conditions)
-		row := h.db.QueryRow(ctx, countSQL, countArgs...)
-		if err := row.Scan(&total); err != nil {
-			// If count fails, default to 0
-			total = 0
+	// The next cursor would carry the values of a hidden sort column
+	for _, sort := range sorts {
+		if masked[sort.column] {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidSort, "sort field '%s' is hidden", sort.column)
 		}
 	}
 
-	// Add cursor condition if provided (AFTER counting)
+	// Build ORDER BY clause
+	orderBy, err := buildOrderBy(sorts, collection, query.NewBuilder(dialect))
+	if err != nil {
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidSort, err.Error())
+	}
+
+	// Add cursor condition if provided (AFTER counting). The comparison follows
+	// the sort direction of each sort column.
 	if after != "" {
-		conditions = append(conditions, query.Condition{
-			Column:   "id",
-			Operator: query.OpGreaterThan,
-			Value:    after,
-		})
+		cursor, err := decodeCursor(after, sorts, collection)
+		if err == nil && cursor.Values == nil && len(sorts) > 1 {
+			err = h.loadCursorValues(r.Context(), collectionName, storageSorts(collection, sorts), cursor)
+		}
+		if err != nil {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidCursor, "invalid cursor: %v", err)
+		}
+
+		var cursorSQL string
+		if len(sorts) == 1 {
+			operator := query.OpGreaterThan
+			if sorts[0].direction == "DESC" {
+				operator = query.OpLessThan
+			}
+			var sb strings.Builder
+			args = query.WriteCondition(&sb, dialect, query.Condition{Column: "id", Operator: operator, Value: cursor.ID}, args)
+			cursorSQL = sb.String()
+		} else {
+			var cursorArgs []any
+			cursorSQL, cursorArgs = buildCursorCondition(storageSorts(collection, sorts), cursor, dialect, len(args)+1)
+			args = append(args, cursorArgs...)
+		}
+		where = appendWhere(where, cursorSQL)
 	}
 
-	// Parse sort parameters
-	sorts, err := parseSort(r)
+	// Parse field selection, falling back to the collection's default fields
+	fields, projection, err := parseFieldsParam(lq.fields, collection, masked)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid sort parameter: %v", err))
-		return
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
 	}
 
-	// Build ORDER BY clause
-	orderBy, err := buildOrderBy(sorts, collection, builder)
+	// Expanded records are nested objects, which CSV cannot hold
+	expand, err := parseExpandParam(r, lq.expand, collection, masked)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+		return nil, err
+	}
+	if len(expand) > 0 && lq.format == ListFormatCSV {
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, "expand is not supported with CSV output")
+	}
+
+	// Sort columns are needed to build the next cursor and expanded columns
+	// to look up the referenced records; those not requested are selected
+	// anyway and removed from the response
+	var hiddenFields []string
+	if fields != nil {
+		needed := make([]string, 0, len(sorts)+len(expand))
+		for _, sort := range sorts {
+			needed = append(needed, sort.column)
+		}
+		for _, col := range expand {
+			needed = append(needed, col.Name)
+		}
+		for _, field := range needed {
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+				hiddenFields = append(hiddenFields, field)
+			}
+		}
+	}
+
+	// Build SELECT query; one extra record tells whether there is more data.
+	// A selected seq field reads the pkid column.
+	offset := 0
+	if lq.page > 0 {
+		offset = (lq.page - 1) * limit
+	}
+	var columns []string
+	for _, field := range fields {
+		columns = append(columns, storageColumn(collection, field))
 	}
+	plan.sql, plan.args = buildListSelect(collectionName, columns, where, args, orderBy, limit+1, offset, dialect)
+	plan.sorts = sorts
+	plan.expand = expand
+	plan.hiddenFields = hiddenFields
+	plan.projection = projection
+	return plan, nil
+}
 
-	// Parse field selection
-	fields, err := parseFields(r, collection)
+// list runs a list query and writes a page of records with the total count
+// and next cursor. Search (q) and include_deleted are read from the URL.
+func (h *DataHandler) list(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, lq listQuery) {
+	limit := lq.limit
+	plan, err := h.planList(r, collectionName, collection, lq)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeAPIError(w, r, err)
 		return
 	}
+	masked, sorts, expand, hiddenFields := plan.masked, plan.sorts, plan.expand, plan.hiddenFields
 
-	// Build SELECT query
-	var sql string
-	var args []any
-	if searchSQL != "" {
-		// Manual query construction with search (OR) and filters (AND)
-		sql, args = buildSearchQueryWithFields(collectionName, fields, conditions, searchSQL, searchArgs, orderBy, limit+1, h.db.Dialect())
-	} else {
-		// Use query builder for non-search queries
-		sql, args = builder.Select(
-			collectionName,
-			fields,
-			conditions,
-			orderBy,
-			limit+1, // Fetch one extra to determine if there's more data
-			0,
-		)
+	// Calculate total count with current filters (PRD-062), unless skipped
+	ctx := r.Context()
+	var total *int
+	if plan.countSQL != "" {
+		count := 0
+		logQuery(ctx, "list count", plan.countSQL, plan.countArgs)
+		if err := h.db.QueryRow(ctx, plan.countSQL, plan.countArgs...).Scan(&count); err != nil {
+			// A canceled or timed-out count fails the request; other failures default to 0
+			if ctx.Err() != nil {
+				writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to count records: %v", err))
+				return
+			}
+			count = 0
+		}
+		total = &count
 	}
 
 	// Execute query
-	rows, err := h.db.Query(ctx, sql, args...)
+	logQuery(ctx, "list", plan.sql, plan.args)
+	rows, err := h.db.Query(ctx, plan.sql, plan.args...)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to query data: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to query data: %v", err))
 		return
 	}
 	defer rows.Close()
 
 	// Parse results
-	data, err := parseRows(rows, collection)
+	dbColumns, err := rows.Columns()
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read columns: %v", err))
+		return
+	}
+	data, err := parseRows(rows, collection, masked)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to parse results: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to parse results: %v", err))
 		return
 	}
 
@@ -317,12 +580,27 @@ func (h *DataHandler) List(w http.ResponseWriter, r *http.Request, collectionNam
 		// More data available, use the ULID of the last returned record as cursor
 		// Truncate to limit first
 		data = data[:limit]
-		// Now get the last item from the returned data
-		lastItem := data[len(data)-1]
-		if ulidVal, ok := lastItem["id"].(string); ok {
-			nextCursor = &ulidVal
+		// Now get the last item from the returned data; numbered pages have no cursor
+		if cursor, ok := encodeCursor(sorts, data[len(data)-1]); ok && lq.page == 0 {
+			nextCursor = &cursor
 		}
 	}
+	if err := h.expandReferences(ctx, data, expand); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to expand references: %v", err))
+		return
+	}
+	// Dotted fields are cut out of their json columns once the cursor is built
+	for _, record := range data {
+		for _, field := range hiddenFields {
+			delete(record, field)
+		}
+		plan.projection.apply(record)
+	}
+
+	if lq.format == ListFormatCSV || lq.format == ListFormatNDJSON {
+		writeListRows(w, r, lq.format, listColumns(dbColumns, collection, masked, hiddenFields), data, total, nextCursor)
+		return
+	}
 
 	// Build response (PRD-062: include total)
 	response := DataListResponse{
@@ -331,95 +609,158 @@ func (h *DataHandler) List(w http.ResponseWriter, r *http.Request, collectionNam
 		NextCursor: nextCursor,
 		Limit:      limit,
 	}
+	if lq.page > 0 && total != nil {
+		pages := totalPages(*total, limit)
+		response.Page = &lq.page
+		response.TotalPages = &pages
+	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // Get handles GET /{name}:get
 func (h *DataHandler) Get(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
 	// Get ID from query parameter (ULID)
 	idStr := r.URL.Query().Get(constants.QueryParamID)
 	if idStr == "" {
-		writeError(w, http.StatusBadRequest, "id parameter is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "id parameter is required")
 		return
 	}
 
 	// Validate ULID format
-	if err := validateULID(idStr); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid id: %v", err))
+	if err := validateRecordID(collection, idStr); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("invalid id: %v", err))
+		return
+	}
+
+	masked, err := responseHidden(r, collectionName, collection)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	expand, err := parseExpandParam(r, r.URL.Query().Get("expand"), collection, masked)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	full, err := fullRecordParam(r)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
 	// Build SELECT query using ULID
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = ?", collectionName)
+	dialect := h.db.Dialect()
+	sqlQuery := fmt.Sprintf("SELECT * FROM %s WHERE id = %s", query.QuoteIdent(dialect, collectionName), query.Placeholder(dialect, 1))
 	args := []any{idStr}
 
-	// Adjust placeholder style based on dialect
-	if h.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("SELECT * FROM %s WHERE id = $1", collectionName)
+	// Hide soft-deleted records unless requested, and expired records
+	if excludeDeleted(r, collection) {
+		sqlQuery += fmt.Sprintf(" AND %s IS NULL", constants.SoftDeleteColumn)
+	}
+	if live, ok := expiry.Live(collection, expiry.Now()); ok {
+		var sb strings.Builder
+		sb.WriteString(sqlQuery + " AND ")
+		args = query.WriteCondition(&sb, dialect, live, args)
+		sqlQuery = sb.String()
 	}
 
 	// Execute query
 	ctx := r.Context()
-	rows, err := h.db.Query(ctx, query, args...)
+	rows, err := h.db.Query(ctx, sqlQuery, args...)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to query data: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to query data: %v", err))
 		return
 	}
 	defer rows.Close()
 
-	// Parse results
-	data, err := parseRows(rows, collection)
+	// Parse results, keeping json documents as read until the record size is known
+	data, err := parseRowsRawJSON(rows, collection, masked)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to parse results: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to parse results: %v", err))
 		return
 	}
 
 	if len(data) == 0 {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("record with id %s not found", idStr))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeRecordNotFound, fmt.Sprintf("record with id %s not found", idStr))
+		return
+	}
+
+	if err := h.expandReferences(ctx, data, expand); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to expand references: %v", err))
 		return
 	}
 
+	// Expose the record revision for If-Match on a later update or destroy
+	if rev, ok := data[0][constants.RevisionColumn].(int64); ok {
+		w.Header().Set(constants.HeaderETag, revisionETag(rev))
+	}
+
 	response := DataGetResponse{
-		Data: data[0],
+		Data:  data[0],
+		Links: h.recordLinks(r, collectionName, idStr),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	// ?full=true streams every json value; otherwise the largest are stubbed
+	// when the record exceeds api.max_record_response_bytes
+	if full {
+		writeStreamedRecord(w, r, response)
+		return
+	}
+	limitRecordSize(response.Data, h.config.API.MaxRecordResponseBytes)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // Create handles POST /{name}:create - supports both single and batch modes (PRD-064)
 func (h *DataHandler) Create(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
 	// Check payload size (PRD-064)
-	if err := h.validatePayloadSize(r); err != nil {
-		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+
+	if key := r.Header.Get(constants.HeaderIdempotencyKey); key != "" && h.idempotency != nil {
+		h.createIdempotent(w, r, collectionName, collection, key)
 		return
 	}
+	h.create(w, r, collectionName, collection)
+}
 
+// create decodes a :create body and creates its single record or batch
+func (h *DataHandler) create(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection) {
 	// Parse request body with raw JSON to detect mode
 	var batchReq BatchCreateDataRequest
 	if err := json.NewDecoder(r.Body).Decode(&batchReq); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
 	// Detect batch vs single mode
 	isBatch, err := detectBatchMode(batchReq.Data)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, err.Error())
 		return
 	}
 
@@ -438,122 +779,156 @@ func (h *DataHandler) Create(w http.ResponseWriter, r *http.Request, collectionN
 func (h *DataHandler) createSingle(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, rawData json.RawMessage) {
 	var data map[string]any
 	if err := json.Unmarshal(rawData, &data); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid data format")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid data format")
+		return
+	}
+	if err := normalizeFieldNames(data); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
 		return
 	}
 
 	// Validate fields against schema
-	if err := validateFields(data, collection); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	if err := h.validateCreateFields(data, collection); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeValidationFailed), err.Error())
+		return
+	}
+	ctx := r.Context()
+	if err := checkReferences(ctx, h.db.Query, h.registry, collection, data, h.db.Dialect()); err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	// Generate ULID for the new record
-	ulid := generateULID()
-
-	// Build INSERT query including ULID
-	columns := []string{"id"}
-	placeholders := []string{}
-	values := []any{ulid}
-	i := 1
+	// Assign the record id and timestamps
+	ulid := newRecordID(collection, data)
+	now := currentTimestamp()
 
-	if h.db.Dialect() == database.DialectPostgres {
-		placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-	} else {
-		placeholders = append(placeholders, "?")
-	}
-	i++
+	// Build INSERT query including ULID and system timestamps
+	query, values := buildInsertQuery(collectionName, collection, data, ulid, now, h.db.Dialect())
 
-	for _, col := range collection.Columns {
-		if val, ok := data[col.Name]; ok {
-			// Field is present in request - use it
-			columns = append(columns, col.Name)
-			if h.db.Dialect() == database.DialectPostgres {
-				placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-			} else {
-				placeholders = append(placeholders, "?")
-			}
-			values = append(values, val)
-			i++
+	// A replayable create stores its response within the insert's transaction
+	exec := h.db.Exec
+	pending := pendingIdempotencyFrom(ctx)
+	var tx *sql.Tx
+	if pending != nil {
+		var err error
+		if tx, err = h.db.BeginTx(ctx); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
+			return
 		}
-		// If field is missing and nullable, let database DEFAULT handle it
-		// If field is missing and not nullable, validation already rejected the request
+		defer tx.Rollback()
+		exec = tx.ExecContext
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		collectionName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
-
 	// Execute insert
-	ctx := r.Context()
-	_, err := h.db.Exec(ctx, query, values...)
+	_, err := exec(ctx, query, values...)
 	if err != nil {
 		// Check for unique constraint violations
-		if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
-			writeError(w, http.StatusConflict, fmt.Sprintf("unique constraint violation: %v", err))
+		if conflict, ok := asUniqueConflict(err, collection, data); ok {
+			writeUniqueConflict(w, r, conflict)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to insert data: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to insert data: %v", err))
 		return
 	}
 
-	// Add ULID to response data (API field name is "id" but value is ULID)
-	responseData := make(map[string]any)
-	responseData["id"] = ulid
-
-	// Include all fields from request
-	for _, col := range collection.Columns {
-		if val, ok := data[col.Name]; ok {
-			responseData[col.Name] = val
-		}
-		// Omitted fields are not included in response - client can query the record to see defaults
-	}
+	// Response data carries the ULID, system timestamps, and request fields
+	responseData := newRecordResponse(collection, data, ulid, now)
 
 	response := CreateDataResponse{
 		Data:    responseData,
+		Links:   h.recordLinks(r, collectionName, ulid),
 		Message: fmt.Sprintf("Record created successfully with id %s", ulid),
 	}
 
-	writeJSON(w, http.StatusCreated, response)
+	if pending != nil {
+		body, ok := h.commitIdempotent(w, r, tx, pending, http.StatusCreated, response)
+		if !ok {
+			return
+		}
+		h.publish(ctx, collectionName, webhook.ActionCreate, []string{ulid}, []map[string]any{responseData})
+		w.Header().Set(constants.HeaderLocation, h.recordPath(r, collectionName, ulid))
+		writeRaw(w, http.StatusCreated, body)
+		return
+	}
+	h.publish(r.Context(), collectionName, webhook.ActionCreate, []string{ulid}, []map[string]any{responseData})
+	w.Header().Set(constants.HeaderLocation, h.recordPath(r, collectionName, ulid))
+	writeResponse(w, r, http.StatusCreated, response)
 }
 
 // createBatch handles batch create operations (PRD-064)
 func (h *DataHandler) createBatch(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, rawData json.RawMessage, atomic bool) {
 	var items []map[string]any
 	if err := json.Unmarshal(rawData, &items); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid batch data format")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid batch data format")
+		return
+	}
+	if err := normalizeItemFieldNames(items); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
 		return
 	}
 
 	// Validate batch size
 	if err := h.validateBatchSize(len(items)); err != nil {
-		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, err.Error())
 		return
 	}
 
 	if len(items) == 0 {
-		writeError(w, http.StatusBadRequest, "batch must contain at least one item")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "batch must contain at least one item")
+		return
+	}
+
+	// Atomic batches are all-or-nothing, so only best-effort ones are journaled
+	journal, err := QueryBoolean(r, "journal", false)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	if journal && (atomic || h.jobs == nil) {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "journal=true applies to best-effort batches (atomic=false)")
 		return
 	}
 
+	// Referenced records are looked up once for the whole batch
 	ctx := r.Context()
+	refErrs, err := referenceErrors(ctx, h.db.Query, h.registry, collection, items, h.db.Dialect())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to check references: %v", err))
+		return
+	}
 
 	if atomic {
 		// Atomic mode: all-or-nothing with transaction
-		h.createBatchAtomic(w, ctx, collectionName, collection, items)
-	} else {
-		// Best-effort mode: partial success
-		h.createBatchBestEffort(w, ctx, collectionName, collection, items)
+		h.createBatchAtomic(w, r, collectionName, collection, items, refErrs)
+		return
+	}
+
+	// A journaled batch records its progress as a job
+	var job *jobs.Job
+	if journal {
+		job = &jobs.Job{Operation: jobs.OperationCreate, Collection: collectionName, Total: len(items), Checksum: jobs.Checksum(rawData)}
+		if err := h.jobs.Start(ctx, job); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+			return
+		}
+		w.Header().Set(constants.HeaderJob, job.ID)
 	}
+
+	// Best-effort mode: partial success
+	h.createBatchBestEffort(w, r, collectionName, collection, items, refErrs, job)
 }
 
 // createBatchAtomic handles atomic batch create with transaction (PRD-064)
-func (h *DataHandler) createBatchAtomic(w http.ResponseWriter, ctx context.Context, collectionName string, collection *registry.Collection, items []map[string]any) {
+func (h *DataHandler) createBatchAtomic(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, items []map[string]any, refErrs map[int]error) {
+	ctx := r.Context()
 	// Validate all items first
 	for idx, item := range items {
-		if err := validateFields(item, collection); err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("validation error at index %d: %v", idx, err))
+		if err := h.validateCreateFields(item, collection); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeValidationFailed), fmt.Sprintf("validation error at index %d: %v", idx, err))
+			return
+		}
+		if err := refErrs[idx]; err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidReference, fmt.Sprintf("validation error at index %d: %v", idx, err))
 			return
 		}
 	}
@@ -561,7 +936,7 @@ func (h *DataHandler) createBatchAtomic(w http.ResponseWriter, ctx context.Conte
 	// Begin transaction
 	tx, err := h.db.BeginTx(ctx)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to begin transaction: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
 		return
 	}
 	defer tx.Rollback()
@@ -569,209 +944,147 @@ func (h *DataHandler) createBatchAtomic(w http.ResponseWriter, ctx context.Conte
 	var createdRecords []map[string]any
 
 	// Insert each item
-	for _, item := range items {
-		ulid := generateULID()
+	ids := newRecordIDs(collection, items)
+	for i, item := range items {
+		ulid := ids[i]
+		now := currentTimestamp()
 
 		// Build INSERT query
-		columns := []string{"id"}
-		placeholders := []string{}
-		values := []any{ulid}
-		i := 1
-
-		if h.db.Dialect() == database.DialectPostgres {
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-		} else {
-			placeholders = append(placeholders, "?")
-		}
-		i++
-
-		for _, col := range collection.Columns {
-			if val, ok := item[col.Name]; ok {
-				// Field is present in request - use it
-				columns = append(columns, col.Name)
-				if h.db.Dialect() == database.DialectPostgres {
-					placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-				} else {
-					placeholders = append(placeholders, "?")
-				}
-				values = append(values, val)
-				i++
-			}
-			// If field is missing and nullable, let database DEFAULT handle it
-			// If field is missing and not nullable, validation already rejected the request
-		}
-
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-			collectionName,
-			strings.Join(columns, ", "),
-			strings.Join(placeholders, ", "))
+		query, values := buildInsertQuery(collectionName, collection, item, ulid, now, h.db.Dialect())
 
 		// Execute insert within transaction
+		logQuery(ctx, "create batch", query, values)
 		_, err := tx.ExecContext(ctx, query, values...)
 		if err != nil {
 			// Check for unique constraint violations
-			if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
-				writeError(w, http.StatusConflict, fmt.Sprintf("unique constraint violation: %v", err))
+			if conflict, ok := asUniqueConflict(err, collection, item); ok {
+				writeUniqueConflict(w, r, conflict)
 				return
 			}
-			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to insert data: %v", err))
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to insert data: %v", err))
 			return
 		}
 
 		// Build response record
-		responseData := make(map[string]any)
-		responseData["id"] = ulid
+		responseData := newRecordResponse(collection, item, ulid, now)
+		createdRecords = append(createdRecords, responseData)
+	}
 
-		// Include all fields from request
-		for _, col := range collection.Columns {
-			if val, ok := item[col.Name]; ok {
-				responseData[col.Name] = val
-			}
-			// Omitted fields are not included in response - client can query the record to see defaults
+	response := BatchCreateResponse{
+		Data:    createdRecords,
+		Message: fmt.Sprintf("%d records created successfully", len(createdRecords)),
+	}
+
+	if pending := pendingIdempotencyFrom(ctx); pending != nil {
+		body, ok := h.commitIdempotent(w, r, tx, pending, http.StatusCreated, response)
+		if !ok {
+			return
 		}
-		createdRecords = append(createdRecords, responseData)
+		h.publish(ctx, collectionName, webhook.ActionCreate, recordIDs(createdRecords), createdRecords)
+		writeRaw(w, http.StatusCreated, body)
+		return
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to commit transaction: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
 		return
 	}
 
-	response := BatchCreateResponse{
-		Data:    createdRecords,
-		Message: fmt.Sprintf("%d records created successfully", len(createdRecords)),
-	}
-
-	writeJSON(w, http.StatusCreated, response)
+	h.publish(r.Context(), collectionName, webhook.ActionCreate, recordIDs(createdRecords), createdRecords)
+	writeResponse(w, r, http.StatusCreated, response)
 }
 
-// createBatchBestEffort handles best-effort batch create (PRD-064)
-func (h *DataHandler) createBatchBestEffort(w http.ResponseWriter, ctx context.Context, collectionName string, collection *registry.Collection, items []map[string]any) {
-	var results []BatchItemResult
-	succeeded := 0
-	failed := 0
+// createBatchBestEffort handles best-effort batch create (PRD-064).
+// With a job, its progress is written every batch.import_chunk_size items.
+func (h *DataHandler) createBatchBestEffort(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, items []map[string]any, refErrs map[int]error, job *jobs.Job) {
+	ctx := r.Context()
+	out := h.newBatchResultWriter(w, ctx, BatchItemCreated)
+	ids := newRecordIDs(collection, items)
+	if job != nil {
+		out.journal = &batchJournal{store: h.jobs, job: job, interval: max(h.config.Batch.ImportChunkSize, 1)}
+	}
 
 	// Process each item independently
 	for idx, item := range items {
 		// Validate item
-		if err := validateFields(item, collection); err != nil {
-			results = append(results, BatchItemResult{
+		if err := h.validateCreateFields(item, collection); err != nil {
+			out.add(BatchItemResult{
 				Index:        idx,
 				Status:       BatchItemFailed,
-				ErrorCode:    "validation_error",
+				ErrorCode:    apperrors.CodeOf(err, apperrors.CodeValidationFailed),
 				ErrorMessage: err.Error(),
 			})
-			failed++
 			continue
 		}
-
-		ulid := generateULID()
-
-		// Build INSERT query
-		columns := []string{"id"}
-		placeholders := []string{}
-		values := []any{ulid}
-		i := 1
-
-		if h.db.Dialect() == database.DialectPostgres {
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-		} else {
-			placeholders = append(placeholders, "?")
+		if err := refErrs[idx]; err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeInvalidReference,
+				ErrorMessage: err.Error(),
+			})
+			continue
 		}
-		i++
 
-		for _, col := range collection.Columns {
-			if val, ok := item[col.Name]; ok {
-				// Field is present in request - use it
-				columns = append(columns, col.Name)
-				if h.db.Dialect() == database.DialectPostgres {
-					placeholders = append(placeholders, fmt.Sprintf("$%d", i))
-				} else {
-					placeholders = append(placeholders, "?")
-				}
-				values = append(values, val)
-				i++
-			}
-			// If field is missing and nullable, let database DEFAULT handle it
-			// If field is missing and not nullable, validation already rejected the request
-		}
+		ulid := ids[idx]
+		now := currentTimestamp()
 
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-			collectionName,
-			strings.Join(columns, ", "),
-			strings.Join(placeholders, ", "))
+		// Build INSERT query
+		query, values := buildInsertQuery(collectionName, collection, item, ulid, now, h.db.Dialect())
 
 		// Execute insert
+		logQuery(ctx, "create batch", query, values)
 		_, err := h.db.Exec(ctx, query, values...)
 		if err != nil {
-			// Check for unique constraint violations
-			errorCode := "database_error"
-			if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
-				errorCode = "duplicate"
-			}
-			results = append(results, BatchItemResult{
-				Index:        idx,
-				Status:       BatchItemFailed,
-				ErrorCode:    errorCode,
-				ErrorMessage: err.Error(),
-			})
-			failed++
+			// Unique violations name the duplicated field
+			out.add(uniqueFailure(idx, "", err, collection, item))
 			continue
 		}
 
 		// Build response record
-		responseData := make(map[string]any)
-		responseData["id"] = ulid
-
-		// Include all fields from request
-		for _, col := range collection.Columns {
-			if val, ok := item[col.Name]; ok {
-				responseData[col.Name] = val
-			}
-			// Omitted fields are not included in response - client can query the record to see defaults
-		}
+		responseData := newRecordResponse(collection, item, ulid, now)
 
-		results = append(results, BatchItemResult{
+		out.add(BatchItemResult{
 			Index:  idx,
 			ID:     ulid,
 			Status: BatchItemCreated,
 			Data:   responseData,
+			Link:   h.recordLink(r, collectionName, ulid),
 		})
-		succeeded++
 	}
 
-	response := BatchResponse{
-		Results: results,
-		Summary: BatchSummary{
-			Total:     len(items),
-			Succeeded: succeeded,
-			Failed:    failed,
-		},
-	}
-
-	writeJSON(w, http.StatusMultiStatus, response)
+	h.publishResults(collectionName, webhook.ActionCreate, out.close(), BatchItemCreated)
 }
 
 // Update handles POST /{name}:update
 func (h *DataHandler) Update(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
 	// Validate collection exists in registry
 	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
 	// Check payload size (PRD-064)
-	if err := h.validatePayloadSize(r); err != nil {
-		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+	ret, err := parseUpdateReturn(r, collectionName, collection)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
 	// Read body into buffer for multiple parses
 	var buf bytes.Buffer
 	if _, err := buf.ReadFrom(r.Body); err != nil {
-		writeError(w, http.StatusBadRequest, "failed to read request body")
+		writeBodyError(w, r, err, "failed to read request body")
 		return
 	}
 	bodyBytes := buf.Bytes()
@@ -779,7 +1092,7 @@ func (h *DataHandler) Update(w http.ResponseWriter, r *http.Request, collectionN
 	// Try to detect format: old format has "id" and "data" at root, new format has only "data" field
 	var rawReq map[string]json.RawMessage
 	if err := json.Unmarshal(bodyBytes, &rawReq); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
 		return
 	}
 
@@ -791,114 +1104,114 @@ func (h *DataHandler) Update(w http.ResponseWriter, r *http.Request, collectionN
 		// Old format: {"id": "...", "data": {...}}
 		var req UpdateDataRequest
 		if err := json.Unmarshal(bodyBytes, &req); err != nil {
-			writeError(w, http.StatusBadRequest, "invalid request body")
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
 			return
 		}
-		h.updateSingleLegacy(w, r, collectionName, collection, req)
+		h.updateSingleLegacy(w, r, collectionName, collection, req, ret)
 		return
 	}
 
 	if !hasData {
-		writeError(w, http.StatusBadRequest, "missing data field")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "missing data field")
 		return
 	}
 
 	// New format: detect batch vs single mode
 	isBatch, err := detectBatchMode(dataField)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, err.Error())
 		return
 	}
 
 	if !isBatch {
 		// Single-object mode (backward compatible)
-		h.updateSingle(w, r, collectionName, collection, dataField)
+		h.updateSingle(w, r, collectionName, collection, dataField, rawReq["rev"], ret)
 		return
 	}
 
 	// Batch mode
 	atomic := parseAtomicFlag(r)
-	h.updateBatch(w, r, collectionName, collection, dataField, atomic)
+	h.updateBatch(w, r, collectionName, collection, dataField, atomic, ret)
 }
 
 // updateSingleLegacy handles single-object update in legacy format (backward compatible)
-func (h *DataHandler) updateSingleLegacy(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, req UpdateDataRequest) {
+func (h *DataHandler) updateSingleLegacy(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, req UpdateDataRequest, ret updateReturn) {
 	if req.ID == "" {
-		writeError(w, http.StatusBadRequest, "id is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
+		return
+	}
+	if err := normalizeFieldNames(req.Data); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
 		return
 	}
 
 	// Validate ULID format
-	if err := validateULID(req.ID); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid id: %v", err))
+	if err := validateRecordID(collection, req.ID); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("invalid id: %v", err))
 		return
 	}
 
-	// Validate fields against schema
-	if err := validateFieldsForUpdate(req.Data, collection); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	// Resolve the expected revision (optimistic concurrency)
+	rev, err := singleRevision(r, req.Data, req.Rev)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRevision, err.Error())
+		return
+	}
+	if err := requireRevision(collection, rev); err != nil {
+		writeError(w, r, http.StatusPreconditionRequired, apperrors.CodeRevisionRequired, err.Error())
 		return
 	}
 
-	// Build UPDATE query
-	setClauses := []string{}
-	values := []any{}
-	i := 1
-
-	for _, col := range collection.Columns {
-		if val, ok := req.Data[col.Name]; ok {
-			if h.db.Dialect() == database.DialectPostgres {
-				setClauses = append(setClauses, fmt.Sprintf("%s = $%d", col.Name, i))
-			} else {
-				setClauses = append(setClauses, fmt.Sprintf("%s = ?", col.Name))
-			}
-			values = append(values, val)
-			i++
-		}
+	// Validate fields against schema
+	if err := h.validateUpdateFields(req.Data, collection); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeValidationFailed), err.Error())
+		return
+	}
+	if err := checkReferences(r.Context(), h.db.Query, h.registry, collection, req.Data, h.db.Dialect()); err != nil {
+		writeAPIError(w, r, err)
+		return
 	}
 
+	// Build UPDATE query (explicit nulls become NULL assignments)
+	setClauses, values := buildUpdateSetClauses(req.Data, collection, h.db.Dialect())
+
 	if len(setClauses) == 0 {
-		writeError(w, http.StatusBadRequest, "no fields to update")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "no fields to update")
 		return
 	}
 
-	// Add ULID to values
-	values = append(values, req.ID)
+	// Match the record by ULID and, when given, the expected revision
+	query, values := buildUpdateQuery(collection, setClauses, values, req.ID, rev, h.db.Dialect())
 
-	var query string
-	if h.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d",
-			collectionName,
-			strings.Join(setClauses, ", "),
-			i)
-	} else {
-		query = fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-			collectionName,
-			strings.Join(setClauses, ", "))
+	// A diff compares with the record as it was before the update
+	ctx := r.Context()
+	old, err := ret.before(ctx, h.db.Query, collection, req.ID, h.db.Dialect())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+		return
 	}
 
 	// Execute update
-	ctx := r.Context()
 	result, err := h.db.Exec(ctx, query, values...)
 	if err != nil {
 		// Check for unique constraint violations
-		if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
-			writeError(w, http.StatusConflict, fmt.Sprintf("unique constraint violation: %v", err))
+		if conflict, ok := asUniqueConflict(err, collection, req.Data); ok {
+			writeUniqueConflict(w, r, conflict)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update data: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to update data: %v", err))
 		return
 	}
 
 	// Check if any rows were affected
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get rows affected: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
 		return
 	}
 
 	if rowsAffected == 0 {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("record with id %s not found", req.ID))
+		writeRecordMiss(w, r, h.db.QueryRow, collection, req.ID, rev, h.db.Dialect())
 		return
 	}
 
@@ -909,105 +1222,120 @@ func (h *DataHandler) updateSingleLegacy(w http.ResponseWriter, r *http.Request,
 		responseData[k] = v
 	}
 
+	// A guarded update moved the record to the next revision; return it so the
+	// client can chain further updates
+	if rev != nil {
+		responseData[constants.RevisionColumn] = *rev + 1
+		w.Header().Set(constants.HeaderETag, revisionETag(*rev+1))
+	}
+	// The record is updated even when it cannot be read back for the response
+	responseData, changed, err := ret.after(ctx, h.db.Query, collection, req.ID, req.Data, responseData, old, h.db.Dialect())
+	if err != nil {
+		logging.Warnf("update: %v", err)
+	}
+
 	response := UpdateDataResponse{
 		Data:    responseData,
+		Changed: changed,
+		Links:   h.recordLinks(r, collectionName, req.ID),
 		Message: fmt.Sprintf("Record %s updated successfully", req.ID),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	h.publish(r.Context(), collectionName, webhook.ActionUpdate, []string{req.ID}, []map[string]any{responseData})
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // updateSingle handles single-object update in new format (backward compatible)
-func (h *DataHandler) updateSingle(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, rawData json.RawMessage) {
+func (h *DataHandler) updateSingle(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, rawData json.RawMessage, bodyRev json.RawMessage, ret updateReturn) {
 	var item map[string]any
 	if err := json.Unmarshal(rawData, &item); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid data format")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid data format")
+		return
+	}
+	if err := normalizeFieldNames(item); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
 		return
 	}
 
 	// Check for id field
 	idVal, hasID := item["id"]
 	if !hasID {
-		writeError(w, http.StatusBadRequest, "id is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 	id, ok := idVal.(string)
 	if !ok {
-		writeError(w, http.StatusBadRequest, "id must be a string")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidType, "id must be a string")
 		return
 	}
 
 	// Validate ULID format
-	if err := validateULID(id); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid id: %v", err))
+	if err := validateRecordID(collection, id); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("invalid id: %v", err))
 		return
 	}
 
-	// Validate fields against schema
-	if err := validateFieldsForUpdate(item, collection); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	// Resolve the expected revision (optimistic concurrency)
+	rev, err := singleRevision(r, item, bodyRev)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRevision, err.Error())
+		return
+	}
+	if err := requireRevision(collection, rev); err != nil {
+		writeError(w, r, http.StatusPreconditionRequired, apperrors.CodeRevisionRequired, err.Error())
 		return
 	}
 
-	// Build UPDATE query
-	setClauses := []string{}
-	values := []any{}
-	i := 1
-
-	for _, col := range collection.Columns {
-		if val, ok := item[col.Name]; ok {
-			if h.db.Dialect() == database.DialectPostgres {
-				setClauses = append(setClauses, fmt.Sprintf("%s = $%d", col.Name, i))
-			} else {
-				setClauses = append(setClauses, fmt.Sprintf("%s = ?", col.Name))
-			}
-			values = append(values, val)
-			i++
-		}
+	// Validate fields against schema
+	if err := h.validateUpdateFields(item, collection); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeValidationFailed), err.Error())
+		return
+	}
+	if err := checkReferences(r.Context(), h.db.Query, h.registry, collection, item, h.db.Dialect()); err != nil {
+		writeAPIError(w, r, err)
+		return
 	}
 
+	// Build UPDATE query (explicit nulls become NULL assignments)
+	setClauses, values := buildUpdateSetClauses(item, collection, h.db.Dialect())
+
 	if len(setClauses) == 0 {
-		writeError(w, http.StatusBadRequest, "no fields to update")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "no fields to update")
 		return
 	}
 
-	// Add ULID to values
-	values = append(values, id)
+	// Match the record by ULID and, when given, the expected revision
+	query, values := buildUpdateQuery(collection, setClauses, values, id, rev, h.db.Dialect())
 
-	var query string
-	if h.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d",
-			collectionName,
-			strings.Join(setClauses, ", "),
-			i)
-	} else {
-		query = fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-			collectionName,
-			strings.Join(setClauses, ", "))
+	// A diff compares with the record as it was before the update
+	ctx := r.Context()
+	old, err := ret.before(ctx, h.db.Query, collection, id, h.db.Dialect())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+		return
 	}
 
 	// Execute update
-	ctx := r.Context()
 	result, err := h.db.Exec(ctx, query, values...)
 	if err != nil {
 		// Check for unique constraint violations
-		if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
-			writeError(w, http.StatusConflict, fmt.Sprintf("unique constraint violation: %v", err))
+		if conflict, ok := asUniqueConflict(err, collection, item); ok {
+			writeUniqueConflict(w, r, conflict)
 			return
 		}
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update data: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to update data: %v", err))
 		return
 	}
 
 	// Check if any rows were affected
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get rows affected: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
 		return
 	}
 
 	if rowsAffected == 0 {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("record with id %s not found", id))
+		writeRecordMiss(w, r, h.db.QueryRow, collection, id, rev, h.db.Dialect())
 		return
 	}
 
@@ -1020,66 +1348,107 @@ func (h *DataHandler) updateSingle(w http.ResponseWriter, r *http.Request, colle
 		}
 	}
 
+	// A guarded update moved the record to the next revision; return it so the
+	// client can chain further updates
+	if rev != nil {
+		responseData[constants.RevisionColumn] = *rev + 1
+		w.Header().Set(constants.HeaderETag, revisionETag(*rev+1))
+	}
+	// The record is updated even when it cannot be read back for the response
+	responseData, changed, err := ret.after(ctx, h.db.Query, collection, id, item, responseData, old, h.db.Dialect())
+	if err != nil {
+		logging.Warnf("update: %v", err)
+	}
+
 	response := UpdateDataResponse{
 		Data:    responseData,
+		Changed: changed,
+		Links:   h.recordLinks(r, collectionName, id),
 		Message: fmt.Sprintf("Record %s updated successfully", id),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	h.publish(r.Context(), collectionName, webhook.ActionUpdate, []string{id}, []map[string]any{responseData})
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // updateBatch handles batch update operations (PRD-064)
-func (h *DataHandler) updateBatch(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, rawData json.RawMessage, atomic bool) {
+func (h *DataHandler) updateBatch(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, rawData json.RawMessage, atomic bool, ret updateReturn) {
 	var items []map[string]any
 	if err := json.Unmarshal(rawData, &items); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid batch data format")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid batch data format")
+		return
+	}
+	if err := normalizeItemFieldNames(items); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
 		return
 	}
 
 	// Validate batch size
 	if err := h.validateBatchSize(len(items)); err != nil {
-		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, err.Error())
 		return
 	}
 
 	if len(items) == 0 {
-		writeError(w, http.StatusBadRequest, "batch must contain at least one item")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "batch must contain at least one item")
 		return
 	}
 
+	// Referenced records are looked up once for the whole batch
 	ctx := r.Context()
+	refErrs, err := referenceErrors(ctx, h.db.Query, h.registry, collection, items, h.db.Dialect())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to check references: %v", err))
+		return
+	}
 
 	if atomic {
 		// Atomic mode: all-or-nothing with transaction
-		h.updateBatchAtomic(w, ctx, collectionName, collection, items)
+		h.updateBatchAtomic(w, r, collectionName, collection, items, refErrs, ret)
 	} else {
 		// Best-effort mode: partial success
-		h.updateBatchBestEffort(w, ctx, collectionName, collection, items)
+		h.updateBatchBestEffort(w, ctx, collectionName, collection, items, refErrs, ret)
 	}
 }
 
 // updateBatchAtomic handles atomic batch update with transaction (PRD-064)
-func (h *DataHandler) updateBatchAtomic(w http.ResponseWriter, ctx context.Context, collectionName string, collection *registry.Collection, items []map[string]any) {
+func (h *DataHandler) updateBatchAtomic(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, items []map[string]any, refErrs map[int]error, ret updateReturn) {
+	ctx := r.Context()
 	// Validate all items first
+	revs := make([]*int64, len(items))
 	for idx, item := range items {
 		// Check for id field
 		idVal, hasID := item["id"]
 		if !hasID {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("validation error at index %d: id is required", idx))
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, fmt.Sprintf("validation error at index %d: id is required", idx))
 			return
 		}
 		id, ok := idVal.(string)
 		if !ok {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("validation error at index %d: id must be a string", idx))
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidType, fmt.Sprintf("validation error at index %d: id must be a string", idx))
 			return
 		}
 		// Validate ULID format
-		if err := validateULID(id); err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("validation error at index %d: invalid id: %v", idx, err))
+		if err := validateRecordID(collection, id); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("validation error at index %d: invalid id: %v", idx, err))
+			return
+		}
+		rev, err := takeItemRevision(item)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRevision, fmt.Sprintf("validation error at index %d: %v", idx, err))
+			return
+		}
+		if err := requireRevision(collection, rev); err != nil {
+			writeError(w, r, http.StatusPreconditionRequired, apperrors.CodeRevisionRequired, fmt.Sprintf("validation error at index %d: %v", idx, err))
 			return
 		}
-		if err := validateFieldsForUpdate(item, collection); err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("validation error at index %d: %v", idx, err))
+		revs[idx] = rev
+		if err := h.validateUpdateFields(item, collection); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeValidationFailed), fmt.Sprintf("validation error at index %d: %v", idx, err))
+			return
+		}
+		if err := refErrs[idx]; err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidReference, fmt.Sprintf("validation error at index %d: %v", idx, err))
 			return
 		}
 	}
@@ -1087,75 +1456,58 @@ func (h *DataHandler) updateBatchAtomic(w http.ResponseWriter, ctx context.Conte
 	// Begin transaction
 	tx, err := h.db.BeginTx(ctx)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to begin transaction: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
 		return
 	}
 	defer tx.Rollback()
 
 	var updatedRecords []map[string]any
+	var changes []map[string]FieldChange
 
 	// Update each item
-	for _, item := range items {
+	for idx, item := range items {
 		id := item["id"].(string)
+		rev := revs[idx]
 
-		// Build UPDATE query
-		setClauses := []string{}
-		values := []any{}
-		i := 1
-
-		for _, col := range collection.Columns {
-			if val, ok := item[col.Name]; ok {
-				if h.db.Dialect() == database.DialectPostgres {
-					setClauses = append(setClauses, fmt.Sprintf("%s = $%d", col.Name, i))
-				} else {
-					setClauses = append(setClauses, fmt.Sprintf("%s = ?", col.Name))
-				}
-				values = append(values, val)
-				i++
-			}
-		}
+		// Build UPDATE query (explicit nulls become NULL assignments)
+		setClauses, values := buildUpdateSetClauses(item, collection, h.db.Dialect())
 
 		if len(setClauses) == 0 {
-			writeError(w, http.StatusBadRequest, "no fields to update")
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "no fields to update")
 			return
 		}
 
-		// Add ULID to values
-		values = append(values, id)
+		// Match the record by ULID and, when given, the expected revision
+		query, values := buildUpdateQuery(collection, setClauses, values, id, rev, h.db.Dialect())
 
-		var query string
-		if h.db.Dialect() == database.DialectPostgres {
-			query = fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d",
-				collectionName,
-				strings.Join(setClauses, ", "),
-				i)
-		} else {
-			query = fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-				collectionName,
-				strings.Join(setClauses, ", "))
+		old, err := ret.before(ctx, tx.QueryContext, collection, id, h.db.Dialect())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+			return
 		}
 
 		// Execute update within transaction
+		logQuery(ctx, "update batch", query, values)
 		result, err := tx.ExecContext(ctx, query, values...)
 		if err != nil {
 			// Check for unique constraint violations
-			if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
-				writeError(w, http.StatusConflict, fmt.Sprintf("unique constraint violation: %v", err))
+			if conflict, ok := asUniqueConflict(err, collection, item); ok {
+				writeUniqueConflict(w, r, conflict)
 				return
 			}
-			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to update data: %v", err))
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to update data: %v", err))
 			return
 		}
 
 		// Check if any rows were affected
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get rows affected: %v", err))
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
 			return
 		}
 
 		if rowsAffected == 0 {
-			writeError(w, http.StatusNotFound, fmt.Sprintf("record with id %s not found", id))
+			writeRecordMiss(w, r, tx.QueryRowContext, collection, id, rev, h.db.Dialect())
 			return
 		}
 
@@ -1167,165 +1519,172 @@ func (h *DataHandler) updateBatchAtomic(w http.ResponseWriter, ctx context.Conte
 				responseData[k] = v
 			}
 		}
+		if rev != nil {
+			responseData[constants.RevisionColumn] = *rev + 1
+		}
+		responseData, changed, err := ret.after(ctx, tx.QueryContext, collection, id, item, responseData, old, h.db.Dialect())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+			return
+		}
 		updatedRecords = append(updatedRecords, responseData)
+		if changed != nil {
+			changes = append(changes, changed)
+		}
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to commit transaction: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
 		return
 	}
 
 	response := BatchUpdateResponse{
 		Data:    updatedRecords,
+		Changed: changes,
 		Message: fmt.Sprintf("%d records updated successfully", len(updatedRecords)),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	h.publish(r.Context(), collectionName, webhook.ActionUpdate, recordIDs(updatedRecords), updatedRecords)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // updateBatchBestEffort handles best-effort batch update (PRD-064)
-func (h *DataHandler) updateBatchBestEffort(w http.ResponseWriter, ctx context.Context, collectionName string, collection *registry.Collection, items []map[string]any) {
-	var results []BatchItemResult
-	succeeded := 0
-	failed := 0
+func (h *DataHandler) updateBatchBestEffort(w http.ResponseWriter, ctx context.Context, collectionName string, collection *registry.Collection, items []map[string]any, refErrs map[int]error, ret updateReturn) {
+	out := h.newBatchResultWriter(w, ctx, BatchItemUpdated)
 
 	// Process each item independently
 	for idx, item := range items {
 		// Check for id field
 		idVal, hasID := item["id"]
 		if !hasID {
-			results = append(results, BatchItemResult{
+			out.add(BatchItemResult{
 				Index:        idx,
 				Status:       BatchItemFailed,
-				ErrorCode:    "validation_error",
+				ErrorCode:    apperrors.CodeRequiredField,
 				ErrorMessage: "id is required",
 			})
-			failed++
 			continue
 		}
 		id, ok := idVal.(string)
 		if !ok {
-			results = append(results, BatchItemResult{
+			out.add(BatchItemResult{
 				Index:        idx,
 				Status:       BatchItemFailed,
-				ErrorCode:    "validation_error",
+				ErrorCode:    apperrors.CodeInvalidType,
 				ErrorMessage: "id must be a string",
 			})
-			failed++
 			continue
 		}
 		// Validate ULID format
-		if err := validateULID(id); err != nil {
-			results = append(results, BatchItemResult{
+		if err := validateRecordID(collection, id); err != nil {
+			out.add(BatchItemResult{
 				Index:        idx,
 				Status:       BatchItemFailed,
-				ErrorCode:    "validation_error",
+				ErrorCode:    apperrors.CodeOf(err, apperrors.CodeInvalidULID),
 				ErrorMessage: fmt.Sprintf("invalid id: %v", err),
 			})
-			failed++
 			continue
 		}
 
-		// Validate item
-		if err := validateFieldsForUpdate(item, collection); err != nil {
-			results = append(results, BatchItemResult{
+		// Take the expected revision out of the item before validation
+		rev, err := takeItemRevision(item)
+		if err != nil {
+			out.add(BatchItemResult{
 				Index:        idx,
 				ID:           id,
 				Status:       BatchItemFailed,
-				ErrorCode:    "validation_error",
+				ErrorCode:    apperrors.CodeInvalidRevision,
+				ErrorMessage: err.Error(),
+			})
+			continue
+		}
+		if err := requireRevision(collection, rev); err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeRevisionRequired,
 				ErrorMessage: err.Error(),
 			})
-			failed++
 			continue
 		}
 
-		// Build UPDATE query
-		setClauses := []string{}
-		values := []any{}
-		i := 1
-
-		for _, col := range collection.Columns {
-			if val, ok := item[col.Name]; ok {
-				if h.db.Dialect() == database.DialectPostgres {
-					setClauses = append(setClauses, fmt.Sprintf("%s = $%d", col.Name, i))
-				} else {
-					setClauses = append(setClauses, fmt.Sprintf("%s = ?", col.Name))
-				}
-				values = append(values, val)
-				i++
-			}
+		// Validate item
+		if err := h.validateUpdateFields(item, collection); err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeOf(err, apperrors.CodeValidationFailed),
+				ErrorMessage: err.Error(),
+			})
+			continue
+		}
+		if err := refErrs[idx]; err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeInvalidReference,
+				ErrorMessage: err.Error(),
+			})
+			continue
 		}
 
+		// Build UPDATE query (explicit nulls become NULL assignments)
+		setClauses, values := buildUpdateSetClauses(item, collection, h.db.Dialect())
+
 		if len(setClauses) == 0 {
-			results = append(results, BatchItemResult{
+			out.add(BatchItemResult{
 				Index:        idx,
 				ID:           id,
 				Status:       BatchItemFailed,
-				ErrorCode:    "validation_error",
+				ErrorCode:    apperrors.CodeInvalidInput,
 				ErrorMessage: "no fields to update",
 			})
-			failed++
 			continue
 		}
 
-		// Add ULID to values
-		values = append(values, id)
-
-		var query string
-		if h.db.Dialect() == database.DialectPostgres {
-			query = fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d",
-				collectionName,
-				strings.Join(setClauses, ", "),
-				i)
-		} else {
-			query = fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-				collectionName,
-				strings.Join(setClauses, ", "))
-		}
+		// Match the record by ULID and, when given, the expected revision
+		query, values := buildUpdateQuery(collection, setClauses, values, id, rev, h.db.Dialect())
 
-		// Execute update
-		result, err := h.db.Exec(ctx, query, values...)
+		old, err := ret.before(ctx, h.db.Query, collection, id, h.db.Dialect())
 		if err != nil {
-			// Check for unique constraint violations
-			errorCode := "database_error"
-			if strings.Contains(err.Error(), "UNIQUE") || strings.Contains(err.Error(), "unique") {
-				errorCode = "duplicate"
-			}
-			results = append(results, BatchItemResult{
+			out.add(BatchItemResult{
 				Index:        idx,
 				ID:           id,
 				Status:       BatchItemFailed,
-				ErrorCode:    errorCode,
+				ErrorCode:    apperrors.CodeDatabaseError,
 				ErrorMessage: err.Error(),
 			})
-			failed++
+			continue
+		}
+
+		// Execute update
+		logQuery(ctx, "update batch", query, values)
+		result, err := h.db.Exec(ctx, query, values...)
+		if err != nil {
+			// Unique violations name the duplicated field
+			out.add(uniqueFailure(idx, id, err, collection, item))
 			continue
 		}
 
 		// Check if any rows were affected
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
-			results = append(results, BatchItemResult{
+			out.add(BatchItemResult{
 				Index:        idx,
 				ID:           id,
 				Status:       BatchItemFailed,
-				ErrorCode:    "database_error",
+				ErrorCode:    apperrors.CodeDatabaseError,
 				ErrorMessage: fmt.Sprintf("failed to get rows affected: %v", err),
 			})
-			failed++
 			continue
 		}
 
 		if rowsAffected == 0 {
-			results = append(results, BatchItemResult{
-				Index:        idx,
-				ID:           id,
-				Status:       BatchItemNotFound,
-				ErrorCode:    "not_found",
-				ErrorMessage: fmt.Sprintf("record with id %s not found", id),
-			})
-			failed++
+			out.add(recordMissResult(ctx, h.db.QueryRow, collection, idx, id, rev, h.db.Dialect()))
 			continue
 		}
 
@@ -1338,46 +1697,179 @@ func (h *DataHandler) updateBatchBestEffort(w http.ResponseWriter, ctx context.C
 			}
 		}
 
-		results = append(results, BatchItemResult{
-			Index:  idx,
-			ID:     id,
-			Status: BatchItemUpdated,
-			Data:   responseData,
+		if rev != nil {
+			responseData[constants.RevisionColumn] = *rev + 1
+		}
+		// The record is updated even when it cannot be read back for the response
+		responseData, changed, err := ret.after(ctx, h.db.Query, collection, id, item, responseData, old, h.db.Dialect())
+		if err != nil {
+			logging.Warnf("update batch: %v", err)
+		}
+
+		out.add(BatchItemResult{
+			Index:   idx,
+			ID:      id,
+			Status:  BatchItemUpdated,
+			Data:    responseData,
+			Changed: changed,
 		})
-		succeeded++
 	}
 
-	response := BatchResponse{
-		Results: results,
-		Summary: BatchSummary{
-			Total:     len(items),
-			Succeeded: succeeded,
-			Failed:    failed,
-		},
+	h.publishResults(collectionName, webhook.ActionUpdate, out.close(), BatchItemUpdated)
+}
+
+// buildUpdateSetClauses builds the SET clause fragments and bound values for an UPDATE.
+// Only columns present in data are included; a JSON null for a column produces
+// "column = NULL" rather than a bound parameter. Nullability is enforced earlier
+// by validateFieldsForUpdate. Computed columns reading a changed column are
+// recomputed. When any column changes, updated_at is bumped to the current
+// UTC time and _rev is incremented; an empty data map still yields no clauses.
+func buildUpdateSetClauses(data map[string]any, collection *registry.Collection, dialect database.DialectType) ([]string, []any) {
+	setClauses := []string{}
+	values := []any{}
+
+	for _, col := range collection.Columns {
+		val, ok := data[col.Name]
+		if !ok {
+			continue
+		}
+		if val == nil {
+			setClauses = append(setClauses, fmt.Sprintf("%s = NULL", query.QuoteIdent(dialect, col.Name)))
+			continue
+		}
+		values = append(values, columnValue(col, val))
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s", query.QuoteIdent(dialect, col.Name), query.Placeholder(dialect, len(values))))
+	}
+	computed, values := computedAssignments(collection, data, values, dialect)
+	setClauses = append(setClauses, computed...)
+
+	if len(setClauses) > 0 {
+		values = append(values, currentTimestamp())
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s", constants.UpdatedAtColumn, query.Placeholder(dialect, len(values))))
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s + 1", constants.RevisionColumn, constants.RevisionColumn))
+	}
+
+	return setClauses, values
+}
+
+// buildUpdateQuery builds the UPDATE statement for one record from the SET clauses
+// of buildUpdateSetClauses. Soft-deleted records do not match, so they are
+// neither changed nor revived by an update. A non-nil rev restricts the update
+// to that revision.
+func buildUpdateQuery(collection *registry.Collection, setClauses []string, values []any, id string, rev *int64, dialect database.DialectType) (string, []any) {
+	values = append(values, id)
+	sqlQuery := fmt.Sprintf("UPDATE %s SET %s WHERE id = %s",
+		query.QuoteIdent(dialect, collection.Name),
+		strings.Join(setClauses, ", "),
+		query.Placeholder(dialect, len(values)))
+	if collection.SoftDelete {
+		sqlQuery += fmt.Sprintf(" AND %s IS NULL", constants.SoftDeleteColumn)
+	}
+	condition, values := revisionCondition(rev, values, dialect)
+	return sqlQuery + condition, values
+}
+
+// buildInsertQuery builds the INSERT statement for a new record. The id and the
+// created_at/updated_at system timestamps are always written; user columns are
+// written when present in data, and omitted columns with a default are bound
+// to that default so every dialect stores the same value (validation has
+// already rejected missing required fields). Computed columns are written
+// with their value computed from the others.
+func buildInsertQuery(collectionName string, collection *registry.Collection, data map[string]any, id string, now string, dialect database.DialectType) (string, []any) {
+	columns := []string{"id", constants.CreatedAtColumn, constants.UpdatedAtColumn}
+	values := []any{id, now, now}
+	placeholders := []string{query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3)}
+
+	for _, col := range collection.Columns {
+		if val, ok := insertValue(collection, col, data); ok {
+			columns = append(columns, query.QuoteIdent(dialect, col.Name))
+			values = append(values, columnValue(col, val))
+			placeholders = append(placeholders, query.Placeholder(dialect, len(values)))
+		}
+	}
+
+	sqlQuery := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		query.QuoteIdent(dialect, collectionName),
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "))
+	return sqlQuery, values
+}
+
+// newRecordResponse builds the response data for a newly inserted record,
+// including the defaults and computed values buildInsertQuery applied
+func newRecordResponse(collection *registry.Collection, data map[string]any, id string, now string) map[string]any {
+	responseData := map[string]any{
+		"id":                      id,
+		constants.CreatedAtColumn: now,
+		constants.UpdatedAtColumn: now,
+		constants.RevisionColumn:  int64(1),
+	}
+	for _, col := range collection.Columns {
+		if val, ok := insertValue(collection, col, data); ok {
+			responseData[col.Name] = val
+		}
+	}
+	return responseData
+}
+
+// insertDefault returns the value an insert applies to an omitted nullable
+// column with a default. Defaults are kept as SQL literals ('0.00', '{}', NULL),
+// so the quotes are stripped before getDefaultValue parses the value
+// for the column type, and NULL binds a real NULL rather than the text "NULL".
+func insertDefault(col registry.Column) (any, bool) {
+	if !col.Nullable || col.DefaultValue == nil {
+		return nil, false
+	}
+	literal := *col.DefaultValue
+	if strings.EqualFold(literal, "null") {
+		return nil, true
+	}
+	if unquoted, ok := unquoteSQLString(literal); ok {
+		if col.Type == registry.TypeString {
+			return unquoted, true
+		}
+		literal = unquoted
+	}
+	col.DefaultValue = &literal
+	value := getDefaultValue(col)
+	if col.Type == registry.TypeDecimal {
+		value = formatDecimal(value)
+	}
+	return value, true
+}
+
+// unquoteSQLString returns the content of a single-quoted SQL string literal
+func unquoteSQLString(literal string) (string, bool) {
+	if len(literal) < 2 || literal[0] != '\'' || literal[len(literal)-1] != '\'' {
+		return literal, false
 	}
+	return strings.ReplaceAll(literal[1:len(literal)-1], "''", "'"), true
+}
 
-	writeJSON(w, http.StatusMultiStatus, response)
+// currentTimestamp returns the current UTC time in the RFC3339 format used for system timestamps
+func currentTimestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
 }
 
 // Destroy handles POST /{name}:destroy
 func (h *DataHandler) Destroy(w http.ResponseWriter, r *http.Request, collectionName string) {
 	// Validate collection exists in registry
-	_, exists := h.registry.Get(collectionName)
+	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("collection '%s' not found", collectionName))
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
 		return
 	}
 
 	// Check payload size (PRD-064)
-	if err := h.validatePayloadSize(r); err != nil {
-		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
 		return
 	}
 
 	// Read body into buffer for multiple parses
 	var buf bytes.Buffer
 	if _, err := buf.ReadFrom(r.Body); err != nil {
-		writeError(w, http.StatusBadRequest, "failed to read request body")
+		writeBodyError(w, r, err, "failed to read request body")
 		return
 	}
 	bodyBytes := buf.Bytes()
@@ -1385,7 +1877,7 @@ func (h *DataHandler) Destroy(w http.ResponseWriter, r *http.Request, collection
 	// Try to detect format: old format has "id" at root, new format has "data" field
 	var rawReq map[string]json.RawMessage
 	if err := json.Unmarshal(bodyBytes, &rawReq); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
 		return
 	}
 
@@ -1393,26 +1885,41 @@ func (h *DataHandler) Destroy(w http.ResponseWriter, r *http.Request, collection
 	_, hasID := rawReq["id"]
 	dataField, hasData := rawReq["data"]
 
+	// Destroy by filter: {"where": {...}}
+	if where, hasWhere := rawReq["where"]; hasWhere {
+		if hasID || hasData {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "where cannot be combined with id or data")
+			return
+		}
+		h.destroyWhere(w, r, collection, where)
+		return
+	}
+
 	if hasID && !hasData {
 		// Old format: {"id": "..."}
 		var req DestroyDataRequest
 		if err := json.Unmarshal(bodyBytes, &req); err != nil {
-			writeError(w, http.StatusBadRequest, "invalid request body")
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
+			return
+		}
+		rev, err := requestRevision(r, req.Rev)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRevision, err.Error())
 			return
 		}
-		h.destroySingleLegacy(w, r, collectionName, req)
+		h.destroySingle(w, r, collection, req.ID, rev)
 		return
 	}
 
 	if !hasData {
-		writeError(w, http.StatusBadRequest, "missing data field")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "missing data field")
 		return
 	}
 
 	// New format: detect batch vs single mode (array of IDs)
 	isBatch, err := detectBatchMode(dataField)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, err.Error())
 		return
 	}
 
@@ -1420,106 +1927,61 @@ func (h *DataHandler) Destroy(w http.ResponseWriter, r *http.Request, collection
 		// Single-object mode (backward compatible) - just a string ID
 		var id string
 		if err := json.Unmarshal(dataField, &id); err != nil {
-			writeError(w, http.StatusBadRequest, "invalid data format")
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid data format")
+			return
+		}
+		rev, err := requestRevision(r, rawReq["rev"])
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRevision, err.Error())
 			return
 		}
-		h.destroySingle(w, r, collectionName, id)
+		h.destroySingle(w, r, collection, id, rev)
 		return
 	}
 
 	// Batch mode
 	atomic := parseAtomicFlag(r)
-	h.destroyBatch(w, r, collectionName, dataField, atomic)
-}
-
-// destroySingleLegacy handles single-object destroy in legacy format (backward compatible)
-func (h *DataHandler) destroySingleLegacy(w http.ResponseWriter, r *http.Request, collectionName string, req DestroyDataRequest) {
-	if req.ID == "" {
-		writeError(w, http.StatusBadRequest, "id is required")
-		return
-	}
-
-	// Validate ULID format
-	if err := validateULID(req.ID); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid id: %v", err))
-		return
-	}
-
-	// Build DELETE query using ULID
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", collectionName)
-	args := []any{req.ID}
-
-	// Adjust placeholder style based on dialect
-	if h.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("DELETE FROM %s WHERE id = $1", collectionName)
-	}
-
-	// Execute delete
-	ctx := r.Context()
-	result, err := h.db.Exec(ctx, query, args...)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete data: %v", err))
-		return
-	}
-
-	// Check if any rows were affected
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get rows affected: %v", err))
-		return
-	}
-
-	if rowsAffected == 0 {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("record with id %s not found", req.ID))
-		return
-	}
-
-	response := DestroyDataResponse{
-		Message: fmt.Sprintf("Record %s deleted successfully", req.ID),
-	}
-
-	writeJSON(w, http.StatusOK, response)
+	h.destroyBatch(w, r, collection, dataField, atomic)
 }
 
 // destroySingle handles single-object destroy in new format (backward compatible)
-func (h *DataHandler) destroySingle(w http.ResponseWriter, r *http.Request, collectionName string, id string) {
+func (h *DataHandler) destroySingle(w http.ResponseWriter, r *http.Request, collection *registry.Collection, id string, rev *int64) {
 	if id == "" {
-		writeError(w, http.StatusBadRequest, "id is required")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 
 	// Validate ULID format
-	if err := validateULID(id); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid id: %v", err))
+	if err := validateRecordID(collection, id); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("invalid id: %v", err))
 		return
 	}
 
-	// Build DELETE query using ULID
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", collectionName)
-	args := []any{id}
-
-	// Adjust placeholder style based on dialect
-	if h.db.Dialect() == database.DialectPostgres {
-		query = fmt.Sprintf("DELETE FROM %s WHERE id = $1", collectionName)
+	if err := requireRevision(collection, rev); err != nil {
+		writeError(w, r, http.StatusPreconditionRequired, apperrors.CodeRevisionRequired, err.Error())
+		return
 	}
 
+	// Build DELETE (or soft delete UPDATE) query using ULID and the expected revision
+	query, args := buildDestroyQuery(collection, id, rev, h.db.Dialect())
+
 	// Execute delete
 	ctx := r.Context()
 	result, err := h.db.Exec(ctx, query, args...)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete data: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to delete data: %v", err))
 		return
 	}
 
 	// Check if any rows were affected
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get rows affected: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
 		return
 	}
 
 	if rowsAffected == 0 {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("record with id %s not found", id))
+		writeRecordMiss(w, r, h.db.QueryRow, collection, id, rev, h.db.Dialect())
 		return
 	}
 
@@ -1527,25 +1989,26 @@ func (h *DataHandler) destroySingle(w http.ResponseWriter, r *http.Request, coll
 		Message: fmt.Sprintf("Record %s deleted successfully", id),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	h.publish(r.Context(), collection.Name, webhook.ActionDestroy, []string{id}, nil)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // destroyBatch handles batch destroy operations (PRD-064)
-func (h *DataHandler) destroyBatch(w http.ResponseWriter, r *http.Request, collectionName string, rawData json.RawMessage, atomic bool) {
-	var ids []string
-	if err := json.Unmarshal(rawData, &ids); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid batch data format")
+func (h *DataHandler) destroyBatch(w http.ResponseWriter, r *http.Request, collection *registry.Collection, rawData json.RawMessage, atomic bool) {
+	targets, err := parseDestroyTargets(rawData)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid batch data format")
 		return
 	}
 
 	// Validate batch size
-	if err := h.validateBatchSize(len(ids)); err != nil {
-		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	if err := h.validateBatchSize(len(targets)); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, err.Error())
 		return
 	}
 
-	if len(ids) == 0 {
-		writeError(w, http.StatusBadRequest, "batch must contain at least one id")
+	if len(targets) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "batch must contain at least one id")
 		return
 	}
 
@@ -1553,19 +2016,24 @@ func (h *DataHandler) destroyBatch(w http.ResponseWriter, r *http.Request, colle
 
 	if atomic {
 		// Atomic mode: all-or-nothing with transaction
-		h.destroyBatchAtomic(w, ctx, collectionName, ids)
+		h.destroyBatchAtomic(w, r, collection, targets)
 	} else {
 		// Best-effort mode: partial success
-		h.destroyBatchBestEffort(w, ctx, collectionName, ids)
+		h.destroyBatchBestEffort(w, ctx, collection, targets)
 	}
 }
 
 // destroyBatchAtomic handles atomic batch destroy with transaction (PRD-064)
-func (h *DataHandler) destroyBatchAtomic(w http.ResponseWriter, ctx context.Context, collectionName string, ids []string) {
+func (h *DataHandler) destroyBatchAtomic(w http.ResponseWriter, r *http.Request, collection *registry.Collection, targets []destroyTarget) {
+	ctx := r.Context()
 	// Validate all IDs first
-	for idx, id := range ids {
-		if err := validateULID(id); err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("validation error at index %d: invalid id: %v", idx, err))
+	for idx, target := range targets {
+		if err := validateRecordID(collection, target.ID); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("validation error at index %d: invalid id: %v", idx, err))
+			return
+		}
+		if err := requireRevision(collection, target.Rev); err != nil {
+			writeError(w, r, http.StatusPreconditionRequired, apperrors.CodeRevisionRequired, fmt.Sprintf("validation error at index %d: %v", idx, err))
 			return
 		}
 	}
@@ -1573,182 +2041,219 @@ func (h *DataHandler) destroyBatchAtomic(w http.ResponseWriter, ctx context.Cont
 	// Begin transaction
 	tx, err := h.db.BeginTx(ctx)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to begin transaction: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
 		return
 	}
 	defer tx.Rollback()
 
 	// Delete each item
-	for _, id := range ids {
-		query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", collectionName)
-		args := []any{id}
-
-		// Adjust placeholder style based on dialect
-		if h.db.Dialect() == database.DialectPostgres {
-			query = fmt.Sprintf("DELETE FROM %s WHERE id = $1", collectionName)
-		}
+	for _, target := range targets {
+		query, args := buildDestroyQuery(collection, target.ID, target.Rev, h.db.Dialect())
 
 		// Execute delete within transaction
+		logQuery(ctx, "destroy batch", query, args)
 		result, err := tx.ExecContext(ctx, query, args...)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to delete data: %v", err))
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to delete data: %v", err))
 			return
 		}
 
 		// Check if any rows were affected
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get rows affected: %v", err))
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
 			return
 		}
 
 		if rowsAffected == 0 {
-			writeError(w, http.StatusNotFound, fmt.Sprintf("record with id %s not found", id))
+			writeRecordMiss(w, r, tx.QueryRowContext, collection, target.ID, target.Rev, h.db.Dialect())
 			return
 		}
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to commit transaction: %v", err))
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
 		return
 	}
 
 	response := BatchDestroyResponse{
-		Message: fmt.Sprintf("%d records deleted successfully", len(ids)),
+		Message: fmt.Sprintf("%d records deleted successfully", len(targets)),
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	ids := make([]string, len(targets))
+	for i, target := range targets {
+		ids[i] = target.ID
+	}
+	h.publish(r.Context(), collection.Name, webhook.ActionDestroy, ids, nil)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // destroyBatchBestEffort handles best-effort batch destroy (PRD-064)
-func (h *DataHandler) destroyBatchBestEffort(w http.ResponseWriter, ctx context.Context, collectionName string, ids []string) {
-	var results []BatchItemResult
-	succeeded := 0
-	failed := 0
+func (h *DataHandler) destroyBatchBestEffort(w http.ResponseWriter, ctx context.Context, collection *registry.Collection, targets []destroyTarget) {
+	out := h.newBatchResultWriter(w, ctx, BatchItemDeleted)
 
 	// Process each item independently
-	for idx, id := range ids {
+	for idx, target := range targets {
+		id := target.ID
+
 		// Validate ULID format
-		if err := validateULID(id); err != nil {
-			results = append(results, BatchItemResult{
+		if err := validateRecordID(collection, id); err != nil {
+			out.add(BatchItemResult{
 				Index:        idx,
 				ID:           id,
 				Status:       BatchItemFailed,
-				ErrorCode:    "validation_error",
+				ErrorCode:    apperrors.CodeOf(err, apperrors.CodeInvalidULID),
 				ErrorMessage: fmt.Sprintf("invalid id: %v", err),
 			})
-			failed++
 			continue
 		}
 
-		// Build DELETE query using ULID
-		query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", collectionName)
-		args := []any{id}
-
-		// Adjust placeholder style based on dialect
-		if h.db.Dialect() == database.DialectPostgres {
-			query = fmt.Sprintf("DELETE FROM %s WHERE id = $1", collectionName)
+		if err := requireRevision(collection, target.Rev); err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeRevisionRequired,
+				ErrorMessage: err.Error(),
+			})
+			continue
 		}
 
+		// Build DELETE (or soft delete UPDATE) query using ULID and the expected revision
+		query, args := buildDestroyQuery(collection, id, target.Rev, h.db.Dialect())
+
 		// Execute delete
+		logQuery(ctx, "destroy batch", query, args)
 		result, err := h.db.Exec(ctx, query, args...)
 		if err != nil {
-			results = append(results, BatchItemResult{
+			out.add(BatchItemResult{
 				Index:        idx,
 				ID:           id,
 				Status:       BatchItemFailed,
-				ErrorCode:    "database_error",
+				ErrorCode:    apperrors.CodeDatabaseError,
 				ErrorMessage: err.Error(),
 			})
-			failed++
 			continue
 		}
 
 		// Check if any rows were affected
 		rowsAffected, err := result.RowsAffected()
 		if err != nil {
-			results = append(results, BatchItemResult{
+			out.add(BatchItemResult{
 				Index:        idx,
 				ID:           id,
 				Status:       BatchItemFailed,
-				ErrorCode:    "database_error",
+				ErrorCode:    apperrors.CodeDatabaseError,
 				ErrorMessage: fmt.Sprintf("failed to get rows affected: %v", err),
 			})
-			failed++
 			continue
 		}
 
 		if rowsAffected == 0 {
-			results = append(results, BatchItemResult{
-				Index:        idx,
-				ID:           id,
-				Status:       BatchItemNotFound,
-				ErrorCode:    "not_found",
-				ErrorMessage: fmt.Sprintf("record with id %s not found", id),
-			})
-			failed++
+			out.add(recordMissResult(ctx, h.db.QueryRow, collection, idx, id, target.Rev, h.db.Dialect()))
 			continue
 		}
 
-		results = append(results, BatchItemResult{
+		out.add(BatchItemResult{
 			Index:  idx,
 			ID:     id,
 			Status: BatchItemDeleted,
 		})
-		succeeded++
-	}
-
-	response := BatchResponse{
-		Results: results,
-		Summary: BatchSummary{
-			Total:     len(ids),
-			Succeeded: succeeded,
-			Failed:    failed,
-		},
 	}
 
-	writeJSON(w, http.StatusMultiStatus, response)
+	h.publishResults(collection.Name, webhook.ActionDestroy, out.close(), BatchItemDeleted)
 }
 
 // SchemaResponse represents the response for the schema endpoint (PRD-054, PRD-061)
 type SchemaResponse struct {
-	Collection string               `json:"collection"`
-	Fields     []schema.FieldSchema `json:"fields"`
-	Total      int                  `json:"total"` // PRD-061: Total record count in collection
+	Collection    string               `json:"collection"`
+	Fields        []schema.FieldSchema `json:"fields"`
+	Indexes       []registry.Index     `json:"indexes,omitempty"`
+	DefaultSort   []string             `json:"default_sort,omitempty"`
+	DefaultFields []string             `json:"default_fields,omitempty"`
+	Pagination    registry.Pagination  `json:"pagination"`    // page sizes of list requests, the server defaults included
+	TTL           *registry.TTL        `json:"ttl,omitempty"` // expiry policy of the records
+	UI            *registry.UI         `json:"ui,omitempty"`  // presentation metadata set by collections:update
+	Total         int                  `json:"total"`         // PRD-061: Total record count in collection
 }
 
+// schemaFormatJSONSchema is the :schema format returning a JSON Schema document
+const schemaFormatJSONSchema = "jsonschema"
+
 // Schema handles GET /{name}:schema (PRD-054, PRD-061)
 func (h *DataHandler) Schema(w http.ResponseWriter, r *http.Request, collectionName string) {
-	// Validate collection exists in registry
-	collection, exists := h.registry.Get(collectionName)
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
+	// Validate collection exists in registry
+	collection, exists := h.registry.Get(collectionName)
 	if !exists {
-		writeError(w, http.StatusNotFound, "Collection not found")
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, "Collection not found")
 		return
 	}
 
-	// Build schema response
+	// ?format=jsonschema returns a JSON Schema of the create payload instead
 	schemaBuilder := schema.NewBuilder()
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+	case schemaFormatJSONSchema:
+		doc := schemaBuilder.ToJSONSchema(collection)
+		doc.Title = logicalName(r, collectionName)
+		writeJSON(w, http.StatusOK, doc)
+		return
+	default:
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("invalid format '%s': must be %s", format, schemaFormatJSONSchema))
+		return
+	}
+
+	// Build schema response
 	fullSchema := schemaBuilder.FromCollection(collection)
 
 	// Get total record count for the collection (PRD-061)
 	ctx := r.Context()
-	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s", collectionName)
+	// Soft-deleted and expired records are not counted
+	var live []query.Condition
+	if collection.SoftDelete {
+		live = append(live, query.Condition{Column: constants.SoftDeleteColumn, Operator: query.OpIsNull})
+	}
+	where, args := buildListWhere(withExpiryFilter(collection, live), "", nil, h.db.Dialect())
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", query.QuoteIdent(h.db.Dialect(), collectionName), where)
 	var total int
-	row := h.db.QueryRow(ctx, countSQL)
+	row := h.db.QueryRow(ctx, countSQL, args...)
 	if err := row.Scan(&total); err != nil {
-		// If error (e.g., table doesn't exist), default to 0
+		// A canceled or timed-out count is an error; otherwise (e.g. the table
+		// doesn't exist) the total defaults to 0
+		if ctx.Err() != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to count records: %v", err))
+			return
+		}
 		total = 0
 	}
 
+	// Referenced collections are named as the tenant sees them
+	for i := range fullSchema.Fields {
+		if fullSchema.Fields[i].References != "" {
+			fullSchema.Fields[i].References = logicalName(r, fullSchema.Fields[i].References)
+		}
+	}
+
 	// Create response matching PRD-054 and PRD-061 specification
+	defaultLimit, maxLimit := pageLimits(collection)
 	response := SchemaResponse{
-		Collection: fullSchema.Collection,
-		Fields:     fullSchema.Fields,
-		Total:      total,
+		Collection:    logicalName(r, fullSchema.Collection),
+		Fields:        fullSchema.Fields,
+		Indexes:       fullSchema.Indexes,
+		DefaultSort:   collection.DefaultSort,
+		DefaultFields: collection.DefaultFields,
+		Pagination:    registry.Pagination{DefaultLimit: defaultLimit, MaxLimit: maxLimit},
+		TTL:           collection.TTL,
+		UI:            collection.UI,
+		Total:         total,
 	}
 
-	writeJSON(w, http.StatusOK, response)
+	writeResponse(w, r, http.StatusOK, response)
 }
 
 // filterParam represents a parsed filter from query string
@@ -1758,17 +2263,26 @@ type filterParam struct {
 	value    string
 }
 
+// jsonPathKeyRegex validates the keys of JSON path filters
+var jsonPathKeyRegex = regexp.MustCompile(constants.JSONPathKeyPattern)
+
+// filterRegex matches a filter parameter name: column[operator]
+var filterRegex = regexp.MustCompile(`^(.+)\[(` + strings.Join(filterOperators, "|") + `)\]$`)
+
 // parseFilters parses filter query parameters from URL
 // Expected format: ?column[operator]=value
 // Example: ?price[gt]=100&name[like]=moon
+// A dotted name filters on a key inside a JSON column: ?meta.color[eq]=red
 // Enforces MaxFiltersPerRequest limit (PRD-048)
 func parseFilters(r *http.Request) ([]filterParam, error) {
 	var filters []filterParam
-	filterRegex := regexp.MustCompile(`^(.+)\[(eq|ne|gt|lt|gte|lte|like|in)\]$`)
 
-	for key, values := range r.URL.Query() {
+	// Keys in order, so the same parameters always build the same SQL
+	params := r.URL.Query()
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		values := params[key]
 		// Skip standard query params
-		if key == constants.QueryParamLimit || key == "after" || key == "sort" || key == "q" || key == "fields" || key == "field" {
+		if key == constants.QueryParamLimit || key == "after" || key == "sort" || key == "q" || key == "q_fields" || key == "q_mode" || key == "fields" || key == "field" || key == "include_deleted" {
 			continue
 		}
 
@@ -1817,11 +2331,49 @@ func mapOperatorToSQL(op string) string {
 		return query.OpLike
 	case "in":
 		return query.OpIn
+	case "isnull":
+		return query.OpIsNull
+	case "notnull":
+		return query.OpIsNotNull
+	case "between":
+		return query.OpBetween
 	default:
 		return query.OpEqual
 	}
 }
 
+// queryableSystemColumns returns the system columns that may be used in filters, sorts and field selection
+func queryableSystemColumns() []registry.Column {
+	return []registry.Column{
+		{Name: "id", Type: registry.TypeString},
+		{Name: constants.CreatedAtColumn, Type: registry.TypeDatetime},
+		{Name: constants.UpdatedAtColumn, Type: registry.TypeDatetime},
+		{Name: constants.RevisionColumn, Type: registry.TypeInteger},
+	}
+}
+
+// sequenceColumn is the read-only seq field of collections with
+// expose_sequence, which reads the pkid column
+var sequenceColumn = registry.Column{Name: constants.SequenceField, Type: registry.TypeInteger}
+
+// storageColumn returns the table column holding a field: pkid for the seq
+// field of collections with expose_sequence, otherwise the field itself
+func storageColumn(collection *registry.Collection, field string) string {
+	if collection.ExposeSequence && field == constants.SequenceField {
+		return constants.PKIDColumn
+	}
+	return field
+}
+
+// storageSorts returns the sort fields with their columns mapped by storageColumn
+func storageSorts(collection *registry.Collection, sorts []sortField) []sortField {
+	mapped := make([]sortField, len(sorts))
+	for i, sort := range sorts {
+		mapped[i] = sortField{column: storageColumn(collection, sort.column), direction: sort.direction}
+	}
+	return mapped
+}
+
 // buildConditions converts filter params to query conditions
 func buildConditions(filters []filterParam, collection *registry.Collection) ([]query.Condition, error) {
 	var conditions []query.Condition
@@ -1831,37 +2383,102 @@ func buildConditions(filters []filterParam, collection *registry.Collection) ([]
 	for _, col := range collection.Columns {
 		validColumns[col.Name] = col
 	}
-	// Also allow filtering by id (ULID column)
-	validColumns["id"] = registry.Column{Name: "id", Type: registry.TypeString}
+	// Also allow filtering by id (ULID column) record timestamps and revision
+	for _, col := range queryableSystemColumns() {
+		validColumns[col.Name] = col
+	}
+	if collection.ExposeSequence {
+		validColumns[sequenceColumn.Name] = sequenceColumn
+	}
 
 	for _, filter := range filters {
+		// A dotted name filters on a key inside a JSON column
+		if root, path, ok := strings.Cut(filter.column, "."); ok {
+			col, exists := validColumns[root]
+			if !exists {
+				return nil, fmt.Errorf("invalid filter column: %s", filter.column)
+			}
+			condition, err := buildJSONPathCondition(filter, col, strings.Split(path, "."))
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+			continue
+		}
+
 		// Validate column exists in schema
 		col, exists := validColumns[filter.column]
+		if !exists && filter.column == constants.SequenceField {
+			return nil, fmt.Errorf("invalid filter column: %s (set expose_sequence on the collection to filter by record sequence)", filter.column)
+		}
 		if !exists {
 			return nil, fmt.Errorf("invalid filter column: %s", filter.column)
 		}
+		column := storageColumn(collection, filter.column)
 
 		sqlOp := mapOperatorToSQL(filter.operator)
 
-		// Handle IN operator - split comma-separated values
-		if sqlOp == query.OpIn {
+		// Handle NULL checks - value selects IS NULL / IS NOT NULL
+		if sqlOp == query.OpIsNull || sqlOp == query.OpIsNotNull {
+			want, err := parseBoolean(filter.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s[%s]: %v", filter.column, filter.operator, err)
+			}
+			if !want {
+				if sqlOp == query.OpIsNull {
+					sqlOp = query.OpIsNotNull
+				} else {
+					sqlOp = query.OpIsNull
+				}
+			}
+			conditions = append(conditions, query.Condition{
+				Column:   column,
+				Operator: sqlOp,
+			})
+		} else if sqlOp == query.OpBetween {
+			// Handle BETWEEN operator - exactly two comma-separated bounds
+			parts := strings.Split(filter.value, ",")
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+				return nil, fmt.Errorf("invalid value for %s[between]: expected two comma-separated values (low,high), got '%s'", filter.column, filter.value)
+			}
+			bounds := make([]any, 2)
+			for i, part := range parts {
+				value, err := convertValue(strings.TrimSpace(part), col.Type)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value for column %s: %v", filter.column, err)
+				}
+				bounds[i] = value
+			}
+			conditions = append(conditions, query.Condition{
+				Column:   column,
+				Operator: sqlOp,
+				Value:    bounds,
+			})
+		} else if sqlOp == query.OpIn {
 			parts := strings.Split(filter.value, ",")
 			values := make([]any, len(parts))
 			for i, part := range parts {
 				values[i] = strings.TrimSpace(part)
+				if col.Type == registry.TypeDatetime || col.Type == registry.TypeBoolean {
+					value, err := convertValue(strings.TrimSpace(part), col.Type)
+					if err != nil {
+						return nil, fmt.Errorf("invalid value for column %s: %v", filter.column, err)
+					}
+					values[i] = value
+				}
 			}
 			conditions = append(conditions, query.Condition{
-				Column:   filter.column,
+				Column:   column,
 				Operator: sqlOp,
 				Value:    values,
 			})
 		} else if sqlOp == query.OpLike {
-			// For LIKE, wrap value with wildcards
-			value := "%" + filter.value + "%"
+			// LIKE matches values containing the filter value; the query
+			// builders add the wildcards and escape the value
 			conditions = append(conditions, query.Condition{
-				Column:   filter.column,
+				Column:   column,
 				Operator: sqlOp,
-				Value:    value,
+				Value:    filter.value,
 			})
 		} else {
 			// Convert value based on column type
@@ -1871,7 +2488,7 @@ func buildConditions(filters []filterParam, collection *registry.Collection) ([]
 			}
 
 			conditions = append(conditions, query.Condition{
-				Column:   filter.column,
+				Column:   column,
 				Operator: sqlOp,
 				Value:    value,
 			})
@@ -1881,14 +2498,45 @@ func buildConditions(filters []filterParam, collection *registry.Collection) ([]
 	return conditions, nil
 }
 
+// buildJSONPathCondition builds the condition for a filter on a key inside a
+// JSON column. Extracted values are compared as text, so only eq, ne and like
+// are supported.
+func buildJSONPathCondition(filter filterParam, col registry.Column, path []string) (query.Condition, error) {
+	if col.Type != registry.TypeJSON {
+		return query.Condition{}, fmt.Errorf("invalid filter column: %s (column '%s' is not a json column)", filter.column, col.Name)
+	}
+	for _, key := range path {
+		if !jsonPathKeyRegex.MatchString(key) {
+			return query.Condition{}, fmt.Errorf("invalid filter column: %s (json path keys may only contain letters, numbers and underscores)", filter.column)
+		}
+	}
+
+	switch filter.operator {
+	case "eq", "ne", "like":
+	default:
+		return query.Condition{}, fmt.Errorf("operator '%s' is not supported on json path %s: use eq, ne or like", filter.operator, filter.column)
+	}
+
+	return query.Condition{
+		Column:   col.Name,
+		Path:     path,
+		Operator: mapOperatorToSQL(filter.operator),
+		Value:    filter.value,
+	}, nil
+}
+
 // convertValue converts a string value to the appropriate type
 func convertValue(value string, colType registry.ColumnType) (any, error) {
 	switch colType {
 	case registry.TypeInteger:
 		return strconv.ParseInt(value, 10, 64)
 	case registry.TypeBoolean:
-		return strconv.ParseBool(value)
-	case registry.TypeString, registry.TypeDatetime, registry.TypeJSON:
+		return parseBoolean(value)
+	case registry.TypeDecimal:
+		return parseDecimalFilter(value)
+	case registry.TypeDatetime:
+		return datetime.Normalize(value)
+	case registry.TypeString, registry.TypeJSON:
 		return value, nil
 	default:
 		return value, nil
@@ -1905,7 +2553,11 @@ type sortField struct {
 // Supports: ?sort=field (ASC), ?sort=-field (DESC), ?sort=field1,-field2 (multiple)
 // Enforces MaxSortFieldsPerRequest limit (PRD-048)
 func parseSort(r *http.Request) ([]sortField, error) {
-	sortParam := r.URL.Query().Get("sort")
+	return parseSortParam(r.URL.Query().Get("sort"))
+}
+
+// parseSortParam parses a sort expression in the syntax of the sort query parameter
+func parseSortParam(sortParam string) ([]sortField, error) {
 	if sortParam == "" {
 		return nil, nil
 	}
@@ -1945,13 +2597,39 @@ func parseSort(r *http.Request) ([]sortField, error) {
 	return fields, nil
 }
 
+// logicalName returns a table name as the requesting tenant knows it, without
+// its tenant prefix
+func logicalName(r *http.Request, table string) string {
+	if tenant := middleware.GetTenant(r.Context()); tenant != "" {
+		return strings.TrimPrefix(table, tenant+constants.TenantSeparator)
+	}
+	return table
+}
+
+// queryOrDefault returns a query parameter when the request carries it, even
+// empty, so that clients can opt out of a collection default, and the default
+// joined with commas otherwise
+func queryOrDefault(r *http.Request, key string, defaults []string) string {
+	if values, ok := r.URL.Query()[key]; ok {
+		return values[0]
+	}
+	return strings.Join(defaults, ",")
+}
+
 // parseFields parses the fields query parameter
 // Returns nil to select all fields, or a list of requested fields (always includes id)
-func parseFields(r *http.Request, collection *registry.Collection) ([]string, error) {
-	fieldsParam := r.URL.Query().Get("fields")
+func parseFields(r *http.Request, collection *registry.Collection, hidden map[string]bool) ([]string, jsonProjection, error) {
+	return parseFieldsParam(r.URL.Query().Get("fields"), collection, hidden)
+}
+
+// parseFieldsParam parses a field list in the syntax of the fields query
+// parameter. Hidden columns cannot be selected. A dotted field such as
+// meta.size.width selects the json column meta and adds the path to the
+// returned projection, unless the column is also selected whole.
+func parseFieldsParam(fieldsParam string, collection *registry.Collection, hidden map[string]bool) ([]string, jsonProjection, error) {
 	if fieldsParam == "" {
 		// No fields parameter, return nil to select all
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	// Parse comma-separated field names
@@ -1962,33 +2640,84 @@ func parseFields(r *http.Request, collection *registry.Collection) ([]string, er
 	for _, col := range collection.Columns {
 		validColumns[col.Name] = true
 	}
-	validColumns["id"] = true // The ULID column
+	for _, col := range queryableSystemColumns() {
+		validColumns[col.Name] = true
+	}
+	if collection.SoftDelete {
+		validColumns[constants.SoftDeleteColumn] = true
+	}
+	if collection.ExposeSequence {
+		validColumns[constants.SequenceField] = true
+	}
 
-	// Validate and collect fields
-	fieldsMap := make(map[string]bool)
+	// Always include id first for pagination consistency, then the
+	// requested fields in request order without duplicates
+	fields := []string{"id"}
+	seen := map[string]bool{"id": true}
+	whole := map[string]bool{}
+	projection := jsonProjection{}
+	paths := 0
 	for _, field := range requestedFields {
 		field = strings.TrimSpace(field)
 		if field == "" {
 			continue
 		}
 
-		if !validColumns[field] {
-			return nil, fmt.Errorf("invalid field: %s", field)
+		column, path, dotted := strings.Cut(field, ".")
+		if !validColumns[column] {
+			return nil, nil, fmt.Errorf("invalid field: %s", field)
+		}
+		if hidden[column] {
+			return nil, nil, fmt.Errorf("field '%s' is hidden", column)
+		}
+		if dotted {
+			col, ok := findColumn(collection.Columns, column)
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid field: %s (column '%s' is not a json column)", field, column)
+			}
+			keys, err := parseFieldPath(field, &col, path)
+			if err != nil {
+				return nil, nil, err
+			}
+			if paths++; paths > constants.MaxFieldPaths {
+				return nil, nil, fmt.Errorf("maximum number of json paths in fields (%d) exceeded", constants.MaxFieldPaths)
+			}
+			projection.add(column, keys)
+		} else {
+			whole[column] = true
 		}
 
-		fieldsMap[field] = true
+		if !seen[column] {
+			seen[column] = true
+			fields = append(fields, column)
+		}
 	}
 
-	// Always include id for pagination consistency
-	fieldsMap["id"] = true
-
-	// Convert map to slice
-	fields := make([]string, 0, len(fieldsMap))
-	for field := range fieldsMap {
-		fields = append(fields, field)
+	// A column selected whole keeps every key
+	for column := range whole {
+		delete(projection, column)
 	}
+	if len(projection) == 0 {
+		projection = nil
+	}
+	return fields, projection, nil
+}
 
-	return fields, nil
+// sortableColumns returns the columns a collection can be sorted by: its user
+// columns plus the id (ULID column), record timestamps and revision, and seq
+// with expose_sequence
+func sortableColumns(collection *registry.Collection) map[string]bool {
+	validColumns := make(map[string]bool)
+	for _, col := range collection.Columns {
+		validColumns[col.Name] = true
+	}
+	for _, col := range queryableSystemColumns() {
+		validColumns[col.Name] = true
+	}
+	if collection.ExposeSequence {
+		validColumns[constants.SequenceField] = true
+	}
+	return validColumns
 }
 
 // buildOrderBy constructs ORDER BY clause from sort fields
@@ -1998,13 +2727,7 @@ func buildOrderBy(sorts []sortField, collection *registry.Collection, builder qu
 		return "id ASC", nil
 	}
 
-	// Create a map of valid column names
-	validColumns := make(map[string]bool)
-	for _, col := range collection.Columns {
-		validColumns[col.Name] = true
-	}
-	// Also allow sorting by id (ULID column)
-	validColumns["id"] = true
+	validColumns := sortableColumns(collection)
 
 	var orderParts []string
 	for _, sort := range sorts {
@@ -2013,322 +2736,421 @@ func buildOrderBy(sorts []sortField, collection *registry.Collection, builder qu
 			return "", fmt.Errorf("invalid sort column: %s", sort.column)
 		}
 
-		// Escape identifier based on dialect
-		escapedCol := sort.column
-		switch builder.Dialect() {
-		case database.DialectPostgres:
-			escapedCol = fmt.Sprintf(`"%s"`, sort.column)
-		case database.DialectMySQL:
-			escapedCol = fmt.Sprintf("`%s`", sort.column)
-		}
-
-		orderParts = append(orderParts, fmt.Sprintf("%s %s", escapedCol, sort.direction))
+		orderParts = append(orderParts, fmt.Sprintf("%s %s", query.QuoteIdent(builder.Dialect(), storageColumn(collection, sort.column)), sort.direction))
 	}
 
 	return strings.Join(orderParts, ", "), nil
 }
 
-// buildSearchConditions builds search conditions for full-text search
-// Returns SQL fragment and args for OR-connected LIKE conditions
-func buildSearchConditions(searchTerm string, collection *registry.Collection, dialect database.DialectType) (string, []any) {
-	// Escape LIKE wildcards in search term
-	escapedTerm := strings.ReplaceAll(searchTerm, `\`, `\\`)
-	escapedTerm = strings.ReplaceAll(escapedTerm, `%`, `\%`)
-	escapedTerm = strings.ReplaceAll(escapedTerm, `_`, `\_`)
-
-	// Wrap with wildcards for partial matching
-	searchValue := "%" + escapedTerm + "%"
-
-	// Find all string columns (full-text search on string fields)
-	var textColumns []string
-	for _, col := range collection.Columns {
-		if col.Type == registry.TypeString {
-			textColumns = append(textColumns, col.Name)
-		}
+// parseSearchFields parses the q_fields parameter, which restricts search to
+// the listed string columns. Returns nil when absent to search every string column.
+func parseSearchFields(r *http.Request, collection *registry.Collection) ([]string, error) {
+	param := r.URL.Query().Get("q_fields")
+	if param == "" {
+		return nil, nil
 	}
 
-	if len(textColumns) == 0 {
-		return "", nil
+	validColumns := make(map[string]registry.Column)
+	for _, col := range collection.Columns {
+		validColumns[col.Name] = col
 	}
 
-	// Build OR conditions for each text column
-	var conditions []string
-	var args []any
-	placeholderNum := 1
-
-	for _, col := range textColumns {
-		escapedCol := col
-		switch dialect {
-		case database.DialectPostgres:
-			escapedCol = fmt.Sprintf(`"%s"`, col)
-			conditions = append(conditions, fmt.Sprintf("%s LIKE $%d", escapedCol, placeholderNum))
-		case database.DialectMySQL:
-			escapedCol = fmt.Sprintf("`%s`", col)
-			conditions = append(conditions, fmt.Sprintf("%s LIKE ?", escapedCol))
-		case database.DialectSQLite:
-			conditions = append(conditions, fmt.Sprintf("%s LIKE ?", col))
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
 		}
-		args = append(args, searchValue)
-		placeholderNum++
+		col, ok := validColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("invalid search field: %s", field)
+		}
+		if col.Type != registry.TypeString {
+			return nil, fmt.Errorf("search field '%s' must be of type string, got %s", field, col.Type)
+		}
+		seen[field] = true
+		fields = append(fields, field)
 	}
-
-	searchSQL := "(" + strings.Join(conditions, " OR ") + ")"
-	return searchSQL, args
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("q_fields must name at least one field")
+	}
+	return fields, nil
 }
 
-// buildCountQuery builds COUNT query with search (OR) and filters (AND) (PRD-062)
-func buildCountQuery(tableName string, filters []query.Condition, searchSQL string, searchArgs []any, dialect database.DialectType) (string, []any) {
-	var sb strings.Builder
-	args := []any{}
-
-	// SELECT COUNT(*) clause
-	sb.WriteString("SELECT COUNT(*) FROM ")
+// searchModes maps the values of the q_mode parameter to query match modes
+var searchModes = map[string]string{
+	"contains":    query.MatchContains,
+	"insensitive": query.MatchInsensitive,
+	"prefix":      query.MatchPrefix,
+	"exact":       query.MatchExact,
+}
 
-	// Escape table name
-	switch dialect {
-	case database.DialectPostgres:
-		sb.WriteString(fmt.Sprintf(`"%s"`, tableName))
-	case database.DialectMySQL:
-		sb.WriteString(fmt.Sprintf("`%s`", tableName))
-	default:
-		sb.WriteString(tableName)
+// parseSearchMode parses the q_mode parameter, which selects how q matches:
+// contains (default), insensitive, prefix or exact
+func parseSearchMode(r *http.Request) (string, error) {
+	param := r.URL.Query().Get("q_mode")
+	if param == "" {
+		return query.MatchContains, nil
 	}
+	mode, ok := searchModes[param]
+	if !ok {
+		return "", fmt.Errorf("invalid q_mode '%s': must be contains, insensitive, prefix or exact", param)
+	}
+	return mode, nil
+}
 
-	// WHERE clause
-	sb.WriteString(" WHERE ")
-
-	// Add search conditions first
-	sb.WriteString(searchSQL)
-	args = append(args, searchArgs...)
-
-	// Add filter conditions with AND
-	placeholderNum := len(searchArgs) + 1
-	for _, cond := range filters {
-		sb.WriteString(" AND ")
+// parseFilterConditions builds the conditions of the filter parameters
+func parseFilterConditions(r *http.Request, collection *registry.Collection) ([]query.Condition, error) {
+	filters, err := parseFilters(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return buildConditions(filters, collection)
+}
 
-		// Escape column name
-		escapedCol := cond.Column
-		switch dialect {
-		case database.DialectPostgres:
-			escapedCol = fmt.Sprintf(`"%s"`, cond.Column)
-		case database.DialectMySQL:
-			escapedCol = fmt.Sprintf("`%s`", cond.Column)
-		}
+// parseRecordConditions builds the conditions selecting the records of a
+// request the way :list does: the filter parameters, the soft-delete and
+// expiry filters and the search of q and q_fields. Errors are *apperrors.APIError values.
+func parseRecordConditions(r *http.Request, collection *registry.Collection) ([]query.Condition, error) {
+	conditions, err := parseFilterConditions(r, collection)
+	if err != nil {
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidFilter, err.Error())
+	}
+	conditions, err = withSearchFilter(r, collection, withExpiryFilter(collection, withSoftDeleteFilter(r, collection, conditions)))
+	if err != nil {
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+	}
+	return conditions, nil
+}
 
-		sb.WriteString(escapedCol)
-		sb.WriteString(" ")
-		sb.WriteString(cond.Operator)
-		sb.WriteString(" ")
+// withSearchFilter appends the full-text search of the q parameter over the
+// columns of q_fields, matched as q_mode says, if q is set
+func withSearchFilter(r *http.Request, collection *registry.Collection, conditions []query.Condition) ([]query.Condition, error) {
+	term := r.URL.Query().Get("q")
+	if term == "" {
+		return conditions, nil
+	}
+	fields, err := parseSearchFields(r, collection)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := parseSearchMode(r)
+	if err != nil {
+		return nil, err
+	}
+	if search, ok := searchCondition(term, collection, fields, mode); ok {
+		conditions = append(conditions, search)
+	}
+	return conditions, nil
+}
 
-		// Handle special operators
-		if cond.Operator == query.OpIn {
-			values, ok := cond.Value.([]any)
-			if !ok {
-				values = []any{cond.Value}
-			}
-			sb.WriteString("(")
-			for j, v := range values {
-				if j > 0 {
-					sb.WriteString(", ")
-				}
-				if dialect == database.DialectPostgres {
-					sb.WriteString(fmt.Sprintf("$%d", placeholderNum))
-				} else {
-					sb.WriteString("?")
-				}
-				args = append(args, v)
-				placeholderNum++
-			}
-			sb.WriteString(")")
-		} else {
-			// Regular operators
-			if dialect == database.DialectPostgres {
-				sb.WriteString(fmt.Sprintf("$%d", placeholderNum))
-			} else {
-				sb.WriteString("?")
+// searchCondition returns the full-text search for term: an OR group of LIKE
+// conditions over fields, or over every string column when fields is nil,
+// matching term in the given query match mode. LIKE wildcards in term match
+// literally. ok is false without columns to search.
+func searchCondition(term string, collection *registry.Collection, fields []string, mode string) (query.Condition, bool) {
+	textColumns := fields
+	if textColumns == nil {
+		for _, col := range collection.Columns {
+			if col.Type == registry.TypeString {
+				textColumns = append(textColumns, col.Name)
 			}
-			args = append(args, cond.Value)
-			placeholderNum++
 		}
 	}
+	if len(textColumns) == 0 {
+		return query.Condition{}, false
+	}
 
+	search := query.Condition{Operator: query.OpOr}
+	for _, col := range textColumns {
+		search.Conditions = append(search.Conditions, query.Condition{Column: col, Operator: query.OpLike, Value: term, Match: mode})
+	}
+	return search, true
+}
+
+// buildSearchConditions builds search conditions for full-text search
+// Returns SQL fragment and args for OR-connected LIKE conditions over fields,
+// or over every string column when fields is nil, in the given match mode
+func buildSearchConditions(searchTerm string, collection *registry.Collection, fields []string, mode string, dialect database.DialectType) (string, []any) {
+	search, ok := searchCondition(searchTerm, collection, fields, mode)
+	if !ok {
+		return "", nil
+	}
+	var sb strings.Builder
+	args := query.WriteCondition(&sb, dialect, search, nil)
 	return sb.String(), args
 }
 
-// buildSearchQueryWithFields builds complete SELECT query with field selection, search (OR) and filters (AND)
-func buildSearchQueryWithFields(tableName string, fields []string, filters []query.Condition, searchSQL string, searchArgs []any, orderBy string, limit int, dialect database.DialectType) (string, []any) {
+// buildListWhere builds the WHERE clause of a list from the search (OR across
+// text columns) and the filters (AND), or "" when there are neither. Postgres
+// placeholders of the search start at $1; the filters continue from there.
+func buildListWhere(filters []query.Condition, searchSQL string, searchArgs []any, dialect database.DialectType) (string, []any) {
 	var sb strings.Builder
 	args := []any{}
 
-	// SELECT clause
-	sb.WriteString("SELECT ")
-	if len(fields) == 0 {
-		sb.WriteString("*")
-	} else {
-		for i, field := range fields {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			// Escape field name
-			switch dialect {
-			case database.DialectPostgres:
-				sb.WriteString(fmt.Sprintf(`"%s"`, field))
-			case database.DialectMySQL:
-				sb.WriteString(fmt.Sprintf("`%s`", field))
-			default:
-				sb.WriteString(field)
-			}
-		}
+	if searchSQL != "" {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(searchSQL)
+		args = append(args, searchArgs...)
+		args = writeFilterConditions(&sb, filters, args, dialect)
+		return sb.String(), args
 	}
-	sb.WriteString(" FROM ")
 
-	// Escape table name
-	switch dialect {
-	case database.DialectPostgres:
-		sb.WriteString(fmt.Sprintf(`"%s"`, tableName))
-	case database.DialectMySQL:
-		sb.WriteString(fmt.Sprintf("`%s`", tableName))
-	default:
-		sb.WriteString(tableName)
+	for i, cond := range filters {
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		args = query.WriteCondition(&sb, dialect, cond, args)
 	}
+	return sb.String(), args
+}
 
-	// WHERE clause
-	sb.WriteString(" WHERE ")
-
-	// Add search conditions first
-	sb.WriteString(searchSQL)
-	args = append(args, searchArgs...)
-
-	// Add filter conditions with AND
-	placeholderNum := len(searchArgs) + 1
-	for _, cond := range filters {
-		sb.WriteString(" AND ")
+// appendWhere adds a condition to a WHERE clause from buildListWhere
+func appendWhere(where, condition string) string {
+	if where == "" {
+		return " WHERE " + condition
+	}
+	return where + " AND " + condition
+}
 
-		// Escape column name
-		escapedCol := cond.Column
-		switch dialect {
-		case database.DialectPostgres:
-			escapedCol = fmt.Sprintf(`"%s"`, cond.Column)
-		case database.DialectMySQL:
-			escapedCol = fmt.Sprintf("`%s`", cond.Column)
-		}
+// buildCountQuery builds the COUNT query of a list (PRD-062); it binds the
+// same args as the WHERE clause
+func buildCountQuery(tableName string, where string, dialect database.DialectType) string {
+	return "SELECT COUNT(*) FROM " + query.QuoteIdent(dialect, tableName) + where
+}
 
-		sb.WriteString(escapedCol)
-		sb.WriteString(" ")
-		sb.WriteString(cond.Operator)
-		sb.WriteString(" ")
+// buildListSelect builds the page query of a list, binding the limit and
+// offset after the args of the WHERE clause
+func buildListSelect(tableName string, fields []string, where string, args []any, orderBy string, limit, offset int, dialect database.DialectType) (string, []any) {
+	var sb strings.Builder
 
-		// Handle special operators
-		if cond.Operator == query.OpIn {
-			values, ok := cond.Value.([]any)
-			if !ok {
-				values = []any{cond.Value}
-			}
-			sb.WriteString("(")
-			for j, v := range values {
-				if j > 0 {
-					sb.WriteString(", ")
-				}
-				if dialect == database.DialectPostgres {
-					sb.WriteString(fmt.Sprintf("$%d", placeholderNum))
-				} else {
-					sb.WriteString("?")
-				}
-				args = append(args, v)
-				placeholderNum++
-			}
-			sb.WriteString(")")
-		} else {
-			if dialect == database.DialectPostgres {
-				sb.WriteString(fmt.Sprintf("$%d", placeholderNum))
-			} else {
-				sb.WriteString("?")
-			}
-			args = append(args, cond.Value)
-			placeholderNum++
-		}
+	sb.WriteString("SELECT ")
+	if len(fields) == 0 {
+		sb.WriteString("*")
+	} else {
+		sb.WriteString(query.QuoteIdents(dialect, fields))
 	}
+	sb.WriteString(" FROM ")
+	sb.WriteString(query.QuoteIdent(dialect, tableName))
+	sb.WriteString(where)
 
-	// ORDER BY clause
 	if orderBy != "" {
 		sb.WriteString(" ORDER BY ")
 		sb.WriteString(orderBy)
 	}
 
-	// LIMIT clause
 	if limit > 0 {
 		sb.WriteString(" LIMIT ")
-		if dialect == database.DialectPostgres {
-			sb.WriteString(fmt.Sprintf("$%d", placeholderNum))
-		} else {
-			sb.WriteString("?")
-		}
+		sb.WriteString(query.Placeholder(dialect, len(args)+1))
 		args = append(args, limit)
+
+		if offset > 0 {
+			sb.WriteString(" OFFSET ")
+			sb.WriteString(query.Placeholder(dialect, len(args)+1))
+			args = append(args, offset)
+		}
 	}
 
 	return sb.String(), args
 }
 
-// parseRows parses SQL rows into a slice of maps
-func parseRows(rows *sql.Rows, collection *registry.Collection) ([]map[string]any, error) {
+// writeFilterConditions appends each filter as " AND <condition>" and returns the updated args.
+// Placeholders continue numbering from len(args)+1 for Postgres.
+func writeFilterConditions(sb *strings.Builder, filters []query.Condition, args []any, dialect database.DialectType) []any {
+	for _, cond := range filters {
+		sb.WriteString(" AND ")
+		args = query.WriteCondition(sb, dialect, cond, args)
+	}
+
+	return args
+}
+
+// buildSearchQueryWithFields builds complete SELECT query with field selection, search (OR) and filters (AND)
+func buildSearchQueryWithFields(tableName string, fields []string, filters []query.Condition, searchSQL string, searchArgs []any, orderBy string, limit int, dialect database.DialectType) (string, []any) {
+	where, args := buildListWhere(filters, searchSQL, searchArgs, dialect)
+	return buildListSelect(tableName, fields, where, args, orderBy, limit, 0, dialect)
+}
+
+// parseRows parses SQL rows into a slice of maps, leaving out the hidden columns
+func parseRows(rows *sql.Rows, collection *registry.Collection, hidden map[string]bool) ([]map[string]any, error) {
+	return scanRows(rows, collection, hidden, false)
+}
+
+// parseRowsRawJSON parses SQL rows like parseRows, but keeps the values of
+// json columns as the rawJSON read from the database, so that large documents
+// can be measured and written without being copied
+func parseRowsRawJSON(rows *sql.Rows, collection *registry.Collection, hidden map[string]bool) ([]map[string]any, error) {
+	return scanRows(rows, collection, hidden, true)
+}
+
+// scanRows scans SQL rows into maps for parseRows and parseRowsRawJSON
+func scanRows(rows *sql.Rows, collection *registry.Collection, hidden map[string]bool, keepRawJSON bool) ([]map[string]any, error) {
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a map of column names to their types for boolean conversion (PRD-051)
+	columnTypes := rowColumnTypes(collection)
+	result := []map[string]any{}
+
+	for rows.Next() {
+		rowData, err := scanRow(rows, columns, columnTypes, hidden, keepRawJSON)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rowData)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// rowColumnTypes maps column names to their types for boolean, decimal,
+// datetime and json conversion (PRD-051), including the system timestamp columns.
+// The pkid column is only mapped for collections with expose_sequence, which
+// tells scanRow to return it as seq.
+func rowColumnTypes(collection *registry.Collection) map[string]registry.ColumnType {
 	columnTypes := make(map[string]registry.ColumnType)
 	for _, col := range collection.Columns {
 		columnTypes[col.Name] = col.Type
 	}
+	columnTypes[constants.CreatedAtColumn] = registry.TypeDatetime
+	columnTypes[constants.UpdatedAtColumn] = registry.TypeDatetime
+	if collection.ExposeSequence {
+		columnTypes[constants.PKIDColumn] = registry.TypeInteger
+	}
+	return columnTypes
+}
 
-	result := []map[string]any{}
+// scanRow scans the current row into a map keyed by column name, leaving out
+// the hidden columns. With keepRawJSON, json column values are rawJSON
+// rather than strings.
+func scanRow(rows *sql.Rows, columns []string, columnTypes map[string]registry.ColumnType, hidden map[string]bool, keepRawJSON bool) (map[string]any, error) {
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
 
-	for rows.Next() {
-		values := make([]any, len(columns))
-		valuePtrs := make([]any, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
 
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
+	rowData := make(map[string]any)
+	for i, col := range columns {
+		// Filter out internal system column pkid - it is only exposed as seq
+		// on collections with expose_sequence
+		if hidden[col] {
+			continue
 		}
-
-		rowData := make(map[string]any)
-		for i, col := range columns {
-			// Filter out internal system column pkid - it must never be exposed via API
-			if col == "pkid" {
+		key := col
+		if col == constants.PKIDColumn {
+			if _, exposed := columnTypes[col]; !exposed {
 				continue
 			}
+			key = constants.SequenceField
+		}
 
-			val := values[i]
+		val := values[i]
 
-			// Convert []byte to string for text fields
-			if b, ok := val.([]byte); ok {
-				val = string(b)
+		// Keep json documents as read, without a copy when the driver returns bytes
+		if colType, exists := columnTypes[col]; keepRawJSON && exists && colType == registry.TypeJSON {
+			switch v := val.(type) {
+			case []byte:
+				val = rawJSON(v)
+			case string:
+				val = rawJSON(v)
 			}
+		}
 
-			// Convert boolean values (PRD-051: Boolean API Response Uniformity)
-			// SQLite stores booleans as integers (0/1), we need to convert to true/false
-			if colType, exists := columnTypes[col]; exists && colType == registry.TypeBoolean {
-				val = convertToBoolean(val)
-			}
+		// Convert []byte to string for text fields
+		if b, ok := val.([]byte); ok {
+			val = string(b)
+		}
 
-			// The 'id' column in the database is exposed as 'id' in the API
-			// (no special mapping needed now that the column is named 'id')
-			rowData[col] = val
+		// Convert boolean values (PRD-051: Boolean API Response Uniformity)
+		// SQLite stores booleans as integers (0/1), we need to convert to true/false
+		if colType, exists := columnTypes[col]; exists && colType == registry.TypeBoolean {
+			val = convertToBoolean(val)
 		}
 
-		result = append(result, rowData)
+		// Decimals are returned as strings with a fixed scale whatever the driver returns
+		if colType, exists := columnTypes[col]; exists && colType == registry.TypeDecimal {
+			val = formatDecimal(val)
+		}
+
+		// Datetimes are returned as UTC RFC3339 whatever the driver returns
+		if colType, exists := columnTypes[col]; exists && colType == registry.TypeDatetime {
+			val = formatDatetime(val)
+		}
+
+		// The 'id' column in the database is exposed as 'id' in the API
+		// (no special mapping needed now that the column is named 'id')
+		rowData[key] = val
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return rowData, nil
+}
+
+// booleanForms lists the text forms of a boolean that parseBoolean accepts
+const booleanForms = "true, false, 1 and 0 (case-insensitive)"
+
+// parseBoolean parses the text form of a boolean: true, false, 1 or 0 in any
+// case. Filters, boolean query parameters, imported CSV cells and lenient
+// writes all accept exactly this set, so "yes" or "t" fail the same way
+// everywhere.
+func parseBoolean(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
 	}
+	return false, fmt.Errorf("invalid boolean '%s': accepted values are %s", value, booleanForms)
+}
 
-	return result, nil
+// QueryBoolean parses the boolean query parameter name with parseBoolean,
+// returning def when it is absent. Errors are *apperrors.APIError values.
+func QueryBoolean(r *http.Request, name string, def bool) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	b, err := parseBoolean(value)
+	if err != nil {
+		return false, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidParameter, "invalid value for %s: %v", name, err)
+	}
+	return b, nil
+}
+
+// lenientBoolean converts a boolean written as text or as the number 0 or 1
+// into a bool, for clients that cannot send JSON booleans (api.lenient_booleans)
+func lenientBoolean(value any) (bool, bool) {
+	switch v := value.(type) {
+	case string:
+		b, err := parseBoolean(v)
+		return b, err == nil
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	case int64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	}
+	return false, false
 }
 
 // convertToBoolean converts various boolean representations to Go bool (PRD-051)
@@ -2361,8 +3183,9 @@ func convertToBoolean(val any) bool {
 	case uint64:
 		return v != 0
 	case string:
-		// Handle string representations
-		return v == "1" || v == "true" || v == "TRUE" || v == "t" || v == "T"
+		// Text forms are those of parseBoolean; anything else reads as false
+		b, _ := parseBoolean(v)
+		return b
 	default:
 		return false
 	}
@@ -2373,27 +3196,54 @@ func convertToBoolean(val any) bool {
 //
 //	if false, only validates fields that are present (for update operations)
 func validateFields(data map[string]any, collection *registry.Collection) error {
-	return validateFieldsWithMode(data, collection, true)
+	return validateFieldsWithMode(data, collection, true, false)
 }
 
 // validateFieldsForUpdate validates request data for update operations (doesn't require all fields)
 func validateFieldsForUpdate(data map[string]any, collection *registry.Collection) error {
-	return validateFieldsWithMode(data, collection, false)
+	return validateFieldsWithMode(data, collection, false, false)
 }
 
-// validateFieldsWithMode validates request data with configurable required field checking
-func validateFieldsWithMode(data map[string]any, collection *registry.Collection, requireAll bool) error {
+// validateCreateFields validates the data of a record to create, accepting
+// text and 0/1 booleans when api.lenient_booleans is set
+func (h *DataHandler) validateCreateFields(data map[string]any, collection *registry.Collection) error {
+	return validateFieldsWithMode(data, collection, true, h.config.API.LenientBooleans)
+}
+
+// validateUpdateFields validates the data of an update, accepting text and
+// 0/1 booleans when api.lenient_booleans is set
+func (h *DataHandler) validateUpdateFields(data map[string]any, collection *registry.Collection) error {
+	return validateFieldsWithMode(data, collection, false, h.config.API.LenientBooleans)
+}
+
+// validateFieldsWithMode validates request data with configurable required field checking.
+// With lenientBooleans, boolean fields given as "true", "false", "1", "0" or
+// the numbers 0 and 1 are converted to booleans in data.
+// Errors are *apperrors.APIError values whose code names the failed rule.
+func validateFieldsWithMode(data map[string]any, collection *registry.Collection, requireAll, lenientBooleans bool) error {
 	// Check for unknown fields
 	validFields := make(map[string]bool)
 	for _, col := range collection.Columns {
 		validFields[col.Name] = true
 	}
-	// Allow id (ULID column) in request data
+	// Allow id in request data; creates check it against the id strategy
 	validFields["id"] = true
+	if requireAll {
+		if err := validateCreateID(data, collection); err != nil {
+			return err
+		}
+	}
 
 	for field := range data {
+		// The seq field of collections with expose_sequence is assigned by the database
+		if field == constants.SequenceField && collection.ExposeSequence {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeReadOnlyField, "field '%s' is read-only", field)
+		}
+		if col, ok := findColumn(collection.Columns, field); ok && col.Computed != "" {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeReadOnlyField, "field '%s' is read-only; it is computed as %s", field, col.Computed)
+		}
 		if !validFields[field] {
-			return fmt.Errorf("unknown field '%s'", field)
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeUnknownField, "unknown field '%s'", field)
 		}
 	}
 
@@ -2403,11 +3253,11 @@ func validateFieldsWithMode(data map[string]any, collection *registry.Collection
 			val, exists := data[col.Name]
 			// For create operations, field must exist
 			if requireAll && !exists {
-				return fmt.Errorf("required field '%s' is missing (nullable=false)", col.Name)
+				return apperrors.Newf(http.StatusBadRequest, apperrors.CodeRequiredField, "required field '%s' is missing (nullable=false)", col.Name)
 			}
 			// For both create and update, provided values cannot be null
 			if exists && val == nil {
-				return fmt.Errorf("required field '%s' cannot be null (nullable=false)", col.Name)
+				return apperrors.Newf(http.StatusBadRequest, apperrors.CodeNullField, "required field '%s' cannot be null (nullable=false)", col.Name)
 			}
 		}
 	}
@@ -2415,8 +3265,26 @@ func validateFieldsWithMode(data map[string]any, collection *registry.Collection
 	// Validate field types
 	for _, col := range collection.Columns {
 		if val, ok := data[col.Name]; ok && val != nil {
+			if lenientBooleans && col.Type == registry.TypeBoolean {
+				if _, isBool := val.(bool); !isBool {
+					b, ok := lenientBoolean(val)
+					if !ok {
+						return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidType, "field '%s' must be a boolean or one of %s", col.Name, booleanForms)
+					}
+					val = b
+					data[col.Name] = val
+				}
+			}
 			if err := validateFieldType(col.Name, val, col.Type); err != nil {
-				return err
+				return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidType, err.Error())
+			}
+			// Datetimes are stored as UTC RFC3339 so text comparison follows time order
+			if col.Type == registry.TypeDatetime {
+				val, _ = validateDatetimeField(col.Name, val)
+				data[col.Name] = val
+			}
+			if err := validateFieldConstraints(col, val); err != nil {
+				return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidFieldValue, err.Error())
 			}
 		}
 	}
@@ -2427,10 +3295,13 @@ func validateFieldsWithMode(data map[string]any, collection *registry.Collection
 // validateFieldType validates a field value against expected type
 func validateFieldType(fieldName string, value any, expectedType registry.ColumnType) error {
 	switch expectedType {
-	case registry.TypeString, registry.TypeDatetime:
+	case registry.TypeString:
 		if _, ok := value.(string); !ok {
 			return fmt.Errorf("field '%s' must be a string", fieldName)
 		}
+	case registry.TypeDatetime:
+		_, err := validateDatetimeField(fieldName, value)
+		return err
 	case registry.TypeInteger:
 		switch value.(type) {
 		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float64:
@@ -2442,18 +3313,151 @@ func validateFieldType(fieldName string, value any, expectedType registry.Column
 		if _, ok := value.(bool); !ok {
 			return fmt.Errorf("field '%s' must be a boolean", fieldName)
 		}
+	case registry.TypeDecimal:
+		return validateDecimalField(fieldName, value)
 	case registry.TypeJSON:
-		// JSON can be any type
+		// Objects, arrays, numbers and booleans are serialized on write;
+		// strings are stored as is and must hold a JSON document
+		if s, ok := value.(string); ok && !json.Valid([]byte(s)) {
+			return fmt.Errorf("field '%s' must be valid JSON", fieldName)
+		}
 	}
 
 	return nil
 }
 
+// columnValue returns the value written to the database for a column.
+// JSON columns given as objects, arrays, numbers or booleans are serialized
+// into canonical JSON (object keys sorted) rather than left to the driver.
+func columnValue(col registry.Column, value any) any {
+	if col.Type != registry.TypeJSON || value == nil {
+		return value
+	}
+	if _, ok := value.(string); ok {
+		return value
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return string(data)
+}
+
+// buildDestroyQuery returns the statement that deletes a record by ULID.
+// Soft-delete collections keep the row and set deleted_at instead; rows that
+// are already deleted do not match, so a repeated destroy reports not found.
+// A non-nil rev restricts the delete to that revision.
+func buildDestroyQuery(collection *registry.Collection, id string, rev *int64, dialect database.DialectType) (string, []any) {
+	if !collection.SoftDelete {
+		sqlQuery := fmt.Sprintf("DELETE FROM %s WHERE id = %s", query.QuoteIdent(dialect, collection.Name), query.Placeholder(dialect, 1))
+		condition, args := revisionCondition(rev, []any{id}, dialect)
+		return sqlQuery + condition, args
+	}
+
+	deletedAt := currentTimestamp()
+	sqlQuery := fmt.Sprintf("UPDATE %s SET %s = %s WHERE id = %s AND %s IS NULL",
+		query.QuoteIdent(dialect, collection.Name),
+		constants.SoftDeleteColumn,
+		query.Placeholder(dialect, 1),
+		query.Placeholder(dialect, 2),
+		constants.SoftDeleteColumn)
+	condition, args := revisionCondition(rev, []any{deletedAt, id}, dialect)
+	return sqlQuery + condition, args
+}
+
+// hiddenColumns returns the names of the hidden columns of a collection
+func hiddenColumns(collection *registry.Collection) map[string]bool {
+	hidden := make(map[string]bool)
+	for _, col := range collection.Columns {
+		if col.Hidden {
+			hidden[col.Name] = true
+		}
+	}
+	return hidden
+}
+
+// responseHidden returns the columns left out of the records a read returns:
+// the hidden columns, unless ?include_hidden=true is passed by an admin whose
+// credential holds the schema scope on the collection. Errors are
+// *apperrors.APIError values.
+func responseHidden(r *http.Request, collectionName string, collection *registry.Collection) (map[string]bool, error) {
+	include, err := QueryBoolean(r, "include_hidden", false)
+	if err != nil {
+		return nil, err
+	}
+	if !include {
+		return hiddenColumns(collection), nil
+	}
+
+	ctx := r.Context()
+	if entity, ok := middleware.GetAuthEntity(ctx); ok && entity.Role != string(auth.RoleAdmin) {
+		return nil, apperrors.NewAPIError(http.StatusForbidden, apperrors.CodeForbidden, "include_hidden requires an admin credential with the schema scope")
+	}
+	if !middleware.HasScope(ctx, logicalName(r, collectionName), auth.ScopeSchema) {
+		return nil, apperrors.NewAPIError(http.StatusForbidden, apperrors.CodeForbidden, "include_hidden requires an admin credential with the schema scope")
+	}
+	return nil, nil
+}
+
+// RequestsHidden reports whether a read passes ?include_hidden. Whether the
+// hidden columns are returned depends on the credential, which the query
+// cache key does not hold, so such reads are never cached.
+func RequestsHidden(r *http.Request) bool {
+	return r.URL.Query().Get("include_hidden") != ""
+}
+
+// excludeDeleted reports whether soft-deleted records should be hidden from a read.
+// Records are hidden for soft-delete collections unless ?include_deleted=true is set.
+func excludeDeleted(r *http.Request, collection *registry.Collection) bool {
+	if !collection.SoftDelete {
+		return false
+	}
+	includeStr := r.URL.Query().Get("include_deleted")
+	return includeStr != "true" && includeStr != "1"
+}
+
+// withSoftDeleteFilter appends a deleted_at IS NULL condition when excludeDeleted applies
+func withSoftDeleteFilter(r *http.Request, collection *registry.Collection, conditions []query.Condition) []query.Condition {
+	if !excludeDeleted(r, collection) {
+		return conditions
+	}
+	return append(conditions, query.Condition{
+		Column:   constants.SoftDeleteColumn,
+		Operator: query.OpIsNull,
+	})
+}
+
+// withExpiryFilter appends the condition hiding expired records when the
+// collection has a ttl policy
+func withExpiryFilter(collection *registry.Collection, conditions []query.Condition) []query.Condition {
+	if live, ok := expiry.Live(collection, expiry.Now()); ok {
+		return append(conditions, live)
+	}
+	return conditions
+}
+
 // generateULID generates a new ULID
 func generateULID() string {
 	return moonulid.Generate()
 }
 
+// generateULIDs generates n ULIDs in ascending order
+func generateULIDs(n int) []string {
+	return moonulid.GenerateBatch(n)
+}
+
+// logModule is the module of the handler logs, configured by logging.levels.handlers
+const logModule = "handlers"
+
+// logQuery logs the final SQL of a list, aggregation or batch statement at
+// debug level. Only the number of bound args is logged, never their values.
+func logQuery(ctx context.Context, operation, sql string, args []any) {
+	if !logging.Enabled(logModule, logging.LevelDebug) {
+		return
+	}
+	logging.WithContext(ctx).Module(logModule).Debugf("%s SQL: %s [%d args]", operation, sql, len(args))
+}
+
 // validateULID validates a ULID string
 func validateULID(id string) error {
 	return moonulid.Validate(id)
@@ -2500,17 +3504,30 @@ func (h *DataHandler) validateBatchSize(size int) error {
 	return nil
 }
 
-// validatePayloadSize checks if payload size is within configured limits (PRD-064)
-func (h *DataHandler) validatePayloadSize(r *http.Request) error {
+// validatePayloadSize checks if payload size is within configured limits (PRD-064).
+// A declared Content-Length over the limit is rejected up front; the body is
+// also wrapped in http.MaxBytesReader so chunked uploads without a length are
+// cut off mid-read and reported through writeBodyError.
+func (h *DataHandler) validatePayloadSize(w http.ResponseWriter, r *http.Request) error {
 	maxSize := int64(h.config.Batch.MaxPayloadBytes)
-	// ContentLength can be -1 if not provided by the client
-	// In that case, we'll let it through and rely on batch size limit
-	if r.ContentLength > 0 && r.ContentLength > maxSize {
+	if r.ContentLength > maxSize {
 		return fmt.Errorf("payload size %d exceeds limit of %d bytes", r.ContentLength, maxSize)
 	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
 	return nil
 }
 
+// writeBodyError reports a failure reading the request body: 413 when the
+// payload limit was reached mid-read, otherwise 400 with the given message
+func writeBodyError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, fmt.Sprintf("payload exceeds limit of %d bytes", maxBytesErr.Limit))
+		return
+	}
+	writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, message)
+}
+
 // getDefaultValue returns the appropriate default value for a field
 // based on the column definition and global defaults.
 //
@@ -2559,7 +3576,10 @@ func getDefaultValue(col registry.Column) any {
 			lower := strings.ToLower(defaultStr)
 			return lower == "true" || lower == "1"
 		case registry.TypeDatetime:
-			// Keep as string (RFC3339 format)
+			// Stored in the same UTC RFC3339 form as written values
+			if normalized, err := datetime.Normalize(defaultStr); err == nil {
+				return normalized
+			}
 			return defaultStr
 		case registry.TypeJSON:
 			// Keep as string (JSON content)
@@ -33,24 +33,24 @@ func TestBuildSearchQueryWithFields_Extended(t *testing.T) {
 			tableName:  "products",
 			fields:     []string{},
 			filters:    []query.Condition{},
-			searchSQL:  "(name LIKE ?)",
+			searchSQL:  `("name" LIKE ?)`,
 			searchArgs: []any{"%test%"},
 			orderBy:    "",
 			limit:      10,
 			dialect:    database.DialectSQLite,
-			wantSQL:    "SELECT * FROM products WHERE (name LIKE ?) LIMIT ?",
+			wantSQL:    `SELECT * FROM "products" WHERE ("name" LIKE ?) LIMIT ?`,
 		},
 		{
 			name:       "select specific fields",
 			tableName:  "products",
 			fields:     []string{"id", "name", "price"},
 			filters:    []query.Condition{},
-			searchSQL:  "(name LIKE ?)",
+			searchSQL:  `("name" LIKE ?)`,
 			searchArgs: []any{"%test%"},
 			orderBy:    "",
 			limit:      10,
 			dialect:    database.DialectSQLite,
-			wantSQL:    "SELECT id, name, price FROM products WHERE (name LIKE ?) LIMIT ?",
+			wantSQL:    `SELECT "id", "name", "price" FROM "products" WHERE ("name" LIKE ?) LIMIT ?`,
 		},
 		{
 			name:      "with filters",
@@ -59,24 +59,24 @@ func TestBuildSearchQueryWithFields_Extended(t *testing.T) {
 			filters: []query.Condition{
 				{Column: "price", Operator: ">", Value: 100},
 			},
-			searchSQL:  "(name LIKE ?)",
+			searchSQL:  `("name" LIKE ?)`,
 			searchArgs: []any{"%test%"},
 			orderBy:    "",
 			limit:      10,
 			dialect:    database.DialectSQLite,
-			wantSQL:    "SELECT * FROM products WHERE (name LIKE ?) AND price > ? LIMIT ?",
+			wantSQL:    `SELECT * FROM "products" WHERE ("name" LIKE ?) AND "price" > ? LIMIT ?`,
 		},
 		{
 			name:       "with order by",
 			tableName:  "products",
 			fields:     []string{},
 			filters:    []query.Condition{},
-			searchSQL:  "(name LIKE ?)",
+			searchSQL:  `("name" LIKE ?)`,
 			searchArgs: []any{"%test%"},
-			orderBy:    "price DESC",
+			orderBy:    `"price" DESC`,
 			limit:      10,
 			dialect:    database.DialectSQLite,
-			wantSQL:    "SELECT * FROM products WHERE (name LIKE ?) ORDER BY price DESC LIMIT ?",
+			wantSQL:    `SELECT * FROM "products" WHERE ("name" LIKE ?) ORDER BY "price" DESC LIMIT ?`,
 		},
 		{
 			name:       "postgres dialect with fields",
@@ -109,12 +109,12 @@ func TestBuildSearchQueryWithFields_Extended(t *testing.T) {
 			filters: []query.Condition{
 				{Column: "status", Operator: query.OpIn, Value: []any{"active", "pending"}},
 			},
-			searchSQL:  "(name LIKE ?)",
+			searchSQL:  `("name" LIKE ?)`,
 			searchArgs: []any{"%test%"},
 			orderBy:    "",
 			limit:      10,
 			dialect:    database.DialectSQLite,
-			wantSQL:    "SELECT * FROM products WHERE (name LIKE ?) AND status IN (?, ?) LIMIT ?",
+			wantSQL:    `SELECT * FROM "products" WHERE ("name" LIKE ?) AND "status" IN (?, ?) LIMIT ?`,
 		},
 	}
 
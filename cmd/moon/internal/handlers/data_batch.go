@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+)
+
+// batchFlushInterval is the number of streamed batch results written between flushes
+const batchFlushInterval = 50
+
+// succeeded reports whether a batch item status records a successful item
+func (s BatchItemStatus) succeeded() bool {
+	switch s {
+	case BatchItemCreated, BatchItemUpdated, BatchItemDeleted, BatchItemRestored:
+		return true
+	}
+	return false
+}
+
+// batchResultWriter streams a best-effort BatchResponse: each item result is
+// written as soon as it is known and the summary follows the results array,
+// so large batches are never held in memory twice
+type batchResultWriter struct {
+	ctx      context.Context // request context; succeeded items are noted for its audit entry
+	w        http.ResponseWriter
+	enc      *json.Encoder
+	summary  BatchSummary
+	version  apiversion.Version // API version the results are written in
+	keep     BatchItemStatus    // status of the results retained for the change feed and webhook event
+	keepData bool               // retained results keep their data, for the webhook event
+	retained []BatchItemResult
+	journal  *batchJournal // progress of a batch sent with journal=true
+}
+
+// batchJournal writes the progress of a journaled batch to its job every
+// interval results, and ends the job with the summary
+type batchJournal struct {
+	store    *jobs.Store
+	job      *jobs.Job
+	interval int
+}
+
+// newBatchResultWriter starts a 207 Multi-Status response. Results with the
+// keep status are retained for publishResults when the change feed or
+// webhooks are enabled, with their data only for webhooks.
+// Results are written in the API version negotiated for ctx.
+func (h *DataHandler) newBatchResultWriter(w http.ResponseWriter, ctx context.Context, keep BatchItemStatus) *batchResultWriter {
+	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, `{"results":[`)
+
+	bw := &batchResultWriter{ctx: ctx, w: w, enc: json.NewEncoder(w), version: apiversion.FromContext(ctx)}
+	if h.changes != nil || h.webhooks != nil {
+		bw.keep = keep
+		bw.keepData = h.webhooks != nil
+	}
+	return bw
+}
+
+// add writes one item result and counts it in the summary. Results are
+// retained with their column names, whatever the field case of the response.
+func (b *batchResultWriter) add(result BatchItemResult) {
+	if b.summary.Total > 0 {
+		io.WriteString(b.w, ",")
+	}
+	written := result
+	if camelFields(b.ctx) {
+		written = result.camelCased().(BatchItemResult)
+	}
+	b.enc.Encode(written.forVersion(b.version))
+
+	b.summary.Total++
+	if result.Status.succeeded() {
+		b.summary.Succeeded++
+		audit.AddRecords(b.ctx, result.ID)
+	} else {
+		b.summary.Failed++
+	}
+	if b.keep != "" && result.Status == b.keep {
+		if !b.keepData {
+			result.Data = nil
+		}
+		b.retained = append(b.retained, result)
+	}
+
+	if b.journal != nil {
+		b.journal.add(b.ctx, result)
+	}
+
+	if b.summary.Total%batchFlushInterval == 0 {
+		if flusher, ok := b.w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+}
+
+// close writes the summary, ends the response and returns the retained
+// results. A journaled batch is marked completed.
+func (b *batchResultWriter) close() []BatchItemResult {
+	if b.journal != nil {
+		b.journal.finish(b.ctx)
+	}
+	io.WriteString(b.w, `],"summary":`)
+	b.enc.Encode(b.summary)
+	io.WriteString(b.w, "}\n")
+	return b.retained
+}
+
+// add counts one item result in the job and writes the job's progress every
+// interval items. Errors are logged: the items are written either way.
+func (j *batchJournal) add(ctx context.Context, result BatchItemResult) {
+	j.job.Processed = result.Index + 1
+	if result.Status.succeeded() {
+		index := result.Index
+		j.job.Succeeded, j.job.LastIndex = j.job.Succeeded+1, &index
+	} else {
+		j.job.Failed++
+	}
+	if j.job.Processed%j.interval == 0 {
+		if err := j.store.Progress(ctx, nil, j.job); err != nil {
+			log.Printf("WARNING: Failed to record progress of job %s: %v", j.job.ID, err)
+		}
+	}
+}
+
+// finish marks the job completed, even when the client went away
+func (j *batchJournal) finish(ctx context.Context) {
+	if err := j.store.Finish(context.WithoutCancel(ctx), j.job, jobs.StatusCompleted, nil); err != nil {
+		log.Printf("WARNING: Failed to end job %s: %v", j.job.ID, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,7 +14,9 @@ import (
 
 	"github.com/thalib/moon/cmd/moon/internal/config"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/webhook"
 )
 
 // TestBatchCreate_BestEffort_PartialSuccess tests batch create with partial success
@@ -102,6 +105,83 @@ func TestBatchCreate_BatchSizeExceeded(t *testing.T) {
 	}
 }
 
+// TestBatch_ChunkedPayloadTooLarge tests that a body without Content-Length is
+// cut off at the payload limit instead of being read in full
+func TestBatch_ChunkedPayloadTooLarge(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name:    "products",
+		Columns: []registry.Column{{Name: "name", Type: registry.TypeString, Nullable: false}},
+	})
+
+	cfg := &config.AppConfig{Batch: config.BatchConfig{MaxSize: 50, MaxPayloadBytes: 1024}}
+	handler := NewDataHandler(&mockDataDriver{dialect: database.DialectSQLite}, reg, cfg)
+
+	actions := map[string]func(http.ResponseWriter, *http.Request, string){
+		"create":  handler.Create,
+		"update":  handler.Update,
+		"destroy": handler.Destroy,
+	}
+	for name, action := range actions {
+		t.Run(name, func(t *testing.T) {
+			// One oversized item inside an otherwise small batch
+			body := io.MultiReader(
+				strings.NewReader(`{"data": [{"name": "small"}, {"name": "`),
+				strings.NewReader(strings.Repeat("x", 64*1024)),
+				strings.NewReader(`"}]}`),
+			)
+			req := httptest.NewRequest(http.MethodPost, "/products:"+name, body)
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+			w := httptest.NewRecorder()
+
+			action(w, req, "products")
+
+			assertErrorCode(t, w, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge)
+		})
+	}
+}
+
+// TestBatchResultWriter tests that streamed results decode as a BatchResponse
+// and that results are only retained when webhooks are enabled
+func TestBatchResultWriter(t *testing.T) {
+	handler := NewDataHandler(&mockDataDriver{dialect: database.DialectSQLite}, registry.NewSchemaRegistry(), testConfig())
+
+	w := httptest.NewRecorder()
+	out := handler.newBatchResultWriter(w, context.Background(), BatchItemCreated)
+	for i := 0; i < batchFlushInterval+1; i++ {
+		status := BatchItemCreated
+		if i%2 == 1 {
+			status = BatchItemFailed
+		}
+		out.add(BatchItemResult{Index: i, Status: status})
+	}
+	if retained := out.close(); retained != nil {
+		t.Errorf("expected no retained results without webhooks, got %d", len(retained))
+	}
+
+	if w.Code != http.StatusMultiStatus {
+		t.Errorf("expected status %d, got %d", http.StatusMultiStatus, w.Code)
+	}
+	var response BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode streamed response: %v", err)
+	}
+	want := BatchSummary{Total: batchFlushInterval + 1, Succeeded: batchFlushInterval/2 + 1, Failed: batchFlushInterval / 2}
+	if len(response.Results) != want.Total || response.Summary != want {
+		t.Errorf("expected %d results and summary %+v, got %d and %+v", want.Total, want, len(response.Results), response.Summary)
+	}
+
+	// With webhooks enabled the successful results are kept for the event
+	handler.SetWebhooks(&webhook.Dispatcher{})
+	out = handler.newBatchResultWriter(httptest.NewRecorder(), context.Background(), BatchItemCreated)
+	out.add(BatchItemResult{Index: 0, ID: "a", Status: BatchItemCreated})
+	out.add(BatchItemResult{Index: 1, Status: BatchItemFailed})
+	if retained := out.close(); len(retained) != 1 || retained[0].ID != "a" {
+		t.Errorf("expected the created result to be retained, got %+v", retained)
+	}
+}
+
 // TestBatchUpdate_BestEffort tests batch update in best-effort mode
 func TestBatchUpdate_BestEffort(t *testing.T) {
 	reg := registry.NewSchemaRegistry()
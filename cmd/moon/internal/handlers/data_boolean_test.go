@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// Tokens accepted as booleans by filters, query parameters, imports and lenient
+// writes, and tokens other parsers take that must be rejected
+var (
+	acceptedBooleans = map[string]bool{
+		"true": true, "TRUE": true, "True": true, "tRuE": true, "1": true,
+		"false": false, "FALSE": false, "False": false, "fAlSe": false, "0": false,
+	}
+	rejectedBooleans = []string{"yes", "no", "YES", "t", "f", "T", "F", "y", "n", "on", "off", "2", "-1", "01", "1.0", " true", "true ", ""}
+)
+
+func newBooleanTestHandler(t *testing.T, lenient bool) *DataHandler {
+	t.Helper()
+	driver := createTestDB(t)
+	t.Cleanup(func() { driver.Close() })
+
+	// SQLite stores booleans as integers
+	_, err := driver.Exec(context.Background(), `CREATE TABLE products (
+		id TEXT PRIMARY KEY,
+		created_at TEXT,
+		updated_at TEXT,
+		_rev INTEGER NOT NULL DEFAULT 1,
+		name TEXT NOT NULL,
+		active BOOLEAN
+	)`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "name", Type: registry.TypeString},
+			{Name: "active", Type: registry.TypeBoolean, Nullable: true},
+		},
+	})
+	cfg := testConfig()
+	cfg.API.LenientBooleans = lenient
+	return NewDataHandler(driver, reg, cfg)
+}
+
+func TestParseBoolean(t *testing.T) {
+	for token, want := range acceptedBooleans {
+		got, err := parseBoolean(token)
+		if err != nil || got != want {
+			t.Errorf("parseBoolean(%q) = %v, %v; want %v", token, got, err, want)
+		}
+	}
+	for _, token := range rejectedBooleans {
+		_, err := parseBoolean(token)
+		if err == nil {
+			t.Errorf("parseBoolean(%q): expected an error", token)
+			continue
+		}
+		if !strings.Contains(err.Error(), "accepted values are true, false, 1 and 0") {
+			t.Errorf("parseBoolean(%q): error does not list the accepted values: %v", token, err)
+		}
+	}
+}
+
+func TestConvertToBoolean(t *testing.T) {
+	for token, want := range acceptedBooleans {
+		if got := convertToBoolean(token); got != want {
+			t.Errorf("convertToBoolean(%q) = %v, want %v", token, got, want)
+		}
+	}
+	for _, token := range rejectedBooleans {
+		if convertToBoolean(token) {
+			t.Errorf("convertToBoolean(%q) = true, want false", token)
+		}
+	}
+	tests := []struct {
+		value any
+		want  bool
+	}{
+		{nil, false},
+		{true, true},
+		{false, false},
+		{int64(1), true},
+		{int64(0), false},
+		{int64(2), true},
+		{uint8(1), true},
+		{1.0, false},
+	}
+	for _, tt := range tests {
+		if got := convertToBoolean(tt.value); got != tt.want {
+			t.Errorf("convertToBoolean(%#v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestQueryBoolean(t *testing.T) {
+	for token, want := range acceptedBooleans {
+		r := httptest.NewRequest(http.MethodGet, "/products:list?total="+url.QueryEscape(token), nil)
+		if got, err := QueryBoolean(r, "total", !want); err != nil || got != want {
+			t.Errorf("QueryBoolean(total=%q) = %v, %v; want %v", token, got, err, want)
+		}
+	}
+	for _, token := range rejectedBooleans {
+		if token == "" {
+			continue
+		}
+		r := httptest.NewRequest(http.MethodGet, "/products:list?total="+url.QueryEscape(token), nil)
+		_, err := QueryBoolean(r, "total", false)
+		if err == nil || !strings.Contains(err.Error(), "accepted values are true, false, 1 and 0") {
+			t.Errorf("QueryBoolean(total=%q): expected an error listing the accepted values, got %v", token, err)
+		}
+	}
+
+	// An absent parameter takes the default
+	r := httptest.NewRequest(http.MethodGet, "/products:list", nil)
+	if got, err := QueryBoolean(r, "total", true); err != nil || !got {
+		t.Errorf("QueryBoolean() without total = %v, %v; want the default", got, err)
+	}
+}
+
+func TestDataHandler_BooleanQueryParams(t *testing.T) {
+	handler := newBooleanTestHandler(t, false)
+	for query, status := range map[string]int{
+		"total=TRUE":           http.StatusOK,
+		"total=0":              http.StatusOK,
+		"total=yes":            http.StatusBadRequest,
+		"strict=t":             http.StatusBadRequest,
+		"include_hidden=on":    http.StatusBadRequest,
+		"include_hidden=FALSE": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		handler.List(w, httptest.NewRequest(http.MethodGet, "/products:list?"+query, nil), "products")
+		if w.Code != status {
+			t.Errorf("%s: expected %d, got %d: %s", query, status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestDataHandler_BooleanFilters(t *testing.T) {
+	handler := newBooleanTestHandler(t, false)
+	for _, data := range []map[string]any{
+		{"name": "on", "active": true},
+		{"name": "off", "active": false},
+		{"name": "unset", "active": nil},
+	} {
+		if w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: data}, ""); w.Code != http.StatusCreated {
+			t.Fatalf("failed to create %v: %s", data["name"], w.Body.String())
+		}
+	}
+
+	names := func(resp DataListResponse) string {
+		var out []string
+		for _, record := range resp.Data {
+			out = append(out, record["name"].(string))
+		}
+		return strings.Join(out, ",")
+	}
+
+	for token, want := range acceptedBooleans {
+		match, other := "on", "off"
+		if !want {
+			match, other = "off", "on"
+		}
+		q := url.QueryEscape(token)
+		if got := names(listPage(t, handler, "active[eq]="+q)); got != match {
+			t.Errorf("active[eq]=%s: expected %s, got %s", token, match, got)
+		}
+		if got := names(listPage(t, handler, "active[ne]="+q)); got != other {
+			t.Errorf("active[ne]=%s: expected %s, got %s", token, other, got)
+		}
+		if got, want := names(listPage(t, handler, "active[isnull]="+q+"&sort=name")), map[bool]string{true: "unset", false: "off,on"}[want]; got != want {
+			t.Errorf("active[isnull]=%s: expected %s, got %s", token, want, got)
+		}
+		if got := names(listPage(t, handler, "active[in]="+q+",0&sort=name")); got != "off,on" && got != "off" {
+			t.Errorf("active[in]=%s,0: unexpected %s", token, got)
+		}
+	}
+
+	for _, token := range rejectedBooleans {
+		for _, op := range []string{"eq", "ne", "in", "isnull"} {
+			// In-lists trim the spaces around their values
+			if op == "in" && token != strings.TrimSpace(token) {
+				continue
+			}
+			query := fmt.Sprintf("active[%s]=%s", op, url.QueryEscape(token))
+			req := httptest.NewRequest(http.MethodGet, "/products:list?"+query, nil)
+			w := httptest.NewRecorder()
+			handler.List(w, req, "products")
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d: %s", query, w.Code, w.Body.String())
+				continue
+			}
+			if !strings.Contains(w.Body.String(), "accepted values are true, false, 1 and 0") {
+				t.Errorf("%s: error does not list the accepted values: %s", query, w.Body.String())
+			}
+		}
+	}
+
+	// Responses render the stored integers as JSON booleans
+	for _, record := range listPage(t, handler, "active[isnull]=false").Data {
+		if _, ok := record["active"].(bool); !ok {
+			t.Errorf("expected a boolean for %v, got %#v", record["name"], record["active"])
+		}
+	}
+}
+
+// booleanWrite is a boolean field value written with lenient_booleans off
+// and on, and whether each write succeeds and stores want
+type booleanWrite struct {
+	name      string
+	value     any
+	strictOK  bool
+	lenientOK bool
+	want      bool
+}
+
+func TestDataHandler_LenientBooleans(t *testing.T) {
+	tests := []booleanWrite{
+		{"json true", true, true, true, true},
+		{"json false", false, true, true, false},
+		{"number 1", 1, false, true, true},
+		{"number 0", 0, false, true, false},
+		{"number 2", 2, false, false, false},
+		{"number 0.5", 0.5, false, false, false},
+		{"object", map[string]any{"v": true}, false, false, false},
+	}
+	for token, want := range acceptedBooleans {
+		tests = append(tests, booleanWrite{"string " + token, token, false, true, want})
+	}
+	for _, token := range rejectedBooleans {
+		tests = append(tests, booleanWrite{"string " + token, token, false, false, false})
+	}
+
+	for _, lenient := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lenient=%v", lenient), func(t *testing.T) {
+			handler := newBooleanTestHandler(t, lenient)
+			for i, tt := range tests {
+				ok := tt.strictOK
+				if lenient {
+					ok = tt.lenientOK
+				}
+				name := fmt.Sprintf("r%d", i)
+
+				w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: map[string]any{"name": name, "active": tt.value}}, "")
+				if !ok {
+					if w.Code != http.StatusBadRequest {
+						t.Errorf("%s: expected create to fail with 400, got %d", tt.name, w.Code)
+					} else if lenient && !strings.Contains(w.Body.String(), "one of true, false, 1 and 0") {
+						t.Errorf("%s: error does not list the accepted values: %s", tt.name, w.Body.String())
+					}
+					continue
+				}
+				if w.Code != http.StatusCreated {
+					t.Errorf("%s: expected 201, got %d: %s", tt.name, w.Code, w.Body.String())
+					continue
+				}
+				record := listPage(t, handler, "name[eq]="+name).Data[0]
+				if record["active"] != tt.want {
+					t.Errorf("%s: expected %v stored, got %#v", tt.name, tt.want, record["active"])
+				}
+
+				// Updates accept the same values
+				body := UpdateDataRequest{ID: record["id"].(string), Data: map[string]any{"active": tt.value}}
+				if w := postData(t, handler.Update, "/products:update", body, ""); w.Code != http.StatusOK {
+					t.Errorf("%s: expected update to succeed, got %d: %s", tt.name, w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
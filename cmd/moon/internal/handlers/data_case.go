@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/schema"
+)
+
+// fieldCaseKey is the context key of the field case of a data request
+type fieldCaseKey struct{}
+
+// withFieldCase resolves the field case of a data request from ?case=,
+// falling back to api.field_case, and stores it in the request context where
+// response writers read it. An invalid value is answered with 400 and
+// reported as false.
+func (h *DataHandler) withFieldCase(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	fieldCase := h.config.API.FieldCase
+	if value := r.URL.Query().Get("case"); value != "" {
+		fieldCase = value
+	}
+	switch fieldCase {
+	case "", config.FieldCaseSnake:
+		return r, true
+	case config.FieldCaseCamel:
+		return r.WithContext(context.WithValue(r.Context(), fieldCaseKey{}, fieldCase)), true
+	}
+	writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("case must be %s or %s", config.FieldCaseSnake, config.FieldCaseCamel))
+	return r, false
+}
+
+// camelFields reports whether the records of a response are written with
+// camelCase field names
+func camelFields(ctx context.Context) bool {
+	fieldCase, _ := ctx.Value(fieldCaseKey{}).(string)
+	return fieldCase == config.FieldCaseCamel
+}
+
+// camelCase returns a column name in camelCase: each underscore followed by
+// a lowercase letter is dropped and the letter uppercased, so unit_price
+// becomes unitPrice. Leading underscores and underscores before a digit or
+// another underscore are kept: _rev stays _rev, line_2 stays line_2 and
+// a__b becomes a_B. As column names hold no uppercase letters, snakeCase
+// inverts it exactly.
+func camelCase(name string) string {
+	if !strings.Contains(name, "_") {
+		return name
+	}
+	start := len(name) - len(strings.TrimLeft(name, "_"))
+	var sb strings.Builder
+	sb.Grow(len(name))
+	sb.WriteString(name[:start])
+	for i := start; i < len(name); i++ {
+		if name[i] == '_' && i+1 < len(name) && name[i+1] >= 'a' && name[i+1] <= 'z' {
+			sb.WriteByte(name[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
+
+// snakeCase returns a field name in snake_case by replacing each uppercase
+// letter with an underscore and the lowercase letter. Names without
+// uppercase letters are returned unchanged.
+func snakeCase(name string) string {
+	if strings.IndexFunc(name, unicode.IsUpper) < 0 {
+		return name
+	}
+	var sb strings.Builder
+	sb.Grow(len(name) + 4)
+	for _, c := range name {
+		if unicode.IsUpper(c) {
+			sb.WriteByte('_')
+			c = unicode.ToLower(c)
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// camelRecord returns a copy of a record with camelCase field names, also in
+// the records expanded under _expanded. Values are not changed, so the keys
+// of json columns keep their names.
+func camelRecord(record map[string]any) map[string]any {
+	if record == nil {
+		return nil
+	}
+	cased := make(map[string]any, len(record))
+	for key, value := range record {
+		if key == constants.ExpandedField {
+			if expanded, ok := value.(map[string]any); ok {
+				value = camelExpanded(expanded)
+			}
+		}
+		cased[camelCase(key)] = value
+	}
+	return cased
+}
+
+// camelExpanded returns the _expanded object of a record with camelCase field
+// names and camelCase expanded records
+func camelExpanded(expanded map[string]any) map[string]any {
+	cased := make(map[string]any, len(expanded))
+	for field, value := range expanded {
+		if target, ok := value.(map[string]any); ok {
+			value = camelRecord(target)
+		}
+		cased[camelCase(field)] = value
+	}
+	return cased
+}
+
+// camelRecords returns copies of records with camelCase field names
+// camelChanges renames the fields of an update diff to camelCase
+func camelChanges(changes map[string]FieldChange) map[string]FieldChange {
+	if changes == nil {
+		return nil
+	}
+	cased := make(map[string]FieldChange, len(changes))
+	for key, change := range changes {
+		cased[camelCase(key)] = change
+	}
+	return cased
+}
+
+func camelRecords(records []map[string]any) []map[string]any {
+	if records == nil {
+		return nil
+	}
+	cased := make([]map[string]any, len(records))
+	for i, record := range records {
+		cased[i] = camelRecord(record)
+	}
+	return cased
+}
+
+// normalizeFieldNames renames the camelCase fields of a written record to
+// their snake_case column names, so that either convention is accepted
+// whatever the response case. Only the top-level fields are renamed. A field
+// given in both conventions is an error.
+func normalizeFieldNames(data map[string]any) error {
+	for key, value := range data {
+		name := snakeCase(key)
+		if name == key {
+			continue
+		}
+		if _, dup := data[name]; dup {
+			return fmt.Errorf("fields '%s' and '%s' name the same column", key, name)
+		}
+		delete(data, key)
+		data[name] = value
+	}
+	return nil
+}
+
+// normalizeItemFieldNames applies normalizeFieldNames to each item of a batch
+func normalizeItemFieldNames(items []map[string]any) error {
+	for idx, item := range items {
+		if err := normalizeFieldNames(item); err != nil {
+			return fmt.Errorf("item %d: %v", idx, err)
+		}
+	}
+	return nil
+}
+
+// camelCasedResponse is a response holding records, written with camelCase
+// field names when the request asks for them
+type camelCasedResponse interface {
+	camelCased() any
+}
+
+func (resp DataListResponse) camelCased() any {
+	resp.Data = camelRecords(resp.Data)
+	return resp
+}
+
+func (resp DataGetResponse) camelCased() any {
+	resp.Data = camelRecord(resp.Data)
+	return resp
+}
+
+func (resp CreateDataResponse) camelCased() any {
+	resp.Data = camelRecord(resp.Data)
+	return resp
+}
+
+func (resp UpdateDataResponse) camelCased() any {
+	resp.Data = camelRecord(resp.Data)
+	resp.Changed = camelChanges(resp.Changed)
+	return resp
+}
+
+func (resp BatchCreateResponse) camelCased() any {
+	resp.Data = camelRecords(resp.Data)
+	return resp
+}
+
+func (resp BatchUpdateResponse) camelCased() any {
+	resp.Data = camelRecords(resp.Data)
+	if resp.Changed != nil {
+		changes := make([]map[string]FieldChange, len(resp.Changed))
+		for i, changed := range resp.Changed {
+			changes[i] = camelChanges(changed)
+		}
+		resp.Changed = changes
+	}
+	return resp
+}
+
+func (resp UpsertDataResponse) camelCased() any {
+	resp.Data = camelRecord(resp.Data)
+	return resp
+}
+
+func (result BatchItemResult) camelCased() any {
+	result.Data = camelRecord(result.Data)
+	result.Changed = camelChanges(result.Changed)
+	return result
+}
+
+func (resp BatchResponse) camelCased() any {
+	results := make([]BatchItemResult, len(resp.Results))
+	for i, result := range resp.Results {
+		results[i] = result.camelCased().(BatchItemResult)
+	}
+	resp.Results = results
+	return resp
+}
+
+func (resp TransactResponse) camelCased() any {
+	results := make([]any, len(resp.Results))
+	for i, result := range resp.Results {
+		if cased, ok := result.(camelCasedResponse); ok {
+			result = cased.camelCased()
+		}
+		results[i] = result
+	}
+	resp.Results = results
+	return resp
+}
+
+func (resp SchemaResponse) camelCased() any {
+	fields := make([]schema.FieldSchema, len(resp.Fields))
+	for i, field := range resp.Fields {
+		field.Name = camelCase(field.Name)
+		fields[i] = field
+	}
+	resp.Fields = fields
+	return resp
+}
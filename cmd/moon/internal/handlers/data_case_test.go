@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+)
+
+func TestCamelCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"id", "id"},
+		{"unit_price", "unitPrice"},
+		{"created_at", "createdAt"},
+		{"address_line_1", "addressLine_1"},
+		{"line_2", "line_2"},
+		{"a__b", "a_B"},
+		{"total_", "total_"},
+		{"_rev", "_rev"},
+		{"_expanded", "_expanded"},
+	}
+	for _, tt := range tests {
+		if got := camelCase(tt.name); got != tt.want {
+			t.Errorf("camelCase(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := snakeCase(tt.want); got != tt.name {
+			t.Errorf("snakeCase(%q) = %q, want %q", tt.want, got, tt.name)
+		}
+	}
+}
+
+func TestCamelRecord_KeepsValues(t *testing.T) {
+	record := map[string]any{
+		"unit_price": "9.99",
+		"meta":       map[string]any{"shelf_life": 30},
+		"_expanded":  map[string]any{"vendor_id": map[string]any{"legal_name": "Acme"}, "owner_id": nil},
+	}
+	cased := camelRecord(record)
+	if cased["unitPrice"] != "9.99" || cased["unit_price"] != nil {
+		t.Errorf("expected unit_price to be renamed, got %v", cased)
+	}
+	if meta := cased["meta"].(map[string]any); meta["shelf_life"] != 30 {
+		t.Errorf("expected json values to keep their keys, got %v", meta)
+	}
+	expanded := cased["_expanded"].(map[string]any)
+	if vendor := expanded["vendorId"].(map[string]any); vendor["legalName"] != "Acme" {
+		t.Errorf("expected expanded records to be renamed, got %v", expanded)
+	}
+	if v, ok := expanded["ownerId"]; !ok || v != nil {
+		t.Errorf("expected a null expansion to be kept, got %v", expanded)
+	}
+	if _, ok := record["unit_price"]; !ok {
+		t.Error("expected the record itself to be unchanged")
+	}
+}
+
+func TestNormalizeFieldNames(t *testing.T) {
+	data := map[string]any{"unitPrice": "9.99", "label": "x", "meta": map[string]any{"shelfLife": 30}}
+	if err := normalizeFieldNames(data); err != nil {
+		t.Fatalf("normalizeFieldNames failed: %v", err)
+	}
+	if data["unit_price"] != "9.99" || data["label"] != "x" || data["unitPrice"] != nil {
+		t.Errorf("expected unitPrice to become unit_price, got %v", data)
+	}
+	if meta := data["meta"].(map[string]any); meta["shelfLife"] != 30 {
+		t.Errorf("expected nested keys to be kept, got %v", meta)
+	}
+
+	if err := normalizeFieldNames(map[string]any{"unitPrice": 1, "unit_price": 2}); err == nil {
+		t.Error("expected a field given in both conventions to fail")
+	}
+}
+
+func TestFieldCase_RoundTrip(t *testing.T) {
+	rt := setupReferenceTest(t)
+
+	// A camelCase body is accepted by the snake_case columns
+	w := rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": map[string]any{"label": "first", "customerId": rt.customer}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Data["customer_id"] != rt.customer || created.Data["created_at"] == nil {
+		t.Errorf("expected a snake_case response by default, got %v", created.Data)
+	}
+	id := created.Data["id"].(string)
+
+	w = rt.do(t, rt.data.Create, "orders", "/orders:create?case=camel", map[string]any{"data": map[string]any{"label": "second", "customer_id": rt.customer}})
+	var camel CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &camel)
+	if w.Code != http.StatusCreated || camel.Data["customerId"] != rt.customer || camel.Data["createdAt"] == nil || camel.Data["customer_id"] != nil {
+		t.Errorf("expected a camelCase create response, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Reads in both modes; filters name the column
+	w = rt.do(t, rt.data.List, "orders", "/orders:list?case=camel&expand=customer_id&label[eq]=first", nil)
+	var list DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Data) != 1 {
+		t.Fatalf("Expected one order, got %d: %s", w.Code, w.Body.String())
+	}
+	record := list.Data[0]
+	customer, _ := record["_expanded"].(map[string]any)["customerId"].(map[string]any)
+	if record["customerId"] != rt.customer || record["updatedAt"] == nil || customer["createdAt"] == nil || record["_rev"] == nil {
+		t.Errorf("expected camelCase fields and expanded record, got %v", record)
+	}
+	if w := rt.do(t, rt.data.List, "orders", "/orders:list?case=camel&customerId[eq]="+rt.customer, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected filters to require the column name, got %d", w.Code)
+	}
+
+	w = rt.do(t, rt.data.Get, "orders", "/orders:get?id="+id, nil)
+	var got DataGetResponse
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Data["customer_id"] != rt.customer || got.Data["label"] != "first" {
+		t.Errorf("expected the snake_case record, got %v", got.Data)
+	}
+
+	// Updates and batches follow the same rules
+	w = rt.do(t, rt.data.Update, "orders", "/orders:update?case=camel", map[string]any{"data": map[string]any{"id": id, "customerId": nil}})
+	var updated UpdateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if v, ok := updated.Data["customerId"]; w.Code != http.StatusOK || !ok || v != nil {
+		t.Errorf("expected customerId to be cleared, got %d: %s", w.Code, w.Body.String())
+	}
+	w = rt.do(t, rt.data.Create, "orders", "/orders:create?case=camel", map[string]any{"data": []map[string]any{{"label": "third", "customerId": rt.customer}}})
+	var batch BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if w.Code != http.StatusMultiStatus || len(batch.Results) != 1 || batch.Results[0].Data["customerId"] != rt.customer {
+		t.Errorf("expected a camelCase batch result, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = rt.do(t, rt.data.Schema, "orders", "/orders:schema?case=camel", nil)
+	var schemaResp SchemaResponse
+	json.Unmarshal(w.Body.Bytes(), &schemaResp)
+	names := map[string]bool{}
+	for _, field := range schemaResp.Fields {
+		names[field.Name] = true
+	}
+	if !names["customerId"] || !names["createdAt"] || names["customer_id"] {
+		t.Errorf("expected camelCase schema fields, got %v", names)
+	}
+
+	if w := rt.do(t, rt.data.List, "orders", "/orders:list?case=kebab", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid case, got %d", w.Code)
+	}
+	w = rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": map[string]any{"label": "x", "customerId": rt.customer, "customer_id": rt.customer}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a field given twice, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFieldCase_ConfigDefault(t *testing.T) {
+	rt := setupReferenceTest(t)
+	cfg := testConfig()
+	cfg.API.FieldCase = config.FieldCaseCamel
+	handler := NewDataHandler(rt.driver, rt.reg, cfg)
+
+	w := rt.do(t, handler.List, "customers", "/customers:list", nil)
+	var list DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) == 0 || list.Data[0]["createdAt"] == nil {
+		t.Errorf("expected camelCase fields from api.field_case, got %s", w.Body.String())
+	}
+
+	w = rt.do(t, handler.List, "customers", "/customers:list?case=snake", nil)
+	var snake DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &snake)
+	if len(snake.Data) == 0 || snake.Data[0]["created_at"] == nil {
+		t.Errorf("expected ?case=snake to override api.field_case, got %s", w.Body.String())
+	}
+
+	// CSV headers follow the case too
+	req := httptest.NewRequest(http.MethodGet, "/customers:list?fields=name,created_at", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+	handler.List(rec, req, "customers")
+	if header, _, _ := strings.Cut(rec.Body.String(), "\n"); header != "id,name,createdAt" {
+		t.Errorf("expected a camelCase CSV header, got %q", header)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/changefeed"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// ChangesResponse is the response of GET /{name}:changes
+type ChangesResponse struct {
+	Changes []changefeed.Change `json:"changes"`
+	Seq     uint64              `json:"seq"`             // cursor for the next since
+	Reset   bool                `json:"reset,omitempty"` // changes were missed; reload the collection
+}
+
+// Changes handles GET /{name}:changes
+// Without since it returns the current sequence of the collection. With
+// since it returns the changes made after that sequence, holding the request
+// for up to timeout seconds until one happens; an empty result carries the
+// unchanged sequence for the next poll.
+func (h *DataHandler) Changes(w http.ResponseWriter, r *http.Request, collectionName string) {
+	if _, exists := h.registry.Get(collectionName); !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+	if h.changes == nil {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "change feed is not enabled")
+		return
+	}
+
+	q := r.URL.Query()
+	timeout := constants.DefaultChangesTimeout
+	if value := q.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > constants.MaxChangesTimeout {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter,
+				fmt.Sprintf("invalid timeout '%s': must be between 0 and %d seconds", value, int(constants.MaxChangesTimeout/time.Second)))
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	value := q.Get("since")
+	if value == "" {
+		writeResponse(w, r, http.StatusOK, ChangesResponse{Changes: []changefeed.Change{}, Seq: h.changes.Seq(collectionName)})
+		return
+	}
+	since, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("invalid since '%s': must be a sequence returned by :changes", value))
+		return
+	}
+
+	// The poll may outlast the server write timeout, so the response deadline is extended to match
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + constants.HTTPWriteTimeout))
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	changes, seq, reset := h.changes.Wait(ctx, collectionName, since)
+	if changes == nil {
+		changes = []changefeed.Change{}
+	}
+	writeResponse(w, r, http.StatusOK, ChangesResponse{Changes: changes, Seq: seq, Reset: reset})
+}
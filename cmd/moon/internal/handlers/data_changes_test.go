@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/changefeed"
+)
+
+func TestChanges_LongPoll(t *testing.T) {
+	rt := setupReferenceTest(t)
+	rt.data.SetChanges(changefeed.New(nil))
+
+	w := rt.do(t, rt.data.Changes, "orders", "/orders:changes", nil)
+	var start ChangesResponse
+	json.Unmarshal(w.Body.Bytes(), &start)
+	if w.Code != http.StatusOK || start.Seq != 0 || start.Changes == nil {
+		t.Fatalf("Expected the current sequence, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A watcher receives a create made while it waits
+	result := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		result <- rt.do(t, rt.data.Changes, "orders", "/orders:changes?since=0&timeout=5", nil)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	w = rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": []map[string]any{{"label": "first"}, {"label": "second"}}})
+	if w.Code != http.StatusCreated && w.Code != http.StatusMultiStatus {
+		t.Fatalf("Failed to create orders: %d %s", w.Code, w.Body.String())
+	}
+
+	var polled ChangesResponse
+	select {
+	case w = <-result:
+		json.Unmarshal(w.Body.Bytes(), &polled)
+	case <-time.After(3 * time.Second):
+		t.Fatal("watcher did not receive the create within the timeout")
+	}
+	if w.Code != http.StatusOK || len(polled.Changes) == 0 || polled.Changes[0].Action != "create" {
+		t.Fatalf("Expected the created records, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The batch adds one change per record
+	w = rt.do(t, rt.data.Changes, "orders", "/orders:changes?since=0&timeout=0", nil)
+	var all ChangesResponse
+	json.Unmarshal(w.Body.Bytes(), &all)
+	if len(all.Changes) != 2 || all.Seq != 2 {
+		t.Fatalf("Expected two changes up to seq 2, got %s", w.Body.String())
+	}
+
+	w = rt.do(t, rt.data.Destroy, "orders", "/orders:destroy", map[string]any{"data": []string{all.Changes[0].ID}})
+	if w.Code >= http.StatusMultipleChoices {
+		t.Fatalf("Failed to delete order: %d %s", w.Code, w.Body.String())
+	}
+	w = rt.do(t, rt.data.Changes, "orders", "/orders:changes?since=2", nil)
+	var destroyed ChangesResponse
+	json.Unmarshal(w.Body.Bytes(), &destroyed)
+	if len(destroyed.Changes) != 1 || destroyed.Changes[0] != (changefeed.Change{Seq: 3, ID: all.Changes[0].ID, Action: "destroy"}) {
+		t.Errorf("Expected the destroy, got %s", w.Body.String())
+	}
+
+	// No change within the timeout returns the unchanged sequence
+	w = rt.do(t, rt.data.Changes, "orders", "/orders:changes?since=3&timeout=0", nil)
+	var empty ChangesResponse
+	json.Unmarshal(w.Body.Bytes(), &empty)
+	if w.Code != http.StatusOK || len(empty.Changes) != 0 || empty.Changes == nil || empty.Seq != 3 {
+		t.Errorf("Expected an empty result at seq 3, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestChanges_InvalidParameters(t *testing.T) {
+	rt := setupReferenceTest(t)
+	rt.data.SetChanges(changefeed.New(nil))
+
+	for _, url := range []string{"/orders:changes?since=abc", "/orders:changes?since=-1", "/orders:changes?since=0&timeout=61", "/orders:changes?timeout=x"} {
+		if w := rt.do(t, rt.data.Changes, "orders", url, nil); w.Code != http.StatusBadRequest || errorCode(w) != "invalid_parameter" {
+			t.Errorf("%s: expected 400 invalid_parameter, got %d %s", url, w.Code, w.Body.String())
+		}
+	}
+	if w := rt.do(t, rt.data.Changes, "missing", "/missing:changes", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown collection, got %d", w.Code)
+	}
+}
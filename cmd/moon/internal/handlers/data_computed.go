@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/expr"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// insertValue returns the value an insert writes to a column: the computed
+// value of a computed column, else the given value or the column default.
+// ok is false when the column is left to the database.
+func insertValue(collection *registry.Collection, col registry.Column, data map[string]any) (any, bool) {
+	if col.Computed != "" {
+		return computedValue(collection, col, data), true
+	}
+	val, ok := data[col.Name]
+	if !ok {
+		val, ok = insertDefault(col)
+	}
+	return val, ok
+}
+
+// computedValue evaluates a computed column over the values of a new record,
+// taking omitted operands at their default. A null operand, a division by
+// zero or an overflow gives nil.
+func computedValue(collection *registry.Collection, col registry.Column, data map[string]any) any {
+	e, err := expr.Parse(col.Computed)
+	if err != nil {
+		return nil
+	}
+	result, ok := e.Eval(func(name string) (float64, bool) {
+		operand, _ := findColumn(collection.Columns, name)
+		val, given := data[name]
+		if !given {
+			val, _ = insertDefault(operand)
+		}
+		return operandValue(val)
+	})
+	if !ok || math.IsInf(result, 0) || math.IsNaN(result) {
+		return nil
+	}
+	if col.Type == registry.TypeInteger {
+		return int64(math.Trunc(result))
+	}
+	// Rounded half away from zero, as the databases round
+	return new(big.Rat).SetFloat64(result).FloatString(constants.DefaultDecimalScale)
+}
+
+// operandValue converts a validated integer or decimal value to a float
+func operandValue(val any) (float64, bool) {
+	_, text, ok := numericValue(val)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	return f, err == nil
+}
+
+// computedAssignments returns the SET clauses recomputing the computed columns
+// whose operands an update changes, with values appended to args. Changed
+// operands are bound with their new values and the others read from the
+// row, so the database computes the result as computedValue would.
+func computedAssignments(collection *registry.Collection, data map[string]any, args []any, dialect database.DialectType) ([]string, []any) {
+	var clauses []string
+	for _, col := range collection.Columns {
+		if col.Computed == "" {
+			continue
+		}
+		e, err := expr.Parse(col.Computed)
+		if err != nil {
+			continue
+		}
+		changed := false
+		for _, name := range e.Columns() {
+			if _, ok := data[name]; ok {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		var sqlExpr string
+		sqlExpr, args = computedSQL(collection, col, e, data, args, dialect)
+		clauses = append(clauses, fmt.Sprintf("%s = %s", query.QuoteIdent(dialect, col.Name), sqlExpr))
+	}
+	return clauses, args
+}
+
+// computedSQL renders the expression of a computed column for the database.
+// Operands in data are bound and appended to args; a nil data reads every
+// operand from the row. Operands are cast to floating point so that every
+// dialect computes as computedValue does; integer results are truncated and
+// decimal results rounded to the column scale.
+func computedSQL(collection *registry.Collection, col registry.Column, e *expr.Expr, data map[string]any, args []any, dialect database.DialectType) (string, []any) {
+	sqlExpr := e.SQL(func(name string) string {
+		val, given := data[name]
+		if !given {
+			return fmt.Sprintf("CAST(%s AS %s)", query.QuoteIdent(dialect, name), floatSQLType(dialect))
+		}
+		if val == nil {
+			return "NULL"
+		}
+		operand, _ := findColumn(collection.Columns, name)
+		args = append(args, columnValue(operand, val))
+		return fmt.Sprintf("CAST(%s AS %s)", query.Placeholder(dialect, len(args)), floatSQLType(dialect))
+	})
+
+	if col.Type == registry.TypeInteger {
+		switch dialect {
+		case database.DialectPostgres:
+			return fmt.Sprintf("TRUNC(%s)", sqlExpr), args
+		case database.DialectMySQL:
+			return fmt.Sprintf("TRUNCATE(%s, 0)", sqlExpr), args
+		default:
+			return fmt.Sprintf("CAST(%s AS INTEGER)", sqlExpr), args
+		}
+	}
+	// PostgreSQL and MySQL round to the scale of the NUMERIC column itself
+	if dialect == database.DialectSQLite {
+		return fmt.Sprintf("ROUND(%s, %d)", sqlExpr, constants.DefaultDecimalScale), args
+	}
+	return sqlExpr, args
+}
+
+// floatSQLType returns the floating point type computed columns are
+// evaluated in
+func floatSQLType(dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectPostgres:
+		return "DOUBLE PRECISION"
+	case database.DialectMySQL:
+		return "DOUBLE"
+	default:
+		return "REAL"
+	}
+}
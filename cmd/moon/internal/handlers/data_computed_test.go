@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// setupComputedTest creates a notes collection whose total is computed as
+// price * quantity and whose unit_price divides them back
+func setupComputedTest(t *testing.T) (*DataHandler, *CollectionsHandler) {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "price", "type": "decimal", "nullable": true},
+			{"name": "quantity", "type": "integer", "nullable": true},
+			{"name": "total", "type": "decimal", "nullable": true, "computed": "price * quantity"},
+			{"name": "unit_price", "type": "decimal", "nullable": true, "computed": "price / quantity"},
+		},
+	})
+	return nt.data, nt.collections
+}
+
+// getNote returns a notes record by id
+func getNote(t *testing.T, handler *DataHandler, id string) map[string]any {
+	t.Helper()
+	w := doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Get failed: %d %s", w.Code, w.Body.String())
+	}
+	var resp map[string]map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp["data"]
+}
+
+// createNote creates a notes record and returns it as the response holds it
+func createNote(t *testing.T, handler *DataHandler, data map[string]any) map[string]any {
+	t.Helper()
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": data})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+	}
+	var resp map[string]map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp["data"]
+}
+
+func TestComputedColumns_CollectionValidation(t *testing.T) {
+	_, collections := setupComputedTest(t)
+
+	tests := []struct {
+		name   string
+		column map[string]any
+		want   string
+	}{
+		{"string type", map[string]any{"name": "c", "type": "string", "nullable": true, "computed": "price"}, "must be an integer or decimal"},
+		{"not nullable", map[string]any{"name": "c", "type": "decimal", "nullable": false, "computed": "price"}, "must be nullable"},
+		{"constraint", map[string]any{"name": "c", "type": "decimal", "nullable": true, "computed": "price", "min": 0}, "cannot have constraints"},
+		{"unknown operand", map[string]any{"name": "c", "type": "decimal", "nullable": true, "computed": "price * weight"}, "unknown column 'weight'"},
+		{"syntax", map[string]any{"name": "c", "type": "decimal", "nullable": true, "computed": "price *"}, "unexpected end of expression"},
+		{"computed operand", map[string]any{"name": "c", "type": "decimal", "nullable": true, "computed": "total * 2"}, "cannot read computed column 'total'"},
+		{"string operand", map[string]any{"name": "c", "type": "decimal", "nullable": true, "computed": "title * 2"}, "must be an integer or decimal, not string"},
+	}
+	for _, tt := range tests {
+		w := postCollections(collections.Update, map[string]any{"name": "notes", "add_columns": []map[string]any{tt.column}})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: expected 400 containing %q, got %d %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	// Operands cannot be removed or lose their type while read
+	w := postCollections(collections.Update, map[string]any{"name": "notes", "remove_columns": []string{"quantity"}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "unknown column 'quantity'") {
+		t.Errorf("expected 400 removing an operand, got %d %s", w.Code, w.Body.String())
+	}
+	w = postCollections(collections.Update, map[string]any{"name": "notes", "modify_columns": []map[string]any{{"name": "quantity", "type": "string", "nullable": true}}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "must be an integer or decimal") {
+		t.Errorf("expected 400 changing an operand to a string, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestComputedColumns_CRUD(t *testing.T) {
+	handler, _ := setupComputedTest(t)
+
+	// Computed fields are read-only
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "x", "total": "1.00"}})
+	if w.Code != http.StatusBadRequest || errorCode(w) != "validation_read_only_field" {
+		t.Errorf("expected 400 validation_read_only_field on create, got %d %s", w.Code, w.Body.String())
+	}
+
+	created := createNote(t, handler, map[string]any{"title": "pens", "price": "2.50", "quantity": 4})
+	if created["total"] != "10.00" || created["unit_price"] != "0.63" {
+		t.Errorf("expected total 10.00 and unit_price 0.63 in the create response, got %v", created)
+	}
+	id := created["id"].(string)
+	if got := getNote(t, handler, id); got["total"] != "10.00" || got["unit_price"] != "0.63" {
+		t.Errorf("expected the computed values stored, got %v", got)
+	}
+
+	// A zero divisor and a null operand give null
+	empty := createNote(t, handler, map[string]any{"title": "empty", "price": "3.00", "quantity": 0})
+	if empty["total"] != "0.00" || empty["unit_price"] != nil {
+		t.Errorf("expected total 0.00 and unit_price null, got %v", empty)
+	}
+	unpriced := createNote(t, handler, map[string]any{"title": "unpriced", "price": nil, "quantity": 2})
+	if unpriced["total"] != nil {
+		t.Errorf("expected total null for a null price, got %v", unpriced)
+	}
+
+	w = doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"id": id, "data": map[string]any{"total": "5.00"}})
+	if w.Code != http.StatusBadRequest || errorCode(w) != "validation_read_only_field" {
+		t.Errorf("expected 400 validation_read_only_field on update, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Updating one operand recomputes from the stored other
+	w = doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"id": id, "data": map[string]any{"quantity": 10}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	if got := getNote(t, handler, id); got["total"] != "25.00" || got["unit_price"] != "0.25" {
+		t.Errorf("expected total 25.00 and unit_price 0.25 after the update, got %v", got)
+	}
+	w = doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"id": id, "data": map[string]any{"quantity": nil}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	if got := getNote(t, handler, id); got["total"] != nil {
+		t.Errorf("expected total null for a null quantity, got %v", got)
+	}
+
+	// Computed fields filter and sort like stored ones
+	titles, _ := listTitles(t, handler, "/notes:list?total[gte]=0&sort=-total")
+	if strings.Join(titles, ",") != "empty" {
+		t.Errorf("expected the filter on total to select empty, got %v", titles)
+	}
+	createNote(t, handler, map[string]any{"title": "books", "price": "12.00", "quantity": 3})
+	titles, _ = listTitles(t, handler, "/notes:list?total[gte]=0&sort=-total")
+	if strings.Join(titles, ",") != "books,empty" {
+		t.Errorf("expected books then empty sorted by total, got %v", titles)
+	}
+}
+
+func TestComputedColumns_Recompute(t *testing.T) {
+	handler, collections := setupComputedTest(t)
+
+	ids := make([]string, 0, recomputeBatchSize+3)
+	for i := range recomputeBatchSize + 3 {
+		ids = append(ids, createNote(t, handler, map[string]any{"title": "n", "price": "1.00", "quantity": i})["id"].(string))
+	}
+
+	// Changing the expression recomputes every record, across batches
+	w := postCollections(collections.Update, map[string]any{"name": "notes", "modify_columns": []map[string]any{
+		{"name": "total", "type": "decimal", "nullable": true, "computed": "price * quantity + 1"},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	for _, i := range []int{0, recomputeBatchSize - 1, recomputeBatchSize, recomputeBatchSize + 2} {
+		want := map[int]string{0: "1.00", recomputeBatchSize - 1: "500.00", recomputeBatchSize: "501.00", recomputeBatchSize + 2: "503.00"}[i]
+		if got := getNote(t, handler, ids[i]); got["total"] != want {
+			t.Errorf("record %d: expected total %s, got %v", i, want, got["total"])
+		}
+	}
+
+	// An added computed column is filled in, a renamed operand rewritten
+	w = postCollections(collections.Update, map[string]any{
+		"name":           "notes",
+		"rename_columns": []map[string]any{{"old_name": "quantity", "new_name": "count"}},
+		"add_columns":    []map[string]any{{"name": "doubled", "type": "integer", "nullable": true, "computed": "count * 2"}},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	collection, _ := collections.registry.Get("notes")
+	if total, _ := findColumn(collection.Columns, "total"); total.Computed != "price * count + 1" {
+		t.Errorf("expected the rename to rewrite the expression, got %q", total.Computed)
+	}
+	if got := getNote(t, handler, ids[7]); got["doubled"] != float64(14) {
+		t.Errorf("expected doubled 14, got %v", got["doubled"])
+	}
+
+	// An empty expression makes the column a plain one again
+	w = postCollections(collections.Update, map[string]any{"name": "notes", "modify_columns": []map[string]any{
+		{"name": "doubled", "type": "integer", "nullable": true, "computed": ""},
+	}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	w = doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"id": ids[7], "data": map[string]any{"doubled": 3}})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected doubled writable once plain, got %d %s", w.Code, w.Body.String())
+	}
+}
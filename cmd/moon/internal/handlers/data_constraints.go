@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/thalib/moon/cmd/moon/internal/decimal"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// maxConstraintValueDisplay bounds how much of an offending string value is
+// echoed back in a constraint error
+const maxConstraintValueDisplay = 40
+
+// validateColumnConstraints checks that the constraints of a column apply to
+// its type and are consistent: max_length and enum on strings, min and max on
+// integers and decimals, min not above max, enum values within max_length
+func validateColumnConstraints(column registry.Column) error {
+	isString := column.Type == registry.TypeString
+	isNumeric := column.Type == registry.TypeInteger || column.Type == registry.TypeDecimal
+
+	if column.MaxLength != nil {
+		if !isString {
+			return fmt.Errorf("column '%s': max_length only applies to string columns", column.Name)
+		}
+		if *column.MaxLength <= 0 {
+			return fmt.Errorf("column '%s': max_length must be greater than 0", column.Name)
+		}
+	}
+
+	if (column.Min != nil || column.Max != nil) && !isNumeric {
+		return fmt.Errorf("column '%s': min and max only apply to integer and decimal columns", column.Name)
+	}
+	minValue, err := constraintBound(column, "min", column.Min)
+	if err != nil {
+		return err
+	}
+	maxValue, err := constraintBound(column, "max", column.Max)
+	if err != nil {
+		return err
+	}
+	if minValue != nil && maxValue != nil && minValue.Greater(*maxValue) {
+		return fmt.Errorf("column '%s': min %s is greater than max %s", column.Name, column.Min, column.Max)
+	}
+
+	if column.Enum != nil {
+		if !isString {
+			return fmt.Errorf("column '%s': enum only applies to string columns", column.Name)
+		}
+		if len(column.Enum) == 0 {
+			return fmt.Errorf("column '%s': enum must list at least one value", column.Name)
+		}
+		for i, value := range column.Enum {
+			if slices.Contains(column.Enum[:i], value) {
+				return fmt.Errorf("column '%s': enum value '%s' is listed more than once", column.Name, value)
+			}
+			if column.MaxLength != nil && utf8.RuneCountInString(value) > *column.MaxLength {
+				return fmt.Errorf("column '%s': enum value '%s' exceeds max_length %d", column.Name, value, *column.MaxLength)
+			}
+		}
+	}
+
+	return nil
+}
+
+// constraintBound parses a min or max constraint for the column type;
+// integer columns take whole numbers only
+func constraintBound(column registry.Column, name string, bound *json.Number) (*decimal.Decimal, error) {
+	if bound == nil {
+		return nil, nil
+	}
+	if column.Type == registry.TypeInteger {
+		if _, err := strconv.ParseInt(bound.String(), 10, 64); err != nil {
+			return nil, fmt.Errorf("column '%s': %s %s is not an integer", column.Name, name, bound)
+		}
+	}
+	value, err := decimal.ParseDecimal(bound.String())
+	if err != nil {
+		return nil, fmt.Errorf("column '%s': %s %s is not a number", column.Name, name, bound)
+	}
+	return &value, nil
+}
+
+// validateFieldConstraints checks a value that already passed
+// validateFieldType against the constraints of its column
+func validateFieldConstraints(column registry.Column, value any) error {
+	switch column.Type {
+	case registry.TypeString:
+		str, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		if column.MaxLength != nil {
+			if length := utf8.RuneCountInString(str); length > *column.MaxLength {
+				return fmt.Errorf("field '%s' exceeds max_length %d: value %s has length %d", column.Name, *column.MaxLength, displayValue(str), length)
+			}
+		}
+		if column.Enum != nil && !slices.Contains(column.Enum, str) {
+			return fmt.Errorf("field '%s' must be one of the enum values %q: got %s", column.Name, column.Enum, displayValue(str))
+		}
+
+	case registry.TypeInteger, registry.TypeDecimal:
+		if column.Min == nil && column.Max == nil {
+			return nil
+		}
+		number, text, ok := numericValue(value)
+		if !ok {
+			return nil
+		}
+		if column.Min != nil {
+			if bound, err := decimal.ParseDecimal(column.Min.String()); err == nil && number.Less(bound) {
+				return fmt.Errorf("field '%s' is below min %s: got %s", column.Name, column.Min, text)
+			}
+		}
+		if column.Max != nil {
+			if bound, err := decimal.ParseDecimal(column.Max.String()); err == nil && number.Greater(bound) {
+				return fmt.Errorf("field '%s' is above max %s: got %s", column.Name, column.Max, text)
+			}
+		}
+	}
+	return nil
+}
+
+// numericValue converts an integer or decimal field value to a decimal and
+// the text it was parsed from; JSON numbers arrive as float64 and decimals
+// as strings
+func numericValue(value any) (decimal.Decimal, string, bool) {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		text = fmt.Sprint(v)
+	default:
+		return decimal.Decimal{}, "", false
+	}
+	number, err := decimal.ParseDecimal(text)
+	return number, text, err == nil
+}
+
+// displayValue quotes a string value for an error message, truncating long
+// values so a rejected payload is not echoed back in full
+func displayValue(str string) string {
+	if utf8.RuneCountInString(str) <= maxConstraintValueDisplay {
+		return strconv.Quote(str)
+	}
+	runes := []rune(str)
+	return strconv.Quote(string(runes[:maxConstraintValueDisplay])) + "..."
+}
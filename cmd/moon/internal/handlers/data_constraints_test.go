@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func numberPtr(s string) *json.Number {
+	n := json.Number(s)
+	return &n
+}
+
+func TestValidateColumnConstraints(t *testing.T) {
+	tests := []struct {
+		name        string
+		column      registry.Column
+		errContains string
+	}{
+		{"no constraints", registry.Column{Name: "title", Type: registry.TypeString}, ""},
+		{"string max_length", registry.Column{Name: "title", Type: registry.TypeString, MaxLength: intPtr(1)}, ""},
+		{"zero max_length", registry.Column{Name: "title", Type: registry.TypeString, MaxLength: intPtr(0)}, "max_length must be greater than 0"},
+		{"max_length on integer", registry.Column{Name: "rank", Type: registry.TypeInteger, MaxLength: intPtr(5)}, "max_length only applies to string columns"},
+		{"integer range", registry.Column{Name: "rank", Type: registry.TypeInteger, Min: numberPtr("-5"), Max: numberPtr("5")}, ""},
+		{"integer min equals max", registry.Column{Name: "rank", Type: registry.TypeInteger, Min: numberPtr("3"), Max: numberPtr("3")}, ""},
+		{"integer min above max", registry.Column{Name: "rank", Type: registry.TypeInteger, Min: numberPtr("6"), Max: numberPtr("5")}, "min 6 is greater than max 5"},
+		{"fractional integer bound", registry.Column{Name: "rank", Type: registry.TypeInteger, Min: numberPtr("1.5")}, "min 1.5 is not an integer"},
+		{"decimal range", registry.Column{Name: "price", Type: registry.TypeDecimal, Min: numberPtr("0.01"), Max: numberPtr("99.99")}, ""},
+		{"decimal min above max", registry.Column{Name: "price", Type: registry.TypeDecimal, Min: numberPtr("10.01"), Max: numberPtr("10.00")}, "min 10.01 is greater than max 10.00"},
+		{"min on string", registry.Column{Name: "title", Type: registry.TypeString, Min: numberPtr("1")}, "min and max only apply to integer and decimal columns"},
+		{"max on boolean", registry.Column{Name: "active", Type: registry.TypeBoolean, Max: numberPtr("1")}, "min and max only apply"},
+		{"string enum", registry.Column{Name: "status", Type: registry.TypeString, Enum: []string{"draft", "published"}}, ""},
+		{"empty enum", registry.Column{Name: "status", Type: registry.TypeString, Enum: []string{}}, "enum must list at least one value"},
+		{"duplicate enum value", registry.Column{Name: "status", Type: registry.TypeString, Enum: []string{"draft", "draft"}}, "enum value 'draft' is listed more than once"},
+		{"enum on integer", registry.Column{Name: "rank", Type: registry.TypeInteger, Enum: []string{"1"}}, "enum only applies to string columns"},
+		{"enum value too long", registry.Column{Name: "status", Type: registry.TypeString, MaxLength: intPtr(5), Enum: []string{"draft", "published"}}, "enum value 'published' exceeds max_length 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateColumnConstraints(tt.column)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestValidateFieldConstraints(t *testing.T) {
+	title := registry.Column{Name: "title", Type: registry.TypeString, MaxLength: intPtr(5)}
+	status := registry.Column{Name: "status", Type: registry.TypeString, Enum: []string{"draft", "published"}}
+	rank := registry.Column{Name: "rank", Type: registry.TypeInteger, Min: numberPtr("1"), Max: numberPtr("10")}
+	price := registry.Column{Name: "price", Type: registry.TypeDecimal, Min: numberPtr("0.50"), Max: numberPtr("99.99")}
+
+	tests := []struct {
+		name        string
+		column      registry.Column
+		value       any
+		errContains string
+	}{
+		{"length below max", title, "abcd", ""},
+		{"length at max", title, "abcde", ""},
+		{"length above max", title, "abcdef", `field 'title' exceeds max_length 5: value "abcdef" has length 6`},
+		{"length counts characters", title, "héllo", ""},
+		{"long value is truncated", title, strings.Repeat("x", 100), `"` + strings.Repeat("x", 40) + `"... has length 100`},
+		{"enum member", status, "draft", ""},
+		{"enum non-member", status, "archived", `field 'status' must be one of the enum values ["draft" "published"]: got "archived"`},
+		{"enum is case sensitive", status, "Draft", "must be one of the enum values"},
+		{"integer at min", rank, float64(1), ""},
+		{"integer at max", rank, float64(10), ""},
+		{"integer below min", rank, float64(0), "field 'rank' is below min 1: got 0"},
+		{"integer above max", rank, float64(11), "field 'rank' is above max 10: got 11"},
+		{"native integer", rank, int64(-3), "field 'rank' is below min 1: got -3"},
+		{"decimal at min", price, "0.50", ""},
+		{"decimal at max", price, "99.99", ""},
+		{"decimal below min", price, "0.49", "field 'price' is below min 0.50: got 0.49"},
+		{"decimal above max", price, "100.00", "field 'price' is above max 99.99: got 100.00"},
+		{"unconstrained column", registry.Column{Name: "note", Type: registry.TypeString}, strings.Repeat("x", 1000), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFieldConstraints(tt.column, tt.value)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errContains, err)
+			}
+		})
+	}
+}
+
+func TestDataConstraints(t *testing.T) {
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false, "max_length": 10},
+			{"name": "status", "type": "string", "nullable": true, "enum": []string{"draft", "published"}},
+			{"name": "rank", "type": "integer", "nullable": true, "min": 1, "max": 5},
+			{"name": "price", "type": "decimal", "nullable": true, "min": "0.50"},
+		},
+	})
+	collections, handler := nt.collections, nt.data
+
+	// Omitted constrained columns fall back to NULL rather than a rejected type default
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "first"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+	}
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	for _, field := range []string{"status", "rank", "price"} {
+		if value, ok := created.Data[field]; !ok || value != nil {
+			t.Errorf("expected %s to be NULL, got %v", field, created.Data)
+		}
+	}
+	id, _ := created.Data["id"].(string)
+
+	tests := []struct {
+		name        string
+		action      func(http.ResponseWriter, *http.Request, string)
+		body        map[string]any
+		errContains string
+	}{
+		{"create max_length", handler.Create, map[string]any{"data": map[string]any{"title": "far too long a title"}}, "exceeds max_length 10"},
+		{"create enum", handler.Create, map[string]any{"data": map[string]any{"title": "x", "status": "archived"}}, "must be one of the enum values"},
+		{"create min", handler.Create, map[string]any{"data": map[string]any{"title": "x", "rank": 0}}, "is below min 1: got 0"},
+		{"create decimal min", handler.Create, map[string]any{"data": map[string]any{"title": "x", "price": "0.25"}}, "is below min 0.50: got 0.25"},
+		{"update max", handler.Update, map[string]any{"data": map[string]any{"id": id, "rank": 6}}, "is above max 5: got 6"},
+		{"update enum", handler.Update, map[string]any{"data": map[string]any{"id": id, "status": "archived"}}, "must be one of the enum values"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doDataAction(t, tt.action, http.MethodPost, "/notes", tt.body)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.errContains) {
+				t.Errorf("expected 400 containing %q, got %d %s", tt.errContains, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), "validation_invalid_value") {
+				t.Errorf("expected validation_invalid_value, got %s", w.Body.String())
+			}
+		})
+	}
+
+	// Batches report the violating item and keep the valid ones
+	w = doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": []map[string]any{
+		{"title": "ok", "rank": 5},
+		{"title": "bad", "rank": 9},
+	}})
+	var batch struct {
+		Results []BatchItemResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if len(batch.Results) != 2 || batch.Results[0].Status != BatchItemCreated || !strings.Contains(batch.Results[1].ErrorMessage, "field 'rank' is above max 5: got 9") {
+		t.Errorf("expected the second item to fail its max, got %s", w.Body.String())
+	}
+	w = doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"data": []map[string]any{
+		{"id": id, "title": "this title is too long"},
+	}})
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if len(batch.Results) != 1 || !strings.Contains(batch.Results[0].ErrorMessage, "exceeds max_length 10") {
+		t.Errorf("expected the batch update to fail its max_length, got %s", w.Body.String())
+	}
+
+	// Constraints are surfaced in the schema
+	w = doDataAction(t, handler.Schema, http.MethodGet, "/notes:schema", nil)
+	for _, want := range []string{`"max_length":10`, `"enum":["draft","published"]`, `"min":1,"max":5`, `"min":0.50`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected the schema to contain %s, got %s", want, w.Body.String())
+		}
+	}
+
+	// Modifying a column replaces its constraints and checks them
+	if w := postCollections(collections.Update, map[string]any{"name": "notes", "modify_columns": []map[string]any{
+		{"name": "rank", "type": "integer", "min": 5, "max": 1},
+	}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "min 5 is greater than max 1") {
+		t.Errorf("expected an inverted range to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+	if w := postCollections(collections.Update, map[string]any{"name": "notes", "modify_columns": []map[string]any{
+		{"name": "rank", "type": "integer", "max": 100},
+	}}); w.Code != http.StatusOK {
+		t.Fatalf("modify failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"data": map[string]any{"id": id, "rank": 0}}); w.Code != http.StatusOK {
+		t.Errorf("expected the removed min to no longer apply, got %d %s", w.Code, w.Body.String())
+	}
+	if w := postCollections(collections.Update, map[string]any{"name": "notes", "add_columns": []map[string]any{
+		{"name": "code", "type": "integer", "nullable": true, "enum": []string{"a"}},
+	}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "enum only applies to string columns") {
+		t.Errorf("expected an enum on an integer column to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+}
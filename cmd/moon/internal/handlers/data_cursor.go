@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// listCursor is the position of the last record of a :list page: the values of
+// the sort columns followed by the record id, which breaks ties
+type listCursor struct {
+	Values []any  `json:"v,omitempty"`
+	ID     string `json:"id"`
+}
+
+// paginationSorts returns the sort used for cursor pagination. The id column
+// is appended as a tie-breaker, in the direction of the first sort field, so
+// every record has a unique position; fields after an explicit id sort are
+// dropped because they can never decide the order.
+func paginationSorts(sorts []sortField) []sortField {
+	if len(sorts) == 0 {
+		return []sortField{{column: "id", direction: "ASC"}}
+	}
+
+	result := make([]sortField, 0, len(sorts)+1)
+	for _, sort := range sorts {
+		result = append(result, sort)
+		if sort.column == "id" {
+			return result
+		}
+	}
+	return append(result, sortField{column: "id", direction: sorts[0].direction})
+}
+
+// encodeCursor returns the next_cursor for the last record of a page. Pages
+// sorted by id alone use the bare record id; other sorts use an opaque base64 token
+// holding the sort values and the id.
+func encodeCursor(sorts []sortField, record map[string]any) (string, bool) {
+	id, ok := record["id"].(string)
+	if !ok {
+		return "", false
+	}
+	if len(sorts) == 1 {
+		return id, true
+	}
+
+	cursor := listCursor{ID: id, Values: make([]any, 0, len(sorts)-1)}
+	for _, sort := range sorts[:len(sorts)-1] {
+		cursor.Values = append(cursor.Values, record[sort.column])
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", false
+	}
+	return base64.RawURLEncoding.EncodeToString(data), true
+}
+
+// decodeCursor parses an after parameter. A bare record id, as returned by
+// earlier versions and by id-only sorts, yields a cursor without sort values.
+// Client-supplied ids can look like tokens, so for those collections a token
+// is tried before the bare id.
+func decodeCursor(after string, sorts []sortField, collection *registry.Collection) (*listCursor, error) {
+	clientID := collection.RecordIDType() == registry.IDTypeClient
+	if !clientID && validateRecordID(collection, after) == nil {
+		return &listCursor{ID: after}, nil
+	}
+
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(after)
+	if err == nil {
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.UseNumber()
+		err = decoder.Decode(&cursor)
+	}
+	if err != nil {
+		if clientID && validateRecordID(collection, after) == nil {
+			return &listCursor{ID: after}, nil
+		}
+		return nil, fmt.Errorf("cursor is neither a record id nor a valid token")
+	}
+	if err := validateRecordID(collection, cursor.ID); err != nil {
+		return nil, fmt.Errorf("cursor id: %v", err)
+	}
+	if len(cursor.Values) != len(sorts)-1 {
+		return nil, fmt.Errorf("cursor does not match the sort order")
+	}
+	for i, value := range cursor.Values {
+		if n, ok := value.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				cursor.Values[i] = v
+			} else if v, err := n.Float64(); err == nil {
+				cursor.Values[i] = v
+			}
+		}
+	}
+	return &cursor, nil
+}
+
+// loadCursorValues fills in the sort values of a bare id cursor from the
+// record it points to, so old cursors keep working with any sort order
+func (h *DataHandler) loadCursorValues(ctx context.Context, collectionName string, sorts []sortField, cursor *listCursor) error {
+	dialect := h.db.Dialect()
+	columns := make([]string, 0, len(sorts)-1)
+	for _, sort := range sorts[:len(sorts)-1] {
+		columns = append(columns, sort.column)
+	}
+
+	sqlQuery := fmt.Sprintf("SELECT %s FROM %s WHERE id = %s",
+		query.QuoteIdents(dialect, columns), query.QuoteIdent(dialect, collectionName), query.Placeholder(dialect, 1))
+
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := h.db.QueryRow(ctx, sqlQuery, cursor.ID).Scan(ptrs...); err != nil {
+		return fmt.Errorf("cursor record not found")
+	}
+	for i, value := range values {
+		if b, ok := value.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+	cursor.Values = values
+	return nil
+}
+
+// buildCursorCondition returns the SQL condition selecting the records after
+// the cursor in the given sort order, numbering placeholders from start:
+//
+//	(a after v0) OR (a = v0 AND b after v1) OR ... OR (a = v0 AND ... AND id after cursor id)
+//
+// NULL sort values are placed where the dialect sorts them: first in ascending
+// order on SQLite and MySQL, last on PostgreSQL, and the reverse for descending.
+func buildCursorCondition(sorts []sortField, cursor *listCursor, dialect database.DialectType, start int) (string, []any) {
+	values := append(append([]any{}, cursor.Values...), cursor.ID)
+
+	var args []any
+	placeholder := func(value any) string {
+		args = append(args, value)
+		return query.Placeholder(dialect, start+len(args)-1)
+	}
+
+	// Every fragment binds its own arguments so ? placeholders stay in order
+	equal := func(i int) string {
+		col := query.QuoteIdent(dialect, sorts[i].column)
+		if values[i] == nil {
+			return col + " IS NULL"
+		}
+		return fmt.Sprintf("%s = %s", col, placeholder(values[i]))
+	}
+	after := func(i int) string {
+		col := query.QuoteIdent(dialect, sorts[i].column)
+		op := ">"
+		if sorts[i].direction == "DESC" {
+			op = "<"
+		}
+		switch {
+		case values[i] == nil:
+			return col + " IS NOT NULL"
+		case nullsSortFirst(dialect, sorts[i].direction):
+			return fmt.Sprintf("%s %s %s", col, op, placeholder(values[i]))
+		default:
+			return fmt.Sprintf("(%s %s %s OR %s IS NULL)", col, op, placeholder(values[i]), col)
+		}
+	}
+
+	var branches []string
+	for i := range sorts {
+		// Nothing sorts after NULL in a column whose NULLs come last
+		if values[i] == nil && !nullsSortFirst(dialect, sorts[i].direction) {
+			continue
+		}
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, equal(j))
+		}
+		parts = append(parts, after(i))
+		branches = append(branches, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	if len(branches) == 0 {
+		return "1 = 0", args
+	}
+	return "(" + strings.Join(branches, " OR ") + ")", args
+}
+
+// nullsSortFirst reports whether the dialect puts NULLs first for the direction
+func nullsSortFirst(dialect database.DialectType, direction string) bool {
+	return (dialect == database.DialectPostgres) == (direction == "DESC")
+}
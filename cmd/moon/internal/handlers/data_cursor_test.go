@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// listPage fetches one :list page and fails the test on a non-200 response
+func listPage(t *testing.T, handler *DataHandler, query string) DataListResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/products:list?"+query, nil)
+	w := httptest.NewRecorder()
+	handler.List(w, req, "products")
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: expected status 200, got %d: %s", query, w.Code, w.Body.String())
+	}
+	var resp DataListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+// paginateIDs walks every page of a sorted list and returns the record ids in order
+func paginateIDs(t *testing.T, handler *DataHandler, query string, limit int) []string {
+	t.Helper()
+	var ids []string
+	var cursor *string
+	for page := 0; page < 100; page++ {
+		q := fmt.Sprintf("%s&limit=%d", query, limit)
+		if cursor != nil {
+			q += "&after=" + url.QueryEscape(*cursor)
+		}
+		resp := listPage(t, handler, q)
+		for _, record := range resp.Data {
+			ids = append(ids, record["id"].(string))
+		}
+		if resp.NextCursor == nil {
+			return ids
+		}
+		cursor = resp.NextCursor
+	}
+	t.Fatalf("%s: pagination did not terminate", query)
+	return nil
+}
+
+func TestDataHandler_List_CursorFollowsSort(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	// 50 records with only 7 distinct prices; every fifth has no category
+	for i := 0; i < 50; i++ {
+		data := map[string]any{"name": fmt.Sprintf("Product %02d", i), "price": (i % 7) * 10}
+		if i%5 != 0 {
+			data["category"] = fmt.Sprintf("cat-%d", i%3)
+		}
+		if w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: data}, ""); w.Code != http.StatusCreated {
+			t.Fatalf("failed to create record %d: %s", i, w.Body.String())
+		}
+	}
+
+	for _, sort := range []string{"-price", "price", "-id", "category", "-category,name", "price,-name"} {
+		t.Run(sort, func(t *testing.T) {
+			query := "sort=" + sort
+			all := listPage(t, handler, query+"&limit=50")
+			if len(all.Data) != 50 {
+				t.Fatalf("expected 50 records in a single page, got %d", len(all.Data))
+			}
+
+			paged := paginateIDs(t, handler, query, 7)
+			if len(paged) != 50 {
+				t.Fatalf("expected 50 records across pages, got %d", len(paged))
+			}
+			seen := map[string]bool{}
+			for i, id := range paged {
+				if seen[id] {
+					t.Errorf("record %s returned twice", id)
+				}
+				seen[id] = true
+				if id != all.Data[i]["id"] {
+					t.Errorf("position %d: expected %v, got %s", i, all.Data[i]["id"], id)
+				}
+			}
+		})
+	}
+
+	t.Run("descending price order", func(t *testing.T) {
+		resp := listPage(t, handler, "sort=-price&limit=50")
+		for i := 1; i < len(resp.Data); i++ {
+			if resp.Data[i-1]["price"].(float64) < resp.Data[i]["price"].(float64) {
+				t.Fatalf("records not sorted by -price at position %d", i)
+			}
+		}
+	})
+
+	t.Run("sort column not in fields", func(t *testing.T) {
+		resp := listPage(t, handler, "sort=-price&fields=name&limit=10")
+		if _, ok := resp.Data[0]["price"]; ok {
+			t.Error("price was not requested and must not be returned")
+		}
+		ids := paginateIDs(t, handler, "sort=-price&fields=name", 9)
+		if len(ids) != 50 {
+			t.Errorf("expected 50 records across pages, got %d", len(ids))
+		}
+	})
+
+	t.Run("bare ULID cursor with sort", func(t *testing.T) {
+		first := listPage(t, handler, "sort=-price&limit=10")
+		second := listPage(t, handler, "sort=-price&limit=10&after="+first.Data[9]["id"].(string))
+		token := listPage(t, handler, "sort=-price&limit=10&after="+url.QueryEscape(*first.NextCursor))
+		if second.Data[0]["id"] != token.Data[0]["id"] {
+			t.Errorf("bare ULID cursor resumed at %v, token at %v", second.Data[0]["id"], token.Data[0]["id"])
+		}
+	})
+
+	t.Run("id sort keeps bare ULID cursors", func(t *testing.T) {
+		resp := listPage(t, handler, "sort=-id&limit=10")
+		if resp.NextCursor == nil || *resp.NextCursor != resp.Data[9]["id"] {
+			t.Errorf("expected the last id as cursor, got %v", resp.NextCursor)
+		}
+	})
+}
+
+func TestDataHandler_List_InvalidCursor(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	for _, query := range []string{
+		"after=not-a-cursor",
+		"sort=-price&after=01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		"sort=-price&after=" + url.QueryEscape(mustCursor(t, []sortField{{"name", "ASC"}, {"price", "ASC"}, {"id", "ASC"}}, map[string]any{"id": "01ARZ3NDEKTSV4RRFFQ69G5FAV", "name": "a", "price": 1})),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/products:list?"+query, nil)
+		w := httptest.NewRecorder()
+		handler.List(w, req, "products")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func mustCursor(t *testing.T, sorts []sortField, record map[string]any) string {
+	t.Helper()
+	cursor, ok := encodeCursor(sorts, record)
+	if !ok {
+		t.Fatal("failed to encode cursor")
+	}
+	return cursor
+}
+
+func TestBuildCursorCondition(t *testing.T) {
+	sorts := []sortField{{"price", "DESC"}, {"id", "DESC"}}
+	cursor := &listCursor{Values: []any{int64(30)}, ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV"}
+
+	tests := []struct {
+		name     string
+		dialect  database.DialectType
+		cursor   *listCursor
+		start    int
+		wantSQL  string
+		wantArgs int
+	}{
+		{
+			name:     "sqlite",
+			dialect:  database.DialectSQLite,
+			cursor:   cursor,
+			start:    1,
+			wantSQL:  `((("price" < ? OR "price" IS NULL)) OR ("price" = ? AND ("id" < ? OR "id" IS NULL)))`,
+			wantArgs: 3,
+		},
+		{
+			name:     "postgres numbering continues after search args",
+			dialect:  database.DialectPostgres,
+			cursor:   cursor,
+			start:    3,
+			wantSQL:  `(("price" < $3) OR ("price" = $4 AND "id" < $5))`,
+			wantArgs: 3,
+		},
+		{
+			name:     "null value on sqlite",
+			dialect:  database.DialectSQLite,
+			cursor:   &listCursor{Values: []any{nil}, ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+			start:    1,
+			wantSQL:  `(("price" IS NULL AND ("id" < ? OR "id" IS NULL)))`,
+			wantArgs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := buildCursorCondition(sorts, tt.cursor, tt.dialect, tt.start)
+			if sql != tt.wantSQL {
+				t.Errorf("sql = %s, want %s", sql, tt.wantSQL)
+			}
+			if len(args) != tt.wantArgs {
+				t.Errorf("args count = %d, want %d", len(args), tt.wantArgs)
+			}
+		})
+	}
+}
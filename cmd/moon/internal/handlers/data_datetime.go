@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+)
+
+// validateDatetimeField checks that a datetime field value is an RFC3339
+// datetime or a short date and returns it in the storage format
+func validateDatetimeField(fieldName string, value any) (string, error) {
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field '%s' must be a datetime string (e.g. \"2024-06-01T10:00:00Z\")", fieldName)
+	}
+	normalized, err := datetime.Normalize(str)
+	if err != nil {
+		return "", fmt.Errorf("field '%s': %v", fieldName, err)
+	}
+	return normalized, nil
+}
+
+// formatDatetime converts a datetime column value read from the database to
+// its API form. PostgreSQL and MySQL return time.Time, SQLite the stored text;
+// text that predates datetime validation and cannot be parsed is returned
+// unchanged.
+func formatDatetime(val any) any {
+	switch v := val.(type) {
+	case time.Time:
+		return datetime.Format(v)
+	case string:
+		if normalized, err := datetime.Normalize(v); err == nil {
+			return normalized
+		}
+	}
+	return val
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// setupDatetimeTest creates a notes collection with a nullable due datetime
+func setupDatetimeTest(t *testing.T) (database.Driver, *DataHandler) {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "due", "type": "datetime", "nullable": true},
+		},
+	})
+	return nt.driver, nt.data
+}
+
+func createDue(t *testing.T, handler *DataHandler, title, due string) map[string]any {
+	t.Helper()
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": title, "due": due}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Create %s failed: %d %s", title, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Data
+}
+
+func TestDatetime_CreateNormalizes(t *testing.T) {
+	driver, handler := setupDatetimeTest(t)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2024-06-01T10:00:00Z", "2024-06-01T10:00:00Z"},
+		{"2024-06-01T12:00:00+02:00", "2024-06-01T10:00:00Z"},
+		{"2024-06-01T10:00:00.250Z", "2024-06-01T10:00:00Z"},
+		{"2024-06-01", "2024-06-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		record := createDue(t, handler, tt.input, tt.input)
+		if record["due"] != tt.want {
+			t.Errorf("expected %s to be returned as %s, got %v", tt.input, tt.want, record["due"])
+		}
+		var stored string
+		driver.QueryRow(context.Background(), "SELECT due FROM notes WHERE id = ?", record["id"]).Scan(&stored)
+		if stored != tt.want {
+			t.Errorf("expected %s to be stored as %s, got %s", tt.input, tt.want, stored)
+		}
+	}
+}
+
+func TestDatetime_CreateRejectsNonRFC3339(t *testing.T) {
+	_, handler := setupDatetimeTest(t)
+
+	for _, due := range []string{"06/01/2024 10:00", "2024-06-01 10:00:00", "2024-06-01T10:00:00", "tomorrow"} {
+		w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "bad", "due": due}})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for due %q, got %d: %s", due, w.Code, w.Body.String())
+		}
+	}
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "bad", "due": 1717236000}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a numeric due, got %d", w.Code)
+	}
+}
+
+func TestDatetime_UpdateNormalizes(t *testing.T) {
+	_, handler := setupDatetimeTest(t)
+	record := createDue(t, handler, "note", "2024-06-01")
+
+	w := doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"id": record["id"], "data": map[string]any{"due": "2024-06-02T01:00:00+03:00"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Data["due"] != "2024-06-01T22:00:00Z" {
+		t.Errorf("expected the update to be normalized, got %v", resp.Data["due"])
+	}
+}
+
+func TestDatetime_FilterAndSort(t *testing.T) {
+	_, handler := setupDatetimeTest(t)
+	// 09:30Z, 10:30Z and 2024-06-02T00:30Z written with different offsets
+	createDue(t, handler, "second", "2024-06-01T12:30:00+02:00")
+	createDue(t, handler, "first", "2024-06-01T05:30:00-04:00")
+	createDue(t, handler, "third", "2024-06-01T20:30:00-04:00")
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"/notes:list?sort=due", []string{"first", "second", "third"}},
+		{"/notes:list?sort=-due", []string{"third", "second", "first"}},
+		{"/notes:list?due[gt]=2024-06-01T10:00:00Z&sort=due", []string{"second", "third"}},
+		{"/notes:list?due[lt]=2024-06-01T12:00:00%2B02:00&sort=due", []string{"first"}},
+		{"/notes:list?due[gte]=2024-06-02", []string{"third"}},
+		{"/notes:list?due[between]=2024-06-01,2024-06-02&sort=due", []string{"first", "second"}},
+		{"/notes:list?due[eq]=2024-06-01T07:30:00-03:00", []string{"second"}},
+		{"/notes:list?due[in]=2024-06-01T09:30:00Z,2024-06-02T00:30:00Z&sort=due", []string{"first", "third"}},
+	}
+	for _, tt := range tests {
+		if got, _ := listTitles(t, handler, tt.url); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.url, tt.want, got)
+		}
+	}
+
+	w := doDataAction(t, handler.List, http.MethodGet, "/notes:list?due[gt]=06/01/2024", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-RFC3339 filter value, got %d", w.Code)
+	}
+}
+
+func TestDatetime_ReadNormalizesLegacyValues(t *testing.T) {
+	driver, handler := setupDatetimeTest(t)
+	record := createDue(t, handler, "note", "2024-06-01")
+	if _, err := driver.Exec(context.Background(), "UPDATE notes SET due = ? WHERE id = ?", "2024-06-01T12:00:00+02:00", record["id"]); err != nil {
+		t.Fatalf("failed to write legacy value: %v", err)
+	}
+	other := createDue(t, handler, "other", "2024-06-01")
+	if _, err := driver.Exec(context.Background(), "UPDATE notes SET due = ? WHERE id = ?", "06/01/2024 10:00", other["id"]); err != nil {
+		t.Fatalf("failed to write legacy value: %v", err)
+	}
+
+	_, resp := listTitles(t, handler, "/notes:list?sort=title")
+	if len(resp.Data) != 2 {
+		t.Fatalf("expected 2 records, got %v", resp.Data)
+	}
+	if resp.Data[0]["due"] != "2024-06-01T10:00:00Z" {
+		t.Errorf("expected an RFC3339 value with an offset to be returned in UTC, got %v", resp.Data[0]["due"])
+	}
+	if resp.Data[1]["due"] != "06/01/2024 10:00" {
+		t.Errorf("expected an unparseable value to be returned unchanged, got %v", resp.Data[1]["due"])
+	}
+}
+
+func TestFormatDatetime(t *testing.T) {
+	loc := time.FixedZone("", -5*3600)
+	tests := []struct {
+		name string
+		val  any
+		want any
+	}{
+		{"nil", nil, nil},
+		{"time from typed columns", time.Date(2024, 6, 1, 5, 0, 0, 0, loc), "2024-06-01T10:00:00Z"},
+		{"stored text", "2024-06-01T10:00:00Z", "2024-06-01T10:00:00Z"},
+		{"stored date", "2024-06-01", "2024-06-01T00:00:00Z"},
+		{"unparseable text", "soon", "soon"},
+	}
+	for _, tt := range tests {
+		if got := formatDatetime(tt.val); got != tt.want {
+			t.Errorf("%s: formatDatetime(%v) = %v, want %v", tt.name, tt.val, got, tt.want)
+		}
+	}
+}
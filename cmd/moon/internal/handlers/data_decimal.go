@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/decimal"
+)
+
+// validateDecimalField checks that a decimal field value is a decimal string
+// within the column scale (constants.DefaultDecimalScale)
+func validateDecimalField(fieldName string, value any) error {
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field '%s' must be a decimal string (e.g. \"10.50\")", fieldName)
+	}
+	return decimal.ValidateDecimalStringForField(fieldName, str, constants.DefaultDecimalScale)
+}
+
+// parseDecimalFilter validates a decimal filter value. The value is bound as a
+// string, which every dialect coerces to the column's numeric type, so
+// comparisons are numeric rather than lexical and keep full precision.
+func parseDecimalFilter(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if err := decimal.ValidateDecimalString(value, constants.MaxDecimalScale); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// formatDecimal converts a decimal column value read from the database to its
+// API form, a string with constants.DefaultDecimalScale places. SQLite returns
+// NUMERIC values as int64 or float64, PostgreSQL and MySQL as text.
+// Values that cannot be parsed are returned unchanged.
+func formatDecimal(val any) any {
+	if val == nil {
+		return nil
+	}
+	if str, ok := formatDecimalWithScale(val, constants.DefaultDecimalScale); ok {
+		return str
+	}
+	return val
+}
+
+// formatDecimalAverage formats an average of decimal values with up to
+// constants.MaxDecimalScale places, trimming trailing zeros beyond the column scale
+func formatDecimalAverage(val any) any {
+	str, ok := formatDecimalWithScale(val, constants.MaxDecimalScale)
+	if !ok {
+		return val
+	}
+	minLen := strings.Index(str, ".") + 1 + constants.DefaultDecimalScale
+	for len(str) > minLen && str[len(str)-1] == '0' {
+		str = str[:len(str)-1]
+	}
+	return str
+}
+
+// formatDecimalWithScale scans a database value and formats it with scale places
+func formatDecimalWithScale(val any, scale int) (string, bool) {
+	var d decimal.Decimal
+	if err := d.Scan(val); err != nil {
+		return "", false
+	}
+	return d.StringWithScale(scale), true
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func TestMapColumnTypeToSQL_Decimal(t *testing.T) {
+	tests := []struct {
+		dialect database.DialectType
+		want    string
+	}{
+		{database.DialectSQLite, "NUMERIC"},
+		{database.DialectPostgres, "NUMERIC(19,2)"},
+		{database.DialectMySQL, "DECIMAL(19,2)"},
+	}
+	for _, tt := range tests {
+		if got := mapColumnTypeToSQL(registry.TypeDecimal, tt.dialect); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.dialect, tt.want, got)
+		}
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		value any
+		want  any
+	}{
+		{nil, nil},
+		{int64(10), "10.00"},
+		{9.9, "9.90"},
+		{0.1 + 0.2, "0.30"},
+		{"12.5", "12.50"},
+		{"1234567890123456.78", "1234567890123456.78"},
+	}
+	for _, tt := range tests {
+		if got := formatDecimal(tt.value); got != tt.want {
+			t.Errorf("formatDecimal(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	if got := formatDecimalAverage("40.0033333333333333"); got != "40.0033333333" {
+		t.Errorf("unexpected average: %v", got)
+	}
+	if got := formatDecimalAverage(int64(40)); got != "40.00" {
+		t.Errorf("unexpected whole average: %v", got)
+	}
+	if got := formatDecimalAverage(10.005); got != "10.005" {
+		t.Errorf("unexpected average: %v", got)
+	}
+}
+
+func TestDecimal_Integration(t *testing.T) {
+	driver := createTestDBForCollections(t)
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	body, _ := json.Marshal(map[string]any{
+		"name": "products",
+		"columns": []map[string]any{
+			{"name": "name", "type": "string", "nullable": false},
+			{"name": "price", "type": "decimal", "nullable": false},
+		},
+	})
+	w := httptest.NewRecorder()
+	NewCollectionsHandler(driver, reg).Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to create collection: %d %s", w.Code, w.Body.String())
+	}
+
+	data := NewDataHandler(driver, reg, testConfig())
+	for _, price := range []string{"10.10", "9.90", "100.01"} {
+		w := postData(t, data.Create, "/products:create", CreateDataRequest{Data: map[string]any{"name": "item " + price, "price": price}}, "")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("failed to create record: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Values must be decimal strings within the column scale
+	for _, price := range []any{"10.999", 10.5, "1e3"} {
+		w := postData(t, data.Create, "/products:create", CreateDataRequest{Data: map[string]any{"name": "bad", "price": price}}, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for price %v, got %d: %s", price, w.Code, w.Body.String())
+		}
+	}
+
+	list := func(url string) []any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		data.List(w, httptest.NewRequest(http.MethodGet, url, nil), "products")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", url, w.Code, w.Body.String())
+		}
+		var resp DataListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		prices := make([]any, len(resp.Data))
+		for i, record := range resp.Data {
+			prices[i] = record["price"]
+		}
+		return prices
+	}
+	assertPrices := func(url string, want ...any) {
+		t.Helper()
+		got := list(url)
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %v", url, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: expected %v, got %v", url, want, got)
+				return
+			}
+		}
+	}
+
+	// Sorting and filtering are numeric, not lexical
+	assertPrices("/products:list?sort=price", "9.90", "10.10", "100.01")
+	assertPrices("/products:list?sort=-price", "100.01", "10.10", "9.90")
+	assertPrices("/products:list?price[gt]=10&sort=price", "10.10", "100.01")
+	assertPrices("/products:list?price[lte]=10.1&sort=price", "9.90", "10.10")
+	assertPrices("/products:list?price[between]=9.95,100&sort=price", "10.10")
+
+	w = httptest.NewRecorder()
+	data.List(w, httptest.NewRequest(http.MethodGet, "/products:list?price[gt]=abc", nil), "products")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid decimal filter, got %d", w.Code)
+	}
+
+	// Aggregates over decimal fields are decimal strings
+	agg := NewAggregationHandler(driver, reg)
+	tests := []struct {
+		action func(http.ResponseWriter, *http.Request, string)
+		url    string
+		want   any
+	}{
+		{agg.Sum, "/products:sum?field=price", "120.01"},
+		{agg.Avg, "/products:avg?field=price", "40.0033333333"},
+		{agg.Min, "/products:min?field=price", "9.90"},
+		{agg.Max, "/products:max?field=price", "100.01"},
+		// No matching values: null rather than zero
+		{agg.Sum, "/products:sum?field=price&price[gt]=1000", nil},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.action(w, httptest.NewRequest(http.MethodGet, tt.url, nil), "products")
+		var resp AggregationResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.Value != tt.want {
+			t.Errorf("%s: expected %v, got %d %v", tt.url, tt.want, w.Code, resp.Value)
+		}
+	}
+}
@@ -100,8 +100,7 @@ func TestDefaultValues_Integration(t *testing.T) {
 		t.Errorf("price: expected 99, got %v", price)
 	}
 
-	// Omitted fields should not be in response (database defaults were applied)
-	// To verify defaults, we need to query the record
+	// The stored record carries the same defaults
 	listReq := httptest.NewRequest(http.MethodGet, "/test_products:list", nil)
 	listW := httptest.NewRecorder()
 	dataHandler.List(listW, listReq, "test_products")
@@ -258,16 +257,15 @@ func TestDefaultValues_BatchCreate(t *testing.T) {
 		t.Fatalf("Expected 3 records, got %d", len(batchResp.Data))
 	}
 
-	// Verify only provided fields are in response
-	// Item 1 and 3 should NOT have count in response (it was omitted, database default applied)
-	if _, exists := batchResp.Data[0]["count"]; exists {
-		t.Errorf("Item 1: count should not be in response (was omitted)")
+	// Items 1 and 3 omitted count, so the response carries the applied default
+	if count := batchResp.Data[0]["count"]; count != float64(0) {
+		t.Errorf("Item 1: expected default count 0, got %v", count)
 	}
 	if count := batchResp.Data[1]["count"]; count != float64(5) {
 		t.Errorf("Item 2: expected count 5, got %v", count)
 	}
-	if _, exists := batchResp.Data[2]["count"]; exists {
-		t.Errorf("Item 3: count should not be in response (was omitted)")
+	if count := batchResp.Data[2]["count"]; count != float64(0) {
+		t.Errorf("Item 3: expected default count 0, got %v", count)
 	}
 
 	// To verify defaults were actually applied, query the records
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
 	"testing"
 
 	"github.com/thalib/moon/cmd/moon/internal/registry"
@@ -244,6 +247,90 @@ func TestGetDefaultValueInvalidParsing(t *testing.T) {
 	}
 }
 
+func TestInsertDefault(t *testing.T) {
+	tests := []struct {
+		name   string
+		column registry.Column
+		want   any
+		ok     bool
+	}{
+		{"string type default", registry.Column{Type: registry.TypeString, Nullable: true, DefaultValue: stringPtr("''")}, "", true},
+		{"quoted string with quote", registry.Column{Type: registry.TypeString, Nullable: true, DefaultValue: stringPtr("'it''s'")}, "it's", true},
+		{"integer", registry.Column{Type: registry.TypeInteger, Nullable: true, DefaultValue: stringPtr("0")}, int64(0), true},
+		{"decimal", registry.Column{Type: registry.TypeDecimal, Nullable: true, DefaultValue: stringPtr("'0.00'")}, "0.00", true},
+		{"decimal scale", registry.Column{Type: registry.TypeDecimal, Nullable: true, DefaultValue: stringPtr("10.5")}, "10.50", true},
+		{"boolean", registry.Column{Type: registry.TypeBoolean, Nullable: true, DefaultValue: stringPtr("0")}, false, true},
+		{"boolean true", registry.Column{Type: registry.TypeBoolean, Nullable: true, DefaultValue: stringPtr("true")}, true, true},
+		{"datetime NULL", registry.Column{Type: registry.TypeDatetime, Nullable: true, DefaultValue: stringPtr("NULL")}, nil, true},
+		{"datetime value", registry.Column{Type: registry.TypeDatetime, Nullable: true, DefaultValue: stringPtr("'2026-01-01T00:00:00Z'")}, "2026-01-01T00:00:00Z", true},
+		{"json", registry.Column{Type: registry.TypeJSON, Nullable: true, DefaultValue: stringPtr("'{}'")}, "{}", true},
+		{"no default", registry.Column{Type: registry.TypeString, Nullable: true}, nil, false},
+		{"required column", registry.Column{Type: registry.TypeString, DefaultValue: stringPtr("''")}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := insertDefault(tt.column)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("insertDefault() = %v (%T), %v, want %v (%T), %v", got, got, ok, tt.want, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCreate_AppliesColumnDefaults(t *testing.T) {
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "label", "type": "string", "nullable": true},
+			{"name": "views", "type": "integer", "nullable": true},
+			{"name": "price", "type": "decimal", "nullable": true},
+			{"name": "active", "type": "boolean", "nullable": true},
+			{"name": "due_at", "type": "datetime", "nullable": true},
+			{"name": "meta", "type": "json", "nullable": true},
+		},
+	})
+	handler := nt.data
+
+	want := map[string]any{"label": "", "views": float64(0), "price": "0.00", "active": false, "due_at": nil, "meta": "{}"}
+	assertDefaults := func(source string, data map[string]any) {
+		t.Helper()
+		for field, value := range want {
+			got, ok := data[field]
+			if !ok || got != value {
+				t.Errorf("%s: expected %s = %v (%T), got %v (%T)", source, field, value, value, got, got)
+			}
+		}
+	}
+
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "single"}})
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assertDefaults("create response", created.Data)
+
+	id, _ := created.Data["id"].(string)
+	w = doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+id, nil)
+	var fetched DataGetResponse
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	assertDefaults("get response", fetched.Data)
+
+	w = doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": []map[string]any{{"title": "batch"}}})
+	var batch struct {
+		Results []BatchItemResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if len(batch.Results) != 1 {
+		t.Fatalf("expected one batch result, got %s", w.Body.String())
+	}
+	assertDefaults("best-effort batch response", batch.Results[0].Data)
+
+	// The datetime default is a real NULL, not the text "NULL"
+	var nullDates int
+	if err := nt.driver.QueryRow(context.Background(), "SELECT COUNT(*) FROM notes WHERE due_at IS NULL").Scan(&nullDates); err != nil || nullDates != 2 {
+		t.Errorf("expected both due_at values to be NULL, got %d (%v)", nullDates, err)
+	}
+}
+
 // stringPtr is a helper to create string pointers
 func stringPtr(s string) *string {
 	return &s
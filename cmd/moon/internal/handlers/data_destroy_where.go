@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/webhook"
+)
+
+// DestroyWhereResponse represents the response of a :destroy by filter
+type DestroyWhereResponse struct {
+	Matched     int64  `json:"matched"`      // records matching the filter
+	RowsDeleted int64  `json:"rows_deleted"` // records deleted; 0 on a dry run
+	DryRun      bool   `json:"dry_run,omitempty"`
+	Message     string `json:"message"`
+}
+
+// destroyWhere handles POST /{name}:destroy with a "where" filter in the
+// syntax of the :query filter. The matching records are counted and deleted
+// by one statement in the same transaction; ?dry_run=true only counts them,
+// and more than api.max_bulk_delete matches are refused unless ?force=true.
+func (h *DataHandler) destroyWhere(w http.ResponseWriter, r *http.Request, collection *registry.Collection, rawWhere json.RawMessage) {
+	// A missing or empty filter would delete every record
+	if trimmed := bytes.TrimSpace(rawWhere); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidFilter, "where must hold at least one condition")
+		return
+	}
+	condition, err := parseFilterTree(rawWhere, collection)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidFilter, fmt.Sprintf("invalid where: %v", err))
+		return
+	}
+
+	flags := map[string]bool{"dry_run": false, "force": false}
+	for name := range flags {
+		if flags[name], err = QueryBoolean(r, name, false); err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+	}
+
+	// Soft-deleted records are already destroyed and neither counted nor touched
+	conditions := []query.Condition{condition}
+	if collection.SoftDelete {
+		conditions = append(conditions, query.Condition{Column: constants.SoftDeleteColumn, Operator: query.OpIsNull})
+	}
+
+	ctx := r.Context()
+	dialect := h.db.Dialect()
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	where, args := buildListWhere(conditions, "", nil, dialect)
+	countSQL := buildCountQuery(collection.Name, where, dialect)
+	logQuery(ctx, "destroy where count", countSQL, args)
+	var matched int64
+	if err := tx.QueryRowContext(ctx, countSQL, args...).Scan(&matched); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to count records: %v", err))
+		return
+	}
+
+	if flags["dry_run"] {
+		audit.Discard(ctx)
+		writeResponse(w, r, http.StatusOK, DestroyWhereResponse{
+			Matched: matched,
+			DryRun:  true,
+			Message: fmt.Sprintf("%d records would be deleted", matched),
+		})
+		return
+	}
+	if limit := h.config.API.MaxBulkDelete; matched > int64(limit) && !flags["force"] {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeBulkDeleteTooLarge,
+			fmt.Sprintf("filter matches %d records, more than api.max_bulk_delete (%d); pass force=true to delete them", matched, limit))
+		return
+	}
+
+	// Change entries, webhook events and audit entries carry the ids, which
+	// are read before they are deleted
+	var ids []string
+	if (h.changes != nil || h.webhooks != nil || audit.Tracked(ctx)) && matched > 0 {
+		selectSQL := "SELECT id FROM " + query.QuoteIdent(dialect, collection.Name) + where
+		logQuery(ctx, "destroy where ids", selectSQL, args)
+		rows, err := tx.QueryContext(ctx, selectSQL, args...)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to query data: %v", err))
+			return
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to parse results: %v", err))
+				return
+			}
+			ids = append(ids, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to parse results: %v", err))
+			return
+		}
+	}
+
+	deleteSQL, deleteArgs := buildDestroyWhereQuery(collection, conditions, dialect)
+	logQuery(ctx, "destroy where", deleteSQL, deleteArgs)
+	result, err := tx.ExecContext(ctx, deleteSQL, deleteArgs...)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to delete data: %v", err))
+		return
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
+		return
+	}
+
+	h.publish(r.Context(), collection.Name, webhook.ActionDestroy, ids, nil)
+	writeResponse(w, r, http.StatusOK, DestroyWhereResponse{
+		Matched:     matched,
+		RowsDeleted: deleted,
+		Message:     fmt.Sprintf("%d records deleted successfully", deleted),
+	})
+}
+
+// buildDestroyWhereQuery builds the DELETE (or soft delete UPDATE) of every
+// record matching the conditions. The soft delete timestamp is bound first.
+func buildDestroyWhereQuery(collection *registry.Collection, conditions []query.Condition, dialect database.DialectType) (string, []any) {
+	var sb strings.Builder
+	var args []any
+	if collection.SoftDelete {
+		fmt.Fprintf(&sb, "UPDATE %s SET %s = %s", query.QuoteIdent(dialect, collection.Name), constants.SoftDeleteColumn, query.Placeholder(dialect, 1))
+		args = append(args, currentTimestamp())
+	} else {
+		fmt.Fprintf(&sb, "DELETE FROM %s", query.QuoteIdent(dialect, collection.Name))
+	}
+	for i, cond := range conditions {
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		args = query.WriteCondition(&sb, dialect, cond, args)
+	}
+	return sb.String(), args
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/changefeed"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func destroyWhere(t *testing.T, handler *DataHandler, url string, body any) (int, DestroyWhereResponse, map[string]any) {
+	t.Helper()
+	w := doDataAction(t, handler.Destroy, http.MethodPost, url, body)
+	var resp DestroyWhereResponse
+	var raw map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	json.Unmarshal(w.Body.Bytes(), &raw)
+	return w.Code, resp, raw
+}
+
+func TestDestroyWhere_DryRun(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	where := map[string]any{"where": map[string]any{"rank": map[string]any{"gte": 2}}}
+
+	status, resp, _ := destroyWhere(t, handler, "/notes:destroy?dry_run=true", where)
+	if status != http.StatusOK || resp.Matched != 2 || resp.RowsDeleted != 0 || !resp.DryRun {
+		t.Fatalf("expected a dry run matching 2 records, got %d %+v", status, resp)
+	}
+	if titles, _ := listTitles(t, handler, "/notes:list"); len(titles) != 3 {
+		t.Errorf("dry run deleted records: %v", titles)
+	}
+
+	status, resp, _ = destroyWhere(t, handler, "/notes:destroy", where)
+	if status != http.StatusOK || resp.Matched != 2 || resp.RowsDeleted != 2 || resp.DryRun {
+		t.Fatalf("expected 2 records deleted, got %d %+v", status, resp)
+	}
+	if titles, _ := listTitles(t, handler, "/notes:list"); !reflect.DeepEqual(titles, []string{"low"}) {
+		t.Errorf("expected only low to remain, got %v", titles)
+	}
+}
+
+func TestDestroyWhere_Changes(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	handler.SetChanges(changefeed.New(nil))
+
+	status, resp, _ := destroyWhere(t, handler, "/notes:destroy", map[string]any{"where": map[string]any{"rank": map[string]any{"gte": 2}}})
+	if status != http.StatusOK || resp.RowsDeleted != 2 {
+		t.Fatalf("expected 2 records deleted, got %d %+v", status, resp)
+	}
+
+	w := doDataAction(t, handler.Changes, http.MethodGet, "/notes:changes?since=0&timeout=0", nil)
+	var changes ChangesResponse
+	json.Unmarshal(w.Body.Bytes(), &changes)
+	if len(changes.Changes) != 2 || changes.Seq != 2 {
+		t.Fatalf("expected one change per deleted record, got %s", w.Body.String())
+	}
+	for _, change := range changes.Changes {
+		if change.Action != "destroy" || change.ID == "" {
+			t.Errorf("expected a destroy change with the record id, got %+v", change)
+		}
+	}
+}
+
+func TestDestroyWhere_Cap(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	handler.config.API.MaxBulkDelete = 1
+	where := map[string]any{"where": map[string]any{"or": []any{
+		map[string]any{"title": map[string]any{"eq": "low"}},
+		map[string]any{"rank": map[string]any{"gt": 2}},
+	}}}
+
+	status, _, raw := destroyWhere(t, handler, "/notes:destroy", where)
+	if status != http.StatusBadRequest || raw["code"] != "bulk_delete_too_large" {
+		t.Fatalf("expected 400 bulk_delete_too_large, got %d %v", status, raw)
+	}
+	if titles, _ := listTitles(t, handler, "/notes:list"); len(titles) != 3 {
+		t.Errorf("capped delete removed records: %v", titles)
+	}
+
+	status, resp, _ := destroyWhere(t, handler, "/notes:destroy?force=true", where)
+	if status != http.StatusOK || resp.RowsDeleted != 2 {
+		t.Fatalf("expected force to delete 2 records, got %d %+v", status, resp)
+	}
+	if titles, _ := listTitles(t, handler, "/notes:list"); !reflect.DeepEqual(titles, []string{"mid"}) {
+		t.Errorf("expected only mid to remain, got %v", titles)
+	}
+}
+
+func TestDestroyWhere_Validation(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+
+	tests := []struct {
+		name string
+		url  string
+		body string
+		code string
+	}{
+		{"missing where", "/notes:destroy", `{}`, "invalid_json"},
+		{"null where", "/notes:destroy", `{"where": null}`, "invalid_filter"},
+		{"empty where", "/notes:destroy", `{"where": {}}`, "invalid_filter"},
+		{"empty group", "/notes:destroy", `{"where": {"and": []}}`, "invalid_filter"},
+		{"unknown column", "/notes:destroy", `{"where": {"nope": {"eq": 1}}}`, "invalid_filter"},
+		{"where with data", "/notes:destroy", `{"where": {"rank": {"eq": 1}}, "data": []}`, "invalid_json"},
+		{"invalid dry_run", "/notes:destroy?dry_run=maybe", `{"where": {"rank": {"eq": 1}}}`, "invalid_parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, raw := destroyWhere(t, handler, tt.url, json.RawMessage(tt.body))
+			if status != http.StatusBadRequest || raw["code"] != tt.code {
+				t.Errorf("expected 400 %s, got %d %v", tt.code, status, raw)
+			}
+		})
+	}
+
+	if titles, _ := listTitles(t, handler, "/notes:list"); len(titles) != 3 {
+		t.Errorf("rejected requests deleted records: %v", titles)
+	}
+}
+
+func TestBuildDestroyWhereQuery(t *testing.T) {
+	conditions := []query.Condition{
+		{Operator: query.OpOr, Conditions: []query.Condition{
+			{Column: "rank", Operator: query.OpGreaterThan, Value: int64(2)},
+			{Column: "title", Operator: query.OpEqual, Value: "low"},
+		}},
+	}
+	hard := &registry.Collection{Name: "notes"}
+	soft := &registry.Collection{Name: "notes", SoftDelete: true}
+	softConditions := append(conditions, query.Condition{Column: "deleted_at", Operator: query.OpIsNull})
+
+	tests := []struct {
+		name       string
+		collection *registry.Collection
+		conditions []query.Condition
+		dialect    database.DialectType
+		want       string
+		args       int
+	}{
+		{"sqlite", hard, conditions, database.DialectSQLite,
+			`DELETE FROM "notes" WHERE ("rank" > ? OR "title" = ?)`, 2},
+		{"postgres", hard, conditions, database.DialectPostgres,
+			`DELETE FROM "notes" WHERE ("rank" > $1 OR "title" = $2)`, 2},
+		{"mysql", hard, conditions, database.DialectMySQL,
+			"DELETE FROM `notes` WHERE (`rank` > ? OR `title` = ?)", 2},
+		{"postgres soft delete", soft, softConditions, database.DialectPostgres,
+			`UPDATE "notes" SET deleted_at = $1 WHERE ("rank" > $2 OR "title" = $3) AND "deleted_at" IS NULL`, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := buildDestroyWhereQuery(tt.collection, tt.conditions, tt.dialect)
+			if sql != tt.want {
+				t.Errorf("got  %s\nwant %s", sql, tt.want)
+			}
+			if len(args) != tt.args {
+				t.Errorf("expected %d args, got %v", tt.args, args)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/expiry"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// parseExpandParam parses a comma-separated list of reference columns to
+// expand. Each must be a visible column with references to a collection the
+// caller may read; at most MaxExpandFields are accepted. Errors are
+// *apperrors.APIError values.
+func parseExpandParam(r *http.Request, expandParam string, collection *registry.Collection, hidden map[string]bool) ([]registry.Column, error) {
+	var expand []registry.Column
+	for _, field := range strings.Split(expandParam, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.ContainsFunc(expand, func(col registry.Column) bool { return col.Name == field }) {
+			continue
+		}
+
+		idx := slices.IndexFunc(collection.Columns, func(col registry.Column) bool { return col.Name == field })
+		if idx < 0 || hidden[field] {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidParameter, "invalid expand field: %s", field)
+		}
+		col := collection.Columns[idx]
+		if col.References == "" {
+			return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidParameter, "expand field '%s' does not reference another collection", field)
+		}
+		if target := logicalName(r, col.References); !middleware.HasScope(r.Context(), target, auth.ScopeRead) {
+			return nil, apperrors.Newf(http.StatusForbidden, apperrors.CodeForbidden, "expanding '%s' requires the read scope on collection '%s'", field, target)
+		}
+		expand = append(expand, col)
+	}
+
+	if len(expand) > constants.MaxExpandFields {
+		return nil, apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidParameter, "expand accepts at most %d fields", constants.MaxExpandFields)
+	}
+	return expand, nil
+}
+
+// expandReferences adds the records referenced by the expand columns of each
+// record under _expanded, keyed by column. The referenced ids of all records
+// are fetched with one query per referenced collection. A null, empty or
+// dangling id, including one of a soft-deleted record, expands to null.
+// Expanded records leave out the hidden columns of their collection.
+func (h *DataHandler) expandReferences(ctx context.Context, records []map[string]any, expand []registry.Column) error {
+	if len(expand) == 0 {
+		return nil
+	}
+
+	// Columns referencing the same collection share its lookup
+	referenced := map[string]map[string]map[string]any{}
+	for _, col := range expand {
+		if referenced[col.References] == nil {
+			referenced[col.References] = map[string]map[string]any{}
+		}
+		for _, record := range records {
+			if id, ok := record[col.Name].(string); ok && id != "" {
+				referenced[col.References][id] = nil
+			}
+		}
+	}
+	for table, found := range referenced {
+		if err := h.fetchReferenced(ctx, table, found); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range records {
+		expanded := make(map[string]any, len(expand))
+		for _, col := range expand {
+			id, _ := record[col.Name].(string)
+			if target := referenced[col.References][id]; target != nil {
+				expanded[col.Name] = target
+			} else {
+				expanded[col.Name] = nil
+			}
+		}
+		record[constants.ExpandedField] = expanded
+	}
+	return nil
+}
+
+// fetchReferenced fills found, keyed by the ids to fetch, with the live
+// records of the table. Ids without a record, or whose record has expired,
+// are left nil.
+func (h *DataHandler) fetchReferenced(ctx context.Context, table string, found map[string]map[string]any) error {
+	target, ok := h.registry.Get(table)
+	if !ok || len(found) == 0 {
+		return nil
+	}
+	liveOnly := ""
+	if target.SoftDelete {
+		liveOnly = fmt.Sprintf(" AND %s IS NULL", constants.SoftDeleteColumn)
+	}
+
+	dialect := h.db.Dialect()
+	hidden := hiddenColumns(target)
+	for chunk := range slices.Chunk(slices.Sorted(maps.Keys(found)), constants.ReferenceLookupSize) {
+		placeholders := make([]string, len(chunk))
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = query.Placeholder(dialect, i+1)
+			args[i] = id
+		}
+		sqlQuery := fmt.Sprintf("SELECT * FROM %s WHERE id IN (%s)%s", query.QuoteIdent(dialect, table), strings.Join(placeholders, ", "), liveOnly)
+		if live, ok := expiry.Live(target, expiry.Now()); ok {
+			var sb strings.Builder
+			sb.WriteString(sqlQuery + " AND ")
+			args = query.WriteCondition(&sb, dialect, live, args)
+			sqlQuery = sb.String()
+		}
+		logQuery(ctx, "expand", sqlQuery, args)
+		rows, err := h.db.Query(ctx, sqlQuery, args...)
+		if err != nil {
+			return err
+		}
+		records, err := parseRows(rows, target, hidden)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if id, ok := record["id"].(string); ok {
+				found[id] = record
+			}
+		}
+	}
+	return nil
+}
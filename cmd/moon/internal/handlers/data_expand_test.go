@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// countingDriver records the queries run through Query
+type countingDriver struct {
+	database.Driver
+	queries []string
+}
+
+func (d *countingDriver) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	d.queries = append(d.queries, query)
+	return d.Driver.Query(ctx, query, args...)
+}
+
+// lookups returns the number of recorded queries reading the table
+func (d *countingDriver) lookups(table string) int {
+	n := 0
+	for _, q := range d.queries {
+		if strings.Contains(q, `FROM "`+table+`"`) {
+			n++
+		}
+	}
+	return n
+}
+
+// setupExpandTest adds four orders to the reference test: two of the live
+// customer, one without a customer and one whose customer does not exist.
+// The returned handler counts its queries.
+func setupExpandTest(t *testing.T) (*referenceTest, *DataHandler, *countingDriver) {
+	t.Helper()
+	rt := setupReferenceTest(t)
+	for _, order := range []map[string]any{
+		{"label": "first", "customer_id": rt.customer},
+		{"label": "second", "customer_id": rt.customer},
+		{"label": "none"},
+		{"label": "dangling", "customer_id": rt.customer},
+	} {
+		if w := rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": order}); w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create order: %d %s", w.Code, w.Body.String())
+		}
+	}
+	if _, err := rt.driver.Exec(context.Background(), `UPDATE orders SET customer_id = ? WHERE label = 'dangling'`, generateULID()); err != nil {
+		t.Fatalf("Failed to dangle the reference: %v", err)
+	}
+
+	counter := &countingDriver{Driver: rt.driver}
+	return rt, NewDataHandler(counter, rt.reg, testConfig()), counter
+}
+
+// expandedCustomer returns the customer expanded into a record, and whether
+// the record has the customer_id key under _expanded
+func expandedCustomer(record map[string]any) (map[string]any, bool) {
+	expanded, _ := record["_expanded"].(map[string]any)
+	customer, ok := expanded["customer_id"]
+	c, _ := customer.(map[string]any)
+	return c, ok
+}
+
+func TestExpand_List(t *testing.T) {
+	rt, handler, counter := setupExpandTest(t)
+
+	w := rt.do(t, handler.List, "orders", "/orders:list?expand=customer_id&sort=label", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := counter.lookups("customers"); n != 1 {
+		t.Errorf("expected one customers query for the page, got %d", n)
+	}
+
+	var resp DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data) != 4 {
+		t.Fatalf("expected 4 orders, got %d", len(resp.Data))
+	}
+	for _, record := range resp.Data {
+		customer, ok := expandedCustomer(record)
+		if !ok {
+			t.Fatalf("expected customer_id under _expanded for %v", record["label"])
+		}
+		switch record["label"] {
+		case "first", "second":
+			if customer["id"] != rt.customer || customer["name"] != "ada" {
+				t.Errorf("expected %v to expand to ada, got %v", record["label"], customer)
+			}
+			if record["customer_id"] != rt.customer {
+				t.Errorf("expected the raw customer_id to be kept, got %v", record["customer_id"])
+			}
+		default:
+			if customer != nil {
+				t.Errorf("expected %v to expand to null, got %v", record["label"], customer)
+			}
+		}
+	}
+}
+
+func TestExpand_FieldsApplyToParent(t *testing.T) {
+	rt, handler, _ := setupExpandTest(t)
+
+	w := rt.do(t, handler.List, "orders", "/orders:list?expand=customer_id&fields=label&sort=label", nil)
+	var resp DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Data) != 4 {
+		t.Fatalf("Expected 4 orders, got %d: %s", w.Code, w.Body.String())
+	}
+	first := resp.Data[1]
+	if _, ok := first["customer_id"]; ok {
+		t.Errorf("expected customer_id to be left out of the parent, got %v", first)
+	}
+	if customer, _ := expandedCustomer(first); customer["name"] != "ada" || customer["created_at"] == nil {
+		t.Errorf("expected the full customer record, got %v", customer)
+	}
+}
+
+func TestExpand_Get(t *testing.T) {
+	rt, handler, counter := setupExpandTest(t)
+
+	w := rt.do(t, handler.List, "orders", "/orders:list?q=first", nil)
+	var list DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 {
+		t.Fatalf("expected to find the first order, got %s", w.Body.String())
+	}
+	id := list.Data[0]["id"].(string)
+
+	w = rt.do(t, handler.Get, "orders", "/orders:get?expand=customer_id&id="+id, nil)
+	var resp DataGetResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if customer, _ := expandedCustomer(resp.Data); w.Code != http.StatusOK || customer["name"] != "ada" {
+		t.Errorf("expected the order to expand to ada, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := counter.lookups("customers"); n != 1 {
+		t.Errorf("expected one customers query, got %d", n)
+	}
+
+	w = rt.do(t, handler.Get, "orders", "/orders:get?id="+id, nil)
+	if strings.Contains(w.Body.String(), "_expanded") {
+		t.Errorf("expected no _expanded without expand, got %s", w.Body.String())
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	_, handler, _ := setupExpandTest(t)
+
+	tests := []struct {
+		name string
+		url  string
+		want int
+	}{
+		{"unknown field", "/orders:list?expand=vendor_id", http.StatusBadRequest},
+		{"not a reference", "/orders:list?expand=label", http.StatusBadRequest},
+		{"csv", "/orders:list?expand=customer_id", http.StatusBadRequest},
+		{"get", "/orders:get?expand=label&id=" + generateULID(), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.name == "csv" {
+				req.Header.Set("Accept", "text/csv")
+			}
+			action := handler.List
+			if tt.name == "get" {
+				action = handler.Get
+			}
+			w := httptest.NewRecorder()
+			action(w, req, "orders")
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The referenced collection must be readable by the caller
+	req := httptest.NewRequest(http.MethodGet, "/orders:list?expand=customer_id", nil)
+	req = req.WithContext(middleware.SetAuthEntity(req.Context(), &middleware.AuthEntity{Type: middleware.EntityTypeAPIKey, Role: string(auth.RoleUser),
+		Scopes: auth.Scopes{{Collection: "orders", Actions: []string{auth.ScopeRead}}}}))
+	w := httptest.NewRecorder()
+	handler.List(w, req, "orders")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without read scope on customers, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestParseExpandParam_Limit(t *testing.T) {
+	collection := &registry.Collection{Name: "shipments"}
+	for _, name := range []string{"a_id", "b_id", "c_id", "d_id"} {
+		collection.Columns = append(collection.Columns, registry.Column{Name: name, Type: registry.TypeString, References: "customers"})
+	}
+	req := httptest.NewRequest(http.MethodGet, "/shipments:list", nil)
+
+	if expand, err := parseExpandParam(req, "a_id, b_id,a_id,c_id", collection, nil); err != nil || len(expand) != 3 {
+		t.Errorf("expected three distinct fields, got %v %v", expand, err)
+	}
+	if _, err := parseExpandParam(req, "a_id,b_id,c_id,d_id", collection, nil); err == nil || !strings.Contains(err.Error(), "at most 3") {
+		t.Errorf("expected the limit to be enforced, got %v", err)
+	}
+	if _, err := parseExpandParam(req, "a_id", collection, map[string]bool{"a_id": true}); err == nil {
+		t.Error("expected a hidden field to be refused")
+	}
+}
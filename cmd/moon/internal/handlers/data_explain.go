@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+)
+
+// ExplainArg describes one bound argument of an explained query
+type ExplainArg struct {
+	Type  string `json:"type"`
+	Value any    `json:"value,omitempty"` // only with ?show_values=true
+}
+
+// ExplainResponse represents the response for GET /{name}:explain
+type ExplainResponse struct {
+	Dialect  string       `json:"dialect"`
+	SQL      string       `json:"sql"`
+	Args     []ExplainArg `json:"args"`
+	ArgCount int          `json:"arg_count"`
+	CountSQL string       `json:"count_sql,omitempty"` // the count :list runs when it reports a total
+	Plan     any          `json:"plan"`
+}
+
+// Explain handles GET /{name}:explain
+// It builds the query :list would run for the same parameters and returns
+// its SQL, its arguments and the database's plan for it. The query itself
+// is never run.
+func (h *DataHandler) Explain(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+	if err := checkQueryParams(r, h.config.API.StrictQueryParams, collection, constants.ListQueryParams, constants.SearchQueryParams, []string{"show_values"}); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	showValues, err := explainShowValues(r, collectionName)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	lq, err := h.parseListQuery(r, collectionName, collection)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	plan, err := h.planList(r, collectionName, collection, lq)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	logQuery(ctx, "explain", plan.sql, plan.args)
+	queryPlan, err := h.explainQuery(ctx, plan.sql, plan.args)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to explain query: %v", err))
+		return
+	}
+
+	args := make([]ExplainArg, len(plan.args))
+	for i, value := range plan.args {
+		args[i].Type = explainArgType(value)
+		if showValues {
+			args[i].Value = value
+		}
+	}
+	writeResponse(w, r, http.StatusOK, ExplainResponse{
+		Dialect:  string(h.db.Dialect()),
+		SQL:      plan.sql,
+		Args:     args,
+		ArgCount: len(args),
+		CountSQL: plan.countSQL,
+		Plan:     queryPlan,
+	})
+}
+
+// explainShowValues parses ?show_values=, which needs an admin credential
+// with the schema scope on the collection since the values may be hidden
+// or sensitive
+func explainShowValues(r *http.Request, collectionName string) (bool, error) {
+	show, err := QueryBoolean(r, "show_values", false)
+	if err != nil || !show {
+		return false, err
+	}
+
+	ctx := r.Context()
+	if entity, ok := middleware.GetAuthEntity(ctx); ok && entity.Role != string(auth.RoleAdmin) {
+		return false, apperrors.NewAPIError(http.StatusForbidden, apperrors.CodeForbidden, "show_values requires an admin credential with the schema scope")
+	}
+	if !middleware.HasScope(ctx, logicalName(r, collectionName), auth.ScopeSchema) {
+		return false, apperrors.NewAPIError(http.StatusForbidden, apperrors.CodeForbidden, "show_values requires an admin credential with the schema scope")
+	}
+	return true, nil
+}
+
+// explainQuery returns the database's plan for a query without running it:
+// the EXPLAIN QUERY PLAN rows on SQLite and the JSON plan on PostgreSQL and
+// MySQL, as the database returned it
+func (h *DataHandler) explainQuery(ctx context.Context, sql string, args []any) (any, error) {
+	switch h.db.Dialect() {
+	case database.DialectPostgres, database.DialectMySQL:
+		explain := "EXPLAIN (FORMAT JSON) " + sql
+		if h.db.Dialect() == database.DialectMySQL {
+			explain = "EXPLAIN FORMAT=JSON " + sql
+		}
+		var plan string
+		if err := h.db.QueryRow(ctx, explain, args...).Scan(&plan); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(plan), nil
+	default:
+		rows, err := h.db.Query(ctx, "EXPLAIN QUERY PLAN "+sql, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		plan := []map[string]any{}
+		for rows.Next() {
+			values := make([]any, len(columns))
+			pointers := make([]any, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				return nil, err
+			}
+			row := make(map[string]any, len(columns))
+			for i, column := range columns {
+				if b, ok := values[i].([]byte); ok {
+					values[i] = string(b)
+				}
+				row[column] = values[i]
+			}
+			plan = append(plan, row)
+		}
+		return plan, rows.Err()
+	}
+}
+
+// explainArgType names the type of a bound argument
+func explainArgType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		return "number"
+	case time.Time:
+		return "datetime"
+	case []byte:
+		return "bytes"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
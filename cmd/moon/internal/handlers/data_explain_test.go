@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+)
+
+// queryLogDriver records the SQL of every Query and QueryRow
+type queryLogDriver struct {
+	database.Driver
+	queries []string
+}
+
+func (d *queryLogDriver) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	d.queries = append(d.queries, query)
+	return d.Driver.Query(ctx, query, args...)
+}
+
+func (d *queryLogDriver) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	d.queries = append(d.queries, query)
+	return d.Driver.QueryRow(ctx, query, args...)
+}
+
+// setupExplainTest creates the orders collection with a few records behind a
+// query log
+func setupExplainTest(t *testing.T) (*queryLogDriver, *DataHandler) {
+	t.Helper()
+	driver, handler := setupTransactTest(t)
+	for _, number := range []string{"A-1", "A-2", "A-3"} {
+		if _, err := driver.Exec(context.Background(), "INSERT INTO orders (id, number, status) VALUES (?, ?, 'open')", "id-"+number, number); err != nil {
+			t.Fatalf("failed to insert order: %v", err)
+		}
+	}
+	log := &queryLogDriver{Driver: driver}
+	return log, NewDataHandler(log, handler.registry, testConfig())
+}
+
+func TestExplain_MatchesListSQL(t *testing.T) {
+	log, handler := setupExplainTest(t)
+	params := "?status[eq]=open&number[gt]=A-1&sort=-number&limit=5"
+
+	w := httptest.NewRecorder()
+	handler.List(w, httptest.NewRequest(http.MethodGet, "/orders:list"+params, nil), "orders")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from list, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(log.queries) != 2 {
+		t.Fatalf("expected list to run a count and a page query, got %q", log.queries)
+	}
+	countSQL, listSQL := log.queries[0], log.queries[1]
+
+	log.queries = nil
+	w = httptest.NewRecorder()
+	handler.Explain(w, httptest.NewRequest(http.MethodGet, "/orders:explain"+params, nil), "orders")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from explain, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp ExplainResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.SQL != listSQL {
+		t.Errorf("expected the SQL list runs\n got: %s\nwant: %s", resp.SQL, listSQL)
+	}
+	if resp.CountSQL != countSQL {
+		t.Errorf("expected the count SQL list runs\n got: %s\nwant: %s", resp.CountSQL, countSQL)
+	}
+	if resp.Dialect != "sqlite" {
+		t.Errorf("expected dialect sqlite, got %q", resp.Dialect)
+	}
+	if resp.ArgCount != strings.Count(listSQL, "?") || resp.ArgCount != len(resp.Args) {
+		t.Errorf("expected one arg per placeholder, got arg_count %d and %d args", resp.ArgCount, len(resp.Args))
+	}
+	for i, arg := range resp.Args {
+		if arg.Type == "" || arg.Value != nil {
+			t.Errorf("expected arg %d to have a type and a redacted value, got %+v", i, arg)
+		}
+	}
+
+	plan, ok := resp.Plan.([]any)
+	if !ok || len(plan) == 0 {
+		t.Fatalf("expected SQLite plan rows, got %v", resp.Plan)
+	}
+	if row, ok := plan[0].(map[string]any); !ok || row["detail"] == nil {
+		t.Errorf("expected a plan row with a detail, got %v", plan[0])
+	}
+
+	// Only the plan was asked for; the SELECT itself never ran
+	if len(log.queries) != 1 || !strings.HasPrefix(log.queries[0], "EXPLAIN QUERY PLAN ") {
+		t.Errorf("expected explain to run only EXPLAIN QUERY PLAN, got %q", log.queries)
+	}
+}
+
+func TestExplain_ShowValues(t *testing.T) {
+	_, handler := setupExplainTest(t)
+	url := "/orders:explain?status[eq]=open&show_values=true"
+
+	w := httptest.NewRecorder()
+	handler.Explain(w, httptest.NewRequest(http.MethodGet, url, nil), "orders")
+	var resp ExplainResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Args) == 0 || resp.Args[0].Value != "open" {
+		t.Fatalf("expected the bound values with show_values, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Values need an admin credential
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req = req.WithContext(middleware.SetAuthEntity(req.Context(), &middleware.AuthEntity{ID: "u1", Type: middleware.EntityTypeUser, Role: "user"}))
+	w = httptest.NewRecorder()
+	handler.Explain(w, req, "orders")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin show_values, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Redacted explain is a plain read
+	req = httptest.NewRequest(http.MethodGet, "/orders:explain?status[eq]=open", nil)
+	req = req.WithContext(middleware.SetAuthEntity(req.Context(), &middleware.AuthEntity{ID: "u1", Type: middleware.EntityTypeUser, Role: "user"}))
+	w = httptest.NewRecorder()
+	handler.Explain(w, req, "orders")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a non-admin explain, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExplain_InvalidQuery(t *testing.T) {
+	log, handler := setupExplainTest(t)
+
+	w := httptest.NewRecorder()
+	handler.Explain(w, httptest.NewRequest(http.MethodGet, "/orders:explain?sort=missing", nil), "orders")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown sort field, got %d", w.Code)
+	}
+	if len(log.queries) != 0 {
+		t.Errorf("expected no queries for an invalid request, got %q", log.queries)
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+)
+
+// Export formats supported by GET /{name}:export
+const (
+	exportFormatCSV  = "csv"
+	exportFormatJSON = "json"
+)
+
+// exportFlushRows is the number of rows written between flushes to the client
+const exportFlushRows = 500
+
+// Export handles GET /{name}:export
+// Records matching the :list filter, search, sort and fields parameters are
+// streamed row by row without pagination, either as CSV with a header row
+// (format=csv, the default) or as newline-delimited JSON (format=json).
+func (h *DataHandler) Export(w http.ResponseWriter, r *http.Request, collectionName string) {
+	// Validate collection exists in registry
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatCSV
+	}
+	if format != exportFormatCSV && format != exportFormatJSON {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("invalid format '%s': must be csv or json", format))
+		return
+	}
+
+	// Select records by filters and search as :list does
+	conditions, err := parseRecordConditions(r, collection)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidFilter), err.Error())
+		return
+	}
+
+	sorts, err := parseSort(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidSort, fmt.Sprintf("invalid sort parameter: %v", err))
+		return
+	}
+
+	builder := query.NewBuilder(h.db.Dialect())
+	orderBy, err := buildOrderBy(sorts, collection, builder)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidSort, err.Error())
+		return
+	}
+	// Break ties on id so repeated exports produce the same row order
+	if !sortsByID(sorts) {
+		orderBy += ", id ASC"
+	}
+
+	masked, err := responseHidden(r, collectionName, collection)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	fields, projection, err := parseFields(r, collection, masked)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	sql, args := builder.Select(collectionName, fields, conditions, orderBy, 0, 0)
+
+	rows, err := h.db.Query(r.Context(), sql, args...)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to query data: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	dbColumns, err := rows.Columns()
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read columns: %v", err))
+		return
+	}
+	columns := make([]string, 0, len(dbColumns))
+	for _, col := range dbColumns {
+		if col != "pkid" && !masked[col] {
+			columns = append(columns, col)
+		}
+	}
+
+	columnTypes := rowColumnTypes(collection)
+	clearStreamingDeadlines(w)
+	rc := http.NewResponseController(w)
+
+	// Once the header is written errors can only be logged; the client sees a truncated body
+	logger := logging.WithContext(r.Context())
+	var writeRow func(row map[string]any) error
+	var flush func() error
+	if format == exportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, collectionName))
+		w.WriteHeader(http.StatusOK)
+
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			logger.Errorf("Export of %s failed writing header: %v", collectionName, err)
+			return
+		}
+		record := make([]string, len(columns))
+		writeRow = func(row map[string]any) error {
+			for i, col := range columns {
+				record[i] = exportCSVValue(row[col])
+			}
+			return cw.Write(record)
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ndjson"`, collectionName))
+		w.WriteHeader(http.StatusOK)
+
+		enc := json.NewEncoder(w)
+		writeRow = func(row map[string]any) error {
+			return enc.Encode(row)
+		}
+		flush = func() error { return nil }
+	}
+
+	count := 0
+	for rows.Next() {
+		row, err := scanRow(rows, dbColumns, columnTypes, masked, false)
+		if err != nil {
+			logger.Errorf("Export of %s failed scanning row %d: %v", collectionName, count, err)
+			return
+		}
+		projection.apply(row)
+		if err := writeRow(row); err != nil {
+			logger.Errorf("Export of %s failed writing row %d: %v", collectionName, count, err)
+			return
+		}
+		count++
+		if count%exportFlushRows == 0 {
+			if err := flush(); err != nil {
+				logger.Errorf("Export of %s failed flushing: %v", collectionName, err)
+				return
+			}
+			// Writers without flush support simply buffer until the handler returns
+			_ = rc.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Errorf("Export of %s failed reading rows: %v", collectionName, err)
+		return
+	}
+	if err := flush(); err != nil {
+		logger.Errorf("Export of %s failed flushing: %v", collectionName, err)
+	}
+}
+
+// sortsByID reports whether the sort fields already include the id column
+func sortsByID(sorts []sortField) bool {
+	if len(sorts) == 0 {
+		// buildOrderBy defaults to id ASC
+		return true
+	}
+	for _, sort := range sorts {
+		if sort.column == "id" {
+			return true
+		}
+	}
+	return false
+}
+
+// exportCSVValue renders a scanned column value as a CSV cell.
+// NULL becomes an empty cell, booleans render as true/false and JSON columns keep their raw string.
+func exportCSVValue(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// heapSamplingWriter discards the response body while tracking the live heap
+// after every sampleBytes written, so tests can assert streaming keeps memory flat.
+type heapSamplingWriter struct {
+	header      http.Header
+	status      int
+	written     int
+	nextSample  int
+	sampleBytes int
+	baseline    uint64
+	peak        uint64
+}
+
+func newHeapSamplingWriter(sampleBytes int) *heapSamplingWriter {
+	w := &heapSamplingWriter{header: http.Header{}, sampleBytes: sampleBytes, nextSample: sampleBytes}
+	w.baseline = liveHeap()
+	w.peak = w.baseline
+	return w
+}
+
+func (w *heapSamplingWriter) Header() http.Header { return w.header }
+
+func (w *heapSamplingWriter) WriteHeader(status int) { w.status = status }
+
+func (w *heapSamplingWriter) Write(p []byte) (int, error) {
+	w.written += len(p)
+	if w.written >= w.nextSample {
+		w.nextSample += w.sampleBytes
+		if heap := liveHeap(); heap > w.peak {
+			w.peak = heap
+		}
+	}
+	return len(p), nil
+}
+
+func liveHeap() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func doExport(t *testing.T, handler *DataHandler, url string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.Export(w, httptest.NewRequest(http.MethodGet, url, nil), "products")
+	return w
+}
+
+func TestDataHandler_Export_CSV(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	ctx := context.Background()
+	rows := []struct {
+		id       string
+		name     string
+		price    int
+		category any
+		active   bool
+	}{
+		{"01ARYZ6S41TSV4RRFFQ69G5FA1", "Banana", 100, "fruit", true},
+		{"01ARYZ6S41TSV4RRFFQ69G5FA2", "Apple, red", 200, nil, false},
+		{"01ARYZ6S41TSV4RRFFQ69G5FA3", "Cherry", 50, "fruit", true},
+	}
+	for _, row := range rows {
+		if _, err := driver.Exec(ctx, "INSERT INTO products (id, name, price, category, active) VALUES (?, ?, ?, ?, ?)",
+			row.id, row.name, row.price, row.category, row.active); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+
+	w := doExport(t, handler, "/products:export?fields=name,active,category&sort=-price")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="products.csv"` {
+		t.Errorf("unexpected Content-Disposition: %s", cd)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	want := [][]string{
+		{"id", "name", "active", "category"},
+		{"01ARYZ6S41TSV4RRFFQ69G5FA2", "Apple, red", "false", ""},
+		{"01ARYZ6S41TSV4RRFFQ69G5FA1", "Banana", "true", "fruit"},
+		{"01ARYZ6S41TSV4RRFFQ69G5FA3", "Cherry", "true", "fruit"},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+
+	// Filters apply as in :list
+	w = doExport(t, handler, "/products:export?price[lt]=150")
+	records, _ = csv.NewReader(w.Body).ReadAll()
+	if len(records) != 3 {
+		t.Errorf("expected header and 2 filtered rows, got %d records", len(records))
+	}
+}
+
+func TestDataHandler_Export_JSON(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		if _, err := driver.Exec(ctx, "INSERT INTO products (id, name, price, active) VALUES (?, ?, ?, 1)",
+			fmt.Sprintf("01ARYZ6S41TSV4RRFFQ69G5FA%d", i), fmt.Sprintf("Item %d", i), i); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+
+	w := doExport(t, handler, "/products:export?format=json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+
+	scanner := bufio.NewScanner(w.Body)
+	lines := 0
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d is not JSON: %v", lines, err)
+		}
+		if _, ok := record["pkid"]; ok {
+			t.Error("pkid must not be exported")
+		}
+		if record["active"] != true {
+			t.Errorf("expected boolean active, got %v", record["active"])
+		}
+		lines++
+	}
+	if lines != 3 {
+		t.Errorf("expected 3 lines, got %d", lines)
+	}
+}
+
+func TestDataHandler_Export_Validation(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"invalid format", "/products:export?format=xml"},
+		{"invalid field", "/products:export?fields=nope"},
+		{"invalid sort", "/products:export?sort=nope"},
+		{"invalid filter column", "/products:export?nope[eq]=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := doExport(t, handler, tt.url); w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler.Export(w, httptest.NewRequest(http.MethodGet, "/missing:export", nil), "missing")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown collection, got %d", w.Code)
+	}
+}
+
+func TestDataHandler_Export_LargeStreaming_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large export in short mode")
+	}
+
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	const total = 10000
+	ctx := context.Background()
+	tx, err := driver.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	padding := strings.Repeat("x", 200)
+	for i := 0; i < total; i++ {
+		// Repeating prices force the id tie-breaker to decide the order
+		if _, err := tx.ExecContext(ctx, "INSERT INTO products (id, name, price, category) VALUES (?, ?, ?, ?)",
+			generateULID(), fmt.Sprintf("Item %05d", i), i%7, padding); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	for _, format := range []string{"csv", "json"} {
+		t.Run(format, func(t *testing.T) {
+			w := newHeapSamplingWriter(256 * 1024)
+			handler.Export(w, httptest.NewRequest(http.MethodGet, "/products:export?sort=price&format="+format, nil), "products")
+			if w.status != http.StatusOK {
+				t.Fatalf("expected 200, got %d", w.status)
+			}
+			// Holding every row in memory would grow the live heap by more than the output size
+			if growth := int64(w.peak) - int64(w.baseline); growth > int64(w.written)/2 {
+				t.Errorf("live heap grew by %d bytes while streaming %d bytes", growth, w.written)
+			}
+		})
+	}
+
+	// Two exports of the same query produce identical, fully ordered output
+	first := doExport(t, handler, "/products:export?sort=price&fields=price")
+	second := doExport(t, handler, "/products:export?sort=price&fields=price")
+	if !bytes.Equal(first.Body.Bytes(), second.Body.Bytes()) {
+		t.Fatal("expected identical output for repeated exports")
+	}
+	records, err := csv.NewReader(first.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records) != total+1 {
+		t.Fatalf("expected %d records plus header, got %d", total, len(records)-1)
+	}
+	for i := 2; i < len(records); i++ {
+		prev, cur := records[i-1], records[i]
+		if prev[1] > cur[1] || (prev[1] == cur[1] && prev[0] >= cur[0]) {
+			t.Fatalf("rows %d and %d out of order: %v, %v", i-1, i, prev, cur)
+		}
+	}
+}
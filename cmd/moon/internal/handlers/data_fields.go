@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// jsonProjection holds the dotted fields selections inside json columns, e.g.
+// meta.size.width, as the keys below each column. The columns are selected
+// whole in SQL; apply cuts their values down to the selected keys.
+type jsonProjection map[string][][]string
+
+// parseFieldPath validates a dotted fields selection whose root is column and
+// returns its keys. Array indexes are not supported.
+func parseFieldPath(field string, col *registry.Column, path string) ([]string, error) {
+	if col.Type != registry.TypeJSON {
+		return nil, fmt.Errorf("invalid field: %s (column '%s' is not a json column)", field, col.Name)
+	}
+	keys := strings.Split(path, ".")
+	if len(keys) > constants.MaxFieldPathDepth {
+		return nil, fmt.Errorf("invalid field: %s (json paths may hold at most %d keys below the column)", field, constants.MaxFieldPathDepth)
+	}
+	for _, key := range keys {
+		if !jsonPathKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid field: %s (json path keys may only contain letters, numbers and underscores)", field)
+		}
+		if strings.Trim(key, "0123456789") == "" {
+			return nil, fmt.Errorf("invalid field: %s (array indexes are not supported in field paths)", field)
+		}
+	}
+	return keys, nil
+}
+
+// add selects keys inside column. A path inside one already selected adds
+// nothing, and a path around others replaces them.
+func (p jsonProjection) add(column string, keys []string) {
+	paths := p[column]
+	for _, path := range paths {
+		if len(path) <= len(keys) && slices.Equal(path, keys[:len(path)]) {
+			return
+		}
+	}
+	paths = slices.DeleteFunc(paths, func(path []string) bool {
+		return len(path) > len(keys) && slices.Equal(path[:len(keys)], keys)
+	})
+	p[column] = append(paths, keys)
+}
+
+// apply replaces the value of each projected column of a record, a JSON
+// document, by an object holding only the selected keys. Missing keys, and
+// keys below a value that is not an object, are left out; NULL stays NULL.
+func (p jsonProjection) apply(record map[string]any) {
+	for column, paths := range p {
+		raw, ok := record[column].(string)
+		if !ok {
+			continue
+		}
+		var doc any
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			continue
+		}
+
+		out := map[string]any{}
+		for _, path := range paths {
+			if value, found := jsonLookup(doc, path); found {
+				jsonInsert(out, path, value)
+			}
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(out); err != nil {
+			continue
+		}
+		record[column] = strings.TrimSuffix(buf.String(), "\n")
+	}
+}
+
+// jsonLookup returns the value at keys inside a decoded JSON document
+func jsonLookup(doc any, keys []string) (any, bool) {
+	for _, key := range keys {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if doc, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// jsonInsert sets value at keys inside out, creating the objects on the way
+func jsonInsert(out map[string]any, keys []string, value any) {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := out[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			out[key] = next
+		}
+		out = next
+	}
+	out[keys[len(keys)-1]] = value
+}
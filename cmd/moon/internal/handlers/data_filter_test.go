@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func filterTestCollection() *registry.Collection {
+	return &registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "name", Type: registry.TypeString},
+			{Name: "price", Type: registry.TypeInteger},
+			{Name: "deleted_at", Type: registry.TypeDatetime, Nullable: true},
+		},
+	}
+}
+
+func TestParseFilters_NullAndBetween(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/products:list?deleted_at[isnull]=true&name[notnull]=true&price[between]=10,100", nil)
+	filters, err := parseFilters(req)
+	if err != nil {
+		t.Fatalf("parseFilters() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range filters {
+		got[f.column] = f.operator
+	}
+	want := map[string]string{"deleted_at": "isnull", "name": "notnull", "price": "between"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBuildConditions_NullAndBetween(t *testing.T) {
+	collection := filterTestCollection()
+
+	tests := []struct {
+		name        string
+		filter      filterParam
+		wantOp      string
+		wantValue   any
+		errContains string
+	}{
+		{"isnull true", filterParam{"deleted_at", "isnull", "true"}, query.OpIsNull, nil, ""},
+		{"isnull false flips", filterParam{"deleted_at", "isnull", "false"}, query.OpIsNotNull, nil, ""},
+		{"notnull true", filterParam{"deleted_at", "notnull", "true"}, query.OpIsNotNull, nil, ""},
+		{"notnull false flips", filterParam{"deleted_at", "notnull", "false"}, query.OpIsNull, nil, ""},
+		{"isnull invalid value", filterParam{"deleted_at", "isnull", "maybe"}, "", nil, "accepted values are true, false, 1 and 0"},
+		{"between integers", filterParam{"price", "between", "10, 100"}, query.OpBetween, []any{int64(10), int64(100)}, ""},
+		{"between strings", filterParam{"name", "between", "a,m"}, query.OpBetween, []any{"a", "m"}, ""},
+		{"between single value", filterParam{"price", "between", "10"}, "", nil, "expected two comma-separated values"},
+		{"between three values", filterParam{"price", "between", "1,2,3"}, "", nil, "expected two comma-separated values"},
+		{"between empty bound", filterParam{"price", "between", "10,"}, "", nil, "expected two comma-separated values"},
+		{"between bad integer", filterParam{"price", "between", "a,b"}, "", nil, "invalid value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds, err := buildConditions([]filterParam{tt.filter}, collection)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if conds[0].Operator != tt.wantOp {
+				t.Errorf("operator = %s, want %s", conds[0].Operator, tt.wantOp)
+			}
+			if !reflect.DeepEqual(conds[0].Value, tt.wantValue) {
+				t.Errorf("value = %#v, want %#v", conds[0].Value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestBuildCountAndSearchQuery_NullAndBetween(t *testing.T) {
+	filters := []query.Condition{
+		{Column: "deleted_at", Operator: query.OpIsNull},
+		{Column: "price", Operator: query.OpBetween, Value: []any{int64(10), int64(100)}},
+		{Column: "name", Operator: query.OpIsNotNull},
+	}
+
+	tests := []struct {
+		name        string
+		dialect     database.DialectType
+		searchSQL   string
+		searchArgs  []any
+		wantCount   string
+		wantSelect  string
+		wantArgsLen int
+	}{
+		{
+			name:        "sqlite",
+			dialect:     database.DialectSQLite,
+			searchSQL:   `("name" LIKE ?)`,
+			searchArgs:  []any{"%x%"},
+			wantCount:   `SELECT COUNT(*) FROM "products" WHERE ("name" LIKE ?) AND "deleted_at" IS NULL AND "price" BETWEEN ? AND ? AND "name" IS NOT NULL`,
+			wantSelect:  `SELECT * FROM "products" WHERE ("name" LIKE ?) AND "deleted_at" IS NULL AND "price" BETWEEN ? AND ? AND "name" IS NOT NULL ORDER BY id ASC LIMIT ?`,
+			wantArgsLen: 3,
+		},
+		{
+			name:        "postgres",
+			dialect:     database.DialectPostgres,
+			searchSQL:   `("name" LIKE $1)`,
+			searchArgs:  []any{"%x%"},
+			wantCount:   `SELECT COUNT(*) FROM "products" WHERE ("name" LIKE $1) AND "deleted_at" IS NULL AND "price" BETWEEN $2 AND $3 AND "name" IS NOT NULL`,
+			wantSelect:  `SELECT * FROM "products" WHERE ("name" LIKE $1) AND "deleted_at" IS NULL AND "price" BETWEEN $2 AND $3 AND "name" IS NOT NULL ORDER BY id ASC LIMIT $4`,
+			wantArgsLen: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, countArgs := buildListWhere(filters, tt.searchSQL, tt.searchArgs, tt.dialect)
+			countSQL := buildCountQuery("products", where, tt.dialect)
+			if countSQL != tt.wantCount {
+				t.Errorf("count query:\n got %s\nwant %s", countSQL, tt.wantCount)
+			}
+			if len(countArgs) != tt.wantArgsLen {
+				t.Errorf("expected %d count args, got %d", tt.wantArgsLen, len(countArgs))
+			}
+
+			selectSQL, selectArgs := buildSearchQueryWithFields("products", nil, filters, tt.searchSQL, tt.searchArgs, "id ASC", 10, tt.dialect)
+			if selectSQL != tt.wantSelect {
+				t.Errorf("select query:\n got %s\nwant %s", selectSQL, tt.wantSelect)
+			}
+			if len(selectArgs) != tt.wantArgsLen+1 {
+				t.Errorf("expected %d select args, got %d", tt.wantArgsLen+1, len(selectArgs))
+			}
+		})
+	}
+}
+
+func TestDataHandler_List_NullAndBetween_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	ctx := t.Context()
+	rows := []struct {
+		name     string
+		price    int
+		category any
+	}{
+		{"a", 5, nil},
+		{"b", 50, "tools"},
+		{"c", 150, nil},
+	}
+	for _, r := range rows {
+		if _, err := driver.Exec(ctx, "INSERT INTO products (id, name, price, category) VALUES (?, ?, ?, ?)", generateULID(), r.name, r.price, r.category); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		url        string
+		wantStatus int
+		wantTotal  int
+	}{
+		{"/products:list?category[isnull]=true", http.StatusOK, 2},
+		{"/products:list?category[notnull]=true", http.StatusOK, 1},
+		{"/products:list?price[between]=10,200", http.StatusOK, 2},
+		{"/products:list?price[between]=10,200&category[isnull]=true", http.StatusOK, 1},
+		{"/products:list?q=a&price[between]=1,10", http.StatusOK, 1},
+		{"/products:list?price[between]=10", http.StatusBadRequest, 0},
+		{"/products:list?category[isnull]=yes", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.List(w, httptest.NewRequest(http.MethodGet, tt.url, nil), "products")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp DataListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if *resp.Total != tt.wantTotal || len(resp.Data) != tt.wantTotal {
+				t.Errorf("expected %d records, got total=%d len=%d", tt.wantTotal, *resp.Total, len(resp.Data))
+			}
+		})
+	}
+}
+
+func TestParseSearchFields(t *testing.T) {
+	collection := &registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "name", Type: registry.TypeString},
+			{Name: "category", Type: registry.TypeString},
+			{Name: "price", Type: registry.TypeInteger},
+		},
+	}
+
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{query: "", want: nil},
+		{query: "q_fields=name", want: []string{"name"}},
+		{query: "q_fields=category,+name,category", want: []string{"category", "name"}},
+		{query: "q_fields=price", wantErr: true},
+		{query: "q_fields=missing", wantErr: true},
+		{query: "q_fields=,", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			fields, err := parseSearchFields(httptest.NewRequest(http.MethodGet, "/products:list?"+tt.query, nil), collection)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.want, ",") || (fields == nil) != (tt.want == nil) {
+				t.Errorf("expected %v, got %v", tt.want, fields)
+			}
+		})
+	}
+}
+
+func TestBuildSearchConditions_EscapeClause(t *testing.T) {
+	collection := &registry.Collection{
+		Name:    "products",
+		Columns: []registry.Column{{Name: "name", Type: registry.TypeString}},
+	}
+
+	sql, args := buildSearchConditions(`100%_done\`, collection, nil, query.MatchContains, database.DialectSQLite)
+	if sql != `("name" LIKE ? ESCAPE '\')` {
+		t.Errorf("unexpected SQLite search SQL: %s", sql)
+	}
+	if args[0] != `%100\%\_done\\%` {
+		t.Errorf("unexpected escaped term: %v", args[0])
+	}
+
+	sql, _ = buildSearchConditions("x", collection, nil, query.MatchContains, database.DialectPostgres)
+	if sql != `("name" LIKE $1)` {
+		t.Errorf("unexpected PostgreSQL search SQL: %s", sql)
+	}
+}
+
+func TestBuildSearchConditions_Modes(t *testing.T) {
+	collection := &registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "name", Type: registry.TypeString},
+			{Name: "category", Type: registry.TypeString},
+		},
+	}
+
+	tests := []struct {
+		mode    string
+		wantSQL string
+		wantArg string
+	}{
+		{query.MatchContains, `("name" LIKE $1 OR "category" LIKE $2)`, "%Lap%"},
+		{query.MatchInsensitive, `(LOWER("name") LIKE LOWER($1) OR LOWER("category") LIKE LOWER($2))`, "%Lap%"},
+		{query.MatchPrefix, `("name" LIKE $1 OR "category" LIKE $2)`, "Lap%"},
+		{query.MatchExact, `("name" LIKE $1 OR "category" LIKE $2)`, "Lap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantArg, func(t *testing.T) {
+			sql, args := buildSearchConditions("Lap", collection, nil, tt.mode, database.DialectPostgres)
+			if sql != tt.wantSQL {
+				t.Errorf("expected SQL %s, got %s", tt.wantSQL, sql)
+			}
+			if len(args) != 2 || args[0] != tt.wantArg || args[1] != tt.wantArg {
+				t.Errorf("expected args [%s %s], got %v", tt.wantArg, tt.wantArg, args)
+			}
+		})
+	}
+}
+
+func TestDataHandler_List_SearchMode_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	ctx := t.Context()
+	for _, name := range []string{"Laptop", "Laptop Stand", "GAMING LAPTOP", "Desk"} {
+		if _, err := driver.Exec(ctx, "INSERT INTO products (id, name, price, category) VALUES (?, ?, ?, ?)", generateULID(), name, 1, "office"); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		url        string
+		wantStatus int
+		wantTotal  int
+	}{
+		{"/products:list?q=laptop", http.StatusOK, 3},
+		{"/products:list?q=laptop&q_mode=contains", http.StatusOK, 3},
+		{"/products:list?q=lAPTOP&q_mode=insensitive", http.StatusOK, 3},
+		{"/products:list?q=lap&q_mode=prefix", http.StatusOK, 2},
+		{"/products:list?q=laptop&q_mode=exact", http.StatusOK, 1},
+		{"/products:list?q=lap&q_mode=exact", http.StatusOK, 0},
+		{"/products:list?q=laptop&q_mode=fuzzy", http.StatusBadRequest, 0},
+		// q_mode without q does not search
+		{"/products:list?q_mode=exact", http.StatusOK, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.List(w, httptest.NewRequest(http.MethodGet, tt.url, nil), "products")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp DataListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if *resp.Total != tt.wantTotal || len(resp.Data) != tt.wantTotal {
+				t.Errorf("expected %d records, got total=%d len=%d", tt.wantTotal, *resp.Total, len(resp.Data))
+			}
+		})
+	}
+}
+
+func TestDataHandler_List_Search_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	ctx := t.Context()
+	rows := []struct {
+		name     string
+		category string
+	}{
+		{"100%_done", "tasks"},
+		{"1000 done", "tasks"},
+		{"100x done", "done"},
+		{`C:\done`, "paths"},
+	}
+	for _, r := range rows {
+		if _, err := driver.Exec(ctx, "INSERT INTO products (id, name, price, category) VALUES (?, ?, ?, ?)", generateULID(), r.name, 1, r.category); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		url        string
+		wantStatus int
+		wantTotal  int
+	}{
+		// Wildcards in the term match only themselves
+		{"/products:list?q=" + url.QueryEscape("100%_done"), http.StatusOK, 1},
+		{"/products:list?q=_", http.StatusOK, 1},
+		{"/products:list?q=" + url.QueryEscape(`:\d`), http.StatusOK, 1},
+		// All string columns are searched by default
+		{"/products:list?q=done", http.StatusOK, 4},
+		{"/products:list?q=tasks", http.StatusOK, 2},
+		// q_fields restricts the searched columns
+		{"/products:list?q=tasks&q_fields=name", http.StatusOK, 0},
+		{"/products:list?q=done&q_fields=category", http.StatusOK, 1},
+		{"/products:list?q=done&q_fields=price", http.StatusBadRequest, 0},
+		{"/products:list?q=done&q_fields=missing", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.List(w, httptest.NewRequest(http.MethodGet, tt.url, nil), "products")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp DataListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if *resp.Total != tt.wantTotal || len(resp.Data) != tt.wantTotal {
+				t.Errorf("expected %d records, got total=%d len=%d", tt.wantTotal, *resp.Total, len(resp.Data))
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+)
+
+// setupHiddenTest creates a notes collection with a hidden cost column and
+// two records
+func setupHiddenTest(t *testing.T) (*DataHandler, *AggregationHandler, string) {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "cost", "type": "integer", "nullable": true, "hidden": true},
+		},
+	})
+
+	var id string
+	for i, title := range []string{"cheap", "dear"} {
+		w := doDataAction(t, nt.data.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": title, "cost": []int{5, 50}[i]}})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		id, _ = resp["data"].(map[string]any)["id"].(string)
+	}
+	return nt.data, NewAggregationHandler(nt.driver, nt.reg), id
+}
+
+// doAsEntity runs a data action with an authenticated entity in the context
+func doAsEntity(action func(http.ResponseWriter, *http.Request, string), url string, entity *middleware.AuthEntity) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req = req.WithContext(middleware.SetAuthEntity(req.Context(), entity))
+	w := httptest.NewRecorder()
+	action(w, req, "notes")
+	return w
+}
+
+func TestHiddenColumns_List(t *testing.T) {
+	handler, _, _ := setupHiddenTest(t)
+
+	_, resp := listTitles(t, handler, "/notes:list")
+	if len(resp.Data) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(resp.Data))
+	}
+	for _, record := range resp.Data {
+		if _, ok := record["cost"]; ok {
+			t.Errorf("expected cost to be hidden, got %v", record)
+		}
+	}
+
+	// Hidden columns can still be filtered on
+	titles, _ := listTitles(t, handler, "/notes:list?cost[gt]=10")
+	if len(titles) != 1 || titles[0] != "dear" {
+		t.Errorf("expected the filter on cost to select dear, got %v", titles)
+	}
+
+	w := doDataAction(t, handler.List, http.MethodGet, "/notes:list?sort=cost", nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "sort field 'cost' is hidden") {
+		t.Errorf("expected 400 for sort on a hidden column, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHiddenColumns_Fields(t *testing.T) {
+	handler, _, _ := setupHiddenTest(t)
+
+	for _, url := range []string{"/notes:list?fields=title,cost", "/notes:export?fields=cost"} {
+		action := handler.List
+		if strings.Contains(url, "export") {
+			action = handler.Export
+		}
+		w := doDataAction(t, action, http.MethodGet, url, nil)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "field 'cost' is hidden") {
+			t.Errorf("%s: expected 400 naming cost as hidden, got %d %s", url, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHiddenColumns_Get(t *testing.T) {
+	handler, _, id := setupHiddenTest(t)
+
+	w := doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Get failed: %d %s", w.Code, w.Body.String())
+	}
+	var resp map[string]map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["data"]["title"] != "dear" {
+		t.Errorf("expected the record, got %v", resp["data"])
+	}
+	if _, ok := resp["data"]["cost"]; ok {
+		t.Errorf("expected cost to be hidden, got %v", resp["data"])
+	}
+}
+
+func TestHiddenColumns_Export(t *testing.T) {
+	handler, _, _ := setupHiddenTest(t)
+
+	w := doDataAction(t, handler.Export, http.MethodGet, "/notes:export?format=csv", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Export failed: %d %s", w.Code, w.Body.String())
+	}
+	if header, _, _ := strings.Cut(w.Body.String(), "\n"); strings.Contains(header, "cost") || !strings.Contains(header, "title") {
+		t.Errorf("expected the CSV header without cost, got %q", header)
+	}
+
+	w = doDataAction(t, handler.Export, http.MethodGet, "/notes:export?format=json", nil)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "cost") {
+		t.Errorf("expected NDJSON without cost, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHiddenColumns_AggregationAndSchema(t *testing.T) {
+	handler, aggregation, _ := setupHiddenTest(t)
+
+	w := doDataAction(t, aggregation.Sum, http.MethodGet, "/notes:sum?field=cost", nil)
+	var sum AggregationResponse
+	json.Unmarshal(w.Body.Bytes(), &sum)
+	if w.Code != http.StatusOK || sum.Value != float64(55) {
+		t.Errorf("expected the sum of cost to be 55, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doDataAction(t, handler.Schema, http.MethodGet, "/notes:schema", nil)
+	var schema SchemaResponse
+	json.Unmarshal(w.Body.Bytes(), &schema)
+	for _, field := range schema.Fields {
+		if field.Hidden != (field.Name == "cost") {
+			t.Errorf("expected only cost marked hidden, got %+v", field)
+		}
+	}
+}
+
+func TestHiddenColumns_AggregationValues(t *testing.T) {
+	_, aggregation, _ := setupHiddenTest(t)
+
+	// Aggregations returning stored values of the column refuse it
+	tests := []struct {
+		name   string
+		action func(http.ResponseWriter, *http.Request, string)
+		url    string
+	}{
+		{"distinct", aggregation.Distinct, "/notes:distinct?field=cost"},
+		{"groupby key", aggregation.GroupBy, "/notes:groupby?by=cost"},
+		{"groupby max", aggregation.GroupBy, "/notes:groupby?by=title&agg=max&field=cost"},
+		{"min", aggregation.Min, "/notes:min?field=cost"},
+		{"max", aggregation.Max, "/notes:max?field=cost"},
+		{"timeseries min", aggregation.TimeSeries, "/notes:timeseries?field=created_at&agg=min&value_field=cost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doDataAction(t, tt.action, http.MethodGet, tt.url, nil)
+			assertErrorCode(t, w, http.StatusBadRequest, apperrors.CodeInvalidParameter)
+			var resp map[string]any
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if got, _ := errorMessage(resp); got != "field 'cost' is hidden" {
+				t.Errorf("expected the hidden field to be named, got %q", got)
+			}
+		})
+	}
+
+	// Counts, sums and averages only reveal totals
+	allowed := []struct {
+		action func(http.ResponseWriter, *http.Request, string)
+		url    string
+	}{
+		{aggregation.GroupBy, "/notes:groupby?by=title&agg=sum&field=cost"},
+		{aggregation.Avg, "/notes:avg?field=cost"},
+		{aggregation.Count, "/notes:count?cost[gt]=10"},
+	}
+	for _, tt := range allowed {
+		if w := doDataAction(t, tt.action, http.MethodGet, tt.url, nil); w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d %s", tt.url, w.Code, w.Body.String())
+		}
+	}
+
+	// An admin with the schema scope may pass include_hidden
+	admin := &middleware.AuthEntity{Type: middleware.EntityTypeUser, Role: string(auth.RoleAdmin)}
+	w := doAsEntity(aggregation.Distinct, "/notes:distinct?field=cost&include_hidden=true", admin)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"values":[5,50]`) {
+		t.Errorf("expected an admin to list the values of cost, got %d %s", w.Code, w.Body.String())
+	}
+	user := &middleware.AuthEntity{Type: middleware.EntityTypeUser, Role: string(auth.RoleUser)}
+	if w := doAsEntity(aggregation.Max, "/notes:max?field=cost&include_hidden=true", user); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a user passing include_hidden, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHiddenColumns_IncludeHidden(t *testing.T) {
+	handler, _, id := setupHiddenTest(t)
+	admin := &middleware.AuthEntity{Type: middleware.EntityTypeUser, Role: string(auth.RoleAdmin)}
+
+	w := doAsEntity(handler.List, "/notes:list?include_hidden=true&sort=cost", admin)
+	var resp DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Data) != 2 || resp.Data[0]["cost"] != float64(5) {
+		t.Errorf("expected an admin to see cost, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doAsEntity(handler.Get, "/notes:get?include_hidden=true&id="+id, admin)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"cost":50`) {
+		t.Errorf("expected an admin to get cost, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doAsEntity(handler.Export, "/notes:export?format=csv&include_hidden=true&fields=cost", admin)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "id,cost") {
+		t.Errorf("expected an admin to export cost, got %d %s", w.Code, w.Body.String())
+	}
+
+	denied := []struct {
+		name   string
+		entity *middleware.AuthEntity
+	}{
+		{"user", &middleware.AuthEntity{Type: middleware.EntityTypeUser, Role: string(auth.RoleUser)}},
+		{"admin key without schema scope", &middleware.AuthEntity{Type: middleware.EntityTypeAPIKey, Role: string(auth.RoleAdmin),
+			Scopes: auth.Scopes{{Collection: "notes", Actions: []string{auth.ScopeRead}}}}},
+	}
+	for _, tt := range denied {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doAsEntity(handler.List, "/notes:list?include_hidden=true", tt.entity)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected 403, got %d %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w = doDataAction(t, handler.List, http.MethodGet, "/notes:list?include_hidden=maybe", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid include_hidden, got %d", w.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// clientIDRegex validates client-supplied record ids
+var clientIDRegex = regexp.MustCompile(constants.ClientIDPattern)
+
+// idTypes are the id strategies a collection can be created with
+var idTypes = map[registry.IDType]bool{
+	registry.IDTypeULID:   true,
+	registry.IDTypeUUIDv7: true,
+	registry.IDTypeClient: true,
+}
+
+// validateIDType checks the id_type of a new collection
+func validateIDType(collection *registry.Collection) error {
+	if collection.IDType != "" && !idTypes[collection.IDType] {
+		return fmt.Errorf("invalid id_type '%s'; supported: ulid, uuidv7, client", collection.IDType)
+	}
+	return nil
+}
+
+// idColumnSQL returns the column type of the id column for an id strategy.
+// ULIDs are 26 characters and UUIDs 36; client ids are variable up to
+// constants.MaxClientIDLength.
+func idColumnSQL(idType registry.IDType) string {
+	switch idType {
+	case registry.IDTypeUUIDv7:
+		return "CHAR(36)"
+	case registry.IDTypeClient:
+		return fmt.Sprintf("VARCHAR(%d)", constants.MaxClientIDLength)
+	}
+	return "CHAR(26)"
+}
+
+// newRecordIDs returns the ids of new records in ascending order. Client-id
+// collections take the id of each item, which validateFields has checked.
+func newRecordIDs(collection *registry.Collection, items []map[string]any) []string {
+	if collection.RecordIDType() != registry.IDTypeClient {
+		return generateRecordIDs(collection.RecordIDType(), len(items))
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i], _ = item["id"].(string)
+	}
+	return ids
+}
+
+// generateRecordIDs generates n ids of a server-generated strategy. ULIDs and
+// UUIDv7s both start with the creation time and increase within the process,
+// so records sort by id in the order they were created.
+func generateRecordIDs(idType registry.IDType, n int) []string {
+	if idType != registry.IDTypeUUIDv7 {
+		return generateULIDs(n)
+	}
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = uuid.Must(uuid.NewV7()).String()
+	}
+	return ids
+}
+
+// newRecordID returns the id of one new record
+func newRecordID(collection *registry.Collection, data map[string]any) string {
+	return newRecordIDs(collection, []map[string]any{data})[0]
+}
+
+// validateRecordID checks that id is an id of the collection's strategy.
+// ULID errors are plain errors, reported as invalid_ulid; the other
+// strategies return *apperrors.APIError values with invalid_id.
+func validateRecordID(collection *registry.Collection, id string) error {
+	switch collection.RecordIDType() {
+	case registry.IDTypeUUIDv7:
+		if parsed, err := uuid.Parse(id); err != nil || parsed.Version() != 7 || parsed.String() != id {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidID, "expected a lowercase UUIDv7, got '%s'", id)
+		}
+		return nil
+	case registry.IDTypeClient:
+		if len(id) > constants.MaxClientIDLength || !clientIDRegex.MatchString(id) {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidID, "expected 1 to %d letters, digits, '_', '-', '.' or ':' starting with a letter or digit", constants.MaxClientIDLength)
+		}
+		return nil
+	}
+	return validateULID(id)
+}
+
+// validateCreateID checks the id of a record to create: client-id collections
+// require a valid one, the others assign it and reject a supplied one
+func validateCreateID(data map[string]any, collection *registry.Collection) error {
+	val, ok := data["id"]
+	if collection.RecordIDType() != registry.IDTypeClient {
+		if ok {
+			return apperrors.Newf(http.StatusBadRequest, apperrors.CodeReadOnlyField, "field 'id' is read-only; ids are assigned by the server (id_type %s)", collection.RecordIDType())
+		}
+		return nil
+	}
+
+	if !ok || val == nil {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeRequiredField, "required field 'id' is missing; the collection uses client-supplied ids")
+	}
+	id, isString := val.(string)
+	if !isString {
+		return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidType, "field 'id' must be a string")
+	}
+	if err := validateRecordID(collection, id); err != nil {
+		return apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidID, "invalid id: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setIDType switches the products collection of setupDataIntegrationTest to idType
+func setIDType(t *testing.T, reg *registry.SchemaRegistry, idType registry.IDType) {
+	t.Helper()
+	collection, ok := reg.Get("products")
+	if !ok {
+		t.Fatal("products collection not registered")
+	}
+	collection.IDType = idType
+	reg.Set(collection)
+}
+
+// getRecord fetches one products record by id
+func getRecord(t *testing.T, handler *DataHandler, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/products:get?id="+url.QueryEscape(id), nil)
+	w := httptest.NewRecorder()
+	handler.Get(w, req, "products")
+	return w
+}
+
+func TestDataHandler_UUIDv7IDs_PaginateInCreationOrder(t *testing.T) {
+	driver, reg, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+	setIDType(t, reg, registry.IDTypeUUIDv7)
+
+	var created []string
+	for i := 0; i < 12; i++ {
+		data := map[string]any{"name": fmt.Sprintf("Product %02d", i), "price": i}
+		w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: data}, "")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("failed to create record %d: %s", i, w.Body.String())
+		}
+		var resp CreateDataResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		id, _ := resp.Data["id"].(string)
+		if parsed, err := uuid.Parse(id); err != nil || parsed.Version() != 7 {
+			t.Fatalf("expected a UUIDv7 id, got %q", id)
+		}
+		created = append(created, id)
+	}
+
+	paged := paginateIDs(t, handler, "sort=id", 5)
+	if strings.Join(paged, ",") != strings.Join(created, ",") {
+		t.Errorf("expected pages in creation order\n got %v\nwant %v", paged, created)
+	}
+	paged = paginateIDs(t, handler, "sort=-price", 5)
+	if len(paged) != len(created) || paged[0] != created[len(created)-1] {
+		t.Errorf("expected token cursors over uuidv7 ids to page through every record, got %v", paged)
+	}
+
+	// ULIDs are not ids of the collection
+	w := getRecord(t, handler, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_id") {
+		t.Errorf("expected 400 invalid_id for a ULID, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDataHandler_ClientIDs(t *testing.T) {
+	driver, reg, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+	setIDType(t, reg, registry.IDTypeClient)
+
+	data := map[string]any{"id": "sku-001", "name": "Widget", "price": 10}
+	if w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: data}, ""); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := getRecord(t, handler, "sku-001"); w.Code != http.StatusOK {
+		t.Errorf("expected to read sku-001 back, got %d: %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		data   map[string]any
+		status int
+		want   string
+	}{
+		{"duplicate id", map[string]any{"id": "sku-001", "name": "Copy", "price": 1}, http.StatusConflict, "unique_violation"},
+		{"missing id", map[string]any{"name": "Gadget", "price": 1}, http.StatusBadRequest, "required field 'id'"},
+		{"invalid id", map[string]any{"id": "has spaces", "name": "Gadget", "price": 1}, http.StatusBadRequest, "invalid_id"},
+		{"id too long", map[string]any{"id": strings.Repeat("a", 65), "name": "Gadget", "price": 1}, http.StatusBadRequest, "invalid_id"},
+		{"non-string id", map[string]any{"id": 7, "name": "Gadget", "price": 1}, http.StatusBadRequest, "must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: tt.data}, "")
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("expected %d with %q, got %d: %s", tt.status, tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
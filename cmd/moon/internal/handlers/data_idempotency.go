@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/idempotency"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// idempotencyContextKey carries the pending idempotent request of a :create
+type idempotencyContextKey struct{}
+
+// pendingIdempotency is a :create sent with an Idempotency-Key whose
+// response is not stored yet
+type pendingIdempotency struct {
+	store      *idempotency.Store
+	key        string
+	collection string
+	hash       string
+	saved      bool // stored within the transaction of the insert
+}
+
+// pendingIdempotencyFrom returns the pending idempotent request of ctx, or nil
+func pendingIdempotencyFrom(ctx context.Context) *pendingIdempotency {
+	pending, _ := ctx.Value(idempotencyContextKey{}).(*pendingIdempotency)
+	return pending
+}
+
+// createIdempotent runs a :create sent with an Idempotency-Key. The first
+// request of a key runs as usual and its 2xx response is stored; later ones
+// with the same body get the stored response with X-Idempotent-Replay, and
+// with another body 422 idempotency_key_reuse. Requests with one key are
+// serialized, so concurrent retries create the records once.
+func (h *DataHandler) createIdempotent(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, key string) {
+	if !idempotency.ValidKey(key) {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter,
+			fmt.Sprintf("%s must be 1 to %d printable ASCII characters", constants.HeaderIdempotencyKey, idempotency.MaxKeyLength))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	pending := &pendingIdempotency{store: h.idempotency, key: key, collection: collectionName, hash: idempotency.Hash(body)}
+
+	ctx := r.Context()
+	unlock := h.idempotency.Lock(key, collectionName)
+	defer unlock()
+	if h.replayed(w, r, pending) {
+		return
+	}
+
+	rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.create(rec, r.WithContext(context.WithValue(ctx, idempotencyContextKey{}, pending)), collectionName, collection)
+
+	// Best-effort batches store their response once it is written
+	if !pending.saved && rec.status >= 200 && rec.status < 300 {
+		resp := idempotency.Response{Hash: pending.hash, Status: rec.status, Body: rec.body.Bytes()}
+		if err := h.idempotency.Save(ctx, h.db.Exec, key, collectionName, resp); err != nil {
+			logging.Warnf("Failed to store the response for %s %q: %v", constants.HeaderIdempotencyKey, key, err)
+		}
+	}
+	if _, err := h.idempotency.PurgeExpired(ctx); err != nil {
+		logging.Warnf("%v", err)
+	}
+}
+
+// replayed writes the response stored for the pending request's key, or 422
+// when it answered another body, and reports whether it wrote anything
+func (h *DataHandler) replayed(w http.ResponseWriter, r *http.Request, pending *pendingIdempotency) bool {
+	stored, err := h.idempotency.Lookup(r.Context(), pending.key, pending.collection)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+		return true
+	}
+	if stored == nil {
+		return false
+	}
+	if stored.Hash != pending.hash {
+		writeError(w, r, http.StatusUnprocessableEntity, apperrors.CodeIdempotencyKeyReuse,
+			fmt.Sprintf("%s '%s' was used with a different request body", constants.HeaderIdempotencyKey, pending.key))
+		return true
+	}
+	w.Header().Set(constants.HeaderIdempotentReplay, "true")
+	if id := createdRecordID(stored.Body); id != "" {
+		w.Header().Set(constants.HeaderLocation, h.recordPath(r, pending.collection, id))
+	}
+	writeRaw(w, stored.Status, stored.Body)
+	return true
+}
+
+// commitIdempotent stores the response of a pending idempotent request
+// within tx and commits it, so that the records and the response are kept
+// together or not at all. It returns the response body; when it fails, the
+// error or a response stored meanwhile by another server is written and ok
+// is false.
+func (h *DataHandler) commitIdempotent(w http.ResponseWriter, r *http.Request, tx *sql.Tx, pending *pendingIdempotency, statusCode int, data any) (body []byte, ok bool) {
+	var buf bytes.Buffer
+	writeResponse(&bodyWriter{Writer: &buf, header: http.Header{}}, r, statusCode, data)
+	resp := idempotency.Response{Hash: pending.hash, Status: statusCode, Body: buf.Bytes()}
+
+	ctx := r.Context()
+	if err := pending.store.Save(ctx, tx.ExecContext, pending.key, pending.collection, resp); err != nil {
+		tx.Rollback()
+		if !h.replayed(w, r, pending) {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+		}
+		return nil, false
+	}
+	if err := tx.Commit(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
+		return nil, false
+	}
+	pending.saved = true
+	return resp.Body, true
+}
+
+// writeRaw writes a JSON response body as is
+func writeRaw(w http.ResponseWriter, statusCode int, body []byte) {
+	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// bodyWriter renders a response into a buffer
+type bodyWriter struct {
+	io.Writer
+	header http.Header
+}
+
+func (b *bodyWriter) Header() http.Header { return b.header }
+func (b *bodyWriter) WriteHeader(int)     {}
+
+// idempotencyRecorder passes a response through while recording its status
+// and body
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Flush lets best-effort batches stream their results
+func (rec *idempotencyRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// createdRecordID returns the id of the record of a stored single-create
+// response, or "" for a batch, whose data is a list without links
+func createdRecordID(body []byte) string {
+	var resp struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		Links *RecordLinks `json:"links"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Links == nil {
+		return ""
+	}
+	return resp.Data.ID
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/idempotency"
+)
+
+// setupIdempotencyTest creates a notes collection and a data handler
+// replaying creates for ttl
+func setupIdempotencyTest(t *testing.T, ttl time.Duration) *DataHandler {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false, "unique": true},
+		},
+	})
+
+	store := idempotency.New(nt.driver, ttl)
+	if err := store.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	nt.data.SetIdempotency(store)
+	return nt.data
+}
+
+// createWithKey posts a :create of body with an Idempotency-Key
+func createWithKey(handler *DataHandler, url, key string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	req.Header.Set(constants.HeaderIdempotencyKey, key)
+	w := httptest.NewRecorder()
+	handler.Create(w, req, "notes")
+	return w
+}
+
+// countNotes returns how many notes records exist
+func countNotes(t *testing.T, handler *DataHandler) int {
+	t.Helper()
+	var count int
+	if err := handler.db.QueryRow(context.Background(), "SELECT COUNT(*) FROM notes").Scan(&count); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	return count
+}
+
+func TestCreate_IdempotencyKeyReplay(t *testing.T) {
+	handler := setupIdempotencyTest(t, time.Hour)
+	body := map[string]any{"data": map[string]any{"title": "first"}}
+
+	first := createWithKey(handler, "/notes:create", "key-1", body)
+	if first.Code != http.StatusCreated || first.Header().Get(constants.HeaderIdempotentReplay) != "" {
+		t.Fatalf("expected 201 without replay header, got %d %v %s", first.Code, first.Header(), first.Body.String())
+	}
+
+	replay := createWithKey(handler, "/notes:create", "key-1", body)
+	if replay.Code != http.StatusCreated || replay.Header().Get(constants.HeaderIdempotentReplay) != "true" {
+		t.Fatalf("expected 201 replay, got %d %v %s", replay.Code, replay.Header(), replay.Body.String())
+	}
+	if !bytes.Equal(first.Body.Bytes(), replay.Body.Bytes()) {
+		t.Errorf("expected a byte-identical replay:\n%s\n%s", first.Body.String(), replay.Body.String())
+	}
+	if location := first.Header().Get(constants.HeaderLocation); location == "" || replay.Header().Get(constants.HeaderLocation) != location {
+		t.Errorf("expected the replay at Location %q, got %q", location, replay.Header().Get(constants.HeaderLocation))
+	}
+	if n := countNotes(t, handler); n != 1 {
+		t.Errorf("expected 1 record, got %d", n)
+	}
+
+	// Another body under the key is rejected
+	w := createWithKey(handler, "/notes:create", "key-1", map[string]any{"data": map[string]any{"title": "second"}})
+	if w.Code != http.StatusUnprocessableEntity || errorCode(w) != "idempotency_key_reuse" {
+		t.Errorf("expected 422 idempotency_key_reuse, got %d %s", w.Code, w.Body.String())
+	}
+
+	// A failed create is not stored, so its key can be retried
+	w = createWithKey(handler, "/notes:create", "key-2", body)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate title, got %d %s", w.Code, w.Body.String())
+	}
+	w = createWithKey(handler, "/notes:create", "key-2", map[string]any{"data": map[string]any{"title": "retried"}})
+	if w.Code != http.StatusCreated || w.Header().Get(constants.HeaderIdempotentReplay) != "" {
+		t.Errorf("expected the retry created, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = createWithKey(handler, "/notes:create", "bad\nkey", body)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid key, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreate_IdempotencyKeyBatches(t *testing.T) {
+	handler := setupIdempotencyTest(t, time.Hour)
+
+	tests := []struct {
+		url    string
+		items  []map[string]any
+		status int
+	}{
+		{"/notes:create?atomic=true", []map[string]any{{"title": "a"}, {"title": "b"}}, http.StatusCreated},
+		// Best-effort batches replay their results, failed items included
+		{"/notes:create", []map[string]any{{"title": "c"}, {"title": "a"}}, http.StatusMultiStatus},
+	}
+	for _, tt := range tests {
+		body := map[string]any{"data": tt.items}
+		first := createWithKey(handler, tt.url, tt.url, body)
+		if first.Code != tt.status {
+			t.Fatalf("%s: expected %d, got %d %s", tt.url, tt.status, first.Code, first.Body.String())
+		}
+		replay := createWithKey(handler, tt.url, tt.url, body)
+		if replay.Code != tt.status || replay.Header().Get(constants.HeaderIdempotentReplay) != "true" {
+			t.Errorf("%s: expected a %d replay, got %d %v", tt.url, tt.status, replay.Code, replay.Header())
+		}
+		if !bytes.Equal(first.Body.Bytes(), replay.Body.Bytes()) {
+			t.Errorf("%s: expected a byte-identical replay:\n%s\n%s", tt.url, first.Body.String(), replay.Body.String())
+		}
+	}
+	if n := countNotes(t, handler); n != 3 {
+		t.Errorf("expected 3 records, got %d", n)
+	}
+}
+
+func TestCreate_IdempotencyKeyConcurrent(t *testing.T) {
+	handler := setupIdempotencyTest(t, time.Hour)
+	body := map[string]any{"data": map[string]any{"title": "once"}}
+
+	const requests = 8
+	codes := make([]int, requests)
+	replays := make([]bool, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := createWithKey(handler, "/notes:create", "concurrent", body)
+			codes[i] = w.Code
+			replays[i] = w.Header().Get(constants.HeaderIdempotentReplay) == "true"
+		}()
+	}
+	wg.Wait()
+
+	replayed := 0
+	for i := range requests {
+		if codes[i] != http.StatusCreated {
+			t.Errorf("request %d: expected 201, got %d", i, codes[i])
+		}
+		if replays[i] {
+			replayed++
+		}
+	}
+	if replayed != requests-1 {
+		t.Errorf("expected %d replays, got %d", requests-1, replayed)
+	}
+	if n := countNotes(t, handler); n != 1 {
+		t.Errorf("expected exactly 1 insert, got %d", n)
+	}
+}
+
+func TestCreate_IdempotencyKeyExpired(t *testing.T) {
+	handler := setupIdempotencyTest(t, 50*time.Millisecond)
+
+	createWithKey(handler, "/notes:create", "expiring", map[string]any{"data": map[string]any{"title": "old"}})
+	time.Sleep(100 * time.Millisecond)
+
+	w := createWithKey(handler, "/notes:create", "expiring", map[string]any{"data": map[string]any{"title": "new"}})
+	if w.Code != http.StatusCreated || w.Header().Get(constants.HeaderIdempotentReplay) != "" {
+		t.Errorf("expected an expired key to create again, got %d %s", w.Code, w.Body.String())
+	}
+	if n := countNotes(t, handler); n != 2 {
+		t.Errorf("expected 2 records, got %d", n)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// TestKeywordColumnNames_CRUD runs the full record lifecycle on SQLite against
+// columns named after SQL keywords that pass column name validation
+func TestKeywordColumnNames_CRUD(t *testing.T) {
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer driver.Close()
+
+	reg := registry.NewSchemaRegistry()
+	collections := NewCollectionsHandler(driver, reg)
+	handler := NewDataHandler(driver, reg, testConfig())
+
+	do := func(action func(http.ResponseWriter, *http.Request, string), method, url string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		w := httptest.NewRecorder()
+		action(w, httptest.NewRequest(method, url, bytes.NewReader(payload)), "entries")
+		return w
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"name": "entries",
+		"columns": []map[string]any{
+			{"name": "escape", "type": "string", "nullable": false},
+			{"name": "returning", "type": "integer", "nullable": true},
+		},
+		"indexes": []map[string]any{{"name": "idx_entries_escape", "columns": []string{"escape"}}},
+	})
+	w := httptest.NewRecorder()
+	collections.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+	}
+
+	ids := make([]string, 0, 2)
+	for _, data := range []map[string]any{{"escape": "alpha", "returning": 1}, {"escape": "beta", "returning": 2}} {
+		w := do(handler.Create, http.MethodPost, "/entries:create", map[string]any{"data": data})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+		var resp CreateDataResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		ids = append(ids, resp.Data["id"].(string))
+	}
+
+	if w := do(handler.Get, http.MethodGet, "/entries:get?id="+ids[0], nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"escape":"alpha"`) {
+		t.Errorf("Get failed: %d %s", w.Code, w.Body.String())
+	}
+
+	list := func(query string) DataListResponse {
+		t.Helper()
+		w := do(handler.List, http.MethodGet, "/entries:list?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("List %q failed: %d %s", query, w.Code, w.Body.String())
+		}
+		var resp DataListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+	if resp := list("sort=-returning"); len(resp.Data) != 2 || resp.Data[0]["escape"] != "beta" {
+		t.Errorf("expected sort on returning to put beta first, got %v", resp.Data)
+	}
+	if resp := list("returning[gt]=1"); len(resp.Data) != 1 || resp.Data[0]["escape"] != "beta" {
+		t.Errorf("expected filter on returning to match beta, got %v", resp.Data)
+	}
+	if resp := list("q=alp"); len(resp.Data) != 1 || resp.Data[0]["escape"] != "alpha" {
+		t.Errorf("expected search on escape to match alpha, got %v", resp.Data)
+	}
+	if resp := list("fields=escape&limit=1"); len(resp.Data) != 1 || resp.NextCursor == nil {
+		t.Errorf("expected a cursor from a field-limited page, got %+v", resp)
+	} else if next := list("limit=1&after=" + *resp.NextCursor); len(next.Data) != 1 {
+		t.Errorf("expected the second page to follow the cursor, got %v", next.Data)
+	}
+
+	w = do(handler.Update, http.MethodPost, "/entries:update", map[string]any{"data": map[string]any{"id": ids[0], "escape": "gamma", "returning": nil}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(handler.Get, http.MethodGet, "/entries:get?id="+ids[0], nil); !strings.Contains(w.Body.String(), `"escape":"gamma"`) {
+		t.Errorf("expected updated record, got %s", w.Body.String())
+	}
+
+	if w := do(handler.Destroy, http.MethodPost, "/entries:destroy", map[string]any{"data": ids[0]}); w.Code != http.StatusOK {
+		t.Fatalf("Destroy failed: %d %s", w.Code, w.Body.String())
+	}
+	if resp := list(""); len(resp.Data) != 1 {
+		t.Errorf("expected one record after destroy, got %v", resp.Data)
+	}
+}
+
+func TestGeneratedSQL_QuotesKeywordColumns(t *testing.T) {
+	columns := []registry.Column{{Name: "escape", Type: registry.TypeString}}
+	if ddl := generateCreateTableDDL("entries", columns, registry.IDTypeULID, database.DialectPostgres); !strings.Contains(ddl, `CREATE TABLE "entries"`) || !strings.Contains(ddl, `"escape" TEXT`) {
+		t.Errorf("expected quoted identifiers in DDL, got %s", ddl)
+	}
+
+	collection := &registry.Collection{Name: "entries", Columns: columns}
+	insert, _ := buildInsertQuery("entries", collection, map[string]any{"escape": "x"}, generateULID(), "now", database.DialectPostgres)
+	if !strings.HasPrefix(insert, `INSERT INTO "entries" (`) || !strings.Contains(insert, `, "escape") VALUES (`) {
+		t.Errorf("unexpected insert: %s", insert)
+	}
+
+	clauses, _ := buildUpdateSetClauses(map[string]any{"escape": "y"}, collection, database.DialectMySQL)
+	if len(clauses) == 0 || clauses[0] != "`escape` = ?" {
+		t.Errorf("unexpected update clauses: %v", clauses)
+	}
+}
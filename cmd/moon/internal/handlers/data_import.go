@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// Import file formats supported by POST /{name}:import
+const (
+	importFormatCSV  = "csv"
+	importFormatJSON = "json"
+)
+
+// ImportRowError describes a row that was skipped during import
+type ImportRowError struct {
+	Row   int    `json:"row"`            // 1-based data row (CSV) or array element (JSON)
+	Line  int    `json:"line,omitempty"` // line in the uploaded file (CSV only)
+	Error string `json:"error"`
+}
+
+// ImportDataResponse summarizes an import operation
+type ImportDataResponse struct {
+	Imported        int              `json:"imported"`
+	Skipped         int              `json:"skipped"`
+	Errors          []ImportRowError `json:"errors"`
+	ErrorsTruncated bool             `json:"errors_truncated"`
+	DryRun          bool             `json:"dry_run"`
+	JobID           string           `json:"job_id,omitempty"` // the journal of an import sent with journal=true or resume
+	Message         string           `json:"message"`
+}
+
+// importRecord is a parsed row ready for validation and insert
+type importRecord struct {
+	row  int
+	line int
+	data map[string]any
+}
+
+// importRowError reports a malformed row that can be skipped without stopping the import
+type importRowError struct {
+	ImportRowError
+}
+
+func (e *importRowError) Error() string {
+	return e.ImportRowError.Error
+}
+
+// importReader yields records one at a time from an uploaded file.
+// Next returns io.EOF at the end of input, an *importRowError for a row that
+// can be skipped, and any other error when the input cannot be read further.
+type importReader interface {
+	Next() (importRecord, error)
+}
+
+// Import handles POST /{name}:import
+// A multipart upload (field "file") containing CSV with a header row or a JSON
+// array of objects is streamed and inserted in transactions of
+// batch.import_chunk_size records. Invalid rows are skipped and reported.
+// With journal=true, or resume=<job id>, the import is journaled in moon_jobs.
+func (h *DataHandler) Import(w http.ResponseWriter, r *http.Request, collectionName string) {
+	// Validate collection exists in registry
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	maxBytes := int64(h.config.Batch.MaxImportBytes)
+	if r.ContentLength > maxBytes {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, fmt.Sprintf("payload size %d exceeds limit of %d bytes", r.ContentLength, maxBytes))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	clearStreamingDeadlines(w)
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != importFormatCSV && format != importFormatJSON {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, fmt.Sprintf("invalid format '%s': must be csv or json", format))
+		return
+	}
+	dryRun, err := QueryBoolean(r, "dry_run", false)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	journal, err := QueryBoolean(r, "journal", false)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	resumeID := r.URL.Query().Get("resume")
+	journaled := journal || resumeID != ""
+	if journaled && h.jobs == nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "import journaling is not available")
+		return
+	}
+	if journaled && dryRun {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "journal and resume cannot be combined with dry_run")
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "request must be multipart/form-data with a file field")
+		return
+	}
+
+	var file io.Reader
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, fmt.Sprintf("invalid multipart body: %v", err))
+			return
+		}
+		if part.FormName() == "file" {
+			file = part
+			if format == "" {
+				format = detectImportFormat(part.FileName(), part.Header.Get("Content-Type"))
+			}
+			break
+		}
+	}
+	if file == nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "missing file field")
+		return
+	}
+
+	if journaled {
+		h.importJournaled(w, r, collectionName, collection, format, file, resumeID)
+		return
+	}
+
+	src, err := newImportReader(format, file, collection)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
+		return
+	}
+
+	resp, _ := h.runImport(r.Context(), collectionName, collection, src, dryRun, nil)
+	if dryRun {
+		audit.Discard(r.Context())
+	}
+
+	status := http.StatusOK
+	if resp.Skipped > 0 {
+		status = http.StatusMultiStatus
+	}
+	writeResponse(w, r, status, resp)
+}
+
+// runImport validates every record from src and inserts valid ones chunk by chunk.
+// With a job, the rows the job already processed are passed over and its
+// progress is written in the transaction of each chunk; a chunk that fails
+// to insert then stops the import with its error instead of being skipped.
+func (h *DataHandler) runImport(ctx context.Context, collectionName string, collection *registry.Collection, src importReader, dryRun bool, job *jobs.Job) (ImportDataResponse, error) {
+	resp := ImportDataResponse{Errors: []ImportRowError{}, DryRun: dryRun}
+	done, baseSucceeded, baseFailed := 0, 0, 0
+	if job != nil {
+		done, baseSucceeded, baseFailed = job.Processed, job.Succeeded, job.Failed
+	}
+	skip := func(rowErr ImportRowError, count int) {
+		resp.Skipped += count
+		if len(resp.Errors) < constants.MaxImportErrors {
+			resp.Errors = append(resp.Errors, rowErr)
+		} else {
+			resp.ErrorsTruncated = true
+		}
+	}
+
+	chunkSize := h.config.Batch.ImportChunkSize
+	chunk := make([]importRecord, 0, chunkSize)
+	row := 0
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		defer func() { chunk = chunk[:0] }()
+		if chunk = h.skipInvalidReferences(ctx, collection, chunk, skip); len(chunk) == 0 {
+			return nil
+		}
+		if dryRun {
+			resp.Imported += len(chunk)
+			return nil
+		}
+
+		var next jobs.Job
+		var progress func(jobs.ExecFunc) error
+		if job != nil {
+			next = *job
+			last := chunk[len(chunk)-1].row
+			next.Processed, next.LastIndex = row, &last
+			next.Succeeded, next.Failed = baseSucceeded+resp.Imported+len(chunk), baseFailed+resp.Skipped
+			progress = func(exec jobs.ExecFunc) error { return h.jobs.Progress(ctx, exec, &next) }
+		}
+		failed, err := h.insertImportChunk(ctx, collectionName, collection, chunk, progress)
+		switch {
+		case err != nil && job != nil:
+			return fmt.Errorf("row %d: %w", failed.row, err)
+		case err != nil:
+			skip(ImportRowError{
+				Row:   failed.row,
+				Line:  failed.line,
+				Error: fmt.Sprintf("%v; %d rows in this chunk were rolled back", err, len(chunk)),
+			}, len(chunk))
+		default:
+			resp.Imported += len(chunk)
+			if job != nil {
+				*job = next
+			}
+		}
+		return nil
+	}
+
+	for {
+		rec, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *importRowError
+		if errors.As(err, &rowErr) {
+			if rowErr.Row > done {
+				skip(rowErr.ImportRowError, 1)
+			}
+			row = rowErr.Row
+			continue
+		}
+		if err != nil {
+			// The rest of the input is unreadable; keep what was parsed so far
+			skip(ImportRowError{Row: row + 1, Error: fmt.Sprintf("failed to read input: %v", err)}, 0)
+			break
+		}
+		row = rec.row
+		if row <= done {
+			continue // written or skipped before the job was resumed
+		}
+
+		if err := h.validateCreateFields(rec.data, collection); err != nil {
+			skip(ImportRowError{Row: rec.row, Line: rec.line, Error: err.Error()}, 1)
+			continue
+		}
+
+		chunk = append(chunk, rec)
+		if len(chunk) >= chunkSize {
+			if err := flush(); err != nil {
+				return resp, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return resp, err
+	}
+	if job != nil {
+		job.Processed, job.Failed = max(row, done), baseFailed+resp.Skipped
+	}
+
+	if dryRun {
+		resp.Message = fmt.Sprintf("Dry run: %d rows would be imported, %d skipped", resp.Imported, resp.Skipped)
+	} else {
+		resp.Message = fmt.Sprintf("Imported %d rows, %d skipped", resp.Imported, resp.Skipped)
+	}
+	return resp, nil
+}
+
+// skipInvalidReferences checks the references of a chunk with one lookup per
+// referenced collection and returns the records that passed; the others are
+// skipped. The whole chunk is skipped when a lookup fails.
+func (h *DataHandler) skipInvalidReferences(ctx context.Context, collection *registry.Collection, chunk []importRecord, skip func(ImportRowError, int)) []importRecord {
+	items := make([]map[string]any, len(chunk))
+	for i, rec := range chunk {
+		items[i] = rec.data
+	}
+	failed, err := referenceErrors(ctx, h.db.Query, h.registry, collection, items, h.db.Dialect())
+	if err != nil {
+		skip(ImportRowError{
+			Row:   chunk[0].row,
+			Line:  chunk[0].line,
+			Error: fmt.Sprintf("failed to check references: %v; %d rows in this chunk were skipped", err, len(chunk)),
+		}, len(chunk))
+		return chunk[:0]
+	}
+
+	valid := chunk[:0]
+	for i, rec := range chunk {
+		if err := failed[i]; err != nil {
+			skip(ImportRowError{Row: rec.row, Line: rec.line, Error: err.Error()}, 1)
+			continue
+		}
+		valid = append(valid, rec)
+	}
+	return valid
+}
+
+// insertImportChunk inserts a chunk of records in one transaction, in which
+// progress, when set, also runs. On failure the chunk is rolled back and the
+// record that failed is returned.
+func (h *DataHandler) insertImportChunk(ctx context.Context, collectionName string, collection *registry.Collection, chunk []importRecord, progress func(jobs.ExecFunc) error) (importRecord, error) {
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		return chunk[0], fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := currentTimestamp()
+	items := make([]map[string]any, len(chunk))
+	for i, rec := range chunk {
+		items[i] = rec.data
+	}
+	ids := newRecordIDs(collection, items)
+	for i, rec := range chunk {
+		query, values := buildInsertQuery(collectionName, collection, rec.data, ids[i], now, h.db.Dialect())
+		if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+			return rec, fmt.Errorf("failed to insert data: %w", err)
+		}
+	}
+	if progress != nil {
+		if err := progress(tx.ExecContext); err != nil {
+			return chunk[len(chunk)-1], err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return chunk[len(chunk)-1], fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	audit.AddRecords(ctx, ids...)
+	return importRecord{}, nil
+}
+
+// clearStreamingDeadlines lifts the server read/write timeouts for a request
+// that streams a large body; the size limits bound the work instead.
+// Writers that do not support deadlines are left unchanged.
+func clearStreamingDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
+
+// detectImportFormat infers the upload format from the file name or part content type
+func detectImportFormat(fileName, contentType string) string {
+	if strings.EqualFold(filepath.Ext(fileName), ".json") || strings.HasPrefix(contentType, "application/json") {
+		return importFormatJSON
+	}
+	return importFormatCSV
+}
+
+// csvImportReader reads records from CSV with a header row
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns []*registry.Column // per header position; nil for ignored system columns
+	row     int
+}
+
+// newImportReader returns the reader of an upload in the given format
+func newImportReader(format string, file io.Reader, collection *registry.Collection) (importReader, error) {
+	if format == importFormatJSON {
+		return newJSONImportReader(file, collection)
+	}
+	return newCSVImportReader(file, collection)
+}
+
+// newCSVImportReader reads and validates the header row against the collection schema.
+// System columns (id, timestamps) are ignored so :export output can be re-imported,
+// except the id of collections with client-supplied ids.
+func newCSVImportReader(file io.Reader, collection *registry.Collection) (*csvImportReader, error) {
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+
+	collectionColumns := make(map[string]*registry.Column, len(collection.Columns))
+	for i := range collection.Columns {
+		collectionColumns[collection.Columns[i].Name] = &collection.Columns[i]
+	}
+
+	columns := make([]*registry.Column, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // UTF-8 byte order mark
+		}
+		name = snakeCase(name)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column '%s' in CSV header", name)
+		}
+		seen[name] = true
+
+		if name == "id" && collection.RecordIDType() == registry.IDTypeClient {
+			columns[i] = &registry.Column{Name: name, Type: registry.TypeString}
+			continue
+		}
+		if systemColumns[name] {
+			continue
+		}
+		col, ok := collectionColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%s' in CSV header", name)
+		}
+		columns[i] = col
+	}
+
+	for _, col := range collection.Columns {
+		if !col.Nullable && !seen[col.Name] {
+			return nil, fmt.Errorf("required column '%s' is missing from CSV header", col.Name)
+		}
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+// Next converts the next CSV row using the column types. Empty cells are
+// omitted so the column default applies.
+func (c *csvImportReader) Next() (importRecord, error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return importRecord{}, io.EOF
+	}
+	c.row++
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return importRecord{}, &importRowError{ImportRowError{Row: c.row, Line: parseErr.StartLine, Error: parseErr.Err.Error()}}
+	}
+	if err != nil {
+		return importRecord{}, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	data := make(map[string]any, len(record))
+	for i, cell := range record {
+		col := c.columns[i]
+		if col == nil || cell == "" {
+			continue
+		}
+		value, err := convertValue(cell, col.Type)
+		if err != nil {
+			return importRecord{}, &importRowError{ImportRowError{Row: c.row, Line: line, Error: fmt.Sprintf("invalid value for column %s: %v", col.Name, err)}}
+		}
+		data[col.Name] = value
+	}
+
+	return importRecord{row: c.row, line: line, data: data}, nil
+}
+
+// jsonImportReader reads objects from a JSON array one element at a time
+type jsonImportReader struct {
+	decoder  *json.Decoder
+	row      int
+	clientID bool // keep the id of collections with client-supplied ids
+}
+
+// newJSONImportReader consumes the opening bracket of the JSON array
+func newJSONImportReader(file io.Reader, collection *registry.Collection) (*jsonImportReader, error) {
+	decoder := json.NewDecoder(file)
+	tok, err := decoder.Token()
+	if err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("JSON import must be an array of objects")
+	}
+	return &jsonImportReader{decoder: decoder, clientID: collection.RecordIDType() == registry.IDTypeClient}, nil
+}
+
+// Next decodes the next array element. System fields are dropped so :export
+// output can be re-imported; client-supplied ids are kept.
+func (j *jsonImportReader) Next() (importRecord, error) {
+	if !j.decoder.More() {
+		if _, err := j.decoder.Token(); err != nil {
+			return importRecord{}, err
+		}
+		return importRecord{}, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := j.decoder.Decode(&raw); err != nil {
+		return importRecord{}, err
+	}
+	j.row++
+
+	var data map[string]any
+	if err := json.Unmarshal(raw, &data); err != nil || data == nil {
+		return importRecord{}, &importRowError{ImportRowError{Row: j.row, Error: "array element must be an object"}}
+	}
+	if err := normalizeFieldNames(data); err != nil {
+		return importRecord{}, &importRowError{ImportRowError{Row: j.row, Error: err.Error()}}
+	}
+	for name := range data {
+		if systemColumns[name] && !(name == "id" && j.clientID) {
+			delete(data, name)
+		}
+	}
+
+	return importRecord{row: j.row, data: data}, nil
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// importJournaled runs an import journaled as a job, or resumes the job
+// resumeID with the same upload. The upload is spooled to a temporary file
+// first: the job records its checksum and row count before the first row is
+// written.
+func (h *DataHandler) importJournaled(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, format string, file io.Reader, resumeID string) {
+	ctx := r.Context()
+	spool, checksum, err := spoolImport(file)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, fmt.Sprintf("payload exceeds limit of %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, fmt.Sprintf("failed to read upload: %v", err))
+		return
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+
+	total, err := countImportRows(format, spool, collection)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
+		return
+	}
+
+	job := &jobs.Job{Operation: jobs.OperationImport, Collection: collectionName, Total: total, Checksum: checksum}
+	if resumeID != "" {
+		if job, err = h.resumeImport(ctx, collectionName, resumeID, checksum); err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+	} else if err := h.jobs.Start(ctx, job); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+		return
+	}
+	w.Header().Set(constants.HeaderJob, job.ID)
+
+	// The job is ended even when the client went away
+	finish := func(status string, cause error) {
+		if err := h.jobs.Finish(context.WithoutCancel(ctx), job, status, cause); err != nil {
+			log.Printf("WARNING: Failed to end job %s: %v", job.ID, err)
+		}
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		finish(jobs.StatusFailed, err)
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to read upload: %v", err))
+		return
+	}
+	src, err := newImportReader(format, spool, collection)
+	if err != nil {
+		finish(jobs.StatusFailed, err)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
+		return
+	}
+
+	resp, err := h.runImport(ctx, collectionName, collection, src, false, job)
+	if err != nil {
+		finish(jobs.StatusFailed, err)
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError,
+			fmt.Sprintf("import stopped at %v; %d rows are done, resume with resume=%s", err, job.Processed, job.ID))
+		return
+	}
+	finish(jobs.StatusCompleted, nil)
+
+	resp.JobID = job.ID
+	status := http.StatusOK
+	if resp.Skipped > 0 {
+		status = http.StatusMultiStatus
+	}
+	writeResponse(w, r, status, resp)
+}
+
+// resumeImport returns the job of an earlier import of the collection, marked
+// running again, after checking that the upload is the one it journaled
+func (h *DataHandler) resumeImport(ctx context.Context, collectionName, id, checksum string) (*jobs.Job, error) {
+	job, err := h.jobs.Get(ctx, id)
+	if errors.Is(err, jobs.ErrNotFound) {
+		return nil, apperrors.Newf(http.StatusNotFound, apperrors.CodeJobNotFound, "job '%s' not found", id)
+	}
+	if err != nil {
+		return nil, apperrors.NewAPIError(http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+	}
+	if job.Operation != jobs.OperationImport || job.Collection != collectionName {
+		return nil, apperrors.Newf(http.StatusConflict, apperrors.CodeJobNotResumable, "job '%s' is not an import of this collection", id)
+	}
+	if job.Checksum != checksum {
+		return nil, apperrors.Newf(http.StatusUnprocessableEntity, apperrors.CodeChecksumMismatch, "the upload does not match the one job '%s' imported", id)
+	}
+	if err := h.jobs.Resume(ctx, job); errors.Is(err, jobs.ErrNotResumable) {
+		return nil, apperrors.Newf(http.StatusConflict, apperrors.CodeJobNotResumable, "job '%s' is %s; only failed and interrupted jobs can be resumed", id, job.Status)
+	} else if err != nil {
+		return nil, apperrors.NewAPIError(http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+	}
+	return job, nil
+}
+
+// spoolImport copies an upload to a temporary file and returns the file and
+// the checksum of the upload
+func spoolImport(file io.Reader) (*os.File, string, error) {
+	spool, err := os.CreateTemp("", "moon-import-*")
+	if err != nil {
+		return nil, "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(spool, hash), file); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, "", err
+	}
+	return spool, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// countImportRows reads a spooled upload through and returns its number of
+// rows, which is the row number of the last one
+func countImportRows(format string, spool io.ReadSeeker, collection *registry.Collection) (int, error) {
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	src, err := newImportReader(format, spool, collection)
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	for {
+		rec, err := src.Next()
+		var rowErr *importRowError
+		switch {
+		case err == io.EOF:
+			return rows, nil
+		case errors.As(err, &rowErr):
+			rows = rowErr.Row
+		case err != nil:
+			return rows, nil // the import reports the unreadable rest
+		default:
+			rows = rec.row
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupJournalTest creates an empty notes collection on a recording SQLite
+// file database, with imports journaled in chunks of two rows
+func setupJournalTest(t *testing.T) (*DataHandler, *jobs.Store, *statementRecorder) {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "moon.db")
+	real, err := database.NewDriver(database.Config{ConnectionString: "sqlite://" + path, MaxOpenConns: 1, ConnMaxLifetime: time.Minute * 5})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := real.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { real.Close() })
+
+	recorder := &statementRecorder{}
+	sql.Register("sqlite_recording_"+t.Name(), recordingSQLDriver{Driver: real.DB().Driver(), recorder: recorder})
+	db, err := sql.Open("sqlite_recording_"+t.Name(), path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatalf("Failed to open recording database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	recording := &recordingDriver{Driver: real, db: db}
+
+	if _, err := recording.Exec(ctx, `CREATE TABLE notes (id TEXT PRIMARY KEY, created_at TEXT, updated_at TEXT, _rev INTEGER NOT NULL DEFAULT 1, title TEXT NOT NULL)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	store := jobs.New(recording)
+	if err := store.Init(ctx); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{Name: "notes", Columns: []registry.Column{{Name: "title", Type: registry.TypeString}}})
+	cfg := testConfig()
+	cfg.Batch.ImportChunkSize = 2
+	handler := NewDataHandler(recording, reg, cfg)
+	handler.SetJobs(store)
+	return handler, store, recorder
+}
+
+func TestImport_JournalResume(t *testing.T) {
+	handler, store, recorder := setupJournalTest(t)
+	ctx := context.Background()
+	csvData := "title\nA\nB\nC\nD\nE\n"
+
+	// Statements: the job, then per chunk two inserts and the progress. The
+	// sixth, the second insert of the second chunk, fails: the first chunk
+	// stays written and the second is rolled back.
+	recorder.reset(6)
+	w := doImport(t, handler, "notes", "/notes:import?journal=true", "notes.csv", csvData)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected the import to stop with 500, got %d: %s", w.Code, w.Body.String())
+	}
+	jobID := w.Header().Get(constants.HeaderJob)
+	if n := countRows(t, handler.db, "notes"); n != 2 {
+		t.Fatalf("expected the first chunk to be written, got %d rows", n)
+	}
+	job, err := store.Get(ctx, jobID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if job.Status != jobs.StatusFailed || job.Total != 5 || job.Processed != 2 || job.Succeeded != 2 || job.LastIndex == nil || *job.LastIndex != 2 || job.Error == "" {
+		t.Fatalf("unexpected failed job: %+v", job)
+	}
+
+	// Resuming with another upload is refused
+	recorder.reset(0)
+	w = doImport(t, handler, "notes", "/notes:import?resume="+jobID, "notes.csv", "title\nA\nB\nX\n")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a different upload, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Resuming with the same upload writes the remaining rows once
+	w = doImport(t, handler, "notes", "/notes:import?resume="+jobID, "notes.csv", csvData)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the resume, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeImport(t, w); resp.Imported != 3 || resp.JobID != jobID {
+		t.Errorf("expected the resume to import 3 rows under the same job, got %+v", resp)
+	}
+	var titles, distinct int
+	handler.db.QueryRow(ctx, "SELECT COUNT(*), COUNT(DISTINCT title) FROM notes").Scan(&titles, &distinct)
+	if titles != 5 || distinct != 5 {
+		t.Errorf("expected each row exactly once, got %d rows with %d titles", titles, distinct)
+	}
+	job, _ = store.Get(ctx, jobID)
+	if job.Status != jobs.StatusCompleted || job.Processed != 5 || job.Succeeded != 5 || *job.LastIndex != 5 {
+		t.Errorf("unexpected completed job: %+v", job)
+	}
+
+	// A completed job cannot be resumed again
+	w = doImport(t, handler, "notes", "/notes:import?resume="+jobID, "notes.csv", csvData)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a completed job, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doImport(t, handler, "notes", "/notes:import?resume=01ARZ3NDEKTSV4RRFFQ69G5FAV", "notes.csv", csvData)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := countRows(t, handler.db, "notes"); n != 5 {
+		t.Errorf("expected refused resumes to write nothing, got %d rows", n)
+	}
+}
+
+func TestImport_JournalInterrupted(t *testing.T) {
+	handler, store, _ := setupJournalTest(t)
+	ctx := context.Background()
+	csvData := "title\nA\nB\nC\n"
+
+	// A server stopping mid-import leaves the job running with the progress
+	// of its last chunk; the next start marks it interrupted
+	job := &jobs.Job{Operation: jobs.OperationImport, Collection: "notes", Total: 3, Checksum: jobs.Checksum([]byte(csvData))}
+	if err := store.Start(ctx, job); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := handler.db.Exec(ctx, "INSERT INTO notes (id, title) VALUES ('n1', 'A')"); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+	job.Processed, job.Succeeded = 1, 1
+	if err := store.Progress(ctx, nil, job); err != nil {
+		t.Fatalf("Progress() error = %v", err)
+	}
+
+	w := doImport(t, handler, "notes", "/notes:import?resume="+job.ID, "notes.csv", csvData)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a running job, got %d: %s", w.Code, w.Body.String())
+	}
+	if n, err := store.Interrupt(ctx); err != nil || n != 1 {
+		t.Fatalf("Interrupt() = %d, %v", n, err)
+	}
+
+	w = doImport(t, handler, "notes", "/notes:import?resume="+job.ID, "notes.csv", csvData)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the resume, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := countRows(t, handler.db, "notes"); n != 3 {
+		t.Errorf("expected 3 rows after the resume, got %d", n)
+	}
+}
+
+func TestCreateBatch_Journal(t *testing.T) {
+	handler, store, _ := setupJournalTest(t)
+
+	body := `{"data": [{"title": "A"}, {"title": null}, {"title": "C"}]}`
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/notes:create?journal=true", bytes.NewBufferString(body)), "notes")
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	job, err := store.Get(context.Background(), w.Header().Get(constants.HeaderJob))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if job.Operation != jobs.OperationCreate || job.Status != jobs.StatusCompleted || job.Total != 3 || job.Processed != 3 ||
+		job.Succeeded != 2 || job.Failed != 1 || *job.LastIndex != 2 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	// Atomic batches are all-or-nothing and not journaled
+	w = httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/notes:create?journal=true&atomic=true", bytes.NewBufferString(body)), "notes")
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a journaled atomic batch, got %d: %v", w.Code, resp)
+	}
+}
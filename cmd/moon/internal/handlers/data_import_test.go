@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// doImport uploads content as the multipart "file" field of an :import request
+func doImport(t *testing.T, handler *DataHandler, collectionName, url, fileName, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write([]byte(content))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	handler.Import(w, req, collectionName)
+	return w
+}
+
+func decodeImport(t *testing.T, w *httptest.ResponseRecorder) ImportDataResponse {
+	t.Helper()
+	var resp ImportDataResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v: %s", err, w.Body.String())
+	}
+	return resp
+}
+
+func countProducts(t *testing.T, handler *DataHandler) int {
+	t.Helper()
+	var count int
+	if err := handler.db.QueryRow(context.Background(), "SELECT COUNT(*) FROM products").Scan(&count); err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	return count
+}
+
+func TestDataHandler_Import_CSV_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	csvData := "name,price,category,active\n" +
+		"Lamp,30,home,true\n" +
+		"\"Desk, oak\",120,,false\n" +
+		"Chair,45,home,1\n"
+
+	w := doImport(t, handler, "products", "/products:import", "products.csv", csvData)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeImport(t, w)
+	if resp.Imported != 3 || resp.Skipped != 0 || len(resp.Errors) != 0 {
+		t.Errorf("unexpected summary: %+v", resp)
+	}
+
+	var category *string
+	var active bool
+	var createdAt string
+	driver.QueryRow(context.Background(), "SELECT category, active, created_at FROM products WHERE name = ?", "Desk, oak").Scan(&category, &active, &createdAt)
+	if category != nil {
+		t.Errorf("expected empty cell to leave category NULL, got %q", *category)
+	}
+	if active {
+		t.Error("expected active to be false")
+	}
+	if createdAt == "" {
+		t.Error("expected created_at to be set on imported rows")
+	}
+}
+
+func TestDataHandler_Import_RowErrors(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	csvData := "name,price\n" +
+		"Lamp,30\n" +
+		"Desk,cheap\n" +
+		"Chair,\n" +
+		"Stool,10,extra\n" +
+		"Shelf,80\n"
+
+	w := doImport(t, handler, "products", "/products:import", "products.csv", csvData)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeImport(t, w)
+	if resp.Imported != 2 || resp.Skipped != 3 {
+		t.Errorf("unexpected summary: %+v", resp)
+	}
+	wantLines := []int{3, 4, 5}
+	if len(resp.Errors) != len(wantLines) {
+		t.Fatalf("expected %d errors, got %+v", len(wantLines), resp.Errors)
+	}
+	for i, line := range wantLines {
+		if resp.Errors[i].Line != line || resp.Errors[i].Row != line-1 {
+			t.Errorf("error %d: expected line %d row %d, got %+v", i, line, line-1, resp.Errors[i])
+		}
+	}
+	if countProducts(t, handler) != 2 {
+		t.Errorf("expected 2 rows in table, got %d", countProducts(t, handler))
+	}
+}
+
+func TestDataHandler_Import_HeaderValidation(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	tests := []struct {
+		name    string
+		content string
+		errPart string
+	}{
+		{"unknown column", "name,price,color\nLamp,30,red\n", "unknown column 'color'"},
+		{"missing required column", "name,category\nLamp,home\n", "required column 'price'"},
+		{"duplicate column", "name,price,name\nLamp,30,Lamp\n", "duplicate column 'name'"},
+		{"empty file", "", "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doImport(t, handler, "products", "/products:import", "products.csv", tt.content)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.errPart) {
+				t.Errorf("expected error containing %q, got %s", tt.errPart, w.Body.String())
+			}
+		})
+	}
+
+	if countProducts(t, handler) != 0 {
+		t.Error("expected no rows to be written for rejected imports")
+	}
+}
+
+func TestDataHandler_Import_DryRun(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	w := doImport(t, handler, "products", "/products:import?dry_run=true", "products.csv", "name,price\nLamp,30\nDesk,oops\n")
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeImport(t, w)
+	if !resp.DryRun || resp.Imported != 1 || resp.Skipped != 1 {
+		t.Errorf("unexpected summary: %+v", resp)
+	}
+	if countProducts(t, handler) != 0 {
+		t.Error("dry run must not write any rows")
+	}
+}
+
+func TestDataHandler_Import_JSON(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	jsonData := `[
+		{"name": "Lamp", "price": 30, "active": true},
+		{"name": "Desk"},
+		"not an object",
+		{"id": "01ARYZ6S41TSV4RRFFQ69G5FA1", "name": "Chair", "price": 45}
+	]`
+
+	w := doImport(t, handler, "products", "/products:import", "products.json", jsonData)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeImport(t, w)
+	if resp.Imported != 2 || resp.Skipped != 2 {
+		t.Errorf("unexpected summary: %+v", resp)
+	}
+	if len(resp.Errors) != 2 || resp.Errors[0].Row != 2 || resp.Errors[1].Row != 3 {
+		t.Errorf("unexpected errors: %+v", resp.Errors)
+	}
+
+	// Client ids are replaced with server-generated ULIDs
+	var count int
+	driver.QueryRow(context.Background(), "SELECT COUNT(*) FROM products WHERE id = ?", "01ARYZ6S41TSV4RRFFQ69G5FA1").Scan(&count)
+	if count != 0 {
+		t.Error("expected imported id to be ignored")
+	}
+
+	w = doImport(t, handler, "products", "/products:import?format=json", "data.txt", `{"name": "Lamp"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for non-array JSON, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDataHandler_Import_ChunkRollback_Integration(t *testing.T) {
+	driver, handler := setupUpsertIntegrationTest(t)
+	defer driver.Close()
+	handler.config.Batch.ImportChunkSize = 2
+
+	// The duplicate sku fails the second chunk, which is rolled back as a whole
+	csvData := "sku,name\nA-1,Anvil\nB-2,Bolt\nA-1,Again\nC-3,Clamp\nD-4,Drill\n"
+	w := doImport(t, handler, "items", "/items:import", "items.csv", csvData)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeImport(t, w)
+	if resp.Imported != 3 || resp.Skipped != 2 {
+		t.Errorf("unexpected summary: %+v", resp)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Row != 3 || resp.Errors[0].Line != 4 {
+		t.Errorf("unexpected errors: %+v", resp.Errors)
+	}
+
+	var count int
+	driver.QueryRow(context.Background(), "SELECT COUNT(*) FROM items WHERE sku IN ('C-3', 'Again')").Scan(&count)
+	if count != 0 {
+		t.Errorf("expected rolled back chunk rows to be absent, found %d", count)
+	}
+}
+
+func TestDataHandler_Import_ExportRoundTrip(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	doImport(t, handler, "products", "/products:import", "products.csv", "name,price,active\nLamp,30,true\nDesk,120,false\n")
+
+	// Exports include system columns, which import ignores
+	exported := doExport(t, handler, "/products:export")
+	w := doImport(t, handler, "products", "/products:import", "products.csv", exported.Body.String())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if countProducts(t, handler) != 4 {
+		t.Errorf("expected 4 rows after re-import, got %d", countProducts(t, handler))
+	}
+}
+
+func TestDataHandler_Import_RequestValidation(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	w := httptest.NewRecorder()
+	handler.Import(w, httptest.NewRequest(http.MethodPost, "/products:import", strings.NewReader(`[{"name":"x"}]`)), "products")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for non-multipart body, got %d", w.Code)
+	}
+
+	w = doImport(t, handler, "products", "/products:import?format=xml", "products.csv", "name,price\n")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid format, got %d", w.Code)
+	}
+
+	handler.config.Batch.MaxImportBytes = 64
+	w = doImport(t, handler, "products", "/products:import", "products.csv", "name,price\n"+strings.Repeat("Lamp,30\n", 20))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for oversized upload, got %d", w.Code)
+	}
+
+	w = doImport(t, handler, "missing", "/missing:import", "missing.csv", "name\n")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown collection, got %d", w.Code)
+	}
+}
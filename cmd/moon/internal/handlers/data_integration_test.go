@@ -35,6 +35,9 @@ func setupDataIntegrationTest(t *testing.T) (database.Driver, *registry.SchemaRe
 	// Create table
 	_, err = driver.Exec(ctx, `CREATE TABLE products (
 		id TEXT PRIMARY KEY,
+		created_at TEXT,
+		updated_at TEXT,
+		_rev INTEGER NOT NULL DEFAULT 1,
 		name TEXT NOT NULL,
 		price INTEGER NOT NULL,
 		category TEXT,
@@ -580,8 +583,8 @@ func TestDataHandler_List_TotalField(t *testing.T) {
 			t.Fatalf("Failed to decode response: %v", err)
 		}
 
-		if resp.Total != 5 {
-			t.Errorf("Expected total 5, got %d", resp.Total)
+		if *resp.Total != 5 {
+			t.Errorf("Expected total 5, got %d", *resp.Total)
 		}
 
 		if len(resp.Data) != 5 {
@@ -605,8 +608,8 @@ func TestDataHandler_List_TotalField(t *testing.T) {
 		}
 
 		// 3 electronics products
-		if resp.Total != 3 {
-			t.Errorf("Expected total 3 for electronics, got %d", resp.Total)
+		if *resp.Total != 3 {
+			t.Errorf("Expected total 3 for electronics, got %d", *resp.Total)
 		}
 
 		if len(resp.Data) != 3 {
@@ -630,8 +633,8 @@ func TestDataHandler_List_TotalField(t *testing.T) {
 		}
 
 		// Total should be 5 (full count), even though we only fetched 2
-		if resp.Total != 5 {
-			t.Errorf("Expected total 5 (full count), got %d", resp.Total)
+		if *resp.Total != 5 {
+			t.Errorf("Expected total 5 (full count), got %d", *resp.Total)
 		}
 
 		if len(resp.Data) != 2 {
@@ -676,8 +679,8 @@ func TestDataHandler_List_TotalField(t *testing.T) {
 			t.Fatalf("Failed to decode response: %v", err)
 		}
 
-		if resp.Total != 0 {
-			t.Errorf("Expected total 0 for empty collection, got %d", resp.Total)
+		if *resp.Total != 0 {
+			t.Errorf("Expected total 0 for empty collection, got %d", *resp.Total)
 		}
 
 		if len(resp.Data) != 0 {
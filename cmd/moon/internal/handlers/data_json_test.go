@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func newJSONTestHandler(t *testing.T) *DataHandler {
+	t.Helper()
+	driver := createTestDB(t)
+	t.Cleanup(func() { driver.Close() })
+
+	_, err := driver.Exec(context.Background(), `CREATE TABLE products (
+		id TEXT PRIMARY KEY,
+		created_at TEXT,
+		updated_at TEXT,
+		_rev INTEGER NOT NULL DEFAULT 1,
+		name TEXT NOT NULL,
+		meta TEXT
+	)`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "name", Type: registry.TypeString},
+			{Name: "meta", Type: registry.TypeJSON, Nullable: true},
+		},
+	})
+	return NewDataHandler(driver, reg, testConfig())
+}
+
+func TestDataHandler_JSONColumns(t *testing.T) {
+	handler := newJSONTestHandler(t)
+
+	records := []map[string]any{
+		{"name": "red shirt", "meta": map[string]any{"color": "red", "size": map[string]any{"label": "M"}, "tags": []any{"cotton"}}},
+		{"name": "blue shirt", "meta": map[string]any{"size": map[string]any{"label": "L"}, "color": "blue"}},
+		{"name": "dark red hat", "meta": `{"color": "dark red"}`},
+		{"name": "plain", "meta": nil},
+	}
+	for _, data := range records {
+		if w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: data}, ""); w.Code != http.StatusCreated {
+			t.Fatalf("failed to create %v: %s", data["name"], w.Body.String())
+		}
+	}
+
+	t.Run("objects are stored as canonical JSON", func(t *testing.T) {
+		var meta string
+		row := handler.db.QueryRow(context.Background(), "SELECT meta FROM products WHERE name = ?", "blue shirt")
+		if err := row.Scan(&meta); err != nil {
+			t.Fatalf("failed to read meta: %v", err)
+		}
+		if meta != `{"color":"blue","size":{"label":"L"}}` {
+			t.Errorf("unexpected stored JSON: %s", meta)
+		}
+	})
+
+	t.Run("update serializes objects", func(t *testing.T) {
+		resp := listPage(t, handler, "name[eq]=plain")
+		body := UpdateDataRequest{ID: resp.Data[0]["id"].(string), Data: map[string]any{"meta": map[string]any{"color": "green"}}}
+		if w := postData(t, handler.Update, "/products:update", body, ""); w.Code != http.StatusOK {
+			t.Fatalf("failed to update: %s", w.Body.String())
+		}
+		if resp := listPage(t, handler, "meta.color[eq]=green"); len(resp.Data) != 1 {
+			t.Errorf("expected the updated record to match, got %d", len(resp.Data))
+		}
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"meta.color[eq]=red", []string{"red shirt"}},
+		{"meta.color[ne]=red&sort=name", []string{"blue shirt", "dark red hat", "plain"}},
+		{"meta.color[like]=red&sort=name", []string{"dark red hat", "red shirt"}},
+		{"meta.size.label[eq]=L", []string{"blue shirt"}},
+		{"meta.missing[eq]=x", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := listPage(t, handler, tt.query)
+			if len(resp.Data) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, resp.Data)
+			}
+			for i, name := range tt.want {
+				if resp.Data[i]["name"] != name {
+					t.Errorf("record %d: expected %s, got %v", i, name, resp.Data[i]["name"])
+				}
+			}
+		})
+	}
+}
+
+func TestDataHandler_JSONColumns_Validation(t *testing.T) {
+	handler := newJSONTestHandler(t)
+
+	w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: map[string]any{"name": "bad", "meta": "{not json"}}, "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected invalid JSON string to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, query := range []string{
+		"name.first[eq]=x",
+		"meta.color[gt]=a",
+		"meta.color[in]=a,b",
+		"meta.co'lor[eq]=x",
+		"meta..color[eq]=x",
+		"missing.color[eq]=x",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/products:list?"+query, nil)
+		w := httptest.NewRecorder()
+		handler.List(w, req, "products")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestDataHandler_JSONFieldPaths(t *testing.T) {
+	handler := newJSONTestHandler(t)
+	records := []map[string]any{
+		{"name": "a", "meta": map[string]any{"color": "red", "size": map[string]any{"width": 10, "height": 20, "unit": map[string]any{"name": "cm"}}, "tags": []any{"cotton", map[string]any{"name": "x"}}}},
+		{"name": "b", "meta": map[string]any{"color": "blue"}},
+		{"name": "c", "meta": `[1, 2]`},
+		{"name": "d", "meta": nil},
+	}
+	for _, data := range records {
+		if w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: data}, ""); w.Code != http.StatusCreated {
+			t.Fatalf("failed to create %v: %s", data["name"], w.Body.String())
+		}
+	}
+
+	tests := []struct {
+		fields string
+		want   []any // meta of a, b, c and d
+	}{
+		{"name,meta.color,meta.size.width", []any{`{"color":"red","size":{"width":10}}`, `{"color":"blue"}`, `{}`, nil}},
+		{"meta.size.unit.name", []any{`{"size":{"unit":{"name":"cm"}}}`, `{}`, `{}`, nil}},
+		{"meta.missing,meta.color.deeper", []any{`{}`, `{}`, `{}`, nil}},
+		// Overlapping paths: the outer one wins, in either order
+		{"meta.size.width,meta.size", []any{`{"size":{"height":20,"unit":{"name":"cm"},"width":10}}`, `{}`, `{}`, nil}},
+		{"meta.size,meta.size.width", []any{`{"size":{"height":20,"unit":{"name":"cm"},"width":10}}`, `{}`, `{}`, nil}},
+		{"meta.color,meta", []any{`{"color":"red","size":{"height":20,"unit":{"name":"cm"},"width":10},"tags":["cotton",{"name":"x"}]}`, `{"color":"blue"}`, `[1, 2]`, nil}},
+		// Arrays are returned whole; keys below them are absent
+		{"meta.tags", []any{`{"tags":["cotton",{"name":"x"}]}`, `{}`, `{}`, nil}},
+		{"meta.tags.name", []any{`{}`, `{}`, `{}`, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fields, func(t *testing.T) {
+			resp := listPage(t, handler, "sort=name&fields="+tt.fields)
+			if len(resp.Data) != 4 {
+				t.Fatalf("expected 4 records, got %d", len(resp.Data))
+			}
+			for i, record := range resp.Data {
+				if record["meta"] != tt.want[i] {
+					t.Errorf("record %s: expected meta %v, got %v", record["name"], tt.want[i], record["meta"])
+				}
+				if record["id"] == nil {
+					t.Errorf("record %d: expected the id to be included", i)
+				}
+			}
+		})
+	}
+
+	// Only the json column is selected, whole
+	req := httptest.NewRequest(http.MethodGet, "/products:explain?fields=meta.color,meta.size.width", nil)
+	w := httptest.NewRecorder()
+	handler.Explain(w, req, "products")
+	var explain ExplainResponse
+	json.Unmarshal(w.Body.Bytes(), &explain)
+	if w.Code != http.StatusOK || !strings.Contains(explain.SQL, `SELECT "id", "meta" FROM`) {
+		t.Errorf("expected the page query to select id and meta, got %d %s", w.Code, explain.SQL)
+	}
+
+	// A sort on the projected column pages on its full value
+	first := listPage(t, handler, "sort=meta&fields=meta.color&limit=2")
+	if first.NextCursor == nil {
+		t.Fatalf("expected a next cursor, got %+v", first)
+	}
+	next := listPage(t, handler, "sort=meta&fields=meta.color&limit=2&after="+*first.NextCursor)
+	if len(next.Data) != 2 || next.Data[0]["id"] == first.Data[1]["id"] {
+		t.Errorf("expected the second page to continue after the first, got %+v", next.Data)
+	}
+}
+
+func TestDataHandler_JSONFieldPaths_Validation(t *testing.T) {
+	handler := newJSONTestHandler(t)
+	many := make([]string, constants.MaxFieldPaths+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("meta.k%d", i)
+	}
+
+	for fields, message := range map[string]string{
+		"name.first":                "not a json column",
+		"missing.color":             "invalid field",
+		"meta.tags.0":               "array indexes are not supported",
+		"meta.co-lor":               "letters, numbers and underscores",
+		"meta..color":               "letters, numbers and underscores",
+		"meta.":                     "letters, numbers and underscores",
+		"meta.a.b.c.d.e":            "at most 4 keys",
+		strings.Join(many, ","):     "maximum number of json paths",
+		"created_at.year":           "not a json column",
+		"meta.a.b.c.d,name,meta.ok": "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/products:list?fields="+fields, nil)
+		w := httptest.NewRecorder()
+		handler.List(w, req, "products")
+		if message == "" {
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d: %s", fields, w.Code, w.Body.String())
+			}
+			continue
+		}
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), message) {
+			t.Errorf("%s: expected 400 with %q, got %d: %s", fields, message, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+// setupLinksTest creates a notes collection with a required title, served
+// under prefix and publicURL
+func setupLinksTest(t *testing.T, prefix, publicURL string) *DataHandler {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{{"name": "title", "type": "string", "nullable": false}},
+	})
+	nt.data.config.Server.Prefix = prefix
+	nt.data.config.Server.PublicURL = publicURL
+	return nt.data
+}
+
+func TestCreate_LocationAndLinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		publicURL string
+		location  string // pattern of the Location header
+		base      string // base of the links
+	}{
+		{"no prefix", "", "", `^/notes:get\?id=[0-9A-Z]{26}$`, "http://example.com"},
+		{"prefix", "/api/v1", "", `^/api/v1/notes:get\?id=[0-9A-Z]{26}$`, "http://example.com/api/v1"},
+		{"public url", "/api/v1", "https://moon.example.org", `^/api/v1/notes:get\?id=[0-9A-Z]{26}$`, "https://moon.example.org/api/v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupLinksTest(t, tt.prefix, tt.publicURL)
+
+			w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "first"}})
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+			}
+			location := w.Header().Get(constants.HeaderLocation)
+			if !regexp.MustCompile(tt.location).MatchString(location) {
+				t.Errorf("expected Location matching %s, got %q", tt.location, location)
+			}
+			var created CreateDataResponse
+			json.Unmarshal(w.Body.Bytes(), &created)
+			id := created.Data["id"].(string)
+			want := RecordLinks{Self: tt.base + "/notes:get?id=" + id, Collection: tt.base + "/notes:list"}
+			if created.Links == nil || *created.Links != want {
+				t.Errorf("expected links %+v, got %+v", want, created.Links)
+			}
+			if location != tt.prefix+"/notes:get?id="+id {
+				t.Errorf("expected Location of record %s, got %q", id, location)
+			}
+
+			// Get and update link to the same record
+			w = doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+id, nil)
+			var got DataGetResponse
+			json.Unmarshal(w.Body.Bytes(), &got)
+			if got.Links == nil || got.Links.Self != want.Self {
+				t.Errorf("expected get links.self %s, got %+v", want.Self, got.Links)
+			}
+			w = doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"data": map[string]any{"id": id, "title": "second"}})
+			var updated UpdateDataResponse
+			json.Unmarshal(w.Body.Bytes(), &updated)
+			if updated.Links == nil || updated.Links.Self != want.Self {
+				t.Errorf("expected update links.self %s, got %+v", want.Self, updated.Links)
+			}
+		})
+	}
+}
+
+func TestCreate_BatchItemLinks(t *testing.T) {
+	handler := setupLinksTest(t, "/api", "")
+
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": []map[string]any{
+		{"title": "first"},
+		{"colour": "red"},
+		{"title": "third"},
+	}})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d %s", w.Code, w.Body.String())
+	}
+	if location := w.Header().Get(constants.HeaderLocation); location != "" {
+		t.Errorf("expected no Location for a batch, got %q", location)
+	}
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	for _, idx := range []int{0, 2} {
+		result := resp.Results[idx]
+		if want := "http://example.com/api/notes:get?id=" + result.ID; result.Link != want {
+			t.Errorf("item %d: expected link %s, got %q", idx, want, result.Link)
+		}
+	}
+	if failed := resp.Results[1]; failed.Status != BatchItemFailed || failed.Link != "" {
+		t.Errorf("expected the failed item without a link, got %+v", failed)
+	}
+}
+
+func TestGet_LinksForwardedHost(t *testing.T) {
+	handler := setupLinksTest(t, "/api", "")
+	handler.config.Server.TrustedProxies = []string{"10.0.0.1"}
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "first"}})
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	id := created.Data["id"].(string)
+
+	get := func(peer string) string {
+		r := httptest.NewRequest(http.MethodGet, "/notes:get?id="+id, nil)
+		r.RemoteAddr = peer
+		r.Header.Set("X-Forwarded-Host", "evil.example.net")
+		r.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		handler.Get(w, r, "notes")
+		var got DataGetResponse
+		json.Unmarshal(w.Body.Bytes(), &got)
+		return got.Links.Self
+	}
+	if self := get("203.0.113.9:4000"); self != "http://example.com/api/notes:get?id="+id {
+		t.Errorf("expected an untrusted peer's X-Forwarded-Host to be ignored, got %s", self)
+	}
+	if self := get("10.0.0.1:4000"); self != "https://evil.example.net/api/notes:get?id="+id {
+		t.Errorf("expected a trusted proxy's X-Forwarded-Host to be honoured, got %s", self)
+	}
+}
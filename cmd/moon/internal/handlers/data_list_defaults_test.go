@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// setupListDefaultsTest creates a notes collection whose list defaults sort by
+// descending rank and select only the title
+func setupListDefaultsTest(t *testing.T) (*CollectionsHandler, *DataHandler) {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "rank", "type": "integer", "nullable": true},
+		},
+		"default_sort":   []string{"-rank"},
+		"default_fields": []string{"title"},
+	},
+		map[string]any{"title": "low", "rank": 1},
+		map[string]any{"title": "high", "rank": 3},
+		map[string]any{"title": "mid", "rank": 2},
+	)
+	return nt.collections, nt.data
+}
+
+func listTitles(t *testing.T, handler *DataHandler, url string) ([]string, DataListResponse) {
+	t.Helper()
+	w := doDataAction(t, handler.List, http.MethodGet, url, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("List %s failed: %d %s", url, w.Code, w.Body.String())
+	}
+	var resp DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	titles := make([]string, len(resp.Data))
+	for i, record := range resp.Data {
+		titles[i], _ = record["title"].(string)
+	}
+	return titles, resp
+}
+
+func TestList_CollectionDefaults(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+
+	titles, resp := listTitles(t, handler, "/notes:list")
+	if strings.Join(titles, ",") != "high,mid,low" {
+		t.Errorf("expected the default sort to apply, got %v", titles)
+	}
+	if _, ok := resp.Data[0]["rank"]; ok {
+		t.Errorf("expected the default fields to apply, got %v", resp.Data[0])
+	}
+
+	// Explicit parameters win; empty ones opt out of the defaults
+	if titles, _ := listTitles(t, handler, "/notes:list?sort=title"); strings.Join(titles, ",") != "high,low,mid" {
+		t.Errorf("expected the sort parameter to win, got %v", titles)
+	}
+	_, resp = listTitles(t, handler, "/notes:list?sort=&fields=")
+	ids := make([]string, len(resp.Data))
+	for i, record := range resp.Data {
+		ids[i], _ = record["id"].(string)
+	}
+	if !slices.IsSorted(ids) {
+		t.Errorf("expected an empty sort to fall back to id ordering, got %v", ids)
+	}
+	if _, ok := resp.Data[0]["rank"]; !ok {
+		t.Errorf("expected an empty fields parameter to select every field, got %v", resp.Data[0])
+	}
+
+	// The defaults survive pagination
+	_, page := listTitles(t, handler, "/notes:list?limit=2")
+	if page.NextCursor == nil {
+		t.Fatal("expected a next cursor")
+	}
+	if titles, _ := listTitles(t, handler, "/notes:list?limit=2&after="+*page.NextCursor); strings.Join(titles, ",") != "low" {
+		t.Errorf("expected the second page to follow the default sort, got %v", titles)
+	}
+}
+
+func TestSchema_ExposesListDefaults(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+
+	w := doDataAction(t, handler.Schema, http.MethodGet, "/notes:schema", nil)
+	var resp SchemaResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.DefaultSort) != 1 || resp.DefaultSort[0] != "-rank" || len(resp.DefaultFields) != 1 || resp.DefaultFields[0] != "title" {
+		t.Errorf("expected list defaults in the schema, got %s", w.Body.String())
+	}
+}
+
+func TestCollections_ListDefaultsValidation(t *testing.T) {
+	collections, _ := setupListDefaultsTest(t)
+
+	tests := []struct {
+		name        string
+		body        map[string]any
+		wantStatus  int
+		errContains string
+	}{
+		{"remove default sort column", map[string]any{"name": "notes", "remove_columns": []string{"rank"}}, http.StatusBadRequest, "column 'rank' is used by default_sort"},
+		{"remove default field", map[string]any{"name": "notes", "default_sort": []string{}, "remove_columns": []string{"title"}}, http.StatusBadRequest, "column 'title' is used by default_fields"},
+		{"new default names removed column", map[string]any{"name": "notes", "default_sort": []string{"title"}, "default_fields": []string{"rank"}, "remove_columns": []string{"rank"}}, http.StatusBadRequest, "used by default_fields"},
+		{"unknown sort column", map[string]any{"name": "notes", "default_sort": []string{"-missing"}}, http.StatusBadRequest, "invalid sort column: missing"},
+		{"malformed sort entry", map[string]any{"name": "notes", "default_sort": []string{"title,rank"}}, http.StatusBadRequest, "invalid entry"},
+		{"unknown field", map[string]any{"name": "notes", "default_fields": []string{"missing"}}, http.StatusBadRequest, "invalid field: missing"},
+		{"default on added column", map[string]any{"name": "notes", "add_columns": []map[string]any{{"name": "score", "type": "integer", "nullable": true}}, "default_sort": []string{"-score", "created_at"}}, http.StatusOK, ""},
+		{"rename keeps defaults", map[string]any{"name": "notes", "rename_columns": []map[string]any{{"old_name": "title", "new_name": "heading"}}}, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postCollections(collections.Update, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.errContains != "" && !strings.Contains(w.Body.String(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %s", tt.errContains, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	collections.Get(w, httptest.NewRequest(http.MethodGet, "/collections:get?name=notes", nil))
+	if !strings.Contains(w.Body.String(), `"default_fields":["heading"]`) {
+		t.Errorf("expected the rename to carry over to default_fields, got %s", w.Body.String())
+	}
+
+	var resp UpdateResponse
+	w = postCollections(collections.Update, map[string]any{"name": "notes", "default_fields": []string{}})
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if got := resp.Collection; got == nil || strings.Join(got.DefaultSort, ",") != "-score,created_at" || got.DefaultFields != nil {
+		t.Errorf("expected the renamed collection to keep its sort and clear its fields, got %s", w.Body.String())
+	}
+
+	if w := postCollections(collections.Create, map[string]any{
+		"name":         "drafts",
+		"columns":      []map[string]any{{"name": "title", "type": "string"}},
+		"default_sort": []string{"-updated"},
+	}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected create with an unknown default sort column to fail, got %d", w.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// Formats :list can respond in, chosen by the Accept header
+const (
+	ListFormatJSON   = "json"
+	ListFormatCSV    = "csv"
+	ListFormatNDJSON = "ndjson"
+)
+
+// listMediaTypes maps the media types of an Accept header to list formats
+var listMediaTypes = map[string]string{
+	"text/csv":             ListFormatCSV,
+	"application/x-ndjson": ListFormatNDJSON,
+	"application/json":     ListFormatJSON,
+}
+
+// NegotiateListFormat returns the format of a :list response requested by the
+// Accept header. The first media type naming a list format counts; quality
+// values are not weighed. Without one, and for unknown or versioned media
+// types, the response is JSON as before, so no Accept header is refused.
+func NegotiateListFormat(r *http.Request) string {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			if format, ok := listMediaTypes[mediaType]; ok {
+				return format
+			}
+		}
+	}
+	return ListFormatJSON
+}
+
+// listColumns returns the fields of the records of a list in the order they
+// were selected: masked columns, the pkid column of collections without
+// expose_sequence and the sort columns selected only for the cursor are left
+// out, and an exposed pkid is named seq as in scanRow
+func listColumns(dbColumns []string, collection *registry.Collection, masked map[string]bool, omitted []string) []string {
+	columns := make([]string, 0, len(dbColumns))
+	for _, col := range dbColumns {
+		if masked[col] {
+			continue
+		}
+		if col == constants.PKIDColumn {
+			if !collection.ExposeSequence {
+				continue
+			}
+			col = constants.SequenceField
+		}
+		if !slices.Contains(omitted, col) {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// writeListRows writes a page of records as CSV with a header row of columns,
+// or as one JSON object per line without an envelope. The pagination of the
+// JSON envelope moves to headers: X-Total when the records were counted and
+// X-Next-Cursor when there is a next page.
+func writeListRows(w http.ResponseWriter, r *http.Request, format string, columns []string, data []map[string]any, total *int, nextCursor *string) {
+	if camelFields(r.Context()) {
+		cased := make([]string, len(columns))
+		for i, col := range columns {
+			cased[i] = camelCase(col)
+		}
+		columns, data = cased, camelRecords(data)
+	}
+	if total != nil {
+		w.Header().Set(constants.HeaderTotal, strconv.Itoa(*total))
+	}
+	if nextCursor != nil {
+		w.Header().Set(constants.HeaderNextCursor, *nextCursor)
+	}
+
+	// Once the header is written errors can only be logged; the client sees a truncated body
+	logger := logging.WithContext(r.Context())
+	if format == ListFormatCSV {
+		w.Header().Set(constants.HeaderContentType, constants.MIMETextCSV)
+		w.WriteHeader(http.StatusOK)
+
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			logger.Errorf("List failed writing the CSV header: %v", err)
+			return
+		}
+		record := make([]string, len(columns))
+		for _, row := range data {
+			for i, col := range columns {
+				record[i] = exportCSVValue(row[col])
+			}
+			if err := cw.Write(record); err != nil {
+				logger.Errorf("List failed writing a CSV row: %v", err)
+				return
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			logger.Errorf("List failed flushing CSV: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationNDJSON)
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for _, row := range data {
+		if err := enc.Encode(row); err != nil {
+			logger.Errorf("List failed writing an NDJSON row: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// setupListFormatTest creates a notes collection with a column of each
+// scalar type and five records
+func setupListFormatTest(t *testing.T) *DataHandler {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "stock", "type": "integer", "nullable": false},
+			{"name": "price", "type": "decimal", "nullable": false},
+			{"name": "done", "type": "boolean", "nullable": false},
+			{"name": "note", "type": "string", "nullable": true},
+		},
+	},
+		map[string]any{"title": "apple", "stock": 5, "price": "2.50", "done": true, "note": `say "hi", then leave`},
+		map[string]any{"title": "banana", "stock": 3, "price": "10.00", "done": false},
+		map[string]any{"title": "cherry", "stock": 8, "price": "0.75", "done": false, "note": "line one\nline two"},
+		map[string]any{"title": "date", "stock": 1, "price": "4.20", "done": true},
+		map[string]any{"title": "elder", "stock": 9, "price": "7.00", "done": false, "note": "plain"},
+	)
+	return nt.data
+}
+
+// listAs lists notes with the given Accept header
+func listAs(handler *DataHandler, url, accept string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	handler.List(w, r, "notes")
+	return w
+}
+
+func TestList_Formats(t *testing.T) {
+	handler := setupListFormatTest(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		wantFields []string // CSV header; nil for every column
+	}{
+		{"all columns", "/notes:list?total=true", nil},
+		{"filter sort and fields", "/notes:list?done[eq]=false&sort=-stock&fields=title,note,price&total=true", []string{"id", "title", "note", "price"}},
+		{"first page", "/notes:list?sort=title&fields=title,stock&limit=2&total=true", []string{"id", "title", "stock"}},
+		{"numbered page", "/notes:list?sort=title&fields=title&page=2&per_page=2", []string{"id", "title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := listAs(handler, tt.url, "application/json")
+			if w.Code != http.StatusOK {
+				t.Fatalf("JSON list failed: %d %s", w.Code, w.Body.String())
+			}
+			var resp DataListResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+
+			// CSV: one row per record with the same cells, after a header row
+			w = listAs(handler, tt.url, "text/csv")
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+				t.Fatalf("CSV list failed: %d %v %s", w.Code, w.Header(), w.Body.String())
+			}
+			checkPaginationHeaders(t, w, resp)
+			rows, err := csv.NewReader(w.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("invalid CSV: %v", err)
+			}
+			header := rows[0]
+			if tt.wantFields != nil && !slices.Equal(header, tt.wantFields) {
+				t.Errorf("expected CSV header %v, got %v", tt.wantFields, header)
+			}
+			if len(rows)-1 != len(resp.Data) {
+				t.Fatalf("expected %d CSV rows, got %d", len(resp.Data), len(rows)-1)
+			}
+			for i, record := range resp.Data {
+				if len(header) != len(record) {
+					t.Errorf("row %d: expected the header to name the %d fields, got %v", i, len(record), header)
+				}
+				for j, col := range header {
+					if want := exportCSVValue(record[col]); rows[i+1][j] != want {
+						t.Errorf("row %d %s: expected %q, got %q", i, col, want, rows[i+1][j])
+					}
+				}
+			}
+
+			// NDJSON: one object per line, equal to the records of the envelope
+			w = listAs(handler, tt.url, "application/x-ndjson")
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+				t.Fatalf("NDJSON list failed: %d %v %s", w.Code, w.Header(), w.Body.String())
+			}
+			checkPaginationHeaders(t, w, resp)
+			var lines []map[string]any
+			scanner := bufio.NewScanner(w.Body)
+			for scanner.Scan() {
+				var record map[string]any
+				if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+					t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+				}
+				lines = append(lines, record)
+			}
+			want, _ := json.Marshal(resp.Data)
+			got, _ := json.Marshal(lines)
+			if len(lines) != len(resp.Data) || string(got) != string(want) {
+				t.Errorf("expected NDJSON records %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+// checkPaginationHeaders compares the pagination headers of a CSV or NDJSON
+// list with the JSON envelope
+func checkPaginationHeaders(t *testing.T, w *httptest.ResponseRecorder, resp DataListResponse) {
+	t.Helper()
+	wantTotal := ""
+	if resp.Total != nil {
+		wantTotal = strconv.Itoa(*resp.Total)
+	}
+	if got := w.Header().Get("X-Total"); got != wantTotal {
+		t.Errorf("expected X-Total %q, got %q", wantTotal, got)
+	}
+	wantCursor := ""
+	if resp.NextCursor != nil {
+		wantCursor = *resp.NextCursor
+	}
+	if got := w.Header().Get("X-Next-Cursor"); got != wantCursor {
+		t.Errorf("expected X-Next-Cursor %q, got %q", wantCursor, got)
+	}
+}
+
+func TestList_FormatCursorPages(t *testing.T) {
+	handler := setupListFormatTest(t)
+
+	// Following X-Next-Cursor walks every record once
+	var titles []string
+	url := "/notes:list?sort=-price&fields=title&limit=2"
+	for pages := 0; url != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("expected the pages to end")
+		}
+		w := listAs(handler, url, "text/csv")
+		rows, _ := csv.NewReader(w.Body).ReadAll()
+		for _, row := range rows[1:] {
+			titles = append(titles, row[1])
+		}
+		url = ""
+		if cursor := w.Header().Get("X-Next-Cursor"); cursor != "" {
+			url = "/notes:list?sort=-price&fields=title&limit=2&after=" + cursor
+		}
+	}
+	if want := []string{"banana", "elder", "date", "apple", "cherry"}; !slices.Equal(titles, want) {
+		t.Errorf("expected %v, got %v", want, titles)
+	}
+}
+
+func TestList_FormatNegotiation(t *testing.T) {
+	handler := setupListFormatTest(t)
+
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", "application/json"},
+		{"application/json", "application/json"},
+		{"*/*", "application/json"},
+		{"text/html,application/xhtml+xml", "application/json"},
+		{"application/vnd.moon.v2+json", "application/json"},
+		{"text/csv;q=0.9, application/json", "text/csv; charset=utf-8"},
+		{"application/vnd.moon.v2+json, application/x-ndjson", "application/x-ndjson"},
+	}
+	for _, tt := range tests {
+		w := listAs(handler, "/notes:list", tt.accept)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("Accept %q: expected 200 with %s, got %d %s", tt.accept, tt.contentType, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+
+	// Errors stay JSON whatever the format
+	w := listAs(handler, "/notes:list?sort=missing", "text/csv")
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected a JSON error, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// setupPageTest creates the notes collection of setupListDefaultsTest with
+// ten records ranked 1 to 10
+func setupPageTest(t *testing.T) *DataHandler {
+	t.Helper()
+	_, handler := setupListDefaultsTest(t)
+	for rank := 4; rank <= 10; rank++ {
+		w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": fmt.Sprintf("r%d", rank), "rank": rank}})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+	return handler
+}
+
+func pageRanks(t *testing.T, handler *DataHandler, query string) ([]int, DataListResponse) {
+	t.Helper()
+	_, resp := listTitles(t, handler, "/notes:list?sort=rank&fields=rank&"+query)
+	ranks := make([]int, len(resp.Data))
+	for i, record := range resp.Data {
+		rank, _ := record["rank"].(float64)
+		ranks[i] = int(rank)
+	}
+	return ranks, resp
+}
+
+func TestList_PageNumbers(t *testing.T) {
+	handler := setupPageTest(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantRanks []int
+		wantPage  int
+		wantPages int
+		wantLimit int
+	}{
+		{"first page", "page=1&per_page=5", []int{1, 2, 3, 4, 5}, 1, 2, 5},
+		{"exactly divisible last page", "page=2&per_page=5", []int{6, 7, 8, 9, 10}, 2, 2, 5},
+		{"partial last page", "page=4&per_page=3", []int{10}, 4, 4, 3},
+		{"page past the end", "page=5&per_page=3", []int{}, 5, 4, 3},
+		{"far past the end", "page=100&per_page=10", []int{}, 100, 1, 10},
+		{"single page", "page=1&per_page=10", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 1, 1, 10},
+		{"page size from limit", "page=2&limit=4", []int{5, 6, 7, 8}, 2, 3, 4},
+		{"page defaults to 1", "per_page=4", []int{1, 2, 3, 4}, 1, 3, 4},
+		{"total=false still counts", "page=3&per_page=4&total=false", []int{9, 10}, 3, 3, 4},
+		{"filters apply before paging", "rank[gt]=5&page=2&per_page=2", []int{8, 9}, 2, 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranks, resp := pageRanks(t, handler, tt.query)
+			if !slices.Equal(ranks, tt.wantRanks) {
+				t.Errorf("expected ranks %v, got %v", tt.wantRanks, ranks)
+			}
+			if resp.Page == nil || *resp.Page != tt.wantPage || resp.TotalPages == nil || *resp.TotalPages != tt.wantPages {
+				t.Errorf("expected page %d of %d, got %v of %v", tt.wantPage, tt.wantPages, resp.Page, resp.TotalPages)
+			}
+			if resp.Limit != tt.wantLimit || resp.Total == nil {
+				t.Errorf("expected limit %d with a total, got %d %v", tt.wantLimit, resp.Limit, resp.Total)
+			}
+			if resp.NextCursor != nil {
+				t.Errorf("expected no cursor with page numbers, got %s", *resp.NextCursor)
+			}
+		})
+	}
+}
+
+func TestList_PageNumbers_EmptyCollection(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	if w := doDataAction(t, handler.Destroy, http.MethodPost, "/notes:destroy", map[string]any{"where": map[string]any{"rank": map[string]any{"gte": 1}}}); w.Code != http.StatusOK {
+		t.Fatalf("Destroy failed: %d %s", w.Code, w.Body.String())
+	}
+
+	ranks, resp := pageRanks(t, handler, "page=1&per_page=5")
+	if len(ranks) != 0 || resp.TotalPages == nil || *resp.TotalPages != 0 || *resp.Total != 0 {
+		t.Errorf("expected no records on zero pages, got %v of %v", ranks, resp.TotalPages)
+	}
+}
+
+func TestList_CursorHasNoPageNumbers(t *testing.T) {
+	handler := setupPageTest(t)
+	_, resp := pageRanks(t, handler, "limit=5")
+	if resp.Page != nil || resp.TotalPages != nil || resp.NextCursor == nil {
+		t.Errorf("expected a cursor without page numbers, got %+v", resp)
+	}
+}
+
+func TestList_PageNumbersInvalid(t *testing.T) {
+	handler := setupPageTest(t)
+	handler.config.API.MaxPageOffset = 6
+
+	// offset 6 is the deepest allowed page
+	if ranks, _ := pageRanks(t, handler, "page=3&per_page=3"); !slices.Equal(ranks, []int{7, 8, 9}) {
+		t.Errorf("expected the page at the offset cap, got %v", ranks)
+	}
+
+	for _, query := range []string{
+		"page=2&after=01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		"page=0",
+		"page=-1",
+		"page=two",
+		"page=1&per_page=0",
+		"page=1&per_page=201",
+		"page=1&per_page=ten",
+		"page=4&per_page=3",
+		"page=9223372036854775807&per_page=200",
+	} {
+		w := doDataAction(t, handler.List, http.MethodGet, "/notes:list?"+query, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestTotalPages(t *testing.T) {
+	tests := []struct{ total, limit, want int }{
+		{0, 10, 0},
+		{1, 10, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{20, 10, 2},
+		{21, 10, 3},
+	}
+	for _, tt := range tests {
+		if got := totalPages(tt.total, tt.limit); got != tt.want {
+			t.Errorf("totalPages(%d, %d) = %d, want %d", tt.total, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestBuildListSelect_Offset(t *testing.T) {
+	sql, args := buildListSelect("notes", nil, " WHERE rank > $1", []any{5}, "rank ASC", 26, 50, database.DialectPostgres)
+	if want := `SELECT * FROM "notes" WHERE rank > $1 ORDER BY rank ASC LIMIT $2 OFFSET $3`; sql != want {
+		t.Errorf("expected %q, got %q", want, sql)
+	}
+	if len(args) != 3 || args[1] != 26 || args[2] != 50 {
+		t.Errorf("expected limit and offset args after the filter, got %v", args)
+	}
+
+	if sql, _ := buildListSelect("notes", nil, "", nil, "", 26, 0, database.DialectSQLite); sql != `SELECT * FROM "notes" LIMIT ?` {
+		t.Errorf("expected no OFFSET for the first page, got %q", sql)
+	}
+}
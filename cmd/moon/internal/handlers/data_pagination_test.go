@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupPaginationTest creates a notes collection with pagination and 12
+// records titled e01 to e12
+func setupPaginationTest(t *testing.T, pagination map[string]any) (*CollectionsHandler, *DataHandler) {
+	t.Helper()
+	schema := map[string]any{
+		"columns": []map[string]any{{"name": "title", "type": "string", "nullable": false}},
+	}
+	if pagination != nil {
+		schema["pagination"] = pagination
+	}
+	records := make([]map[string]any, 12)
+	for i := range records {
+		records[i] = map[string]any{"title": fmt.Sprintf("e%02d", i+1)}
+	}
+	nt := setupNotesTest(t, schema, records...)
+	return nt.collections, nt.data
+}
+
+// listNotes lists notes and decodes the response
+func listNotes(t *testing.T, handler *DataHandler, url string) DataListResponse {
+	t.Helper()
+	w := doDataAction(t, handler.List, http.MethodGet, url, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("List %s failed: %d %s", url, w.Code, w.Body.String())
+	}
+	var resp DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp
+}
+
+// schemaPagination returns the page sizes the :schema of notes reports
+func schemaPagination(t *testing.T, handler *DataHandler) registry.Pagination {
+	t.Helper()
+	w := doDataAction(t, handler.Schema, http.MethodGet, "/notes:schema", nil)
+	var resp SchemaResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Pagination
+}
+
+func TestPagination_CollectionLimits(t *testing.T) {
+	_, handler := setupPaginationTest(t, map[string]any{"default_limit": 5, "max_limit": 10})
+
+	if resp := listNotes(t, handler, "/notes:list"); len(resp.Data) != 5 || resp.Limit != 5 {
+		t.Errorf("expected the default page of 5, got %d records and limit %d", len(resp.Data), resp.Limit)
+	}
+	if resp := listNotes(t, handler, "/notes:list?limit=10"); len(resp.Data) != 10 {
+		t.Errorf("expected a page of 10, got %d", len(resp.Data))
+	}
+
+	// A tenant's collection is named without the tenant prefix of its table
+	table := "acme" + constants.TenantSeparator + "notes"
+	collection, _ := handler.registry.Get("notes")
+	tenantCollection := *collection
+	tenantCollection.Name = table
+	handler.registry.Set(&tenantCollection)
+	tenant := &middleware.AuthEntity{ID: "u1", Type: middleware.EntityTypeUser, Role: "user", Tenant: "acme"}
+
+	want := "limit cannot exceed 10, the max_limit of collection 'notes'"
+	tests := []struct {
+		name   string
+		action func(http.ResponseWriter, *http.Request, string)
+		method string
+		url    string
+		body   any
+		want   string
+	}{
+		{"limit", handler.List, http.MethodGet, "/notes:list?limit=11", nil, want},
+		{"per_page", handler.List, http.MethodGet, "/notes:list?per_page=11", nil, "per_page: " + want},
+		{"query", handler.Query, http.MethodPost, "/notes:query", map[string]any{"limit": 11}, want},
+	}
+	for _, tt := range tests {
+		for _, entity := range []*middleware.AuthEntity{nil, tenant} {
+			var body bytes.Buffer
+			if tt.body != nil {
+				json.NewEncoder(&body).Encode(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.url, &body)
+			name := "notes"
+			if entity != nil {
+				req = req.WithContext(middleware.SetAuthEntity(req.Context(), entity))
+				name = table
+			}
+			w := httptest.NewRecorder()
+			tt.action(w, req, name)
+			var resp map[string]any
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if message, _ := errorMessage(resp); w.Code != http.StatusBadRequest || message != tt.want {
+				t.Errorf("%s on %s: expected 400 with %q, got %d %s", tt.name, name, tt.want, w.Code, w.Body.String())
+			}
+		}
+	}
+
+	if got, want := schemaPagination(t, handler), (registry.Pagination{DefaultLimit: 5, MaxLimit: 10}); got != want {
+		t.Errorf("expected :schema to report %+v, got %+v", want, got)
+	}
+}
+
+func TestPagination_Fallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination map[string]any
+		want       registry.Pagination
+	}{
+		{"server defaults", nil, registry.Pagination{DefaultLimit: 15, MaxLimit: 200}},
+		{"max only", map[string]any{"max_limit": 1000}, registry.Pagination{DefaultLimit: 15, MaxLimit: 1000}},
+		{"max below the default", map[string]any{"max_limit": 8}, registry.Pagination{DefaultLimit: 8, MaxLimit: 8}},
+		{"default above the maximum", map[string]any{"default_limit": 500}, registry.Pagination{DefaultLimit: 500, MaxLimit: 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler := setupPaginationTest(t, tt.pagination)
+			if got := schemaPagination(t, handler); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+			if resp := listNotes(t, handler, "/notes:list"); resp.Limit != tt.want.DefaultLimit {
+				t.Errorf("expected limit %d, got %d", tt.want.DefaultLimit, resp.Limit)
+			}
+			listNotes(t, handler, fmt.Sprintf("/notes:list?limit=%d", tt.want.MaxLimit))
+			w := doDataAction(t, handler.List, http.MethodGet, fmt.Sprintf("/notes:list?limit=%d", tt.want.MaxLimit+1), nil)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), fmt.Sprintf("limit cannot exceed %d", tt.want.MaxLimit)) {
+				t.Errorf("expected 400 above %d, got %d %s", tt.want.MaxLimit, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPagination_Validation(t *testing.T) {
+	collections, handler := setupPaginationTest(t, nil)
+
+	invalid := []map[string]any{
+		{"default_limit": 20, "max_limit": 10},
+		{"max_limit": 1001},
+		{"default_limit": 1001},
+		{"default_limit": -1},
+		{"max_limit": -5},
+	}
+	for i, pagination := range invalid {
+		w := postCollections(collections.Create, map[string]any{
+			"name":       fmt.Sprintf("bad%d", i),
+			"columns":    []map[string]any{{"name": "title", "type": "string", "nullable": false}},
+			"pagination": pagination,
+		})
+		if w.Code != http.StatusBadRequest || errorCode(w) != "invalid_schema" {
+			t.Errorf("create with %v: expected 400 invalid_schema, got %d %s", pagination, w.Code, w.Body.String())
+		}
+		w = postCollections(collections.Update, map[string]any{"name": "notes", "pagination": pagination})
+		if w.Code != http.StatusBadRequest || errorCode(w) != "invalid_schema" {
+			t.Errorf("update with %v: expected 400 invalid_schema, got %d %s", pagination, w.Code, w.Body.String())
+		}
+	}
+
+	// A default must fit under the maximum of the same request
+	w := postCollections(collections.Update, map[string]any{"name": "notes", "pagination": map[string]any{"max_limit": 10}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	w = postCollections(collections.Update, map[string]any{"name": "notes", "pagination": map[string]any{"max_limit": 10, "default_limit": 11}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a default above the maximum, got %d %s", w.Code, w.Body.String())
+	}
+
+	// An empty object clears the override
+	w = postCollections(collections.Update, map[string]any{"name": "notes", "pagination": map[string]any{}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+	if got, want := schemaPagination(t, handler), (registry.Pagination{DefaultLimit: 15, MaxLimit: 200}); got != want {
+		t.Errorf("expected the server defaults %+v, got %+v", want, got)
+	}
+}
+
+func TestPagination_LoweringMaxKeepsCursors(t *testing.T) {
+	collections, handler := setupPaginationTest(t, map[string]any{"default_limit": 5, "max_limit": 10})
+
+	first := listNotes(t, handler, "/notes:list")
+	if first.NextCursor == nil {
+		t.Fatal("expected a next cursor")
+	}
+
+	w := postCollections(collections.Update, map[string]any{"name": "notes", "pagination": map[string]any{"default_limit": 3, "max_limit": 3}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", w.Code, w.Body.String())
+	}
+
+	// The cursor keeps its position and follows with the smaller pages
+	var titles []string
+	for after := first.NextCursor; after != nil; {
+		resp := listNotes(t, handler, "/notes:list?after="+*after)
+		if len(resp.Data) > 3 {
+			t.Fatalf("expected pages of at most 3, got %d", len(resp.Data))
+		}
+		for _, record := range resp.Data {
+			titles = append(titles, record["title"].(string))
+		}
+		after = resp.NextCursor
+	}
+	if want := "e06 e07 e08 e09 e10 e11 e12"; strings.Join(titles, " ") != want {
+		t.Errorf("expected %s, got %v", want, titles)
+	}
+}
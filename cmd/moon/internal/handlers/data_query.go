@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// QueryRequest represents the body of POST /{name}:query. Sort and fields use
+// the syntax of the :list parameters and fall back to the collection defaults
+// when omitted; total falls back to api.include_total_default.
+type QueryRequest struct {
+	Filter json.RawMessage `json:"filter,omitempty"`
+	Sort   *string         `json:"sort,omitempty"`
+	Fields *string         `json:"fields,omitempty"`
+	Limit  *int            `json:"limit,omitempty"`
+	After  string          `json:"after,omitempty"`
+	Total  *bool           `json:"total,omitempty"`
+}
+
+// filterOperators are the operators of :list filters, also used in :query
+var filterOperators = []string{"eq", "ne", "gt", "lt", "gte", "lte", "like", "in", "isnull", "notnull", "between"}
+
+// Query handles POST /{name}:query, a :list whose filter is a JSON tree of
+// and/or groups instead of query string parameters
+func (h *DataHandler) Query(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+
+	var req QueryRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	limit, _ := pageLimits(collection)
+	if req.Limit != nil {
+		limit = *req.Limit
+	}
+	if err := validatePageLimit(limit, logicalName(r, collectionName), collection); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	var conditions []query.Condition
+	if len(req.Filter) > 0 && !bytes.Equal(req.Filter, []byte("null")) {
+		condition, err := parseFilterTree(req.Filter, collection)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidFilter, fmt.Sprintf("invalid filter: %v", err))
+			return
+		}
+		conditions = []query.Condition{condition}
+	}
+
+	sort := strings.Join(collection.DefaultSort, ",")
+	if req.Sort != nil {
+		sort = *req.Sort
+	}
+	fields := strings.Join(collection.DefaultFields, ",")
+	if req.Fields != nil {
+		fields = *req.Fields
+	}
+	total := h.config.API.IncludeTotal()
+	if req.Total != nil {
+		total = *req.Total
+	}
+
+	h.list(w, r, collectionName, collection, listQuery{
+		limit:      limit,
+		after:      req.After,
+		conditions: conditions,
+		sort:       sort,
+		fields:     fields,
+		total:      total,
+	})
+}
+
+// parseFilterTree compiles a :query filter into a single condition. A node is
+// an object whose keys are "and" or "or", holding an array of nodes, or column
+// names, holding an object of operators and values such as {"gt": 100}.
+// Several keys in one node are ANDed. Leaves are validated by buildConditions
+// exactly like :list filters; nesting is limited to constants.MaxFilterDepth
+// groups and the tree to constants.MaxFilterConditions conditions.
+func parseFilterTree(raw json.RawMessage, collection *registry.Collection) (query.Condition, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var root any
+	if err := decoder.Decode(&root); err != nil {
+		return query.Condition{}, fmt.Errorf("filter must be a JSON object")
+	}
+	tree := &filterTree{collection: collection}
+	return tree.node(root, 0)
+}
+
+// withFilterTree appends the :query filter tree of the filter parameter, if
+// it is set, to the conditions of an aggregation. Errors are
+// *apperrors.APIError values.
+func withFilterTree(r *http.Request, collection *registry.Collection, conditions []query.Condition) ([]query.Condition, error) {
+	raw := r.URL.Query().Get(constants.QueryParamFilter)
+	if raw == "" {
+		return conditions, nil
+	}
+	condition, err := parseFilterTree(json.RawMessage(raw), collection)
+	if err != nil {
+		return nil, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidFilter, fmt.Sprintf("invalid filter: %v", err))
+	}
+	return append(conditions, condition), nil
+}
+
+// filterTree tracks the conditions compiled so far while walking a filter
+type filterTree struct {
+	collection *registry.Collection
+	conditions int
+}
+
+// node compiles a filter node at the given group depth
+func (t *filterTree) node(value any, depth int) (query.Condition, error) {
+	object, ok := value.(map[string]any)
+	if !ok || len(object) == 0 {
+		return query.Condition{}, fmt.Errorf("each filter must be a non-empty JSON object")
+	}
+
+	// Keys are visited in order so the generated SQL is deterministic
+	var members []query.Condition
+	for _, key := range slices.Sorted(maps.Keys(object)) {
+		switch key {
+		case "and", "or":
+			if depth >= constants.MaxFilterDepth {
+				return query.Condition{}, fmt.Errorf("and/or groups may be nested at most %d deep", constants.MaxFilterDepth)
+			}
+			items, ok := object[key].([]any)
+			if !ok || len(items) == 0 {
+				return query.Condition{}, fmt.Errorf("'%s' must be a non-empty array of filters", key)
+			}
+			group := query.Condition{Operator: query.OpAnd}
+			if key == "or" {
+				group.Operator = query.OpOr
+			}
+			for _, item := range items {
+				member, err := t.node(item, depth+1)
+				if err != nil {
+					return query.Condition{}, err
+				}
+				group.Conditions = append(group.Conditions, member)
+			}
+			members = append(members, group)
+		default:
+			leaves, err := t.leaves(key, object[key])
+			if err != nil {
+				return query.Condition{}, err
+			}
+			members = append(members, leaves...)
+		}
+	}
+
+	if len(members) == 1 {
+		return members[0], nil
+	}
+	return query.Condition{Operator: query.OpAnd, Conditions: members}, nil
+}
+
+// leaves compiles the operators given for one column, e.g. {"gte": 1, "lt": 5}
+func (t *filterTree) leaves(column string, value any) ([]query.Condition, error) {
+	operators, ok := value.(map[string]any)
+	if !ok || len(operators) == 0 {
+		return nil, fmt.Errorf("filter on '%s' must be an object of operators, e.g. {\"eq\": value}", column)
+	}
+
+	var conditions []query.Condition
+	for _, operator := range slices.Sorted(maps.Keys(operators)) {
+		if !slices.Contains(filterOperators, operator) {
+			return nil, fmt.Errorf("unknown operator '%s' on '%s': use one of %s", operator, column, strings.Join(filterOperators, ", "))
+		}
+		t.conditions++
+		if t.conditions > constants.MaxFilterConditions {
+			return nil, fmt.Errorf("maximum number of filter conditions (%d) exceeded", constants.MaxFilterConditions)
+		}
+
+		text, err := filterValueText(column, operator, operators[operator])
+		if err != nil {
+			return nil, err
+		}
+		built, err := buildConditions([]filterParam{{column: column, operator: operator, value: text}}, t.collection)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, built...)
+	}
+	return conditions, nil
+}
+
+// filterValueText converts a JSON filter value to the text of the equivalent
+// query string filter. in and between also take arrays, whose values may not
+// contain commas.
+func filterValueText(column, operator string, value any) (string, error) {
+	if values, ok := value.([]any); ok {
+		if operator != "in" && operator != "between" {
+			return "", fmt.Errorf("invalid value for %s[%s]: arrays are only accepted by in and between", column, operator)
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			part, err := filterScalarText(column, operator, v)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(part, ",") {
+				return "", fmt.Errorf("invalid value for %s[%s]: array values may not contain commas", column, operator)
+			}
+			parts[i] = part
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return filterScalarText(column, operator, value)
+}
+
+// filterScalarText converts a JSON string, number or boolean to filter text
+func filterScalarText(column, operator string, value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", fmt.Errorf("invalid value for %s[%s]: use isnull to match null values", column, operator)
+	default:
+		return "", fmt.Errorf("invalid value for %s[%s]: expected a string, number or boolean", column, operator)
+	}
+}
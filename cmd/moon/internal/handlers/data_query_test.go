@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// setupQueryTest creates a notes collection with four records:
+// a (active, 50.00, 0), b (pending, 150.00, 5), c (pending, 150.00, 0)
+// and d (archived, 300.00, 2)
+func setupQueryTest(t *testing.T) *DataHandler {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "status", "type": "string", "nullable": false},
+			{"name": "price", "type": "decimal", "nullable": false},
+			{"name": "stock", "type": "integer", "nullable": false},
+		},
+	},
+		map[string]any{"title": "a", "status": "active", "price": "50.00", "stock": 0},
+		map[string]any{"title": "b", "status": "pending", "price": "150.00", "stock": 5},
+		map[string]any{"title": "c", "status": "pending", "price": "150.00", "stock": 0},
+		map[string]any{"title": "d", "status": "archived", "price": "300.00", "stock": 2},
+	)
+	return nt.data
+}
+
+func queryTitles(t *testing.T, handler *DataHandler, body string) ([]string, DataListResponse) {
+	t.Helper()
+	var payload any
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		t.Fatalf("invalid test body: %v", err)
+	}
+	w := doDataAction(t, handler.Query, http.MethodPost, "/notes:query", payload)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Query %s failed: %d %s", body, w.Code, w.Body.String())
+	}
+	var resp DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	titles := make([]string, len(resp.Data))
+	for i, record := range resp.Data {
+		titles[i], _ = record["title"].(string)
+	}
+	return titles, resp
+}
+
+func TestQuery_FilterTree(t *testing.T) {
+	handler := setupQueryTest(t)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"or with nested and", `{"filter": {"or": [{"status": {"eq": "active"}}, {"and": [{"price": {"gt": 100}}, {"stock": {"gt": 0}}]}]}, "sort": "-price"}`, "d,b,a"},
+		{"and of ors", `{"filter": {"and": [{"or": [{"title": {"eq": "a"}}, {"title": {"eq": "c"}}]}, {"or": [{"stock": {"eq": 0}}, {"price": {"gt": 1000}}]}]}, "sort": "title"}`, "a,c"},
+		{"sibling keys are anded", `{"filter": {"status": {"eq": "pending"}, "stock": {"gte": 1, "lt": 10}}}`, "b"},
+		{"array values", `{"filter": {"or": [{"title": {"in": ["a", "d"]}}, {"price": {"between": [140, 160]}}]}, "sort": "title"}`, "a,b,c,d"},
+		{"null check", `{"filter": {"title": {"isnull": false}, "status": {"ne": "pending"}}, "sort": "title"}`, "a,d"},
+		{"no filter", `{"sort": "-title"}`, "d,c,b,a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			titles, resp := queryTitles(t, handler, tt.body)
+			if got := strings.Join(titles, ","); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if *resp.Total != len(titles) {
+				t.Errorf("expected total %d, got %d", len(titles), *resp.Total)
+			}
+		})
+	}
+
+	// Pagination follows the filter and sort
+	filter := `"filter": {"or": [{"status": {"eq": "pending"}}, {"price": {"gte": 300}}]}, "sort": "-price,title", "limit": 2`
+	titles, page := queryTitles(t, handler, `{`+filter+`}`)
+	if strings.Join(titles, ",") != "d,b" || *page.Total != 3 || page.NextCursor == nil {
+		t.Fatalf("unexpected first page %v (total %d)", titles, *page.Total)
+	}
+	titles, page = queryTitles(t, handler, `{`+filter+`, "after": "`+*page.NextCursor+`"}`)
+	if strings.Join(titles, ",") != "c" || page.NextCursor != nil {
+		t.Errorf("unexpected second page %v", titles)
+	}
+}
+
+func TestQuery_InvalidFilters(t *testing.T) {
+	handler := setupQueryTest(t)
+
+	leaves := make([]string, 21)
+	for i := range leaves {
+		leaves[i] = `{"stock": {"eq": 1}}`
+	}
+	nested := `{"title": {"eq": "a"}}`
+	for range 5 {
+		nested = `{"or": [` + nested + `]}`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		errContains string
+	}{
+		{"unknown column", `{"filter": {"missing": {"eq": 1}}}`, "invalid filter column: missing"},
+		{"unknown operator", `{"filter": {"title": {"regex": "a"}}}`, "unknown operator 'regex' on 'title'"},
+		{"type mismatch", `{"filter": {"stock": {"gt": "many"}}}`, "invalid value for column stock"},
+		{"empty group", `{"filter": {"or": []}}`, "'or' must be a non-empty array of filters"},
+		{"group of scalars", `{"filter": {"and": [1]}}`, "each filter must be a non-empty JSON object"},
+		{"operator value not an object", `{"filter": {"title": "a"}}`, "must be an object of operators"},
+		{"array for eq", `{"filter": {"title": {"eq": ["a"]}}}`, "arrays are only accepted by in and between"},
+		{"null value", `{"filter": {"title": {"eq": null}}}`, "use isnull"},
+		{"too deep", `{"filter": ` + nested + `}`, "nested at most 4 deep"},
+		{"too many conditions", `{"filter": {"or": [` + strings.Join(leaves, ",") + `]}}`, "maximum number of filter conditions (20) exceeded"},
+		{"filter not an object", `{"filter": [1]}`, "each filter must be a non-empty JSON object"},
+		{"limit", `{"limit": 0}`, "limit must be at least 1"},
+		{"unknown body field", `{"where": {}}`, "invalid request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload any
+			if err := json.Unmarshal([]byte(tt.body), &payload); err != nil {
+				t.Fatalf("invalid test body: %v", err)
+			}
+			w := doDataAction(t, handler.Query, http.MethodPost, "/notes:query", payload)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.errContains) {
+				t.Errorf("expected 400 containing %q, got %d %s", tt.errContains, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Four levels of nesting are accepted
+	nested = `{"title": {"eq": "a"}}`
+	for range 4 {
+		nested = `{"or": [` + nested + `]}`
+	}
+	if titles, _ := queryTitles(t, handler, `{"filter": `+nested+`}`); strings.Join(titles, ",") != "a" {
+		t.Errorf("expected four nested groups to match a, got %v", titles)
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+// streamBufferSize is the write buffer of a streamed :get response
+const streamBufferSize = 32 * 1024
+
+// rawJSON is the value of a json column as read from the database, kept by
+// parseRowsRawJSON so that :get can measure and stream large documents. It
+// encodes as the string holding the document, as json values are returned.
+type rawJSON []byte
+
+// MarshalJSON implements json.Marshaler
+func (v rawJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(v))
+}
+
+// truncatedJSON replaces a json value left out of a record that exceeds
+// api.max_record_response_bytes
+type truncatedJSON struct {
+	Truncated bool `json:"_truncated"`
+	Bytes     int  `json:"bytes"` // size of the stored document
+}
+
+// fullRecordParam parses ?full= of :get. Errors are *apperrors.APIError values.
+func fullRecordParam(r *http.Request) (bool, error) {
+	return QueryBoolean(r, constants.QueryParamFull, false)
+}
+
+// StreamsResponse reports whether a request is answered by a streamed
+// response, a :get with ?full=true, which the query cache does not keep
+func StreamsResponse(r *http.Request) bool {
+	full, _ := fullRecordParam(r)
+	return full && strings.HasSuffix(r.URL.Path, ":get")
+}
+
+// limitRecordSize replaces the json values of a record by truncatedJSON
+// stubs, largest first, until its encoded size is at most limit bytes, and
+// turns the json values kept into strings. The size counts json values by
+// their stored length, so the documents are never parsed. A limit of 0
+// keeps every value.
+func limitRecordSize(record map[string]any, limit int) {
+	size := 2 // {}
+	var documents []string
+	for key, value := range record {
+		size += len(key) + 4 // "key":,
+		if raw, ok := value.(rawJSON); ok {
+			size += len(raw) + 2
+			documents = append(documents, key)
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		size += len(encoded)
+	}
+
+	slices.SortFunc(documents, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(record[b].(rawJSON)), len(record[a].(rawJSON))), strings.Compare(a, b))
+	})
+	for _, key := range documents {
+		raw := record[key].(rawJSON)
+		if limit > 0 && size > limit {
+			stub := truncatedJSON{Truncated: true, Bytes: len(raw)}
+			encoded, _ := json.Marshal(stub)
+			size += len(encoded) - len(raw) - 2
+			record[key] = stub
+			continue
+		}
+		record[key] = string(raw)
+	}
+}
+
+// writeStreamedRecord writes the response of :get with ?full=true without
+// encoding it in memory first: json values are escaped straight from the
+// bytes read into the buffered writer, the other fields are encoded one at
+// a time. Content-Length is dropped, so the response is chunked.
+func writeStreamedRecord(w http.ResponseWriter, r *http.Request, response DataGetResponse) {
+	if camelFields(r.Context()) {
+		response.Data = camelRecord(response.Data)
+	}
+	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+	// The status is sent; a failed write means the client has gone
+	_ = streamRecord(w, response)
+}
+
+// streamRecord writes response to w as writeJSON would, with the keys of the
+// record sorted and rawJSON values streamed
+func streamRecord(w io.Writer, response DataGetResponse) error {
+	bw := bufio.NewWriterSize(w, streamBufferSize)
+	bw.WriteString(`{"data":{`)
+	for i, key := range slices.Sorted(maps.Keys(response.Data)) {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		encoded, _ := json.Marshal(key)
+		bw.Write(encoded)
+		bw.WriteByte(':')
+		if raw, ok := response.Data[key].(rawJSON); ok {
+			writeJSONString(bw, raw)
+			continue
+		}
+		encoded, err := json.Marshal(response.Data[key])
+		if err != nil {
+			return err
+		}
+		bw.Write(encoded)
+	}
+	bw.WriteByte('}')
+	if response.Links != nil {
+		encoded, err := json.Marshal(response.Links)
+		if err != nil {
+			return err
+		}
+		bw.WriteString(`,"links":`)
+		bw.Write(encoded)
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// writeJSONString writes s as a JSON string, escaped as encoding/json does
+// (HTML characters included), copying the runs that need no escaping as is
+func writeJSONString(bw *bufio.Writer, s []byte) {
+	const hex = "0123456789abcdef"
+	bw.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			bw.Write(s[start:i])
+			switch c {
+			case '"', '\\':
+				bw.WriteByte('\\')
+				bw.WriteByte(c)
+			case '\b':
+				bw.WriteString(`\b`)
+			case '\f':
+				bw.WriteString(`\f`)
+			case '\n':
+				bw.WriteString(`\n`)
+			case '\r':
+				bw.WriteString(`\r`)
+			case '\t':
+				bw.WriteString(`\t`)
+			default:
+				bw.WriteString(`\u00`)
+				bw.WriteByte(hex[c>>4])
+				bw.WriteByte(hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRune(s[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			bw.Write(s[start:i])
+			bw.WriteRune(utf8.RuneError)
+		case c == '\u2028' || c == '\u2029':
+			// Line terminators in JavaScript
+			bw.Write(s[start:i])
+			bw.WriteString(`\u202`)
+			bw.WriteByte(hex[c&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	bw.Write(s[start:])
+	bw.WriteByte('"')
+}
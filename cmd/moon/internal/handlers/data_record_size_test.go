@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// largeDocument returns a JSON array of about size bytes
+func largeDocument(size int) string {
+	var sb strings.Builder
+	sb.WriteString(`[`)
+	for i := 0; sb.Len() < size; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"n":%d,"text":"line \"%d\" <b>&</b>\n"}`, i, i)
+	}
+	sb.WriteString(`]`)
+	return sb.String()
+}
+
+func TestDataHandler_Get_LargeJSON(t *testing.T) {
+	handler := newJSONTestHandler(t)
+	handler.config.API.MaxRecordResponseBytes = 1 << 20
+
+	document := largeDocument(5 << 20)
+	large, small := generateULID(), generateULID()
+	for id, meta := range map[string]string{large: document, small: `{"color":"red"}`} {
+		if _, err := handler.db.Exec(context.Background(), "INSERT INTO products (id, name, meta) VALUES (?, ?, ?)", id, "shirt", meta); err != nil {
+			t.Fatalf("Failed to insert: %v", err)
+		}
+	}
+
+	get := func(query string) (*httptest.ResponseRecorder, map[string]any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.Get(w, httptest.NewRequest(http.MethodGet, "/products:get?"+query, nil), "products")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Get failed: %d %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		return w, resp.Data
+	}
+
+	t.Run("stubbed by default", func(t *testing.T) {
+		w, data := get("id=" + large)
+		stub, ok := data["meta"].(map[string]any)
+		if !ok || stub["_truncated"] != true || stub["bytes"] != float64(len(document)) {
+			t.Errorf("Expected a stub of %d bytes, got %v", len(document), data["meta"])
+		}
+		if data["name"] != "shirt" || w.Body.Len() > 1<<20 {
+			t.Errorf("Expected the other fields in a small response, got %d bytes", w.Body.Len())
+		}
+	})
+
+	t.Run("small records are whole", func(t *testing.T) {
+		if _, data := get("id=" + small); data["meta"] != `{"color":"red"}` {
+			t.Errorf("Expected the json value, got %v", data["meta"])
+		}
+	})
+
+	t.Run("full streams the value", func(t *testing.T) {
+		w, data := get("full=true&id=" + large)
+		if data["meta"] != document || data["name"] != "shirt" {
+			t.Error("Expected the full document with ?full=true")
+		}
+		if w.Header().Get("Content-Length") != "" || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected headers %v", w.Header())
+		}
+	})
+
+	t.Run("invalid full", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.Get(w, httptest.NewRequest(http.MethodGet, "/products:get?full=maybe&id="+large, nil), "products")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestLimitRecordSize(t *testing.T) {
+	record := map[string]any{
+		"id":    "01J",
+		"big":   rawJSON(strings.Repeat("1", 600)),
+		"mid":   rawJSON(strings.Repeat("2", 300)),
+		"small": rawJSON(`{}`),
+	}
+	limitRecordSize(record, 500)
+	if stub, ok := record["big"].(truncatedJSON); !ok || stub.Bytes != 600 {
+		t.Errorf("Expected the largest value stubbed, got %v", record["big"])
+	}
+	if record["mid"] != strings.Repeat("2", 300) || record["small"] != "{}" {
+		t.Errorf("Expected the values that fit as strings, got %v, %v", record["mid"], record["small"])
+	}
+
+	record = map[string]any{"big": rawJSON(strings.Repeat("1", 600))}
+	if limitRecordSize(record, 0); record["big"] != strings.Repeat("1", 600) {
+		t.Error("Expected a limit of 0 to keep every value")
+	}
+}
+
+func TestWriteJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", `{"a":"<b> & \"q\""}`, "tab\tnl\ncr\r\x00\x1f\b\f", "é ü 漢字 😀", "line\u2028sep\u2029", "bad\xffutf8"} {
+		var buf bytes.Buffer
+		bw := bufio.NewWriter(&buf)
+		writeJSONString(bw, []byte(s))
+		bw.Flush()
+		want, _ := json.Marshal(s)
+		if buf.String() != string(want) {
+			t.Errorf("%q: got %s, want %s", s, buf.String(), want)
+		}
+	}
+}
+
+func TestStreamRecord_BoundedMemory(t *testing.T) {
+	response := DataGetResponse{Data: map[string]any{
+		"id":   "01J",
+		"meta": rawJSON(largeDocument(5 << 20)),
+	}}
+
+	allocs := testing.AllocsPerRun(5, func() {
+		if err := streamRecord(io.Discard, response); err != nil {
+			t.Fatalf("streamRecord() error = %v", err)
+		}
+	})
+	if allocs > 20 {
+		t.Errorf("Expected a handful of allocations, got %.0f", allocs)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	streamRecord(io.Discard, response)
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 256<<10 {
+		t.Errorf("Expected a 5 MB value to be streamed with less than 256 KB allocated, got %d bytes", allocated)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// queryFunc runs a query on the database or within a transaction
+type queryFunc func(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+
+// referenceErrors checks the values of the reference columns in items against
+// the records of the referenced collections, with one lookup per referenced
+// collection and ReferenceLookupSize ids. Empty and null values are not
+// checked. It returns the invalid_reference error of each item that holds an
+// id with no live record, keyed by item index; the error is only returned
+// when a lookup fails.
+func referenceErrors(ctx context.Context, q queryFunc, reg *registry.SchemaRegistry, collection *registry.Collection, items []map[string]any, dialect database.DialectType) (map[int]error, error) {
+	failed := map[int]error{}
+	for _, col := range collection.Columns {
+		if col.References == "" {
+			continue
+		}
+
+		// holders maps each referenced id to the items that hold it
+		holders := map[string][]int{}
+		for idx, item := range items {
+			if id, ok := item[col.Name].(string); ok && id != "" && failed[idx] == nil {
+				holders[id] = append(holders[id], idx)
+			}
+		}
+		if len(holders) == 0 {
+			continue
+		}
+
+		found, err := existingRecordIDs(ctx, q, reg, col.References, slices.Sorted(maps.Keys(holders)), dialect)
+		if err != nil {
+			return nil, err
+		}
+		_, target := registry.SplitTenantKey(col.References)
+		for id, idxs := range holders {
+			if found[id] {
+				continue
+			}
+			for _, idx := range idxs {
+				failed[idx] = apperrors.Newf(http.StatusBadRequest, apperrors.CodeInvalidReference, "field '%s' references '%s', which is not a record of collection '%s'", col.Name, id, target)
+			}
+		}
+	}
+	return failed, nil
+}
+
+// checkReferences checks the reference columns of a single record. Errors
+// are *apperrors.APIError values.
+func checkReferences(ctx context.Context, q queryFunc, reg *registry.SchemaRegistry, collection *registry.Collection, data map[string]any, dialect database.DialectType) error {
+	failed, err := referenceErrors(ctx, q, reg, collection, []map[string]any{data}, dialect)
+	if err != nil {
+		return apperrors.Newf(http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check references: %v", err)
+	}
+	return failed[0]
+}
+
+// existingRecordIDs returns which of ids are live records of the table.
+// Soft-deleted records do not count.
+func existingRecordIDs(ctx context.Context, q queryFunc, reg *registry.SchemaRegistry, table string, ids []string, dialect database.DialectType) (map[string]bool, error) {
+	liveOnly := ""
+	if target, ok := reg.Get(table); ok && target.SoftDelete {
+		liveOnly = fmt.Sprintf(" AND %s IS NULL", constants.SoftDeleteColumn)
+	}
+
+	found := make(map[string]bool, len(ids))
+	for chunk := range slices.Chunk(ids, constants.ReferenceLookupSize) {
+		placeholders := make([]string, len(chunk))
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = query.Placeholder(dialect, i+1)
+			args[i] = id
+		}
+		lookup := fmt.Sprintf("SELECT id FROM %s WHERE id IN (%s)%s", query.QuoteIdent(dialect, table), strings.Join(placeholders, ", "), liveOnly)
+		rows, err := q(ctx, lookup, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			found[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
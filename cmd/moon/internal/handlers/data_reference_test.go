@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// referenceTest holds a customers collection with one live and one
+// soft-deleted record, and an orders collection referencing it
+type referenceTest struct {
+	driver      database.Driver
+	reg         *registry.SchemaRegistry
+	collections *CollectionsHandler
+	data        *DataHandler
+	customer    string
+	deleted     string
+}
+
+func setupReferenceTest(t *testing.T) *referenceTest {
+	t.Helper()
+	driver := newTestDriver(t)
+
+	reg := registry.NewSchemaRegistry()
+	rt := &referenceTest{driver: driver, reg: reg, collections: NewCollectionsHandler(driver, reg), data: NewDataHandler(driver, reg, testConfig())}
+	for _, body := range []map[string]any{
+		{"name": "customers", "soft_delete": true, "columns": []map[string]any{{"name": "name", "type": "string"}}},
+		{"name": "orders", "columns": []map[string]any{
+			{"name": "label", "type": "string"},
+			{"name": "customer_id", "type": "string", "nullable": true, "references": "customers"},
+		}},
+	} {
+		if w := postCollections(rt.collections.Create, body); w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	for _, name := range []string{"ada", "bob"} {
+		w := rt.do(t, rt.data.Create, "customers", "/customers:create", map[string]any{"data": map[string]any{"name": name}})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create customer: %d %s", w.Code, w.Body.String())
+		}
+		var resp CreateDataResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		rt.customer, rt.deleted = rt.deleted, resp.Data["id"].(string)
+	}
+	if w := rt.do(t, rt.data.Destroy, "customers", "/customers:destroy", map[string]any{"data": []string{rt.deleted}}); w.Code >= http.StatusMultipleChoices {
+		t.Fatalf("Failed to delete customer: %d %s", w.Code, w.Body.String())
+	}
+	return rt
+}
+
+// do runs a data action on the named collection
+func (rt *referenceTest) do(t *testing.T, action func(http.ResponseWriter, *http.Request, string), collection, url string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	action(w, httptest.NewRequest(http.MethodPost, url, bytes.NewReader(payload)), collection)
+	return w
+}
+
+// errorCode returns the error code of a response body
+func errorCode(w *httptest.ResponseRecorder) string {
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	code, _ := resp["code"].(string)
+	return code
+}
+
+func TestReferences_CollectionValidation(t *testing.T) {
+	rt := setupReferenceTest(t)
+
+	tests := []struct {
+		name   string
+		column map[string]any
+		want   string
+	}{
+		{"missing collection", map[string]any{"name": "vendor_id", "type": "string", "references": "vendors"}, "referenced collection 'vendors' not found"},
+		{"non-string column", map[string]any{"name": "customer_id", "type": "integer", "references": "customers"}, "requires a string column"},
+		{"system collection", map[string]any{"name": "user_id", "type": "string", "references": "moon_users"}, "invalid references"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postCollections(rt.collections.Create, map[string]any{"name": "invoices", "columns": []map[string]any{tt.column}})
+			if w.Code != http.StatusBadRequest || errorCode(w) != string(apperrors.CodeInvalidSchema) || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("expected 400 invalid_schema with %q, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// A collection may reference itself
+	w := postCollections(rt.collections.Create, map[string]any{"name": "categories", "columns": []map[string]any{
+		{"name": "parent_id", "type": "string", "nullable": true, "references": "categories"},
+	}})
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected a self reference to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A reference column stays a string
+	w = postCollections(rt.collections.Update, map[string]any{"name": "orders", "modify_columns": []map[string]any{{"name": "customer_id", "type": "integer", "nullable": true}}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "must remain a string") {
+		t.Errorf("expected the type change to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The relation is part of the schema
+	w = rt.do(t, rt.data.Schema, "orders", "/orders:schema", nil)
+	var schema SchemaResponse
+	json.Unmarshal(w.Body.Bytes(), &schema)
+	for _, field := range schema.Fields {
+		want := ""
+		if field.Name == "customer_id" {
+			want = "customers"
+		}
+		if field.References != want {
+			t.Errorf("field %s: expected references %q, got %q", field.Name, want, field.References)
+		}
+	}
+}
+
+func TestReferences_Create(t *testing.T) {
+	rt := setupReferenceTest(t)
+
+	tests := []struct {
+		name       string
+		customerID any
+		status     int
+	}{
+		{"existing record", rt.customer, http.StatusCreated},
+		{"null", nil, http.StatusCreated},
+		{"empty", "", http.StatusCreated},
+		{"missing record", generateULID(), http.StatusBadRequest},
+		{"soft-deleted record", rt.deleted, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": map[string]any{"label": "order", "customer_id": tt.customerID}})
+			if w.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status == http.StatusBadRequest {
+				if errorCode(w) != string(apperrors.CodeInvalidReference) || !strings.Contains(w.Body.String(), "field 'customer_id' references '"+tt.customerID.(string)+"'") {
+					t.Errorf("expected invalid_reference naming the field and value, got %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestReferences_Update(t *testing.T) {
+	rt := setupReferenceTest(t)
+	w := rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": map[string]any{"label": "order", "customer_id": rt.customer}})
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	id := created.Data["id"].(string)
+
+	w = rt.do(t, rt.data.Update, "orders", "/orders:update", map[string]any{"data": map[string]any{"id": id, "customer_id": rt.deleted}})
+	if w.Code != http.StatusBadRequest || errorCode(w) != string(apperrors.CodeInvalidReference) {
+		t.Errorf("expected 400 invalid_reference, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Updates leaving the reference alone do not look it up
+	w = rt.do(t, rt.data.Update, "orders", "/orders:update", map[string]any{"data": map[string]any{"id": id, "label": "renamed"}})
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReferences_Batch(t *testing.T) {
+	rt := setupReferenceTest(t)
+	missing := generateULID()
+	items := []map[string]any{
+		{"label": "first", "customer_id": rt.customer},
+		{"label": "second", "customer_id": missing},
+		{"label": "third", "customer_id": rt.customer},
+	}
+
+	w := rt.do(t, rt.data.Create, "orders", "/orders:create?atomic=true", map[string]any{"data": items})
+	if w.Code != http.StatusBadRequest || errorCode(w) != string(apperrors.CodeInvalidReference) || !strings.Contains(w.Body.String(), "validation error at index 1") {
+		t.Errorf("expected the atomic batch to fail at index 1, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = rt.do(t, rt.data.Create, "orders", "/orders:create?atomic=false", map[string]any{"data": items})
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Summary.Succeeded != 2 || resp.Summary.Failed != 1 {
+		t.Fatalf("expected 2 created and 1 failed, got %s", w.Body.String())
+	}
+	var ids []string
+	for _, result := range resp.Results {
+		if result.Index == 1 {
+			if result.Status != BatchItemFailed || result.ErrorCode != apperrors.CodeInvalidReference {
+				t.Errorf("expected item 1 to fail with invalid_reference, got %+v", result)
+			}
+			continue
+		}
+		ids = append(ids, result.ID)
+	}
+
+	// Batch updates are checked the same way
+	updates := []map[string]any{{"id": ids[0], "customer_id": rt.customer}, {"id": ids[1], "customer_id": missing}}
+	w = rt.do(t, rt.data.Update, "orders", "/orders:update?atomic=true", map[string]any{"data": updates})
+	if w.Code != http.StatusBadRequest || errorCode(w) != string(apperrors.CodeInvalidReference) || !strings.Contains(w.Body.String(), "validation error at index 1") {
+		t.Errorf("expected the atomic update to fail at index 1, got %d: %s", w.Code, w.Body.String())
+	}
+	w = rt.do(t, rt.data.Update, "orders", "/orders:update?atomic=false", map[string]any{"data": updates})
+	resp = BatchResponse{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Summary.Succeeded != 1 || resp.Summary.Failed != 1 {
+		t.Errorf("expected 1 updated and 1 failed, got %s", w.Body.String())
+	}
+}
+
+func TestReferenceErrors_OneLookupPerCollection(t *testing.T) {
+	rt := setupReferenceTest(t)
+	orders, _ := rt.reg.Get("orders")
+
+	items := make([]map[string]any, 120)
+	for i := range items {
+		items[i] = map[string]any{"customer_id": rt.customer}
+		if i%3 == 0 {
+			items[i]["customer_id"] = generateULID()
+		}
+	}
+	lookups := 0
+	count := func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+		lookups++
+		return rt.driver.Query(ctx, query, args...)
+	}
+	failed, err := referenceErrors(context.Background(), count, rt.reg, orders, items, rt.driver.Dialect())
+	if err != nil {
+		t.Fatalf("referenceErrors failed: %v", err)
+	}
+	if lookups != 1 {
+		t.Errorf("expected one lookup for the batch, got %d", lookups)
+	}
+	if len(failed) != 40 || failed[0] == nil || failed[1] != nil {
+		t.Errorf("expected every third item to fail, got %d failures", len(failed))
+	}
+}
+
+func TestReferences_DestroyGuard(t *testing.T) {
+	rt := setupReferenceTest(t)
+	destroy := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rt.collections.Destroy(w, httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"name": "customers"}`)))
+		return w
+	}
+
+	w := destroy("/collections:destroy")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "referenced by orders") {
+		t.Fatalf("expected 409 listing orders, got %d: %s", w.Code, w.Body.String())
+	}
+	if !rt.reg.Exists("customers") {
+		t.Fatal("expected customers to be kept")
+	}
+
+	if w := destroy("/collections:destroy?cascade_check=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid cascade_check, got %d", w.Code)
+	}
+
+	w = destroy("/collections:destroy?cascade_check=false")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	orders, _ := rt.reg.Get("orders")
+	for _, col := range orders.Columns {
+		if col.References != "" {
+			t.Errorf("expected the reference of %s to be removed, got %q", col.Name, col.References)
+		}
+	}
+	w = rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": map[string]any{"label": "order", "customer_id": rt.customer}})
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected the former reference to be a plain string, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReferences_Rename(t *testing.T) {
+	rt := setupReferenceTest(t)
+	w := postCollections(rt.collections.Rename, map[string]any{"name": "customers", "new_name": "clients"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	orders, _ := rt.reg.Get("orders")
+	if col, _ := findColumn(orders.Columns, "customer_id"); col.References != "clients" {
+		t.Errorf("expected the reference to follow the rename, got %q", col.References)
+	}
+
+	w = rt.do(t, rt.data.Create, "orders", "/orders:create", map[string]any{"data": map[string]any{"label": "order", "customer_id": rt.customer}})
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected the renamed collection to be looked up, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// RestoreDataRequest represents request for restore operation
+type RestoreDataRequest struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// RestoreDataResponse represents response for restore operation
+type RestoreDataResponse struct {
+	Message string `json:"message"`
+}
+
+// Restore handles POST /{name}:restore
+// Clears deleted_at on soft-deleted records. The body mirrors :destroy:
+// {"data": "<id>"} for a single record or {"data": ["<id>", ...]} for a batch.
+func (h *DataHandler) Restore(w http.ResponseWriter, r *http.Request, collectionName string) {
+	// Validate collection exists in registry
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	if !collection.SoftDelete {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeBadRequest, fmt.Sprintf("collection '%s' does not have soft delete enabled", collectionName))
+		return
+	}
+
+	// Check payload size (PRD-064)
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+
+	var req RestoreDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	if len(req.Data) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "missing data field")
+		return
+	}
+
+	// Detect batch vs single mode (array of IDs)
+	isBatch, err := detectBatchMode(req.Data)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, err.Error())
+		return
+	}
+
+	if !isBatch {
+		var id string
+		if err := json.Unmarshal(req.Data, &id); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid data format")
+			return
+		}
+		h.restoreSingle(w, r, collection, id)
+		return
+	}
+
+	atomic := parseAtomicFlag(r)
+	h.restoreBatch(w, r, collection, req.Data, atomic)
+}
+
+// restoreSingle restores one soft-deleted record
+func (h *DataHandler) restoreSingle(w http.ResponseWriter, r *http.Request, collection *registry.Collection, id string) {
+	if id == "" {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
+		return
+	}
+
+	// Validate ULID format
+	if err := validateRecordID(collection, id); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("invalid id: %v", err))
+		return
+	}
+
+	query, args := buildRestoreQuery(collection, id, h.db.Dialect())
+
+	ctx := r.Context()
+	result, err := h.db.Exec(ctx, query, args...)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to restore data: %v", err))
+		return
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
+		return
+	}
+
+	if rowsAffected == 0 {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeRecordNotFound, fmt.Sprintf("deleted record with id %s not found", id))
+		return
+	}
+
+	audit.AddRecords(ctx, id)
+	writeResponse(w, r, http.StatusOK, RestoreDataResponse{
+		Message: fmt.Sprintf("Record %s restored successfully", id),
+	})
+}
+
+// restoreBatch handles batch restore operations
+func (h *DataHandler) restoreBatch(w http.ResponseWriter, r *http.Request, collection *registry.Collection, rawData json.RawMessage, atomic bool) {
+	var ids []string
+	if err := json.Unmarshal(rawData, &ids); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid batch data format")
+		return
+	}
+
+	// Validate batch size
+	if err := h.validateBatchSize(len(ids)); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, err.Error())
+		return
+	}
+
+	if len(ids) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "batch must contain at least one id")
+		return
+	}
+
+	ctx := r.Context()
+
+	if atomic {
+		// Atomic mode: all-or-nothing with transaction
+		h.restoreBatchAtomic(w, r, collection, ids)
+	} else {
+		// Best-effort mode: partial success
+		h.restoreBatchBestEffort(w, ctx, collection, ids)
+	}
+}
+
+// restoreBatchAtomic restores every record in a single transaction
+func (h *DataHandler) restoreBatchAtomic(w http.ResponseWriter, r *http.Request, collection *registry.Collection, ids []string) {
+	ctx := r.Context()
+	// Validate all IDs first
+	for idx, id := range ids {
+		if err := validateRecordID(collection, id); err != nil {
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("validation error at index %d: invalid id: %v", idx, err))
+			return
+		}
+	}
+
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		query, args := buildRestoreQuery(collection, id, h.db.Dialect())
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to restore data: %v", err))
+			return
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to get rows affected: %v", err))
+			return
+		}
+
+		if rowsAffected == 0 {
+			writeError(w, r, http.StatusNotFound, apperrors.CodeRecordNotFound, fmt.Sprintf("deleted record with id %s not found", id))
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
+		return
+	}
+
+	audit.AddRecords(ctx, ids...)
+	writeResponse(w, r, http.StatusOK, RestoreDataResponse{
+		Message: fmt.Sprintf("%d records restored successfully", len(ids)),
+	})
+}
+
+// restoreBatchBestEffort restores each record independently and reports per-item status
+func (h *DataHandler) restoreBatchBestEffort(w http.ResponseWriter, ctx context.Context, collection *registry.Collection, ids []string) {
+	out := h.newBatchResultWriter(w, ctx, "")
+
+	for idx, id := range ids {
+		if err := validateRecordID(collection, id); err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeOf(err, apperrors.CodeInvalidULID),
+				ErrorMessage: fmt.Sprintf("invalid id: %v", err),
+			})
+			continue
+		}
+
+		query, args := buildRestoreQuery(collection, id, h.db.Dialect())
+
+		result, err := h.db.Exec(ctx, query, args...)
+		if err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeDatabaseError,
+				ErrorMessage: err.Error(),
+			})
+			continue
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeDatabaseError,
+				ErrorMessage: fmt.Sprintf("failed to get rows affected: %v", err),
+			})
+			continue
+		}
+
+		if rowsAffected == 0 {
+			out.add(BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemNotFound,
+				ErrorCode:    apperrors.CodeRecordNotFound,
+				ErrorMessage: fmt.Sprintf("deleted record with id %s not found", id),
+			})
+			continue
+		}
+
+		out.add(BatchItemResult{
+			Index:  idx,
+			ID:     id,
+			Status: BatchItemRestored,
+		})
+	}
+
+	out.close()
+}
+
+// buildRestoreQuery returns the statement that clears deleted_at on a soft-deleted record.
+// Live records do not match, so restoring them reports not found.
+func buildRestoreQuery(collection *registry.Collection, id string, dialect database.DialectType) (string, []any) {
+	sqlQuery := fmt.Sprintf("UPDATE %s SET %s = NULL WHERE id = %s AND %s IS NOT NULL",
+		query.QuoteIdent(dialect, collection.Name),
+		constants.SoftDeleteColumn,
+		query.Placeholder(dialect, 1),
+		constants.SoftDeleteColumn)
+	return sqlQuery, []any{id}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// queryRowFunc runs a single-row query on the database or within a transaction
+type queryRowFunc func(ctx context.Context, query string, args ...any) *sql.Row
+
+// parseRevision converts a JSON revision value to a positive integer
+func parseRevision(val any) (int64, error) {
+	switch v := val.(type) {
+	case float64:
+		if v >= 1 && v == float64(int64(v)) {
+			return int64(v), nil
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil && n >= 1 {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%s must be a positive integer", constants.RevisionColumn)
+}
+
+// takeItemRevision removes the _rev field from an update item and returns its value.
+// It returns nil when the item carries no revision.
+func takeItemRevision(item map[string]any) (*int64, error) {
+	val, ok := item[constants.RevisionColumn]
+	if !ok {
+		return nil, nil
+	}
+	delete(item, constants.RevisionColumn)
+	rev, err := parseRevision(val)
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// requestRevision returns the expected revision of a single-record request from
+// the "rev" body field or, when the body has none, the If-Match header.
+// It returns nil when neither is present or If-Match is "*".
+func requestRevision(r *http.Request, bodyRev json.RawMessage) (*int64, error) {
+	if len(bodyRev) > 0 {
+		var val any
+		if err := json.Unmarshal(bodyRev, &val); err != nil {
+			return nil, fmt.Errorf("rev must be a positive integer")
+		}
+		rev, err := parseRevision(val)
+		if err != nil {
+			return nil, fmt.Errorf("rev must be a positive integer")
+		}
+		return &rev, nil
+	}
+	return parseIfMatch(r.Header.Get(constants.HeaderIfMatch))
+}
+
+// parseIfMatch parses an If-Match header holding a single revision ETag
+// such as "3", W/"3" or a bare 3.
+func parseIfMatch(header string) (*int64, error) {
+	value := strings.TrimSpace(header)
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	rev, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || rev < 1 {
+		return nil, fmt.Errorf("invalid %s header: expected a record revision", constants.HeaderIfMatch)
+	}
+	return &rev, nil
+}
+
+// revisionETag formats a record revision as a strong ETag
+func revisionETag(rev int64) string {
+	return fmt.Sprintf(`"%d"`, rev)
+}
+
+// requireRevision rejects a write without a revision on collections created
+// or updated with require_revision.
+func requireRevision(collection *registry.Collection, rev *int64) error {
+	if collection.RequireRevision && rev == nil {
+		return fmt.Errorf("collection '%s' requires a record revision (%s field, rev or %s header)",
+			collection.Name, constants.RevisionColumn, constants.HeaderIfMatch)
+	}
+	return nil
+}
+
+// revisionCondition returns the WHERE fragment that matches the expected revision
+// and appends its value to args. Nothing is added when rev is nil.
+func revisionCondition(rev *int64, args []any, dialect database.DialectType) (string, []any) {
+	if rev == nil {
+		return "", args
+	}
+	args = append(args, *rev)
+	return fmt.Sprintf(" AND %s = %s", constants.RevisionColumn, query.Placeholder(dialect, len(args))), args
+}
+
+// currentRevision returns the stored revision of a record. found is false when
+// no live record has the id: writes treat soft-deleted records as missing.
+func currentRevision(ctx context.Context, queryRow queryRowFunc, collection *registry.Collection, id string, dialect database.DialectType) (rev int64, found bool, err error) {
+	sqlQuery := fmt.Sprintf("SELECT %s FROM %s WHERE id = %s", constants.RevisionColumn, query.QuoteIdent(dialect, collection.Name), query.Placeholder(dialect, 1))
+	if collection.SoftDelete {
+		sqlQuery += fmt.Sprintf(" AND %s IS NULL", constants.SoftDeleteColumn)
+	}
+	err = queryRow(ctx, sqlQuery, id).Scan(&rev)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return rev, true, nil
+}
+
+// writeRevisionConflict writes a 409 response carrying the current record revision
+// so the client can reload the record and retry.
+func writeRevisionConflict(w http.ResponseWriter, r *http.Request, message string, current int64) {
+	w.Header().Set(constants.HeaderETag, revisionETag(current))
+	body := errorBody(r, http.StatusConflict, apperrors.CodeRevisionConflict, message)
+	apperrors.SetField(body, "current_rev", current)
+	writeJSON(w, http.StatusConflict, body)
+}
+
+// revisionConflictMessage describes a stale revision for a record
+func revisionConflictMessage(id string, expected, current int64) string {
+	return fmt.Sprintf("record %s has revision %d, expected %d", id, current, expected)
+}
+
+// singleRevision returns the expected revision of a single-record write from the
+// _rev field of the item, the "rev" body field or the If-Match header, in that
+// order. The _rev field is removed from the item so it is not written as data.
+func singleRevision(r *http.Request, item map[string]any, bodyRev json.RawMessage) (*int64, error) {
+	rev, err := takeItemRevision(item)
+	if err != nil || rev != nil {
+		return rev, err
+	}
+	return requestRevision(r, bodyRev)
+}
+
+// writeRecordMiss reports why a write matched no row: 409 with the current
+// revision when the record exists under another revision, otherwise 404.
+func writeRecordMiss(w http.ResponseWriter, r *http.Request, queryRow queryRowFunc, collection *registry.Collection, id string, rev *int64, dialect database.DialectType) {
+	if rev != nil {
+		current, found, err := currentRevision(r.Context(), queryRow, collection, id, dialect)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read record revision: %v", err))
+			return
+		}
+		if found {
+			writeRevisionConflict(w, r, revisionConflictMessage(id, *rev, current), current)
+			return
+		}
+	}
+	writeError(w, r, http.StatusNotFound, apperrors.CodeRecordNotFound, fmt.Sprintf("record with id %s not found", id))
+}
+
+// recordMissResult is the batch counterpart of writeRecordMiss
+func recordMissResult(ctx context.Context, queryRow queryRowFunc, collection *registry.Collection, idx int, id string, rev *int64, dialect database.DialectType) BatchItemResult {
+	if rev != nil {
+		current, found, err := currentRevision(ctx, queryRow, collection, id, dialect)
+		if err != nil {
+			return BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemFailed,
+				ErrorCode:    apperrors.CodeDatabaseError,
+				ErrorMessage: fmt.Sprintf("failed to read record revision: %v", err),
+			}
+		}
+		if found {
+			return BatchItemResult{
+				Index:        idx,
+				ID:           id,
+				Status:       BatchItemConflict,
+				ErrorCode:    apperrors.CodeRevisionConflict,
+				ErrorMessage: revisionConflictMessage(id, *rev, current),
+				CurrentRev:   &current,
+			}
+		}
+	}
+	return BatchItemResult{
+		Index:        idx,
+		ID:           id,
+		Status:       BatchItemNotFound,
+		ErrorCode:    apperrors.CodeRecordNotFound,
+		ErrorMessage: fmt.Sprintf("record with id %s not found", id),
+	}
+}
+
+// destroyTarget identifies a record to destroy and its expected revision
+type destroyTarget struct {
+	ID  string
+	Rev *int64
+}
+
+// parseDestroyTargets decodes batch destroy entries. Each entry is either an id
+// string or an object {"id": "...", "_rev": 3} carrying the expected revision.
+func parseDestroyTargets(rawData json.RawMessage) ([]destroyTarget, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(rawData, &entries); err != nil {
+		return nil, err
+	}
+
+	targets := make([]destroyTarget, len(entries))
+	for i, entry := range entries {
+		if err := json.Unmarshal(entry, &targets[i].ID); err == nil {
+			continue
+		}
+		var item map[string]any
+		if err := json.Unmarshal(entry, &item); err != nil || item == nil {
+			return nil, fmt.Errorf("entry %d must be an id or an object with id and %s", i, constants.RevisionColumn)
+		}
+		id, ok := item["id"].(string)
+		if !ok {
+			return nil, fmt.Errorf("entry %d: id must be a string", i)
+		}
+		rev, err := takeItemRevision(item)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i, err)
+		}
+		targets[i] = destroyTarget{ID: id, Rev: rev}
+	}
+	return targets, nil
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// postData sends a JSON body to a data action handler and returns the recorder
+func postData(t *testing.T, action func(http.ResponseWriter, *http.Request, string), url string, body any, ifMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	action(w, req, "products")
+	return w
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int64
+		wantNil bool
+		wantErr bool
+	}{
+		{header: "", wantNil: true},
+		{header: "*", wantNil: true},
+		{header: `"3"`, want: 3},
+		{header: `W/"7"`, want: 7},
+		{header: "12", want: 12},
+		{header: `"0"`, wantErr: true},
+		{header: `"abc"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			rev, err := parseIfMatch(tt.header)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", rev)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantNil {
+				if rev != nil {
+					t.Errorf("expected no revision, got %d", *rev)
+				}
+				return
+			}
+			if rev == nil || *rev != tt.want {
+				t.Errorf("expected revision %d, got %v", tt.want, rev)
+			}
+		})
+	}
+}
+
+func TestDataHandler_Revisions_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	// New records start at revision 1
+	w := postData(t, handler.Create, "/products:create", CreateDataRequest{Data: map[string]any{"name": "Lamp", "price": 30}}, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	id := created.Data["id"].(string)
+	if created.Data["_rev"] != float64(1) {
+		t.Errorf("expected _rev 1 on create, got %v", created.Data["_rev"])
+	}
+
+	// :get returns the revision in the body and as an ETag
+	w = httptest.NewRecorder()
+	handler.Get(w, httptest.NewRequest(http.MethodGet, "/products:get?id="+id, nil), "products")
+	if etag := w.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("expected ETag \"1\", got %q", etag)
+	}
+
+	// A matching If-Match header updates the record and advances the revision
+	w = postData(t, handler.Update, "/products:update", map[string]any{"data": map[string]any{"id": id, "price": 35}}, `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated UpdateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if updated.Data["_rev"] != float64(2) || w.Header().Get("ETag") != `"2"` {
+		t.Errorf("expected revision 2 after update, got %v (ETag %q)", updated.Data["_rev"], w.Header().Get("ETag"))
+	}
+
+	// A stale body revision is rejected with the current revision
+	w = postData(t, handler.Update, "/products:update", map[string]any{"data": map[string]any{"id": id, "price": 40}, "rev": 1}, "")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	var conflict map[string]any
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	if conflict["current_rev"] != float64(2) || conflict["code"] != string(apperrors.CodeRevisionConflict) {
+		t.Errorf("unexpected conflict body: %v", conflict)
+	}
+
+	// Updates without a revision still apply
+	w = postData(t, handler.Update, "/products:update", map[string]any{"data": map[string]any{"id": id, "price": 45}}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var rev int64
+	driver.QueryRow(context.Background(), "SELECT _rev FROM products WHERE id = ?", id).Scan(&rev)
+	if rev != 3 {
+		t.Errorf("expected stored revision 3, got %d", rev)
+	}
+
+	// A stale destroy is rejected and a matching one succeeds
+	w = postData(t, handler.Destroy, "/products:destroy", map[string]any{"data": id, "rev": 2}, "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for stale destroy, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postData(t, handler.Destroy, "/products:destroy", map[string]any{"data": id}, `"3"`)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for matching destroy, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Missing records are still reported as not found
+	w = postData(t, handler.Update, "/products:update", map[string]any{"data": map[string]any{"id": id, "price": 50}, "rev": 3}, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for destroyed record, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDataHandler_Revisions_Batch_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	ctx := context.Background()
+	ids := []string{"01ARYZ6S41TSV4RRFFQ69G5FA1", "01ARYZ6S41TSV4RRFFQ69G5FA2"}
+	for _, id := range ids {
+		if _, err := driver.Exec(ctx, "INSERT INTO products (id, name, price) VALUES (?, ?, ?)", id, "Item", 10); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+
+	// Best-effort batches report stale items individually
+	items := []map[string]any{
+		{"id": ids[0], "_rev": 1, "price": 11},
+		{"id": ids[1], "_rev": 5, "price": 12},
+	}
+	w := postData(t, handler.Update, "/products:update?atomic=false", map[string]any{"data": items}, "")
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Results[0].Status != BatchItemUpdated || resp.Results[0].Data["_rev"] != float64(2) {
+		t.Errorf("expected first item updated to revision 2, got %+v", resp.Results[0])
+	}
+	second := resp.Results[1]
+	if second.Status != BatchItemConflict || second.ErrorCode != "revision_conflict" || second.CurrentRev == nil || *second.CurrentRev != 1 {
+		t.Errorf("expected second item to conflict at revision 1, got %+v", second)
+	}
+
+	// Atomic batches roll back on the first conflict
+	items = []map[string]any{
+		{"id": ids[0], "_rev": 2, "price": 20},
+		{"id": ids[1], "_rev": 5, "price": 21},
+	}
+	w = postData(t, handler.Update, "/products:update?atomic=true", map[string]any{"data": items}, "")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	var price int
+	driver.QueryRow(ctx, "SELECT price FROM products WHERE id = ?", ids[0]).Scan(&price)
+	if price != 11 {
+		t.Errorf("expected atomic batch to be rolled back, price is %d", price)
+	}
+
+	// Batch destroy accepts objects carrying revisions
+	targets := []any{ids[0], map[string]any{"id": ids[1], "_rev": 4}}
+	w = postData(t, handler.Destroy, "/products:destroy?atomic=false", map[string]any{"data": targets}, "")
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	resp = BatchResponse{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Results[0].Status != BatchItemDeleted || resp.Results[1].Status != BatchItemConflict {
+		t.Errorf("unexpected destroy results: %+v", resp.Results)
+	}
+}
+
+func TestDataHandler_RequireRevision_Integration(t *testing.T) {
+	driver, reg, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	collection, _ := reg.Get("products")
+	collection.RequireRevision = true
+	reg.Set(collection)
+
+	const id = "01ARYZ6S41TSV4RRFFQ69G5FA1"
+	if _, err := driver.Exec(context.Background(), "INSERT INTO products (id, name, price) VALUES (?, ?, ?)", id, "Item", 10); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	w := postData(t, handler.Update, "/products:update", map[string]any{"data": map[string]any{"id": id, "price": 11}}, "")
+	if w.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected 428 for update without revision, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postData(t, handler.Destroy, "/products:destroy", map[string]any{"data": id}, "")
+	if w.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected 428 for destroy without revision, got %d: %s", w.Code, w.Body.String())
+	}
+	w = postData(t, handler.Update, "/products:update", map[string]any{"data": map[string]any{"id": id, "_rev": 1, "price": 11}}, "")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with revision, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -3,9 +3,16 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/thalib/moon/cmd/moon/internal/database"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
@@ -287,9 +294,9 @@ func TestSchemaEndpoint(t *testing.T) {
 			t.Error("Expected user-defined 'title' field in schema")
 		}
 
-		// Expected fields: id (external), title
-		if len(resp.Fields) != 2 {
-			t.Errorf("Expected 2 fields (id, title), got %d fields", len(resp.Fields))
+		// Expected fields: id (external), title, created_at, updated_at, _rev
+		if len(resp.Fields) != 5 {
+			t.Errorf("Expected 5 fields (id, title, created_at, updated_at, _rev), got %d fields", len(resp.Fields))
 		}
 	})
 
@@ -370,3 +377,169 @@ func TestSchemaEndpoint(t *testing.T) {
 		}
 	})
 }
+
+// validateJSONSchema checks value against the subset of JSON Schema that
+// schema.ToJSONSchema emits and returns the violations found. Values are
+// decoded JSON, so numbers are float64.
+func validateJSONSchema(schema map[string]any, value any, path string) []string {
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types, ok := schema["type"]; ok {
+		var names []string
+		switch types := types.(type) {
+		case string:
+			names = []string{types}
+		case []any:
+			for _, name := range types {
+				names = append(names, name.(string))
+			}
+		}
+		if !slices.Contains(names, jsonTypeOf(value)) && !(jsonTypeOf(value) == "integer" && slices.Contains(names, "number")) {
+			fail("expected type %v, got %s", names, jsonTypeOf(value))
+			return errs
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(v any) bool { return reflect.DeepEqual(v, value) }) {
+		fail("%v is not one of %v", value, enum)
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && !slices.ContainsFunc(anyOf, func(sub any) bool {
+		return len(validateJSONSchema(sub.(map[string]any), value, path)) == 0
+	}) {
+		fail("%v matches none of anyOf", value)
+	}
+
+	switch value := value.(type) {
+	case string:
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(value) {
+			fail("%q does not match %s", value, pattern)
+		}
+		if maxLength, ok := schema["maxLength"].(float64); ok && utf8.RuneCountInString(value) > int(maxLength) {
+			fail("%q is longer than %v", value, maxLength)
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				fail("%q is not a date-time", value)
+			}
+		case "date":
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				fail("%q is not a date", value)
+			}
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && value < minimum {
+			fail("%v is below %v", value, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && value > maximum {
+			fail("%v is above %v", value, maximum)
+		}
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := value[name.(string)]; !ok {
+				fail("missing required property %s", name)
+			}
+		}
+		for name, v := range value {
+			if sub, ok := properties[name].(map[string]any); ok {
+				// Read-only properties are sent by the server, not written
+				if sub["readOnly"] == true {
+					fail("property %s is read-only", name)
+				}
+				errs = append(errs, validateJSONSchema(sub, v, path+"."+name)...)
+			} else if schema["additionalProperties"] == false {
+				fail("unknown property %s", name)
+			}
+		}
+	}
+	return errs
+}
+
+// jsonTypeOf returns the JSON Schema type name of a decoded JSON value
+func jsonTypeOf(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func TestSchemaEndpoint_JSONSchema(t *testing.T) {
+	handler := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false, "max_length": 20},
+			{"name": "status", "type": "string", "nullable": true, "enum": []string{"draft", "published"}},
+			{"name": "rank", "type": "integer", "nullable": false, "min": 1, "max": 5},
+			{"name": "price", "type": "decimal", "nullable": true},
+			{"name": "due", "type": "datetime", "nullable": true},
+			{"name": "done", "type": "boolean", "nullable": false},
+			{"name": "meta", "type": "json", "nullable": true},
+		},
+	}).data
+
+	w := doDataAction(t, handler.Schema, http.MethodGet, "/notes:schema?format=jsonschema", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+	if doc["$schema"] != "https://json-schema.org/draft/2020-12/schema" || doc["title"] != "notes" {
+		t.Errorf("expected a draft 2020-12 schema titled notes, got %s", w.Body.String())
+	}
+
+	// Records Moon accepts validate against the schema, and records it rejects do not
+	records := []struct {
+		data     string
+		accepted bool
+	}{
+		{`{"title": "minimal", "rank": 1, "done": false}`, true},
+		{`{"title": "full", "status": "draft", "rank": 5, "price": "-10.50", "due": "2024-06-01T10:00:00+02:00", "done": true, "meta": {"tags": ["a"]}}`, true},
+		{`{"title": "nulls", "status": null, "rank": 3, "price": null, "due": "2024-06-01", "done": true, "meta": null}`, true},
+		{`{"title": "missing rank", "done": true}`, false},
+		{`{"title": "unknown", "rank": 1, "done": true, "color": "red"}`, false},
+		{`{"title": "too long for the title column", "rank": 1, "done": true}`, false},
+		{`{"title": "rank", "rank": 6, "done": true}`, false},
+		{`{"title": "status", "status": "archived", "rank": 1, "done": true}`, false},
+		{`{"title": "price", "rank": 1, "done": true, "price": "1.234"}`, false},
+		{`{"title": "due", "rank": 1, "done": true, "due": 20240601}`, false},
+		{`{"id": "01ARZ3NDEKTSV4RRFFQ69G5FAV", "title": "id", "rank": 1, "done": true}`, false},
+	}
+	for _, record := range records {
+		var data map[string]any
+		json.Unmarshal([]byte(record.data), &data)
+
+		w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": data})
+		if accepted := w.Code == http.StatusCreated; accepted != record.accepted {
+			t.Errorf("%s: expected accepted=%v, got %d: %s", record.data, record.accepted, w.Code, w.Body.String())
+		}
+
+		errs := validateJSONSchema(doc, data, "data")
+		if valid := len(errs) == 0; valid != record.accepted {
+			t.Errorf("%s: expected valid=%v against the schema, got %v", record.data, record.accepted, errs)
+		}
+	}
+
+	w = doDataAction(t, handler.Schema, http.MethodGet, "/notes:schema?format=yaml", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupSequenceTest creates a notes collection with expose_sequence and ten
+// records titled note-1 to note-10 in insertion order
+func setupSequenceTest(t *testing.T) (database.Driver, *CollectionsHandler, *DataHandler) {
+	t.Helper()
+	records := make([]map[string]any, 10)
+	for i := range records {
+		records[i] = map[string]any{"title": fmt.Sprintf("note-%d", i+1)}
+	}
+	nt := setupNotesTest(t, map[string]any{
+		"columns":         []map[string]any{{"name": "title", "type": "string", "nullable": false}},
+		"expose_sequence": true,
+	}, records...)
+	return nt.driver, nt.collections, nt.data
+}
+
+func TestSequence_IncrementalSync(t *testing.T) {
+	_, _, handler := setupSequenceTest(t)
+
+	titles, resp := listTitles(t, handler, "/notes:list?seq[gt]=5&sort=seq")
+	want := []string{"note-6", "note-7", "note-8", "note-9", "note-10"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, titles)
+	}
+	for i, record := range resp.Data {
+		if record["seq"] != float64(i+6) {
+			t.Errorf("expected seq %d for %s, got %v", i+6, record["title"], record["seq"])
+		}
+		if _, ok := record["pkid"]; ok {
+			t.Errorf("expected pkid not to be exposed under its own name, got %v", record)
+		}
+	}
+
+	// Descending order, field selection and cursor pages follow seq too
+	titles, resp = listTitles(t, handler, "/notes:list?sort=-seq&fields=seq&limit=3")
+	if len(resp.Data) != 3 || resp.Data[0]["seq"] != float64(10) || resp.NextCursor == nil {
+		t.Fatalf("expected seq 10 first with a next cursor, got %+v", resp)
+	}
+	_, resp = listTitles(t, handler, "/notes:list?sort=-seq&fields=seq&limit=3&after="+*resp.NextCursor)
+	if len(resp.Data) != 3 || resp.Data[0]["seq"] != float64(7) {
+		t.Errorf("expected the second page to start at seq 7, got %+v", resp.Data)
+	}
+
+	// :get returns seq with the record
+	w := doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+resp.Data[0]["id"].(string), nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"seq":7`) {
+		t.Errorf("expected seq in :get, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSequence_ReadOnly(t *testing.T) {
+	_, _, handler := setupSequenceTest(t)
+
+	w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": "x", "seq": 99}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "validation_read_only_field") {
+		t.Errorf("expected seq rejected as read-only on create, got %d %s", w.Code, w.Body.String())
+	}
+
+	_, resp := listTitles(t, handler, "/notes:list?limit=1")
+	w = doDataAction(t, handler.Update, http.MethodPost, "/notes:update", map[string]any{"id": resp.Data[0]["id"], "data": map[string]any{"seq": 99}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "validation_read_only_field") {
+		t.Errorf("expected seq rejected as read-only on update, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSequence_Schema(t *testing.T) {
+	_, _, handler := setupSequenceTest(t)
+
+	w := doDataAction(t, handler.Schema, http.MethodGet, "/notes:schema", nil)
+	var resp SchemaResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	found := false
+	for _, field := range resp.Fields {
+		if field.Name == "seq" {
+			found = field.Type == "integer" && field.Readonly && !field.Nullable
+		}
+	}
+	if !found {
+		t.Errorf("expected seq listed as a read-only integer, got %+v", resp.Fields)
+	}
+}
+
+func TestSequence_Disabled(t *testing.T) {
+	_, collections, handler := setupSequenceTest(t)
+
+	w := postCollections(collections.Update, map[string]any{"name": "notes", "expose_sequence": false})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", w.Code, w.Body.String())
+	}
+
+	w = doDataAction(t, handler.List, http.MethodGet, "/notes:list?seq[gt]=5", nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "expose_sequence") {
+		t.Errorf("expected 400 pointing at expose_sequence, got %d %s", w.Code, w.Body.String())
+	}
+	_, resp := listTitles(t, handler, "/notes:list")
+	if _, ok := resp.Data[0]["seq"]; ok {
+		t.Errorf("expected no seq without expose_sequence, got %v", resp.Data[0])
+	}
+
+	// A seq column would be shadowed by the field
+	w = postCollections(collections.Update, map[string]any{
+		"name":            "notes",
+		"add_columns":     []map[string]any{{"name": "seq", "type": "integer", "nullable": true}},
+		"expose_sequence": true,
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a seq column with expose_sequence, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSequence_RequiresPKID(t *testing.T) {
+	driver, collections, _ := setupSequenceTest(t)
+
+	// Tables Moon did not create may lack the pkid column
+	if _, err := driver.Exec(context.Background(), "CREATE TABLE legacy (id TEXT PRIMARY KEY, note TEXT)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	collections.registry.Set(&registry.Collection{Name: "legacy", Columns: []registry.Column{{Name: "note", Type: registry.TypeString, Nullable: true}}})
+
+	w := postCollections(collections.Update, map[string]any{"name": "legacy", "expose_sequence": true})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "pkid") {
+		t.Errorf("expected 400 for a table without pkid, got %d %s", w.Code, w.Body.String())
+	}
+	if collection, _ := collections.registry.Get("legacy"); collection.ExposeSequence {
+		t.Error("expected expose_sequence to stay off")
+	}
+}
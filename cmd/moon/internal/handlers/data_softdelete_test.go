@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupSoftDeleteIntegrationTest creates a soft-delete notes collection through collections:create
+func setupSoftDeleteIntegrationTest(t *testing.T) (database.Driver, *registry.SchemaRegistry, *DataHandler) {
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	reg := registry.NewSchemaRegistry()
+	body, _ := json.Marshal(map[string]any{
+		"name":        "notes",
+		"soft_delete": true,
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "views", "type": "integer", "nullable": true},
+		},
+	})
+	w := httptest.NewRecorder()
+	NewCollectionsHandler(driver, reg).Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+	}
+
+	return driver, reg, NewDataHandler(driver, reg, testConfig())
+}
+
+func TestBuildDestroyQuery(t *testing.T) {
+	hard := &registry.Collection{Name: "notes"}
+	soft := &registry.Collection{Name: "notes", SoftDelete: true}
+	id := generateULID()
+
+	tests := []struct {
+		name       string
+		collection *registry.Collection
+		dialect    database.DialectType
+		wantQuery  string
+		wantArgs   int
+	}{
+		{"hard delete sqlite", hard, database.DialectSQLite, `DELETE FROM "notes" WHERE id = ?`, 1},
+		{"hard delete postgres", hard, database.DialectPostgres, `DELETE FROM "notes" WHERE id = $1`, 1},
+		{"soft delete sqlite", soft, database.DialectSQLite, `UPDATE "notes" SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, 2},
+		{"soft delete postgres", soft, database.DialectPostgres, `UPDATE "notes" SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := buildDestroyQuery(tt.collection, id, nil, tt.dialect)
+			if query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", query, tt.wantQuery)
+			}
+			if len(args) != tt.wantArgs {
+				t.Errorf("expected %d args, got %d", tt.wantArgs, len(args))
+			}
+			if args[len(args)-1] != id {
+				t.Errorf("expected id as last arg, got %v", args)
+			}
+		})
+	}
+
+	query, _ := buildRestoreQuery(soft, id, database.DialectPostgres)
+	if want := `UPDATE "notes" SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`; query != want {
+		t.Errorf("restore query = %q, want %q", query, want)
+	}
+}
+
+func TestDataHandler_SoftDelete_Integration(t *testing.T) {
+	driver, reg, handler := setupSoftDeleteIntegrationTest(t)
+	defer driver.Close()
+
+	collection, _ := reg.Get("notes")
+	if !collection.SoftDelete {
+		t.Fatal("expected collection to have soft delete enabled")
+	}
+	for _, col := range collection.Columns {
+		if col.Name == "deleted_at" {
+			t.Fatal("deleted_at must not be registered as a user column")
+		}
+	}
+
+	// Create three records
+	var ids []string
+	for _, title := range []string{"one", "two", "three"} {
+		w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": title, "views": 1}})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+		}
+		var resp CreateDataResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		ids = append(ids, resp.Data["id"].(string))
+	}
+
+	// Soft delete the first record
+	w := doDataAction(t, handler.Destroy, http.MethodPost, "/notes:destroy", map[string]any{"data": ids[0]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("destroy failed: %d %s", w.Code, w.Body.String())
+	}
+
+	// The row is kept with deleted_at set
+	var rows int
+	driver.QueryRow(context.Background(), "SELECT COUNT(*) FROM notes WHERE deleted_at IS NOT NULL").Scan(&rows)
+	if rows != 1 {
+		t.Errorf("expected 1 soft-deleted row, got %d", rows)
+	}
+
+	// Destroying again reports not found
+	w = doDataAction(t, handler.Destroy, http.MethodPost, "/notes:destroy", map[string]any{"data": ids[0]})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for repeated destroy, got %d", w.Code)
+	}
+
+	// Pagination totals exclude deleted rows
+	w = doDataAction(t, handler.List, http.MethodGet, "/notes:list?limit=1", nil)
+	var list DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if *list.Total != 2 {
+		t.Errorf("expected total 2, got %d", *list.Total)
+	}
+	if list.NextCursor == nil {
+		t.Error("expected next cursor with limit=1 and 2 live records")
+	}
+
+	w = doDataAction(t, handler.List, http.MethodGet, "/notes:list?include_deleted=true", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if *list.Total != 3 || len(list.Data) != 3 {
+		t.Errorf("expected 3 records with include_deleted, got total=%d len=%d", *list.Total, len(list.Data))
+	}
+
+	// Get hides the deleted record unless requested
+	w = doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+ids[0], nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for deleted record, got %d", w.Code)
+	}
+	w = doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+ids[0]+"&include_deleted=true", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with include_deleted, got %d", w.Code)
+	}
+	var got DataGetResponse
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Data["deleted_at"] == nil {
+		t.Error("expected deleted_at to be set on deleted record")
+	}
+
+	// Aggregations exclude deleted rows
+	aggHandler := NewAggregationHandler(driver, reg)
+	w = doDataAction(t, aggHandler.Count, http.MethodGet, "/notes:count", nil)
+	var agg AggregationResponse
+	json.Unmarshal(w.Body.Bytes(), &agg)
+	if agg.Value != float64(2) {
+		t.Errorf("expected count 2, got %v", agg.Value)
+	}
+
+	// Restore round trip
+	w = doDataAction(t, handler.Restore, http.MethodPost, "/notes:restore", map[string]any{"data": ids[0]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore failed: %d %s", w.Code, w.Body.String())
+	}
+	w = doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+ids[0], nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected restored record to be visible, got %d", w.Code)
+	}
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Data["deleted_at"] != nil {
+		t.Errorf("expected deleted_at to be cleared, got %v", got.Data["deleted_at"])
+	}
+
+	// Restoring a live record reports not found
+	w = doDataAction(t, handler.Restore, http.MethodPost, "/notes:restore", map[string]any{"data": ids[0]})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when restoring a live record, got %d", w.Code)
+	}
+
+	w = doDataAction(t, handler.List, http.MethodGet, "/notes:list", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if *list.Total != 3 {
+		t.Errorf("expected total 3 after restore, got %d", *list.Total)
+	}
+}
+
+func TestDataHandler_Restore_Batch_Integration(t *testing.T) {
+	driver, _, handler := setupSoftDeleteIntegrationTest(t)
+	defer driver.Close()
+
+	var ids []string
+	for _, title := range []string{"one", "two"} {
+		w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": title}})
+		var resp CreateDataResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		ids = append(ids, resp.Data["id"].(string))
+	}
+
+	w := doDataAction(t, handler.Destroy, http.MethodPost, "/notes:destroy?atomic=true", map[string]any{"data": ids})
+	if w.Code != http.StatusOK {
+		t.Fatalf("batch destroy failed: %d %s", w.Code, w.Body.String())
+	}
+
+	// Best-effort restore reports per-item status
+	missing := generateULID()
+	w = doDataAction(t, handler.Restore, http.MethodPost, "/notes:restore", map[string]any{"data": []string{ids[0], missing}})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Results[0].Status != BatchItemRestored || resp.Results[1].Status != BatchItemNotFound {
+		t.Errorf("unexpected statuses: %s, %s", resp.Results[0].Status, resp.Results[1].Status)
+	}
+
+	// Atomic restore rolls back when any record cannot be restored
+	w = doDataAction(t, handler.Restore, http.MethodPost, "/notes:restore?atomic=true", map[string]any{"data": []string{ids[1], ids[0]}})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	var deleted int
+	driver.QueryRow(context.Background(), "SELECT COUNT(*) FROM notes WHERE deleted_at IS NOT NULL").Scan(&deleted)
+	if deleted != 1 {
+		t.Errorf("expected rollback to leave 1 deleted row, got %d", deleted)
+	}
+}
+
+func TestDataHandler_Restore_RequiresSoftDelete(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	body, _ := json.Marshal(map[string]any{"data": generateULID()})
+	w := httptest.NewRecorder()
+	handler.Restore(w, httptest.NewRequest(http.MethodPost, "/products:restore", bytes.NewReader(body)), "products")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDataHandler_SoftDelete_Writes(t *testing.T) {
+	nt := setupNotesTest(t, map[string]any{
+		"soft_delete": true,
+		"columns": []map[string]any{
+			{"name": "slug", "type": "string", "nullable": false, "unique": true},
+			{"name": "title", "type": "string", "nullable": true},
+		},
+	}, map[string]any{"slug": "gone", "title": "old"})
+
+	var id string
+	nt.driver.QueryRow(context.Background(), "SELECT id FROM notes WHERE slug = 'gone'").Scan(&id)
+	if w := doDataAction(t, nt.data.Destroy, http.MethodPost, "/notes:destroy", map[string]any{"data": id}); w.Code != http.StatusOK {
+		t.Fatalf("destroy failed: %d %s", w.Code, w.Body.String())
+	}
+
+	// :update treats a soft-deleted record as missing, with or without a revision
+	w := doDataAction(t, nt.data.Update, http.MethodPost, "/notes:update", map[string]any{"id": id, "data": map[string]any{"title": "new"}})
+	assertErrorCode(t, w, http.StatusNotFound, apperrors.CodeRecordNotFound)
+	w = doDataAction(t, nt.data.Update, http.MethodPost, "/notes:update", map[string]any{"id": id, "data": map[string]any{"title": "new", "_rev": 1}})
+	assertErrorCode(t, w, http.StatusNotFound, apperrors.CodeRecordNotFound)
+	w = doDataAction(t, nt.data.Update, http.MethodPost, "/notes:update", map[string]any{"data": []map[string]any{{"id": id, "title": "new"}}})
+	if !strings.Contains(w.Body.String(), string(apperrors.CodeRecordNotFound)) {
+		t.Errorf("Expected the batch item to be not found, got %d %s", w.Code, w.Body.String())
+	}
+
+	// :upsert does not match it, and its key still conflicts on insert
+	w = doDataAction(t, nt.data.Upsert, http.MethodPost, "/notes:upsert", map[string]any{"key": "slug", "data": map[string]any{"slug": "gone", "title": "new"}})
+	assertErrorCode(t, w, http.StatusConflict, apperrors.CodeUniqueViolation)
+
+	var title string
+	var deletedAt *string
+	nt.driver.QueryRow(context.Background(), "SELECT title, deleted_at FROM notes WHERE id = ?", id).Scan(&title, &deletedAt)
+	if title != "old" || deletedAt == nil {
+		t.Errorf("Expected the deleted record to stay unchanged and deleted, got title %q deleted_at %v", title, deletedAt)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// CollectionStatsResponse represents the response of /{name}:stats
+type CollectionStatsResponse struct {
+	Collection    string               `json:"collection"`
+	RowCount      int64                `json:"row_count"`       // rows in the table, soft-deleted included
+	SizeBytes     *int64               `json:"size_bytes"`      // approximate size on disk; null when unknown
+	ColumnCount   int                  `json:"column_count"`    // user-defined columns
+	LastCreatedAt *string              `json:"last_created_at"` // created_at of the newest record
+	LastUpdatedAt *string              `json:"last_updated_at"` // most recent updated_at
+	Nulls         map[string]NullStats `json:"nulls"`           // per nullable column
+	SampleSize    int64                `json:"sample_size"`     // rows the null counts were computed over
+	Sampled       bool                 `json:"sampled"`         // true when the sample is smaller than the table
+}
+
+// NullStats counts the null values of a column in the sampled rows
+type NullStats struct {
+	Count int64   `json:"count"`
+	Ratio float64 `json:"ratio"` // count / sample_size; 0 for an empty sample
+}
+
+// Stats handles GET /{name}:stats, reporting the row count, size and null
+// value ratios of a collection. Null counts cover at most stats.sample_size
+// rows so that large tables are not scanned column by column.
+func (h *DataHandler) Stats(w http.ResponseWriter, r *http.Request, collectionName string) {
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, "Collection not found")
+		return
+	}
+
+	ctx := r.Context()
+	dialect := h.db.Dialect()
+	table, err := h.db.TableStats(ctx, collectionName)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read table statistics: %v", err))
+		return
+	}
+
+	response := CollectionStatsResponse{
+		Collection:  logicalName(r, collectionName),
+		RowCount:    table.RowCount,
+		SizeBytes:   table.SizeBytes,
+		ColumnCount: len(collection.Columns),
+		Nulls:       make(map[string]NullStats),
+	}
+
+	timestampSQL := fmt.Sprintf("SELECT MAX(%s), MAX(%s) FROM %s",
+		query.QuoteIdent(dialect, constants.CreatedAtColumn), query.QuoteIdent(dialect, constants.UpdatedAtColumn),
+		query.QuoteIdent(dialect, collectionName))
+	logQuery(ctx, "stats timestamps", timestampSQL, nil)
+	var lastCreated, lastUpdated sql.NullString
+	if err := h.db.QueryRow(ctx, timestampSQL).Scan(&lastCreated, &lastUpdated); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to read record timestamps: %v", err))
+		return
+	}
+	if lastCreated.Valid {
+		response.LastCreatedAt = &lastCreated.String
+	}
+	if lastUpdated.Valid {
+		response.LastUpdatedAt = &lastUpdated.String
+	}
+
+	columns := nullableColumns(collection)
+	nullSQL, args := buildNullStatsQuery(collectionName, columns, h.config.Stats.SampleSize, dialect)
+	logQuery(ctx, "stats nulls", nullSQL, args)
+	counts := make([]int64, len(columns)+1)
+	dest := make([]any, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	if err := h.db.QueryRow(ctx, nullSQL, args...).Scan(dest...); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to count null values: %v", err))
+		return
+	}
+
+	response.SampleSize = counts[0]
+	response.Sampled = response.SampleSize < table.RowCount
+	for i, column := range columns {
+		response.Nulls[column] = nullStats(counts[i+1], response.SampleSize)
+	}
+
+	writeResponse(w, r, http.StatusOK, response)
+}
+
+// nullableColumns returns the nullable, visible columns of a collection
+func nullableColumns(collection *registry.Collection) []string {
+	var columns []string
+	for _, col := range collection.Columns {
+		if col.Nullable && !col.Hidden {
+			columns = append(columns, col.Name)
+		}
+	}
+	return columns
+}
+
+// buildNullStatsQuery builds the query counting the sampled rows and the null
+// values of each column among them, in the order of columns
+func buildNullStatsQuery(table string, columns []string, sampleSize int, dialect database.DialectType) (string, []any) {
+	selects := []string{"COUNT(*)"}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = query.QuoteIdent(dialect, column)
+		selects = append(selects, fmt.Sprintf("COUNT(*) - COUNT(%s)", quoted[i]))
+	}
+	inner := "1"
+	if len(quoted) > 0 {
+		inner = strings.Join(quoted, ", ")
+	}
+	nullSQL := fmt.Sprintf("SELECT %s FROM (SELECT %s FROM %s LIMIT %s) sample",
+		strings.Join(selects, ", "), inner, query.QuoteIdent(dialect, table), query.Placeholder(dialect, 1))
+	return nullSQL, []any{sampleSize}
+}
+
+// nullStats returns the null count of a column and its share of the sample
+func nullStats(count, sampleSize int64) NullStats {
+	stats := NullStats{Count: count}
+	if sampleSize > 0 {
+		stats.Ratio = float64(count) / float64(sampleSize)
+	}
+	return stats
+}
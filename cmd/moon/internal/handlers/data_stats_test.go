@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+func collectionStats(t *testing.T, handler *DataHandler) CollectionStatsResponse {
+	t.Helper()
+	w := doDataAction(t, handler.Stats, http.MethodGet, "/notes:stats", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Stats failed: %d %s", w.Code, w.Body.String())
+	}
+	var resp CollectionStatsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestStats(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	for _, title := range []string{"unranked", "unranked too"} {
+		w := doDataAction(t, handler.Create, http.MethodPost, "/notes:create", map[string]any{"data": map[string]any{"title": title, "rank": nil}})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	resp := collectionStats(t, handler)
+	if resp.Collection != "notes" || resp.RowCount != 5 || resp.ColumnCount != 2 {
+		t.Errorf("expected 5 rows and 2 columns in notes, got %+v", resp)
+	}
+	if resp.SizeBytes == nil || *resp.SizeBytes <= 0 {
+		t.Errorf("expected a size from the SQLite pragmas, got %v", resp.SizeBytes)
+	}
+	if resp.LastCreatedAt == nil || resp.LastUpdatedAt == nil {
+		t.Errorf("expected the newest record timestamps, got %+v", resp)
+	}
+	if resp.SampleSize != 5 || resp.Sampled {
+		t.Errorf("expected every row to be examined, got sample_size %d sampled %v", resp.SampleSize, resp.Sampled)
+	}
+	// title is not nullable and has no entry
+	if len(resp.Nulls) != 1 || resp.Nulls["rank"] != (NullStats{Count: 2, Ratio: 0.4}) {
+		t.Errorf("expected 2 of 5 ranks to be null, got %+v", resp.Nulls)
+	}
+}
+
+func TestStats_Sampled(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	handler.config.Stats.SampleSize = 2
+
+	resp := collectionStats(t, handler)
+	if resp.RowCount != 3 || resp.SampleSize != 2 || !resp.Sampled {
+		t.Fatalf("expected 2 of 3 rows sampled, got %+v", resp)
+	}
+	if rank := resp.Nulls["rank"]; rank != (NullStats{}) {
+		t.Errorf("expected no null ranks in the sample, got %+v", rank)
+	}
+}
+
+func TestStats_EmptyCollection(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	if w := doDataAction(t, handler.Destroy, http.MethodPost, "/notes:destroy", map[string]any{"where": map[string]any{"rank": map[string]any{"gte": 1}}}); w.Code != http.StatusOK {
+		t.Fatalf("Destroy failed: %d %s", w.Code, w.Body.String())
+	}
+
+	resp := collectionStats(t, handler)
+	if resp.RowCount != 0 || resp.SampleSize != 0 || resp.LastCreatedAt != nil {
+		t.Errorf("expected empty stats, got %+v", resp)
+	}
+	if rank := resp.Nulls["rank"]; rank != (NullStats{}) {
+		t.Errorf("expected a zero ratio for an empty sample, got %+v", rank)
+	}
+}
+
+func TestBuildNullStatsQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		dialect database.DialectType
+		want    string
+	}{
+		{"sqlite", []string{"rank", "note"}, database.DialectSQLite,
+			`SELECT COUNT(*), COUNT(*) - COUNT("rank"), COUNT(*) - COUNT("note") FROM (SELECT "rank", "note" FROM "notes" LIMIT ?) sample`},
+		{"postgres", []string{"rank"}, database.DialectPostgres,
+			`SELECT COUNT(*), COUNT(*) - COUNT("rank") FROM (SELECT "rank" FROM "notes" LIMIT $1) sample`},
+		{"mysql", []string{"rank"}, database.DialectMySQL,
+			"SELECT COUNT(*), COUNT(*) - COUNT(`rank`) FROM (SELECT `rank` FROM `notes` LIMIT ?) sample"},
+		{"no nullable columns", nil, database.DialectSQLite,
+			`SELECT COUNT(*) FROM (SELECT 1 FROM "notes" LIMIT ?) sample`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := buildNullStatsQuery("notes", tt.columns, 100, tt.dialect)
+			if sql != tt.want {
+				t.Errorf("got  %s\nwant %s", sql, tt.want)
+			}
+			if len(args) != 1 || args[0] != 100 {
+				t.Errorf("expected the sample size as the only arg, got %v", args)
+			}
+		})
+	}
+}
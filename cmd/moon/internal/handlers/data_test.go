@@ -23,6 +23,16 @@ func testConfig() *config.AppConfig {
 		Batch: config.BatchConfig{
 			MaxSize:         50,
 			MaxPayloadBytes: 2097152, // 2 MB
+			ImportChunkSize: 500,
+			MaxImportBytes:  104857600, // 100 MB
+			MaxTransactOps:  50,
+		},
+		API: config.APIConfig{
+			MaxBulkDelete: 1000,
+			MaxPageOffset: 10000,
+		},
+		Stats: config.StatsConfig{
+			SampleSize: 10000,
 		},
 	}
 }
@@ -148,6 +158,10 @@ func (m *mockDataDriver) TableExists(ctx context.Context, tableName string) (boo
 	return false, nil
 }
 
+func (m *mockDataDriver) TableStats(ctx context.Context, tableName string) (*database.TableStats, error) {
+	return &database.TableStats{}, nil
+}
+
 // mockResult implements sql.Result
 type mockResult struct {
 	lastInsertID int64
@@ -684,7 +698,7 @@ func TestDataHandler_Create_UnknownField(t *testing.T) {
 	}
 }
 
-func TestDataHandler_Create_IgnoresClientProvidedULID(t *testing.T) {
+func TestDataHandler_Create_RejectsClientProvidedULID(t *testing.T) {
 	reg := registry.NewSchemaRegistry()
 	collection := &registry.Collection{
 		Name: "products",
@@ -695,21 +709,20 @@ func TestDataHandler_Create_IgnoresClientProvidedULID(t *testing.T) {
 	}
 	reg.Set(collection)
 
-	var capturedArgs []any
+	inserted := false
 	driver := &mockDataDriver{
 		dialect: database.DialectSQLite,
 		execFunc: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
-			capturedArgs = args
+			inserted = true
 			return mockResult{lastInsertID: 42, rowsAffected: 1}, nil
 		},
 	}
 	handler := NewDataHandler(driver, reg, testConfig())
 
-	// Client attempts to provide an ID
-	clientProvidedID := "01AAAAAAAAAAAAAAAAAAAAAAA"
+	// Client attempts to provide an ID on a collection of server-generated ULIDs
 	reqBody := CreateDataRequest{
 		Data: map[string]any{
-			"id":    clientProvidedID,
+			"id":    "01ARZ3NDEKTSV4RRFFQ69G5FAV",
 			"name":  "Test Product",
 			"price": 19,
 		},
@@ -721,39 +734,14 @@ func TestDataHandler_Create_IgnoresClientProvidedULID(t *testing.T) {
 
 	handler.Create(w, req, "products")
 
-	if w.Code != http.StatusCreated {
-		t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
-	}
-
-	var response CreateDataResponse
-	json.NewDecoder(w.Body).Decode(&response)
-
-	// Verify the response contains a ULID
-	idStr, ok := response.Data["id"].(string)
-	if !ok {
-		t.Fatalf("expected id to be string, got %T", response.Data["id"])
-	}
-	if len(idStr) != 26 {
-		t.Errorf("expected ULID (26 chars), got %d chars: %v", len(idStr), idStr)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
-
-	// Verify the server-generated ID is different from the client-provided one
-	if idStr == clientProvidedID {
-		t.Errorf("server should generate a new ID, not use client-provided value")
+	if !strings.Contains(w.Body.String(), "read-only") {
+		t.Errorf("expected a read-only field error, got %s", w.Body.String())
 	}
-
-	// Verify the INSERT query used the server-generated ID, not the client-provided one
-	if len(capturedArgs) > 0 {
-		insertedID, ok := capturedArgs[0].(string)
-		if !ok {
-			t.Fatalf("expected first arg to be ID string, got %T", capturedArgs[0])
-		}
-		if insertedID == clientProvidedID {
-			t.Errorf("INSERT should use server-generated ID, not client-provided value")
-		}
-		if insertedID != idStr {
-			t.Errorf("INSERT ID (%s) should match response ID (%s)", insertedID, idStr)
-		}
+	if inserted {
+		t.Error("expected no INSERT for a rejected id")
 	}
 }
 
@@ -781,6 +769,9 @@ func TestDataHandler_Integration_SQLite(t *testing.T) {
 	createTableSQL := `
 		CREATE TABLE products (
 			id TEXT PRIMARY KEY,
+			created_at TEXT,
+			updated_at TEXT,
+			_rev INTEGER NOT NULL DEFAULT 1,
 			name TEXT NOT NULL,
 			price REAL NOT NULL
 		)
@@ -977,6 +968,9 @@ func TestMapOperatorToSQL(t *testing.T) {
 		{"lte", "<="},
 		{"like", "LIKE"},
 		{"in", "IN"},
+		{"isnull", "IS NULL"},
+		{"notnull", "IS NOT NULL"},
+		{"between", "BETWEEN"},
 	}
 
 	for _, tt := range tests {
@@ -1326,7 +1320,7 @@ func TestBuildOrderBy(t *testing.T) {
 				{column: "price", direction: "ASC"},
 			},
 			dialect:  database.DialectSQLite,
-			expected: "price ASC",
+			expected: `"price" ASC`,
 			wantErr:  false,
 		},
 		{
@@ -1335,7 +1329,7 @@ func TestBuildOrderBy(t *testing.T) {
 				{column: "price", direction: "DESC"},
 			},
 			dialect:  database.DialectSQLite,
-			expected: "price DESC",
+			expected: `"price" DESC`,
 			wantErr:  false,
 		},
 		{
@@ -1363,7 +1357,7 @@ func TestBuildOrderBy(t *testing.T) {
 				{column: "name", direction: "ASC"},
 			},
 			dialect:  database.DialectSQLite,
-			expected: "created_at DESC, name ASC",
+			expected: `"created_at" DESC, "name" ASC`,
 			wantErr:  false,
 		},
 		{
@@ -1372,7 +1366,7 @@ func TestBuildOrderBy(t *testing.T) {
 				{column: "id", direction: "DESC"},
 			},
 			dialect:  database.DialectSQLite,
-			expected: "id DESC",
+			expected: `"id" DESC`,
 			wantErr:  false,
 		},
 		{
@@ -1422,7 +1416,7 @@ func TestBuildSearchConditions_Basic(t *testing.T) {
 		},
 	}
 
-	sql, args := buildSearchConditions("laptop", collection, database.DialectSQLite)
+	sql, args := buildSearchConditions("laptop", collection, nil, query.MatchContains, database.DialectSQLite)
 
 	if sql == "" {
 		t.Error("expected non-empty SQL")
@@ -1452,7 +1446,7 @@ func TestBuildSearchConditions_NoTextColumns(t *testing.T) {
 		},
 	}
 
-	sql, _ := buildSearchConditions("test", collection, database.DialectSQLite)
+	sql, _ := buildSearchConditions("test", collection, nil, query.MatchContains, database.DialectSQLite)
 
 	if sql != "" {
 		t.Errorf("expected empty SQL for non-text columns, got %s", sql)
@@ -1546,7 +1540,7 @@ func TestParseFields(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
-			fields, err := parseFields(req, collection)
+			fields, _, err := parseFields(req, collection, nil)
 
 			if tt.wantErr {
 				if err == nil {
@@ -1592,6 +1586,9 @@ func TestDataHandler_CursorPagination(t *testing.T) {
 	createTableSQL := `
 CREATE TABLE test_pagination (
 id TEXT PRIMARY KEY,
+created_at TEXT,
+updated_at TEXT,
+_rev INTEGER NOT NULL DEFAULT 1,
 name TEXT NOT NULL
 )
 `
@@ -1725,6 +1722,9 @@ name TEXT NOT NULL
 		createSQL := `
 CREATE TABLE test_single (
 id TEXT PRIMARY KEY,
+created_at TEXT,
+updated_at TEXT,
+_rev INTEGER NOT NULL DEFAULT 1,
 name TEXT NOT NULL
 )
 `
@@ -1781,6 +1781,9 @@ name TEXT NOT NULL
 		createSQL := `
 CREATE TABLE test_empty (
 id TEXT PRIMARY KEY,
+created_at TEXT,
+updated_at TEXT,
+_rev INTEGER NOT NULL DEFAULT 1,
 name TEXT NOT NULL
 )
 `
@@ -1825,6 +1828,9 @@ name TEXT NOT NULL
 		createSQL := `
 CREATE TABLE test_exact (
 id TEXT PRIMARY KEY,
+created_at TEXT,
+updated_at TEXT,
+_rev INTEGER NOT NULL DEFAULT 1,
 name TEXT NOT NULL
 )
 `
@@ -1964,6 +1970,9 @@ func TestDataHandler_NoPkidExposure(t *testing.T) {
 		CREATE TABLE products (
 			pkid INTEGER PRIMARY KEY AUTOINCREMENT,
 			id TEXT NOT NULL UNIQUE,
+			created_at TEXT,
+			updated_at TEXT,
+			_rev INTEGER NOT NULL DEFAULT 1,
 			name TEXT NOT NULL,
 			price NUMERIC NOT NULL
 		)
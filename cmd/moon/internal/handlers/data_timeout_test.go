@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// blockingDriver returns a mock driver whose queries block until their
+// context is done and which reports the context error it saw
+func blockingDriver() (*mockDataDriver, chan error) {
+	seen := make(chan error, 1)
+	driver := &mockDataDriver{
+		dialect: database.DialectSQLite,
+		queryFunc: func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+			<-ctx.Done()
+			seen <- ctx.Err()
+			return nil, ctx.Err()
+		},
+	}
+	return driver, seen
+}
+
+// queryContextError returns the context error seen by the blocked query
+func queryContextError(t *testing.T, seen chan error, w *httptest.ResponseRecorder) error {
+	t.Helper()
+	select {
+	case err := <-seen:
+		return err
+	default:
+		t.Fatalf("expected the query to run, got %d: %s", w.Code, w.Body.String())
+		return nil
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name:    "products",
+		Columns: []registry.Column{{Name: "category", Type: registry.TypeString, Nullable: true}},
+	})
+
+	tests := []struct {
+		name   string
+		url    string
+		action func(*DataHandler, *AggregationHandler) func(http.ResponseWriter, *http.Request, string)
+	}{
+		{"list", "/products:list?total=false", func(d *DataHandler, _ *AggregationHandler) func(http.ResponseWriter, *http.Request, string) {
+			return d.List
+		}},
+		{"groupby", "/products:groupby?by=category", func(_ *DataHandler, a *AggregationHandler) func(http.ResponseWriter, *http.Request, string) {
+			return a.GroupBy
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver, seen := blockingDriver()
+			action := tt.action(NewDataHandler(driver, reg, testConfig()), NewAggregationHandler(driver, reg))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			w := httptest.NewRecorder()
+			action(w, httptest.NewRequest(http.MethodGet, tt.url, nil).WithContext(ctx), "products")
+
+			if err := queryContextError(t, seen, w); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected the query context to hit its deadline, got %v", err)
+			}
+			var resp struct {
+				Code apperrors.ErrorCode `json:"code"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if w.Code != http.StatusGatewayTimeout || resp.Code != apperrors.CodeQueryTimeout {
+				t.Errorf("expected 504 query_timeout, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// A client disconnect cancels the query but is not a timeout
+	t.Run("client_disconnect", func(t *testing.T) {
+		driver, seen := blockingDriver()
+		handler := NewDataHandler(driver, reg, testConfig())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		w := httptest.NewRecorder()
+		handler.List(w, httptest.NewRequest(http.MethodGet, "/products:list?total=false", nil).WithContext(ctx), "products")
+
+		if err := queryContextError(t, seen, w); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the query context to be canceled, got %v", err)
+		}
+		if w.Code == http.StatusGatewayTimeout {
+			t.Errorf("expected a canceled request not to be reported as a timeout, got %s", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDataHandler_RecordTimestamps_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+	ctx := context.Background()
+
+	// Create sets both timestamps to the same UTC time
+	body, _ := json.Marshal(CreateDataRequest{Data: map[string]any{"name": "Lamp", "price": 30}})
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/products:create", bytes.NewReader(body)), "products")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	id := created.Data["id"].(string)
+	createdAt, _ := created.Data["created_at"].(string)
+	if _, err := time.Parse(time.RFC3339, createdAt); err != nil {
+		t.Fatalf("expected RFC3339 created_at in response, got %v", created.Data["created_at"])
+	}
+	if created.Data["updated_at"] != createdAt {
+		t.Errorf("expected updated_at to equal created_at on create, got %v", created.Data["updated_at"])
+	}
+
+	// Client-supplied timestamps are rejected
+	body, _ = json.Marshal(CreateDataRequest{Data: map[string]any{"name": "Desk", "price": 10, "created_at": "2000-01-01T00:00:00Z"}})
+	w = httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/products:create", bytes.NewReader(body)), "products")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for client created_at, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Update bumps updated_at and leaves created_at untouched
+	const past = "2000-01-01T00:00:00Z"
+	if _, err := driver.Exec(ctx, "UPDATE products SET updated_at = ? WHERE id = ?", past, id); err != nil {
+		t.Fatalf("failed to backdate record: %v", err)
+	}
+	body, _ = json.Marshal(map[string]any{"data": map[string]any{"id": id, "price": 35}})
+	w = httptest.NewRecorder()
+	handler.Update(w, httptest.NewRequest(http.MethodPost, "/products:update", bytes.NewReader(body)), "products")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var gotCreated, gotUpdated string
+	driver.QueryRow(ctx, "SELECT created_at, updated_at FROM products WHERE id = ?", id).Scan(&gotCreated, &gotUpdated)
+	if gotCreated != createdAt {
+		t.Errorf("expected created_at %s to be unchanged, got %s", createdAt, gotCreated)
+	}
+	if gotUpdated == past {
+		t.Error("expected updated_at to be bumped by update")
+	}
+
+	// Timestamps are returned by get and usable in sort and filter
+	w = httptest.NewRecorder()
+	handler.List(w, httptest.NewRequest(http.MethodGet, "/products:list?sort=-created_at&created_at[gte]=2001-01-01T00:00:00Z", nil), "products")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var list DataListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(list.Data))
+	}
+	if list.Data[0]["created_at"] != createdAt || list.Data[0]["updated_at"] != gotUpdated {
+		t.Errorf("unexpected timestamps in list response: %v", list.Data[0])
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// listTotal lists notes and returns the raw total of the response
+func listTotal(t *testing.T, handler *DataHandler, url string) json.RawMessage {
+	t.Helper()
+	w := doDataAction(t, handler.List, http.MethodGet, url, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("List %s failed: %d %s", url, w.Code, w.Body.String())
+	}
+	var resp map[string]json.RawMessage
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp["total"]
+}
+
+func TestList_Total(t *testing.T) {
+	handler := setupQueryTest(t)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"/notes:list", "4"},
+		{"/notes:list?total=true", "4"},
+		{"/notes:list?total=false", "null"},
+		{"/notes:list?total=0&status[eq]=pending", "null"},
+		{"/notes:list?total=1&status[eq]=pending", "2"},
+	}
+	for _, tt := range tests {
+		if got := string(listTotal(t, handler, tt.url)); got != tt.want {
+			t.Errorf("%s: expected total %s, got %s", tt.url, tt.want, got)
+		}
+	}
+
+	// Skipping the count leaves the page and its cursor intact
+	titles, page := listTitles(t, handler, "/notes:list?total=false&sort=title&limit=3")
+	if len(titles) != 3 || page.Total != nil || page.NextCursor == nil {
+		t.Errorf("expected 3 records, a null total and a cursor, got %v %v %v", titles, page.Total, page.NextCursor)
+	}
+	titles, _ = listTitles(t, handler, "/notes:list?total=false&sort=title&after="+*page.NextCursor)
+	if len(titles) != 1 || titles[0] != "d" {
+		t.Errorf("expected the second page to hold d, got %v", titles)
+	}
+
+	w := doDataAction(t, handler.List, http.MethodGet, "/notes:list?total=maybe", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid total, got %d %s", w.Code, w.Body.String())
+	}
+
+	// :query takes total in the body
+	if _, resp := queryTitles(t, handler, `{"total": false}`); resp.Total != nil {
+		t.Errorf("expected a null total from :query, got %d", *resp.Total)
+	}
+
+	// api.include_total_default turns the count off unless asked for
+	off := false
+	handler.config.API.IncludeTotalDefault = &off
+	if got := string(listTotal(t, handler, "/notes:list")); got != "null" {
+		t.Errorf("expected a null total by default, got %s", got)
+	}
+	if got := string(listTotal(t, handler, "/notes:list?total=true")); got != "4" {
+		t.Errorf("expected total 4 when asked for, got %s", got)
+	}
+	if _, resp := queryTitles(t, handler, `{"total": true}`); resp.Total == nil || *resp.Total != 4 {
+		t.Errorf("expected total 4 from :query when asked for, got %v", resp.Total)
+	}
+}
+
+// BenchmarkList_Total compares a filtered :list page on 100k records with and
+// without the total count
+func BenchmarkList_Total(b *testing.B) {
+	driver := newTestDriver(b)
+	ctx := context.Background()
+
+	reg := registry.NewSchemaRegistry()
+	w := postCollections(NewCollectionsHandler(driver, reg).Create, map[string]any{
+		"name": "notes",
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "stock", "type": "integer", "nullable": false},
+		},
+	})
+	if w.Code != http.StatusCreated {
+		b.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+	}
+
+	tx, err := driver.BeginTx(ctx)
+	if err != nil {
+		b.Fatalf("Failed to begin transaction: %v", err)
+	}
+	for i := range 100000 {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO notes (id, title, stock) VALUES (?, ?, ?)", generateULID(), fmt.Sprintf("note %d", i), i%10); err != nil {
+			b.Fatalf("Failed to insert: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatalf("Failed to commit: %v", err)
+	}
+
+	handler := NewDataHandler(driver, reg, testConfig())
+	for _, total := range []string{"true", "false"} {
+		b.Run("total="+total, func(b *testing.B) {
+			url := "/notes:list?stock[gte]=5&limit=50&total=" + total
+			for b.Loop() {
+				w := httptest.NewRecorder()
+				handler.List(w, httptest.NewRequest(http.MethodGet, url, nil), "notes")
+				if w.Code != http.StatusOK {
+					b.Fatalf("List failed: %d %s", w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/webhook"
+)
+
+// transactRefPrefix marks a string value resolved from the result of an
+// earlier operation: "$ref:<ref>.<field>"
+const transactRefPrefix = "$ref:"
+
+// TransactOperation is one operation of a batch:transact request
+type TransactOperation struct {
+	Collection string          `json:"collection"`
+	Action     string          `json:"action"`
+	Data       json.RawMessage `json:"data"`
+	Ref        string          `json:"ref,omitempty"`
+}
+
+// TransactResponse represents response for batch:transact. Results hold the
+// response of each operation, in order, shaped as its single-record action.
+type TransactResponse struct {
+	Results []any  `json:"results"`
+	Message string `json:"message"`
+}
+
+// transactError describes why an operation of a transaction failed
+type transactError struct {
+	HTTPStatus int
+	Code       apperrors.ErrorCode
+	Message    string
+	CurrentRev *int64
+	Conflict   *uniqueConflict // set for unique violations
+}
+
+// transactStep is a validated operation ready to run
+type transactStep struct {
+	table      string
+	collection *registry.Collection
+	action     string
+	data       map[string]any
+	ref        string
+}
+
+// transactDone is a committed operation, published once the transaction commits
+type transactDone struct {
+	table  string
+	action string
+	id     string
+	data   map[string]any
+}
+
+// Transact handles POST /batch:transact
+// Operations on any collections run in order in one transaction; values of the
+// form "$ref:<ref>.<field>" take a field of the record of an earlier operation.
+// The first failing operation rolls back all of them and is reported by index.
+func (h *DataHandler) Transact(w http.ResponseWriter, r *http.Request) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
+	// Check payload size (PRD-064)
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+
+	var ops []TransactOperation
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		writeBodyError(w, r, err, "request body must be an array of operations")
+		return
+	}
+	if len(ops) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "transaction must contain at least one operation")
+		return
+	}
+	if maxOps := h.config.Batch.MaxTransactOps; len(ops) > maxOps {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, fmt.Sprintf("transaction of %d operations exceeds limit of %d", len(ops), maxOps))
+		return
+	}
+
+	// Validate every operation before anything is written
+	steps := make([]transactStep, len(ops))
+	refs := make(map[string]bool)
+	for idx, op := range ops {
+		step, terr := h.prepareTransactStep(r, op, refs)
+		if terr != nil {
+			writeTransactError(w, r, idx, terr)
+			return
+		}
+		if op.Ref != "" {
+			refs[op.Ref] = true
+		}
+		steps[idx] = step
+	}
+
+	ctx := r.Context()
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	results := make([]any, len(steps))
+	done := make([]transactDone, len(steps))
+	records := make(map[string]map[string]any)
+	for idx, step := range steps {
+		data, err := resolveTransactRefs(step.data, records)
+		if err != nil {
+			writeTransactError(w, r, idx, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidInput, Message: err.Error()})
+			return
+		}
+		item, _ := data.(map[string]any)
+
+		var record map[string]any
+		var terr *transactError
+		switch step.action {
+		case webhook.ActionCreate:
+			record, results[idx], terr = h.transactCreate(r, tx, step, item)
+		case webhook.ActionUpdate:
+			record, results[idx], terr = h.transactUpdate(r, tx, step, item)
+		case webhook.ActionDestroy:
+			record, results[idx], terr = h.transactDestroy(r, tx, step, item)
+		}
+		if terr != nil {
+			writeTransactError(w, r, idx, terr)
+			return
+		}
+
+		done[idx] = transactDone{table: step.table, action: step.action, id: record["id"].(string)}
+		if step.action != webhook.ActionDestroy {
+			done[idx].data = record
+		}
+		if step.ref != "" {
+			records[step.ref] = record
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
+		return
+	}
+
+	for _, op := range done {
+		var data []map[string]any
+		if op.data != nil {
+			data = []map[string]any{op.data}
+		}
+		h.publish(ctx, op.table, op.action, []string{op.id}, data)
+	}
+	writeResponse(w, r, http.StatusOK, TransactResponse{
+		Results: results,
+		Message: fmt.Sprintf("%d operations committed successfully", len(results)),
+	})
+}
+
+// prepareTransactStep checks an operation's action, collection, write scope
+// and refs against the refs of the operations before it
+func (h *DataHandler) prepareTransactStep(r *http.Request, op TransactOperation, refs map[string]bool) (transactStep, *transactError) {
+	switch op.Action {
+	case webhook.ActionCreate, webhook.ActionUpdate, webhook.ActionDestroy:
+	default:
+		return transactStep{}, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeUnknownAction, Message: fmt.Sprintf("unknown action '%s'; supported actions: create, update, destroy", op.Action)}
+	}
+
+	ctx := r.Context()
+	notFound := &transactError{HTTPStatus: http.StatusNotFound, Code: apperrors.CodeCollectionNotFound, Message: fmt.Sprintf("collection '%s' not found", op.Collection)}
+	// Tenant tables are only reachable through their owner's logical names
+	if op.Collection == "" || h.config.Tenancy.Enabled && strings.Contains(op.Collection, constants.TenantSeparator) {
+		return transactStep{}, notFound
+	}
+	table := registry.TenantKey(middleware.GetTenant(ctx), op.Collection)
+	collection, exists := h.registry.Get(table)
+	if !exists {
+		return transactStep{}, notFound
+	}
+	if collection.Archived {
+		return transactStep{}, &transactError{HTTPStatus: http.StatusGone, Code: apperrors.CodeCollectionArchived, Message: fmt.Sprintf("collection '%s' is archived", op.Collection)}
+	}
+	if !middleware.HasScope(ctx, op.Collection, auth.ScopeWrite) {
+		return transactStep{}, &transactError{HTTPStatus: http.StatusForbidden, Code: apperrors.CodeInsufficientScope, Message: fmt.Sprintf("API key scope does not allow %s on collection '%s'", auth.ScopeWrite, op.Collection)}
+	}
+
+	if op.Ref != "" && refs[op.Ref] {
+		return transactStep{}, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidInput, Message: fmt.Sprintf("duplicate ref '%s'", op.Ref)}
+	}
+	var data map[string]any
+	if err := json.Unmarshal(op.Data, &data); err != nil || data == nil {
+		return transactStep{}, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidJSON, Message: "data must be an object"}
+	}
+	if err := normalizeFieldNames(data); err != nil {
+		return transactStep{}, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidInput, Message: err.Error()}
+	}
+	if err := checkTransactRefs(data, refs); err != nil {
+		return transactStep{}, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidInput, Message: err.Error()}
+	}
+
+	return transactStep{table: table, collection: collection, action: op.Action, data: data, ref: op.Ref}, nil
+}
+
+// parseTransactRef splits a "$ref:<ref>.<field>" value. ok is false for
+// values that are not refs.
+func parseTransactRef(value string) (ref, field string, ok bool, err error) {
+	rest, found := strings.CutPrefix(value, transactRefPrefix)
+	if !found {
+		return "", "", false, nil
+	}
+	ref, field, found = strings.Cut(rest, ".")
+	if !found || ref == "" || field == "" {
+		return "", "", true, fmt.Errorf("invalid ref '%s'; expected %s<ref>.<field>", value, transactRefPrefix)
+	}
+	return ref, field, true, nil
+}
+
+// checkTransactRefs rejects refs to operations that do not come earlier
+func checkTransactRefs(value any, refs map[string]bool) error {
+	switch v := value.(type) {
+	case string:
+		ref, _, ok, err := parseTransactRef(v)
+		if err != nil {
+			return err
+		}
+		if ok && !refs[ref] {
+			return fmt.Errorf("unknown ref '%s'; refs must name an earlier operation", ref)
+		}
+	case map[string]any:
+		for _, item := range v {
+			if err := checkTransactRefs(item, refs); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := checkTransactRefs(item, refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveTransactRefs returns a copy of value with each ref replaced by the
+// field of the referenced record
+func resolveTransactRefs(value any, records map[string]map[string]any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		ref, field, ok, err := parseTransactRef(v)
+		if err != nil || !ok {
+			return v, err
+		}
+		resolved, found := records[ref][field]
+		if !found {
+			return nil, fmt.Errorf("ref '%s' has no field '%s'", ref, field)
+		}
+		return resolved, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			resolved, err := resolveTransactRefs(item, records)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			resolved, err := resolveTransactRefs(item, records)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+// transactCreate inserts the record of a create operation
+func (h *DataHandler) transactCreate(r *http.Request, tx *sql.Tx, step transactStep, data map[string]any) (map[string]any, any, *transactError) {
+	if err := h.validateCreateFields(data, step.collection); err != nil {
+		return nil, nil, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeOf(err, apperrors.CodeValidationFailed), Message: err.Error()}
+	}
+	if terr := h.transactReferences(r, tx, step, data); terr != nil {
+		return nil, nil, terr
+	}
+
+	id := newRecordID(step.collection, data)
+	now := currentTimestamp()
+	query, values := buildInsertQuery(step.table, step.collection, data, id, now, h.db.Dialect())
+	logQuery(r.Context(), "transact create", query, values)
+	if _, err := tx.ExecContext(r.Context(), query, values...); err != nil {
+		return nil, nil, transactExecError(err, "failed to insert data", step.collection, data)
+	}
+
+	record := newRecordResponse(step.collection, data, id, now)
+	return record, CreateDataResponse{
+		Data:    record,
+		Message: fmt.Sprintf("Record created successfully with id %s", id),
+	}, nil
+}
+
+// transactUpdate applies an update operation to the record named by its id
+func (h *DataHandler) transactUpdate(r *http.Request, tx *sql.Tx, step transactStep, item map[string]any) (map[string]any, any, *transactError) {
+	id, terr := transactRecordID(step.collection, item)
+	if terr != nil {
+		return nil, nil, terr
+	}
+	rev, err := takeItemRevision(item)
+	if err != nil {
+		return nil, nil, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidRevision, Message: err.Error()}
+	}
+	if err := requireRevision(step.collection, rev); err != nil {
+		return nil, nil, &transactError{HTTPStatus: http.StatusPreconditionRequired, Code: apperrors.CodeRevisionRequired, Message: err.Error()}
+	}
+	if err := h.validateUpdateFields(item, step.collection); err != nil {
+		return nil, nil, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeOf(err, apperrors.CodeValidationFailed), Message: err.Error()}
+	}
+	if terr := h.transactReferences(r, tx, step, item); terr != nil {
+		return nil, nil, terr
+	}
+
+	// Build UPDATE query (explicit nulls become NULL assignments)
+	setClauses, values := buildUpdateSetClauses(item, step.collection, h.db.Dialect())
+	if len(setClauses) == 0 {
+		return nil, nil, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidInput, Message: "no fields to update"}
+	}
+	query, values := buildUpdateQuery(step.collection, setClauses, values, id, rev, h.db.Dialect())
+
+	ctx := r.Context()
+	logQuery(ctx, "transact update", query, values)
+	result, err := tx.ExecContext(ctx, query, values...)
+	if err != nil {
+		return nil, nil, transactExecError(err, "failed to update data", step.collection, item)
+	}
+	if terr := h.transactMiss(r, tx, step, result, id, rev); terr != nil {
+		return nil, nil, terr
+	}
+
+	record := map[string]any{"id": id}
+	for k, v := range item {
+		if k != "id" {
+			record[k] = v
+		}
+	}
+	if rev != nil {
+		record[constants.RevisionColumn] = *rev + 1
+	}
+	return record, UpdateDataResponse{
+		Data:    record,
+		Message: fmt.Sprintf("Record %s updated successfully", id),
+	}, nil
+}
+
+// transactReferences checks the reference columns of a record within the
+// transaction, so records created by earlier operations can be referenced
+func (h *DataHandler) transactReferences(r *http.Request, tx *sql.Tx, step transactStep, data map[string]any) *transactError {
+	var apiErr *apperrors.APIError
+	if errors.As(checkReferences(r.Context(), tx.QueryContext, h.registry, step.collection, data, h.db.Dialect()), &apiErr) {
+		return &transactError{HTTPStatus: apiErr.StatusCode, Code: apiErr.ErrorCode, Message: apiErr.Message}
+	}
+	return nil
+}
+
+// transactDestroy deletes (or soft deletes) the record named by its id
+func (h *DataHandler) transactDestroy(r *http.Request, tx *sql.Tx, step transactStep, item map[string]any) (map[string]any, any, *transactError) {
+	id, terr := transactRecordID(step.collection, item)
+	if terr != nil {
+		return nil, nil, terr
+	}
+	rev, err := takeItemRevision(item)
+	if err != nil {
+		return nil, nil, &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidRevision, Message: err.Error()}
+	}
+	if err := requireRevision(step.collection, rev); err != nil {
+		return nil, nil, &transactError{HTTPStatus: http.StatusPreconditionRequired, Code: apperrors.CodeRevisionRequired, Message: err.Error()}
+	}
+
+	query, args := buildDestroyQuery(step.collection, id, rev, h.db.Dialect())
+	ctx := r.Context()
+	logQuery(ctx, "transact destroy", query, args)
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, transactExecError(err, "failed to delete data", step.collection, nil)
+	}
+	if terr := h.transactMiss(r, tx, step, result, id, rev); terr != nil {
+		return nil, nil, terr
+	}
+
+	return map[string]any{"id": id}, DestroyDataResponse{
+		Message: fmt.Sprintf("Record %s deleted successfully", id),
+	}, nil
+}
+
+// transactRecordID returns the id an update or destroy operation names
+func transactRecordID(collection *registry.Collection, item map[string]any) (string, *transactError) {
+	idVal, hasID := item["id"]
+	if !hasID {
+		return "", &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeRequiredField, Message: "id is required"}
+	}
+	id, ok := idVal.(string)
+	if !ok {
+		return "", &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidType, Message: "id must be a string"}
+	}
+	if err := validateRecordID(collection, id); err != nil {
+		return "", &transactError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeOf(err, apperrors.CodeInvalidULID), Message: fmt.Sprintf("invalid id: %v", err)}
+	}
+	return id, nil
+}
+
+// transactMiss reports an update or destroy that matched no row as not found
+// or, for a stale revision, as a conflict
+func (h *DataHandler) transactMiss(r *http.Request, tx *sql.Tx, step transactStep, result sql.Result, id string, rev *int64) *transactError {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return &transactError{HTTPStatus: http.StatusInternalServerError, Code: apperrors.CodeDatabaseError, Message: fmt.Sprintf("failed to get rows affected: %v", err)}
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	miss := recordMissResult(r.Context(), tx.QueryRowContext, step.collection, 0, id, rev, h.db.Dialect())
+	status := http.StatusNotFound
+	switch miss.Status {
+	case BatchItemConflict:
+		status = http.StatusConflict
+	case BatchItemFailed:
+		status = http.StatusInternalServerError
+	}
+	return &transactError{HTTPStatus: status, Code: miss.ErrorCode, Message: miss.ErrorMessage, CurrentRev: miss.CurrentRev}
+}
+
+// transactExecError maps a failed statement to a unique violation or a
+// database error
+func transactExecError(err error, action string, collection *registry.Collection, data map[string]any) *transactError {
+	if conflict, ok := asUniqueConflict(err, collection, data); ok {
+		return &transactError{HTTPStatus: http.StatusConflict, Code: apperrors.CodeUniqueViolation, Message: conflict.Message, Conflict: conflict}
+	}
+	return &transactError{HTTPStatus: http.StatusInternalServerError, Code: apperrors.CodeDatabaseError, Message: fmt.Sprintf("%s: %v", action, err)}
+}
+
+// writeTransactError writes the error of the operation at idx, naming its index
+func writeTransactError(w http.ResponseWriter, r *http.Request, idx int, terr *transactError) {
+	body := errorBody(r, terr.HTTPStatus, terr.Code, fmt.Sprintf("operation %d: %s", idx, terr.Message))
+	apperrors.SetField(body, "index", idx)
+	if terr.CurrentRev != nil {
+		w.Header().Set(constants.HeaderETag, revisionETag(*terr.CurrentRev))
+		apperrors.SetField(body, "current_rev", *terr.CurrentRev)
+	}
+	if terr.Conflict != nil {
+		setConflictFields(body, terr.Conflict)
+	}
+	writeJSON(w, terr.HTTPStatus, body)
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupTransactTest creates a database with orders, unique by number, and
+// their order_items
+func setupTransactTest(t *testing.T) (database.Driver, *DataHandler) {
+	driver := newTestDriver(t)
+	ctx := context.Background()
+
+	for _, ddl := range []string{
+		`CREATE TABLE orders (
+			id TEXT PRIMARY KEY,
+			created_at TEXT,
+			updated_at TEXT,
+			_rev INTEGER NOT NULL DEFAULT 1,
+			number TEXT NOT NULL UNIQUE,
+			status TEXT
+		)`,
+		`CREATE TABLE order_items (
+			id TEXT PRIMARY KEY,
+			created_at TEXT,
+			updated_at TEXT,
+			_rev INTEGER NOT NULL DEFAULT 1,
+			order_id TEXT NOT NULL,
+			sku TEXT NOT NULL
+		)`,
+	} {
+		if _, err := driver.Exec(ctx, ddl); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "orders",
+		Columns: []registry.Column{
+			{Name: "number", Type: registry.TypeString, Nullable: false, Unique: true},
+			{Name: "status", Type: registry.TypeString, Nullable: true},
+		},
+	})
+	reg.Set(&registry.Collection{
+		Name: "order_items",
+		Columns: []registry.Column{
+			{Name: "order_id", Type: registry.TypeString, Nullable: false},
+			{Name: "sku", Type: registry.TypeString, Nullable: false},
+		},
+	})
+
+	return driver, NewDataHandler(driver, reg, testConfig())
+}
+
+func doTransact(t *testing.T, handler *DataHandler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/batch:transact", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	handler.Transact(w, req)
+	return w
+}
+
+// countRows returns the number of rows of table
+func countRows(t *testing.T, driver database.Driver, table string) int {
+	t.Helper()
+	var count int
+	if err := driver.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+	return count
+}
+
+// transactErrorIndex decodes the index of the failing operation of an error response
+func transactErrorIndex(t *testing.T, w *httptest.ResponseRecorder) int {
+	t.Helper()
+	var body struct {
+		Index *int `json:"index"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Index == nil {
+		t.Fatalf("expected an error naming the failing index, got %s", w.Body.String())
+	}
+	return *body.Index
+}
+
+func TestTransact_ResolvesRefs(t *testing.T) {
+	driver, handler := setupTransactTest(t)
+
+	w := doTransact(t, handler, `[
+		{"collection": "orders", "action": "create", "data": {"number": "A-1", "status": "new"}, "ref": "order"},
+		{"collection": "order_items", "action": "create", "data": {"order_id": "$ref:order.id", "sku": "widget"}, "ref": "item"},
+		{"collection": "orders", "action": "update", "data": {"id": "$ref:order.id", "status": "$ref:item.sku"}}
+	]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Results []struct {
+			Data map[string]any `json:"data"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("expected 3 results, got %s", w.Body.String())
+	}
+	orderID, _ := resp.Results[0].Data["id"].(string)
+	if orderID == "" || resp.Results[1].Data["order_id"] != orderID {
+		t.Errorf("expected the item to reference order %s, got %v", orderID, resp.Results[1].Data)
+	}
+	if resp.Results[2].Data["id"] != orderID || resp.Results[2].Data["status"] != "widget" {
+		t.Errorf("expected the update of order %s to status widget, got %v", orderID, resp.Results[2].Data)
+	}
+
+	var status string
+	driver.QueryRow(context.Background(), "SELECT status FROM orders WHERE id = ?", orderID).Scan(&status)
+	if status != "widget" {
+		t.Errorf("expected the stored status widget, got %q", status)
+	}
+
+	// Destroy in the same transaction as the records are created
+	w = doTransact(t, handler, `[
+		{"collection": "orders", "action": "create", "data": {"number": "A-2"}, "ref": "order"},
+		{"collection": "orders", "action": "destroy", "data": {"id": "$ref:order.id"}}
+	]`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "deleted successfully") {
+		t.Fatalf("expected the create and destroy to commit, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := countRows(t, driver, "orders"); n != 1 {
+		t.Errorf("expected 1 order, got %d", n)
+	}
+}
+
+func TestTransact_RollsBackOnFailure(t *testing.T) {
+	driver, handler := setupTransactTest(t)
+
+	w := doTransact(t, handler, `[
+		{"collection": "orders", "action": "create", "data": {"number": "A-1"}, "ref": "order"},
+		{"collection": "order_items", "action": "create", "data": {"order_id": "$ref:order.id", "sku": "widget"}},
+		{"collection": "orders", "action": "create", "data": {"number": "A-1"}},
+		{"collection": "order_items", "action": "create", "data": {"order_id": "$ref:order.id", "sku": "gadget"}}
+	]`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "unique_violation") {
+		t.Fatalf("expected 409 unique violation, got %d: %s", w.Code, w.Body.String())
+	}
+	if idx := transactErrorIndex(t, w); idx != 2 {
+		t.Errorf("expected the failure at index 2, got %d", idx)
+	}
+	for _, table := range []string{"orders", "order_items"} {
+		if n := countRows(t, driver, table); n != 0 {
+			t.Errorf("expected no %s after the rollback, got %d", table, n)
+		}
+	}
+
+	// A missing record fails the transaction the same way
+	w = doTransact(t, handler, `[
+		{"collection": "orders", "action": "create", "data": {"number": "A-1"}},
+		{"collection": "orders", "action": "update", "data": {"id": "01ARZ3NDEKTSV4RRFFQ69G5FAV", "status": "paid"}}
+	]`)
+	if w.Code != http.StatusNotFound || transactErrorIndex(t, w) != 1 {
+		t.Fatalf("expected 404 at index 1, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := countRows(t, driver, "orders"); n != 0 {
+		t.Errorf("expected no orders after the rollback, got %d", n)
+	}
+}
+
+func TestTransact_RejectsInvalidOperations(t *testing.T) {
+	driver, handler := setupTransactTest(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		index  int
+		want   string
+	}{
+		{
+			name: "unknown ref",
+			body: `[{"collection": "orders", "action": "create", "data": {"number": "A-1"}, "ref": "order"},
+				{"collection": "order_items", "action": "create", "data": {"order_id": "$ref:other.id", "sku": "widget"}}]`,
+			status: http.StatusBadRequest, index: 1, want: "unknown ref 'other'",
+		},
+		{
+			name: "ref to a later operation",
+			body: `[{"collection": "order_items", "action": "create", "data": {"order_id": "$ref:order.id", "sku": "widget"}},
+				{"collection": "orders", "action": "create", "data": {"number": "A-1"}, "ref": "order"}]`,
+			status: http.StatusBadRequest, index: 0, want: "unknown ref 'order'",
+		},
+		{
+			name: "ref without a field",
+			body: `[{"collection": "orders", "action": "create", "data": {"number": "A-1"}, "ref": "order"},
+				{"collection": "order_items", "action": "create", "data": {"order_id": "$ref:order", "sku": "widget"}}]`,
+			status: http.StatusBadRequest, index: 1, want: "invalid ref",
+		},
+		{
+			name: "duplicate ref",
+			body: `[{"collection": "orders", "action": "create", "data": {"number": "A-1"}, "ref": "order"},
+				{"collection": "orders", "action": "create", "data": {"number": "A-2"}, "ref": "order"}]`,
+			status: http.StatusBadRequest, index: 1, want: "duplicate ref",
+		},
+		{
+			name:   "unsupported action",
+			body:   `[{"collection": "orders", "action": "upsert", "data": {"number": "A-1"}}]`,
+			status: http.StatusBadRequest, index: 0, want: "unknown_action",
+		},
+		{
+			name: "missing collection",
+			body: `[{"collection": "orders", "action": "create", "data": {"number": "A-1"}},
+				{"collection": "invoices", "action": "create", "data": {"number": "A-1"}}]`,
+			status: http.StatusNotFound, index: 1, want: "collection_not_found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doTransact(t, handler, tt.body)
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("expected %d with %q, got %d: %s", tt.status, tt.want, w.Code, w.Body.String())
+			}
+			if idx := transactErrorIndex(t, w); idx != tt.index {
+				t.Errorf("expected the failure at index %d, got %d", tt.index, idx)
+			}
+		})
+	}
+	if n := countRows(t, driver, "orders"); n != 0 {
+		t.Errorf("expected rejected transactions to write nothing, got %d orders", n)
+	}
+
+	if w := doTransact(t, handler, `[]`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty transaction, got %d", w.Code)
+	}
+	ops := make([]string, handler.config.Batch.MaxTransactOps+1)
+	for i := range ops {
+		ops[i] = `{"collection": "orders", "action": "create", "data": {"number": "x"}}`
+	}
+	if w := doTransact(t, handler, "["+strings.Join(ops, ",")+"]"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 over the operation limit, got %d", w.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/expiry"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupTTLTest connects an in-memory database for collections with a ttl policy
+func setupTTLTest(t *testing.T) (database.Driver, *registry.SchemaRegistry, *CollectionsHandler) {
+	t.Helper()
+	driver := newTestDriver(t)
+	reg := registry.NewSchemaRegistry()
+	return driver, reg, NewCollectionsHandler(driver, reg)
+}
+
+// createTTLCollection sends collections:create for a sessions collection with
+// the given ttl policy and an expires_at datetime column
+func createTTLCollection(handler *CollectionsHandler, ttl any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]any{
+		"name": "sessions",
+		"ttl":  ttl,
+		"columns": []map[string]any{
+			{"name": "token", "type": "string", "nullable": false},
+			{"name": "expires_at", "type": "datetime", "nullable": true},
+		},
+	})
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/collections:create", bytes.NewReader(body)))
+	return w
+}
+
+func TestCollectionsCreate_TTLValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  any
+		want string
+	}{
+		{"field and duration", map[string]any{"field": "expires_at", "duration": "1h"}, "set either field"},
+		{"empty policy", map[string]any{}, "set either field"},
+		{"unknown field", map[string]any{"field": "ends_at"}, "not a column"},
+		{"field not datetime", map[string]any{"field": "token"}, "must be a datetime column"},
+		{"field from created_at", map[string]any{"field": "created_at"}, "not a column"},
+		{"from with field", map[string]any{"field": "expires_at", "from": "created_at"}, "from only applies"},
+		{"bad duration", map[string]any{"duration": "a month"}, "invalid duration"},
+		{"short duration", map[string]any{"duration": "10ms"}, "invalid duration"},
+		{"from not datetime", map[string]any{"duration": "1h", "from": "token"}, "must be a datetime column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, reg, handler := setupTTLTest(t)
+			w := createTTLCollection(handler, tt.ttl)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("Expected 400 containing %q, got %d %s", tt.want, w.Code, w.Body.String())
+			}
+			if reg.Exists("sessions") {
+				t.Error("Expected the collection not to be created")
+			}
+		})
+	}
+
+	t.Run("duration defaults from to created_at", func(t *testing.T) {
+		_, reg, handler := setupTTLTest(t)
+		if w := createTTLCollection(handler, map[string]any{"duration": "720h"}); w.Code != http.StatusCreated {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+		collection, _ := reg.Get("sessions")
+		if collection.TTL == nil || collection.TTL.Duration != "720h" || collection.TTL.From != "created_at" {
+			t.Errorf("Expected 720h from created_at, got %+v", collection.TTL)
+		}
+	})
+}
+
+func TestDataHandler_TTL_HidesExpiredRecords(t *testing.T) {
+	driver, reg, collections := setupTTLTest(t)
+	if w := createTTLCollection(collections, map[string]any{"field": "expires_at"}); w.Code != http.StatusCreated {
+		t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+	}
+	handler := NewDataHandler(driver, reg, testConfig())
+	aggregations := NewAggregationHandler(driver, reg)
+
+	epoch := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := epoch
+	defer expiry.SetClock(func() time.Time { return now })()
+
+	ids := map[string]string{}
+	for token, expires := range map[string]any{"short": epoch.Add(time.Hour).Format(time.RFC3339), "long": epoch.Add(48 * time.Hour).Format(time.RFC3339), "never": nil} {
+		body, _ := json.Marshal(map[string]any{"data": map[string]any{"token": token, "expires_at": expires}})
+		w := httptest.NewRecorder()
+		handler.Create(w, httptest.NewRequest(http.MethodPost, "/sessions:create", bytes.NewReader(body)), "sessions")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Create %s failed: %d %s", token, w.Code, w.Body.String())
+		}
+		var resp CreateDataResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		ids[token] = resp.Data["id"].(string)
+	}
+
+	call := func(action func(http.ResponseWriter, *http.Request, string), url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		action(w, httptest.NewRequest(http.MethodGet, url, nil), "sessions")
+		return w
+	}
+	listed := func() int {
+		var resp DataListResponse
+		json.Unmarshal(call(handler.List, "/sessions:list").Body.Bytes(), &resp)
+		return len(resp.Data)
+	}
+	counted := func() int64 {
+		var resp AggregationResponse
+		json.Unmarshal(call(aggregations.Count, "/sessions:count").Body.Bytes(), &resp)
+		return resp.Count
+	}
+	schema := func() SchemaResponse {
+		var resp SchemaResponse
+		json.Unmarshal(call(handler.Schema, "/sessions:schema").Body.Bytes(), &resp)
+		return resp
+	}
+	stored := func() int {
+		var n int
+		driver.QueryRow(context.Background(), "SELECT COUNT(*) FROM sessions").Scan(&n)
+		return n
+	}
+
+	if n, c, s := listed(), counted(), schema(); n != 3 || c != 3 || s.Total != 3 {
+		t.Fatalf("Expected three live records, got list %d, count %d, schema total %d", n, c, s.Total)
+	}
+	if ttl := schema().TTL; ttl == nil || ttl.Field != "expires_at" {
+		t.Errorf("Expected :schema to report the ttl policy, got %+v", ttl)
+	}
+
+	// Once expired, the record is hidden from every read before any sweep
+	now = epoch.Add(time.Hour)
+	if n, c, s := listed(), counted(), schema(); n != 2 || c != 2 || s.Total != 2 {
+		t.Errorf("Expected two live records, got list %d, count %d, schema total %d", n, c, s.Total)
+	}
+	if w := call(handler.Get, "/sessions:get?id="+ids["short"]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected the expired record to be not found, got %d %s", w.Code, w.Body.String())
+	}
+	if w := call(handler.Get, "/sessions:get?id="+ids["long"]); w.Code != http.StatusOK {
+		t.Errorf("Expected the live record, got %d %s", w.Code, w.Body.String())
+	}
+	if n := stored(); n != 3 {
+		t.Errorf("Expected the expired record to be stored until swept, got %d rows", n)
+	}
+
+	deleted := expiry.NewSweeper(driver, reg).Sweep(context.Background())
+	if deleted["sessions"] != 1 || stored() != 2 {
+		t.Errorf("Expected the sweep to delete the expired record, got %v and %d rows", deleted, stored())
+	}
+}
+
+func TestCollectionsUpdate_TTLColumn(t *testing.T) {
+	update := func(handler *CollectionsHandler, body map[string]any) *httptest.ResponseRecorder {
+		body["name"] = "sessions"
+		payload, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		handler.Update(w, httptest.NewRequest(http.MethodPost, "/collections:update", bytes.NewReader(payload)))
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+		want   string
+	}{
+		{"remove", map[string]any{"remove_columns": []string{"expires_at"}}, http.StatusBadRequest, "used by the ttl policy"},
+		{"modify type", map[string]any{"modify_columns": []map[string]any{{"name": "expires_at", "type": "string"}}}, http.StatusBadRequest, "ttl"},
+		{"rename", map[string]any{"rename_columns": []map[string]any{{"old_name": "expires_at", "new_name": "ends_at"}}}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, reg, handler := setupTTLTest(t)
+			if w := createTTLCollection(handler, map[string]any{"field": "expires_at"}); w.Code != http.StatusCreated {
+				t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+			}
+			w := update(handler, tt.body)
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("Expected %d containing %q, got %d %s", tt.status, tt.want, w.Code, w.Body.String())
+			}
+			collection, _ := reg.Get("sessions")
+			want := "expires_at"
+			if tt.status == http.StatusOK {
+				want = "ends_at"
+			}
+			if collection.TTL.Field != want {
+				t.Errorf("Expected the ttl policy to read %s, got %s", want, collection.TTL.Field)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// uniqueConflict is a unique violation traced back to the field of the
+// collection holding the duplicated value
+type uniqueConflict struct {
+	Field   string // column, or comma-separated columns of a composite index; "" when unknown
+	Value   any    // duplicated value; nil when unknown
+	Message string
+}
+
+// asUniqueConflict reports whether err is a unique violation and names the
+// field and value from the driver error, the unique columns and indexes of
+// the collection and the data that was written
+func asUniqueConflict(err error, collection *registry.Collection, data map[string]any) (*uniqueConflict, bool) {
+	v, ok := database.AsUniqueViolation(err)
+	if !ok {
+		return nil, false
+	}
+
+	columns := v.Columns
+	if len(columns) == 0 && v.Constraint != "" {
+		columns = constraintColumns(v.Constraint, collection)
+	}
+	if len(columns) == 0 {
+		return &uniqueConflict{Message: fmt.Sprintf("unique constraint violation: %v", err)}, true
+	}
+
+	conflict := &uniqueConflict{Field: strings.Join(columns, ",")}
+	if value, ok := data[columns[0]]; ok && len(columns) == 1 {
+		conflict.Value = value
+	} else if v.Value != "" {
+		conflict.Value = v.Value
+	}
+	conflict.Message = fmt.Sprintf("unique constraint violation: a record with this %s already exists", conflict.Field)
+	return conflict, true
+}
+
+// constraintColumns returns the columns of a unique constraint or index by
+// its name: a unique index of the collection, the primary key, or a unique
+// column under the names the dialects give it (the column itself on MySQL,
+// {table}_{column}_key on Postgres, {table}_{column}_unique and
+// idx_{table}_{column} when added by a schema change). The longest column
+// name wins, so work_email is not mistaken for email.
+func constraintColumns(constraint string, collection *registry.Collection) []string {
+	for _, idx := range collection.Indexes {
+		if idx.Unique && idx.Name == constraint {
+			return idx.Columns
+		}
+	}
+	if constraint == "PRIMARY" || strings.HasSuffix(constraint, "_pkey") {
+		return []string{"id"}
+	}
+
+	best := ""
+	for _, col := range collection.Columns {
+		if !col.Unique || len(col.Name) <= len(best) {
+			continue
+		}
+		if constraint == col.Name ||
+			strings.HasSuffix(constraint, "_"+col.Name+"_key") ||
+			strings.HasSuffix(constraint, "_"+col.Name+"_unique") ||
+			(strings.HasPrefix(constraint, "idx_") && strings.HasSuffix(constraint, "_"+col.Name)) {
+			best = col.Name
+		}
+	}
+	if best == "" {
+		return nil
+	}
+	return []string{best}
+}
+
+// writeUniqueConflict writes a 409 unique_violation naming the field and value
+func writeUniqueConflict(w http.ResponseWriter, r *http.Request, conflict *uniqueConflict) {
+	body := errorBody(r, http.StatusConflict, apperrors.CodeUniqueViolation, conflict.Message)
+	setConflictFields(body, conflict)
+	writeJSON(w, http.StatusConflict, body)
+}
+
+// setConflictFields adds the known field and value of a conflict to an error body
+func setConflictFields(body map[string]any, conflict *uniqueConflict) {
+	if conflict.Field != "" {
+		apperrors.SetField(body, "field", conflict.Field)
+	}
+	if conflict.Value != nil {
+		apperrors.SetField(body, "value", conflict.Value)
+	}
+}
+
+// uniqueFailure returns the failed batch item result for a write error
+func uniqueFailure(idx int, id string, err error, collection *registry.Collection, data map[string]any) BatchItemResult {
+	result := BatchItemResult{Index: idx, ID: id, Status: BatchItemFailed, ErrorCode: apperrors.CodeDatabaseError, ErrorMessage: err.Error()}
+	if conflict, ok := asUniqueConflict(err, collection, data); ok {
+		result.ErrorCode = apperrors.CodeUniqueViolation
+		result.ErrorMessage = conflict.Message
+		result.Field = conflict.Field
+	}
+	return result
+}
+
+// CheckUniqueRequest is the body of POST /{name}:checkunique
+type CheckUniqueRequest struct {
+	Field  string `json:"field"`
+	Values []any  `json:"values"`
+}
+
+// CheckUniqueResponse is the response of POST /{name}:checkunique
+type CheckUniqueResponse struct {
+	Field     string `json:"field"`
+	Existing  []any  `json:"existing"`  // values already taken
+	Available []any  `json:"available"` // values still free
+}
+
+// CheckUnique handles POST /{name}:checkunique
+// It reports which of the given values of a unique field are already taken,
+// so a form can validate many candidates before writing. Soft-deleted
+// records keep their values taken, as the constraint still covers them.
+func (h *DataHandler) CheckUnique(w http.ResponseWriter, r *http.Request, collectionName string) {
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+
+	var req CheckUniqueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	req.Field = snakeCase(req.Field)
+	col, err := uniqueColumn(req.Field, collection)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+	if len(req.Values) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "values must be a non-empty array")
+		return
+	}
+	if err := h.validateBatchSize(len(req.Values)); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, err.Error())
+		return
+	}
+	if slices.Contains(req.Values, nil) {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "values must not contain null")
+		return
+	}
+
+	dialect := h.db.Dialect()
+	placeholders := make([]string, len(req.Values))
+	args := make([]any, len(req.Values))
+	for i, value := range req.Values {
+		placeholders[i] = query.Placeholder(dialect, i+1)
+		args[i] = columnValue(col, value)
+	}
+	column := query.QuoteIdent(dialect, col.Name)
+	sqlQuery := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IN (%s)",
+		column, query.QuoteIdent(dialect, collectionName), column, strings.Join(placeholders, ", "))
+
+	ctx := r.Context()
+	logQuery(ctx, "checkunique", sqlQuery, args)
+	rows, err := h.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to check values: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var value any
+		if err := rows.Scan(&value); err != nil {
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to scan value: %v", err))
+			return
+		}
+		taken[uniqueKey(value)] = true
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to check values: %v", err))
+		return
+	}
+
+	// Values are reported as given, in request order
+	resp := CheckUniqueResponse{Field: col.Name, Existing: []any{}, Available: []any{}}
+	for i, value := range req.Values {
+		if taken[uniqueKey(args[i])] {
+			resp.Existing = append(resp.Existing, value)
+		} else {
+			resp.Available = append(resp.Available, value)
+		}
+	}
+	writeResponse(w, r, http.StatusOK, resp)
+}
+
+// uniqueColumn returns the column named field if it is unique on its own:
+// id, a unique column or the only column of a unique index
+func uniqueColumn(field string, collection *registry.Collection) (registry.Column, error) {
+	if field == "" {
+		return registry.Column{}, fmt.Errorf("field is required")
+	}
+	if field == "id" {
+		return registry.Column{Name: "id", Type: registry.TypeString}, nil
+	}
+	for _, col := range collection.Columns {
+		if col.Name != field {
+			continue
+		}
+		if col.Unique {
+			return col, nil
+		}
+		for _, idx := range collection.Indexes {
+			if idx.Unique && len(idx.Columns) == 1 && idx.Columns[0] == field {
+				return col, nil
+			}
+		}
+		return registry.Column{}, fmt.Errorf("field '%s' must be a unique column", field)
+	}
+	return registry.Column{}, fmt.Errorf("field '%s' not found in collection", field)
+}
+
+// uniqueKey compares a requested value with a stored one, as drivers return
+// text as []byte and JSON numbers decode as float64
+func uniqueKey(value any) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupUniqueTest adds a members collection with unique email and code
+// columns and one member, ada@example.com with code 1
+func setupUniqueTest(t *testing.T) *referenceTest {
+	t.Helper()
+	rt := setupReferenceTest(t)
+	body := map[string]any{"name": "members", "soft_delete": true, "columns": []map[string]any{
+		{"name": "email", "type": "string", "unique": true},
+		{"name": "code", "type": "integer", "unique": true},
+		{"name": "nickname", "type": "string", "nullable": true},
+	}}
+	if w := postCollections(rt.collections.Create, body); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+	}
+	if w := rt.do(t, rt.data.Create, "members", "/members:create", map[string]any{"data": map[string]any{"email": "ada@example.com", "code": 1}}); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create member: %d %s", w.Code, w.Body.String())
+	}
+	return rt
+}
+
+// conflictFields returns the code, field and value of a unique_violation response
+func conflictFields(w *httptest.ResponseRecorder) (string, string, any) {
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	code, _ := resp["code"].(string)
+	field, _ := resp["field"].(string)
+	return code, field, resp["value"]
+}
+
+func TestUniqueViolation_NamesFieldAndValue(t *testing.T) {
+	rt := setupUniqueTest(t)
+
+	w := rt.do(t, rt.data.Create, "members", "/members:create", map[string]any{"data": map[string]any{"email": "ada@example.com", "code": 2}})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if code, field, value := conflictFields(w); code != "unique_violation" || field != "email" || value != "ada@example.com" {
+		t.Errorf("expected unique_violation on email ada@example.com, got %s %s %v", code, field, value)
+	}
+
+	w = rt.do(t, rt.data.Create, "members", "/members:create", map[string]any{"data": map[string]any{"email": "bob@example.com", "code": 1}})
+	if code, field, value := conflictFields(w); code != "unique_violation" || field != "code" || value != float64(1) {
+		t.Errorf("expected unique_violation on code 1, got %d %s %s %v", w.Code, code, field, value)
+	}
+
+	// An update to a taken value names the field too
+	w = rt.do(t, rt.data.Create, "members", "/members:create", map[string]any{"data": map[string]any{"email": "bob@example.com", "code": 2}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create member: %d %s", w.Code, w.Body.String())
+	}
+	var created CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	w = rt.do(t, rt.data.Update, "members", "/members:update", map[string]any{"data": map[string]any{"id": created.Data["id"], "email": "ada@example.com"}})
+	if code, field, _ := conflictFields(w); w.Code != http.StatusConflict || code != "unique_violation" || field != "email" {
+		t.Errorf("expected 409 unique_violation on email, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Upsert keyed on code inserts a duplicate email
+	w = rt.do(t, rt.data.Upsert, "members", "/members:upsert", map[string]any{"key": "code", "data": map[string]any{"code": 3, "email": "ada@example.com"}})
+	if code, field, value := conflictFields(w); w.Code != http.StatusConflict || code != "unique_violation" || field != "email" || value != "ada@example.com" {
+		t.Errorf("expected upsert 409 unique_violation on email, got %d: %s", w.Code, w.Body.String())
+	}
+
+}
+
+func TestConstraintColumns(t *testing.T) {
+	collection := &registry.Collection{
+		Name: "users",
+		Columns: []registry.Column{
+			{Name: "email", Type: registry.TypeString, Unique: true},
+			{Name: "work_email", Type: registry.TypeString, Unique: true},
+			{Name: "name", Type: registry.TypeString},
+		},
+		Indexes: []registry.Index{{Name: "users_org_name", Columns: []string{"org", "name"}, Unique: true}},
+	}
+	tests := []struct {
+		constraint string
+		want       []string
+	}{
+		{"email", []string{"email"}},                        // MySQL inline UNIQUE
+		{"users_email_key", []string{"email"}},              // Postgres inline UNIQUE
+		{"users_work_email_key", []string{"work_email"}},    // longest column wins
+		{"users_work_email_unique", []string{"work_email"}}, // added by a schema change
+		{"idx_users_email", []string{"email"}},
+		{"users_org_name", []string{"org", "name"}},
+		{"PRIMARY", []string{"id"}},
+		{"users_pkey", []string{"id"}},
+		{"users_name_key", nil}, // name is not unique
+		{"other", nil},
+	}
+	for _, tt := range tests {
+		if got := constraintColumns(tt.constraint, collection); !slices.Equal(got, tt.want) {
+			t.Errorf("constraintColumns(%q) = %v, want %v", tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueViolation_BatchItemField(t *testing.T) {
+	rt := setupUniqueTest(t)
+
+	w := rt.do(t, rt.data.Create, "members", "/members:create", map[string]any{"data": []map[string]any{
+		{"email": "bob@example.com", "code": 2},
+		{"email": "ada@example.com", "code": 3},
+	}})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %s", w.Body.String())
+	}
+	if item := resp.Results[0]; item.Status != BatchItemCreated || item.Field != "" {
+		t.Errorf("expected item 0 created, got %+v", item)
+	}
+	if item := resp.Results[1]; item.Status != BatchItemFailed || item.ErrorCode != "unique_violation" || item.Field != "email" {
+		t.Errorf("expected item 1 to fail on email, got %+v", item)
+	}
+
+	// Atomic batches fail with the field of the first duplicate
+	w = rt.do(t, rt.data.Create, "members", "/members:create?atomic=true", map[string]any{"data": []map[string]any{
+		{"email": "carol@example.com", "code": 5},
+		{"email": "dan@example.com", "code": 1},
+	}})
+	if code, field, value := conflictFields(w); w.Code != http.StatusConflict || code != "unique_violation" || field != "code" || value != float64(1) {
+		t.Errorf("expected atomic 409 unique_violation on code, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCheckUnique(t *testing.T) {
+	rt := setupUniqueTest(t)
+
+	check := func(body map[string]any) (*httptest.ResponseRecorder, CheckUniqueResponse) {
+		w := rt.do(t, rt.data.CheckUnique, "members", "/members:checkunique", body)
+		var resp CheckUniqueResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, resp := check(map[string]any{"field": "email", "values": []any{"new@example.com", "ada@example.com", "ADA@example.com", "ada@example.com"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Field != "email" ||
+		!slices.Equal(resp.Existing, []any{"ada@example.com", "ada@example.com"}) ||
+		!slices.Equal(resp.Available, []any{"new@example.com", "ADA@example.com"}) {
+		t.Errorf("unexpected result: %s", w.Body.String())
+	}
+
+	// Numbers match stored integers; soft-deleted records keep their values
+	if w := rt.do(t, rt.data.Create, "members", "/members:create", map[string]any{"data": map[string]any{"email": "bob@example.com", "code": 2}}); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create member: %d %s", w.Code, w.Body.String())
+	}
+	var list DataListResponse
+	json.Unmarshal(rt.do(t, rt.data.List, "members", "/members:list?code[eq]=2", nil).Body.Bytes(), &list)
+	if w := rt.do(t, rt.data.Destroy, "members", "/members:destroy", map[string]any{"data": []any{list.Data[0]["id"]}}); w.Code >= http.StatusMultipleChoices {
+		t.Fatalf("Failed to delete member: %d %s", w.Code, w.Body.String())
+	}
+	w, resp = check(map[string]any{"field": "code", "values": []any{1, 2, 3}})
+	if w.Code != http.StatusOK || !slices.Equal(resp.Existing, []any{float64(1), float64(2)}) || !slices.Equal(resp.Available, []any{float64(3)}) {
+		t.Errorf("unexpected result for code: %d %s", w.Code, w.Body.String())
+	}
+
+	// id is unique too
+	if w, resp := check(map[string]any{"field": "id", "values": []any{list.Data[0]["id"], "missing"}}); w.Code != http.StatusOK || len(resp.Existing) != 1 {
+		t.Errorf("expected the id to exist, got %d: %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		body   map[string]any
+		status int
+		code   string
+	}{
+		{map[string]any{"field": "nickname", "values": []any{"x"}}, http.StatusBadRequest, "invalid_parameter"},
+		{map[string]any{"field": "missing", "values": []any{"x"}}, http.StatusBadRequest, "invalid_parameter"},
+		{map[string]any{"values": []any{"x"}}, http.StatusBadRequest, "invalid_parameter"},
+		{map[string]any{"field": "email", "values": []any{}}, http.StatusBadRequest, "validation_required_field"},
+		{map[string]any{"field": "email", "values": []any{"x", nil}}, http.StatusBadRequest, "invalid_input"},
+		{map[string]any{"field": "email", "values": make([]any, 51)}, http.StatusRequestEntityTooLarge, "batch_too_large"},
+	}
+	for _, tt := range tests {
+		if w, _ := check(tt.body); w.Code != tt.status || errorCode(w) != tt.code {
+			t.Errorf("%s: expected %d %s, got %d: %s", fmt.Sprint(tt.body["field"]), tt.status, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
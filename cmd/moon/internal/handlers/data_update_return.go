@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/expr"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// Update responses selected with ?return=; without it an update echoes the
+// submitted fields
+const (
+	updateReturnDiff = "diff" // the echo plus the old and new value of each changed field
+	updateReturnFull = "full" // the complete record, read back after the update
+)
+
+// FieldChange is the value of a field before and after an update
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// updateReturn is the response an update asks for with ?return=
+type updateReturn struct {
+	mode   string
+	hidden map[string]bool // columns left out of diffs and full records
+}
+
+// parseUpdateReturn reads ?return= of an update. Errors are
+// *apperrors.APIError values.
+func parseUpdateReturn(r *http.Request, collectionName string, collection *registry.Collection) (updateReturn, error) {
+	mode := r.URL.Query().Get("return")
+	switch mode {
+	case "":
+		return updateReturn{}, nil
+	case updateReturnDiff, updateReturnFull:
+	default:
+		return updateReturn{}, apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeInvalidParameter, "return must be diff or full")
+	}
+	hidden, err := responseHidden(r, collectionName, collection)
+	if err != nil {
+		return updateReturn{}, err
+	}
+	return updateReturn{mode: mode, hidden: hidden}, nil
+}
+
+// before reads the record an update is about to change when the response
+// diffs it, or returns nil. A missing record gives nil too and is reported
+// once the update matches nothing.
+func (ret updateReturn) before(ctx context.Context, queryFn queryFunc, collection *registry.Collection, id string, dialect database.DialectType) (map[string]any, error) {
+	if ret.mode != updateReturnDiff {
+		return nil, nil
+	}
+	return readRecord(ctx, queryFn, collection, id, nil, dialect)
+}
+
+// after completes the response of a successful update of data: the changes
+// from old for diff, or the record read back for full, which replaces the
+// echoed record. It returns the record to respond with, the echo when the
+// record cannot be read back, and the changes.
+func (ret updateReturn) after(ctx context.Context, queryFn queryFunc, collection *registry.Collection, id string, data, echo, old map[string]any, dialect database.DialectType) (map[string]any, map[string]FieldChange, error) {
+	switch ret.mode {
+	case updateReturnDiff:
+		return echo, updateChanges(collection, old, data, ret.hidden), nil
+	case updateReturnFull:
+		record, err := readRecord(ctx, queryFn, collection, id, ret.hidden, dialect)
+		if err != nil {
+			return echo, nil, err
+		}
+		if record == nil {
+			return echo, nil, fmt.Errorf("record %s not found after update", id)
+		}
+		return record, nil, nil
+	}
+	return echo, nil, nil
+}
+
+// readRecord returns a record by id as reads return it, leaving out the
+// hidden columns, or nil when it does not exist
+func readRecord(ctx context.Context, queryFn queryFunc, collection *registry.Collection, id string, hidden map[string]bool, dialect database.DialectType) (map[string]any, error) {
+	sqlQuery := fmt.Sprintf("SELECT * FROM %s WHERE id = %s", query.QuoteIdent(dialect, collection.Name), query.Placeholder(dialect, 1))
+	rows, err := queryFn(ctx, sqlQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record: %w", err)
+	}
+	defer rows.Close()
+
+	records, err := parseRows(rows, collection, hidden)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
+
+// updateChanges returns the fields whose values the update of data changed
+// in the old record, computed columns included, leaving out the hidden
+// columns. New values are compared as reads return them, so a decimal sent
+// as 12.5 does not differ from a stored "12.50" and a boolean from its
+// stored 0 or 1. An old record of nil gives no changes.
+func updateChanges(collection *registry.Collection, old, data map[string]any, hidden map[string]bool) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	if old == nil {
+		return changes
+	}
+	updated := maps.Clone(old)
+	for _, col := range collection.Columns {
+		if val, ok := data[col.Name]; ok {
+			updated[col.Name] = readValue(col, val)
+		}
+	}
+	for _, col := range collection.Columns {
+		if col.Computed == "" {
+			continue
+		}
+		e, err := expr.Parse(col.Computed)
+		if err != nil {
+			continue
+		}
+		for _, name := range e.Columns() {
+			if _, ok := data[name]; ok {
+				updated[col.Name] = computedValue(collection, col, updated)
+				break
+			}
+		}
+	}
+
+	for _, col := range collection.Columns {
+		if hidden[col.Name] || sameValue(old[col.Name], updated[col.Name]) {
+			continue
+		}
+		changes[col.Name] = FieldChange{From: old[col.Name], To: updated[col.Name]}
+	}
+	return changes
+}
+
+// readValue converts a written value to the form reads return for the column
+func readValue(col registry.Column, val any) any {
+	if val == nil {
+		return nil
+	}
+	switch col.Type {
+	case registry.TypeBoolean:
+		return convertToBoolean(val)
+	case registry.TypeDecimal:
+		return formatDecimal(val)
+	case registry.TypeDatetime:
+		return formatDatetime(val)
+	case registry.TypeJSON:
+		return columnValue(col, val)
+	}
+	return val
+}
+
+// sameValue reports whether two field values are equal once encoded, so that
+// an integer read as int64 equals one decoded from JSON as float64
+func sameValue(a, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
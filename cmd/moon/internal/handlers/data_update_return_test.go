@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// selectCounter counts the SELECT statements run outside transactions
+type selectCounter struct {
+	database.Driver
+	selects atomic.Int64
+}
+
+func (c *selectCounter) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if strings.HasPrefix(query, "SELECT") {
+		c.selects.Add(1)
+	}
+	return c.Driver.Query(ctx, query, args...)
+}
+
+func (c *selectCounter) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	if strings.HasPrefix(query, "SELECT") {
+		c.selects.Add(1)
+	}
+	return c.Driver.QueryRow(ctx, query, args...)
+}
+
+// setupUpdateReturnTest creates a notes collection with a computed total and
+// a hidden cost, and a data handler counting its SELECT statements
+func setupUpdateReturnTest(t *testing.T) (*DataHandler, *selectCounter) {
+	t.Helper()
+	nt := setupNotesTest(t, map[string]any{
+		"columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "price", "type": "decimal", "nullable": true},
+			{"name": "quantity", "type": "integer", "nullable": true},
+			{"name": "active", "type": "boolean", "nullable": true},
+			{"name": "note", "type": "string", "nullable": true},
+			{"name": "cost", "type": "decimal", "nullable": true, "hidden": true},
+			{"name": "total", "type": "decimal", "nullable": true, "computed": "price * quantity"},
+		},
+	})
+	counter := &selectCounter{Driver: nt.driver}
+	return NewDataHandler(counter, nt.reg, testConfig()), counter
+}
+
+// updateNotes runs an :update and decodes its response, counting the SELECT
+// statements it ran
+func updateNotes(t *testing.T, handler *DataHandler, counter *selectCounter, url string, body any, wantStatus int) (map[string]any, int64) {
+	t.Helper()
+	counter.selects.Store(0)
+	w := doDataAction(t, handler.Update, http.MethodPost, url, body)
+	if w.Code != wantStatus {
+		t.Fatalf("%s: expected %d, got %d %s", url, wantStatus, w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp, counter.selects.Load()
+}
+
+func TestUpdate_ReturnDiff(t *testing.T) {
+	handler, counter := setupUpdateReturnTest(t)
+	id := createNote(t, handler, map[string]any{"title": "pen", "price": "10.00", "quantity": 2, "active": true, "note": nil, "cost": "4.00"})["id"].(string)
+
+	// Unchanged values, sent in another form, are not reported
+	resp, selects := updateNotes(t, handler, counter, "/notes:update?return=diff", map[string]any{"data": map[string]any{
+		"id": id, "title": "pen", "quantity": 2, "price": "12", "active": false, "note": "gift", "cost": "5.00",
+	}}, http.StatusOK)
+	want := map[string]any{
+		"price":  map[string]any{"from": "10.00", "to": "12.00"},
+		"active": map[string]any{"from": true, "to": false},
+		"note":   map[string]any{"from": nil, "to": "gift"},
+		"total":  map[string]any{"from": "20.00", "to": "24.00"},
+	}
+	if !reflect.DeepEqual(resp["changed"], want) {
+		t.Errorf("expected changes %v, got %v", want, resp["changed"])
+	}
+	if selects != 1 {
+		t.Errorf("expected 1 SELECT for the diff, got %d", selects)
+	}
+	if data := resp["data"].(map[string]any); data["price"] != "12" {
+		t.Errorf("expected the data echoed, got %v", data)
+	}
+
+	// Null transitions both ways, and an update changing nothing
+	resp, _ = updateNotes(t, handler, counter, "/notes:update?return=diff", map[string]any{"id": id, "data": map[string]any{"note": nil, "quantity": nil}}, http.StatusOK)
+	want = map[string]any{
+		"note":     map[string]any{"from": "gift", "to": nil},
+		"quantity": map[string]any{"from": float64(2), "to": nil},
+		"total":    map[string]any{"from": "24.00", "to": nil},
+	}
+	if !reflect.DeepEqual(resp["changed"], want) {
+		t.Errorf("expected changes %v, got %v", want, resp["changed"])
+	}
+	resp, _ = updateNotes(t, handler, counter, "/notes:update?return=diff", map[string]any{"data": map[string]any{"id": id, "title": "pen"}}, http.StatusOK)
+	if changed, ok := resp["changed"].(map[string]any); !ok || len(changed) != 0 {
+		t.Errorf("expected empty changes, got %v", resp["changed"])
+	}
+
+	// The default response stays the echo, without reads
+	resp, selects = updateNotes(t, handler, counter, "/notes:update", map[string]any{"data": map[string]any{"id": id, "title": "pencil"}}, http.StatusOK)
+	if _, ok := resp["changed"]; ok || selects != 0 {
+		t.Errorf("expected no changes and no SELECT by default, got %v and %d", resp["changed"], selects)
+	}
+
+	updateNotes(t, handler, counter, "/notes:update?return=everything", map[string]any{"data": map[string]any{"id": id, "title": "pen"}}, http.StatusBadRequest)
+}
+
+func TestUpdate_ReturnFull(t *testing.T) {
+	handler, counter := setupUpdateReturnTest(t)
+	id := createNote(t, handler, map[string]any{"title": "pen", "price": "10.00", "quantity": 2, "cost": "4.00"})["id"].(string)
+
+	resp, selects := updateNotes(t, handler, counter, "/notes:update?return=full", map[string]any{"data": map[string]any{"id": id, "price": "3.50"}}, http.StatusOK)
+	data := resp["data"].(map[string]any)
+	if data["title"] != "pen" || data["price"] != "3.50" || data["total"] != "7.00" || data["_rev"] != float64(2) || data["created_at"] == nil {
+		t.Errorf("expected the complete updated record, got %v", data)
+	}
+	if _, ok := data["cost"]; ok {
+		t.Errorf("expected the hidden cost left out, got %v", data)
+	}
+	if selects != 1 {
+		t.Errorf("expected 1 SELECT to read the record back, got %d", selects)
+	}
+}
+
+func TestUpdate_ReturnBatches(t *testing.T) {
+	handler, counter := setupUpdateReturnTest(t)
+	first := createNote(t, handler, map[string]any{"title": "a", "price": "1.00", "quantity": 1})["id"].(string)
+	second := createNote(t, handler, map[string]any{"title": "b", "price": "2.00", "quantity": 1})["id"].(string)
+	items := []map[string]any{{"id": first, "price": "1.50"}, {"id": second, "title": "c"}}
+
+	resp, _ := updateNotes(t, handler, counter, "/notes:update?atomic=true&return=diff", map[string]any{"data": items}, http.StatusOK)
+	want := []any{
+		map[string]any{"price": map[string]any{"from": "1.00", "to": "1.50"}, "total": map[string]any{"from": "1.00", "to": "1.50"}},
+		map[string]any{"title": map[string]any{"from": "b", "to": "c"}},
+	}
+	if !reflect.DeepEqual(resp["changed"], want) {
+		t.Errorf("expected changes %v, got %v", want, resp["changed"])
+	}
+
+	items = []map[string]any{{"id": first, "price": "2.50"}, {"id": second, "title": "d"}}
+	resp, selects := updateNotes(t, handler, counter, "/notes:update?return=full", map[string]any{"data": items}, http.StatusMultiStatus)
+	results := resp["results"].([]any)
+	if data := results[1].(map[string]any)["data"].(map[string]any); data["title"] != "d" || data["price"] != "2.00" {
+		t.Errorf("expected the complete second record, got %v", data)
+	}
+	if selects != 2 {
+		t.Errorf("expected 1 SELECT per record, got %d", selects)
+	}
+
+	items = []map[string]any{{"id": first, "quantity": 2}}
+	resp, _ = updateNotes(t, handler, counter, "/notes:update?return=diff", map[string]any{"data": items}, http.StatusMultiStatus)
+	changed := resp["results"].([]any)[0].(map[string]any)["changed"]
+	if want := map[string]any{"quantity": map[string]any{"from": float64(1), "to": float64(2)}, "total": map[string]any{"from": "2.50", "to": "5.00"}}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("expected changes %v, got %v", want, changed)
+	}
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func partialUpdateCollection() *registry.Collection {
+	return &registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "name", Type: registry.TypeString, Nullable: false},
+			{Name: "price", Type: registry.TypeInteger, Nullable: false},
+			{Name: "description", Type: registry.TypeString, Nullable: true},
+		},
+	}
+}
+
+func TestBuildUpdateSetClauses(t *testing.T) {
+	collection := partialUpdateCollection()
+
+	tests := []struct {
+		name        string
+		dialect     database.DialectType
+		data        map[string]any
+		wantClauses []string
+		wantValues  []any
+	}{
+		{
+			name:        "sqlite single field",
+			dialect:     database.DialectSQLite,
+			data:        map[string]any{"price": float64(10)},
+			wantClauses: []string{`"price" = ?`, "updated_at = ?", "_rev = _rev + 1"},
+			wantValues:  []any{float64(10)},
+		},
+		{
+			name:        "postgres single field",
+			dialect:     database.DialectPostgres,
+			data:        map[string]any{"price": float64(10)},
+			wantClauses: []string{`"price" = $1`, "updated_at = $2", "_rev = _rev + 1"},
+			wantValues:  []any{float64(10)},
+		},
+		{
+			name:        "sqlite several fields",
+			dialect:     database.DialectSQLite,
+			data:        map[string]any{"name": "Widget", "description": "Blue"},
+			wantClauses: []string{`"name" = ?`, `"description" = ?`, "updated_at = ?", "_rev = _rev + 1"},
+			wantValues:  []any{"Widget", "Blue"},
+		},
+		{
+			name:        "postgres several fields",
+			dialect:     database.DialectPostgres,
+			data:        map[string]any{"name": "Widget", "price": float64(5), "description": "Blue"},
+			wantClauses: []string{`"name" = $1`, `"price" = $2`, `"description" = $3`, "updated_at = $4", "_rev = _rev + 1"},
+			wantValues:  []any{"Widget", float64(5), "Blue"},
+		},
+		{
+			name:        "sqlite null clear",
+			dialect:     database.DialectSQLite,
+			data:        map[string]any{"description": nil},
+			wantClauses: []string{`"description" = NULL`, "updated_at = ?", "_rev = _rev + 1"},
+			wantValues:  []any{},
+		},
+		{
+			name:        "postgres null clear keeps placeholder numbering contiguous",
+			dialect:     database.DialectPostgres,
+			data:        map[string]any{"name": "Widget", "description": nil, "price": float64(5)},
+			wantClauses: []string{`"name" = $1`, `"price" = $2`, `"description" = NULL`, "updated_at = $3", "_rev = _rev + 1"},
+			wantValues:  []any{"Widget", float64(5)},
+		},
+		{
+			name:        "empty data does not bump updated_at",
+			dialect:     database.DialectSQLite,
+			data:        map[string]any{},
+			wantClauses: []string{},
+			wantValues:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clauses, values := buildUpdateSetClauses(tt.data, collection, tt.dialect)
+			if !reflect.DeepEqual(clauses, tt.wantClauses) {
+				t.Errorf("clauses = %v, want %v", clauses, tt.wantClauses)
+			}
+			if len(tt.wantClauses) == 0 {
+				if len(values) != 0 {
+					t.Errorf("values = %v, want none", values)
+				}
+				return
+			}
+			// The trailing value is the updated_at timestamp
+			if len(values) != len(tt.wantValues)+1 {
+				t.Fatalf("values = %v, want %v plus updated_at", values, tt.wantValues)
+			}
+			if !reflect.DeepEqual(values[:len(tt.wantValues)], tt.wantValues) {
+				t.Errorf("values = %v, want %v", values[:len(tt.wantValues)], tt.wantValues)
+			}
+			if ts, ok := values[len(values)-1].(string); !ok {
+				t.Errorf("expected updated_at string value, got %v", values[len(values)-1])
+			} else if _, err := time.Parse(time.RFC3339, ts); err != nil {
+				t.Errorf("updated_at %q is not RFC3339: %v", ts, err)
+			}
+		})
+	}
+}
+
+func TestDataHandler_Update_PartialAndNull(t *testing.T) {
+	const id = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+
+	tests := []struct {
+		name       string
+		dialect    database.DialectType
+		data       map[string]any
+		wantStatus int
+		wantQuery  string
+		wantArgs   int
+	}{
+		{
+			name:       "sqlite one field",
+			dialect:    database.DialectSQLite,
+			data:       map[string]any{"id": id, "price": 20},
+			wantStatus: http.StatusOK,
+			wantQuery:  `UPDATE "products" SET "price" = ?, updated_at = ?, _rev = _rev + 1 WHERE id = ?`,
+			wantArgs:   3,
+		},
+		{
+			name:       "postgres several fields",
+			dialect:    database.DialectPostgres,
+			data:       map[string]any{"id": id, "name": "Widget", "price": 20},
+			wantStatus: http.StatusOK,
+			wantQuery:  `UPDATE "products" SET "name" = $1, "price" = $2, updated_at = $3, _rev = _rev + 1 WHERE id = $4`,
+			wantArgs:   4,
+		},
+		{
+			name:       "sqlite null clearing",
+			dialect:    database.DialectSQLite,
+			data:       map[string]any{"id": id, "description": nil},
+			wantStatus: http.StatusOK,
+			wantQuery:  `UPDATE "products" SET "description" = NULL, updated_at = ?, _rev = _rev + 1 WHERE id = ?`,
+			wantArgs:   2,
+		},
+		{
+			name:       "postgres null clearing with other field",
+			dialect:    database.DialectPostgres,
+			data:       map[string]any{"id": id, "price": 20, "description": nil},
+			wantStatus: http.StatusOK,
+			wantQuery:  `UPDATE "products" SET "price" = $1, "description" = NULL, updated_at = $2, _rev = _rev + 1 WHERE id = $3`,
+			wantArgs:   3,
+		},
+		{
+			name:       "sqlite null on non-nullable column",
+			dialect:    database.DialectSQLite,
+			data:       map[string]any{"id": id, "name": nil},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "postgres null on non-nullable column",
+			dialect:    database.DialectPostgres,
+			data:       map[string]any{"id": id, "price": nil},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := registry.NewSchemaRegistry()
+			reg.Set(partialUpdateCollection())
+
+			var gotQuery string
+			var gotArgs []any
+			driver := &mockDataDriver{
+				dialect: tt.dialect,
+				execFunc: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+					gotQuery = query
+					gotArgs = args
+					return mockResult{rowsAffected: 1}, nil
+				},
+			}
+			handler := NewDataHandler(driver, reg, testConfig())
+
+			body, _ := json.Marshal(map[string]any{"data": tt.data})
+			req := httptest.NewRequest(http.MethodPost, "/products:update", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.Update(w, req, "products")
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotQuery != "" {
+					t.Errorf("expected no query to be executed, got %q", gotQuery)
+				}
+				if !strings.Contains(w.Body.String(), "cannot be null") {
+					t.Errorf("expected null error message, got %s", w.Body.String())
+				}
+				return
+			}
+			if gotQuery != tt.wantQuery {
+				t.Errorf("query = %q, want %q", gotQuery, tt.wantQuery)
+			}
+			if len(gotArgs) != tt.wantArgs {
+				t.Errorf("expected %d args, got %d: %v", tt.wantArgs, len(gotArgs), gotArgs)
+			}
+		})
+	}
+}
+
+func TestDataHandler_Update_ClearNullable_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	ctx := context.Background()
+	id := generateULID()
+	if _, err := driver.Exec(ctx, "INSERT INTO products (id, name, price, category) VALUES (?, ?, ?, ?)", id, "Lamp", 30, "home"); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]any{"data": map[string]any{"id": id, "category": nil}})
+	req := httptest.NewRequest(http.MethodPost, "/products:update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.Update(w, req, "products")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var category sql.NullString
+	var name string
+	if err := driver.QueryRow(ctx, "SELECT name, category FROM products WHERE id = ?", id).Scan(&name, &category); err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if category.Valid {
+		t.Errorf("expected category to be NULL, got %q", category.String)
+	}
+	if name != "Lamp" {
+		t.Errorf("expected untouched name to remain 'Lamp', got %q", name)
+	}
+}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// UpsertDataRequest represents request for upsert operation
+type UpsertDataRequest struct {
+	Key  string          `json:"key"`
+	Data json.RawMessage `json:"data"`
+}
+
+// UpsertDataResponse represents response for single-object upsert operation
+type UpsertDataResponse struct {
+	Data    map[string]any  `json:"data"`
+	Status  BatchItemStatus `json:"status"` // created or updated
+	Message string          `json:"message"`
+}
+
+// upsertError describes why a single upsert item failed
+type upsertError struct {
+	HTTPStatus int
+	Code       apperrors.ErrorCode
+	Message    string
+	Conflict   *uniqueConflict // set for unique violations
+}
+
+// Upsert handles POST /{name}:upsert
+// Records are matched by the unique column named in "key". Matching rows are
+// updated, otherwise a new record is inserted with a fresh ULID.
+func (h *DataHandler) Upsert(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
+	// Validate collection exists in registry
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	// Check payload size (PRD-064)
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+
+	var req UpsertDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+
+	req.Key = snakeCase(req.Key)
+	if err := validateUpsertKey(req.Key, collection); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	if len(req.Data) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "missing data field")
+		return
+	}
+
+	// Detect batch vs single mode
+	isBatch, err := detectBatchMode(req.Data)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, err.Error())
+		return
+	}
+
+	if !isBatch {
+		h.upsertSingle(w, r, collectionName, collection, req.Key, req.Data)
+		return
+	}
+
+	atomic := parseAtomicFlag(r)
+	h.upsertBatch(w, r, collectionName, collection, req.Key, req.Data, atomic)
+}
+
+// upsertSingle handles single-object upsert inside its own transaction
+func (h *DataHandler) upsertSingle(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, key string, rawData json.RawMessage) {
+	var item map[string]any
+	if err := json.Unmarshal(rawData, &item); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid data format")
+		return
+	}
+	if err := normalizeFieldNames(item); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	result, uerr := h.upsertItem(ctx, tx, collectionName, collection, key, item)
+	if uerr != nil {
+		if uerr.Conflict != nil {
+			writeUniqueConflict(w, r, uerr.Conflict)
+			return
+		}
+		writeError(w, r, uerr.HTTPStatus, uerr.Code, uerr.Message)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
+		return
+	}
+
+	audit.AddRecords(ctx, result.ID)
+	status := http.StatusOK
+	if result.Status == BatchItemCreated {
+		status = http.StatusCreated
+	}
+
+	writeResponse(w, r, status, UpsertDataResponse{
+		Data:    result.Data,
+		Status:  result.Status,
+		Message: fmt.Sprintf("Record %s %s successfully", result.ID, result.Status),
+	})
+}
+
+// upsertBatch handles batch upsert operations
+func (h *DataHandler) upsertBatch(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, key string, rawData json.RawMessage, atomic bool) {
+	var items []map[string]any
+	if err := json.Unmarshal(rawData, &items); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid batch data format")
+		return
+	}
+	if err := normalizeItemFieldNames(items); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
+		return
+	}
+
+	// Validate batch size
+	if err := h.validateBatchSize(len(items)); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, err.Error())
+		return
+	}
+
+	if len(items) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "batch must contain at least one item")
+		return
+	}
+
+	ctx := r.Context()
+
+	if atomic {
+		// Atomic mode: all-or-nothing with transaction
+		h.upsertBatchAtomic(w, r, collectionName, collection, key, items)
+	} else {
+		// Best-effort mode: partial success
+		h.upsertBatchBestEffort(w, ctx, collectionName, collection, key, items)
+	}
+}
+
+// upsertBatchAtomic processes every item in a single transaction
+func (h *DataHandler) upsertBatchAtomic(w http.ResponseWriter, r *http.Request, collectionName string, collection *registry.Collection, key string, items []map[string]any) {
+	ctx := r.Context()
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to begin transaction: %v", err))
+		return
+	}
+	defer tx.Rollback()
+
+	results := make([]BatchItemResult, 0, len(items))
+	for idx, item := range items {
+		result, uerr := h.upsertItem(ctx, tx, collectionName, collection, key, item)
+		if uerr != nil {
+			if uerr.Conflict != nil {
+				conflict := *uerr.Conflict
+				conflict.Message = fmt.Sprintf("error at index %d: %s", idx, conflict.Message)
+				writeUniqueConflict(w, r, &conflict)
+				return
+			}
+			writeError(w, r, uerr.HTTPStatus, uerr.Code, fmt.Sprintf("error at index %d: %s", idx, uerr.Message))
+			return
+		}
+		result.Index = idx
+		results = append(results, result)
+		audit.AddRecords(ctx, result.ID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to commit transaction: %v", err))
+		return
+	}
+
+	writeResponse(w, r, http.StatusOK, BatchResponse{
+		Results: results,
+		Summary: BatchSummary{
+			Total:     len(items),
+			Succeeded: len(results),
+			Failed:    0,
+		},
+	})
+}
+
+// upsertBatchBestEffort processes each item in its own transaction and reports per-item status
+func (h *DataHandler) upsertBatchBestEffort(w http.ResponseWriter, ctx context.Context, collectionName string, collection *registry.Collection, key string, items []map[string]any) {
+	out := h.newBatchResultWriter(w, ctx, "")
+
+	for idx, item := range items {
+		result, uerr := h.upsertItemInTx(ctx, collectionName, collection, key, item)
+		if uerr != nil {
+			result := BatchItemResult{
+				Index:        idx,
+				Status:       BatchItemFailed,
+				ErrorCode:    uerr.Code,
+				ErrorMessage: uerr.Message,
+			}
+			if uerr.Conflict != nil {
+				result.Field = uerr.Conflict.Field
+			}
+			out.add(result)
+			continue
+		}
+		result.Index = idx
+		out.add(result)
+	}
+
+	out.close()
+}
+
+// upsertItemInTx wraps upsertItem in a dedicated transaction
+func (h *DataHandler) upsertItemInTx(ctx context.Context, collectionName string, collection *registry.Collection, key string, item map[string]any) (BatchItemResult, *upsertError) {
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		return BatchItemResult{}, &upsertError{HTTPStatus: http.StatusInternalServerError, Code: apperrors.CodeDatabaseError, Message: fmt.Sprintf("failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	result, uerr := h.upsertItem(ctx, tx, collectionName, collection, key, item)
+	if uerr != nil {
+		return BatchItemResult{}, uerr
+	}
+
+	if err := tx.Commit(); err != nil {
+		return BatchItemResult{}, &upsertError{HTTPStatus: http.StatusInternalServerError, Code: apperrors.CodeDatabaseError, Message: fmt.Sprintf("failed to commit transaction: %v", err)}
+	}
+	return result, nil
+}
+
+// upsertItem looks up the record by key and updates it, or inserts a new record when none matches.
+// The read and write both run on tx so concurrent upserts on the same key are serialized by the database.
+func (h *DataHandler) upsertItem(ctx context.Context, tx *sql.Tx, collectionName string, collection *registry.Collection, key string, item map[string]any) (BatchItemResult, *upsertError) {
+	// Client-supplied ids are only used when the item is inserted
+	if _, hasID := item["id"]; hasID && collection.RecordIDType() != registry.IDTypeClient {
+		return BatchItemResult{}, &upsertError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeInvalidInput, Message: "id must not be provided; records are matched by key"}
+	}
+
+	keyValue, hasKey := item[key]
+	if !hasKey || keyValue == nil {
+		return BatchItemResult{}, &upsertError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeRequiredField, Message: fmt.Sprintf("key field '%s' is required", key)}
+	}
+
+	// Validate types and unknown fields before touching the database
+	if err := h.validateUpdateFields(item, collection); err != nil {
+		return BatchItemResult{}, &upsertError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeOf(err, apperrors.CodeValidationFailed), Message: err.Error()}
+	}
+
+	dialect := h.db.Dialect()
+	var apiErr *apperrors.APIError
+	if errors.As(checkReferences(ctx, tx.QueryContext, h.registry, collection, item, dialect), &apiErr) {
+		return BatchItemResult{}, &upsertError{HTTPStatus: apiErr.StatusCode, Code: apiErr.ErrorCode, Message: apiErr.Message}
+	}
+
+	// A soft-deleted record is not matched, so it is not revived; its key
+	// still holds the unique index, so the insert reports a conflict
+	var existingID string
+	lookup := fmt.Sprintf("SELECT id FROM %s WHERE %s = %s", query.QuoteIdent(dialect, collectionName), query.QuoteIdent(dialect, key), query.Placeholder(dialect, 1))
+	if collection.SoftDelete {
+		lookup += fmt.Sprintf(" AND %s IS NULL", constants.SoftDeleteColumn)
+	}
+	err := tx.QueryRowContext(ctx, lookup, keyValue).Scan(&existingID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return BatchItemResult{}, &upsertError{HTTPStatus: http.StatusInternalServerError, Code: apperrors.CodeDatabaseError, Message: fmt.Sprintf("failed to look up record: %v", err)}
+	}
+
+	responseData := map[string]any{}
+	for _, col := range collection.Columns {
+		if val, ok := item[col.Name]; ok {
+			responseData[col.Name] = val
+		}
+	}
+
+	if existingID != "" {
+		// Key column itself is the match criterion and is left untouched
+		changes := make(map[string]any, len(item))
+		for k, v := range item {
+			if k != key {
+				changes[k] = v
+			}
+		}
+
+		setClauses, values := buildUpdateSetClauses(changes, collection, dialect)
+		if len(setClauses) > 0 {
+			update, values := buildUpdateQuery(collection, setClauses, values, existingID, nil, dialect)
+			if _, err := tx.ExecContext(ctx, update, values...); err != nil {
+				return BatchItemResult{}, upsertExecError(err, "failed to update data", collection, item)
+			}
+		}
+
+		responseData["id"] = existingID
+		return BatchItemResult{ID: existingID, Status: BatchItemUpdated, Data: responseData}, nil
+	}
+
+	// No match: insert requires the full set of non-nullable fields
+	if err := h.validateCreateFields(item, collection); err != nil {
+		return BatchItemResult{}, &upsertError{HTTPStatus: http.StatusBadRequest, Code: apperrors.CodeOf(err, apperrors.CodeValidationFailed), Message: err.Error()}
+	}
+
+	ulid := newRecordID(collection, item)
+	now := currentTimestamp()
+	insert, values := buildInsertQuery(collectionName, collection, item, ulid, now, dialect)
+	if _, err := tx.ExecContext(ctx, insert, values...); err != nil {
+		return BatchItemResult{}, upsertExecError(err, "failed to insert data", collection, item)
+	}
+
+	return BatchItemResult{ID: ulid, Status: BatchItemCreated, Data: newRecordResponse(collection, item, ulid, now)}, nil
+}
+
+// validateUpsertKey ensures the key names a unique column in the collection
+func validateUpsertKey(key string, collection *registry.Collection) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	for _, col := range collection.Columns {
+		if col.Name == key {
+			if !col.Unique {
+				return fmt.Errorf("key field '%s' must be a unique column", key)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("key field '%s' not found in collection", key)
+}
+
+// upsertExecError maps a write error to an upsertError, detecting unique violations
+func upsertExecError(err error, action string, collection *registry.Collection, item map[string]any) *upsertError {
+	if conflict, ok := asUniqueConflict(err, collection, item); ok {
+		return &upsertError{HTTPStatus: http.StatusConflict, Code: apperrors.CodeUniqueViolation, Message: conflict.Message, Conflict: conflict}
+	}
+	return &upsertError{HTTPStatus: http.StatusInternalServerError, Code: apperrors.CodeDatabaseError, Message: fmt.Sprintf("%s: %v", action, err)}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupUpsertIntegrationTest creates a database with an items collection keyed by sku
+func setupUpsertIntegrationTest(t *testing.T) (database.Driver, *DataHandler) {
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	_, err = driver.Exec(ctx, `CREATE TABLE items (
+		id TEXT PRIMARY KEY,
+		created_at TEXT,
+		updated_at TEXT,
+		_rev INTEGER NOT NULL DEFAULT 1,
+		sku TEXT NOT NULL UNIQUE,
+		name TEXT NOT NULL,
+		quantity INTEGER
+	)`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "items",
+		Columns: []registry.Column{
+			{Name: "sku", Type: registry.TypeString, Nullable: false, Unique: true},
+			{Name: "name", Type: registry.TypeString, Nullable: false},
+			{Name: "quantity", Type: registry.TypeInteger, Nullable: true},
+		},
+	})
+
+	return driver, NewDataHandler(driver, reg, testConfig())
+}
+
+func doUpsert(t *testing.T, handler *DataHandler, url string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	w := httptest.NewRecorder()
+	handler.Upsert(w, req, "items")
+	return w
+}
+
+func TestDataHandler_Upsert_Single_Integration(t *testing.T) {
+	driver, handler := setupUpsertIntegrationTest(t)
+	defer driver.Close()
+
+	// First call inserts
+	w := doUpsert(t, handler, "/items:upsert", map[string]any{
+		"key":  "sku",
+		"data": map[string]any{"sku": "A-1", "name": "Anvil", "quantity": 1},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created UpsertDataResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Status != BatchItemCreated {
+		t.Errorf("expected status created, got %s", created.Status)
+	}
+	id, _ := created.Data["id"].(string)
+	if len(id) != 26 {
+		t.Fatalf("expected ULID id, got %v", created.Data["id"])
+	}
+
+	// Second call with the same key updates, without requiring all fields
+	w = doUpsert(t, handler, "/items:upsert", map[string]any{
+		"key":  "sku",
+		"data": map[string]any{"sku": "A-1", "quantity": 5},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated UpsertDataResponse
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if updated.Status != BatchItemUpdated {
+		t.Errorf("expected status updated, got %s", updated.Status)
+	}
+	if updated.Data["id"] != id {
+		t.Errorf("expected same id %s, got %v", id, updated.Data["id"])
+	}
+
+	var count, quantity int
+	var name string
+	ctx := context.Background()
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM items").Scan(&count)
+	driver.QueryRow(ctx, "SELECT name, quantity FROM items WHERE sku = ?", "A-1").Scan(&name, &quantity)
+	if count != 1 {
+		t.Errorf("expected 1 row, got %d", count)
+	}
+	if name != "Anvil" || quantity != 5 {
+		t.Errorf("unexpected row state: name=%s quantity=%d", name, quantity)
+	}
+}
+
+func TestDataHandler_Upsert_KeyValidation(t *testing.T) {
+	driver, handler := setupUpsertIntegrationTest(t)
+	defer driver.Close()
+
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"missing key", map[string]any{"data": map[string]any{"sku": "A-1", "name": "x"}}},
+		{"non-unique key", map[string]any{"key": "name", "data": map[string]any{"sku": "A-1", "name": "x"}}},
+		{"unknown key", map[string]any{"key": "nope", "data": map[string]any{"sku": "A-1", "name": "x"}}},
+		{"missing key value", map[string]any{"key": "sku", "data": map[string]any{"name": "x"}}},
+		{"id provided", map[string]any{"key": "sku", "data": map[string]any{"id": generateULID(), "sku": "A-1", "name": "x"}}},
+		{"insert missing required", map[string]any{"key": "sku", "data": map[string]any{"sku": "A-1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doUpsert(t, handler, "/items:upsert", tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDataHandler_Upsert_BatchBestEffort_Integration(t *testing.T) {
+	driver, handler := setupUpsertIntegrationTest(t)
+	defer driver.Close()
+
+	doUpsert(t, handler, "/items:upsert", map[string]any{
+		"key":  "sku",
+		"data": map[string]any{"sku": "A-1", "name": "Anvil"},
+	})
+
+	w := doUpsert(t, handler, "/items:upsert", map[string]any{
+		"key": "sku",
+		"data": []map[string]any{
+			{"sku": "A-1", "name": "Anvil v2"},
+			{"sku": "B-2", "name": "Bolt"},
+			{"sku": "C-3"},
+		},
+	})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	want := []BatchItemStatus{BatchItemUpdated, BatchItemCreated, BatchItemFailed}
+	for i, status := range want {
+		if resp.Results[i].Status != status {
+			t.Errorf("item %d: expected %s, got %s", i, status, resp.Results[i].Status)
+		}
+	}
+	if resp.Summary.Succeeded != 2 || resp.Summary.Failed != 1 {
+		t.Errorf("unexpected summary: %+v", resp.Summary)
+	}
+}
+
+func TestDataHandler_Upsert_BatchAtomic_Integration(t *testing.T) {
+	driver, handler := setupUpsertIntegrationTest(t)
+	defer driver.Close()
+	ctx := context.Background()
+
+	// A failing item rolls back the whole batch
+	w := doUpsert(t, handler, "/items:upsert?atomic=true", map[string]any{
+		"key": "sku",
+		"data": []map[string]any{
+			{"sku": "A-1", "name": "Anvil"},
+			{"sku": "B-2"},
+		},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM items").Scan(&count)
+	if count != 0 {
+		t.Errorf("expected rollback to leave 0 rows, got %d", count)
+	}
+
+	w = doUpsert(t, handler, "/items:upsert?atomic=true", map[string]any{
+		"key": "sku",
+		"data": []map[string]any{
+			{"sku": "A-1", "name": "Anvil"},
+			{"sku": "A-1", "quantity": 3},
+		},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Results[0].Status != BatchItemCreated || resp.Results[1].Status != BatchItemUpdated {
+		t.Errorf("unexpected statuses: %s, %s", resp.Results[0].Status, resp.Results[1].Status)
+	}
+	driver.QueryRow(ctx, "SELECT COUNT(*) FROM items").Scan(&count)
+	if count != 1 {
+		t.Errorf("expected 1 row, got %d", count)
+	}
+}
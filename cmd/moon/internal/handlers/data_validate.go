@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// Writes whose checks :validate runs, selected with ?action=
+const (
+	validateActionCreate = "create"
+	validateActionUpdate = "update"
+)
+
+// Validate handles POST /{name}:validate
+// It runs the checks of :create, or of :update with ?action=update, on the
+// single record or batch of a write body and reports each item as valid or
+// invalid. Unique values and referenced and updated records are looked up
+// with SELECTs; nothing is written.
+func (h *DataHandler) Validate(w http.ResponseWriter, r *http.Request, collectionName string) {
+	r, ok := h.withFieldCase(w, r)
+	if !ok {
+		return
+	}
+	collection, exists := h.registry.Get(collectionName)
+	if !exists {
+		writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, fmt.Sprintf("collection '%s' not found", logicalName(r, collectionName)))
+		return
+	}
+
+	if err := h.validatePayloadSize(w, r); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, err.Error())
+		return
+	}
+
+	action := r.URL.Query().Get("action")
+	switch action {
+	case "":
+		action = validateActionCreate
+	case validateActionCreate, validateActionUpdate:
+	default:
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "action must be create or update")
+		return
+	}
+
+	var req BatchCreateDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBodyError(w, r, err, "invalid request body")
+		return
+	}
+	isBatch, err := detectBatchMode(req.Data)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, err.Error())
+		return
+	}
+	var items []map[string]any
+	if isBatch {
+		err = json.Unmarshal(req.Data, &items)
+	} else {
+		var item map[string]any
+		err = json.Unmarshal(req.Data, &item)
+		items = []map[string]any{item}
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid data format")
+		return
+	}
+	if err := normalizeItemFieldNames(items); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
+		return
+	}
+	if err := h.validateBatchSize(len(items)); err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge, err.Error())
+		return
+	}
+	if len(items) == 0 {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "batch must contain at least one item")
+		return
+	}
+
+	results, err := h.validateItems(r.Context(), collection, items, action == validateActionUpdate)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("failed to validate records: %v", err))
+		return
+	}
+
+	resp := BatchResponse{Results: results, Summary: BatchSummary{Total: len(results)}}
+	for _, result := range results {
+		if result.Status == BatchItemValid {
+			resp.Summary.Succeeded++
+		} else {
+			resp.Summary.Failed++
+		}
+	}
+	writeResponse(w, r, http.StatusOK, resp)
+}
+
+// validateItems returns the result of each item of a create, or of an update
+// when update is set. An item reports the first check it fails; the error is
+// only returned when a lookup fails.
+func (h *DataHandler) validateItems(ctx context.Context, collection *registry.Collection, items []map[string]any, update bool) ([]BatchItemResult, error) {
+	results := make([]BatchItemResult, len(items))
+	fail := func(idx int, code apperrors.ErrorCode, message string) *BatchItemResult {
+		if results[idx].Status == BatchItemInvalid {
+			return nil
+		}
+		results[idx].Status = BatchItemInvalid
+		results[idx].ErrorCode = code
+		results[idx].ErrorMessage = message
+		return &results[idx]
+	}
+
+	revs := make([]*int64, len(items))
+	for idx, item := range items {
+		results[idx] = BatchItemResult{Index: idx, Status: BatchItemValid}
+		if !update {
+			if err := h.validateCreateFields(item, collection); err != nil {
+				fail(idx, apperrors.CodeOf(err, apperrors.CodeValidationFailed), err.Error())
+			}
+			continue
+		}
+
+		id, ok := item["id"].(string)
+		switch {
+		case item["id"] == nil:
+			fail(idx, apperrors.CodeRequiredField, "id is required")
+			continue
+		case !ok:
+			fail(idx, apperrors.CodeInvalidType, "id must be a string")
+			continue
+		}
+		results[idx].ID = id
+		if err := validateRecordID(collection, id); err != nil {
+			fail(idx, apperrors.CodeOf(err, apperrors.CodeInvalidULID), fmt.Sprintf("invalid id: %v", err))
+			continue
+		}
+		rev, err := takeItemRevision(item)
+		if err != nil {
+			fail(idx, apperrors.CodeInvalidRevision, err.Error())
+			continue
+		}
+		if err := requireRevision(collection, rev); err != nil {
+			fail(idx, apperrors.CodeRevisionRequired, err.Error())
+			continue
+		}
+		revs[idx] = rev
+		if err := h.validateUpdateFields(item, collection); err != nil {
+			fail(idx, apperrors.CodeOf(err, apperrors.CodeValidationFailed), err.Error())
+		}
+	}
+
+	// The lookups only cover items that passed the checks above
+	valid := func(idx int) bool { return results[idx].Status == BatchItemValid }
+	dialect := h.db.Dialect()
+
+	if update {
+		targets := map[string][]int{}
+		for idx := range items {
+			if valid(idx) {
+				targets[results[idx].ID] = append(targets[results[idx].ID], idx)
+			}
+		}
+		stored, err := recordRevisions(ctx, h.db.Query, collection, slices.Sorted(maps.Keys(targets)), dialect)
+		if err != nil {
+			return nil, err
+		}
+		for id, idxs := range targets {
+			for _, idx := range idxs {
+				current, found := stored[id]
+				switch {
+				case !found:
+					fail(idx, apperrors.CodeRecordNotFound, fmt.Sprintf("record with id %s not found", id))
+				case revs[idx] != nil && *revs[idx] != current:
+					if result := fail(idx, apperrors.CodeRevisionConflict, revisionConflictMessage(id, *revs[idx], current)); result != nil {
+						result.CurrentRev = &current
+					}
+				}
+			}
+		}
+	}
+
+	checked := make([]map[string]any, len(items))
+	for idx, item := range items {
+		if valid(idx) {
+			checked[idx] = item
+		}
+	}
+	refErrs, err := referenceErrors(ctx, h.db.Query, h.registry, collection, checked, dialect)
+	if err != nil {
+		return nil, err
+	}
+	for idx, err := range refErrs {
+		fail(idx, apperrors.CodeInvalidReference, err.Error())
+	}
+
+	for _, col := range validateUniqueColumns(collection, update) {
+		// holders maps each value to the items that write it, in item order
+		holders := map[string][]int{}
+		values := map[string]any{}
+		for idx, item := range items {
+			val, ok := item[col.Name]
+			if !ok || val == nil || !valid(idx) {
+				continue
+			}
+			arg := columnValue(col, val)
+			key := uniqueKey(arg)
+			holders[key] = append(holders[key], idx)
+			values[key] = arg
+		}
+		if len(holders) == 0 {
+			continue
+		}
+
+		owners, err := uniqueValueOwners(ctx, h.db.Query, collection, col, slices.Collect(maps.Values(values)), dialect)
+		if err != nil {
+			return nil, err
+		}
+		for key, idxs := range holders {
+			// A value already stored is only free for the record holding it
+			for _, idx := range idxs {
+				if slices.ContainsFunc(owners[key], func(owner string) bool { return !update || owner != results[idx].ID }) {
+					if result := fail(idx, apperrors.CodeUniqueViolation, fmt.Sprintf("unique constraint violation: a record with this %s already exists", col.Name)); result != nil {
+						result.Field = col.Name
+					}
+				}
+			}
+			for _, idx := range idxs[1:] {
+				if result := fail(idx, apperrors.CodeUniqueViolation, fmt.Sprintf("unique constraint violation: item %d of the batch has the same %s", idxs[0], col.Name)); result != nil {
+					result.Field = col.Name
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// validateUniqueColumns returns the columns whose values :validate looks up:
+// the columns unique on their own and, for the creates of a collection with
+// client ids, the id
+func validateUniqueColumns(collection *registry.Collection, update bool) []registry.Column {
+	var columns []registry.Column
+	if !update && collection.RecordIDType() == registry.IDTypeClient {
+		columns = append(columns, registry.Column{Name: "id", Type: registry.TypeString})
+	}
+	for _, col := range collection.Columns {
+		if _, err := uniqueColumn(col.Name, collection); err == nil {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+// uniqueValueOwners returns the ids of the records holding each of values in
+// col, keyed by uniqueKey, with ReferenceLookupSize values per lookup.
+// Soft-deleted records count, as the constraint still covers them.
+func uniqueValueOwners(ctx context.Context, q queryFunc, collection *registry.Collection, col registry.Column, values []any, dialect database.DialectType) (map[string][]string, error) {
+	owners := map[string][]string{}
+	column := query.QuoteIdent(dialect, col.Name)
+	for chunk := range slices.Chunk(values, constants.ReferenceLookupSize) {
+		placeholders := make([]string, len(chunk))
+		for i := range chunk {
+			placeholders[i] = query.Placeholder(dialect, i+1)
+		}
+		lookup := fmt.Sprintf("SELECT id, %s FROM %s WHERE %s IN (%s)",
+			column, query.QuoteIdent(dialect, collection.Name), column, strings.Join(placeholders, ", "))
+		rows, err := q(ctx, lookup, chunk...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			var value any
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			key := uniqueKey(value)
+			owners[key] = append(owners[key], id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return owners, nil
+}
+
+// recordRevisions returns the revision of each of ids that is a record of
+// the collection, with ReferenceLookupSize ids per lookup
+func recordRevisions(ctx context.Context, q queryFunc, collection *registry.Collection, ids []string, dialect database.DialectType) (map[string]int64, error) {
+	revs := make(map[string]int64, len(ids))
+	for chunk := range slices.Chunk(ids, constants.ReferenceLookupSize) {
+		placeholders := make([]string, len(chunk))
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = query.Placeholder(dialect, i+1)
+			args[i] = id
+		}
+		lookup := fmt.Sprintf("SELECT id, %s FROM %s WHERE id IN (%s)",
+			query.QuoteIdent(dialect, constants.RevisionColumn), query.QuoteIdent(dialect, collection.Name), strings.Join(placeholders, ", "))
+		rows, err := q(ctx, lookup, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			var rev int64
+			if err := rows.Scan(&id, &rev); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			revs[id] = rev
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return revs, nil
+}
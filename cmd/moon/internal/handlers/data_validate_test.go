@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// setupValidateTest creates an authors collection with one record and a
+// notes collection with a unique email and a reference to authors, holding
+// two notes. It returns the handler, the author id and the note ids.
+func setupValidateTest(t *testing.T) (*DataHandler, string, []string) {
+	t.Helper()
+	driver := newTestDriver(t)
+
+	reg := registry.NewSchemaRegistry()
+	collections := NewCollectionsHandler(driver, reg)
+	for _, body := range []map[string]any{
+		{"name": "authors", "columns": []map[string]any{{"name": "name", "type": "string"}}},
+		{"name": "notes", "columns": []map[string]any{
+			{"name": "title", "type": "string", "nullable": false},
+			{"name": "email", "type": "string", "nullable": true, "unique": true},
+			{"name": "quantity", "type": "integer", "nullable": true, "min": 0},
+			{"name": "author_id", "type": "string", "nullable": true, "references": "authors"},
+		}},
+	} {
+		if w := postCollections(collections.Create, body); w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	handler := NewDataHandler(driver, reg, testConfig())
+	payload, _ := json.Marshal(map[string]any{"data": map[string]any{"name": "ada"}})
+	w := httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/authors:create", bytes.NewReader(payload)), "authors")
+	var author CreateDataResponse
+	json.Unmarshal(w.Body.Bytes(), &author)
+
+	notes := []string{
+		createNote(t, handler, map[string]any{"title": "first", "email": "taken@example.com"})["id"].(string),
+		createNote(t, handler, map[string]any{"title": "second", "email": "other@example.com"})["id"].(string),
+	}
+	return handler, author.Data["id"].(string), notes
+}
+
+// validateNotes runs a :validate and decodes its report
+func validateNotes(t *testing.T, handler *DataHandler, url string, data any) BatchResponse {
+	t.Helper()
+	w := doDataAction(t, handler.Validate, http.MethodPost, url, map[string]any{"data": data})
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: expected 200, got %d %s", url, w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp
+}
+
+// checkResults compares the status, error code and field of each result
+func checkResults(t *testing.T, resp BatchResponse, want []BatchItemResult) {
+	t.Helper()
+	if len(resp.Results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), resp.Results)
+	}
+	for i, result := range resp.Results {
+		if result.Index != i || result.Status != want[i].Status || result.ErrorCode != want[i].ErrorCode || result.Field != want[i].Field {
+			t.Errorf("item %d: expected %s %s %s, got %+v", i, want[i].Status, want[i].ErrorCode, want[i].Field, result)
+		}
+	}
+}
+
+func TestValidate_CreateBatch(t *testing.T) {
+	handler, author, _ := setupValidateTest(t)
+
+	resp := validateNotes(t, handler, "/notes:validate", []map[string]any{
+		{"title": "ok", "email": "new@example.com", "author_id": author},
+		{"email": "untitled@example.com"},
+		{"title": "colour", "colour": "red"},
+		{"title": "typed", "quantity": "many"},
+		{"title": "negative", "quantity": -1},
+		{"title": "taken", "email": "taken@example.com"},
+		{"title": "again", "email": "new@example.com"},
+		{"title": "orphan", "author_id": "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{"title": "also ok", "email": "another@example.com"},
+	})
+	checkResults(t, resp, []BatchItemResult{
+		{Status: BatchItemValid},
+		{Status: BatchItemInvalid, ErrorCode: "validation_required_field"},
+		{Status: BatchItemInvalid, ErrorCode: "validation_unknown_field"},
+		{Status: BatchItemInvalid, ErrorCode: "validation_invalid_type"},
+		{Status: BatchItemInvalid, ErrorCode: "validation_invalid_value"},
+		{Status: BatchItemInvalid, ErrorCode: "unique_violation", Field: "email"},
+		{Status: BatchItemInvalid, ErrorCode: "unique_violation", Field: "email"},
+		{Status: BatchItemInvalid, ErrorCode: "invalid_reference"},
+		{Status: BatchItemValid},
+	})
+	if want := (BatchSummary{Total: 9, Succeeded: 2, Failed: 7}); resp.Summary != want {
+		t.Errorf("expected summary %+v, got %+v", want, resp.Summary)
+	}
+	if msg := resp.Results[6].ErrorMessage; msg != "unique constraint violation: item 0 of the batch has the same email" {
+		t.Errorf("expected the duplicate named after item 0, got %q", msg)
+	}
+
+	// Nothing was written
+	if n := countNotes(t, handler); n != 2 {
+		t.Errorf("expected 2 records, got %d", n)
+	}
+}
+
+func TestValidate_UpdateBatch(t *testing.T) {
+	handler, _, notes := setupValidateTest(t)
+
+	resp := validateNotes(t, handler, "/notes:validate?action=update", []map[string]any{
+		{"id": notes[0], "email": "taken@example.com", "title": "renamed"},
+		{"id": notes[1], "email": "taken@example.com"},
+		{"id": "01ARZ3NDEKTSV4RRFFQ69G5FAV", "title": "missing"},
+		{"id": notes[0], "_rev": 7, "title": "stale"},
+		{"title": "no id"},
+		{"id": notes[1], "title": nil},
+	})
+	checkResults(t, resp, []BatchItemResult{
+		{Status: BatchItemValid},
+		{Status: BatchItemInvalid, ErrorCode: "unique_violation", Field: "email"},
+		{Status: BatchItemInvalid, ErrorCode: "record_not_found"},
+		{Status: BatchItemInvalid, ErrorCode: "revision_conflict"},
+		{Status: BatchItemInvalid, ErrorCode: "validation_required_field"},
+		{Status: BatchItemInvalid, ErrorCode: "validation_null_field"},
+	})
+	if rev := resp.Results[3].CurrentRev; rev == nil || *rev != 1 {
+		t.Errorf("expected current_rev 1, got %v", rev)
+	}
+
+	// The records are unchanged
+	w := doDataAction(t, handler.Get, http.MethodGet, "/notes:get?id="+notes[0], nil)
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"title":"first"`)) {
+		t.Errorf("expected the record unchanged, got %s", w.Body.String())
+	}
+}
+
+func TestValidate_SingleRecord(t *testing.T) {
+	handler, _, _ := setupValidateTest(t)
+
+	resp := validateNotes(t, handler, "/notes:validate", map[string]any{"title": "one", "email": "taken@example.com"})
+	checkResults(t, resp, []BatchItemResult{{Status: BatchItemInvalid, ErrorCode: "unique_violation", Field: "email"}})
+
+	w := doDataAction(t, handler.Validate, http.MethodPost, "/notes:validate?action=destroy", map[string]any{"data": map[string]any{"title": "one"}})
+	if w.Code != http.StatusBadRequest || errorCode(w) != "invalid_parameter" {
+		t.Errorf("expected 400 invalid_parameter, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/webhook"
+)
+
+// publish notes the records changed by one request for its audit entry,
+// records them in the change feed and queues a webhook event for them
+func (h *DataHandler) publish(ctx context.Context, collectionName, action string, ids []string, data []map[string]any) {
+	audit.AddRecords(ctx, ids...)
+	h.changes.Record(collectionName, action, ids)
+	if h.webhooks == nil || len(ids) == 0 {
+		return
+	}
+	h.webhooks.Publish(webhook.NewEvent(collectionName, action, ids, data))
+}
+
+// publishResults records the items of a best-effort batch that succeeded
+// with the given status in the change feed and queues a webhook event for
+// them. The batch writer has already noted them for the audit entry.
+func (h *DataHandler) publishResults(collectionName, action string, results []BatchItemResult, status BatchItemStatus) {
+	var ids []string
+	var data []map[string]any
+	for _, result := range results {
+		if result.Status != status {
+			continue
+		}
+		ids = append(ids, result.ID)
+		if result.Data != nil {
+			data = append(data, result.Data)
+		}
+	}
+	h.changes.Record(collectionName, action, ids)
+	if h.webhooks != nil && len(ids) > 0 {
+		h.webhooks.Publish(webhook.NewEvent(collectionName, action, ids, data))
+	}
+}
+
+// recordIDs returns the id of each response record
+func recordIDs(records []map[string]any) []string {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i], _ = record["id"].(string)
+	}
+	return ids
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/webhook"
+)
+
+func TestDataHandler_Webhooks_Integration(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	var mu sync.Mutex
+	var events []webhook.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhook.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dispatcher := webhook.New(config.WebhooksConfig{
+		QueueSize:   10,
+		MaxAttempts: 1,
+		Timeout:     5,
+		Endpoints:   []config.WebhookEndpointConfig{{URL: srv.URL, Secret: "s3cret", Events: []string{"products:create", "*:destroy"}}},
+	})
+	handler.SetWebhooks(dispatcher)
+
+	w := postData(t, handler.Create, "/products:create?atomic=true", map[string]any{"data": []map[string]any{
+		{"name": "Lamp", "price": 30},
+		{"name": "Desk", "price": 90},
+	}}, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created BatchCreateResponse
+	json.Unmarshal(w.Body.Bytes(), &created)
+	id := created.Data[0]["id"].(string)
+
+	// Updates are not subscribed to; failed writes publish nothing
+	postData(t, handler.Update, "/products:update", map[string]any{"data": map[string]any{"id": id, "price": 35}}, "")
+	postData(t, handler.Destroy, "/products:destroy", map[string]any{"data": "01ARYZ6S41TSV4RRFFQ69G5FAV"}, "")
+	w = postData(t, handler.Destroy, "/products:destroy", map[string]any{"data": id}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if err := dispatcher.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("expected create and destroy events, got %+v", events)
+	}
+	byEvent := map[string]webhook.Event{}
+	for _, event := range events {
+		byEvent[event.Event] = event
+	}
+	if create := byEvent["products:create"]; len(create.IDs) != 2 || len(create.Data) != 2 || create.Data[1]["name"] != "Desk" {
+		t.Errorf("unexpected create event: %+v", create)
+	}
+	if destroy := byEvent["products:destroy"]; len(destroy.IDs) != 1 || destroy.IDs[0] != id || destroy.Data != nil {
+		t.Errorf("unexpected destroy event: %+v", destroy)
+	}
+}
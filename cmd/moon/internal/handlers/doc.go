@@ -7,9 +7,11 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"log"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"text/template"
@@ -17,6 +19,10 @@ import (
 
 	"github.com/thalib/moon/cmd/moon/internal/config"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/query"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
@@ -30,6 +36,10 @@ var docTemplateContent string
 //go:embed templates/md/*.md
 var mdFiles embed.FS
 
+// docExampleBaseURL is the server address used by the curl examples in
+// templates/md; it is replaced with the resolved base URL and prefix
+const docExampleBaseURL = "http://localhost:6006"
+
 // DocData holds the data passed to the documentation template
 type DocData struct {
 	ServiceName   string
@@ -40,22 +50,33 @@ type DocData struct {
 	APIKeyEnabled bool
 	APIKeyHeader  string
 	Collections   []string
+	Sample        DocSample
 	JSONAppendix  string
 }
 
+// maxDocCacheEntries bounds how many base URLs each document is cached for
+const maxDocCacheEntries = 16
+
+// cachedDoc is a rendered document and its ETag
+type cachedDoc struct {
+	body []byte
+	etag string
+}
+
 // DocHandler handles documentation endpoints
 type DocHandler struct {
 	registry     *registry.SchemaRegistry
 	config       *config.AppConfig
 	version      string
 	cacheMutex   sync.RWMutex
-	htmlCache    []byte
-	mdCache      []byte
-	htmlETag     string
-	mdETag       string
+	htmlCache    map[string]cachedDoc // keyed by base URL
+	mdCache      map[string]cachedDoc
+	openapiCache map[string]cachedDoc
 	lastModified time.Time
 	mdTemplate   *template.Template
 	mdConverter  goldmark.Markdown
+	schemaEvents <-chan registry.SchemaEvent // changes since the documents were generated
+	db           database.Driver             // reads a record id for the sample collection examples; may be nil
 }
 
 // NewDocHandler creates a new documentation handler
@@ -118,6 +139,9 @@ func NewDocHandler(reg *registry.SchemaRegistry, cfg *config.AppConfig, version
 		),
 	)
 
+	// The handler lives as long as the registry, so it never unsubscribes
+	events, _ := reg.Subscribe()
+
 	return &DocHandler{
 		registry:     reg,
 		config:       cfg,
@@ -125,164 +149,256 @@ func NewDocHandler(reg *registry.SchemaRegistry, cfg *config.AppConfig, version
 		lastModified: time.Now(),
 		mdTemplate:   tmpl,
 		mdConverter:  md,
+		schemaEvents: events,
 	}
 }
 
 // HTML serves the HTML documentation
 func (h *DocHandler) HTML(w http.ResponseWriter, r *http.Request) {
-	h.cacheMutex.RLock()
-	cached := h.htmlCache
-	etag := h.htmlETag
-	h.cacheMutex.RUnlock()
+	h.serveCached(w, r, &h.htmlCache, "html", "text/html; charset=utf-8", func(baseURL, tenant string) ([]byte, error) {
+		html, err := h.generateHTML(baseURL, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate HTML documentation: %w", err)
+		}
+		return []byte(html), nil
+	})
+}
 
-	// Generate if not cached
-	if cached == nil {
-		h.cacheMutex.Lock()
-		// Double-check after acquiring write lock
-		if h.htmlCache == nil {
-			html, err := h.generateHTML()
-			if err != nil {
-				log.Printf("ERROR: Failed to generate HTML documentation: %v", err)
-				http.Error(w, "Failed to generate documentation", http.StatusInternalServerError)
-				h.cacheMutex.Unlock()
-				return
-			}
-			h.htmlCache = []byte(html)
-			h.htmlETag = fmt.Sprintf(`"html-%d"`, time.Now().Unix())
+// Markdown serves the Markdown documentation
+func (h *DocHandler) Markdown(w http.ResponseWriter, r *http.Request) {
+	h.serveCached(w, r, &h.mdCache, "md", "text/markdown; charset=utf-8", func(baseURL, tenant string) ([]byte, error) {
+		md, err := h.generateMarkdown(baseURL, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Markdown documentation: %w", err)
 		}
-		cached = h.htmlCache
-		etag = h.htmlETag
-		h.cacheMutex.Unlock()
+		return []byte(md), nil
+	})
+}
+
+// JSON serves the JSON appendix
+func (h *DocHandler) JSON(w http.ResponseWriter, r *http.Request) {
+	h.applySchemaEvents()
+
+	// Build JSON appendix
+	jsonAppendix := h.buildJSONAppendix(h.resolveBaseURL(r))
+
+	// Check if JSON appendix is empty or error
+	if jsonAppendix == "" {
+		log.Printf("ERROR: JSON appendix is missing")
+		writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "JSON appendix not available")
+		return
+	}
+
+	// Check if appendix contains error
+	if strings.Contains(jsonAppendix, `"error"`) {
+		log.Printf("ERROR: Failed to generate JSON appendix")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "Failed to generate JSON appendix")
+		return
 	}
 
 	// Set cache headers
-	w.Header().Set(constants.HeaderContentType, "text/html; charset=utf-8")
+	w.Header().Set(constants.HeaderContentType, "application/json; charset=utf-8")
 	w.Header().Set("Cache-Control", "public, max-age=3600")
-	w.Header().Set("ETag", etag)
+	h.setVary(w)
 	w.Header().Set("Last-Modified", h.lastModified.UTC().Format(http.TimeFormat))
 
-	// Check If-None-Match header
-	if match := r.Header.Get("If-None-Match"); match == etag {
-		w.WriteHeader(http.StatusNotModified)
-		return
-	}
-
 	w.WriteHeader(http.StatusOK)
-	w.Write(cached)
+	w.Write([]byte(jsonAppendix))
 }
 
-// Markdown serves the Markdown documentation
-func (h *DocHandler) Markdown(w http.ResponseWriter, r *http.Request) {
+// OpenAPI serves the OpenAPI 3.0 specification generated from the schema registry
+func (h *DocHandler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.serveCached(w, r, &h.openapiCache, "openapi", "application/json; charset=utf-8", func(baseURL, tenant string) ([]byte, error) {
+		spec, err := h.generateOpenAPI(baseURL, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate OpenAPI specification: %w", err)
+		}
+		return spec, nil
+	})
+}
+
+// serveCached writes a generated document, rendering it on first use for the
+// request's base URL and tenant. Each pair gets its own cache entry and ETag
+// so that clients reaching the server through different hosts never see each
+// other's examples, and tenants never see each other's collections.
+func (h *DocHandler) serveCached(w http.ResponseWriter, r *http.Request, cache *map[string]cachedDoc, kind, contentType string, generate func(baseURL, tenant string) ([]byte, error)) {
+	h.applySchemaEvents()
+	baseURL := h.resolveBaseURL(r)
+	tenant := middleware.GetTenant(r.Context())
+	key := baseURL
+	if tenant != "" {
+		key = tenant + " " + baseURL
+	}
+
 	h.cacheMutex.RLock()
-	cached := h.mdCache
-	etag := h.mdETag
+	doc, ok := (*cache)[key]
 	h.cacheMutex.RUnlock()
 
 	// Generate if not cached
-	if cached == nil {
+	if !ok {
 		h.cacheMutex.Lock()
 		// Double-check after acquiring write lock
-		if h.mdCache == nil {
-			md, err := h.generateMarkdown()
+		if doc, ok = (*cache)[key]; !ok {
+			body, err := generate(baseURL, tenant)
 			if err != nil {
-				log.Printf("ERROR: Failed to generate Markdown documentation: %v", err)
-				http.Error(w, "Failed to generate documentation", http.StatusInternalServerError)
+				log.Printf("ERROR: %v", err)
+				writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "Failed to generate documentation")
 				h.cacheMutex.Unlock()
 				return
 			}
-			h.mdCache = []byte(md)
-			h.mdETag = fmt.Sprintf(`"md-%d"`, time.Now().Unix())
+			// Forwarded hosts are client-controlled; bound the number of entries
+			if *cache == nil || len(*cache) >= maxDocCacheEntries {
+				*cache = make(map[string]cachedDoc)
+			}
+			doc = cachedDoc{
+				body: body,
+				etag: fmt.Sprintf(`"%s-%d-%08x"`, kind, time.Now().Unix(), crc32.ChecksumIEEE([]byte(key))),
+			}
+			(*cache)[key] = doc
 		}
-		cached = h.mdCache
-		etag = h.mdETag
 		h.cacheMutex.Unlock()
 	}
 
-	// Set cache headers
-	w.Header().Set(constants.HeaderContentType, "text/markdown; charset=utf-8")
-	w.Header().Set("Cache-Control", "public, max-age=3600")
-	w.Header().Set("ETag", etag)
+	// Set cache headers; a tenant's documents are for the tenant only
+	w.Header().Set(constants.HeaderContentType, contentType)
+	if tenant != "" {
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+	w.Header().Set("ETag", doc.etag)
+	h.setVary(w)
 	w.Header().Set("Last-Modified", h.lastModified.UTC().Format(http.TimeFormat))
 
 	// Check If-None-Match header
-	if match := r.Header.Get("If-None-Match"); match == etag {
+	if match := r.Header.Get("If-None-Match"); match == doc.etag {
 		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write(cached)
+	w.Write(doc.body)
 }
 
-// JSON serves the JSON appendix
-func (h *DocHandler) JSON(w http.ResponseWriter, r *http.Request) {
-	// Build JSON appendix
-	jsonAppendix := h.buildJSONAppendix()
-
-	// Check if JSON appendix is empty or error
-	if jsonAppendix == "" {
-		log.Printf("ERROR: JSON appendix is missing")
-		w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]any{
-			"error": "JSON appendix not available",
-			"code":  http.StatusNotFound,
-		})
-		return
+// setVary tells shared caches that documentation depends on the request host
+// unless server.public_url pins it, and with tenancy enabled on the
+// credentials naming the caller's tenant
+func (h *DocHandler) setVary(w http.ResponseWriter) {
+	var vary []string
+	if h.config.Server.PublicURL == "" {
+		vary = append(vary, "Host", "X-Forwarded-Host", "X-Forwarded-Proto")
 	}
+	if h.config.Tenancy.Enabled {
+		vary = append(vary, constants.HeaderAuthorization)
+		if h.config.APIKey.Header != "" {
+			vary = append(vary, h.config.APIKey.Header)
+		}
+	}
+	if len(vary) > 0 {
+		w.Header().Set("Vary", strings.Join(vary, ", "))
+	}
+}
 
-	// Check if appendix contains error
-	if strings.Contains(jsonAppendix, `"error"`) {
-		log.Printf("ERROR: Failed to generate JSON appendix")
-		w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]any{
-			"error": "Failed to generate JSON appendix",
-			"code":  http.StatusInternalServerError,
-		})
-		return
+// resolveBaseURL returns the scheme and host used in documentation examples
+func (h *DocHandler) resolveBaseURL(r *http.Request) string {
+	return RequestBaseURL(h.config.Server, r)
+}
+
+// defaultBaseURL is the base URL used when no request is available
+func (h *DocHandler) defaultBaseURL() string {
+	return serverBaseURL(h.config.Server)
+}
+
+// firstForwardedValue returns the left-most entry of a comma-separated
+// forwarding header, which is the one set by the proxy closest to the client
+func firstForwardedValue(value string) string {
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		value = value[:i]
 	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
 
-	// Set cache headers
-	w.Header().Set(constants.HeaderContentType, "application/json; charset=utf-8")
-	w.Header().Set("Cache-Control", "public, max-age=3600")
-	w.Header().Set("Last-Modified", h.lastModified.UTC().Format(http.TimeFormat))
+// validDocHost reports whether host is a plain host[:port] that is safe to
+// embed in generated Markdown and HTML
+func validDocHost(host string) bool {
+	if host == "" || len(host) > 255 {
+		return false
+	}
+	for _, c := range host {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == ':', c == '[', c == ']':
+		default:
+			return false
+		}
+	}
+	return true
+}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(jsonAppendix))
+// SetDatabase sets the database the quickstart reads a record of the sample
+// collection from, so its examples name a real record
+func (h *DocHandler) SetDatabase(db database.Driver) {
+	h.db = db
 }
 
 // RefreshCache clears the cached documentation
 func (h *DocHandler) RefreshCache(w http.ResponseWriter, r *http.Request) {
+	h.ClearCache()
+
+	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"Documentation cache refreshed"}`))
+}
+
+// ClearCache drops the generated documentation so it is rebuilt from the
+// registry on the next request
+func (h *DocHandler) ClearCache() {
 	h.cacheMutex.Lock()
 	h.htmlCache = nil
 	h.mdCache = nil
-	h.htmlETag = ""
-	h.mdETag = ""
+	h.openapiCache = nil
 	h.lastModified = time.Now()
 	h.cacheMutex.Unlock()
+}
 
-	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message":"Documentation cache refreshed"}`))
+// applySchemaEvents clears the cache when collections changed since the last
+// request. Registry events are sent before the change returns, so a request
+// made after a schema change always sees it. Events dropped because nobody
+// read them leave a full buffer behind, which is still read as a change.
+func (h *DocHandler) applySchemaEvents() {
+	changed := false
+	for {
+		select {
+		case <-h.schemaEvents:
+			changed = true
+			continue
+		default:
+		}
+		break
+	}
+	if changed {
+		h.ClearCache()
+	}
 }
 
-// generateMarkdown generates the Markdown documentation from the template
-func (h *DocHandler) generateMarkdown() (string, error) {
-	data := h.buildDocData()
+// generateMarkdown generates the Markdown documentation of a tenant from the
+// template
+func (h *DocHandler) generateMarkdown(baseURL, tenant string) (string, error) {
+	data := h.buildDocData(baseURL, tenant)
 
 	var buf bytes.Buffer
 	if err := h.mdTemplate.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	return buf.String(), nil
+	// Included sections are written against docExampleBaseURL
+	return strings.ReplaceAll(buf.String(), docExampleBaseURL, baseURL+h.config.Server.Prefix), nil
 }
 
 // generateHTML generates the HTML documentation by converting rendered Markdown
-func (h *DocHandler) generateHTML() (string, error) {
+func (h *DocHandler) generateHTML(baseURL, tenant string) (string, error) {
 	// First generate the Markdown
-	markdownContent, err := h.generateMarkdown()
+	markdownContent, err := h.generateMarkdown(baseURL, tenant)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate markdown: %w", err)
 	}
@@ -364,9 +480,9 @@ document.addEventListener('DOMContentLoaded', function() {
 }
 
 // buildDocData constructs the data structure for the template
-func (h *DocHandler) buildDocData() DocData {
-	collections := h.getCollectionNames()
-	baseURL := fmt.Sprintf("http://localhost:%d", h.config.Server.Port)
+func (h *DocHandler) buildDocData(baseURL, tenant string) DocData {
+	collections := h.getCollectionNames(tenant)
+	sample := h.buildDocSample(h.documentedCollections(tenant), time.Now())
 
 	return DocData{
 		ServiceName:   "moon",
@@ -377,17 +493,41 @@ func (h *DocHandler) buildDocData() DocData {
 		APIKeyEnabled: h.config.APIKey.Enabled,
 		APIKeyHeader:  h.config.APIKey.Header,
 		Collections:   collections,
-		JSONAppendix:  h.buildJSONAppendix(),
+		Sample:        sample,
+		JSONAppendix:  h.buildJSONAppendix(baseURL),
 	}
 }
 
-// getCollectionNames returns a sorted list of collection names
-func (h *DocHandler) getCollectionNames() []string {
+// documentedCollections returns the collections the documentation of a
+// tenant describes: archived collections are left out, and with tenancy
+// enabled only the tenant's collections are described, named as the tenant
+// knows them. Callers outside every tenant see the collections of no tenant.
+func (h *DocHandler) documentedCollections(tenant string) []*registry.Collection {
 	collections := h.registry.GetAll()
+	documented := make([]*registry.Collection, 0, len(collections))
+	for _, c := range collections {
+		if c.Archived {
+			continue
+		}
+		if h.config.Tenancy.Enabled {
+			if owner, _ := registry.SplitTenantKey(c.Name); owner != tenant {
+				continue
+			}
+			c = tenantView(c)
+		}
+		documented = append(documented, c)
+	}
+	return documented
+}
+
+// getCollectionNames returns a sorted list of the collection names of a tenant
+func (h *DocHandler) getCollectionNames(tenant string) []string {
+	collections := h.documentedCollections(tenant)
 	names := make([]string, 0, len(collections))
 	for _, c := range collections {
 		names = append(names, c.Name)
 	}
+	slices.Sort(names)
 	return names
 }
 
@@ -435,7 +575,7 @@ type DataTypeInfo struct {
 }
 
 // buildJSONAppendix generates a dynamic JSON appendix from the registry and config
-func (h *DocHandler) buildJSONAppendix() string {
+func (h *DocHandler) buildJSONAppendix(baseURL string) string {
 	// Prepare authentication modes
 	authModes := []string{}
 	tokenFormats := map[string]string{}
@@ -458,7 +598,7 @@ func (h *DocHandler) buildJSONAppendix() string {
 	appendix := JSONAppendixData{
 		Service:   "moon",
 		Version:   h.version,
-		BaseURL:   fmt.Sprintf("http://localhost:%d", h.config.Server.Port),
+		BaseURL:   baseURL,
 		URLPrefix: urlPrefix,
 		Authentication: AuthInfo{
 			Modes:        authModes,
@@ -498,7 +638,7 @@ func (h *DocHandler) buildJSONAppendix() string {
 				Description: "Read-only ULID (128-bit, 26-character, URL-safe unique ID) generated by the server.",
 				SQLMapping:  "CHAR(26)",
 				Example:     "01H1X5Y6Z7A8B9C0D1E2F3G4H5",
-				Note:        "Automatically assigned; globally unique and sortable.",
+				Note:        "Automatically assigned; globally unique and sortable. Collections created with id_type uuidv7 use CHAR(36) UUIDv7s, id_type client VARCHAR(64) ids supplied on create.",
 			},
 			{
 				Name:        "string",
@@ -519,15 +659,15 @@ func (h *DocHandler) buildJSONAppendix() string {
 				Description: "true/false values",
 				SQLMapping:  "BOOLEAN",
 				Example:     true,
-				Note:        "Nullable fields default to false when null",
+				Note:        "Nullable fields default to false when null. Filters take true, false, 1 or 0 (case-insensitive); writes take JSON booleans, or those strings and 0/1 with api.lenient_booleans",
 			},
 			{
 				Name:        "datetime",
-				Description: "Date/time in RFC3339 or ISO 8601 format",
+				Description: "Date/time in RFC3339, or a date meaning midnight UTC",
 				SQLMapping:  "DATETIME",
 				Example:     "2023-01-31T13:45:00Z",
 				Format:      "RFC3339",
-				Note:        "Stored as ISO 8601, nullable fields default to empty string when null",
+				Note:        "Stored and returned in UTC RFC3339; other formats are rejected",
 			},
 			{
 				Name:        "json",
@@ -550,7 +690,13 @@ func (h *DocHandler) buildJSONAppendix() string {
 				"path":          "/health",
 				"method":        "GET",
 				"auth_required": false,
-				"description":   "Health check endpoint",
+				"description":   "Health check with database status and build info, returns 503 when the database is unreachable; served with /admin:* and /doc:refresh on server.admin_port when it is set",
+			},
+			"liveness": map[string]any{
+				"path":          "/health/live",
+				"method":        "GET",
+				"auth_required": false,
+				"description":   "Liveness probe that never touches the database",
 			},
 			"authentication": map[string]any{
 				"login": map[string]any{
@@ -564,14 +710,14 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"path":          "/auth:logout",
 					"method":        "POST",
 					"auth_required": true,
-					"description":   "Invalidate current session's refresh token",
+					"description":   "Revoke current session's refresh token",
 					"example":       "/auth:logout with JSON body {\"refresh_token\": \"$REFRESH_TOKEN\"}",
 				},
 				"refresh": map[string]any{
 					"path":          "/auth:refresh",
 					"method":        "POST",
 					"auth_required": false,
-					"description":   "Exchange refresh token for new access token",
+					"description":   "Exchange refresh token for a new token pair; the old refresh token is revoked and replaying it revokes the whole session",
 					"example":       "/auth:refresh with JSON body {\"refresh_token\": \"$REFRESH_TOKEN\"}",
 				},
 				"me": map[string]any{
@@ -652,18 +798,24 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"method":        "POST",
 					"auth_required": true,
 					"role_required": "admin",
-					"description":   "Create new API key, returns the generated key value which is only shown once",
-					"example":       "/apikeys:create with JSON body {\"name\": \"My API Key\", \"can_write\": [\"true\"]}",
+					"description":   "Create new API key, returns the generated key value which is only shown once. Optional scopes restrict the key to collections and actions (read, write, schema); out-of-scope requests return 403 insufficient_scope. Optional expires_at (RFC 3339) makes the key stop working at that time with 401 api_key_expired",
+					"examples": []string{
+						"/apikeys:create with JSON body {\"name\": \"My API Key\", \"can_write\": [\"true\"]}",
+						"/apikeys:create with JSON body {\"name\": \"Product Reader\", \"role\": \"user\", \"scopes\": [{\"collection\": \"products\", \"actions\": [\"read\"]}]}",
+						"/apikeys:create with JSON body {\"name\": \"Nightly Export\", \"role\": \"user\", \"expires_at\": \"2026-12-31T00:00:00Z\"}",
+					},
 				},
 				"update": map[string]any{
 					"path":          "/apikeys:update?id={key_id}",
 					"method":        "POST",
 					"auth_required": true,
 					"role_required": "admin",
-					"description":   "Update API key details or perform actions like rotation",
+					"description":   "Update API key details or perform actions like rotation and revocation. A revoked key is kept for auditing but returns 401 api_key_revoked",
 					"examples": []string{
 						"/apikeys:update?id=01KHCZKCR7MHB0Q69KM63D6AXF with JSON body {\"name\": \"Renamed API Key\", \"can_write\": true}",
 						"/apikeys:update?id=01KHCZKCR7MHB0Q69KM63D6AXF with JSON body { \"action\": \"rotate\"}",
+						"/apikeys:update?id=01KHCZKCR7MHB0Q69KM63D6AXF with JSON body { \"action\": \"revoke\"}",
+						"/apikeys:update?id=01KHCZKCR7MHB0Q69KM63D6AXF with JSON body {\"scopes\": [{\"collection\": \"*\", \"actions\": [\"read\", \"write\"]}]}",
 					},
 				},
 				"destroy": map[string]any{
@@ -681,7 +833,7 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"method":        "GET",
 					"auth_required": true,
 					"role_required": "admin",
-					"description":   "List all collections (tables) in the database",
+					"description":   "List all collections (tables) in the database; archived ones only with ?include_archived=true",
 					"example":       "/collections:list",
 				},
 				"get": map[string]any{
@@ -705,8 +857,8 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"method":        "POST",
 					"auth_required": true,
 					"role_required": "admin",
-					"operations":    []string{"add_columns", "rename_columns", "modify_columns", "remove_columns"},
-					"description":   "Update collection schema",
+					"operations":    []string{"add_columns", "rename_columns", "modify_columns", "remove_columns", "indexes", "remove_indexes"},
+					"description":   "Update collection schema in one transaction where the database supports transactional DDL; dry_run=true returns the planned DDL and resulting columns without applying them; a type change that records cannot convert is refused with 409 incompatible_data unless force=true sets those values to null or the default; a new computed expression recomputes existing records",
 					"example":       "/collections:update with JSON body {\"name\": \"products\", \"add_columns\": [{\"name\": \"description\", \"type\": \"string\"}]}",
 				},
 				"destroy": map[string]any{
@@ -717,6 +869,54 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"description":   "Delete collection and all its data",
 					"example":       "/collections:destroy with JSON body {\"name\": \"old_collection\"}",
 				},
+				"rename": map[string]any{
+					"path":          "/collections:rename",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Rename collection and its table",
+					"example":       "/collections:rename with JSON body {\"name\": \"customers\", \"new_name\": \"customers_v2\"}",
+				},
+				"archive": map[string]any{
+					"path":          "/collections:archive",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Hide a collection from collections:list and the data endpoints, keeping its table and records",
+					"example":       "/collections:archive with JSON body {\"name\": \"old_events\"}",
+				},
+				"unarchive": map[string]any{
+					"path":          "/collections:unarchive",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Make an archived collection available again",
+					"example":       "/collections:unarchive with JSON body {\"name\": \"old_events\"}",
+				},
+				"duplicate": map[string]any{
+					"path":          "/collections:duplicate",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Create a collection with the schema of another, optionally copying its records with new ids",
+					"example":       "/collections:duplicate with JSON body {\"source\": \"products\", \"target\": \"products_staging\", \"copy_data\": true}",
+				},
+				"export": map[string]any{
+					"path":          "/collections:export",
+					"method":        "GET",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Return the schema of every collection as one document",
+					"example":       "/collections:export",
+				},
+				"import": map[string]any{
+					"path":          "/collections:import",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Create or sync collections from an exported schema document; modes create_missing, sync and dry_run",
+					"example":       "/collections:import with JSON body {\"mode\": \"dry_run\", \"collections\": [...]}",
+				},
 				"aggregation": map[string]any{
 					"count": map[string]any{
 						"path":          "/{collection}:count",
@@ -729,30 +929,56 @@ func (h *DocHandler) buildJSONAppendix() string {
 						"path":          "/{collection}:sum?field={field_name}",
 						"method":        "GET",
 						"auth_required": true,
-						"description":   "Sum numeric field (only integer and decimal fields, returns 0 for non-numeric fields)",
+						"description":   "Sum of an integer or decimal field; value is null and count 0 when no values match",
 						"example":       "/products:sum?field=quantity",
 					},
 					"avg": map[string]any{
 						"path":          "/{collection}:avg?field={field_name}",
 						"method":        "GET",
 						"auth_required": true,
-						"description":   "Average numeric field (only integer and decimal fields, returns 0 for non-numeric fields)",
+						"description":   "Average of an integer or decimal field; value is null and count 0 when no values match",
 						"example":       "/products:avg?field=quantity",
 					},
 					"min": map[string]any{
 						"path":          "/{collection}:min?field={field_name}",
 						"method":        "GET",
 						"auth_required": true,
-						"description":   "Minimum value of a field (only integer, decimal, and datetime fields, returns null for non-numeric fields)",
+						"description":   "Minimum value of an integer, decimal, string or datetime field (including created_at and updated_at); value is null and count 0 when no values match",
 						"example":       "/products:min?field=quantity",
 					},
 					"max": map[string]any{
 						"path":          "/{collection}:max?field={field_name}",
 						"method":        "GET",
 						"auth_required": true,
-						"description":   "Maximum value of a field (only integer, decimal, and datetime fields, returns null for non-numeric fields)",
+						"description":   "Maximum value of an integer, decimal, string or datetime field (including created_at and updated_at); value is null and count 0 when no values match",
 						"example":       "/products:max?field=quantity",
 					},
+					"groupby": map[string]any{
+						"path":          "/{collection}:groupby?by={field_name}&agg={function}&field={field_name}",
+						"method":        "GET",
+						"auth_required": true,
+						"functions":     []string{"count", "sum", "avg", "min", "max"},
+						"max_groups":    constants.MaxGroupByGroups,
+						"description":   "Aggregate per distinct value of a column (agg defaults to count; field is required and must be numeric for other functions)",
+						"example":       "/products:groupby?by=category&agg=sum&field=price",
+					},
+					"timeseries": map[string]any{
+						"path":          "/{collection}:timeseries?field={datetime_field}&interval={interval}&agg={function}&value_field={field_name}",
+						"method":        "GET",
+						"auth_required": true,
+						"intervals":     query.TimeIntervals,
+						"max_buckets":   constants.MaxTimeSeriesBuckets,
+						"description":   "Aggregate per hour, day, week (from Monday) or month of a datetime field in UTC; buckets without records between the first and last one, or from and to, are returned with 0 (null for avg, min and max)",
+						"example":       "/orders:timeseries?field=created_at&interval=day&agg=sum&value_field=total&from=2024-06-01&to=2024-06-30",
+					},
+					"distinct": map[string]any{
+						"path":          "/{collection}:distinct?field={field_name}&limit={n}&count={bool}",
+						"method":        "GET",
+						"auth_required": true,
+						"max_limit":     constants.MaxDistinctLimit,
+						"description":   "Distinct values of a column in ascending order (count=true returns value and occurrence pairs)",
+						"example":       "/products:distinct?field=category&limit=100",
+					},
 				},
 			},
 			"data_access": map[string]any{
@@ -760,7 +986,7 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"path":          "/{collection}:list",
 					"method":        "GET",
 					"auth_required": true,
-					"description":   "List records in collection",
+					"description":   "List records in collection, by cursor (after) or page number (page, per_page); Accept: text/csv or application/x-ndjson returns CSV or NDJSON",
 					"example":       "/products:list",
 				},
 				"get": map[string]any{
@@ -774,31 +1000,104 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"path":          "/{collection}:create",
 					"method":        "POST",
 					"auth_required": true,
-					"description":   "Create new record",
+					"description":   "Create new record; an Idempotency-Key header replays the stored response to retries, and journal=true records a best-effort batch as a job (X-Moon-Job)",
 					"example":       "/products:create with JSON body {\"name\": \"New products\", \"price\": 19.99}",
 				},
 				"update": map[string]any{
 					"path":          "/{collection}:update",
 					"method":        "POST",
 					"auth_required": true,
-					"description":   "Update existing record",
+					"description":   "Update existing record; ?return=diff adds the changed values, ?return=full returns the complete record",
 					"example":       "/products:update with JSON body {\"id\": \"01KHCZKSBQV1KH69AA6PVS12MM\", \"name\": \"Updated products\", \"price\": 29.99}",
 				},
 				"destroy": map[string]any{
 					"path":          "/{collection}:destroy",
 					"method":        "POST",
 					"auth_required": true,
-					"description":   "Delete record",
-					"example":       "/products:destroy with JSON body {\"id\": \"01KHCZKSBQV1KH69AA6PVS12MM\"}",
+					"description":   "Delete record, or every record matching a where filter (dry_run=true counts them; more than api.max_bulk_delete need force=true)",
+					"example":       "/products:destroy with JSON body {\"id\": \"01KHCZKSBQV1KH69AA6PVS12MM\"} or {\"where\": {\"expires_at\": {\"lt\": \"2024-01-01T00:00:00Z\"}}}",
+				},
+				"upsert": map[string]any{
+					"path":          "/{collection}:upsert",
+					"method":        "POST",
+					"auth_required": true,
+					"description":   "Insert or update records matched by a unique key column",
+					"example":       "/products:upsert with JSON body {\"key\": \"sku\", \"data\": {\"sku\": \"SKU-001\", \"name\": \"Keyboard\"}}",
+				},
+				"checkunique": map[string]any{
+					"path":          "/{collection}:checkunique",
+					"method":        "POST",
+					"auth_required": true,
+					"description":   "Report which of the given values of a unique field are already taken; a write of a taken value fails with 409 unique_violation naming the field and value",
+					"example":       "/products:checkunique with JSON body {\"field\": \"sku\", \"values\": [\"SKU-001\", \"SKU-999\"]}",
+				},
+				"explain": map[string]any{
+					"path":          "/{collection}:explain",
+					"method":        "GET",
+					"auth_required": true,
+					"description":   "Return the SQL, bound argument types and database plan :list would use for the same query parameters, without running the query; show_values=true includes the values (admins with the schema scope)",
+					"example":       "/products:explain?price[gt]=100&sort=-price",
+				},
+				"validate": map[string]any{
+					"path":          "/{collection}:validate",
+					"method":        "POST",
+					"auth_required": true,
+					"description":   "Run the checks of :create, or of :update with ?action=update, on a record or batch and report each item as valid or invalid without writing; unique values, references and updated records are looked up, and duplicates within the batch are reported",
+					"example":       "/products:validate?action=create with JSON body {\"data\": [{\"sku\": \"SKU-001\"}, {\"sku\": \"SKU-001\"}]}",
+				},
+				"transact": map[string]any{
+					"path":          "/batch:transact",
+					"method":        "POST",
+					"auth_required": true,
+					"description":   "Run create, update and destroy operations across collections in one transaction; \"$ref:<ref>.<field>\" takes a field of an earlier operation's record, and the first failure rolls back all of them",
+					"example":       "/batch:transact with JSON body [{\"collection\": \"orders\", \"action\": \"create\", \"data\": {\"number\": \"A-1\"}, \"ref\": \"order\"}, {\"collection\": \"order_items\", \"action\": \"create\", \"data\": {\"order_id\": \"$ref:order.id\"}}]",
+				},
+				"export": map[string]any{
+					"path":          "/{collection}:export?format={csv|json}",
+					"method":        "GET",
+					"auth_required": true,
+					"description":   "Stream all records matching the list filter, search, sort and fields parameters as CSV (default) or newline-delimited JSON",
+					"example":       "/products:export?format=csv&price[gte]=100&sort=-price",
+				},
+				"changes": map[string]any{
+					"path":          "/{collection}:changes?since={seq}&timeout={seconds}",
+					"method":        "GET",
+					"auth_required": true,
+					"description":   "Long-poll for records created, updated or deleted after a sequence; waits up to timeout seconds (default 30, max 60) and returns the changes with the next seq, or reset when changes were missed",
+					"example":       "/products:changes?since=42&timeout=30",
+				},
+				"import": map[string]any{
+					"path":          "/{collection}:import",
+					"method":        "POST",
+					"auth_required": true,
+					"description":   "Bulk import a multipart CSV (header row) or JSON array file in chunked transactions; returns imported/skipped counts and row errors (dry_run=true validates only); journal=true records the import as a job that stops at a failed chunk, and resume={job_id} with the same file imports the remaining rows exactly once",
+					"example":       "/products:import with multipart field file=@products.csv",
+				},
+				"restore": map[string]any{
+					"path":          "/{collection}:restore",
+					"method":        "POST",
+					"auth_required": true,
+					"description":   "Restore soft-deleted records (collections created with soft_delete: true; reads hide deleted records unless include_deleted=true)",
+					"example":       "/notes:restore with JSON body {\"data\": \"01KHCZKSBQV1KH69AA6PVS12MM\"}",
+				},
+				"stats": map[string]any{
+					"path":          "/{collection}:stats",
+					"method":        "GET",
+					"auth_required": true,
+					"description":   "Row count, approximate size, column count, newest created_at/updated_at and null counts per nullable column over at most stats.sample_size rows; cached for stats.cache_ttl seconds",
+					"example":       "/products:stats",
 				},
 				"query": map[string]any{
 					"filter": map[string]any{
 						"syntax":      "/{collection}:list?column[operator]=value",
-						"description": "Filter records based on column values using operators (eq, ne, gt, lt, gte, lte, like, in)",
+						"description": "Filter records based on column values using operators (eq, ne, gt, lt, gte, lte, like, in, isnull, notnull, between); a dotted name filters on a key inside a json column with eq, ne or like; boolean values are true, false, 1 or 0, case-insensitive",
 						"examples": []string{
 							"/products:list?price[gte]=100",
 							"/products:list?category[eq]=electronics",
-							"/products:list?name[like]=%mouse%",
+							"/products:list?name[like]=mouse",
+							"/products:list?details[isnull]=true",
+							"/products:list?price[between]=10,100",
+							"/products:list?meta.color[eq]=red",
 						},
 					},
 					"sorting": map[string]any{
@@ -816,16 +1115,35 @@ func (h *DocHandler) buildJSONAppendix() string {
 						"description": "Maximum number of records to return (default 50, max 1000)",
 						"example":     "/products:list?limit=10&after=01ARZ3NDEKTSV4RRFFQ69G5FBX",
 					},
+					"total": map[string]any{
+						"syntax":      "/{collection}:list?total={true|false}",
+						"description": "total=true counts the matching records, total=false skips the count and returns \"total\": null; omitted follows api.include_total_default (true unless configured)",
+						"example":     "/products:list?total=false&limit=50",
+					},
 					"search": map[string]any{
-						"syntax":      "/{collection}:list?q={search_term}",
-						"description": "Full text searches across all text/string columns",
-						"example":     "/products:list?q=wireless",
+						"syntax":      "/{collection}:list?q={search_term}&q_mode={contains|insensitive|prefix|exact}",
+						"description": "Full text searches across all text/string columns; q_mode=insensitive ignores case, prefix matches the start of a value and exact the whole value (default contains)",
+						"example":     "/products:list?q=wireless&q_mode=insensitive",
 					},
 					"field_selection": map[string]any{
 						"syntax":      "/{collection}:list?fields={field1,field2}",
 						"description": "Return only specified fields (id always included)",
 						"example":     "/products:list?fields=name,price",
 					},
+					"field_case": map[string]any{
+						"syntax":      "/{collection}:list?case={snake|camel}",
+						"description": "Name record fields as their columns (snake) or in camelCase (camel, unit_price as unitPrice) in reads and writes; omitted follows api.field_case. Writes accept either convention, query parameters use column names",
+						"example":     "/products:list?case=camel",
+					},
+					"filter_tree": map[string]any{
+						"path":           "/{collection}:query",
+						"method":         "POST",
+						"auth_required":  true,
+						"max_depth":      constants.MaxFilterDepth,
+						"max_conditions": constants.MaxFilterConditions,
+						"description":    "List with a JSON filter of nested and/or groups; the body also takes sort, fields, limit and after, and the response matches :list",
+						"example":        "/products:query with JSON body {\"filter\": {\"or\": [{\"status\": {\"eq\": \"active\"}}, {\"price\": {\"gt\": 100}}]}, \"sort\": \"-price\"}",
+					},
 					"combine_query_examples": []string{
 						"/products:list?quantity[gte]=10&price[lt]=100&sort=-price&limit=5",
 						"/products:list?q=laptop&brand[eq]=Wow&fields=title,price,quantity",
@@ -833,6 +1151,88 @@ func (h *DocHandler) buildJSONAppendix() string {
 					},
 				},
 			},
+			"admin": map[string]any{
+				"consistency": map[string]any{
+					"path":          "/admin:consistency?repair={true|false}",
+					"method":        "GET",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Compare the schema registry with the database; repair=true applies auto-repair",
+					"example":       "/admin:consistency",
+				},
+				"maintenance": map[string]any{
+					"path":          "/admin:maintenance",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Run vacuum, analyze or normalize_datetimes on the database; SQLite writes return 503 while vacuum runs",
+					"example":       "/admin:maintenance with JSON body {\"operation\": \"vacuum\"}",
+				},
+				"loglevel": map[string]any{
+					"path":          "/admin:loglevel",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Change the base log level or the level of one module at runtime",
+					"example":       "/admin:loglevel with JSON body {\"module\": \"handlers\", \"level\": \"debug\"}",
+				},
+				"audit": map[string]any{
+					"path":          "/admin:audit?collection={name}&after={id}&limit={n}",
+					"method":        "GET",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "List audit log entries of successful mutating requests, oldest first, when audit.enabled",
+					"example":       "/admin:audit?collection=products&limit=100",
+				},
+				"jobs": map[string]any{
+					"path":          "/admin:jobs?collection={name}&status={status}&after={id}&limit={n}",
+					"method":        "GET",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "List the jobs of journaled imports and batch creates, oldest first, with their status and progress",
+					"example":       "/admin:jobs?status=failed",
+				},
+				"jobs_get": map[string]any{
+					"path":          "/admin:jobs:get?id={job_id}",
+					"method":        "GET",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Return one job; running jobs left by a stop become interrupted at startup and imports among them can be resumed",
+					"example":       "/admin:jobs:get?id=01KHCZKSBQV1KH69AA6PVS12MM",
+				},
+				"backup": map[string]any{
+					"path":          "/admin:backup",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Write a snapshot of a SQLite file database to backup.directory; 501 for other databases",
+					"example":       "/admin:backup",
+				},
+				"backups": map[string]any{
+					"path":          "/admin:backups",
+					"method":        "GET",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "List the snapshots in backup.directory, newest first",
+					"example":       "/admin:backups",
+				},
+				"restore": map[string]any{
+					"path":          "/admin:restore",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Replace the database with a snapshot from admin:backups, reload the collections and run the consistency check; writes return 503 meanwhile",
+					"example":       "/admin:restore with JSON body {\"file\": \"moon-20240601T101500.123Z.db\"}",
+				},
+				"reset_demo": map[string]any{
+					"path":          "/admin:reset-demo",
+					"method":        "POST",
+					"auth_required": true,
+					"role_required": "admin",
+					"description":   "Drop and recreate the demo_tasks sample collection with its seed records",
+					"example":       "/admin:reset-demo",
+				},
+			},
 			"documentation": map[string]any{
 				"html": map[string]any{
 					"path":          "/doc/",
@@ -858,6 +1258,12 @@ func (h *DocHandler) buildJSONAppendix() string {
 					"auth_required": false,
 					"description":   "JSON appendix for machine consumption",
 				},
+				"openapi": map[string]any{
+					"path":          "/doc/openapi.json",
+					"method":        "GET",
+					"auth_required": false,
+					"description":   "OpenAPI 3.0 specification generated from registered collections",
+				},
 				"refresh": map[string]any{
 					"path":          "/doc:refresh",
 					"method":        "POST",
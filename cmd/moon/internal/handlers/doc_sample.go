@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// docPlaceholderSample is used by the quickstart examples when there are no
+// collections to write them against
+var docPlaceholderSample = DocSample{
+	Collection: "{collection}",
+	Data:       `{"field": "value"}`,
+	Filter:     "field[eq]=value",
+}
+
+// DocSample holds the quickstart examples of the documentation, written
+// against a real collection when the registry has one
+type DocSample struct {
+	Collection string // collection name, or "{collection}" without collections
+	Data       string // JSON data of the create-record example
+	Filter     string // query string of the filter example
+	Live       bool   // the examples use a collection of this server
+	Demo       bool   // the collection is the sample collection of bootstrap.sample_collection
+	RecordID   string // id of a sample collection record for the get and update examples
+}
+
+// buildDocSample writes the quickstart examples against doc.sample_collection
+// if it is documented, otherwise against the sample collection or the first
+// collection by name
+func (h *DocHandler) buildDocSample(collections []*registry.Collection, now time.Time) DocSample {
+	preferred := h.config.Doc.SampleCollection
+	if preferred == "" {
+		preferred = SampleCollection
+	}
+	var sample *registry.Collection
+	for _, c := range collections {
+		if c.Name == preferred {
+			sample = c
+			break
+		}
+		if sample == nil || c.Name < sample.Name {
+			sample = c
+		}
+	}
+	if sample == nil {
+		return docPlaceholderSample
+	}
+
+	// Columns are listed by name, like the JSON appendix, so the examples do
+	// not depend on the order columns were added in; the ui order of the
+	// collection comes first when it has one
+	columns := slices.Clone(sample.Columns)
+	slices.SortFunc(columns, func(a, b registry.Column) int { return strings.Compare(a.Name, b.Name) })
+	columns = uiOrderedColumns(sample.UI, columns)
+
+	var data strings.Builder
+	filter := ""
+	data.WriteString("{")
+	for i, col := range columns {
+		value := docSampleValue(col, now)
+		key, _ := json.Marshal(col.Name)
+		encoded, _ := json.Marshal(value)
+		if i > 0 {
+			data.WriteString(", ")
+		}
+		fmt.Fprintf(&data, "%s: %s", key, encoded)
+
+		// JSON columns are filtered by path, so the first other column is used
+		if filter == "" && col.Type != registry.TypeJSON {
+			filter = fmt.Sprintf("%s[%s]=%s", col.Name, docSampleOperator(col.Type), url.QueryEscape(fmt.Sprint(value)))
+		}
+	}
+	data.WriteString("}")
+	if filter == "" {
+		filter = "limit=10"
+	}
+
+	docSample := DocSample{Collection: sample.Name, Data: data.String(), Filter: filter, Live: true}
+	if sample.Name == SampleCollection {
+		docSample.Demo = true
+		docSample.RecordID = h.sampleRecordID()
+	}
+	return docSample
+}
+
+// sampleRecordID returns the id of the first record of the sample collection,
+// or "" without a database or records
+func (h *DocHandler) sampleRecordID() string {
+	if h.db == nil {
+		return ""
+	}
+	sql := fmt.Sprintf("SELECT id FROM %s ORDER BY id LIMIT 1", query.QuoteIdent(h.db.Dialect(), SampleCollection))
+	var id string
+	if err := h.db.QueryRow(context.Background(), sql).Scan(&id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// docSampleValue returns an example value a create request accepts for a
+// column: the first enum value, or a value of its type within its limits
+func docSampleValue(col registry.Column, now time.Time) any {
+	if len(col.Enum) > 0 {
+		return col.Enum[0]
+	}
+	switch col.Type {
+	case registry.TypeInteger:
+		return docSampleNumber(col, 42)
+	case registry.TypeDecimal:
+		return string(docSampleNumber(col, 42))
+	case registry.TypeBoolean:
+		return true
+	case registry.TypeDatetime:
+		return datetime.Format(now)
+	case registry.TypeJSON:
+		return map[string]any{"example": true}
+	default:
+		value := "example"
+		if col.MaxLength != nil && *col.MaxLength < len(value) {
+			value = value[:max(*col.MaxLength, 0)]
+		}
+		return value
+	}
+}
+
+// docSampleNumber returns value, or the column's min or max when value is
+// outside them
+func docSampleNumber(col registry.Column, value int) json.Number {
+	sample := json.Number(fmt.Sprint(value))
+	if col.Min != nil {
+		if lower, err := col.Min.Float64(); err == nil && float64(value) < lower {
+			sample = *col.Min
+		}
+	}
+	if col.Max != nil {
+		if upper, err := col.Max.Float64(); err == nil && float64(value) > upper {
+			sample = *col.Max
+		}
+	}
+	return sample
+}
+
+// docSampleOperator is the filter operator of the example for a column type
+func docSampleOperator(colType registry.ColumnType) string {
+	switch colType {
+	case registry.TypeInteger, registry.TypeDecimal:
+		return "gte"
+	case registry.TypeDatetime:
+		return "lte"
+	default:
+		return "eq"
+	}
+}
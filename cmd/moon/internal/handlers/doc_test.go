@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/thalib/moon/cmd/moon/internal/config"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
@@ -234,6 +235,41 @@ func TestDocHandler_RefreshCache(t *testing.T) {
 	}
 }
 
+func TestDocHandler_SchemaChangesClearCache(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	cfg := &config.AppConfig{Server: config.ServerConfig{Host: "localhost", Port: 6006}}
+	handler := NewDocHandler(reg, cfg, "1.99")
+
+	get := func(serve http.HandlerFunc, path string) string {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Body.String()
+	}
+	if md := get(handler.Markdown, "/doc/llms.md"); strings.Contains(md, "/orders:create") {
+		t.Fatal("expected no orders before it is created")
+	}
+	get(handler.OpenAPI, "/doc/openapi.json")
+
+	// No RefreshCache: the registry change is enough
+	reg.Set(&registry.Collection{Name: "orders", Columns: []registry.Column{{Name: "total", Type: registry.TypeInteger}}})
+	if md := get(handler.Markdown, "/doc/llms.md"); !strings.Contains(md, "/orders:create") {
+		t.Error("expected the Markdown to show orders after it was created")
+	}
+	if spec := get(handler.OpenAPI, "/doc/openapi.json"); !strings.Contains(spec, "/orders:list") {
+		t.Error("expected the OpenAPI document to show orders after it was created")
+	}
+
+	reg.Rename("orders", "invoices")
+	if md := get(handler.Markdown, "/doc/llms.md"); strings.Contains(md, "/orders:create") || !strings.Contains(md, "/invoices:create") {
+		t.Error("expected the Markdown to follow the rename")
+	}
+
+	reg.Delete("invoices")
+	if md := get(handler.Markdown, "/doc/llms.md"); !strings.Contains(md, "/{collection}:create") {
+		t.Error("expected placeholders after the last collection was deleted")
+	}
+}
+
 func TestDocHandler_WithPrefix(t *testing.T) {
 	// Setup with prefix
 	reg := registry.NewSchemaRegistry()
@@ -345,6 +381,85 @@ func TestDocHandler_QuickstartSection(t *testing.T) {
 	}
 }
 
+func TestDocHandler_QuickstartPlaceholders(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	cfg := &config.AppConfig{Server: config.ServerConfig{Host: "localhost", Port: 6006}}
+	handler := NewDocHandler(reg, cfg, "1.99")
+
+	rec := httptest.NewRecorder()
+	handler.Markdown(rec, httptest.NewRequest(http.MethodGet, "/doc/llms.md", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"## Quickstart",
+		"There are no collections yet",
+		`/{collection}:create"`,
+		`-d '{"data": {"field": "value"}}'`,
+		`/{collection}:list?field[eq]=value"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the quickstart", want)
+		}
+	}
+}
+
+func TestDocHandler_QuickstartUsesCollections(t *testing.T) {
+	maxLength := 3
+	minQty, maxPrice := json.Number("100"), json.Number("9.99")
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "title", Type: registry.TypeString},
+			{Name: "quantity", Type: registry.TypeInteger, Min: &minQty},
+			{Name: "price", Type: registry.TypeDecimal, Max: &maxPrice},
+			{Name: "active", Type: registry.TypeBoolean},
+			{Name: "sold_at", Type: registry.TypeDatetime},
+			{Name: "details", Type: registry.TypeJSON},
+			{Name: "status", Type: registry.TypeString, Enum: []string{"draft", "live"}},
+			{Name: "code", Type: registry.TypeString, MaxLength: &maxLength},
+		},
+	})
+	reg.Set(&registry.Collection{
+		Name: "tags",
+		Columns: []registry.Column{
+			{Name: "added", Type: registry.TypeDatetime},
+			{Name: "about", Type: registry.TypeJSON},
+		},
+	})
+	cfg := &config.AppConfig{Server: config.ServerConfig{Host: "localhost", Port: 6006}}
+	handler := NewDocHandler(reg, cfg, "1.99")
+
+	rec := httptest.NewRecorder()
+	handler.Markdown(rec, httptest.NewRequest(http.MethodGet, "/doc/llms.md", nil))
+	body := rec.Body.String()
+
+	// The first collection by name, with values each column accepts
+	sample := handler.buildDocSample(handler.documentedCollections(""), time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	wantData := `{"active": true, "code": "exa", "details": {"example":true}, "price": "9.99", ` +
+		`"quantity": 100, "sold_at": "2026-01-02T03:04:05Z", "status": "draft", "title": "example"}`
+	if sample.Collection != "products" || sample.Data != wantData || sample.Filter != "active[eq]=true" || !sample.Live {
+		t.Errorf("unexpected sample %+v", sample)
+	}
+	for _, want := range []string{
+		"These examples use the `products` collection",
+		`/products:create"`,
+		`"quantity": 100, "sold_at": "`,
+		`/products:list?active[eq]=true"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the quickstart", want)
+		}
+	}
+
+	// doc.sample_collection picks another collection; JSON columns are not filtered on
+	cfg.Doc.SampleCollection = "tags"
+	sample = handler.buildDocSample(handler.documentedCollections(""), time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if sample.Collection != "tags" || sample.Filter != "added[lte]=2026-01-02T03%3A04%3A05Z" {
+		t.Errorf("unexpected sample %+v", sample)
+	}
+}
+
 func TestDocHandler_ErrorSection(t *testing.T) {
 	reg := registry.NewSchemaRegistry()
 	cfg := &config.AppConfig{
@@ -951,7 +1066,7 @@ func TestDocHandler_IncludeFunctionIntegration(t *testing.T) {
 	// but the function is available for use
 
 	// Verify we can generate markdown without errors
-	markdown, err := handler.generateMarkdown()
+	markdown, err := handler.generateMarkdown(handler.defaultBaseURL(), "")
 	if err != nil {
 		t.Fatalf("failed to generate markdown: %v", err)
 	}
@@ -980,12 +1095,12 @@ func TestDocHandler_IncludeWithTemplateContext(t *testing.T) {
 	handler := NewDocHandler(reg, cfg, "1.99")
 
 	// Generate both markdown and HTML to ensure includes work in both contexts
-	markdown, err := handler.generateMarkdown()
+	markdown, err := handler.generateMarkdown(handler.defaultBaseURL(), "")
 	if err != nil {
 		t.Fatalf("failed to generate markdown: %v", err)
 	}
 
-	html, err := handler.generateHTML()
+	html, err := handler.generateHTML(handler.defaultBaseURL(), "")
 	if err != nil {
 		t.Fatalf("failed to generate HTML: %v", err)
 	}
@@ -1031,7 +1146,7 @@ func TestDocHandler_IncludeSecurityValidation(t *testing.T) {
 	}
 
 	// Generate markdown - this executes the template with the include function
-	markdown, err := handler.generateMarkdown()
+	markdown, err := handler.generateMarkdown(handler.defaultBaseURL(), "")
 	if err != nil {
 		t.Fatalf("failed to generate markdown: %v", err)
 	}
@@ -1284,3 +1399,84 @@ func TestDocHandler_TextEndpoint(t *testing.T) {
 		t.Error("expected /doc/llms.txt to return same content as /doc/llms.md")
 	}
 }
+
+func TestDocHandler_ForwardedBaseURL(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	cfg := &config.AppConfig{
+		// httptest requests come from 192.0.2.1
+		Server: config.ServerConfig{Port: 6006, Prefix: "/api", TrustedProxies: []string{"192.0.2.0/24"}},
+	}
+	handler := NewDocHandler(reg, cfg, "1.99")
+
+	get := func(host, forwardedHost, forwardedProto string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/doc/llms.md", nil)
+		req.Host = host
+		if forwardedHost != "" {
+			req.Header.Set("X-Forwarded-Host", forwardedHost)
+		}
+		if forwardedProto != "" {
+			req.Header.Set("X-Forwarded-Proto", forwardedProto)
+		}
+		rec := httptest.NewRecorder()
+		handler.Markdown(rec, req)
+		return rec
+	}
+
+	proxied := get("10.0.0.5:6006", "docs.example.com", "https")
+	body := proxied.Body.String()
+	if !strings.Contains(body, `curl -s -X POST "https://docs.example.com/api/auth:login"`) {
+		t.Error("expected curl examples to point at the forwarded host over https")
+	}
+	if strings.Contains(body, "localhost:6006") || strings.Contains(body, "10.0.0.5") {
+		t.Error("expected no references to the internal address")
+	}
+	if !strings.Contains(proxied.Header().Get("Vary"), "X-Forwarded-Host") {
+		t.Errorf("expected Vary to include X-Forwarded-Host, got %q", proxied.Header().Get("Vary"))
+	}
+
+	// A different host must not be served the cached proxy output
+	direct := get("moon.internal:6006", "", "")
+	if !strings.Contains(direct.Body.String(), "http://moon.internal:6006/api/auth:login") || strings.Contains(direct.Body.String(), "docs.example.com") {
+		t.Error("expected direct request to use its own host")
+	}
+	if direct.Header().Get("ETag") == proxied.Header().Get("ETag") {
+		t.Error("expected different ETags per base URL")
+	}
+
+	// The first request's output is still cached unchanged
+	if again := get("10.0.0.5:6006", "docs.example.com", "https"); again.Body.String() != body || again.Header().Get("ETag") != proxied.Header().Get("ETag") {
+		t.Error("expected cached proxy output to be reused")
+	}
+
+	// Hosts that could inject markup fall back to the request host
+	if injected := get("moon.internal:6006", "evil.com/\"><script>", ""); strings.Contains(injected.Body.String(), "evil.com") {
+		t.Error("expected malformed forwarded host to be ignored")
+	}
+
+	// Forwarding headers from a peer that is not a trusted proxy are ignored
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	if spoofed := get("moon.internal:6006", "spoofed.example.net", "https"); !strings.Contains(spoofed.Body.String(), "http://moon.internal:6006/api/auth:login") {
+		t.Error("expected forwarding headers from an untrusted peer to be ignored")
+	}
+}
+
+func TestDocHandler_PublicURL(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	cfg := &config.AppConfig{
+		Server: config.ServerConfig{Port: 6006, PublicURL: "https://api.example.com"},
+	}
+	handler := NewDocHandler(reg, cfg, "1.99")
+
+	req := httptest.NewRequest(http.MethodGet, "/doc/llms.md", nil)
+	req.Header.Set("X-Forwarded-Host", "spoofed.example.net")
+	rec := httptest.NewRecorder()
+	handler.Markdown(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "https://api.example.com/auth:login") || strings.Contains(body, "spoofed.example.net") {
+		t.Error("expected server.public_url to override request headers")
+	}
+	if rec.Header().Get("Vary") != "" {
+		t.Errorf("expected no Vary header with a fixed public URL, got %q", rec.Header().Get("Vary"))
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// errorMessage returns the message of a decoded error response
+func errorMessage(resp map[string]any) (string, bool) {
+	detail, ok := resp["error"].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	message, ok := detail["message"].(string)
+	return message, ok
+}
+
+// assertErrorCode checks the status and both copies of the code in an error response
+func assertErrorCode(t *testing.T, w *httptest.ResponseRecorder, status int, code apperrors.ErrorCode) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["code"] != string(code) || resp["status"] != float64(status) {
+		t.Errorf("expected code %s and status %d, got %v and %v", code, status, resp["code"], resp["status"])
+	}
+	detail, _ := resp["error"].(map[string]any)
+	if detail["code"] != string(code) || detail["message"] == "" {
+		t.Errorf("expected error object with code %s and a message, got %v", code, resp["error"])
+	}
+}
+
+func TestDataHandler_ErrorCodes(t *testing.T) {
+	driver, _, handler := setupDataIntegrationTest(t)
+	defer driver.Close()
+
+	get := func(action func(http.ResponseWriter, *http.Request, string), collection, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		action(w, req, collection)
+		return w
+	}
+	create := func(data any) *httptest.ResponseRecorder {
+		return postData(t, handler.Create, "/products:create", map[string]any{"data": data}, "")
+	}
+
+	tests := []struct {
+		name   string
+		do     func() *httptest.ResponseRecorder
+		status int
+		code   apperrors.ErrorCode
+	}{
+		{"unknown collection", func() *httptest.ResponseRecorder { return get(handler.List, "missing", "/missing:list") }, http.StatusNotFound, apperrors.CodeCollectionNotFound},
+		{"invalid cursor", func() *httptest.ResponseRecorder {
+			return get(handler.List, "products", "/products:list?after=not-a-cursor")
+		}, http.StatusBadRequest, apperrors.CodeInvalidCursor},
+		{"unknown filter column", func() *httptest.ResponseRecorder {
+			return get(handler.List, "products", "/products:list?color[eq]=red")
+		}, http.StatusBadRequest, apperrors.CodeInvalidFilter},
+		{"unknown sort column", func() *httptest.ResponseRecorder { return get(handler.List, "products", "/products:list?sort=color") }, http.StatusBadRequest, apperrors.CodeInvalidSort},
+		{"limit too large", func() *httptest.ResponseRecorder { return get(handler.List, "products", "/products:list?limit=100000") }, http.StatusBadRequest, apperrors.CodeInvalidParameter},
+		{"invalid ULID", func() *httptest.ResponseRecorder { return get(handler.Get, "products", "/products:get?id=nope") }, http.StatusBadRequest, apperrors.CodeInvalidULID},
+		{"missing record", func() *httptest.ResponseRecorder {
+			return get(handler.Get, "products", "/products:get?id=01ARZ3NDEKTSV4RRFFQ69G5FAV")
+		}, http.StatusNotFound, apperrors.CodeRecordNotFound},
+		{"unknown field", func() *httptest.ResponseRecorder {
+			return create(map[string]any{"name": "a", "price": 1, "color": "red"})
+		}, http.StatusBadRequest, apperrors.CodeUnknownField},
+		{"missing required field", func() *httptest.ResponseRecorder { return create(map[string]any{"name": "a"}) }, http.StatusBadRequest, apperrors.CodeRequiredField},
+		{"null required field", func() *httptest.ResponseRecorder {
+			return create(map[string]any{"name": nil, "price": 1})
+		}, http.StatusBadRequest, apperrors.CodeNullField},
+		{"wrong type", func() *httptest.ResponseRecorder { return create(map[string]any{"name": "a", "price": "ten"}) }, http.StatusBadRequest, apperrors.CodeInvalidType},
+		{"malformed body", func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/products:create", strings.NewReader("{"))
+			w := httptest.NewRecorder()
+			handler.Create(w, req, "products")
+			return w
+		}, http.StatusBadRequest, apperrors.CodeInvalidJSON},
+		{"batch too large", func() *httptest.ResponseRecorder {
+			items := make([]map[string]any, 51)
+			for i := range items {
+				items[i] = map[string]any{"name": "a", "price": 1}
+			}
+			return create(items)
+		}, http.StatusRequestEntityTooLarge, apperrors.CodeBatchTooLarge},
+		{"invalid batch item in atomic mode", func() *httptest.ResponseRecorder {
+			return postData(t, handler.Create, "/products:create?atomic=true", map[string]any{"data": []map[string]any{{"name": "a", "price": 1}, {"name": "b"}}}, "")
+		}, http.StatusBadRequest, apperrors.CodeRequiredField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertErrorCode(t, tt.do(), tt.status, tt.code)
+		})
+	}
+}
+
+func TestDataHandler_ErrorCodes_UniqueViolation(t *testing.T) {
+	driver, handler := setupUpsertIntegrationTest(t)
+	defer driver.Close()
+
+	post := func(url string, data any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"data": data})
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.Create(w, req, "items")
+		return w
+	}
+
+	if w := post("/items:create", map[string]any{"sku": "A-1", "name": "first"}); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create record: %s", w.Body.String())
+	}
+	assertErrorCode(t, post("/items:create", map[string]any{"sku": "A-1", "name": "again"}), http.StatusConflict, apperrors.CodeUniqueViolation)
+
+	// Best-effort batch items use the same vocabulary
+	w := post("/items:create", []map[string]any{
+		{"sku": "A-2", "name": "ok"},
+		{"sku": "A-1", "name": "duplicate"},
+		{"sku": "A-3", "name": "unknown", "color": "red"},
+	})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := []apperrors.ErrorCode{"", apperrors.CodeUniqueViolation, apperrors.CodeUnknownField}
+	for i, result := range resp.Results {
+		if result.ErrorCode != want[i] {
+			t.Errorf("item %d: expected error_code %q, got %q", i, want[i], result.ErrorCode)
+		}
+	}
+}
+
+func TestCollectionsHandler_ErrorCodes(t *testing.T) {
+	handler, driver := setupTestHandler(t)
+	defer driver.Close()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/collections:create", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.Create(w, req)
+		return w
+	}
+
+	products := `{"name": "products", "columns": [{"name": "title", "type": "string"}]}`
+	if w := create(products); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create collection: %s", w.Body.String())
+	}
+
+	assertErrorCode(t, create(products), http.StatusConflict, apperrors.CodeDuplicateCollection)
+	assertErrorCode(t, create(`{"name": "1bad", "columns": [{"name": "title", "type": "string"}]}`), http.StatusBadRequest, apperrors.CodeCollectionNameInvalid)
+	assertErrorCode(t, create(`{"name": "orders", "columns": [{"name": "total", "type": "money"}]}`), http.StatusBadRequest, apperrors.CodeInvalidSchema)
+	assertErrorCode(t, create(`{"name": "orders", "bogus": true}`), http.StatusBadRequest, apperrors.CodeInvalidJSON)
+
+	req := httptest.NewRequest(http.MethodGet, "/collections:get?name=missing", nil)
+	w := httptest.NewRecorder()
+	handler.Get(w, req)
+	assertErrorCode(t, w, http.StatusNotFound, apperrors.CodeCollectionNotFound)
+}
+
+func TestWriteError_LegacyFormat(t *testing.T) {
+	apperrors.SetLegacyFormat(true)
+	defer apperrors.SetLegacyFormat(false)
+
+	w := httptest.NewRecorder()
+	writeError(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusNotFound, apperrors.CodeCollectionNotFound, "collection 'x' not found")
+
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["error"] != "collection 'x' not found" || resp["code"] != float64(http.StatusNotFound) || resp["error_code"] != "collection_not_found" {
+		t.Errorf("expected the legacy error shape, got %v", resp)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// notesTest holds a notes collection created through collections:create in a
+// fresh in-memory database, with the handlers serving it
+type notesTest struct {
+	driver      database.Driver
+	reg         *registry.SchemaRegistry
+	collections *CollectionsHandler
+	data        *DataHandler
+}
+
+// newTestDriver opens an in-memory SQLite database that is closed when the
+// test ends. It keeps a single connection, as every connection to :memory:
+// opens a database of its own.
+func newTestDriver(t testing.TB) database.Driver {
+	t.Helper()
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	return driver
+}
+
+// setupNotesTest creates a notes collection from the collections:create body
+// schema, which needs no name, and creates records in it one at a time
+func setupNotesTest(t *testing.T, schema map[string]any, records ...map[string]any) *notesTest {
+	t.Helper()
+	driver := newTestDriver(t)
+	reg := registry.NewSchemaRegistry()
+	nt := &notesTest{driver: driver, reg: reg, collections: NewCollectionsHandler(driver, reg), data: NewDataHandler(driver, reg, testConfig())}
+
+	body := map[string]any{"name": "notes"}
+	for key, value := range schema {
+		body[key] = value
+	}
+	if w := postCollections(nt.collections.Create, body); w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create collection: %d %s", w.Code, w.Body.String())
+	}
+	for _, record := range records {
+		if w := doDataAction(t, nt.data.Create, http.MethodPost, "/notes:create", map[string]any{"data": record}); w.Code != http.StatusCreated {
+			t.Fatalf("Create failed: %d %s", w.Code, w.Body.String())
+		}
+	}
+	return nt
+}
+
+// postCollections posts a JSON body to a collections action
+func postCollections(action http.HandlerFunc, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	action(w, httptest.NewRequest(http.MethodPost, "/collections", bytes.NewReader(payload)))
+	return w
+}
+
+// doDataAction runs a data action on the notes collection with an optional
+// JSON body
+func doDataAction(t *testing.T, action func(http.ResponseWriter, *http.Request, string), method, url string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	w := httptest.NewRecorder()
+	action(w, httptest.NewRequest(method, url, reader), "notes")
+	return w
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+// RecordLinks are the URLs of a record and of the collection holding it
+type RecordLinks struct {
+	Self       string `json:"self"`
+	Collection string `json:"collection"`
+}
+
+// recordPath returns the path of the :get of record id, under server.prefix,
+// as set in the Location header of a create
+func (h *DataHandler) recordPath(r *http.Request, collectionName, id string) string {
+	return h.config.Server.Prefix + dataActionPath(r, collectionName, "get") + "?" + constants.QueryParamID + "=" + url.QueryEscape(id)
+}
+
+// recordLink returns the absolute URL of the :get of record id
+func (h *DataHandler) recordLink(r *http.Request, collectionName, id string) string {
+	return RequestBaseURL(h.config.Server, r) + h.recordPath(r, collectionName, id)
+}
+
+// recordLinks returns the links of record id: its :get and the :list of its
+// collection
+func (h *DataHandler) recordLinks(r *http.Request, collectionName, id string) *RecordLinks {
+	return &RecordLinks{
+		Self:       h.recordLink(r, collectionName, id),
+		Collection: RequestBaseURL(h.config.Server, r) + h.config.Server.Prefix + dataActionPath(r, collectionName, "list"),
+	}
+}
+
+// dataActionPath returns the path of an action on a collection, named as
+// the client knows it, without server.prefix
+func dataActionPath(r *http.Request, collectionName, action string) string {
+	return "/" + url.PathEscape(logicalName(r, collectionName)) + ":" + action
+}
+
+// RequestBaseURL returns the scheme and host clients reach the server at:
+// server.public_url when configured, otherwise the host the request was
+// sent to. X-Forwarded-Proto and X-Forwarded-Host are only honoured when the
+// direct peer is one of server.trusted_proxies, so a client cannot choose
+// the host of the links and examples returned to others.
+func RequestBaseURL(server config.ServerConfig, r *http.Request) string {
+	if server.PublicURL != "" {
+		return server.PublicURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := ""
+	if trustedPeer(server, r) {
+		if proto := firstForwardedValue(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		host = firstForwardedValue(r.Header.Get("X-Forwarded-Host"))
+	}
+	if !validDocHost(host) {
+		host = r.Host
+	}
+	if !validDocHost(host) {
+		return serverBaseURL(server)
+	}
+	return scheme + "://" + host
+}
+
+// trustedPeer reports whether the direct peer of a request is one of
+// server.trusted_proxies
+func trustedPeer(server config.ServerConfig, r *http.Request) bool {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	prefixes, _ := server.TrustedProxyPrefixes()
+	for _, prefix := range prefixes {
+		if prefix.Contains(peer.Addr().Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverBaseURL is the base URL used when no request is available
+func serverBaseURL(server config.ServerConfig) string {
+	if server.PublicURL != "" {
+		return server.PublicURL
+	}
+	return fmt.Sprintf("http://localhost:%d", server.Port)
+}
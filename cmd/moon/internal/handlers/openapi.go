@@ -0,0 +1,929 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// OpenAPIVersion is the OpenAPI specification version emitted by /doc/openapi.json
+const OpenAPIVersion = "3.0.3"
+
+// openAPIAggregations lists the aggregation actions documented for each collection
+var openAPIAggregations = []string{"count", "sum", "avg", "min", "max"}
+
+// generateOpenAPI builds an OpenAPI 3.0 document of a tenant's collections from
+// the schema registry
+func (h *DocHandler) generateOpenAPI(baseURL, tenant string) ([]byte, error) {
+	collections := h.documentedCollections(tenant)
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].Name < collections[j].Name
+	})
+
+	securitySchemes := map[string]any{}
+	security := []map[string][]string{}
+	if h.config.JWT.Secret != "" {
+		securitySchemes["bearerAuth"] = map[string]any{
+			"type":         "http",
+			"scheme":       "bearer",
+			"bearerFormat": "JWT",
+			"description":  "JWT access token obtained from POST /auth:login",
+		}
+		security = append(security, map[string][]string{"bearerAuth": {}})
+	}
+	if h.config.APIKey.Enabled {
+		securitySchemes["apiKeyAuth"] = map[string]any{
+			"type":        "http",
+			"scheme":      "bearer",
+			"description": "API key sent as Authorization: Bearer moon_live_<64_chars>",
+		}
+		security = append(security, map[string][]string{"apiKeyAuth": {}})
+	}
+
+	schemas := map[string]any{
+		"Error": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"error": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"code":    map[string]any{"type": "string"},
+						"message": map[string]any{"type": "string"},
+					},
+				},
+				"code":       map[string]any{"type": "string"},
+				"status":     map[string]any{"type": "integer"},
+				"request_id": map[string]any{"type": "string"},
+			},
+			"required": []string{"error", "code", "status"},
+		},
+		"AggregationResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"value": openAPIAggregateValue(),
+				"count": map[string]any{"type": "integer", "description": "Records counted, or non-null values aggregated; 0 when value is null"},
+			},
+		},
+		"GroupByResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"groups": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"key":   map[string]any{"nullable": true},
+							"value": openAPIAggregateValue(),
+						},
+					},
+				},
+				"count": map[string]any{"type": "integer"},
+			},
+		},
+		"TimeSeriesResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"interval": map[string]any{"type": "string"},
+				"buckets": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"bucket": map[string]any{"type": "string", "description": "Start of the bucket in UTC: 2024-06-01T10:00:00Z, 2024-06-01 (day, or Monday of a week) or 2024-06"},
+							"value":  openAPIAggregateValue(),
+						},
+					},
+				},
+				"count": map[string]any{"type": "integer"},
+			},
+		},
+		"DistinctResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"field": map[string]any{"type": "string"},
+				"values": map[string]any{
+					"type":        "array",
+					"description": "Distinct values, or {value, count} pairs when count=true",
+					"items":       map[string]any{"nullable": true},
+				},
+				"count": map[string]any{"type": "integer"},
+			},
+		},
+		"CollectionStatsResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"collection":      map[string]any{"type": "string"},
+				"row_count":       map[string]any{"type": "integer"},
+				"size_bytes":      map[string]any{"type": "integer", "nullable": true, "description": "Approximate size on disk; null when the database cannot report it"},
+				"column_count":    map[string]any{"type": "integer"},
+				"last_created_at": map[string]any{"type": "string", "format": "date-time", "nullable": true},
+				"last_updated_at": map[string]any{"type": "string", "format": "date-time", "nullable": true},
+				"nulls": map[string]any{
+					"type": "object",
+					"additionalProperties": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"count": map[string]any{"type": "integer"},
+							"ratio": map[string]any{"type": "number"},
+						},
+					},
+				},
+				"sample_size": map[string]any{"type": "integer", "description": "Rows the null counts were computed over"},
+				"sampled":     map[string]any{"type": "boolean"},
+			},
+		},
+		"CheckUniqueResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"field":     map[string]any{"type": "string"},
+				"existing":  map[string]any{"type": "array", "description": "Values already taken", "items": map[string]any{}},
+				"available": map[string]any{"type": "array", "description": "Values still free", "items": map[string]any{}},
+			},
+		},
+		"ExplainResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"dialect":   map[string]any{"type": "string", "enum": []string{"sqlite", "postgres", "mysql"}},
+				"sql":       map[string]any{"type": "string", "description": "The SELECT :list runs for the same parameters"},
+				"args":      map[string]any{"type": "array", "description": "Bound arguments in order; value only with show_values", "items": map[string]any{"type": "object"}},
+				"arg_count": map[string]any{"type": "integer"},
+				"count_sql": map[string]any{"type": "string", "description": "The count query, when :list reports a total"},
+				"plan":      map[string]any{"description": "The database's plan: EXPLAIN QUERY PLAN rows on SQLite, the JSON plan on PostgreSQL and MySQL"},
+			},
+		},
+		"RecordLinks": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"self":       map[string]any{"type": "string", "format": "uri", "description": "URL of the record's :get"},
+				"collection": map[string]any{"type": "string", "format": "uri", "description": "URL of the collection's :list"},
+			},
+		},
+		"MessageResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"message": map[string]any{"type": "string"},
+			},
+		},
+		"ChangesResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"changes": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"seq":    map[string]any{"type": "integer"},
+							"id":     map[string]any{"type": "string"},
+							"action": map[string]any{"type": "string", "enum": []string{"create", "update", "destroy"}},
+						},
+					},
+				},
+				"seq":   map[string]any{"type": "integer", "description": "Cursor for the next since"},
+				"reset": map[string]any{"type": "boolean", "description": "Changes after since are no longer known; reload the collection"},
+			},
+		},
+		"ImportResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"imported": map[string]any{"type": "integer"},
+				"skipped":  map[string]any{"type": "integer"},
+				"errors": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"row":   map[string]any{"type": "integer"},
+							"line":  map[string]any{"type": "integer"},
+							"error": map[string]any{"type": "string"},
+						},
+					},
+				},
+				"errors_truncated": map[string]any{"type": "boolean"},
+				"dry_run":          map[string]any{"type": "boolean"},
+				"job_id":           map[string]any{"type": "string", "description": "only for journaled imports"},
+				"message":          map[string]any{"type": "string"},
+			},
+		},
+	}
+
+	paths := map[string]any{}
+	for _, collection := range collections {
+		recordName := openAPISchemaName(collection.Name)
+		inputName := recordName + "Input"
+		schemas[recordName] = openAPIRecordSchema(collection, true)
+		schemas[inputName] = openAPIRecordSchema(collection, false)
+
+		var references []string
+		for _, col := range collection.Columns {
+			if col.References != "" && !col.Hidden {
+				references = append(references, col.Name)
+			}
+		}
+		for action, item := range openAPICollectionPaths(collection.Name, recordName, inputName, collection.SoftDelete, len(hiddenColumns(collection)) > 0, references) {
+			paths[fmt.Sprintf("/%s:%s", collection.Name, action)] = item
+		}
+	}
+
+	spec := map[string]any{
+		"openapi": OpenAPIVersion,
+		"info": map[string]any{
+			"title":       "Moon API",
+			"version":     h.version,
+			"description": "Dynamic collection API generated from the Moon schema registry. Schemas describe API version 1; version 2 (api_version=2 or Accept: application/vnd.moon.v2+json) nests errors in an error object and list pagination under page.",
+		},
+		"servers": []map[string]any{
+			{"url": baseURL + h.config.Server.Prefix},
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas":         schemas,
+			"securitySchemes": securitySchemes,
+		},
+		"security": security,
+	}
+
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+// openAPICollectionPaths builds the path items for a single collection keyed
+// by action; references names the reference columns :list and :get can expand
+func openAPICollectionPaths(name, recordName, inputName string, softDelete, hidden bool, references []string) map[string]any {
+	recordRef := openAPIRef(recordName)
+	inputRef := openAPIRef(inputName)
+	errorResponses := map[string]any{
+		"400": openAPIErrorResponse("Invalid request"),
+		"401": openAPIErrorResponse("Missing or invalid authentication"),
+		"404": openAPIErrorResponse("Collection or record not found"),
+	}
+
+	withErrors := func(responses map[string]any) map[string]any {
+		for code, resp := range errorResponses {
+			responses[code] = resp
+		}
+		return responses
+	}
+
+	listResponse := openAPIJSONResponse("Paginated list of records", map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"data":        map[string]any{"type": "array", "items": recordRef},
+			"total":       map[string]any{"type": "integer", "nullable": true, "description": "null when skipped with total=false"},
+			"next_cursor": map[string]any{"type": "string", "nullable": true},
+			"limit":       map[string]any{"type": "integer"},
+			"page":        map[string]any{"type": "integer", "description": "only with page numbers"},
+			"total_pages": map[string]any{"type": "integer", "description": "only with page numbers"},
+		},
+	})
+
+	// :list also answers Accept: text/csv and application/x-ndjson with the
+	// bare records and the pagination in headers
+	listFormatsResponse := map[string]any{
+		"description": "Paginated list of records; as CSV or NDJSON when requested by Accept",
+		"content": map[string]any{
+			"application/json":     listResponse["content"].(map[string]any)["application/json"],
+			"text/csv":             map[string]any{"schema": map[string]any{"type": "string"}},
+			"application/x-ndjson": map[string]any{"schema": recordRef},
+		},
+		"headers": map[string]any{
+			"X-Total":       map[string]any{"description": "Matching records, for CSV and NDJSON when counted", "schema": map[string]any{"type": "integer"}},
+			"X-Next-Cursor": map[string]any{"description": "Cursor of the next page, for CSV and NDJSON", "schema": map[string]any{"type": "string"}},
+		},
+	}
+
+	paths := map[string]any{
+		"list": map[string]any{
+			"get": map[string]any{
+				"operationId": name + "_list",
+				"summary":     fmt.Sprintf("List %s records", name),
+				"tags":        []string{name},
+				"parameters": []map[string]any{
+					openAPIQueryParam("limit", "Maximum number of records to return", map[string]any{"type": "integer"}),
+					openAPIQueryParam("after", "next_cursor from a previous response, used with the same sort", map[string]any{"type": "string"}),
+					openAPIQueryParam("page", "Page number (from 1) instead of a cursor; not combined with after", map[string]any{"type": "integer", "minimum": 1}),
+					openAPIQueryParam("per_page", "Records per page with page (defaults to limit)", map[string]any{"type": "integer"}),
+					openAPIQueryParam("sort", "Comma-separated fields, prefix with '-' for descending", map[string]any{"type": "string"}),
+					openAPIQueryParam("q", "Full-text search across string columns", map[string]any{"type": "string"}),
+					openAPIQueryParam("q_fields", "Comma-separated string fields to search (defaults to all)", map[string]any{"type": "string"}),
+					openAPIQueryParam("q_mode", "How q matches (defaults to contains)", map[string]any{"type": "string", "enum": []string{"contains", "insensitive", "prefix", "exact"}}),
+					openAPIQueryParam("fields", "Comma-separated fields to return (id always included); dotted names such as meta.color select keys of a json column", map[string]any{"type": "string"}),
+					openAPIQueryParam("total", "Count the matching records (defaults to api.include_total_default)", map[string]any{"type": "boolean"}),
+					openAPIStrictParam(),
+				},
+				"responses": withErrors(map[string]any{
+					"200": listFormatsResponse,
+				}),
+			},
+		},
+		"query": map[string]any{
+			"post": map[string]any{
+				"operationId": name + "_query",
+				"summary":     fmt.Sprintf("List %s records matching a JSON filter of and/or groups", name),
+				"tags":        []string{name},
+				"requestBody": openAPIRequestBody(map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"filter": map[string]any{"type": "object", "description": "Node with 'and'/'or' arrays of nodes or column keys holding {operator: value}"},
+						"sort":   map[string]any{"type": "string"},
+						"fields": map[string]any{"type": "string"},
+						"limit":  map[string]any{"type": "integer"},
+						"after":  map[string]any{"type": "string"},
+						"total":  map[string]any{"type": "boolean"},
+					},
+				}),
+				"responses": withErrors(map[string]any{
+					"200": listResponse,
+				}),
+			},
+		},
+		"get": map[string]any{
+			"get": map[string]any{
+				"operationId": name + "_get",
+				"summary":     fmt.Sprintf("Get a single %s record", name),
+				"tags":        []string{name},
+				"parameters": []map[string]any{
+					openAPIRequiredQueryParam("id", "Record id", map[string]any{"type": "string"}),
+					openAPIQueryParam("full", "Return every json value of a record over api.max_record_response_bytes instead of stubs, streamed", map[string]any{"type": "boolean"}),
+				},
+				"responses": withErrors(map[string]any{
+					"200": openAPIJSONResponse("The requested record", openAPILinkedEnvelope(recordRef)),
+				}),
+			},
+		},
+		"create": map[string]any{
+			"post": map[string]any{
+				"operationId": name + "_create",
+				"summary":     fmt.Sprintf("Create one or more %s records", name),
+				"tags":        []string{name},
+				"parameters": []map[string]any{
+					openAPIQueryParam("atomic", "Process batch requests in a single transaction", map[string]any{"type": "boolean"}),
+					openAPIQueryParam("journal", "Record the progress of a best-effort batch as a job named in X-Moon-Job", map[string]any{"type": "boolean"}),
+					{"name": "Idempotency-Key", "in": "header", "description": "Replays the stored response to retries of the same key and body", "schema": map[string]any{"type": "string", "maxLength": 255}},
+				},
+				"requestBody": openAPIRequestBody(openAPIDataEnvelope(openAPIOneOrMany(inputRef))),
+				"responses": withErrors(map[string]any{
+					"201": openAPIWithHeader(openAPIJSONResponse("Record(s) created", openAPILinkedEnvelope(openAPIOneOrMany(recordRef))),
+						"Location", "Path of the :get of a single created record"),
+					"207": openAPIJSONResponse("Batch processed with per-item results", map[string]any{"type": "object"}),
+					"409": openAPIErrorResponse("Unique constraint violation"),
+					"422": openAPIErrorResponse("Idempotency-Key reused with a different body"),
+				}),
+			},
+		},
+		"update": map[string]any{
+			"post": map[string]any{
+				"operationId": name + "_update",
+				"summary":     fmt.Sprintf("Update one or more %s records", name),
+				"tags":        []string{name},
+				"parameters": []map[string]any{
+					openAPIQueryParam("atomic", "Process batch requests in a single transaction", map[string]any{"type": "boolean"}),
+					openAPIQueryParam("return", "diff adds the old and new value of each changed field; full returns the complete records", map[string]any{"type": "string", "enum": []string{"diff", "full"}}),
+				},
+				"requestBody": openAPIRequestBody(openAPIDataEnvelope(openAPIOneOrMany(recordRef))),
+				"responses": withErrors(map[string]any{
+					"200": openAPIJSONResponse("Record(s) updated", openAPILinkedEnvelope(openAPIOneOrMany(recordRef))),
+					"207": openAPIJSONResponse("Batch processed with per-item results", map[string]any{"type": "object"}),
+				}),
+			},
+		},
+		"destroy": map[string]any{
+			"post": map[string]any{
+				"operationId": name + "_destroy",
+				"summary":     fmt.Sprintf("Delete one or more %s records", name),
+				"tags":        []string{name},
+				"parameters": []map[string]any{
+					openAPIQueryParam("atomic", "Process batch requests in a single transaction", map[string]any{"type": "boolean"}),
+					openAPIQueryParam("dry_run", "With where: only count the matching records", map[string]any{"type": "boolean"}),
+					openAPIQueryParam("force", "With where: delete more than api.max_bulk_delete records", map[string]any{"type": "boolean"}),
+				},
+				"requestBody": openAPIRequestBody(map[string]any{
+					"oneOf": []map[string]any{
+						openAPIDataEnvelope(openAPIOneOrMany(map[string]any{"type": "string"})),
+						{
+							"type": "object",
+							"properties": map[string]any{
+								"where": map[string]any{"type": "object", "description": "Non-empty :query filter selecting the records to delete"},
+							},
+							"required": []string{"where"},
+						},
+					},
+				}),
+				"responses": withErrors(map[string]any{
+					"200": openAPIJSONResponse("Record(s) deleted; a where filter reports matched and rows_deleted", openAPIRef("MessageResponse")),
+					"207": openAPIJSONResponse("Batch processed with per-item results", map[string]any{"type": "object"}),
+				}),
+			},
+		},
+	}
+
+	paths["upsert"] = map[string]any{
+		"post": map[string]any{
+			"operationId": name + "_upsert",
+			"summary":     fmt.Sprintf("Insert or update %s records matched by a unique key", name),
+			"tags":        []string{name},
+			"parameters": []map[string]any{
+				openAPIQueryParam("atomic", "Process batch requests in a single transaction", map[string]any{"type": "boolean"}),
+			},
+			"requestBody": openAPIRequestBody(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"key":  map[string]any{"type": "string", "description": "Unique column used to match existing records"},
+					"data": openAPIOneOrMany(inputRef),
+				},
+				"required": []string{"key", "data"},
+			}),
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Record updated, or atomic batch processed", map[string]any{"type": "object"}),
+				"201": openAPIJSONResponse("Record created", map[string]any{"type": "object"}),
+				"207": openAPIJSONResponse("Batch processed with per-item results", map[string]any{"type": "object"}),
+				"409": openAPIErrorResponse("Unique constraint violation"),
+			}),
+		},
+	}
+
+	paths["checkunique"] = map[string]any{
+		"post": map[string]any{
+			"operationId": name + "_checkunique",
+			"summary":     fmt.Sprintf("Check which values of a unique %s field are already taken", name),
+			"tags":        []string{name},
+			"requestBody": openAPIRequestBody(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"field":  map[string]any{"type": "string", "description": "Unique column to check"},
+					"values": map[string]any{"type": "array", "description": "Candidate values, at most batch.max_size", "items": map[string]any{}},
+				},
+				"required": []string{"field", "values"},
+			}),
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Values split into existing and available", openAPIRef("CheckUniqueResponse")),
+			}),
+		},
+	}
+
+	paths["validate"] = map[string]any{
+		"post": map[string]any{
+			"operationId": name + "_validate",
+			"summary":     fmt.Sprintf("Validate %s records for a create or update without writing them", name),
+			"tags":        []string{name},
+			"parameters": []map[string]any{
+				openAPIQueryParam("action", "Checks to run: those of :create (default) or :update", map[string]any{"type": "string", "enum": []string{"create", "update"}}),
+			},
+			"requestBody": openAPIRequestBody(openAPIDataEnvelope(openAPIOneOrMany(map[string]any{"type": "object"}))),
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Per-item results with status valid or invalid", map[string]any{"type": "object"}),
+			}),
+		},
+	}
+
+	filterParam := openAPIQueryParam("filter", "Filter tree of and/or groups as in the :query body, as JSON", map[string]any{"type": "string"})
+	for _, agg := range openAPIAggregations {
+		params := []map[string]any{}
+		if agg != "count" {
+			params = append(params, openAPIRequiredQueryParam("field", "Numeric field to aggregate", map[string]any{"type": "string"}))
+		}
+		params = append(params, openAPISearchParams()...)
+		params = append(params, filterParam)
+		params = append(params, openAPIStrictParam())
+		paths[agg] = map[string]any{
+			"get": map[string]any{
+				"operationId": name + "_" + agg,
+				"summary":     fmt.Sprintf("Compute %s over %s records", agg, name),
+				"tags":        []string{name},
+				"parameters":  params,
+				"responses": withErrors(map[string]any{
+					"200": openAPIJSONResponse("Aggregation result", openAPIRef("AggregationResponse")),
+				}),
+			},
+		}
+	}
+
+	paths["import"] = map[string]any{
+		"post": map[string]any{
+			"operationId": name + "_import",
+			"summary":     fmt.Sprintf("Bulk import %s records from a CSV or JSON file", name),
+			"tags":        []string{name},
+			"parameters": []map[string]any{
+				openAPIQueryParam("format", "File format; inferred from the file name when omitted", map[string]any{"type": "string", "enum": []string{"csv", "json"}}),
+				openAPIQueryParam("dry_run", "Validate the file without writing any records", map[string]any{"type": "boolean"}),
+				openAPIQueryParam("journal", "Record the import as a job that stops at a failed chunk and can be resumed", map[string]any{"type": "boolean"}),
+				openAPIQueryParam("resume", "Continue a failed or interrupted import job with the same file", map[string]any{"type": "string"}),
+			},
+			"requestBody": map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"multipart/form-data": map[string]any{
+						"schema": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"file": map[string]any{"type": "string", "format": "binary"},
+							},
+							"required": []string{"file"},
+						},
+					},
+				},
+			},
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("All rows imported", openAPIRef("ImportResponse")),
+				"207": openAPIJSONResponse("Import finished with skipped rows", openAPIRef("ImportResponse")),
+				"409": openAPIErrorResponse("The resumed job is running, completed or not an import of the collection"),
+				"413": openAPIErrorResponse("Upload exceeds batch.max_import_bytes"),
+				"422": openAPIErrorResponse("The upload differs from the one the resumed job imported"),
+			}),
+		},
+	}
+
+	paths["export"] = map[string]any{
+		"get": map[string]any{
+			"operationId": name + "_export",
+			"summary":     fmt.Sprintf("Export all matching %s records as CSV or newline-delimited JSON", name),
+			"tags":        []string{name},
+			"parameters": []map[string]any{
+				openAPIQueryParam("format", "Export format (defaults to csv)", map[string]any{"type": "string", "enum": []string{"csv", "json"}}),
+				openAPIQueryParam("sort", "Comma-separated fields, prefix with '-' for descending", map[string]any{"type": "string"}),
+				openAPIQueryParam("q", "Full-text search across string columns", map[string]any{"type": "string"}),
+				openAPIQueryParam("q_fields", "Comma-separated string fields to search (defaults to all)", map[string]any{"type": "string"}),
+				openAPIQueryParam("q_mode", "How q matches (defaults to contains)", map[string]any{"type": "string", "enum": []string{"contains", "insensitive", "prefix", "exact"}}),
+				openAPIQueryParam("fields", "Comma-separated fields to export (id always included); dotted names such as meta.color select keys of a json column", map[string]any{"type": "string"}),
+			},
+			"responses": withErrors(map[string]any{
+				"200": map[string]any{
+					"description": "Streamed records, downloaded as an attachment",
+					"content": map[string]any{
+						"text/csv":             map[string]any{"schema": map[string]any{"type": "string"}},
+						"application/x-ndjson": map[string]any{"schema": recordRef},
+					},
+				},
+			}),
+		},
+	}
+
+	paths["changes"] = map[string]any{
+		"get": map[string]any{
+			"operationId": name + "_changes",
+			"summary":     fmt.Sprintf("Wait for %s records to be created, updated or deleted", name),
+			"tags":        []string{name},
+			"parameters": []map[string]any{
+				openAPIQueryParam("since", "Sequence returned by the previous call; omit to get the current sequence", map[string]any{"type": "integer", "minimum": 0}),
+				openAPIQueryParam("timeout", "Seconds to wait for a change (defaults to 30)", map[string]any{"type": "integer", "minimum": 0, "maximum": int(constants.MaxChangesTimeout.Seconds())}),
+			},
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Changes after since, or none when the timeout passed", openAPIRef("ChangesResponse")),
+			}),
+		},
+	}
+
+	if hidden {
+		includeHidden := openAPIQueryParam("include_hidden", "Include hidden columns (admins with the schema scope)", map[string]any{"type": "boolean"})
+		for _, action := range []string{"list", "get", "export"} {
+			op := paths[action].(map[string]any)["get"].(map[string]any)
+			op["parameters"] = append(op["parameters"].([]map[string]any), includeHidden)
+		}
+	}
+
+	if len(references) > 0 {
+		expand := openAPIQueryParam("expand", fmt.Sprintf("Comma-separated reference fields whose records are returned under _expanded (at most %d)", constants.MaxExpandFields),
+			map[string]any{"type": "string", "example": strings.Join(references, ",")})
+		for _, action := range []string{"list", "get"} {
+			op := paths[action].(map[string]any)["get"].(map[string]any)
+			op["parameters"] = append(op["parameters"].([]map[string]any), expand)
+		}
+	}
+
+	if softDelete {
+		includeDeleted := openAPIQueryParam("include_deleted", "Include soft-deleted records", map[string]any{"type": "boolean"})
+		for _, action := range []string{"list", "get", "export"} {
+			op := paths[action].(map[string]any)["get"].(map[string]any)
+			op["parameters"] = append(op["parameters"].([]map[string]any), includeDeleted)
+		}
+
+		paths["restore"] = map[string]any{
+			"post": map[string]any{
+				"operationId": name + "_restore",
+				"summary":     fmt.Sprintf("Restore one or more soft-deleted %s records", name),
+				"tags":        []string{name},
+				"parameters": []map[string]any{
+					openAPIQueryParam("atomic", "Process batch requests in a single transaction", map[string]any{"type": "boolean"}),
+				},
+				"requestBody": openAPIRequestBody(openAPIDataEnvelope(openAPIOneOrMany(map[string]any{"type": "string"}))),
+				"responses": withErrors(map[string]any{
+					"200": openAPIJSONResponse("Record(s) restored", openAPIRef("MessageResponse")),
+					"207": openAPIJSONResponse("Batch processed with per-item results", map[string]any{"type": "object"}),
+				}),
+			},
+		}
+	}
+
+	listOp := paths["list"].(map[string]any)["get"].(map[string]any)
+	paths["explain"] = map[string]any{
+		"get": map[string]any{
+			"operationId": name + "_explain",
+			"summary":     fmt.Sprintf("Show the SQL and query plan of a %s list without running it", name),
+			"tags":        []string{name},
+			"parameters": append(append([]map[string]any{}, listOp["parameters"].([]map[string]any)...),
+				openAPIQueryParam("show_values", "Include the bound values (admins with the schema scope)", map[string]any{"type": "boolean"})),
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("SQL, arguments and plan", openAPIRef("ExplainResponse")),
+			}),
+		},
+	}
+
+	paths["groupby"] = map[string]any{
+		"get": map[string]any{
+			"operationId": name + "_groupby",
+			"summary":     fmt.Sprintf("Aggregate %s records per distinct value of a column", name),
+			"tags":        []string{name},
+			"parameters": append([]map[string]any{
+				openAPIRequiredQueryParam("by", "Column to group by", map[string]any{"type": "string"}),
+				openAPIQueryParam("agg", "Aggregate function (defaults to count)", map[string]any{"type": "string", "enum": openAPIAggregations}),
+				openAPIQueryParam("field", "Numeric field to aggregate; required unless agg is count", map[string]any{"type": "string"}),
+			}, append(openAPISearchParams(), filterParam)...),
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Grouped aggregation result", openAPIRef("GroupByResponse")),
+			}),
+		},
+	}
+
+	paths["timeseries"] = map[string]any{
+		"get": map[string]any{
+			"operationId": name + "_timeseries",
+			"summary":     fmt.Sprintf("Aggregate %s records per hour, day, week or month of a datetime field", name),
+			"tags":        []string{name},
+			"parameters": append([]map[string]any{
+				openAPIRequiredQueryParam("field", "Datetime field to bucket by", map[string]any{"type": "string"}),
+				openAPIQueryParam("interval", "Bucket size (defaults to day)", map[string]any{"type": "string", "enum": query.TimeIntervals}),
+				openAPIQueryParam("agg", "Aggregate function (defaults to count)", map[string]any{"type": "string", "enum": openAPIAggregations}),
+				openAPIQueryParam("value_field", "Field to aggregate; required unless agg is count", map[string]any{"type": "string"}),
+				openAPIQueryParam("from", "First bucket; defaults to the first bucket with records", map[string]any{"type": "string", "format": "date-time"}),
+				openAPIQueryParam("to", "Last bucket; defaults to the last bucket with records", map[string]any{"type": "string", "format": "date-time"}),
+			}, openAPISearchParams()...),
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Buckets in ascending order, empty ones included", openAPIRef("TimeSeriesResponse")),
+			}),
+		},
+	}
+
+	paths["stats"] = map[string]any{
+		"get": map[string]any{
+			"operationId": name + "_stats",
+			"summary":     fmt.Sprintf("Report row count, size and null ratios of %s", name),
+			"tags":        []string{name},
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Collection statistics", openAPIRef("CollectionStatsResponse")),
+			}),
+		},
+	}
+
+	paths["distinct"] = map[string]any{
+		"get": map[string]any{
+			"operationId": name + "_distinct",
+			"summary":     fmt.Sprintf("List distinct values of a %s column", name),
+			"tags":        []string{name},
+			"parameters": append([]map[string]any{
+				openAPIRequiredQueryParam("field", "Column to list values of", map[string]any{"type": "string"}),
+				openAPIQueryParam("limit", "Maximum number of values", map[string]any{"type": "integer", "default": constants.DefaultDistinctLimit, "maximum": constants.MaxDistinctLimit}),
+				openAPIQueryParam("count", "Return {value, count} pairs with the number of records per value", map[string]any{"type": "boolean"}),
+			}, append(openAPISearchParams(), filterParam)...),
+			"responses": withErrors(map[string]any{
+				"200": openAPIJSONResponse("Distinct values in ascending order", openAPIRef("DistinctResponse")),
+			}),
+		},
+	}
+
+	// Aggregations that return single values refuse hidden columns without it
+	if hidden {
+		includeHidden := openAPIQueryParam("include_hidden", "Allow hidden columns as the field (admins with the schema scope)", map[string]any{"type": "boolean"})
+		for _, action := range []string{"min", "max", "groupby", "distinct", "timeseries"} {
+			op := paths[action].(map[string]any)["get"].(map[string]any)
+			op["parameters"] = append(op["parameters"].([]map[string]any), includeHidden)
+		}
+	}
+
+	return paths
+}
+
+// openAPIRecordSchema converts a collection definition to an OpenAPI object schema.
+// When includeID is true the read-only id property is added; collections of
+// client-supplied ids require it in create payloads instead.
+func openAPIRecordSchema(collection *registry.Collection, includeID bool) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	clientID := collection.RecordIDType() == registry.IDTypeClient
+	if includeID {
+		properties["id"] = map[string]any{
+			"type":     "string",
+			"readOnly": !clientID,
+		}
+	} else if clientID {
+		properties["id"] = map[string]any{"type": "string", "maxLength": constants.MaxClientIDLength}
+		required = append(required, "id")
+	}
+
+	// Required columns are listed in the ui order of the collection
+	for _, col := range uiOrderedColumns(collection.UI, collection.Columns) {
+		// Hidden columns are only written, never returned; computed columns
+		// only returned, never written
+		if systemColumns[col.Name] || col.Hidden && includeID || col.Computed != "" && !includeID {
+			continue
+		}
+		prop := openAPIColumnType(col.Type)
+		addOpenAPIConstraints(prop, col)
+		if col.Hidden {
+			prop["writeOnly"] = true
+		}
+		if col.Computed != "" {
+			prop["readOnly"] = true
+			prop["description"] = "Computed as " + col.Computed
+		}
+		if col.Nullable {
+			prop["nullable"] = true
+		} else {
+			required = append(required, col.Name)
+		}
+		properties[col.Name] = prop
+	}
+
+	if includeID {
+		timestamps := []string{constants.CreatedAtColumn, constants.UpdatedAtColumn}
+		if collection.SoftDelete {
+			timestamps = append(timestamps, constants.SoftDeleteColumn)
+		}
+		for _, name := range timestamps {
+			properties[name] = map[string]any{
+				"type":     "string",
+				"format":   "date-time",
+				"nullable": true,
+				"readOnly": true,
+			}
+		}
+		properties[constants.RevisionColumn] = map[string]any{
+			"type":     "integer",
+			"format":   "int64",
+			"readOnly": true,
+		}
+		if collection.ExposeSequence {
+			properties[constants.SequenceField] = map[string]any{
+				"type":     "integer",
+				"format":   "int64",
+				"readOnly": true,
+			}
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPIColumnType maps a registry column type to an OpenAPI schema fragment
+func openAPIColumnType(colType registry.ColumnType) map[string]any {
+	switch colType {
+	case registry.TypeInteger:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case registry.TypeBoolean:
+		return map[string]any{"type": "boolean"}
+	case registry.TypeDatetime:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case registry.TypeJSON:
+		return map[string]any{"type": "object"}
+	case registry.TypeDecimal:
+		return map[string]any{"type": "string", "format": "decimal", "example": "199.99"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// addOpenAPIConstraints adds the value constraints of a column to its
+// property; decimal bounds have no OpenAPI keyword, as decimals are strings
+func addOpenAPIConstraints(prop map[string]any, col registry.Column) {
+	if col.MaxLength != nil {
+		prop["maxLength"] = *col.MaxLength
+	}
+	if len(col.Enum) > 0 {
+		prop["enum"] = col.Enum
+	}
+	if col.Type == registry.TypeInteger {
+		if col.Min != nil {
+			prop["minimum"] = *col.Min
+		}
+		if col.Max != nil {
+			prop["maximum"] = *col.Max
+		}
+	}
+}
+
+// openAPISchemaName returns the component schema name for a collection
+func openAPISchemaName(collectionName string) string {
+	return "Collection_" + collectionName
+}
+
+func openAPIRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func openAPIOneOrMany(item map[string]any) map[string]any {
+	return map[string]any{
+		"oneOf": []map[string]any{
+			item,
+			{"type": "array", "items": item},
+		},
+	}
+}
+
+// openAPIAggregateValue describes an aggregate: a number for integer fields
+// and a decimal string for decimal fields
+func openAPIAggregateValue() map[string]any {
+	return map[string]any{
+		"oneOf": []map[string]any{
+			{"type": "number"},
+			{"type": "string", "format": "decimal"},
+			{"type": "string", "description": "min and max of string and datetime fields"},
+		},
+		"nullable": true,
+	}
+}
+
+func openAPIDataEnvelope(data map[string]any) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"data": data,
+		},
+		"required": []string{"data"},
+	}
+}
+
+// openAPILinkedEnvelope is openAPIDataEnvelope with the links of a single
+// record
+func openAPILinkedEnvelope(data map[string]any) map[string]any {
+	envelope := openAPIDataEnvelope(data)
+	envelope["properties"].(map[string]any)["links"] = openAPIRef("RecordLinks")
+	return envelope
+}
+
+func openAPIQueryParam(name, description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      schema,
+	}
+}
+
+// openAPISearchParams describes the full-text search parameters of aggregations
+func openAPISearchParams() []map[string]any {
+	return []map[string]any{
+		openAPIQueryParam("q", "Full-text search across string columns", map[string]any{"type": "string"}),
+		openAPIQueryParam("q_fields", "Comma-separated string fields to search (defaults to all)", map[string]any{"type": "string"}),
+		openAPIQueryParam("q_mode", "How q matches (defaults to contains)", map[string]any{"type": "string", "enum": []string{"contains", "insensitive", "prefix", "exact"}}),
+	}
+}
+
+// openAPIStrictParam is the ?strict= override of api.strict_query_params
+func openAPIStrictParam() map[string]any {
+	return openAPIQueryParam("strict", "Reject unknown query parameters (defaults to api.strict_query_params)", map[string]any{"type": "boolean"})
+}
+
+func openAPIRequiredQueryParam(name, description string, schema map[string]any) map[string]any {
+	param := openAPIQueryParam(name, description, schema)
+	param["required"] = true
+	return param
+}
+
+func openAPIRequestBody(schema map[string]any) map[string]any {
+	return map[string]any{
+		"required": true,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schema},
+		},
+	}
+}
+
+func openAPIJSONResponse(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schema},
+		},
+	}
+}
+
+func openAPIWithHeader(response map[string]any, name, description string) map[string]any {
+	response["headers"] = map[string]any{
+		name: map[string]any{"description": description, "schema": map[string]any{"type": "string"}},
+	}
+	return response
+}
+
+func openAPIErrorResponse(description string) map[string]any {
+	return openAPIJSONResponse(description, openAPIRef("Error"))
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+func newOpenAPITestHandler(t *testing.T, cfg *config.AppConfig) *DocHandler {
+	t.Helper()
+	reg := registry.NewSchemaRegistry()
+	if err := reg.Set(&registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "name", Type: registry.TypeString, Nullable: false},
+			{Name: "price", Type: registry.TypeDecimal, Nullable: false},
+			{Name: "quantity", Type: registry.TypeInteger, Nullable: true},
+			{Name: "active", Type: registry.TypeBoolean, Nullable: true},
+			{Name: "released_at", Type: registry.TypeDatetime, Nullable: true},
+			{Name: "meta", Type: registry.TypeJSON, Nullable: true},
+		},
+	}); err != nil {
+		t.Fatalf("failed to register collection: %v", err)
+	}
+	return NewDocHandler(reg, cfg, "1.99")
+}
+
+func decodeOpenAPI(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var spec map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("failed to decode OpenAPI spec: %v", err)
+	}
+	return spec
+}
+
+func TestDocHandler_OpenAPI(t *testing.T) {
+	cfg := &config.AppConfig{
+		Server: config.ServerConfig{Port: 6006, Prefix: "/api/v1"},
+		JWT:    config.JWTConfig{Secret: "test-secret"},
+		APIKey: config.APIKeyConfig{Enabled: true},
+	}
+	handler := newOpenAPITestHandler(t, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/doc/openapi.json", nil)
+	req.Host = "localhost:6006"
+	rec := httptest.NewRecorder()
+	handler.OpenAPI(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Error("expected ETag header to be set")
+	}
+
+	spec := decodeOpenAPI(t, rec)
+	if spec["openapi"] != OpenAPIVersion {
+		t.Errorf("expected openapi %s, got %v", OpenAPIVersion, spec["openapi"])
+	}
+
+	servers := spec["servers"].([]any)
+	if url := servers[0].(map[string]any)["url"]; url != "http://localhost:6006/api/v1" {
+		t.Errorf("unexpected server url: %v", url)
+	}
+
+	paths := spec["paths"].(map[string]any)
+	for _, action := range []string{"list", "get", "create", "update", "destroy", "upsert", "count", "sum", "avg", "min", "max", "groupby", "distinct", "export", "changes", "import", "checkunique", "validate", "explain", "timeseries"} {
+		if _, ok := paths["/products:"+action]; !ok {
+			t.Errorf("expected path /products:%s", action)
+		}
+	}
+	if _, ok := paths["/products:restore"]; ok {
+		t.Error("restore path should only be present for soft-delete collections")
+	}
+
+	components := spec["components"].(map[string]any)
+	schemes := components["securitySchemes"].(map[string]any)
+	if _, ok := schemes["bearerAuth"]; !ok {
+		t.Error("expected bearerAuth security scheme")
+	}
+	if _, ok := schemes["apiKeyAuth"]; !ok {
+		t.Error("expected apiKeyAuth security scheme")
+	}
+
+	schemas := components["schemas"].(map[string]any)
+	record := schemas["Collection_products"].(map[string]any)
+	props := record["properties"].(map[string]any)
+
+	tests := map[string]string{
+		"id":          "string",
+		"name":        "string",
+		"price":       "string",
+		"quantity":    "integer",
+		"active":      "boolean",
+		"released_at": "string",
+		"meta":        "object",
+	}
+	for field, want := range tests {
+		prop, ok := props[field].(map[string]any)
+		if !ok {
+			t.Errorf("expected property %s", field)
+			continue
+		}
+		if prop["type"] != want {
+			t.Errorf("property %s: expected type %s, got %v", field, want, prop["type"])
+		}
+	}
+	if props["released_at"].(map[string]any)["format"] != "date-time" {
+		t.Error("expected datetime column to use date-time format")
+	}
+
+	for _, field := range []string{"created_at", "updated_at"} {
+		prop, ok := props[field].(map[string]any)
+		if !ok || prop["readOnly"] != true {
+			t.Errorf("expected read-only %s property on record schema", field)
+		}
+	}
+
+	input := schemas["Collection_productsInput"].(map[string]any)
+	inputProps := input["properties"].(map[string]any)
+	for _, field := range []string{"id", "created_at", "updated_at"} {
+		if _, ok := inputProps[field]; ok {
+			t.Errorf("input schema should not include %s", field)
+		}
+	}
+}
+
+func TestDocHandler_OpenAPI_SecuritySchemesDisabled(t *testing.T) {
+	cfg := &config.AppConfig{
+		Server: config.ServerConfig{Port: 6006},
+	}
+	handler := newOpenAPITestHandler(t, cfg)
+
+	rec := httptest.NewRecorder()
+	handler.OpenAPI(rec, httptest.NewRequest(http.MethodGet, "/doc/openapi.json", nil))
+
+	spec := decodeOpenAPI(t, rec)
+	schemes := spec["components"].(map[string]any)["securitySchemes"].(map[string]any)
+	if len(schemes) != 0 {
+		t.Errorf("expected no security schemes, got %v", schemes)
+	}
+}
+
+func TestDocHandler_OpenAPI_CachingAndRefresh(t *testing.T) {
+	cfg := &config.AppConfig{
+		Server: config.ServerConfig{Port: 6006},
+	}
+	handler := newOpenAPITestHandler(t, cfg)
+
+	rec1 := httptest.NewRecorder()
+	handler.OpenAPI(rec1, httptest.NewRequest(http.MethodGet, "/doc/openapi.json", nil))
+	etag := rec1.Header().Get("ETag")
+
+	req2 := httptest.NewRequest(http.MethodGet, "/doc/openapi.json", nil)
+	req2.Header.Set("If-None-Match", etag)
+	rec2 := httptest.NewRecorder()
+	handler.OpenAPI(rec2, req2)
+	if rec2.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", rec2.Code)
+	}
+
+	handler.RefreshCache(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/doc:refresh", nil))
+
+	handler.cacheMutex.RLock()
+	cache := handler.openapiCache
+	handler.cacheMutex.RUnlock()
+	if cache != nil {
+		t.Error("expected OpenAPI cache to be cleared")
+	}
+}
+
+func TestDocHandler_OpenAPI_SoftDelete(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name:       "notes",
+		Columns:    []registry.Column{{Name: "title", Type: registry.TypeString}},
+		SoftDelete: true,
+	})
+	handler := NewDocHandler(reg, &config.AppConfig{Server: config.ServerConfig{Port: 6006}}, "1.99")
+
+	rec := httptest.NewRecorder()
+	handler.OpenAPI(rec, httptest.NewRequest(http.MethodGet, "/doc/openapi.json", nil))
+	spec := decodeOpenAPI(t, rec)
+
+	paths := spec["paths"].(map[string]any)
+	if _, ok := paths["/notes:restore"]; !ok {
+		t.Error("expected restore path for soft-delete collection")
+	}
+
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	props := schemas["Collection_notes"].(map[string]any)["properties"].(map[string]any)
+	if _, ok := props["deleted_at"]; !ok {
+		t.Error("expected deleted_at property on record schema")
+	}
+	inputProps := schemas["Collection_notesInput"].(map[string]any)["properties"].(map[string]any)
+	if _, ok := inputProps["deleted_at"]; ok {
+		t.Error("input schema should not include deleted_at")
+	}
+}
+
+func TestDocHandler_OpenAPI_Constraints(t *testing.T) {
+	reg := registry.NewSchemaRegistry()
+	reg.Set(&registry.Collection{
+		Name: "notes",
+		Columns: []registry.Column{
+			{Name: "title", Type: registry.TypeString, MaxLength: intPtr(10)},
+			{Name: "status", Type: registry.TypeString, Enum: []string{"draft", "published"}},
+			{Name: "rank", Type: registry.TypeInteger, Min: numberPtr("1"), Max: numberPtr("5")},
+		},
+	})
+	handler := NewDocHandler(reg, &config.AppConfig{Server: config.ServerConfig{Port: 6006}}, "1.99")
+
+	rec := httptest.NewRecorder()
+	handler.OpenAPI(rec, httptest.NewRequest(http.MethodGet, "/doc/openapi.json", nil))
+	spec := decodeOpenAPI(t, rec)
+
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	props := schemas["Collection_notesInput"].(map[string]any)["properties"].(map[string]any)
+	if got := props["title"].(map[string]any)["maxLength"]; got != float64(10) {
+		t.Errorf("expected maxLength 10, got %v", got)
+	}
+	if got, _ := props["status"].(map[string]any)["enum"].([]any); len(got) != 2 {
+		t.Errorf("expected two enum values, got %v", got)
+	}
+	rank := props["rank"].(map[string]any)
+	if rank["minimum"] != float64(1) || rank["maximum"] != float64(5) {
+		t.Errorf("expected minimum 1 and maximum 5, got %v", rank)
+	}
+}
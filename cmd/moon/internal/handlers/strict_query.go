@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// filterKeyRegex matches any parameter shaped like a filter, also with an
+// unknown operator, so that strict checking can suggest the intended one
+var filterKeyRegex = regexp.MustCompile(`^(.+)\[([^\[\]]*)\]$`)
+
+// checkQueryParams rejects the query parameters of a request that are
+// neither in one of the known lists nor a filter on a column of the
+// collection, when strict checking is on: ?strict= or else strictDefault
+// (api.strict_query_params). Each unknown parameter is named with the
+// closest known parameter or column. Errors are *apperrors.APIError values.
+func checkQueryParams(r *http.Request, strictDefault bool, collection *registry.Collection, known ...[]string) error {
+	params := r.URL.Query()
+	strict, err := QueryBoolean(r, constants.QueryParamStrict, strictDefault)
+	if err != nil || !strict {
+		return err
+	}
+
+	names := slices.Clone(constants.CommonQueryParams)
+	for _, list := range known {
+		names = append(names, list...)
+	}
+	columns := filterColumnNames(collection)
+
+	var unknown []string
+	for key := range params {
+		if slices.Contains(names, key) {
+			continue
+		}
+		if problem := checkFilterKey(key, names, columns); problem != "" {
+			unknown = append(unknown, problem)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	slices.Sort(unknown)
+	return apperrors.NewAPIError(http.StatusBadRequest, apperrors.CodeUnknownParameter,
+		"unknown query parameters: "+strings.Join(unknown, ", "))
+}
+
+// checkFilterKey returns "" for a filter on a known column with a known
+// operator, and otherwise the key with a suggestion when one is close enough
+func checkFilterKey(key string, names, columns []string) string {
+	matches := filterKeyRegex.FindStringSubmatch(key)
+	if matches == nil {
+		// A bare column name is most likely a filter missing its operator
+		if slices.Contains(columns, key) {
+			return fmt.Sprintf("%s (did you mean %s[eq]?)", key, key)
+		}
+		if name, ok := closestName(key, names); ok {
+			return fmt.Sprintf("%s (did you mean %s?)", key, name)
+		}
+		if column, ok := closestName(key, columns); ok {
+			return fmt.Sprintf("%s (did you mean %s[eq]?)", key, column)
+		}
+		return key
+	}
+
+	column, operator := matches[1], matches[2]
+	root, path, dotted := strings.Cut(column, ".")
+	rootKnown := slices.Contains(columns, root)
+	operatorKnown := slices.Contains(filterOperators, operator)
+	if rootKnown && operatorKnown {
+		return ""
+	}
+
+	var ok bool
+	if !rootKnown {
+		if root, ok = closestName(root, columns); !ok {
+			return key
+		}
+		column = root
+		if dotted {
+			column += "." + path
+		}
+	}
+	if !operatorKnown {
+		if operator, ok = closestName(operator, filterOperators); !ok {
+			return key
+		}
+	}
+	return fmt.Sprintf("%s (did you mean %s[%s]?)", key, column, operator)
+}
+
+// filterColumnNames returns the fields a filter may name: the columns, the
+// queryable system columns and seq with expose_sequence
+func filterColumnNames(collection *registry.Collection) []string {
+	var names []string
+	for _, col := range collection.Columns {
+		names = append(names, col.Name)
+	}
+	for _, col := range queryableSystemColumns() {
+		names = append(names, col.Name)
+	}
+	if collection.ExposeSequence {
+		names = append(names, sequenceColumn.Name)
+	}
+	return names
+}
+
+// closestName returns the candidate with the smallest edit distance to name,
+// the first one on ties, if it is close enough to be a likely typo: at most
+// a third of the length of name plus one edits, and fewer edits than name
+// has characters
+func closestName(name string, candidates []string) (string, bool) {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		if d := levenshtein(name, candidate); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	length := len([]rune(name))
+	if bestDistance < 0 || bestDistance > length/3+1 || bestDistance >= length {
+		return "", false
+	}
+	return best, true
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions that turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
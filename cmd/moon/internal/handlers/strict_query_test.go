@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"limit", "limit", 0},
+		{"limt", "limit", 1},
+		{"sotr", "sort", 2},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"prïce", "price", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestName(t *testing.T) {
+	candidates := []string{"limit", "after", "sort", "fields", "q", "q_fields", "customer_id", "label"}
+	tests := []struct {
+		name string
+		want string // "" for no suggestion
+	}{
+		{"limt", "limit"},
+		{"lmit", "limit"},
+		{"fileds", "fields"},
+		{"sort_by", "sort"},
+		{"q_fiels", "q_fields"},
+		{"customerid", "customer_id"},
+		{"lable", "label"},
+		{"x", ""},
+		{"zzzzzz", ""},
+		{"description", ""},
+	}
+	for _, tt := range tests {
+		got, ok := closestName(tt.name, candidates)
+		if tt.want == "" && ok {
+			t.Errorf("closestName(%q) = %q, expected no suggestion", tt.name, got)
+		}
+		if tt.want != "" && got != tt.want {
+			t.Errorf("closestName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStrictQueryParams_List(t *testing.T) {
+	rt := setupReferenceTest(t)
+	cfg := testConfig()
+	cfg.API.StrictQueryParams = true
+	handler := NewDataHandler(rt.driver, rt.reg, cfg)
+
+	tests := []struct {
+		url  string
+		want []string // fragments of the error message; nil for 200
+	}{
+		{"/orders:list?limit=10&sort=-label&fields=label&label[eq]=x&customer_id[isnull]=true&created_at[gte]=2020-01-01T00:00:00Z&id[ne]=x&q=a&q_mode=prefix&total=false&case=camel&strict=true", nil},
+		{"/orders:list?limt=10", []string{"limt (did you mean limit?)"}},
+		{"/orders:list?sotr=label&limit=5", []string{"sotr (did you mean sort?)"}},
+		{"/orders:list?lable[eq]=x", []string{"lable[eq] (did you mean label[eq]?)"}},
+		{"/orders:list?label[eqq]=x", []string{"label[eqq] (did you mean label[eq]?)"}},
+		{"/orders:list?label=x", []string{"label (did you mean label[eq]?)"}},
+		{"/orders:list?customerid[eq]=x&foo=1", []string{"customerid[eq] (did you mean customer_id[eq]?)", "foo"}},
+		{"/orders:list?seq[gt]=1", []string{"seq"}},
+	}
+	for _, tt := range tests {
+		w := rt.do(t, handler.List, "orders", tt.url, nil)
+		if tt.want == nil {
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d: %s", tt.url, w.Code, w.Body.String())
+			}
+			continue
+		}
+		if w.Code != http.StatusBadRequest || errorCode(w) != "unknown_parameter" {
+			t.Errorf("%s: expected 400 unknown_parameter, got %d: %s", tt.url, w.Code, w.Body.String())
+			continue
+		}
+		for _, fragment := range tt.want {
+			if !strings.Contains(w.Body.String(), fragment) {
+				t.Errorf("%s: expected %q in %s", tt.url, fragment, w.Body.String())
+			}
+		}
+	}
+
+	// ?strict=false turns the check off for one request
+	if w := rt.do(t, handler.List, "orders", "/orders:list?limt=10&strict=false", nil); w.Code != http.StatusOK {
+		t.Errorf("expected ?strict=false to ignore unknown parameters, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := rt.do(t, handler.List, "orders", "/orders:list?strict=maybe", nil); w.Code != http.StatusBadRequest || errorCode(w) != "invalid_parameter" {
+		t.Errorf("expected 400 invalid_parameter for an invalid strict, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStrictQueryParams_NonStrictDefault(t *testing.T) {
+	rt := setupReferenceTest(t)
+
+	if w := rt.do(t, rt.data.List, "orders", "/orders:list?limt=10&label[eqq]=x", nil); w.Code != http.StatusOK {
+		t.Errorf("expected unknown parameters to be ignored by default, got %d: %s", w.Code, w.Body.String())
+	}
+	w := rt.do(t, rt.data.List, "orders", "/orders:list?limt=10&strict=true", nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "did you mean limit?") {
+		t.Errorf("expected ?strict=true to reject limt, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStrictQueryParams_Aggregation(t *testing.T) {
+	rt := setupReferenceTest(t)
+	handler := NewAggregationHandler(rt.driver, rt.reg)
+	handler.SetStrictQueryParams(true)
+
+	tests := []struct {
+		action func(http.ResponseWriter, *http.Request, string)
+		url    string
+		status int
+		want   string
+	}{
+		{handler.Count, "/orders:count?label[like]=x&q=a&include_deleted=false", http.StatusOK, ""},
+		{handler.Count, "/orders:count?limit=5", http.StatusBadRequest, "limit"},
+		{handler.GroupBy, "/orders:groupby?by=label&agg=count&label[ne]=x", http.StatusOK, ""},
+		{handler.GroupBy, "/orders:groupby?by=label&agg=count&fied=label", http.StatusBadRequest, "fied (did you mean field?)"},
+		{handler.Distinct, "/orders:distinct?field=label&limit=5&count=true", http.StatusOK, ""},
+		{handler.Distinct, "/orders:distinct?fild=label", http.StatusBadRequest, "fild (did you mean field?)"},
+		{handler.Max, "/orders:max?feild=created_at", http.StatusBadRequest, "feild (did you mean field?)"},
+	}
+	for _, tt := range tests {
+		w := rt.do(t, tt.action, "orders", tt.url, nil)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: expected %d with %q, got %d: %s", tt.url, tt.status, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
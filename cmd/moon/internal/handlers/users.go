@@ -7,9 +7,11 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/thalib/moon/cmd/moon/internal/audit"
 	"github.com/thalib/moon/cmd/moon/internal/auth"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 // UsersHandler handles user management endpoints (admin only).
@@ -32,21 +34,6 @@ func NewUsersHandler(db database.Driver, jwtSecret string, accessExpiry, refresh
 	}
 }
 
-// Error codes for user management.
-const (
-	ErrCodeMissingRequiredField  = "MISSING_REQUIRED_FIELD"
-	ErrCodeInvalidFieldValue     = "INVALID_FIELD_VALUE"
-	ErrCodeWeakPassword          = "WEAK_PASSWORD"
-	ErrCodeInvalidEmailFormat    = "INVALID_EMAIL_FORMAT"
-	ErrCodeInvalidRole           = "INVALID_ROLE"
-	ErrCodeAdminRequired         = "ADMIN_REQUIRED"
-	ErrCodeCannotModifySelf      = "CANNOT_MODIFY_SELF"
-	ErrCodeCannotDeleteLastAdmin = "CANNOT_DELETE_LAST_ADMIN"
-	ErrCodeUserNotFound          = "USER_NOT_FOUND"
-	ErrCodeUsernameExists        = "USERNAME_EXISTS"
-	ErrCodeEmailExists           = "EMAIL_EXISTS"
-)
-
 // UserListRequest represents a request to list users.
 type UserListRequest struct {
 	Limit int    `json:"limit,omitempty"`
@@ -68,6 +55,7 @@ type UserPublicInfo struct {
 	Email       string  `json:"email"`
 	Role        string  `json:"role"`
 	CanWrite    bool    `json:"can_write"`
+	Tenant      string  `json:"tenant,omitempty"`
 	CreatedAt   string  `json:"created_at"`
 	UpdatedAt   string  `json:"updated_at"`
 	LastLoginAt *string `json:"last_login_at,omitempty"`
@@ -80,6 +68,7 @@ type CreateUserRequest struct {
 	Password string `json:"password"`
 	Role     string `json:"role"`
 	CanWrite *bool  `json:"can_write,omitempty"`
+	Tenant   string `json:"tenant,omitempty"` // fixed at creation
 }
 
 // CreateUserResponse represents a response after creating a user.
@@ -114,14 +103,14 @@ var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]
 // List handles GET /users:list
 func (h *UsersHandler) List(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Validate admin access
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, err.Error(), ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, err.Error())
 		return
 	}
 
@@ -141,7 +130,7 @@ func (h *UsersHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	// Validate role filter if provided
 	if roleFilter != "" && !auth.IsValidRole(roleFilter) {
-		writeErrorWithCode(w, http.StatusBadRequest, "invalid role filter", ErrCodeInvalidRole)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRole, "invalid role filter")
 		return
 	}
 
@@ -152,7 +141,7 @@ func (h *UsersHandler) List(w http.ResponseWriter, r *http.Request) {
 		RoleFilter: roleFilter,
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list users")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to list users")
 		return
 	}
 
@@ -170,9 +159,9 @@ func (h *UsersHandler) List(w http.ResponseWriter, r *http.Request) {
 		publicUsers[i] = userToPublicInfo(user)
 	}
 
-	h.logAdminAction("user_list", claims.UserID, "")
+	h.logAdminAction(r, "user_list", claims.UserID, "")
 
-	writeJSON(w, http.StatusOK, UserListResponse{
+	writeResponse(w, r, http.StatusOK, UserListResponse{
 		Users:      publicUsers,
 		NextCursor: nextCursor,
 		Limit:      limit,
@@ -182,14 +171,14 @@ func (h *UsersHandler) List(w http.ResponseWriter, r *http.Request) {
 // Get handles GET /users:get?id={ulid}
 func (h *UsersHandler) Get(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Validate admin access
 	_, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, err.Error(), ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, err.Error())
 		return
 	}
 
@@ -198,23 +187,23 @@ func (h *UsersHandler) Get(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from query
 	userID := r.URL.Query().Get("id")
 	if userID == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "id is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 
 	// Get user
 	user, err := h.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get user")
 		return
 	}
 
 	if user == nil {
-		writeErrorWithCode(w, http.StatusNotFound, "user not found", ErrCodeUserNotFound)
+		writeError(w, r, http.StatusNotFound, apperrors.CodeUserNotFound, "user not found")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
+	writeResponse(w, r, http.StatusOK, map[string]any{
 		"user": userToPublicInfo(user),
 	})
 }
@@ -222,20 +211,20 @@ func (h *UsersHandler) Get(w http.ResponseWriter, r *http.Request) {
 // Create handles POST /users:create
 func (h *UsersHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Validate admin access
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, err.Error(), ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, err.Error())
 		return
 	}
 
 	var req CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
@@ -243,66 +232,72 @@ func (h *UsersHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// Validate required fields
 	if req.Username == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "username is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "username is required")
 		return
 	}
 	if req.Email == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "email is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "email is required")
 		return
 	}
 	if req.Password == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "password is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "password is required")
 		return
 	}
 	if req.Role == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "role is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "role is required")
 		return
 	}
 
 	// Validate email format
 	if !emailRegex.MatchString(req.Email) {
-		writeErrorWithCode(w, http.StatusBadRequest, "invalid email format", ErrCodeInvalidEmailFormat)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidEmailFormat, "invalid email format")
 		return
 	}
 
 	// Validate role
 	if !auth.IsValidRole(req.Role) {
-		writeErrorWithCode(w, http.StatusBadRequest, "invalid role", ErrCodeInvalidRole)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRole, "invalid role")
+		return
+	}
+
+	// Validate tenant
+	if err := auth.ValidateTenant(req.Tenant); err != nil {
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidTenant, err.Error())
 		return
 	}
 
 	// Validate password
 	if err := h.passwordPolicy.Validate(req.Password); err != nil {
-		writeErrorWithCode(w, http.StatusBadRequest, err.Error(), ErrCodeWeakPassword)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeWeakPassword, err.Error())
 		return
 	}
 
 	// Check if username exists
 	exists, err := h.userRepo.UsernameExists(ctx, req.Username, 0)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to check username")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check username")
 		return
 	}
 	if exists {
-		writeErrorWithCode(w, http.StatusConflict, "username already exists", ErrCodeUsernameExists)
+		writeError(w, r, http.StatusConflict, apperrors.CodeUsernameExists, "username already exists")
 		return
 	}
 
 	// Check if email exists
 	exists, err = h.userRepo.EmailExists(ctx, req.Email, 0)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to check email")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check email")
 		return
 	}
 	if exists {
-		writeErrorWithCode(w, http.StatusConflict, "email already exists", ErrCodeEmailExists)
+		writeError(w, r, http.StatusConflict, apperrors.CodeEmailExists, "email already exists")
 		return
 	}
 
 	// Hash password
 	passwordHash, err := auth.HashPassword(req.Password)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to create user")
 		return
 	}
 
@@ -321,16 +316,18 @@ func (h *UsersHandler) Create(w http.ResponseWriter, r *http.Request) {
 		PasswordHash: passwordHash,
 		Role:         req.Role,
 		CanWrite:     canWrite,
+		Tenant:       req.Tenant,
 	}
 
 	if err := h.userRepo.Create(ctx, user); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to create user")
 		return
 	}
 
-	h.logAdminAction("user_created", claims.UserID, user.ID)
+	h.logAdminAction(r, "user_created", claims.UserID, user.ID)
+	audit.AddRecords(r.Context(), user.ID)
 
-	writeJSON(w, http.StatusCreated, CreateUserResponse{
+	writeResponse(w, r, http.StatusCreated, CreateUserResponse{
 		Message: "user created successfully",
 		User:    userToPublicInfo(user),
 	})
@@ -339,14 +336,14 @@ func (h *UsersHandler) Create(w http.ResponseWriter, r *http.Request) {
 // Update handles POST /users:update?id={ulid}
 func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Validate admin access
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, err.Error(), ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, err.Error())
 		return
 	}
 
@@ -355,31 +352,31 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from query
 	userID := r.URL.Query().Get("id")
 	if userID == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "id is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 
 	// Check if admin is trying to modify themselves
 	if claims.UserID == userID {
-		writeErrorWithCode(w, http.StatusForbidden, "cannot modify own account via user management endpoints", ErrCodeCannotModifySelf)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeCannotModifySelf, "cannot modify own account via user management endpoints")
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		writeBodyError(w, r, err, "invalid request body")
 		return
 	}
 
 	// Get user
 	user, err := h.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get user")
 		return
 	}
 
 	if user == nil {
-		writeErrorWithCode(w, http.StatusNotFound, "user not found", ErrCodeUserNotFound)
+		writeError(w, r, http.StatusNotFound, apperrors.CodeUserNotFound, "user not found")
 		return
 	}
 
@@ -387,32 +384,32 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	switch req.Action {
 	case "reset_password":
 		if req.NewPassword == "" {
-			writeErrorWithCode(w, http.StatusBadRequest, "new_password is required for password reset", ErrCodeMissingRequiredField)
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "new_password is required for password reset")
 			return
 		}
 
 		// Validate password
 		if err := h.passwordPolicy.Validate(req.NewPassword); err != nil {
-			writeErrorWithCode(w, http.StatusBadRequest, err.Error(), ErrCodeWeakPassword)
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeWeakPassword, err.Error())
 			return
 		}
 
 		// Hash new password
 		passwordHash, err := auth.HashPassword(req.NewPassword)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to reset password")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to reset password")
 			return
 		}
 		user.PasswordHash = passwordHash
 
 		if err := h.userRepo.Update(ctx, user); err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to update user")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to update user")
 			return
 		}
 
-		h.logAdminAction("password_reset", claims.UserID, user.ID)
+		h.logAdminAction(r, "password_reset", claims.UserID, user.ID)
 
-		writeJSON(w, http.StatusOK, UpdateUserResponse{
+		writeResponse(w, r, http.StatusOK, UpdateUserResponse{
 			Message: "password reset successfully",
 			User:    userToPublicInfo(user),
 		})
@@ -421,13 +418,13 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	case "revoke_sessions":
 		// Delete all refresh tokens for this user
 		if err := h.tokenRepo.DeleteByUserID(ctx, user.PKID); err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to revoke sessions")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to revoke sessions")
 			return
 		}
 
-		h.logAdminAction("sessions_revoked", claims.UserID, user.ID)
+		h.logAdminAction(r, "sessions_revoked", claims.UserID, user.ID)
 
-		writeJSON(w, http.StatusOK, UpdateUserResponse{
+		writeResponse(w, r, http.StatusOK, UpdateUserResponse{
 			Message: "all sessions revoked successfully",
 			User:    userToPublicInfo(user),
 		})
@@ -436,7 +433,7 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	case "":
 		// Normal update, continue below
 	default:
-		writeErrorWithCode(w, http.StatusBadRequest, "invalid action", ErrCodeInvalidFieldValue)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidFieldValue, "invalid action")
 		return
 	}
 
@@ -446,18 +443,18 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Email != nil {
 		// Validate email format
 		if !emailRegex.MatchString(*req.Email) {
-			writeErrorWithCode(w, http.StatusBadRequest, "invalid email format", ErrCodeInvalidEmailFormat)
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidEmailFormat, "invalid email format")
 			return
 		}
 
 		// Check if email exists for another user
 		exists, err := h.userRepo.EmailExists(ctx, *req.Email, user.PKID)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to check email")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check email")
 			return
 		}
 		if exists {
-			writeErrorWithCode(w, http.StatusConflict, "email already exists", ErrCodeEmailExists)
+			writeError(w, r, http.StatusConflict, apperrors.CodeEmailExists, "email already exists")
 			return
 		}
 
@@ -468,7 +465,7 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if req.Role != nil {
 		// Validate role
 		if !auth.IsValidRole(*req.Role) {
-			writeErrorWithCode(w, http.StatusBadRequest, "invalid role", ErrCodeInvalidRole)
+			writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidRole, "invalid role")
 			return
 		}
 
@@ -476,11 +473,11 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 		if user.Role == string(auth.RoleAdmin) && *req.Role != string(auth.RoleAdmin) {
 			adminCount, err := h.userRepo.CountByRole(ctx, string(auth.RoleAdmin))
 			if err != nil {
-				writeError(w, http.StatusInternalServerError, "failed to check admin count")
+				writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check admin count")
 				return
 			}
 			if adminCount <= 1 {
-				writeErrorWithCode(w, http.StatusForbidden, "cannot downgrade the last admin user", ErrCodeCannotDeleteLastAdmin)
+				writeError(w, r, http.StatusForbidden, apperrors.CodeCannotDeleteLastAdmin, "cannot downgrade the last admin user")
 				return
 			}
 		}
@@ -495,18 +492,19 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !updated {
-		writeError(w, http.StatusBadRequest, "no fields to update")
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "no fields to update")
 		return
 	}
 
 	if err := h.userRepo.Update(ctx, user); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to update user")
 		return
 	}
 
-	h.logAdminAction("user_updated", claims.UserID, user.ID)
+	h.logAdminAction(r, "user_updated", claims.UserID, user.ID)
+	audit.AddRecords(r.Context(), user.ID)
 
-	writeJSON(w, http.StatusOK, UpdateUserResponse{
+	writeResponse(w, r, http.StatusOK, UpdateUserResponse{
 		Message: "user updated successfully",
 		User:    userToPublicInfo(user),
 	})
@@ -515,14 +513,14 @@ func (h *UsersHandler) Update(w http.ResponseWriter, r *http.Request) {
 // Destroy handles POST /users:destroy?id={ulid}
 func (h *UsersHandler) Destroy(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	// Validate admin access
 	claims, err := h.validateAdminAccess(r)
 	if err != nil {
-		writeErrorWithCode(w, http.StatusForbidden, err.Error(), ErrCodeAdminRequired)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeAdminRequired, err.Error())
 		return
 	}
 
@@ -531,25 +529,25 @@ func (h *UsersHandler) Destroy(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from query
 	userID := r.URL.Query().Get("id")
 	if userID == "" {
-		writeErrorWithCode(w, http.StatusBadRequest, "id is required", ErrCodeMissingRequiredField)
+		writeError(w, r, http.StatusBadRequest, apperrors.CodeRequiredField, "id is required")
 		return
 	}
 
 	// Check if admin is trying to delete themselves
 	if claims.UserID == userID {
-		writeErrorWithCode(w, http.StatusForbidden, "cannot delete own account via user management endpoints", ErrCodeCannotModifySelf)
+		writeError(w, r, http.StatusForbidden, apperrors.CodeCannotModifySelf, "cannot delete own account via user management endpoints")
 		return
 	}
 
 	// Get user to check role
 	user, err := h.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to get user")
 		return
 	}
 
 	if user == nil {
-		writeErrorWithCode(w, http.StatusNotFound, "user not found", ErrCodeUserNotFound)
+		writeError(w, r, http.StatusNotFound, apperrors.CodeUserNotFound, "user not found")
 		return
 	}
 
@@ -557,30 +555,31 @@ func (h *UsersHandler) Destroy(w http.ResponseWriter, r *http.Request) {
 	if user.Role == string(auth.RoleAdmin) {
 		adminCount, err := h.userRepo.CountByRole(ctx, string(auth.RoleAdmin))
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to check admin count")
+			writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to check admin count")
 			return
 		}
 		if adminCount <= 1 {
-			writeErrorWithCode(w, http.StatusForbidden, "cannot delete the last admin user", ErrCodeCannotDeleteLastAdmin)
+			writeError(w, r, http.StatusForbidden, apperrors.CodeCannotDeleteLastAdmin, "cannot delete the last admin user")
 			return
 		}
 	}
 
 	// Delete user's refresh tokens first (cascade)
 	if err := h.tokenRepo.DeleteByUserID(ctx, user.PKID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to delete user sessions")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to delete user sessions")
 		return
 	}
 
 	// Delete user
 	if err := h.userRepo.Delete(ctx, user.PKID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to delete user")
+		writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to delete user")
 		return
 	}
 
-	h.logAdminAction("user_deleted", claims.UserID, userID)
+	h.logAdminAction(r, "user_deleted", claims.UserID, userID)
+	audit.AddRecords(r.Context(), userID)
 
-	writeJSON(w, http.StatusOK, DeleteUserResponse{
+	writeResponse(w, r, http.StatusOK, DeleteUserResponse{
 		Message: "user deleted successfully",
 	})
 }
@@ -615,11 +614,12 @@ func (h *UsersHandler) validateAdminAccess(r *http.Request) (*auth.Claims, error
 }
 
 // logAdminAction logs an admin action for audit purposes.
-func (h *UsersHandler) logAdminAction(action, adminULID, targetULID string) {
+func (h *UsersHandler) logAdminAction(r *http.Request, action, adminULID, targetULID string) {
+	ip := getClientIP(r)
 	if targetULID != "" {
-		log.Printf("INFO: ADMIN_ACTION %s by=%s target=%s", action, adminULID, targetULID)
+		log.Printf("INFO: ADMIN_ACTION %s by=%s target=%s ip=%s", action, adminULID, targetULID, ip)
 	} else {
-		log.Printf("INFO: ADMIN_ACTION %s by=%s", action, adminULID)
+		log.Printf("INFO: ADMIN_ACTION %s by=%s ip=%s", action, adminULID, ip)
 	}
 }
 
@@ -631,6 +631,7 @@ func userToPublicInfo(user *auth.User) UserPublicInfo {
 		Email:     user.Email,
 		Role:      user.Role,
 		CanWrite:  user.CanWrite,
+		Tenant:    user.Tenant,
 		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 	}
@@ -657,12 +658,3 @@ func parseIntWithDefault(s string, defaultVal int) int {
 	}
 	return result
 }
-
-// writeErrorWithCode writes a JSON error response with an error code.
-func writeErrorWithCode(w http.ResponseWriter, statusCode int, message, code string) {
-	writeJSON(w, statusCode, map[string]any{
-		"error":      message,
-		"error_code": code,
-		"code":       statusCode,
-	})
-}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/thalib/moon/cmd/moon/internal/auth"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 func setupTestUsersHandler(t *testing.T) (*UsersHandler, *auth.User, string, database.Driver) {
@@ -281,8 +282,8 @@ func TestUsersHandler_Create_WeakPassword(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeWeakPassword {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeWeakPassword)
+	if resp["code"] != string(apperrors.CodeWeakPassword) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeWeakPassword)
 	}
 }
 
@@ -311,8 +312,8 @@ func TestUsersHandler_Create_InvalidEmail(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeInvalidEmailFormat {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeInvalidEmailFormat)
+	if resp["code"] != string(apperrors.CodeInvalidEmailFormat) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeInvalidEmailFormat)
 	}
 }
 
@@ -341,8 +342,8 @@ func TestUsersHandler_Create_InvalidRole(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeInvalidRole {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeInvalidRole)
+	if resp["code"] != string(apperrors.CodeInvalidRole) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeInvalidRole)
 	}
 }
 
@@ -371,8 +372,8 @@ func TestUsersHandler_Create_DuplicateUsername(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeUsernameExists {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeUsernameExists)
+	if resp["code"] != string(apperrors.CodeUsernameExists) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeUsernameExists)
 	}
 }
 
@@ -401,8 +402,8 @@ func TestUsersHandler_Create_DuplicateEmail(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeEmailExists {
-		t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeEmailExists)
+	if resp["code"] != string(apperrors.CodeEmailExists) {
+		t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeEmailExists)
 	}
 }
 
@@ -557,8 +558,8 @@ func TestUsersHandler_Update_CannotModifySelf(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeCannotModifySelf {
-		t.Errorf("Update() error_code = %v, want %v", resp["error_code"], ErrCodeCannotModifySelf)
+	if resp["code"] != string(apperrors.CodeCannotModifySelf) {
+		t.Errorf("Update() code = %v, want %v", resp["code"], apperrors.CodeCannotModifySelf)
 	}
 }
 
@@ -654,8 +655,8 @@ func TestUsersHandler_Destroy_CannotDeleteSelf(t *testing.T) {
 
 	var resp map[string]any
 	json.NewDecoder(w.Body).Decode(&resp)
-	if resp["error_code"] != ErrCodeCannotModifySelf {
-		t.Errorf("Destroy() error_code = %v, want %v", resp["error_code"], ErrCodeCannotModifySelf)
+	if resp["code"] != string(apperrors.CodeCannotModifySelf) {
+		t.Errorf("Destroy() code = %v, want %v", resp["code"], apperrors.CodeCannotModifySelf)
 	}
 }
 
@@ -752,8 +753,8 @@ func TestUsersHandler_Create_MissingFields(t *testing.T) {
 
 			var resp map[string]any
 			json.NewDecoder(w.Body).Decode(&resp)
-			if resp["error_code"] != ErrCodeMissingRequiredField {
-				t.Errorf("Create() error_code = %v, want %v", resp["error_code"], ErrCodeMissingRequiredField)
+			if resp["code"] != string(apperrors.CodeRequiredField) {
+				t.Errorf("Create() code = %v, want %v", resp["code"], apperrors.CodeRequiredField)
 			}
 		})
 	}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// PageInfo is the pagination of a list response from API version 2
+type PageInfo struct {
+	NextCursor *string `json:"next_cursor"`
+	Limit      int     `json:"limit"`
+	Total      *int    `json:"total,omitempty"`       // absent when the records were not counted
+	Page       *int    `json:"page,omitempty"`        // with ?page= pagination
+	TotalPages *int    `json:"total_pages,omitempty"` // with ?page= pagination
+}
+
+// dataListResponseV2 is DataListResponse from API version 2
+type dataListResponseV2 struct {
+	Data []map[string]any `json:"data"`
+	Page PageInfo         `json:"page"`
+}
+
+// userListResponseV2 is UserListResponse from API version 2
+type userListResponseV2 struct {
+	Users []UserPublicInfo `json:"users"`
+	Page  PageInfo         `json:"page"`
+}
+
+// apiKeyListResponseV2 is APIKeyListResponse from API version 2
+type apiKeyListResponseV2 struct {
+	APIKeys []APIKeyPublicInfo `json:"apikeys"`
+	Page    PageInfo           `json:"page"`
+}
+
+// batchItemResultV2 is BatchItemResult from API version 2, where a failed
+// item carries the same error object as an error response
+type batchItemResultV2 struct {
+	Index      int                    `json:"index"`
+	ID         string                 `json:"id,omitempty"`
+	Status     BatchItemStatus        `json:"status"`
+	Data       map[string]any         `json:"data,omitempty"`
+	Error      *apperrors.ErrorDetail `json:"error,omitempty"`
+	CurrentRev *int64                 `json:"current_rev,omitempty"`
+	Link       string                 `json:"link,omitempty"`
+}
+
+// batchResponseV2 is BatchResponse from API version 2
+type batchResponseV2 struct {
+	Results []any        `json:"results"`
+	Summary BatchSummary `json:"summary"`
+}
+
+func (resp DataListResponse) forVersion(version apiversion.Version) any {
+	if version < apiversion.V2 {
+		return resp
+	}
+	return dataListResponseV2{
+		Data: resp.Data,
+		Page: PageInfo{NextCursor: resp.NextCursor, Limit: resp.Limit, Total: resp.Total, Page: resp.Page, TotalPages: resp.TotalPages},
+	}
+}
+
+func (resp UserListResponse) forVersion(version apiversion.Version) any {
+	if version < apiversion.V2 {
+		return resp
+	}
+	return userListResponseV2{
+		Users: resp.Users,
+		Page:  PageInfo{NextCursor: resp.NextCursor, Limit: resp.Limit},
+	}
+}
+
+func (resp APIKeyListResponse) forVersion(version apiversion.Version) any {
+	if version < apiversion.V2 {
+		return resp
+	}
+	return apiKeyListResponseV2{
+		APIKeys: resp.APIKeys,
+		Page:    PageInfo{NextCursor: resp.NextCursor, Limit: resp.Limit},
+	}
+}
+
+func (result BatchItemResult) forVersion(version apiversion.Version) any {
+	if version < apiversion.V2 {
+		return result
+	}
+	v2 := batchItemResultV2{
+		Index:      result.Index,
+		ID:         result.ID,
+		Status:     result.Status,
+		Data:       result.Data,
+		CurrentRev: result.CurrentRev,
+		Link:       result.Link,
+	}
+	if result.ErrorCode != "" || result.ErrorMessage != "" {
+		v2.Error = &apperrors.ErrorDetail{Code: result.ErrorCode, Message: result.ErrorMessage}
+	}
+	return v2
+}
+
+func (resp BatchResponse) forVersion(version apiversion.Version) any {
+	if version < apiversion.V2 {
+		return resp
+	}
+	results := make([]any, len(resp.Results))
+	for i, result := range resp.Results {
+		results[i] = result.forVersion(version)
+	}
+	return batchResponseV2{Results: results, Summary: resp.Summary}
+}
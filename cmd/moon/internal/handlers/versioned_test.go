@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
+)
+
+// doVersionedAction runs a data action for a request negotiated to version
+func doVersionedAction(t *testing.T, action func(http.ResponseWriter, *http.Request, string), version apiversion.Version, method, url string, body any) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, url, bytes.NewReader(payload))
+	w := httptest.NewRecorder()
+	action(w, req.WithContext(apiversion.WithContext(req.Context(), version)), "notes")
+	var raw map[string]any
+	json.Unmarshal(w.Body.Bytes(), &raw)
+	return w, raw
+}
+
+func TestVersionedList(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+
+	_, v1 := doVersionedAction(t, handler.List, apiversion.V1, http.MethodGet, "/notes:list?limit=2", nil)
+	if v1["limit"] != float64(2) || v1["total"] != float64(3) || v1["next_cursor"] == nil {
+		t.Errorf("expected top-level pagination in v1, got %v", v1)
+	}
+	if _, ok := v1["page"]; ok {
+		t.Errorf("v1 list should not carry page, got %v", v1)
+	}
+
+	w, v2 := doVersionedAction(t, handler.List, apiversion.V2, http.MethodGet, "/notes:list?limit=2", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("List failed: %d %s", w.Code, w.Body.String())
+	}
+	page, _ := v2["page"].(map[string]any)
+	if page["limit"] != float64(2) || page["total"] != float64(3) || page["next_cursor"] == nil {
+		t.Errorf("expected pagination under page in v2, got %v", v2)
+	}
+	for _, key := range []string{"limit", "total", "next_cursor"} {
+		if _, ok := v2[key]; ok {
+			t.Errorf("v2 list should not carry %s at the top level, got %v", key, v2)
+		}
+	}
+	if data, _ := v2["data"].([]any); len(data) != 2 {
+		t.Errorf("expected 2 records in v2, got %v", v2["data"])
+	}
+}
+
+func TestVersionedError(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+
+	w, body := doVersionedAction(t, handler.List, apiversion.V2, http.MethodGet, "/notes:list?sort=nope", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %s", w.Code, w.Body.String())
+	}
+	detail, _ := body["error"].(map[string]any)
+	if detail["code"] != "invalid_sort" || detail["status"] != float64(http.StatusBadRequest) || detail["message"] == "" {
+		t.Errorf("expected a v2 error object, got %v", body)
+	}
+	if _, ok := body["code"]; ok {
+		t.Errorf("v2 error should not carry a top-level code, got %v", body)
+	}
+}
+
+func TestVersionedBatch(t *testing.T) {
+	_, handler := setupListDefaultsTest(t)
+	items := map[string]any{"data": []map[string]any{
+		{"title": "ok", "rank": 4},
+		{"rank": 5},
+	}}
+
+	w, body := doVersionedAction(t, handler.Create, apiversion.V2, http.MethodPost, "/notes:create?atomic=false", items)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d %s", w.Code, w.Body.String())
+	}
+	results, _ := body["results"].([]any)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %v", body)
+	}
+	failed, _ := results[1].(map[string]any)
+	detail, _ := failed["error"].(map[string]any)
+	if failed["status"] != string(BatchItemFailed) || detail["code"] == nil || detail["message"] == nil {
+		t.Errorf("expected the failed item to carry an error object, got %v", failed)
+	}
+	if _, ok := failed["error_code"]; ok {
+		t.Errorf("v2 batch item should not carry error_code, got %v", failed)
+	}
+	if created, _ := results[0].(map[string]any); created["error"] != nil {
+		t.Errorf("created item should carry no error, got %v", created)
+	}
+
+	_, v1 := doVersionedAction(t, handler.Create, apiversion.V1, http.MethodPost, "/notes:create?atomic=false", items)
+	results, _ = v1["results"].([]any)
+	if failed, _ := results[1].(map[string]any); failed["error_code"] == nil {
+		t.Errorf("expected error_code on the v1 failed item, got %v", failed)
+	}
+}
@@ -0,0 +1,224 @@
+// Package idempotency stores the responses of :create requests sent with an
+// Idempotency-Key header in the moon_idempotency table, so that a retried
+// request is answered with the stored response instead of creating its
+// records again. Responses are kept per key and collection for a TTL; expired
+// rows are ignored and purged lazily.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+)
+
+// MaxKeyLength is the longest Idempotency-Key accepted
+const MaxKeyLength = 255
+
+// purgeInterval is the least time between two purges of expired rows
+const purgeInterval = time.Minute
+
+// Response is a stored response and the hash of the request body it answered
+type Response struct {
+	Hash   string
+	Status int
+	Body   []byte
+}
+
+// ExecFunc runs a statement, on the database or within a transaction
+type ExecFunc func(ctx context.Context, query string, args ...any) (sql.Result, error)
+
+// Store keeps responses in moon_idempotency
+type Store struct {
+	db  database.Driver
+	ttl time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*keyLock
+
+	purgedAt atomic.Int64 // unix nanoseconds of the last purge
+}
+
+// keyLock serializes the requests of one key; waiters counts the holder and
+// those waiting, so that the lock is dropped with the last of them
+type keyLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// New creates a store keeping responses for ttl
+func New(db database.Driver, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl, locks: make(map[string]*keyLock)}
+}
+
+// ValidKey reports whether key can be used as an Idempotency-Key: 1 to
+// MaxKeyLength printable ASCII characters
+func ValidKey(key string) bool {
+	if key == "" || len(key) > MaxKeyLength {
+		return false
+	}
+	for i := range len(key) {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash returns the hash a request body is stored and compared with
+func Hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Init creates the moon_idempotency table and its index if they do not exist
+func (s *Store) Init(ctx context.Context) error {
+	for _, statement := range createTableSQL(s.db.Dialect()) {
+		if _, err := s.db.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", constants.TableIdempotency, err)
+		}
+	}
+	return nil
+}
+
+// Lock waits until no other request of this process holds the key of the
+// collection, and returns the function releasing it. A request looks up and
+// stores its response while holding the lock, so that concurrent requests
+// with one key create the records once.
+func (s *Store) Lock(key, collection string) (unlock func()) {
+	id := collection + "\x00" + key
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &keyLock{}
+		s.locks[id] = l
+	}
+	l.waiters++
+	s.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.mu.Lock()
+		if l.waiters--; l.waiters == 0 {
+			delete(s.locks, id)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Lookup returns the unexpired response stored for the key in the
+// collection, or nil when there is none
+func (s *Store) Lookup(ctx context.Context, key, collection string) (*Response, error) {
+	dialect := s.db.Dialect()
+	statement := fmt.Sprintf("SELECT request_hash, status, body FROM %s WHERE idem_key = %s AND collection = %s AND expires_at > %s",
+		constants.TableIdempotency, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3))
+	rows, err := s.db.Query(ctx, statement, key, collection, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableIdempotency, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	var resp Response
+	if err := rows.Scan(&resp.Hash, &resp.Status, &resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to scan %s row: %w", constants.TableIdempotency, err)
+	}
+	return &resp, nil
+}
+
+// Save stores the response for the key in the collection with exec, which
+// may run within the transaction that created the records. An expired
+// response of the key is replaced; an unexpired one makes Save fail.
+func (s *Store) Save(ctx context.Context, exec ExecFunc, key, collection string, resp Response) error {
+	dialect := s.db.Dialect()
+	now := time.Now().UTC()
+	remove := fmt.Sprintf("DELETE FROM %s WHERE idem_key = %s AND collection = %s AND expires_at <= %s",
+		constants.TableIdempotency, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3))
+	if _, err := exec(ctx, remove, key, collection, now); err != nil {
+		return fmt.Errorf("failed to replace expired response: %w", err)
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (idem_key, collection, request_hash, status, body, created_at, expires_at) VALUES (%s, %s, %s, %s, %s, %s, %s)",
+		constants.TableIdempotency, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3),
+		query.Placeholder(dialect, 4), query.Placeholder(dialect, 5), query.Placeholder(dialect, 6), query.Placeholder(dialect, 7))
+	if _, err := exec(ctx, insert, key, collection, resp.Hash, resp.Status, resp.Body, now, now.Add(s.ttl)); err != nil {
+		return fmt.Errorf("failed to store response: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes the expired responses, at most once per minute, and
+// returns how many were deleted
+func (s *Store) PurgeExpired(ctx context.Context) (int64, error) {
+	last := s.purgedAt.Load()
+	now := time.Now()
+	if now.UnixNano()-last < int64(purgeInterval) || !s.purgedAt.CompareAndSwap(last, now.UnixNano()) {
+		return 0, nil
+	}
+	statement := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= %s", constants.TableIdempotency, query.Placeholder(s.db.Dialect(), 1))
+	result, err := s.db.Exec(ctx, statement, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge %s: %w", constants.TableIdempotency, err)
+	}
+	return result.RowsAffected()
+}
+
+// createTableSQL returns the moon_idempotency DDL for the given dialect. The
+// index serves the purge of expired rows.
+func createTableSQL(dialect database.DialectType) []string {
+	switch dialect {
+	case database.DialectPostgres:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableIdempotency + ` (
+			idem_key VARCHAR(255) NOT NULL,
+			collection VARCHAR(255) NOT NULL,
+			request_hash VARCHAR(64) NOT NULL,
+			status INTEGER NOT NULL,
+			body BYTEA NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (idem_key, collection)
+		)`,
+			`CREATE INDEX IF NOT EXISTS idx_moon_idempotency_expires_at ON ` + constants.TableIdempotency + `(expires_at)`,
+		}
+	case database.DialectMySQL:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableIdempotency + ` (
+			idem_key VARCHAR(255) NOT NULL,
+			collection VARCHAR(255) NOT NULL,
+			request_hash VARCHAR(64) NOT NULL,
+			status INT NOT NULL,
+			body LONGBLOB NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			PRIMARY KEY (idem_key, collection),
+			INDEX idx_moon_idempotency_expires_at (expires_at)
+		)`,
+		}
+	default:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableIdempotency + ` (
+			idem_key TEXT NOT NULL,
+			collection TEXT NOT NULL,
+			request_hash TEXT NOT NULL,
+			status INTEGER NOT NULL,
+			body BLOB NOT NULL,
+			created_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL,
+			PRIMARY KEY (idem_key, collection)
+		)`,
+			`CREATE INDEX IF NOT EXISTS idx_moon_idempotency_expires_at ON ` + constants.TableIdempotency + `(expires_at)`,
+		}
+	}
+}
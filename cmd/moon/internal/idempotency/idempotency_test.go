@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// setupStore returns a store on a fresh in-memory database
+func setupStore(t *testing.T, ttl time.Duration) *Store {
+	t.Helper()
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://:memory:",
+		MaxOpenConns:     1,
+		MaxIdleConns:     1,
+		ConnMaxLifetime:  time.Minute * 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	s := New(driver, ttl)
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return s
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"order-42", true},
+		{"a b/c:d", true},
+		{strings.Repeat("k", MaxKeyLength), true},
+		{"", false},
+		{strings.Repeat("k", MaxKeyLength+1), false},
+		{"tab\tkey", false},
+		{"clé", false},
+	}
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestStore_SaveAndLookup(t *testing.T) {
+	s := setupStore(t, time.Hour)
+	ctx := context.Background()
+
+	resp := Response{Hash: Hash([]byte(`{"data":{}}`)), Status: 201, Body: []byte("{\"data\":{}}\n")}
+	if err := s.Save(ctx, s.db.Exec, "k", "orders", resp); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	got, err := s.Lookup(ctx, "k", "orders")
+	if err != nil || got == nil {
+		t.Fatalf("Lookup failed: %v %v", got, err)
+	}
+	if got.Hash != resp.Hash || got.Status != resp.Status || string(got.Body) != string(resp.Body) {
+		t.Errorf("expected %+v, got %+v", resp, got)
+	}
+
+	// Keys are scoped to their collection
+	if got, _ := s.Lookup(ctx, "k", "products"); got != nil {
+		t.Errorf("expected no response in another collection, got %+v", got)
+	}
+	if err := s.Save(ctx, s.db.Exec, "k", "orders", resp); err == nil {
+		t.Error("expected saving an unexpired key again to fail")
+	}
+}
+
+func TestStore_Expiry(t *testing.T) {
+	s := setupStore(t, 10*time.Millisecond)
+	ctx := context.Background()
+
+	resp := Response{Hash: "h", Status: 201, Body: []byte("{}")}
+	if err := s.Save(ctx, s.db.Exec, "old", "orders", resp); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got, _ := s.Lookup(ctx, "old", "orders"); got != nil {
+		t.Errorf("expected an expired response ignored, got %+v", got)
+	}
+
+	// An expired key can be stored again, and the purge removes expired rows
+	if err := s.Save(ctx, s.db.Exec, "old", "orders", resp); err != nil {
+		t.Fatalf("expected an expired key replaced, got %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n, err := s.PurgeExpired(ctx); err != nil || n != 1 {
+		t.Errorf("expected 1 row purged, got %d %v", n, err)
+	}
+	if n, _ := s.PurgeExpired(ctx); n != 0 {
+		t.Errorf("expected the next purge throttled, got %d", n)
+	}
+}
@@ -0,0 +1,327 @@
+// Package jobs journals long-running writes, :import and batch :create
+// requests sent with journal=true, in the moon_jobs table. A job records the
+// collection, the number of items and the checksum of the payload before the
+// first item is written, then how many items were processed as they are, so
+// that an import cut short by an error or a crash can be resumed without
+// writing any item twice.
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// Operations journaled as jobs
+const (
+	OperationImport = "import"
+	OperationCreate = "create"
+)
+
+// Job statuses. A job is interrupted when the server stopped while it was
+// running; failed and interrupted imports can be resumed.
+const (
+	StatusRunning     = "running"
+	StatusCompleted   = "completed"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+)
+
+var (
+	// ErrNotFound is returned for a job id that is not journaled
+	ErrNotFound = errors.New("job not found")
+
+	// ErrNotResumable is returned when resuming a job that is running or completed
+	ErrNotResumable = errors.New("job is not failed or interrupted")
+)
+
+// Job is one journaled request. Items are numbered as the request reports
+// them: the 1-based rows of an import and the 0-based indexes of a batch.
+type Job struct {
+	ID         string    `json:"id"`
+	Operation  string    `json:"operation"`
+	Collection string    `json:"collection"`
+	Status     string    `json:"status"`
+	Total      int       `json:"total"`
+	Processed  int       `json:"processed"`       // items handled in order, written or skipped
+	LastIndex  *int      `json:"last_index"`      // the last item written, null before the first
+	Succeeded  int       `json:"succeeded"`       // items written
+	Failed     int       `json:"failed"`          // items skipped or failed
+	Checksum   string    `json:"checksum"`        // SHA-256 of the payload, hex encoded
+	Error      string    `json:"error,omitempty"` // why a failed job stopped
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Filter selects the jobs returned by List
+type Filter struct {
+	Collection string // jobs of one collection; empty for all
+	Status     string // jobs in one status; empty for all
+	After      string // id of the last job of the previous page
+	Limit      int
+}
+
+// ExecFunc runs a statement, on the database or within a transaction
+type ExecFunc func(ctx context.Context, query string, args ...any) (sql.Result, error)
+
+// Store keeps jobs in moon_jobs
+type Store struct {
+	db database.Driver
+}
+
+// New creates a job store backed by the given database
+func New(db database.Driver) *Store {
+	return &Store{db: db}
+}
+
+// Checksum returns the checksum a payload is journaled and compared with
+func Checksum(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Init creates the moon_jobs table and its index if they do not exist
+func (s *Store) Init(ctx context.Context) error {
+	for _, statement := range createTableSQL(s.db.Dialect()) {
+		if _, err := s.db.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", constants.TableJobs, err)
+		}
+	}
+	return nil
+}
+
+// Interrupt marks the jobs left running by a previous process as
+// interrupted and returns how many there were. It is called at startup,
+// before any request can start a job.
+func (s *Store) Interrupt(ctx context.Context) (int64, error) {
+	dialect := s.db.Dialect()
+	statement := fmt.Sprintf("UPDATE %s SET status = %s, updated_at = %s WHERE status = %s",
+		constants.TableJobs, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3))
+	result, err := s.db.Exec(ctx, statement, StatusInterrupted, time.Now().UTC(), StatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update %s: %w", constants.TableJobs, err)
+	}
+	return result.RowsAffected()
+}
+
+// Prune deletes the jobs started more than retentionDays days ago and
+// returns how many were deleted. Ids sort by creation time, so the cutoff is
+// compared as the id of a job started at that moment.
+func (s *Store) Prune(ctx context.Context, retentionDays int) (int64, error) {
+	cutoff := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour)
+	result, err := s.db.Exec(ctx, "DELETE FROM "+constants.TableJobs+" WHERE id < "+query.Placeholder(s.db.Dialect(), 1), ulid.GenerateWithTime(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune %s: %w", constants.TableJobs, err)
+	}
+	return result.RowsAffected()
+}
+
+// Start journals a new running job; its id and timestamps are assigned
+func (s *Store) Start(ctx context.Context, job *Job) error {
+	now := time.Now().UTC()
+	job.ID = ulid.Generate()
+	job.Status = StatusRunning
+	job.CreatedAt, job.UpdatedAt = now, now
+
+	dialect := s.db.Dialect()
+	values := make([]string, 13)
+	for i := range values {
+		values[i] = query.Placeholder(dialect, i+1)
+	}
+	_, err := s.db.Exec(ctx, "INSERT INTO "+constants.TableJobs+
+		" (id, operation, collection, status, total, processed, last_index, succeeded, failed, checksum, error, created_at, updated_at) VALUES ("+strings.Join(values, ", ")+")",
+		job.ID, job.Operation, job.Collection, job.Status, job.Total, job.Processed, job.LastIndex, job.Succeeded, job.Failed, job.Checksum, job.Error, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", constants.TableJobs, err)
+	}
+	return nil
+}
+
+// Get returns the job with the given id, or ErrNotFound
+func (s *Store) Get(ctx context.Context, id string) (*Job, error) {
+	jobs, err := s.query(ctx, " WHERE id = "+query.Placeholder(s.db.Dialect(), 1), id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &jobs[0], nil
+}
+
+// List returns up to filter.Limit jobs ordered by id, oldest first
+func (s *Store) List(ctx context.Context, filter Filter) ([]Job, error) {
+	dialect := s.db.Dialect()
+	var where []string
+	var args []any
+	if filter.Collection != "" {
+		args = append(args, filter.Collection)
+		where = append(where, "collection = "+query.Placeholder(dialect, len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where = append(where, "status = "+query.Placeholder(dialect, len(args)))
+	}
+	if filter.After != "" {
+		args = append(args, filter.After)
+		where = append(where, "id > "+query.Placeholder(dialect, len(args)))
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+	return s.query(ctx, clause+fmt.Sprintf(" ORDER BY id LIMIT %d", filter.Limit), args...)
+}
+
+// Resume marks a failed or interrupted job running again, or returns
+// ErrNotResumable. Only one of several concurrent resumes of a job succeeds.
+func (s *Store) Resume(ctx context.Context, job *Job) error {
+	dialect := s.db.Dialect()
+	statement := fmt.Sprintf("UPDATE %s SET status = %s, error = '', updated_at = %s WHERE id = %s AND status IN (%s, %s)",
+		constants.TableJobs, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3), query.Placeholder(dialect, 4), query.Placeholder(dialect, 5))
+	now := time.Now().UTC()
+	result, err := s.db.Exec(ctx, statement, StatusRunning, now, job.ID, StatusFailed, StatusInterrupted)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", constants.TableJobs, err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotResumable
+	}
+	job.Status, job.Error, job.UpdatedAt = StatusRunning, "", now
+	return nil
+}
+
+// Progress writes the counts of a running job with exec, which may run
+// within the transaction that wrote its items, or on the database when nil
+func (s *Store) Progress(ctx context.Context, exec ExecFunc, job *Job) error {
+	if exec == nil {
+		exec = s.db.Exec
+	}
+	job.UpdatedAt = time.Now().UTC()
+	return s.update(ctx, exec, job)
+}
+
+// Finish writes the counts of a job and ends it with the given status and,
+// for a failed job, the error that stopped it
+func (s *Store) Finish(ctx context.Context, job *Job, status string, cause error) error {
+	job.Status, job.Error, job.UpdatedAt = status, "", time.Now().UTC()
+	if cause != nil {
+		job.Error = cause.Error()
+	}
+	return s.update(ctx, s.db.Exec, job)
+}
+
+// update writes the mutable columns of a job
+func (s *Store) update(ctx context.Context, exec ExecFunc, job *Job) error {
+	dialect := s.db.Dialect()
+	statement := fmt.Sprintf("UPDATE %s SET status = %s, processed = %s, last_index = %s, succeeded = %s, failed = %s, error = %s, updated_at = %s WHERE id = %s",
+		constants.TableJobs, query.Placeholder(dialect, 1), query.Placeholder(dialect, 2), query.Placeholder(dialect, 3), query.Placeholder(dialect, 4),
+		query.Placeholder(dialect, 5), query.Placeholder(dialect, 6), query.Placeholder(dialect, 7), query.Placeholder(dialect, 8))
+	if _, err := exec(ctx, statement, job.Status, job.Processed, job.LastIndex, job.Succeeded, job.Failed, job.Error, job.UpdatedAt, job.ID); err != nil {
+		return fmt.Errorf("failed to update %s: %w", constants.TableJobs, err)
+	}
+	return nil
+}
+
+// query returns the jobs selected by the clause
+func (s *Store) query(ctx context.Context, clause string, args ...any) ([]Job, error) {
+	rows, err := s.db.Query(ctx, "SELECT id, operation, collection, status, total, processed, last_index, succeeded, failed, checksum, error, created_at, updated_at FROM "+constants.TableJobs+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableJobs, err)
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		var job Job
+		var lastIndex sql.NullInt64
+		if err := rows.Scan(&job.ID, &job.Operation, &job.Collection, &job.Status, &job.Total, &job.Processed, &lastIndex,
+			&job.Succeeded, &job.Failed, &job.Checksum, &job.Error, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", constants.TableJobs, err)
+		}
+		if lastIndex.Valid {
+			index := int(lastIndex.Int64)
+			job.LastIndex = &index
+		}
+		job.CreatedAt, job.UpdatedAt = job.CreatedAt.UTC(), job.UpdatedAt.UTC()
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableJobs, err)
+	}
+	return jobs, nil
+}
+
+// createTableSQL returns the moon_jobs DDL for the given dialect. The index
+// serves the collection filter of List; the primary key serves its order.
+func createTableSQL(dialect database.DialectType) []string {
+	switch dialect {
+	case database.DialectPostgres:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableJobs + ` (
+			id VARCHAR(26) PRIMARY KEY,
+			operation VARCHAR(20) NOT NULL,
+			collection VARCHAR(255) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			total INTEGER NOT NULL,
+			processed INTEGER NOT NULL,
+			last_index INTEGER,
+			succeeded INTEGER NOT NULL,
+			failed INTEGER NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			error TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`,
+			`CREATE INDEX IF NOT EXISTS idx_moon_jobs_collection ON ` + constants.TableJobs + `(collection, id)`,
+		}
+	case database.DialectMySQL:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableJobs + ` (
+			id VARCHAR(26) PRIMARY KEY,
+			operation VARCHAR(20) NOT NULL,
+			collection VARCHAR(255) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			total INT NOT NULL,
+			processed INT NOT NULL,
+			last_index INT,
+			succeeded INT NOT NULL,
+			failed INT NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			error TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			INDEX idx_moon_jobs_collection (collection, id)
+		)`,
+		}
+	default:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS ` + constants.TableJobs + ` (
+			id TEXT PRIMARY KEY,
+			operation TEXT NOT NULL,
+			collection TEXT NOT NULL,
+			status TEXT NOT NULL,
+			total INTEGER NOT NULL,
+			processed INTEGER NOT NULL,
+			last_index INTEGER,
+			succeeded INTEGER NOT NULL,
+			failed INTEGER NOT NULL,
+			checksum TEXT NOT NULL,
+			error TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`,
+			`CREATE INDEX IF NOT EXISTS idx_moon_jobs_collection ON ` + constants.TableJobs + `(collection, id)`,
+		}
+	}
+}
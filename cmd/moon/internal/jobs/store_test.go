@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+func setupStore(t *testing.T) *Store {
+	t.Helper()
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://:memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	s := New(driver)
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	return s
+}
+
+func TestStore_Lifecycle(t *testing.T) {
+	s := setupStore(t)
+	ctx := context.Background()
+
+	job := &Job{Operation: OperationImport, Collection: "notes", Total: 4, Checksum: Checksum([]byte("title\nA\n"))}
+	if err := s.Start(ctx, job); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	last := 2
+	job.Processed, job.Succeeded, job.LastIndex = 2, 2, &last
+	if err := s.Progress(ctx, nil, job); err != nil {
+		t.Fatalf("Progress failed: %v", err)
+	}
+	if err := s.Resume(ctx, job); err != ErrNotResumable {
+		t.Errorf("expected a running job not to be resumable, got %v", err)
+	}
+
+	if err := s.Finish(ctx, job, StatusFailed, context.Canceled); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	got, err := s.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.Status != StatusFailed || got.Processed != 2 || got.LastIndex == nil || *got.LastIndex != 2 || got.Error != "context canceled" || got.Checksum != job.Checksum {
+		t.Fatalf("unexpected job %+v", got)
+	}
+
+	if err := s.Resume(ctx, got); err != nil || got.Status != StatusRunning || got.Error != "" {
+		t.Fatalf("expected the failed job to resume, got %v %+v", err, got)
+	}
+	if err := s.Resume(ctx, got); err != ErrNotResumable {
+		t.Errorf("expected a second resume to fail, got %v", err)
+	}
+	if _, err := s.Get(ctx, ulid.Generate()); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	s := setupStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	for _, age := range []time.Duration{10 * 24 * time.Hour, 8 * 24 * time.Hour, 6 * 24 * time.Hour, 0} {
+		job := &Job{Operation: OperationCreate, Collection: "notes", Total: 1}
+		if err := s.Start(ctx, job); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		// Ids carry the start time
+		if _, err := s.db.Exec(ctx, "UPDATE moon_jobs SET id = ? WHERE id = ?", ulid.GenerateWithTime(now.Add(-age)), job.ID); err != nil {
+			t.Fatalf("failed to age job: %v", err)
+		}
+	}
+
+	pruned, err := s.Prune(ctx, 7)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("expected 2 jobs pruned, got %d", pruned)
+	}
+	if jobs, _ := s.List(ctx, Filter{Limit: 10}); len(jobs) != 2 {
+		t.Errorf("expected 2 jobs kept, got %d", len(jobs))
+	}
+}
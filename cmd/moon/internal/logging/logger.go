@@ -1,6 +1,7 @@
 // Package logging provides structured logging with zerolog.
-// It supports simple text and console formats, log levels, file output,
-// request ID tracking, and automatic masking of sensitive fields.
+// It supports simple text and console formats, log levels with per-module
+// overrides that can change at runtime, file output, request ID tracking,
+// and automatic masking of sensitive fields.
 package logging
 
 import (
@@ -12,14 +13,20 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
 )
 
-// simpleWriter is a custom writer that formats logs as: [LEVEL](TIMESTAMP): {MESSAGE}
+// moduleField is the field naming the module a log line comes from
+const moduleField = "module"
+
+// simpleWriter is a custom writer that formats logs as
+// [LEVEL](TIMESTAMP) module request_id=ID: {MESSAGE}, where the module and
+// request ID are only present when the log line carries them
 type simpleWriter struct {
 	out io.Writer
 }
@@ -35,15 +42,61 @@ func (sw *simpleWriter) Write(p []byte) (n int, err error) {
 	level, _ := logEntry["level"].(string)
 	timestamp, _ := logEntry["time"].(string)
 	message, _ := logEntry["message"].(string)
+	module, _ := logEntry[moduleField].(string)
+	requestID, _ := logEntry[constants.ContextKeyRequestID].(string)
+
+	// Format as [LEVEL](TIMESTAMP) module request_id=ID: {MESSAGE}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s](%s)", strings.ToUpper(level), timestamp)
+	if module != "" {
+		b.WriteString(" " + module)
+	}
+	if requestID != "" {
+		b.WriteString(" " + constants.ContextKeyRequestID + "=" + requestID)
+	}
+	fmt.Fprintf(&b, ": %s\n", message)
+
+	return sw.out.Write([]byte(b.String()))
+}
+
+// newConsoleWriter returns a colorized console writer that shows the module
+// and request ID after the level. Service and version are left out as they
+// are the same on every line.
+func newConsoleWriter(out io.Writer) zerolog.ConsoleWriter {
+	return zerolog.ConsoleWriter{
+		Out:        out,
+		TimeFormat: time.RFC3339,
+		PartsOrder: []string{
+			zerolog.TimestampFieldName,
+			zerolog.LevelFieldName,
+			moduleField,
+			constants.ContextKeyRequestID,
+			zerolog.MessageFieldName,
+		},
+		FieldsExclude: []string{moduleField, constants.ContextKeyRequestID, "service", "version"},
+		FormatPartValueByName: func(value any, name string) string {
+			text, _ := value.(string)
+			switch {
+			case text == "":
+				return ""
+			case name == moduleField:
+				return colorize("["+text+"]", colorCyan)
+			default:
+				return colorize(text, colorDarkGray)
+			}
+		},
+	}
+}
 
-	// Format as [LEVEL](TIMESTAMP): {MESSAGE}
-	formatted := fmt.Sprintf("[%s](%s): %s\n",
-		strings.ToUpper(level),
-		timestamp,
-		message,
-	)
+// ANSI colors of the console parts added by newConsoleWriter
+const (
+	colorCyan     = 36
+	colorDarkGray = 90
+)
 
-	return sw.out.Write([]byte(formatted))
+// colorize wraps text in an ANSI color sequence
+func colorize(text string, color int) string {
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, text)
 }
 
 // dualWriter writes JSON logs to two outputs with different formatting:
@@ -114,6 +167,56 @@ type LoggerConfig struct {
 
 	// SensitiveFields are field names that should be masked in logs
 	SensitiveFields []string
+
+	// ModuleLevels overrides Level for the loggers of the named modules
+	ModuleLevels map[string]Level
+}
+
+// ParseLevel returns the level named by s
+func ParseLevel(s string) (Level, error) {
+	switch level := Level(strings.ToLower(s)); level {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+		return level, nil
+	}
+	return "", fmt.Errorf("invalid log level %q: must be debug, info, warn or error", s)
+}
+
+// zerologLevel maps a level to zerolog, defaulting to info
+func zerologLevel(level Level) zerolog.Level {
+	switch level {
+	case LevelDebug:
+		return zerolog.DebugLevel
+	case LevelWarn:
+		return zerolog.WarnLevel
+	case LevelError:
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
+
+// levelSet holds the base level and the per-module overrides of a logger.
+// Loggers derived with Module, WithContext or WithField share it, so a
+// change applies to all of them immediately.
+type levelSet struct {
+	mu      sync.RWMutex
+	base    Level
+	modules map[string]Level
+}
+
+// level returns the level in effect for a module
+func (ls *levelSet) level(module string) Level {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	if level, ok := ls.modules[module]; ok && module != "" {
+		return level
+	}
+	return ls.base
+}
+
+// enabled reports whether a module writes lines of the given level
+func (ls *levelSet) enabled(module string, level Level) bool {
+	return zerologLevel(level) >= zerologLevel(ls.level(module))
 }
 
 // Logger wraps zerolog for structured logging
@@ -121,6 +224,8 @@ type Logger struct {
 	logger          zerolog.Logger
 	config          LoggerConfig
 	sensitiveFields map[string]bool
+	levels          *levelSet
+	module          string
 }
 
 // NewLogger creates a new structured logger
@@ -146,10 +251,9 @@ func NewLogger(config LoggerConfig) *Logger {
 				output = os.Stdout
 			} else {
 				// Create dual writer: stdout gets console format, file gets simple format
-				consoleOut := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
 				fileOut := &simpleWriter{out: file}
 				output = &dualWriter{
-					consoleWriter: consoleOut,
+					consoleWriter: newConsoleWriter(os.Stdout),
 					fileWriter:    fileOut,
 				}
 			}
@@ -183,20 +287,8 @@ func NewLogger(config LoggerConfig) *Logger {
 		config.SlowQueryThreshold = constants.SlowQueryThreshold
 	}
 
-	// Set zerolog level
-	var zeroLevel zerolog.Level
-	switch config.Level {
-	case LevelDebug:
-		zeroLevel = zerolog.DebugLevel
-	case LevelInfo:
-		zeroLevel = zerolog.InfoLevel
-	case LevelWarn:
-		zeroLevel = zerolog.WarnLevel
-	case LevelError:
-		zeroLevel = zerolog.ErrorLevel
-	default:
-		zeroLevel = zerolog.InfoLevel
-	}
+	// Levels are filtered per module by event; zerolog passes everything
+	zeroLevel := zerolog.DebugLevel
 
 	var logger zerolog.Logger
 
@@ -209,8 +301,7 @@ func NewLogger(config LoggerConfig) *Logger {
 		logger = zerolog.New(output).Level(zeroLevel).With().Timestamp().Logger()
 	} else if config.Format == "console" {
 		// Console format (colorized)
-		consoleOut := zerolog.ConsoleWriter{Out: output, TimeFormat: time.RFC3339}
-		logger = zerolog.New(consoleOut).Level(zeroLevel).With().Timestamp().Logger()
+		logger = zerolog.New(newConsoleWriter(output)).Level(zeroLevel).With().Timestamp().Logger()
 	} else {
 		// Default to simple text format: [LEVEL](TIMESTAMP): {MESSAGE}
 		simpleOut := &simpleWriter{out: output}
@@ -236,20 +327,84 @@ func NewLogger(config LoggerConfig) *Logger {
 		sensitiveFields[field] = true
 	}
 
+	levels := &levelSet{base: config.Level, modules: make(map[string]Level, len(config.ModuleLevels))}
+	for module, level := range config.ModuleLevels {
+		levels.modules[module] = level
+	}
+
 	return &Logger{
 		logger:          logger,
 		config:          config,
 		sensitiveFields: sensitiveFields,
+		levels:          levels,
+	}
+}
+
+// Module returns a logger for the named module. Its lines carry a module
+// field and are filtered by the module's level when one is set.
+func (l *Logger) Module(name string) *Logger {
+	newLogger := *l
+	newLogger.module = name
+	newLogger.logger = l.logger.With().Str(moduleField, name).Logger()
+	return &newLogger
+}
+
+// Enabled reports whether the logger writes lines of the given level
+func (l *Logger) Enabled(level Level) bool {
+	return l.levels.enabled(l.module, level)
+}
+
+// SetLevel changes the base level of the logger and the loggers derived from it
+func (l *Logger) SetLevel(level Level) {
+	l.levels.mu.Lock()
+	defer l.levels.mu.Unlock()
+	l.levels.base = level
+}
+
+// SetModuleLevel overrides the base level for a module
+func (l *Logger) SetModuleLevel(module string, level Level) {
+	l.levels.mu.Lock()
+	defer l.levels.mu.Unlock()
+	l.levels.modules[module] = level
+}
+
+// ClearModuleLevel removes the override of a module, which then logs at the base level
+func (l *Logger) ClearModuleLevel(module string) {
+	l.levels.mu.Lock()
+	defer l.levels.mu.Unlock()
+	delete(l.levels.modules, module)
+}
+
+// Levels returns the base level and a copy of the module overrides
+func (l *Logger) Levels() (Level, map[string]Level) {
+	l.levels.mu.RLock()
+	defer l.levels.mu.RUnlock()
+	modules := make(map[string]Level, len(l.levels.modules))
+	for module, level := range l.levels.modules {
+		modules[module] = level
 	}
+	return l.levels.base, modules
+}
+
+// event starts a log line, or returns nil when the level is filtered out;
+// zerolog ignores every call on a nil event
+func (l *Logger) event(level Level) *zerolog.Event {
+	if !l.Enabled(level) {
+		return nil
+	}
+	return l.logger.WithLevel(zerologLevel(level))
 }
 
 // WithContext returns a logger with context fields
 func (l *Logger) WithContext(ctx context.Context) *Logger {
 	newLogger := *l
 
-	// Add request ID if present
+	// Add request ID and client IP if present
 	if requestID := GetRequestID(ctx); requestID != "" {
-		newLogger.logger = l.logger.With().Str(constants.ContextKeyRequestID, requestID).Logger()
+		newLogger.logger = newLogger.logger.With().Str(constants.ContextKeyRequestID, requestID).Logger()
+	}
+	if clientIP := GetClientIP(ctx); clientIP != "" {
+		newLogger.logger = newLogger.logger.With().Str(constants.ContextKeyClientIP, clientIP).Logger()
 	}
 
 	return &newLogger
@@ -283,53 +438,53 @@ func (l *Logger) maskSensitive(key string, value any) any {
 
 // Debug logs a debug message
 func (l *Logger) Debug(msg string) {
-	l.logger.Debug().Msg(msg)
+	l.event(LevelDebug).Msg(msg)
 }
 
 // Debugf logs a formatted debug message
 func (l *Logger) Debugf(format string, args ...any) {
-	l.logger.Debug().Msgf(format, args...)
+	l.event(LevelDebug).Msgf(format, args...)
 }
 
 // Info logs an info message
 func (l *Logger) Info(msg string) {
-	l.logger.Info().Msg(msg)
+	l.event(LevelInfo).Msg(msg)
 }
 
 // Infof logs a formatted info message
 func (l *Logger) Infof(format string, args ...any) {
-	l.logger.Info().Msgf(format, args...)
+	l.event(LevelInfo).Msgf(format, args...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(msg string) {
-	l.logger.Warn().Msg(msg)
+	l.event(LevelWarn).Msg(msg)
 }
 
 // Warnf logs a formatted warning message
 func (l *Logger) Warnf(format string, args ...any) {
-	l.logger.Warn().Msgf(format, args...)
+	l.event(LevelWarn).Msgf(format, args...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(msg string) {
-	l.logger.Error().Msg(msg)
+	l.event(LevelError).Msg(msg)
 }
 
 // Errorf logs a formatted error message
 func (l *Logger) Errorf(format string, args ...any) {
-	l.logger.Error().Msgf(format, args...)
+	l.event(LevelError).Msgf(format, args...)
 }
 
 // ErrorWithErr logs an error with the error object
 func (l *Logger) ErrorWithErr(msg string, err error) {
-	l.logger.Error().Err(err).Msg(msg)
+	l.event(LevelError).Err(err).Msg(msg)
 }
 
 // LogSlowQuery logs a slow query warning
 func (l *Logger) LogSlowQuery(query string, duration time.Duration, args ...any) {
 	if duration >= l.config.SlowQueryThreshold {
-		l.logger.Warn().
+		l.event(LevelWarn).
 			Str("query", query).
 			Dur("duration", duration).
 			Interface("args", args).
@@ -337,10 +492,13 @@ func (l *Logger) LogSlowQuery(query string, duration time.Duration, args ...any)
 	}
 }
 
-// Context key for request ID
+// Context keys for request ID and client IP
 type contextKey string
 
-const requestIDKey contextKey = constants.ContextKeyRequestID
+const (
+	requestIDKey contextKey = constants.ContextKeyRequestID
+	clientIPKey  contextKey = constants.ContextKeyClientIP
+)
 
 // SetRequestID sets the request ID in the context
 func SetRequestID(ctx context.Context, requestID string) context.Context {
@@ -355,6 +513,19 @@ func GetRequestID(ctx context.Context) string {
 	return ""
 }
 
+// SetClientIP sets the resolved client IP in the context
+func SetClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// GetClientIP gets the client IP from the context
+func GetClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return ""
+}
+
 // RequestLoggerConfig holds configuration for request logging middleware
 type RequestLoggerConfig struct {
 	Logger *Logger
@@ -402,7 +573,7 @@ func (rl *RequestLogger) Middleware(next http.HandlerFunc) http.HandlerFunc {
 		// Generate or get request ID
 		requestID := r.Header.Get(constants.HeaderRequestID)
 		if requestID == "" {
-			requestID = uuid.New().String()
+			requestID = ulid.Generate()
 		}
 
 		// Add request ID to response header
@@ -422,7 +593,7 @@ func (rl *RequestLogger) Middleware(next http.HandlerFunc) http.HandlerFunc {
 		// Log request start (debug level)
 		logger := rl.config.Logger.WithContext(ctx)
 
-		if rl.config.Logger.config.Level == LevelDebug {
+		if rl.config.Logger.Enabled(LevelDebug) {
 			debugFields := map[string]any{
 				"method": r.Method,
 				"path":   r.URL.Path,
@@ -452,11 +623,11 @@ func (rl *RequestLogger) Middleware(next http.HandlerFunc) http.HandlerFunc {
 		duration := time.Since(start)
 
 		// Log request completion
-		event := rl.config.Logger.logger.Info()
+		event := rl.config.Logger.event(LevelInfo)
 		if rw.statusCode >= 500 {
-			event = rl.config.Logger.logger.Error()
+			event = rl.config.Logger.event(LevelError)
 		} else if rw.statusCode >= 400 {
-			event = rl.config.Logger.logger.Warn()
+			event = rl.config.Logger.event(LevelWarn)
 		}
 
 		event.
@@ -490,6 +661,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Global logger instance
 var globalLogger *Logger
 
@@ -498,6 +674,11 @@ func Init(config LoggerConfig) {
 	globalLogger = NewLogger(config)
 }
 
+// WithContext returns the global logger with context fields such as the request ID
+func WithContext(ctx context.Context) *Logger {
+	return GetLogger().WithContext(ctx)
+}
+
 // GetLogger returns the global logger
 func GetLogger() *Logger {
 	if globalLogger == nil {
@@ -510,6 +691,36 @@ func GetLogger() *Logger {
 	return globalLogger
 }
 
+// Module returns the global logger for the named module
+func Module(name string) *Logger {
+	return GetLogger().Module(name)
+}
+
+// Enabled reports whether the global logger writes lines of the given level for a module
+func Enabled(module string, level Level) bool {
+	return GetLogger().levels.enabled(module, level)
+}
+
+// SetLevel changes the base level of the global logger at runtime
+func SetLevel(level Level) {
+	GetLogger().SetLevel(level)
+}
+
+// SetModuleLevel overrides the level of a module on the global logger at runtime
+func SetModuleLevel(module string, level Level) {
+	GetLogger().SetModuleLevel(module, level)
+}
+
+// ClearModuleLevel removes the level override of a module on the global logger
+func ClearModuleLevel(module string) {
+	GetLogger().ClearModuleLevel(module)
+}
+
+// Levels returns the base level of the global logger and its module overrides
+func Levels() (Level, map[string]Level) {
+	return GetLogger().Levels()
+}
+
 // Debug logs a debug message using the global logger
 func Debug(msg string) {
 	GetLogger().Debug(msg)
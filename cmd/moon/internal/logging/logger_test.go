@@ -819,3 +819,100 @@ func TestLogger_ConsoleFormat(t *testing.T) {
 		}
 	})
 }
+
+func TestLogger_ModuleLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(LoggerConfig{
+		Level:        LevelInfo,
+		Format:       "json",
+		Output:       &buf,
+		ModuleLevels: map[string]Level{"database": LevelWarn},
+	})
+	handlers := logger.Module("handlers")
+	db := logger.Module("database")
+
+	lines := func() []map[string]any {
+		t.Helper()
+		var entries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Failed to parse log line %q: %v", line, err)
+			}
+			entries = append(entries, entry)
+		}
+		buf.Reset()
+		return entries
+	}
+
+	handlers.Debug("handlers debug")
+	handlers.Info("handlers info")
+	db.Info("database info")
+	db.Warn("database warn")
+	entries := lines()
+	if len(entries) != 2 || entries[0]["message"] != "handlers info" || entries[1]["message"] != "database warn" {
+		t.Fatalf("Expected handlers info and database warn, got %v", entries)
+	}
+	if entries[0]["module"] != "handlers" || entries[1]["module"] != "database" {
+		t.Errorf("Expected module fields, got %v", entries)
+	}
+
+	// A module override changes only that module, also for loggers derived before
+	logger.SetModuleLevel("handlers", LevelDebug)
+	handlers.WithField("k", "v").Debug("handlers debug")
+	logger.Debug("root debug")
+	logger.Module("webhook").Debug("webhook debug")
+	if entries := lines(); len(entries) != 1 || entries[0]["message"] != "handlers debug" {
+		t.Fatalf("Expected only the handlers debug line, got %v", entries)
+	}
+	if !handlers.Enabled(LevelDebug) || logger.Enabled(LevelDebug) {
+		t.Error("Expected debug enabled for handlers only")
+	}
+
+	// The base level applies to modules without an override
+	logger.SetLevel(LevelError)
+	logger.ClearModuleLevel("handlers")
+	handlers.Warn("handlers warn")
+	db.Warn("database warn")
+	logger.Error("root error")
+	if entries := lines(); len(entries) != 2 || entries[0]["message"] != "database warn" || entries[1]["message"] != "root error" {
+		t.Fatalf("Expected database warn and root error, got %v", entries)
+	}
+
+	base, modules := logger.Levels()
+	if base != LevelError || len(modules) != 1 || modules["database"] != LevelWarn {
+		t.Errorf("Unexpected levels: %s %v", base, modules)
+	}
+}
+
+func TestLogger_ModuleAndRequestIDFormat(t *testing.T) {
+	ctx := SetRequestID(context.Background(), "req-123")
+
+	var simple bytes.Buffer
+	NewLogger(LoggerConfig{Output: &simple}).WithContext(ctx).Module("handlers").Info("simple line")
+	if !strings.Contains(simple.String(), ") handlers request_id=req-123: simple line") {
+		t.Errorf("Expected module and request ID in simple format, got: %s", simple.String())
+	}
+
+	var console bytes.Buffer
+	NewLogger(LoggerConfig{Format: "console", Output: &console, ServiceName: "moon"}).WithContext(ctx).Module("handlers").Info("console line")
+	out := console.String()
+	if !strings.Contains(out, "[handlers]") || !strings.Contains(out, "req-123") || !strings.Contains(out, "console line") {
+		t.Errorf("Expected module and request ID in console format, got: %s", out)
+	}
+	if strings.Contains(out, "service=") || strings.Contains(out, "module=") {
+		t.Errorf("Expected service and module not to repeat as fields, got: %s", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel("DEBUG"); err != nil || level != LevelDebug {
+		t.Errorf("Expected debug, got %q %v", level, err)
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
@@ -0,0 +1,101 @@
+// Package meta keeps instance-wide markers in the moon_meta system table,
+// such as whether a one-time startup task has already run. Each marker is a
+// key with a string value.
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+// KeySampleSeeded marks that the sample collection of bootstrap.sample_collection was seeded
+const KeySampleSeeded = "sample_collection_seeded"
+
+// Store reads and writes markers in the moon_meta table
+type Store struct {
+	db database.Driver
+}
+
+// New creates a marker store backed by the given database
+func New(db database.Driver) *Store {
+	return &Store{db: db}
+}
+
+// Init creates the moon_meta table if it does not exist
+func (s *Store) Init(ctx context.Context) error {
+	if _, err := s.db.Exec(ctx, createTableSQL(s.db.Dialect())); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", constants.TableMeta, err)
+	}
+	return nil
+}
+
+// Get returns the value of a marker and whether it is set
+func (s *Store) Get(ctx context.Context, key string) (string, bool, error) {
+	query := "SELECT value FROM " + constants.TableMeta + " WHERE name = ?"
+	if s.db.Dialect() == database.DialectPostgres {
+		query = "SELECT value FROM " + constants.TableMeta + " WHERE name = $1"
+	}
+
+	var value string
+	err := s.db.QueryRow(ctx, query, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", constants.TableMeta, err)
+	}
+	return value, true, nil
+}
+
+// Set inserts or replaces a marker
+func (s *Store) Set(ctx context.Context, key, value string) error {
+	if _, err := s.db.Exec(ctx, upsertSQL(s.db.Dialect()), key, value, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", constants.TableMeta, err)
+	}
+	return nil
+}
+
+// createTableSQL returns the moon_meta DDL for the given dialect
+func createTableSQL(dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectPostgres:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableMeta + ` (
+			name VARCHAR(255) PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`
+	case database.DialectMySQL:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableMeta + ` (
+			name VARCHAR(255) PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`
+	default:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableMeta + ` (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`
+	}
+}
+
+// upsertSQL returns the statement that inserts or replaces one marker
+func upsertSQL(dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectPostgres:
+		return `INSERT INTO ` + constants.TableMeta + ` (name, value, updated_at) VALUES ($1, $2, $3)
+			ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`
+	case database.DialectMySQL:
+		return `INSERT INTO ` + constants.TableMeta + ` (name, value, updated_at) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE value = VALUES(value), updated_at = VALUES(updated_at)`
+	default:
+		return `INSERT INTO ` + constants.TableMeta + ` (name, value, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT (name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+	}
+}
@@ -5,13 +5,14 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
-	"encoding/json"
 	"log"
 	"net/http"
 	"strings"
 	"sync"
 
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 )
 
 const (
@@ -135,7 +136,7 @@ func (m *APIKeyMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc
 		apiKey := m.extractAPIKey(r)
 		if apiKey == "" {
 			m.logAuthFailure(r, "missing API key")
-			m.writeAuthError(w, http.StatusUnauthorized, "API key is required")
+			m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeMissingAPIKey, "API key is required")
 			return
 		}
 
@@ -144,7 +145,7 @@ func (m *APIKeyMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc
 		keyInfo, ok := m.config.Store.Get(hashedKey)
 		if !ok {
 			m.logAuthFailure(r, "invalid API key")
-			m.writeAuthError(w, http.StatusUnauthorized, "Invalid API key")
+			m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidAPIKey, "Invalid API key")
 			return
 		}
 
@@ -153,7 +154,7 @@ func (m *APIKeyMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc
 		if len(requiredPerms) > 0 {
 			if !m.hasPermissions(keyInfo, path, requiredPerms) {
 				m.logAuthFailure(r, "insufficient permissions")
-				m.writeAuthError(w, http.StatusForbidden, "Insufficient permissions")
+				m.writeAuthError(w, r, http.StatusForbidden, apperrors.CodeInsufficientPermissions, "Insufficient permissions")
 				return
 			}
 		}
@@ -279,22 +280,17 @@ func (m *APIKeyMiddleware) permissionMatchesPath(resource, path string) bool {
 
 // logAuthFailure logs authentication failures for security monitoring
 func (m *APIKeyMiddleware) logAuthFailure(r *http.Request, reason string) {
-	log.Printf("APIKEY_AUTH_FAILURE: %s %s - %s", r.Method, r.URL.Path, reason)
+	log.Printf("APIKEY_AUTH_FAILURE: %s %s ip=%s - %s", r.Method, r.URL.Path, logging.GetClientIP(r.Context()), reason)
 }
 
 // logAPIKeyUsage logs API key usage for audit trail
 func (m *APIKeyMiddleware) logAPIKeyUsage(r *http.Request, keyInfo *APIKeyInfo) {
-	log.Printf("APIKEY_USAGE: %s %s - key_id=%s key_name=%s", r.Method, r.URL.Path, keyInfo.ID, keyInfo.Name)
+	log.Printf("APIKEY_USAGE: %s %s ip=%s - key_id=%s key_name=%s", r.Method, r.URL.Path, logging.GetClientIP(r.Context()), keyInfo.ID, keyInfo.Name)
 }
 
 // writeAuthError writes an authentication error response
-func (m *APIKeyMiddleware) writeAuthError(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]any{
-		"error": message,
-		"code":  statusCode,
-	})
+func (m *APIKeyMiddleware) writeAuthError(w http.ResponseWriter, r *http.Request, statusCode int, code apperrors.ErrorCode, message string) {
+	writeError(w, r, statusCode, code, message)
 }
 
 // GetAPIKeyInfo extracts API key info from request context
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 func TestNewAPIKeyStore(t *testing.T) {
@@ -535,7 +537,7 @@ func TestAPIKeyMiddleware_WriteAuthError(t *testing.T) {
 	middleware := NewAPIKeyMiddleware(APIKeyConfig{Enabled: true})
 
 	w := httptest.NewRecorder()
-	middleware.writeAuthError(w, http.StatusUnauthorized, "Test error")
+	middleware.writeAuthError(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusUnauthorized, apperrors.CodeInvalidAPIKey, "Test error")
 
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
@@ -550,8 +552,12 @@ func TestAPIKeyMiddleware_WriteAuthError(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if response["error"] != "Test error" {
-		t.Errorf("Expected error 'Test error', got '%v'", response["error"])
+	if detail, _ := response["error"].(map[string]any); detail["message"] != "Test error" {
+		t.Errorf("Expected error message 'Test error', got '%v'", response["error"])
+	}
+
+	if response["code"] != string(apperrors.CodeInvalidAPIKey) {
+		t.Errorf("Expected code %s, got %v", apperrors.CodeInvalidAPIKey, response["code"])
 	}
 }
 
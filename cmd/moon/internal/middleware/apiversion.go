@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// APIVersion negotiates the response version of a request from ?api_version
+// or the Accept header and stores it in the request context, where response
+// writers read it. The version is echoed in X-Moon-API-Version. An unsupported
+// version is refused with 406 Not Acceptable listing the supported versions.
+func APIVersion(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Responses differ by Accept, so shared caches must keep them apart
+		w.Header().Add("Vary", "Accept")
+
+		version, err := apiversion.Negotiate(r)
+		if err != nil {
+			w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
+			w.WriteHeader(http.StatusNotAcceptable)
+			body := errorBody(r, http.StatusNotAcceptable, apperrors.CodeUnsupportedAPIVersion, err.Error())
+			apperrors.SetField(body, "supported_versions", apiversion.Supported)
+			json.NewEncoder(w).Encode(body)
+			return
+		}
+
+		w.Header().Set(constants.HeaderAPIVersion, version.String())
+		next(w, r.WithContext(apiversion.WithContext(r.Context(), version)))
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+func TestAPIVersion_Negotiated(t *testing.T) {
+	var got apiversion.Version
+	handler := APIVersion(func(w http.ResponseWriter, r *http.Request) {
+		got = apiversion.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/notes:list", nil)
+	req.Header.Set("Accept", "application/vnd.moon.v2+json")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK || got != apiversion.V2 {
+		t.Fatalf("expected the handler to see v2, got %d v%s", w.Code, got)
+	}
+	if h := w.Header().Get(constants.HeaderAPIVersion); h != "2" {
+		t.Errorf("expected %s: 2, got %q", constants.HeaderAPIVersion, h)
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("expected Vary: Accept, got %q", vary)
+	}
+}
+
+func TestAPIVersion_Unsupported(t *testing.T) {
+	called := false
+	handler := APIVersion(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/notes:list?api_version=7", nil))
+
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406, got %d", w.Code)
+	}
+	var body map[string]any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if body["code"] != "unsupported_api_version" {
+		t.Errorf("expected code unsupported_api_version, got %v", body)
+	}
+	if supported, _ := body["supported_versions"].([]any); len(supported) != 2 {
+		t.Errorf("expected the supported versions, got %v", body)
+	}
+	if called {
+		t.Error("handler called for an unsupported version")
+	}
+}
@@ -5,7 +5,6 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,6 +13,8 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 )
 
 // ContextKey type for context keys
@@ -80,7 +81,7 @@ func (m *JWTMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 		token, err := m.extractToken(r)
 		if err != nil {
 			m.logAuthFailure(r, "missing or invalid authorization header", err)
-			m.writeAuthError(w, http.StatusUnauthorized, "Missing or invalid authorization header")
+			m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeAuthenticationRequired, "Missing or invalid authorization header")
 			return
 		}
 
@@ -89,12 +90,12 @@ func (m *JWTMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 			blacklisted, err := m.config.TokenBlacklist.IsBlacklisted(r.Context(), token)
 			if err != nil {
 				m.logAuthFailure(r, "error checking token blacklist", err)
-				m.writeAuthError(w, http.StatusInternalServerError, "Authentication error")
+				m.writeAuthError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "Authentication error")
 				return
 			}
 			if blacklisted {
 				m.logAuthFailure(r, "token has been revoked", nil)
-				m.writeAuthError(w, http.StatusUnauthorized, "Token has been revoked")
+				m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeTokenRevoked, "Token has been revoked")
 				return
 			}
 		}
@@ -103,7 +104,7 @@ func (m *JWTMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 		claims, err := m.validateToken(token)
 		if err != nil {
 			m.logAuthFailure(r, "invalid token", err)
-			m.writeAuthError(w, http.StatusUnauthorized, "Invalid or expired token")
+			m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidToken, "Invalid or expired token")
 			return
 		}
 
@@ -111,7 +112,7 @@ func (m *JWTMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 		if requiredRoles, ok := m.config.RequiredRoles[path]; ok && len(requiredRoles) > 0 {
 			if !m.hasRequiredRoles(claims.Roles, requiredRoles) {
 				m.logAuthFailure(r, "insufficient permissions", nil)
-				m.writeAuthError(w, http.StatusForbidden, "Insufficient permissions")
+				m.writeAuthError(w, r, http.StatusForbidden, apperrors.CodeInsufficientPermissions, "Insufficient permissions")
 				return
 			}
 		}
@@ -217,20 +218,15 @@ func (m *JWTMiddleware) hasRequiredRoles(userRoles, requiredRoles []string) bool
 // logAuthFailure logs authentication failures for security monitoring
 func (m *JWTMiddleware) logAuthFailure(r *http.Request, reason string, err error) {
 	if err != nil {
-		log.Printf("AUTH_FAILURE: %s %s - %s: %v", r.Method, r.URL.Path, reason, err)
+		log.Printf("AUTH_FAILURE: %s %s ip=%s - %s: %v", r.Method, r.URL.Path, logging.GetClientIP(r.Context()), reason, err)
 	} else {
-		log.Printf("AUTH_FAILURE: %s %s - %s", r.Method, r.URL.Path, reason)
+		log.Printf("AUTH_FAILURE: %s %s ip=%s - %s", r.Method, r.URL.Path, logging.GetClientIP(r.Context()), reason)
 	}
 }
 
 // writeAuthError writes an authentication error response
-func (m *JWTMiddleware) writeAuthError(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]any{
-		"error": message,
-		"code":  statusCode,
-	})
+func (m *JWTMiddleware) writeAuthError(w http.ResponseWriter, r *http.Request, statusCode int, code apperrors.ErrorCode, message string) {
+	writeError(w, r, statusCode, code, message)
 }
 
 // GenerateToken generates a new JWT token with the given claims
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 const testSecret = "test-secret-key-for-testing"
@@ -514,7 +516,7 @@ func TestWriteAuthError(t *testing.T) {
 	middleware := NewJWTMiddleware(JWTConfig{Secret: testSecret})
 
 	w := httptest.NewRecorder()
-	middleware.writeAuthError(w, http.StatusUnauthorized, "Test error")
+	middleware.writeAuthError(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusUnauthorized, apperrors.CodeInvalidToken, "Test error")
 
 	if w.Code != http.StatusUnauthorized {
 		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
@@ -529,12 +531,12 @@ func TestWriteAuthError(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if response["error"] != "Test error" {
-		t.Errorf("Expected error 'Test error', got '%v'", response["error"])
+	if detail, _ := response["error"].(map[string]any); detail["message"] != "Test error" {
+		t.Errorf("Expected error message 'Test error', got '%v'", response["error"])
 	}
 
-	if response["code"].(float64) != float64(http.StatusUnauthorized) {
-		t.Errorf("Expected code %d, got %v", http.StatusUnauthorized, response["code"])
+	if response["code"] != string(apperrors.CodeInvalidToken) || response["status"] != float64(http.StatusUnauthorized) {
+		t.Errorf("Expected code %s and status %d, got %v and %v", apperrors.CodeInvalidToken, http.StatusUnauthorized, response["code"], response["status"])
 	}
 }
 
@@ -5,20 +5,26 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
 	"github.com/thalib/moon/cmd/moon/internal/auth"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 )
 
 // AuthEntity represents an authenticated entity (user or API key).
 type AuthEntity struct {
-	ID       string // ULID of the entity
-	Type     string // "user" or "apikey"
-	Role     string // "admin" or "user"
-	CanWrite bool   // Write permission flag
-	Username string // Username (only for users)
+	ID       string      // ULID of the entity
+	Type     string      // "user" or "apikey"
+	Role     string      // "admin" or "user"
+	CanWrite bool        // Write permission flag
+	Username string      // Username (only for users)
+	Scopes   auth.Scopes // Collection scopes (only for API keys, empty means unrestricted)
+	Tenant   string      // Tenant owning the entity's collections (only set when tenancy is enabled)
 }
 
 const (
@@ -42,6 +48,26 @@ func SetAuthEntity(ctx context.Context, entity *AuthEntity) context.Context {
 	return context.WithValue(ctx, AuthEntityContextKey, entity)
 }
 
+// HasScope reports whether the authenticated entity in the context may perform
+// the scope action on the collection. Requests without an entity and entities
+// without scopes are not restricted.
+func HasScope(ctx context.Context, collection, action string) bool {
+	entity, ok := GetAuthEntity(ctx)
+	if !ok {
+		return true
+	}
+	return entity.Scopes.Allows(collection, action)
+}
+
+// GetTenant returns the tenant of the authenticated entity in the context, or
+// an empty string when there is none or tenancy is disabled.
+func GetTenant(ctx context.Context) string {
+	if entity, ok := GetAuthEntity(ctx); ok {
+		return entity.Tenant
+	}
+	return ""
+}
+
 // AuthorizationMiddleware provides authorization middleware.
 type AuthorizationMiddleware struct{}
 
@@ -59,7 +85,7 @@ func (m *AuthorizationMiddleware) RequireRole(role string) func(http.HandlerFunc
 			entity, ok := GetAuthEntity(r.Context())
 			if !ok {
 				m.logAuthzFailure(r, "", "", "no auth entity in context")
-				m.writeAuthzError(w, http.StatusForbidden, "access denied", "AUTHZ_NO_ENTITY")
+				m.writeAuthzError(w, r, http.StatusForbidden, "access denied", apperrors.CodeForbidden)
 				return
 			}
 
@@ -72,7 +98,7 @@ func (m *AuthorizationMiddleware) RequireRole(role string) func(http.HandlerFunc
 			// Check if user has the required role
 			if entity.Role != role && role != string(auth.RoleUser) {
 				m.logAuthzFailure(r, entity.ID, entity.Type, "insufficient role")
-				m.writeAuthzError(w, http.StatusForbidden, "admin access required", "AUTHZ_ROLE_REQUIRED")
+				m.writeAuthzError(w, r, http.StatusForbidden, "admin access required", apperrors.CodeAdminRequired)
 				return
 			}
 
@@ -94,7 +120,7 @@ func (m *AuthorizationMiddleware) RequireWrite(next http.HandlerFunc) http.Handl
 		entity, ok := GetAuthEntity(r.Context())
 		if !ok {
 			m.logAuthzFailure(r, "", "", "no auth entity in context")
-			m.writeAuthzError(w, http.StatusForbidden, "access denied", "AUTHZ_NO_ENTITY")
+			m.writeAuthzError(w, r, http.StatusForbidden, "access denied", apperrors.CodeForbidden)
 			return
 		}
 
@@ -107,7 +133,7 @@ func (m *AuthorizationMiddleware) RequireWrite(next http.HandlerFunc) http.Handl
 		// Check if user has write permission
 		if !entity.CanWrite {
 			m.logAuthzFailure(r, entity.ID, entity.Type, "write permission required")
-			m.writeAuthzError(w, http.StatusForbidden, "write permission required", "AUTHZ_WRITE_REQUIRED")
+			m.writeAuthzError(w, r, http.StatusForbidden, "write permission required", apperrors.CodeInsufficientPermissions)
 			return
 		}
 
@@ -122,7 +148,7 @@ func (m *AuthorizationMiddleware) RequireAuthenticated(next http.HandlerFunc) ht
 		_, ok := GetAuthEntity(r.Context())
 		if !ok {
 			m.logAuthzFailure(r, "", "", "authentication required")
-			m.writeAuthzError(w, http.StatusForbidden, "authentication required", "AUTHZ_NOT_AUTHENTICATED")
+			m.writeAuthzError(w, r, http.StatusForbidden, "authentication required", apperrors.CodeAuthenticationRequired)
 			return
 		}
 
@@ -130,6 +156,29 @@ func (m *AuthorizationMiddleware) RequireAuthenticated(next http.HandlerFunc) ht
 	}
 }
 
+// RequireScope returns middleware that checks the entity's scopes allow the
+// action on the collection. Role and write checks still apply separately.
+func (m *AuthorizationMiddleware) RequireScope(collection, action string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), collection, action) {
+				entity, _ := GetAuthEntity(r.Context())
+				m.logAuthzFailure(r, entity.ID, entity.Type, "out of scope")
+				WriteScopeError(w, r, collection, action)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// WriteScopeError writes the 403 response for a request outside the API key's scopes.
+func WriteScopeError(w http.ResponseWriter, r *http.Request, collection, action string) {
+	writeError(w, r, http.StatusForbidden, apperrors.CodeInsufficientScope,
+		fmt.Sprintf("API key scope does not allow %s on collection '%s'", action, collection))
+}
+
 // logAuthzFailure logs authorization failures.
 func (m *AuthorizationMiddleware) logAuthzFailure(r *http.Request, entityID, entityType, reason string) {
 	if entityID == "" {
@@ -138,17 +187,24 @@ func (m *AuthorizationMiddleware) logAuthzFailure(r *http.Request, entityID, ent
 	if entityType == "" {
 		entityType = "unknown"
 	}
-	log.Printf("WARN: AUTHZ_FAILURE entity_id=%s entity_type=%s endpoint=%s reason=%s",
-		entityID, entityType, r.URL.Path, reason)
+	log.Printf("WARN: AUTHZ_FAILURE entity_id=%s entity_type=%s ip=%s endpoint=%s reason=%s",
+		entityID, entityType, logging.GetClientIP(r.Context()), r.URL.Path, reason)
 }
 
 // writeAuthzError writes an authorization error response.
-func (m *AuthorizationMiddleware) writeAuthzError(w http.ResponseWriter, statusCode int, message, code string) {
+func (m *AuthorizationMiddleware) writeAuthzError(w http.ResponseWriter, r *http.Request, statusCode int, message string, code apperrors.ErrorCode) {
+	writeError(w, r, statusCode, code, message)
+}
+
+// errorBody builds an error payload in the negotiated API version, carrying
+// the request ID from the request context
+func errorBody(r *http.Request, statusCode int, code apperrors.ErrorCode, message string) map[string]any {
+	return apperrors.VersionedBody(apiversion.FromContext(r.Context()), statusCode, code, message, logging.GetRequestID(r.Context()))
+}
+
+// writeError writes a JSON error response with a machine-readable code
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, code apperrors.ErrorCode, message string) {
 	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]any{
-		"error":      message,
-		"code":       statusCode,
-		"error_code": code,
-	})
+	json.NewEncoder(w).Encode(errorBody(r, statusCode, code, message))
 }
@@ -5,6 +5,8 @@ import (
 	"log"
 	"net/http"
 	"strings"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 // CORSConfig holds CORS middleware configuration
@@ -44,6 +46,14 @@ func NewCORSMiddleware(config CORSConfig) *CORSMiddleware {
 			"X-RateLimit-Remaining",
 			"X-RateLimit-Reset",
 			"X-Request-ID",
+			"ETag",
+			"X-Moon-Cache",
+			"X-Moon-API-Version",
+			"X-Next-Cursor",
+			"X-Total",
+			"X-Idempotent-Replay",
+			"Location",
+			"X-Moon-Job",
 		}
 	}
 	return &CORSMiddleware{config: config}
@@ -60,6 +70,12 @@ func (m *CORSMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
 
 		origin := r.Header.Get("Origin")
 
+		// Preflights from origins outside the allowlist are rejected before authentication
+		if isPreflight(r) && !m.isOriginAllowed(origin) {
+			m.writeCORSError(w, r, http.StatusForbidden, "origin not allowed")
+			return
+		}
+
 		// Check if origin is allowed
 		if origin != "" && m.isOriginAllowed(origin) {
 			// Set Access-Control-Allow-Origin
@@ -121,6 +137,18 @@ func (m *CORSMiddleware) isOriginAllowed(origin string) bool {
 	return false
 }
 
+// isPreflight reports whether r is a browser CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// writeCORSError writes a CORS rejection response
+func (m *CORSMiddleware) writeCORSError(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	writeError(w, r, statusCode, apperrors.CodeOriginNotAllowed, message)
+}
+
 // HandlePublic adds public CORS headers (Access-Control-Allow-Origin: *) for public endpoints (PRD-052)
 // This is used for health checks, documentation, and other non-sensitive endpoints
 func (m *CORSMiddleware) HandlePublic(next http.HandlerFunc) http.HandlerFunc {
@@ -215,6 +243,11 @@ func (m *CORSMiddleware) HandleDynamic(next http.HandlerFunc) http.HandlerFunc {
 				origins = m.config.AllowedOrigins
 			}
 
+			if isPreflight(r) && !m.isOriginAllowedFor(r.Header.Get("Origin"), origins) {
+				m.writeCORSError(w, r, http.StatusForbidden, "origin not allowed")
+				return
+			}
+
 			// Apply endpoint-specific CORS
 			m.applyCORSHeaders(w, r, origins,
 				endpointConfig.AllowedMethods,
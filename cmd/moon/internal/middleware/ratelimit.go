@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 )
 
 const (
@@ -239,7 +241,7 @@ func (m *RateLimitMiddleware) RateLimit(next http.HandlerFunc) http.HandlerFunc
 
 		if !allowed {
 			m.logRateLimitExceeded(r, entity.ID, entity.Type)
-			m.writeRateLimitError(w, limit, reset)
+			m.writeRateLimitError(w, r, limit, reset)
 			return
 		}
 
@@ -249,12 +251,12 @@ func (m *RateLimitMiddleware) RateLimit(next http.HandlerFunc) http.HandlerFunc
 
 // logRateLimitExceeded logs rate limit violations.
 func (m *RateLimitMiddleware) logRateLimitExceeded(r *http.Request, entityID, entityType string) {
-	log.Printf("WARN: RATE_LIMIT_EXCEEDED entity_id=%s entity_type=%s endpoint=%s",
-		entityID, entityType, r.URL.Path)
+	log.Printf("WARN: RATE_LIMIT_EXCEEDED entity_id=%s entity_type=%s ip=%s endpoint=%s",
+		entityID, entityType, logging.GetClientIP(r.Context()), r.URL.Path)
 }
 
 // writeRateLimitError writes a rate limit error response.
-func (m *RateLimitMiddleware) writeRateLimitError(w http.ResponseWriter, limit int, reset time.Time) {
+func (m *RateLimitMiddleware) writeRateLimitError(w http.ResponseWriter, r *http.Request, limit int, reset time.Time) {
 	// Add Retry-After header (PRD-049)
 	retryAfter := int(time.Until(reset).Seconds())
 	if retryAfter < 0 {
@@ -263,14 +265,10 @@ func (m *RateLimitMiddleware) writeRateLimitError(w http.ResponseWriter, limit i
 	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
 	w.WriteHeader(http.StatusTooManyRequests)
-	// Note: Using string literal instead of errors.CodeRateLimitExceeded to avoid circular import
-	json.NewEncoder(w).Encode(map[string]any{
-		"error":      "rate limit exceeded",
-		"code":       "RATE_LIMIT_EXCEEDED",
-		"error_code": "RATE_LIMIT_EXCEEDED",
-		"limit":      limit,
-		"reset":      reset.Unix(),
-	})
+	body := errorBody(r, http.StatusTooManyRequests, apperrors.CodeRateLimitExceeded, "rate limit exceeded")
+	apperrors.SetField(body, "limit", limit)
+	apperrors.SetField(body, "reset", reset.Unix())
+	json.NewEncoder(w).Encode(body)
 }
 
 // LoginRateLimiter manages rate limits for login attempts.
@@ -430,15 +428,12 @@ func (lrl *LoginRateLimiter) IsBlocked(ip, username string) (blocked bool, reset
 }
 
 // WriteLoginRateLimitError writes a login rate limit error response.
-func WriteLoginRateLimitError(w http.ResponseWriter, resetAt time.Time) {
+func WriteLoginRateLimitError(w http.ResponseWriter, r *http.Request, resetAt time.Time) {
 	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
 	w.WriteHeader(http.StatusTooManyRequests)
-	json.NewEncoder(w).Encode(map[string]any{
-		"error":      "too many login attempts",
-		"code":       http.StatusTooManyRequests,
-		"error_code": "LOGIN_RATE_LIMIT",
-		"reset":      resetAt.Unix(),
-	})
+	body := errorBody(r, http.StatusTooManyRequests, apperrors.CodeLoginRateLimited, "too many login attempts")
+	apperrors.SetField(body, "reset", resetAt.Unix())
+	json.NewEncoder(w).Encode(body)
 }
 
 // LogLoginRateLimitExceeded logs login rate limit violations.
@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
 	"time"
+
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
 )
 
 func TestNewTokenBucket(t *testing.T) {
@@ -489,7 +492,7 @@ func TestWriteLoginRateLimitError(t *testing.T) {
 	w := httptest.NewRecorder()
 	resetAt := time.Now().Add(15 * time.Minute)
 
-	WriteLoginRateLimitError(w, resetAt)
+	WriteLoginRateLimitError(w, httptest.NewRequest(http.MethodPost, "/auth:login", nil), resetAt)
 
 	if w.Code != http.StatusTooManyRequests {
 		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
@@ -499,4 +502,12 @@ func TestWriteLoginRateLimitError(t *testing.T) {
 	if contentType != "application/json" {
 		t.Errorf("Expected Content-Type application/json, got %s", contentType)
 	}
+
+	var response map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["code"] != string(apperrors.CodeLoginRateLimited) || response["reset"] != float64(resetAt.Unix()) {
+		t.Errorf("Expected login_rate_limited with reset, got %v", response)
+	}
 }
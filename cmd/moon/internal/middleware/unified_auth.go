@@ -2,13 +2,14 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 )
 
 // UnifiedAuthConfig holds configuration for unified authentication middleware
@@ -75,7 +76,7 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 
 		// If no token, return authentication required error
 		if err != nil || token == "" {
-			m.writeAuthError(w, http.StatusUnauthorized, "authentication_required",
+			m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeAuthenticationRequired,
 				"Authorization header required. Use: Authorization: Bearer <token>")
 			return
 		}
@@ -84,7 +85,7 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 		if m.isAPIKey(token) {
 			// Route to API key authentication
 			if m.config.APIKeyMiddleware == nil {
-				m.writeAuthError(w, http.StatusUnauthorized, "invalid_credentials",
+				m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials,
 					"API key authentication is not enabled")
 				return
 			}
@@ -94,7 +95,7 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 			keyInfo, ok := m.config.APIKeyMiddleware.config.Store.Get(hashedKey)
 			if !ok {
 				m.logAuthFailure(r, "invalid API key", nil)
-				m.writeAuthError(w, http.StatusUnauthorized, "invalid_credentials",
+				m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials,
 					"Token is invalid, expired, or has been revoked")
 				return
 			}
@@ -105,14 +106,14 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 			if len(requiredPerms) > 0 {
 				if !m.hasPermissions(keyInfo, path, requiredPerms) {
 					m.logAuthFailure(r, "insufficient permissions", nil)
-					m.writeAuthError(w, http.StatusForbidden, "forbidden",
+					m.writeAuthError(w, r, http.StatusForbidden, apperrors.CodeForbidden,
 						"Insufficient permissions for this operation")
 					return
 				}
 			}
 
 			// Log API key usage
-			log.Printf("APIKEY_USAGE: %s %s - key_id=%s key_name=%s", r.Method, r.URL.Path, keyInfo.ID, keyInfo.Name)
+			log.Printf("APIKEY_USAGE: %s %s ip=%s - key_id=%s key_name=%s", r.Method, r.URL.Path, logging.GetClientIP(r.Context()), keyInfo.ID, keyInfo.Name)
 
 			// Add key info to context
 			ctx := context.WithValue(r.Context(), APIKeyContextKey, keyInfo)
@@ -121,7 +122,7 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 		} else if m.isJWT(token) {
 			// Route to JWT authentication
 			if m.config.JWTMiddleware == nil {
-				m.writeAuthError(w, http.StatusUnauthorized, "invalid_credentials",
+				m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials,
 					"JWT authentication is not enabled")
 				return
 			}
@@ -131,13 +132,13 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 				blacklisted, err := m.config.JWTMiddleware.config.TokenBlacklist.IsBlacklisted(r.Context(), token)
 				if err != nil {
 					m.logAuthFailure(r, "error checking token blacklist", err)
-					m.writeAuthError(w, http.StatusInternalServerError, "authentication_error",
+					m.writeAuthError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError,
 						"Authentication error")
 					return
 				}
 				if blacklisted {
 					m.logAuthFailure(r, "token has been revoked", nil)
-					m.writeAuthError(w, http.StatusUnauthorized, "invalid_credentials",
+					m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials,
 						"Token is invalid, expired, or has been revoked")
 					return
 				}
@@ -147,7 +148,7 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 			claims, err := m.config.JWTMiddleware.validateToken(token)
 			if err != nil {
 				m.logAuthFailure(r, "invalid token", err)
-				m.writeAuthError(w, http.StatusUnauthorized, "invalid_credentials",
+				m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidCredentials,
 					"Token is invalid, expired, or has been revoked")
 				return
 			}
@@ -156,7 +157,7 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 			if requiredRoles, ok := m.config.JWTMiddleware.config.RequiredRoles[path]; ok && len(requiredRoles) > 0 {
 				if !m.hasRequiredRoles(claims.Roles, requiredRoles) {
 					m.logAuthFailure(r, "insufficient permissions", nil)
-					m.writeAuthError(w, http.StatusForbidden, "forbidden",
+					m.writeAuthError(w, r, http.StatusForbidden, apperrors.CodeForbidden,
 						"Insufficient permissions for this operation")
 					return
 				}
@@ -169,7 +170,7 @@ func (m *UnifiedAuthMiddleware) Authenticate(next http.HandlerFunc) http.Handler
 		} else {
 			// Invalid token format
 			m.logAuthFailure(r, "invalid token format", nil)
-			m.writeAuthError(w, http.StatusUnauthorized, "invalid_token_format",
+			m.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidTokenFormat,
 				"Token must be a valid JWT or API key (starting with moon_live_)")
 			return
 		}
@@ -326,19 +327,13 @@ func (m *UnifiedAuthMiddleware) hasRequiredRoles(userRoles, requiredRoles []stri
 // logAuthFailure logs authentication failures for security monitoring
 func (m *UnifiedAuthMiddleware) logAuthFailure(r *http.Request, reason string, err error) {
 	if err != nil {
-		log.Printf("AUTH_FAILURE: %s %s - %s: %v", r.Method, r.URL.Path, reason, err)
+		log.Printf("AUTH_FAILURE: %s %s ip=%s - %s: %v", r.Method, r.URL.Path, logging.GetClientIP(r.Context()), reason, err)
 	} else {
-		log.Printf("AUTH_FAILURE: %s %s - %s", r.Method, r.URL.Path, reason)
+		log.Printf("AUTH_FAILURE: %s %s ip=%s - %s", r.Method, r.URL.Path, logging.GetClientIP(r.Context()), reason)
 	}
 }
 
 // writeAuthError writes a standardized authentication error response
-func (m *UnifiedAuthMiddleware) writeAuthError(w http.ResponseWriter, statusCode int, errorType, message string) {
-	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]any{
-		"error":   errorType,
-		"message": message,
-		"status":  statusCode,
-	})
+func (m *UnifiedAuthMiddleware) writeAuthError(w http.ResponseWriter, r *http.Request, statusCode int, code apperrors.ErrorCode, message string) {
+	writeError(w, r, statusCode, code, message)
 }
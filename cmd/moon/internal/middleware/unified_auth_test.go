@@ -138,8 +138,8 @@ func TestUnifiedAuth_LegacyXAPIKeyHeader_Rejected(t *testing.T) {
 	var resp map[string]any
 	json.Unmarshal(w.Body.Bytes(), &resp)
 
-	if resp["error"] != "authentication_required" {
-		t.Errorf("Expected error 'authentication_required', got %v", resp["error"])
+	if resp["code"] != "authentication_required" {
+		t.Errorf("Expected code 'authentication_required', got %v", resp["code"])
 	}
 
 	// Verify NO deprecation headers (feature removed completely)
@@ -231,8 +231,8 @@ func TestUnifiedAuth_MissingAuthorizationHeader(t *testing.T) {
 	var resp map[string]any
 	json.Unmarshal(w.Body.Bytes(), &resp)
 
-	if resp["error"] != "authentication_required" {
-		t.Errorf("Expected error 'authentication_required', got %v", resp["error"])
+	if resp["code"] != "authentication_required" {
+		t.Errorf("Expected code 'authentication_required', got %v", resp["code"])
 	}
 }
 
@@ -265,8 +265,8 @@ func TestUnifiedAuth_InvalidTokenFormat(t *testing.T) {
 	var resp map[string]any
 	json.Unmarshal(w.Body.Bytes(), &resp)
 
-	if resp["error"] != "invalid_token_format" {
-		t.Errorf("Expected error 'invalid_token_format', got %v", resp["error"])
+	if resp["code"] != "invalid_token_format" {
+		t.Errorf("Expected code 'invalid_token_format', got %v", resp["code"])
 	}
 }
 
@@ -301,8 +301,8 @@ func TestUnifiedAuth_ExpiredJWT(t *testing.T) {
 	var resp map[string]any
 	json.Unmarshal(w.Body.Bytes(), &resp)
 
-	if resp["error"] != "invalid_credentials" {
-		t.Errorf("Expected error 'invalid_credentials', got %v", resp["error"])
+	if resp["code"] != "invalid_credentials" {
+		t.Errorf("Expected code 'invalid_credentials', got %v", resp["code"])
 	}
 }
 
@@ -337,8 +337,8 @@ func TestUnifiedAuth_InvalidAPIKey(t *testing.T) {
 	var resp map[string]any
 	json.Unmarshal(w.Body.Bytes(), &resp)
 
-	if resp["error"] != "invalid_credentials" {
-		t.Errorf("Expected error 'invalid_credentials', got %v", resp["error"])
+	if resp["code"] != "invalid_credentials" {
+		t.Errorf("Expected code 'invalid_credentials', got %v", resp["code"])
 	}
 }
 
@@ -383,8 +383,8 @@ func TestUnifiedAuth_InsufficientPermissions(t *testing.T) {
 	var resp map[string]any
 	json.Unmarshal(w.Body.Bytes(), &resp)
 
-	if resp["error"] != "forbidden" {
-		t.Errorf("Expected error 'forbidden', got %v", resp["error"])
+	if resp["code"] != "forbidden" {
+		t.Errorf("Expected code 'forbidden', got %v", resp["code"])
 	}
 }
 
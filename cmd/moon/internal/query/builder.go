@@ -5,6 +5,7 @@ package query
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/thalib/moon/cmd/moon/internal/constants"
@@ -22,6 +23,21 @@ const (
 	OpLessThanOrEqual    = "<="
 	OpLike               = "LIKE"
 	OpIn                 = "IN"
+	OpIsNull             = "IS NULL"
+	OpIsNotNull          = "IS NOT NULL"
+	OpBetween            = "BETWEEN"
+
+	// Group operators join the nested conditions of a group condition
+	OpAnd = "AND"
+	OpOr  = "OR"
+)
+
+// Match modes select how the value of an OpLike condition is matched
+const (
+	MatchContains    = ""            // the column contains the value (default)
+	MatchInsensitive = "insensitive" // the column contains the value, ignoring case
+	MatchPrefix      = "prefix"      // the column starts with the value
+	MatchExact       = "exact"       // the column is the value, without wildcards
 )
 
 // validOperators contains all supported SQL operators
@@ -34,6 +50,9 @@ var validOperators = map[string]bool{
 	OpLessThanOrEqual:    true,
 	OpLike:               true,
 	OpIn:                 true,
+	OpIsNull:             true,
+	OpIsNotNull:          true,
+	OpBetween:            true,
 }
 
 // ValidateOperator checks if an operator is valid and safe to use
@@ -63,16 +82,29 @@ type Builder interface {
 	Avg(tableName string, field string, where []Condition) (string, []any)
 	Min(tableName string, field string, where []Condition) (string, []any)
 	Max(tableName string, field string, where []Condition) (string, []any)
+	GroupBy(tableName string, groupColumn string, function string, field string, where []Condition, limit int) (string, []any)
+	Distinct(tableName string, column string, withCount bool, where []Condition, limit int) (string, []any)
+	TimeSeries(tableName string, column string, interval string, function string, field string, where []Condition) (string, []any)
 
 	// Dialect returns the database dialect
 	Dialect() database.DialectType
 }
 
-// Condition represents a WHERE clause condition
+// Condition represents a WHERE clause condition. A group condition has the
+// operator OpAnd or OpOr and no column; it joins its nested Conditions in
+// parentheses, so groups can be nested to express any AND/OR tree.
 type Condition struct {
-	Column   string
-	Operator string
-	Value    any
+	Column     string
+	Path       []string // Keys inside a JSON column; the condition applies to the extracted value
+	Operator   string
+	Value      any
+	Match      string      // Match mode of an OpLike condition; MatchContains when empty
+	Conditions []Condition // Members of an OpAnd or OpOr group
+}
+
+// IsGroup reports whether the condition is an AND or OR group
+func (c Condition) IsGroup() bool {
+	return c.Operator == OpAnd || c.Operator == OpOr
 }
 
 // builder implements Builder interface
@@ -286,35 +318,73 @@ func (b *builder) Delete(tableName string, where []Condition) (string, []any) {
 
 // escapeIdentifier escapes table/column names based on dialect
 func (b *builder) escapeIdentifier(name string) string {
-	switch b.dialect {
+	return QuoteIdent(b.dialect, name)
+}
+
+// QuoteIdent quotes a table or column name for the dialect: backticks for
+// MySQL, double quotes for PostgreSQL and SQLite. An embedded quote character
+// is doubled. Every identifier written into SQL, including hand-built
+// statements outside the builder, goes through QuoteIdent so that user
+// columns named like soft keywords (escape, returning, ...) keep working.
+func QuoteIdent(dialect database.DialectType, name string) string {
+	if dialect == database.DialectMySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteIdents quotes each name with QuoteIdent and joins them with ", "
+func QuoteIdents(dialect database.DialectType, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteIdent(dialect, name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// JSONPathExpression returns the expression extracting the value at path from
+// an already escaped JSON column. Path keys are written into the SQL as is and
+// must be validated by the caller (see constants.JSONPathKeyPattern).
+func JSONPathExpression(dialect database.DialectType, column string, path []string) string {
+	switch dialect {
 	case database.DialectPostgres:
-		// PostgreSQL uses double quotes
-		return fmt.Sprintf(`"%s"`, name)
+		expr := column
+		for _, key := range path[:len(path)-1] {
+			expr += "->'" + key + "'"
+		}
+		return expr + "->>'" + path[len(path)-1] + "'"
 	case database.DialectMySQL:
-		// MySQL uses backticks
-		return fmt.Sprintf("`%s`", name)
-	case database.DialectSQLite:
-		// SQLite supports double quotes but also works without
-		return name
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s'))", column, strings.Join(path, "."))
 	default:
-		return name
+		return fmt.Sprintf("json_extract(%s, '$.%s')", column, strings.Join(path, "."))
 	}
 }
 
 // placeholder returns the appropriate placeholder for parameterized queries
 func (b *builder) placeholder(position int) string {
-	switch b.dialect {
-	case database.DialectPostgres:
+	return Placeholder(b.dialect, position)
+}
+
+// Placeholder returns the bind placeholder at the 1-based position for the
+// dialect: $n for PostgreSQL, ? otherwise
+func Placeholder(dialect database.DialectType, position int) string {
+	if dialect == database.DialectPostgres {
 		return fmt.Sprintf("$%d", position)
-	case database.DialectMySQL, database.DialectSQLite:
-		return "?"
-	default:
-		return "?"
 	}
+	return "?"
+}
+
+// LikePattern returns the LIKE pattern matching values that contain value.
+// Wildcards in value are escaped with a backslash so they match literally.
+func LikePattern(value any) any {
+	return MatchPattern(value, MatchContains)
 }
 
-// escapeLikeValue escapes special characters in LIKE patterns to prevent unintended wildcard matches
-func (b *builder) escapeLikeValue(value any) any {
+// MatchPattern returns the LIKE pattern of value for a match mode: wrapped in
+// wildcards for MatchContains and MatchInsensitive, followed by one for
+// MatchPrefix and bare for MatchExact. Wildcards in value are escaped with a
+// backslash so they match literally.
+func MatchPattern(value any, match string) any {
 	str, ok := value.(string)
 	if !ok {
 		return value
@@ -326,7 +396,23 @@ func (b *builder) escapeLikeValue(value any) any {
 	str = strings.ReplaceAll(str, `%`, `\%`)
 	str = strings.ReplaceAll(str, `_`, `\_`)
 
-	return str
+	switch match {
+	case MatchPrefix:
+		return str + "%"
+	case MatchExact:
+		return str
+	default:
+		return "%" + str + "%"
+	}
+}
+
+// LikeEscapeClause returns the ESCAPE clause that makes backslash the LIKE
+// escape character. PostgreSQL and MySQL use it by default; SQLite has none.
+func LikeEscapeClause(dialect database.DialectType) string {
+	if dialect == database.DialectSQLite {
+		return ` ESCAPE '\'`
+	}
+	return ""
 }
 
 // buildWhereClause builds a WHERE clause from conditions and returns the updated args slice
@@ -340,37 +426,97 @@ func (b *builder) buildWhereClause(sb *strings.Builder, where []Condition, args
 		if i > 0 {
 			sb.WriteString(" AND ")
 		}
-		sb.WriteString(b.escapeIdentifier(cond.Column))
-		sb.WriteString(" ")
-		sb.WriteString(cond.Operator)
-		sb.WriteString(" ")
+		args = WriteCondition(sb, b.dialect, cond, args)
+	}
 
-		// Handle special operators
-		if cond.Operator == OpIn {
-			// IN operator expects a slice of values
-			values, ok := cond.Value.([]any)
-			if !ok {
-				// If not a slice, treat as single value
-				values = []any{cond.Value}
+	return args
+}
+
+// WriteCondition writes a condition and returns args with its bound values
+// appended. Placeholders are numbered from len(args)+1, so a condition can
+// follow other bound SQL; groups are written in parentheses, recursively.
+func WriteCondition(sb *strings.Builder, dialect database.DialectType, cond Condition, args []any) []any {
+	if cond.IsGroup() {
+		// An empty AND matches every row and an empty OR none
+		if len(cond.Conditions) == 0 {
+			if cond.Operator == OpAnd {
+				sb.WriteString("1 = 1")
+			} else {
+				sb.WriteString("1 = 0")
 			}
-			sb.WriteString("(")
-			for j, v := range values {
-				if j > 0 {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(b.placeholder(len(args) + 1))
-				args = append(args, v)
+			return args
+		}
+		sb.WriteString("(")
+		for i, member := range cond.Conditions {
+			if i > 0 {
+				sb.WriteString(" " + cond.Operator + " ")
 			}
-			sb.WriteString(")")
-		} else if cond.Operator == OpLike {
-			// LIKE operator - escape special characters in value
-			sb.WriteString(b.placeholder(len(args) + 1))
-			args = append(args, b.escapeLikeValue(cond.Value))
+			args = WriteCondition(sb, dialect, member, args)
+		}
+		sb.WriteString(")")
+		return args
+	}
+
+	column := QuoteIdent(dialect, cond.Column)
+	if len(cond.Path) > 0 {
+		column = JSONPathExpression(dialect, column, cond.Path)
+	}
+	if cond.Operator == OpLike && cond.Match == MatchInsensitive {
+		column = "LOWER(" + column + ")"
+	}
+	sb.WriteString(column)
+	sb.WriteString(" ")
+	sb.WriteString(cond.Operator)
+
+	// NULL checks take no operand
+	if cond.Operator == OpIsNull || cond.Operator == OpIsNotNull {
+		return args
+	}
+	sb.WriteString(" ")
+
+	// Handle special operators
+	if cond.Operator == OpBetween {
+		// BETWEEN expects a two-element slice [low, high]
+		bounds, _ := cond.Value.([]any)
+		if len(bounds) != 2 {
+			bounds = []any{cond.Value, cond.Value}
+		}
+		sb.WriteString(Placeholder(dialect, len(args)+1))
+		args = append(args, bounds[0])
+		sb.WriteString(" AND ")
+		sb.WriteString(Placeholder(dialect, len(args)+1))
+		args = append(args, bounds[1])
+	} else if cond.Operator == OpIn {
+		// IN operator expects a slice of values
+		values, ok := cond.Value.([]any)
+		if !ok {
+			// If not a slice, treat as single value
+			values = []any{cond.Value}
+		}
+		sb.WriteString("(")
+		for j, v := range values {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(Placeholder(dialect, len(args)+1))
+			args = append(args, v)
+		}
+		sb.WriteString(")")
+	} else if cond.Operator == OpLike {
+		// LIKE operator - match the escaped value as the match mode says.
+		// Insensitive matching lowercases both sides in SQL rather than in
+		// Go, so the column and the pattern are folded by the same rules.
+		if cond.Match == MatchInsensitive {
+			sb.WriteString("LOWER(" + Placeholder(dialect, len(args)+1) + ")")
 		} else {
-			// Standard operators
-			sb.WriteString(b.placeholder(len(args) + 1))
-			args = append(args, cond.Value)
+			sb.WriteString(Placeholder(dialect, len(args)+1))
 		}
+		sb.WriteString(LikeEscapeClause(dialect))
+		args = append(args, MatchPattern(cond.Value, cond.Match))
+	} else {
+		// Standard operators
+		sb.WriteString(Placeholder(dialect, len(args)+1))
+		args = append(args, cond.Value)
 	}
 
 	return args
@@ -378,7 +524,12 @@ func (b *builder) buildWhereClause(sb *strings.Builder, where []Condition, args
 
 // mapColumnTypeToSQL maps ColumnType to SQL type for the dialect
 func (b *builder) mapColumnTypeToSQL(colType registry.ColumnType) string {
-	switch b.dialect {
+	return ColumnSQLType(b.dialect, colType)
+}
+
+// ColumnSQLType returns the SQL type a column of the given type is created with
+func ColumnSQLType(dialect database.DialectType, colType registry.ColumnType) string {
+	switch dialect {
 	case database.DialectPostgres:
 		return mapColumnTypeToPostgres(colType)
 	case database.DialectMySQL:
@@ -461,13 +612,40 @@ func (b *builder) Count(tableName string, where []Condition) (string, []any) {
 	return sb.String(), args
 }
 
-// Sum generates SUM(field) aggregation query with optional WHERE clause
+// Sum generates a SUM(field), COUNT(field) aggregation query with optional WHERE clause
 func (b *builder) Sum(tableName string, field string, where []Condition) (string, []any) {
+	return b.aggregate("SUM", tableName, field, where)
+}
+
+// Avg generates an AVG(field), COUNT(field) aggregation query with optional WHERE clause
+func (b *builder) Avg(tableName string, field string, where []Condition) (string, []any) {
+	return b.aggregate("AVG", tableName, field, where)
+}
+
+// Min generates a MIN(field), COUNT(field) aggregation query with optional WHERE clause
+func (b *builder) Min(tableName string, field string, where []Condition) (string, []any) {
+	return b.aggregate("MIN", tableName, field, where)
+}
+
+// Max generates a MAX(field), COUNT(field) aggregation query with optional WHERE clause
+func (b *builder) Max(tableName string, field string, where []Condition) (string, []any) {
+	return b.aggregate("MAX", tableName, field, where)
+}
+
+// aggregate generates a query returning an aggregate of a field and the number
+// of non-NULL values it was computed from, which is zero when the aggregate is
+// NULL because no values matched
+func (b *builder) aggregate(sqlFunc, tableName, field string, where []Condition) (string, []any) {
 	var sb strings.Builder
 	args := []any{}
 
-	sb.WriteString("SELECT SUM(")
-	sb.WriteString(b.escapeIdentifier(field))
+	col := b.escapeIdentifier(field)
+	sb.WriteString("SELECT ")
+	sb.WriteString(sqlFunc)
+	sb.WriteString("(")
+	sb.WriteString(col)
+	sb.WriteString("), COUNT(")
+	sb.WriteString(col)
 	sb.WriteString(") FROM ")
 	sb.WriteString(b.escapeIdentifier(tableName))
 
@@ -477,50 +655,188 @@ func (b *builder) Sum(tableName string, field string, where []Condition) (string
 	return sb.String(), args
 }
 
-// Avg generates AVG(field) aggregation query with optional WHERE clause
-func (b *builder) Avg(tableName string, field string, where []Condition) (string, []any) {
+// validAggregateFunctions maps supported group-by aggregate names to SQL functions
+var validAggregateFunctions = map[string]string{
+	"count": "COUNT",
+	"sum":   "SUM",
+	"avg":   "AVG",
+	"min":   "MIN",
+	"max":   "MAX",
+}
+
+// ValidateAggregateFunction checks if an aggregate function name is supported
+func ValidateAggregateFunction(function string) error {
+	if _, ok := validAggregateFunctions[function]; !ok {
+		return fmt.Errorf("invalid aggregate function: %s", function)
+	}
+	return nil
+}
+
+// GroupBy generates a grouped aggregation query returning group_key and value columns.
+// For count the field is ignored and COUNT(*) is used. Results are ordered by the
+// group column; a positive limit caps the number of returned groups.
+func (b *builder) GroupBy(tableName string, groupColumn string, function string, field string, where []Condition, limit int) (string, []any) {
 	var sb strings.Builder
 	args := []any{}
 
-	sb.WriteString("SELECT AVG(")
-	sb.WriteString(b.escapeIdentifier(field))
-	sb.WriteString(") FROM ")
+	sqlFunc := validAggregateFunctions[function]
+	if sqlFunc == "" {
+		sqlFunc = "COUNT"
+	}
+
+	groupCol := b.escapeIdentifier(groupColumn)
+
+	sb.WriteString("SELECT ")
+	sb.WriteString(groupCol)
+	sb.WriteString(" AS group_key, ")
+	sb.WriteString(sqlFunc)
+	if sqlFunc == "COUNT" || field == "" {
+		sb.WriteString("(*)")
+	} else {
+		sb.WriteString("(")
+		sb.WriteString(b.escapeIdentifier(field))
+		sb.WriteString(")")
+	}
+	sb.WriteString(" AS value FROM ")
 	sb.WriteString(b.escapeIdentifier(tableName))
 
 	// WHERE clause
 	args = b.buildWhereClause(&sb, where, args)
 
+	sb.WriteString(" GROUP BY ")
+	sb.WriteString(groupCol)
+	sb.WriteString(" ORDER BY ")
+	sb.WriteString(groupCol)
+
+	if limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(b.placeholder(len(args) + 1))
+		args = append(args, limit)
+	}
+
 	return sb.String(), args
 }
 
-// Min generates MIN(field) aggregation query with optional WHERE clause
-func (b *builder) Min(tableName string, field string, where []Condition) (string, []any) {
+// TimeIntervals lists the bucket sizes of a time series, finest first
+var TimeIntervals = []string{"hour", "day", "week", "month"}
+
+// ValidateTimeInterval checks if a time series interval is supported
+func ValidateTimeInterval(interval string) error {
+	if !slices.Contains(TimeIntervals, interval) {
+		return fmt.Errorf("invalid interval: %s (use one of %s)", interval, strings.Join(TimeIntervals, ", "))
+	}
+	return nil
+}
+
+// TimeSeries generates a query returning bucket and value columns: the
+// aggregate of the rows per interval of a datetime column. Buckets are text in
+// UTC: 2006-01-02T15:00:00Z for an hour, 2006-01-02 for a day and for the
+// Monday starting a week, 2006-01 for a month. Rows whose column is NULL are
+// left out; results are ordered by bucket.
+func (b *builder) TimeSeries(tableName string, column string, interval string, function string, field string, where []Condition) (string, []any) {
 	var sb strings.Builder
 	args := []any{}
 
-	sb.WriteString("SELECT MIN(")
-	sb.WriteString(b.escapeIdentifier(field))
-	sb.WriteString(") FROM ")
+	sqlFunc := validAggregateFunctions[function]
+	if sqlFunc == "" {
+		sqlFunc = "COUNT"
+	}
+
+	sb.WriteString("SELECT ")
+	sb.WriteString(b.timeBucket(b.escapeIdentifier(column), interval))
+	sb.WriteString(" AS bucket, ")
+	sb.WriteString(sqlFunc)
+	if sqlFunc == "COUNT" || field == "" {
+		sb.WriteString("(*)")
+	} else {
+		sb.WriteString("(")
+		sb.WriteString(b.escapeIdentifier(field))
+		sb.WriteString(")")
+	}
+	sb.WriteString(" AS value FROM ")
 	sb.WriteString(b.escapeIdentifier(tableName))
 
-	// WHERE clause
+	where = append(slices.Clone(where), Condition{Column: column, Operator: OpIsNotNull})
 	args = b.buildWhereClause(&sb, where, args)
 
+	// Positions, as PostgreSQL would resolve a bucket column before the alias
+	sb.WriteString(" GROUP BY 1 ORDER BY 1")
+
 	return sb.String(), args
 }
 
-// Max generates MAX(field) aggregation query with optional WHERE clause
-func (b *builder) Max(tableName string, field string, where []Condition) (string, []any) {
+// timeBucket returns the expression truncating a datetime column to the
+// start of its interval, formatted as TimeSeries describes
+func (b *builder) timeBucket(col string, interval string) string {
+	switch b.dialect {
+	case database.DialectPostgres:
+		formats := map[string]string{
+			"hour":  `YYYY-MM-DD"T"HH24:00:00"Z"`,
+			"day":   "YYYY-MM-DD",
+			"week":  "YYYY-MM-DD",
+			"month": "YYYY-MM",
+		}
+		return fmt.Sprintf("to_char(date_trunc('%s', %s), '%s')", interval, col, formats[interval])
+	case database.DialectMySQL:
+		switch interval {
+		case "hour":
+			return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%dT%%H:00:00Z')", col)
+		case "week":
+			return fmt.Sprintf("DATE_FORMAT(DATE_SUB(%s, INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d')", col, col)
+		case "month":
+			return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m')", col)
+		}
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", col)
+	default:
+		switch interval {
+		case "hour":
+			return fmt.Sprintf("strftime('%%Y-%%m-%%dT%%H:00:00Z', %s)", col)
+		case "week":
+			// %w is 0 on Sunday; weeks start on Monday as on the other dialects
+			return fmt.Sprintf("date(%s, '-' || ((CAST(strftime('%%w', %s) AS INTEGER) + 6) %% 7) || ' days')", col, col)
+		case "month":
+			return fmt.Sprintf("strftime('%%Y-%%m', %s)", col)
+		}
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s)", col)
+	}
+}
+
+// Distinct generates a query returning the distinct values of a column as value,
+// ordered ascending. With withCount the query groups by the column and also returns
+// the number of matching rows per value as count. A positive limit caps the number
+// of returned values.
+func (b *builder) Distinct(tableName string, column string, withCount bool, where []Condition, limit int) (string, []any) {
 	var sb strings.Builder
 	args := []any{}
 
-	sb.WriteString("SELECT MAX(")
-	sb.WriteString(b.escapeIdentifier(field))
-	sb.WriteString(") FROM ")
+	col := b.escapeIdentifier(column)
+
+	if withCount {
+		sb.WriteString("SELECT ")
+		sb.WriteString(col)
+		sb.WriteString(" AS value, COUNT(*) AS count FROM ")
+	} else {
+		sb.WriteString("SELECT DISTINCT ")
+		sb.WriteString(col)
+		sb.WriteString(" AS value FROM ")
+	}
 	sb.WriteString(b.escapeIdentifier(tableName))
 
 	// WHERE clause
 	args = b.buildWhereClause(&sb, where, args)
 
+	if withCount {
+		sb.WriteString(" GROUP BY ")
+		sb.WriteString(col)
+	}
+	sb.WriteString(" ORDER BY ")
+	sb.WriteString(col)
+
+	if limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(b.placeholder(len(args) + 1))
+		args = append(args, limit)
+	}
+
 	return sb.String(), args
 }
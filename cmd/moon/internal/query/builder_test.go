@@ -103,8 +103,8 @@ func TestDropTable(t *testing.T) {
 	builder := NewBuilder(database.DialectSQLite)
 	sql := builder.DropTable("old_table")
 
-	if sql != "DROP TABLE old_table" {
-		t.Errorf("expected 'DROP TABLE old_table', got '%s'", sql)
+	if sql != `DROP TABLE "old_table"` {
+		t.Errorf("expected 'DROP TABLE \"old_table\"', got '%s'", sql)
 	}
 }
 
@@ -112,7 +112,7 @@ func TestSelect_Simple(t *testing.T) {
 	builder := NewBuilder(database.DialectSQLite)
 	sql, args := builder.Select("products", nil, nil, "", 0, 0)
 
-	expected := "SELECT * FROM products"
+	expected := `SELECT * FROM "products"`
 	if sql != expected {
 		t.Errorf("expected '%s', got '%s'", expected, sql)
 	}
@@ -226,7 +226,7 @@ func TestDelete(t *testing.T) {
 
 	sql, args := builder.Delete("products", where)
 
-	expected := "DELETE FROM products WHERE id = ?"
+	expected := `DELETE FROM "products" WHERE "id" = ?`
 	if sql != expected {
 		t.Errorf("expected '%s', got '%s'", expected, sql)
 	}
@@ -260,7 +260,9 @@ func TestEscapeIdentifier(t *testing.T) {
 	}{
 		{database.DialectPostgres, "user_name", `"user_name"`},
 		{database.DialectMySQL, "user_name", "`user_name`"},
-		{database.DialectSQLite, "user_name", "user_name"},
+		{database.DialectSQLite, "user_name", `"user_name"`},
+		{database.DialectPostgres, `odd"name`, `"odd""name"`},
+		{database.DialectMySQL, "odd`name", "`odd``name`"},
 	}
 
 	for _, tt := range tests {
@@ -436,31 +438,31 @@ func TestSelect_LikeOperator(t *testing.T) {
 		{
 			name:          "Normal string",
 			value:         "moon",
-			expectedValue: "moon",
+			expectedValue: "%moon%",
 			dialect:       database.DialectSQLite,
 		},
 		{
 			name:          "String with percent wildcard",
 			value:         "test%value",
-			expectedValue: `test\%value`,
+			expectedValue: `%test\%value%`,
 			dialect:       database.DialectSQLite,
 		},
 		{
 			name:          "String with underscore wildcard",
 			value:         "test_value",
-			expectedValue: `test\_value`,
+			expectedValue: `%test\_value%`,
 			dialect:       database.DialectPostgres,
 		},
 		{
 			name:          "String with both wildcards",
 			value:         "test%_value",
-			expectedValue: `test\%\_value`,
+			expectedValue: `%test\%\_value%`,
 			dialect:       database.DialectMySQL,
 		},
 		{
 			name:          "String with backslash",
 			value:         `test\value`,
-			expectedValue: `test\\value`,
+			expectedValue: `%test\\value%`,
 			dialect:       database.DialectSQLite,
 		},
 	}
@@ -490,6 +492,35 @@ func TestSelect_LikeOperator(t *testing.T) {
 	}
 }
 
+func TestWriteCondition_LikeMatchModes(t *testing.T) {
+	tests := []struct {
+		match       string
+		dialect     database.DialectType
+		expectedSQL string
+		expectedArg string
+	}{
+		{MatchContains, database.DialectPostgres, `"name" LIKE $1`, `%a\_b%`},
+		{MatchInsensitive, database.DialectPostgres, `LOWER("name") LIKE LOWER($1)`, `%a\_b%`},
+		{MatchInsensitive, database.DialectSQLite, `LOWER("name") LIKE LOWER(?) ESCAPE '\'`, `%a\_b%`},
+		{MatchInsensitive, database.DialectMySQL, "LOWER(`name`) LIKE LOWER(?)", `%a\_b%`},
+		{MatchPrefix, database.DialectPostgres, `"name" LIKE $1`, `a\_b%`},
+		{MatchExact, database.DialectSQLite, `"name" LIKE ? ESCAPE '\'`, `a\_b`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.match+"/"+string(tt.dialect), func(t *testing.T) {
+			var sb strings.Builder
+			args := WriteCondition(&sb, tt.dialect, Condition{Column: "name", Operator: OpLike, Value: "a_b", Match: tt.match}, nil)
+			if sb.String() != tt.expectedSQL {
+				t.Errorf("expected SQL %s, got %s", tt.expectedSQL, sb.String())
+			}
+			if len(args) != 1 || args[0] != tt.expectedArg {
+				t.Errorf("expected arg %q, got %v", tt.expectedArg, args)
+			}
+		})
+	}
+}
+
 func TestSelect_InOperator(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -580,6 +611,62 @@ func TestSelect_InOperator_NonSliceValue(t *testing.T) {
 	}
 }
 
+func TestSelect_NullAndBetweenOperators(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  database.DialectType
+		where    []Condition
+		expected string
+		args     int
+	}{
+		{
+			name:     "IS NULL sqlite",
+			dialect:  database.DialectSQLite,
+			where:    []Condition{{Column: "deleted_at", Operator: OpIsNull}},
+			expected: `SELECT * FROM "table" WHERE "deleted_at" IS NULL`,
+			args:     0,
+		},
+		{
+			name:     "IS NOT NULL postgres",
+			dialect:  database.DialectPostgres,
+			where:    []Condition{{Column: "deleted_at", Operator: OpIsNotNull}},
+			expected: `SELECT * FROM "table" WHERE "deleted_at" IS NOT NULL`,
+			args:     0,
+		},
+		{
+			name:     "BETWEEN sqlite",
+			dialect:  database.DialectSQLite,
+			where:    []Condition{{Column: "price", Operator: OpBetween, Value: []any{10, 100}}},
+			expected: `SELECT * FROM "table" WHERE "price" BETWEEN ? AND ?`,
+			args:     2,
+		},
+		{
+			name:    "BETWEEN postgres after other condition",
+			dialect: database.DialectPostgres,
+			where: []Condition{
+				{Column: "deleted_at", Operator: OpIsNull},
+				{Column: "name", Operator: OpEqual, Value: "x"},
+				{Column: "price", Operator: OpBetween, Value: []any{10, 100}},
+			},
+			expected: `SELECT * FROM "table" WHERE "deleted_at" IS NULL AND "name" = $1 AND "price" BETWEEN $2 AND $3`,
+			args:     3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.dialect)
+			sql, args := builder.Select("table", nil, tt.where, "", 0, 0)
+			if sql != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, sql)
+			}
+			if len(args) != tt.args {
+				t.Errorf("expected %d args, got %d", tt.args, len(args))
+			}
+		})
+	}
+}
+
 func TestSelect_MultipleOperators(t *testing.T) {
 	builder := NewBuilder(database.DialectPostgres)
 	where := []Condition{
@@ -745,7 +832,7 @@ func TestCount(t *testing.T) {
 			name:     "count all - sqlite",
 			dialect:  database.DialectSQLite,
 			table:    "orders",
-			wantSQL:  "SELECT COUNT(*) FROM orders",
+			wantSQL:  `SELECT COUNT(*) FROM "orders"`,
 			wantArgs: 0,
 		},
 		{
@@ -790,7 +877,7 @@ func TestSum(t *testing.T) {
 			dialect:  database.DialectPostgres,
 			table:    "orders",
 			field:    "total",
-			wantSQL:  `SELECT SUM("total") FROM "orders"`,
+			wantSQL:  `SELECT SUM("total"), COUNT("total") FROM "orders"`,
 			wantArgs: 0,
 		},
 		{
@@ -798,7 +885,7 @@ func TestSum(t *testing.T) {
 			dialect:  database.DialectSQLite,
 			table:    "orders",
 			field:    "total",
-			wantSQL:  "SELECT SUM(total) FROM orders",
+			wantSQL:  `SELECT SUM("total"), COUNT("total") FROM "orders"`,
 			wantArgs: 0,
 		},
 		{
@@ -809,7 +896,7 @@ func TestSum(t *testing.T) {
 			conditions: []Condition{
 				{Column: "status", Operator: OpEqual, Value: "completed"},
 			},
-			wantSQL:  `SELECT SUM("total") FROM "orders" WHERE "status" = $1`,
+			wantSQL:  `SELECT SUM("total"), COUNT("total") FROM "orders" WHERE "status" = $1`,
 			wantArgs: 1,
 		},
 	}
@@ -873,3 +960,264 @@ func TestMax(t *testing.T) {
 		t.Errorf("expected 0 args, got %d", len(args))
 	}
 }
+
+func TestGroupBy(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialect    database.DialectType
+		function   string
+		field      string
+		conditions []Condition
+		limit      int
+		wantSQL    string
+		wantArgs   int
+	}{
+		{
+			name:     "count - sqlite",
+			dialect:  database.DialectSQLite,
+			function: "count",
+			wantSQL:  `SELECT "status" AS group_key, COUNT(*) AS value FROM "orders" GROUP BY "status" ORDER BY "status"`,
+			wantArgs: 0,
+		},
+		{
+			name:     "sum with limit - postgres",
+			dialect:  database.DialectPostgres,
+			function: "sum",
+			field:    "total",
+			limit:    10,
+			wantSQL:  `SELECT "status" AS group_key, SUM("total") AS value FROM "orders" GROUP BY "status" ORDER BY "status" LIMIT $1`,
+			wantArgs: 1,
+		},
+		{
+			name:     "avg with filter and limit - postgres",
+			dialect:  database.DialectPostgres,
+			function: "avg",
+			field:    "total",
+			conditions: []Condition{
+				{Column: "quantity", Operator: OpGreaterThan, Value: 1},
+			},
+			limit:    10,
+			wantSQL:  `SELECT "status" AS group_key, AVG("total") AS value FROM "orders" WHERE "quantity" > $1 GROUP BY "status" ORDER BY "status" LIMIT $2`,
+			wantArgs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.dialect)
+			sql, args := builder.GroupBy("orders", "status", tt.function, tt.field, tt.conditions, tt.limit)
+
+			if sql != tt.wantSQL {
+				t.Errorf("GroupBy() sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if len(args) != tt.wantArgs {
+				t.Errorf("GroupBy() args count = %d, want %d", len(args), tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestTimeSeries(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialect    database.DialectType
+		interval   string
+		function   string
+		field      string
+		conditions []Condition
+		wantSQL    string
+	}{
+		{
+			name:     "count per day - sqlite",
+			dialect:  database.DialectSQLite,
+			interval: "day",
+			function: "count",
+			wantSQL:  `SELECT strftime('%Y-%m-%d', "placed_at") AS bucket, COUNT(*) AS value FROM "orders" WHERE "placed_at" IS NOT NULL GROUP BY 1 ORDER BY 1`,
+		},
+		{
+			name:     "sum per week - sqlite",
+			dialect:  database.DialectSQLite,
+			interval: "week",
+			function: "sum",
+			field:    "total",
+			conditions: []Condition{
+				{Column: "status", Operator: OpEqual, Value: "paid"},
+			},
+			wantSQL: `SELECT date("placed_at", '-' || ((CAST(strftime('%w', "placed_at") AS INTEGER) + 6) % 7) || ' days') AS bucket, SUM("total") AS value FROM "orders" WHERE "status" = ? AND "placed_at" IS NOT NULL GROUP BY 1 ORDER BY 1`,
+		},
+		{
+			name:     "avg per hour - postgres",
+			dialect:  database.DialectPostgres,
+			interval: "hour",
+			function: "avg",
+			field:    "total",
+			wantSQL:  `SELECT to_char(date_trunc('hour', "placed_at"), 'YYYY-MM-DD"T"HH24:00:00"Z"') AS bucket, AVG("total") AS value FROM "orders" WHERE "placed_at" IS NOT NULL GROUP BY 1 ORDER BY 1`,
+		},
+		{
+			name:     "count per month - mysql",
+			dialect:  database.DialectMySQL,
+			interval: "month",
+			function: "count",
+			wantSQL:  "SELECT DATE_FORMAT(`placed_at`, '%Y-%m') AS bucket, COUNT(*) AS value FROM `orders` WHERE `placed_at` IS NOT NULL GROUP BY 1 ORDER BY 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.dialect)
+			sql, _ := builder.TimeSeries("orders", "placed_at", tt.interval, tt.function, tt.field, tt.conditions)
+			if sql != tt.wantSQL {
+				t.Errorf("TimeSeries() sql = %v, want %v", sql, tt.wantSQL)
+			}
+		})
+	}
+
+	if err := ValidateTimeInterval("year"); err == nil {
+		t.Error("expected year to be rejected")
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialect    database.DialectType
+		withCount  bool
+		conditions []Condition
+		limit      int
+		wantSQL    string
+		wantArgs   int
+	}{
+		{
+			name:     "values - sqlite",
+			dialect:  database.DialectSQLite,
+			wantSQL:  `SELECT DISTINCT "category" AS value FROM "products" ORDER BY "category"`,
+			wantArgs: 0,
+		},
+		{
+			name:     "values with limit - mysql",
+			dialect:  database.DialectMySQL,
+			limit:    100,
+			wantSQL:  "SELECT DISTINCT `category` AS value FROM `products` ORDER BY `category` LIMIT ?",
+			wantArgs: 1,
+		},
+		{
+			name:      "counts with filter and limit - postgres",
+			dialect:   database.DialectPostgres,
+			withCount: true,
+			conditions: []Condition{
+				{Column: "price", Operator: OpGreaterThan, Value: 10},
+			},
+			limit:    100,
+			wantSQL:  `SELECT "category" AS value, COUNT(*) AS count FROM "products" WHERE "price" > $1 GROUP BY "category" ORDER BY "category" LIMIT $2`,
+			wantArgs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewBuilder(tt.dialect)
+			sql, args := builder.Distinct("products", "category", tt.withCount, tt.conditions, tt.limit)
+
+			if sql != tt.wantSQL {
+				t.Errorf("Distinct() sql = %v, want %v", sql, tt.wantSQL)
+			}
+			if len(args) != tt.wantArgs {
+				t.Errorf("Distinct() args count = %d, want %d", len(args), tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestValidateAggregateFunction(t *testing.T) {
+	for _, fn := range []string{"count", "sum", "avg", "min", "max"} {
+		if err := ValidateAggregateFunction(fn); err != nil {
+			t.Errorf("expected %s to be valid, got %v", fn, err)
+		}
+	}
+	if err := ValidateAggregateFunction("median"); err == nil {
+		t.Error("expected error for unsupported function")
+	}
+}
+
+func TestSelect_JSONPathCondition(t *testing.T) {
+	tests := []struct {
+		dialect database.DialectType
+		path    []string
+		want    string
+	}{
+		{database.DialectSQLite, []string{"color"}, `SELECT * FROM "products" WHERE json_extract("meta", '$.color') = ?`},
+		{database.DialectSQLite, []string{"size", "label"}, `SELECT * FROM "products" WHERE json_extract("meta", '$.size.label') = ?`},
+		{database.DialectPostgres, []string{"color"}, `SELECT * FROM "products" WHERE "meta"->>'color' = $1`},
+		{database.DialectPostgres, []string{"size", "label"}, `SELECT * FROM "products" WHERE "meta"->'size'->>'label' = $1`},
+		{database.DialectMySQL, []string{"size", "label"}, "SELECT * FROM `products` WHERE JSON_UNQUOTE(JSON_EXTRACT(`meta`, '$.size.label')) = ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect)+"/"+strings.Join(tt.path, "."), func(t *testing.T) {
+			where := []Condition{{Column: "meta", Path: tt.path, Operator: OpEqual, Value: "red"}}
+			sql, args := NewBuilder(tt.dialect).Select("products", nil, where, "", 0, 0)
+			if sql != tt.want {
+				t.Errorf("sql = %s, want %s", sql, tt.want)
+			}
+			if len(args) != 1 || args[0] != "red" {
+				t.Errorf("unexpected args %v", args)
+			}
+		})
+	}
+}
+
+func TestSelect_GroupConditions(t *testing.T) {
+	// status = 'active' OR (price > 100 AND stock > 0), then a plain AND condition
+	where := []Condition{
+		{Operator: OpOr, Conditions: []Condition{
+			{Column: "status", Operator: OpEqual, Value: "active"},
+			{Operator: OpAnd, Conditions: []Condition{
+				{Column: "price", Operator: OpGreaterThan, Value: 100},
+				{Column: "stock", Operator: OpIn, Value: []any{1, 2}},
+			}},
+		}},
+		{Column: "title", Operator: OpBetween, Value: []any{"a", "m"}},
+	}
+
+	tests := []struct {
+		dialect database.DialectType
+		want    string
+	}{
+		{database.DialectSQLite, `SELECT * FROM "products" WHERE ("status" = ? OR ("price" > ? AND "stock" IN (?, ?))) AND "title" BETWEEN ? AND ? LIMIT ?`},
+		{database.DialectPostgres, `SELECT * FROM "products" WHERE ("status" = $1 OR ("price" > $2 AND "stock" IN ($3, $4))) AND "title" BETWEEN $5 AND $6 LIMIT $7`},
+		{database.DialectMySQL, "SELECT * FROM `products` WHERE (`status` = ? OR (`price` > ? AND `stock` IN (?, ?))) AND `title` BETWEEN ? AND ? LIMIT ?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			sql, args := NewBuilder(tt.dialect).Select("products", nil, where, "", 10, 0)
+			if sql != tt.want {
+				t.Errorf("sql = %s, want %s", sql, tt.want)
+			}
+			want := []any{"active", 100, 1, 2, "a", "m", 10}
+			if fmt.Sprint(args) != fmt.Sprint(want) {
+				t.Errorf("args = %v, want %v", args, want)
+			}
+		})
+	}
+}
+
+func TestWriteCondition_EmptyGroups(t *testing.T) {
+	for op, want := range map[string]string{OpAnd: "1 = 1", OpOr: "1 = 0"} {
+		var sb strings.Builder
+		args := WriteCondition(&sb, database.DialectSQLite, Condition{Operator: op}, nil)
+		if sb.String() != want || len(args) != 0 {
+			t.Errorf("empty %s group = %q %v, want %q", op, sb.String(), args, want)
+		}
+	}
+
+	// Placeholders continue after the args already bound
+	var sb strings.Builder
+	args := WriteCondition(&sb, database.DialectPostgres, Condition{Operator: OpOr, Conditions: []Condition{
+		{Column: "a", Operator: OpEqual, Value: 1},
+		{Column: "b", Operator: OpIsNull},
+	}}, []any{"x", "y"})
+	if sb.String() != `("a" = $3 OR "b" IS NULL)` || len(args) != 3 {
+		t.Errorf("got %s %v", sb.String(), args)
+	}
+}
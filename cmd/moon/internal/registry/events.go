@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by before
+// further events to it are dropped
+const subscriberBuffer = 64
+
+// SchemaEventType is the kind of change a SchemaEvent reports
+type SchemaEventType string
+
+const (
+	SchemaCreated SchemaEventType = "created"
+	SchemaUpdated SchemaEventType = "updated"
+	SchemaDeleted SchemaEventType = "deleted"
+)
+
+// SchemaEvent reports a change to one collection schema. Collection is a copy
+// of the schema after the change, or of the removed schema for deletions.
+// A rename is reported as the deletion of the old name and the creation of
+// the new one.
+type SchemaEvent struct {
+	Type       SchemaEventType
+	Name       string
+	Collection *Collection
+}
+
+// subscribers holds the channels events are delivered to
+type subscribers struct {
+	mu      sync.RWMutex
+	chans   map[chan SchemaEvent]struct{}
+	dropped atomic.Uint64
+}
+
+// Subscribe returns a channel receiving an event for every change made
+// through Set, Delete, Rename and Clear, and a function that unsubscribes and
+// closes the channel. Events are sent without blocking: when a subscriber has
+// subscriberBuffer events it has not read, later ones are dropped and counted
+// in DroppedEvents, so a slow subscriber never holds up schema changes.
+// Events are sent after the registry is updated and before the changing
+// call returns, so subscribers may read the registry while handling them.
+func (r *SchemaRegistry) Subscribe() (<-chan SchemaEvent, func()) {
+	ch := make(chan SchemaEvent, subscriberBuffer)
+
+	r.subscribers.mu.Lock()
+	if r.subscribers.chans == nil {
+		r.subscribers.chans = make(map[chan SchemaEvent]struct{})
+	}
+	r.subscribers.chans[ch] = struct{}{}
+	r.subscribers.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			r.subscribers.mu.Lock()
+			delete(r.subscribers.chans, ch)
+			r.subscribers.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// DroppedEvents returns the number of events not delivered because a
+// subscriber's buffer was full
+func (r *SchemaRegistry) DroppedEvents() uint64 {
+	return r.subscribers.dropped.Load()
+}
+
+// notify sends an event to every subscriber, each with its own copy of the
+// collection
+func (r *SchemaRegistry) notify(eventType SchemaEventType, collection *Collection) {
+	r.subscribers.mu.RLock()
+	defer r.subscribers.mu.RUnlock()
+
+	for ch := range r.subscribers.chans {
+		event := SchemaEvent{Type: eventType, Name: collection.Name, Collection: copyCollection(collection)}
+		select {
+		case ch <- event:
+		default:
+			r.subscribers.dropped.Add(1)
+		}
+	}
+}
@@ -1,11 +1,15 @@
 // Package registry provides in-memory schema caching for dynamic database
 // management. It maintains a thread-safe registry of collection schemas using
-// sync.Map for zero-latency validation before database operations.
+// sync.Map for zero-latency validation before database operations, and
+// publishes schema changes to subscribers.
 package registry
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
 // ColumnType represents the data type of a column
@@ -20,6 +24,15 @@ const (
 	TypeDecimal  ColumnType = "decimal"
 )
 
+// IDType is the strategy a collection uses for record ids
+type IDType string
+
+const (
+	IDTypeULID   IDType = "ulid"   // server-generated ULIDs (default)
+	IDTypeUUIDv7 IDType = "uuidv7" // server-generated, time-ordered UUIDv7s
+	IDTypeClient IDType = "client" // supplied by the client in the create payload
+)
+
 // Column represents a single column in a collection
 type Column struct {
 	Name         string     `json:"name"`
@@ -27,17 +40,113 @@ type Column struct {
 	Nullable     bool       `json:"nullable"`
 	Unique       bool       `json:"unique"`
 	DefaultValue *string    `json:"default_value,omitempty"`
+
+	// Optional value constraints, enforced on create and update
+	MaxLength *int         `json:"max_length,omitempty"` // string: maximum length in characters
+	Min       *json.Number `json:"min,omitempty"`        // integer, decimal: smallest allowed value
+	Max       *json.Number `json:"max,omitempty"`        // integer, decimal: largest allowed value
+	Enum      []string     `json:"enum,omitempty"`       // string: allowed values
+
+	// Hidden columns are written and filtered on but left out of read responses
+	Hidden bool `json:"hidden,omitempty"`
+
+	// References names the collection whose record ids the column holds
+	References string `json:"references,omitempty"`
+
+	// Computed holds the expression the server computes the column from,
+	// e.g. "price * quantity"; such columns are read-only (see package expr)
+	Computed string `json:"computed,omitempty"`
+}
+
+// Index represents a secondary index over one or more columns
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+}
+
+// Pagination overrides the page sizes of list requests on a collection. A
+// zero limit falls back to the server default.
+type Pagination struct {
+	DefaultLimit int `json:"default_limit,omitempty"` // page size when a request has no limit
+	MaxLimit     int `json:"max_limit,omitempty"`     // largest page a request may ask for
+}
+
+// TTL expires the records of a collection. Field names a datetime column
+// holding the expiry of each record; otherwise Duration is the lifetime of a
+// record, counted from the datetime column From. Records whose column is NULL
+// never expire.
+type TTL struct {
+	Field    string `json:"field,omitempty"`    // datetime column holding the expiry of each record
+	Duration string `json:"duration,omitempty"` // lifetime of a record as a Go duration, e.g. "720h"
+	From     string `json:"from,omitempty"`     // datetime column Duration counts from
+}
+
+// Cutoff returns the column deciding whether a record has expired at now,
+// and the cutoff: records whose column is at or before it have expired
+func (t *TTL) Cutoff(now time.Time) (string, time.Time) {
+	if t.Field != "" {
+		return t.Field, now
+	}
+	duration, _ := time.ParseDuration(t.Duration)
+	return t.From, now.Add(-duration)
+}
+
+// UI is presentation metadata for admin interfaces generating forms from a
+// collection: the order its columns are shown in and labelled groups of
+// columns. It is stored and returned with the schema; reads and writes of
+// records ignore it.
+type UI struct {
+	Order  []string  `json:"order,omitempty"`  // columns in display order; those not listed follow
+	Groups []UIGroup `json:"groups,omitempty"` // labelled groups of columns
+}
+
+// UIGroup is a labelled group of columns of the presentation metadata
+type UIGroup struct {
+	Label   string   `json:"label"`
+	Columns []string `json:"columns"`
 }
 
 // Collection represents a database table schema
 type Collection struct {
-	Name    string   `json:"name"`
-	Columns []Column `json:"columns"`
+	Name            string      `json:"name"`
+	Columns         []Column    `json:"columns"`
+	Indexes         []Index     `json:"indexes,omitempty"`
+	SoftDelete      bool        `json:"soft_delete,omitempty"`
+	RequireRevision bool        `json:"require_revision,omitempty"`
+	ExposeSequence  bool        `json:"expose_sequence,omitempty"` // pkid is readable, filterable and sortable as seq
+	IDType          IDType      `json:"id_type,omitempty"`         // record id strategy; empty means ulid
+	DefaultSort     []string    `json:"default_sort,omitempty"`    // sort applied when a list request has no sort parameter
+	DefaultFields   []string    `json:"default_fields,omitempty"`  // fields applied when a list request has no fields parameter
+	Pagination      *Pagination `json:"pagination,omitempty"`      // page sizes of list requests; nil uses the server defaults
+	Archived        bool        `json:"archived,omitempty"`        // detached from the data endpoints; the table and its records are kept
+	TTL             *TTL        `json:"ttl,omitempty"`             // expiry of records; nil keeps them until deleted
+	UI              *UI         `json:"ui,omitempty"`              // presentation metadata; nil when unset
+}
+
+// RecordIDType returns the id strategy of the collection, ulid when unset
+func (c *Collection) RecordIDType() IDType {
+	if c.IDType == "" {
+		return IDTypeULID
+	}
+	return c.IDType
+}
+
+// Store persists collection schemas so they survive restarts
+type Store interface {
+	// Save inserts or replaces the persisted schema of a collection
+	Save(collection *Collection) error
+
+	// Delete removes the persisted schema of a collection
+	Delete(name string) error
 }
 
 // SchemaRegistry manages the in-memory cache of collection schemas
 type SchemaRegistry struct {
 	collections sync.Map // map[string]*Collection
+	locks       sync.Map // map[string]*sync.Mutex
+	store       Store
+	subscribers subscribers
 }
 
 // NewSchemaRegistry creates a new schema registry
@@ -45,6 +154,23 @@ func NewSchemaRegistry() *SchemaRegistry {
 	return &SchemaRegistry{}
 }
 
+// SetStore attaches a persistent store. From then on Set, Delete and Rename
+// write to the store before changing the in-memory entry, and leave the entry
+// unchanged when the store fails. It must be called before the registry is
+// shared between goroutines.
+func (r *SchemaRegistry) SetStore(store Store) {
+	r.store = store
+}
+
+// lock serializes writes to one collection so the in-memory and persisted
+// schemas are always updated in the same order
+func (r *SchemaRegistry) lock(name string) func() {
+	value, _ := r.locks.LoadOrStore(name, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
 // Set stores or updates a collection schema in the registry
 func (r *SchemaRegistry) Set(collection *Collection) error {
 	if collection == nil {
@@ -56,15 +182,22 @@ func (r *SchemaRegistry) Set(collection *Collection) error {
 	}
 
 	// Store a copy to prevent external modifications
-	copy := &Collection{
-		Name:    collection.Name,
-		Columns: make([]Column, len(collection.Columns)),
+	copied := copyCollection(collection)
+
+	unlock := r.lock(collection.Name)
+	defer unlock()
+
+	if r.store != nil {
+		if err := r.store.Save(copied); err != nil {
+			return fmt.Errorf("failed to persist collection '%s': %w", collection.Name, err)
+		}
 	}
-	for i, col := range collection.Columns {
-		copy.Columns[i] = col
+	_, existed := r.collections.Swap(collection.Name, copied)
+	if existed {
+		r.notify(SchemaUpdated, copied)
+	} else {
+		r.notify(SchemaCreated, copied)
 	}
-
-	r.collections.Store(collection.Name, copy)
 	return nil
 }
 
@@ -82,15 +215,51 @@ func (r *SchemaRegistry) Get(name string) (*Collection, bool) {
 	}
 
 	// Return a copy to prevent external modifications
-	copy := &Collection{
-		Name:    collection.Name,
-		Columns: make([]Column, len(collection.Columns)),
+	return copyCollection(collection), true
+}
+
+// copyCollection returns a deep copy of a collection schema
+func copyCollection(collection *Collection) *Collection {
+	copied := &Collection{
+		Name:            collection.Name,
+		Columns:         make([]Column, len(collection.Columns)),
+		SoftDelete:      collection.SoftDelete,
+		RequireRevision: collection.RequireRevision,
+		ExposeSequence:  collection.ExposeSequence,
+		IDType:          collection.IDType,
+		DefaultSort:     append([]string(nil), collection.DefaultSort...),
+		DefaultFields:   append([]string(nil), collection.DefaultFields...),
+		Archived:        collection.Archived,
 	}
-	for i, col := range collection.Columns {
-		copy.Columns[i] = col
+	if collection.Pagination != nil {
+		pagination := *collection.Pagination
+		copied.Pagination = &pagination
 	}
-
-	return copy, true
+	if collection.TTL != nil {
+		ttl := *collection.TTL
+		copied.TTL = &ttl
+	}
+	if collection.UI != nil {
+		copied.UI = &UI{Order: append([]string(nil), collection.UI.Order...)}
+		for _, group := range collection.UI.Groups {
+			copied.UI.Groups = append(copied.UI.Groups, UIGroup{Label: group.Label, Columns: append([]string(nil), group.Columns...)})
+		}
+	}
+	copy(copied.Columns, collection.Columns)
+	for i := range copied.Columns {
+		copied.Columns[i].Enum = append([]string(nil), collection.Columns[i].Enum...)
+	}
+	if len(collection.Indexes) > 0 {
+		copied.Indexes = make([]Index, len(collection.Indexes))
+		for i, idx := range collection.Indexes {
+			copied.Indexes[i] = Index{
+				Name:    idx.Name,
+				Columns: append([]string(nil), idx.Columns...),
+				Unique:  idx.Unique,
+			}
+		}
+	}
+	return copied
 }
 
 // Delete removes a collection schema from the registry
@@ -99,7 +268,66 @@ func (r *SchemaRegistry) Delete(name string) error {
 		return fmt.Errorf("collection name cannot be empty")
 	}
 
-	r.collections.Delete(name)
+	unlock := r.lock(name)
+	defer unlock()
+
+	if r.store != nil {
+		if err := r.store.Delete(name); err != nil {
+			return fmt.Errorf("failed to remove persisted collection '%s': %w", name, err)
+		}
+	}
+	if value, ok := r.collections.LoadAndDelete(name); ok {
+		r.notify(SchemaDeleted, value.(*Collection))
+	}
+	return nil
+}
+
+// Rename moves a collection schema to a new name. The new entry is stored
+// before the old one is removed, so concurrent readers always find the
+// collection under at least one of the names. It fails if the old name is
+// missing or the new name is taken.
+func (r *SchemaRegistry) Rename(oldName, newName string) error {
+	if oldName == "" || newName == "" {
+		return fmt.Errorf("collection name cannot be empty")
+	}
+	if oldName == newName {
+		return fmt.Errorf("collection '%s' already exists", newName)
+	}
+
+	// Lock both names in a fixed order to avoid deadlocks with a reverse rename
+	first, second := oldName, newName
+	if second < first {
+		first, second = second, first
+	}
+	unlockFirst := r.lock(first)
+	defer unlockFirst()
+	unlockSecond := r.lock(second)
+	defer unlockSecond()
+
+	value, ok := r.collections.Load(oldName)
+	if !ok {
+		return fmt.Errorf("collection '%s' not found", oldName)
+	}
+	if _, exists := r.collections.Load(newName); exists {
+		return fmt.Errorf("collection '%s' already exists", newName)
+	}
+
+	renamed := copyCollection(value.(*Collection))
+	renamed.Name = newName
+	if r.store != nil {
+		if err := r.store.Save(renamed); err != nil {
+			return fmt.Errorf("failed to persist collection '%s': %w", newName, err)
+		}
+		if err := r.store.Delete(oldName); err != nil {
+			r.store.Delete(newName)
+			return fmt.Errorf("failed to remove persisted collection '%s': %w", oldName, err)
+		}
+	}
+
+	r.collections.Store(newName, renamed)
+	r.collections.Delete(oldName)
+	r.notify(SchemaDeleted, value.(*Collection))
+	r.notify(SchemaCreated, renamed)
 	return nil
 }
 
@@ -129,25 +357,39 @@ func (r *SchemaRegistry) GetAll() []*Collection {
 		collection := value.(*Collection)
 
 		// Return a copy to prevent external modifications
-		copy := &Collection{
-			Name:    collection.Name,
-			Columns: make([]Column, len(collection.Columns)),
-		}
-		for i, col := range collection.Columns {
-			copy.Columns[i] = col
-		}
-
-		collections = append(collections, copy)
+		collections = append(collections, copyCollection(collection))
 		return true
 	})
 
 	return collections
 }
 
-// Clear removes all collections from the registry
+// Referencing returns the collections other than name with a column that
+// references name, sorted by name
+func (r *SchemaRegistry) Referencing(name string) []*Collection {
+	var referencing []*Collection
+	for _, collection := range r.GetAll() {
+		if collection.Name == name {
+			continue
+		}
+		for _, col := range collection.Columns {
+			if col.References == name {
+				referencing = append(referencing, collection)
+				break
+			}
+		}
+	}
+	sort.Slice(referencing, func(i, j int) bool { return referencing[i].Name < referencing[j].Name })
+	return referencing
+}
+
+// Clear removes all collections from the registry. The persistent store,
+// if any, is left untouched.
 func (r *SchemaRegistry) Clear() {
 	r.collections.Range(func(key, value any) bool {
-		r.collections.Delete(key)
+		if value, ok := r.collections.LoadAndDelete(key); ok {
+			r.notify(SchemaDeleted, value.(*Collection))
+		}
 		return true
 	})
 }
@@ -2,8 +2,11 @@ package registry
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewSchemaRegistry(t *testing.T) {
@@ -129,6 +132,89 @@ func TestSchemaRegistry_Delete_EmptyName(t *testing.T) {
 	}
 }
 
+func TestSchemaRegistry_Rename(t *testing.T) {
+	registry := NewSchemaRegistry()
+	registry.Set(&Collection{Name: "products", Columns: []Column{{Name: "name", Type: TypeString}}})
+	registry.Set(&Collection{Name: "orders"})
+
+	if err := registry.Rename("products", "catalog"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if registry.Exists("products") {
+		t.Error("Old name should not exist after rename")
+	}
+	renamed, ok := registry.Get("catalog")
+	if !ok || renamed.Name != "catalog" || len(renamed.Columns) != 1 {
+		t.Errorf("Expected renamed collection with its columns, got %+v", renamed)
+	}
+
+	if err := registry.Rename("catalog", "orders"); err == nil {
+		t.Error("Expected error when the new name is taken")
+	}
+	if !registry.Exists("catalog") {
+		t.Error("Failed rename should keep the collection")
+	}
+	if err := registry.Rename("missing", "other"); err == nil {
+		t.Error("Expected error for a missing collection")
+	}
+}
+
+// failingStore is a Store whose writes fail once fail is set
+type failingStore struct {
+	saved map[string]bool
+	fail  bool
+}
+
+func (s *failingStore) Save(collection *Collection) error {
+	if s.fail {
+		return fmt.Errorf("store unavailable")
+	}
+	s.saved[collection.Name] = true
+	return nil
+}
+
+func (s *failingStore) Delete(name string) error {
+	if s.fail {
+		return fmt.Errorf("store unavailable")
+	}
+	delete(s.saved, name)
+	return nil
+}
+
+func TestSchemaRegistry_Store(t *testing.T) {
+	store := &failingStore{saved: map[string]bool{}}
+	registry := NewSchemaRegistry()
+	registry.SetStore(store)
+
+	if err := registry.Set(&Collection{Name: "products"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := registry.Rename("products", "catalog"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if !store.saved["catalog"] || store.saved["products"] {
+		t.Errorf("expected the store to follow the rename, got %v", store.saved)
+	}
+
+	// Failed writes leave the in-memory registry unchanged
+	store.fail = true
+	if err := registry.Set(&Collection{Name: "orders"}); err == nil {
+		t.Error("expected Set() to fail when the store fails")
+	}
+	if registry.Exists("orders") {
+		t.Error("collection should not be registered when persisting fails")
+	}
+	if err := registry.Delete("catalog"); err == nil {
+		t.Error("expected Delete() to fail when the store fails")
+	}
+	if err := registry.Rename("catalog", "products"); err == nil {
+		t.Error("expected Rename() to fail when the store fails")
+	}
+	if !registry.Exists("catalog") || registry.Exists("products") {
+		t.Errorf("failed writes should keep the collection, got %v", registry.List())
+	}
+}
+
 func TestSchemaRegistry_Exists(t *testing.T) {
 	registry := NewSchemaRegistry()
 
@@ -208,6 +294,26 @@ func TestSchemaRegistry_GetAll(t *testing.T) {
 	}
 }
 
+func TestSchemaRegistry_Referencing(t *testing.T) {
+	registry := NewSchemaRegistry()
+	customerID := Column{Name: "customer_id", Type: TypeString, References: "customers"}
+	registry.Set(&Collection{Name: "customers", Columns: []Column{{Name: "referrer_id", Type: TypeString, References: "customers"}}})
+	registry.Set(&Collection{Name: "orders", Columns: []Column{customerID}})
+	registry.Set(&Collection{Name: "invoices", Columns: []Column{customerID, {Name: "billing_id", Type: TypeString, References: "customers"}}})
+	registry.Set(&Collection{Name: "products", Columns: []Column{{Name: "name", Type: TypeString}}})
+
+	var names []string
+	for _, collection := range registry.Referencing("customers") {
+		names = append(names, collection.Name)
+	}
+	if len(names) != 2 || names[0] != "invoices" || names[1] != "orders" {
+		t.Errorf("Expected invoices and orders, got %v", names)
+	}
+	if referencing := registry.Referencing("products"); len(referencing) != 0 {
+		t.Errorf("Expected no collection to reference products, got %d", len(referencing))
+	}
+}
+
 func TestSchemaRegistry_Clear(t *testing.T) {
 	registry := NewSchemaRegistry()
 
@@ -355,6 +461,9 @@ func TestSchemaRegistry_ImmutableCopy(t *testing.T) {
 		Columns: []Column{
 			{Name: "id", Type: TypeInteger},
 		},
+		Indexes: []Index{
+			{Name: "idx_users_id", Columns: []string{"id"}},
+		},
 	}
 
 	registry.Set(original)
@@ -362,6 +471,7 @@ func TestSchemaRegistry_ImmutableCopy(t *testing.T) {
 	// Modify original after Set
 	original.Name = "modified"
 	original.Columns[0].Name = "modified_id"
+	original.Indexes[0].Columns[0] = "modified_id"
 
 	// Retrieved should not be affected
 	retrieved, _ := registry.Get("users")
@@ -371,10 +481,14 @@ func TestSchemaRegistry_ImmutableCopy(t *testing.T) {
 	if retrieved.Columns[0].Name != "id" {
 		t.Errorf("Expected column name 'id', got '%s'", retrieved.Columns[0].Name)
 	}
+	if retrieved.Indexes[0].Columns[0] != "id" {
+		t.Errorf("Expected index column 'id', got '%s'", retrieved.Indexes[0].Columns[0])
+	}
 
 	// Modify retrieved should not affect registry
 	retrieved.Name = "modified_again"
 	retrieved.Columns[0].Name = "modified_id_again"
+	retrieved.Indexes[0].Name = "modified_index"
 
 	retrieved2, _ := registry.Get("users")
 	if retrieved2.Name != "users" {
@@ -383,6 +497,9 @@ func TestSchemaRegistry_ImmutableCopy(t *testing.T) {
 	if retrieved2.Columns[0].Name != "id" {
 		t.Errorf("Expected column name 'id', got '%s'", retrieved2.Columns[0].Name)
 	}
+	if retrieved2.Indexes[0].Name != "idx_users_id" {
+		t.Errorf("Expected index name 'idx_users_id', got '%s'", retrieved2.Indexes[0].Name)
+	}
 }
 
 // Benchmark tests
@@ -452,3 +569,200 @@ func BenchmarkSchemaRegistry_ConcurrentReads(b *testing.B) {
 		}
 	})
 }
+
+func TestSchemaRegistry_TenantKeys(t *testing.T) {
+	registry := NewSchemaRegistry()
+	for _, key := range []string{"products", TenantKey("acme", "products"), TenantKey("acme", "orders"), TenantKey("globex", "products")} {
+		if err := registry.Set(&Collection{Name: key}); err != nil {
+			t.Fatalf("Set(%q) error = %v", key, err)
+		}
+	}
+
+	if key := TenantKey("acme", "products"); key != "acme__products" {
+		t.Errorf("TenantKey() = %q, want acme__products", key)
+	}
+	if tenant, name := SplitTenantKey("acme__order_items"); tenant != "acme" || name != "order_items" {
+		t.Errorf("SplitTenantKey() = %q, %q", tenant, name)
+	}
+
+	acme := registry.ListTenant("acme")
+	sort.Strings(acme)
+	if fmt.Sprint(acme) != "[orders products]" {
+		t.Errorf("ListTenant(acme) = %v", acme)
+	}
+	if shared := registry.ListTenant(""); fmt.Sprint(shared) != "[products]" {
+		t.Errorf("ListTenant(\"\") = %v", shared)
+	}
+}
+
+// receive reads the next event from a subscription, failing after a second
+func receive(t *testing.T, events <-chan SchemaEvent) SchemaEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return SchemaEvent{}
+}
+
+func TestSchemaRegistry_Subscribe(t *testing.T) {
+	registry := NewSchemaRegistry()
+	first, unsubscribeFirst := registry.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := registry.Subscribe()
+	defer unsubscribeSecond()
+
+	registry.Set(&Collection{Name: "users", Columns: []Column{{Name: "email", Type: TypeString}}})
+	registry.Set(&Collection{Name: "users", Columns: []Column{{Name: "email", Type: TypeString}, {Name: "age", Type: TypeInteger}}})
+	registry.Rename("users", "people")
+	registry.Delete("people")
+	registry.Delete("missing")
+	registry.Set(&Collection{Name: "logs", Columns: []Column{}})
+	registry.Clear()
+
+	want := []struct {
+		eventType SchemaEventType
+		name      string
+		columns   int
+	}{
+		{SchemaCreated, "users", 1},
+		{SchemaUpdated, "users", 2},
+		{SchemaDeleted, "users", 2},
+		{SchemaCreated, "people", 2},
+		{SchemaDeleted, "people", 2},
+		{SchemaCreated, "logs", 0},
+		{SchemaDeleted, "logs", 0},
+	}
+	for _, events := range []<-chan SchemaEvent{first, second} {
+		for _, w := range want {
+			event := receive(t, events)
+			if event.Type != w.eventType || event.Name != w.name || event.Collection == nil ||
+				event.Collection.Name != w.name || len(event.Collection.Columns) != w.columns {
+				t.Errorf("Expected %s %s with %d columns, got %+v", w.eventType, w.name, w.columns, event)
+			}
+		}
+		select {
+		case event := <-events:
+			t.Errorf("Expected no more events, got %+v", event)
+		default:
+		}
+	}
+
+	// Each subscriber gets its own snapshot
+	registry.Set(&Collection{Name: "orders", Columns: []Column{{Name: "total", Type: TypeInteger}}})
+	a, b := receive(t, first), receive(t, second)
+	a.Collection.Columns[0].Name = "changed"
+	if b.Collection.Columns[0].Name != "total" {
+		t.Error("Expected subscribers not to share collection snapshots")
+	}
+	if got, _ := registry.Get("orders"); got.Columns[0].Name != "total" {
+		t.Error("Expected the registry unaffected by changes to a snapshot")
+	}
+
+	// Failed writes send nothing
+	registry.SetStore(&failingStore{saved: map[string]bool{}, fail: true})
+	registry.Set(&Collection{Name: "orders", Columns: []Column{}})
+	select {
+	case event := <-first:
+		t.Errorf("Expected no event for a failed write, got %+v", event)
+	default:
+	}
+}
+
+func TestSchemaRegistry_Unsubscribe(t *testing.T) {
+	registry := NewSchemaRegistry()
+	events, unsubscribe := registry.Subscribe()
+	kept, unsubscribeKept := registry.Subscribe()
+	defer unsubscribeKept()
+
+	unsubscribe()
+	unsubscribe() // safe to call twice
+
+	if _, ok := <-events; ok {
+		t.Error("Expected the channel closed after unsubscribing")
+	}
+	registry.Set(&Collection{Name: "users", Columns: []Column{}})
+	if event := receive(t, kept); event.Name != "users" {
+		t.Errorf("Expected the remaining subscriber to get users, got %+v", event)
+	}
+	if n := len(registry.subscribers.chans); n != 1 {
+		t.Errorf("Expected one subscriber left, got %d", n)
+	}
+}
+
+func TestSchemaRegistry_SlowSubscriber(t *testing.T) {
+	registry := NewSchemaRegistry()
+	_, unsubscribe := registry.Subscribe()
+	defer unsubscribe()
+
+	// Nobody reads: writes still complete and the overflow is counted
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBuffer+10; i++ {
+			registry.Set(&Collection{Name: fmt.Sprintf("collection_%d", i), Columns: []Column{}})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected writes not to block on a full subscriber")
+	}
+	if dropped := registry.DroppedEvents(); dropped != 10 {
+		t.Errorf("Expected 10 dropped events, got %d", dropped)
+	}
+}
+
+func TestSchemaRegistry_SubscriberReadsRegistry(t *testing.T) {
+	registry := NewSchemaRegistry()
+	events, unsubscribe := registry.Subscribe()
+
+	// The subscriber reads and writes the registry while handling events,
+	// while other goroutines keep changing it
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		for event := range events {
+			registry.Get(event.Name)
+			registry.GetAll()
+			if event.Type == SchemaCreated && strings.HasPrefix(event.Name, "collection_") {
+				registry.Set(&Collection{Name: "copy_" + event.Name, Columns: []Column{}})
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			name := fmt.Sprintf("collection_%d", index)
+			registry.Set(&Collection{Name: name, Columns: []Column{}})
+			registry.Rename(name, "renamed_"+name)
+			registry.Delete("renamed_" + name)
+		}(i)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected schema changes not to deadlock with a subscriber using the registry")
+	}
+
+	unsubscribe()
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the subscriber to stop after unsubscribing")
+	}
+}
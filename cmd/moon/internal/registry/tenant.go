@@ -0,0 +1,37 @@
+package registry
+
+import (
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+// TenantKey returns the registry key, which is also the physical table name,
+// of a tenant's collection. Collections without a tenant keep their name.
+func TenantKey(tenant, name string) string {
+	if tenant == "" {
+		return name
+	}
+	return tenant + constants.TenantSeparator + name
+}
+
+// SplitTenantKey splits a registry key into its tenant and logical collection
+// name. Keys without the tenant separator belong to no tenant.
+func SplitTenantKey(key string) (tenant, name string) {
+	if tenant, name, ok := strings.Cut(key, constants.TenantSeparator); ok {
+		return tenant, name
+	}
+	return "", key
+}
+
+// ListTenant returns the logical names of the collections owned by a tenant.
+// An empty tenant lists the collections that belong to no tenant.
+func (r *SchemaRegistry) ListTenant(tenant string) []string {
+	var names []string
+	for _, key := range r.List() {
+		if owner, name := SplitTenantKey(key); owner == tenant {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -2,6 +2,9 @@
 package schema
 
 import (
+	"encoding/json"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 )
 
@@ -13,14 +16,32 @@ type FieldSchema struct {
 	Readonly    bool   `json:"readonly,omitempty"`
 	Default     *any   `json:"default,omitempty"`
 	Description string `json:"description,omitempty"`
+
+	// Value constraints of the column, see registry.Column
+	MaxLength *int         `json:"max_length,omitempty"`
+	Min       *json.Number `json:"min,omitempty"`
+	Max       *json.Number `json:"max,omitempty"`
+	Enum      []string     `json:"enum,omitempty"`
+
+	// Hidden marks a column left out of read responses
+	Hidden bool `json:"hidden,omitempty"`
+
+	// References names the collection whose record ids the field holds, so
+	// clients can join the two
+	References string `json:"references,omitempty"`
+
+	// Computed holds the expression of a read-only computed field
+	Computed string `json:"computed,omitempty"`
 }
 
 // Schema represents the complete schema metadata for a resource
 type Schema struct {
-	Collection string        `json:"collection"`
-	Fields     []FieldSchema `json:"fields"`
-	PrimaryKey string        `json:"primary_key"`
-	Metadata   *Metadata     `json:"metadata,omitempty"`
+	Collection string           `json:"collection"`
+	Fields     []FieldSchema    `json:"fields"`
+	Indexes    []registry.Index `json:"indexes,omitempty"`
+	PrimaryKey string           `json:"primary_key"`
+	IDType     registry.IDType  `json:"id_type"`
+	Metadata   *Metadata        `json:"metadata,omitempty"`
 }
 
 // Metadata contains system-generated field information
@@ -42,32 +63,42 @@ func (b *Builder) FromCollection(collection *registry.Collection) *Schema {
 	schema := &Schema{
 		Collection: collection.Name,
 		Fields:     make([]FieldSchema, 0, len(collection.Columns)),
-		PrimaryKey: "id", // All collections use 'id' (ulid) as primary key
+		Indexes:    collection.Indexes,
+		PrimaryKey: "id", // All collections use 'id' as primary key
+		IDType:     collection.RecordIDType(),
 		Metadata: &Metadata{
 			CreatedAt: "datetime",
 			UpdatedAt: "datetime",
 		},
 	}
 
-	// Add the primary key field first
+	// Add the primary key field first; only client-supplied ids are written
 	schema.Fields = append(schema.Fields, FieldSchema{
 		Name:     "id",
 		Type:     "string",
 		Nullable: false,
-		Readonly: true,
+		Readonly: collection.RecordIDType() != registry.IDTypeClient,
 	})
 
 	// Add all other fields, excluding internal system columns (id, ulid)
 	for _, col := range collection.Columns {
 		// Skip internal system columns - they should never be exposed
-		if col.Name == "id" || col.Name == "ulid" {
+		if col.Name == "id" || col.Name == "ulid" || col.Name == constants.CreatedAtColumn || col.Name == constants.UpdatedAtColumn || col.Name == constants.RevisionColumn {
 			continue
 		}
 
 		fieldSchema := FieldSchema{
-			Name:     col.Name,
-			Type:     string(col.Type),
-			Nullable: col.Nullable,
+			Name:       col.Name,
+			Type:       string(col.Type),
+			Nullable:   col.Nullable,
+			MaxLength:  col.MaxLength,
+			Min:        col.Min,
+			Max:        col.Max,
+			Enum:       col.Enum,
+			Hidden:     col.Hidden,
+			References: col.References,
+			Readonly:   col.Computed != "",
+			Computed:   col.Computed,
 		}
 
 		// Only show default value for nullable fields
@@ -79,6 +110,44 @@ func (b *Builder) FromCollection(collection *registry.Collection) *Schema {
 		schema.Fields = append(schema.Fields, fieldSchema)
 	}
 
+	// Record timestamps are maintained by the server and exposed as read-only
+	for _, name := range []string{constants.CreatedAtColumn, constants.UpdatedAtColumn} {
+		schema.Fields = append(schema.Fields, FieldSchema{
+			Name:     name,
+			Type:     string(registry.TypeDatetime),
+			Nullable: true,
+			Readonly: true,
+		})
+	}
+
+	// The record revision is incremented by the server on every update
+	schema.Fields = append(schema.Fields, FieldSchema{
+		Name:     constants.RevisionColumn,
+		Type:     string(registry.TypeInteger),
+		Nullable: false,
+		Readonly: true,
+	})
+
+	// Collections with expose_sequence read the auto-increment pkid as seq
+	if collection.ExposeSequence {
+		schema.Fields = append(schema.Fields, FieldSchema{
+			Name:     constants.SequenceField,
+			Type:     string(registry.TypeInteger),
+			Nullable: false,
+			Readonly: true,
+		})
+	}
+
+	// Soft-delete collections expose the system deleted_at timestamp as read-only
+	if collection.SoftDelete {
+		schema.Fields = append(schema.Fields, FieldSchema{
+			Name:     constants.SoftDeleteColumn,
+			Type:     string(registry.TypeDatetime),
+			Nullable: true,
+			Readonly: true,
+		})
+	}
+
 	return schema
 }
 
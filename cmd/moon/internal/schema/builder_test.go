@@ -23,9 +23,9 @@ func TestFromCollection_FiltersInternalColumns(t *testing.T) {
 
 		schema := builder.FromCollection(collection)
 
-		// Verify that schema has exactly 3 fields (id from builder + name + price)
-		if len(schema.Fields) != 3 {
-			t.Errorf("Expected 3 fields, got %d", len(schema.Fields))
+		// Verify that schema has exactly 6 fields (id from builder + name + price + timestamps + _rev)
+		if len(schema.Fields) != 6 {
+			t.Errorf("Expected 6 fields, got %d", len(schema.Fields))
 		}
 
 		// Verify the first field is 'id' (string, non-nullable) - the external identifier
@@ -86,9 +86,9 @@ func TestFromCollection_FiltersInternalColumns(t *testing.T) {
 
 		schema := builder.FromCollection(collection)
 
-		// Should have 3 fields: id + username + email
-		if len(schema.Fields) != 3 {
-			t.Errorf("Expected 3 fields, got %d", len(schema.Fields))
+		// Should have 6 fields: id + username + email + created_at + updated_at + _rev
+		if len(schema.Fields) != 6 {
+			t.Errorf("Expected 6 fields, got %d", len(schema.Fields))
 		}
 
 		// First field should be 'id'
@@ -105,7 +105,7 @@ func TestFromCollection_FiltersInternalColumns(t *testing.T) {
 			fieldNames[field.Name] = true
 		}
 
-		expectedFields := []string{"id", "username", "email"}
+		expectedFields := []string{"id", "username", "email", "created_at", "updated_at", "_rev"}
 		for _, expected := range expectedFields {
 			if !fieldNames[expected] {
 				t.Errorf("Expected field '%s' in schema", expected)
@@ -124,9 +124,9 @@ func TestFromCollection_FiltersInternalColumns(t *testing.T) {
 
 		schema := builder.FromCollection(collection)
 
-		// Should have only 1 field: the external 'id' from the builder
-		if len(schema.Fields) != 1 {
-			t.Errorf("Expected 1 field, got %d", len(schema.Fields))
+		// Should have only the system fields: the external 'id', the timestamps and _rev
+		if len(schema.Fields) != 4 {
+			t.Errorf("Expected 4 fields, got %d", len(schema.Fields))
 		}
 
 		if schema.Fields[0].Name != "id" || schema.Fields[0].Type != "string" {
@@ -152,6 +152,34 @@ func TestFromCollection_FiltersInternalColumns(t *testing.T) {
 			t.Errorf("Expected primary_key to be 'id', got '%s'", schema.PrimaryKey)
 		}
 	})
+
+	// Test case 5: Record timestamps are exposed as read-only datetime fields
+	t.Run("timestamps_are_readonly", func(t *testing.T) {
+		collection := &registry.Collection{
+			Name: "orders",
+			Columns: []registry.Column{
+				{Name: "total", Type: registry.TypeInteger, Nullable: false},
+			},
+		}
+
+		schema := builder.FromCollection(collection)
+
+		for _, name := range []string{"created_at", "updated_at"} {
+			var found *FieldSchema
+			for i := range schema.Fields {
+				if schema.Fields[i].Name == name {
+					found = &schema.Fields[i]
+				}
+			}
+			if found == nil {
+				t.Errorf("Expected '%s' field in schema", name)
+				continue
+			}
+			if found.Type != "datetime" || !found.Readonly {
+				t.Errorf("Expected '%s' to be a read-only datetime, got %+v", name, *found)
+			}
+		}
+	})
 }
 
 func TestFromCollection_PreservesFieldProperties(t *testing.T) {
@@ -215,3 +243,45 @@ func TestFromCollection_PreservesFieldProperties(t *testing.T) {
 		}
 	}
 }
+
+func TestFromCollection_IncludesIndexes(t *testing.T) {
+	collection := &registry.Collection{
+		Name: "stock",
+		Columns: []registry.Column{
+			{Name: "tenant_id", Type: registry.TypeString},
+			{Name: "sku", Type: registry.TypeString},
+		},
+		Indexes: []registry.Index{
+			{Name: "idx_tenant_sku", Columns: []string{"tenant_id", "sku"}, Unique: true},
+		},
+	}
+
+	schema := NewBuilder().FromCollection(collection)
+	if len(schema.Indexes) != 1 || schema.Indexes[0].Name != "idx_tenant_sku" || !schema.Indexes[0].Unique {
+		t.Errorf("Expected idx_tenant_sku in schema, got %+v", schema.Indexes)
+	}
+}
+
+func TestFromCollection_MarksComputedFields(t *testing.T) {
+	collection := &registry.Collection{
+		Name: "orders",
+		Columns: []registry.Column{
+			{Name: "price", Type: registry.TypeDecimal, Nullable: true},
+			{Name: "total", Type: registry.TypeDecimal, Nullable: true, Computed: "price * 2"},
+		},
+	}
+
+	schema := NewBuilder().FromCollection(collection)
+	for _, field := range schema.Fields {
+		switch field.Name {
+		case "price":
+			if field.Readonly || field.Computed != "" {
+				t.Errorf("Expected price to be a plain field, got %+v", field)
+			}
+		case "total":
+			if !field.Readonly || field.Computed != "price * 2" {
+				t.Errorf("Expected total to be read-only and computed as price * 2, got %+v", field)
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// JSONSchemaDialect is the JSON Schema draft of the documents built by ToJSONSchema
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema document or subschema. Only the keywords Moon
+// emits are modeled; Type is a type name or a list of them.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 any                    `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Minimum              *json.Number           `json:"minimum,omitempty"`
+	Maximum              *json.Number           `json:"maximum,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	AnyOf                []*JSONSchema          `json:"anyOf,omitempty"`
+	ReadOnly             bool                   `json:"readOnly,omitempty"`
+}
+
+// jsonDocumentTypes are the values a non-null json column accepts
+var jsonDocumentTypes = []string{"object", "array", "number", "boolean", "string"}
+
+// ToJSONSchema generates a draft 2020-12 JSON Schema of the data a client
+// sends to create a record. It mirrors the validation of the data handlers:
+// system fields are left out and unknown properties rejected, non-nullable
+// columns are required and column constraints carried over. Decimal bounds
+// cannot be expressed on decimal strings and are only checked by the server.
+func (b *Builder) ToJSONSchema(collection *registry.Collection) *JSONSchema {
+	closed := false
+	doc := &JSONSchema{
+		Schema:               JSONSchemaDialect,
+		Title:                collection.Name,
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema, len(collection.Columns)+1),
+		Required:             []string{},
+		AdditionalProperties: &closed,
+	}
+
+	// Generated ids are read-only; client ids are part of the payload
+	id := &JSONSchema{Type: "string"}
+	switch collection.RecordIDType() {
+	case registry.IDTypeClient:
+		maxLength := constants.MaxClientIDLength
+		id.Pattern = constants.ClientIDPattern
+		id.MaxLength = &maxLength
+		doc.Required = append(doc.Required, "id")
+	case registry.IDTypeUUIDv7:
+		id.Pattern = constants.UUIDv7Pattern
+		id.ReadOnly = true
+	default:
+		id.Pattern = constants.ULIDPattern
+		id.ReadOnly = true
+	}
+	doc.Properties["id"] = id
+
+	for _, col := range collection.Columns {
+		// Skip internal system columns, as FromCollection does
+		if col.Name == "id" || col.Name == "ulid" || col.Name == constants.CreatedAtColumn || col.Name == constants.UpdatedAtColumn || col.Name == constants.RevisionColumn {
+			continue
+		}
+		// Computed columns are set by the server and rejected in payloads
+		if col.Computed != "" {
+			continue
+		}
+		doc.Properties[col.Name] = columnJSONSchema(col)
+		if !col.Nullable && col.DefaultValue == nil {
+			doc.Required = append(doc.Required, col.Name)
+		}
+	}
+
+	return doc
+}
+
+// columnJSONSchema returns the schema of the values a column accepts
+func columnJSONSchema(col registry.Column) *JSONSchema {
+	prop := &JSONSchema{}
+	var types []string
+	switch col.Type {
+	case registry.TypeString:
+		types = []string{"string"}
+		prop.MaxLength = col.MaxLength
+	case registry.TypeInteger:
+		types = []string{"integer"}
+		prop.Minimum = col.Min
+		prop.Maximum = col.Max
+	case registry.TypeBoolean:
+		types = []string{"boolean"}
+	case registry.TypeDecimal:
+		// Decimals are exchanged as strings to keep their precision
+		types = []string{"string"}
+		prop.Pattern = fmt.Sprintf(`^-?\d+(\.\d{1,%d})?$`, constants.DefaultDecimalScale)
+	case registry.TypeDatetime:
+		// RFC3339 datetimes and short dates are both accepted
+		types = []string{"string"}
+		prop.AnyOf = []*JSONSchema{{Format: "date-time"}, {Format: "date"}}
+	case registry.TypeJSON:
+		// Any JSON value; null is only allowed on nullable columns
+		if !col.Nullable {
+			types = jsonDocumentTypes
+		}
+	}
+
+	if len(col.Enum) > 0 {
+		for _, value := range col.Enum {
+			prop.Enum = append(prop.Enum, value)
+		}
+		if col.Nullable {
+			prop.Enum = append(prop.Enum, nil)
+		}
+	}
+
+	if types != nil && col.Nullable {
+		types = append(types, "null")
+	}
+	switch len(types) {
+	case 0:
+	case 1:
+		prop.Type = types[0]
+	default:
+		prop.Type = types
+	}
+	return prop
+}
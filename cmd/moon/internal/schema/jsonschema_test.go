@@ -0,0 +1,153 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// toJSON renders a JSON Schema document as a generic value for comparison
+func toJSON(t *testing.T, doc *JSONSchema) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to marshal schema: %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+	return out
+}
+
+// fromJSON parses an expected JSON Schema fragment
+func fromJSON(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		t.Fatalf("invalid fixture %s: %v", s, err)
+	}
+	return out
+}
+
+func TestToJSONSchema(t *testing.T) {
+	maxLength := 40
+	minRank, maxRank := json.Number("1"), json.Number("5")
+	defaultStatus := "draft"
+
+	tests := []struct {
+		name       string
+		collection *registry.Collection
+		properties map[string]string
+		required   []string
+	}{
+		{
+			name: "scalar columns",
+			collection: &registry.Collection{
+				Name: "products",
+				Columns: []registry.Column{
+					{Name: "name", Type: registry.TypeString, MaxLength: &maxLength},
+					{Name: "rank", Type: registry.TypeInteger, Min: &minRank, Max: &maxRank},
+					{Name: "active", Type: registry.TypeBoolean, Nullable: true},
+					{Name: "price", Type: registry.TypeDecimal, Min: &minRank},
+				},
+			},
+			properties: map[string]string{
+				"id":     `{"type": "string", "pattern": "` + constants.ULIDPattern + `", "readOnly": true}`,
+				"name":   `{"type": "string", "maxLength": 40}`,
+				"rank":   `{"type": "integer", "minimum": 1, "maximum": 5}`,
+				"active": `{"type": ["boolean", "null"]}`,
+				"price":  `{"type": "string", "pattern": "^-?\\d+(\\.\\d{1,2})?$"}`,
+			},
+			required: []string{"name", "rank", "price"},
+		},
+		{
+			name: "datetime, json and enum columns",
+			collection: &registry.Collection{
+				Name: "events",
+				Columns: []registry.Column{
+					{Name: "starts_at", Type: registry.TypeDatetime},
+					{Name: "ends_at", Type: registry.TypeDatetime, Nullable: true},
+					{Name: "payload", Type: registry.TypeJSON},
+					{Name: "meta", Type: registry.TypeJSON, Nullable: true},
+					{Name: "status", Type: registry.TypeString, Nullable: true, Enum: []string{"draft", "live"}, DefaultValue: &defaultStatus},
+				},
+			},
+			properties: map[string]string{
+				"id":        `{"type": "string", "pattern": "` + constants.ULIDPattern + `", "readOnly": true}`,
+				"starts_at": `{"type": "string", "anyOf": [{"format": "date-time"}, {"format": "date"}]}`,
+				"ends_at":   `{"type": ["string", "null"], "anyOf": [{"format": "date-time"}, {"format": "date"}]}`,
+				"payload":   `{"type": ["object", "array", "number", "boolean", "string"]}`,
+				"meta":      `{}`,
+				"status":    `{"type": ["string", "null"], "enum": ["draft", "live", null]}`,
+			},
+			required: []string{"starts_at", "payload"},
+		},
+		{
+			name: "client ids and system columns",
+			collection: &registry.Collection{
+				Name:   "skus",
+				IDType: registry.IDTypeClient,
+				Columns: []registry.Column{
+					{Name: "ulid", Type: registry.TypeString},
+					{Name: constants.CreatedAtColumn, Type: registry.TypeDatetime},
+					{Name: "label", Type: registry.TypeString, Nullable: true},
+				},
+				SoftDelete:     true,
+				ExposeSequence: true,
+			},
+			properties: map[string]string{
+				"id":    `{"type": "string", "pattern": "` + constants.ClientIDPattern + `", "maxLength": 64}`,
+				"label": `{"type": ["string", "null"]}`,
+			},
+			required: []string{"id"},
+		},
+		{
+			name: "uuidv7 ids",
+			collection: &registry.Collection{
+				Name:   "orders",
+				IDType: registry.IDTypeUUIDv7,
+			},
+			properties: map[string]string{
+				"id": `{"type": "string", "pattern": "` + constants.UUIDv7Pattern + `", "readOnly": true}`,
+			},
+			required: []string{},
+		},
+	}
+
+	builder := NewBuilder()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := toJSON(t, builder.ToJSONSchema(tt.collection))
+
+			if doc["$schema"] != JSONSchemaDialect {
+				t.Errorf("expected $schema %s, got %v", JSONSchemaDialect, doc["$schema"])
+			}
+			if doc["title"] != tt.collection.Name || doc["type"] != "object" || doc["additionalProperties"] != false {
+				t.Errorf("expected a closed object titled %s, got %v", tt.collection.Name, doc)
+			}
+
+			properties, _ := doc["properties"].(map[string]any)
+			if len(properties) != len(tt.properties) {
+				t.Errorf("expected %d properties, got %v", len(tt.properties), properties)
+			}
+			for name, want := range tt.properties {
+				if got := properties[name]; !reflect.DeepEqual(got, fromJSON(t, want)) {
+					t.Errorf("property %s: expected %s, got %v", name, want, got)
+				}
+			}
+
+			required := []string{}
+			names, _ := doc["required"].([]any)
+			for _, name := range names {
+				required = append(required, name.(string))
+			}
+			if !reflect.DeepEqual(required, tt.required) {
+				t.Errorf("expected required %v, got %v", tt.required, required)
+			}
+		})
+	}
+}
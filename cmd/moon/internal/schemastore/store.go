@@ -0,0 +1,135 @@
+// Package schemastore persists collection schemas in the moon_schemas system
+// table so that registry-only metadata (nullable flags, defaults, indexes,
+// soft delete and revision settings) survives restarts. Each collection is
+// stored as one row holding its JSON encoded definition.
+package schemastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// Store reads and writes collection schemas in the moon_schemas table.
+// It implements registry.Store.
+type Store struct {
+	db database.Driver
+}
+
+// New creates a schema store backed by the given database
+func New(db database.Driver) *Store {
+	return &Store{db: db}
+}
+
+// Init creates the moon_schemas table if it does not exist. created reports
+// whether the table was missing, meaning existing tables have never been
+// recorded and need to be migrated.
+func (s *Store) Init(ctx context.Context) (created bool, err error) {
+	exists, err := s.db.TableExists(ctx, constants.TableSchemas)
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s table: %w", constants.TableSchemas, err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	if _, err := s.db.Exec(ctx, createTableSQL(s.db.Dialect())); err != nil {
+		return false, fmt.Errorf("failed to create %s table: %w", constants.TableSchemas, err)
+	}
+	return true, nil
+}
+
+// LoadAll returns every persisted collection schema ordered by name
+func (s *Store) LoadAll(ctx context.Context) ([]*registry.Collection, error) {
+	rows, err := s.db.Query(ctx, "SELECT name, definition FROM "+constants.TableSchemas+" ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableSchemas, err)
+	}
+	defer rows.Close()
+
+	var collections []*registry.Collection
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", constants.TableSchemas, err)
+		}
+
+		var collection registry.Collection
+		if err := json.Unmarshal([]byte(definition), &collection); err != nil {
+			return nil, fmt.Errorf("invalid schema for collection '%s': %w", name, err)
+		}
+		collection.Name = name
+		collections = append(collections, &collection)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TableSchemas, err)
+	}
+
+	return collections, nil
+}
+
+// Save inserts or replaces the persisted schema of a collection
+func (s *Store) Save(collection *registry.Collection) error {
+	definition, err := json.Marshal(collection)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	_, err = s.db.Exec(context.Background(), upsertSQL(s.db.Dialect()), collection.Name, string(definition), time.Now().UTC())
+	return err
+}
+
+// Delete removes the persisted schema of a collection
+func (s *Store) Delete(name string) error {
+	query := "DELETE FROM " + constants.TableSchemas + " WHERE name = ?"
+	if s.db.Dialect() == database.DialectPostgres {
+		query = "DELETE FROM " + constants.TableSchemas + " WHERE name = $1"
+	}
+
+	_, err := s.db.Exec(context.Background(), query, name)
+	return err
+}
+
+// createTableSQL returns the moon_schemas DDL for the given dialect
+func createTableSQL(dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectPostgres:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableSchemas + ` (
+			name VARCHAR(255) PRIMARY KEY,
+			definition TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)`
+	case database.DialectMySQL:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableSchemas + ` (
+			name VARCHAR(255) PRIMARY KEY,
+			definition LONGTEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`
+	default:
+		return `CREATE TABLE IF NOT EXISTS ` + constants.TableSchemas + ` (
+			name TEXT PRIMARY KEY,
+			definition TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`
+	}
+}
+
+// upsertSQL returns the statement that inserts or replaces one schema row
+func upsertSQL(dialect database.DialectType) string {
+	switch dialect {
+	case database.DialectPostgres:
+		return `INSERT INTO ` + constants.TableSchemas + ` (name, definition, updated_at) VALUES ($1, $2, $3)
+			ON CONFLICT (name) DO UPDATE SET definition = EXCLUDED.definition, updated_at = EXCLUDED.updated_at`
+	case database.DialectMySQL:
+		return `INSERT INTO ` + constants.TableSchemas + ` (name, definition, updated_at) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE definition = VALUES(definition), updated_at = VALUES(updated_at)`
+	default:
+		return `INSERT INTO ` + constants.TableSchemas + ` (name, definition, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT (name) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`
+	}
+}
@@ -0,0 +1,164 @@
+package schemastore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// openDriver connects to a SQLite database file that outlives the driver
+func openDriver(t *testing.T, path string) database.Driver {
+	t.Helper()
+
+	driver, err := database.NewDriver(database.Config{
+		ConnectionString: "sqlite://" + path,
+		MaxOpenConns:     1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	return driver
+}
+
+// openRegistry loads a registry from the store the way the server does at startup
+func openRegistry(t *testing.T, driver database.Driver) (*registry.SchemaRegistry, bool) {
+	t.Helper()
+
+	store := New(driver)
+	created, err := store.Init(context.Background())
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	collections, err := store.LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	reg := registry.NewSchemaRegistry()
+	for _, collection := range collections {
+		if err := reg.Set(collection); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	reg.SetStore(store)
+	return reg, created
+}
+
+func TestStore_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moon.db")
+	defaultValue := "'draft'"
+
+	driver := openDriver(t, path)
+	reg, created := openRegistry(t, driver)
+	if !created {
+		t.Error("expected the schema table to be created on first start")
+	}
+
+	products := &registry.Collection{
+		Name: "products",
+		Columns: []registry.Column{
+			{Name: "title", Type: registry.TypeString, Unique: true},
+			{Name: "status", Type: registry.TypeString, Nullable: true, DefaultValue: &defaultValue},
+			{Name: "price", Type: registry.TypeDecimal},
+		},
+		Indexes:         []registry.Index{{Name: "idx_products_status_price", Columns: []string{"status", "price"}}},
+		SoftDelete:      true,
+		RequireRevision: true,
+	}
+	for _, collection := range []*registry.Collection{products, {Name: "orders", Archived: true}, {Name: "drafts"}} {
+		if err := reg.Set(collection); err != nil {
+			t.Fatalf("Set(%s) error = %v", collection.Name, err)
+		}
+	}
+	if err := reg.Delete("drafts"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := reg.Rename("orders", "purchases"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	driver.Close()
+
+	// Restart with a new driver and registry
+	driver = openDriver(t, path)
+	defer driver.Close()
+	reg, created = openRegistry(t, driver)
+	if created {
+		t.Error("expected the existing schema table to be reused")
+	}
+
+	if reg.Exists("drafts") || reg.Exists("orders") || !reg.Exists("purchases") {
+		t.Errorf("expected deletes and renames to persist, got %v", reg.List())
+	}
+	if purchases, _ := reg.Get("purchases"); purchases == nil || !purchases.Archived {
+		t.Errorf("expected the archived flag to survive the restart, got %+v", purchases)
+	}
+
+	got, ok := reg.Get("products")
+	if !ok {
+		t.Fatal("expected products to survive the restart")
+	}
+	if len(got.Columns) != 3 {
+		t.Fatalf("expected 3 columns, got %+v", got.Columns)
+	}
+	status := got.Columns[1]
+	if !status.Nullable || status.DefaultValue == nil || *status.DefaultValue != defaultValue {
+		t.Errorf("expected nullable status with default %s, got %+v", defaultValue, status)
+	}
+	if !got.Columns[0].Unique || got.Columns[0].Nullable {
+		t.Errorf("expected unique non-nullable title, got %+v", got.Columns[0])
+	}
+	if got.Columns[2].Type != registry.TypeDecimal {
+		t.Errorf("expected decimal price, got %s", got.Columns[2].Type)
+	}
+	if !got.SoftDelete || !got.RequireRevision {
+		t.Errorf("expected soft delete and revision settings to survive, got %+v", got)
+	}
+	if len(got.Indexes) != 1 || got.Indexes[0].Name != "idx_products_status_price" || len(got.Indexes[0].Columns) != 2 {
+		t.Errorf("expected the index to survive, got %+v", got.Indexes)
+	}
+}
+
+func TestStore_ConcurrentSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moon.db")
+	driver := openDriver(t, path)
+	defer driver.Close()
+	reg, _ := openRegistry(t, driver)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			columns := make([]registry.Column, i+1)
+			for j := range columns {
+				columns[j] = registry.Column{Name: fmt.Sprintf("col%d", j), Type: registry.TypeString}
+			}
+			if err := reg.Set(&registry.Collection{Name: "products", Columns: columns}); err != nil {
+				t.Errorf("Set() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	collections, err := New(driver).LoadAll(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	if len(collections) != 1 {
+		t.Fatalf("expected one persisted row, got %d", len(collections))
+	}
+
+	// The last write wins in memory and in the database alike
+	inMemory, _ := reg.Get("products")
+	if len(collections[0].Columns) != len(inMemory.Columns) {
+		t.Errorf("persisted schema has %d columns, registry has %d", len(collections[0].Columns), len(inMemory.Columns))
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+func assertAuthCode(t *testing.T, w *httptest.ResponseRecorder, code apperrors.ErrorCode) {
+	t.Helper()
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["code"] != string(code) {
+		t.Errorf("expected code %s, got %v", code, resp["code"])
+	}
+}
+
+func TestAPIKeyAuth_Expired(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	repo := auth.NewAPIKeyRepository(srv.db)
+	ctx := context.Background()
+
+	rawKey, keyHash, _ := auth.GenerateAPIKey()
+	expires := time.Now().Add(time.Hour)
+	apiKey := &auth.APIKey{Name: "expiring", KeyHash: keyHash, Role: "user", CanWrite: true, ExpiresAt: &expires}
+	if err := repo.Create(ctx, apiKey); err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if w := serveWithKey(srv, rawKey, http.MethodGet, "/products:list", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected a key before its expiry to work, got %d: %s", w.Code, w.Body.String())
+	}
+
+	past := time.Now().Add(-time.Second)
+	if _, err := srv.db.Exec(ctx, "UPDATE "+constants.TableAPIKeys+" SET expires_at = ? WHERE pkid = ?", past, apiKey.PKID); err != nil {
+		t.Fatalf("failed to expire key: %v", err)
+	}
+	assertAuthCode(t, serveWithKey(srv, rawKey, http.MethodGet, "/products:list", ""), apperrors.CodeAPIKeyExpired)
+}
+
+func TestAPIKeyAuth_Revoked(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	key := createScopedKey(t, srv, "revocable", "user", nil)
+
+	if w := serveWithKey(srv, key, http.MethodGet, "/products:list", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the key to work, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Revocation takes effect on the next request, without a restart
+	repo := auth.NewAPIKeyRepository(srv.db)
+	apiKey, _ := repo.GetByHash(context.Background(), auth.HashAPIKey(key))
+	if err := repo.Revoke(context.Background(), apiKey); err != nil {
+		t.Fatalf("failed to revoke key: %v", err)
+	}
+	assertAuthCode(t, serveWithKey(srv, key, http.MethodGet, "/products:list", ""), apperrors.CodeAPIKeyRevoked)
+
+	assertAuthCode(t, serveWithKey(srv, auth.APIKeyPrefix+"unknown", http.MethodGet, "/products:list", ""), apperrors.CodeInvalidAPIKey)
+}
+
+func TestAPIKeyAuth_LastUsedThrottled(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	key := createScopedKey(t, srv, "tracked", "user", nil)
+	repo := auth.NewAPIKeyRepository(srv.db)
+	ctx := context.Background()
+
+	serveWithKey(srv, key, http.MethodGet, "/products:list", "")
+
+	// The update runs in the background
+	var lastUsed *time.Time
+	for i := 0; i < 100 && lastUsed == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		apiKey, _ := repo.GetByHash(ctx, auth.HashAPIKey(key))
+		lastUsed = apiKey.LastUsedAt
+	}
+	if lastUsed == nil {
+		t.Fatal("expected last_used_at to be set after the first request")
+	}
+
+	// Further requests within the interval do not write it again
+	apiKey, _ := repo.GetByHash(ctx, auth.HashAPIKey(key))
+	for i := 0; i < 3; i++ {
+		serveWithKey(srv, key, http.MethodGet, "/products:list", "")
+	}
+	if srv.apiKeyUsage.Due(apiKey, time.Now()) {
+		t.Error("expected the next last_used_at update to wait for the interval")
+	}
+	time.Sleep(50 * time.Millisecond)
+	apiKey, _ = repo.GetByHash(ctx, auth.HashAPIKey(key))
+	if !apiKey.LastUsedAt.Equal(*lastUsed) {
+		t.Errorf("expected last_used_at to stay %v, got %v", lastUsed, apiKey.LastUsedAt)
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/consistency"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/handlers"
+)
+
+func TestCollectionArchive(t *testing.T) {
+	srv, adminKey := setupScopeTestServer(t)
+
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/products:create", `{"data": {"title": "Widget"}}`); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create record: %d %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:archive", `{"name": "products"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 archiving products, got %d: %s", w.Code, w.Body.String())
+	}
+
+	listed := func(path string) map[string]bool {
+		t.Helper()
+		w := serveWithKey(srv, adminKey, http.MethodGet, path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var resp handlers.ListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode list: %v", err)
+		}
+		archived := map[string]bool{}
+		for _, item := range resp.Collections {
+			archived[item.Name] = item.Archived
+		}
+		return archived
+	}
+	if got := listed("/collections:list"); len(got) != 1 || got["orders"] {
+		t.Errorf("expected only orders to be listed, got %v", got)
+	}
+	if got := listed("/collections:list?include_archived=true"); len(got) != 2 || !got["products"] || got["orders"] {
+		t.Errorf("expected products listed as archived, got %v", got)
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/collections:list?include_archived=maybe", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid include_archived flag, got %d", w.Code)
+	}
+
+	assertGone := func(method, path, body string) {
+		t.Helper()
+		w := serveWithKey(srv, adminKey, method, path, body)
+		if w.Code != http.StatusGone {
+			t.Fatalf("%s %s: expected 410, got %d: %s", method, path, w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp["code"] != string(apperrors.CodeCollectionArchived) {
+			t.Errorf("%s %s: expected code %s, got %v", method, path, apperrors.CodeCollectionArchived, resp["code"])
+		}
+	}
+	assertGone(http.MethodGet, "/products:list", "")
+	assertGone(http.MethodGet, "/products:count", "")
+	assertGone(http.MethodPost, "/products:create", `{"data": {"title": "Gadget"}}`)
+	assertGone(http.MethodPost, "/batch:transact", `[{"collection": "products", "action": "create", "data": {"title": "Gadget"}}]`)
+
+	// The table and its records are untouched and not reported as orphaned
+	var count int
+	if err := srv.db.QueryRow(context.Background(), "SELECT COUNT(*) FROM products").Scan(&count); err != nil || count != 1 {
+		t.Fatalf("expected the archived table to keep its record, got %d (%v)", count, err)
+	}
+	w := serveWithKey(srv, adminKey, http.MethodGet, "/admin:consistency", "")
+	var result consistency.CheckResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if w.Code != http.StatusOK || !result.Consistent {
+		t.Errorf("expected an archived collection to be consistent, got %d %+v", w.Code, result.Issues)
+	}
+
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:archive", `{"name": "products"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 archiving twice, got %d", w.Code)
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:unarchive", `{"name": "products"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 unarchiving products, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/products:list", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the unarchived collection to serve data again, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:unarchive", `{"name": "products"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 unarchiving an active collection, got %d", w.Code)
+	}
+
+	// collections:destroy still removes an archived collection for good
+	serveWithKey(srv, adminKey, http.MethodPost, "/collections:archive", `{"name": "products"}`)
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:destroy", `{"name": "products"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 destroying an archived collection, got %d: %s", w.Code, w.Body.String())
+	}
+	if srv.registry.Exists("products") {
+		t.Error("expected the destroyed collection to leave the registry")
+	}
+
+	userKey := createScopedKey(t, srv, "user", "user", nil)
+	if w := serveWithKey(srv, userKey, http.MethodPost, "/collections:archive", `{"name": "orders"}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin key, got %d", w.Code)
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:archive", `{"name": "missing"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 archiving a missing collection, got %d", w.Code)
+	}
+}
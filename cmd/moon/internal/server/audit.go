@@ -0,0 +1,134 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/pagination"
+)
+
+// auditListResponse is the response of GET /admin:audit
+type auditListResponse struct {
+	Entries    []audit.Entry `json:"entries"`
+	NextCursor *string       `json:"next_cursor"`
+	Limit      int           `json:"limit"`
+}
+
+// SetAudit sets the audit log recording successful mutating requests; without
+// one, requests are not audited and /admin:audit is not found
+func (s *Server) SetAudit(l *audit.Log) {
+	s.audit = l
+}
+
+// audited records an audit entry for action when the request succeeds. The
+// collection may be empty for handlers that note it themselves; handlers also
+// note the records they changed. It must run inside the auth wrappers, which
+// set the actor.
+func (s *Server) audited(action, collection string, next http.HandlerFunc) http.HandlerFunc {
+	return s.auditedFunc(action, func(*http.Request) string { return collection }, next)
+}
+
+// auditedData is audited for a data action, recorded under the collection's
+// physical table so entries of tenants stay apart
+func (s *Server) auditedData(action, collectionName string, next http.HandlerFunc) http.HandlerFunc {
+	return s.auditedFunc(action, func(r *http.Request) string { return tenantTable(r, collectionName) }, next)
+}
+
+// auditedFunc is audited with the collection resolved per request
+func (s *Server) auditedFunc(action string, collection func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.audit == nil {
+			next(w, r)
+			return
+		}
+
+		ctx, note := audit.Track(r.Context())
+		r = r.WithContext(ctx)
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rw, r)
+
+		if rw.statusCode < 200 || rw.statusCode >= 300 {
+			return
+		}
+		entry, ok := note.Entry(audit.Entry{
+			Actor:      audit.ActorAnonymous,
+			ActorType:  audit.ActorAnonymous,
+			Collection: collection(r),
+			Action:     action,
+			RequestID:  logging.GetRequestID(ctx),
+		})
+		if !ok {
+			return
+		}
+		if entity, found := middleware.GetAuthEntity(ctx); found && entry.ActorType == audit.ActorAnonymous {
+			entry.Actor, entry.ActorType = entity.ID, entity.Type
+		}
+		s.audit.Record(entry)
+	}
+}
+
+// auditHandler handles GET /admin:audit: entries oldest first, optionally of
+// one collection, paged by the id of the last entry of the previous page
+func (s *Server) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		s.writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "audit log is disabled; set audit.enabled to true")
+		return
+	}
+
+	// Entries cover every collection, so the key must hold the read scope on all of them
+	if !middleware.HasScope(r.Context(), auth.ScopeAllCollections, auth.ScopeRead) {
+		middleware.WriteScopeError(w, r, auth.ScopeAllCollections, auth.ScopeRead)
+		return
+	}
+
+	params := r.URL.Query()
+	limit := pagination.GetDefaultPageSize(s.config)
+	if raw := params.Get(constants.QueryParamLimit); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "limit must be an integer")
+			return
+		}
+		if err := pagination.ValidatePageSize(parsed, s.config); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, apperrors.CodePageSizeExceeded, err.Error())
+			return
+		}
+		limit = parsed
+	}
+	after := params.Get("after")
+	if err := pagination.ValidateCursor(after); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidCursor, "after must be the id of an audit entry")
+		return
+	}
+
+	entries, err := s.audit.Query(r.Context(), audit.Filter{
+		Collection: params.Get("collection"),
+		After:      after,
+		Limit:      limit + 1, // one extra to tell whether there is a next page
+	})
+	if err != nil {
+		log.Printf("ERROR: Audit query failed: %v", err)
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to read the audit log")
+		return
+	}
+
+	var nextCursor *string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		cursor := entries[len(entries)-1].ID
+		nextCursor = &cursor
+	}
+
+	s.writeJSON(w, http.StatusOK, auditListResponse{
+		Entries:    entries,
+		NextCursor: nextCursor,
+		Limit:      limit,
+	})
+}
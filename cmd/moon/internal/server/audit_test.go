@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/audit"
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+)
+
+// listAudit returns a page of GET /admin:audit
+func listAudit(t *testing.T, srv *Server, key, query string) auditListResponse {
+	t.Helper()
+	w := serveWithKey(srv, key, http.MethodGet, "/admin:audit"+query, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("audit query failed: %d %s", w.Code, w.Body.String())
+	}
+	var resp auditListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode audit page: %v", err)
+	}
+	return resp
+}
+
+func TestAudit_RecordsMutations(t *testing.T) {
+	srv, adminKey := setupScopeTestServer(t)
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/admin:audit", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 while the audit log is disabled, got %d", w.Code)
+	}
+
+	auditLog := audit.New(srv.db, 100)
+	if err := auditLog.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	srv.SetAudit(auditLog)
+
+	w := serveWithKey(srv, adminKey, http.MethodPost, "/products:create", `{"data": {"title": "Widget"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data map[string]any `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	id, _ := created.Data["id"].(string)
+
+	requests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, "/products:update", `{"id": "` + id + `", "data": {"title": "Gadget"}}`, http.StatusOK},
+		{http.MethodGet, "/products:list", "", http.StatusOK},                                            // reads are not audited
+		{http.MethodPost, "/products:update", `{"id": "` + id + `", "data": {}}`, http.StatusBadRequest}, // nor failures
+		{http.MethodPost, "/products:destroy?force=true", `{"where": {"title": {"eq": "Gadget"}}}`, http.StatusOK},
+		{http.MethodPost, "/orders:create", `{"data": [{"title": "a"}, {"title": "b"}]}`, http.StatusMultiStatus},
+		{http.MethodPost, "/collections:update?dry_run=true", `{"name": "orders", "add_columns": [{"name": "note", "type": "string", "nullable": true}]}`, http.StatusOK}, // nor dry runs
+		{http.MethodPost, "/collections:update", `{"name": "orders", "add_columns": [{"name": "note", "type": "string", "nullable": true}]}`, http.StatusOK},
+	}
+	for _, req := range requests {
+		if w := serveWithKey(srv, adminKey, req.method, req.path, req.body); w.Code != req.status {
+			t.Fatalf("%s %s: expected %d, got %d %s", req.method, req.path, req.status, w.Code, w.Body.String())
+		}
+	}
+	// Entries are written in the background; closing the log flushes them
+	auditLog.Close(context.Background())
+
+	admin, err := auth.NewAPIKeyRepository(srv.db).GetByHash(context.Background(), auth.HashAPIKey(adminKey))
+	if err != nil {
+		t.Fatalf("failed to look up the admin key: %v", err)
+	}
+	resp := listAudit(t, srv, adminKey, "")
+	want := []struct {
+		collection, action string
+		records            int
+	}{
+		{"products", "create", 1},
+		{"products", "update", 1},
+		{"products", "destroy", 1},
+		{"orders", "create", 2},
+		{"orders", "collections:update", 0},
+	}
+	if len(resp.Entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), resp.Entries)
+	}
+	for i, entry := range resp.Entries {
+		if entry.Collection != want[i].collection || entry.Action != want[i].action || entry.RecordCount != want[i].records {
+			t.Errorf("entry %d: expected %s %s of %d records, got %+v", i, want[i].action, want[i].collection, want[i].records, entry)
+		}
+		if entry.Actor != admin.ID || entry.ActorType != audit.ActorAPIKey {
+			t.Errorf("entry %d: expected api key %s, got %+v", i, admin.ID, entry)
+		}
+	}
+	if ids := resp.Entries[1].RecordIDs; len(ids) != 1 || ids[0] != id {
+		t.Errorf("expected the update to name record %s, got %v", id, ids)
+	}
+
+	// Pages of one collection follow next_cursor
+	page := listAudit(t, srv, adminKey, "?collection=products&limit=2")
+	if len(page.Entries) != 2 || page.NextCursor == nil || page.Limit != 2 {
+		t.Fatalf("expected a first page of 2 with a cursor, got %+v", page)
+	}
+	page = listAudit(t, srv, adminKey, "?collection=products&limit=2&after="+*page.NextCursor)
+	if len(page.Entries) != 1 || page.Entries[0].Action != "destroy" || page.NextCursor != nil {
+		t.Fatalf("expected the last page with the destroy, got %+v", page)
+	}
+
+	for _, query := range []string{"?after=not-a-ulid", "?limit=0", "?limit=abc"} {
+		if w := serveWithKey(srv, adminKey, http.MethodGet, "/admin:audit"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+	scoped := createScopedKey(t, srv, "scoped", "admin", auth.Scopes{{Collection: "products", Actions: []string{auth.ScopeRead}}})
+	if w := serveWithKey(srv, scoped, http.MethodGet, "/admin:audit", ""); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "scope") {
+		t.Errorf("expected 403 for a key without read on every collection, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/consistency"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/schemastore"
+)
+
+// Backups are named moon-{UTC timestamp}.db
+const (
+	backupPrefix     = "moon-"
+	backupExtension  = ".db"
+	backupTimeFormat = "20060102T150405.000Z"
+)
+
+// backupTables are the system tables a backup must hold to be restored
+var backupTables = []string{constants.TableSchemas, constants.TableUsers, constants.TableAPIKeys}
+
+// backupFile describes a snapshot in backup.directory
+type backupFile struct {
+	File      string    `json:"file"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupResult is the response of POST /admin:backup
+type backupResult struct {
+	backupFile
+	Duration jsonDuration `json:"duration"`
+}
+
+// restoreRequest is the body of POST /admin:restore
+type restoreRequest struct {
+	File string `json:"file"`
+}
+
+// restoreResult is the response of POST /admin:restore. Added and removed
+// compare the collections of the restored database with those before it.
+type restoreResult struct {
+	File        string                   `json:"file"`
+	Size        int64                    `json:"size"`
+	Duration    jsonDuration             `json:"duration"`
+	Collections int                      `json:"collections"`
+	Added       []string                 `json:"added"`
+	Removed     []string                 `json:"removed"`
+	Consistency *consistency.CheckResult `json:"consistency"`
+}
+
+// backupsAllowed checks the scope and the database of a backup request and
+// writes the error response when it is not allowed. Snapshots cover every
+// collection, so the key must hold the schema scope on all of them.
+func (s *Server) backupsAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if !middleware.HasScope(r.Context(), auth.ScopeAllCollections, auth.ScopeSchema) {
+		middleware.WriteScopeError(w, r, auth.ScopeAllCollections, auth.ScopeSchema)
+		return false
+	}
+	if s.sqliteFile() == "" {
+		s.writeError(w, r, http.StatusNotImplemented, apperrors.CodeNotImplemented, fmt.Sprintf("backups are only supported for SQLite file databases; back up %s with its own tools", s.db.Dialect()))
+		return false
+	}
+	return true
+}
+
+// backupDirectory returns the configured backup directory
+func (s *Server) backupDirectory() string {
+	if s.config.Backup.Directory == "" {
+		return config.Defaults.Backup.Directory
+	}
+	return s.config.Backup.Directory
+}
+
+// backupHandler handles POST /admin:backup. It writes a snapshot of the SQLite
+// database to backup.directory with VACUUM INTO; writes go on meanwhile.
+func (s *Server) backupHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.backupsAllowed(w, r) {
+		return
+	}
+	if !s.maintenanceRunning.CompareAndSwap(false, true) {
+		s.writeError(w, r, http.StatusConflict, apperrors.CodeConflict, "another maintenance operation is already running")
+		return
+	}
+	defer s.maintenanceRunning.Store(false)
+
+	dir := s.backupDirectory()
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to create backup directory: %v", err))
+		return
+	}
+
+	// The snapshot may outlast the server write timeout, so the response deadline is extended to match
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(constants.MaintenanceTimeout + constants.HTTPWriteTimeout)); err != nil {
+		log.Printf("WARNING: Failed to extend write deadline for backup: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), constants.MaintenanceTimeout)
+	defer cancel()
+
+	start := time.Now()
+	name := backupPrefix + start.UTC().Format(backupTimeFormat) + backupExtension
+	path := filepath.Join(dir, name)
+	if err := database.BackupSQLite(ctx, s.db, path); err != nil {
+		os.Remove(path)
+		if ctx.Err() == context.DeadlineExceeded {
+			s.writeError(w, r, http.StatusServiceUnavailable, apperrors.CodeQueryTimeout, fmt.Sprintf("backup timed out after %s", constants.MaintenanceTimeout))
+			return
+		}
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to read backup: %v", err))
+		return
+	}
+	result := backupResult{
+		backupFile: backupFile{File: name, Path: path, Size: info.Size(), CreatedAt: info.ModTime().UTC()},
+		Duration:   jsonDuration(time.Since(start)),
+	}
+	log.Printf("INFO: Backup %s written in %s (%d bytes)", path, time.Duration(result.Duration), result.Size)
+	s.writeJSON(w, http.StatusCreated, result)
+}
+
+// listBackupsHandler handles GET /admin:backups, newest first
+func (s *Server) listBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.backupsAllowed(w, r) {
+		return
+	}
+
+	dir := s.backupDirectory()
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("failed to read backup directory: %v", err))
+		return
+	}
+
+	backups := []backupFile{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), backupExtension) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{
+			File:      entry.Name(),
+			Path:      filepath.Join(dir, entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime().UTC(),
+		})
+	}
+	slices.SortFunc(backups, func(a, b backupFile) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.File, a.File)
+	})
+
+	s.writeJSON(w, http.StatusOK, map[string]any{"directory": dir, "backups": backups})
+}
+
+// restoreHandler handles POST /admin:restore. The backup is checked before
+// anything changes; writes are then paused while the SQLite backup API copies
+// it over the live database, the registry is reloaded from the restored
+// moon_schemas and the consistency check runs against the result.
+func (s *Server) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.backupsAllowed(w, r) {
+		return
+	}
+
+	var req restoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
+		return
+	}
+	if req.File == "" || req.File != filepath.Base(req.File) || req.File == "." || req.File == ".." {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "file must be the name of a backup in the backup directory, as listed by admin:backups")
+		return
+	}
+	path := filepath.Join(s.backupDirectory(), req.File)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		s.writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, fmt.Sprintf("backup '%s' not found", req.File))
+		return
+	}
+
+	tables, err := database.InspectSQLite(r.Context(), path)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, fmt.Sprintf("backup '%s' cannot be restored: %v", req.File, err))
+		return
+	}
+	var missing []string
+	for _, table := range backupTables {
+		if !slices.Contains(tables, table) {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, fmt.Sprintf("backup '%s' is not a Moon database: missing system tables %s", req.File, strings.Join(missing, ", ")))
+		return
+	}
+
+	if !s.maintenanceRunning.CompareAndSwap(false, true) {
+		s.writeError(w, r, http.StatusConflict, apperrors.CodeConflict, "another maintenance operation is already running")
+		return
+	}
+	defer s.maintenanceRunning.Store(false)
+	s.writesPaused.Store(true)
+	defer s.writesPaused.Store(false)
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(constants.MaintenanceTimeout + constants.HTTPWriteTimeout)); err != nil {
+		log.Printf("WARNING: Failed to extend write deadline for restore: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), constants.MaintenanceTimeout)
+	defer cancel()
+
+	start := time.Now()
+	before := s.registry.List()
+	if err := database.RestoreSQLite(ctx, s.db, path); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, err.Error())
+		return
+	}
+	log.Printf("WARNING: Database restored from %s", path)
+
+	if err := s.reloadRegistry(ctx); err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, fmt.Sprintf("database restored, but its collections could not be loaded: %v", err))
+		return
+	}
+
+	cfg := s.config.Recovery
+	if cfg.CheckTimeout <= 0 {
+		cfg.CheckTimeout = config.Defaults.Recovery.CheckTimeout
+	}
+	check, err := consistency.NewChecker(s.db, s.registry, &cfg).Check(ctx)
+	if err != nil {
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("database restored, but the consistency check failed: %v", err))
+		return
+	}
+
+	after := s.registry.List()
+	result := restoreResult{
+		File:        req.File,
+		Size:        info.Size(),
+		Duration:    jsonDuration(time.Since(start)),
+		Collections: len(after),
+		Added:       missingFrom(after, before),
+		Removed:     missingFrom(before, after),
+		Consistency: check,
+	}
+	log.Printf("INFO: Restore of %s completed in %s: %d collection(s), %d added, %d removed", req.File, time.Duration(result.Duration), result.Collections, len(result.Added), len(result.Removed))
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// reloadRegistry replaces the registry with the schemas persisted in the
+// database, as at startup
+func (s *Server) reloadRegistry(ctx context.Context) error {
+	collections, err := schemastore.New(s.db).LoadAll(ctx)
+	if err != nil {
+		return err
+	}
+	s.registry.Clear()
+	for _, collection := range collections {
+		if err := s.registry.Set(collection); err != nil {
+			return fmt.Errorf("failed to register collection '%s': %w", collection.Name, err)
+		}
+	}
+	return nil
+}
+
+// missingFrom returns the sorted names of names that are not in other
+func missingFrom(names, other []string) []string {
+	missing := []string{}
+	for _, name := range names {
+		if !slices.Contains(other, name) {
+			missing = append(missing, name)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/schemastore"
+)
+
+// setupBackupTestServer creates a file server whose schemas are persisted, as
+// at startup, and whose backups go to a temp directory
+func setupBackupTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	srv, adminKey, _ := setupFileTestServer(t)
+	srv.config.Backup.Directory = filepath.Join(t.TempDir(), "backups")
+
+	store := schemastore.New(srv.db)
+	if _, err := store.Init(context.Background()); err != nil {
+		t.Fatalf("failed to create the schema store: %v", err)
+	}
+	srv.registry.SetStore(store)
+	logs, _ := srv.registry.Get("logs")
+	if err := srv.registry.Set(logs); err != nil {
+		t.Fatalf("failed to persist logs: %v", err)
+	}
+	return srv, adminKey
+}
+
+// logBodies returns the bodies of every logs record
+func logBodies(t *testing.T, srv *Server, adminKey string) []string {
+	t.Helper()
+	w := serveWithKey(srv, adminKey, http.MethodGet, "/logs:list?sort=body", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to list logs: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data []map[string]any `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	bodies := []string{}
+	for _, record := range resp.Data {
+		bodies = append(bodies, record["body"].(string))
+	}
+	return bodies
+}
+
+func TestBackupRestore(t *testing.T) {
+	srv, adminKey := setupBackupTestServer(t)
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/logs:create", `{"data": {"body": "kept"}}`); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create a log: %d %s", w.Code, w.Body.String())
+	}
+
+	w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:backup", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var backup backupResult
+	if err := json.Unmarshal(w.Body.Bytes(), &backup); err != nil || backup.Duration <= 0 {
+		t.Errorf("expected a duration string, got %s (%v)", w.Body.String(), err)
+	}
+	if info, err := os.Stat(backup.Path); err != nil || info.Size() != backup.Size || backup.Size == 0 {
+		t.Fatalf("expected the backup at %s with its size, got %+v (%v)", backup.Path, backup, err)
+	}
+
+	// Changes after the backup are undone by the restore
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/logs:create", `{"data": {"body": "lost"}}`); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create a log: %d %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:create", `{"name": "notes", "columns": [{"name": "title", "type": "string"}]}`); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create notes: %d %s", w.Code, w.Body.String())
+	}
+
+	w = serveWithKey(srv, adminKey, http.MethodGet, "/admin:backups", "")
+	var list struct {
+		Backups []backupFile `json:"backups"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Backups) != 1 || list.Backups[0].File != backup.File {
+		t.Fatalf("expected the backup to be listed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serveWithKey(srv, adminKey, http.MethodPost, "/admin:restore", `{"file": "`+backup.File+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result restoreResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Duration <= 0 {
+		t.Errorf("expected a duration string, got %s (%v)", w.Body.String(), err)
+	}
+	if !slices.Equal(result.Removed, []string{"notes"}) || len(result.Added) != 0 || result.Collections != 1 {
+		t.Errorf("expected notes to be removed, got %s", w.Body.String())
+	}
+	if result.Consistency == nil || !result.Consistency.Consistent {
+		t.Errorf("expected a consistent database after the restore, got %s", w.Body.String())
+	}
+
+	if bodies := logBodies(t, srv, adminKey); !slices.Equal(bodies, []string{"kept"}) {
+		t.Errorf("expected only the log of the backup, got %v", bodies)
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/notes:list", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected notes to be gone, got %d", w.Code)
+	}
+	if exists, _ := srv.db.TableExists(context.Background(), "notes"); exists {
+		t.Error("expected the notes table to be gone")
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/logs:create", `{"data": {"body": "new"}}`); w.Code != http.StatusCreated {
+		t.Errorf("expected writes to resume after the restore, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRestore_RejectsInvalidBackups(t *testing.T) {
+	srv, adminKey := setupBackupTestServer(t)
+	dir := srv.config.Backup.Directory
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatalf("failed to create the backup directory: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "garbage.db"), []byte("not a database"), 0o640)
+
+	// A SQLite database without the Moon system tables
+	other, err := database.NewDriver(database.Config{ConnectionString: "sqlite://" + filepath.Join(dir, "other.db"), MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+	if err := other.Connect(context.Background()); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	other.Exec(context.Background(), "CREATE TABLE things (id TEXT)")
+	other.Close()
+
+	tests := []struct {
+		file   string
+		status int
+		want   string
+	}{
+		{"../moon.db", http.StatusBadRequest, "name of a backup"},
+		{"missing.db", http.StatusNotFound, "not found"},
+		{"garbage.db", http.StatusBadRequest, "cannot be restored"},
+		{"other.db", http.StatusBadRequest, "missing system tables"},
+	}
+	for _, tt := range tests {
+		w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:restore", `{"file": "`+tt.file+`"}`)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s: expected %d with %q, got %d: %s", tt.file, tt.status, tt.want, w.Code, w.Body.String())
+		}
+	}
+	if bodies := logBodies(t, srv, adminKey); len(bodies) != 0 {
+		t.Errorf("expected the database to be untouched, got %v", bodies)
+	}
+
+	// One backup, restore or maintenance operation runs at a time
+	srv.maintenanceRunning.Store(true)
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:backup", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 while another operation runs, got %d: %s", w.Code, w.Body.String())
+	}
+	srv.maintenanceRunning.Store(false)
+}
+
+func TestBackup_RequiresSQLiteFile(t *testing.T) {
+	srv, adminKey := setupScopeTestServer(t)
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/admin:backup", ""},
+		{http.MethodGet, "/admin:backups", ""},
+		{http.MethodPost, "/admin:restore", `{"file": "moon.db"}`},
+	} {
+		if w := serveWithKey(srv, adminKey, req.method, req.path, req.body); w.Code != http.StatusNotImplemented {
+			t.Errorf("%s: expected 501, got %d: %s", req.path, w.Code, w.Body.String())
+		}
+	}
+}
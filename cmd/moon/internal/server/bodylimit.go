@@ -0,0 +1,124 @@
+package server
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// setBodyLimit overrides server.max_body_bytes for a data action; the data
+// handlers check their batch and import limits themselves, so the global
+// limit must not cut them short. Non-positive limits are ignored.
+func (s *Server) setBodyLimit(action string, limit int) {
+	if limit > 0 {
+		s.bodyLimits[action] = int64(limit)
+	}
+}
+
+// dataAction returns the action of a request to a collection, e.g. "create"
+func (s *Server) dataAction(r *http.Request) (string, bool) {
+	path := strings.TrimPrefix(r.URL.Path, s.config.Server.Prefix+"/")
+	name, action, ok := strings.Cut(path, ":")
+	if !ok || constants.IsReservedEndpointName(name) {
+		return "", false
+	}
+	return action, true
+}
+
+// bodyLimit returns the body limit of a request: the override of its data
+// action if there is one, otherwise server.max_body_bytes
+func (s *Server) bodyLimit(r *http.Request) int64 {
+	if action, ok := s.dataAction(r); ok {
+		if limit, found := s.bodyLimits[action]; found {
+			return limit
+		}
+	}
+	if s.config.Server.MaxBodyBytes > 0 {
+		return int64(s.config.Server.MaxBodyBytes)
+	}
+	return int64(config.Defaults.Server.MaxBodyBytes)
+}
+
+// acceptsCompressedBody reports whether a request may send a gzip body: the
+// POST data actions, whose limits are overridden, and batch:transact
+func (s *Server) acceptsCompressedBody(r *http.Request) bool {
+	if action, ok := s.dataAction(r); ok {
+		_, found := s.bodyLimits[action]
+		return found
+	}
+	return r.URL.Path == s.config.Server.Prefix+"/batch:transact"
+}
+
+// bodyLimitMiddleware caps the request body of every endpoint so no handler
+// reads an unbounded body into memory. A declared Content-Length over the
+// limit is rejected with 413 up front; otherwise the body is wrapped in
+// http.MaxBytesReader and reads stop at the limit, which handlers report as 413.
+// A gzip body is decompressed here, and the limit bounds both the compressed
+// and the decompressed size, so a small body expanding past it still gets 413.
+func (s *Server) bodyLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := s.bodyLimit(r)
+		if r.ContentLength > limit {
+			s.writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, fmt.Sprintf("payload size %d exceeds limit of %d bytes", r.ContentLength, limit))
+			return
+		}
+		if r.Body == nil || r.Body == http.NoBody {
+			next(w, r)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		if encoding := strings.TrimSpace(r.Header.Get("Content-Encoding")); encoding != "" && !strings.EqualFold(encoding, "identity") {
+			if !strings.EqualFold(encoding, "gzip") || !s.acceptsCompressedBody(r) {
+				s.writeError(w, r, http.StatusUnsupportedMediaType, apperrors.CodeUnsupportedEncoding, fmt.Sprintf("Content-Encoding '%s' is not supported here; gzip is accepted on POST data actions and batch:transact", encoding))
+				return
+			}
+			if !s.decompressBody(w, r, limit) {
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// decompressBody replaces a gzip request body with its decompressed content,
+// itself capped at limit. Handlers then see a plain body of unknown length.
+func (s *Server) decompressBody(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		s.writeDecompressError(w, r, err)
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, gzipBody{Reader: zr, body: r.Body}, limit)
+	r.Header.Del("Content-Encoding")
+	r.ContentLength = -1
+	return true
+}
+
+// writeDecompressError reports a body that could not be decompressed: 413
+// when the compressed body alone exceeds the limit, otherwise 400
+func (s *Server) writeDecompressError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		s.writeError(w, r, http.StatusRequestEntityTooLarge, apperrors.CodePayloadTooLarge, fmt.Sprintf("payload exceeds limit of %d bytes", maxBytesErr.Limit))
+		return
+	}
+	s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, fmt.Sprintf("invalid gzip request body: %v", err))
+}
+
+// gzipBody closes the original request body along with the decompressor
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	r    io.Reader
+	read int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	return n, err
+}
+
+// serveBody sends a request through the full server handler; contentLength -1
+// makes the body chunked
+func serveBody(srv *Server, key, path string, body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.ContentLength = contentLength
+	req.Header.Set(constants.HeaderAPIKey, key)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+func assertPayloadTooLarge(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["code"] != string(apperrors.CodePayloadTooLarge) {
+		t.Errorf("expected code %s, got %v", apperrors.CodePayloadTooLarge, resp["code"])
+	}
+}
+
+func TestBodyLimit_CollectionsCreate(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+	srv.config.Server.MaxBodyBytes = 64 * 1024
+
+	// An oversized collections:create body: {"name": "aaa...
+	oversized := func(size int64) *countingReader {
+		return &countingReader{r: io.MultiReader(strings.NewReader(`{"name": "`), io.LimitReader(neverEnding('a'), size))}
+	}
+
+	// A declared length over the limit is refused before the body is read
+	body := oversized(1 << 20)
+	assertPayloadTooLarge(t, serveBody(srv, adminKey, "/collections:create", body, 1<<20))
+	if body.read != 0 {
+		t.Errorf("expected the body to stay unread, read %d bytes", body.read)
+	}
+
+	// A chunked body stops being read at the limit
+	body = oversized(64 << 20)
+	assertPayloadTooLarge(t, serveBody(srv, adminKey, "/collections:create", body, -1))
+	if body.read > 2*64*1024 {
+		t.Errorf("expected reading to stop near the 64 KB limit, read %d bytes", body.read)
+	}
+
+	// The same applies to the other endpoints decoding JSON
+	body = oversized(64 << 20)
+	assertPayloadTooLarge(t, serveBody(srv, "", "/auth:login", body, -1))
+	if body.read > 2*64*1024 {
+		t.Errorf("expected reading to stop near the 64 KB limit, read %d bytes", body.read)
+	}
+
+	// Bodies within the limit are served as before
+	w := serveBody(srv, adminKey, "/collections:create", strings.NewReader(`{"name": "notes", "columns": [{"name": "body", "type": "string"}]}`), -1)
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBodyLimit_DataOverride(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+	srv.config.Server.MaxBodyBytes = 1024
+
+	// Data writes keep the batch payload limit, larger than server.max_body_bytes
+	record := `{"data": {"body": "` + strings.Repeat("x", 8*1024) + `"}}`
+	w := serveBody(srv, adminKey, "/logs:create", strings.NewReader(record), int64(len(record)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201 under the batch limit, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// System endpoints with the same action do not get the override
+	w = serveBody(srv, adminKey, "/collections:create", strings.NewReader(record), int64(len(record)))
+	assertPayloadTooLarge(t, w)
+
+	// The batch limit still applies to data writes
+	record = `{"data": {"body": "` + strings.Repeat("x", srv.config.Batch.MaxPayloadBytes) + `"}}`
+	assertPayloadTooLarge(t, serveBody(srv, adminKey, "/logs:create", strings.NewReader(record), int64(len(record))))
+}
+
+// neverEnding is an endless reader of one byte
+type neverEnding byte
+
+func (b neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// maxForwardedHops bounds the X-Forwarded-For entries inspected per request
+// so an oversized header cannot make resolution expensive
+const maxForwardedHops = 32
+
+// clientIP resolves the effective client IP of a request. X-Forwarded-For is
+// only believed when the direct peer is a trusted proxy; it is then walked
+// from the right, skipping trusted hops, and the first untrusted address is
+// the client. A malformed entry stops the walk at the last hop known to be
+// genuine. With no trusted proxies configured the peer address is used as is.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, ok := parseHop(r.RemoteAddr)
+	if !ok {
+		// Not an address (e.g. a unix socket or a test request): report it
+		// without the port rather than guessing
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	}
+	if !isTrusted(peer, trusted) {
+		return peer.String()
+	}
+
+	hops := forwardedHops(r.Header.Values("X-Forwarded-For"))
+	client := peer
+	for i := len(hops) - 1; i >= 0 && len(hops)-i <= maxForwardedHops; i-- {
+		hop, ok := parseHop(hops[i])
+		if !ok {
+			break
+		}
+		client = hop
+		if !isTrusted(hop, trusted) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// forwardedHops splits X-Forwarded-For values, which may be repeated headers
+// or comma-separated lists, into hops ordered from client to nearest proxy
+func forwardedHops(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseHop parses an address with or without a port: "203.0.113.7",
+// "203.0.113.7:5000", "2001:db8::1" or "[2001:db8::1]:5000". IPv4-mapped IPv6
+// addresses are unmapped so they match IPv4 prefixes.
+func parseHop(s string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// isTrusted reports whether an address belongs to a trusted proxy
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.7/32"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{"no proxies configured trusts nothing", nil, "10.0.0.1:4000", []string{"203.0.113.9"}, "10.0.0.1"},
+		{"no proxies configured without header", nil, "198.51.100.2:4000", nil, "198.51.100.2"},
+		{"spoofed header from untrusted peer", trusted, "198.51.100.2:4000", []string{"203.0.113.9"}, "198.51.100.2"},
+		{"trusted peer without header", trusted, "10.0.0.1:4000", nil, "10.0.0.1"},
+		{"single trusted proxy", trusted, "10.0.0.1:4000", []string{"203.0.113.9"}, "203.0.113.9"},
+		{"nested proxies", trusted, "10.0.0.1:4000", []string{"203.0.113.9, 192.168.1.7, 10.1.2.3"}, "203.0.113.9"},
+		{"nested proxies in repeated headers", trusted, "10.0.0.1:4000", []string{"203.0.113.9", "10.1.2.3"}, "203.0.113.9"},
+		{"spoofed entry left of the client is ignored", trusted, "10.0.0.1:4000", []string{"1.2.3.4, 203.0.113.9, 10.1.2.3"}, "203.0.113.9"},
+		{"untrusted hop between proxies", trusted, "10.0.0.1:4000", []string{"203.0.113.9, 198.51.100.2, 10.1.2.3"}, "198.51.100.2"},
+		{"all hops trusted", trusted, "10.0.0.1:4000", []string{"10.2.0.1, 10.1.2.3"}, "10.2.0.1"},
+		{"IPv6 peer with port", trusted, "[fd00::1]:4000", []string{"2001:db8::5"}, "2001:db8::5"},
+		{"IPv6 untrusted peer with port", trusted, "[2001:db8::9]:4000", []string{"203.0.113.9"}, "2001:db8::9"},
+		{"IPv6 hop with port", trusted, "10.0.0.1:4000", []string{"[2001:db8::5]:51000"}, "2001:db8::5"},
+		{"IPv4 hop with port", trusted, "10.0.0.1:4000", []string{"203.0.113.9:51000"}, "203.0.113.9"},
+		{"IPv4-mapped peer matches IPv4 prefix", trusted, "[::ffff:10.0.0.1]:4000", []string{"203.0.113.9"}, "203.0.113.9"},
+		{"malformed hop stops at last genuine hop", trusted, "10.0.0.1:4000", []string{"203.0.113.9, not-an-ip, 10.1.2.3"}, "10.1.2.3"},
+		{"malformed header falls back to peer", trusted, "10.0.0.1:4000", []string{"garbage"}, "10.0.0.1"},
+		{"empty header entries", trusted, "10.0.0.1:4000", []string{" , ,"}, "10.0.0.1"},
+		{"unparseable remote addr", trusted, "@unix", []string{"203.0.113.9"}, "@unix"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(req, tt.trusted); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware_ClientIP(t *testing.T) {
+	cfg := &config.AppConfig{Server: config.ServerConfig{TrustedProxies: []string{"10.0.0.0/8"}}}
+	srv := &Server{config: cfg}
+	srv.trustedProxies, _ = cfg.Server.TrustedProxyPrefixes()
+
+	var got string
+	handler := srv.loggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		got = logging.GetClientIP(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = "10.0.0.1:4000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	handler(httptest.NewRecorder(), req)
+	if got != "203.0.113.9" {
+		t.Errorf("Expected client IP 203.0.113.9 in context, got %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+// compressibleTypes are the media types gzipped for clients that accept it
+var compressibleTypes = []string{
+	constants.MIMEApplicationJSON,
+	constants.MIMEApplicationNDJSON,
+	"text/csv",
+	"text/markdown",
+	"text/html",
+}
+
+// gzipWriters pools compressors, which are costly to allocate per response
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+
+// compressionMiddleware gzips responses of a compressible type once their body
+// reaches server.compression_min_bytes, for clients whose Accept-Encoding
+// allows gzip. The body is held back until the threshold or the end of the
+// response decides, so small responses keep their Content-Length. Headers
+// such as ETag are left alone: a 304 has no body, so the handlers' If-None-Match
+// checks are unaffected.
+func (s *Server) compressionMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		minBytes := s.config.Server.CompressionMinBytes
+		if minBytes < 0 || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, minBytes: minBytes, accepted: acceptsGzip(r)}
+		defer gw.close()
+		next(gw, r)
+	}
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// to compress it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minBytes int
+	accepted bool         // the client accepts gzip
+	status   int          // status held back with the body; 0 until set
+	buf      []byte       // body held back below minBytes
+	started  bool         // headers are written
+	gz       *gzip.Writer // nil when the body goes out as is
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.started || g.status != 0 {
+		return
+	}
+	g.status = code
+	if !bodyAllowed(code) {
+		g.start()
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.started {
+		if g.gz != nil {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= g.minBytes {
+		if err := g.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush writes the held back part of a streamed response; whether it is
+// compressed is decided by what has been written so far
+func (g *gzipResponseWriter) Flush() {
+	if !g.started {
+		g.start()
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// start decides on compression and writes the headers and the held back body
+func (g *gzipResponseWriter) start() error {
+	g.started = true
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+
+	h := g.Header()
+	if compressible(h) {
+		h.Add("Vary", "Accept-Encoding")
+		if g.accepted && h.Get("Content-Encoding") == "" && bodyAllowed(g.status) && len(g.buf) > 0 && len(g.buf) >= g.minBytes {
+			h.Set("Content-Encoding", "gzip")
+			h.Del("Content-Length")
+			g.gz = gzipWriters.Get().(*gzip.Writer)
+			g.gz.Reset(g.ResponseWriter)
+		}
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if g.gz != nil {
+		_, err := g.gz.Write(buf)
+		return err
+	}
+	_, err := g.ResponseWriter.Write(buf)
+	return err
+}
+
+// close ends the response, writing a body that never reached the threshold
+func (g *gzipResponseWriter) close() {
+	if !g.started {
+		if g.status == 0 && len(g.buf) == 0 {
+			// Nothing was written; leave the implicit 200 to net/http
+			return
+		}
+		g.start()
+	}
+	if g.gz != nil {
+		g.gz.Close()
+		gzipWriters.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// compressible reports whether a response's Content-Type is worth gzipping
+func compressible(h http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(h.Get(constants.HeaderContentType))
+	return err == nil && slices.Contains(compressibleTypes, mediaType)
+}
+
+// bodyAllowed reports whether a response with the status may have a body
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip,
+// honouring q=0 and the * wildcard
+func acceptsGzip(r *http.Request) bool {
+	accepted := false
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "x-gzip" && name != "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name != "*" {
+			// An explicit gzip entry overrides the wildcard
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// serveGet sends a GET through the full server handler with the given
+// Accept-Encoding
+func serveGet(srv *Server, key, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(constants.HeaderAPIKey, key)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+// gzipped compresses data
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// gunzipped decompresses a response body
+func gunzipped(t *testing.T, w *httptest.ResponseRecorder) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("failed to open gzip body: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress body: %v", err)
+	}
+	return data
+}
+
+func TestCompression_Responses(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+	srv.config.Server.CompressionMinBytes = 1024
+	for range 20 {
+		if w := serveWithKey(srv, adminKey, http.MethodPost, "/logs:create", `{"data": {"body": "`+strings.Repeat("x", 200)+`"}}`); w.Code != http.StatusCreated {
+			t.Fatalf("failed to create log: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	plain := serveGet(srv, adminKey, "/logs:list", "")
+	if plain.Code != http.StatusOK || plain.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected a plain 200 without Accept-Encoding, got %d %q", plain.Code, plain.Header().Get("Content-Encoding"))
+	}
+	if vary := plain.Header().Values("Vary"); !strings.Contains(strings.Join(vary, ","), "Accept-Encoding") {
+		t.Errorf("expected Vary to name Accept-Encoding, got %v", vary)
+	}
+
+	// The compressed list decompresses to the plain one
+	w := serveGet(srv, adminKey, "/logs:list", "br, gzip")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzipped 200, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Content-Length") != "" {
+		t.Errorf("expected no Content-Length, got %s", w.Header().Get("Content-Length"))
+	}
+	if w.Body.Len() >= plain.Body.Len() {
+		t.Errorf("expected the gzipped body to be smaller, got %d >= %d bytes", w.Body.Len(), plain.Body.Len())
+	}
+	if got := gunzipped(t, w); !bytes.Equal(got, plain.Body.Bytes()) {
+		t.Errorf("expected the decompressed body to match:\n%s\n%s", got, plain.Body.String())
+	}
+
+	// Bodies under the threshold and clients refusing gzip get plain responses
+	for _, tt := range []struct{ path, acceptEncoding string }{
+		{"/logs:count", "gzip"},
+		{"/logs:list", "gzip;q=0, *"},
+		{"/logs:list", "identity"},
+	} {
+		if w := serveGet(srv, adminKey, tt.path, tt.acceptEncoding); w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s with %q: expected a plain 200, got %d %q", tt.path, tt.acceptEncoding, w.Code, w.Header().Get("Content-Encoding"))
+		}
+	}
+
+	// A negative threshold turns compression off
+	srv.config.Server.CompressionMinBytes = -1
+	if w := serveGet(srv, adminKey, "/logs:list", "gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected no compression when disabled, got %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompression_DocETag(t *testing.T) {
+	srv, _, _ := setupFileTestServer(t)
+	srv.config.Server.CompressionMinBytes = 1024
+
+	w := serveGet(srv, "", "/doc/llms.md", "gzip")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzipped 200, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+	plain := serveGet(srv, "", "/doc/llms.md", "")
+	if got := gunzipped(t, w); !bytes.Equal(got, plain.Body.Bytes()) {
+		t.Errorf("expected the decompressed document to match the plain one")
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || etag != plain.Header().Get("ETag") {
+		t.Fatalf("expected the same ETag for both encodings, got %q and %q", etag, plain.Header().Get("ETag"))
+	}
+
+	// A revalidation is answered with an empty, uncompressed 304
+	req := httptest.NewRequest(http.MethodGet, "/doc/llms.md", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected an empty 304 without Content-Encoding, got %d %q with %d bytes", w.Code, w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+}
+
+func TestCompression_RequestBodies(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+
+	serveGzip := func(path string, body []byte, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set(constants.HeaderAPIKey, adminKey)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// A gzipped batch creates the same records as a plain one
+	batch := []byte(`{"data": [{"body": "one"}, {"body": "two"}]}`)
+	if w := serveGzip("/logs:create", gzipped(t, batch), "gzip"); w.Code != http.StatusMultiStatus || !strings.Contains(w.Body.String(), `"succeeded":2`) {
+		t.Fatalf("expected both records of a gzipped batch created, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveGet(srv, adminKey, "/logs:count", ""); !strings.Contains(w.Body.String(), `"value":2`) {
+		t.Errorf("expected 2 records, got %s", w.Body.String())
+	}
+
+	// A few KB expanding past batch.max_payload_bytes is refused
+	bomb := gzipped(t, []byte(`{"data": {"body": "`+strings.Repeat("x", 2*srv.config.Batch.MaxPayloadBytes)+`"}}`))
+	if len(bomb) > 64*1024 {
+		t.Fatalf("expected a small compressed body, got %d bytes", len(bomb))
+	}
+	assertPayloadTooLarge(t, serveGzip("/logs:create", bomb, "gzip"))
+
+	tests := []struct {
+		path     string
+		body     []byte
+		encoding string
+		status   int
+		code     apperrors.ErrorCode
+	}{
+		{"/collections:create", gzipped(t, []byte(`{"name": "notes"}`)), "gzip", http.StatusUnsupportedMediaType, apperrors.CodeUnsupportedEncoding},
+		{"/logs:create", batch, "br", http.StatusUnsupportedMediaType, apperrors.CodeUnsupportedEncoding},
+		{"/logs:create", batch, "gzip", http.StatusBadRequest, apperrors.CodeInvalidInput},
+	}
+	for _, tt := range tests {
+		w := serveGzip(tt.path, tt.body, tt.encoding)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), string(tt.code)) {
+			t.Errorf("%s with %s: expected %d %s, got %d: %s", tt.path, tt.encoding, tt.status, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"gzip;q=0, *", false},
+		{"br, identity", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", tt.header)
+		if got := acceptsGzip(req); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/consistency"
+)
+
+func TestConsistencyEndpoint(t *testing.T) {
+	srv, adminKey := setupScopeTestServer(t)
+
+	check := func(path string) consistency.CheckResult {
+		t.Helper()
+		w := serveWithKey(srv, adminKey, http.MethodGet, path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		var result consistency.CheckResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		return result
+	}
+
+	if result := check("/admin:consistency"); !result.Consistent {
+		t.Fatalf("expected a fresh database to be consistent, got %+v", result.Issues)
+	}
+
+	// Drift the products table behind the registry's back
+	if _, err := srv.db.Exec(context.Background(), "ALTER TABLE products ADD COLUMN notes TEXT"); err != nil {
+		t.Fatalf("failed to alter table: %v", err)
+	}
+
+	result := check("/admin:consistency")
+	if result.Consistent || len(result.Issues) != 1 || result.Issues[0].Type != consistency.IssueExtraColumn || result.Issues[0].Repaired {
+		t.Fatalf("expected one unrepaired extra column, got %+v", result.Issues)
+	}
+	if collection, _ := srv.registry.Get("products"); len(collection.Columns) != 1 {
+		t.Errorf("expected a report-only check to leave the registry alone, got %+v", collection.Columns)
+	}
+
+	result = check("/admin:consistency?repair=true")
+	if len(result.Issues) != 1 || !result.Issues[0].Repaired {
+		t.Fatalf("expected the extra column to be repaired, got %+v", result.Issues)
+	}
+	if result := check("/admin:consistency"); !result.Consistent {
+		t.Errorf("expected consistency after repair, got %+v", result.Issues)
+	}
+
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/admin:consistency?repair=maybe", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid repair flag, got %d", w.Code)
+	}
+
+	userKey := createScopedKey(t, srv, "user", "user", nil)
+	if w := serveWithKey(srv, userKey, http.MethodGet, "/admin:consistency", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin key, got %d", w.Code)
+	}
+	schemaless := createScopedKey(t, srv, "data-admin", "admin", auth.Scopes{{Collection: "*", Actions: []string{auth.ScopeRead}}})
+	assertScopeDenied(t, serveWithKey(srv, schemaless, http.MethodGet, "/admin:consistency", ""))
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/datetime"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+)
+
+// datetimeMigration reports the normalize_datetimes maintenance operation
+type datetimeMigration struct {
+	Rewritten   int `json:"rewritten"`   // values rewritten as UTC RFC3339
+	Unparseable int `json:"unparseable"` // ambiguous or unknown values left unchanged
+}
+
+// datetimeRewrite is a stored datetime value and its normalized form
+type datetimeRewrite struct {
+	id    string
+	value string
+}
+
+// normalizeDatetimes rewrites the datetime values stored before datetime input
+// was validated into the UTC RFC3339 form written today. Only SQLite keeps
+// datetime columns as TEXT; PostgreSQL and MySQL columns are typed, so there
+// is nothing to rewrite. Values are read back with datetime.ParseLegacy and
+// ones that cannot be read unambiguously are counted and left as they are.
+// Record revisions and updated_at are not changed: the stored time is the same.
+func (s *Server) normalizeDatetimes(ctx context.Context) (*datetimeMigration, error) {
+	result := &datetimeMigration{}
+	if s.db.Dialect() != database.DialectSQLite {
+		return result, nil
+	}
+
+	for _, collection := range s.registry.GetAll() {
+		for _, col := range collection.Columns {
+			if col.Type != registry.TypeDatetime {
+				continue
+			}
+			rewrites, unparseable, err := s.datetimeRewrites(ctx, collection.Name, col.Name)
+			if err != nil {
+				return nil, err
+			}
+			result.Unparseable += unparseable
+			if len(rewrites) == 0 {
+				continue
+			}
+			if err := s.applyDatetimeRewrites(ctx, collection.Name, col.Name, rewrites); err != nil {
+				return nil, err
+			}
+			result.Rewritten += len(rewrites)
+		}
+	}
+	return result, nil
+}
+
+// datetimeRewrites reads the values of a datetime column that are not in the
+// storage format and returns the ones that can be normalized, with the count
+// of the ones that cannot
+func (s *Server) datetimeRewrites(ctx context.Context, table, column string) ([]datetimeRewrite, int, error) {
+	quotedCol := query.QuoteIdent(database.DialectSQLite, column)
+	rows, err := s.db.Query(ctx, fmt.Sprintf("SELECT id, %s FROM %s WHERE %s IS NOT NULL",
+		quotedCol, query.QuoteIdent(database.DialectSQLite, table), quotedCol))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+	}
+	defer rows.Close()
+
+	var rewrites []datetimeRewrite
+	unparseable := 0
+	for rows.Next() {
+		var id string
+		var stored any
+		if err := rows.Scan(&id, &stored); err != nil {
+			return nil, 0, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+		}
+		if b, isBytes := stored.([]byte); isBytes {
+			stored = string(b)
+		}
+		value, ok := stored.(string)
+		if !ok {
+			// Numbers or blobs were never valid datetimes
+			unparseable++
+			continue
+		}
+		t, ok := datetime.ParseLegacy(value)
+		if !ok {
+			unparseable++
+			continue
+		}
+		if normalized := datetime.Format(t); normalized != value {
+			rewrites = append(rewrites, datetimeRewrite{id: id, value: normalized})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+	}
+	return rewrites, unparseable, nil
+}
+
+// applyDatetimeRewrites writes the normalized values of a column in one transaction
+func (s *Server) applyDatetimeRewrites(ctx context.Context, table, column string, rewrites []datetimeRewrite) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?",
+		query.QuoteIdent(database.DialectSQLite, table), query.QuoteIdent(database.DialectSQLite, column))
+	for _, rewrite := range rewrites {
+		if _, err := tx.ExecContext(ctx, update, rewrite.value, rewrite.id); err != nil {
+			return fmt.Errorf("failed to rewrite %s.%s: %w", table, column, err)
+		}
+	}
+	return tx.Commit()
+}
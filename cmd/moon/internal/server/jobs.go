@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/pagination"
+)
+
+// jobStatuses are the values of the status filter of GET /admin:jobs
+var jobStatuses = []string{jobs.StatusRunning, jobs.StatusCompleted, jobs.StatusFailed, jobs.StatusInterrupted}
+
+// jobListResponse is the response of GET /admin:jobs
+type jobListResponse struct {
+	Jobs       []jobs.Job `json:"jobs"`
+	NextCursor *string    `json:"next_cursor"`
+	Limit      int        `json:"limit"`
+}
+
+// InitJobs creates the table journaling imports and batch creates, marks the
+// jobs a previous process left running as interrupted and deletes the jobs
+// older than jobs.retention_days
+func (s *Server) InitJobs(ctx context.Context) error {
+	if err := s.jobs.Init(ctx); err != nil {
+		return err
+	}
+	interrupted, err := s.jobs.Interrupt(ctx)
+	if err != nil {
+		return err
+	}
+	if interrupted > 0 {
+		logging.Warnf("Marked %d jobs interrupted by the last shutdown; imports among them can be resumed", interrupted)
+	}
+	if days := s.config.Jobs.RetentionDays; days > 0 {
+		pruned, err := s.jobs.Prune(ctx, days)
+		if err != nil {
+			return err
+		}
+		logging.Infof("Pruned %d jobs older than %d days", pruned, days)
+	}
+	return nil
+}
+
+// listJobsHandler handles GET /admin:jobs: jobs oldest first, optionally of
+// one collection or status, paged by the id of the last job of the previous
+// page
+func (s *Server) listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	// Jobs cover every collection, so the key must hold the read scope on all of them
+	if !middleware.HasScope(r.Context(), auth.ScopeAllCollections, auth.ScopeRead) {
+		middleware.WriteScopeError(w, r, auth.ScopeAllCollections, auth.ScopeRead)
+		return
+	}
+
+	params := r.URL.Query()
+	limit := pagination.GetDefaultPageSize(s.config)
+	if raw := params.Get(constants.QueryParamLimit); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "limit must be an integer")
+			return
+		}
+		if err := pagination.ValidatePageSize(parsed, s.config); err != nil {
+			s.writeError(w, r, http.StatusBadRequest, apperrors.CodePageSizeExceeded, err.Error())
+			return
+		}
+		limit = parsed
+	}
+	after := params.Get("after")
+	if err := pagination.ValidateCursor(after); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidCursor, "after must be the id of a job")
+		return
+	}
+	status := params.Get("status")
+	if status != "" && !slices.Contains(jobStatuses, status) {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "status must be running, completed, failed or interrupted")
+		return
+	}
+
+	list, err := s.jobs.List(r.Context(), jobs.Filter{
+		Collection: params.Get("collection"),
+		Status:     status,
+		After:      after,
+		Limit:      limit + 1, // one extra to tell whether there is a next page
+	})
+	if err != nil {
+		log.Printf("ERROR: Job query failed: %v", err)
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to read the jobs")
+		return
+	}
+
+	var nextCursor *string
+	if len(list) > limit {
+		list = list[:limit]
+		cursor := list[len(list)-1].ID
+		nextCursor = &cursor
+	}
+
+	s.writeJSON(w, http.StatusOK, jobListResponse{
+		Jobs:       list,
+		NextCursor: nextCursor,
+		Limit:      limit,
+	})
+}
+
+// getJobHandler handles GET /admin:jobs:get?id=: one job, for a key with the
+// read scope on its collection
+func (s *Server) getJobHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, "id is required")
+		return
+	}
+
+	job, err := s.jobs.Get(r.Context(), id)
+	if errors.Is(err, jobs.ErrNotFound) {
+		s.writeError(w, r, http.StatusNotFound, apperrors.CodeJobNotFound, "job '"+id+"' not found")
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR: Job query failed: %v", err)
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, "failed to read the job")
+		return
+	}
+	if !middleware.HasScope(r.Context(), job.Collection, auth.ScopeRead) {
+		middleware.WriteScopeError(w, r, job.Collection, auth.ScopeRead)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, job)
+}
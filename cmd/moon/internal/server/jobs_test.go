@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+)
+
+func TestJobs_ListAndGet(t *testing.T) {
+	srv, adminKey := setupScopeTestServer(t)
+	ctx := context.Background()
+	if err := srv.InitJobs(ctx); err != nil {
+		t.Fatalf("InitJobs() error = %v", err)
+	}
+
+	w := serveWithKey(srv, adminKey, http.MethodPost, "/products:create?journal=true", `{"data": [{"title": "a"}, {"title": "b"}]}`)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	jobID := w.Header().Get("X-Moon-Job")
+	running := &jobs.Job{Operation: jobs.OperationImport, Collection: "orders", Total: 10}
+	if err := srv.jobs.Start(ctx, running); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	w = serveWithKey(srv, adminKey, http.MethodGet, "/admin:jobs?limit=1", "")
+	var page jobListResponse
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if w.Code != http.StatusOK || len(page.Jobs) != 1 || page.Jobs[0].ID != jobID || page.NextCursor == nil {
+		t.Fatalf("unexpected first page: %d %s", w.Code, w.Body.String())
+	}
+	if job := page.Jobs[0]; job.Status != jobs.StatusCompleted || job.Collection != "products" || job.Succeeded != 2 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	w = serveWithKey(srv, adminKey, http.MethodGet, "/admin:jobs?status=running", "")
+	page = jobListResponse{}
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Jobs) != 1 || page.Jobs[0].ID != running.ID {
+		t.Errorf("expected the running job only, got %s", w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/admin:jobs?status=done", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown status, got %d", w.Code)
+	}
+
+	w = serveWithKey(srv, adminKey, http.MethodGet, "/admin:jobs:get?id="+running.ID, "")
+	var job jobs.Job
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if w.Code != http.StatusOK || job.Total != 10 || job.Status != jobs.StatusRunning {
+		t.Errorf("unexpected job: %d %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/admin:jobs:get?id=01ARZ3NDEKTSV4RRFFQ69G5FAV", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", w.Code)
+	}
+
+	// Keys need the read scope on the job's collection, and on all for the list
+	scoped := createScopedKey(t, srv, "scoped", "admin", auth.Scopes{{Collection: "products", Actions: []string{auth.ScopeRead}}})
+	if w := serveWithKey(srv, scoped, http.MethodGet, "/admin:jobs:get?id="+jobID, ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a job of a readable collection, got %d", w.Code)
+	}
+	if w := serveWithKey(srv, scoped, http.MethodGet, "/admin:jobs:get?id="+running.ID, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a job of another collection, got %d", w.Code)
+	}
+	if w := serveWithKey(srv, scoped, http.MethodGet, "/admin:jobs", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for the list, got %d", w.Code)
+	}
+
+	// A restart marks the running job interrupted
+	if err := srv.InitJobs(ctx); err != nil {
+		t.Fatalf("InitJobs() error = %v", err)
+	}
+	if job, _ := srv.jobs.Get(ctx, running.ID); job.Status != jobs.StatusInterrupted {
+		t.Errorf("expected the running job to be interrupted, got %s", job.Status)
+	}
+}
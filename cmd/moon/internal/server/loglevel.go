@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+)
+
+// logLevelRequest is the body of POST /admin:loglevel. Without a module the
+// base level changes; a module without a level falls back to the base level.
+type logLevelRequest struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}
+
+// logLevelResult is the response of POST /admin:loglevel: the levels in
+// effect after the change
+type logLevelResult struct {
+	Level   logging.Level            `json:"level"`
+	Modules map[string]logging.Level `json:"modules"`
+}
+
+// logLevelHandler handles POST /admin:loglevel. Changes apply immediately and
+// last until the next change or restart; the config file is not rewritten.
+func (s *Server) logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	// Log levels cover every collection, so the key must hold the schema scope on all of them
+	if !middleware.HasScope(r.Context(), auth.ScopeAllCollections, auth.ScopeSchema) {
+		middleware.WriteScopeError(w, r, auth.ScopeAllCollections, auth.ScopeSchema)
+		return
+	}
+
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
+		return
+	}
+
+	switch {
+	case req.Module != "" && req.Level == "":
+		logging.ClearModuleLevel(req.Module)
+		log.Printf("INFO: Log level override of module %s removed", req.Module)
+	case req.Level == "":
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, "level is required")
+		return
+	default:
+		level, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, err.Error())
+			return
+		}
+		if req.Module == "" {
+			logging.SetLevel(level)
+			log.Printf("INFO: Log level set to %s", level)
+		} else {
+			logging.SetModuleLevel(req.Module, level)
+			log.Printf("INFO: Log level of module %s set to %s", req.Module, level)
+		}
+	}
+
+	base, modules := logging.Levels()
+	s.writeJSON(w, http.StatusOK, logLevelResult{Level: base, Modules: modules})
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+)
+
+func TestLogLevelEndpoint(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+
+	var buf bytes.Buffer
+	logging.Init(logging.LoggerConfig{Level: logging.LevelInfo, Format: "json", Output: &buf})
+	t.Cleanup(func() { logging.Init(logging.LoggerConfig{Level: logging.LevelInfo, Format: "json"}) })
+
+	setLevel := func(body string) logLevelResult {
+		t.Helper()
+		w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:loglevel", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var result logLevelResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		return result
+	}
+
+	result := setLevel(`{"module": "handlers", "level": "DEBUG"}`)
+	if result.Level != logging.LevelInfo || result.Modules["handlers"] != logging.LevelDebug {
+		t.Fatalf("unexpected levels: %+v", result)
+	}
+
+	const secret = "top-secret-value"
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/logs:create",
+		`{"data": [{"body": "`+secret+`"}, {"body": "other"}]}`); w.Code != http.StatusMultiStatus {
+		t.Fatalf("create failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/logs:list?body[eq]="+secret, ""); w.Code != http.StatusOK {
+		t.Fatalf("list failed: %d %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/logs:count?body[eq]="+secret, ""); w.Code != http.StatusOK {
+		t.Fatalf("count failed: %d %s", w.Code, w.Body.String())
+	}
+
+	logs := buf.String()
+	for _, want := range []string{"create batch SQL: INSERT INTO", "list SQL: SELECT", "count SQL: SELECT COUNT(*)", `"module":"handlers"`} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("expected %q in logs, got:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, secret) {
+		t.Errorf("expected no argument values in logs, got:\n%s", logs)
+	}
+
+	// Removing the override returns the module to the base level
+	result = setLevel(`{"module": "handlers"}`)
+	if len(result.Modules) != 0 {
+		t.Fatalf("expected no module levels, got %+v", result)
+	}
+	buf.Reset()
+	serveWithKey(srv, adminKey, http.MethodGet, "/logs:list", "")
+	if buf.Len() != 0 {
+		t.Errorf("expected no debug lines at the base level, got:\n%s", buf.String())
+	}
+
+	if result := setLevel(`{"level": "warn"}`); result.Level != logging.LevelWarn {
+		t.Errorf("expected base level warn, got %+v", result)
+	}
+
+	for _, body := range []string{`{"level": "trace"}`, `{}`, `not json`} {
+		if w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:loglevel", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
+	"github.com/thalib/moon/cmd/moon/internal/query"
+)
+
+// Operations accepted by POST /admin:maintenance
+const (
+	maintenanceVacuum             = "vacuum"
+	maintenanceAnalyze            = "analyze"
+	maintenanceNormalizeDatetimes = "normalize_datetimes"
+)
+
+// maintenanceRequest is the body of POST /admin:maintenance
+type maintenanceRequest struct {
+	Operation string `json:"operation"`
+}
+
+// maintenanceResult is the response of POST /admin:maintenance. The sizes
+// cover a SQLite file database and its WAL and are omitted for other databases.
+type maintenanceResult struct {
+	Operation  string             `json:"operation"`
+	Duration   jsonDuration       `json:"duration"`
+	SizeBefore *int64             `json:"size_before,omitempty"`
+	SizeAfter  *int64             `json:"size_after,omitempty"`
+	Datetimes  *datetimeMigration `json:"datetimes,omitempty"` // normalize_datetimes only
+}
+
+// jsonDuration is a time.Duration that encodes as its string form, e.g.
+// "412.093551ms", the way ttl policy durations are written
+type jsonDuration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = jsonDuration(duration)
+	return nil
+}
+
+// maintenanceHandler handles POST /admin:maintenance. One operation runs at a
+// time; while VACUUM runs on SQLite, writes are rejected by writable.
+func (s *Server) maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	// The operation covers every collection, so the key must hold the schema scope on all of them
+	if !middleware.HasScope(r.Context(), auth.ScopeAllCollections, auth.ScopeSchema) {
+		middleware.WriteScopeError(w, r, auth.ScopeAllCollections, auth.ScopeSchema)
+		return
+	}
+
+	var req maintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidJSON, "invalid request body")
+		return
+	}
+	switch req.Operation {
+	case maintenanceVacuum, maintenanceAnalyze, maintenanceNormalizeDatetimes:
+	default:
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidInput, fmt.Sprintf("operation must be %s, %s or %s", maintenanceVacuum, maintenanceAnalyze, maintenanceNormalizeDatetimes))
+		return
+	}
+
+	if !s.maintenanceRunning.CompareAndSwap(false, true) {
+		s.writeError(w, r, http.StatusConflict, apperrors.CodeConflict, "another maintenance operation is already running")
+		return
+	}
+	defer s.maintenanceRunning.Store(false)
+
+	dialect := s.db.Dialect()
+	if req.Operation == maintenanceVacuum && dialect == database.DialectSQLite {
+		s.writesPaused.Store(true)
+		defer s.writesPaused.Store(false)
+	}
+
+	// The operation may outlast the server write timeout, so the response deadline is extended to match
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(constants.MaintenanceTimeout + constants.HTTPWriteTimeout)); err != nil {
+		log.Printf("WARNING: Failed to extend write deadline for maintenance: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), constants.MaintenanceTimeout)
+	defer cancel()
+
+	result := maintenanceResult{Operation: req.Operation}
+	path := s.sqliteFile()
+	if path != "" {
+		result.SizeBefore = sqliteFileSize(path)
+	}
+
+	start := time.Now()
+	var err error
+	if req.Operation == maintenanceNormalizeDatetimes {
+		result.Datetimes, err = s.normalizeDatetimes(ctx)
+	} else {
+		var statements []string
+		statements, err = s.maintenanceStatements(ctx, req.Operation)
+		if err == nil {
+			for _, statement := range statements {
+				if _, err = s.db.Exec(ctx, statement); err != nil {
+					break
+				}
+			}
+		}
+	}
+	result.Duration = jsonDuration(time.Since(start))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			s.writeError(w, r, http.StatusServiceUnavailable, apperrors.CodeQueryTimeout, fmt.Sprintf("%s timed out after %s", req.Operation, constants.MaintenanceTimeout))
+			return
+		}
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("%s failed: %v", req.Operation, err))
+		return
+	}
+
+	if path != "" {
+		result.SizeAfter = sqliteFileSize(path)
+	}
+	log.Printf("INFO: Maintenance %s completed in %s", req.Operation, time.Duration(result.Duration))
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// maintenanceStatements returns the statements of an operation for the
+// database dialect. VACUUM on SQLite is followed by a WAL checkpoint, as the
+// file only shrinks once the rewritten pages leave the WAL; MySQL has no
+// database-wide form, so every table is optimized or analyzed in turn.
+func (s *Server) maintenanceStatements(ctx context.Context, operation string) ([]string, error) {
+	switch s.db.Dialect() {
+	case database.DialectSQLite:
+		if operation == maintenanceVacuum {
+			return []string{"VACUUM", "PRAGMA wal_checkpoint(TRUNCATE)"}, nil
+		}
+		return []string{"ANALYZE"}, nil
+	case database.DialectMySQL:
+		tables, err := s.db.ListTables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		verb := "ANALYZE TABLE"
+		if operation == maintenanceVacuum {
+			verb = "OPTIMIZE TABLE"
+		}
+		statements := make([]string, len(tables))
+		for i, table := range tables {
+			statements[i] = fmt.Sprintf("%s %s", verb, query.QuoteIdent(database.DialectMySQL, table))
+		}
+		return statements, nil
+	default:
+		if operation == maintenanceVacuum {
+			return []string{"VACUUM"}, nil
+		}
+		return []string{"ANALYZE"}, nil
+	}
+}
+
+// sqliteFile returns the path of a SQLite file database, or "" for other
+// databases and in-memory SQLite
+func (s *Server) sqliteFile() string {
+	if s.db.Dialect() != database.DialectSQLite {
+		return ""
+	}
+	path := s.config.Database.Database
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+	return path
+}
+
+// sqliteFileSize returns the size in bytes of a SQLite database file and its
+// WAL, or nil if the database file cannot be read
+func sqliteFileSize(path string) *int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	size := info.Size()
+	if wal, err := os.Stat(path + "-wal"); err == nil {
+		size += wal.Size()
+	}
+	return &size
+}
+
+// writable rejects writes with 503 while VACUUM or a restore runs on SQLite;
+// they would otherwise queue for the single write connection until it finishes
+func (s *Server) writable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.writesPaused.Load() {
+			s.writeError(w, r, http.StatusServiceUnavailable, apperrors.CodeServiceUnavailable, "writes are paused while the database is vacuumed or restored; retry shortly")
+			return
+		}
+		next(w, r)
+	}
+}
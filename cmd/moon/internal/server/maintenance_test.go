@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// setupFileTestServer creates a server on a SQLite file in a temp directory
+func setupFileTestServer(t *testing.T) (*Server, string, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "moon.db")
+	cfg := &config.AppConfig{
+		Server:   config.ServerConfig{Port: 6006, Host: "0.0.0.0"},
+		Database: config.DatabaseConfig{Connection: "sqlite", Database: path},
+		JWT:      config.JWTConfig{Secret: "test-secret", Expiry: 3600},
+		Batch:    config.BatchConfig{MaxSize: config.Defaults.Batch.MaxSize, MaxPayloadBytes: config.Defaults.Batch.MaxPayloadBytes},
+	}
+
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://" + path, MaxOpenConns: 4, MaxIdleConns: 4})
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	adminKey := auth.APIKeyPrefix + strings.Repeat("a", auth.APIKeyLength)
+	if err := auth.Bootstrap(context.Background(), driver, &auth.BootstrapConfig{APIKey: adminKey}); err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+	srv := New(cfg, driver, registry.NewSchemaRegistry(), "1-test")
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:create",
+		`{"name": "logs", "columns": [{"name": "body", "type": "string"}]}`); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create logs: %d %s", w.Code, w.Body.String())
+	}
+	return srv, adminKey, path
+}
+
+func TestMaintenanceEndpoint_Vacuum(t *testing.T) {
+	srv, adminKey, path := setupFileTestServer(t)
+	ctx := context.Background()
+
+	tx, err := srv.db.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	body := strings.Repeat("x", 2000)
+	for range 2000 {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO logs (id, body) VALUES (?, ?)", ulid.Generate(), body); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if _, err := srv.db.Exec(ctx, "DELETE FROM logs WHERE pkid > 100"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	maintain := func(operation string) maintenanceResult {
+		t.Helper()
+		w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:maintenance", `{"operation": "`+operation+`"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", operation, w.Code, w.Body.String())
+		}
+		var result maintenanceResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		return result
+	}
+
+	w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:maintenance", `{"operation": "analyze"}`)
+	var raw map[string]any
+	json.Unmarshal(w.Body.Bytes(), &raw)
+	if _, ok := raw["duration"].(string); !ok {
+		t.Errorf("expected duration as a string such as \"30s\", got %v", raw["duration"])
+	}
+
+	result := maintain("vacuum")
+	if result.Operation != "vacuum" || result.Duration <= 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.SizeBefore == nil || result.SizeAfter == nil {
+		t.Fatalf("expected file sizes for SQLite, got %+v", result)
+	}
+	if *result.SizeAfter*2 > *result.SizeBefore {
+		t.Errorf("expected vacuum to at least halve the file, got %d -> %d bytes", *result.SizeBefore, *result.SizeAfter)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > *result.SizeAfter {
+		t.Errorf("expected the file on disk to match the reported size %d, got %v (%v)", *result.SizeAfter, info, err)
+	}
+
+	var count int
+	srv.db.QueryRow(ctx, "SELECT COUNT(*) FROM logs").Scan(&count)
+	if count != 100 {
+		t.Errorf("expected the remaining 100 records to survive, got %d", count)
+	}
+
+	if result := maintain("analyze"); result.Operation != "analyze" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestMaintenanceEndpoint_Guards(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:maintenance", `{"operation": "reindex"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown operation, got %d", w.Code)
+	}
+
+	// A second operation is refused while one runs
+	srv.maintenanceRunning.Store(true)
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:maintenance", `{"operation": "analyze"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 while maintenance runs, got %d: %s", w.Code, w.Body.String())
+	}
+	srv.maintenanceRunning.Store(false)
+
+	// Writes are refused while SQLite is vacuumed; reads go on
+	srv.writesPaused.Store(true)
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/logs:create", `{"data": {"body": "hello"}}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for a data write, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:destroy", `{"name": "logs"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 for a schema change, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, adminKey, http.MethodGet, "/logs:list", ""); w.Code != http.StatusOK {
+		t.Errorf("expected reads to continue, got %d: %s", w.Code, w.Body.String())
+	}
+	srv.writesPaused.Store(false)
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/logs:create", `{"data": {"body": "hello"}}`); w.Code != http.StatusCreated {
+		t.Errorf("expected writes to resume, got %d: %s", w.Code, w.Body.String())
+	}
+
+	userKey := createScopedKey(t, srv, "user", "user", nil)
+	if w := serveWithKey(srv, userKey, http.MethodPost, "/admin:maintenance", `{"operation": "analyze"}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin key, got %d", w.Code)
+	}
+	schemaless := createScopedKey(t, srv, "data-admin", "admin", auth.Scopes{{Collection: "*", Actions: []string{auth.ScopeRead, auth.ScopeWrite}}})
+	assertScopeDenied(t, serveWithKey(srv, schemaless, http.MethodPost, "/admin:maintenance", `{"operation": "analyze"}`))
+}
+
+func TestMaintenanceEndpoint_NormalizeDatetimes(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+	ctx := context.Background()
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:create",
+		`{"name": "events", "columns": [{"name": "title", "type": "string"}, {"name": "starts", "type": "datetime", "nullable": true}]}`); w.Code != http.StatusCreated {
+		t.Fatalf("failed to create events: %d %s", w.Code, w.Body.String())
+	}
+
+	stored := map[string]string{
+		"offset":    "2024-06-01T12:00:00+02:00",
+		"space":     "2024-06-01 10:00:00",
+		"date":      "2024-06-01",
+		"slash":     "13/06/2024 10:00",
+		"ambiguous": "06/01/2024 10:00",
+		"garbage":   "soon",
+		"conformed": "2024-06-01T10:00:00Z",
+	}
+	want := map[string]string{
+		"offset":    "2024-06-01T10:00:00Z",
+		"space":     "2024-06-01T10:00:00Z",
+		"date":      "2024-06-01T00:00:00Z",
+		"slash":     "2024-06-13T10:00:00Z",
+		"ambiguous": "06/01/2024 10:00",
+		"garbage":   "soon",
+		"conformed": "2024-06-01T10:00:00Z",
+	}
+	for title, value := range stored {
+		if _, err := srv.db.Exec(ctx, "INSERT INTO events (id, title, starts, _rev) VALUES (?, ?, ?, 1)", ulid.Generate(), title, value); err != nil {
+			t.Fatalf("failed to insert: %v", err)
+		}
+	}
+	if _, err := srv.db.Exec(ctx, "INSERT INTO events (id, title, _rev) VALUES (?, 'empty', 1)", ulid.Generate()); err != nil {
+		t.Fatalf("failed to insert: %v", err)
+	}
+
+	w := serveWithKey(srv, adminKey, http.MethodPost, "/admin:maintenance", `{"operation": "normalize_datetimes"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result maintenanceResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Datetimes == nil || *result.Datetimes != (datetimeMigration{Rewritten: 4, Unparseable: 2}) {
+		t.Errorf("expected 4 rewritten and 2 unparseable values, got %+v", result.Datetimes)
+	}
+
+	rows, err := srv.db.Query(ctx, "SELECT title, starts, _rev FROM events WHERE starts IS NOT NULL")
+	if err != nil {
+		t.Fatalf("failed to read events: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var title, starts string
+		var rev int
+		rows.Scan(&title, &starts, &rev)
+		if starts != want[title] {
+			t.Errorf("%s: expected %q, got %q", title, want[title], starts)
+		}
+		if rev != 1 {
+			t.Errorf("%s: expected the revision to be kept, got %d", title, rev)
+		}
+	}
+
+	// A second run finds nothing left to rewrite
+	w = serveWithKey(srv, adminKey, http.MethodPost, "/admin:maintenance", `{"operation": "normalize_datetimes"}`)
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Datetimes == nil || result.Datetimes.Rewritten != 0 {
+		t.Errorf("expected the migration to be idempotent, got %+v", result.Datetimes)
+	}
+}
+
+func TestDocs_FollowCollectionChanges(t *testing.T) {
+	srv, adminKey, _ := setupFileTestServer(t)
+
+	llms := func() string {
+		t.Helper()
+		w := serveWithKey(srv, adminKey, http.MethodGet, "/doc/llms.md", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+	change := func(path, body string) {
+		t.Helper()
+		if w := serveWithKey(srv, adminKey, http.MethodPost, path, body); w.Code >= 400 {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	if doc := llms(); !strings.Contains(doc, "/logs:create") || !strings.Contains(doc, `{"body": "example"}`) {
+		t.Fatalf("expected the quickstart to use logs, got:\n%s", doc)
+	}
+
+	// No /doc:refresh between the changes and the reads
+	change("/collections:update", `{"name": "logs", "add_columns": [{"name": "level", "type": "integer", "nullable": true}]}`)
+	if doc := llms(); !strings.Contains(doc, `{"body": "example", "level": 42}`) {
+		t.Errorf("expected the added column in the quickstart, got:\n%s", doc)
+	}
+
+	change("/collections:create", `{"name": "alerts", "columns": [{"name": "active", "type": "boolean"}]}`)
+	if doc := llms(); !strings.Contains(doc, "/alerts:list?active[eq]=true") {
+		t.Errorf("expected the quickstart to move to alerts, got:\n%s", doc)
+	}
+
+	change("/collections:destroy", `{"name": "alerts"}`)
+	change("/collections:destroy", `{"name": "logs"}`)
+	if doc := llms(); !strings.Contains(doc, "/{collection}:create") {
+		t.Errorf("expected placeholders without collections, got:\n%s", doc)
+	}
+}
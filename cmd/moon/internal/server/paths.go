@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+)
+
+// canonicalPath collapses repeated slashes and strips the trailing slash of a
+// request path. The root and the documentation root docRoot keep theirs.
+func canonicalPath(path, docRoot string) string {
+	if !strings.Contains(path, "//") && (len(path) <= 1 || !strings.HasSuffix(path, "/")) {
+		return path
+	}
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && i > 0 && path[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	clean := b.String()
+	if len(clean) > 1 && strings.HasSuffix(clean, "/") && clean != docRoot {
+		clean = clean[:len(clean)-1]
+	}
+	return clean
+}
+
+// normalizePathMiddleware routes messy paths such as /api/v1//products:list
+// or /products:list/ to their canonical form. GET and HEAD requests are
+// redirected with 308 Permanent Redirect, so clients and caches learn the
+// canonical URL; other methods are served at the canonical path directly, so
+// no client has to send its body twice.
+func (s *Server) normalizePathMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	docRoot := s.config.Server.Prefix + "/doc/"
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := canonicalPath(r.URL.Path, docRoot)
+		if path == r.URL.Path {
+			next(w, r)
+			return
+		}
+
+		u := *r.URL
+		u.Path = path
+		if u.RawPath != "" {
+			u.RawPath = canonicalPath(u.RawPath, docRoot)
+		}
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			target := u.EscapedPath()
+			if u.RawQuery != "" {
+				target += "?" + u.RawQuery
+			}
+			w.Header().Set(constants.HeaderLocation, target)
+			w.WriteHeader(http.StatusPermanentRedirect)
+			return
+		}
+
+		// As http.StripPrefix, a shallow copy with its own URL
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = &u
+		next(w, r2)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		docRoot string
+		want    string
+	}{
+		{"/", "/doc/", "/"},
+		{"//", "/doc/", "/"},
+		{"///", "/doc/", "/"},
+		{"/health", "/doc/", "/health"},
+		{"/health/", "/doc/", "/health"},
+		{"//health", "/doc/", "/health"},
+		{"/health/live/", "/doc/", "/health/live"},
+		{"/health//live", "/doc/", "/health/live"},
+		{"/products:list", "/doc/", "/products:list"},
+		{"//products:list", "/doc/", "/products:list"},
+		{"/products:list/", "/doc/", "/products:list"},
+		{"//products:list//", "/doc/", "/products:list"},
+		{"/collections:list/", "/doc/", "/collections:list"},
+		{"/doc/", "/doc/", "/doc/"},
+		{"//doc/", "/doc/", "/doc/"},
+		{"/doc//", "/doc/", "/doc/"},
+		{"/doc/llms.md/", "/doc/", "/doc/llms.md"},
+		{"/doc//llms.json", "/doc/", "/doc/llms.json"},
+		{"/api/v1/products:list", "/api/v1/doc/", "/api/v1/products:list"},
+		{"/api/v1//products:list", "/api/v1/doc/", "/api/v1/products:list"},
+		{"//api/v1/products:list", "/api/v1/doc/", "/api/v1/products:list"},
+		{"/api//v1/products:list/", "/api/v1/doc/", "/api/v1/products:list"},
+		{"/api/v1/", "/api/v1/doc/", "/api/v1"},
+		{"/api/v1/doc/", "/api/v1/doc/", "/api/v1/doc/"},
+		{"/api/v1//doc//", "/api/v1/doc/", "/api/v1/doc/"},
+		{"/api/v1/doc/", "/doc/", "/api/v1/doc"},
+		{"/admin:jobs:get/", "/doc/", "/admin:jobs:get"},
+	}
+	for _, tt := range tests {
+		if got := canonicalPath(tt.path, tt.docRoot); got != tt.want {
+			t.Errorf("canonicalPath(%q, %q) = %q, want %q", tt.path, tt.docRoot, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePath_EndToEnd(t *testing.T) {
+	srv, adminKey := setupScopeTestServer(t)
+
+	// Requests as the API check script builds them, base URL and prefix
+	// joined to each endpoint with an extra slash
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-KEY", adminKey)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Writes are served at the canonical path without a redirect
+	for _, path := range []string{"//products:create", "/products:create/", "//products:create//"} {
+		if w := serve(http.MethodPost, path, `{"data": {"title": "lamp"}}`); w.Code != http.StatusCreated {
+			t.Errorf("POST %s: expected 201, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	// Reads are redirected to the canonical path, keeping the query
+	redirects := map[string]string{
+		"//products:list?limit=2": "/products:list?limit=2",
+		"/products:list/":         "/products:list",
+		"//collections:list":      "/collections:list",
+		"/health/":                "/health",
+		"//doc/":                  "/doc/",
+	}
+	for path, want := range redirects {
+		w := serve(http.MethodGet, path, "")
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != want {
+			t.Errorf("GET %s: expected 308 to %s, got %d to %q", path, want, w.Code, w.Header().Get("Location"))
+			continue
+		}
+		if w := serve(http.MethodGet, want, ""); w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d: %s", want, w.Code, w.Body.String())
+		}
+	}
+	var list struct {
+		Data []map[string]any `json:"data"`
+	}
+	json.Unmarshal(serve(http.MethodGet, "/products:list", "").Body.Bytes(), &list)
+	if len(list.Data) != 3 {
+		t.Errorf("expected the three created records, got %d", len(list.Data))
+	}
+
+	// Preflights cannot follow redirects and are answered in place
+	req := httptest.NewRequest(http.MethodOptions, "//products:list", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w := httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(w, req)
+	if w.Code == http.StatusPermanentRedirect || w.Code == http.StatusNotFound {
+		t.Errorf("OPTIONS //products:list: expected the preflight response, got %d", w.Code)
+	}
+}
+
+func TestNormalizePath_Prefix(t *testing.T) {
+	srv := setupTestServerWithPrefix(t, "/api/v1")
+	for path, want := range map[string]int{
+		"/api/v1//health":         http.StatusPermanentRedirect,
+		"/api/v1/health":          http.StatusOK,
+		"/api/v1/doc/":            http.StatusOK,
+		"/api/v1/products:list":   http.StatusUnauthorized,
+		"/api/v1/products:list/":  http.StatusPermanentRedirect,
+		"/api//v1/products:list":  http.StatusPermanentRedirect,
+		"/api/v1/unknown/":        http.StatusPermanentRedirect,
+		"/api/v1/unknown":         http.StatusNotFound,
+		"/api/v1/collections:get": http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s: expected %d, got %d", path, want, w.Code)
+		}
+	}
+}
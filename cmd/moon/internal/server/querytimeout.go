@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+)
+
+// streamingActions are data actions that stream records for as long as the
+// transfer takes, or hold the request until a change; they are not bounded
+// by database.query_timeout
+var streamingActions = map[string]bool{
+	"export":  true,
+	"import":  true,
+	"changes": true,
+}
+
+// queryTimeout returns database.query_timeout as a duration
+func (s *Server) queryTimeout() time.Duration {
+	seconds := s.config.Database.QueryTimeout
+	if seconds <= 0 {
+		seconds = config.Defaults.Database.QueryTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// queryDeadline bounds the queries of a data or aggregation request by
+// database.query_timeout. The handlers pass the request context to the driver,
+// so the deadline, like a client disconnect, cancels the running statement;
+// they then report the database error as 504 query_timeout.
+func (s *Server) queryDeadline(action string, next http.HandlerFunc) http.HandlerFunc {
+	if streamingActions[action] {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.queryTimeout())
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
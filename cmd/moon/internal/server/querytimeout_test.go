@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryDeadline(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	srv.config.Database.QueryTimeout = 7
+
+	for _, tt := range []struct {
+		action  string
+		bounded bool
+	}{
+		{"list", true},
+		{"create", true},
+		{"groupby", true},
+		{"export", false},
+		{"import", false},
+	} {
+		var deadline time.Time
+		var ok bool
+		handler := srv.queryDeadline(tt.action, func(w http.ResponseWriter, r *http.Request) {
+			deadline, ok = r.Context().Deadline()
+		})
+		start := time.Now()
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products:"+tt.action, nil))
+
+		if ok != tt.bounded {
+			t.Errorf("%s: expected a deadline %v, got %v", tt.action, tt.bounded, ok)
+			continue
+		}
+		if ok && (deadline.Before(start.Add(7*time.Second)) || deadline.After(time.Now().Add(7*time.Second))) {
+			t.Errorf("%s: expected a deadline 7s ahead, got %s", tt.action, deadline.Sub(start))
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+)
+
+// setupScopeTestServer bootstraps the auth tables with an all-access admin key
+// and creates the products and orders collections with it
+func setupScopeTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	srv := setupTestServer(t)
+	// Every test server shares the one :memory: database until its last
+	// connection closes, so last-used updates must be done before the close
+	t.Cleanup(func() {
+		srv.lastUsed.Wait()
+		srv.db.Close()
+	})
+	// Every connection to :memory: is a separate database, so keep exactly one open
+	srv.db.DB().SetMaxOpenConns(1)
+	srv.db.DB().SetMaxIdleConns(1)
+	srv.config.Batch = config.BatchConfig{MaxSize: config.Defaults.Batch.MaxSize, MaxPayloadBytes: config.Defaults.Batch.MaxPayloadBytes, MaxTransactOps: config.Defaults.Batch.MaxTransactOps}
+
+	adminKey := auth.APIKeyPrefix + strings.Repeat("a", auth.APIKeyLength)
+	if err := auth.Bootstrap(context.Background(), srv.db, &auth.BootstrapConfig{APIKey: adminKey}); err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+
+	for _, name := range []string{"products", "orders"} {
+		w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:create",
+			`{"name": "`+name+`", "columns": [{"name": "title", "type": "string"}]}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("failed to create %s: %d %s", name, w.Code, w.Body.String())
+		}
+	}
+	return srv, adminKey
+}
+
+// createScopedKey stores an API key with the given role and scopes and returns the raw key
+func createScopedKey(t *testing.T, srv *Server, name, role string, scopes auth.Scopes) string {
+	t.Helper()
+	rawKey, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	apiKey := &auth.APIKey{Name: name, KeyHash: keyHash, Role: role, CanWrite: true, Scopes: scopes}
+	if err := auth.NewAPIKeyRepository(srv.db).Create(context.Background(), apiKey); err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	return rawKey
+}
+
+func serveWithKey(srv *Server, key, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set(constants.HeaderAPIKey, key)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	return w
+}
+
+func assertScopeDenied(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["code"] != string(apperrors.CodeInsufficientScope) {
+		t.Errorf("expected code %s, got %v", apperrors.CodeInsufficientScope, resp["code"])
+	}
+}
+
+func TestAPIKeyScopes_ReadOnly(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	key := createScopedKey(t, srv, "reader", "user", auth.Scopes{{Collection: "*", Actions: []string{auth.ScopeRead}}})
+
+	if w := serveWithKey(srv, key, http.MethodGet, "/products:list", ""); w.Code != http.StatusOK {
+		t.Errorf("expected read-only key to list, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, key, http.MethodGet, "/collections:get?name=products", ""); w.Code != http.StatusOK {
+		t.Errorf("expected read-only key to get the collection, got %d: %s", w.Code, w.Body.String())
+	}
+	assertScopeDenied(t, serveWithKey(srv, key, http.MethodPost, "/products:create", `{"data": {"title": "x"}}`))
+}
+
+func TestAPIKeyScopes_Collection(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	key := createScopedKey(t, srv, "products-only", "user", auth.Scopes{{Collection: "products", Actions: []string{auth.ScopeRead, auth.ScopeWrite}}})
+
+	if w := serveWithKey(srv, key, http.MethodPost, "/products:create", `{"data": {"title": "x"}}`); w.Code != http.StatusCreated {
+		t.Errorf("expected create on products to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	assertScopeDenied(t, serveWithKey(srv, key, http.MethodGet, "/orders:list", ""))
+	assertScopeDenied(t, serveWithKey(srv, key, http.MethodPost, "/orders:create", `{"data": {"title": "x"}}`))
+	assertScopeDenied(t, serveWithKey(srv, key, http.MethodGet, "/collections:get?name=orders", ""))
+
+	w := serveWithKey(srv, key, http.MethodGet, "/collections:list", "")
+	var resp struct {
+		Collections []struct {
+			Name string `json:"name"`
+		} `json:"collections"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Collections) != 1 || resp.Collections[0].Name != "products" {
+		t.Errorf("expected collections:list to show only products, got %s", w.Body.String())
+	}
+}
+
+func TestAPIKeyScopes_Schema(t *testing.T) {
+	srv, adminKey := setupScopeTestServer(t)
+	key := createScopedKey(t, srv, "data-admin", "admin", auth.Scopes{{Collection: "*", Actions: []string{auth.ScopeRead, auth.ScopeWrite}}})
+
+	assertScopeDenied(t, serveWithKey(srv, key, http.MethodPost, "/collections:create",
+		`{"name": "customers", "columns": [{"name": "title", "type": "string"}]}`))
+	assertScopeDenied(t, serveWithKey(srv, key, http.MethodPost, "/collections:destroy", `{"name": "orders"}`))
+
+	// Renaming and duplicating need the schema scope on both names
+	ordersKey := createScopedKey(t, srv, "orders-schema", "admin", auth.Scopes{{Collection: "orders", Actions: []string{auth.ScopeSchema}}})
+	assertScopeDenied(t, serveWithKey(srv, ordersKey, http.MethodPost, "/collections:rename", `{"name": "orders", "new_name": "archive"}`))
+	assertScopeDenied(t, serveWithKey(srv, ordersKey, http.MethodPost, "/collections:duplicate", `{"source": "orders", "target": "archive"}`))
+
+	// The bootstrap key carries every scope
+	if w := serveWithKey(srv, adminKey, http.MethodPost, "/collections:destroy", `{"name": "orders"}`); w.Code != http.StatusOK {
+		t.Errorf("expected bootstrap key to destroy the collection, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIKeyScopes_Unscoped(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	key := createScopedKey(t, srv, "legacy", "user", nil)
+
+	if w := serveWithKey(srv, key, http.MethodPost, "/orders:create", `{"data": {"title": "x"}}`); w.Code != http.StatusCreated {
+		t.Errorf("expected unscoped key to keep full data access, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -4,24 +4,47 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/apiversion"
+	"github.com/thalib/moon/cmd/moon/internal/audit"
 	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/cache"
+	"github.com/thalib/moon/cmd/moon/internal/changefeed"
+	"github.com/thalib/moon/cmd/moon/internal/checks"
 	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/consistency"
 	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/daemon"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/expiry"
 	"github.com/thalib/moon/cmd/moon/internal/handlers"
+	"github.com/thalib/moon/cmd/moon/internal/idempotency"
+	"github.com/thalib/moon/cmd/moon/internal/jobs"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
 	"github.com/thalib/moon/cmd/moon/internal/middleware"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+	"github.com/thalib/moon/cmd/moon/internal/webhook"
 )
 
 // Server represents the HTTP server
@@ -31,6 +54,8 @@ type Server struct {
 	registry       *registry.SchemaRegistry
 	mux            *http.ServeMux
 	server         *http.Server
+	adminMux       *http.ServeMux // administrative routes when server.admin_port is set; nil serves them on mux
+	adminServer    *http.Server   // listener of adminMux; nil unless server.admin_port is set
 	version        string
 	rateLimiter    *middleware.RateLimitMiddleware
 	authzMiddle    *middleware.AuthorizationMiddleware
@@ -38,12 +63,34 @@ type Server struct {
 	tokenService   *auth.TokenService
 	tokenBlacklist *auth.TokenBlacklist
 	apiKeyRepo     *auth.APIKeyRepository
+	apiKeyUsage    *auth.LastUsedThrottle
+	lastUsed       sync.WaitGroup // last_used_at updates still writing
+	webhooks       *webhook.Dispatcher
+	changes        *changefeed.Feed
+	sweeper        *expiry.Sweeper
+	idempotency    *idempotency.Store
+	jobs           *jobs.Store
+	audit          *audit.Log       // nil unless audit.enabled
+	cache          *cache.Cache     // nil unless cache.enabled
+	statsCache     *cache.Cache     // :stats responses; nil when stats.cache_ttl is 0
+	bodyLimits     map[string]int64 // body limits of data actions, overriding server.max_body_bytes
+	trustedProxies []netip.Prefix   // server.trusted_proxies; empty trusts no X-Forwarded-For
+	cleanups       []func()
+	instanceLock   *daemon.InstanceLock // released on shutdown; nil when none was taken
+	startedAt      time.Time
+	daemon         bool
+
+	maintenanceRunning atomic.Bool // an admin:maintenance, admin:backup or admin:restore operation is in progress
+	writesPaused       atomic.Bool // writes are rejected while SQLite is vacuumed or restored
 }
 
 // New creates a new server instance
 func New(cfg *config.AppConfig, db database.Driver, reg *registry.SchemaRegistry, version string) *Server {
 	mux := http.NewServeMux()
 
+	// Error responses use the legacy shape until clients have migrated
+	apperrors.SetLegacyFormat(cfg.Server.LegacyErrors)
+
 	// Create rate limiter with config values
 	rateLimiterConfig := middleware.RateLimiterConfig{
 		UserRPM:   cfg.Auth.RateLimit.UserRPM,
@@ -56,15 +103,16 @@ func New(cfg *config.AppConfig, db database.Driver, reg *registry.SchemaRegistry
 		rateLimiterConfig.APIKeyRPM = config.Defaults.Auth.RateLimit.APIKeyRPM
 	}
 
-	// Create CORS middleware with config values
+	// Create CORS middleware with config values; the configured API key header
+	// is always allowed so browsers can authenticate with it
 	corsConfig := middleware.CORSConfig{
 		Enabled:          cfg.CORS.Enabled,
 		AllowedOrigins:   cfg.CORS.AllowedOrigins,
 		AllowedMethods:   cfg.CORS.AllowedMethods,
-		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		AllowedHeaders:   withHeader(cfg.CORS.AllowedHeaders, cfg.APIKey.Header),
 		AllowCredentials: cfg.CORS.AllowCredentials,
 		MaxAge:           cfg.CORS.MaxAge,
-		Endpoints:        convertCORSEndpoints(cfg.CORS.Endpoints), // PRD-058
+		Endpoints:        convertCORSEndpoints(cfg.CORS.Endpoints, cfg.APIKey.Header), // PRD-058
 	}
 
 	// Create token service for authentication
@@ -80,38 +128,110 @@ func New(cfg *config.AppConfig, db database.Driver, reg *registry.SchemaRegistry
 		registry:       reg,
 		mux:            mux,
 		version:        version,
+		startedAt:      time.Now(),
 		rateLimiter:    middleware.NewRateLimitMiddleware(rateLimiterConfig),
 		authzMiddle:    middleware.NewAuthorizationMiddleware(),
 		corsMiddle:     middleware.NewCORSMiddleware(corsConfig),
 		tokenService:   tokenService,
 		tokenBlacklist: auth.NewTokenBlacklist(db),
 		apiKeyRepo:     auth.NewAPIKeyRepository(db),
+		apiKeyUsage:    auth.NewLastUsedThrottle(),
+		webhooks:       webhook.New(cfg.Webhooks),
+		changes:        changefeed.New(db),
+		sweeper:        expiry.NewSweeper(db, reg),
+		idempotency:    idempotency.New(db, time.Duration(cfg.Idempotency.TTL)*time.Second),
+		jobs:           jobs.New(db),
+		bodyLimits:     make(map[string]int64),
 		server: &http.Server{
 			Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
-			Handler:      mux,
 			ReadTimeout:  constants.HTTPReadTimeout,
 			WriteTimeout: constants.HTTPWriteTimeout,
 			IdleTimeout:  constants.HTTPIdleTimeout,
 		},
 	}
 
+	// Validated at load; a config that fails to parse trusts nothing
+	srv.trustedProxies, _ = cfg.Server.TrustedProxyPrefixes()
+
+	if cfg.Cache.Enabled {
+		srv.cache = cache.New(cfg.Cache.MaxEntries, time.Duration(cfg.Cache.TTL)*time.Second)
+	}
+	if cfg.Stats.CacheTTL > 0 {
+		srv.statsCache = cache.New(cfg.Cache.MaxEntries, time.Duration(cfg.Stats.CacheTTL)*time.Second)
+	}
+
+	// Long-polls return at once so that they do not hold up the shutdown
+	srv.server.RegisterOnShutdown(srv.changes.Release)
+
+	if cfg.Server.AdminPort != 0 {
+		srv.adminMux = http.NewServeMux()
+		srv.adminServer = &http.Server{
+			Addr:         fmt.Sprintf("%s:%d", cfg.Server.AdminHost, cfg.Server.AdminPort),
+			ReadTimeout:  constants.HTTPReadTimeout,
+			WriteTimeout: constants.HTTPWriteTimeout,
+			IdleTimeout:  constants.HTTPIdleTimeout,
+		}
+	}
+
 	srv.setupRoutes()
+	srv.server.Handler = srv.handler(mux)
+	if srv.adminServer != nil {
+		srv.adminServer.Handler = srv.handler(srv.adminMux)
+	}
 	return srv
 }
 
+// handler wraps a mux in the middleware every listener applies
+func (s *Server) handler(mux *http.ServeMux) http.Handler {
+	return s.loggingMiddleware(s.normalizePathMiddleware(s.compressionMiddleware(s.bodyLimitMiddleware(middleware.APIVersion(mux.ServeHTTP)))))
+}
+
+// route is a pattern and the handler registered for it
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// mount registers routes on mux
+func mount(mux *http.ServeMux, routes []route) {
+	for _, rt := range routes {
+		mux.HandleFunc(rt.pattern, rt.handler)
+	}
+}
+
+// mountNotFound answers every method on the paths of routes with 404, so that
+// routes served by another listener are not taken for data endpoints
+func (s *Server) mountNotFound(mux *http.ServeMux, routes []route) {
+	var paths []string
+	for _, rt := range routes {
+		_, path, _ := strings.Cut(rt.pattern, " ")
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+			mux.HandleFunc(path, s.notFoundHandler)
+		}
+	}
+}
+
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
 	// Create collections handler
 	collectionsHandler := handlers.NewCollectionsHandler(s.db, s.registry)
+	collectionsHandler.SetTenancy(s.config.Tenancy.Enabled)
 
 	// Create data handler
 	dataHandler := handlers.NewDataHandler(s.db, s.registry, s.config)
+	dataHandler.SetWebhooks(s.webhooks)
+	dataHandler.SetChanges(s.changes)
+	dataHandler.SetIdempotency(s.idempotency)
+	dataHandler.SetJobs(s.jobs)
 
 	// Create aggregation handler
 	aggregationHandler := handlers.NewAggregationHandler(s.db, s.registry)
+	aggregationHandler.SetStrictQueryParams(s.config.API.StrictQueryParams)
 
 	// Create documentation handler
 	docHandler := handlers.NewDocHandler(s.registry, s.config, s.version)
+	docHandler.SetDatabase(s.db)
 
 	// Create auth handler with login rate limiting
 	accessExpiry := s.config.JWT.AccessExpiry
@@ -134,46 +254,61 @@ func (s *Server) setupRoutes() {
 	prefix := s.config.Server.Prefix
 
 	// Middleware helper functions for cleaner route definitions
+	// Request ID assignment and access logging wrap the whole mux (see New)
+
 	// Dynamic CORS: Uses endpoint registration with auth bypass support (PRD-058)
 	dynamicCORS := func(h http.HandlerFunc) http.HandlerFunc {
-		return s.corsMiddle.HandleDynamic(s.loggingMiddleware(h))
+		return s.corsMiddle.HandleDynamic(h)
 	}
 
-	// Public endpoints: Standard CORS + logging (for endpoints like root message)
+	// Public endpoints: Standard CORS (for endpoints like root message)
 	public := func(h http.HandlerFunc) http.HandlerFunc {
-		return s.corsMiddle.Handle(s.loggingMiddleware(h))
+		return s.corsMiddle.Handle(h)
 	}
 
-	// Auth endpoints: CORS + logging + auth (login/refresh don't need rate limit or authz)
+	// Auth endpoints: CORS only (login/refresh don't need auth, rate limit or authz)
 	authNoLimit := func(h http.HandlerFunc) http.HandlerFunc {
-		return s.corsMiddle.Handle(s.loggingMiddleware(h))
+		return s.corsMiddle.Handle(h)
 	}
 
-	// Authenticated: CORS + logging + auth + rate limit (any authenticated entity)
+	// Authenticated: CORS + auth + rate limit (any authenticated entity)
 	authenticated := func(h http.HandlerFunc) http.HandlerFunc {
 		return s.corsMiddle.Handle(
-			s.loggingMiddleware(
-				s.authMiddleware(
-					s.rateLimiter.RateLimit(
-						s.authzMiddle.RequireAuthenticated(h)))))
+			s.authMiddleware(
+				s.rateLimiter.RateLimit(
+					s.authzMiddle.RequireAuthenticated(h))))
 	}
 
-	// Admin only: CORS + logging + auth + rate limit + admin role
+	// Admin only: CORS + auth + rate limit + admin role
 	adminOnly := func(h http.HandlerFunc) http.HandlerFunc {
 		return s.corsMiddle.Handle(
-			s.loggingMiddleware(
-				s.authMiddleware(
-					s.rateLimiter.RateLimit(
-						s.authzMiddle.RequireAdmin(h)))))
+			s.authMiddleware(
+				s.rateLimiter.RateLimit(
+					s.authzMiddle.RequireAdmin(h))))
+	}
+
+	// Operator only: admin, and outside every tenant when tenancy is enabled
+	operatorOnly := func(h http.HandlerFunc) http.HandlerFunc {
+		return adminOnly(s.hideFromTenants(h))
 	}
 
-	// Write required: CORS + logging + auth + rate limit + write permission
+	// Write required: CORS + auth + rate limit + write permission
 	writeRequired := func(h http.HandlerFunc) http.HandlerFunc {
 		return s.corsMiddle.Handle(
-			s.loggingMiddleware(
-				s.authMiddleware(
-					s.rateLimiter.RateLimit(
-						s.authzMiddle.RequireWrite(h)))))
+			s.authMiddleware(
+				s.rateLimiter.RateLimit(
+					s.authzMiddle.RequireWrite(h))))
+	}
+
+	// Preflight: CORS with the route's Allow header. Browsers send
+	// preflights without credentials, so OPTIONS never requires authentication.
+	preflight := func(methods ...string) http.HandlerFunc {
+		return withAllow(methods, s.corsMiddle.Handle(s.corsPreflightHandler))
+	}
+
+	// Dynamic preflight: as preflight, using endpoint registration (PRD-058)
+	dynamicPreflight := func(methods ...string) http.HandlerFunc {
+		return withAllow(methods, dynamicCORS(s.corsPreflightHandler))
 	}
 
 	// Root message endpoint (only for exact "/" path with no prefix)
@@ -185,114 +320,213 @@ func (s *Server) setupRoutes() {
 	// PUBLIC ENDPOINTS (No Auth)
 	// ==========================================
 
-	// Health check endpoint (always at /health, respects prefix) - PRD-058: Dynamic CORS
+	// Liveness probe (always at /health/live, respects prefix) - PRD-058: Dynamic CORS
 	healthPath := prefix + "/health"
-	s.mux.HandleFunc("GET "+healthPath, dynamicCORS(s.healthHandler))
-	s.mux.HandleFunc("OPTIONS "+healthPath, dynamicCORS(s.healthHandler))
-
-	// Documentation endpoints (public) - PRD-058: Dynamic CORS
-	s.mux.HandleFunc("GET "+prefix+"/doc/{$}", dynamicCORS(docHandler.HTML))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/{$}", dynamicCORS(docHandler.HTML))
-	s.mux.HandleFunc("GET "+prefix+"/doc/llms.md", dynamicCORS(docHandler.Markdown))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/llms.md", dynamicCORS(docHandler.Markdown))
-	s.mux.HandleFunc("GET "+prefix+"/doc/llms.txt", dynamicCORS(docHandler.Markdown))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/llms.txt", dynamicCORS(docHandler.Markdown))
+	liveness := []route{
+		{"GET " + healthPath + "/live", dynamicCORS(s.livenessHandler)},
+		{"OPTIONS " + healthPath + "/live", dynamicPreflight(http.MethodGet)},
+	}
+	mount(s.mux, liveness)
+
+	// Documentation endpoints (public) - PRD-058: Dynamic CORS. Tenants that
+	// authenticate see their own collections.
+	s.mux.HandleFunc("GET "+prefix+"/doc/{$}", dynamicCORS(s.optionalAuth(docHandler.HTML)))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/{$}", dynamicPreflight(http.MethodGet))
+	s.mux.HandleFunc("GET "+prefix+"/doc/llms.md", dynamicCORS(s.optionalAuth(docHandler.Markdown)))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/llms.md", dynamicPreflight(http.MethodGet))
+	s.mux.HandleFunc("GET "+prefix+"/doc/llms.txt", dynamicCORS(s.optionalAuth(docHandler.Markdown)))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/llms.txt", dynamicPreflight(http.MethodGet))
 	s.mux.HandleFunc("GET "+prefix+"/doc/llms.json", dynamicCORS(docHandler.JSON))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/llms.json", dynamicCORS(docHandler.JSON))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/llms.json", dynamicPreflight(http.MethodGet))
+	s.mux.HandleFunc("GET "+prefix+"/doc/openapi.json", dynamicCORS(s.optionalAuth(docHandler.OpenAPI)))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/doc/openapi.json", dynamicPreflight(http.MethodGet))
 
 	// ==========================================
 	// AUTH ENDPOINTS (No role check)
 	// ==========================================
 
 	// Login and refresh don't need auth/rate limit (they have their own rate limiting)
-	s.mux.HandleFunc("POST "+prefix+"/auth:login", authNoLimit(authHandler.Login))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:login", authNoLimit(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/auth:refresh", authNoLimit(authHandler.Refresh))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:refresh", authNoLimit(s.corsPreflightHandler))
+	s.mux.HandleFunc("POST "+prefix+"/auth:login", authNoLimit(s.audited("auth:login", "", authHandler.Login)))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:login", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/auth:refresh", authNoLimit(s.audited("auth:refresh", "", authHandler.Refresh)))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:refresh", preflight(http.MethodPost))
 
 	// ==========================================
 	// AUTHENTICATED ENDPOINTS (Any Role)
 	// ==========================================
 
 	// Logout requires authentication
-	s.mux.HandleFunc("POST "+prefix+"/auth:logout", authenticated(authHandler.Logout))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:logout", authenticated(s.corsPreflightHandler))
+	s.mux.HandleFunc("POST "+prefix+"/auth:logout", authenticated(s.audited("auth:logout", "", authHandler.Logout)))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:logout", preflight(http.MethodPost))
 
 	// Me endpoints require authentication
 	s.mux.HandleFunc("GET "+prefix+"/auth:me", authenticated(authHandler.GetMe))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:me", authenticated(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/auth:me", authenticated(authHandler.UpdateMe))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/auth:me", preflight(http.MethodGet, http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/auth:me", authenticated(s.writable(s.audited("auth:me", "", authHandler.UpdateMe))))
 
 	// Collections read endpoints (any authenticated user)
 	s.mux.HandleFunc("GET "+prefix+"/collections:list", authenticated(collectionsHandler.List))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:list", authenticated(s.corsPreflightHandler))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:list", preflight(http.MethodGet))
 	s.mux.HandleFunc("GET "+prefix+"/collections:get", authenticated(collectionsHandler.Get))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:get", authenticated(s.corsPreflightHandler))
-
-	// Doc refresh requires authentication
-	s.mux.HandleFunc("POST "+prefix+"/doc:refresh", authenticated(docHandler.RefreshCache))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/doc:refresh", authenticated(s.corsPreflightHandler))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:get", preflight(http.MethodGet))
 
 	// ==========================================
 	// ADMIN ONLY ENDPOINTS
 	// ==========================================
 
-	// User management endpoints (admin only)
-	s.mux.HandleFunc("GET "+prefix+"/users:list", adminOnly(usersHandler.List))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/users:list", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("GET "+prefix+"/users:get", adminOnly(usersHandler.Get))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/users:get", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/users:create", adminOnly(usersHandler.Create))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/users:create", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/users:update", adminOnly(usersHandler.Update))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/users:update", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/users:destroy", adminOnly(usersHandler.Destroy))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/users:destroy", adminOnly(s.corsPreflightHandler))
-
-	// API key management endpoints (admin only)
-	s.mux.HandleFunc("GET "+prefix+"/apikeys:list", adminOnly(apiKeysHandler.List))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:list", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("GET "+prefix+"/apikeys:get", adminOnly(apiKeysHandler.Get))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:get", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/apikeys:create", adminOnly(apiKeysHandler.Create))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:create", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/apikeys:update", adminOnly(apiKeysHandler.Update))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:update", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/apikeys:destroy", adminOnly(apiKeysHandler.Destroy))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:destroy", adminOnly(s.corsPreflightHandler))
+	// User management endpoints (operator only)
+	s.mux.HandleFunc("GET "+prefix+"/users:list", operatorOnly(usersHandler.List))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/users:list", preflight(http.MethodGet))
+	s.mux.HandleFunc("GET "+prefix+"/users:get", operatorOnly(usersHandler.Get))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/users:get", preflight(http.MethodGet))
+	s.mux.HandleFunc("POST "+prefix+"/users:create", operatorOnly(s.writable(s.audited("users:create", "", usersHandler.Create))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/users:create", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/users:update", operatorOnly(s.writable(s.audited("users:update", "", usersHandler.Update))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/users:update", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/users:destroy", operatorOnly(s.writable(s.audited("users:destroy", "", usersHandler.Destroy))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/users:destroy", preflight(http.MethodPost))
+
+	// API key management endpoints (operator only)
+	s.mux.HandleFunc("GET "+prefix+"/apikeys:list", operatorOnly(apiKeysHandler.List))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:list", preflight(http.MethodGet))
+	s.mux.HandleFunc("GET "+prefix+"/apikeys:get", operatorOnly(apiKeysHandler.Get))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:get", preflight(http.MethodGet))
+	s.mux.HandleFunc("POST "+prefix+"/apikeys:create", operatorOnly(s.writable(s.audited("apikeys:create", "", apiKeysHandler.Create))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:create", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/apikeys:update", operatorOnly(s.writable(s.audited("apikeys:update", "", apiKeysHandler.Update))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:update", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/apikeys:destroy", operatorOnly(s.writable(s.audited("apikeys:destroy", "", apiKeysHandler.Destroy))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/apikeys:destroy", preflight(http.MethodPost))
 
 	// Collections management endpoints (admin only)
-	s.mux.HandleFunc("POST "+prefix+"/collections:create", adminOnly(collectionsHandler.Create))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:create", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/collections:update", adminOnly(collectionsHandler.Update))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:update", adminOnly(s.corsPreflightHandler))
-	s.mux.HandleFunc("POST "+prefix+"/collections:destroy", adminOnly(collectionsHandler.Destroy))
-	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:destroy", adminOnly(s.corsPreflightHandler))
+	s.mux.HandleFunc("POST "+prefix+"/collections:create", adminOnly(s.writable(s.invalidateAll(s.audited("collections:create", "", collectionsHandler.Create)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:create", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/collections:update", adminOnly(s.writable(s.invalidateAll(s.audited("collections:update", "", collectionsHandler.Update)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:update", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/collections:destroy", adminOnly(s.writable(s.invalidateAll(s.audited("collections:destroy", "", collectionsHandler.Destroy)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:destroy", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/collections:rename", adminOnly(s.writable(s.invalidateAll(s.audited("collections:rename", "", collectionsHandler.Rename)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:rename", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/collections:archive", adminOnly(s.writable(s.invalidateAll(s.audited("collections:archive", "", collectionsHandler.Archive)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:archive", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/collections:unarchive", adminOnly(s.writable(s.invalidateAll(s.audited("collections:unarchive", "", collectionsHandler.Unarchive)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:unarchive", preflight(http.MethodPost))
+	s.mux.HandleFunc("POST "+prefix+"/collections:duplicate", adminOnly(s.writable(s.invalidateAll(s.audited("collections:duplicate", "", collectionsHandler.Duplicate)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:duplicate", preflight(http.MethodPost))
+	s.mux.HandleFunc("GET "+prefix+"/collections:export", adminOnly(collectionsHandler.Export))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:export", preflight(http.MethodGet))
+	s.mux.HandleFunc("POST "+prefix+"/collections:import", adminOnly(s.writable(s.invalidateAll(s.audited("collections:import", "", collectionsHandler.Import)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/collections:import", preflight(http.MethodPost))
+
+	// ==========================================
+	// ADMINISTRATIVE ENDPOINTS
+	// ==========================================
+
+	// Served by the admin listener when server.admin_port is set, where the
+	// public listener answers them with 404
+	administrative := []route{
+		// Health check with dependency status and build info - PRD-058: Dynamic CORS
+		{"GET " + healthPath, dynamicCORS(s.healthHandler)},
+		{"OPTIONS " + healthPath, dynamicPreflight(http.MethodGet)},
+
+		// Doc refresh requires authentication
+		{"POST " + prefix + "/doc:refresh", authenticated(docHandler.RefreshCache)},
+		{"OPTIONS " + prefix + "/doc:refresh", preflight(http.MethodPost)},
+
+		// On-demand consistency check; repair=true may change the registry
+		{"GET " + prefix + "/admin:consistency", operatorOnly(s.invalidateAll(s.consistencyHandler))},
+		{"OPTIONS " + prefix + "/admin:consistency", preflight(http.MethodGet)},
+
+		// Database maintenance; VACUUM on SQLite pauses writes until it finishes and
+		// normalize_datetimes rewrites stored values, so cached reads are dropped
+		{"POST " + prefix + "/admin:maintenance", operatorOnly(s.invalidateAll(s.audited("admin:maintenance", "", s.maintenanceHandler)))},
+		{"OPTIONS " + prefix + "/admin:maintenance", preflight(http.MethodPost)},
+
+		// Snapshots of a SQLite database in backup.directory; a restore pauses
+		// writes and reloads the registry, so cached reads are dropped
+		{"POST " + prefix + "/admin:backup", operatorOnly(s.audited("admin:backup", "", s.backupHandler))},
+		{"OPTIONS " + prefix + "/admin:backup", preflight(http.MethodPost)},
+		{"GET " + prefix + "/admin:backups", operatorOnly(s.listBackupsHandler)},
+		{"OPTIONS " + prefix + "/admin:backups", preflight(http.MethodGet)},
+		{"POST " + prefix + "/admin:restore", operatorOnly(s.invalidateAll(s.audited("admin:restore", "", s.restoreHandler)))},
+		{"OPTIONS " + prefix + "/admin:restore", preflight(http.MethodPost)},
+
+		// Drops and recreates the sample collection with its seed records
+		{"POST " + prefix + "/admin:reset-demo", operatorOnly(s.writable(s.invalidateAll(s.audited("admin:reset-demo", "", collectionsHandler.ResetSample))))},
+		{"OPTIONS " + prefix + "/admin:reset-demo", preflight(http.MethodPost)},
+
+		// Runtime log levels, per module or for the whole server
+		{"POST " + prefix + "/admin:loglevel", operatorOnly(s.audited("admin:loglevel", "", s.logLevelHandler))},
+		{"OPTIONS " + prefix + "/admin:loglevel", preflight(http.MethodPost)},
+
+		// Audit log of successful mutating requests, when audit.enabled
+		{"GET " + prefix + "/admin:audit", operatorOnly(s.auditHandler)},
+		{"OPTIONS " + prefix + "/admin:audit", preflight(http.MethodGet)},
+
+		// Journals of imports and batch creates sent with journal=true
+		{"GET " + prefix + "/admin:jobs", operatorOnly(s.listJobsHandler)},
+		{"OPTIONS " + prefix + "/admin:jobs", preflight(http.MethodGet)},
+		{"GET " + prefix + "/admin:jobs:get", operatorOnly(s.getJobHandler)},
+		{"OPTIONS " + prefix + "/admin:jobs:get", preflight(http.MethodGet)},
+	}
+	if s.adminMux != nil {
+		mount(s.adminMux, administrative)
+		mount(s.adminMux, liveness)
+		s.adminMux.HandleFunc("/", s.notFoundHandler)
+		s.mountNotFound(s.mux, administrative)
+	} else {
+		mount(s.mux, administrative)
+	}
+
+	// Data writes across collections in one transaction; each operation is
+	// scope-checked by the handler
+	s.mux.HandleFunc("POST "+prefix+"/batch:transact", writeRequired(s.writable(s.invalidateAll(s.audited("batch:transact", "", dataHandler.Transact)))))
+	s.mux.HandleFunc("OPTIONS "+prefix+"/batch:transact", preflight(http.MethodPost))
 
 	// ==========================================
 	// DYNAMIC DATA ENDPOINTS
 	// ==========================================
 
+	// Data writes keep the batch payload limit and :import its upload limit
+	// in place of server.max_body_bytes
+	for action, method := range dataActionMethods {
+		if method == http.MethodPost {
+			s.setBodyLimit(action, s.config.Batch.MaxPayloadBytes)
+		}
+	}
+	s.setBodyLimit("import", s.config.Batch.MaxImportBytes)
+
 	// Data access endpoints (dynamic collections with :action pattern)
 	// This also serves as catch-all when prefix is empty
 	if prefix == "" {
 		// Apply dynamic CORS handling to dynamic data endpoints so OPTIONS preflight
-		// requests are handled by the CORS middleware instead of falling through
-		// to the dynamic handler which would return 405 for OPTIONS.
-		s.mux.HandleFunc("/", dynamicCORS(s.dynamicDataHandler(dataHandler, aggregationHandler, authenticated, writeRequired)))
+		// requests are handled by the CORS middleware; the Allow header is set
+		// first so preflight, OPTIONS and 405 responses all list the action's methods.
+		s.mux.HandleFunc("/", dataAllowHeader(dynamicCORS(s.dynamicDataHandler(dataHandler, aggregationHandler, authenticated, writeRequired))))
 	} else {
-		s.mux.HandleFunc(prefix+"/", dynamicCORS(s.dynamicDataHandler(dataHandler, aggregationHandler, authenticated, writeRequired)))
+		s.mux.HandleFunc(prefix+"/", dataAllowHeader(dynamicCORS(s.dynamicDataHandler(dataHandler, aggregationHandler, authenticated, writeRequired))))
 		// Catch-all for 404 when prefix is set
-		s.mux.HandleFunc("/", s.loggingMiddleware(s.notFoundHandler))
+		s.mux.HandleFunc("/", s.notFoundHandler)
 	}
 }
 
-// loggingMiddleware logs HTTP requests and responses
+// loggingMiddleware assigns a request ID and writes one access log line per request.
+// A valid incoming X-Request-ID is honored, otherwise a ULID is generated. The ID is
+// stored in the request context, echoed in the response header and included in errors.
+// The resolved client IP is stored alongside it for rate limiting and audit logs.
 func (s *Server) loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		// Create a response writer wrapper to capture status code
+		requestID := r.Header.Get(constants.HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = ulid.Generate()
+		}
+		w.Header().Set(constants.HeaderRequestID, requestID)
+		ip := clientIP(r, s.trustedProxies)
+		r = r.WithContext(logging.SetClientIP(logging.SetRequestID(r.Context(), requestID), ip))
+
+		// Create a response writer wrapper to capture status code and size
 		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
 		// Call the next handler
@@ -300,26 +534,79 @@ func (s *Server) loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 		// Log the request
 		duration := time.Since(start)
-		log.Printf(
-			"%s %s %d %s",
+		logger := logging.WithContext(r.Context()).WithFields(map[string]any{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      rw.statusCode,
+			"duration_ms": duration.Milliseconds(),
+			"bytes":       rw.bytesWritten,
+		})
+		logger.Infof("%s %s %d %s %dB request_id=%s ip=%s",
 			r.Method,
 			r.URL.Path,
 			rw.statusCode,
 			duration,
+			rw.bytesWritten,
+			requestID,
+			ip,
 		)
 	}
 }
 
+// validRequestID reports whether a client-supplied request ID is safe to reuse:
+// non-empty, bounded in length and limited to printable ASCII without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > constants.MaxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 // authMiddleware extracts and validates JWT or API key and sets the auth entity in context.
 func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	authenticate := s.authenticate(next)
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-
 		// Check if this endpoint should bypass authentication (PRD-058)
 		if s.shouldBypassAuth(r.URL.Path) {
 			next(w, r)
 			return
 		}
+		authenticate(w, r)
+	}
+}
+
+// optionalAuth authenticates requests that carry credentials as
+// authMiddleware does and passes anonymous ones through, for public endpoints
+// that show a tenant its own collections. Without tenancy every request is
+// passed through.
+func (s *Server) optionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	authenticate := s.authenticate(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.config.Tenancy.Enabled || !s.hasCredentials(r) {
+			next(w, r)
+			return
+		}
+		authenticate(w, r)
+	}
+}
+
+// hasCredentials reports whether a request carries a bearer token or an API key
+func (s *Server) hasCredentials(r *http.Request) bool {
+	return r.Header.Get(constants.HeaderAuthorization) != "" ||
+		r.Header.Get(s.config.APIKey.Header) != "" ||
+		r.Header.Get(constants.HeaderAPIKey) != ""
+}
+
+// authenticate sets the auth entity of a bearer token or API key and calls
+// next, writing 401 for missing or invalid credentials
+func (s *Server) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 
 		// Try JWT authentication first (Authorization: Bearer <token>)
 		authHeader := r.Header.Get(constants.HeaderAuthorization)
@@ -332,11 +619,11 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 					blacklisted, err := s.tokenBlacklist.IsBlacklisted(ctx, token)
 					if err != nil {
 						log.Printf("Error checking token blacklist: %v", err)
-						s.writeAuthError(w, http.StatusInternalServerError, "authentication error")
+						s.writeAuthError(w, r, http.StatusInternalServerError, apperrors.CodeInternalError, "authentication error")
 						return
 					}
 					if blacklisted {
-						s.writeAuthError(w, http.StatusUnauthorized, "token has been revoked")
+						s.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeTokenRevoked, "token has been revoked")
 						return
 					}
 
@@ -350,12 +637,15 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 							CanWrite: claims.CanWrite,
 							Username: claims.Username,
 						}
+						if s.config.Tenancy.Enabled {
+							entity.Tenant = claims.Tenant
+						}
 						ctx = middleware.SetAuthEntity(ctx, entity)
 						next(w, r.WithContext(ctx))
 						return
 					}
 					// Invalid JWT token
-					s.writeAuthError(w, http.StatusUnauthorized, "invalid or expired token")
+					s.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidToken, "invalid or expired token")
 					return
 				}
 			}
@@ -370,32 +660,51 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			keyHash := auth.HashAPIKey(apiKey)
 			apiKeyObj, err := s.apiKeyRepo.GetByHash(ctx, keyHash)
 			if err == nil && apiKeyObj != nil {
+				// Revoked and expired keys are looked up on every request, so
+				// revocation takes effect without a restart
+				if apiKeyObj.IsRevoked() {
+					s.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeAPIKeyRevoked, "API key has been revoked")
+					return
+				}
+				if apiKeyObj.IsExpired() {
+					s.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeAPIKeyExpired, "API key has expired")
+					return
+				}
+
 				// Valid API key - create auth entity
 				entity := &middleware.AuthEntity{
 					ID:       apiKeyObj.ID,
 					Type:     middleware.EntityTypeAPIKey,
 					Role:     apiKeyObj.Role,
 					CanWrite: apiKeyObj.CanWrite,
+					Scopes:   apiKeyObj.Scopes,
+				}
+				if s.config.Tenancy.Enabled {
+					entity.Tenant = apiKeyObj.Tenant
 				}
 				ctx = middleware.SetAuthEntity(ctx, entity)
 
-				// Update last used (non-blocking)
-				go func(pkid int64) {
-					if err := s.apiKeyRepo.UpdateLastUsed(context.Background(), pkid); err != nil {
-						log.Printf("Failed to update API key last used: %v", err)
-					}
-				}(apiKeyObj.PKID)
+				// Update last used at most once per interval (non-blocking)
+				if s.apiKeyUsage.Due(apiKeyObj, time.Now()) {
+					s.lastUsed.Add(1)
+					go func(pkid int64) {
+						defer s.lastUsed.Done()
+						if err := s.apiKeyRepo.UpdateLastUsed(context.Background(), pkid); err != nil {
+							log.Printf("Failed to update API key last used: %v", err)
+						}
+					}(apiKeyObj.PKID)
+				}
 
 				next(w, r.WithContext(ctx))
 				return
 			}
 			// Invalid API key
-			s.writeAuthError(w, http.StatusUnauthorized, "invalid API key")
+			s.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeInvalidAPIKey, "invalid API key")
 			return
 		}
 
 		// No authentication provided
-		s.writeAuthError(w, http.StatusUnauthorized, "authentication required")
+		s.writeAuthError(w, r, http.StatusUnauthorized, apperrors.CodeAuthenticationRequired, "authentication required")
 	}
 }
 
@@ -413,19 +722,15 @@ func (s *Server) shouldBypassAuth(path string) bool {
 }
 
 // writeAuthError writes an authentication error response.
-func (s *Server) writeAuthError(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]any{
-		"error": message,
-		"code":  statusCode,
-	})
+func (s *Server) writeAuthError(w http.ResponseWriter, r *http.Request, statusCode int, code apperrors.ErrorCode, message string) {
+	s.writeError(w, r, statusCode, code, message)
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture status code and bytes written
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -433,16 +738,32 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// convertCORSEndpoints converts config.CORSEndpointConfig to middleware.CORSEndpointConfig (PRD-058)
-func convertCORSEndpoints(cfgEndpoints []config.CORSEndpointConfig) []middleware.CORSEndpointConfig {
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += n
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// convertCORSEndpoints converts config.CORSEndpointConfig to middleware.CORSEndpointConfig (PRD-058).
+// Endpoints that require authentication also allow the API key header.
+func convertCORSEndpoints(cfgEndpoints []config.CORSEndpointConfig, apiKeyHeader string) []middleware.CORSEndpointConfig {
 	endpoints := make([]middleware.CORSEndpointConfig, len(cfgEndpoints))
 	for i, cfg := range cfgEndpoints {
+		headers := cfg.AllowedHeaders
+		if !cfg.BypassAuth {
+			headers = withHeader(headers, apiKeyHeader)
+		}
 		endpoints[i] = middleware.CORSEndpointConfig{
 			Path:             cfg.Path,
 			PatternType:      cfg.PatternType,
 			AllowedOrigins:   cfg.AllowedOrigins,
 			AllowedMethods:   cfg.AllowedMethods,
-			AllowedHeaders:   cfg.AllowedHeaders,
+			AllowedHeaders:   headers,
 			AllowCredentials: cfg.AllowCredentials,
 			BypassAuth:       cfg.BypassAuth,
 		}
@@ -450,81 +771,521 @@ func convertCORSEndpoints(cfgEndpoints []config.CORSEndpointConfig) []middleware
 	return endpoints
 }
 
-// Start starts the HTTP server
-func (s *Server) Start() error {
-	log.Printf("Starting server on %s", s.server.Addr)
-	return s.server.ListenAndServe()
+// withHeader returns headers with header appended unless already present (case-insensitive)
+func withHeader(headers []string, header string) []string {
+	if header == "" {
+		return headers
+	}
+	for _, h := range headers {
+		if strings.EqualFold(h, header) {
+			return headers
+		}
+	}
+	return append(append([]string{}, headers...), header)
 }
 
-// Shutdown gracefully shuts down the server
+// Shutdown gracefully shuts down the server and the admin listener together
 func (s *Server) Shutdown(ctx context.Context) error {
-	log.Println("Shutting down server...")
-	return s.server.Shutdown(ctx)
+	logging.Info("Shutting down server: no longer accepting connections")
+	if s.adminServer == nil {
+		return s.server.Shutdown(ctx)
+	}
+	adminErr := make(chan error, 1)
+	go func() { adminErr <- s.adminServer.Shutdown(ctx) }()
+	return errors.Join(s.server.Shutdown(ctx), <-adminErr)
+}
+
+// close closes the server and the admin listener and their connections
+func (s *Server) close() error {
+	if s.adminServer == nil {
+		return s.server.Close()
+	}
+	return errors.Join(s.server.Close(), s.adminServer.Close())
+}
+
+// OnShutdown registers fn to run during shutdown after the database driver is closed.
+// Functions run in registration order.
+func (s *Server) OnShutdown(fn func()) {
+	s.cleanups = append(s.cleanups, fn)
 }
 
-// Run starts the server and handles graceful shutdown
+// Run starts the server and handles graceful shutdown on SIGINT or SIGTERM
 func (s *Server) Run() error {
-	// Start server in a goroutine
-	serverErrors := make(chan error, 1)
+	// Listen for interrupt signals before accepting connections
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	var adminListener net.Listener
+	if s.adminServer != nil {
+		if adminListener, err = net.Listen("tcp", s.adminServer.Addr); err != nil {
+			listener.Close()
+			return fmt.Errorf("admin server error: %w", err)
+		}
+	}
+	return s.serve(listener, adminListener, signals)
+}
+
+// serve accepts connections on listener, and on adminListener unless it is
+// nil, until a signal arrives, then shuts down in order: stop accepting
+// connections on both and wait up to server.shutdown_timeout for in-flight
+// requests, deliver queued webhooks and write queued audit entries within the
+// same deadline, checkpoint the change sequences, release the instance lock,
+// close the database driver, and run the OnShutdown functions.
+func (s *Server) serve(listener, adminListener net.Listener, signals <-chan os.Signal) error {
+	logging.Infof("Starting server on %s", listener.Addr())
+
+	// Start the servers in goroutines
+	serverErrors := make(chan error, 2)
 	go func() {
-		serverErrors <- s.Start()
+		serverErrors <- s.server.Serve(listener)
 	}()
+	if adminListener != nil {
+		logging.Infof("Starting admin server on %s", adminListener.Addr())
+		go func() {
+			if err := s.adminServer.Serve(adminListener); err != nil {
+				serverErrors <- fmt.Errorf("admin: %w", err)
+			}
+		}()
+	}
 
-	// Listen for interrupt signals
-	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
-
-	// Block until we receive a signal or server error
+	// Block until we receive a signal or server error; one listener failing
+	// takes the other down with it
 	select {
 	case err := <-serverErrors:
+		s.close()
+		s.releaseInstanceLock(context.Background())
 		return fmt.Errorf("server error: %w", err)
+	case sig := <-signals:
+		logging.Infof("Received signal: %v", sig)
+	}
 
-	case sig := <-shutdown:
-		log.Printf("Received signal: %v", sig)
-
-		// Give outstanding requests a deadline for completion
-		ctx, cancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
-		defer cancel()
+	// Give outstanding requests a deadline for completion
+	timeout := time.Duration(s.config.Server.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = constants.ShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var shutdownErr error
+	if err := s.Shutdown(ctx); err != nil {
+		logging.Warnf("In-flight requests did not finish within %s, closing connections: %v", timeout, err)
+		if err := s.close(); err != nil {
+			shutdownErr = fmt.Errorf("could not stop server gracefully: %w", err)
+		}
+	} else {
+		logging.Info("All in-flight requests completed")
+	}
 
-		// Shutdown the server
-		if err := s.Shutdown(ctx); err != nil {
-			if err := s.server.Close(); err != nil {
-				return fmt.Errorf("could not stop server gracefully: %w", err)
-			}
+	// No handler can publish any more; flush what is queued
+	if s.webhooks != nil {
+		if err := s.webhooks.Close(ctx); err != nil {
+			logging.Warnf("Queued webhooks were not delivered within %s: %v", timeout, err)
+		} else {
+			logging.Info("Queued webhooks delivered")
+		}
+	}
+	if s.audit != nil {
+		if err := s.audit.Close(ctx); err != nil {
+			logging.Warnf("Queued audit entries were not written within %s: %v", timeout, err)
+		} else {
+			logging.Info("Queued audit entries written")
 		}
 	}
+	if err := s.changes.Close(ctx); err != nil {
+		logging.Warnf("Failed to checkpoint change sequences: %v", err)
+	}
+	if err := s.sweeper.Close(ctx); err != nil {
+		logging.Warnf("TTL sweep did not stop within %s: %v", timeout, err)
+	}
+	s.releaseInstanceLock(ctx)
+
+	// Handlers are done with the database, so SQLite can checkpoint its WAL on
+	// close; last-used updates they started still finish first
+	s.lastUsed.Wait()
+	if err := s.db.Close(); err != nil {
+		logging.Errorf("Failed to close database: %v", err)
+	} else {
+		logging.Info("Database connection closed")
+	}
 
-	return nil
+	if stats, ok := s.CacheStats(); ok {
+		logging.Infof("Query cache: %d hits, %d misses, %d entries", stats.Hits, stats.Misses, stats.Entries)
+	}
+
+	for _, fn := range s.cleanups {
+		fn()
+	}
+
+	return shutdownErr
 }
 
-// Health check handler
-func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+// SetInstanceLock sets the instance lock released on shutdown, before the
+// database driver is closed
+func (s *Server) SetInstanceLock(l *daemon.InstanceLock) {
+	s.instanceLock = l
+}
+
+// releaseInstanceLock releases the instance lock, if any, so that another
+// instance can start on the database
+func (s *Server) releaseInstanceLock(ctx context.Context) {
+	if s.instanceLock == nil {
+		return
+	}
+	if err := s.instanceLock.Release(ctx); err != nil {
+		logging.Warnf("%v", err)
+		return
+	}
+	logging.Info("Instance lock released")
+}
+
+// SetDaemon records whether the process runs in daemon mode, as reported by /health
+func (s *Server) SetDaemon(daemon bool) {
+	s.daemon = daemon
+}
 
-	status := "live"
+// StartChanges resumes the change sequences of :changes from their last
+// checkpoint and checkpoints them periodically until shutdown
+func (s *Server) StartChanges(ctx context.Context) error {
+	return s.changes.Start(ctx, constants.ChangeFeedCheckpointInterval)
+}
+
+// StartSweeper deletes the expired records of the collections with a ttl
+// policy every ttl.sweep_interval until shutdown; 0 disables it
+func (s *Server) StartSweeper() {
+	if s.config.TTL.SweepInterval > 0 {
+		s.sweeper.Start(time.Duration(s.config.TTL.SweepInterval) * time.Second)
+	}
+}
+
+// InitIdempotency creates the table of the :create responses replayed by
+// their Idempotency-Key
+func (s *Server) InitIdempotency(ctx context.Context) error {
+	return s.idempotency.Init(ctx)
+}
+
+// HealthResponse is the body of the /health endpoint
+type HealthResponse struct {
+	Status        string         `json:"status"`
+	Name          string         `json:"name"`
+	Version       string         `json:"version"`
+	Database      DatabaseHealth `json:"database"`
+	Collections   int            `json:"collections"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Daemon        bool           `json:"daemon"`
+}
+
+// DatabaseHealth reports the database dialect, whether it answered the ping
+// and the state of its connection pool
+type DatabaseHealth struct {
+	Dialect   string      `json:"dialect"`
+	Connected bool        `json:"connected"`
+	Pool      *PoolHealth `json:"pool,omitempty"`
+}
+
+// PoolHealth reports the connection pool from sql.DBStats. The wait count
+// and duration are totals since startup; a growing wait count means requests
+// queue for a connection and database.pool.max_open is too low.
+type PoolHealth struct {
+	MaxOpen        int   `json:"max_open"`
+	Open           int   `json:"open"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"`
+	WaitDurationMs int64 `json:"wait_duration_ms"`
+}
+
+// poolHealth returns the pool stats of db, or nil without a pool
+func poolHealth(db *sql.DB) *PoolHealth {
+	if db == nil {
+		return nil
+	}
+	stats := db.Stats()
+	return &PoolHealth{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDurationMs: stats.WaitDuration.Milliseconds(),
+	}
+}
+
+// healthHandler reports dependency status and build info. It returns 503 when
+// the database does not answer the ping within constants.HealthPingTimeout so
+// load balancers can take the instance out of rotation.
+func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), constants.HealthPingTimeout)
+	defer cancel()
+
+	response := HealthResponse{
+		Status:        "live",
+		Name:          "moon",
+		Version:       s.version,
+		Database:      DatabaseHealth{Dialect: string(s.db.Dialect()), Connected: true, Pool: poolHealth(s.db.DB())},
+		Collections:   s.registry.Count(),
+		UptimeSeconds: int64(time.Since(s.startedAt).Seconds()),
+		Daemon:        s.daemon,
+	}
 
-	// Check database connection
-	if err := s.db.Ping(ctx); err != nil {
-		status = "down"
+	if _, err := (&checks.Database{Driver: s.db}).Run(ctx); err != nil {
+		logging.Warnf("Health check: %v", err)
+		response.Status = "down"
+		response.Database.Connected = false
+		s.writeJSON(w, http.StatusServiceUnavailable, response)
+		return
 	}
 
-	response := map[string]string{
-		"status":  status,
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// livenessHandler reports that the process is serving requests. It never
+// touches the database, so liveness probes do not restart the server while
+// the database is unavailable.
+func (s *Server) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "live",
 		"name":    "moon",
 		"version": s.version,
+	})
+}
+
+// consistencyHandler runs the consistency checker against the live database
+// and returns its issues. Nothing is changed unless repair=true, which applies
+// auto-repair with the configured recovery settings. The check runs under the
+// request context, so it stops when the client goes away.
+func (s *Server) consistencyHandler(w http.ResponseWriter, r *http.Request) {
+	// The report covers every collection, so the key must hold the schema scope on all of them
+	if !middleware.HasScope(r.Context(), auth.ScopeAllCollections, auth.ScopeSchema) {
+		middleware.WriteScopeError(w, r, auth.ScopeAllCollections, auth.ScopeSchema)
+		return
 	}
 
-	// Always return HTTP 200, even if service is down
-	// Clients must check the "status" field to determine service health
-	s.writeJSON(w, http.StatusOK, response)
+	repair, err := handlers.QueryBoolean(r, "repair", false)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, apperrors.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	cfg := s.config.Recovery
+	cfg.AutoRepair = repair
+	if cfg.CheckTimeout <= 0 {
+		cfg.CheckTimeout = config.Defaults.Recovery.CheckTimeout
+	}
+
+	result, err := consistency.NewChecker(s.db, s.registry, &cfg).Check(r.Context())
+	if err != nil {
+		if result != nil && result.TimedOut {
+			s.writeError(w, r, http.StatusServiceUnavailable, apperrors.CodeQueryTimeout, err.Error())
+			return
+		}
+		s.writeError(w, r, http.StatusInternalServerError, apperrors.CodeDatabaseError, fmt.Sprintf("consistency check failed: %v", err))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-// corsPreflightHandler handles CORS preflight OPTIONS requests.
-// The CORS middleware wrapping this handler sets all necessary headers and returns 204.
-// This handler exists solely to provide a route for OPTIONS requests.
+// corsPreflightHandler handles OPTIONS requests.
+// The CORS middleware wrapping this handler answers browser preflights itself;
+// plain OPTIONS requests reach this handler and get 204 with the Allow header.
 func (s *Server) corsPreflightHandler(w http.ResponseWriter, r *http.Request) {
-	// The CORS middleware handles everything for OPTIONS requests
-	// This is just a no-op handler that allows the route to exist
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// withAllow sets the Allow header for a route before running next.
+// GET routes also accept HEAD, and every route accepts OPTIONS.
+func withAllow(methods []string, next http.HandlerFunc) http.HandlerFunc {
+	allow := allowHeader(methods...)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		next(w, r)
+	}
+}
+
+// allowHeader builds the Allow header value for the given route methods
+func allowHeader(methods ...string) string {
+	allowed := make([]string, 0, len(methods)+2)
+	for _, method := range methods {
+		allowed = append(allowed, method)
+		if method == http.MethodGet {
+			allowed = append(allowed, http.MethodHead)
+		}
+	}
+	return strings.Join(append(allowed, http.MethodOptions), ", ")
+}
+
+// dataActionMethods maps each dynamic data action to the method it accepts
+var dataActionMethods = map[string]string{
+	"list":        http.MethodGet,
+	"get":         http.MethodGet,
+	"export":      http.MethodGet,
+	"changes":     http.MethodGet,
+	"schema":      http.MethodGet,
+	"count":       http.MethodGet,
+	"sum":         http.MethodGet,
+	"avg":         http.MethodGet,
+	"min":         http.MethodGet,
+	"max":         http.MethodGet,
+	"groupby":     http.MethodGet,
+	"distinct":    http.MethodGet,
+	"timeseries":  http.MethodGet,
+	"stats":       http.MethodGet,
+	"explain":     http.MethodGet,
+	"create":      http.MethodPost,
+	"update":      http.MethodPost,
+	"destroy":     http.MethodPost,
+	"upsert":      http.MethodPost,
+	"import":      http.MethodPost,
+	"restore":     http.MethodPost,
+	"query":       http.MethodPost,
+	"checkunique": http.MethodPost,
+	"validate":    http.MethodPost,
+}
+
+// dataActionNames lists the data actions in the unknown_action error
+var dataActionNames = strings.Join(slices.Sorted(maps.Keys(dataActionMethods)), ", ")
+
+// dataAllowHeader sets the Allow header for known data actions so that OPTIONS,
+// preflight and 405 responses list the accepted methods
+func dataAllowHeader(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, action, ok := strings.Cut(r.URL.Path, ":"); ok {
+			if method, known := dataActionMethods[action]; known {
+				w.Header().Set("Allow", allowHeader(method))
+			}
+		}
+		next(w, r)
+	}
+}
+
+// cachedRead serves a GET data action from the query cache when enabled.
+// Responses are keyed by API version, base URL (which record links hold),
+// path and normalized query string, and :list responses also by the format
+// negotiated from Accept; only 200 responses to GET are stored, and never a
+// :get streamed with ?full=true or a read with ?include_hidden, whose
+// response depends on the caller. Responses carry X-Moon-Cache: hit or miss.
+func (s *Server) cachedRead(collectionName string, next http.HandlerFunc) http.HandlerFunc {
+	if s.cache == nil {
+		return next
+	}
+	return cachedResponse(s.cache, s.config.Server, collectionName, next)
+}
+
+// cachedStats serves :stats from the stats cache for stats.cache_ttl seconds.
+// Data writes leave it alone, so counts may lag by up to the TTL; schema
+// changes drop it through invalidateAll.
+func (s *Server) cachedStats(collectionName string, next http.HandlerFunc) http.HandlerFunc {
+	if s.statsCache == nil {
+		return next
+	}
+	return cachedResponse(s.statsCache, s.config.Server, collectionName, next)
+}
+
+// cachedResponse serves a GET data action from c, storing 200 responses
+func cachedResponse(c *cache.Cache, server config.ServerConfig, collectionName string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Streamed responses are too large to keep in memory, and hidden
+		// columns are only returned to some callers, which the key does not hold
+		if handlers.StreamsResponse(r) || handlers.RequestsHidden(r) {
+			next(w, r)
+			return
+		}
+
+		// The key pins the current generation before the query runs; tenants
+		// are kept apart by keying on the physical table
+		version := apiversion.FromContext(r.Context())
+		request := "v" + version.String() + " " + handlers.RequestBaseURL(server, r) + r.URL.Path + "?" + r.URL.Query().Encode()
+		if format := handlers.NegotiateListFormat(r); format != handlers.ListFormatJSON && strings.HasSuffix(r.URL.Path, ":list") {
+			request = format + " " + request
+		}
+		key := c.Key(tenantTable(r, collectionName), request)
+		if entry, ok := c.Get(key); ok {
+			for name, values := range entry.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(constants.HeaderCache, "hit")
+			w.WriteHeader(entry.Status)
+			w.Write(entry.Body)
+			return
+		}
+
+		w.Header().Set(constants.HeaderCache, "miss")
+		rec := &cacheRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rec, r)
+		if r.Method == http.MethodGet && rec.statusCode == http.StatusOK {
+			header := http.Header{}
+			for _, name := range []string{constants.HeaderContentType, constants.HeaderETag, constants.HeaderTotal, constants.HeaderNextCursor} {
+				if value := w.Header().Get(name); value != "" {
+					header.Set(name, value)
+				}
+			}
+			c.Set(key, cache.Entry{Status: rec.statusCode, Header: header, Body: rec.body.Bytes()})
+		}
+	}
+}
+
+// invalidate drops the cached responses of a collection after a write that
+// may have changed it; only client errors (4xx) leave the cache untouched
+func (s *Server) invalidate(collectionName string, next http.HandlerFunc) http.HandlerFunc {
+	if s.cache == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rw, r)
+		if rw.statusCode < 400 || rw.statusCode >= 500 {
+			s.cache.Invalidate(tenantTable(r, collectionName))
+		}
+	}
+}
+
+// invalidateAll drops every cached response, :stats included, after a schema change
+func (s *Server) invalidateAll(next http.HandlerFunc) http.HandlerFunc {
+	if s.cache == nil && s.statsCache == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rw, r)
+		if rw.statusCode < 400 || rw.statusCode >= 500 {
+			for _, c := range []*cache.Cache{s.cache, s.statsCache} {
+				if c != nil {
+					c.InvalidateAll()
+				}
+			}
+		}
+	}
+}
+
+// CacheStats returns the query cache counters; ok is false when caching is disabled
+func (s *Server) CacheStats() (stats cache.Stats, ok bool) {
+	if s.cache == nil {
+		return cache.Stats{}, false
+	}
+	return s.cache.Stats(), true
+}
+
+// cacheRecorder passes a response through while keeping a copy of the body
+type cacheRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *cacheRecorder) WriteHeader(code int) {
+	rec.statusCode = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *cacheRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
 }
 
 // Root message handler - returns a friendly message at the root path
@@ -542,7 +1303,7 @@ func (s *Server) rootMessageHandler(w http.ResponseWriter, r *http.Request) {
 
 // Not found handler
 func (s *Server) notFoundHandler(w http.ResponseWriter, r *http.Request) {
-	s.writeError(w, http.StatusNotFound, "Endpoint not found")
+	s.writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "Endpoint not found")
 }
 
 // writeJSON writes a JSON response
@@ -555,12 +1316,43 @@ func (s *Server) writeJSON(w http.ResponseWriter, statusCode int, data any) {
 	}
 }
 
-// writeError writes a JSON error response
-func (s *Server) writeError(w http.ResponseWriter, statusCode int, message string) {
-	s.writeJSON(w, statusCode, map[string]any{
-		"error": message,
-		"code":  statusCode,
-	})
+// writeError writes a JSON error response with a machine-readable code,
+// carrying the request ID for correlation
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, statusCode int, code apperrors.ErrorCode, message string) {
+	s.writeJSON(w, statusCode, apperrors.VersionedBody(apiversion.FromContext(r.Context()), statusCode, code, message, logging.GetRequestID(r.Context())))
+}
+
+// tenantTable returns the physical table of a collection for the tenant of
+// the authenticated entity; without a tenant it is the collection name
+func tenantTable(r *http.Request, collectionName string) string {
+	return registry.TenantKey(middleware.GetTenant(r.Context()), collectionName)
+}
+
+// unarchived answers 410 Gone for archived collections; their table and
+// records stay in place until the collection is unarchived or destroyed
+func (s *Server) unarchived(collectionName string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c, ok := s.registry.Get(tenantTable(r, collectionName)); ok && c.Archived {
+			s.writeError(w, r, http.StatusGone, apperrors.CodeCollectionArchived, fmt.Sprintf("Collection '%s' is archived", collectionName))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// hideFromTenants hides instance-wide endpoints from tenant principals when
+// tenancy is enabled; they see the same 404 as an unknown endpoint
+func (s *Server) hideFromTenants(next http.HandlerFunc) http.HandlerFunc {
+	if !s.config.Tenancy.Enabled {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if middleware.GetTenant(r.Context()) != "" {
+			s.writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "Endpoint not found")
+			return
+		}
+		next(w, r)
+	}
 }
 
 // Data handler wrappers that extract collection name from URL path
@@ -573,7 +1365,7 @@ func (s *Server) dynamicDataHandler(dataHandler *handlers.DataHandler, aggregati
 		// Split by colon to get name and action
 		parts := strings.SplitN(path, ":", 2)
 		if len(parts) != 2 {
-			s.writeError(w, http.StatusNotFound, "Endpoint not found")
+			s.writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "Endpoint not found")
 			return
 		}
 
@@ -582,110 +1374,146 @@ func (s *Server) dynamicDataHandler(dataHandler *handlers.DataHandler, aggregati
 
 		// Prevent accessing collections endpoint
 		if collectionName == "collections" {
-			s.writeError(w, http.StatusNotFound, "Endpoint not found")
+			s.writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "Endpoint not found")
 			return
 		}
 
 		// Skip reserved endpoints that are handled by other routes
-		if collectionName == "auth" || collectionName == "users" || collectionName == "apikeys" || collectionName == "doc" {
-			s.writeError(w, http.StatusNotFound, "Endpoint not found")
+		if collectionName == "auth" || collectionName == "users" || collectionName == "apikeys" || collectionName == "doc" || collectionName == "batch" {
+			s.writeError(w, r, http.StatusNotFound, apperrors.CodeNotFound, "Endpoint not found")
 			return
 		}
 
+		// Tenant tables are only reachable through their owner's logical names
+		if s.config.Tenancy.Enabled && strings.Contains(collectionName, constants.TenantSeparator) {
+			s.writeError(w, r, http.StatusNotFound, apperrors.CodeCollectionNotFound, "Collection not found")
+			return
+		}
+
+		method, known := dataActionMethods[action]
+		if !known {
+			s.writeError(w, r, http.StatusNotFound, apperrors.CodeUnknownAction, fmt.Sprintf("Unknown action '%s'; supported actions: %s", action, dataActionNames))
+			return
+		}
+
+		// OPTIONS without a browser preflight is answered here with the Allow header
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.Method != method && !(method == http.MethodGet && r.Method == http.MethodHead) {
+			s.writeError(w, r, http.StatusMethodNotAllowed, apperrors.CodeMethodNotAllowed, fmt.Sprintf("Method %s not allowed for :%s; use %s", r.Method, action, method))
+			return
+		}
+
+		// API key scopes are checked after authentication for the collection
+		read := func(h http.HandlerFunc) http.HandlerFunc {
+			return authenticated(s.authzMiddle.RequireScope(collectionName, auth.ScopeRead)(s.unarchived(collectionName, s.queryDeadline(action, h))))
+		}
+		write := func(h http.HandlerFunc) http.HandlerFunc {
+			return writeRequired(s.authzMiddle.RequireScope(collectionName, auth.ScopeWrite)(s.unarchived(collectionName, s.writable(s.auditedData(action, collectionName, s.queryDeadline(action, h))))))
+		}
+
 		// Route to appropriate handler based on action
-		// Read operations: authenticated (any role)
-		// Write operations: writeRequired (admin or user with can_write)
+		// Read operations: authenticated (any role) with read scope
+		// Write operations: writeRequired (admin or user with can_write) with write scope
 		switch action {
 		case "list":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				dataHandler.List(w, r, collectionName)
-			})(w, r)
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.List(w, r, tenantTable(r, collectionName))
+			}))(w, r)
 		case "get":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				dataHandler.Get(w, r, collectionName)
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Get(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "export":
+			read(func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Export(w, r, tenantTable(r, collectionName))
 			})(w, r)
-		case "create":
-			if r.Method != http.MethodPost {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			writeRequired(func(w http.ResponseWriter, r *http.Request) {
-				dataHandler.Create(w, r, collectionName)
+		case "changes":
+			read(func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Changes(w, r, tenantTable(r, collectionName))
 			})(w, r)
+		case "create":
+			write(s.invalidate(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Create(w, r, tenantTable(r, collectionName))
+			}))(w, r)
 		case "update":
-			if r.Method != http.MethodPost {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			writeRequired(func(w http.ResponseWriter, r *http.Request) {
-				dataHandler.Update(w, r, collectionName)
-			})(w, r)
+			write(s.invalidate(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Update(w, r, tenantTable(r, collectionName))
+			}))(w, r)
 		case "destroy":
-			if r.Method != http.MethodPost {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			writeRequired(func(w http.ResponseWriter, r *http.Request) {
-				dataHandler.Destroy(w, r, collectionName)
+			write(s.invalidate(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Destroy(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "upsert":
+			write(s.invalidate(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Upsert(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "import":
+			write(s.invalidate(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Import(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "restore":
+			write(s.invalidate(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Restore(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "query":
+			read(func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Query(w, r, tenantTable(r, collectionName))
 			})(w, r)
-		case "count":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				aggregationHandler.Count(w, r, collectionName)
+		case "checkunique":
+			read(func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.CheckUnique(w, r, tenantTable(r, collectionName))
 			})(w, r)
-		case "sum":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				aggregationHandler.Sum(w, r, collectionName)
+		case "validate":
+			read(func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Validate(w, r, tenantTable(r, collectionName))
 			})(w, r)
+		case "count":
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.Count(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "sum":
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.Sum(w, r, tenantTable(r, collectionName))
+			}))(w, r)
 		case "avg":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				aggregationHandler.Avg(w, r, collectionName)
-			})(w, r)
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.Avg(w, r, tenantTable(r, collectionName))
+			}))(w, r)
 		case "min":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				aggregationHandler.Min(w, r, collectionName)
-			})(w, r)
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.Min(w, r, tenantTable(r, collectionName))
+			}))(w, r)
 		case "max":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				aggregationHandler.Max(w, r, collectionName)
-			})(w, r)
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.Max(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "groupby":
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.GroupBy(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "distinct":
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.Distinct(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "timeseries":
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				aggregationHandler.TimeSeries(w, r, tenantTable(r, collectionName))
+			}))(w, r)
 		case "schema":
-			if r.Method != http.MethodGet {
-				s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
-				return
-			}
-			authenticated(func(w http.ResponseWriter, r *http.Request) {
-				dataHandler.Schema(w, r, collectionName)
+			read(s.cachedRead(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Schema(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "stats":
+			read(s.cachedStats(collectionName, func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Stats(w, r, tenantTable(r, collectionName))
+			}))(w, r)
+		case "explain":
+			read(func(w http.ResponseWriter, r *http.Request) {
+				dataHandler.Explain(w, r, tenantTable(r, collectionName))
 			})(w, r)
-		default:
-			s.writeError(w, http.StatusNotFound, "Unknown action")
 		}
 	}
 }
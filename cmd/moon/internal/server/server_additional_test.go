@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/database"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 )
@@ -197,20 +199,70 @@ func TestHealthHandler_DatabaseDown(t *testing.T) {
 
 	srv.healthHandler(w, req)
 
-	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
 	}
 
-	var response map[string]any
+	var response HealthResponse
 	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if response["status"] != "down" {
-		t.Errorf("Expected status 'down' when db fails, got '%v'", response["status"])
+	if response.Status != "down" {
+		t.Errorf("Expected status 'down' when db fails, got '%v'", response.Status)
+	}
+	if response.Database.Connected || response.Database.Dialect != "sqlite" {
+		t.Errorf("Expected disconnected sqlite database, got %+v", response.Database)
+	}
+	if response.Version != "1.0" {
+		t.Errorf("Expected version '1.0', got '%v'", response.Version)
+	}
+
+	// Liveness never touches the database
+	w = httptest.NewRecorder()
+	srv.livenessHandler(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected liveness status %d, got %d", http.StatusOK, w.Code)
 	}
 }
 
+// TestHealthHandler_PingTimeout tests that a hanging database ping is cut off
+func TestHealthHandler_PingTimeout(t *testing.T) {
+	srv := New(&config.AppConfig{}, &mockHangingPingDriver{}, registry.NewSchemaRegistry(), "1.0")
+	srv.SetDaemon(true)
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	srv.healthHandler(w, req)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the ping to time out after %v, took %v", constants.HealthPingTimeout, elapsed)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	var response HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Daemon {
+		t.Error("Expected daemon mode to be reported")
+	}
+}
+
+// mockHangingPingDriver is a mock driver whose ping blocks until the context ends
+type mockHangingPingDriver struct {
+	mockFailingPingDriver
+}
+
+func (m *mockHangingPingDriver) Ping(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
 // mockFailingPingDriver is a mock driver that fails on ping
 type mockFailingPingDriver struct{}
 
@@ -226,6 +278,9 @@ func (m *mockFailingPingDriver) GetTableInfo(ctx context.Context, tableName stri
 func (m *mockFailingPingDriver) TableExists(ctx context.Context, tableName string) (bool, error) {
 	return false, nil
 }
+func (m *mockFailingPingDriver) TableStats(ctx context.Context, tableName string) (*database.TableStats, error) {
+	return nil, nil
+}
 func (m *mockFailingPingDriver) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	return nil, nil
 }
@@ -1,15 +1,31 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/cache"
 	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/daemon"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	apperrors "github.com/thalib/moon/cmd/moon/internal/errors"
+	"github.com/thalib/moon/cmd/moon/internal/handlers"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/middleware"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
 )
 
@@ -129,9 +145,51 @@ func TestHealthHandler(t *testing.T) {
 		t.Errorf("Expected version '1-test', got '%v'", response["version"])
 	}
 
-	// Ensure no other fields are present
-	if len(response) != 3 {
-		t.Errorf("Expected exactly 3 fields, got %d: %v", len(response), response)
+	db, _ := response["database"].(map[string]any)
+	if db["dialect"] != "sqlite" || db["connected"] != true {
+		t.Errorf("Expected connected sqlite database, got %v", response["database"])
+	}
+	if pool, ok := db["pool"].(map[string]any); !ok || pool["open"] == nil || pool["wait_count"] != float64(0) {
+		t.Errorf("Expected the connection pool stats, got %v", db["pool"])
+	}
+
+	if response["collections"] != float64(0) {
+		t.Errorf("Expected 0 collections, got %v", response["collections"])
+	}
+
+	if response["daemon"] != false {
+		t.Errorf("Expected daemon false, got %v", response["daemon"])
+	}
+
+	if _, ok := response["uptime_seconds"].(float64); !ok {
+		t.Errorf("Expected numeric uptime_seconds, got %v", response["uptime_seconds"])
+	}
+
+	// The structure is stable: no other fields are present
+	if len(response) != 7 {
+		t.Errorf("Expected exactly 7 fields, got %d: %v", len(response), response)
+	}
+}
+
+func TestHealthLiveHandler(t *testing.T) {
+	srv := setupTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response["status"] != "live" || response["version"] != "1-test" || len(response) != 3 {
+		t.Errorf("Expected status, name and version only, got %v", response)
 	}
 }
 
@@ -307,8 +365,9 @@ func TestWriteError(t *testing.T) {
 	srv := setupTestServer(t)
 
 	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 
-	srv.writeError(w, http.StatusBadRequest, "Test error message")
+	srv.writeError(w, req, http.StatusBadRequest, apperrors.CodeInvalidParameter, "Test error message")
 
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
@@ -319,12 +378,12 @@ func TestWriteError(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if response["error"] != "Test error message" {
-		t.Errorf("Expected error 'Test error message', got '%v'", response["error"])
+	if detail, _ := response["error"].(map[string]any); detail["message"] != "Test error message" {
+		t.Errorf("Expected error message 'Test error message', got '%v'", response["error"])
 	}
 
-	if response["code"].(float64) != float64(http.StatusBadRequest) {
-		t.Errorf("Expected code %d, got %v", http.StatusBadRequest, response["code"])
+	if response["code"] != string(apperrors.CodeInvalidParameter) || response["status"] != float64(http.StatusBadRequest) {
+		t.Errorf("Expected code %s and status %d, got %v and %v", apperrors.CodeInvalidParameter, http.StatusBadRequest, response["code"], response["status"])
 	}
 }
 
@@ -980,3 +1039,789 @@ func TestAuthEndpointsCORSPreflight(t *testing.T) {
 		})
 	}
 }
+
+// setupCORSTestServer creates a server with CORS enabled for a single allowed origin
+func setupCORSTestServer(t *testing.T) *Server {
+	cfg := &config.AppConfig{
+		Server: config.ServerConfig{
+			Port: 6006,
+			Host: "0.0.0.0",
+		},
+		Database: config.DatabaseConfig{
+			Connection: "sqlite",
+			Database:   ":memory:",
+		},
+		JWT: config.JWTConfig{
+			Secret: "test-secret",
+			Expiry: 3600,
+		},
+		APIKey: config.APIKeyConfig{
+			Header: "X-API-Key",
+		},
+		CORS: config.CORSConfig{
+			Enabled:          true,
+			AllowedOrigins:   []string{"https://app.example.com"},
+			AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+			AllowedHeaders:   []string{"Content-Type", "Authorization"},
+			AllowCredentials: true,
+			MaxAge:           3600,
+			Endpoints:        config.Defaults.CORS.Endpoints,
+		},
+	}
+
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create database driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	return New(cfg, driver, registry.NewSchemaRegistry(), "1-test")
+}
+
+// TestCORSPreflight_APIKeyHeader tests preflight for a POST carrying the API key header
+func TestCORSPreflight_APIKeyHeader(t *testing.T) {
+	srv := setupCORSTestServer(t)
+
+	tests := []struct {
+		name      string
+		path      string
+		wantAllow string
+	}{
+		{"data_create", "/products:create", "POST, OPTIONS"},
+		{"collections_create", "/collections:create", "POST, OPTIONS"},
+		{"auth_me", "/auth:me", "GET, HEAD, POST, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", "content-type, x-api-key")
+			w := httptest.NewRecorder()
+
+			srv.mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+				t.Errorf("Expected Access-Control-Allow-Origin https://app.example.com, got %q", got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "X-API-Key") {
+				t.Errorf("Expected Access-Control-Allow-Headers to include X-API-Key, got %q", got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, "POST") {
+				t.Errorf("Expected Access-Control-Allow-Methods to include POST, got %q", got)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Expected Allow %q, got %q", tt.wantAllow, got)
+			}
+		})
+	}
+}
+
+// TestCORSPreflight_DisallowedOrigin tests that preflights from unlisted origins get 403
+func TestCORSPreflight_DisallowedOrigin(t *testing.T) {
+	srv := setupCORSTestServer(t)
+
+	for _, path := range []string{"/products:create", "/products:list", "/collections:list", "/auth:login"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, path, nil)
+			req.Header.Set("Origin", "https://evil.example.com")
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", "x-api-key")
+			w := httptest.NewRecorder()
+
+			srv.mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("Expected no Access-Control-Allow-Origin, got %q", got)
+			}
+		})
+	}
+}
+
+// TestOptionsWithoutPreflight tests that plain OPTIONS requests get the Allow header without authentication
+func TestOptionsWithoutPreflight(t *testing.T) {
+	srv := setupTestServer(t)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{"/products:list", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{"/products:destroy", http.StatusNoContent, "POST, OPTIONS"},
+		{"/users:list", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{"/health", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{"/health/live", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{"/products:unknown", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			srv.mux.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Expected Allow %q, got %q", tt.wantAllow, got)
+			}
+		})
+	}
+
+	// A wrong method on a data action reports the accepted methods
+	req := httptest.NewRequest(http.MethodPost, "/products:list", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if got := w.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow on 405 response, got %q", got)
+	}
+}
+
+// TestDataActionMethods tests every data action against every method: the
+// accepted method reaches authentication, any other gets 405 with Allow
+func TestDataActionMethods(t *testing.T) {
+	srv := setupTestServer(t)
+
+	actions := []struct {
+		action string
+		method string
+	}{
+		{"list", http.MethodGet},
+		{"get", http.MethodGet},
+		{"export", http.MethodGet},
+		{"schema", http.MethodGet},
+		{"count", http.MethodGet},
+		{"sum", http.MethodGet},
+		{"avg", http.MethodGet},
+		{"min", http.MethodGet},
+		{"max", http.MethodGet},
+		{"groupby", http.MethodGet},
+		{"distinct", http.MethodGet},
+		{"timeseries", http.MethodGet},
+		{"explain", http.MethodGet},
+		{"create", http.MethodPost},
+		{"update", http.MethodPost},
+		{"destroy", http.MethodPost},
+		{"upsert", http.MethodPost},
+		{"import", http.MethodPost},
+		{"restore", http.MethodPost},
+		{"query", http.MethodPost},
+		{"checkunique", http.MethodPost},
+		{"validate", http.MethodPost},
+	}
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+	for _, a := range actions {
+		wantAllow := "POST, OPTIONS"
+		if a.method == http.MethodGet {
+			wantAllow = "GET, HEAD, OPTIONS"
+		}
+		for _, method := range methods {
+			t.Run(a.action+"/"+method, func(t *testing.T) {
+				req := httptest.NewRequest(method, "/products:"+a.action, nil)
+				w := httptest.NewRecorder()
+				srv.mux.ServeHTTP(w, req)
+
+				if got := w.Header().Get("Allow"); got != wantAllow {
+					t.Errorf("Expected Allow %q, got %q", wantAllow, got)
+				}
+				accepted := method == a.method || (a.method == http.MethodGet && method == http.MethodHead)
+				if accepted {
+					if w.Code != http.StatusUnauthorized {
+						t.Errorf("Expected the accepted method to reach authentication, got %d", w.Code)
+					}
+					return
+				}
+				if w.Code != http.StatusMethodNotAllowed {
+					t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+				}
+				if method == http.MethodHead {
+					return
+				}
+				var resp map[string]any
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("Expected a JSON error body: %v", err)
+				}
+				if resp["code"] != string(apperrors.CodeMethodNotAllowed) {
+					t.Errorf("Expected code %s, got %v", apperrors.CodeMethodNotAllowed, resp["code"])
+				}
+			})
+		}
+	}
+
+	// Unknown actions list the supported ones
+	req := httptest.NewRequest(http.MethodGet, "/products:explode", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var resp map[string]any
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusNotFound || resp["code"] != string(apperrors.CodeUnknownAction) {
+		t.Fatalf("Expected 404 %s, got %d %v", apperrors.CodeUnknownAction, w.Code, resp["code"])
+	}
+	detail, _ := resp["error"].(map[string]any)
+	if msg, _ := detail["message"].(string); !strings.Contains(msg, "supported actions: avg, changes, checkunique, count, create") {
+		t.Errorf("Expected the supported actions in the message, got %q", msg)
+	}
+}
+
+// TestHeadRequests tests that GET endpoints answer HEAD with headers only
+func TestHeadRequests(t *testing.T) {
+	srv := setupTestServer(t)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/health", http.StatusOK},
+		{"/doc/llms.md", http.StatusOK},
+		// Data reads pass the method check and reach authentication
+		{"/products:list", http.StatusUnauthorized},
+		// Write actions do not accept HEAD
+		{"/products:create", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			// Serve through a real server so net/http discards HEAD bodies
+			ts := httptest.NewServer(srv.mux)
+			defer ts.Close()
+
+			resp, err := http.Head(ts.URL + tt.path)
+			if err != nil {
+				t.Fatalf("HEAD request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if len(body) != 0 {
+				t.Errorf("Expected empty body for HEAD, got %d bytes", len(body))
+			}
+		})
+	}
+}
+
+// TestRequestID_Header tests that the request ID is generated, honored and echoed
+func TestRequestID_Header(t *testing.T) {
+	srv := setupTestServer(t)
+	handler := srv.server.Handler
+
+	// Generated IDs are ULIDs
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if id := w.Header().Get("X-Request-ID"); len(id) != 26 {
+		t.Errorf("Expected generated 26-character ULID request ID, got %q", id)
+	}
+
+	// A valid incoming ID is echoed unchanged
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("X-Request-ID", "client-trace-42")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if id := w.Header().Get("X-Request-ID"); id != "client-trace-42" {
+		t.Errorf("Expected incoming request ID to be echoed, got %q", id)
+	}
+
+	// Invalid incoming IDs are replaced
+	for _, bad := range []string{"has space", strings.Repeat("a", 129)} {
+		req = httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("X-Request-ID", bad)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if id := w.Header().Get("X-Request-ID"); id == bad || len(id) != 26 {
+			t.Errorf("Expected invalid request ID %q to be replaced, got %q", bad, id)
+		}
+	}
+}
+
+// TestRequestID_UniqueAcrossConcurrentRequests tests that concurrent requests get distinct IDs
+func TestRequestID_UniqueAcrossConcurrentRequests(t *testing.T) {
+	srv := setupTestServer(t)
+	handler := srv.server.Handler
+
+	const requests = 50
+	ids := make(chan string, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+			ids <- w.Header().Get("X-Request-ID")
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		if id == "" || seen[id] {
+			t.Fatalf("Expected unique non-empty request IDs, got duplicate or empty %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+// TestRequestID_ErrorBody tests that error responses carry the request ID
+func TestRequestID_ErrorBody(t *testing.T) {
+	srv := setupTestServer(t)
+	handler := srv.server.Handler
+
+	for _, path := range []string{"/products:list", "/products:unknown", "/collections:list"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode error response: %v", err)
+			}
+			if body["request_id"] != w.Header().Get("X-Request-ID") {
+				t.Errorf("Expected request_id %q in error body, got %v", w.Header().Get("X-Request-ID"), body["request_id"])
+			}
+		})
+	}
+}
+
+// TestAccessLog tests that one structured access log line is written per request
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logging.Init(logging.LoggerConfig{Level: logging.LevelInfo, Format: "json", Output: &buf})
+	defer logging.Init(logging.LoggerConfig{Level: logging.LevelInfo, Format: "json"})
+
+	srv := setupTestServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/products:list", nil)
+	req.Header.Set("X-Request-ID", "trace-1")
+	w := httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(w, req)
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if json.Unmarshal([]byte(line), &entry) == nil && entry["path"] == "/products:list" {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 access log line, got %d: %s", len(entries), buf.String())
+	}
+	entry := entries[0]
+	if entry["request_id"] != "trace-1" || entry["method"] != "GET" || entry["status"] != float64(http.StatusUnauthorized) {
+		t.Errorf("Unexpected access log fields: %v", entry)
+	}
+	if entry["bytes"] != float64(w.Body.Len()) {
+		t.Errorf("Expected bytes %d, got %v", w.Body.Len(), entry["bytes"])
+	}
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Error("Expected duration_ms in access log")
+	}
+}
+
+// TestGracefulShutdown tests that in-flight requests complete, new connections are
+// refused, and the database is closed before shutdown functions run
+func TestGracefulShutdown(t *testing.T) {
+	srv := setupTestServer(t)
+	srv.config.Server.ShutdownTimeout = 5
+
+	started := make(chan struct{})
+	srv.mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-time.After(300 * time.Millisecond):
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+		}
+	})
+
+	var order []string
+	srv.OnShutdown(func() {
+		if err := srv.db.Ping(context.Background()); err == nil {
+			t.Error("Expected database to be closed before shutdown functions run")
+		}
+		order = append(order, "cleanup")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- srv.serve(listener, nil, signals) }()
+
+	slowStatus := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			t.Errorf("Slow request failed: %v", err)
+			slowStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		slowStatus <- resp.StatusCode
+	}()
+
+	<-started
+	signals <- syscall.SIGTERM
+
+	// New connections are refused while the slow request is still running
+	refused := false
+	for deadline := time.Now().Add(250 * time.Millisecond); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			refused = true
+			break
+		}
+		conn.Close()
+	}
+	if !refused {
+		t.Error("Expected new connections to be refused after shutdown started")
+	}
+
+	if status := <-slowStatus; status != http.StatusOK {
+		t.Errorf("Expected in-flight request to complete with %d, got %d", http.StatusOK, status)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+	if len(order) != 1 {
+		t.Errorf("Expected shutdown function to run once, got %v", order)
+	}
+}
+
+// TestGracefulShutdown_ReleasesInstanceLock tests that shutdown releases the
+// instance lock so that the next instance can start on the database
+func TestGracefulShutdown_ReleasesInstanceLock(t *testing.T) {
+	srv := setupTestServer(t)
+	path := filepath.Join(t.TempDir(), "moon.db.lock")
+	lock, err := daemon.LockFile(path)
+	if err != nil {
+		t.Fatalf("LockFile() error = %v", err)
+	}
+	srv.SetInstanceLock(lock)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- srv.serve(listener, nil, signals) }()
+
+	if _, err := daemon.LockFile(path); err == nil {
+		t.Fatal("Expected the lock to be held while serving")
+	}
+	signals <- syscall.SIGTERM
+	if err := <-done; err != nil {
+		t.Fatalf("Expected clean shutdown, got %v", err)
+	}
+	next, err := daemon.LockFile(path)
+	if err != nil {
+		t.Fatalf("Expected the lock to be released on shutdown, got %v", err)
+	}
+	next.Release(context.Background())
+}
+
+// TestAdminListener tests that with server.admin_port the administrative routes
+// are only served by the admin listener and both listeners shut down together
+func TestAdminListener(t *testing.T) {
+	cfg := *setupTestServer(t).config
+	cfg.Server.AdminPort = 6007
+	cfg.Server.AdminHost = "127.0.0.1"
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("Failed to create database driver: %v", err)
+	}
+	if err := driver.Connect(context.Background()); err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	srv := New(&cfg, driver, registry.NewSchemaRegistry(), "1-test")
+	if srv.adminServer == nil || srv.adminServer.Addr != "127.0.0.1:6007" {
+		t.Fatalf("Expected an admin server on 127.0.0.1:6007, got %v", srv.adminServer)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	adminListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	publicAddr, adminAddr := listener.Addr().String(), adminListener.Addr().String()
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- srv.serve(listener, adminListener, signals) }()
+
+	status := func(method, addr, path string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, "http://"+addr+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s%s failed: %v", method, addr, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		method, path string
+		public       int
+		admin        int
+	}{
+		{http.MethodGet, "/health", http.StatusNotFound, http.StatusOK},
+		{http.MethodGet, "/health/live", http.StatusOK, http.StatusOK},
+		{http.MethodGet, "/admin:consistency", http.StatusNotFound, http.StatusUnauthorized},
+		{http.MethodPost, "/admin:maintenance", http.StatusNotFound, http.StatusUnauthorized},
+		{http.MethodGet, "/admin:backups", http.StatusNotFound, http.StatusUnauthorized},
+		{http.MethodPost, "/admin:loglevel", http.StatusNotFound, http.StatusUnauthorized},
+		{http.MethodOptions, "/admin:loglevel", http.StatusNotFound, http.StatusNoContent},
+		{http.MethodPost, "/doc:refresh", http.StatusNotFound, http.StatusUnauthorized},
+		{http.MethodGet, "/collections:list", http.StatusUnauthorized, http.StatusNotFound},
+		{http.MethodGet, "/products:list", http.StatusUnauthorized, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := status(tt.method, publicAddr, tt.path); got != tt.public {
+			t.Errorf("public %s %s: expected %d, got %d", tt.method, tt.path, tt.public, got)
+		}
+		if got := status(tt.method, adminAddr, tt.path); got != tt.admin {
+			t.Errorf("admin %s %s: expected %d, got %d", tt.method, tt.path, tt.admin, got)
+		}
+	}
+
+	signals <- syscall.SIGTERM
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+	for _, addr := range []string{publicAddr, adminAddr} {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			t.Errorf("Expected %s to be closed after shutdown", addr)
+		}
+	}
+
+	// Without admin_port the administrative routes stay on the single port
+	w := httptest.NewRecorder()
+	single := setupTestServer(t)
+	single.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin:consistency", nil))
+	if w.Code != http.StatusUnauthorized || single.adminServer != nil {
+		t.Errorf("Expected admin:consistency on the single listener, got %d", w.Code)
+	}
+}
+
+// TestQueryCache tests hit/miss headers and invalidation by writes and schema changes
+func TestQueryCache(t *testing.T) {
+	srv := setupTestServer(t)
+	srv.cache = cache.New(10, time.Minute)
+
+	calls := 0
+	read := srv.cachedRead("products", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("ETag", `"1"`)
+		srv.writeJSON(w, http.StatusOK, map[string]any{"calls": calls})
+	})
+	status := http.StatusOK
+	write := srv.invalidate("products", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+	schema := srv.invalidateAll(func(w http.ResponseWriter, r *http.Request) {})
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		read(w, httptest.NewRequest(http.MethodGet, "/products:list"+query, nil))
+		return w
+	}
+	expect := func(w *httptest.ResponseRecorder, cacheStatus string, wantCalls int) {
+		t.Helper()
+		if got := w.Header().Get("X-Moon-Cache"); got != cacheStatus {
+			t.Errorf("Expected X-Moon-Cache %q, got %q", cacheStatus, got)
+		}
+		var body map[string]any
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body["calls"] != float64(wantCalls) {
+			t.Errorf("Expected response from call %d, got %v", wantCalls, body["calls"])
+		}
+	}
+
+	expect(get("?limit=5&sort=name"), "miss", 1)
+	// Equivalent query strings share an entry and keep the handler's headers
+	w := get("?sort=name&limit=5")
+	expect(w, "hit", 1)
+	if w.Header().Get("ETag") != `"1"` || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected cached headers, got %v", w.Header())
+	}
+
+	// A rejected write leaves the cache intact, a successful one invalidates it
+	status = http.StatusBadRequest
+	write(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/products:update", nil))
+	expect(get("?limit=5&sort=name"), "hit", 1)
+	status = http.StatusOK
+	write(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/products:update", nil))
+	expect(get("?limit=5&sort=name"), "miss", 2)
+
+	// Schema changes invalidate every collection
+	schema(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/collections:update", nil))
+	expect(get("?limit=5&sort=name"), "miss", 3)
+	expect(get("?limit=5&sort=name"), "hit", 3)
+
+	stats, ok := srv.CacheStats()
+	if !ok || stats.Hits != 3 || stats.Misses != 3 {
+		t.Errorf("Unexpected cache stats: %+v (enabled %v)", stats, ok)
+	}
+}
+
+// TestQueryCache_ListFormats tests that CSV and NDJSON lists are cached apart
+// from JSON ones with their pagination headers
+func TestQueryCache_ListFormats(t *testing.T) {
+	srv := setupTestServer(t)
+	srv.cache = cache.New(10, time.Minute)
+
+	read := srv.cachedRead("products", func(w http.ResponseWriter, r *http.Request) {
+		format := handlers.NegotiateListFormat(r)
+		w.Header().Set("X-Next-Cursor", "cursor-"+format)
+		w.Header().Set("X-Total", "7")
+		w.Write([]byte(format))
+	})
+	get := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/products:list?limit=5", nil)
+		r.Header.Set("Accept", accept)
+		read(w, r)
+		return w
+	}
+
+	for _, accept := range []string{"application/json", "text/csv", "application/x-ndjson"} {
+		get(accept)
+	}
+	for accept, want := range map[string]string{"": "json", "text/csv": "csv", "application/x-ndjson": "ndjson"} {
+		w := get(accept)
+		if w.Header().Get("X-Moon-Cache") != "hit" || w.Body.String() != want {
+			t.Errorf("Accept %q: expected a cached %s response, got %s %q", accept, want, w.Header().Get("X-Moon-Cache"), w.Body.String())
+		}
+		if w.Header().Get("X-Next-Cursor") != "cursor-"+want || w.Header().Get("X-Total") != "7" {
+			t.Errorf("Accept %q: expected the pagination headers to be cached, got %v", accept, w.Header())
+		}
+	}
+}
+
+// TestQueryCache_IncludeHidden tests that an admin's include_hidden response
+// is not served from the cache to a caller who may not see hidden columns
+func TestQueryCache_IncludeHidden(t *testing.T) {
+	srv := setupTestServer(t)
+	srv.cache = cache.New(10, time.Minute)
+	ctx := context.Background()
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://:memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil || driver.Connect(ctx) != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if _, err := driver.Exec(ctx, "CREATE TABLE notes (pkid INTEGER PRIMARY KEY AUTOINCREMENT, id TEXT, title TEXT, cost INTEGER, created_at TEXT, updated_at TEXT, _rev INTEGER DEFAULT 1)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	driver.Exec(ctx, "INSERT INTO notes (id, title, cost) VALUES ('01J', 'dear', 50)")
+	srv.registry.Set(&registry.Collection{Name: "notes", Columns: []registry.Column{
+		{Name: "title", Type: registry.TypeString},
+		{Name: "cost", Type: registry.TypeInteger, Nullable: true, Hidden: true},
+	}})
+	dataHandler := handlers.NewDataHandler(driver, srv.registry, srv.config)
+	list := srv.cachedRead("notes", func(w http.ResponseWriter, r *http.Request) { dataHandler.List(w, r, "notes") })
+
+	get := func(role string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/notes:list?include_hidden=true", nil)
+		r = r.WithContext(middleware.SetAuthEntity(r.Context(), &middleware.AuthEntity{Type: middleware.EntityTypeUser, Role: role}))
+		w := httptest.NewRecorder()
+		list(w, r)
+		return w
+	}
+	if w := get(string(auth.RoleAdmin)); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"cost":50`) {
+		t.Fatalf("Expected the admin to see cost, got %d %s", w.Code, w.Body.String())
+	}
+	if w := get(string(auth.RoleUser)); w.Code != http.StatusForbidden || strings.Contains(w.Body.String(), "cost") {
+		t.Errorf("Expected 403 for a reader after an admin read the same URL, got %d %s (X-Moon-Cache %q)", w.Code, w.Body.String(), w.Header().Get("X-Moon-Cache"))
+	}
+}
+
+// TestQueryCache_BaseURL tests that responses are cached per base URL, which
+// record links are built from, and that forwarding headers of an untrusted
+// peer do not reach the key
+func TestQueryCache_BaseURL(t *testing.T) {
+	srv := setupTestServer(t)
+	srv.cache = cache.New(10, time.Minute)
+	srv.config.Server.TrustedProxies = []string{"10.0.0.1"}
+	read := srv.cachedRead("products", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(handlers.RequestBaseURL(srv.config.Server, r)))
+	})
+	get := func(host, peer, forwardedHost string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/products:get?id=1", nil)
+		r.Host, r.RemoteAddr = host, peer
+		r.Header.Set("X-Forwarded-Host", forwardedHost)
+		w := httptest.NewRecorder()
+		read(w, r)
+		return w
+	}
+
+	get("moon.internal", "203.0.113.9:4000", "evil.example.net")
+	if w := get("moon.internal", "203.0.113.9:4000", ""); w.Header().Get("X-Moon-Cache") != "hit" || w.Body.String() != "http://moon.internal" {
+		t.Errorf("Expected the untrusted forwarded host to be ignored, got %s %q", w.Header().Get("X-Moon-Cache"), w.Body.String())
+	}
+	if w := get("moon.internal", "10.0.0.1:4000", "api.example.com"); w.Header().Get("X-Moon-Cache") != "miss" || w.Body.String() != "http://api.example.com" {
+		t.Errorf("Expected another base URL to be cached apart, got %s %q", w.Header().Get("X-Moon-Cache"), w.Body.String())
+	}
+}
+
+// TestStatsCache tests that :stats responses survive data writes and are
+// dropped by schema changes
+func TestStatsCache(t *testing.T) {
+	srv := setupTestServer(t)
+	srv.statsCache = cache.New(10, time.Minute)
+
+	calls := 0
+	stats := srv.cachedStats("products", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		srv.writeJSON(w, http.StatusOK, map[string]any{"calls": calls})
+	})
+	write := srv.invalidate("products", func(w http.ResponseWriter, r *http.Request) {})
+	schema := srv.invalidateAll(func(w http.ResponseWriter, r *http.Request) {})
+
+	get := func(wantCache string, wantCalls int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		stats(w, httptest.NewRequest(http.MethodGet, "/products:stats", nil))
+		if got := w.Header().Get("X-Moon-Cache"); got != wantCache {
+			t.Errorf("Expected X-Moon-Cache %q, got %q", wantCache, got)
+		}
+		var body map[string]any
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body["calls"] != float64(wantCalls) {
+			t.Errorf("Expected response from call %d, got %v", wantCalls, body["calls"])
+		}
+	}
+
+	get("miss", 1)
+	get("hit", 1)
+	write(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/products:create", nil))
+	get("hit", 1)
+	schema(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/collections:update", nil))
+	get("miss", 2)
+}
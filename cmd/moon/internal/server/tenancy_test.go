@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/thalib/moon/cmd/moon/internal/auth"
+)
+
+// setupTenancyTestServer enables tenancy on a scope test server and returns
+// admin keys for the acme and globex tenants alongside the operator key
+func setupTenancyTestServer(t *testing.T) (srv *Server, operatorKey, acmeKey, globexKey string) {
+	t.Helper()
+	srv, operatorKey = setupScopeTestServer(t)
+	srv.config.Tenancy.Enabled = true
+	srv.mux = http.NewServeMux()
+	srv.setupRoutes()
+	return srv, operatorKey, createTenantKey(t, srv, "acme"), createTenantKey(t, srv, "globex")
+}
+
+// createTenantKey stores an admin API key bound to a tenant and returns the raw key
+func createTenantKey(t *testing.T, srv *Server, tenant string) string {
+	t.Helper()
+	rawKey, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	apiKey := &auth.APIKey{Name: tenant, KeyHash: keyHash, Role: "admin", CanWrite: true, Tenant: tenant}
+	if err := auth.NewAPIKeyRepository(srv.db).Create(context.Background(), apiKey); err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	return rawKey
+}
+
+func TestTenancy_Isolation(t *testing.T) {
+	srv, operatorKey, acmeKey, globexKey := setupTenancyTestServer(t)
+
+	for _, key := range []string{acmeKey, globexKey} {
+		w := serveWithKey(srv, key, http.MethodPost, "/collections:create",
+			`{"name": "products", "columns": [{"name": "title", "type": "string"}]}`)
+		if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"name":"products"`) {
+			t.Fatalf("expected each tenant to create its own products, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if !srv.registry.Exists("acme__products") || !srv.registry.Exists("globex__products") {
+		t.Fatalf("expected tenant-prefixed tables, got %v", srv.registry.List())
+	}
+
+	if w := serveWithKey(srv, acmeKey, http.MethodPost, "/products:create", `{"data": {"title": "anvil"}}`); w.Code != http.StatusCreated {
+		t.Fatalf("acme create failed: %d %s", w.Code, w.Body.String())
+	}
+
+	count := func(key string) float64 {
+		t.Helper()
+		w := serveWithKey(srv, key, http.MethodGet, "/products:count", "")
+		var resp map[string]any
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		value, _ := resp["value"].(float64)
+		return value
+	}
+	if got := count(acmeKey); got != 1 {
+		t.Errorf("expected acme to count its record, got %v", got)
+	}
+	if got := count(globexKey); got != 0 {
+		t.Errorf("expected globex to see none of acme's records, got %v", got)
+	}
+	if got := count(operatorKey); got != 0 {
+		t.Errorf("expected the operator's products to stay separate, got %v", got)
+	}
+
+	w := serveWithKey(srv, acmeKey, http.MethodGet, "/products:schema", "")
+	if !strings.Contains(w.Body.String(), `"collection":"products"`) {
+		t.Errorf("expected the schema to use the logical name, got %s", w.Body.String())
+	}
+
+	w = serveWithKey(srv, globexKey, http.MethodGet, "/collections:list", "")
+	var list struct {
+		Collections []struct {
+			Name    string `json:"name"`
+			Records int    `json:"records"`
+		} `json:"collections"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Collections) != 1 || list.Collections[0].Name != "products" || list.Collections[0].Records != 0 {
+		t.Errorf("expected globex to list only its own products, got %s", w.Body.String())
+	}
+
+	// Physical names are unreachable, whoever asks
+	for _, key := range []string{globexKey, operatorKey} {
+		if w := serveWithKey(srv, key, http.MethodGet, "/acme__products:list", ""); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a tenant table, got %d", w.Code)
+		}
+		if w := serveWithKey(srv, key, http.MethodGet, "/collections:get?name=acme__products", ""); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a tenant table, got %d", w.Code)
+		}
+	}
+	if w := serveWithKey(srv, globexKey, http.MethodGet, "/orders:list", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected the operator's orders to be invisible to globex, got %d", w.Code)
+	}
+	if w := serveWithKey(srv, operatorKey, http.MethodPost, "/collections:create",
+		`{"name": "acme__orders", "columns": [{"name": "title", "type": "string"}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected the tenant separator to be rejected in names, got %d", w.Code)
+	}
+
+	if w := serveWithKey(srv, acmeKey, http.MethodPost, "/collections:rename", `{"name": "products", "new_name": "goods"}`); w.Code != http.StatusOK {
+		t.Fatalf("acme rename failed: %d %s", w.Code, w.Body.String())
+	}
+	if !srv.registry.Exists("acme__goods") || !srv.registry.Exists("globex__products") {
+		t.Errorf("expected the rename to stay inside acme, got %v", srv.registry.List())
+	}
+
+	// Instance-wide administration belongs to the operator
+	if w := serveWithKey(srv, acmeKey, http.MethodGet, "/apikeys:list", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a tenant admin on apikeys:list, got %d", w.Code)
+	}
+	if w := serveWithKey(srv, operatorKey, http.MethodGet, "/apikeys:list", ""); w.Code == http.StatusNotFound {
+		t.Errorf("expected apikeys:list to exist for the operator, got %d", w.Code)
+	}
+}
+
+func TestTenancy_Documentation(t *testing.T) {
+	srv, _, acmeKey, globexKey := setupTenancyTestServer(t)
+	for key, name := range map[string]string{acmeKey: "anvils", globexKey: "widgets"} {
+		body := `{"name": "` + name + `", "columns": [{"name": "title", "type": "string"}]}`
+		if w := serveWithKey(srv, key, http.MethodPost, "/collections:create", body); w.Code != http.StatusCreated {
+			t.Fatalf("create %s failed: %d %s", name, w.Code, w.Body.String())
+		}
+	}
+
+	// Each tenant sees its own collections by their logical names, anonymous
+	// callers the operator's orders and products
+	tests := []struct {
+		name   string
+		key    string
+		want   string
+		hidden []string
+	}{
+		{"acme", acmeKey, "anvils", []string{"widgets", "orders:list", "acme__"}},
+		{"globex", globexKey, "widgets", []string{"anvils", "orders:list", "globex__"}},
+		{"anonymous", "", "orders", []string{"anvils", "widgets"}},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/doc/openapi.json", "/doc/llms.md"} {
+			w := serveWithKey(srv, tt.key, http.MethodGet, path, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s %s: expected 200, got %d %s", tt.name, path, w.Code, w.Body.String())
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, "/"+tt.want+":list") {
+				t.Errorf("%s %s: expected %s to be documented", tt.name, path, tt.want)
+			}
+			for _, hidden := range tt.hidden {
+				if strings.Contains(body, hidden) {
+					t.Errorf("%s %s: expected %q to stay out of the documentation", tt.name, path, hidden)
+				}
+			}
+			if private := strings.HasPrefix(w.Header().Get("Cache-Control"), "private"); private != (tt.key != "") {
+				t.Errorf("%s %s: unexpected Cache-Control %q", tt.name, path, w.Header().Get("Cache-Control"))
+			}
+		}
+	}
+
+	if w := serveWithKey(srv, "moon_live_unknown", http.MethodGet, "/doc/openapi.json", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an invalid key, got %d", w.Code)
+	}
+}
+
+func TestTenancy_Disabled(t *testing.T) {
+	srv, _ := setupScopeTestServer(t)
+	key := createTenantKey(t, srv, "acme")
+
+	// A stored tenant is ignored until tenancy is enabled
+	if w := serveWithKey(srv, key, http.MethodGet, "/products:list", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the shared namespace without tenancy, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serveWithKey(srv, key, http.MethodGet, "/apikeys:list", ""); w.Code == http.StatusNotFound {
+		t.Errorf("expected apikeys:list to exist without tenancy, got %d", w.Code)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -17,9 +18,72 @@ var (
 	ErrInvalidULID = errors.New("invalid ULID format")
 )
 
-// Generate creates a new ULID using the current timestamp and secure random data
+// generator hands out strictly increasing ULIDs for the whole process: ULIDs
+// of the same millisecond increment the random part of the previous one, and
+// a clock that steps back reuses the last millisecond instead
+var generator = struct {
+	sync.Mutex
+	entropy *ulid.MonotonicEntropy
+	clock   func() time.Time
+	last    uint64 // millisecond timestamp of the last ULID
+}{
+	entropy: ulid.Monotonic(rand.Reader, 0),
+	clock:   time.Now,
+}
+
+// SetClock replaces the clock of Generate and GenerateBatch and returns a
+// function restoring the previous one. It is meant for tests.
+func SetClock(clock func() time.Time) (restore func()) {
+	generator.Lock()
+	defer generator.Unlock()
+	previous := generator.clock
+	generator.clock = clock
+	return func() {
+		generator.Lock()
+		defer generator.Unlock()
+		generator.clock = previous
+	}
+}
+
+// Generate creates a new ULID using the current timestamp and secure random
+// data. ULIDs generated by one process are strictly increasing.
 func Generate() string {
-	return ulid.MustNew(ulid.Timestamp(time.Now()), rand.Reader).String()
+	generator.Lock()
+	defer generator.Unlock()
+	return next().String()
+}
+
+// GenerateBatch creates n strictly increasing ULIDs in one step, so a batch of
+// records sorts by id in the order it was given
+func GenerateBatch(n int) []string {
+	ids := make([]string, n)
+	generator.Lock()
+	defer generator.Unlock()
+	for i := range ids {
+		ids[i] = next().String()
+	}
+	return ids
+}
+
+// next returns the ULID following the last one; the generator must be locked
+func next() ulid.ULID {
+	ms := ulid.Timestamp(generator.clock())
+	if ms < generator.last {
+		ms = generator.last
+	}
+	for {
+		id, err := ulid.New(ms, generator.entropy)
+		if err == nil {
+			generator.last = ms
+			return id
+		}
+		// The random part ran out within the millisecond; continue in the next one
+		if errors.Is(err, ulid.ErrMonotonicOverflow) {
+			ms++
+			continue
+		}
+		panic(err)
+	}
 }
 
 // GenerateWithTime creates a new ULID using the specified timestamp and secure random data
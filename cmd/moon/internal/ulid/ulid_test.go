@@ -2,6 +2,7 @@ package ulid
 
 import (
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -39,6 +40,83 @@ func TestGenerate(t *testing.T) {
 	})
 }
 
+func TestGenerate_Monotonic(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	restore := SetClock(func() time.Time { return now })
+	defer restore()
+
+	t.Run("Same millisecond", func(t *testing.T) {
+		prev := Generate()
+		for i := 0; i < 1000; i++ {
+			id := Generate()
+			if id <= prev {
+				t.Fatalf("expected %s > %s within one millisecond", id, prev)
+			}
+			prev = id
+		}
+	})
+
+	t.Run("Clock going backwards", func(t *testing.T) {
+		before := Generate()
+		now = now.Add(-time.Second)
+		after := Generate()
+		if after <= before {
+			t.Errorf("expected %s > %s after the clock stepped back", after, before)
+		}
+		ts, _ := Time(after)
+		if ts.Before(now.Add(time.Second)) {
+			t.Errorf("expected the last timestamp to be kept, got %v", ts)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		ids := GenerateBatch(100)
+		if len(ids) != 100 {
+			t.Fatalf("expected 100 ULIDs, got %d", len(ids))
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] <= ids[i-1] {
+				t.Fatalf("expected batch ULIDs in ascending order, got %s after %s", ids[i], ids[i-1])
+			}
+		}
+	})
+}
+
+func TestGenerate_Concurrent(t *testing.T) {
+	const goroutines, perGoroutine = 50, 200
+
+	results := make([][]string, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ids := make([]string, perGoroutine)
+			for i := range ids {
+				ids[i] = Generate()
+			}
+			results[g] = ids
+		}(g)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, goroutines*perGoroutine)
+	for g, ids := range results {
+		for i, id := range ids {
+			if seen[id] {
+				t.Fatalf("duplicate ULID %s", id)
+			}
+			seen[id] = true
+			if i > 0 && id < ids[i-1] {
+				t.Errorf("goroutine %d: %s generated after %s", g, id, ids[i-1])
+			}
+		}
+	}
+	if len(seen) != goroutines*perGoroutine {
+		t.Errorf("expected %d ULIDs, got %d", goroutines*perGoroutine, len(seen))
+	}
+}
+
 func TestGenerateWithTime(t *testing.T) {
 	t.Run("Generate with specific time", func(t *testing.T) {
 		testTime := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
@@ -267,6 +345,12 @@ func BenchmarkGenerate(b *testing.B) {
 	}
 }
 
+func BenchmarkGenerateBatch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		GenerateBatch(100)
+	}
+}
+
 func BenchmarkValidate(b *testing.B) {
 	id := Generate()
 	b.ResetTimer()
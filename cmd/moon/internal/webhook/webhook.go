@@ -0,0 +1,289 @@
+// Package webhook delivers data mutation events to configured HTTP endpoints.
+// Events are queued in memory and posted by background workers, so API
+// latency does not depend on the receivers. Each delivery is signed with
+// HMAC-SHA256 and retried with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/logging"
+	"github.com/thalib/moon/cmd/moon/internal/ulid"
+)
+
+// logModule is the module of the webhook logs, configured by logging.levels.webhook
+const logModule = "webhook"
+
+// Mutation actions reported in events
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDestroy = "destroy"
+)
+
+// Event describes a successful data mutation on a collection
+type Event struct {
+	ID         string           `json:"id"`    // ULID, also sent as X-Moon-Delivery
+	Event      string           `json:"event"` // {collection}:{action}
+	Collection string           `json:"collection"`
+	Action     string           `json:"action"`
+	IDs        []string         `json:"ids"`
+	Data       []map[string]any `json:"data,omitempty"` // new record data; omitted for destroy
+	Timestamp  string           `json:"timestamp"`
+}
+
+// NewEvent builds an event for records changed by one request
+func NewEvent(collection, action string, ids []string, data []map[string]any) Event {
+	return Event{
+		ID:         ulid.Generate(),
+		Event:      collection + ":" + action,
+		Collection: collection,
+		Action:     action,
+		IDs:        ids,
+		Data:       data,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Sign returns the X-Moon-Signature value for a payload: "sha256=" followed by
+// the hex HMAC-SHA256 of the body keyed with the endpoint secret.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Matches reports whether an event filter selects the collection and action.
+// Filters have the form {collection}:{action} where either part may be "*";
+// a bare "*" selects every event.
+func Matches(filter, collection, action string) bool {
+	if filter == "*" {
+		return true
+	}
+	name, act, ok := strings.Cut(filter, ":")
+	if !ok {
+		return false
+	}
+	return (name == "*" || name == collection) && (act == "*" || act == action)
+}
+
+// endpoint is a configured receiver
+type endpoint struct {
+	url    string
+	secret string
+	events []string
+}
+
+// wants reports whether the endpoint subscribes to the event; an endpoint
+// without filters receives every event.
+func (e endpoint) wants(event Event) bool {
+	if len(e.events) == 0 {
+		return true
+	}
+	for _, filter := range e.events {
+		if Matches(filter, event.Collection, event.Action) {
+			return true
+		}
+	}
+	return false
+}
+
+// delivery is one event bound for one endpoint
+type delivery struct {
+	endpoint endpoint
+	event    Event
+	payload  []byte
+}
+
+// Dispatcher queues events and posts them to subscribed endpoints
+type Dispatcher struct {
+	endpoints   []endpoint
+	client      *http.Client
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	mu     sync.RWMutex // guards closed and sends on queue
+	closed bool
+	queue  chan delivery
+
+	ctx    context.Context // cancelled to abandon deliveries on forced shutdown
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a dispatcher for the configured endpoints and starts its workers.
+// It returns nil when no endpoints are configured; a nil dispatcher ignores events.
+func New(cfg config.WebhooksConfig) *Dispatcher {
+	if len(cfg.Endpoints) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		client:      &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		maxAttempts: cfg.MaxAttempts,
+		baseBackoff: constants.WebhookRetryBackoff,
+		maxBackoff:  constants.WebhookMaxRetryBackoff,
+		queue:       make(chan delivery, cfg.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	for _, ep := range cfg.Endpoints {
+		d.endpoints = append(d.endpoints, endpoint{url: ep.URL, secret: ep.Secret, events: ep.Events})
+	}
+
+	for i := 0; i < constants.WebhookWorkers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Publish queues the event for every endpoint subscribed to it without blocking.
+// When the queue is full or the dispatcher is closed the delivery is dropped and logged.
+func (d *Dispatcher) Publish(event Event) {
+	if d == nil {
+		return
+	}
+
+	var payload []byte
+	for _, ep := range d.endpoints {
+		if !ep.wants(event) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				logging.Module(logModule).Errorf("Webhook event %s dropped: failed to encode: %v", event.ID, err)
+				return
+			}
+		}
+		d.enqueue(delivery{endpoint: ep, event: event, payload: payload})
+	}
+}
+
+// enqueue adds a delivery to the queue unless it is full or closed
+func (d *Dispatcher) enqueue(job delivery) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		logging.Module(logModule).Warnf("Webhook event %s (%s) to %s dropped: dispatcher is shut down", job.event.ID, job.event.Event, job.endpoint.url)
+		return
+	}
+	select {
+	case d.queue <- job:
+	default:
+		logging.Module(logModule).Warnf("Webhook event %s (%s) to %s dropped: queue is full", job.event.ID, job.event.Event, job.endpoint.url)
+	}
+}
+
+// Close stops accepting events and waits for queued deliveries to finish.
+// When ctx expires first, in-flight requests and retry waits are abandoned.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	close(d.queue)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return fmt.Errorf("webhook deliveries abandoned: %w", ctx.Err())
+	}
+}
+
+// work delivers queued events until the queue is closed and drained
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for job := range d.queue {
+		d.deliver(job)
+	}
+}
+
+// deliver posts a delivery, retrying network errors, 429 and 5xx responses
+// with exponential backoff up to maxAttempts attempts.
+func (d *Dispatcher) deliver(job delivery) {
+	backoff := d.baseBackoff
+	for attempt := 1; ; attempt++ {
+		status, err := d.post(job)
+		if err == nil && status < 300 {
+			logging.Module(logModule).Debugf("Webhook event %s (%s) delivered to %s on attempt %d", job.event.ID, job.event.Event, job.endpoint.url, attempt)
+			return
+		}
+
+		reason := fmt.Sprintf("status %d", status)
+		if err != nil {
+			reason = err.Error()
+		}
+		if err == nil && status < 500 && status != http.StatusTooManyRequests {
+			logging.Module(logModule).Warnf("Webhook event %s (%s) to %s rejected with %s", job.event.ID, job.event.Event, job.endpoint.url, reason)
+			return
+		}
+		if attempt >= d.maxAttempts {
+			logging.Module(logModule).Errorf("Webhook event %s (%s) to %s failed after %d attempts: %s", job.event.ID, job.event.Event, job.endpoint.url, attempt, reason)
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+			logging.Module(logModule).Warnf("Webhook event %s (%s) to %s abandoned on shutdown after %d attempts", job.event.ID, job.event.Event, job.endpoint.url, attempt)
+			return
+		}
+		backoff = min(backoff*2, d.maxBackoff)
+	}
+}
+
+// post sends one signed delivery attempt and returns the response status
+func (d *Dispatcher) post(job delivery) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, job.endpoint.url, bytes.NewReader(job.payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(constants.HeaderContentType, constants.MIMEApplicationJSON)
+	req.Header.Set(constants.HeaderWebhookEvent, job.event.Event)
+	req.Header.Set(constants.HeaderWebhookDelivery, job.event.ID)
+	req.Header.Set(constants.HeaderWebhookSignature, Sign(job.endpoint.secret, job.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
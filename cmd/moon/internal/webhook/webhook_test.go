@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+)
+
+// newTestDispatcher creates a dispatcher for one endpoint with millisecond backoff
+func newTestDispatcher(url string, events []string, maxAttempts, queueSize int) *Dispatcher {
+	d := New(config.WebhooksConfig{
+		QueueSize:   queueSize,
+		MaxAttempts: maxAttempts,
+		Timeout:     5,
+		Endpoints:   []config.WebhookEndpointConfig{{URL: url, Secret: "s3cret", Events: events}},
+	})
+	d.baseBackoff = time.Millisecond
+	d.maxBackoff = 5 * time.Millisecond
+	return d
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"*", true},
+		{"*:*", true},
+		{"products:create", true},
+		{"products:*", true},
+		{"*:create", true},
+		{"products:update", false},
+		{"orders:create", false},
+		{"orders:*", false},
+		{"*:destroy", false},
+		{"products", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.filter, "products", ActionCreate); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestNew_NoEndpoints(t *testing.T) {
+	d := New(config.WebhooksConfig{QueueSize: 10, MaxAttempts: 3})
+	if d != nil {
+		t.Fatal("expected nil dispatcher without endpoints")
+	}
+	// A nil dispatcher ignores events and closes cleanly
+	d.Publish(NewEvent("products", ActionCreate, []string{"id"}, nil))
+	if err := d.Close(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDispatcher_SignsAndRetriesAfterServerError(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	var bodies [][]byte
+	var signatures []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get("X-Moon-Signature"))
+		if r.Header.Get("X-Moon-Event") != "products:update" {
+			t.Errorf("unexpected event header %q", r.Header.Get("X-Moon-Event"))
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(srv.URL, []string{"products:*"}, 5, 10)
+	event := NewEvent("products", ActionUpdate, []string{"01ARYZ6S41TSV4RRFFQ69G5FAV"}, []map[string]any{{"id": "01ARYZ6S41TSV4RRFFQ69G5FAV", "price": 12}})
+	d.Publish(event)
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts (two 500s then success), got %d", attempts)
+	}
+	for i, body := range bodies {
+		if signatures[i] != Sign("s3cret", body) {
+			t.Errorf("attempt %d: signature %q does not match body", i+1, signatures[i])
+		}
+	}
+	var received Event
+	if err := json.Unmarshal(bodies[0], &received); err != nil {
+		t.Fatalf("invalid event body: %v", err)
+	}
+	if received.ID != event.ID || received.Collection != "products" || received.Action != ActionUpdate || len(received.IDs) != 1 || len(received.Data) != 1 {
+		t.Errorf("unexpected event body: %+v", received)
+	}
+}
+
+func TestDispatcher_StopsAfterMaxAttempts(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(srv.URL, nil, 3, 10)
+	d.Publish(NewEvent("products", ActionDestroy, []string{"a"}, nil))
+	d.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestDispatcher_DoesNotRetryClientErrors(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(srv.URL, nil, 5, 10)
+	d.Publish(NewEvent("products", ActionCreate, []string{"a"}, nil))
+	d.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 1 {
+		t.Errorf("expected a single attempt for 400, got %d", attempts)
+	}
+}
+
+func TestDispatcher_FiltersEvents(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		events = append(events, r.Header.Get("X-Moon-Event"))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(srv.URL, []string{"*:destroy", "orders:create"}, 1, 10)
+	d.Publish(NewEvent("products", ActionCreate, []string{"a"}, nil))
+	d.Publish(NewEvent("products", ActionDestroy, []string{"a"}, nil))
+	d.Publish(NewEvent("orders", ActionCreate, []string{"b"}, nil))
+	d.Publish(NewEvent("orders", ActionUpdate, []string{"b"}, nil))
+	d.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 deliveries, got %v", events)
+	}
+}
+
+func TestDispatcher_DropsWhenQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	delivered := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		mu.Lock()
+		delivered++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(srv.URL, nil, 1, 1)
+	// Publishing never blocks even though every worker is stuck on the receiver
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		d.Publish(NewEvent("products", ActionCreate, []string{"a"}, nil))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("publishing blocked for %s", elapsed)
+	}
+	close(release)
+	d.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if delivered == 0 || delivered >= 50 {
+		t.Errorf("expected overflowing events to be dropped, %d of 50 delivered", delivered)
+	}
+}
+
+func TestDispatcher_CloseAbandonsAtDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(srv.URL, nil, 100, 10)
+	d.baseBackoff = time.Hour
+	d.maxBackoff = time.Hour
+	d.Publish(NewEvent("products", ActionCreate, []string{"a"}, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := d.Close(ctx); err == nil {
+		t.Error("expected an error when deliveries are abandoned")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("close waited %s past its deadline", elapsed)
+	}
+
+	// Events published after close are dropped
+	d.Publish(NewEvent("products", ActionCreate, []string{"b"}, nil))
+}
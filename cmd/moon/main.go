@@ -2,19 +2,28 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/thalib/moon/cmd/moon/internal/audit"
 	"github.com/thalib/moon/cmd/moon/internal/auth"
+	"github.com/thalib/moon/cmd/moon/internal/checks"
 	"github.com/thalib/moon/cmd/moon/internal/config"
 	"github.com/thalib/moon/cmd/moon/internal/consistency"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
 	"github.com/thalib/moon/cmd/moon/internal/daemon"
 	"github.com/thalib/moon/cmd/moon/internal/database"
+	"github.com/thalib/moon/cmd/moon/internal/handlers"
 	"github.com/thalib/moon/cmd/moon/internal/logging"
 	"github.com/thalib/moon/cmd/moon/internal/preflight"
 	"github.com/thalib/moon/cmd/moon/internal/registry"
+	"github.com/thalib/moon/cmd/moon/internal/schemastore"
 	"github.com/thalib/moon/cmd/moon/internal/server"
 )
 
@@ -23,54 +32,162 @@ func main() {
 	configPath := flag.String("config", "", "path to configuration file (default: /etc/moon.conf)")
 	daemonMode := flag.Bool("daemon", false, "run in daemon mode (background)")
 	daemonShort := flag.Bool("d", false, "run in daemon mode (background) - shorthand")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets masked and exit")
+	selfTest := flag.Bool("check", false, "run the startup self-test, print a report and exit (non-zero on failure)")
+	jsonReport := flag.Bool("json", false, "print the -check report as JSON")
+	settings := make(map[string]string)
+	flag.Func("set", "override a setting, e.g. -set server.port=8080 (repeatable; wins over the file and MOON_* variables)", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value")
+		}
+		settings[key] = val
+		return nil
+	})
 	flag.Parse()
 
 	// Check if daemon mode is enabled (either flag)
 	isDaemon := *daemonMode || *daemonShort
 
-	fmt.Println("Moon - Dynamic Headless Engine")
-
 	// Load configuration
-	cfg, err := config.Load(*configPath)
+	cfg, err := config.LoadWith(*configPath, settings)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
+	if *printConfig {
+		out, _ := json.MarshalIndent(cfg.Masked(), "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	// The self-test neither daemonizes, locks the database, writes
+	// authentication data nor serves traffic
+	if *selfTest {
+		report := checks.SelfTest(context.Background(), cfg, databaseConfig(cfg))
+		if *jsonReport {
+			report.WriteJSON(os.Stdout)
+		} else {
+			report.WriteText(os.Stdout)
+		}
+		os.Exit(report.ExitCode())
+	}
+
+	fmt.Println("Moon - Dynamic Headless Engine")
+
 	// Run preflight checks before any other initialization
 	fmt.Println("Running preflight checks...")
-	if err := runPreflightChecks(cfg, isDaemon); err != nil {
+	if err := runPreflightChecks(cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Preflight checks failed: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Handle daemon mode
+	daemonCfg := daemon.DefaultConfig()
 	if isDaemon {
 		fmt.Println("Starting in daemon mode...")
 
 		// Daemonize the process
-		daemonCfg := daemon.DefaultConfig()
 		if err := daemon.Daemonize(daemonCfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to daemonize: %v\n", err)
 			os.Exit(1)
 		}
+	}
+
+	// Initialize database driver
+	driver, err := database.NewDriver(databaseConfig(cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create database driver: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Refuse to share the database with another instance, before anything
+	// destructive such as truncating the log file or repairing the schema
+	instanceLock, err := acquireInstanceLock(ctx, cfg, driver)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to acquire instance lock: %v\n", err)
+		os.Exit(1)
+	}
+
+	srv, pidFile, err := start(ctx, cfg, driver, instanceLock, isDaemon, daemonCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Startup failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The server closes the database on shutdown; the PID file is removed last
+	if isDaemon {
+		srv.OnShutdown(func() {
+			if err := daemon.RemovePIDFile(pidFile); err != nil {
+				logging.Errorf("%v", err)
+				return
+			}
+			logging.Infof("Removed PID file %s", pidFile)
+		})
+	}
+
+	fmt.Println("Starting HTTP server...")
+	if err := srv.Run(); err != nil {
+		if isDaemon {
+			logging.Errorf("Server error: %v", err)
+			daemon.RemovePIDFile(pidFile)
+		}
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if isDaemon {
+		logging.Info("Server stopped gracefully")
+	}
+	fmt.Println("Server stopped gracefully")
+}
+
+// start prepares the server once the instance lock is held: it sets up
+// logging and the PID file in daemon mode, loads and checks the schemas,
+// bootstraps authentication and creates the server. It returns the server
+// and the PID file written, if any. On failure the lock is released, as a
+// lock row left behind would refuse restarts from another host until it
+// goes stale.
+func start(ctx context.Context, cfg *config.AppConfig, driver database.Driver, instanceLock *daemon.InstanceLock, isDaemon bool, daemonCfg daemon.Config) (_ *server.Server, _ string, err error) {
+	defer func() {
+		if err == nil {
+			return
+		}
+		if releaseErr := instanceLock.Release(ctx); releaseErr != nil {
+			err = errors.Join(err, releaseErr)
+		}
+	}()
+
+	var pidFile string
+	if isDaemon {
+		// Truncate the log file to start fresh
+		logFile := filepath.Join(cfg.Logging.Path, "main.log")
+		fmt.Printf("Truncating log file: %s\n", logFile)
+		if err := preflight.CreateOrTruncateFile(logFile); err != nil {
+			return nil, "", fmt.Errorf("failed to truncate log file: %w", err)
+		}
 
 		// Write PID file (after daemonization, in child process)
 		if err := daemon.WritePIDFile(daemonCfg.PIDFile); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to write PID file: %v\n", err)
-			os.Exit(1)
+			return nil, "", fmt.Errorf("failed to write PID file: %w", err)
 		}
 
-		// Setup cleanup on exit
-		defer daemon.RemovePIDFile(daemonCfg.PIDFile)
+		pidFile = daemonCfg.PIDFile
 
 		// Initialize file-based logging for daemon mode
-		logFile := filepath.Join(cfg.Logging.Path, "main.log")
 		logging.Init(logging.LoggerConfig{
-			Level:       logging.LevelInfo,
-			Format:      "simple",
-			FilePath:    logFile,
-			ServiceName: "moon",
+			Level:        logging.Level(cfg.Logging.Level),
+			ModuleLevels: moduleLogLevels(cfg.Logging.Levels),
+			Format:       "simple",
+			FilePath:     logFile,
+			ServiceName:  "moon",
 		})
 
 		logging.Info("Moon daemon started")
@@ -80,16 +197,21 @@ func main() {
 		// Console mode - log to stdout AND file (dual output)
 		logFile := filepath.Join(cfg.Logging.Path, "main.log")
 		logging.Init(logging.LoggerConfig{
-			Level:       logging.LevelInfo,
-			Format:      "console",
-			FilePath:    logFile,
-			DualOutput:  true,
-			ServiceName: "moon",
+			Level:        logging.Level(cfg.Logging.Level),
+			ModuleLevels: moduleLogLevels(cfg.Logging.Levels),
+			Format:       "console",
+			FilePath:     logFile,
+			DualOutput:   true,
+			ServiceName:  "moon",
 		})
 	}
 
 	// Log configuration summary
 	logConfigSummary(cfg)
+	if instanceLock.Broken != nil {
+		logging.Warnf("Broke the stale instance lock of PID %d on host %s, started %s",
+			instanceLock.Broken.PID, instanceLock.Broken.Host, instanceLock.Broken.StartedAt.Format(time.RFC3339))
+	}
 
 	fmt.Printf("Server will start on %s:%d\n", cfg.Server.Host, cfg.Server.Port)
 	fmt.Printf("Database: %s (%s)\n", cfg.Database.Connection, cfg.Database.Database)
@@ -97,59 +219,79 @@ func main() {
 		fmt.Printf("Log file: %s/main.log\n", cfg.Logging.Path)
 	}
 
-	// Initialize database driver
-	dbConfig := database.Config{
-		ConnectionString: buildConnectionString(cfg.Database),
-	}
+	fmt.Printf("Connected to %s database\n", driver.Dialect())
 
-	driver, err := database.NewDriver(dbConfig)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create database driver: %v\n", err)
-		os.Exit(1)
+	// Initialize schema registry from the persisted schemas
+	reg := registry.NewSchemaRegistry()
+	fmt.Println("Loading collection schemas...")
+	if err := loadSchemas(ctx, driver, reg, &cfg.Recovery, !cfg.Discovery.Enabled); err != nil {
+		return nil, "", fmt.Errorf("failed to load collection schemas: %w", err)
 	}
 
-	ctx := context.Background()
-	if err := driver.Connect(ctx); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
-		os.Exit(1)
+	// Register pre-existing tables Moon did not create, if enabled
+	var undiscovered []string
+	if cfg.Discovery.Enabled {
+		fmt.Println("Discovering existing tables...")
+		undiscovered, err = discoverTables(ctx, driver, reg, cfg)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to discover existing tables: %w", err)
+		}
 	}
-	defer driver.Close()
-
-	fmt.Printf("Connected to %s database\n", driver.Dialect())
-
-	// Initialize schema registry
-	reg := registry.NewSchemaRegistry()
 
 	// Run consistency check and repair if needed
 	fmt.Println("Running consistency check...")
-	if err := runConsistencyCheck(ctx, driver, reg, &cfg.Recovery); err != nil {
-		fmt.Fprintf(os.Stderr, "Consistency check failed: %v\n", err)
-		os.Exit(1)
+	if err := runConsistencyCheck(ctx, driver, reg, &cfg.Recovery, undiscovered); err != nil {
+		return nil, "", fmt.Errorf("consistency check failed: %w", err)
 	}
 
 	// Bootstrap authentication (create admin user on first startup)
 	fmt.Println("Bootstrapping authentication...")
 	if err := bootstrapAuth(ctx, driver, cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to bootstrap authentication: %v\n", err)
-		os.Exit(1)
+		return nil, "", fmt.Errorf("failed to bootstrap authentication: %w", err)
 	}
 
-	// Create and start HTTP server
-	srv := server.New(cfg, driver, reg, config.Version())
-
-	fmt.Println("Starting HTTP server...")
-	if err := srv.Run(); err != nil {
-		if isDaemon {
-			logging.Errorf("Server error: %v", err)
+	// Sample collection for trying the API, created on the first startup only
+	if cfg.Bootstrap.SampleCollection {
+		if err := seedSampleCollection(ctx, driver, reg); err != nil {
+			return nil, "", fmt.Errorf("failed to create sample collection: %w", err)
 		}
-		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
-		os.Exit(1)
 	}
 
-	if isDaemon {
-		logging.Info("Server stopped gracefully")
+	// Audit log of mutating requests; old entries are pruned on startup
+	auditLog, err := startAudit(ctx, driver, cfg)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to start audit log: %w", err)
+	}
+
+	// Create the HTTP server
+	srv := server.New(cfg, driver, reg, config.Version())
+	srv.SetDaemon(isDaemon)
+	srv.SetInstanceLock(instanceLock)
+	if auditLog != nil {
+		srv.SetAudit(auditLog)
+	}
+	if err := srv.StartChanges(ctx); err != nil {
+		return nil, "", fmt.Errorf("failed to start change feed: %w", err)
+	}
+	srv.StartSweeper()
+	if err := srv.InitIdempotency(ctx); err != nil {
+		return nil, "", fmt.Errorf("failed to initialize idempotency keys: %w", err)
+	}
+	if err := srv.InitJobs(ctx); err != nil {
+		return nil, "", fmt.Errorf("failed to initialize job journal: %w", err)
+	}
+	return srv, pidFile, nil
+}
+
+// databaseConfig returns the driver configuration of the configured database
+func databaseConfig(cfg *config.AppConfig) database.Config {
+	return database.Config{
+		ConnectionString: buildConnectionString(cfg.Database),
+		MaxOpenConns:     cfg.Database.Pool.MaxOpen,
+		MaxIdleConns:     cfg.Database.Pool.MaxIdle,
+		ConnMaxLifetime:  time.Duration(cfg.Database.Pool.ConnMaxLifetime) * time.Second,
+		ConnMaxIdleTime:  time.Duration(cfg.Database.Pool.ConnMaxIdleTime) * time.Second,
 	}
-	fmt.Println("Server stopped gracefully")
 }
 
 // buildConnectionString creates a database connection string from DatabaseConfig
@@ -173,32 +315,21 @@ func buildConnectionString(db config.DatabaseConfig) string {
 	}
 }
 
-// runPreflightChecks validates and creates required files and directories
-func runPreflightChecks(cfg *config.AppConfig, isDaemon bool) error {
-	var checks []preflight.FileCheck
-
-	// Check logging directory
-	checks = append(checks, preflight.FileCheck{
-		Path:      cfg.Logging.Path,
-		IsDir:     true,
-		Required:  true,
-		FailFatal: true,
-	})
-
-	// For SQLite, check database file parent directory and create if needed
-	// Database path is already normalized to absolute in config.validate()
-	if cfg.Database.Connection == config.Defaults.Database.Connection {
-		dbDir := filepath.Dir(cfg.Database.Database)
-		checks = append(checks, preflight.FileCheck{
-			Path:      dbDir,
-			IsDir:     true,
-			Required:  true,
-			FailFatal: true,
-		})
+// moduleLogLevels converts the validated logging.levels setting
+func moduleLogLevels(levels map[string]string) map[string]logging.Level {
+	modules := make(map[string]logging.Level, len(levels))
+	for module, level := range levels {
+		modules[module] = logging.Level(level)
 	}
+	return modules
+}
 
-	// Run validation
-	results, err := preflight.ValidateAndCreate(checks)
+// runPreflightChecks validates and creates required files and directories.
+// Nothing is truncated here: the log file of a daemon is truncated once the
+// instance lock is held.
+func runPreflightChecks(cfg *config.AppConfig) error {
+	// The log directory and, for SQLite, the directory of the database file
+	results, err := preflight.ValidateAndCreate(checks.PreflightFiles(cfg))
 	if err != nil {
 		return err
 	}
@@ -215,18 +346,29 @@ func runPreflightChecks(cfg *config.AppConfig, isDaemon bool) error {
 		}
 	}
 
-	// If daemon mode, truncate the log file to start fresh
-	if isDaemon {
-		logFile := filepath.Join(cfg.Logging.Path, "main.log")
-		fmt.Printf("Truncating log file: %s\n", logFile)
-		if err := preflight.CreateOrTruncateFile(logFile); err != nil {
-			return fmt.Errorf("failed to truncate log file: %w", err)
-		}
-	}
-
 	return nil
 }
 
+// acquireInstanceLock takes the lock keeping other Moon processes off the
+// database: a lock file next to a SQLite database file, a moon_lock row on
+// PostgreSQL and MySQL. The lock is released on graceful shutdown.
+func acquireInstanceLock(ctx context.Context, cfg *config.AppConfig, driver database.Driver) (*daemon.InstanceLock, error) {
+	var lock *daemon.InstanceLock
+	var err error
+	if driver.Dialect() == database.DialectSQLite {
+		lock, err = daemon.LockFile(cfg.Database.Database + ".lock")
+	} else {
+		lock, err = daemon.LockDatabase(ctx, driver, constants.InstanceLockStaleAfter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lock.Broken != nil {
+		fmt.Printf("⚠ Broke the stale instance lock of PID %d on host %s\n", lock.Broken.PID, lock.Broken.Host)
+	}
+	return lock, nil
+}
+
 // logConfigSummary logs the loaded configuration for debugging
 func logConfigSummary(cfg *config.AppConfig) {
 	logging.Info("=== Configuration Summary ===")
@@ -239,7 +381,12 @@ func logConfigSummary(cfg *config.AppConfig) {
 	if cfg.Database.Host != "" && cfg.Database.Connection != "sqlite" {
 		logging.Infof("Database Host: %s", cfg.Database.Host)
 	}
+	logging.Infof("Database Pool: max_open=%d max_idle=%d", cfg.Database.Pool.MaxOpen, cfg.Database.Pool.MaxIdle)
 	logging.Infof("Logging Path: %s", cfg.Logging.Path)
+	logging.Infof("Log Level: %s", cfg.Logging.Level)
+	for _, override := range cfg.Overrides {
+		logging.Infof("Override: %s", override)
+	}
 	logging.Infof("JWT Expiry: %d seconds", cfg.JWT.Expiry)
 	logging.Infof("API Key Enabled: %v", cfg.APIKey.Enabled)
 	if cfg.APIKey.Enabled {
@@ -248,9 +395,76 @@ func logConfigSummary(cfg *config.AppConfig) {
 	logging.Info("============================")
 }
 
-// runConsistencyCheck performs startup consistency check and repair
-func runConsistencyCheck(ctx context.Context, driver database.Driver, reg *registry.SchemaRegistry, cfg *config.RecoveryConfig) error {
+// loadSchemas restores the persisted collection schemas into the registry and
+// attaches the store so later registry changes are persisted. When the schema
+// table is new and migrate is set, existing tables are migrated by inferring
+// their schemas; with schema discovery enabled, discovery registers them instead.
+func loadSchemas(ctx context.Context, driver database.Driver, reg *registry.SchemaRegistry, cfg *config.RecoveryConfig, migrate bool) error {
+	store := schemastore.New(driver)
+
+	created, err := store.Init(ctx)
+	if err != nil {
+		return err
+	}
+
+	collections, err := store.LoadAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, collection := range collections {
+		if err := reg.Set(collection); err != nil {
+			return fmt.Errorf("failed to register collection '%s': %w", collection.Name, err)
+		}
+	}
+	reg.SetStore(store)
+	logging.Infof("Loaded %d collection schema(s)", len(collections))
+
+	if !created || !migrate {
+		return nil
+	}
+
+	adopted, err := consistency.NewChecker(driver, reg, cfg).AdoptTables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate existing tables: %w", err)
+	}
+	if len(adopted) > 0 {
+		fmt.Printf("✓ Migrated %d existing table(s) to persisted schemas\n", len(adopted))
+		logging.Infof("Migrated %d existing table(s) to persisted schemas", len(adopted))
+	}
+	return nil
+}
+
+// discoverTables registers the pre-existing tables that can be served as
+// collections and prints a summary of the tables adopted and skipped, with
+// the reason for each skipped table. It returns the skipped tables, which the
+// startup consistency check leaves alone rather than failing on them.
+func discoverTables(ctx context.Context, driver database.Driver, reg *registry.SchemaRegistry, cfg *config.AppConfig) ([]string, error) {
+	result, err := consistency.NewChecker(driver, reg, &cfg.Recovery).Discover(ctx, cfg.Discovery.Adopt, handlers.ValidateCollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("✓ Discovery registered %d table(s), skipped %d\n", len(result.Adopted), len(result.Skipped))
+	logging.Infof("Discovery registered %d table(s), skipped %d", len(result.Adopted), len(result.Skipped))
+	for _, table := range result.Adopted {
+		fmt.Printf("  ✓ %s\n", table)
+		logging.Infof("DISCOVERY_ADOPTED table=%s", table)
+	}
+
+	skipped := make([]string, 0, len(result.Skipped))
+	for _, table := range result.Skipped {
+		fmt.Printf("  ✗ %s: %s\n", table.Name, table.Reason)
+		logging.Warnf("DISCOVERY_SKIPPED table=%s reason=%q", table.Name, table.Reason)
+		skipped = append(skipped, table.Name)
+	}
+	return skipped, nil
+}
+
+// runConsistencyCheck performs startup consistency check and repair. The
+// ignored tables are left out of the orphaned table check.
+func runConsistencyCheck(ctx context.Context, driver database.Driver, reg *registry.SchemaRegistry, cfg *config.RecoveryConfig, ignored []string) error {
 	checker := consistency.NewChecker(driver, reg, cfg)
+	checker.IgnoreTables(ignored...)
 
 	result, err := checker.Check(ctx)
 	if err != nil {
@@ -304,11 +518,12 @@ func runConsistencyCheck(ctx context.Context, driver database.Driver, reg *regis
 func bootstrapAuth(ctx context.Context, driver database.Driver, cfg *config.AppConfig) error {
 	// Create bootstrap config from app config
 	var bootstrapCfg *auth.BootstrapConfig
-	if cfg.Auth.BootstrapAdmin.Username != "" {
+	if cfg.Auth.BootstrapAdmin.Username != "" || cfg.Auth.BootstrapAdmin.APIKey != "" {
 		bootstrapCfg = &auth.BootstrapConfig{
 			Username: cfg.Auth.BootstrapAdmin.Username,
 			Email:    cfg.Auth.BootstrapAdmin.Email,
 			Password: cfg.Auth.BootstrapAdmin.Password,
+			APIKey:   cfg.Auth.BootstrapAdmin.APIKey,
 		}
 	}
 
@@ -317,7 +532,56 @@ func bootstrapAuth(ctx context.Context, driver database.Driver, cfg *config.AppC
 		return fmt.Errorf("bootstrap failed: %w", err)
 	}
 
+	// With API keys enabled, a fresh install gets an admin key that is shown once
+	if cfg.APIKey.Enabled {
+		rawKey, err := auth.MintBootstrapAPIKey(ctx, driver)
+		if err != nil {
+			return fmt.Errorf("bootstrap failed: %w", err)
+		}
+		if rawKey != "" {
+			logging.Info("Bootstrap admin API key minted; it is printed once to stdout")
+			fmt.Printf("Bootstrap admin API key (store it securely, it will not be shown again): %s\n", rawKey)
+		}
+	}
+
 	logging.Info("✓ Authentication bootstrap completed")
 	fmt.Println("✓ Authentication bootstrap completed")
 	return nil
 }
+
+// seedSampleCollection creates and seeds the sample collection unless an
+// earlier startup already did
+func seedSampleCollection(ctx context.Context, driver database.Driver, reg *registry.SchemaRegistry) error {
+	created, err := handlers.SeedSample(ctx, driver, reg)
+	if err != nil {
+		return err
+	}
+	if created {
+		logging.Infof("Created sample collection '%s'", handlers.SampleCollection)
+		fmt.Printf("✓ Created sample collection '%s'\n", handlers.SampleCollection)
+	}
+	return nil
+}
+
+// startAudit creates the audit table and prunes entries older than
+// audit.retention_days. It returns nil when audit.enabled is false.
+func startAudit(ctx context.Context, driver database.Driver, cfg *config.AppConfig) (*audit.Log, error) {
+	if !cfg.Audit.Enabled {
+		return nil, nil
+	}
+
+	auditLog := audit.New(driver, cfg.Audit.QueueSize)
+	if err := auditLog.Init(ctx); err != nil {
+		return nil, err
+	}
+	if cfg.Audit.RetentionDays > 0 {
+		pruned, err := auditLog.Prune(ctx, cfg.Audit.RetentionDays)
+		if err != nil {
+			return nil, err
+		}
+		logging.Infof("Pruned %d audit entries older than %d days", pruned, cfg.Audit.RetentionDays)
+	}
+
+	logging.Info("✓ Audit log enabled")
+	return auditLog, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thalib/moon/cmd/moon/internal/config"
+	"github.com/thalib/moon/cmd/moon/internal/constants"
+	"github.com/thalib/moon/cmd/moon/internal/daemon"
+	"github.com/thalib/moon/cmd/moon/internal/database"
+)
+
+func TestStart_FailureReleasesInstanceLock(t *testing.T) {
+	driver, err := database.NewDriver(database.Config{ConnectionString: "sqlite://:memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	ctx := context.Background()
+	if err := driver.Connect(ctx); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer driver.Close()
+
+	// The row lock of PostgreSQL and MySQL, taken on SQLite for the test
+	lock, err := daemon.LockDatabase(ctx, driver, time.Minute)
+	if err != nil {
+		t.Fatalf("LockDatabase() error = %v", err)
+	}
+
+	// The log file cannot be created under a regular file, so startup fails
+	// after the lock was taken
+	notDir := filepath.Join(t.TempDir(), "logs")
+	if err := os.WriteFile(notDir, nil, 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cfg := &config.AppConfig{Logging: config.LoggingConfig{Path: notDir}}
+	daemonCfg := daemon.Config{PIDFile: filepath.Join(t.TempDir(), "moon.pid")}
+	if _, _, err := start(ctx, cfg, driver, lock, true, daemonCfg); err == nil {
+		t.Fatal("Expected startup to fail")
+	}
+
+	var rows int
+	if err := driver.QueryRow(ctx, "SELECT COUNT(*) FROM "+constants.TableLock).Scan(&rows); err != nil {
+		t.Fatalf("Failed to count lock rows: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("Expected the failed startup to delete the lock row, %d left", rows)
+	}
+	if _, err := os.Stat(daemonCfg.PIDFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no PID file, got %v", err)
+	}
+}